@@ -0,0 +1,9 @@
+// Package swagger embeds the API contract so the server can check itself against it
+package swagger
+
+import _ "embed"
+
+// Spec - the Swagger 2.0 document of /api/v1
+//
+//go:embed swagger.yaml
+var Spec []byte
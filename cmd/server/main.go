@@ -2,20 +2,75 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
-	"strconv"
+	"regexp"
+	"slices"
 	"strings"
 	"syscall"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
 
+	"subs_tracker/internal/activity"
+	"subs_tracker/internal/alerts"
+	"subs_tracker/internal/app"
+	"subs_tracker/internal/archive"
+	"subs_tracker/internal/audit"
+	"subs_tracker/internal/backup"
+	"subs_tracker/internal/buildinfo"
+	"subs_tracker/internal/changes"
 	"subs_tracker/internal/config"
+	"subs_tracker/internal/enrichment"
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/events"
 	httpGateway "subs_tracker/internal/gateways/http"
+	"subs_tracker/internal/integrations"
+	"subs_tracker/internal/leader"
+	"subs_tracker/internal/loadshed"
+	"subs_tracker/internal/logging"
+	"subs_tracker/internal/metrics"
+	"subs_tracker/internal/migrate"
+	"subs_tracker/internal/pricecheck"
+	"subs_tracker/internal/quarantine"
+	"subs_tracker/internal/readmodel"
+	"subs_tracker/internal/reconcile"
+	activityPostgres "subs_tracker/internal/repository/activity/postgres"
+	auditPostgres "subs_tracker/internal/repository/audit/postgres"
+	leaderPostgres "subs_tracker/internal/repository/leader/postgres"
+	pricecheckPostgres "subs_tracker/internal/repository/pricecheck/postgres"
+	quarantinePostgres "subs_tracker/internal/repository/quarantine/postgres"
+	readModelPostgres "subs_tracker/internal/repository/readmodel/postgres"
+	reconcilePostgres "subs_tracker/internal/repository/reconcile/postgres"
+	sandboxPostgres "subs_tracker/internal/repository/sandbox/postgres"
+	sharePostgres "subs_tracker/internal/repository/share/postgres"
+	"subs_tracker/internal/repository/subscription/instrumented"
 	subsRepository "subs_tracker/internal/repository/subscription/postgres"
+	"subs_tracker/internal/repository/subscription/pseudonymized"
+	"subs_tracker/internal/repository/subscription/sharded"
+	tablesPostgres "subs_tracker/internal/repository/tables/postgres"
+	themePostgres "subs_tracker/internal/repository/theme/postgres"
+	userhooksPostgres "subs_tracker/internal/repository/userhooks/postgres"
+	widgetPostgres "subs_tracker/internal/repository/widget/postgres"
+	"subs_tracker/internal/s3"
+	"subs_tracker/internal/sandbox"
+	"subs_tracker/internal/share"
+	"subs_tracker/internal/snapshot"
+	"subs_tracker/internal/stripe"
+	"subs_tracker/internal/tenancy"
+	"subs_tracker/internal/theme"
+	"subs_tracker/internal/tracing"
+	"subs_tracker/internal/usage"
 	usecaseInternal "subs_tracker/internal/usecase"
+	"subs_tracker/internal/userhooks"
+	"subs_tracker/internal/webhooks"
+	"subs_tracker/internal/widget"
+	"subs_tracker/pkg/fieldcrypt"
 )
 
 const (
@@ -34,75 +89,697 @@ func main() {
 		return
 	}
 
+	info := buildinfo.Get()
 	pgCfg := cfg.Pg
-	log := setupLogger(cfg.Env)
+	if pgCfg.ApplicationName == "" {
+		pgCfg.ApplicationName = info.UserAgent()
+	}
+	log := setupLogger(cfg.Env, cfg.Log)
 
-	log.Info("starting subs tracker", slog.String("env", cfg.Env))
+	log.Info("starting subs tracker",
+		slog.String("env", cfg.Env),
+		slog.String("version", info.Version),
+		slog.String("commit", info.Commit),
+	)
 	log.Debug("debug messages are enabled")
 
-	pool := initStorage(pgCfg, ctx, log)
+	stopTracing := setupTracing(ctx, cfg.Tracing, log)
+	defer stopTracing()
+
+	poolOpts := subsRepository.PoolOptions{MinConns: pgCfg.MinConns, MaxConns: pgCfg.MaxConns, StatementCache: pgCfg.StatementCache}
+	tenantIDs := pgCfg.TenantSchemas
+	if t := cfg.Sandbox.Tenant; t != "" && !slices.Contains(tenantIDs, t) {
+		// the sandbox is reset by wiping its schema, it never shares one
+		tenantIDs = append(slices.Clone(tenantIDs), t)
+	}
+	var tenants *tenancy.Schemas
+	if len(tenantIDs) > 0 {
+		tenants = tenancy.NewSchemas(tenantIDs)
+		log.Info("tenants are kept in schemas of their own", slog.Any("tenants", tenantIDs))
+	}
+	pool := initStorage(pgCfg.DSN(), poolOpts, tenants, ctx, log)
 	defer pool.Close()
+	checks := []httpGateway.HealthCheck{{Name: "postgres", Check: pingCheck(pool)}}
+	pools := []*pgxpool.Pool{pool}
 
 	log.Debug("init database")
 
-	sr := subsRepository.NewSubRepository(pool)
+	metricsOpts := setupMetrics(cfg)
+	var migrations *metrics.Migrations
+	if pgCfg.MigrationsDir != "" {
+		migrations = metrics.NewMigrations(prometheus.DefaultRegisterer, metricsOpts)
+		migrateStorage(ctx, pool, pgCfg.DSN(), pgCfg.MigrationsDir, tenants, migrations, log)
+	}
+
+	mainRepo := subsRepository.NewSubRepository(pool,
+		subsRepository.WithExplain(pgCfg.ExplainThreshold, log),
+	)
+	var sr usecaseInternal.SubscriptionRepository = mainRepo
+	legacyCosts := []usecaseInternal.LegacyCostStore{mainRepo}
+	if len(pgCfg.ShardDSNs) > 0 {
+		shards := []usecaseInternal.SubscriptionRepository{sr}
+		for i, dsn := range pgCfg.ShardDSNs {
+			shardPool := initStorage(dsn, poolOpts, tenants, ctx, log)
+			defer shardPool.Close()
+			pools = append(pools, shardPool)
+			if pgCfg.MigrationsDir != "" {
+				migrateStorage(ctx, shardPool, dsn, pgCfg.MigrationsDir, tenants, migrations, log)
+			}
+			checks = append(checks, httpGateway.HealthCheck{Name: fmt.Sprintf("postgres_shard_%d", i+1), Check: pingCheck(shardPool)})
+			shardRepo := subsRepository.NewSubRepository(shardPool,
+				subsRepository.WithExplain(pgCfg.ExplainThreshold, log),
+			)
+			shards = append(shards, shardRepo)
+			legacyCosts = append(legacyCosts, shardRepo)
+		}
+		sr = sharded.NewRouter(shards...)
+		log.Info("storage is sharded", slog.Int("shards", len(shards)))
+	}
+	sr = instrumented.NewRepository(sr, metrics.NewRepository(prometheus.DefaultRegisterer, metricsOpts))
+	pseudonyms := setupPseudonyms(cfg.Pseudonym, sr, mainRepo, log)
+	if pseudonyms != nil {
+		sr = pseudonyms
+	}
+	hookClient := setupWebhooks(cfg.Webhook)
+	userHooks := setupUserHooks(cfg.Webhook, pool)
+	feed := setupActivity(cfg.Users, pool, pseudonyms)
+	changeLog := setupChanges(cfg.Events)
+	tracked := integrations.NewRegistry()
+	bus := setupEvents(cfg.Events, hookClient, userHooks, feed, changeLog, tracked, log)
+	if len(bus.Subscribers()) > 0 {
+		checks = append(checks, httpGateway.HealthCheck{Name: "events", Soft: true, Check: bus.Check})
+	}
+	var projector *readmodel.Projector
+	var analytics usecaseInternal.AnalyticsReader
+	if cfg.ReadModel.Enabled {
+		rmPool := pool
+		if cfg.ReadModel.DSN != "" {
+			rmPool = initStorage(cfg.ReadModel.DSN, poolOpts, nil, ctx, log)
+			defer rmPool.Close()
+			// only analytics are read from it, the API keeps working without it
+			checks = append(checks, httpGateway.HealthCheck{Name: "read_model", Soft: true, Check: pingCheck(rmPool)})
+		}
+		var store readmodel.Store = readModelPostgres.NewStore(rmPool)
+		if pseudonyms != nil {
+			store = pseudonymized.NewSpendStore(store, pseudonyms)
+		}
+		projector = readmodel.NewProjector(sr, store, log, readmodel.WithRebuildInterval(cfg.ReadModel.RebuildInterval))
+		bus.Subscribe(projector.Subscriber())
+		analytics = store
+		log.Info("analytics are served from the read model", slog.Bool("own_database", cfg.ReadModel.DSN != ""))
+	}
+	archiver := setupArchive(cfg.Archive, sr, pseudonyms, log)
+	var archived usecaseInternal.ArchiveReader
+	if archiver != nil {
+		archived = archiver
+	}
+	box := setupQuarantine(cfg.Import, pool, pseudonyms)
+	var held usecaseInternal.Quarantine
+	if box != nil {
+		held = box
+	}
+	subUC := usecaseInternal.NewSubscription(sr,
+		usecaseInternal.WithEvents(bus),
+		usecaseInternal.WithMetrics(metrics.NewBusiness(prometheus.DefaultRegisterer, metricsOpts)),
+		usecaseInternal.WithValidationRules(validationRules(cfg.Validation)),
+		usecaseInternal.WithAnomalyRules(usecaseInternal.AnomalyRules{
+			BaselineMonths:   cfg.Anomaly.BaselineMonths,
+			ThresholdPercent: cfg.Anomaly.ThresholdPercent,
+		}),
+		usecaseInternal.WithBenchmarkMinUsers(cfg.Benchmark.MinUsers),
+		usecaseInternal.WithArchive(archived),
+		usecaseInternal.WithAnalytics(analytics),
+		usecaseInternal.WithCostNowTTL(cfg.Server.CostNowTTL),
+		usecaseInternal.WithCostNowPrime(cfg.Server.CostNowPrime),
+		usecaseInternal.WithLegacyCosts(legacyCosts...),
+		usecaseInternal.WithUserDeletion(usecaseInternal.UserDeletionPolicy(cfg.Users.DeletePolicy)),
+		usecaseInternal.WithIDStrategy(usecaseInternal.IDStrategy(cfg.IDs.Strategy)),
+		usecaseInternal.WithHashids(cfg.IDs.HashidSecret, cfg.IDs.HashidMinLength),
+		usecaseInternal.WithImportLimits(usecaseInternal.ImportLimits{
+			MaxRows:   cfg.Import.MaxRows,
+			ChunkSize: cfg.Import.ChunkSize,
+		}),
+		usecaseInternal.WithQuarantine(held),
+	)
 
+	stripeSync := setupStripe(cfg.Stripe, subUC, tracked.Track("stripe"), log)
+	backups := setupBackup(cfg.Backup, pool, tenants, metrics.NewBackup(prometheus.DefaultRegisterer, metricsOpts), log)
+	if backups != nil {
+		checks = append(checks, httpGateway.HealthCheck{Name: "backup", Soft: true, Check: backups.Check})
+	}
+	catalog := setupCatalog(cfg.Enrich)
+	priceCheck, priceReviews := setupPriceCheck(cfg.Enrich, sr, catalog, pool, pseudonyms, log)
+	reconciler, ledger := setupReconcile(cfg.Reconcile, sr, pool, pseudonyms, log)
 	useCases := httpGateway.UseCases{
-		Sub: usecaseInternal.NewSubscription(sr),
+		Sub:      subUC,
+		Catalog:  catalog,
+		Webhooks: hookClient,
+		Stripe:   stripeSync,
+		Checks:   checks,
+	}
+	useCases.Shares = share.NewLinks(sharePostgres.NewStore(pool), share.WithMaxTTL(cfg.Share.MaxTTL))
+	useCases.Themes = theme.NewThemes(themePostgres.NewStore(pool))
+	useCases.Widgets = widget.NewTokens(widgetPostgres.NewStore(pool))
+	useCases.UserHooks = userHooks
+	useCases.Activity = feed
+	useCases.Changes = changeLog
+	useCases.PriceReviews = priceReviews
+	useCases.Reconcile = ledger
+	useCases.Quarantine = box
+	useCases.ReadModel = projector
+	useCases.Archive = archiver
+	if cfg.Snapshot.Secret != "" {
+		useCases.Snapshots = snapshot.NewSealer([]byte(cfg.Snapshot.Secret))
 	}
 
+	jobs, closeJobs := setupJobs(cfg.Jobs, cfg.Metrics.Instance, pool, log)
+	defer closeJobs()
+	useCases.Jobs = jobs
+	useCases.Usage = setupUsage(cfg.Analytics, tracked.Track("usage"), log)
+	useCases.Integrations = tracked
+	useCases.Tenants = tenants
+	useCases.Sandbox = setupSandbox(cfg.Sandbox, pools, subUC, log)
+	useCases.LoadShed = setupLoadShed(cfg.LoadShed, pools, log)
+	auditPurger := setupRequestAudit(cfg.RequestAudit, pool, &useCases, log)
+
+	refresher := metrics.NewRefresher(cfg.Metrics.RefreshInterval, subUC.RefreshStats, log)
+
 	server := httpGateway.New(useCases,
 		*cfg,
 		log,
-		httpGateway.WithHost(cfg.Server.Host),
+		httpGateway.WithHosts(cfg.Server.Hosts...),
 		httpGateway.WithPort(uint16(cfg.Server.Port)),
 		httpGateway.WithLogger(log),
 		httpGateway.WithTimeout(cfg.Server.Timeout),
+		httpGateway.WithReusePort(cfg.Server.ReusePort),
+		httpGateway.WithDrainDelay(cfg.Server.DrainDelay),
+		httpGateway.WithMetrics(metrics.NewHTTP(prometheus.DefaultRegisterer, metricsOpts)),
+	)
+
+	group := app.New(
+		app.WithLogger(log),
+		app.WithShutdownTimeout(cfg.ShutdownTimeout),
 	)
+	group.Add("metrics-refresher", refresher.Run)
+	if cfg.Anomaly.Interval > 0 {
+		anomalies := alerts.NewAnomalyJob(cfg.Anomaly.Interval, subUC.DetectSpendAnomalies, alerts.NewLogNotifier(log), log)
+		group.Add("anomaly-detector", jobs.Guard("anomaly-detector", anomalies.Run))
+	}
+	if cfg.TableGrowth.Interval > 0 {
+		growth := alerts.NewGrowthJob(cfg.TableGrowth.Interval, tablesPostgres.NewStats(pool).Tables,
+			alerts.GrowthRules{MaxRowsPerHour: cfg.TableGrowth.MaxRowsPerHour, MaxBytes: cfg.TableGrowth.MaxBytes},
+			alerts.NewLogNotifier(log), log,
+			alerts.WithGrowthRecorder(metrics.NewTables(prometheus.DefaultRegisterer, metricsOpts)),
+		)
+		group.Add("table-growth", jobs.Guard("table-growth", growth.Run))
+	}
+	group.Add("events", bus.Run)
+	if useCases.LoadShed != nil {
+		// the pools are per instance, every replica sheds on its own
+		group.Add("load-shedder", useCases.LoadShed.Run)
+	}
+	if subUC.CostNowPriming() {
+		// the cache is per instance, every replica fills its own
+		group.Add("cost-now-primer", primeCostNow(subUC, log))
+	}
+	if priceCheck != nil {
+		group.Add("price-check", jobs.Guard("price-check", priceCheck.Run))
+	}
+	if reconciler != nil {
+		group.Add("reconcile", jobs.Guard("reconcile", reconciler.Run))
+	}
+	if stripeSync != nil {
+		group.Add("stripe-sync", jobs.Guard("stripe-sync", stripeSync.Run))
+	}
+	if archiver != nil {
+		group.Add("archiver", jobs.Guard("archiver", archiver.Run))
+	}
+	if projector != nil {
+		group.Add("read-model", jobs.Guard("read-model", projector.Run))
+	}
+	if backups != nil {
+		group.Add("backups", jobs.Guard("backups", backups.Run))
+	}
+	if useCases.Usage != nil {
+		// counts are per instance, every replica sends its own
+		group.Add("usage", useCases.Usage.Run)
+	}
+	if useCases.Audit != nil {
+		// every replica stores what it received
+		group.Add("request-audit", useCases.Audit.Run)
+	}
+	if auditPurger != nil {
+		group.Add("request-audit-purge", jobs.Guard("request-audit-purge", auditPurger.Run))
+	}
+	group.Add("http", server.Run)
 
-	addr := cfg.Server.Host + ":" + strconv.Itoa(cfg.Server.Port)
-	log.Info("starting server", slog.String("address", addr))
-	if err := server.Run(ctx); err != nil {
+	log.Info("starting server", slog.Any("hosts", cfg.Server.Hosts), slog.Int("port", cfg.Server.Port))
+	if err := group.Run(ctx); err != nil {
 		log.Error("server stopped with error", slog.Any("error", err))
 		return
 	}
 	log.Info("server stopped")
 }
 
-// initStorage - init postgres db
-func initStorage(pgCfg config.PgConfig, ctx context.Context, log *slog.Logger) *pgxpool.Pool {
-	databaseUrl := fmt.Sprintf(
-		"postgres://%s:%s@%s:%d/%s",
-		pgCfg.User,
-		pgCfg.Password,
-		pgCfg.Host,
-		pgCfg.Port,
-		pgCfg.Db)
+// initStorage - init postgres db, opening the minimum of connections of opts right away; the connections of
+// requests of tenants are switched to their schemas
+func initStorage(dsn string, opts subsRepository.PoolOptions, tenants *tenancy.Schemas, ctx context.Context, log *slog.Logger) *pgxpool.Pool {
+	poolCfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		log.Error("failed to parse storage config", slog.Any("error", err))
+		os.Exit(1)
+	}
+	poolCfg.ConnConfig.Tracer = tracing.QueryTracer{}
+	subsRepository.CancelStatements(poolCfg)
+	opts.Apply(poolCfg)
+	if tenants != nil {
+		tenants.Configure(poolCfg)
+	}
 
-	pool, err := pgxpool.New(ctx, databaseUrl)
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
 	if err != nil {
 		log.Error("failed to init storage", slog.Any("error", err))
 		os.Exit(1)
 	}
+	if poolCfg.MinConns > 0 {
+		// an unreachable database is left to the readiness check, the pool keeps connecting in the background
+		n, err := subsRepository.WarmUp(ctx, pool)
+		if err != nil {
+			log.Warn("storage warm-up incomplete", slog.String("host", poolCfg.ConnConfig.Host), slog.Int("conns", n), slog.Any("error", err))
+		} else {
+			log.Info("storage warmed up", slog.String("host", poolCfg.ConnConfig.Host), slog.Int("conns", n))
+		}
+	}
 	return pool
 }
 
-// setupLogger - setup slog.Logger for logging
-func setupLogger(env string) *slog.Logger {
-	var log *slog.Logger
+// migrateStorage - apply the migrations of dir to the database of pool and to every tenant schema in it, one
+// replica at a time
+func migrateStorage(ctx context.Context, pool *pgxpool.Pool, dsn, dir string, tenants *tenancy.Schemas, rec migrate.Recorder, log *slog.Logger) {
+	schemas := []string{""}
+	if tenants != nil {
+		for _, t := range tenants.Tenants() {
+			schemas = append(schemas, tenancy.SchemaName(t))
+		}
+	}
+	for _, schema := range schemas {
+		err := migrate.New(pool, dsn, dir, log, migrate.WithRecorder(rec), migrate.WithSchema(schema)).Up(ctx)
+		if err != nil {
+			log.Error("failed to migrate storage", slog.String("schema", schema), slog.Any("error", err))
+			os.Exit(1)
+		}
+	}
+}
+
+// pingCheck - readiness check of a database pool
+func pingCheck(pool *pgxpool.Pool) func(ctx context.Context) (any, error) {
+	return func(ctx context.Context) (any, error) { return nil, pool.Ping(ctx) }
+}
+
+// setupTracing - export request traces when a collector is configured; the returned func flushes the last spans
+func setupTracing(ctx context.Context, c config.TracingConfig, log *slog.Logger) func() {
+	if c.Endpoint == "" {
+		return func() {}
+	}
+	shutdown, err := tracing.Setup(ctx, c.Endpoint, c.SampleRatio)
+	if err != nil {
+		log.Error("failed to set up tracing", slog.Any("error", err))
+		os.Exit(1)
+	}
+	log.Info("exporting traces", slog.String("endpoint", c.Endpoint), slog.Float64("sample_ratio", c.SampleRatio))
+	return func() {
+		flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if err := shutdown(flushCtx); err != nil {
+			log.Error("failed to flush traces", slog.Any("error", err))
+		}
+	}
+}
+
+// setupCatalog - build the service metadata enricher, nil when no catalog URL is configured
+func setupCatalog(c config.EnrichConfig) *enrichment.Enricher {
+	if c.URL == "" {
+		return nil
+	}
+	provider := enrichment.NewHTTPProvider(c.URL, enrichment.WithAPIKey(c.APIKey))
+	return enrichment.NewEnricher(provider,
+		enrichment.WithTTL(c.CacheTTL),
+		enrichment.WithTimeout(c.Timeout),
+	)
+}
+
+// setupPriceCheck - build the job comparing current subscriptions with the catalog prices and the reviews it
+// opens, both nil unless the catalog is configured and ENRICH_PRICE_CHECK_INTERVAL is set
+func setupPriceCheck(c config.EnrichConfig, subs usecaseInternal.SubscriptionRepository, catalog *enrichment.Enricher,
+	pool *pgxpool.Pool, pseudonyms *pseudonymized.Repository, log *slog.Logger) (*pricecheck.Job, *pricecheck.Reviews) {
+	if catalog == nil || c.PriceCheckInterval <= 0 {
+		return nil, nil
+	}
+	var store pricecheck.Store = pricecheckPostgres.NewStore(pool)
+	if pseudonyms != nil {
+		store = pseudonymized.NewReviewStore(store, pseudonyms)
+	}
+	reviews := pricecheck.NewReviews(store)
+	return pricecheck.NewJob(c.PriceCheckInterval, subs, catalog, reviews, log), reviews
+}
+
+// setupReconcile - build the job reconciling subscriptions with the charges of uploaded bank statements and
+// the ledger keeping them, both nil unless RECONCILE_INTERVAL is set
+func setupReconcile(c config.ReconcileConfig, subs usecaseInternal.SubscriptionRepository, pool *pgxpool.Pool,
+	pseudonyms *pseudonymized.Repository, log *slog.Logger) (*reconcile.Job, *reconcile.Ledger) {
+	if c.Interval <= 0 {
+		return nil, nil
+	}
+	var store reconcile.Store = reconcilePostgres.NewStore(pool)
+	if pseudonyms != nil {
+		store = pseudonymized.NewLedgerStore(store, pseudonyms)
+	}
+	ledger := reconcile.NewLedger(store)
+	return reconcile.NewJob(c.Interval, subs, ledger, reconcile.NewLogNotifier(log), log,
+		reconcile.WithMonths(c.Months)), ledger
+}
+
+// setupQuarantine - build the box holding the import rows that fail validation, nil unless
+// IMPORT_QUARANTINE_ENABLED is set
+func setupQuarantine(c config.ImportConfig, pool *pgxpool.Pool, pseudonyms *pseudonymized.Repository) *quarantine.Box {
+	if !c.QuarantineEnabled {
+		return nil
+	}
+	var store quarantine.Store = quarantinePostgres.NewStore(pool)
+	if pseudonyms != nil {
+		store = pseudonymized.NewQuarantineStore(store, pseudonyms)
+	}
+	return quarantine.NewBox(store)
+}
+
+// setupUsage - count API requests for product analytics when a sink is configured; the key is already
+// checked by config
+func setupUsage(c config.AnalyticsConfig, tracker *integrations.Tracker, log *slog.Logger) *usage.Counter {
+	if c.Sink != "posthog" {
+		return nil
+	}
+	log.Info("anonymous usage counts are sent to posthog", slog.String("host", c.PostHogHost))
+	return usage.NewCounter(usage.NewPostHog(c.PostHogHost, c.PostHogKey), log,
+		usage.WithInterval(c.FlushInterval),
+		usage.WithTracker(tracker),
+	)
+}
+
+// primeCostNow - fill the current-month totals cache at startup and again after every flush; a failed priming
+// is logged, the totals it missed are computed on demand
+func primeCostNow(sub *usecaseInternal.Subscription, log *slog.Logger) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		for {
+			start := time.Now()
+			n, err := sub.PrimeCostNow(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.Warn("cost now priming failed", slog.Int("primed", n), slog.Any("error", err))
+			} else if err == nil {
+				log.Info("cost now primed", slog.Int("users", n), slog.Duration("took", time.Since(start)))
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-sub.CostNowFlushed():
+			}
+		}
+	}
+}
+
+// setupRequestAudit - record write requests in the request_audit table when enabled, returns the purger of
+// old entries, nil when they are kept forever
+func setupRequestAudit(c config.RequestAuditConfig, pool *pgxpool.Pool, useCases *httpGateway.UseCases,
+	log *slog.Logger) *audit.Purger {
+	if !c.Enabled {
+		return nil
+	}
+	store := auditPostgres.NewStore(pool)
+	useCases.Audit = audit.NewLog(store, log)
+	log.Info("write requests are recorded in the request audit", slog.Duration("retention", c.Retention))
+	if c.Retention == 0 {
+		return nil
+	}
+	return audit.NewPurger(store, c.Retention, log)
+}
+
+// setupSandbox - build the sandbox integrators reset, nil unless SANDBOX_TENANT is set; its tenant already has a
+// schema in every database of pools
+func setupSandbox(c config.SandboxConfig, pools []*pgxpool.Pool, subs sandbox.Registrar, log *slog.Logger) *sandbox.Sandbox {
+	if c.Tenant == "" {
+		return nil
+	}
+	seed, err := sandbox.LoadSeed(c.SeedFile)
+	if err != nil {
+		log.Error("failed to load the sandbox seed", slog.Any("error", err))
+		os.Exit(1)
+	}
+	log.Info("sandbox is enabled", slog.String("tenant", c.Tenant), slog.Int("seed", len(seed)))
+	store := sandboxPostgres.NewStore(tenancy.SchemaName(c.Tenant), pools...)
+	return sandbox.New(c.Tenant, seed, store, subs)
+}
+
+// setupLoadShed - build the controller shedding low-priority requests while the pools of the subscriptions are
+// overloaded, nil unless LOAD_SHED_ENABLED is set
+func setupLoadShed(c config.LoadShedConfig, pools []*pgxpool.Pool, log *slog.Logger) *loadshed.Controller {
+	if !c.Enabled {
+		return nil
+	}
+	return loadshed.NewController(loadshed.PoolStats(pools...),
+		loadshed.Thresholds{MaxAcquireWait: c.MaxAcquireWait, MaxPoolUsage: c.MaxPoolUsage}, log,
+		loadshed.WithInterval(c.Interval),
+	)
+}
+
+// setupWebhooks - build the webhook client, nil when no URL is configured; the format is already checked by config
+func setupWebhooks(c config.WebhookConfig) *webhooks.Client {
+	if c.URL == "" {
+		return nil
+	}
+	format, _ := webhooks.ParseFormat(c.Format)
+	client := webhooks.NewClient(c.URL,
+		webhooks.WithFormat(format),
+		webhooks.WithSecret(c.Secret),
+		webhooks.WithTimeout(c.Timeout),
+	)
+	return client
+}
+
+// setupUserHooks - build the webhooks users register for themselves, nil unless WEBHOOK_USER_ENABLED is set
+func setupUserHooks(c config.WebhookConfig, pool *pgxpool.Pool) *userhooks.Hooks {
+	if !c.UserEnabled {
+		return nil
+	}
+	return userhooks.NewHooks(userhooksPostgres.NewStore(pool),
+		userhooks.WithLimit(c.UserLimit),
+		userhooks.WithTimeout(c.Timeout),
+		userhooks.WithPrivateNetworks(c.UserPrivate),
+	)
+}
+
+// setupActivity - build the activity feeds of users, nil unless USER_ACTIVITY_ENABLED is set
+func setupActivity(c config.UsersConfig, pool *pgxpool.Pool, pseudonyms *pseudonymized.Repository) *activity.Feed {
+	if !c.ActivityEnabled {
+		return nil
+	}
+	var store activity.Store = activityPostgres.NewStore(pool)
+	if pseudonyms != nil {
+		store = pseudonymized.NewActivityStore(store, pseudonyms)
+	}
+	return activity.NewFeed(store)
+}
+
+// setupChanges - build the log of changes long-polled by clients, nil when EVENTS_CHANGES_BUFFER is 0
+func setupChanges(c config.EventsConfig) *changes.Log {
+	if c.ChangesBuffer <= 0 {
+		return nil
+	}
+	return changes.NewLog(changes.WithSize(c.ChangesBuffer))
+}
+
+// setupEvents - build the event bus and subscribe the configured sinks: webhooks, user webhooks, activity feeds,
+// the changes log, NATS and Kafka. Every delivery attempt is tracked as a call of the subscriber's integration. URLs are already
+// checked by config
+func setupEvents(c config.EventsConfig, hooks *webhooks.Client, users *userhooks.Hooks, feed *activity.Feed,
+	changeLog *changes.Log, tracked *integrations.Registry, log *slog.Logger) *events.Bus {
+	bus := events.NewBus(log, events.WithQueueSize(c.QueueSize), events.WithIntegrations(tracked))
+	if hooks != nil {
+		bus.Subscribe(events.Webhook(hooks))
+	}
+	if users != nil {
+		bus.Subscribe(events.UserWebhooks(users))
+	}
+	if feed != nil {
+		bus.Subscribe(events.UserActivity(feed))
+	}
+	if changeLog != nil {
+		bus.Subscribe(events.Changes(changeLog))
+	}
+	if c.NATSURL != "" {
+		if n, err := events.NewNATS(c.NATSURL, c.NATSSubject, events.WithNATSTimeout(c.Timeout)); err == nil {
+			bus.Subscribe(n.Subscriber())
+		}
+	}
+	if c.KafkaRESTURL != "" {
+		bus.Subscribe(events.NewKafkaREST(c.KafkaRESTURL, c.KafkaTopic, events.WithKafkaTimeout(c.Timeout)).Subscriber())
+	}
+	if names := bus.Subscribers(); len(names) > 0 {
+		log.Info("events are published", slog.Any("subscribers", names))
+	}
+	return bus
+}
+
+// setupArchive - build the archiver of long-ended subscriptions, nil when no bucket is configured; with pseudonyms
+// the files hold the pseudonyms of the users, as the database does
+func setupArchive(c config.ArchiveConfig, src archive.Source, pseudonyms *pseudonymized.Repository, log *slog.Logger) *archive.Archiver {
+	if c.Bucket == "" {
+		return nil
+	}
+	store, err := s3.NewClient(c.Endpoint, c.Region, c.Bucket, c.AccessKey, c.SecretKey)
+	if err != nil {
+		log.Error("failed to init archive storage", slog.Any("error", err))
+		os.Exit(1)
+	}
+	options := []func(*archive.Archiver){
+		archive.WithAge(c.AfterYears),
+		archive.WithInterval(c.Interval),
+		archive.WithBatchSize(c.BatchSize),
+		archive.WithPrefix(c.Prefix),
+	}
+	if pseudonyms != nil {
+		options = append(options, archive.WithUsers(pseudonyms))
+	}
+	return archive.NewArchiver(src, store, log, options...)
+}
+
+// setupBackup - build the nightly backup job of the main database and its tenant schemas, the sandbox one included,
+// nil when neither a directory nor a bucket is configured
+func setupBackup(c config.BackupConfig, pool *pgxpool.Pool, tenants *tenancy.Schemas, rec backup.Recorder, log *slog.Logger) *backup.Job {
+	var (
+		store backup.Store
+		err   error
+	)
+	switch {
+	case c.Dir != "":
+		store, err = backup.NewDirStore(c.Dir)
+	case c.Bucket != "":
+		store, err = s3.NewClient(c.Endpoint, c.Region, c.Bucket, c.AccessKey, c.SecretKey)
+	default:
+		return nil
+	}
+	if err != nil {
+		log.Error("failed to init backup storage", slog.Any("error", err))
+		os.Exit(1)
+	}
+	var schemas []string
+	if tenants != nil {
+		for _, t := range tenants.Tenants() {
+			schemas = append(schemas, tenancy.SchemaName(t))
+		}
+	}
+	return backup.NewJob(backup.NewCopyExporter(pool, backup.WithSchemas(schemas...)), store, log,
+		backup.WithTime(c.At),
+		backup.WithRetention(c.Retention),
+		backup.WithPrefix(c.Prefix),
+		backup.WithRecorder(rec),
+	)
+}
+
+// setupJobs - build the elector running each scheduled job on one replica, over Postgres advisory locks or, with
+// JOBS_LEADER_ELECTION=false, in-process locks; the returned func gives the locks back before the pool closes
+func setupJobs(c config.JobsConfig, instance string, pool *pgxpool.Pool, log *slog.Logger) (*leader.Elector, func()) {
+	options := []func(*leader.Elector){
+		leader.WithInstance(instance),
+		leader.WithInterval(c.LeaderInterval),
+		leader.WithLogger(log),
+	}
+	if !c.LeaderElection {
+		return leader.NewElector(leader.NewLocalLocker(), options...), func() {}
+	}
+	locker := leaderPostgres.NewLocker(pool)
+	jobs := leader.NewElector(locker, options...)
+	log.Info("scheduled jobs use leader election", slog.String("instance", jobs.Instance()))
+	return jobs, func() { locker.Close(context.Background()) }
+}
+
+// setupPseudonyms - wrap repo to store user IDs as pseudonyms, nil when no key is configured; the other stores
+// keeping user IDs are wrapped with the same pseudonyms. The keys are already checked by config
+func setupPseudonyms(c config.PseudonymConfig, repo usecaseInternal.SubscriptionRepository, lookup pseudonymized.Lookup, log *slog.Logger) *pseudonymized.Repository {
+	if c.Key == "" {
+		return nil
+	}
+	key, _ := base64.StdEncoding.DecodeString(c.Key)
+	keyring, _ := fieldcrypt.ParseKeyring(c.EncryptionKeys)
+	out, err := pseudonymized.NewRepository(repo, lookup, key, keyring)
+	if err != nil {
+		log.Error("failed to init user pseudonyms", slog.Any("error", err))
+		os.Exit(1)
+	}
+	log.Info("user IDs are stored as pseudonyms")
+	return out
+}
+
+// setupStripe - build the Stripe subscription syncer, nil when no API key is configured;
+// the user ID is already checked by config
+func setupStripe(c config.StripeConfig, charger stripe.Charger, tracker *integrations.Tracker, log *slog.Logger) *stripe.Syncer {
+	if c.APIKey == "" {
+		return nil
+	}
+	client := stripe.NewClient(c.APIKey, stripe.WithCustomer(c.Customer))
+	return stripe.NewSyncer(client, charger, entity.UserID(uuid.MustParse(c.UserID)), log,
+		stripe.WithInterval(c.SyncInterval),
+		stripe.WithWebhookSecret(c.WebhookSecret),
+		stripe.WithTracker(tracker),
+	)
+}
+
+// validationRules - build use case validation limits; the pattern is already checked by config
+func validationRules(c config.ValidationConfig) usecaseInternal.ValidationRules {
+	r := usecaseInternal.ValidationRules{
+		MaxCost:         c.MaxCost,
+		MaxPeriodMonths: c.MaxPeriodMonths,
+		EndDateRequired: c.EndDateRequired,
+		EarliestDate:    c.EarliestDate,
+		MaxYearsAhead:   c.MaxYearsAhead,
+	}
+	if c.ServiceNamePattern != "" {
+		r.ServiceName = regexp.MustCompile(c.ServiceNamePattern)
+	}
+	return r
+}
+
+// setupMetrics - build common metrics options with env and app_instance constant labels; not instance, which
+// Prometheus sets to the scrape target and would rename ours to exported_instance
+func setupMetrics(cfg *config.Config) metrics.Options {
+	instance := cfg.Metrics.Instance
+	if instance == "" {
+		instance, _ = os.Hostname()
+	}
+	return metrics.Options{
+		Namespace: cfg.Metrics.Namespace,
+		ConstLabels: prometheus.Labels{
+			"env":          cfg.Env,
+			"app_instance": instance,
+		},
+		Buckets: cfg.Metrics.Buckets,
+		Routes:  cfg.Metrics.Routes,
+	}
+}
+
+// setupLogger - setup slog.Logger for logging; personal data is redacted unless disabled
+func setupLogger(env string, c config.LogConfig) *slog.Logger {
+	var h slog.Handler
 	switch strings.ToLower(env) {
 	case envLocal:
-		log = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		h = slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})
 	case envDev:
-		log = slog.New(
-			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-		)
+		h = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})
 	case envProd:
-		log = slog.New(
-			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}),
-		)
+		h = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo})
 	default:
-		log = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+		h = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo})
+	}
+	if c.Redact {
+		h = logging.NewRedactHandler(h, c.SensitiveParams...)
 	}
-	return log
+	return slog.New(h)
 }
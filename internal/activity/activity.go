@@ -0,0 +1,160 @@
+// Package activity keeps the feed of what happened to the subscriptions of a user, for the activity tab of the
+// UI: a subscription was created, changed its price, got an end date or was deleted. Entries are made from
+// the subscription events of the bus, so like every other sink the feed is filled asynchronously and on a
+// best effort basis; updates that change neither the price nor the end date are left out
+package activity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/pagination"
+)
+
+// Limits - page sizes of a feed
+var Limits = pagination.Limits{Default: 20, Max: 100}
+
+// ErrIgnored - the event does not make an entry of the feed
+var ErrIgnored = errors.New("event not in the activity feed")
+
+// Kind — what happened to the subscription
+type Kind string
+
+const (
+	// KindCreated - the subscription was added
+	KindCreated Kind = "created"
+	// KindPriceChanged - the monthly cost changed
+	KindPriceChanged Kind = "price_changed"
+	// KindCancelled - the subscription got an end date, or an earlier one
+	KindCancelled Kind = "cancelled"
+	// KindDeleted - the subscription was removed
+	KindDeleted Kind = "deleted"
+)
+
+// Entry — one item of the feed
+type Entry struct {
+	// ID - position in the feeds, later entries have higher IDs
+	ID     int64
+	UserID entity.UserID
+	Kind   Kind
+	// SubscriptionID, PublicID - the subscription as the event named it; PublicID is empty with serial IDs
+	SubscriptionID int64
+	PublicID       string
+	ServiceName    string
+	// Cost - monthly cost after the change
+	Cost int64
+	// PreviousCost - monthly cost before the change, with KindPriceChanged
+	PreviousCost int64
+	// EndDate - last paid month, with KindCancelled
+	EndDate *time.Time
+	// At - when the change was written
+	At time.Time
+}
+
+// Store — storage of the feeds
+type Store interface {
+	// AddEntry - append the entry to the feed of its user and set its ID
+	AddEntry(ctx context.Context, e *Entry) error
+	// ListEntries - up to limit entries of the user with an ID below before, newest first; before 0 starts
+	// with the latest
+	ListEntries(ctx context.Context, userID entity.UserID, before int64, limit int) ([]Entry, error)
+	// DeleteEntries - remove the feed of the user, return how many entries it had
+	DeleteEntries(ctx context.Context, userID entity.UserID) (int64, error)
+}
+
+// Page — entries of a feed and whether older ones follow
+type Page struct {
+	Entries []Entry
+	HasMore bool
+}
+
+// Feed records subscription events into the feeds of their users and reads them back
+type Feed struct {
+	store Store
+}
+
+// NewFeed creates a feed kept in store
+func NewFeed(store Store) *Feed {
+	return &Feed{store: store}
+}
+
+// Record adds the entry made from the event to the feed of its user, ErrIgnored when it makes none
+func (f *Feed) Record(ctx context.Context, e usecase.SubscriptionEvent) error {
+	entry, ok := FromEvent(e)
+	if !ok {
+		return ErrIgnored
+	}
+	if err := f.store.AddEntry(ctx, &entry); err != nil {
+		return fmt.Errorf("record activity: %w", err)
+	}
+	return nil
+}
+
+// List returns up to limit entries of the user older than the entry before, newest first; before 0 starts
+// with the latest and limit is clamped by Limits
+func (f *Feed) List(ctx context.Context, userID entity.UserID, before int64, limit int) (Page, error) {
+	if userID.IsZero() {
+		return Page{}, entity.ErrInvalidUserID
+	}
+	limit = Limits.Clamp(limit)
+	// one entry more than asked tells whether another page follows
+	entries, err := f.store.ListEntries(ctx, userID, before, limit+1)
+	if err != nil {
+		return Page{}, fmt.Errorf("list activity: %w", err)
+	}
+	if len(entries) > limit {
+		return Page{Entries: entries[:limit], HasMore: true}, nil
+	}
+	return Page{Entries: entries}, nil
+}
+
+// Forget removes the feed of the user
+func (f *Feed) Forget(ctx context.Context, userID entity.UserID) error {
+	if _, err := f.store.DeleteEntries(ctx, userID); err != nil {
+		return fmt.Errorf("forget activity: %w", err)
+	}
+	return nil
+}
+
+// FromEvent makes the entry of the feed the event stands for; an update is a price change when the cost
+// changed and a cancellation when the end date was set or moved earlier, the price winning when both did
+func FromEvent(e usecase.SubscriptionEvent) (Entry, bool) {
+	sub := e.Subscription
+	if sub == nil || sub.UserID.IsZero() {
+		return Entry{}, false
+	}
+	entry := Entry{
+		UserID:         sub.UserID,
+		SubscriptionID: sub.ID,
+		PublicID:       e.PublicID,
+		ServiceName:    sub.ServiceName,
+		Cost:           sub.Cost,
+		At:             e.OccurredAt,
+	}
+	switch e.Type {
+	case usecase.EventSubscriptionCreated:
+		entry.Kind = KindCreated
+	case usecase.EventSubscriptionDeleted:
+		entry.Kind = KindDeleted
+	case usecase.EventSubscriptionUpdated:
+		prev := e.Previous
+		switch {
+		case prev == nil:
+			return Entry{}, false
+		case prev.Cost != sub.Cost:
+			entry.Kind, entry.PreviousCost = KindPriceChanged, prev.Cost
+		case sub.DateTo != nil && (prev.DateTo == nil || sub.DateTo.Before(*prev.DateTo)):
+			end := *sub.DateTo
+			entry.Kind, entry.EndDate = KindCancelled, &end
+		default:
+			return Entry{}, false
+		}
+	default:
+		return Entry{}, false
+	}
+	return entry, true
+}
@@ -0,0 +1,123 @@
+package activity_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/internal/activity"
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/storetest"
+	"subs_tracker/internal/usecase"
+)
+
+func month(m time.Month) *time.Time {
+	t := time.Date(2025, m, 1, 0, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func TestFromEvent(t *testing.T) {
+	ann := entity.UserID(uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba"))
+	sub := entity.Subscription{ID: 7, UserID: ann, ServiceName: "Netflix", Cost: 999}
+	with := func(change func(*entity.Subscription)) *entity.Subscription {
+		s := sub
+		change(&s)
+		return &s
+	}
+	tests := []struct {
+		name  string
+		event usecase.SubscriptionEvent
+		want  activity.Kind
+	}{
+		{"created", usecase.SubscriptionEvent{Type: usecase.EventSubscriptionCreated, Subscription: &sub}, activity.KindCreated},
+		{"deleted", usecase.SubscriptionEvent{Type: usecase.EventSubscriptionDeleted, Subscription: &sub}, activity.KindDeleted},
+		{"price", usecase.SubscriptionEvent{
+			Type:         usecase.EventSubscriptionUpdated,
+			Subscription: &sub,
+			Previous:     with(func(s *entity.Subscription) { s.Cost = 799 }),
+		}, activity.KindPriceChanged},
+		{"end set", usecase.SubscriptionEvent{
+			Type:         usecase.EventSubscriptionUpdated,
+			Subscription: with(func(s *entity.Subscription) { s.DateTo = month(9) }),
+			Previous:     &sub,
+		}, activity.KindCancelled},
+		{"end moved earlier", usecase.SubscriptionEvent{
+			Type:         usecase.EventSubscriptionUpdated,
+			Subscription: with(func(s *entity.Subscription) { s.DateTo = month(9) }),
+			Previous:     with(func(s *entity.Subscription) { s.DateTo = month(12) }),
+		}, activity.KindCancelled},
+		{"end moved later", usecase.SubscriptionEvent{
+			Type:         usecase.EventSubscriptionUpdated,
+			Subscription: with(func(s *entity.Subscription) { s.DateTo = month(12) }),
+			Previous:     with(func(s *entity.Subscription) { s.DateTo = month(9) }),
+		}, ""},
+		{"renamed", usecase.SubscriptionEvent{
+			Type:         usecase.EventSubscriptionUpdated,
+			Subscription: with(func(s *entity.Subscription) { s.ServiceName = "Netflix Premium" }),
+			Previous:     &sub,
+		}, ""},
+		{"update without the previous record", usecase.SubscriptionEvent{Type: usecase.EventSubscriptionUpdated, Subscription: &sub}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := activity.FromEvent(tt.event)
+			if tt.want == "" {
+				assert.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, tt.want, got.Kind)
+			assert.Equal(t, ann, got.UserID)
+			assert.Equal(t, int64(999), got.Cost)
+		})
+	}
+
+	price, _ := activity.FromEvent(tests[2].event)
+	assert.Equal(t, int64(799), price.PreviousCost)
+	cancelled, _ := activity.FromEvent(tests[3].event)
+	assert.Equal(t, month(9), cancelled.EndDate)
+}
+
+func TestFeed(t *testing.T) {
+	ctx := context.Background()
+	ann := entity.UserID(uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba"))
+	bob := entity.UserID(uuid.MustParse("0b0b0b0b-2bf1-4721-ae6f-7636e79a0cba"))
+	feed := activity.NewFeed(storetest.NewActivityStore())
+
+	for i, uid := range []entity.UserID{ann, bob, ann, ann} {
+		require.NoError(t, feed.Record(ctx, usecase.SubscriptionEvent{
+			Type:         usecase.EventSubscriptionCreated,
+			OccurredAt:   time.Date(2025, 7, 1, i, 0, 0, 0, time.UTC),
+			Subscription: &entity.Subscription{ID: int64(i + 1), UserID: uid, ServiceName: "Netflix", Cost: 999},
+		}))
+	}
+	assert.ErrorIs(t, feed.Record(ctx, usecase.SubscriptionEvent{Type: usecase.EventSubscriptionUpdated,
+		Subscription: &entity.Subscription{ID: 1, UserID: ann}}), activity.ErrIgnored)
+
+	page, err := feed.List(ctx, ann, 0, 2)
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 2)
+	assert.True(t, page.HasMore)
+	assert.Equal(t, []int64{4, 3}, []int64{page.Entries[0].SubscriptionID, page.Entries[1].SubscriptionID}, "newest first")
+
+	page, err = feed.List(ctx, ann, page.Entries[1].ID, 2)
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 1)
+	assert.False(t, page.HasMore)
+	assert.Equal(t, int64(1), page.Entries[0].SubscriptionID)
+
+	_, err = feed.List(ctx, entity.UserID{}, 0, 0)
+	assert.ErrorIs(t, err, entity.ErrInvalidUserID)
+
+	require.NoError(t, feed.Forget(ctx, ann))
+	page, err = feed.List(ctx, ann, 0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, page.Entries)
+	page, err = feed.List(ctx, bob, 0, 0)
+	require.NoError(t, err)
+	assert.Len(t, page.Entries, 1, "other feeds stay")
+}
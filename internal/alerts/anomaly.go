@@ -0,0 +1,121 @@
+// Package alerts runs background checks that notify about unexpected subscription activity.
+package alerts
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/clock"
+	"subs_tracker/pkg/dates"
+)
+
+const defaultAnomalyInterval = time.Hour
+
+// Notifier — delivery channel for spending anomalies
+type Notifier interface {
+	// NotifyAnomaly - deliver a single anomaly
+	NotifyAnomaly(ctx context.Context, a usecase.SpendAnomaly) error
+}
+
+// LogNotifier delivers anomalies as structured warnings in the service log
+type LogNotifier struct {
+	log *slog.Logger
+}
+
+// NewLogNotifier creates a notifier writing to log
+func NewLogNotifier(log *slog.Logger) *LogNotifier {
+	return &LogNotifier{log: log}
+}
+
+// NotifyAnomaly logs the anomaly with its baseline, projection and new services
+func (n *LogNotifier) NotifyAnomaly(_ context.Context, a usecase.SpendAnomaly) error {
+	n.log.Warn("spending anomaly",
+		slog.String("user_id", a.UserID.String()),
+		slog.String("month", dates.Format(a.Month)),
+		slog.Int64("baseline", a.Baseline),
+		slog.Int64("projected", a.Projected),
+		slog.Float64("deviation_percent", a.DeviationPercent),
+		slog.Any("new_services", a.NewServices),
+	)
+	return nil
+}
+
+// AnomalyJob periodically detects spending anomalies and notifies about each user at most once a month
+type AnomalyJob struct {
+	interval time.Duration
+	detect   func(ctx context.Context, now time.Time) ([]usecase.SpendAnomaly, error)
+	notifier Notifier
+	log      *slog.Logger
+	clock    clock.Clock
+	notified map[entity.UserID]time.Time
+}
+
+// NewAnomalyJob creates a job calling detect every interval and passing new anomalies to notifier, and applies options
+func NewAnomalyJob(interval time.Duration, detect func(ctx context.Context, now time.Time) ([]usecase.SpendAnomaly, error),
+	notifier Notifier, log *slog.Logger, options ...func(*AnomalyJob)) *AnomalyJob {
+	if interval <= 0 {
+		interval = defaultAnomalyInterval
+	}
+	j := &AnomalyJob{
+		interval: interval,
+		detect:   detect,
+		notifier: notifier,
+		log:      log,
+		clock:    clock.System,
+		notified: make(map[entity.UserID]time.Time),
+	}
+	for _, o := range options {
+		o(j)
+	}
+	return j
+}
+
+// WithClock sets the source of the current time
+func WithClock(c clock.Clock) func(*AnomalyJob) {
+	return func(j *AnomalyJob) {
+		if c != nil {
+			j.clock = c
+		}
+	}
+}
+
+// Run checks immediately and then on every tick until ctx is cancelled
+func (j *AnomalyJob) Run(ctx context.Context) error {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.Check(ctx); err != nil && ctx.Err() == nil {
+			j.log.Warn("spending anomaly check failed", slog.Any("error", err))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check detects anomalies once and notifies about those not yet reported for their month;
+// a failed notification is retried on the next check
+func (j *AnomalyJob) Check(ctx context.Context) error {
+	anomalies, err := j.detect(ctx, j.clock.Now())
+	if err != nil {
+		return err
+	}
+	for _, a := range anomalies {
+		if month, ok := j.notified[a.UserID]; ok && month.Equal(a.Month) {
+			continue
+		}
+		if err := j.notifier.NotifyAnomaly(ctx, a); err != nil {
+			j.log.Warn("spending anomaly notification failed",
+				slog.String("user_id", a.UserID.String()), slog.Any("error", err))
+			continue
+		}
+		j.notified[a.UserID] = a.Month
+	}
+	return nil
+}
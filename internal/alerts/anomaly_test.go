@@ -0,0 +1,66 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/usecase"
+)
+
+type recordingNotifier struct {
+	got  []usecase.SpendAnomaly
+	fail bool
+}
+
+func (n *recordingNotifier) NotifyAnomaly(_ context.Context, a usecase.SpendAnomaly) error {
+	if n.fail {
+		return errors.New("channel down")
+	}
+	n.got = append(n.got, a)
+	return nil
+}
+
+func TestAnomalyJob_Check(t *testing.T) {
+	ctx := context.Background()
+	user := entity.UserID(uuid.New())
+	july := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	august := july.AddDate(0, 1, 0)
+
+	month := july
+	detect := func(_ context.Context, _ time.Time) ([]usecase.SpendAnomaly, error) {
+		return []usecase.SpendAnomaly{{UserID: user, Month: month, Baseline: 500, Projected: 1500}}, nil
+	}
+	n := &recordingNotifier{fail: true}
+	job := NewAnomalyJob(time.Minute, detect, n, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	require.NoError(t, job.Check(ctx))
+	assert.Empty(t, n.got, "failed delivery")
+
+	n.fail = false
+	require.NoError(t, job.Check(ctx))
+	require.NoError(t, job.Check(ctx))
+	assert.Len(t, n.got, 1, "retried once, then reported once per month")
+
+	month = august
+	require.NoError(t, job.Check(ctx))
+	assert.Len(t, n.got, 2, "reported again in a new month")
+}
+
+func TestAnomalyJob_CheckError(t *testing.T) {
+	detect := func(_ context.Context, _ time.Time) ([]usecase.SpendAnomaly, error) {
+		return nil, errors.New("db down")
+	}
+	job := NewAnomalyJob(0, detect, &recordingNotifier{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	assert.Error(t, job.Check(context.Background()))
+	assert.Equal(t, defaultAnomalyInterval, job.interval)
+}
@@ -0,0 +1,207 @@
+package alerts
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"subs_tracker/pkg/clock"
+)
+
+const defaultGrowthInterval = 15 * time.Minute
+
+// Reasons of a GrowthAlert
+const (
+	// GrowthRate - the table gained rows faster than GrowthRules.MaxRowsPerHour
+	GrowthRate = "rate"
+	// GrowthSize - the table is larger than GrowthRules.MaxBytes
+	GrowthSize = "size"
+)
+
+// TableSize — rows and disk size of a table at the time it was read
+type TableSize struct {
+	Table string
+	// Rows - estimated number of live rows
+	Rows int64
+	// Bytes - size on disk including indexes and TOAST
+	Bytes int64
+}
+
+// GrowthRules — when a table's growth is alerted on
+type GrowthRules struct {
+	// MaxRowsPerHour - rows a table may gain per hour between two checks, 0 disables the rate alert
+	MaxRowsPerHour int64
+	// MaxBytes - soft cap of a table's size, 0 disables the size alert
+	MaxBytes int64
+}
+
+// GrowthAlert — a table growing past its rules
+type GrowthAlert struct {
+	Table string
+	// Reason - GrowthRate or GrowthSize
+	Reason string
+	Rows   int64
+	Bytes  int64
+	// RowsPerHour - rows gained per hour since the previous check, for GrowthRate
+	RowsPerHour float64
+	// Limit - the exceeded rule: rows per hour or bytes
+	Limit int64
+}
+
+// GrowthNotifier — delivery channel for table growth alerts
+type GrowthNotifier interface {
+	// NotifyGrowth - deliver a single alert
+	NotifyGrowth(ctx context.Context, a GrowthAlert) error
+}
+
+// GrowthRecorder — exports table sizes and alerts, e.g. to Prometheus
+type GrowthRecorder interface {
+	// TableSize - record the latest size of a table
+	TableSize(table string, rows, bytes int64)
+	// GrowthAlerted - count an alert delivered for a table
+	GrowthAlerted(table, reason string)
+}
+
+// NotifyGrowth logs the alert as an operator warning
+func (n *LogNotifier) NotifyGrowth(_ context.Context, a GrowthAlert) error {
+	n.log.Warn("table growth",
+		slog.String("table", a.Table),
+		slog.String("reason", a.Reason),
+		slog.Int64("rows", a.Rows),
+		slog.Int64("bytes", a.Bytes),
+		slog.Float64("rows_per_hour", a.RowsPerHour),
+		slog.Int64("limit", a.Limit),
+	)
+	return nil
+}
+
+// GrowthJob periodically reads table sizes, records them and alerts once per breach of the rules: an alert
+// is repeated only after the table has been back within its rules for a check
+type GrowthJob struct {
+	interval time.Duration
+	read     func(ctx context.Context) ([]TableSize, error)
+	rules    GrowthRules
+	notifier GrowthNotifier
+	recorder GrowthRecorder
+	log      *slog.Logger
+	clock    clock.Clock
+
+	last     map[string]sample
+	breached map[growthKey]bool
+}
+
+// sample - a table size and when it was read
+type sample struct {
+	TableSize
+	at time.Time
+}
+
+type growthKey struct {
+	table, reason string
+}
+
+// NewGrowthJob creates a job calling read every interval and passing breaches of rules to notifier, and
+// applies options
+func NewGrowthJob(interval time.Duration, read func(ctx context.Context) ([]TableSize, error), rules GrowthRules,
+	notifier GrowthNotifier, log *slog.Logger, options ...func(*GrowthJob)) *GrowthJob {
+	if interval <= 0 {
+		interval = defaultGrowthInterval
+	}
+	j := &GrowthJob{
+		interval: interval,
+		read:     read,
+		rules:    rules,
+		notifier: notifier,
+		log:      log,
+		clock:    clock.System,
+		last:     make(map[string]sample),
+		breached: make(map[growthKey]bool),
+	}
+	for _, o := range options {
+		o(j)
+	}
+	return j
+}
+
+// WithGrowthRecorder sets where table sizes and alerts are recorded
+func WithGrowthRecorder(r GrowthRecorder) func(*GrowthJob) {
+	return func(j *GrowthJob) {
+		j.recorder = r
+	}
+}
+
+// WithGrowthClock sets the source of the current time
+func WithGrowthClock(c clock.Clock) func(*GrowthJob) {
+	return func(j *GrowthJob) {
+		if c != nil {
+			j.clock = c
+		}
+	}
+}
+
+// Run checks immediately and then on every tick until ctx is cancelled
+func (j *GrowthJob) Run(ctx context.Context) error {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.Check(ctx); err != nil && ctx.Err() == nil {
+			j.log.Warn("table growth check failed", slog.Any("error", err))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check reads the tables once, records their sizes and alerts on new breaches; the growth rate is taken
+// against the previous check, so the first check only alerts on size
+func (j *GrowthJob) Check(ctx context.Context) error {
+	tables, err := j.read(ctx)
+	if err != nil {
+		return err
+	}
+	now := j.clock.Now()
+	for _, t := range tables {
+		if j.recorder != nil {
+			j.recorder.TableSize(t.Table, t.Rows, t.Bytes)
+		}
+		if prev, ok := j.last[t.Table]; ok && now.After(prev.at) && j.rules.MaxRowsPerHour > 0 {
+			perHour := float64(t.Rows-prev.Rows) / now.Sub(prev.at).Hours()
+			j.evaluate(ctx, perHour > float64(j.rules.MaxRowsPerHour), GrowthAlert{
+				Table: t.Table, Reason: GrowthRate, Rows: t.Rows, Bytes: t.Bytes,
+				RowsPerHour: perHour, Limit: j.rules.MaxRowsPerHour,
+			})
+		}
+		if j.rules.MaxBytes > 0 {
+			j.evaluate(ctx, t.Bytes > j.rules.MaxBytes, GrowthAlert{
+				Table: t.Table, Reason: GrowthSize, Rows: t.Rows, Bytes: t.Bytes, Limit: j.rules.MaxBytes,
+			})
+		}
+		j.last[t.Table] = sample{TableSize: t, at: now}
+	}
+	return nil
+}
+
+// evaluate notifies about a when its table has just breached the rule; a failed notification is retried on
+// the next check
+func (j *GrowthJob) evaluate(ctx context.Context, breached bool, a GrowthAlert) {
+	key := growthKey{table: a.Table, reason: a.Reason}
+	if !breached {
+		delete(j.breached, key)
+		return
+	}
+	if j.breached[key] {
+		return
+	}
+	if err := j.notifier.NotifyGrowth(ctx, a); err != nil {
+		j.log.Warn("table growth notification failed", slog.String("table", a.Table), slog.Any("error", err))
+		return
+	}
+	j.breached[key] = true
+	if j.recorder != nil {
+		j.recorder.GrowthAlerted(a.Table, a.Reason)
+	}
+}
@@ -0,0 +1,85 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/pkg/clock"
+)
+
+type growthRecorder struct {
+	notified []GrowthAlert
+	fail     bool
+	sizes    map[string]int64
+	alerted  []string
+}
+
+func (r *growthRecorder) NotifyGrowth(_ context.Context, a GrowthAlert) error {
+	if r.fail {
+		return errors.New("channel down")
+	}
+	r.notified = append(r.notified, a)
+	return nil
+}
+
+func (r *growthRecorder) TableSize(table string, rows, _ int64) {
+	r.sizes[table] = rows
+}
+
+func (r *growthRecorder) GrowthAlerted(table, reason string) {
+	r.alerted = append(r.alerted, table+"/"+reason)
+}
+
+func TestGrowthJob_Check(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2025, time.July, 1, 10, 0, 0, 0, time.UTC))
+	tables := []TableSize{{Table: "subscriptions", Rows: 1000, Bytes: 1 << 20}, {Table: "user_settings", Rows: 10, Bytes: 8192}}
+	read := func(context.Context) ([]TableSize, error) { return tables, nil }
+	rec := &growthRecorder{sizes: map[string]int64{}, fail: true}
+	job := NewGrowthJob(time.Minute, read, GrowthRules{MaxRowsPerHour: 1000, MaxBytes: 2 << 20}, rec,
+		slog.New(slog.NewTextHandler(io.Discard, nil)), WithGrowthRecorder(rec), WithGrowthClock(clk))
+
+	require.NoError(t, job.Check(ctx))
+	assert.Equal(t, map[string]int64{"subscriptions": 1000, "user_settings": 10}, rec.sizes)
+
+	// a runaway importer: 600 rows in 15 minutes is 2400 an hour
+	clk.Advance(15 * time.Minute)
+	tables[0].Rows = 1600
+	require.NoError(t, job.Check(ctx))
+	assert.Empty(t, rec.notified, "failed delivery")
+
+	rec.fail = false
+	clk.Advance(15 * time.Minute)
+	tables[0].Rows, tables[0].Bytes = 2200, 3<<20
+	require.NoError(t, job.Check(ctx))
+	require.Len(t, rec.notified, 2, "retried")
+	assert.Equal(t, GrowthAlert{Table: "subscriptions", Reason: GrowthRate, Rows: 2200, Bytes: 3 << 20, RowsPerHour: 2400, Limit: 1000}, rec.notified[0])
+	assert.Equal(t, GrowthSize, rec.notified[1].Reason)
+
+	clk.Advance(15 * time.Minute)
+	tables[0].Rows = 2800
+	require.NoError(t, job.Check(ctx))
+	assert.Len(t, rec.notified, 2, "one alert per breach")
+
+	clk.Advance(15 * time.Minute)
+	require.NoError(t, job.Check(ctx))
+	clk.Advance(15 * time.Minute)
+	tables[0].Rows = 3400
+	require.NoError(t, job.Check(ctx))
+	assert.Len(t, rec.notified, 3, "alerted again after the rate went back to normal")
+	assert.Equal(t, []string{"subscriptions/rate", "subscriptions/size", "subscriptions/rate"}, rec.alerted)
+}
+
+func TestGrowthJob_CheckError(t *testing.T) {
+	read := func(context.Context) ([]TableSize, error) { return nil, errors.New("db down") }
+	job := NewGrowthJob(0, read, GrowthRules{}, NewLogNotifier(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.EqualError(t, job.Check(context.Background()), "db down")
+}
@@ -0,0 +1,121 @@
+// Package app runs the long-lived parts of the service under a single lifecycle.
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const defaultShutdownTimeout = 10 * time.Second
+
+// Component — named long-running part of the application; Run must return once ctx is cancelled
+type Component struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Group starts registered components together and stops them with one context and shutdown timeout
+type Group struct {
+	components      []Component
+	shutdownTimeout time.Duration
+	log             *slog.Logger
+}
+
+// New creates an empty group and applies options
+func New(options ...func(*Group)) *Group {
+	g := &Group{
+		shutdownTimeout: defaultShutdownTimeout,
+		log:             slog.New(slog.NewTextHandler(os.Stdout, nil)),
+	}
+	for _, o := range options {
+		o(g)
+	}
+	return g
+}
+
+// WithShutdownTimeout returns an option that bounds how long Run waits for components to stop
+func WithShutdownTimeout(timeout time.Duration) func(*Group) {
+	return func(g *Group) {
+		if timeout > 0 {
+			g.shutdownTimeout = timeout
+		}
+	}
+}
+
+// WithLogger returns an option that sets the group logger
+func WithLogger(log *slog.Logger) func(*Group) {
+	return func(g *Group) {
+		if log != nil {
+			g.log = log
+		}
+	}
+}
+
+// Add registers a component; components must be added before Run
+func (g *Group) Add(name string, run func(ctx context.Context) error) {
+	g.components = append(g.components, Component{Name: name, Run: run})
+}
+
+type result struct {
+	name string
+	err  error
+}
+
+// Run starts all components and blocks until ctx is cancelled or any component exits,
+// then cancels the rest and waits for them up to the shutdown timeout
+func (g *Group) Run(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan result, len(g.components))
+	running := make(map[string]struct{}, len(g.components))
+	for _, c := range g.components {
+		running[c.Name] = struct{}{}
+		go func() {
+			results <- result{name: c.Name, err: c.Run(runCtx)}
+		}()
+		g.log.Debug("component started", slog.String("component", c.Name))
+	}
+
+	var errs []error
+	collect := func(r result) {
+		delete(running, r.name)
+		if r.err != nil && !errors.Is(r.err, context.Canceled) {
+			errs = append(errs, fmt.Errorf("%s: %w", r.name, r.err))
+			g.log.Error("component failed", slog.String("component", r.name), slog.Any("error", r.err))
+			return
+		}
+		g.log.Debug("component stopped", slog.String("component", r.name))
+	}
+
+	if len(running) > 0 {
+		select {
+		case <-ctx.Done():
+		case r := <-results:
+			collect(r)
+		}
+	}
+	cancel()
+
+	timer := time.NewTimer(g.shutdownTimeout)
+	defer timer.Stop()
+	for len(running) > 0 {
+		select {
+		case r := <-results:
+			collect(r)
+		case <-timer.C:
+			names := slices.Sorted(maps.Keys(running))
+			errs = append(errs, fmt.Errorf("shutdown timed out after %s, still running: %s",
+				g.shutdownTimeout, strings.Join(names, ", ")))
+			return errors.Join(errs...)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func quietGroup(options ...func(*Group)) *Group {
+	return New(append([]func(*Group){WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))}, options...)...)
+}
+
+func blockUntilDone(stopped chan<- string, name string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		<-ctx.Done()
+		stopped <- name
+		return nil
+	}
+}
+
+func TestGroup_StopsAllOnCancel(t *testing.T) {
+	stopped := make(chan string, 2)
+	g := quietGroup()
+	g.Add("a", blockUntilDone(stopped, "a"))
+	g.Add("b", blockUntilDone(stopped, "b"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- g.Run(ctx) }()
+	cancel()
+
+	require.NoError(t, <-done)
+	assert.ElementsMatch(t, []string{"a", "b"}, []string{<-stopped, <-stopped})
+}
+
+func TestGroup_FailureStopsOthers(t *testing.T) {
+	stopped := make(chan string, 1)
+	boom := errors.New("boom")
+	g := quietGroup()
+	g.Add("worker", blockUntilDone(stopped, "worker"))
+	g.Add("http", func(context.Context) error { return boom })
+
+	err := g.Run(context.Background())
+	assert.ErrorIs(t, err, boom)
+	assert.Equal(t, "worker", <-stopped)
+}
+
+func TestGroup_ShutdownTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	g := quietGroup(WithShutdownTimeout(20 * time.Millisecond))
+	g.Add("stuck", func(context.Context) error {
+		<-release
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := g.Run(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "still running: stuck")
+}
@@ -0,0 +1,370 @@
+// Package archive moves long-ended subscriptions out of the database into Parquet files in object storage
+// and reads them back when a list explicitly asks for archived data
+package archive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/s3"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/clock"
+	"subs_tracker/pkg/dates"
+)
+
+// Source — where archived subscriptions come from and are removed once stored
+type Source interface {
+	// EndedBefore - get up to limit subscriptions that ended before the month
+	EndedBefore(ctx context.Context, before time.Time, limit int) ([]*entity.Subscription, error)
+	// PurgeSubs - delete the archived subscriptions
+	PurgeSubs(ctx context.Context, ids []int64) (int64, error)
+}
+
+// Store — object storage holding the archive files
+type Store interface {
+	Put(ctx context.Context, key string, body []byte, contentType string) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	List(ctx context.Context, prefix string) ([]s3.Object, error)
+}
+
+// Users — what the archive files hold in place of user IDs, e.g. the pseudonyms of pseudonymized.Repository
+type Users interface {
+	// Pseudonym - the ID stored for the user
+	Pseudonym(user entity.UserID) entity.UserID
+	// RevealSubs - replace the stored IDs of subs with the real user IDs in place
+	RevealSubs(ctx context.Context, subs ...*entity.Subscription) error
+}
+
+// record — a subscription as stored in a Parquet file
+type record struct {
+	ID          int64     `parquet:"id"`
+	UserID      string    `parquet:"user_id"`
+	ServiceName string    `parquet:"service_name,dict"`
+	Cost        int64     `parquet:"cost"`
+	StartDate   int32     `parquet:"start_date,date"`
+	EndDate     int32     `parquet:"end_date,date"`
+	CreatedAt   time.Time `parquet:"created_at,timestamp(millisecond)"`
+	UpdatedAt   time.Time `parquet:"updated_at,timestamp(millisecond)"`
+}
+
+const secondsPerDay = 24 * 60 * 60
+
+func toDays(t time.Time) int32 {
+	return int32(t.Unix() / secondsPerDay)
+}
+
+func fromDays(d int32) time.Time {
+	return time.Unix(int64(d)*secondsPerDay, 0).UTC()
+}
+
+// Encode writes ended subscriptions as a zstd-compressed Parquet file
+func Encode(subs []*entity.Subscription) ([]byte, error) {
+	rows := make([]record, 0, len(subs))
+	for _, s := range subs {
+		if s.DateTo == nil {
+			return nil, fmt.Errorf("encode archive: subscription %d has no end_date", s.ID)
+		}
+		rows = append(rows, record{
+			ID:          s.ID,
+			UserID:      s.UserID.String(),
+			ServiceName: s.ServiceName,
+			Cost:        s.Cost,
+			StartDate:   toDays(s.DateFrom),
+			EndDate:     toDays(*s.DateTo),
+			CreatedAt:   s.CreatedAt.UTC(),
+			UpdatedAt:   s.UpdatedAt.UTC(),
+		})
+	}
+	var buf bytes.Buffer
+	if err := parquet.Write(&buf, rows, parquet.Compression(&parquet.Zstd)); err != nil {
+		return nil, fmt.Errorf("encode archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode reads subscriptions back from a file written by Encode
+func Decode(data []byte) ([]*entity.Subscription, error) {
+	rows, err := parquet.Read[record](bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("decode archive: %w", err)
+	}
+	out := make([]*entity.Subscription, 0, len(rows))
+	for _, r := range rows {
+		uid, err := entity.ParseUserID(r.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("decode archive: subscription %d: %w", r.ID, err)
+		}
+		end := fromDays(r.EndDate)
+		out = append(out, &entity.Subscription{
+			ID:          r.ID,
+			UserID:      uid,
+			ServiceName: r.ServiceName,
+			Cost:        r.Cost,
+			DateFrom:    fromDays(r.StartDate),
+			DateTo:      &end,
+			CreatedAt:   r.CreatedAt,
+			UpdatedAt:   r.UpdatedAt,
+		})
+	}
+	return out, nil
+}
+
+// Archiver periodically moves subscriptions that ended long ago to the store
+type Archiver struct {
+	src      Source
+	store    Store
+	log      *slog.Logger
+	years    int
+	interval time.Duration
+	batch    int
+	prefix   string
+	clock    clock.Clock
+	users    Users
+}
+
+// NewArchiver creates an archiver moving subscriptions from src to store and applies options
+func NewArchiver(src Source, store Store, log *slog.Logger, options ...func(*Archiver)) *Archiver {
+	a := &Archiver{
+		src:      src,
+		store:    store,
+		log:      log,
+		years:    3,
+		interval: 24 * time.Hour,
+		batch:    10_000,
+		prefix:   "archive/subscriptions/",
+		clock:    clock.System,
+	}
+	for _, o := range options {
+		o(a)
+	}
+	return a
+}
+
+// WithAge sets how many years ago a subscription must have ended to be archived
+func WithAge(years int) func(*Archiver) {
+	return func(a *Archiver) {
+		if years > 0 {
+			a.years = years
+		}
+	}
+}
+
+// WithInterval sets how often the archiver runs
+func WithInterval(d time.Duration) func(*Archiver) {
+	return func(a *Archiver) {
+		if d > 0 {
+			a.interval = d
+		}
+	}
+}
+
+// WithBatchSize sets how many subscriptions go into one file
+func WithBatchSize(n int) func(*Archiver) {
+	return func(a *Archiver) {
+		if n > 0 {
+			a.batch = n
+		}
+	}
+}
+
+// WithClock sets the source of the current time
+func WithClock(c clock.Clock) func(*Archiver) {
+	return func(a *Archiver) {
+		if c != nil {
+			a.clock = c
+		}
+	}
+}
+
+// WithPrefix sets the key prefix of the archive files
+func WithPrefix(prefix string) func(*Archiver) {
+	return func(a *Archiver) {
+		if prefix != "" {
+			a.prefix = strings.TrimSuffix(prefix, "/") + "/"
+		}
+	}
+}
+
+// WithUsers makes the archive files hold the IDs of users instead of the real ones; files written before keep
+// the real IDs, so it is meant to be set on an empty archive
+func WithUsers(u Users) func(*Archiver) {
+	return func(a *Archiver) {
+		a.users = u
+	}
+}
+
+// stored returns the ID the archive files hold for the user
+func (a *Archiver) stored(user entity.UserID) entity.UserID {
+	if a.users == nil || user.IsZero() {
+		return user
+	}
+	return a.users.Pseudonym(user)
+}
+
+// Run archives once per interval until ctx is done
+func (a *Archiver) Run(ctx context.Context) error {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := a.ArchiveOnce(ctx)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			a.log.Warn("archiving failed", slog.Int("archived", n), slog.Any("error", err))
+		case err == nil && n > 0:
+			a.log.Info("subscriptions archived", slog.Int("archived", n))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// ArchiveOnce moves every subscription that ended before the cutoff and returns how many were moved.
+// A batch is deleted only after its file is stored; if deleting fails, the next run stores the same
+// batch again under the same key.
+func (a *Archiver) ArchiveOnce(ctx context.Context) (int, error) {
+	cutoff := dates.MonthStart(a.clock.Now()).AddDate(-a.years, 0, 0)
+	total := 0
+	for {
+		subs, err := a.src.EndedBefore(ctx, cutoff, a.batch)
+		if err != nil || len(subs) == 0 {
+			return total, err
+		}
+		stored := make([]*entity.Subscription, 0, len(subs))
+		for _, s := range subs {
+			c := *s
+			c.UserID = a.stored(s.UserID)
+			stored = append(stored, &c)
+		}
+		data, err := Encode(stored)
+		if err != nil {
+			return total, err
+		}
+		key := fmt.Sprintf("%s%d-%d.parquet", a.prefix, subs[0].ID, subs[len(subs)-1].ID)
+		if err := a.store.Put(ctx, key, data, "application/vnd.apache.parquet"); err != nil {
+			return total, fmt.Errorf("store archive: %w", err)
+		}
+		ids := make([]int64, 0, len(subs))
+		for _, s := range subs {
+			ids = append(ids, s.ID)
+		}
+		n, err := a.src.PurgeSubs(ctx, ids)
+		total += int(n)
+		if err != nil {
+			return total, fmt.Errorf("purge archived: %w", err)
+		}
+		if len(subs) < a.batch {
+			return total, nil
+		}
+	}
+}
+
+// Archived reads all archive files and returns the subscriptions matching f in list order,
+// ignoring its Limit and Offset
+func (a *Archiver) Archived(ctx context.Context, f usecase.SubFilter) ([]*entity.Subscription, error) {
+	objects, err := a.store.List(ctx, a.prefix)
+	if err != nil {
+		return nil, fmt.Errorf("list archive: %w", err)
+	}
+	f.UserID = a.stored(f.UserID)
+	seen := map[int64]bool{}
+	var out []*entity.Subscription
+	for _, o := range objects {
+		if !strings.HasSuffix(o.Key, ".parquet") {
+			continue
+		}
+		data, err := a.store.Get(ctx, o.Key)
+		if err != nil {
+			return nil, fmt.Errorf("read archive: %w", err)
+		}
+		subs, err := Decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", o.Key, err)
+		}
+		for _, s := range subs {
+			// a batch stored twice after a failed purge shows up in two files
+			if !seen[s.ID] && matches(s, f) {
+				seen[s.ID] = true
+				out = append(out, s)
+			}
+		}
+	}
+	slices.SortFunc(out, usecase.CompareListOrder)
+	if a.users != nil {
+		if err := a.users.RevealSubs(ctx, out...); err != nil {
+			return nil, fmt.Errorf("reveal archived: %w", err)
+		}
+	}
+	return out, nil
+}
+
+// Forget rewrites every archive file holding subscriptions of the user without them, so a deleted user does not
+// come back in lists including archived data
+func (a *Archiver) Forget(ctx context.Context, user entity.UserID) error {
+	user = a.stored(user)
+	objects, err := a.store.List(ctx, a.prefix)
+	if err != nil {
+		return fmt.Errorf("forget archived: list archive: %w", err)
+	}
+	for _, o := range objects {
+		if !strings.HasSuffix(o.Key, ".parquet") {
+			continue
+		}
+		data, err := a.store.Get(ctx, o.Key)
+		if err != nil {
+			return fmt.Errorf("forget archived: read archive: %w", err)
+		}
+		subs, err := Decode(data)
+		if err != nil {
+			return fmt.Errorf("forget archived: %s: %w", o.Key, err)
+		}
+		kept := slices.DeleteFunc(subs, func(s *entity.Subscription) bool { return s.UserID == user })
+		if len(kept) == len(subs) {
+			continue
+		}
+		if data, err = Encode(kept); err != nil {
+			return fmt.Errorf("forget archived: %s: %w", o.Key, err)
+		}
+		if err := a.store.Put(ctx, o.Key, data, "application/vnd.apache.parquet"); err != nil {
+			return fmt.Errorf("forget archived: store archive: %w", err)
+		}
+	}
+	return nil
+}
+
+// matches applies the filter the way the repository list query does
+func matches(s *entity.Subscription, f usecase.SubFilter) bool {
+	switch {
+	case !f.UserID.IsZero() && s.UserID != f.UserID:
+		return false
+	case f.ServiceName != nil && s.ServiceName != *f.ServiceName:
+		return false
+	case f.UpdatedSince != nil && !s.UpdatedAt.After(*f.UpdatedSince):
+		return false
+	case !f.Where.Match(s):
+		return false
+	}
+	if p := f.Period; p != nil && !p.From.IsZero() {
+		if s.DateTo != nil && s.DateTo.Before(p.From) {
+			return false
+		}
+		if !p.To.IsZero() && s.DateFrom.After(p.To) {
+			return false
+		}
+	}
+	if c := f.After; c != nil {
+		return usecase.CompareListOrder(s, &entity.Subscription{DateFrom: c.StartDate, ServiceName: c.ServiceName, ID: c.ID}) > 0
+	}
+	return true
+}
@@ -0,0 +1,286 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/s3"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/clock"
+)
+
+// memSource - in-memory table of subscriptions
+type memSource struct {
+	subs     []*entity.Subscription
+	purgeErr error
+}
+
+func (m *memSource) EndedBefore(_ context.Context, before time.Time, limit int) ([]*entity.Subscription, error) {
+	var out []*entity.Subscription
+	for _, s := range m.subs {
+		if s.DateTo != nil && s.DateTo.Before(before) && len(out) < limit {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (m *memSource) PurgeSubs(_ context.Context, ids []int64) (int64, error) {
+	if m.purgeErr != nil {
+		return 0, m.purgeErr
+	}
+	before := len(m.subs)
+	m.subs = slices.DeleteFunc(m.subs, func(s *entity.Subscription) bool { return slices.Contains(ids, s.ID) })
+	return int64(before - len(m.subs)), nil
+}
+
+// memStore - in-memory bucket
+type memStore map[string][]byte
+
+func (m memStore) Put(_ context.Context, key string, body []byte, _ string) error {
+	m[key] = body
+	return nil
+}
+
+func (m memStore) Get(_ context.Context, key string) ([]byte, error) {
+	body, ok := m[key]
+	if !ok {
+		return nil, s3.ErrNotFound
+	}
+	return body, nil
+}
+
+func (m memStore) List(_ context.Context, prefix string) ([]s3.Object, error) {
+	var out []s3.Object
+	for k, v := range m {
+		if strings.HasPrefix(k, prefix) {
+			out = append(out, s3.Object{Key: k, Size: int64(len(v))})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out, nil
+}
+
+var (
+	alice = entity.UserID(uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba"))
+	bob   = entity.UserID(uuid.MustParse("0d5b8c8e-8f43-4b8e-9d55-0c1d1c0f5a11"))
+)
+
+func month(y int, m time.Month) time.Time {
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func ended(id int64, user entity.UserID, service string, from, to time.Time) *entity.Subscription {
+	stamp := time.Date(2021, 3, 4, 5, 6, 7, 8_000_000, time.UTC)
+	return &entity.Subscription{
+		ID: id, UserID: user, ServiceName: service, Cost: id * 100,
+		DateFrom: from, DateTo: &to, CreatedAt: stamp, UpdatedAt: stamp,
+	}
+}
+
+func newTestArchiver(src Source, store Store, options ...func(*Archiver)) *Archiver {
+	a := NewArchiver(src, store, slog.New(slog.NewTextHandler(io.Discard, nil)), options...)
+	a.clock = clock.NewFake(time.Date(2025, 7, 15, 12, 0, 0, 0, time.UTC))
+	return a
+}
+
+func TestEncodeDecode(t *testing.T) {
+	subs := []*entity.Subscription{
+		ended(1, alice, "Netflix", month(2019, 1), month(2020, 12)),
+		ended(2, bob, "Okko", month(1990, 1), month(1995, 6)),
+	}
+	data, err := Encode(subs)
+	require.NoError(t, err)
+
+	got, err := Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, subs, got)
+
+	_, err = Encode([]*entity.Subscription{{ID: 3, UserID: alice}})
+	assert.Error(t, err, "active subscriptions are never archived")
+	_, err = Decode([]byte("not parquet"))
+	assert.Error(t, err)
+}
+
+func TestArchiver_ArchiveOnce(t *testing.T) {
+	ctx := context.Background()
+	src := &memSource{subs: []*entity.Subscription{
+		ended(1, alice, "Netflix", month(2018, 1), month(2020, 12)),
+		ended(2, alice, "Okko", month(2019, 1), month(2022, 6)),
+		ended(3, bob, "Netflix", month(2020, 1), month(2021, 3)),
+		ended(4, bob, "Spotify", month(2021, 1), month(2022, 7)),
+		{ID: 5, UserID: bob, ServiceName: "Ivi", DateFrom: month(2015, 1)},
+	}}
+	store := memStore{}
+	a := newTestArchiver(src, store, WithBatchSize(2), WithPrefix("cold"))
+
+	n, err := a.ArchiveOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n, "ended before 2022-07 is older than three years")
+	assert.ElementsMatch(t, []string{"cold/1-2.parquet", "cold/3-3.parquet"}, keys(store))
+	var left []int64
+	for _, s := range src.subs {
+		left = append(left, s.ID)
+	}
+	assert.Equal(t, []int64{4, 5}, left)
+
+	n, err = a.ArchiveOnce(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestArchiver_PurgeFailure(t *testing.T) {
+	ctx := context.Background()
+	src := &memSource{
+		subs:     []*entity.Subscription{ended(1, alice, "Netflix", month(2018, 1), month(2020, 12))},
+		purgeErr: errors.New("db down"),
+	}
+	store := memStore{}
+	a := newTestArchiver(src, store)
+
+	_, err := a.ArchiveOnce(ctx)
+	require.Error(t, err)
+	require.Len(t, src.subs, 1, "kept until deleted")
+
+	src.purgeErr = nil
+	n, err := a.ArchiveOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"archive/subscriptions/1-1.parquet"}, keys(store), "the retry overwrites the same file")
+}
+
+func TestArchiver_Archived(t *testing.T) {
+	ctx := context.Background()
+	store := memStore{}
+	put := func(key string, subs ...*entity.Subscription) {
+		data, err := Encode(subs)
+		require.NoError(t, err)
+		store[key] = data
+	}
+	put("archive/subscriptions/1-3.parquet",
+		ended(1, alice, "Okko", month(2018, 1), month(2019, 12)),
+		ended(2, alice, "Netflix", month(2018, 1), month(2020, 12)),
+		ended(3, bob, "Netflix", month(2017, 5), month(2018, 3)),
+	)
+	put("archive/subscriptions/3-4.parquet",
+		ended(3, bob, "Netflix", month(2017, 5), month(2018, 3)),
+		ended(4, alice, "Netflix", month(2016, 1), month(2016, 12)),
+	)
+	store["archive/subscriptions/readme.txt"] = []byte("not an archive")
+	a := newTestArchiver(&memSource{}, store)
+
+	ids := func(f usecase.SubFilter) []int64 {
+		t.Helper()
+		subs, err := a.Archived(ctx, f)
+		require.NoError(t, err)
+		out := []int64{}
+		for _, s := range subs {
+			out = append(out, s.ID)
+		}
+		return out
+	}
+	netflix := "Netflix"
+	since := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, []int64{4, 3, 2, 1}, ids(usecase.SubFilter{}), "list order without duplicates")
+	assert.Equal(t, []int64{4, 2, 1}, ids(usecase.SubFilter{UserID: alice}))
+	assert.Equal(t, []int64{4, 3, 2}, ids(usecase.SubFilter{ServiceName: &netflix}))
+	assert.Equal(t, []int64{2, 1}, ids(usecase.SubFilter{Period: &usecase.Period{From: month(2019, 6), To: month(2025, 1)}}))
+	assert.Equal(t, []int64{4, 3}, ids(usecase.SubFilter{Period: &usecase.Period{From: month(2016, 6), To: month(2017, 12)}}))
+	assert.Equal(t, []int64{}, ids(usecase.SubFilter{UpdatedSince: &since}))
+	assert.Equal(t, []int64{2, 1}, ids(usecase.SubFilter{After: &usecase.ListCursor{StartDate: month(2017, 5), ServiceName: "Netflix", ID: 3}}))
+}
+
+func TestArchiver_Forget(t *testing.T) {
+	ctx := context.Background()
+	store := memStore{}
+	put := func(key string, subs ...*entity.Subscription) {
+		data, err := Encode(subs)
+		require.NoError(t, err)
+		store[key] = data
+	}
+	put("archive/subscriptions/1-2.parquet",
+		ended(1, alice, "Okko", month(2018, 1), month(2019, 12)),
+		ended(2, bob, "Netflix", month(2017, 5), month(2018, 3)),
+	)
+	put("archive/subscriptions/3-3.parquet", ended(3, alice, "Netflix", month(2016, 1), month(2016, 12)))
+	put("archive/subscriptions/4-4.parquet", ended(4, bob, "Ivi", month(2016, 1), month(2016, 12)))
+	untouched := store["archive/subscriptions/4-4.parquet"]
+	a := newTestArchiver(&memSource{}, store)
+
+	require.NoError(t, a.Forget(ctx, alice))
+	got, err := a.Archived(ctx, usecase.SubFilter{UserID: alice})
+	require.NoError(t, err)
+	assert.Empty(t, got, "include_archived no longer lists the deleted user")
+	got, err = a.Archived(ctx, usecase.SubFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.Subscription{
+		ended(4, bob, "Ivi", month(2016, 1), month(2016, 12)),
+		ended(2, bob, "Netflix", month(2017, 5), month(2018, 3)),
+	}, got)
+	assert.Equal(t, untouched, store["archive/subscriptions/4-4.parquet"], "files without the user are not rewritten")
+}
+
+// masked - Users storing every user under a fixed pseudonym
+type masked map[entity.UserID]entity.UserID
+
+func (m masked) Pseudonym(user entity.UserID) entity.UserID {
+	return m[user]
+}
+
+func (m masked) RevealSubs(_ context.Context, subs ...*entity.Subscription) error {
+	for _, s := range subs {
+		for real, p := range m {
+			if s.UserID == p {
+				s.UserID = real
+			}
+		}
+	}
+	return nil
+}
+
+func TestArchiver_Users(t *testing.T) {
+	ctx := context.Background()
+	pseudonym := entity.UserID(uuid.MustParse("9b2d7c1e-3f4a-8b5c-9d6e-7f8091a2b3c4"))
+	src := &memSource{subs: []*entity.Subscription{ended(1, alice, "Netflix", month(2018, 1), month(2020, 12))}}
+	store := memStore{}
+	a := newTestArchiver(src, store, WithUsers(masked{alice: pseudonym}))
+
+	_, err := a.ArchiveOnce(ctx)
+	require.NoError(t, err)
+	stored, err := Decode(store["archive/subscriptions/1-1.parquet"])
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	assert.Equal(t, pseudonym, stored[0].UserID, "the file holds no real user ID")
+
+	got, err := a.Archived(ctx, usecase.SubFilter{UserID: alice})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, alice, got[0].UserID)
+
+	require.NoError(t, a.Forget(ctx, alice))
+	got, err = a.Archived(ctx, usecase.SubFilter{UserID: alice})
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func keys(m memStore) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,160 @@
+// Package audit keeps the request audit: for every write request the API answers, a SHA-256 of its raw body and
+// where it came from, so that for a disputed change one can tell who sent what. Bodies themselves are not kept,
+// only their hash, which a client's copy of the body can be checked against. Recording is best effort: entries
+// are queued and stored in batches by Log.Run, and when the queue is full they are dropped with a warning rather
+// than slowing requests down. Purger removes the entries older than the retention
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultQueueSize     = 4096
+	defaultBatchSize     = 200
+	defaultFlushInterval = time.Second
+	// flushTimeout bounds storing what is queued on shutdown
+	flushTimeout = 5 * time.Second
+)
+
+// MaxHashedBody - bytes of a request body the hash and size cover, the rest is not read
+const MaxHashedBody = 8 << 20
+
+// Entry — one write request
+type Entry struct {
+	// At - when the request was received
+	At time.Time
+	// Method, Route, Path - HTTP method, matched route template and the path as requested
+	Method string
+	Route  string
+	Path   string
+	// Status - status of the response
+	Status int
+	// BodySHA256 - hex SHA-256 of the raw request body, of its first MaxHashedBody bytes when it is longer
+	BodySHA256 string
+	// BodySize - bytes of the body hashed
+	BodySize int64
+	// Client - "token:<fingerprint>" for a known bearer token, else "ip:<address>"
+	Client string
+	// RemoteIP - address of the client as the trusted proxies report it
+	RemoteIP string
+	// PeerIP - address the connection came from, the proxy in front when there is one
+	PeerIP string
+	// UserAgent - User-Agent header, cut to a bounded length
+	UserAgent string
+	// RequestID - X-Request-ID of the response, if any
+	RequestID string
+	// TenantID - tenant the gateway in front named in the baggage, if any
+	TenantID string
+}
+
+// Store — where entries are kept
+type Store interface {
+	// SaveEntries - store the entries
+	SaveEntries(ctx context.Context, entries []Entry) error
+	// PurgeBefore - delete up to limit entries received before the instant, oldest first; return how many
+	PurgeBefore(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+// Log queues entries and stores them in batches
+type Log struct {
+	store    Store
+	log      *slog.Logger
+	queue    chan Entry
+	batch    int
+	interval time.Duration
+	dropped  atomic.Int64
+}
+
+// NewLog creates a log storing to store and applies options
+func NewLog(store Store, log *slog.Logger, options ...func(*Log)) *Log {
+	l := &Log{
+		store:    store,
+		log:      log,
+		batch:    defaultBatchSize,
+		interval: defaultFlushInterval,
+	}
+	for _, o := range options {
+		o(l)
+	}
+	if l.queue == nil {
+		l.queue = make(chan Entry, defaultQueueSize)
+	}
+	return l
+}
+
+// WithQueueSize returns an option that sets how many entries may wait to be stored
+func WithQueueSize(n int) func(*Log) {
+	return func(l *Log) {
+		if n > 0 {
+			l.queue = make(chan Entry, n)
+		}
+	}
+}
+
+// WithFlushInterval returns an option that sets how long an entry may wait for its batch to fill
+func WithFlushInterval(d time.Duration) func(*Log) {
+	return func(l *Log) {
+		if d > 0 {
+			l.interval = d
+		}
+	}
+}
+
+// Record queues e without blocking; it is dropped when the queue is full
+func (l *Log) Record(e Entry) {
+	select {
+	case l.queue <- e:
+	default:
+		l.dropped.Add(1)
+	}
+}
+
+// Run stores queued entries until ctx is cancelled, then stores what is left
+func (l *Log) Run(ctx context.Context) error {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	pending := make([]Entry, 0, l.batch)
+	for {
+		select {
+		case <-ctx.Done():
+			for drained := false; !drained; {
+				select {
+				case e := <-l.queue:
+					pending = append(pending, e)
+				default:
+					drained = true
+				}
+			}
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), flushTimeout)
+			defer cancel()
+			l.flush(flushCtx, pending)
+			return nil
+		case e := <-l.queue:
+			if pending = append(pending, e); len(pending) >= l.batch {
+				l.flush(ctx, pending)
+				pending = pending[:0]
+			}
+		case <-ticker.C:
+			l.flush(ctx, pending)
+			pending = pending[:0]
+		}
+	}
+}
+
+// flush stores entries, logging what was lost since the last flush
+func (l *Log) flush(ctx context.Context, entries []Entry) {
+	if n := l.dropped.Swap(0); n > 0 {
+		l.log.Warn("request audit queue is full, entries dropped", slog.Int64("dropped", n))
+	}
+	if len(entries) == 0 {
+		return
+	}
+	if err := l.store.SaveEntries(ctx, entries); err != nil {
+		l.log.Warn("request audit entries lost", slog.Int("entries", len(entries)), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,55 @@
+package audit_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/internal/audit"
+	"subs_tracker/internal/storetest"
+	"subs_tracker/pkg/clock"
+)
+
+func TestLog_Run(t *testing.T) {
+	store := storetest.NewAuditStore()
+	l := audit.NewLog(store, slog.New(slog.NewTextHandler(io.Discard, nil)), audit.WithQueueSize(2), audit.WithFlushInterval(time.Hour))
+
+	l.Record(audit.Entry{Method: "POST", Path: "/api/v1/subscriptions"})
+	l.Record(audit.Entry{Method: "DELETE", Path: "/api/v1/subscriptions/1"})
+	l.Record(audit.Entry{Method: "PUT", Path: "/api/v1/subscriptions/1"})
+	assert.Equal(t, int64(1), l.Dropped(), "the queue holds two")
+
+	// what is queued is stored on shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, l.Run(ctx))
+	entries := store.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "POST", entries[0].Method)
+	assert.Equal(t, "DELETE", entries[1].Method)
+	assert.Zero(t, l.Dropped(), "drops are reported once")
+}
+
+func TestPurger_PurgeOnce(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, time.July, 31, 12, 0, 0, 0, time.UTC)
+	store := storetest.NewAuditStore()
+	require.NoError(t, store.SaveEntries(ctx, []audit.Entry{
+		{At: now.AddDate(0, 0, -40), Path: "/a"},
+		{At: now.AddDate(0, 0, -35), Path: "/b"},
+		{At: now.AddDate(0, 0, -31), Path: "/c"},
+		{At: now.AddDate(0, 0, -1), Path: "/d"},
+	}))
+
+	p := audit.NewPurger(store, 30*24*time.Hour, slog.New(slog.NewTextHandler(io.Discard, nil)),
+		audit.WithClock(clock.NewFake(now)), audit.WithPurgeBatch(2))
+	n, err := p.PurgeOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n, "purged in batches until one is short")
+	assert.Equal(t, []audit.Entry{{At: now.AddDate(0, 0, -1), Path: "/d"}}, store.Entries())
+}
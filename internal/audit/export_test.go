@@ -0,0 +1,6 @@
+package audit
+
+// Dropped reports the entries dropped since the last flush, for the external tests
+func (l *Log) Dropped() int64 {
+	return l.dropped.Load()
+}
@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"subs_tracker/pkg/clock"
+)
+
+const (
+	defaultPurgeInterval = time.Hour
+	defaultPurgeBatch    = 10_000
+)
+
+// Purger periodically removes entries older than the retention
+type Purger struct {
+	store     Store
+	log       *slog.Logger
+	retention time.Duration
+	interval  time.Duration
+	batch     int
+	clock     clock.Clock
+}
+
+// NewPurger creates a purger keeping the entries of the last retention and applies options
+func NewPurger(store Store, retention time.Duration, log *slog.Logger, options ...func(*Purger)) *Purger {
+	p := &Purger{
+		store:     store,
+		log:       log,
+		retention: retention,
+		interval:  defaultPurgeInterval,
+		batch:     defaultPurgeBatch,
+		clock:     clock.System,
+	}
+	for _, o := range options {
+		o(p)
+	}
+	return p
+}
+
+// WithPurgeInterval returns an option that sets how often old entries are removed
+func WithPurgeInterval(d time.Duration) func(*Purger) {
+	return func(p *Purger) {
+		if d > 0 {
+			p.interval = d
+		}
+	}
+}
+
+// WithPurgeBatch returns an option that sets how many entries one delete removes at most
+func WithPurgeBatch(n int) func(*Purger) {
+	return func(p *Purger) {
+		if n > 0 {
+			p.batch = n
+		}
+	}
+}
+
+// WithClock returns an option that sets the source of the current time
+func WithClock(c clock.Clock) func(*Purger) {
+	return func(p *Purger) {
+		if c != nil {
+			p.clock = c
+		}
+	}
+}
+
+// Run purges once per interval until ctx is done
+func (p *Purger) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := p.PurgeOnce(ctx)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			p.log.Warn("request audit purge failed", slog.Int64("purged", n), slog.Any("error", err))
+		case err == nil && n > 0:
+			p.log.Info("request audit purged", slog.Int64("purged", n))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeOnce removes every entry received before now minus the retention, in batches, and returns how many
+func (p *Purger) PurgeOnce(ctx context.Context) (int64, error) {
+	cutoff := p.clock.Now().Add(-p.retention)
+	var total int64
+	for {
+		n, err := p.store.PurgeBefore(ctx, cutoff, p.batch)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("purge request audit: %w", err)
+		}
+		if n < int64(p.batch) {
+			return total, nil
+		}
+	}
+}
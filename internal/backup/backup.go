@@ -0,0 +1,244 @@
+// Package backup takes scheduled logical backups of the database and keeps the newest ones in a store
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"subs_tracker/internal/s3"
+	"subs_tracker/pkg/clock"
+)
+
+const keySuffix = ".sql.gz"
+
+// Exporter — writes a restorable dump of the database
+type Exporter interface {
+	Export(ctx context.Context, w io.Writer) error
+}
+
+// Store — where backups are kept, an S3 bucket or a local directory
+type Store interface {
+	Put(ctx context.Context, key string, body []byte, contentType string) error
+	List(ctx context.Context, prefix string) ([]s3.Object, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Recorder — receives the outcome of every backup, e.g. for metrics
+type Recorder interface {
+	BackupDone(took time.Duration, size int64, err error)
+}
+
+// Status — outcome of the latest backups, as reported by /readyz
+type Status struct {
+	LastRun     time.Time `json:"last_run,omitzero"`
+	LastSuccess time.Time `json:"last_success,omitzero"`
+	LastKey     string    `json:"last_key,omitempty"`
+	LastSize    int64     `json:"last_size_bytes,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+	NextRun     time.Time `json:"next_run,omitzero"`
+}
+
+// Job takes a backup every day at a fixed UTC time and removes all but the newest ones
+type Job struct {
+	exporter  Exporter
+	store     Store
+	recorder  Recorder
+	log       *slog.Logger
+	at        time.Duration
+	retention int
+	prefix    string
+	clock     clock.Clock
+
+	mu     sync.Mutex
+	status Status
+}
+
+// NewJob creates a backup job writing dumps of exporter to store and applies options
+func NewJob(exporter Exporter, store Store, log *slog.Logger, options ...func(*Job)) *Job {
+	j := &Job{
+		exporter:  exporter,
+		store:     store,
+		log:       log,
+		at:        2 * time.Hour,
+		retention: 7,
+		prefix:    "backups/",
+		clock:     clock.System,
+	}
+	for _, o := range options {
+		o(j)
+	}
+	return j
+}
+
+// WithTime sets the UTC time of day as an offset from midnight, e.g. 2h30m
+func WithTime(at time.Duration) func(*Job) {
+	return func(j *Job) {
+		if at >= 0 && at < 24*time.Hour {
+			j.at = at
+		}
+	}
+}
+
+// WithRetention sets how many backups are kept
+func WithRetention(n int) func(*Job) {
+	return func(j *Job) {
+		if n > 0 {
+			j.retention = n
+		}
+	}
+}
+
+// WithPrefix sets the key prefix of the backup files
+func WithPrefix(prefix string) func(*Job) {
+	return func(j *Job) {
+		if prefix != "" {
+			j.prefix = strings.TrimSuffix(prefix, "/") + "/"
+		}
+	}
+}
+
+// WithClock sets the source of the current time
+func WithClock(c clock.Clock) func(*Job) {
+	return func(j *Job) {
+		if c != nil {
+			j.clock = c
+		}
+	}
+}
+
+// WithRecorder sets where backup outcomes are reported
+func WithRecorder(r Recorder) func(*Job) {
+	return func(j *Job) {
+		if r != nil {
+			j.recorder = r
+		}
+	}
+}
+
+// Run takes a backup every day at the configured time until ctx is done
+func (j *Job) Run(ctx context.Context) error {
+	for {
+		next := j.nextRun(j.clock.Now())
+		j.mu.Lock()
+		j.status.NextRun = next
+		j.mu.Unlock()
+
+		timer := time.NewTimer(next.Sub(j.clock.Now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+		if _, err := j.BackupOnce(ctx); err != nil && ctx.Err() == nil {
+			j.log.Warn("backup failed", slog.Any("error", err))
+		}
+	}
+}
+
+// nextRun returns the first configured time of day after now
+func (j *Job) nextRun(now time.Time) time.Time {
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).Add(j.at)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// BackupOnce takes a backup, stores it and rotates old ones; it returns the key of the new backup
+func (j *Job) BackupOnce(ctx context.Context) (string, error) {
+	started := j.clock.Now()
+	key, size, err := j.backup(ctx, started)
+
+	j.mu.Lock()
+	j.status.LastRun = started
+	if err != nil {
+		j.status.LastError = err.Error()
+	} else {
+		j.status.LastSuccess, j.status.LastKey, j.status.LastSize, j.status.LastError = started, key, size, ""
+	}
+	j.mu.Unlock()
+
+	if j.recorder != nil {
+		j.recorder.BackupDone(j.clock.Now().Sub(started), size, err)
+	}
+	if err != nil {
+		return "", err
+	}
+	j.log.Info("backup stored", slog.String("key", key), slog.Int64("bytes", size))
+
+	// a failed rotation leaves extra files but the backup itself is fine
+	if err := j.rotate(ctx); err != nil {
+		j.log.Warn("backup rotation failed", slog.Any("error", err))
+	}
+	return key, nil
+}
+
+func (j *Job) backup(ctx context.Context, started time.Time) (string, int64, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := j.exporter.Export(ctx, zw); err != nil {
+		return "", 0, fmt.Errorf("export: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return "", 0, fmt.Errorf("compress: %w", err)
+	}
+	key := j.prefix + "subs_tracker-" + started.UTC().Format("20060102T150405Z") + keySuffix
+	if err := j.store.Put(ctx, key, buf.Bytes(), "application/gzip"); err != nil {
+		return "", 0, fmt.Errorf("store: %w", err)
+	}
+	return key, int64(buf.Len()), nil
+}
+
+// rotate deletes all but the newest backups; keys sort by the time they were taken
+func (j *Job) rotate(ctx context.Context) error {
+	objects, err := j.store.List(ctx, j.prefix)
+	if err != nil {
+		return err
+	}
+	var keys []string
+	for _, o := range objects {
+		if strings.HasSuffix(o.Key, keySuffix) {
+			keys = append(keys, o.Key)
+		}
+	}
+	sort.Strings(keys)
+	var errs []error
+	for len(keys) > j.retention {
+		if err := j.store.Delete(ctx, keys[0]); err != nil {
+			errs = append(errs, err)
+		}
+		keys = keys[1:]
+	}
+	return errors.Join(errs...)
+}
+
+// Status returns the outcome of the latest backups
+func (j *Job) Status() Status {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+// Check reports the status for /readyz and fails when the latest backup failed or the last
+// successful one is more than a day overdue
+func (j *Job) Check(context.Context) (any, error) {
+	st := j.Status()
+	switch {
+	case st.LastError != "":
+		return st, errors.New("last backup failed")
+	case !st.LastSuccess.IsZero() && j.clock.Now().Sub(st.LastSuccess) > 25*time.Hour:
+		return st, errors.New("backup is overdue")
+	}
+	return st, nil
+}
@@ -0,0 +1,160 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/pkg/clock"
+)
+
+type stubExporter struct {
+	err error
+}
+
+func (e *stubExporter) Export(_ context.Context, w io.Writer) error {
+	if e.err != nil {
+		return e.err
+	}
+	_, err := io.WriteString(w, "COPY public.subscriptions (id) FROM stdin;\n1\n\\.\n")
+	return err
+}
+
+type stubRecorder struct {
+	ok, failed int
+}
+
+func (r *stubRecorder) BackupDone(_ time.Duration, _ int64, err error) {
+	if err != nil {
+		r.failed++
+	} else {
+		r.ok++
+	}
+}
+
+func newTestJob(t *testing.T, exp Exporter, now *time.Time, options ...func(*Job)) (*Job, *DirStore) {
+	t.Helper()
+	store, err := NewDirStore(t.TempDir())
+	require.NoError(t, err)
+	options = append(options, WithClock(clock.Func(func() time.Time { return *now })))
+	j := NewJob(exp, store, slog.New(slog.DiscardHandler), options...)
+	return j, store
+}
+
+func TestJob_BackupOnce(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 7, 1, 2, 0, 0, 0, time.UTC)
+	exp, rec := &stubExporter{}, &stubRecorder{}
+	j, store := newTestJob(t, exp, &now, WithRetention(2), WithPrefix("nightly"), WithRecorder(rec))
+
+	key, err := j.BackupOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "nightly/subs_tracker-20250701T020000Z.sql.gz", key)
+
+	raw, err := os.ReadFile(filepath.Join(store.root, "nightly", "subs_tracker-20250701T020000Z.sql.gz"))
+	require.NoError(t, err)
+	zr, err := gzip.NewReader(bytes.NewReader(raw))
+	require.NoError(t, err)
+	script, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Contains(t, string(script), "COPY public.subscriptions")
+
+	for range 3 {
+		now = now.AddDate(0, 0, 1)
+		_, err = j.BackupOnce(ctx)
+		require.NoError(t, err)
+	}
+	objects, err := store.List(ctx, "nightly/")
+	require.NoError(t, err)
+	require.Len(t, objects, 2, "older backups are rotated out")
+	assert.Equal(t, "nightly/subs_tracker-20250703T020000Z.sql.gz", objects[0].Key)
+	assert.Equal(t, "nightly/subs_tracker-20250704T020000Z.sql.gz", objects[1].Key)
+
+	st := j.Status()
+	assert.Equal(t, now, st.LastSuccess)
+	assert.Equal(t, objects[1].Key, st.LastKey)
+	assert.Equal(t, objects[1].Size, st.LastSize)
+	assert.Equal(t, 4, rec.ok)
+
+	exp.err = errors.New("db down")
+	now = now.Add(time.Hour)
+	_, err = j.BackupOnce(ctx)
+	require.Error(t, err)
+	st = j.Status()
+	assert.Equal(t, now, st.LastRun)
+	assert.Equal(t, objects[1].Key, st.LastKey, "the last good backup is still reported")
+	assert.Contains(t, st.LastError, "db down")
+	assert.Equal(t, 1, rec.failed)
+	objects, err = store.List(ctx, "nightly/")
+	require.NoError(t, err)
+	assert.Len(t, objects, 2, "a failed backup removes nothing")
+}
+
+func TestJob_Check(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 7, 1, 2, 0, 0, 0, time.UTC)
+	exp := &stubExporter{}
+	j, _ := newTestJob(t, exp, &now)
+
+	_, err := j.Check(ctx)
+	assert.NoError(t, err, "no backup is due yet")
+
+	_, err = j.BackupOnce(ctx)
+	require.NoError(t, err)
+	now = now.Add(24 * time.Hour)
+	_, err = j.Check(ctx)
+	assert.NoError(t, err)
+
+	now = now.Add(2 * time.Hour)
+	_, err = j.Check(ctx)
+	assert.EqualError(t, err, "backup is overdue")
+
+	exp.err = errors.New("db down")
+	_, _ = j.BackupOnce(ctx)
+	details, err := j.Check(ctx)
+	assert.EqualError(t, err, "last backup failed")
+	assert.Contains(t, details.(Status).LastError, "db down")
+}
+
+func TestJob_NextRun(t *testing.T) {
+	now := time.Date(2025, 7, 1, 1, 0, 0, 0, time.UTC)
+	j, _ := newTestJob(t, &stubExporter{}, &now, WithTime(2*time.Hour+30*time.Minute))
+
+	assert.Equal(t, time.Date(2025, 7, 1, 2, 30, 0, 0, time.UTC), j.nextRun(now))
+	assert.Equal(t, time.Date(2025, 7, 2, 2, 30, 0, 0, time.UTC), j.nextRun(time.Date(2025, 7, 1, 2, 30, 0, 0, time.UTC)))
+	moscow := time.FixedZone("MSK", 3*60*60)
+	assert.Equal(t, time.Date(2025, 7, 2, 2, 30, 0, 0, time.UTC), j.nextRun(time.Date(2025, 7, 2, 1, 0, 0, 0, moscow)))
+}
+
+func TestDirStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewDirStore(filepath.Join(t.TempDir(), "backups"))
+	require.NoError(t, err)
+
+	require.NoError(t, store.Put(ctx, "a/one.sql.gz", []byte("1"), ""))
+	require.NoError(t, store.Put(ctx, "a/two.sql.gz", []byte("22"), ""))
+	require.NoError(t, store.Put(ctx, "b/three.sql.gz", []byte("333"), ""))
+	require.NoError(t, store.Put(ctx, "a/one.sql.gz", []byte("111"), ""))
+
+	objects, err := store.List(ctx, "a/")
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+	assert.Equal(t, "a/one.sql.gz", objects[0].Key)
+	assert.EqualValues(t, 3, objects[0].Size)
+
+	require.NoError(t, store.Delete(ctx, "a/one.sql.gz"))
+	require.NoError(t, store.Delete(ctx, "a/one.sql.gz"))
+	objects, err = store.List(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, objects, 2)
+}
@@ -0,0 +1,159 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DB — database the export reads from, e.g. *pgxpool.Pool
+type DB interface {
+	BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error)
+}
+
+// CopyExporter dumps the data of every table in the schema the connections work in (the first existing one of
+// POSTGRES_SEARCH_PATH, public by default) with COPY, as a psql script meant to be replayed into a database
+// migrated to the same version, followed by the schemas of WithSchemas
+type CopyExporter struct {
+	db      DB
+	schemas []string
+}
+
+// NewCopyExporter creates an exporter reading from db
+func NewCopyExporter(db DB, opts ...func(*CopyExporter)) *CopyExporter {
+	e := &CopyExporter{db: db}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// WithSchemas returns an option that also dumps the schemas, e.g. those of the tenants kept apart
+func WithSchemas(schemas ...string) func(*CopyExporter) {
+	return func(e *CopyExporter) {
+		e.schemas = schemas
+	}
+}
+
+// Export writes the script to w; all tables are read in one snapshot
+func (e *CopyExporter) Export(ctx context.Context, w io.Writer) error {
+	tx, err := e.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("begin export: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var shared string
+	if err := tx.QueryRow(ctx, "SELECT current_schema()").Scan(&shared); err != nil {
+		return fmt.Errorf("current schema: %w", err)
+	}
+
+	// triggers stay off while restoring so the change log is not written twice
+	if _, err := fmt.Fprintf(w, "-- subs_tracker logical backup taken at %s\n"+
+		"BEGIN;\nSET session_replication_role = replica;\n\n", time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return err
+	}
+	for _, schema := range append([]string{shared}, e.schemas...) {
+		if err := exportSchema(ctx, tx, w, schema); err != nil {
+			return err
+		}
+	}
+	_, err = io.WriteString(w, "COMMIT;\n")
+	return err
+}
+
+// exportSchema writes the data and the sequences of the schema
+func exportSchema(ctx context.Context, tx pgx.Tx, w io.Writer, schema string) error {
+	tables, err := tableNames(ctx, tx, schema)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		if err := copyTable(ctx, tx, w, schema, table); err != nil {
+			return fmt.Errorf("export %s.%s: %w", schema, table, err)
+		}
+	}
+	return writeSequences(ctx, tx, w, schema)
+}
+
+// tableNames lists plain tables of the schema except the migration bookkeeping
+func tableNames(ctx context.Context, tx pgx.Tx, schema string) ([]string, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT c.relname
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relkind = 'r' AND c.relname <> 'schema_migrations'
+		ORDER BY c.relname`, schema)
+	if err != nil {
+		return nil, fmt.Errorf("list tables: %w", err)
+	}
+	tables, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("list tables: %w", err)
+	}
+	return tables, nil
+}
+
+func copyTable(ctx context.Context, tx pgx.Tx, w io.Writer, schema, table string) error {
+	rows, err := tx.Query(ctx, `
+		SELECT column_name
+		FROM information_schema.columns
+		WHERE table_schema = $1 AND table_name = $2 AND is_generated = 'NEVER'
+		ORDER BY ordinal_position`, schema, table)
+	if err != nil {
+		return err
+	}
+	columns, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+	quoted := make([]string, 0, len(columns))
+	for _, c := range columns {
+		quoted = append(quoted, pgx.Identifier{c}.Sanitize())
+	}
+	target := pgx.Identifier{schema, table}.Sanitize() + " (" + strings.Join(quoted, ", ") + ")"
+
+	if _, err := fmt.Fprintf(w, "COPY %s FROM stdin;\n", target); err != nil {
+		return err
+	}
+	if _, err := tx.Conn().PgConn().CopyTo(ctx, w, "COPY "+target+" TO STDOUT"); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\\.\n\n")
+	return err
+}
+
+// writeSequences moves sequences past the restored IDs
+func writeSequences(ctx context.Context, tx pgx.Tx, w io.Writer, schema string) error {
+	rows, err := tx.Query(ctx, `
+		SELECT sequencename, last_value
+		FROM pg_sequences
+		WHERE schemaname = $1 AND last_value IS NOT NULL
+		ORDER BY sequencename`, schema)
+	if err != nil {
+		return fmt.Errorf("list sequences: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			name string
+			last int64
+		)
+		if err := rows.Scan(&name, &last); err != nil {
+			return fmt.Errorf("list sequences: %w", err)
+		}
+		seq := pgx.Identifier{schema, name}.Sanitize()
+		if _, err := fmt.Fprintf(w, "SELECT pg_catalog.setval('%s', %d, true);\n", strings.ReplaceAll(seq, "'", "''"), last); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("list sequences: %w", err)
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
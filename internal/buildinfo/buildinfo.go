@@ -0,0 +1,67 @@
+// Package buildinfo exposes application identity populated at link time, e.g.
+//
+//	go build -ldflags "-X subs_tracker/internal/buildinfo.Version=1.2.3 -X subs_tracker/internal/buildinfo.Commit=abc123 -X subs_tracker/internal/buildinfo.BuildDate=2025-07-01T00:00:00Z"
+package buildinfo
+
+import (
+	"runtime/debug"
+	"time"
+)
+
+// Name - application name used in headers and as the Postgres application_name
+const Name = "subs_tracker"
+
+// Set via -ldflags -X; left empty they fall back to the module build info
+var (
+	Version   = ""
+	Commit    = ""
+	BuildDate = ""
+)
+
+// Info — identity of the running binary
+type Info struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+}
+
+// Get returns the build identity, filling unset fields from runtime/debug build info
+func Get() Info {
+	info := Info{
+		Name:      Name,
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = s.Value
+			}
+		}
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	return info
+}
+
+// UserAgent returns "name/version", suitable for Server headers and connection labels
+func (i Info) UserAgent() string {
+	return i.Name + "/" + i.Version
+}
+
+// startedAt - moment the process loaded this package, used as the uptime origin
+var startedAt = time.Now()
+
+// Uptime returns how long the process has been running
+func Uptime() time.Duration {
+	return time.Since(startedAt)
+}
@@ -0,0 +1,206 @@
+// Package changes keeps the latest subscription events in memory for clients that long-poll for them instead
+// of holding a stream open, e.g. behind proxies that cut SSE and WebSockets. The log is filled from the event
+// bus like every other sink, so it sees the writes made through this instance only, and it forgets the
+// oldest changes once full: a cursor that is too old, or of another instance or run, resets the client,
+// which then reloads the list instead of applying changes
+package changes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/tenancy"
+	"subs_tracker/internal/usecase"
+)
+
+const (
+	// DefaultWait - how long a poll waits for a change when the client does not say
+	DefaultWait = 30 * time.Second
+	// MaxWait - the longest wait a client may ask for
+	MaxWait = time.Minute
+
+	defaultSize = 1024
+	maxBatch    = 100
+)
+
+// ErrInvalidCursor - the cursor is not one the log hands out
+var ErrInvalidCursor = errors.New("invalid changes cursor")
+
+// Change — one write of a subscription
+type Change struct {
+	Type usecase.SubscriptionEventType
+	// SubscriptionID, PublicID - the subscription as the event named it; PublicID is empty with serial IDs
+	SubscriptionID int64
+	PublicID       string
+	UserID         entity.UserID
+	OccurredAt     time.Time
+
+	seq    int64
+	tenant string
+}
+
+// Cursor — position in the log: the client passes the cursor of a batch to get the changes after it
+type Cursor struct {
+	epoch int64
+	seq   int64
+}
+
+// String encodes the cursor for clients
+func (c Cursor) String() string {
+	return strconv.FormatInt(c.epoch, 36) + "-" + strconv.FormatInt(c.seq, 10)
+}
+
+// ParseCursor decodes a cursor made by String
+func ParseCursor(s string) (Cursor, error) {
+	epoch, seq, ok := strings.Cut(s, "-")
+	if !ok {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var c Cursor
+	var err error
+	if c.epoch, err = strconv.ParseInt(epoch, 36, 64); err != nil || c.epoch <= 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	if c.seq, err = strconv.ParseInt(seq, 10, 64); err != nil || c.seq < 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// Batch — the answer to a poll
+type Batch struct {
+	// Changes - oldest first, empty when the wait ran out
+	Changes []Change
+	// Cursor - what to poll with next
+	Cursor Cursor
+	// Reset - changes may have been missed: the log forgot them or the cursor is of another run or instance
+	Reset bool
+}
+
+// Log — ring of the latest changes with waiters woken on every new one
+type Log struct {
+	mu     sync.Mutex
+	epoch  int64
+	seq    int64
+	ring   []Change
+	wake   chan struct{}
+	closed bool
+}
+
+// NewLog creates an empty log and applies options
+func NewLog(options ...func(*Log)) *Log {
+	l := &Log{
+		epoch: time.Now().UnixNano(),
+		ring:  make([]Change, defaultSize),
+		wake:  make(chan struct{}),
+	}
+	for _, o := range options {
+		o(l)
+	}
+	return l
+}
+
+// WithSize sets how many of the latest changes the log keeps
+func WithSize(n int) func(*Log) {
+	return func(l *Log) {
+		if n > 0 {
+			l.ring = make([]Change, n)
+		}
+	}
+}
+
+// Record appends the change made by the event and wakes the waiters
+func (l *Log) Record(e usecase.SubscriptionEvent) error {
+	if e.Subscription == nil {
+		return fmt.Errorf("record change: %s event without a subscription", e.Type)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seq++
+	l.ring[l.seq%int64(len(l.ring))] = Change{
+		Type:           e.Type,
+		SubscriptionID: e.Subscription.ID,
+		PublicID:       e.PublicID,
+		UserID:         e.Subscription.UserID,
+		OccurredAt:     e.OccurredAt,
+		seq:            l.seq,
+		tenant:         e.Tenant,
+	}
+	if !l.closed {
+		close(l.wake)
+		l.wake = make(chan struct{})
+	}
+	return nil
+}
+
+// Head returns the cursor after the latest change, where a client starts before it loads the list
+func (l *Log) Head() Cursor {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return Cursor{epoch: l.epoch, seq: l.seq}
+}
+
+// Wait returns the changes after the cursor of the tenant of ctx, of the user only when user is set, as soon
+// as there are any; without any it waits for up to wait, until ctx is cancelled or the log is closed and
+// returns an empty batch with the cursor moved past the changes of others
+func (l *Log) Wait(ctx context.Context, since Cursor, wait time.Duration, user *entity.UserID) Batch {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	tenant := tenancy.FromContext(ctx)
+	for {
+		l.mu.Lock()
+		batch := l.after(since, tenant, user)
+		wake, closed := l.wake, l.closed
+		l.mu.Unlock()
+		if len(batch.Changes) > 0 || batch.Reset || closed {
+			return batch
+		}
+		since = batch.Cursor
+		select {
+		case <-wake:
+		case <-timer.C:
+			return batch
+		case <-ctx.Done():
+			return batch
+		}
+	}
+}
+
+// Close wakes the waiters and makes later polls return at once, so the server can shut down without
+// waiting them out
+func (l *Log) Close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.closed {
+		l.closed = true
+		close(l.wake)
+	}
+}
+
+// after collects up to maxBatch matching changes after the cursor; the caller holds mu
+func (l *Log) after(since Cursor, tenant string, user *entity.UserID) Batch {
+	head := Cursor{epoch: l.epoch, seq: l.seq}
+	oldest := max(l.seq-int64(len(l.ring))+1, 1)
+	if since.epoch != l.epoch || since.seq > l.seq || since.seq < oldest-1 {
+		return Batch{Cursor: head, Reset: true}
+	}
+	out := Batch{Cursor: head}
+	for seq := since.seq + 1; seq <= l.seq; seq++ {
+		ch := l.ring[seq%int64(len(l.ring))]
+		if ch.tenant != tenant || (user != nil && ch.UserID != *user) {
+			continue
+		}
+		out.Changes = append(out.Changes, ch)
+		if len(out.Changes) == maxBatch {
+			out.Cursor.seq = seq
+			break
+		}
+	}
+	return out
+}
@@ -0,0 +1,95 @@
+package changes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/tenancy"
+	"subs_tracker/internal/usecase"
+)
+
+var (
+	ann = entity.UserID(uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba"))
+	bob = entity.UserID(uuid.MustParse("2f0c6a57-8c4e-4f43-9a49-3b8f1d3b9e21"))
+)
+
+func event(id int64, user entity.UserID) usecase.SubscriptionEvent {
+	return usecase.SubscriptionEvent{
+		Type:         usecase.EventSubscriptionCreated,
+		OccurredAt:   time.Date(2025, time.July, 10, 12, 0, 0, 0, time.UTC),
+		Subscription: &entity.Subscription{ID: id, UserID: user},
+	}
+}
+
+func ids(b Batch) []int64 {
+	out := []int64{}
+	for _, ch := range b.Changes {
+		out = append(out, ch.SubscriptionID)
+	}
+	return out
+}
+
+func TestLog_Wait(t *testing.T) {
+	ctx := context.Background()
+	l := NewLog(WithSize(3))
+	start := l.Head()
+
+	empty := l.Wait(ctx, start, time.Millisecond, nil)
+	assert.Empty(t, empty.Changes)
+	assert.False(t, empty.Reset)
+	assert.Equal(t, start, empty.Cursor)
+
+	require.NoError(t, l.Record(event(1, ann)))
+	require.NoError(t, l.Record(event(2, bob)))
+	b := l.Wait(ctx, start, time.Minute, nil)
+	assert.Equal(t, []int64{1, 2}, ids(b))
+	assert.Equal(t, l.Head(), b.Cursor)
+
+	mine := l.Wait(ctx, start, time.Minute, &bob)
+	assert.Equal(t, []int64{2}, ids(mine))
+	none := l.Wait(ctx, b.Cursor, time.Millisecond, &bob)
+	assert.Empty(t, none.Changes)
+
+	done := make(chan Batch)
+	go func() { done <- l.Wait(ctx, b.Cursor, time.Minute, &ann) }()
+	require.NoError(t, l.Record(event(3, bob)))
+	require.NoError(t, l.Record(event(4, ann)))
+	select {
+	case woken := <-done:
+		assert.Equal(t, []int64{4}, ids(woken), "changes of others move the cursor on")
+	case <-time.After(5 * time.Second):
+		t.Fatal("the waiter was not woken")
+	}
+
+	forgotten := l.Wait(ctx, start, time.Minute, nil)
+	assert.True(t, forgotten.Reset, "change 1 is no longer kept")
+	assert.Empty(t, forgotten.Changes)
+	assert.Equal(t, l.Head(), forgotten.Cursor)
+	assert.True(t, l.Wait(ctx, NewLog().Head(), time.Minute, nil).Reset, "cursor of another run")
+
+	tenant := l.Wait(tenancy.WithTenant(ctx, "acme"), b.Cursor, time.Millisecond, nil)
+	assert.Empty(t, tenant.Changes, "changes of the shared schema are not shown to tenants")
+
+	l.Close()
+	closed := l.Wait(ctx, l.Head(), time.Minute, nil)
+	assert.Empty(t, closed.Changes)
+	require.NoError(t, l.Record(event(5, ann)), "writes still go on while the server shuts down")
+	assert.Error(t, l.Record(usecase.SubscriptionEvent{Type: usecase.EventSubscriptionDeleted}))
+}
+
+func TestParseCursor(t *testing.T) {
+	c := NewLog().Head()
+	parsed, err := ParseCursor(c.String())
+	require.NoError(t, err)
+	assert.Equal(t, c, parsed)
+	for _, bad := range []string{"", "12", "-1", "?-1", "abc--1", "0-1"} {
+		_, err := ParseCursor(bad)
+		assert.ErrorIs(t, err, ErrInvalidCursor, bad)
+	}
+}
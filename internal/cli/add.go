@@ -0,0 +1,362 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/entity/generated"
+	"subs_tracker/internal/importer"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/dates"
+)
+
+// localRules mirror the server's default validation settings; the server stays authoritative
+var localRules = usecase.ValidationRules{
+	EarliestDate:  time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
+	MaxYearsAhead: 20,
+}
+
+// maxCompletions - suggestions shown for a partially typed service name
+const maxCompletions = 10
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// addParams — values of "subsctl add" given as flags; the ones not given are prompted for
+type addParams struct {
+	UserID   string
+	Service  string
+	Cost     string
+	Currency string
+	Cycle    string
+	From     string
+	To       string
+	// Yes - never prompt: missing optional values take their defaults, missing required ones are usage errors
+	Yes bool
+}
+
+func (p *addParams) register(fs *flag.FlagSet) {
+	fs.StringVar(&p.UserID, "user-id", "", "user ID")
+	fs.StringVar(&p.Service, "service", "", "service name")
+	fs.StringVar(&p.Cost, "cost", "", "cost per billing cycle, 0 for a free subscription")
+	fs.StringVar(&p.Currency, "currency", "", "currency of the cost, the user's currency by default")
+	fs.StringVar(&p.Cycle, "cycle", "", "billing cycle, monthly or yearly")
+	fs.StringVar(&p.From, "from", "", "first month, MM-YYYY")
+	fs.StringVar(&p.To, "to", "", "last month, MM-YYYY, empty for open-ended")
+	fs.BoolVar(&p.Yes, "yes", false, "do not prompt")
+	fs.BoolVar(&p.Yes, "y", false, "do not prompt")
+}
+
+// wizard — asks for the values of a new subscription on a terminal
+type wizard struct {
+	in    *bufio.Scanner
+	out   io.Writer
+	batch bool
+	// set - names of the flags given on the command line
+	set map[string]bool
+}
+
+// ask prints a prompt and reads one line; an empty answer yields def
+func (w *wizard) ask(label, def string) (string, error) {
+	if def != "" {
+		label += " [" + def + "]"
+	}
+	_, _ = fmt.Fprint(w.out, label+": ")
+	if !w.in.Scan() {
+		_, _ = fmt.Fprintln(w.out)
+		return "", fmt.Errorf("%w: input closed", ErrAborted)
+	}
+	if v := strings.TrimSpace(w.in.Text()); v != "" {
+		return v, nil
+	}
+	return def, nil
+}
+
+// field returns the parsed value of a flag when it was given and prompts for it otherwise;
+// a bad answer is explained and asked again, a bad flag ends the command
+func (w *wizard) field(name, given, label, def string, parse func(string) (string, error)) (string, error) {
+	if w.set[name] || w.batch {
+		v := given
+		if !w.set[name] {
+			v = def
+		}
+		out, err := parse(v)
+		if err != nil {
+			return "", fmt.Errorf("%w: --%s: %w", ErrUsage, name, err)
+		}
+		return out, nil
+	}
+	for {
+		v, err := w.ask(label, def)
+		if err != nil {
+			return "", err
+		}
+		out, err := parse(v)
+		if err == nil {
+			return out, nil
+		}
+		_, _ = fmt.Fprintln(w.out, "  "+err.Error())
+	}
+}
+
+// service asks for a service name, completing partial input from the catalog
+func (w *wizard) service(given string, catalog []string) (string, error) {
+	if w.set["service"] || w.batch {
+		given = strings.TrimSpace(given)
+		if given == "" {
+			return "", fmt.Errorf("%w: --service is required", ErrUsage)
+		}
+		return canonicalService(given, catalog), nil
+	}
+	for {
+		v, err := w.ask("Service (? lists known services)", "")
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case v == "":
+			_, _ = fmt.Fprintln(w.out, "  service is required")
+			continue
+		case v == "?":
+			w.list(catalog)
+			continue
+		}
+		if inCatalog(v, catalog) {
+			return canonicalService(v, catalog), nil
+		}
+		matches := completions(v, catalog)
+		if len(matches) == 0 {
+			return v, nil
+		}
+		w.list(matches)
+		pick, err := w.ask(fmt.Sprintf("Number to pick, Enter to keep %q", v), "")
+		if err != nil {
+			return "", err
+		}
+		if pick == "" {
+			return v, nil
+		}
+		n, err := strconv.Atoi(pick)
+		if err != nil || n < 1 || n > len(matches) {
+			_, _ = fmt.Fprintln(w.out, "  no such choice")
+			continue
+		}
+		return matches[n-1], nil
+	}
+}
+
+// confirm asks a yes/no question defaulting to yes; batch mode always agrees
+func (w *wizard) confirm(question string) error {
+	if w.batch {
+		return nil
+	}
+	v, err := w.ask(question+" [Y/n]", "")
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(v) {
+	case "", "y", "yes":
+		return nil
+	default:
+		return ErrAborted
+	}
+}
+
+func (w *wizard) list(names []string) {
+	for i, n := range names {
+		_, _ = fmt.Fprintf(w.out, "  %d) %s\n", i+1, n)
+	}
+}
+
+// canonicalService returns the catalog spelling of name when it matches an entry case-insensitively
+func canonicalService(name string, catalog []string) string {
+	name = strings.TrimSpace(name)
+	for _, c := range catalog {
+		if strings.EqualFold(c, name) {
+			return c
+		}
+	}
+	return name
+}
+
+func inCatalog(name string, catalog []string) bool {
+	return slices.ContainsFunc(catalog, func(c string) bool { return strings.EqualFold(c, strings.TrimSpace(name)) })
+}
+
+// completions returns catalog entries containing s case-insensitively, prefix matches first
+func completions(s string, catalog []string) []string {
+	s = strings.ToLower(s)
+	var prefix, inside []string
+	for _, c := range catalog {
+		switch lc := strings.ToLower(c); {
+		case strings.HasPrefix(lc, s):
+			prefix = append(prefix, c)
+		case strings.Contains(lc, s):
+			inside = append(inside, c)
+		}
+	}
+	out := append(prefix, inside...)
+	if len(out) > maxCompletions {
+		out = out[:maxCompletions]
+	}
+	return out
+}
+
+// serviceCatalog merges the well-known services with the ones the user already tracks
+func serviceCatalog(ctx context.Context, client *Client, userID string) []string {
+	names := slices.Clone(importer.DefaultCatalog)
+	// suggestions only: without the user's own services the wizard still works
+	if subs, err := client.List(ctx, ListParams{UserID: userID}); err == nil {
+		for _, s := range subs {
+			if s.ServiceName != nil && !inCatalog(*s.ServiceName, names) {
+				names = append(names, *s.ServiceName)
+			}
+		}
+	}
+	slices.SortFunc(names, func(a, b string) int { return strings.Compare(strings.ToLower(a), strings.ToLower(b)) })
+	return names
+}
+
+// cycleMonths parses a billing cycle into its length in months
+func cycleMonths(s string) (int64, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "m", "month", "monthly":
+		return 1, nil
+	case "y", "year", "yearly", "annual":
+		return 12, nil
+	default:
+		return 0, errors.New("cycle must be monthly or yearly")
+	}
+}
+
+// runAdd gathers a subscription from flags and prompts, validates it locally and creates it
+func runAdd(ctx context.Context, client *Client, o options, p addParams, w *wizard, stdout io.Writer) error {
+	dp := dates.NewParser()
+	month := func(optional bool) func(string) (string, error) {
+		return func(v string) (string, error) {
+			if v == "" && optional {
+				return "", nil
+			}
+			t, err := dp.Parse(v)
+			if err != nil {
+				return "", fmt.Errorf("want a month like 07-2025: %w", err)
+			}
+			return dates.Format(t), nil
+		}
+	}
+
+	userID, err := w.field("user-id", p.UserID, "User ID", "", func(v string) (string, error) {
+		uid, err := entity.ParseUserID(v)
+		if err != nil {
+			return "", err
+		}
+		return uid.String(), nil
+	})
+	if err != nil {
+		return err
+	}
+	settings, err := client.Settings(ctx, userID)
+	if err != nil {
+		return err
+	}
+	userCurrency := ""
+	if settings.Currency != nil {
+		userCurrency = *settings.Currency
+	}
+
+	service, err := w.service(p.Service, serviceCatalog(ctx, client, userID))
+	if err != nil {
+		return err
+	}
+	costStr, err := w.field("cost", p.Cost, "Cost per billing cycle", "", func(v string) (string, error) {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return "", errors.New("cost must be a whole number >= 0, 0 for a free subscription")
+		}
+		return v, nil
+	})
+	if err != nil {
+		return err
+	}
+	currency, err := w.field("currency", p.Currency, "Currency", userCurrency, func(v string) (string, error) {
+		v = strings.ToUpper(strings.TrimSpace(v))
+		if !currencyCode.MatchString(v) {
+			return "", errors.New("currency must be an ISO 4217 code, e.g. RUB")
+		}
+		if userCurrency != "" && v != userCurrency {
+			return "", fmt.Errorf("costs of this user are kept in %s: convert the amount or change the user's currency", userCurrency)
+		}
+		return v, nil
+	})
+	if err != nil {
+		return err
+	}
+	cycle, err := w.field("cycle", p.Cycle, "Billing cycle (monthly, yearly)", "monthly", func(v string) (string, error) {
+		_, err := cycleMonths(v)
+		return v, err
+	})
+	if err != nil {
+		return err
+	}
+	from, err := w.field("from", p.From, "First month", dates.Format(dates.MonthStart(time.Now())), month(false))
+	if err != nil {
+		return err
+	}
+	to, err := w.field("to", p.To, "Last month (empty if open-ended)", "", month(true))
+	if err != nil {
+		return err
+	}
+
+	cost, _ := strconv.ParseInt(costStr, 10, 64)
+	months, _ := cycleMonths(cycle)
+	// the API stores monthly costs; longer cycles are spread evenly, rounded to whole units
+	monthly := (cost + months/2) / months
+	uid, _ := entity.ParseUserID(userID)
+	sub := entity.Subscription{UserID: uid, ServiceName: service, Cost: monthly}
+	sub.DateFrom, _ = dp.Parse(from)
+	if to != "" {
+		end, _ := dp.Parse(to)
+		sub.DateTo = &end
+	}
+	if err := localRules.Validate(&sub); err != nil {
+		return err
+	}
+
+	period := from + " - open-ended"
+	if to != "" {
+		period = from + " - " + to
+	}
+	price := fmt.Sprintf("%d %s a month", monthly, currency)
+	if cost == 0 {
+		price = "free"
+	}
+	if err := w.confirm(fmt.Sprintf("Create %s for %s: %s, %s?", sub.ServiceName, userID, price, period)); err != nil {
+		return err
+	}
+
+	userUUID := strfmt.UUID(userID)
+	in := generated.SubscriptionInput{
+		UserID:      &userUUID,
+		ServiceName: &sub.ServiceName,
+		Cost:        &monthly,
+		StartDate:   &from,
+		EndDate:     to,
+		Free:        cost == 0,
+	}
+	created, err := client.Create(ctx, in)
+	if err != nil {
+		return err
+	}
+	return WriteSubs(stdout, o.format, o.quiet, []*generated.Subscription{created})
+}
@@ -0,0 +1,303 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/entity/generated"
+)
+
+const usage = `usage: subsctl <command> [flags] [args]
+
+commands:
+  list                 list subscriptions (--user-id, --service, --from, --to, --limit, --offset)
+  get <id>             show a subscription
+  delete <id>          delete a subscription
+  cost --from --to     total cost of subscriptions in the period (--user-id, --service)
+  add                  create a subscription, prompting for every value not given as a flag
+                       (--user-id, --service, --cost, --currency, --cycle, --from, --to; -y never prompts)
+  admin rotate-tokens  invalidate share link tokens issued before --before (RFC 3339, default now),
+                       e.g. after a leak (--user-id; needs --admin-token or $SUBSCTL_ADMIN_TOKEN)
+  admin migrate-costs  backfill currency and kopeck costs of subscriptions stored in whole rubles, --batch
+                       at a time until done; rerun to resume (--dry-run only counts; needs --admin-token)
+
+common flags:
+  --server URL         API server, default $SUBSCTL_SERVER or http://localhost:8080
+  -o, --output FORMAT  table (default), json, yaml or csv
+  -q, --quiet          print only IDs (or the bare total for cost)
+  --timeout DURATION   request timeout, default 10s
+  --token TOKEN        API token when the server sets HTTP_API_TOKENS, default $SUBSCTL_TOKEN
+
+periods: --from and --to of list and cost take MM-YYYY, YYYY-MM, "june 2025", a month of this year ("june"),
+  "this month", "last month", "next month", "3 months ago", "this year" or "last year" (January as --from,
+  December as --to); --user is short for --user-id
+
+exit codes: 0 ok, 1 error, 2 usage, 3 not found, 4 rejected input, 5 server error or unreachable
+`
+
+// options — flags shared by every command
+type options struct {
+	server  string
+	output  string
+	quiet   bool
+	timeout time.Duration
+	token   string
+	format  Format
+}
+
+func (o *options) register(fs *flag.FlagSet) {
+	server := os.Getenv("SUBSCTL_SERVER")
+	if server == "" {
+		server = "http://localhost:8080"
+	}
+	fs.StringVar(&o.server, "server", server, "API server")
+	fs.StringVar(&o.output, "output", "table", "output format")
+	fs.StringVar(&o.output, "o", "table", "output format")
+	fs.BoolVar(&o.quiet, "quiet", false, "print only IDs")
+	fs.BoolVar(&o.quiet, "q", false, "print only IDs")
+	fs.DurationVar(&o.timeout, "timeout", defaultTimeout, "request timeout")
+	fs.StringVar(&o.token, "token", os.Getenv("SUBSCTL_TOKEN"), "API token")
+}
+
+// Run executes the command line args (without the program name) and returns the exit code;
+// prompts of interactive commands read stdin and are written to stderr
+func Run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	err := run(ctx, args, stdin, stdout, stderr)
+	if err != nil {
+		if errors.Is(err, ErrUsage) {
+			_, _ = fmt.Fprint(stderr, usage)
+		}
+		if !errors.Is(err, flag.ErrHelp) {
+			_, _ = fmt.Fprintln(stderr, "subsctl:", err)
+		}
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return ExitOK
+	}
+	return ExitCode(err)
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: no command", ErrUsage)
+	}
+	cmd, args := args[0], args[1:]
+	if cmd == "admin" {
+		if len(args) == 0 {
+			return fmt.Errorf("%w: admin needs a subcommand", ErrUsage)
+		}
+		cmd, args = "admin "+args[0], args[1:]
+	}
+	if cmd == "help" || cmd == "-h" || cmd == "--help" {
+		_, _ = fmt.Fprint(stdout, usage)
+		return nil
+	}
+
+	var (
+		o          options
+		params     ListParams
+		add        addParams
+		rotate     rotateParams
+		migrate    migrateParams
+		adminToken string
+	)
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	o.register(fs)
+	switch cmd {
+	case "list", "cost":
+		fs.StringVar(&params.UserID, "user-id", "", "user ID")
+		fs.StringVar(&params.UserID, "user", "", "user ID")
+		fs.StringVar(&params.ServiceName, "service", "", "service name")
+		fs.StringVar(&params.From, "from", "", "period start, MM-YYYY or e.g. \"last month\"")
+		fs.StringVar(&params.To, "to", "", "period end, MM-YYYY or e.g. \"this month\"")
+		if cmd == "list" {
+			fs.IntVar(&params.Limit, "limit", 0, "maximum number of subscriptions")
+			fs.IntVar(&params.Offset, "offset", 0, "subscriptions to skip")
+		}
+	case "add":
+		add.register(fs)
+	case "admin rotate-tokens":
+		rotate.register(fs)
+	case "admin migrate-costs":
+		migrate.register(fs)
+	case "get", "delete":
+	default:
+		return fmt.Errorf("%w: unknown command %q", ErrUsage, cmd)
+	}
+	if strings.HasPrefix(cmd, "admin ") {
+		fs.StringVar(&adminToken, "admin-token", os.Getenv("SUBSCTL_ADMIN_TOKEN"), "HTTP_ADMIN_TOKEN of the server")
+	}
+	if err := parseInterspersed(fs, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return fmt.Errorf("%w: %w", ErrUsage, err)
+	}
+	format, err := ParseFormat(o.output)
+	if err != nil {
+		return err
+	}
+	o.format = format
+	if strings.HasPrefix(cmd, "admin ") {
+		if fs.NArg() > 0 {
+			return fmt.Errorf("%w: %s takes no arguments", ErrUsage, cmd)
+		}
+		if adminToken == "" {
+			return fmt.Errorf("%w: %s needs --admin-token or $SUBSCTL_ADMIN_TOKEN", ErrUsage, cmd)
+		}
+	}
+	if params.From != "" || params.To != "" {
+		now := time.Now()
+		params.From, params.To = resolveMonth(params.From, now, false), resolveMonth(params.To, now, true)
+	}
+	client := NewClient(o.server, WithTimeout(o.timeout), WithToken(o.token), WithAdminToken(adminToken))
+
+	switch cmd {
+	case "add":
+		w := &wizard{in: bufio.NewScanner(stdin), out: stderr, batch: add.Yes, set: map[string]bool{}}
+		fs.Visit(func(f *flag.Flag) { w.set[f.Name] = true })
+		if fs.NArg() > 0 {
+			return fmt.Errorf("%w: add takes no arguments", ErrUsage)
+		}
+		return runAdd(ctx, client, o, add, w, stdout)
+	case "list":
+		subs, err := client.List(ctx, params)
+		if err != nil {
+			return err
+		}
+		return WriteSubs(stdout, o.format, o.quiet, subs)
+	case "cost":
+		if params.From == "" || params.To == "" {
+			return fmt.Errorf("%w: cost needs --from and --to", ErrUsage)
+		}
+		cost, err := client.Cost(ctx, params)
+		if err != nil {
+			return err
+		}
+		return WriteCost(stdout, o.format, o.quiet, Cost{Total: cost.Total, Currency: cost.Currency, Locale: systemLocale()})
+	case "admin rotate-tokens":
+		return runRotate(ctx, client, o, rotate, stdout)
+	case "admin migrate-costs":
+		return runMigrateCosts(ctx, client, o, migrate, stdout)
+	default:
+		if fs.NArg() != 1 {
+			return fmt.Errorf("%w: %s needs exactly one subscription id", ErrUsage, cmd)
+		}
+		id := strings.TrimSpace(fs.Arg(0))
+		if !validID(id) {
+			return fmt.Errorf("%w: invalid id %q", ErrUsage, fs.Arg(0))
+		}
+		var sub *generated.Subscription
+		var err error
+		if cmd == "get" {
+			sub, err = client.Get(ctx, id)
+		} else {
+			sub, err = client.Delete(ctx, id)
+		}
+		if err != nil {
+			return err
+		}
+		return WriteSubs(stdout, o.format, o.quiet, []*generated.Subscription{sub})
+	}
+}
+
+// validID reports whether s is a subscription ID of any strategy: a positive number, a UUID, a ULID or a
+// hashid of letters and digits. Which of them the server takes is up to its SUBSCRIPTION_ID_STRATEGY
+func validID(s string) bool {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n > 0
+	}
+	if _, err := entity.ParsePublicID(s); err == nil {
+		return true
+	}
+	return s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+	}) < 0
+}
+
+// rotateParams — flags of admin rotate-tokens
+type rotateParams struct {
+	before string
+	userID string
+}
+
+func (p *rotateParams) register(fs *flag.FlagSet) {
+	fs.StringVar(&p.before, "before", "", "revoke tokens issued before this RFC 3339 time, default now")
+	fs.StringVar(&p.userID, "user-id", "", "only tokens of this user")
+}
+
+func runRotate(ctx context.Context, client *Client, o options, p rotateParams, stdout io.Writer) error {
+	var before time.Time
+	if p.before != "" {
+		var err error
+		if before, err = time.Parse(time.RFC3339, p.before); err != nil {
+			return fmt.Errorf("%w: invalid --before %q, want RFC 3339", ErrUsage, p.before)
+		}
+	}
+	res, err := client.RevokeTokens(ctx, before, p.userID)
+	if err != nil {
+		return err
+	}
+	return WriteRevoked(stdout, o.format, o.quiet, res)
+}
+
+// migrateParams — flags of admin migrate-costs
+type migrateParams struct {
+	dryRun bool
+	batch  int
+}
+
+func (p *migrateParams) register(fs *flag.FlagSet) {
+	fs.BoolVar(&p.dryRun, "dry-run", false, "only count subscriptions left to migrate")
+	fs.IntVar(&p.batch, "batch", 0, "subscriptions per request, default of the server")
+}
+
+// runMigrateCosts requests batches until the server reports the migration done. A batch that migrates nothing
+// while rows remain (all of them locked by writes) ends the run early; it is resumed by running it again
+func runMigrateCosts(ctx context.Context, client *Client, o options, p migrateParams, stdout io.Writer) error {
+	if p.batch < 0 {
+		return fmt.Errorf("%w: invalid --batch %d", ErrUsage, p.batch)
+	}
+	var total LegacyCosts
+	for {
+		res, err := client.MigrateLegacyCosts(ctx, p.dryRun, p.batch)
+		if err != nil {
+			return err
+		}
+		if total.Pending == 0 {
+			total.Pending = res.Pending
+		}
+		total.Migrated += res.Migrated
+		total.Remaining, total.Done, total.DryRun = res.Remaining, res.Done, res.DryRun
+		if res.Done || res.DryRun || res.Migrated == 0 {
+			break
+		}
+	}
+	return WriteLegacyCosts(stdout, o.format, o.quiet, total)
+}
+
+// parseInterspersed parses flags that may follow positional arguments, e.g. "get 42 -o json"
+func parseInterspersed(fs *flag.FlagSet, args []string) error {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	return fs.Parse(append([]string{"--"}, positional...))
+}
@@ -0,0 +1,327 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"subs_tracker/internal/errcode"
+	"subs_tracker/pkg/dates"
+)
+
+const netflix = `{"id":7,"user_id":"60601fee-2bf1-4721-ae6f-7636e79a0cba","service_name":"Netflix","cost":999,` +
+	`"start_date":"07-2025","updated_at":"2025-07-03T10:00:00Z"}`
+
+func testServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	legacy := 3
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/v1/users/60601fee-2bf1-4721-ae6f-7636e79a0cba/settings":
+			_, _ = fmt.Fprint(w, `{"currency":"RUB","locale":"ru","date_format":"01-2006","first_day_of_week":1,"timezone":"UTC"}`)
+		case r.URL.Path == "/api/v1/subscriptions" && r.Method == http.MethodPost:
+			var in map[string]any
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+			in["id"] = 8
+			w.WriteHeader(http.StatusCreated)
+			assert.NoError(t, json.NewEncoder(w).Encode(in))
+		case r.URL.Path == "/api/v1/subscriptions" && r.URL.Query().Get("service_name") == "":
+			_, _ = fmt.Fprint(w, `[{"id":9,"service_name":"Gym Plus","cost":1500,"start_date":"01-2025"}]`)
+		case r.URL.Path == "/api/v1/subscriptions":
+			assert.Equal(t, "Netflix", r.URL.Query().Get("service_name"))
+			_, _ = fmt.Fprint(w, "["+netflix+"]")
+		case r.URL.Path == "/api/v1/subscriptions/cost" && r.URL.Query().Get("start_date") == "13-2025":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = fmt.Fprint(w, `{"error":"invalid period","code":"DATE_INVALID"}`)
+		case r.URL.Path == "/api/v1/subscriptions/cost":
+			_, _ = fmt.Fprint(w, `{"total":2997,"currency":"RUB"}`)
+		case r.URL.Path == "/api/v1/subscriptions/7":
+			_, _ = fmt.Fprint(w, netflix)
+		case r.URL.Path == "/api/v1/subscriptions/01J9Z8M2Q4V6X8Z0A2C4E6G8J0":
+			_, _ = fmt.Fprint(w, `{"public_id":"01J9Z8M2Q4V6X8Z0A2C4E6G8J0","service_name":"Netflix","cost":999,"start_date":"07-2025"}`)
+		case r.URL.Path == "/api/v1/admin/tokens/revoke" && r.Header.Get("Authorization") != "Bearer adm1n":
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = fmt.Fprint(w, `{"error":"unauthorized"}`)
+		case r.URL.Path == "/api/v1/admin/tokens/revoke":
+			var in map[string]any
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+			assert.Equal(t, map[string]any{"issued_before": "2025-08-01T00:00:00Z"}, in)
+			_, _ = fmt.Fprint(w, `{"revoked":3,"issued_before":"2025-08-01T00:00:00Z"}`)
+		case r.URL.Path == "/api/v1/admin/migrations/legacy-costs":
+			assert.Equal(t, "Bearer adm1n", r.Header.Get("Authorization"))
+			var in struct {
+				DryRun    bool `json:"dry_run"`
+				BatchSize int  `json:"batch_size"`
+			}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+			pending, done := legacy, 0
+			if !in.DryRun {
+				done = min(legacy, in.BatchSize)
+				legacy -= done
+			}
+			_, _ = fmt.Fprintf(w, `{"pending":%d,"migrated":%d,"remaining":%d,"done":%t,"dry_run":%t}`,
+				pending, done, legacy, legacy == 0, in.DryRun)
+		case r.URL.Path == "/api/v1/subscriptions/500":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = fmt.Fprint(w, `{"error":"internal error"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `{"error":"not found"}`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func invoke(srv *httptest.Server, args ...string) (int, string, string) {
+	return invokeWithInput(srv, "", args...)
+}
+
+func invokeWithInput(srv *httptest.Server, input string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := Run(context.Background(), append(args, "--server", srv.URL), strings.NewReader(input), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun_Output(t *testing.T) {
+	srv := testServer(t)
+
+	t.Run("table", func(t *testing.T) {
+		code, out, _ := invoke(srv, "list", "--service", "Netflix")
+		require.Equal(t, ExitOK, code)
+		assert.Contains(t, out, "SERVICE_NAME")
+		assert.Regexp(t, `7\s+60601fee-2bf1-4721-ae6f-7636e79a0cba\s+Netflix\s+999\s+07-2025\s+-\s+2025-07-03T10:00:00Z`, out)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		code, out, _ := invoke(srv, "list", "-o", "json", "--service", "Netflix")
+		require.Equal(t, ExitOK, code)
+		var rows []Row
+		require.NoError(t, json.Unmarshal([]byte(out), &rows))
+		assert.Equal(t, []Row{{ID: 7, UserID: "60601fee-2bf1-4721-ae6f-7636e79a0cba", ServiceName: "Netflix", Cost: 999,
+			StartDate: "07-2025", UpdatedAt: "2025-07-03T10:00:00Z"}}, rows)
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		code, out, _ := invoke(srv, "get", "7", "--output", "YAML")
+		require.Equal(t, ExitOK, code)
+		var rows []Row
+		require.NoError(t, yaml.Unmarshal([]byte(out), &rows))
+		require.Len(t, rows, 1)
+		assert.Equal(t, "Netflix", rows[0].ServiceName)
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		code, out, _ := invoke(srv, "list", "-o", "csv", "--service", "Netflix")
+		require.Equal(t, ExitOK, code)
+		assert.Equal(t, "id,user_id,service_name,cost,start_date,end_date,updated_at\n"+
+			"7,60601fee-2bf1-4721-ae6f-7636e79a0cba,Netflix,999,07-2025,,2025-07-03T10:00:00Z\n", out)
+	})
+
+	t.Run("quiet", func(t *testing.T) {
+		code, out, _ := invoke(srv, "list", "-q", "--service", "Netflix")
+		require.Equal(t, ExitOK, code)
+		assert.Equal(t, "7\n", out)
+
+		code, out, _ = invoke(srv, "cost", "--from", "07-2025", "--to", "09-2025", "-q")
+		require.Equal(t, ExitOK, code)
+		assert.Equal(t, "2997\n", out)
+	})
+
+	t.Run("public_id", func(t *testing.T) {
+		code, out, errOut := invoke(srv, "get", "01J9Z8M2Q4V6X8Z0A2C4E6G8J0", "-q")
+		require.Equal(t, ExitOK, code, errOut)
+		assert.Equal(t, "01J9Z8M2Q4V6X8Z0A2C4E6G8J0\n", out, "servers with public IDs are read and printed by them")
+
+		code, out, _ = invoke(srv, "get", "01J9Z8M2Q4V6X8Z0A2C4E6G8J0", "-o", "csv")
+		require.Equal(t, ExitOK, code)
+		assert.Contains(t, out, "\n01J9Z8M2Q4V6X8Z0A2C4E6G8J0,,Netflix,999,07-2025,,\n")
+	})
+
+	t.Run("cost_formatted_for_locale", func(t *testing.T) {
+		t.Setenv("LC_ALL", "ru_RU.UTF-8")
+		code, out, _ := invoke(srv, "cost", "--from", "07-2025", "--to", "09-2025")
+		require.Equal(t, ExitOK, code)
+		assert.Equal(t, "2\u00a0997\u00a0₽\n", out)
+
+		t.Setenv("LC_ALL", "C")
+		code, out, _ = invoke(srv, "cost", "--from", "07-2025", "--to", "09-2025", "-o", "csv")
+		require.Equal(t, ExitOK, code)
+		assert.Equal(t, "total,currency,formatted\n2997,RUB,\"RUB\u00a02,997\"\n", out)
+	})
+
+	t.Run("rotate_tokens", func(t *testing.T) {
+		code, out, errOut := invoke(srv, "admin", "rotate-tokens", "--admin-token", "adm1n", "--before", "2025-08-01T00:00:00Z")
+		require.Equal(t, ExitOK, code, errOut)
+		assert.Equal(t, "revoked 3 tokens issued before 2025-08-01T00:00:00Z\n", out)
+	})
+
+	t.Run("migrate_costs", func(t *testing.T) {
+		code, out, errOut := invoke(srv, "admin", "migrate-costs", "--admin-token", "adm1n", "--dry-run")
+		require.Equal(t, ExitOK, code, errOut)
+		assert.Equal(t, "3 subscriptions to migrate\n", out)
+
+		code, out, errOut = invoke(srv, "admin", "migrate-costs", "--admin-token", "adm1n", "--batch", "2", "-o", "json")
+		require.Equal(t, ExitOK, code, errOut)
+		assert.JSONEq(t, `{"pending":3,"migrated":3,"remaining":0,"done":true,"dry_run":false}`, out, "batches until done")
+	})
+}
+
+func TestRun_ExitCodes(t *testing.T) {
+	srv := testServer(t)
+
+	tcases := []struct {
+		Name string
+		Args []string
+		Want int
+	}{
+		{Name: "no_command", Args: nil, Want: ExitUsage},
+		{Name: "unknown_command", Args: []string{"purge"}, Want: ExitUsage},
+		{Name: "bad_format", Args: []string{"get", "7", "-o", "xml"}, Want: ExitUsage},
+		{Name: "bad_id", Args: []string{"get", "seven?"}, Want: ExitUsage},
+		{Name: "cost_without_period", Args: []string{"cost"}, Want: ExitUsage},
+		{Name: "not_found", Args: []string{"get", "8"}, Want: ExitNotFound},
+		{Name: "validation", Args: []string{"cost", "--from", "13-2025", "--to", "09-2025"}, Want: ExitValidation},
+		{Name: "server", Args: []string{"delete", "500"}, Want: ExitServer},
+		{Name: "admin_without_subcommand", Args: []string{"admin"}, Want: ExitUsage},
+		{Name: "rotate_without_admin_token", Args: []string{"admin", "rotate-tokens", "--admin-token", ""}, Want: ExitUsage},
+		{Name: "rotate_bad_before", Args: []string{"admin", "rotate-tokens", "--admin-token", "adm1n", "--before", "08-2025"}, Want: ExitUsage},
+		{Name: "migrate_costs_without_admin_token", Args: []string{"admin", "migrate-costs", "--admin-token", ""}, Want: ExitUsage},
+		{Name: "migrate_costs_bad_batch", Args: []string{"admin", "migrate-costs", "--admin-token", "adm1n", "--batch", "-1"}, Want: ExitUsage},
+		{Name: "rotate_wrong_admin_token", Args: []string{"admin", "rotate-tokens", "--admin-token", "nope"}, Want: ExitError},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			code, out, errOut := invoke(srv, tc.Args...)
+			assert.Equal(t, tc.Want, code, errOut)
+			assert.Empty(t, out)
+			assert.NotEmpty(t, errOut)
+		})
+	}
+
+	t.Run("error_code", func(t *testing.T) {
+		_, err := NewClient(srv.URL).Cost(context.Background(), ListParams{From: "13-2025", To: "09-2025"})
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, errcode.DateInvalid, apiErr.Code)
+		assert.Equal(t, "invalid period", apiErr.Message)
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := Run(context.Background(), []string{"get", "7", "--server", "http://127.0.0.1:1"}, strings.NewReader(""), &stdout, &stderr)
+		assert.Equal(t, ExitServer, code)
+	})
+}
+
+func TestRun_Add(t *testing.T) {
+	srv := testServer(t)
+	const user = "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+
+	t.Run("interactive", func(t *testing.T) {
+		// user, partial service + pick, bad cost, cost, foreign currency, default currency, yearly, dates, confirm
+		input := strings.Join([]string{user, "gym", "1", "-5", "12000", "usd", "", "yearly", "02-2025", "", "y"}, "\n") + "\n"
+		code, out, prompts := invokeWithInput(srv, input, "add", "-o", "json")
+		require.Equal(t, ExitOK, code, prompts)
+		assert.Contains(t, prompts, "1) Gym Plus")
+		assert.Contains(t, prompts, "cost must be a whole number >= 0, 0 for a free subscription")
+		assert.Contains(t, prompts, "costs of this user are kept in RUB")
+		assert.Contains(t, prompts, "Create Gym Plus for "+user+": 1000 RUB a month, 02-2025 - open-ended?")
+
+		var rows []Row
+		require.NoError(t, json.Unmarshal([]byte(out), &rows))
+		assert.Equal(t, []Row{{ID: 8, UserID: user, ServiceName: "Gym Plus", Cost: 1000, StartDate: "02-2025"}}, rows)
+	})
+
+	t.Run("flags", func(t *testing.T) {
+		code, out, errOut := invoke(srv, "add", "-y", "-q", "--user-id", user, "--service", "netflix", "--cost", "999", "--from", "2025-07")
+		require.Equal(t, ExitOK, code, errOut)
+		assert.Equal(t, "8\n", out)
+	})
+
+	t.Run("declined", func(t *testing.T) {
+		code, out, _ := invokeWithInput(srv, "n\n", "add", "--user-id", user, "--service", "Netflix", "--cost", "999",
+			"--currency", "RUB", "--cycle", "monthly", "--from", "07-2025", "--to", "")
+		assert.Equal(t, ExitError, code)
+		assert.Empty(t, out)
+	})
+
+	tcases := []struct {
+		Name string
+		Args []string
+		Want int
+	}{
+		{Name: "missing_service", Args: []string{"--cost", "999", "--from", "07-2025"}, Want: ExitUsage},
+		{Name: "bad_cycle", Args: []string{"--service", "Netflix", "--cost", "999", "--cycle", "weekly"}, Want: ExitUsage},
+		{Name: "end_before_start", Args: []string{"--service", "Netflix", "--cost", "999", "--from", "07-2025", "--to", "06-2025"}, Want: ExitValidation},
+		{Name: "typo_year", Args: []string{"--service", "Netflix", "--cost", "999", "--from", "07-1025"}, Want: ExitValidation},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			code, out, errOut := invoke(srv, append([]string{"add", "-y", "--user-id", user}, tc.Args...)...)
+			assert.Equal(t, tc.Want, code, errOut)
+			assert.Empty(t, out)
+		})
+	}
+}
+
+func TestResolveMonth(t *testing.T) {
+	now := time.Date(2025, time.March, 17, 23, 0, 0, 0, time.UTC)
+	tcases := []struct {
+		In   string
+		End  bool
+		Want string
+	}{
+		{In: "this month", Want: "03-2025"},
+		{In: "  Last   Month ", Want: "02-2025"},
+		{In: "next month", Want: "04-2025"},
+		{In: "3 months ago", Want: "12-2024"},
+		{In: "2 месяца назад", Want: "01-2025"},
+		{In: "прошлый месяц", Want: "02-2025"},
+		{In: "last year", Want: "01-2024"},
+		{In: "last year", End: true, Want: "12-2024"},
+		{In: "this year", End: true, Want: "12-2025"},
+		{In: "jan", Want: "01-2025"},
+		{In: "December", Want: "12-2025"},
+		{In: "июль", Want: "07-2025"},
+		{In: "June 2024", Want: "06-2024"},
+		{In: "2024-06", Want: "06-2024"},
+		{In: "06-2024", Want: "06-2024"},
+		{In: "13-2025", Want: "13-2025"},
+		{In: "someday", Want: "someday"},
+		{In: "", Want: ""},
+	}
+	for _, tc := range tcases {
+		assert.Equal(t, tc.Want, resolveMonth(tc.In, now, tc.End), "%q end=%t", tc.In, tc.End)
+	}
+}
+
+func TestRun_NaturalPeriod(t *testing.T) {
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		_, _ = fmt.Fprint(w, `{"total":1998,"currency":"RUB"}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	this := dates.MonthStart(time.Now())
+	code, out, errOut := invoke(srv, "cost", "--user", "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+		"--from", "last month", "--to", "this month", "-q")
+	require.Equal(t, ExitOK, code, errOut)
+	assert.Equal(t, "1998\n", out)
+	assert.Equal(t, "60601fee-2bf1-4721-ae6f-7636e79a0cba", query.Get("user_id"))
+	assert.Equal(t, dates.Format(this.AddDate(0, -1, 0)), query.Get("start_date"))
+	assert.Equal(t, dates.Format(this), query.Get("end_date"))
+}
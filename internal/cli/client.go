@@ -0,0 +1,263 @@
+// Package cli implements subsctl, a command line client of the subscriptions API for shell scripts and cron jobs
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"subs_tracker/internal/entity/generated"
+	"subs_tracker/internal/errcode"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// APIError — a non-2xx answer of the API
+type APIError struct {
+	StatusCode int
+	// Code - error code of the catalog, e.g. SUB_NOT_FOUND; empty when the server sent none
+	Code    errcode.Code
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("server answered %d", e.StatusCode)
+	}
+	return fmt.Sprintf("%s (%d)", e.Message, e.StatusCode)
+}
+
+// ListParams — filter of Client.List; zero values are not sent
+type ListParams struct {
+	UserID      string
+	ServiceName string
+	// From, To - period bounds as accepted by the server, e.g. MM-YYYY
+	From   string
+	To     string
+	Limit  int
+	Offset int
+}
+
+func (p ListParams) query() url.Values {
+	q := url.Values{}
+	for k, v := range map[string]string{
+		"user_id":      p.UserID,
+		"service_name": p.ServiceName,
+		"start_date":   p.From,
+		"end_date":     p.To,
+	} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	if p.Limit > 0 {
+		q.Set("limit", strconv.Itoa(p.Limit))
+	}
+	if p.Offset > 0 {
+		q.Set("offset", strconv.Itoa(p.Offset))
+	}
+	return q
+}
+
+// Client calls the subscriptions API of a subs_tracker server
+type Client struct {
+	base       string
+	client     *http.Client
+	token      string
+	adminToken string
+}
+
+// NewClient creates a client for the server base URL, e.g. http://localhost:8080, and applies options
+func NewClient(base string, options ...func(*Client)) *Client {
+	c := &Client{
+		base:   strings.TrimSuffix(base, "/") + "/api/v1",
+		client: &http.Client{Timeout: defaultTimeout},
+	}
+	for _, o := range options {
+		o(c)
+	}
+	return c
+}
+
+// WithTimeout bounds every request
+func WithTimeout(timeout time.Duration) func(*Client) {
+	return func(c *Client) {
+		if timeout > 0 {
+			c.client = &http.Client{Timeout: timeout}
+		}
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for requests
+func WithHTTPClient(hc *http.Client) func(*Client) {
+	return func(c *Client) {
+		if hc != nil {
+			c.client = hc
+		}
+	}
+}
+
+// WithToken sets the bearer token sent to API endpoints (an entry of HTTP_API_TOKENS of the server)
+func WithToken(token string) func(*Client) {
+	return func(c *Client) {
+		c.token = strings.TrimSpace(token)
+	}
+}
+
+// WithAdminToken sets the bearer token sent to admin endpoints (HTTP_ADMIN_TOKEN of the server)
+func WithAdminToken(token string) func(*Client) {
+	return func(c *Client) {
+		c.adminToken = strings.TrimSpace(token)
+	}
+}
+
+// List returns subscriptions matching p
+func (c *Client) List(ctx context.Context, p ListParams) ([]*generated.Subscription, error) {
+	var out []*generated.Subscription
+	if err := c.do(ctx, http.MethodGet, "/subscriptions?"+p.query().Encode(), &out); err != nil {
+		return nil, fmt.Errorf("list subscriptions: %w", err)
+	}
+	return out, nil
+}
+
+// Get returns a single subscription
+func (c *Client) Get(ctx context.Context, id string) (*generated.Subscription, error) {
+	var out generated.Subscription
+	if err := c.do(ctx, http.MethodGet, "/subscriptions/"+url.PathEscape(id), &out); err != nil {
+		return nil, fmt.Errorf("get subscription %s: %w", id, err)
+	}
+	return &out, nil
+}
+
+// Delete removes a subscription and returns it as it was
+func (c *Client) Delete(ctx context.Context, id string) (*generated.Subscription, error) {
+	var out generated.Subscription
+	if err := c.do(ctx, http.MethodDelete, "/subscriptions/"+url.PathEscape(id), &out); err != nil {
+		return nil, fmt.Errorf("delete subscription %s: %w", id, err)
+	}
+	return &out, nil
+}
+
+// Create stores a new subscription and returns it as saved by the server
+func (c *Client) Create(ctx context.Context, in generated.SubscriptionInput) (*generated.Subscription, error) {
+	var out generated.Subscription
+	if err := c.send(ctx, http.MethodPost, "/subscriptions", in, &out); err != nil {
+		return nil, fmt.Errorf("create subscription: %w", err)
+	}
+	return &out, nil
+}
+
+// Settings returns the settings of a user, server defaults when the user never saved any
+func (c *Client) Settings(ctx context.Context, userID string) (generated.UserSettings, error) {
+	var out generated.UserSettings
+	if err := c.do(ctx, http.MethodGet, "/users/"+url.PathEscape(userID)+"/settings", &out); err != nil {
+		return out, fmt.Errorf("user settings: %w", err)
+	}
+	return out, nil
+}
+
+// Cost returns the total cost of subscriptions matching p; the period is required by the server
+func (c *Client) Cost(ctx context.Context, p ListParams) (generated.SubscriptionsCost, error) {
+	var out generated.SubscriptionsCost
+	if err := c.do(ctx, http.MethodGet, "/subscriptions/cost?"+p.query().Encode(), &out); err != nil {
+		return out, fmt.Errorf("subscriptions cost: %w", err)
+	}
+	return out, nil
+}
+
+// RevokeTokens invalidates share link tokens issued before the cutoff (now when zero), only of userID when set
+func (c *Client) RevokeTokens(ctx context.Context, before time.Time, userID string) (Revoked, error) {
+	in := map[string]any{}
+	if !before.IsZero() {
+		in["issued_before"] = before
+	}
+	if userID != "" {
+		in["user_id"] = userID
+	}
+	var out Revoked
+	if err := c.send(ctx, http.MethodPost, "/admin/tokens/revoke", in, &out); err != nil {
+		return out, fmt.Errorf("revoke tokens: %w", err)
+	}
+	return out, nil
+}
+
+// MigrateLegacyCosts backfills one batch (the server default when batch is zero) of subscriptions stored with
+// the legacy ruble cost; dryRun only counts them
+func (c *Client) MigrateLegacyCosts(ctx context.Context, dryRun bool, batch int) (LegacyCosts, error) {
+	in := map[string]any{}
+	if dryRun {
+		in["dry_run"] = true
+	}
+	if batch > 0 {
+		in["batch_size"] = batch
+	}
+	var out LegacyCosts
+	if err := c.send(ctx, http.MethodPost, "/admin/migrations/legacy-costs", in, &out); err != nil {
+		return out, fmt.Errorf("migrate legacy costs: %w", err)
+	}
+	return out, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, out any) error {
+	return c.send(ctx, method, path, nil, out)
+}
+
+// send performs a request with an optional JSON body and decodes the answer into out
+func (c *Client) send(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		raw, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+		body = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "subsctl")
+	switch {
+	case c.adminToken != "" && strings.HasPrefix(path, "/admin/"):
+		req.Header.Set("Authorization", "Bearer "+c.adminToken)
+	case c.token != "":
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		var body struct {
+			Error string       `json:"error"`
+			Code  errcode.Code `json:"code"`
+		}
+		if raw, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10)); err == nil && json.Unmarshal(raw, &body) == nil {
+			apiErr.Message, apiErr.Code = body.Error, body.Code
+		}
+		return apiErr
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
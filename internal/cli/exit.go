@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"errors"
+	"net/http"
+
+	"subs_tracker/internal/usecase"
+)
+
+// Exit codes of subsctl, stable for scripts
+const (
+	ExitOK = 0
+	// ExitError - any failure not covered below
+	ExitError = 1
+	// ExitUsage - unknown command, bad flags or arguments
+	ExitUsage = 2
+	// ExitNotFound - the subscription does not exist
+	ExitNotFound = 3
+	// ExitValidation - the input was rejected locally or by the server (400, 409, 412, 422, 428)
+	ExitValidation = 4
+	// ExitServer - the server failed (5xx) or could not be reached
+	ExitServer = 5
+)
+
+var (
+	// ErrUsage marks errors in the command line itself
+	ErrUsage = errors.New("usage")
+	// ErrAborted - the user declined a confirmation or closed the input
+	ErrAborted = errors.New("aborted")
+)
+
+// ExitCode maps an error returned by a command to the process exit code
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	if errors.Is(err, ErrUsage) || errors.Is(err, ErrUnknownFormat) {
+		return ExitUsage
+	}
+	if errors.Is(err, ErrAborted) {
+		return ExitError
+	}
+	if errors.Is(err, usecase.ErrInvalidSubscription) || errors.Is(err, usecase.ErrInvalidPeriod) ||
+		errors.Is(err, usecase.ErrDateOutOfRange) {
+		return ExitValidation
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		// no answer at all: connection refused, timeout, DNS
+		return ExitServer
+	}
+	switch code := apiErr.StatusCode; {
+	case code == http.StatusNotFound:
+		return ExitNotFound
+	case code == http.StatusBadRequest, code == http.StatusConflict, code == http.StatusPreconditionFailed,
+		code == http.StatusUnprocessableEntity, code == http.StatusPreconditionRequired:
+		return ExitValidation
+	case code >= 500:
+		return ExitServer
+	default:
+		return ExitError
+	}
+}
@@ -0,0 +1,268 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"subs_tracker/internal/entity/generated"
+	"subs_tracker/pkg/money"
+)
+
+// Format — how command results are printed
+type Format string
+
+const (
+	FormatTable Format = "table"
+	FormatJSON  Format = "json"
+	FormatYAML  Format = "yaml"
+	FormatCSV   Format = "csv"
+)
+
+var ErrUnknownFormat = errors.New("unknown output format")
+
+// ParseFormat parses an --output value, case-insensitive; empty means FormatTable
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case "":
+		return FormatTable, nil
+	case FormatTable, FormatJSON, FormatYAML, FormatCSV:
+		return f, nil
+	default:
+		return "", fmt.Errorf("%w %q: want json, yaml, table or csv", ErrUnknownFormat, s)
+	}
+}
+
+// Row — a subscription as printed; the keys are stable across formats. Servers showing public IDs leave ID 0
+// and fill PublicID, which the id column of tables and CSV then shows
+type Row struct {
+	ID          int64  `json:"id" yaml:"id"`
+	PublicID    string `json:"public_id,omitempty" yaml:"public_id,omitempty"`
+	UserID      string `json:"user_id" yaml:"user_id"`
+	ServiceName string `json:"service_name" yaml:"service_name"`
+	Cost        int64  `json:"cost" yaml:"cost"`
+	StartDate   string `json:"start_date" yaml:"start_date"`
+	EndDate     string `json:"end_date" yaml:"end_date"`
+	UpdatedAt   string `json:"updated_at" yaml:"updated_at"`
+}
+
+var rowHeader = []string{"id", "user_id", "service_name", "cost", "start_date", "end_date", "updated_at"}
+
+func (r Row) fields() []string {
+	return []string{r.id(), r.UserID, r.ServiceName, strconv.FormatInt(r.Cost, 10), r.StartDate, r.EndDate, r.UpdatedAt}
+}
+
+// id is the identifier of the row as the server shows it
+func (r Row) id() string {
+	if r.PublicID != "" {
+		return r.PublicID
+	}
+	return strconv.FormatInt(r.ID, 10)
+}
+
+// NewRow flattens an API subscription
+func NewRow(s *generated.Subscription) Row {
+	r := Row{ID: s.ID, PublicID: s.PublicID, EndDate: s.EndDate}
+	if s.UserID != nil {
+		r.UserID = s.UserID.String()
+	}
+	if s.ServiceName != nil {
+		r.ServiceName = *s.ServiceName
+	}
+	if s.Cost != nil {
+		r.Cost = *s.Cost
+	}
+	if s.StartDate != nil {
+		r.StartDate = *s.StartDate
+	}
+	if ts := time.Time(s.UpdatedAt); !ts.IsZero() {
+		r.UpdatedAt = ts.UTC().Format(time.RFC3339)
+	}
+	return r
+}
+
+// WriteSubs prints subscriptions in format f; quiet prints only their IDs, one per line
+func WriteSubs(w io.Writer, f Format, quiet bool, subs []*generated.Subscription) error {
+	rows := make([]Row, 0, len(subs))
+	for _, s := range subs {
+		rows = append(rows, NewRow(s))
+	}
+	if quiet {
+		for _, r := range rows {
+			if _, err := fmt.Fprintln(w, r.id()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch f {
+	case FormatJSON:
+		return writeJSON(w, rows)
+	case FormatYAML:
+		return yaml.NewEncoder(w).Encode(rows)
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		_ = cw.Write(rowHeader)
+		for _, r := range rows {
+			_ = cw.Write(r.fields())
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, strings.ToUpper(strings.Join(rowHeader, "\t")))
+		for _, r := range rows {
+			fields := r.fields()
+			for i, v := range fields {
+				if v == "" {
+					fields[i] = "-"
+				}
+			}
+			_, _ = fmt.Fprintln(tw, strings.Join(fields, "\t"))
+		}
+		return tw.Flush()
+	}
+}
+
+// Cost — the printed result of the cost command
+type Cost struct {
+	Total    int64  `json:"total" yaml:"total"`
+	Currency string `json:"currency,omitempty" yaml:"currency,omitempty"`
+	// Locale - how the table and CSV show the amount to a person, see systemLocale
+	Locale string `json:"-" yaml:"-"`
+}
+
+// Formatted returns the total with the currency in the conventions of the locale, e.g. "2 997 ₽"
+func (c Cost) Formatted() string {
+	return money.New(c.Currency, c.Locale).Format(c.Total)
+}
+
+// WriteCost prints a cost total in format f; quiet prints the bare number. CSV keeps the bare total for
+// spreadsheets and adds the formatted one next to it
+func WriteCost(w io.Writer, f Format, quiet bool, c Cost) error {
+	if quiet {
+		_, err := fmt.Fprintln(w, c.Total)
+		return err
+	}
+	switch f {
+	case FormatJSON:
+		return writeJSON(w, c)
+	case FormatYAML:
+		return yaml.NewEncoder(w).Encode(c)
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{"total", "currency", "formatted"})
+		_ = cw.Write([]string{strconv.FormatInt(c.Total, 10), c.Currency, c.Formatted()})
+		cw.Flush()
+		return cw.Error()
+	default:
+		_, err := fmt.Fprintln(w, c.Formatted())
+		return err
+	}
+}
+
+// Revoked — the printed result of admin rotate-tokens
+type Revoked struct {
+	Revoked      int64     `json:"revoked" yaml:"revoked"`
+	IssuedBefore time.Time `json:"issued_before" yaml:"issued_before"`
+}
+
+// WriteRevoked prints the number of revoked tokens in format f; quiet prints the bare number
+func WriteRevoked(w io.Writer, f Format, quiet bool, r Revoked) error {
+	if quiet {
+		_, err := fmt.Fprintln(w, r.Revoked)
+		return err
+	}
+	before := r.IssuedBefore.UTC().Format(time.RFC3339)
+	switch f {
+	case FormatJSON:
+		return writeJSON(w, r)
+	case FormatYAML:
+		return yaml.NewEncoder(w).Encode(r)
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{"revoked", "issued_before"})
+		_ = cw.Write([]string{strconv.FormatInt(r.Revoked, 10), before})
+		cw.Flush()
+		return cw.Error()
+	default:
+		_, err := fmt.Fprintf(w, "revoked %d tokens issued before %s\n", r.Revoked, before)
+		return err
+	}
+}
+
+// LegacyCosts — the printed result of admin migrate-costs
+type LegacyCosts struct {
+	Pending   int64 `json:"pending" yaml:"pending"`
+	Migrated  int64 `json:"migrated" yaml:"migrated"`
+	Remaining int64 `json:"remaining" yaml:"remaining"`
+	Done      bool  `json:"done" yaml:"done"`
+	DryRun    bool  `json:"dry_run" yaml:"dry_run"`
+}
+
+// WriteLegacyCosts prints the progress of the legacy cost migration in format f; quiet prints the bare number
+// of subscriptions left
+func WriteLegacyCosts(w io.Writer, f Format, quiet bool, r LegacyCosts) error {
+	if quiet {
+		_, err := fmt.Fprintln(w, r.Remaining)
+		return err
+	}
+	switch f {
+	case FormatJSON:
+		return writeJSON(w, r)
+	case FormatYAML:
+		return yaml.NewEncoder(w).Encode(r)
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{"pending", "migrated", "remaining", "done", "dry_run"})
+		_ = cw.Write([]string{strconv.FormatInt(r.Pending, 10), strconv.FormatInt(r.Migrated, 10),
+			strconv.FormatInt(r.Remaining, 10), strconv.FormatBool(r.Done), strconv.FormatBool(r.DryRun)})
+		cw.Flush()
+		return cw.Error()
+	default:
+		var err error
+		switch {
+		case r.DryRun:
+			_, err = fmt.Fprintf(w, "%d subscriptions to migrate\n", r.Remaining)
+		case r.Done:
+			_, err = fmt.Fprintf(w, "migrated %d subscriptions, done\n", r.Migrated)
+		default:
+			_, err = fmt.Fprintf(w, "migrated %d subscriptions, %d left; run again to resume\n", r.Migrated, r.Remaining)
+		}
+		return err
+	}
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// systemLocale returns the BCP 47 tag of the POSIX locale amounts are shown in: LC_ALL, LC_MONETARY or LANG, so
+// ru_RU.UTF-8 gives ru-RU. The C locale and an unset one give "", which money formats in English
+func systemLocale() string {
+	for _, name := range []string{"LC_ALL", "LC_MONETARY", "LANG"} {
+		v := os.Getenv(name)
+		if v == "" {
+			continue
+		}
+		v, _, _ = strings.Cut(v, ".")
+		v, _, _ = strings.Cut(v, "@")
+		if v == "C" || v == "POSIX" {
+			return ""
+		}
+		return strings.ReplaceAll(v, "_", "-")
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"subs_tracker/pkg/dates"
+)
+
+// monthParser reads the layouts of the API and spelled out months, e.g. "07-2025", "2025-07" or "июль 2025"
+var monthParser = dates.NewParser(dates.WithMonthNames(dates.RussianMonths))
+
+// relativeMonths - phrases naming a month relative to the current one
+var relativeMonths = map[string]int{
+	"this month": 0, "current month": 0, "now": 0, "today": 0,
+	"last month": -1, "previous month": -1, "prev month": -1,
+	"next month": 1,
+	"этот месяц": 0, "текущий месяц": 0, "сейчас": 0,
+	"прошлый месяц": -1, "предыдущий месяц": -1,
+	"следующий месяц": 1,
+}
+
+// relativeYears - phrases naming a year relative to the current one; as --from they mean its January, as --to
+// its December
+var relativeYears = map[string]int{
+	"this year": 0, "current year": 0,
+	"last year": -1, "previous year": -1,
+	"next year": 1,
+	"этот год":  0, "текущий год": 0,
+	"прошлый год": -1, "предыдущий год": -1,
+	"следующий год": 1,
+}
+
+// resolveMonth turns a period bound typed by a person into the MM-YYYY form of the API, relative to now:
+// "this month", "last month", "3 months ago", "last year", a month name of the current year ("march", "март")
+// or any layout of monthParser. end picks the last month of a year phrase. Values it does not recognize are
+// returned as typed, so the server reports them with its own error
+func resolveMonth(s string, now time.Time, end bool) string {
+	v := strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	if v == "" {
+		return s
+	}
+	this := dates.MonthStart(now.UTC())
+	if n, ok := relativeMonths[v]; ok {
+		return dates.Format(this.AddDate(0, n, 0))
+	}
+	if n, ok := relativeYears[v]; ok {
+		year := time.Date(this.Year()+n, time.January, 1, 0, 0, 0, 0, time.UTC)
+		if end {
+			year = year.AddDate(0, 11, 0)
+		}
+		return dates.Format(year)
+	}
+	if n, ok := monthsAgo(v); ok {
+		return dates.Format(this.AddDate(0, -n, 0))
+	}
+	if m, ok := monthName(v); ok {
+		return dates.Format(time.Date(this.Year(), m, 1, 0, 0, 0, 0, time.UTC))
+	}
+	if t, err := monthParser.Parse(v); err == nil {
+		return dates.Format(t)
+	}
+	return s
+}
+
+// monthsAgo parses "N months ago" and "N месяцев назад"
+func monthsAgo(v string) (int, bool) {
+	f := strings.Fields(v)
+	if len(f) != 3 || (f[2] != "ago" && f[2] != "назад") {
+		return 0, false
+	}
+	switch f[1] {
+	case "month", "months", "месяц", "месяца", "месяцев":
+	default:
+		return 0, false
+	}
+	n, err := strconv.Atoi(f[0])
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// monthName parses a bare English or Russian month name, full or abbreviated to three letters
+func monthName(v string) (time.Month, bool) {
+	if m, ok := dates.RussianMonths[v]; ok {
+		return m, true
+	}
+	for m := time.January; m <= time.December; m++ {
+		name := strings.ToLower(m.String())
+		if v == name || v == name[:3] {
+			return m, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,76 @@
+// Package concurrency runs the fan-out of a request, e.g. a batch of items or the shards of a query, on a
+// bounded number of goroutines. Every goroutine started by a call has returned when the call returns, the
+// first failure cancels the context of the others, and a panic in a worker comes back as an error instead of
+// taking down the process, since recovery middleware only covers the goroutine of the handler
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultLimit - calls at once when the limit passed is not positive
+const DefaultLimit = 8
+
+// ErrPanic - a worker panicked; the error carries the panic value and the stack
+var ErrPanic = errors.New("worker panicked")
+
+// Each calls fn for every item with at most limit calls at once. The first error cancels the ctx passed to
+// the calls still running, keeps the pending ones from starting and is returned once the running ones are done
+func Each[T any](ctx context.Context, limit int, items []T, fn func(ctx context.Context, item T) error) error {
+	_, err := Map(ctx, limit, items, func(ctx context.Context, item T) (struct{}, error) {
+		return struct{}{}, fn(ctx, item)
+	})
+	return err
+}
+
+// Map calls fn for every item like Each and returns the results in the order of items
+func Map[T, R any](ctx context.Context, limit int, items []T, fn func(ctx context.Context, item T) (R, error)) ([]R, error) {
+	out := make([]R, len(items))
+	if len(items) == 1 {
+		// nothing to run side by side, the caller's goroutine does
+		r, err := call(ctx, items[0], fn)
+		if err != nil {
+			return nil, err
+		}
+		out[0] = r
+		return out, nil
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(limit)
+	for i, item := range items {
+		if gctx.Err() != nil {
+			break
+		}
+		g.Go(func() error {
+			r, err := call(gctx, item, fn)
+			out[i] = r
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	// a parent cancelled before every item started leaves holes in out
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// call runs fn on item and turns a panic into ErrPanic
+func call[T, R any](ctx context.Context, item T, fn func(context.Context, T) (R, error)) (r R, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%w: %v\n%s", ErrPanic, p, debug.Stack())
+		}
+	}()
+	return fn(ctx, item)
+}
@@ -0,0 +1,81 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMap(t *testing.T) {
+	ctx := context.Background()
+	items := []int{5, 1, 4, 2, 3}
+	var running, peak atomic.Int32
+	out, err := Map(ctx, 2, items, func(_ context.Context, n int) (int, error) {
+		now := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if now <= p || peak.CompareAndSwap(p, now) {
+				break
+			}
+		}
+		time.Sleep(time.Duration(n) * time.Millisecond)
+		return n * n, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{25, 1, 16, 4, 9}, out, "results keep the order of items")
+	assert.LessOrEqual(t, peak.Load(), int32(2))
+
+	out, err = Map(ctx, 0, []int{}, func(context.Context, int) (int, error) { return 0, nil })
+	require.NoError(t, err)
+	assert.Empty(t, out)
+}
+
+func TestEach_FirstErrorCancels(t *testing.T) {
+	boom := errors.New("boom")
+	var cancelled atomic.Bool
+	err := Each(context.Background(), 3, []int{0, 1, 2}, func(ctx context.Context, n int) error {
+		if n == 0 {
+			return boom
+		}
+		select {
+		case <-ctx.Done():
+			cancelled.Store(true)
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	})
+	assert.ErrorIs(t, err, boom)
+	assert.True(t, cancelled.Load(), "the other calls see the cancellation")
+}
+
+func TestEach_Panic(t *testing.T) {
+	for _, items := range [][]string{{"a"}, {"a", "b"}} {
+		err := Each(context.Background(), 2, items, func(_ context.Context, s string) error {
+			if s == "a" {
+				panic("nil map")
+			}
+			return nil
+		})
+		require.ErrorIs(t, err, ErrPanic)
+		assert.Contains(t, err.Error(), "nil map")
+	}
+}
+
+func TestMap_ParentCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var calls atomic.Int32
+	_, err := Map(ctx, 1, []int{1, 2, 3}, func(context.Context, int) (int, error) {
+		calls.Add(1)
+		return 0, nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Zero(t, calls.Load())
+}
@@ -1,56 +1,599 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
+	"github.com/google/uuid"
 	"github.com/spf13/viper"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
+	"subs_tracker/pkg/fieldcrypt"
 	"time"
 )
 
 // Config - structure with all info about db
 type Config struct {
-	Env    string `mapstructure:"APP_ENV"`
-	Server ServerConfig
-	Pg     PgConfig
+	Env             string        `mapstructure:"APP_ENV"`
+	ShutdownTimeout time.Duration `mapstructure:"APP_SHUTDOWN_TIMEOUT"`
+	Log             LogConfig
+	Server          ServerConfig
+	Pg              PgConfig
+	Metrics         MetricsConfig
+	Dates           DatesConfig
+	Enrich          EnrichConfig
+	Inbound         InboundConfig
+	Validation      ValidationConfig
+	Anomaly         AnomalyConfig
+	Benchmark       BenchmarkConfig
+	Webhook         WebhookConfig
+	Events          EventsConfig
+	Stripe          StripeConfig
+	Archive         ArchiveConfig
+	Backup          BackupConfig
+	Pseudonym       PseudonymConfig
+	ReadModel       ReadModelConfig
+	Snapshot        SnapshotConfig
+	Share           ShareConfig
+	Jobs            JobsConfig
+	Tracing         TracingConfig
+	TableGrowth     TableGrowthConfig
+	Users           UsersConfig
+	IDs             IDsConfig
+	Analytics       AnalyticsConfig
+	RequestAudit    RequestAuditConfig
+	Sandbox         SandboxConfig
+	LoadShed        LoadShedConfig
+	Reconcile       ReconcileConfig
+	Import          ImportConfig
+}
+
+// LogConfig - structure with fields about logging
+type LogConfig struct {
+	// Redact - replace user IDs and emails in every log record, on unless disabled for local debugging
+	Redact bool `mapstructure:"LOG_REDACT"`
+	// SensitiveParams - query parameters and attributes whose values are always removed
+	SensitiveParams []string `mapstructure:"LOG_SENSITIVE_PARAMS"`
+	// BodySampleRate - share of requests, 0..1, whose request and response bodies are logged; 0 logs none
+	BodySampleRate float64 `mapstructure:"LOG_BODY_SAMPLE_RATE"`
+	// BodyRoutes - route patterns, e.g. /api/v1/subscriptions/:id, whose bodies are always logged
+	BodyRoutes []string `mapstructure:"LOG_BODY_ROUTES"`
+	// BodyStatuses - response status codes whose bodies are always logged, e.g. 400,422
+	BodyStatuses []int `mapstructure:"LOG_BODY_STATUSES"`
+	// BodyMaxBytes - logged bytes of each body, the rest is cut
+	BodyMaxBytes int `mapstructure:"LOG_BODY_MAX_BYTES"`
+}
+
+// BodyLogEnabled reports whether any request gets its bodies logged
+func (c LogConfig) BodyLogEnabled() bool {
+	return c.BodySampleRate > 0 || len(c.BodyRoutes) > 0 || len(c.BodyStatuses) > 0
 }
 
 // ServerConfig - structure with fields about server
 type ServerConfig struct {
-	Host        string        `mapstructure:"HTTP_HOST"`
+	Hosts       []string      `mapstructure:"HTTP_HOST"`
 	Port        int           `mapstructure:"HTTP_PORT"`
 	Timeout     time.Duration `mapstructure:"HTTP_TIMEOUT"`
 	CORSOrigins []string      `mapstructure:"HTTP_CORS_ORIGINS"`
+	// TrustedProxies - addresses or CIDRs of the proxies whose X-Forwarded-For is believed; empty believes
+	// nobody and a client is its peer address
+	TrustedProxies []string `mapstructure:"HTTP_TRUSTED_PROXIES"`
+	// CursorSecret - HMAC key for pagination cursors; a random key is used when empty
+	CursorSecret string `mapstructure:"HTTP_CURSOR_SECRET"`
+	// ReusePort - bind with SO_REUSEPORT so a new instance can start before the old one stops
+	ReusePort bool `mapstructure:"HTTP_REUSEPORT"`
+	// DrainDelay - time to keep serving with a failing /ping before shutdown starts
+	DrainDelay time.Duration `mapstructure:"HTTP_DRAIN_DELAY"`
+	// MaxInFlight - maximum concurrent API requests, 0 disables the limiter
+	MaxInFlight int `mapstructure:"HTTP_MAX_INFLIGHT"`
+	// ReadMaxInFlight - maximum concurrent API reads (GET, HEAD, OPTIONS), 0 means no separate limit
+	ReadMaxInFlight int `mapstructure:"HTTP_READ_MAX_INFLIGHT"`
+	// WriteMaxInFlight - maximum concurrent API writes, 0 means no separate limit
+	WriteMaxInFlight int `mapstructure:"HTTP_WRITE_MAX_INFLIGHT"`
+	// ExpensiveMaxInFlight - maximum concurrent requests to expensive routes (costs, calendar, benchmarks,
+	// export); they take these slots instead of read ones, 0 means no separate limit
+	ExpensiveMaxInFlight int `mapstructure:"HTTP_EXPENSIVE_MAX_INFLIGHT"`
+	// ExpensiveRate - requests per second admitted to expensive routes across all clients, 0 disables it
+	ExpensiveRate float64 `mapstructure:"HTTP_EXPENSIVE_RATE"`
+	// ExpensiveBurst - requests to expensive routes admitted at once above ExpensiveRate; 0 means one second's worth
+	ExpensiveBurst int `mapstructure:"HTTP_EXPENSIVE_BURST"`
+	// QueueLength - API requests allowed to wait for a free slot before 503 is returned
+	QueueLength int `mapstructure:"HTTP_QUEUE_LENGTH"`
+	// QueueTimeout - how long a queued request waits for a slot
+	QueueTimeout time.Duration `mapstructure:"HTTP_QUEUE_TIMEOUT"`
+	// AbuseWindow - rolling window of the per-client request and error counts, 0 disables tracking
+	AbuseWindow time.Duration `mapstructure:"HTTP_ABUSE_WINDOW"`
+	// AbuseMaxRequests - API requests a client may make in AbuseWindow, 0 means no limit
+	AbuseMaxRequests int `mapstructure:"HTTP_ABUSE_MAX_REQUESTS"`
+	// AbuseMaxErrorRate - share of error responses a client may get in AbuseWindow, 0 means no limit
+	AbuseMaxErrorRate float64 `mapstructure:"HTTP_ABUSE_MAX_ERROR_RATE"`
+	// AbuseBan - how long a client over the abuse limits is answered 429, 0 only reports it
+	AbuseBan time.Duration `mapstructure:"HTTP_ABUSE_BAN"`
+	// CostMaxAge - Cache-Control max-age of GET /subscriptions/cost in private caches, 0 disables caching headers
+	CostMaxAge time.Duration `mapstructure:"HTTP_COST_MAX_AGE"`
+	// CostNowTTL - how long GET /subscriptions/cost/now serves a user's total from memory, 0 always reads the database
+	CostNowTTL time.Duration `mapstructure:"HTTP_COST_NOW_TTL"`
+	// CostNowPrime - how many users with the largest spend get their current-month total computed into memory at
+	// startup and after POST /admin/cache/flush, so first polls after a deploy are fast; 0 disables priming
+	CostNowPrime int `mapstructure:"HTTP_COST_NOW_PRIME"`
+	// RequireIfMatch - reject PUT/DELETE of a subscription without an If-Match header
+	RequireIfMatch bool `mapstructure:"HTTP_REQUIRE_IF_MATCH"`
+	// StrictPagination - answer 400 to a limit or offset outside the allowed range instead of clamping it
+	StrictPagination bool `mapstructure:"HTTP_STRICT_PAGINATION"`
+	// ListMaxBytes - size budget of a GET /subscriptions page, the page is cut short with a next cursor
+	// rather than exceed it; 0 disables the budget
+	ListMaxBytes int `mapstructure:"HTTP_LIST_MAX_BYTES"`
+	// DefaultPeriod - period of list and cost requests naming neither start_date nor end_date: all, current_month
+	// or current_year; with all the list is not limited and cost requires both dates
+	DefaultPeriod string `mapstructure:"HTTP_DEFAULT_PERIOD"`
+	// AdminToken - bearer token for admin write endpoints, empty disables them
+	AdminToken string `mapstructure:"HTTP_ADMIN_TOKEN"`
+	// APITokens - "scope:token" bearer tokens required by the API when set, scope read, write or admin; empty
+	// leaves the API open
+	APITokens []string `mapstructure:"HTTP_API_TOKENS"`
+	// SPADir - directory of a built frontend served at /, overrides the one embedded with -tags spa
+	SPADir string `mapstructure:"HTTP_SPA_DIR"`
+	// ContractValidation - check API requests and responses against api/swagger, ignored in prod
+	ContractValidation bool `mapstructure:"HTTP_CONTRACT_VALIDATION"`
 }
 
 // PgConfig - structure with fields about postgres db
 type PgConfig struct {
-	Host     string `mapstructure:"POSTGRES_HOST"`
-	Port     int    `mapstructure:"POSTGRES_PORT"`
-	User     string `mapstructure:"POSTGRES_USER"`
-	Password string `mapstructure:"POSTGRES_PASSWORD"`
-	Db       string `mapstructure:"POSTGRES_DB"`
-	SSLMode  string `mapstructure:"POSTGRES_SSLMODE"`
+	Host             string        `mapstructure:"POSTGRES_HOST"`
+	Port             int           `mapstructure:"POSTGRES_PORT"`
+	User             string        `mapstructure:"POSTGRES_USER"`
+	Password         string        `mapstructure:"POSTGRES_PASSWORD"`
+	Db               string        `mapstructure:"POSTGRES_DB"`
+	SSLMode          string        `mapstructure:"POSTGRES_SSLMODE"`
+	ExplainThreshold time.Duration `mapstructure:"POSTGRES_EXPLAIN_THRESHOLD"`
+	ConnectTimeout   time.Duration `mapstructure:"POSTGRES_CONNECT_TIMEOUT"`
+	ApplicationName  string        `mapstructure:"POSTGRES_APPLICATION_NAME"`
+	SearchPath       string        `mapstructure:"POSTGRES_SEARCH_PATH"`
+	// TenantSchemas - tenants whose data is kept in a schema of their own, tenant_<id> of every database;
+	// the other tenants share the schema of the DSN
+	TenantSchemas []string `mapstructure:"POSTGRES_TENANT_SCHEMAS"`
+	// ShardDSNs - connection URLs of additional shards; users are spread over the main database and these
+	ShardDSNs []string `mapstructure:"POSTGRES_SHARD_DSNS"`
+	// MigrationsDir - directory of SQL migrations applied to every database at startup, empty to leave them
+	// to make migrate-up
+	MigrationsDir string `mapstructure:"POSTGRES_MIGRATIONS_DIR"`
+	// MinConns - connections of every pool opened at startup and kept open, 0 - opened on demand
+	MinConns int32 `mapstructure:"POSTGRES_MIN_CONNS"`
+	// MaxConns - connections of every pool at most, 0 - the pgx default of max(4, CPUs)
+	MaxConns int32 `mapstructure:"POSTGRES_MAX_CONNS"`
+	// StatementCache - prepare, describe, exec or simple; anything but prepare works behind pgbouncer in
+	// transaction pooling
+	StatementCache string `mapstructure:"POSTGRES_STATEMENT_CACHE"`
+}
+
+// DSN - build a postgres:// connection URL with credentials escaped and optional parameters set
+func (c PgConfig) DSN() string {
+	q := url.Values{}
+	if c.SSLMode != "" {
+		q.Set("sslmode", c.SSLMode)
+	}
+	if c.ConnectTimeout > 0 {
+		q.Set("connect_timeout", strconv.Itoa(int(c.ConnectTimeout.Round(time.Second).Seconds())))
+	}
+	if c.ApplicationName != "" {
+		q.Set("application_name", c.ApplicationName)
+	}
+	if c.SearchPath != "" {
+		q.Set("search_path", c.SearchPath)
+	}
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		Path:     "/" + c.Db,
+		RawQuery: q.Encode(),
+	}
+	return u.String()
+}
+
+// MetricsConfig - structure with fields about exported metrics
+type MetricsConfig struct {
+	RefreshInterval time.Duration `mapstructure:"METRICS_REFRESH_INTERVAL"`
+	Namespace       string        `mapstructure:"METRICS_NAMESPACE"`
+	Instance        string        `mapstructure:"METRICS_INSTANCE"`
+	Buckets         []float64     `mapstructure:"METRICS_BUCKETS"`
+	// Routes - route templates with their own HTTP metric series, a trailing * matches any rest; empty - all
+	Routes []string `mapstructure:"METRICS_ROUTES"`
+}
+
+// EnrichConfig - structure with fields about the external service catalog
+type EnrichConfig struct {
+	// URL - company autocomplete endpoint, empty disables ?enrich=true
+	URL      string        `mapstructure:"ENRICH_URL"`
+	APIKey   string        `mapstructure:"ENRICH_API_KEY"`
+	CacheTTL time.Duration `mapstructure:"ENRICH_CACHE_TTL"`
+	Timeout  time.Duration `mapstructure:"ENRICH_TIMEOUT"`
+	// PriceCheckInterval - how often current subscriptions are compared with the catalog prices, 0 disables
+	// the job; without URL there is nothing to compare with
+	PriceCheckInterval time.Duration `mapstructure:"ENRICH_PRICE_CHECK_INTERVAL"`
+}
+
+// InboundConfig - structure with fields about inbound integrations
+type InboundConfig struct {
+	// MailgunSigningKey - webhook signing key of Mailgun inbound routes, empty disables the endpoint
+	MailgunSigningKey string `mapstructure:"INBOUND_MAILGUN_SIGNING_KEY"`
+	// IngestKeys - API key per integration name allowed to post billing events, empty disables ingest
+	IngestKeys map[string]string `mapstructure:"INBOUND_INGEST_KEYS"`
+}
+
+// ValidationConfig - structure with fields about configurable business validation limits
+type ValidationConfig struct {
+	// MaxCost - upper bound of a monthly cost, 0 disables the check
+	MaxCost int64 `mapstructure:"VALIDATION_MAX_COST"`
+	// MaxPeriodMonths - longest allowed start_date..end_date span, 0 disables the check
+	MaxPeriodMonths int `mapstructure:"VALIDATION_MAX_PERIOD_MONTHS"`
+	// ServiceNamePattern - regular expression service names must match, empty allows any
+	ServiceNamePattern string `mapstructure:"VALIDATION_SERVICE_NAME_PATTERN"`
+	// EndDateRequired - services that must be stored with an end_date
+	EndDateRequired []string `mapstructure:"VALIDATION_END_DATE_REQUIRED"`
+	// EarliestDate - first accepted month (MM-YYYY), zero disables the check
+	EarliestDate time.Time `mapstructure:"VALIDATION_EARLIEST_DATE"`
+	// MaxYearsAhead - how far into the future dates may go, 0 disables the check
+	MaxYearsAhead int `mapstructure:"VALIDATION_MAX_YEARS_AHEAD"`
+}
+
+// AnomalyConfig - structure with fields about the spending anomaly detection job
+type AnomalyConfig struct {
+	// Interval - how often spending is checked, 0 disables the job
+	Interval time.Duration `mapstructure:"ANOMALY_CHECK_INTERVAL"`
+	// BaselineMonths - previous months averaged into a user's baseline spend
+	BaselineMonths int `mapstructure:"ANOMALY_BASELINE_MONTHS"`
+	// ThresholdPercent - deviation from the baseline, in percent, reported as an anomaly
+	ThresholdPercent float64 `mapstructure:"ANOMALY_THRESHOLD_PERCENT"`
+}
+
+// BenchmarkConfig - structure with fields about anonymous cross-user price statistics
+type BenchmarkConfig struct {
+	// MinUsers - distinct opted-in users a service needs before its statistics are shown
+	MinUsers int `mapstructure:"BENCHMARK_MIN_USERS"`
+}
+
+// WebhookConfig - structure with fields about outgoing subscription event webhooks
+type WebhookConfig struct {
+	// URL - endpoint receiving subscription events, empty disables webhooks
+	URL string `mapstructure:"WEBHOOK_URL"`
+	// Format - payload shape: "envelope" (nested data) or "simple" (flat, for Zapier/IFTTT)
+	Format string `mapstructure:"WEBHOOK_FORMAT"`
+	// Secret - HMAC-SHA256 key of the X-Webhook-Signature header, empty sends unsigned requests
+	Secret  string        `mapstructure:"WEBHOOK_SECRET"`
+	Timeout time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+	// UserEnabled - lets users register a webhook of their own receiving only their events
+	UserEnabled bool `mapstructure:"WEBHOOK_USER_ENABLED"`
+	// UserLimit - delivery attempts to the webhook of one user per hour, tests included
+	UserLimit int `mapstructure:"WEBHOOK_USER_LIMIT"`
+	// UserPrivate - allows user webhooks on loopback and private addresses, for self-hosted installs
+	UserPrivate bool `mapstructure:"WEBHOOK_USER_ALLOW_PRIVATE"`
+}
+
+// EventsConfig - structure with fields about the event bus and the brokers it publishes to
+type EventsConfig struct {
+	// QueueSize - events waiting for delivery per subscriber before new ones are dropped
+	QueueSize int           `mapstructure:"EVENTS_QUEUE_SIZE"`
+	Timeout   time.Duration `mapstructure:"EVENTS_TIMEOUT"`
+	// NATSURL - nats://[user:pass@]host:port of the NATS server, empty disables NATS
+	NATSURL string `mapstructure:"EVENTS_NATS_URL"`
+	// NATSSubject - subject prefix, events go to "<prefix>.<event type>"
+	NATSSubject string `mapstructure:"EVENTS_NATS_SUBJECT"`
+	// KafkaRESTURL - base URL of a Confluent REST Proxy, empty disables Kafka
+	KafkaRESTURL string `mapstructure:"EVENTS_KAFKA_REST_URL"`
+	KafkaTopic   string `mapstructure:"EVENTS_KAFKA_TOPIC"`
+	// ChangesBuffer - latest changes kept for /subscriptions/changes long-polls, 0 disables the endpoint
+	ChangesBuffer int `mapstructure:"EVENTS_CHANGES_BUFFER"`
+}
+
+// StripeConfig - structure with fields about the Stripe subscription sync
+type StripeConfig struct {
+	// APIKey - secret or restricted key with read access to subscriptions and products, empty disables the sync
+	APIKey string `mapstructure:"STRIPE_API_KEY"`
+	// UserID - tracker user receiving the synced subscriptions, required with APIKey
+	UserID string `mapstructure:"STRIPE_USER_ID"`
+	// Customer - only sync subscriptions of this customer (cus_...), empty syncs the whole account
+	Customer string `mapstructure:"STRIPE_CUSTOMER"`
+	// WebhookSecret - signing secret (whsec_...) of /api/v1/integrations/stripe, empty disables webhooks
+	WebhookSecret string        `mapstructure:"STRIPE_WEBHOOK_SECRET"`
+	SyncInterval  time.Duration `mapstructure:"STRIPE_SYNC_INTERVAL"`
+}
+
+// ArchiveConfig - structure with fields about moving long-ended subscriptions to S3
+type ArchiveConfig struct {
+	// Bucket - S3 bucket of the Parquet files, empty disables archiving
+	Bucket string `mapstructure:"ARCHIVE_S3_BUCKET"`
+	// Endpoint - S3-compatible endpoint such as MinIO, empty means AWS in Region
+	Endpoint  string `mapstructure:"ARCHIVE_S3_ENDPOINT"`
+	Region    string `mapstructure:"ARCHIVE_S3_REGION"`
+	AccessKey string `mapstructure:"ARCHIVE_S3_ACCESS_KEY"`
+	SecretKey string `mapstructure:"ARCHIVE_S3_SECRET_KEY"`
+	Prefix    string `mapstructure:"ARCHIVE_PREFIX"`
+	// AfterYears - archive subscriptions that ended more than this many years ago
+	AfterYears int           `mapstructure:"ARCHIVE_AFTER_YEARS"`
+	Interval   time.Duration `mapstructure:"ARCHIVE_INTERVAL"`
+	BatchSize  int           `mapstructure:"ARCHIVE_BATCH_SIZE"`
+}
+
+// BackupConfig - structure with fields about nightly logical backups
+type BackupConfig struct {
+	// Dir - local directory (e.g. a mounted volume) receiving the backups
+	Dir string `mapstructure:"BACKUP_DIR"`
+	// Bucket - S3 bucket receiving the backups instead of Dir; both empty disable backups
+	Bucket    string `mapstructure:"BACKUP_S3_BUCKET"`
+	Endpoint  string `mapstructure:"BACKUP_S3_ENDPOINT"`
+	Region    string `mapstructure:"BACKUP_S3_REGION"`
+	AccessKey string `mapstructure:"BACKUP_S3_ACCESS_KEY"`
+	SecretKey string `mapstructure:"BACKUP_S3_SECRET_KEY"`
+	Prefix    string `mapstructure:"BACKUP_PREFIX"`
+	// At - UTC time of day as an offset from midnight, set as HH:MM
+	At time.Duration `mapstructure:"BACKUP_TIME"`
+	// Retention - how many backups are kept
+	Retention int `mapstructure:"BACKUP_RETENTION"`
+}
+
+// ReadModelConfig - structure with fields about the analytics read model
+type ReadModelConfig struct {
+	// Enabled - serve per-user spend totals and anomaly detection from the read model
+	Enabled bool `mapstructure:"READ_MODEL_ENABLED"`
+	// DSN - postgres:// URL of the read model database, empty uses the main one
+	DSN             string        `mapstructure:"READ_MODEL_PG_DSN"`
+	RebuildInterval time.Duration `mapstructure:"READ_MODEL_REBUILD_INTERVAL"`
+}
+
+// SnapshotConfig - structure with fields about user data snapshots
+type SnapshotConfig struct {
+	// Secret - HMAC key signing exported snapshots, shared by the instances they move between; empty disables them
+	Secret string `mapstructure:"SNAPSHOT_SECRET"`
+}
+
+// ShareConfig - structure with fields about read-only public links to a user's summary
+type ShareConfig struct {
+	// MaxTTL - longest lifetime a share link may be created with
+	MaxTTL time.Duration `mapstructure:"SHARE_MAX_TTL"`
+}
+
+// JobsConfig - structure with fields about running scheduled jobs on several replicas
+type JobsConfig struct {
+	// LeaderElection - run every scheduled job on one instance at a time, elected with Postgres advisory locks
+	LeaderElection bool `mapstructure:"JOBS_LEADER_ELECTION"`
+	// LeaderInterval - how often a waiting instance retries a job lock and its holder checks it is still held
+	LeaderInterval time.Duration `mapstructure:"JOBS_LEADER_INTERVAL"`
+}
+
+// TableGrowthConfig - structure with fields about watching the growth of database tables
+type TableGrowthConfig struct {
+	// Interval - how often table sizes are read, 0 disables the job
+	Interval time.Duration `mapstructure:"TABLE_GROWTH_INTERVAL"`
+	// MaxRowsPerHour - rows a table may gain per hour before an alert, 0 disables the rate alert
+	MaxRowsPerHour int64 `mapstructure:"TABLE_GROWTH_MAX_ROWS_PER_HOUR"`
+	// MaxBytes - soft cap of a table's size in bytes before an alert, 0 disables the size alert
+	MaxBytes int64 `mapstructure:"TABLE_GROWTH_MAX_BYTES"`
+}
+
+// UsersConfig - structure with fields about managing users
+type UsersConfig struct {
+	// DeletePolicy - what deleting a user does to their subscriptions: block while any has not ended, cascade
+	// or anonymize
+	DeletePolicy string `mapstructure:"USER_DELETE_POLICY"`
+	// ActivityEnabled - keep the feed of subscription changes of every user in the user_activity table
+	ActivityEnabled bool `mapstructure:"USER_ACTIVITY_ENABLED"`
+}
+
+// IDsConfig - structure with fields about identifying subscriptions to clients
+type IDsConfig struct {
+	// Strategy - what clients see as subscription IDs: serial, public uuidv7 or ulid IDs that are not
+	// enumerable and do not collide across shards, or hashid obfuscating the serial IDs
+	Strategy string `mapstructure:"SUBSCRIPTION_ID_STRATEGY"`
+	// HashidSecret - salt of the hashids, required with the hashid strategy; changing it changes every ID
+	// clients know
+	HashidSecret string `mapstructure:"SUBSCRIPTION_HASHID_SECRET"`
+	// HashidMinLength - shortest hashid, shorter ones are padded
+	HashidMinLength int `mapstructure:"SUBSCRIPTION_HASHID_MIN_LENGTH"`
+}
+
+// AnalyticsConfig - structure with fields about the anonymous usage counts of API endpoints
+type AnalyticsConfig struct {
+	// Sink - where the counts go: none (the default, nothing is counted) or posthog
+	Sink string `mapstructure:"ANALYTICS_SINK"`
+	// PostHogKey - project API key (phc_...), required with the posthog sink
+	PostHogKey string `mapstructure:"ANALYTICS_POSTHOG_KEY"`
+	// PostHogHost - ingestion URL of PostHog Cloud or a self-hosted instance
+	PostHogHost string `mapstructure:"ANALYTICS_POSTHOG_HOST"`
+	// FlushInterval - how often each instance sends its counts
+	FlushInterval time.Duration `mapstructure:"ANALYTICS_FLUSH_INTERVAL"`
+}
+
+// RequestAuditConfig - structure with fields about recording who sent which write request
+type RequestAuditConfig struct {
+	// Enabled - store the body hash and source of every write request in the request_audit table
+	Enabled bool `mapstructure:"REQUEST_AUDIT_ENABLED"`
+	// Retention - how long entries are kept, 0 keeps them forever
+	Retention time.Duration `mapstructure:"REQUEST_AUDIT_RETENTION"`
+}
+
+// SandboxConfig - structure with fields about the tenant integrators test against
+type SandboxConfig struct {
+	// Tenant - tenant whose schema can be reset to the seed through POST /api/v1/sandbox/reset; empty - no sandbox
+	Tenant string `mapstructure:"SANDBOX_TENANT"`
+	// SeedFile - JSON file of the subscriptions a reset leaves, empty - the built-in ones
+	SeedFile string `mapstructure:"SANDBOX_SEED_FILE"`
+}
+
+// LoadShedConfig - structure with fields about turning low-priority requests away while the database is overloaded
+type LoadShedConfig struct {
+	// Enabled - shed the exports and cost reports while a pool is over a threshold
+	Enabled bool `mapstructure:"LOAD_SHED_ENABLED"`
+	// Interval - how often the pools are sampled
+	Interval time.Duration `mapstructure:"LOAD_SHED_INTERVAL"`
+	// MaxAcquireWait - average wait for a connection over an interval, 0 - not checked
+	MaxAcquireWait time.Duration `mapstructure:"LOAD_SHED_MAX_ACQUIRE_WAIT"`
+	// MaxPoolUsage - share of the connections of a pool in use, 0..1, 0 - not checked
+	MaxPoolUsage float64 `mapstructure:"LOAD_SHED_MAX_POOL_USAGE"`
+}
+
+// ReconcileConfig - structure with fields about reconciling subscriptions with the charges of bank statements
+type ReconcileConfig struct {
+	// Interval - how often the complete months are reconciled, 0 disables the job and keeps no charges
+	Interval time.Duration `mapstructure:"RECONCILE_INTERVAL"`
+	// Months - complete months before the current one that are reconciled
+	Months int `mapstructure:"RECONCILE_MONTHS"`
+}
+
+// ImportConfig - structure with fields about confirming imports
+type ImportConfig struct {
+	// MaxRows - rows a single confirmation may create, larger ones are rejected
+	MaxRows int `mapstructure:"IMPORT_MAX_ROWS"`
+	// ChunkSize - rows saved in one transaction
+	ChunkSize int `mapstructure:"IMPORT_CHUNK_SIZE"`
+	// QuarantineEnabled - hold the rows failing validation for the user to fix instead of failing the import
+	QuarantineEnabled bool `mapstructure:"IMPORT_QUARANTINE_ENABLED"`
+}
+
+// TracingConfig - structure with fields about exporting request traces
+type TracingConfig struct {
+	// Endpoint - OTLP/HTTP URL of the trace collector, e.g. http://otel-collector:4318; empty disables tracing
+	Endpoint string `mapstructure:"TRACING_OTLP_ENDPOINT"`
+	// SampleRatio - share of new traces kept, 0..1; traces started by the caller follow its decision
+	SampleRatio float64 `mapstructure:"TRACING_SAMPLE_RATIO"`
+}
+
+// PseudonymConfig - structure with fields about storing user IDs as pseudonyms
+type PseudonymConfig struct {
+	// Key - base64 HMAC key deriving the stored pseudonyms, empty stores real user IDs
+	Key string `mapstructure:"USER_PSEUDONYM_KEY"`
+	// EncryptionKeys - id:base64key keyring sealing the pseudonym lookup table, the first key is primary
+	EncryptionKeys string `mapstructure:"USER_PSEUDONYM_ENCRYPTION_KEYS"`
+}
+
+// DatesConfig - structure with fields about accepted date formats
+type DatesConfig struct {
+	Layouts []string `mapstructure:"DATE_LAYOUTS"`
+	Strict  bool     `mapstructure:"DATE_STRICT"`
+	Locale  string   `mapstructure:"DATE_LOCALE"`
 }
 
 // LoadConfig - load config from ENV_FILE if present, falling back to the environment
 func LoadConfig() (*Config, error) {
 	cfg := &Config{
-		Env: "local",
+		Env:             "local",
+		ShutdownTimeout: 10 * time.Second,
+		Log: LogConfig{
+			Redact:          true,
+			SensitiveParams: []string{"token", "api_key", "secret", "password"},
+			BodyMaxBytes:    4096,
+		},
 		Server: ServerConfig{
-			Host:    "0.0.0.0",
-			Port:    8080,
-			Timeout: 5 * time.Second,
+			Hosts:         []string{"0.0.0.0"},
+			Port:          8080,
+			Timeout:       5 * time.Second,
+			CostNowTTL:    30 * time.Second,
+			AbuseWindow:   time.Minute,
+			DefaultPeriod: "all",
 		},
 		Pg: PgConfig{
-			Host:     "postgres",
-			Port:     5432,
-			User:     "subs_user",
-			Password: "subs_password",
-			Db:       "subs_db",
-			SSLMode:  "disable",
+			Host:           "postgres",
+			Port:           5432,
+			User:           "subs_user",
+			Password:       "subs_password",
+			Db:             "subs_db",
+			SSLMode:        "disable",
+			ConnectTimeout: 5 * time.Second,
+		},
+		Metrics: MetricsConfig{
+			RefreshInterval: time.Minute,
+		},
+		Enrich: EnrichConfig{
+			CacheTTL: 24 * time.Hour,
+			Timeout:  2 * time.Second,
+		},
+		Validation: ValidationConfig{
+			EarliestDate:  time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
+			MaxYearsAhead: 20,
+		},
+		Anomaly: AnomalyConfig{
+			Interval:         time.Hour,
+			BaselineMonths:   3,
+			ThresholdPercent: 50,
+		},
+		Benchmark: BenchmarkConfig{
+			MinUsers: 5,
+		},
+		Webhook: WebhookConfig{
+			Format:    "envelope",
+			Timeout:   5 * time.Second,
+			UserLimit: 60,
+		},
+		Events: EventsConfig{
+			QueueSize:     256,
+			Timeout:       5 * time.Second,
+			NATSSubject:   "subs_tracker",
+			KafkaTopic:    "subscriptions",
+			ChangesBuffer: 1024,
+		},
+		Stripe: StripeConfig{
+			SyncInterval: time.Hour,
+		},
+		Archive: ArchiveConfig{
+			Region:     "us-east-1",
+			Prefix:     "archive/subscriptions/",
+			AfterYears: 3,
+			Interval:   24 * time.Hour,
+			BatchSize:  10_000,
+		},
+		Backup: BackupConfig{
+			Region:    "us-east-1",
+			Prefix:    "backups/",
+			At:        2 * time.Hour,
+			Retention: 7,
+		},
+		ReadModel: ReadModelConfig{
+			RebuildInterval: time.Hour,
+		},
+		Share: ShareConfig{
+			MaxTTL: 90 * 24 * time.Hour,
+		},
+		Jobs: JobsConfig{
+			LeaderElection: true,
+			LeaderInterval: 5 * time.Second,
+		},
+		Tracing: TracingConfig{
+			SampleRatio: 1,
+		},
+		TableGrowth: TableGrowthConfig{
+			Interval:       15 * time.Minute,
+			MaxRowsPerHour: 100000,
+		},
+		Users: UsersConfig{
+			DeletePolicy: "block",
+		},
+		IDs: IDsConfig{
+			Strategy:        "serial",
+			HashidMinLength: 8,
+		},
+		Analytics: AnalyticsConfig{
+			Sink:          "none",
+			PostHogHost:   "https://us.i.posthog.com",
+			FlushInterval: time.Minute,
+		},
+		RequestAudit: RequestAuditConfig{
+			Retention: 90 * 24 * time.Hour,
+		},
+		LoadShed: LoadShedConfig{
+			Interval:       time.Second,
+			MaxAcquireWait: 100 * time.Millisecond,
+			MaxPoolUsage:   0.9,
+		},
+		Reconcile: ReconcileConfig{
+			Months: 3,
+		},
+		Import: ImportConfig{
+			MaxRows:   1000,
+			ChunkSize: 100,
 		},
 	}
 
@@ -102,8 +645,64 @@ func applyOverrides(cfg *Config, lookup func(string) (string, bool), source stri
 		cfg.Env = strings.TrimSpace(v)
 	}
 
+	if v, ok := lookup("APP_SHUTDOWN_TIMEOUT"); ok {
+		timeout, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s APP_SHUTDOWN_TIMEOUT: %w", source, err)
+		}
+		cfg.ShutdownTimeout = timeout
+	}
+
+	if v, ok := lookup("LOG_REDACT"); ok {
+		redact, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s LOG_REDACT: %w", source, err)
+		}
+		cfg.Log.Redact = redact
+	}
+
+	if v, ok := lookup("LOG_SENSITIVE_PARAMS"); ok {
+		cfg.Log.SensitiveParams = splitList(v)
+	}
+
+	if v, ok := lookup("LOG_BODY_SAMPLE_RATE"); ok {
+		rate, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return fmt.Errorf("parse %s LOG_BODY_SAMPLE_RATE: must be a number from 0 to 1, got %q", source, v)
+		}
+		cfg.Log.BodySampleRate = rate
+	}
+
+	if v, ok := lookup("LOG_BODY_ROUTES"); ok {
+		cfg.Log.BodyRoutes = splitList(v)
+	}
+
+	if v, ok := lookup("LOG_BODY_STATUSES"); ok {
+		var statuses []int
+		for _, s := range splitList(v) {
+			code, err := strconv.Atoi(s)
+			if err != nil || code < 100 || code > 599 {
+				return fmt.Errorf("parse %s LOG_BODY_STATUSES: invalid status code %q", source, s)
+			}
+			statuses = append(statuses, code)
+		}
+		cfg.Log.BodyStatuses = statuses
+	}
+
+	if v, ok := lookup("LOG_BODY_MAX_BYTES"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n <= 0 {
+			return fmt.Errorf("parse %s LOG_BODY_MAX_BYTES: must be a positive integer, got %q", source, v)
+		}
+		cfg.Log.BodyMaxBytes = n
+	}
+
 	if v, ok := lookup("HTTP_HOST"); ok {
-		cfg.Server.Host = strings.TrimSpace(v)
+		hosts := splitList(v)
+		for i, h := range hosts {
+			hosts[i] = strings.TrimSuffix(strings.TrimPrefix(h, "["), "]")
+		}
+		cfg.Server.Hosts = hosts
 	}
 
 	if v, ok := lookup("HTTP_PORT"); ok {
@@ -122,20 +721,224 @@ func applyOverrides(cfg *Config, lookup func(string) (string, bool), source stri
 		cfg.Server.Timeout = timeout
 	}
 
+	if v, ok := lookup("HTTP_CURSOR_SECRET"); ok {
+		cfg.Server.CursorSecret = strings.TrimSpace(v)
+	}
+
+	if v, ok := lookup("HTTP_REUSEPORT"); ok {
+		reuse, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s HTTP_REUSEPORT: %w", source, err)
+		}
+		cfg.Server.ReusePort = reuse
+	}
+
+	if v, ok := lookup("HTTP_DRAIN_DELAY"); ok {
+		delay, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s HTTP_DRAIN_DELAY: %w", source, err)
+		}
+		cfg.Server.DrainDelay = delay
+	}
+
+	if v, ok := lookup("HTTP_MAX_INFLIGHT"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s HTTP_MAX_INFLIGHT: %w", source, err)
+		}
+		cfg.Server.MaxInFlight = n
+	}
+
+	if v, ok := lookup("HTTP_READ_MAX_INFLIGHT"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s HTTP_READ_MAX_INFLIGHT: %w", source, err)
+		}
+		cfg.Server.ReadMaxInFlight = n
+	}
+
+	if v, ok := lookup("HTTP_WRITE_MAX_INFLIGHT"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s HTTP_WRITE_MAX_INFLIGHT: %w", source, err)
+		}
+		cfg.Server.WriteMaxInFlight = n
+	}
+
+	if v, ok := lookup("HTTP_EXPENSIVE_MAX_INFLIGHT"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s HTTP_EXPENSIVE_MAX_INFLIGHT: %w", source, err)
+		}
+		cfg.Server.ExpensiveMaxInFlight = n
+	}
+
+	if v, ok := lookup("HTTP_EXPENSIVE_RATE"); ok {
+		rate, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || rate < 0 {
+			return fmt.Errorf("parse %s HTTP_EXPENSIVE_RATE: must be a non-negative number, got %q", source, v)
+		}
+		cfg.Server.ExpensiveRate = rate
+	}
+
+	if v, ok := lookup("HTTP_EXPENSIVE_BURST"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s HTTP_EXPENSIVE_BURST: %w", source, err)
+		}
+		cfg.Server.ExpensiveBurst = n
+	}
+
+	if v, ok := lookup("HTTP_QUEUE_LENGTH"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s HTTP_QUEUE_LENGTH: %w", source, err)
+		}
+		cfg.Server.QueueLength = n
+	}
+
+	if v, ok := lookup("HTTP_QUEUE_TIMEOUT"); ok {
+		timeout, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s HTTP_QUEUE_TIMEOUT: %w", source, err)
+		}
+		cfg.Server.QueueTimeout = timeout
+	}
+
+	if v, ok := lookup("HTTP_ABUSE_WINDOW"); ok {
+		window, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || window < 0 {
+			return fmt.Errorf("parse %s HTTP_ABUSE_WINDOW: must be a non-negative duration, got %q", source, v)
+		}
+		cfg.Server.AbuseWindow = window
+	}
+
+	if v, ok := lookup("HTTP_ABUSE_MAX_REQUESTS"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 0 {
+			return fmt.Errorf("parse %s HTTP_ABUSE_MAX_REQUESTS: must be a non-negative integer, got %q", source, v)
+		}
+		cfg.Server.AbuseMaxRequests = n
+	}
+
+	if v, ok := lookup("HTTP_ABUSE_MAX_ERROR_RATE"); ok {
+		rate, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return fmt.Errorf("parse %s HTTP_ABUSE_MAX_ERROR_RATE: must be between 0 and 1, got %q", source, v)
+		}
+		cfg.Server.AbuseMaxErrorRate = rate
+	}
+
+	if v, ok := lookup("HTTP_ABUSE_BAN"); ok {
+		ban, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || ban < 0 {
+			return fmt.Errorf("parse %s HTTP_ABUSE_BAN: must be a non-negative duration, got %q", source, v)
+		}
+		cfg.Server.AbuseBan = ban
+	}
+
+	if v, ok := lookup("HTTP_COST_MAX_AGE"); ok {
+		age, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s HTTP_COST_MAX_AGE: %w", source, err)
+		}
+		cfg.Server.CostMaxAge = age
+	}
+
+	if v, ok := lookup("HTTP_COST_NOW_TTL"); ok {
+		ttl, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || ttl < 0 {
+			return fmt.Errorf("parse %s HTTP_COST_NOW_TTL: must be a non-negative duration, got %q", source, v)
+		}
+		cfg.Server.CostNowTTL = ttl
+	}
+
+	if v, ok := lookup("HTTP_COST_NOW_PRIME"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 0 {
+			return fmt.Errorf("parse %s HTTP_COST_NOW_PRIME: must be a non-negative integer, got %q", source, v)
+		}
+		cfg.Server.CostNowPrime = n
+	}
+
+	if v, ok := lookup("HTTP_REQUIRE_IF_MATCH"); ok {
+		require, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s HTTP_REQUIRE_IF_MATCH: %w", source, err)
+		}
+		cfg.Server.RequireIfMatch = require
+	}
+
+	if v, ok := lookup("HTTP_STRICT_PAGINATION"); ok {
+		strict, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s HTTP_STRICT_PAGINATION: %w", source, err)
+		}
+		cfg.Server.StrictPagination = strict
+	}
+
+	if v, ok := lookup("HTTP_LIST_MAX_BYTES"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 0 {
+			return fmt.Errorf("parse %s HTTP_LIST_MAX_BYTES: must be a non-negative integer, got %q", source, v)
+		}
+		cfg.Server.ListMaxBytes = n
+	}
+
+	if v, ok := lookup("HTTP_DEFAULT_PERIOD"); ok {
+		period := strings.ToLower(strings.TrimSpace(v))
+		if !defaultPeriods[period] {
+			return fmt.Errorf("parse %s HTTP_DEFAULT_PERIOD: want all, current_month or current_year", source)
+		}
+		cfg.Server.DefaultPeriod = period
+	}
+
+	if v, ok := lookup("HTTP_ADMIN_TOKEN"); ok {
+		cfg.Server.AdminToken = strings.TrimSpace(v)
+	}
+
+	if v, ok := lookup("HTTP_API_TOKENS"); ok {
+		tokens := splitList(v)
+		for i, t := range tokens {
+			scope, token, _ := strings.Cut(t, ":")
+			if !apiTokenScopes[strings.ToLower(strings.TrimSpace(scope))] || strings.TrimSpace(token) == "" {
+				// the entry itself is a secret and stays out of the error
+				return fmt.Errorf("parse %s HTTP_API_TOKENS: entry %d: want scope:token with scope read, write or admin", source, i+1)
+			}
+		}
+		cfg.Server.APITokens = tokens
+	}
+
+	if v, ok := lookup("HTTP_SPA_DIR"); ok {
+		dir := strings.TrimSpace(v)
+		if dir != "" {
+			if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+				return fmt.Errorf("parse %s HTTP_SPA_DIR: %q is not a directory", source, v)
+			}
+		}
+		cfg.Server.SPADir = dir
+	}
+
+	if v, ok := lookup("HTTP_CONTRACT_VALIDATION"); ok {
+		validate, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s HTTP_CONTRACT_VALIDATION: %w", source, err)
+		}
+		cfg.Server.ContractValidation = validate
+	}
+
 	if v, ok := lookup("HTTP_CORS_ORIGINS"); ok {
-		raw := strings.TrimSpace(v)
-		if raw == "" {
-			cfg.Server.CORSOrigins = nil
-		} else {
-			parts := strings.Split(raw, ",")
-			cors := make([]string, 0, len(parts))
-			for _, part := range parts {
-				if s := strings.TrimSpace(part); s != "" {
-					cors = append(cors, s)
-				}
+		cfg.Server.CORSOrigins = splitList(v)
+	}
+
+	if v, ok := lookup("HTTP_TRUSTED_PROXIES"); ok {
+		proxies := splitList(v)
+		for _, p := range proxies {
+			if _, _, err := net.ParseCIDR(p); err != nil && net.ParseIP(p) == nil {
+				return fmt.Errorf("parse %s HTTP_TRUSTED_PROXIES: %q is neither an IP nor a CIDR", source, p)
 			}
-			cfg.Server.CORSOrigins = cors
 		}
+		cfg.Server.TrustedProxies = proxies
 	}
 
 	if v, ok := lookup("POSTGRES_HOST"); ok {
@@ -166,5 +969,933 @@ func applyOverrides(cfg *Config, lookup func(string) (string, bool), source stri
 		cfg.Pg.SSLMode = strings.TrimSpace(v)
 	}
 
-	return nil
+	if v, ok := lookup("POSTGRES_EXPLAIN_THRESHOLD"); ok {
+		threshold, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s POSTGRES_EXPLAIN_THRESHOLD: %w", source, err)
+		}
+		cfg.Pg.ExplainThreshold = threshold
+	}
+
+	if v, ok := lookup("POSTGRES_CONNECT_TIMEOUT"); ok {
+		timeout, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s POSTGRES_CONNECT_TIMEOUT: %w", source, err)
+		}
+		cfg.Pg.ConnectTimeout = timeout
+	}
+
+	if v, ok := lookup("POSTGRES_APPLICATION_NAME"); ok {
+		cfg.Pg.ApplicationName = strings.TrimSpace(v)
+	}
+
+	if v, ok := lookup("POSTGRES_SEARCH_PATH"); ok {
+		cfg.Pg.SearchPath = strings.TrimSpace(v)
+	}
+
+	if v, ok := lookup("POSTGRES_TENANT_SCHEMAS"); ok {
+		tenants := splitList(v)
+		for _, t := range tenants {
+			if len(t) > maxSchemaTenant || !tenantPattern.MatchString(t) {
+				return fmt.Errorf("parse %s POSTGRES_TENANT_SCHEMAS: %q is not a tenant ID of up to %d letters, digits, dots, dashes and underscores", source, t, maxSchemaTenant)
+			}
+		}
+		cfg.Pg.TenantSchemas = tenants
+	}
+
+	if v, ok := lookup("POSTGRES_SHARD_DSNS"); ok {
+		dsns := splitList(v)
+		for _, dsn := range dsns {
+			u, err := url.Parse(dsn)
+			if err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") || u.Host == "" {
+				return fmt.Errorf("parse %s POSTGRES_SHARD_DSNS: want postgres:// URLs", source)
+			}
+		}
+		cfg.Pg.ShardDSNs = dsns
+	}
+
+	if v, ok := lookup("POSTGRES_MIGRATIONS_DIR"); ok {
+		dir := strings.TrimSpace(v)
+		if dir != "" {
+			if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+				return fmt.Errorf("parse %s POSTGRES_MIGRATIONS_DIR: %q is not a directory", source, v)
+			}
+		}
+		cfg.Pg.MigrationsDir = dir
+	}
+
+	if v, ok := lookup("POSTGRES_MAX_CONNS"); ok {
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 32)
+		if err != nil || n < 0 {
+			return fmt.Errorf("parse %s POSTGRES_MAX_CONNS: want a non-negative integer", source)
+		}
+		cfg.Pg.MaxConns = int32(n)
+	}
+
+	if v, ok := lookup("POSTGRES_MIN_CONNS"); ok {
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 32)
+		if err != nil || n < 0 {
+			return fmt.Errorf("parse %s POSTGRES_MIN_CONNS: want a non-negative integer", source)
+		}
+		if cfg.Pg.MaxConns > 0 && int32(n) > cfg.Pg.MaxConns {
+			return fmt.Errorf("parse %s POSTGRES_MIN_CONNS: %d exceeds POSTGRES_MAX_CONNS %d", source, n, cfg.Pg.MaxConns)
+		}
+		cfg.Pg.MinConns = int32(n)
+	}
+
+	if v, ok := lookup("POSTGRES_STATEMENT_CACHE"); ok {
+		mode := strings.ToLower(strings.TrimSpace(v))
+		if mode != "" && !statementCacheModes[mode] {
+			return fmt.Errorf("parse %s POSTGRES_STATEMENT_CACHE: want prepare, describe, exec or simple", source)
+		}
+		cfg.Pg.StatementCache = mode
+	}
+
+	if v, ok := lookup("METRICS_REFRESH_INTERVAL"); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s METRICS_REFRESH_INTERVAL: %w", source, err)
+		}
+		cfg.Metrics.RefreshInterval = interval
+	}
+
+	if v, ok := lookup("METRICS_NAMESPACE"); ok {
+		cfg.Metrics.Namespace = strings.TrimSpace(v)
+	}
+
+	if v, ok := lookup("METRICS_INSTANCE"); ok {
+		cfg.Metrics.Instance = strings.TrimSpace(v)
+	}
+
+	if v, ok := lookup("METRICS_BUCKETS"); ok {
+		buckets, err := parseBuckets(v)
+		if err != nil {
+			return fmt.Errorf("parse %s METRICS_BUCKETS: %w", source, err)
+		}
+		cfg.Metrics.Buckets = buckets
+	}
+
+	if v, ok := lookup("METRICS_ROUTES"); ok {
+		routes := splitList(v)
+		for _, r := range routes {
+			if !strings.HasPrefix(r, "/") {
+				return fmt.Errorf("parse %s METRICS_ROUTES: want route templates starting with /, got %q", source, r)
+			}
+		}
+		cfg.Metrics.Routes = routes
+	}
+
+	if v, ok := lookup("DATE_LAYOUTS"); ok {
+		cfg.Dates.Layouts = splitList(v)
+	}
+
+	if v, ok := lookup("DATE_STRICT"); ok {
+		strict, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s DATE_STRICT: %w", source, err)
+		}
+		cfg.Dates.Strict = strict
+	}
+
+	if v, ok := lookup("DATE_LOCALE"); ok {
+		cfg.Dates.Locale = strings.ToLower(strings.TrimSpace(v))
+	}
+
+	if v, ok := lookup("ENRICH_URL"); ok {
+		cfg.Enrich.URL = strings.TrimSpace(v)
+	}
+
+	if v, ok := lookup("ENRICH_API_KEY"); ok {
+		cfg.Enrich.APIKey = strings.TrimSpace(v)
+	}
+
+	if v, ok := lookup("ENRICH_CACHE_TTL"); ok {
+		ttl, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s ENRICH_CACHE_TTL: %w", source, err)
+		}
+		cfg.Enrich.CacheTTL = ttl
+	}
+
+	if v, ok := lookup("ENRICH_TIMEOUT"); ok {
+		timeout, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s ENRICH_TIMEOUT: %w", source, err)
+		}
+		cfg.Enrich.Timeout = timeout
+	}
+
+	if v, ok := lookup("ENRICH_PRICE_CHECK_INTERVAL"); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s ENRICH_PRICE_CHECK_INTERVAL: %w", source, err)
+		}
+		cfg.Enrich.PriceCheckInterval = interval
+	}
+
+	if v, ok := lookup("INBOUND_MAILGUN_SIGNING_KEY"); ok {
+		cfg.Inbound.MailgunSigningKey = strings.TrimSpace(v)
+	}
+
+	if v, ok := lookup("INBOUND_INGEST_KEYS"); ok {
+		keys, err := parseIngestKeys(v)
+		if err != nil {
+			return fmt.Errorf("parse %s INBOUND_INGEST_KEYS: %w", source, err)
+		}
+		cfg.Inbound.IngestKeys = keys
+	}
+
+	if v, ok := lookup("VALIDATION_MAX_COST"); ok {
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return fmt.Errorf("parse %s VALIDATION_MAX_COST: %w", source, err)
+		}
+		cfg.Validation.MaxCost = n
+	}
+
+	if v, ok := lookup("VALIDATION_MAX_PERIOD_MONTHS"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s VALIDATION_MAX_PERIOD_MONTHS: %w", source, err)
+		}
+		cfg.Validation.MaxPeriodMonths = n
+	}
+
+	if v, ok := lookup("VALIDATION_SERVICE_NAME_PATTERN"); ok {
+		pattern := strings.TrimSpace(v)
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("parse %s VALIDATION_SERVICE_NAME_PATTERN: %w", source, err)
+		}
+		cfg.Validation.ServiceNamePattern = pattern
+	}
+
+	if v, ok := lookup("VALIDATION_END_DATE_REQUIRED"); ok {
+		cfg.Validation.EndDateRequired = splitList(v)
+	}
+
+	if v, ok := lookup("VALIDATION_EARLIEST_DATE"); ok {
+		var earliest time.Time
+		if raw := strings.TrimSpace(v); raw != "" {
+			t, err := time.Parse("01-2006", raw)
+			if err != nil {
+				return fmt.Errorf("parse %s VALIDATION_EARLIEST_DATE: %w", source, err)
+			}
+			earliest = t
+		}
+		cfg.Validation.EarliestDate = earliest
+	}
+
+	if v, ok := lookup("VALIDATION_MAX_YEARS_AHEAD"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s VALIDATION_MAX_YEARS_AHEAD: %w", source, err)
+		}
+		cfg.Validation.MaxYearsAhead = n
+	}
+
+	if v, ok := lookup("ANOMALY_CHECK_INTERVAL"); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s ANOMALY_CHECK_INTERVAL: %w", source, err)
+		}
+		cfg.Anomaly.Interval = interval
+	}
+
+	if v, ok := lookup("ANOMALY_BASELINE_MONTHS"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n <= 0 {
+			return fmt.Errorf("parse %s ANOMALY_BASELINE_MONTHS: must be a positive integer, got %q", source, v)
+		}
+		cfg.Anomaly.BaselineMonths = n
+	}
+
+	if v, ok := lookup("ANOMALY_THRESHOLD_PERCENT"); ok {
+		pct, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || pct <= 0 {
+			return fmt.Errorf("parse %s ANOMALY_THRESHOLD_PERCENT: must be a positive number, got %q", source, v)
+		}
+		cfg.Anomaly.ThresholdPercent = pct
+	}
+
+	if v, ok := lookup("BENCHMARK_MIN_USERS"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n <= 0 {
+			return fmt.Errorf("parse %s BENCHMARK_MIN_USERS: must be a positive integer, got %q", source, v)
+		}
+		cfg.Benchmark.MinUsers = n
+	}
+
+	if v, ok := lookup("WEBHOOK_URL"); ok {
+		raw := strings.TrimSpace(v)
+		if raw != "" {
+			u, err := url.Parse(raw)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("parse %s WEBHOOK_URL: must be an absolute http(s) URL, got %q", source, v)
+			}
+		}
+		cfg.Webhook.URL = raw
+	}
+
+	if v, ok := lookup("WEBHOOK_FORMAT"); ok {
+		format := strings.ToLower(strings.TrimSpace(v))
+		switch format {
+		case "":
+			format = "envelope"
+		case "envelope", "simple":
+		default:
+			return fmt.Errorf("parse %s WEBHOOK_FORMAT: must be envelope or simple, got %q", source, v)
+		}
+		cfg.Webhook.Format = format
+	}
+
+	if v, ok := lookup("WEBHOOK_SECRET"); ok {
+		cfg.Webhook.Secret = strings.TrimSpace(v)
+	}
+
+	if v, ok := lookup("WEBHOOK_TIMEOUT"); ok {
+		timeout, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s WEBHOOK_TIMEOUT: %w", source, err)
+		}
+		cfg.Webhook.Timeout = timeout
+	}
+
+	if v, ok := lookup("WEBHOOK_USER_ENABLED"); ok {
+		enabled, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s WEBHOOK_USER_ENABLED: %w", source, err)
+		}
+		cfg.Webhook.UserEnabled = enabled
+	}
+
+	if v, ok := lookup("WEBHOOK_USER_LIMIT"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n <= 0 {
+			return fmt.Errorf("parse %s WEBHOOK_USER_LIMIT: must be a positive integer, got %q", source, v)
+		}
+		cfg.Webhook.UserLimit = n
+	}
+
+	if v, ok := lookup("WEBHOOK_USER_ALLOW_PRIVATE"); ok {
+		allow, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s WEBHOOK_USER_ALLOW_PRIVATE: %w", source, err)
+		}
+		cfg.Webhook.UserPrivate = allow
+	}
+
+	if v, ok := lookup("EVENTS_QUEUE_SIZE"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n <= 0 {
+			return fmt.Errorf("parse %s EVENTS_QUEUE_SIZE: must be a positive integer, got %q", source, v)
+		}
+		cfg.Events.QueueSize = n
+	}
+
+	if v, ok := lookup("EVENTS_TIMEOUT"); ok {
+		timeout, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s EVENTS_TIMEOUT: %w", source, err)
+		}
+		cfg.Events.Timeout = timeout
+	}
+
+	if v, ok := lookup("EVENTS_NATS_URL"); ok {
+		raw := strings.TrimSpace(v)
+		if raw != "" {
+			u, err := url.Parse(raw)
+			if err != nil || u.Scheme != "nats" || u.Host == "" {
+				return fmt.Errorf("parse %s EVENTS_NATS_URL: must be nats://host:port, got %q", source, v)
+			}
+		}
+		cfg.Events.NATSURL = raw
+	}
+
+	if v, ok := lookup("EVENTS_NATS_SUBJECT"); ok {
+		subject := strings.Trim(strings.TrimSpace(v), ".")
+		if subject == "" || strings.ContainsAny(subject, " \t*>") {
+			return fmt.Errorf("parse %s EVENTS_NATS_SUBJECT: must be a subject without spaces or wildcards, got %q", source, v)
+		}
+		cfg.Events.NATSSubject = subject
+	}
+
+	if v, ok := lookup("EVENTS_KAFKA_REST_URL"); ok {
+		raw := strings.TrimSpace(v)
+		if raw != "" {
+			u, err := url.Parse(raw)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("parse %s EVENTS_KAFKA_REST_URL: must be an absolute http(s) URL, got %q", source, v)
+			}
+		}
+		cfg.Events.KafkaRESTURL = raw
+	}
+
+	if v, ok := lookup("EVENTS_KAFKA_TOPIC"); ok {
+		if topic := strings.TrimSpace(v); topic != "" {
+			cfg.Events.KafkaTopic = topic
+		}
+	}
+
+	if v, ok := lookup("EVENTS_CHANGES_BUFFER"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 0 {
+			return fmt.Errorf("parse %s EVENTS_CHANGES_BUFFER: must be a non-negative integer, got %q", source, v)
+		}
+		cfg.Events.ChangesBuffer = n
+	}
+
+	if v, ok := lookup("STRIPE_API_KEY"); ok {
+		cfg.Stripe.APIKey = strings.TrimSpace(v)
+	}
+
+	if v, ok := lookup("STRIPE_USER_ID"); ok {
+		id := strings.TrimSpace(v)
+		if id != "" {
+			if _, err := uuid.Parse(id); err != nil {
+				return fmt.Errorf("parse %s STRIPE_USER_ID: %w", source, err)
+			}
+		}
+		cfg.Stripe.UserID = id
+	}
+
+	if v, ok := lookup("STRIPE_CUSTOMER"); ok {
+		cfg.Stripe.Customer = strings.TrimSpace(v)
+	}
+
+	if v, ok := lookup("STRIPE_WEBHOOK_SECRET"); ok {
+		cfg.Stripe.WebhookSecret = strings.TrimSpace(v)
+	}
+
+	if v, ok := lookup("STRIPE_SYNC_INTERVAL"); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s STRIPE_SYNC_INTERVAL: %w", source, err)
+		}
+		cfg.Stripe.SyncInterval = interval
+	}
+
+	if cfg.Stripe.APIKey != "" && cfg.Stripe.UserID == "" {
+		return fmt.Errorf("parse %s STRIPE_USER_ID: required when STRIPE_API_KEY is set", source)
+	}
+
+	if v, ok := lookup("ARCHIVE_S3_BUCKET"); ok {
+		cfg.Archive.Bucket = strings.TrimSpace(v)
+	}
+
+	if v, ok := lookup("ARCHIVE_S3_ENDPOINT"); ok {
+		endpoint := strings.TrimSpace(v)
+		if endpoint != "" {
+			u, err := url.Parse(endpoint)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("parse %s ARCHIVE_S3_ENDPOINT: want an http(s) URL, got %q", source, v)
+			}
+		}
+		cfg.Archive.Endpoint = endpoint
+	}
+
+	if v, ok := lookup("ARCHIVE_S3_REGION"); ok {
+		if region := strings.TrimSpace(v); region != "" {
+			cfg.Archive.Region = region
+		}
+	}
+
+	if v, ok := lookup("ARCHIVE_S3_ACCESS_KEY"); ok {
+		cfg.Archive.AccessKey = strings.TrimSpace(v)
+	}
+
+	if v, ok := lookup("ARCHIVE_S3_SECRET_KEY"); ok {
+		cfg.Archive.SecretKey = strings.TrimSpace(v)
+	}
+
+	if v, ok := lookup("ARCHIVE_PREFIX"); ok {
+		if prefix := strings.TrimSpace(v); prefix != "" {
+			cfg.Archive.Prefix = prefix
+		}
+	}
+
+	if v, ok := lookup("ARCHIVE_AFTER_YEARS"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n <= 0 {
+			return fmt.Errorf("parse %s ARCHIVE_AFTER_YEARS: must be a positive integer, got %q", source, v)
+		}
+		cfg.Archive.AfterYears = n
+	}
+
+	if v, ok := lookup("ARCHIVE_INTERVAL"); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || interval <= 0 {
+			return fmt.Errorf("parse %s ARCHIVE_INTERVAL: must be a positive duration, got %q", source, v)
+		}
+		cfg.Archive.Interval = interval
+	}
+
+	if v, ok := lookup("ARCHIVE_BATCH_SIZE"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n <= 0 {
+			return fmt.Errorf("parse %s ARCHIVE_BATCH_SIZE: must be a positive integer, got %q", source, v)
+		}
+		cfg.Archive.BatchSize = n
+	}
+
+	if v, ok := lookup("BACKUP_DIR"); ok {
+		cfg.Backup.Dir = strings.TrimSpace(v)
+	}
+
+	if v, ok := lookup("BACKUP_S3_BUCKET"); ok {
+		cfg.Backup.Bucket = strings.TrimSpace(v)
+	}
+
+	if v, ok := lookup("BACKUP_S3_ENDPOINT"); ok {
+		endpoint := strings.TrimSpace(v)
+		if endpoint != "" {
+			u, err := url.Parse(endpoint)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("parse %s BACKUP_S3_ENDPOINT: want an http(s) URL, got %q", source, v)
+			}
+		}
+		cfg.Backup.Endpoint = endpoint
+	}
+
+	if v, ok := lookup("BACKUP_S3_REGION"); ok {
+		if region := strings.TrimSpace(v); region != "" {
+			cfg.Backup.Region = region
+		}
+	}
+
+	if v, ok := lookup("BACKUP_S3_ACCESS_KEY"); ok {
+		cfg.Backup.AccessKey = strings.TrimSpace(v)
+	}
+
+	if v, ok := lookup("BACKUP_S3_SECRET_KEY"); ok {
+		cfg.Backup.SecretKey = strings.TrimSpace(v)
+	}
+
+	if v, ok := lookup("BACKUP_PREFIX"); ok {
+		if prefix := strings.TrimSpace(v); prefix != "" {
+			cfg.Backup.Prefix = prefix
+		}
+	}
+
+	if v, ok := lookup("BACKUP_TIME"); ok {
+		at, err := time.Parse("15:04", strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s BACKUP_TIME: want HH:MM, got %q", source, v)
+		}
+		cfg.Backup.At = time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute
+	}
+
+	if v, ok := lookup("BACKUP_RETENTION"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n <= 0 {
+			return fmt.Errorf("parse %s BACKUP_RETENTION: must be a positive integer, got %q", source, v)
+		}
+		cfg.Backup.Retention = n
+	}
+
+	if cfg.Backup.Dir != "" && cfg.Backup.Bucket != "" {
+		return fmt.Errorf("parse %s BACKUP_DIR: set either BACKUP_DIR or BACKUP_S3_BUCKET, not both", source)
+	}
+
+	if v, ok := lookup("USER_PSEUDONYM_KEY"); ok {
+		key := strings.TrimSpace(v)
+		if key != "" {
+			raw, err := base64.StdEncoding.DecodeString(key)
+			if err != nil || len(raw) < 32 {
+				return fmt.Errorf("parse %s USER_PSEUDONYM_KEY: want at least 32 base64-encoded bytes", source)
+			}
+		}
+		cfg.Pseudonym.Key = key
+	}
+
+	if v, ok := lookup("USER_PSEUDONYM_ENCRYPTION_KEYS"); ok {
+		keys := strings.TrimSpace(v)
+		if keys != "" {
+			if _, err := fieldcrypt.ParseKeyring(keys); err != nil {
+				return fmt.Errorf("parse %s USER_PSEUDONYM_ENCRYPTION_KEYS: %w", source, err)
+			}
+		}
+		cfg.Pseudonym.EncryptionKeys = keys
+	}
+
+	if cfg.Pseudonym.Key != "" && cfg.Pseudonym.EncryptionKeys == "" {
+		return fmt.Errorf("parse %s USER_PSEUDONYM_ENCRYPTION_KEYS: required with USER_PSEUDONYM_KEY", source)
+	}
+
+	if v, ok := lookup("READ_MODEL_ENABLED"); ok {
+		enabled, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s READ_MODEL_ENABLED: %w", source, err)
+		}
+		cfg.ReadModel.Enabled = enabled
+	}
+
+	if v, ok := lookup("READ_MODEL_PG_DSN"); ok {
+		dsn := strings.TrimSpace(v)
+		if dsn != "" {
+			u, err := url.Parse(dsn)
+			if err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") || u.Host == "" {
+				return fmt.Errorf("parse %s READ_MODEL_PG_DSN: want a postgres:// URL", source)
+			}
+		}
+		cfg.ReadModel.DSN = dsn
+	}
+
+	if v, ok := lookup("READ_MODEL_REBUILD_INTERVAL"); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || interval <= 0 {
+			return fmt.Errorf("parse %s READ_MODEL_REBUILD_INTERVAL: must be a positive duration, got %q", source, v)
+		}
+		cfg.ReadModel.RebuildInterval = interval
+	}
+
+	if cfg.ReadModel.Enabled && cfg.Pseudonym.Key != "" {
+		return fmt.Errorf("parse %s READ_MODEL_ENABLED: the read model would store real user IDs, not supported with USER_PSEUDONYM_KEY", source)
+	}
+
+	if v, ok := lookup("SNAPSHOT_SECRET"); ok {
+		secret := strings.TrimSpace(v)
+		if secret != "" && len(secret) < 16 {
+			return fmt.Errorf("parse %s SNAPSHOT_SECRET: must be at least 16 bytes", source)
+		}
+		cfg.Snapshot.Secret = secret
+	}
+
+	if v, ok := lookup("SHARE_MAX_TTL"); ok {
+		ttl, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || ttl <= 0 {
+			return fmt.Errorf("parse %s SHARE_MAX_TTL: must be a positive duration, got %q", source, v)
+		}
+		cfg.Share.MaxTTL = ttl
+	}
+
+	if v, ok := lookup("JOBS_LEADER_ELECTION"); ok {
+		enabled, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s JOBS_LEADER_ELECTION: %w", source, err)
+		}
+		cfg.Jobs.LeaderElection = enabled
+	}
+
+	if v, ok := lookup("JOBS_LEADER_INTERVAL"); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || interval <= 0 {
+			return fmt.Errorf("parse %s JOBS_LEADER_INTERVAL: must be a positive duration, got %q", source, v)
+		}
+		cfg.Jobs.LeaderInterval = interval
+	}
+
+	if v, ok := lookup("TRACING_OTLP_ENDPOINT"); ok {
+		raw := strings.TrimSpace(v)
+		if raw != "" {
+			u, err := url.Parse(raw)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("parse %s TRACING_OTLP_ENDPOINT: must be an absolute http(s) URL, got %q", source, v)
+			}
+		}
+		cfg.Tracing.Endpoint = raw
+	}
+
+	if v, ok := lookup("TRACING_SAMPLE_RATIO"); ok {
+		ratio, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return fmt.Errorf("parse %s TRACING_SAMPLE_RATIO: must be between 0 and 1, got %q", source, v)
+		}
+		cfg.Tracing.SampleRatio = ratio
+	}
+
+	if v, ok := lookup("TABLE_GROWTH_INTERVAL"); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || interval < 0 {
+			return fmt.Errorf("parse %s TABLE_GROWTH_INTERVAL: must be a duration, 0 to disable, got %q", source, v)
+		}
+		cfg.TableGrowth.Interval = interval
+	}
+
+	if v, ok := lookup("TABLE_GROWTH_MAX_ROWS_PER_HOUR"); ok {
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil || n < 0 {
+			return fmt.Errorf("parse %s TABLE_GROWTH_MAX_ROWS_PER_HOUR: must be a non-negative integer, got %q", source, v)
+		}
+		cfg.TableGrowth.MaxRowsPerHour = n
+	}
+
+	if v, ok := lookup("TABLE_GROWTH_MAX_BYTES"); ok {
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil || n < 0 {
+			return fmt.Errorf("parse %s TABLE_GROWTH_MAX_BYTES: must be a non-negative integer, got %q", source, v)
+		}
+		cfg.TableGrowth.MaxBytes = n
+	}
+
+	if v, ok := lookup("USER_DELETE_POLICY"); ok {
+		policy := strings.ToLower(strings.TrimSpace(v))
+		if !userDeletePolicies[policy] {
+			return fmt.Errorf("parse %s USER_DELETE_POLICY: want block, cascade or anonymize", source)
+		}
+		cfg.Users.DeletePolicy = policy
+	}
+
+	if v, ok := lookup("USER_ACTIVITY_ENABLED"); ok {
+		enabled, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s USER_ACTIVITY_ENABLED: %w", source, err)
+		}
+		cfg.Users.ActivityEnabled = enabled
+	}
+
+	if v, ok := lookup("SUBSCRIPTION_ID_STRATEGY"); ok {
+		strategy := strings.ToLower(strings.TrimSpace(v))
+		if !idStrategies[strategy] {
+			return fmt.Errorf("parse %s SUBSCRIPTION_ID_STRATEGY: want serial, uuidv7, ulid or hashid", source)
+		}
+		cfg.IDs.Strategy = strategy
+	}
+
+	if v, ok := lookup("SUBSCRIPTION_HASHID_SECRET"); ok {
+		secret := strings.TrimSpace(v)
+		if secret != "" && len(secret) < 16 {
+			return fmt.Errorf("parse %s SUBSCRIPTION_HASHID_SECRET: must be at least 16 bytes", source)
+		}
+		cfg.IDs.HashidSecret = secret
+	}
+
+	if v, ok := lookup("SUBSCRIPTION_HASHID_MIN_LENGTH"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 1 || n > 32 {
+			return fmt.Errorf("parse %s SUBSCRIPTION_HASHID_MIN_LENGTH: must be between 1 and 32, got %q", source, v)
+		}
+		cfg.IDs.HashidMinLength = n
+	}
+
+	if cfg.IDs.Strategy == "hashid" && cfg.IDs.HashidSecret == "" {
+		return fmt.Errorf("parse %s SUBSCRIPTION_HASHID_SECRET: required with SUBSCRIPTION_ID_STRATEGY=hashid, anyone could decode the IDs without it", source)
+	}
+
+	if v, ok := lookup("ANALYTICS_SINK"); ok {
+		sink := strings.ToLower(strings.TrimSpace(v))
+		if !analyticsSinks[sink] {
+			return fmt.Errorf("parse %s ANALYTICS_SINK: want none or posthog", source)
+		}
+		cfg.Analytics.Sink = sink
+	}
+
+	if v, ok := lookup("ANALYTICS_POSTHOG_KEY"); ok {
+		cfg.Analytics.PostHogKey = strings.TrimSpace(v)
+	}
+
+	if v, ok := lookup("ANALYTICS_POSTHOG_HOST"); ok {
+		raw := strings.TrimSpace(v)
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("parse %s ANALYTICS_POSTHOG_HOST: must be an absolute http(s) URL, got %q", source, v)
+		}
+		cfg.Analytics.PostHogHost = raw
+	}
+
+	if v, ok := lookup("ANALYTICS_FLUSH_INTERVAL"); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || interval <= 0 {
+			return fmt.Errorf("parse %s ANALYTICS_FLUSH_INTERVAL: must be a positive duration, got %q", source, v)
+		}
+		cfg.Analytics.FlushInterval = interval
+	}
+
+	if cfg.Analytics.Sink == "posthog" && cfg.Analytics.PostHogKey == "" {
+		return fmt.Errorf("parse %s ANALYTICS_POSTHOG_KEY: required with ANALYTICS_SINK=posthog", source)
+	}
+
+	if v, ok := lookup("REQUEST_AUDIT_ENABLED"); ok {
+		enabled, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s REQUEST_AUDIT_ENABLED: %w", source, err)
+		}
+		cfg.RequestAudit.Enabled = enabled
+	}
+
+	if v, ok := lookup("REQUEST_AUDIT_RETENTION"); ok {
+		retention, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || retention < 0 {
+			return fmt.Errorf("parse %s REQUEST_AUDIT_RETENTION: must be a non-negative duration, got %q", source, v)
+		}
+		cfg.RequestAudit.Retention = retention
+	}
+
+	if v, ok := lookup("SANDBOX_TENANT"); ok {
+		tenant := strings.TrimSpace(v)
+		if tenant != "" && (len(tenant) > maxSchemaTenant || !tenantPattern.MatchString(tenant)) {
+			return fmt.Errorf("parse %s SANDBOX_TENANT: %q is not a tenant ID of up to %d letters, digits, dots, dashes and underscores", source, tenant, maxSchemaTenant)
+		}
+		cfg.Sandbox.Tenant = tenant
+	}
+
+	if v, ok := lookup("SANDBOX_SEED_FILE"); ok {
+		path := strings.TrimSpace(v)
+		if path != "" {
+			if fi, err := os.Stat(path); err != nil || fi.IsDir() {
+				return fmt.Errorf("parse %s SANDBOX_SEED_FILE: %q is not a file", source, v)
+			}
+		}
+		cfg.Sandbox.SeedFile = path
+	}
+
+	if v, ok := lookup("LOAD_SHED_ENABLED"); ok {
+		enabled, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s LOAD_SHED_ENABLED: %w", source, err)
+		}
+		cfg.LoadShed.Enabled = enabled
+	}
+
+	if v, ok := lookup("LOAD_SHED_INTERVAL"); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || interval <= 0 {
+			return fmt.Errorf("parse %s LOAD_SHED_INTERVAL: must be a positive duration, got %q", source, v)
+		}
+		cfg.LoadShed.Interval = interval
+	}
+
+	if v, ok := lookup("LOAD_SHED_MAX_ACQUIRE_WAIT"); ok {
+		wait, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || wait < 0 {
+			return fmt.Errorf("parse %s LOAD_SHED_MAX_ACQUIRE_WAIT: must be a non-negative duration, got %q", source, v)
+		}
+		cfg.LoadShed.MaxAcquireWait = wait
+	}
+
+	if v, ok := lookup("LOAD_SHED_MAX_POOL_USAGE"); ok {
+		usage, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || usage < 0 || usage > 1 {
+			return fmt.Errorf("parse %s LOAD_SHED_MAX_POOL_USAGE: must be a number from 0 to 1, got %q", source, v)
+		}
+		cfg.LoadShed.MaxPoolUsage = usage
+	}
+
+	if v, ok := lookup("RECONCILE_INTERVAL"); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || interval < 0 {
+			return fmt.Errorf("parse %s RECONCILE_INTERVAL: must be a non-negative duration, got %q", source, v)
+		}
+		cfg.Reconcile.Interval = interval
+	}
+
+	if v, ok := lookup("RECONCILE_MONTHS"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n <= 0 {
+			return fmt.Errorf("parse %s RECONCILE_MONTHS: must be a positive integer, got %q", source, v)
+		}
+		cfg.Reconcile.Months = n
+	}
+
+	if v, ok := lookup("IMPORT_MAX_ROWS"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n <= 0 {
+			return fmt.Errorf("parse %s IMPORT_MAX_ROWS: must be a positive integer, got %q", source, v)
+		}
+		cfg.Import.MaxRows = n
+	}
+
+	if v, ok := lookup("IMPORT_CHUNK_SIZE"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n <= 0 {
+			return fmt.Errorf("parse %s IMPORT_CHUNK_SIZE: must be a positive integer, got %q", source, v)
+		}
+		cfg.Import.ChunkSize = n
+	}
+
+	if v, ok := lookup("IMPORT_QUARANTINE_ENABLED"); ok {
+		enabled, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("parse %s IMPORT_QUARANTINE_ENABLED: %w", source, err)
+		}
+		cfg.Import.QuarantineEnabled = enabled
+	}
+
+	return nil
+}
+
+// apiTokenScopes - scopes an HTTP_API_TOKENS entry may have
+var apiTokenScopes = map[string]bool{"read": true, "write": true, "admin": true}
+
+// defaultPeriods - values of HTTP_DEFAULT_PERIOD
+var defaultPeriods = map[string]bool{"all": true, "current_month": true, "current_year": true}
+
+// tenantPattern - tenant IDs of POSTGRES_TENANT_SCHEMAS and SANDBOX_TENANT, as the gateway sends them
+var tenantPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// maxSchemaTenant - the longest tenant ID whose schema name, tenant_<id>, fits the 63 bytes of a Postgres name
+const maxSchemaTenant = 56
+
+// statementCacheModes - values of POSTGRES_STATEMENT_CACHE
+var statementCacheModes = map[string]bool{"prepare": true, "describe": true, "exec": true, "simple": true}
+
+// userDeletePolicies - values of USER_DELETE_POLICY
+var userDeletePolicies = map[string]bool{"block": true, "cascade": true, "anonymize": true}
+
+// idStrategies - values of SUBSCRIPTION_ID_STRATEGY
+var idStrategies = map[string]bool{"serial": true, "uuidv7": true, "ulid": true, "hashid": true}
+
+// analyticsSinks - values of ANALYTICS_SINK
+var analyticsSinks = map[string]bool{"none": true, "posthog": true}
+
+// splitList splits a comma-separated value, dropping blank items; an empty value yields nil
+func splitList(raw string) []string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+	parts := strings.Split(raw, ",")
+	out := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if s := strings.TrimSpace(part); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// parseIngestKeys parses comma-separated name:key pairs; names and keys must be unique
+func parseIngestKeys(raw string) (map[string]string, error) {
+	items := splitList(raw)
+	if len(items) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]string, len(items))
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		name, key, ok := strings.Cut(item, ":")
+		name, key = strings.TrimSpace(name), strings.TrimSpace(key)
+		if !ok || name == "" || key == "" {
+			return nil, fmt.Errorf("want name:key, got %q", name)
+		}
+		if _, dup := out[name]; dup {
+			return nil, fmt.Errorf("duplicate integration %q", name)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("integration %q reuses another key", name)
+		}
+		out[name] = key
+		seen[key] = true
+	}
+	return out, nil
+}
+
+// parseBuckets parses a comma-separated list of strictly increasing histogram bounds
+func parseBuckets(raw string) ([]float64, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	parts := strings.Split(raw, ",")
+	buckets := make([]float64, 0, len(parts))
+	for _, part := range parts {
+		b, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(buckets); n > 0 && b <= buckets[n-1] {
+			return nil, fmt.Errorf("buckets must be strictly increasing, got %v after %v", b, buckets[n-1])
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, nil
 }
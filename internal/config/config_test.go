@@ -1,6 +1,9 @@
 package config
 
 import (
+	"bytes"
+	"encoding/base64"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"os"
@@ -14,7 +17,7 @@ func TestLoadConfig(t *testing.T) {
 
 	envPath := filepath.Join(dir, "app.env")
 
-	if err := os.WriteFile(envPath, []byte("APP_ENV=local\nHTTP_HOST=localhost\nHTTP_PORT=8080\nHTTP_TIMEOUT=4s\nHTTP_CORS_ORIGINS=http://localhost:3000,http://127.0.0.1:3000\nPOSTGRES_HOST=localhost\nPOSTGRES_PORT=5432\nPOSTGRES_USER=subs_user\nPOSTGRES_PASSWORD=subs_password\nPOSTGRES_DB=subs_db\nPOSTGRES_SSLMODE=disable\n"), 0o600); err != nil {
+	if err := os.WriteFile(envPath, []byte("APP_ENV=local\nHTTP_HOST=localhost\nHTTP_PORT=8080\nHTTP_TIMEOUT=4s\nHTTP_CORS_ORIGINS=http://localhost:3000,http://127.0.0.1:3000\nPOSTGRES_HOST=localhost\nPOSTGRES_PORT=5432\nPOSTGRES_USER=subs_user\nPOSTGRES_PASSWORD=subs_password\nPOSTGRES_DB=subs_db\nPOSTGRES_SSLMODE=disable\nMETRICS_REFRESH_INTERVAL=30s\nMETRICS_NAMESPACE=subs\nMETRICS_INSTANCE=app-1\nMETRICS_BUCKETS=0.05, 0.1,0.5\nMETRICS_ROUTES=/api/v1/subscriptions, /api/v1/admin/*\n"), 0o600); err != nil {
 		t.Fatalf("failed to write env: %v", err)
 	}
 
@@ -25,20 +28,1201 @@ func TestLoadConfig(t *testing.T) {
 	require.NotNil(t, cfg)
 
 	assert.Equal(t, Config{
-		Env: "local",
+		Env:             "local",
+		ShutdownTimeout: 10 * time.Second,
+		Log: LogConfig{
+			Redact:          true,
+			SensitiveParams: []string{"token", "api_key", "secret", "password"},
+			BodyMaxBytes:    4096,
+		},
 		Server: ServerConfig{
-			Host:        "localhost",
-			Port:        8080,
-			Timeout:     4 * time.Second,
-			CORSOrigins: []string{"http://localhost:3000", "http://127.0.0.1:3000"},
+			Hosts:         []string{"localhost"},
+			Port:          8080,
+			Timeout:       4 * time.Second,
+			CORSOrigins:   []string{"http://localhost:3000", "http://127.0.0.1:3000"},
+			CostNowTTL:    30 * time.Second,
+			AbuseWindow:   time.Minute,
+			DefaultPeriod: "all",
 		},
 		Pg: PgConfig{
-			Host:     "localhost",
-			Port:     5432,
-			User:     "subs_user",
-			Password: "subs_password",
-			Db:       "subs_db",
-			SSLMode:  "disable",
+			Host:           "localhost",
+			Port:           5432,
+			User:           "subs_user",
+			Password:       "subs_password",
+			Db:             "subs_db",
+			SSLMode:        "disable",
+			ConnectTimeout: 5 * time.Second,
+		},
+		Metrics: MetricsConfig{
+			RefreshInterval: 30 * time.Second,
+			Namespace:       "subs",
+			Instance:        "app-1",
+			Buckets:         []float64{0.05, 0.1, 0.5},
+			Routes:          []string{"/api/v1/subscriptions", "/api/v1/admin/*"},
+		},
+		Enrich: EnrichConfig{
+			CacheTTL: 24 * time.Hour,
+			Timeout:  2 * time.Second,
+		},
+		Validation: ValidationConfig{
+			EarliestDate:  time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
+			MaxYearsAhead: 20,
+		},
+		Anomaly: AnomalyConfig{
+			Interval:         time.Hour,
+			BaselineMonths:   3,
+			ThresholdPercent: 50,
+		},
+		Benchmark: BenchmarkConfig{
+			MinUsers: 5,
+		},
+		Webhook: WebhookConfig{
+			Format:    "envelope",
+			Timeout:   5 * time.Second,
+			UserLimit: 60,
+		},
+		Events: EventsConfig{
+			QueueSize:     256,
+			Timeout:       5 * time.Second,
+			NATSSubject:   "subs_tracker",
+			KafkaTopic:    "subscriptions",
+			ChangesBuffer: 1024,
+		},
+		Stripe: StripeConfig{
+			SyncInterval: time.Hour,
+		},
+		Archive: ArchiveConfig{
+			Region:     "us-east-1",
+			Prefix:     "archive/subscriptions/",
+			AfterYears: 3,
+			Interval:   24 * time.Hour,
+			BatchSize:  10_000,
+		},
+		Backup: BackupConfig{
+			Region:    "us-east-1",
+			Prefix:    "backups/",
+			At:        2 * time.Hour,
+			Retention: 7,
+		},
+		ReadModel: ReadModelConfig{
+			RebuildInterval: time.Hour,
+		},
+		Share: ShareConfig{
+			MaxTTL: 90 * 24 * time.Hour,
+		},
+		Jobs: JobsConfig{
+			LeaderElection: true,
+			LeaderInterval: 5 * time.Second,
+		},
+		Tracing: TracingConfig{
+			SampleRatio: 1,
+		},
+		TableGrowth: TableGrowthConfig{
+			Interval:       15 * time.Minute,
+			MaxRowsPerHour: 100000,
+		},
+		Users: UsersConfig{
+			DeletePolicy: "block",
+		},
+		IDs: IDsConfig{
+			Strategy:        "serial",
+			HashidMinLength: 8,
+		},
+		Analytics: AnalyticsConfig{
+			Sink:          "none",
+			PostHogHost:   "https://us.i.posthog.com",
+			FlushInterval: time.Minute,
+		},
+		RequestAudit: RequestAuditConfig{
+			Retention: 90 * 24 * time.Hour,
+		},
+		LoadShed: LoadShedConfig{
+			Interval:       time.Second,
+			MaxAcquireWait: 100 * time.Millisecond,
+			MaxPoolUsage:   0.9,
+		},
+		Reconcile: ReconcileConfig{
+			Months: 3,
+		},
+		Import: ImportConfig{
+			MaxRows:   1000,
+			ChunkSize: 100,
 		},
 	}, *cfg)
 }
+
+func TestLoadConfig_InvalidBuckets(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	if err := os.WriteFile(envPath, []byte("METRICS_BUCKETS=0.5,0.1\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	_, err := LoadConfig()
+	require.Error(t, err)
+}
+
+func TestLoadConfig_InvalidMetricsRoutes(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	if err := os.WriteFile(envPath, []byte("METRICS_ROUTES=api/v1/subscriptions\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	_, err := LoadConfig()
+	require.Error(t, err)
+}
+
+func TestLoadConfig_APITokens(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	if err := os.WriteFile(envPath, []byte("HTTP_API_TOKENS=read:dash, write:app\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read:dash", "write:app"}, cfg.Server.APITokens)
+
+	if err := os.WriteFile(envPath, []byte("HTTP_API_TOKENS=owner:s3cr3t\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	_, err = LoadConfig()
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "s3cr3t", "tokens stay out of errors")
+}
+
+func TestLoadConfig_TrustedProxies(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	if err := os.WriteFile(envPath, []byte("HTTP_TRUSTED_PROXIES=10.0.0.0/8, 192.168.1.1,::1\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.1", "::1"}, cfg.Server.TrustedProxies)
+
+	if err := os.WriteFile(envPath, []byte("HTTP_TRUSTED_PROXIES=proxy.local\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "HTTP_TRUSTED_PROXIES")
+}
+
+func TestLoadConfig_Dates(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	content := "DATE_LAYOUTS=01-2006, 2006-01\nDATE_STRICT=true\nDATE_LOCALE=RU\n"
+	if err := os.WriteFile(envPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, DatesConfig{Layouts: []string{"01-2006", "2006-01"}, Strict: true, Locale: "ru"}, cfg.Dates)
+}
+
+func TestLoadConfig_Validation(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	content := "VALIDATION_MAX_COST=100000\nVALIDATION_MAX_PERIOD_MONTHS=120\nVALIDATION_SERVICE_NAME_PATTERN=^[a-z]+$\nVALIDATION_END_DATE_REQUIRED=Trial, Promo\n"
+	if err := os.WriteFile(envPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, ValidationConfig{
+		MaxCost:            100000,
+		MaxPeriodMonths:    120,
+		ServiceNamePattern: "^[a-z]+$",
+		EndDateRequired:    []string{"Trial", "Promo"},
+		EarliestDate:       time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
+		MaxYearsAhead:      20,
+	}, cfg.Validation)
+
+	if err := os.WriteFile(envPath, []byte("VALIDATION_EARLIEST_DATE=06-2000\nVALIDATION_MAX_YEARS_AHEAD=0\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2000, time.June, 1, 0, 0, 0, 0, time.UTC), cfg.Validation.EarliestDate)
+	require.Zero(t, cfg.Validation.MaxYearsAhead)
+
+	for _, bad := range []string{"VALIDATION_SERVICE_NAME_PATTERN=[a-\n", "VALIDATION_EARLIEST_DATE=1990\n"} {
+		if err := os.WriteFile(envPath, []byte(bad), 0o600); err != nil {
+			t.Fatalf("failed to write env: %v", err)
+		}
+		_, err = LoadConfig()
+		require.Error(t, err)
+	}
+}
+
+func TestLoadConfig_Anomaly(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	content := "ANOMALY_CHECK_INTERVAL=15m\nANOMALY_BASELINE_MONTHS=6\nANOMALY_THRESHOLD_PERCENT=25.5\nBENCHMARK_MIN_USERS=10\n"
+	if err := os.WriteFile(envPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, AnomalyConfig{Interval: 15 * time.Minute, BaselineMonths: 6, ThresholdPercent: 25.5}, cfg.Anomaly)
+	require.Equal(t, 10, cfg.Benchmark.MinUsers)
+
+	for _, bad := range []string{"ANOMALY_CHECK_INTERVAL=hourly\n", "ANOMALY_BASELINE_MONTHS=0\n", "ANOMALY_THRESHOLD_PERCENT=-5\n", "BENCHMARK_MIN_USERS=1.5\n"} {
+		if err := os.WriteFile(envPath, []byte(bad), 0o600); err != nil {
+			t.Fatalf("failed to write env: %v", err)
+		}
+		_, err = LoadConfig()
+		require.Error(t, err)
+	}
+}
+
+func TestLoadConfig_Webhook(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	content := "WEBHOOK_URL=https://hooks.zapier.com/hooks/catch/1/abc/\nWEBHOOK_FORMAT=Simple\nWEBHOOK_SECRET=s3cret\nWEBHOOK_TIMEOUT=2s\n" +
+		"WEBHOOK_USER_ENABLED=true\nWEBHOOK_USER_LIMIT=10\nWEBHOOK_USER_ALLOW_PRIVATE=1\n"
+	if err := os.WriteFile(envPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, WebhookConfig{
+		URL:         "https://hooks.zapier.com/hooks/catch/1/abc/",
+		Format:      "simple",
+		Secret:      "s3cret",
+		Timeout:     2 * time.Second,
+		UserEnabled: true,
+		UserLimit:   10,
+		UserPrivate: true,
+	}, cfg.Webhook)
+
+	for _, bad := range []string{"WEBHOOK_URL=hooks.zapier.com/x\n", "WEBHOOK_FORMAT=xml\n", "WEBHOOK_TIMEOUT=soon\n",
+		"WEBHOOK_USER_ENABLED=maybe\n", "WEBHOOK_USER_LIMIT=0\n", "WEBHOOK_USER_ALLOW_PRIVATE=lan\n"} {
+		if err := os.WriteFile(envPath, []byte(bad), 0o600); err != nil {
+			t.Fatalf("failed to write env: %v", err)
+		}
+		_, err = LoadConfig()
+		require.Error(t, err)
+	}
+}
+
+func TestLoadConfig_Events(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	content := "EVENTS_QUEUE_SIZE=64\nEVENTS_TIMEOUT=2s\nEVENTS_NATS_URL=nats://svc:pw@nats:4222\nEVENTS_NATS_SUBJECT=billing.subs.\n" +
+		"EVENTS_KAFKA_REST_URL=http://kafka-rest:8082\nEVENTS_CHANGES_BUFFER=0\n"
+	if err := os.WriteFile(envPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, EventsConfig{
+		QueueSize:    64,
+		Timeout:      2 * time.Second,
+		NATSURL:      "nats://svc:pw@nats:4222",
+		NATSSubject:  "billing.subs",
+		KafkaRESTURL: "http://kafka-rest:8082",
+		KafkaTopic:   "subscriptions",
+	}, cfg.Events)
+
+	for _, bad := range []string{"EVENTS_QUEUE_SIZE=0\n", "EVENTS_NATS_URL=nats:4222\n", "EVENTS_NATS_SUBJECT=subs.>\n",
+		"EVENTS_KAFKA_REST_URL=kafka-rest:8082\n", "EVENTS_TIMEOUT=soon\n",
+		"EVENTS_CHANGES_BUFFER=-1\n"} {
+		if err := os.WriteFile(envPath, []byte(bad), 0o600); err != nil {
+			t.Fatalf("failed to write env: %v", err)
+		}
+		_, err = LoadConfig()
+		require.Error(t, err, bad)
+	}
+}
+
+func TestLoadConfig_Stripe(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	content := "STRIPE_API_KEY=rk_test_1\nSTRIPE_USER_ID=60601fee-2bf1-4721-ae6f-7636e79a0cba\nSTRIPE_CUSTOMER=cus_1\n" +
+		"STRIPE_WEBHOOK_SECRET=whsec_1\nSTRIPE_SYNC_INTERVAL=15m\n"
+	if err := os.WriteFile(envPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, StripeConfig{
+		APIKey:        "rk_test_1",
+		UserID:        "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+		Customer:      "cus_1",
+		WebhookSecret: "whsec_1",
+		SyncInterval:  15 * time.Minute,
+	}, cfg.Stripe)
+
+	for _, bad := range []string{"STRIPE_API_KEY=rk_test_1\n", "STRIPE_USER_ID=me\n", "STRIPE_SYNC_INTERVAL=often\n"} {
+		if err := os.WriteFile(envPath, []byte(bad), 0o600); err != nil {
+			t.Fatalf("failed to write env: %v", err)
+		}
+		_, err = LoadConfig()
+		require.Error(t, err)
+	}
+}
+
+func TestLoadConfig_Archive(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	content := "ARCHIVE_S3_BUCKET=cold\nARCHIVE_S3_ENDPOINT=http://minio:9000\nARCHIVE_S3_REGION=eu-central-1\n" +
+		"ARCHIVE_S3_ACCESS_KEY=ak\nARCHIVE_S3_SECRET_KEY=sk\nARCHIVE_PREFIX=subs/\nARCHIVE_AFTER_YEARS=5\n" +
+		"ARCHIVE_INTERVAL=6h\nARCHIVE_BATCH_SIZE=500\n"
+	if err := os.WriteFile(envPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, ArchiveConfig{
+		Bucket:     "cold",
+		Endpoint:   "http://minio:9000",
+		Region:     "eu-central-1",
+		AccessKey:  "ak",
+		SecretKey:  "sk",
+		Prefix:     "subs/",
+		AfterYears: 5,
+		Interval:   6 * time.Hour,
+		BatchSize:  500,
+	}, cfg.Archive)
+
+	for _, bad := range []string{"ARCHIVE_S3_ENDPOINT=minio:9000\n", "ARCHIVE_AFTER_YEARS=0\n", "ARCHIVE_INTERVAL=daily\n", "ARCHIVE_BATCH_SIZE=-1\n"} {
+		if err := os.WriteFile(envPath, []byte(bad), 0o600); err != nil {
+			t.Fatalf("failed to write env: %v", err)
+		}
+		_, err = LoadConfig()
+		require.Error(t, err)
+	}
+}
+
+func TestLoadConfig_Backup(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	content := "BACKUP_S3_BUCKET=backups\nBACKUP_S3_ENDPOINT=https://minio:9000\nBACKUP_S3_REGION=eu-central-1\n" +
+		"BACKUP_S3_ACCESS_KEY=ak\nBACKUP_S3_SECRET_KEY=sk\nBACKUP_PREFIX=db/\nBACKUP_TIME=03:45\nBACKUP_RETENTION=14\n"
+	if err := os.WriteFile(envPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, BackupConfig{
+		Bucket:    "backups",
+		Endpoint:  "https://minio:9000",
+		Region:    "eu-central-1",
+		AccessKey: "ak",
+		SecretKey: "sk",
+		Prefix:    "db/",
+		At:        3*time.Hour + 45*time.Minute,
+		Retention: 14,
+	}, cfg.Backup)
+
+	for _, bad := range []string{"BACKUP_TIME=25:00\n", "BACKUP_TIME=3am\n", "BACKUP_RETENTION=0\n",
+		"BACKUP_S3_ENDPOINT=minio\n", "BACKUP_DIR=/var/backups\nBACKUP_S3_BUCKET=backups\n"} {
+		if err := os.WriteFile(envPath, []byte(bad), 0o600); err != nil {
+			t.Fatalf("failed to write env: %v", err)
+		}
+		_, err = LoadConfig()
+		require.Error(t, err)
+	}
+}
+
+func TestLoadConfig_Pseudonym(t *testing.T) {
+	dir := t.TempDir()
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+	keys := "k1:" + key
+
+	envPath := filepath.Join(dir, "app.env")
+	if err := os.WriteFile(envPath, []byte("USER_PSEUDONYM_KEY="+key+"\nUSER_PSEUDONYM_ENCRYPTION_KEYS="+keys+"\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, PseudonymConfig{Key: key, EncryptionKeys: keys}, cfg.Pseudonym)
+
+	short := base64.StdEncoding.EncodeToString([]byte("short"))
+	for _, bad := range []string{"USER_PSEUDONYM_KEY=" + key + "\n", "USER_PSEUDONYM_KEY=" + short + "\nUSER_PSEUDONYM_ENCRYPTION_KEYS=" + keys + "\n",
+		"USER_PSEUDONYM_ENCRYPTION_KEYS=k1:" + short + "\n"} {
+		if err := os.WriteFile(envPath, []byte(bad), 0o600); err != nil {
+			t.Fatalf("failed to write env: %v", err)
+		}
+		_, err = LoadConfig()
+		require.Error(t, err)
+	}
+}
+
+func TestLoadConfig_ReadModel(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	content := "READ_MODEL_ENABLED=true\nREAD_MODEL_PG_DSN=postgres://u:p@analytics:5432/subs_db\nREAD_MODEL_REBUILD_INTERVAL=15m\n"
+	if err := os.WriteFile(envPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, ReadModelConfig{
+		Enabled:         true,
+		DSN:             "postgres://u:p@analytics:5432/subs_db",
+		RebuildInterval: 15 * time.Minute,
+	}, cfg.ReadModel)
+
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+	for _, bad := range []string{"READ_MODEL_ENABLED=maybe\n", "READ_MODEL_PG_DSN=analytics:5432\n", "READ_MODEL_REBUILD_INTERVAL=0s\n",
+		"READ_MODEL_ENABLED=true\nUSER_PSEUDONYM_KEY=" + key + "\nUSER_PSEUDONYM_ENCRYPTION_KEYS=k1:" + key + "\n"} {
+		if err := os.WriteFile(envPath, []byte(bad), 0o600); err != nil {
+			t.Fatalf("failed to write env: %v", err)
+		}
+		_, err = LoadConfig()
+		require.Error(t, err, bad)
+	}
+}
+
+func TestLoadConfig_Snapshot(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	if err := os.WriteFile(envPath, []byte("SNAPSHOT_SECRET= 0123456789abcdef \n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, SnapshotConfig{Secret: "0123456789abcdef"}, cfg.Snapshot)
+
+	if err := os.WriteFile(envPath, []byte("SNAPSHOT_SECRET=short\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	_, err = LoadConfig()
+	require.Error(t, err)
+}
+
+func TestLoadConfig_Share(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	t.Setenv("ENV_FILE", envPath)
+
+	if err := os.WriteFile(envPath, []byte("APP_ENV=local\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, 90*24*time.Hour, cfg.Share.MaxTTL)
+
+	if err := os.WriteFile(envPath, []byte("SHARE_MAX_TTL=720h\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, 30*24*time.Hour, cfg.Share.MaxTTL)
+
+	if err := os.WriteFile(envPath, []byte("SHARE_MAX_TTL=0s\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	_, err = LoadConfig()
+	require.Error(t, err)
+}
+
+func TestLoadConfig_Jobs(t *testing.T) {
+	dir := t.TempDir()
+	envPath := filepath.Join(dir, "app.env")
+	t.Setenv("ENV_FILE", envPath)
+
+	if err := os.WriteFile(envPath, []byte("JOBS_LEADER_ELECTION=false\nJOBS_LEADER_INTERVAL=2s\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, JobsConfig{LeaderElection: false, LeaderInterval: 2 * time.Second}, cfg.Jobs)
+
+	if err := os.WriteFile(envPath, []byte("JOBS_LEADER_INTERVAL=0s\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	_, err = LoadConfig()
+	require.Error(t, err)
+}
+
+func TestLoadConfig_Tracing(t *testing.T) {
+	dir := t.TempDir()
+	envPath := filepath.Join(dir, "app.env")
+	t.Setenv("ENV_FILE", envPath)
+
+	if err := os.WriteFile(envPath, []byte("TRACING_OTLP_ENDPOINT=http://otel-collector:4318\nTRACING_SAMPLE_RATIO=0.25\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, TracingConfig{Endpoint: "http://otel-collector:4318", SampleRatio: 0.25}, cfg.Tracing)
+
+	for _, env := range []string{"TRACING_OTLP_ENDPOINT=otel-collector:4318\n", "TRACING_SAMPLE_RATIO=1.5\n"} {
+		if err := os.WriteFile(envPath, []byte(env), 0o600); err != nil {
+			t.Fatalf("failed to write env: %v", err)
+		}
+		_, err = LoadConfig()
+		require.Error(t, err, env)
+	}
+}
+
+func TestLoadConfig_TableGrowth(t *testing.T) {
+	dir := t.TempDir()
+	envPath := filepath.Join(dir, "app.env")
+	t.Setenv("ENV_FILE", envPath)
+
+	if err := os.WriteFile(envPath, []byte("TABLE_GROWTH_INTERVAL=0\nTABLE_GROWTH_MAX_ROWS_PER_HOUR=0\nTABLE_GROWTH_MAX_BYTES=10737418240\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, TableGrowthConfig{MaxBytes: 10 << 30}, cfg.TableGrowth)
+
+	if err := os.WriteFile(envPath, []byte("TABLE_GROWTH_MAX_BYTES=10G\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	_, err = LoadConfig()
+	require.Error(t, err)
+}
+
+func TestLoadConfig_UserDeletePolicy(t *testing.T) {
+	dir := t.TempDir()
+	envPath := filepath.Join(dir, "app.env")
+	t.Setenv("ENV_FILE", envPath)
+
+	if err := os.WriteFile(envPath, []byte("APP_ENV=test\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, "block", cfg.Users.DeletePolicy)
+
+	if err := os.WriteFile(envPath, []byte("USER_DELETE_POLICY= Anonymize\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, "anonymize", cfg.Users.DeletePolicy)
+
+	if err := os.WriteFile(envPath, []byte("USER_DELETE_POLICY=soft\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	_, err = LoadConfig()
+	require.Error(t, err)
+}
+
+func TestLoadConfig_UserActivity(t *testing.T) {
+	dir := t.TempDir()
+	envPath := filepath.Join(dir, "app.env")
+	t.Setenv("ENV_FILE", envPath)
+
+	if err := os.WriteFile(envPath, []byte("USER_ACTIVITY_ENABLED=true\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.True(t, cfg.Users.ActivityEnabled)
+
+	if err := os.WriteFile(envPath, []byte("USER_ACTIVITY_ENABLED=sometimes\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	_, err = LoadConfig()
+	require.Error(t, err)
+}
+
+func TestLoadConfig_IDStrategy(t *testing.T) {
+	dir := t.TempDir()
+	envPath := filepath.Join(dir, "app.env")
+	t.Setenv("ENV_FILE", envPath)
+
+	if err := os.WriteFile(envPath, []byte("SUBSCRIPTION_ID_STRATEGY= ULID\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, "ulid", cfg.IDs.Strategy)
+
+	if err := os.WriteFile(envPath, []byte("SUBSCRIPTION_ID_STRATEGY=uuidv4\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	_, err = LoadConfig()
+	require.Error(t, err)
+
+	if err := os.WriteFile(envPath, []byte("SUBSCRIPTION_ID_STRATEGY=hashid\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	_, err = LoadConfig()
+	require.Error(t, err, "hashid needs a secret")
+
+	env := "SUBSCRIPTION_ID_STRATEGY=hashid\nSUBSCRIPTION_HASHID_SECRET=0123456789abcdef\nSUBSCRIPTION_HASHID_MIN_LENGTH=12\n"
+	if err := os.WriteFile(envPath, []byte(env), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, IDsConfig{Strategy: "hashid", HashidSecret: "0123456789abcdef", HashidMinLength: 12}, cfg.IDs)
+	require.Equal(t, redacted, cfg.Summary()["SUBSCRIPTION_HASHID_SECRET"])
+}
+
+func TestLoadConfig_Analytics(t *testing.T) {
+	dir := t.TempDir()
+	envPath := filepath.Join(dir, "app.env")
+	t.Setenv("ENV_FILE", envPath)
+
+	if err := os.WriteFile(envPath, []byte("ANALYTICS_SINK=PostHog\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	_, err := LoadConfig()
+	require.Error(t, err, "posthog needs a key")
+
+	env := "ANALYTICS_SINK=posthog\nANALYTICS_POSTHOG_KEY=phc_test\nANALYTICS_POSTHOG_HOST=https://eu.i.posthog.com\nANALYTICS_FLUSH_INTERVAL=30s\n"
+	if err := os.WriteFile(envPath, []byte(env), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, AnalyticsConfig{
+		Sink:          "posthog",
+		PostHogKey:    "phc_test",
+		PostHogHost:   "https://eu.i.posthog.com",
+		FlushInterval: 30 * time.Second,
+	}, cfg.Analytics)
+	require.Equal(t, redacted, cfg.Summary()["ANALYTICS_POSTHOG_KEY"])
+
+	for _, bad := range []string{"ANALYTICS_SINK=segment", "ANALYTICS_POSTHOG_HOST=eu.i.posthog.com", "ANALYTICS_FLUSH_INTERVAL=0s"} {
+		if err := os.WriteFile(envPath, []byte(bad+"\n"), 0o600); err != nil {
+			t.Fatalf("failed to write env: %v", err)
+		}
+		_, err = LoadConfig()
+		require.Error(t, err, bad)
+	}
+}
+
+func TestLoadConfig_RequestAudit(t *testing.T) {
+	dir := t.TempDir()
+	envPath := filepath.Join(dir, "app.env")
+	t.Setenv("ENV_FILE", envPath)
+
+	if err := os.WriteFile(envPath, []byte("REQUEST_AUDIT_ENABLED=true\nREQUEST_AUDIT_RETENTION=720h\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, RequestAuditConfig{Enabled: true, Retention: 30 * 24 * time.Hour}, cfg.RequestAudit)
+
+	for _, bad := range []string{"REQUEST_AUDIT_ENABLED=sometimes", "REQUEST_AUDIT_RETENTION=-1h", "REQUEST_AUDIT_RETENTION=90d"} {
+		if err := os.WriteFile(envPath, []byte(bad+"\n"), 0o600); err != nil {
+			t.Fatalf("failed to write env: %v", err)
+		}
+		_, err = LoadConfig()
+		require.Error(t, err, bad)
+	}
+}
+
+func TestLoadConfig_Log(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	if err := os.WriteFile(envPath, []byte("LOG_REDACT=false\nLOG_SENSITIVE_PARAMS=token, session ,\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, LogConfig{Redact: false, SensitiveParams: []string{"token", "session"}, BodyMaxBytes: 4096}, cfg.Log)
+	require.False(t, cfg.Log.BodyLogEnabled())
+
+	body := "LOG_BODY_SAMPLE_RATE=0.01\nLOG_BODY_ROUTES=/api/v1/subscriptions\nLOG_BODY_STATUSES=400, 422\nLOG_BODY_MAX_BYTES=1024\n"
+	if err := os.WriteFile(envPath, []byte(body), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	require.True(t, cfg.Log.BodyLogEnabled())
+	require.Equal(t, 0.01, cfg.Log.BodySampleRate)
+	require.Equal(t, []string{"/api/v1/subscriptions"}, cfg.Log.BodyRoutes)
+	require.Equal(t, []int{400, 422}, cfg.Log.BodyStatuses)
+	require.Equal(t, 1024, cfg.Log.BodyMaxBytes)
+
+	for _, bad := range []string{"LOG_REDACT=sometimes", "LOG_BODY_SAMPLE_RATE=2", "LOG_BODY_STATUSES=4xx", "LOG_BODY_MAX_BYTES=0"} {
+		if err := os.WriteFile(envPath, []byte(bad+"\n"), 0o600); err != nil {
+			t.Fatalf("failed to write env: %v", err)
+		}
+		_, err = LoadConfig()
+		require.Error(t, err, bad)
+	}
+}
+
+func TestLoadConfig_SPADir(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	if err := os.WriteFile(envPath, []byte("HTTP_SPA_DIR="+dir+"\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, dir, cfg.Server.SPADir)
+
+	if err := os.WriteFile(envPath, []byte("HTTP_SPA_DIR="+envPath+"\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	_, err = LoadConfig()
+	require.Error(t, err)
+}
+
+func TestLoadConfig_MigrationsDir(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	if err := os.WriteFile(envPath, []byte("POSTGRES_MIGRATIONS_DIR="+dir+"\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, dir, cfg.Pg.MigrationsDir)
+
+	if err := os.WriteFile(envPath, []byte("POSTGRES_MIGRATIONS_DIR="+filepath.Join(dir, "missing")+"\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	_, err = LoadConfig()
+	require.Error(t, err)
+}
+
+func TestLoadConfig_Abuse(t *testing.T) {
+	dir := t.TempDir()
+	envPath := filepath.Join(dir, "app.env")
+	t.Setenv("ENV_FILE", envPath)
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(envPath, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write env: %v", err)
+		}
+	}
+
+	write("HTTP_PORT=8080\n")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, time.Minute, cfg.Server.AbuseWindow)
+	require.Zero(t, cfg.Server.AbuseBan, "clients are only reported by default")
+
+	write("HTTP_ABUSE_WINDOW=5m\nHTTP_ABUSE_MAX_REQUESTS=3000\nHTTP_ABUSE_MAX_ERROR_RATE=0.5\nHTTP_ABUSE_BAN=15m\n")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, 5*time.Minute, cfg.Server.AbuseWindow)
+	require.Equal(t, 3000, cfg.Server.AbuseMaxRequests)
+	require.InDelta(t, 0.5, cfg.Server.AbuseMaxErrorRate, 1e-9)
+	require.Equal(t, 15*time.Minute, cfg.Server.AbuseBan)
+
+	for _, bad := range []string{
+		"HTTP_ABUSE_WINDOW=-1m\n",
+		"HTTP_ABUSE_MAX_REQUESTS=-5\n",
+		"HTTP_ABUSE_MAX_ERROR_RATE=1.5\n",
+		"HTTP_ABUSE_BAN=soon\n",
+	} {
+		write(bad)
+		_, err = LoadConfig()
+		require.Error(t, err, bad)
+	}
+}
+
+func TestLoadConfig_Pool(t *testing.T) {
+	dir := t.TempDir()
+	envPath := filepath.Join(dir, "app.env")
+	t.Setenv("ENV_FILE", envPath)
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(envPath, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write env: %v", err)
+		}
+	}
+
+	write("POSTGRES_MIN_CONNS=4\nPOSTGRES_MAX_CONNS=10\nPOSTGRES_STATEMENT_CACHE=Describe\n")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.EqualValues(t, 4, cfg.Pg.MinConns)
+	require.EqualValues(t, 10, cfg.Pg.MaxConns)
+	require.Equal(t, "describe", cfg.Pg.StatementCache)
+
+	for _, bad := range []string{
+		"POSTGRES_MIN_CONNS=-1\n",
+		"POSTGRES_MAX_CONNS=many\n",
+		"POSTGRES_MIN_CONNS=11\nPOSTGRES_MAX_CONNS=10\n",
+		"POSTGRES_STATEMENT_CACHE=always\n",
+	} {
+		write(bad)
+		_, err = LoadConfig()
+		require.Error(t, err, bad)
+	}
+}
+
+func TestLoadConfig_ContractValidation(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	if err := os.WriteFile(envPath, []byte("HTTP_CONTRACT_VALIDATION=true\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.True(t, cfg.Server.ContractValidation)
+
+	if err := os.WriteFile(envPath, []byte("HTTP_CONTRACT_VALIDATION=sometimes\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	_, err = LoadConfig()
+	require.Error(t, err)
+}
+
+func TestLoadConfig_DefaultPeriod(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	if err := os.WriteFile(envPath, []byte("HTTP_DEFAULT_PERIOD=Current_Month\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, "current_month", cfg.Server.DefaultPeriod)
+
+	if err := os.WriteFile(envPath, []byte("HTTP_DEFAULT_PERIOD=last_week\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	_, err = LoadConfig()
+	require.Error(t, err)
+}
+
+func TestLoadConfig_CostNowPrime(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	if err := os.WriteFile(envPath, []byte("HTTP_COST_NOW_TTL=5m\nHTTP_COST_NOW_PRIME=200\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, 5*time.Minute, cfg.Server.CostNowTTL)
+	require.Equal(t, 200, cfg.Server.CostNowPrime)
+
+	if err := os.WriteFile(envPath, []byte("HTTP_COST_NOW_PRIME=all\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	_, err = LoadConfig()
+	require.Error(t, err)
+}
+
+func TestLoadConfig_ListMaxBytes(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	if err := os.WriteFile(envPath, []byte("HTTP_LIST_MAX_BYTES=65536\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, 65536, cfg.Server.ListMaxBytes)
+
+	if err := os.WriteFile(envPath, []byte("HTTP_LIST_MAX_BYTES=-1\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	_, err = LoadConfig()
+	require.Error(t, err)
+}
+
+func TestLoadConfig_ShardDSNs(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	env := "POSTGRES_SHARD_DSNS=postgres://u:p@shard1:5432/subs, postgresql://u:p@shard2/subs?sslmode=require\n"
+	if err := os.WriteFile(envPath, []byte(env), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, []string{"postgres://u:p@shard1:5432/subs", "postgresql://u:p@shard2/subs?sslmode=require"}, cfg.Pg.ShardDSNs)
+	require.Equal(t, redacted, cfg.Summary()["POSTGRES_SHARD_DSNS"])
+
+	if err := os.WriteFile(envPath, []byte("POSTGRES_SHARD_DSNS=host=shard1 user=u\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+	_, err = LoadConfig()
+	require.Error(t, err)
+}
+
+func TestLoadConfig_TenantSchemas(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	if err := os.WriteFile(envPath, []byte("POSTGRES_TENANT_SCHEMAS=acme, globex.eu\nSANDBOX_TENANT=sandbox\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, []string{"acme", "globex.eu"}, cfg.Pg.TenantSchemas)
+	require.Equal(t, "sandbox", cfg.Sandbox.Tenant)
+
+	for _, bad := range []string{"POSTGRES_TENANT_SCHEMAS=acme;drop\n", "POSTGRES_TENANT_SCHEMAS=-acme\n", "SANDBOX_TENANT=sand box\n", "SANDBOX_SEED_FILE=/no/such/seed.json\n", "POSTGRES_TENANT_SCHEMAS=a23456789012345678901234567890123456789012345678901234567\n"} {
+		if err := os.WriteFile(envPath, []byte(bad), 0o600); err != nil {
+			t.Fatalf("failed to write env: %v", err)
+		}
+		_, err = LoadConfig()
+		require.Error(t, err, bad)
+	}
+}
+
+func TestLoadConfig_LoadShed(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	content := "LOAD_SHED_ENABLED=true\nLOAD_SHED_INTERVAL=500ms\nLOAD_SHED_MAX_ACQUIRE_WAIT=0\nLOAD_SHED_MAX_POOL_USAGE=0.75\n"
+	if err := os.WriteFile(envPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, LoadShedConfig{Enabled: true, Interval: 500 * time.Millisecond, MaxPoolUsage: 0.75}, cfg.LoadShed)
+
+	for _, bad := range []string{"LOAD_SHED_ENABLED=sometimes\n", "LOAD_SHED_INTERVAL=0s\n", "LOAD_SHED_MAX_ACQUIRE_WAIT=-1s\n",
+		"LOAD_SHED_MAX_POOL_USAGE=90\n"} {
+		if err := os.WriteFile(envPath, []byte(bad), 0o600); err != nil {
+			t.Fatalf("failed to write env: %v", err)
+		}
+		_, err = LoadConfig()
+		require.Error(t, err, bad)
+	}
+}
+
+func TestLoadConfig_Reconcile(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	content := "RECONCILE_INTERVAL=12h\nRECONCILE_MONTHS=6\n"
+	if err := os.WriteFile(envPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, ReconcileConfig{Interval: 12 * time.Hour, Months: 6}, cfg.Reconcile)
+
+	for _, bad := range []string{"RECONCILE_INTERVAL=daily\n", "RECONCILE_INTERVAL=-1h\n", "RECONCILE_MONTHS=0\n"} {
+		if err := os.WriteFile(envPath, []byte(bad), 0o600); err != nil {
+			t.Fatalf("failed to write env: %v", err)
+		}
+		_, err = LoadConfig()
+		require.Error(t, err, bad)
+	}
+}
+
+func TestLoadConfig_Import(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	content := "IMPORT_MAX_ROWS=5000\nIMPORT_CHUNK_SIZE=250\nIMPORT_QUARANTINE_ENABLED=true\n"
+	if err := os.WriteFile(envPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, ImportConfig{MaxRows: 5000, ChunkSize: 250, QuarantineEnabled: true}, cfg.Import)
+
+	for _, bad := range []string{"IMPORT_MAX_ROWS=0\n", "IMPORT_CHUNK_SIZE=many\n", "IMPORT_QUARANTINE_ENABLED=maybe\n"} {
+		if err := os.WriteFile(envPath, []byte(bad), 0o600); err != nil {
+			t.Fatalf("failed to write env: %v", err)
+		}
+		_, err = LoadConfig()
+		require.Error(t, err, bad)
+	}
+}
+
+func TestLoadConfig_IngestKeys(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	if err := os.WriteFile(envPath, []byte("INBOUND_INGEST_KEYS=billing:k1, chargebee : k2\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"billing": "k1", "chargebee": "k2"}, cfg.Inbound.IngestKeys)
+	require.Equal(t, redacted, cfg.Summary()["INBOUND_INGEST_KEYS"])
+
+	for _, bad := range []string{"INBOUND_INGEST_KEYS=billing\n", "INBOUND_INGEST_KEYS=a:k1,a:k2\n", "INBOUND_INGEST_KEYS=a:k1,b:k1\n", "INBOUND_INGEST_KEYS=:k1\n"} {
+		if err := os.WriteFile(envPath, []byte(bad), 0o600); err != nil {
+			t.Fatalf("failed to write env: %v", err)
+		}
+		_, err = LoadConfig()
+		require.Error(t, err)
+	}
+}
+
+func TestPgConfig_DSN(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  PgConfig
+		want string
+	}{
+		{
+			name: "minimal",
+			cfg:  PgConfig{Host: "localhost", Port: 5432, User: "subs_user", Password: "pw", Db: "subs_db"},
+			want: "postgres://subs_user:pw@localhost:5432/subs_db",
+		},
+		{
+			name: "all params",
+			cfg: PgConfig{
+				Host: "db", Port: 6432, User: "u", Password: "p@ss:w/rd", Db: "subs",
+				SSLMode: "verify-full", ConnectTimeout: 3 * time.Second, ApplicationName: "subs tracker", SearchPath: "app,public",
+			},
+			want: "postgres://u:p%40ss%3Aw%2Frd@db:6432/subs?application_name=subs+tracker&connect_timeout=3&search_path=app%2Cpublic&sslmode=verify-full",
+		},
+		{
+			name: "ipv6 host",
+			cfg:  PgConfig{Host: "::1", Port: 5432, User: "u", Password: "p", Db: "d", SSLMode: "disable"},
+			want: "postgres://u:p@[::1]:5432/d?sslmode=disable",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.cfg.DSN())
+		})
+	}
+}
+
+func TestPgConfig_DSN_ParseConfig(t *testing.T) {
+	cfg := PgConfig{
+		Host: "db", Port: 5432, User: "u", Password: "p@ss", Db: "subs",
+		SSLMode: "disable", ConnectTimeout: 2 * time.Second, ApplicationName: "subs_tracker", SearchPath: "app",
+	}
+
+	poolCfg, err := pgxpool.ParseConfig(cfg.DSN())
+	require.NoError(t, err)
+
+	cc := poolCfg.ConnConfig
+	assert.Equal(t, "db", cc.Host)
+	assert.Equal(t, uint16(5432), cc.Port)
+	assert.Equal(t, "u", cc.User)
+	assert.Equal(t, "p@ss", cc.Password)
+	assert.Equal(t, "subs", cc.Database)
+	assert.Nil(t, cc.TLSConfig)
+	assert.Equal(t, 2*time.Second, cc.ConnectTimeout)
+	assert.Equal(t, "subs_tracker", cc.RuntimeParams["application_name"])
+	assert.Equal(t, "app", cc.RuntimeParams["search_path"])
+}
+
+func TestConfig_Summary(t *testing.T) {
+	c := Config{
+		Env:    "prod",
+		Server: ServerConfig{Port: 8080, Timeout: 5 * time.Second, CORSOrigins: []string{"a", "b"}, CursorSecret: "s3cr3t"},
+		Pg:     PgConfig{User: "subs_user", Password: "subs_password"},
+	}
+
+	got := c.Summary()
+	assert.Equal(t, "prod", got["APP_ENV"])
+	assert.Equal(t, "8080", got["HTTP_PORT"])
+	assert.Equal(t, "5s", got["HTTP_TIMEOUT"])
+	assert.Equal(t, "a,b", got["HTTP_CORS_ORIGINS"])
+	assert.Equal(t, "subs_user", got["POSTGRES_USER"])
+	assert.Equal(t, "***", got["POSTGRES_PASSWORD"])
+	assert.Equal(t, "***", got["HTTP_CURSOR_SECRET"])
+}
+
+func TestLoadConfig_MultipleHosts(t *testing.T) {
+	dir := t.TempDir()
+
+	envPath := filepath.Join(dir, "app.env")
+	if err := os.WriteFile(envPath, []byte("HTTP_HOST=0.0.0.0, [::]\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env: %v", err)
+	}
+
+	t.Setenv("ENV_FILE", envPath)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0.0.0.0", "::"}, cfg.Server.Hosts)
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const redacted = "***"
+
+// secretMarkers - substrings of variable names whose values must never be exposed
+var secretMarkers = []string{"PASSWORD", "SECRET", "TOKEN", "KEY", "DSN"}
+
+// Summary - flatten the config into VARIABLE -> value pairs with secrets redacted, for support diagnostics
+func (c Config) Summary() map[string]string {
+	out := make(map[string]string)
+	flatten(reflect.ValueOf(c), out)
+	return out
+}
+
+func flatten(v reflect.Value, out map[string]string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f, fv := t.Field(i), v.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		key := f.Tag.Get("mapstructure")
+		if key == "" {
+			if fv.Kind() == reflect.Struct {
+				flatten(fv, out)
+			}
+			continue
+		}
+		out[key] = summaryValue(key, fv)
+	}
+}
+
+func summaryValue(key string, v reflect.Value) string {
+	for _, m := range secretMarkers {
+		if strings.Contains(key, m) {
+			if v.IsZero() {
+				return ""
+			}
+			return redacted
+		}
+	}
+	if v.Kind() == reflect.Slice {
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
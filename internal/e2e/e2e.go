@@ -0,0 +1,224 @@
+// Package e2e boots the whole service against a real PostgreSQL and checks it over HTTP:
+// a container from testcontainers, the SQL migrations, the HTTP server on a free port and the scenario suite
+package e2e
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+
+	"subs_tracker/internal/config"
+	httpGateway "subs_tracker/internal/gateways/http"
+	subsRepository "subs_tracker/internal/repository/subscription/postgres"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/contracttest"
+)
+
+const usage = `usage: e2e [flags]
+
+Starts PostgreSQL in a container (Docker required), applies the migrations, runs the HTTP server
+on a free port and executes the end-to-end scenarios against it. Exits 1 if any scenario fails.
+
+flags:
+`
+
+// Run parses args, runs the suite and returns the process exit code
+func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("e2e", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	migrations := fs.String("migrations", "migrations", "directory with the SQL migrations")
+	image := fs.String("image", "postgres:16-alpine", "PostgreSQL image")
+	timeout := fs.Duration("timeout", 3*time.Minute, "time limit for the whole run")
+	verbose := fs.Bool("v", false, "print server logs")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	level := slog.LevelError + 1
+	if *verbose {
+		level = slog.LevelDebug
+	}
+	log := slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: level}))
+
+	baseURL, stop, err := start(ctx, *image, *migrations, log)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "e2e: %v\n", err)
+		return 1
+	}
+	defer stop()
+
+	if failed := runScenarios(baseURL, stdout); failed {
+		return 1
+	}
+	return 0
+}
+
+// start boots PostgreSQL, migrates it and serves the API; stop tears everything down
+func start(ctx context.Context, image, migrations string, log *slog.Logger) (string, func(), error) {
+	pg, err := runPostgres(ctx, image)
+	var stops []func()
+	stop := func() {
+		for i := len(stops) - 1; i >= 0; i-- {
+			stops[i]()
+		}
+	}
+	if pg != nil {
+		stops = append(stops, func() { _ = pg.Terminate(context.Background()) })
+	}
+	if err != nil {
+		stop()
+		return "", nil, fmt.Errorf("run postgres container: %w", err)
+	}
+
+	dsn, err := pg.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		stop()
+		return "", nil, fmt.Errorf("connection string: %w", err)
+	}
+	if err := migrateUp(dsn, migrations); err != nil {
+		stop()
+		return "", nil, err
+	}
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		stop()
+		return "", nil, fmt.Errorf("connect: %w", err)
+	}
+	stops = append(stops, pool.Close)
+
+	port, err := freePort()
+	if err != nil {
+		stop()
+		return "", nil, err
+	}
+	useCases := httpGateway.UseCases{
+		Sub: usecase.NewSubscription(subsRepository.NewSubRepository(pool)),
+		Checks: []httpGateway.HealthCheck{{
+			Name:  "postgres",
+			Check: func(ctx context.Context) (any, error) { return nil, pool.Ping(ctx) },
+		}},
+	}
+	server := httpGateway.New(useCases, config.Config{Env: "test"}, log,
+		httpGateway.WithHost("127.0.0.1"),
+		httpGateway.WithPort(port),
+		httpGateway.WithLogger(log),
+	)
+	srvCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- server.Run(srvCtx) }()
+	stops = append(stops, func() {
+		cancel()
+		<-done
+	})
+
+	baseURL := fmt.Sprintf("http://127.0.0.1:%d", port)
+	if err := waitReady(ctx, baseURL, done); err != nil {
+		stop()
+		return "", nil, err
+	}
+	return baseURL, stop, nil
+}
+
+// runPostgres starts the container; testcontainers panics when it finds no Docker, which becomes an error here
+func runPostgres(ctx context.Context, image string) (pg *postgres.PostgresContainer, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("docker unavailable: %v", r)
+		}
+	}()
+	return postgres.Run(ctx, image,
+		postgres.WithDatabase("subs_db"),
+		postgres.WithUsername("subs_user"),
+		postgres.WithPassword("subs_password"),
+		postgres.BasicWaitStrategies(),
+	)
+}
+
+func migrateUp(dsn, dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("migrations path: %w", err)
+	}
+	m, err := migrate.New("file://"+filepath.ToSlash(abs), dsn)
+	if err != nil {
+		return fmt.Errorf("open migrations: %w", err)
+	}
+	defer func() { _, _ = m.Close() }()
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("migrate up: %w", err)
+	}
+	return nil
+}
+
+// freePort asks the kernel for an unused port; another process may take it before the server binds,
+// which only fails the run
+func freePort() (uint16, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("find free port: %w", err)
+	}
+	defer ln.Close()
+	return uint16(ln.Addr().(*net.TCPAddr).Port), nil
+}
+
+// waitReady polls /readyz until the server answers 200, exits or ctx ends
+func waitReady(ctx context.Context, baseURL string, done <-chan error) error {
+	tick := time.NewTicker(50 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/readyz", nil)
+		if err != nil {
+			return err
+		}
+		if resp, err := http.DefaultClient.Do(req); err == nil {
+			_ = resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+		select {
+		case err := <-done:
+			return fmt.Errorf("server exited: %w", err)
+		case <-ctx.Done():
+			return fmt.Errorf("server not ready: %w", ctx.Err())
+		case <-tick.C:
+		}
+	}
+}
+
+// runScenarios prints one line per scenario and reports whether any failed. Scenarios share
+// captured IDs, so the first failure skips the rest
+func runScenarios(baseURL string, out io.Writer) bool {
+	vars := contracttest.NewVars()
+	scenarios := Scenarios()
+	for i, sc := range scenarios {
+		if err := contracttest.Check(baseURL, sc, vars); err != nil {
+			_, _ = fmt.Fprintf(out, "FAIL %s\n     %s\n", sc.Name, strings.ReplaceAll(err.Error(), "\n", "\n     "))
+			_, _ = fmt.Fprintf(out, "%d passed, 1 failed, %d not run\n", i, len(scenarios)-i-1)
+			return true
+		}
+		_, _ = fmt.Fprintf(out, "ok   %s\n", sc.Name)
+	}
+	_, _ = fmt.Fprintf(out, "%d passed\n", len(scenarios))
+	return false
+}
@@ -0,0 +1,139 @@
+package e2e
+
+import (
+	"net/http"
+
+	"subs_tracker/pkg/contracttest"
+)
+
+// Scenarios returns the end-to-end suite: the published contract cases followed by filters,
+// cost aggregation and error codes that only a real database exercises fully
+func Scenarios() []contracttest.Case {
+	return append(contracttest.Suite(), []contracttest.Case{
+		{
+			Name:   "create open-ended subscription",
+			Method: http.MethodPost,
+			Path:   "/api/v1/subscriptions",
+			Body:   `{"service_name":"Spotify","cost":300,"user_id":"{user}","start_date":"2026-01"}`,
+			Status: http.StatusCreated,
+			JSON:   `{"service_name":"Spotify","start_date":"01-2026"}`,
+			Vars:   map[string]string{"spotify": "id"},
+		},
+		{
+			Name:   "create ended subscription",
+			Method: http.MethodPost,
+			Path:   "/api/v1/subscriptions",
+			Body:   `{"service_name":"Netflix","cost":999,"user_id":"{user}","start_date":"07-2025","end_date":"12-2025"}`,
+			Status: http.StatusCreated,
+			Vars:   map[string]string{"netflix": "id"},
+		},
+		{
+			Name:   "list in start date order",
+			Method: http.MethodGet,
+			Path:   "/api/v1/subscriptions?user_id={user}",
+			Status: http.StatusOK,
+			JSON:   `[{"id":{netflix}},{"id":{spotify}}]`,
+		},
+		{
+			Name:   "filter by service name",
+			Method: http.MethodGet,
+			Path:   "/api/v1/subscriptions?user_id={user}&service_name=Spotify",
+			Status: http.StatusOK,
+			JSON:   `[{"id":{spotify}}]`,
+		},
+		{
+			Name:   "filter by period",
+			Method: http.MethodGet,
+			Path:   "/api/v1/subscriptions?user_id={user}&start_date=02-2026&end_date=03-2026",
+			Status: http.StatusOK,
+			JSON:   `[{"id":{spotify}}]`,
+		},
+		{
+			Name:   "page by limit and offset",
+			Method: http.MethodGet,
+			Path:   "/api/v1/subscriptions?user_id={user}&limit=1&offset=1",
+			Status: http.StatusOK,
+			JSON:   `[{"id":{spotify}}]`,
+		},
+		{
+			Name:   "cost across both subscriptions",
+			Method: http.MethodGet,
+			Path:   "/api/v1/subscriptions/cost?user_id={user}&start_date=10-2025&end_date=02-2026",
+			Status: http.StatusOK,
+			JSON:   `{"total":3597}`,
+		},
+		{
+			Name:   "cost of one service",
+			Method: http.MethodGet,
+			Path:   "/api/v1/subscriptions/cost?user_id={user}&service_name=Netflix&start_date=01-2025&end_date=12-2026",
+			Status: http.StatusOK,
+			JSON:   `{"total":5994}`,
+		},
+		{
+			Name:   "non-numeric id",
+			Method: http.MethodGet,
+			Path:   "/api/v1/subscriptions/abc",
+			Status: http.StatusUnprocessableEntity,
+		},
+		{
+			Name:   "unknown id",
+			Method: http.MethodGet,
+			Path:   "/api/v1/subscriptions/2147483000",
+			Status: http.StatusNotFound,
+			JSON:   `{"error":"not found"}`,
+		},
+		{
+			Name:   "malformed JSON body",
+			Method: http.MethodPost,
+			Path:   "/api/v1/subscriptions",
+			Body:   `{"service_name":`,
+			Status: http.StatusBadRequest,
+		},
+		{
+			Name:   "invalid user_id filter",
+			Method: http.MethodGet,
+			Path:   "/api/v1/subscriptions?user_id=nope",
+			Status: http.StatusUnprocessableEntity,
+		},
+		{
+			Name:   "end before start",
+			Method: http.MethodPost,
+			Path:   "/api/v1/subscriptions",
+			Body:   `{"service_name":"Kion","cost":100,"user_id":"{user}","start_date":"07-2025","end_date":"06-2025"}`,
+			Status: http.StatusUnprocessableEntity,
+		},
+		{
+			Name:   "stale If-Match",
+			Method: http.MethodDelete,
+			Path:   "/api/v1/subscriptions/{netflix}",
+			Header: http.Header{"If-Match": {`"1"`}},
+			Status: http.StatusPreconditionFailed,
+		},
+		{
+			Name:   "invalid settings",
+			Method: http.MethodPut,
+			Path:   "/api/v1/users/{user}/settings",
+			Body:   `{"currency":"euro","locale":"en","first_day_of_week":0,"date_format":"2006-01"}`,
+			Status: http.StatusUnprocessableEntity,
+		},
+		{
+			Name:   "delete ended subscription",
+			Method: http.MethodDelete,
+			Path:   "/api/v1/subscriptions/{netflix}",
+			Status: http.StatusOK,
+		},
+		{
+			Name:   "delete open-ended subscription",
+			Method: http.MethodDelete,
+			Path:   "/api/v1/subscriptions/{spotify}",
+			Status: http.StatusOK,
+		},
+		{
+			Name:   "nothing left",
+			Method: http.MethodGet,
+			Path:   "/api/v1/subscriptions?user_id={user}",
+			Status: http.StatusOK,
+			JSON:   `[]`,
+		},
+	}...)
+}
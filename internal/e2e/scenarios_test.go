@@ -0,0 +1,19 @@
+package e2e
+
+import (
+	"strings"
+	"testing"
+
+	"subs_tracker/pkg/contracttest"
+)
+
+// TestScenarios keeps the suite runnable without Docker: the in-memory repository must pass it as postgres does
+func TestScenarios(t *testing.T) {
+	srv := contracttest.NewServer()
+	defer srv.Close()
+
+	var out strings.Builder
+	if runScenarios(srv.URL, &out) {
+		t.Fatal(out.String())
+	}
+}
@@ -0,0 +1,137 @@
+package enrichment
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound - provider has no metadata for the service
+var ErrNotFound = errors.New("service not found")
+
+// ServiceInfo - public metadata of a subscription service
+type ServiceInfo struct {
+	// Domain - primary web domain of the service
+	Domain string
+	// Logo - URL of the service logo
+	Logo string
+	// Category - service category, e.g. "video" or "music"
+	Category string
+	// Price - monthly list price of the basic plan in rubles, 0 when the catalog has none
+	Price int64
+}
+
+// Provider - source of service metadata looked up by service name
+type Provider interface {
+	Lookup(ctx context.Context, name string) (ServiceInfo, error)
+}
+
+// cacheEntry - cached lookup result; found is false for negative results
+type cacheEntry struct {
+	info    ServiceInfo
+	found   bool
+	expires time.Time
+}
+
+// Enricher caches provider lookups per service name, including misses and failures
+type Enricher struct {
+	provider Provider
+	ttl      time.Duration
+	errorTTL time.Duration
+	timeout  time.Duration
+	now      func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+// NewEnricher creates an Enricher over the provider and applies options
+func NewEnricher(p Provider, options ...func(*Enricher)) *Enricher {
+	e := &Enricher{
+		provider: p,
+		ttl:      24 * time.Hour,
+		errorTTL: time.Minute,
+		timeout:  2 * time.Second,
+		now:      time.Now,
+		cache:    make(map[string]cacheEntry),
+	}
+	for _, o := range options {
+		o(e)
+	}
+	return e
+}
+
+// WithTTL sets how long found and not-found results are cached
+func WithTTL(d time.Duration) func(*Enricher) {
+	return func(e *Enricher) {
+		if d > 0 {
+			e.ttl = d
+		}
+	}
+}
+
+// WithErrorTTL sets how long provider failures are cached before the lookup is retried
+func WithErrorTTL(d time.Duration) func(*Enricher) {
+	return func(e *Enricher) {
+		if d > 0 {
+			e.errorTTL = d
+		}
+	}
+}
+
+// WithTimeout bounds a single provider lookup
+func WithTimeout(d time.Duration) func(*Enricher) {
+	return func(e *Enricher) {
+		if d > 0 {
+			e.timeout = d
+		}
+	}
+}
+
+// Lookup returns metadata for the service name, asking the provider only on a cache miss
+func (e *Enricher) Lookup(ctx context.Context, name string) (ServiceInfo, bool) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if key == "" {
+		return ServiceInfo{}, false
+	}
+
+	e.mu.Lock()
+	entry, ok := e.cache[key]
+	e.mu.Unlock()
+	if ok && e.now().Before(entry.expires) {
+		return entry.info, entry.found
+	}
+
+	lctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	info, err := e.provider.Lookup(lctx, name)
+	entry = cacheEntry{info: info, found: err == nil, expires: e.now().Add(e.ttl)}
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		if ctx.Err() != nil {
+			// the caller gave up, that says nothing about the provider
+			return ServiceInfo{}, false
+		}
+		entry.expires = e.now().Add(e.errorTTL)
+	}
+
+	e.mu.Lock()
+	e.cache[key] = entry
+	e.mu.Unlock()
+	return entry.info, entry.found
+}
+
+// Enrich looks up every distinct name and returns the found metadata keyed by the names as given
+func (e *Enricher) Enrich(ctx context.Context, names []string) map[string]ServiceInfo {
+	out := make(map[string]ServiceInfo, len(names))
+	for _, name := range names {
+		if _, done := out[name]; done {
+			continue
+		}
+		if info, ok := e.Lookup(ctx, name); ok {
+			out[name] = info
+		}
+	}
+	return out
+}
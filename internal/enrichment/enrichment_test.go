@@ -0,0 +1,108 @@
+package enrichment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProvider struct {
+	calls int
+	info  ServiceInfo
+	err   error
+}
+
+func (f *fakeProvider) Lookup(_ context.Context, _ string) (ServiceInfo, error) {
+	f.calls++
+	return f.info, f.err
+}
+
+func TestEnricher_Lookup(t *testing.T) {
+	now := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	t.Run("found is cached until ttl", func(t *testing.T) {
+		p := &fakeProvider{info: ServiceInfo{Domain: "netflix.com"}}
+		e := NewEnricher(p, WithTTL(time.Hour))
+		e.now = clock
+
+		for _, name := range []string{"Netflix", " netflix "} {
+			info, ok := e.Lookup(context.Background(), name)
+			assert.True(t, ok)
+			assert.Equal(t, "netflix.com", info.Domain)
+		}
+		assert.Equal(t, 1, p.calls)
+
+		e.now = func() time.Time { return now.Add(2 * time.Hour) }
+		_, _ = e.Lookup(context.Background(), "Netflix")
+		assert.Equal(t, 2, p.calls)
+	})
+
+	t.Run("not found is cached", func(t *testing.T) {
+		p := &fakeProvider{err: ErrNotFound}
+		e := NewEnricher(p)
+		e.now = clock
+
+		_, ok := e.Lookup(context.Background(), "unknown")
+		assert.False(t, ok)
+		_, ok = e.Lookup(context.Background(), "unknown")
+		assert.False(t, ok)
+		assert.Equal(t, 1, p.calls)
+	})
+
+	t.Run("failure is retried after error ttl", func(t *testing.T) {
+		p := &fakeProvider{err: errors.New("boom")}
+		e := NewEnricher(p, WithErrorTTL(time.Minute))
+		e.now = clock
+
+		_, ok := e.Lookup(context.Background(), "Spotify")
+		assert.False(t, ok)
+		_, _ = e.Lookup(context.Background(), "Spotify")
+		assert.Equal(t, 1, p.calls)
+
+		e.now = func() time.Time { return now.Add(2 * time.Minute) }
+		_, _ = e.Lookup(context.Background(), "Spotify")
+		assert.Equal(t, 2, p.calls)
+	})
+
+	t.Run("enrich skips misses", func(t *testing.T) {
+		p := &fakeProvider{err: ErrNotFound}
+		got := NewEnricher(p).Enrich(context.Background(), []string{"a", "a", ""})
+		assert.Empty(t, got)
+		assert.Equal(t, 1, p.calls)
+	})
+}
+
+func TestHTTPProvider_Lookup(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer k3y", r.Header.Get("Authorization"))
+		switch r.URL.Query().Get("query") {
+		case "Netflix":
+			_, _ = w.Write([]byte(`[{"name":"Netflix","domain":"netflix.com","logo":"https://logo.example/netflix.com","category":"video","price":999}]`))
+		case "Empty":
+			_, _ = w.Write([]byte(`[]`))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	p := NewHTTPProvider(srv.URL+"/v1/companies/suggest", WithAPIKey("k3y"), WithHTTPClient(srv.Client()))
+
+	info, err := p.Lookup(context.Background(), "Netflix")
+	require.NoError(t, err)
+	assert.Equal(t, ServiceInfo{Domain: "netflix.com", Logo: "https://logo.example/netflix.com", Category: "video", Price: 999}, info)
+
+	_, err = p.Lookup(context.Background(), "Empty")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = p.Lookup(context.Background(), "Broken")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNotFound)
+}
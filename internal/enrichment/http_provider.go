@@ -0,0 +1,102 @@
+package enrichment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// HTTPProvider looks services up in a company autocomplete API (Clearbit style):
+// GET <url>?query=<name> answering with a JSON array of {name, domain, logo, category}; a catalog of its own may
+// add the monthly list price in rubles as price
+type HTTPProvider struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+// NewHTTPProvider creates a provider for the endpoint URL and applies options
+func NewHTTPProvider(endpoint string, options ...func(*HTTPProvider)) *HTTPProvider {
+	p := &HTTPProvider{
+		url:    endpoint,
+		client: http.DefaultClient,
+	}
+	for _, o := range options {
+		o(p)
+	}
+	return p
+}
+
+// WithAPIKey sets the bearer token sent to the provider
+func WithAPIKey(key string) func(*HTTPProvider) {
+	return func(p *HTTPProvider) {
+		p.apiKey = key
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for lookups
+func WithHTTPClient(c *http.Client) func(*HTTPProvider) {
+	return func(p *HTTPProvider) {
+		if c != nil {
+			p.client = c
+		}
+	}
+}
+
+// suggestion - single element of the provider response
+type suggestion struct {
+	Name     string `json:"name"`
+	Domain   string `json:"domain"`
+	Logo     string `json:"logo"`
+	Category string `json:"category"`
+	Price    int64  `json:"price"`
+}
+
+// Lookup fetches the best suggestion for the service name
+func (p *HTTPProvider) Lookup(ctx context.Context, name string) (ServiceInfo, error) {
+	u, err := url.Parse(p.url)
+	if err != nil {
+		return ServiceInfo{}, fmt.Errorf("enrichment url: %w", err)
+	}
+	q := u.Query()
+	q.Set("query", name)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return ServiceInfo{}, fmt.Errorf("enrichment request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return ServiceInfo{}, fmt.Errorf("enrichment lookup %q: %w", name, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ServiceInfo{}, ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		return ServiceInfo{}, fmt.Errorf("enrichment lookup %q: status %d", name, resp.StatusCode)
+	}
+
+	var items []suggestion
+	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		return ServiceInfo{}, fmt.Errorf("enrichment decode %q: %w", name, err)
+	}
+	if len(items) == 0 {
+		return ServiceInfo{}, ErrNotFound
+	}
+	return ServiceInfo{
+		Domain:   items[0].Domain,
+		Logo:     items[0].Logo,
+		Category: items[0].Category,
+		Price:    items[0].Price,
+	}, nil
+}
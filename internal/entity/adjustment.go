@@ -0,0 +1,20 @@
+package entity
+
+import "time"
+
+// Adjustment - one-off correction of the spend on a subscription in a month, such as a refund or a credit;
+// cost totals are net of adjustments
+type Adjustment struct {
+	// ID - unique identifier of the adjustment
+	ID int64
+	// SubscriptionID - ID of the adjusted subscription
+	SubscriptionID int64
+	// Month - first day of the adjusted month
+	Month time.Time
+	// Amount - added to the spend of the month: negative for refunds and credits, positive for extra charges
+	Amount int64
+	// Note - free-form reason, e.g. "refund for the outage"
+	Note string
+	// CreatedAt - time the adjustment was recorded
+	CreatedAt time.Time
+}
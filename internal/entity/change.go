@@ -0,0 +1,54 @@
+package entity
+
+import "time"
+
+// ChangeOp - kind of write recorded in the subscription change log
+type ChangeOp string
+
+const (
+	// ChangeInsert - the subscription was created
+	ChangeInsert ChangeOp = "insert"
+	// ChangeUpdate - the subscription was modified
+	ChangeUpdate ChangeOp = "update"
+	// ChangeDelete - the subscription was removed
+	ChangeDelete ChangeOp = "delete"
+)
+
+// SubscriptionChange - single entry of the subscription change log
+type SubscriptionChange struct {
+	// Tx - ID of the transaction that wrote the entry
+	Tx uint64
+	// Seq - monotonically increasing position in the change log, taken at insert, not at commit
+	Seq int64
+	// SubscriptionID - ID of the changed subscription
+	SubscriptionID int64
+	// UserID - owner of the subscription at the time of the write
+	UserID UserID
+	// PublicID - public ID of the changed subscription, zero for entries written before it was recorded
+	PublicID PublicID
+	// Op - kind of the write
+	Op ChangeOp
+	// ChangedAt - time the write happened
+	ChangedAt time.Time
+}
+
+// Pos returns the position of the entry in the change log
+func (c SubscriptionChange) Pos() ChangePos {
+	return ChangePos{Tx: c.Tx, Seq: c.Seq}
+}
+
+// ChangePos - position in the change log. Entries are read in (Tx, Seq) order and only from transactions that
+// finished: Seq alone is not enough, as a transaction can commit a lower Seq after a reader saw a higher one.
+// The zero value is the start of the log
+type ChangePos struct {
+	Tx  uint64
+	Seq int64
+}
+
+// Before reports whether p comes before o in the change log
+func (p ChangePos) Before(o ChangePos) bool {
+	if p.Tx != o.Tx {
+		return p.Tx < o.Tx
+	}
+	return p.Seq < o.Seq
+}
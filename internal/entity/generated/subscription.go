@@ -20,6 +20,10 @@ type Subscription struct {
 	SubscriptionInput
 
 	SubscriptionID
+
+	SubscriptionTimestamps
+
+	SubscriptionEnrichment
 }
 
 // UnmarshalJSON unmarshals this object from a JSON structure
@@ -38,12 +42,26 @@ func (m *Subscription) UnmarshalJSON(raw []byte) error {
 	}
 	m.SubscriptionID = aO1
 
+	// AO2
+	var aO2 SubscriptionTimestamps
+	if err := swag.ReadJSON(raw, &aO2); err != nil {
+		return err
+	}
+	m.SubscriptionTimestamps = aO2
+
+	// AO3
+	var aO3 SubscriptionEnrichment
+	if err := swag.ReadJSON(raw, &aO3); err != nil {
+		return err
+	}
+	m.SubscriptionEnrichment = aO3
+
 	return nil
 }
 
 // MarshalJSON marshals this object to a JSON structure
 func (m Subscription) MarshalJSON() ([]byte, error) {
-	_parts := make([][]byte, 0, 2)
+	_parts := make([][]byte, 0, 4)
 
 	aO0, err := swag.WriteJSON(m.SubscriptionInput)
 	if err != nil {
@@ -56,6 +74,18 @@ func (m Subscription) MarshalJSON() ([]byte, error) {
 		return nil, err
 	}
 	_parts = append(_parts, aO1)
+
+	aO2, err := swag.WriteJSON(m.SubscriptionTimestamps)
+	if err != nil {
+		return nil, err
+	}
+	_parts = append(_parts, aO2)
+
+	aO3, err := swag.WriteJSON(m.SubscriptionEnrichment)
+	if err != nil {
+		return nil, err
+	}
+	_parts = append(_parts, aO3)
 	return swag.ConcatJSON(_parts...), nil
 }
 
@@ -71,6 +101,14 @@ func (m *Subscription) Validate(formats strfmt.Registry) error {
 	if err := m.SubscriptionID.Validate(formats); err != nil {
 		res = append(res, err)
 	}
+	// validation for a type composition with SubscriptionTimestamps
+	if err := m.SubscriptionTimestamps.Validate(formats); err != nil {
+		res = append(res, err)
+	}
+	// validation for a type composition with SubscriptionEnrichment
+	if err := m.SubscriptionEnrichment.Validate(formats); err != nil {
+		res = append(res, err)
+	}
 
 	if len(res) > 0 {
 		return errors.CompositeValidationError(res...)
@@ -90,6 +128,14 @@ func (m *Subscription) ContextValidate(ctx context.Context, formats strfmt.Regis
 	if err := m.SubscriptionID.ContextValidate(ctx, formats); err != nil {
 		res = append(res, err)
 	}
+	// validation for a type composition with SubscriptionTimestamps
+	if err := m.SubscriptionTimestamps.ContextValidate(ctx, formats); err != nil {
+		res = append(res, err)
+	}
+	// validation for a type composition with SubscriptionEnrichment
+	if err := m.SubscriptionEnrichment.ContextValidate(ctx, formats); err != nil {
+		res = append(res, err)
+	}
 
 	if len(res) > 0 {
 		return errors.CompositeValidationError(res...)
@@ -20,6 +20,10 @@ type SubscriptionID struct {
 	// id
 	// Example: 42
 	ID int64 `json:"id,omitempty"`
+
+	// public ID, sent instead of id when subscriptions are identified by UUIDv7 or ULID
+	// Example: 01J9Z8M2Q4V6X8Z0A2C4E6G8J0
+	PublicID string `json:"public_id,omitempty"`
 }
 
 // Validate validates this subscription Id
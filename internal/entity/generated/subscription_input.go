@@ -28,6 +28,9 @@ type SubscriptionInput struct {
 	// Example: 12-2025
 	EndDate string `json:"end_date,omitempty"`
 
+	// Бесплатный тариф, пробный период или сервис в составе пакета: cost должен быть 0
+	Free bool `json:"free,omitempty"`
+
 	// service name
 	// Example: Yandex Plus
 	// Required: true
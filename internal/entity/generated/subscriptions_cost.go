@@ -17,6 +17,10 @@ import (
 // swagger:model SubscriptionsCost
 type SubscriptionsCost struct {
 
+	// Валюта пользователя из его настроек, только при фильтре по user_id
+	// Example: RUB
+	Currency string `json:"currency,omitempty"`
+
 	// total
 	// Example: 1200
 	Total int64 `json:"total,omitempty"`
@@ -20,7 +20,7 @@ import (
 type SubscriptionsFilter struct {
 
 	// limit
-	// Maximum: 100
+	// Maximum: 200
 	// Minimum: 0
 	Limit *int32 `json:"limit,omitempty"`
 
@@ -35,6 +35,11 @@ type SubscriptionsFilter struct {
 	// Example: Yandex Plus
 	ServiceName string `json:"service_name,omitempty"`
 
+	// updated since
+	// Example: 2025-07-01T12:00:00Z
+	// Format: date-time
+	UpdatedSince strfmt.DateTime `json:"updated_since,omitempty"`
+
 	// user id
 	// Example: 60601fee-2bf1-4721-ae6f-7636e79a0cba
 	// Format: uuid
@@ -57,6 +62,10 @@ func (m *SubscriptionsFilter) Validate(formats strfmt.Registry) error {
 		res = append(res, err)
 	}
 
+	if err := m.validateUpdatedSince(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if err := m.validateUserID(formats); err != nil {
 		res = append(res, err)
 	}
@@ -76,7 +85,7 @@ func (m *SubscriptionsFilter) validateLimit(formats strfmt.Registry) error {
 		return err
 	}
 
-	if err := validate.MaximumInt("limit", "body", int64(*m.Limit), 100, false); err != nil {
+	if err := validate.MaximumInt("limit", "body", int64(*m.Limit), 200, false); err != nil {
 		return err
 	}
 
@@ -114,6 +123,18 @@ func (m *SubscriptionsFilter) validatePeriod(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *SubscriptionsFilter) validateUpdatedSince(formats strfmt.Registry) error {
+	if swag.IsZero(m.UpdatedSince) { // not required
+		return nil
+	}
+
+	if err := validate.FormatOf("updated_since", "body", "date-time", m.UpdatedSince.String(), formats); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (m *SubscriptionsFilter) validateUserID(formats strfmt.Registry) error {
 	if swag.IsZero(m.UserID) { // not required
 		return nil
@@ -0,0 +1,63 @@
+package entity
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+
+	"subs_tracker/internal/errcode"
+	"subs_tracker/pkg/ulid"
+)
+
+// ErrInvalidPublicID - public identifier is neither a UUID nor a ULID, or is all zeros
+var ErrInvalidPublicID = errcode.New(errcode.IDInvalid, "invalid id")
+
+// PublicID - 128-bit identifier of a subscription shown to clients instead of its serial ID by a non-serial ID
+// strategy: it reveals neither how many subscriptions exist nor the shard holding one. The same value is
+// written as a UUID or as a ULID; the zero value means "none yet"
+type PublicID uuid.UUID
+
+// ParsePublicID parses the UUID or the ULID text of a public identifier, rejecting the zero value
+func ParsePublicID(s string) (PublicID, error) {
+	s = strings.TrimSpace(s)
+	var id PublicID
+	switch len(s) {
+	case ulid.Len:
+		raw, err := ulid.Parse(s)
+		if err != nil {
+			return PublicID{}, ErrInvalidPublicID
+		}
+		id = PublicID(raw)
+	default:
+		u, err := uuid.Parse(s)
+		if err != nil {
+			return PublicID{}, ErrInvalidPublicID
+		}
+		id = PublicID(u)
+	}
+	if id.IsZero() {
+		return PublicID{}, ErrInvalidPublicID
+	}
+	return id, nil
+}
+
+// IsZero reports whether the identifier is unset
+func (id PublicID) IsZero() bool {
+	return uuid.UUID(id) == uuid.Nil
+}
+
+// String returns the canonical UUID text, or an empty string for the zero value
+func (id PublicID) String() string {
+	if id.IsZero() {
+		return ""
+	}
+	return uuid.UUID(id).String()
+}
+
+// ULID returns the ULID text, or an empty string for the zero value
+func (id PublicID) ULID() string {
+	if id.IsZero() {
+		return ""
+	}
+	return ulid.Encode(id)
+}
@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+// Seats - split of a family or team plan between users; the owner of the subscription pays for the plan and
+// holds one of its seats
+type Seats struct {
+	// SubscriptionID - ID of the shared subscription
+	SubscriptionID int64
+	// Total - seats of the plan including the owner's, 0 when the subscription is not shared
+	Total int
+	// UserIDs - members assigned a seat besides the owner, at most Total-1
+	UserIDs []UserID
+	// UpdatedAt - time the seats were last assigned
+	UpdatedAt time.Time
+}
+
+// Share returns the monthly cost of one seat of a plan costing cost, rounded down; the whole cost when the
+// plan is not shared
+func (s *Seats) Share(cost int64) int64 {
+	if s.Total <= 1 {
+		return cost
+	}
+	return cost / int64(s.Total)
+}
@@ -0,0 +1,109 @@
+package entity
+
+import (
+	"errors"
+	"regexp"
+	"time"
+
+	"subs_tracker/internal/errcode"
+)
+
+// ErrInvalidSettings - user settings hold an unsupported value
+var ErrInvalidSettings = errcode.New(errcode.SettingsInvalid, "invalid settings")
+
+var (
+	currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+	localeTag    = regexp.MustCompile(`^[a-z]{2}(-[A-Z]{2})?$`)
+)
+
+// Settings - per-user presentation preferences for costs, reports and schedules
+type Settings struct {
+	// UserID - owner of the settings
+	UserID UserID
+	// Currency - ISO 4217 code of the user's currency
+	Currency string
+	// Locale - BCP 47 language tag, e.g. "ru" or "en-US"
+	Locale string
+	// FirstDayOfWeek - day weeks start on in calendars and weekly schedules
+	FirstDayOfWeek time.Weekday
+	// DateFormat - Go layout used to show months to the user
+	DateFormat string
+	// Timezone - IANA zone name month boundaries are computed in, e.g. "Europe/Moscow"
+	Timezone string
+	// SharePriceStats - opt-in to contribute costs, anonymously, to cross-user price benchmarks
+	SharePriceStats bool
+	// MonthlyGoal - target monthly spend in the user's currency, 0 when the user set none
+	MonthlyGoal int64
+	// UpdatedAt - last time the settings were saved, zero for defaults
+	UpdatedAt time.Time
+}
+
+// DefaultSettings returns the settings of a user who never saved any
+func DefaultSettings(userID UserID) Settings {
+	return Settings{
+		UserID:         userID,
+		Currency:       "RUB",
+		Locale:         "ru",
+		FirstDayOfWeek: time.Monday,
+		DateFormat:     "01-2006",
+		Timezone:       "UTC",
+	}
+}
+
+// Validate reports ErrInvalidSettings when a field holds an unsupported value
+func (s Settings) Validate() error {
+	switch {
+	case s.UserID.IsZero():
+		return ErrInvalidUserID
+	case !currencyCode.MatchString(s.Currency):
+		return errors.Join(ErrInvalidSettings, errors.New("currency must be an ISO 4217 code"))
+	case !localeTag.MatchString(s.Locale):
+		return errors.Join(ErrInvalidSettings, errors.New("locale must look like ru or en-US"))
+	case s.FirstDayOfWeek < time.Sunday || s.FirstDayOfWeek > time.Saturday:
+		return errors.Join(ErrInvalidSettings, errors.New("first_day_of_week must be 0..6"))
+	case !monthLayout(s.DateFormat):
+		return errors.Join(ErrInvalidSettings, errors.New("date_format must be a Go layout with month and year"))
+	case !knownZone(s.Timezone):
+		return errors.Join(ErrInvalidSettings, errors.New("timezone must be an IANA zone name"))
+	case s.MonthlyGoal < 0:
+		return errors.Join(ErrInvalidSettings, errors.New("monthly_goal must not be negative"))
+	}
+	return nil
+}
+
+// Location returns the user's time zone, UTC when unset or unknown
+func (s Settings) Location() *time.Location {
+	if s.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// MonthOf returns the first day (UTC midnight) of the month t falls into in the user's zone
+func (s Settings) MonthOf(t time.Time) time.Time {
+	local := t.In(s.Location())
+	return time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// knownZone reports whether name is a loadable IANA zone; "Local" is rejected as host dependent
+func knownZone(name string) bool {
+	if name == "" || name == "Local" || len(name) > 64 {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// monthLayout reports whether layout round-trips a month, i.e. it carries both month and year
+func monthLayout(layout string) bool {
+	if layout == "" || len(layout) > 32 {
+		return false
+	}
+	ref := time.Date(2031, time.November, 1, 0, 0, 0, 0, time.UTC)
+	got, err := time.Parse(layout, ref.Format(layout))
+	return err == nil && got.Year() == ref.Year() && got.Month() == ref.Month()
+}
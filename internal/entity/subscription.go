@@ -1,23 +1,43 @@
 package entity
 
-import (
-	"time"
-
-	"github.com/go-openapi/strfmt"
-)
+import "time"
 
 // Subscription - entity with subscription information
 type Subscription struct {
 	// ID - subscription identifier in UUID format
 	ID int64
+	// PublicID - identifier shown to clients by a non-serial ID strategy; assigned on creation
+	PublicID PublicID
 	// UserID - identifier of the subscribed user
-	UserID strfmt.UUID
+	UserID UserID
 	// ServiceName - name of the service providing the subscription
 	ServiceName string
-	// Cost - monthly subscription cost in rubles
+	// Cost - monthly subscription cost in rubles, 0 for a free subscription
 	Cost int64
 	// DateFrom - subscription start date (month and year)
 	DateFrom time.Time
 	// DateTo - subscription end date (month and year)
 	DateTo *time.Time
+	// CreatedAt - time the subscription was first stored
+	CreatedAt time.Time
+	// UpdatedAt - time of the last write to the subscription
+	UpdatedAt time.Time
+}
+
+// Free reports whether s is a free tier, trial or bundled service: it costs 0, is listed like any other
+// subscription and stays out of cost aggregates
+func (s *Subscription) Free() bool {
+	return s.Cost == 0
+}
+
+// SameContent reports whether s and o have the same user, service, cost and period; IDs and timestamps
+// are not compared
+func (s *Subscription) SameContent(o *Subscription) bool {
+	if s.UserID != o.UserID || s.ServiceName != o.ServiceName || s.Cost != o.Cost || !s.DateFrom.Equal(o.DateFrom) {
+		return false
+	}
+	if s.DateTo == nil || o.DateTo == nil {
+		return s.DateTo == nil && o.DateTo == nil
+	}
+	return s.DateTo.Equal(*o.DateTo)
 }
@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+
+	"subs_tracker/internal/errcode"
+)
+
+// ErrInvalidUserID - user identifier is empty, malformed or the nil UUID
+var ErrInvalidUserID = errcode.New(errcode.UserIDInvalid, "invalid user id")
+
+// UserID - identifier of a user; the zero value means "no user"
+type UserID uuid.UUID
+
+// NewUserID wraps u into a UserID, rejecting the nil UUID
+func NewUserID(u uuid.UUID) (UserID, error) {
+	if u == uuid.Nil {
+		return UserID{}, ErrInvalidUserID
+	}
+	return UserID(u), nil
+}
+
+// ParseUserID parses a textual UUID into a UserID, rejecting empty and nil values
+func ParseUserID(s string) (UserID, error) {
+	u, err := uuid.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return UserID{}, ErrInvalidUserID
+	}
+	return NewUserID(u)
+}
+
+// IsZero reports whether the identifier is unset
+func (id UserID) IsZero() bool {
+	return uuid.UUID(id) == uuid.Nil
+}
+
+// UUID returns the underlying UUID value
+func (id UserID) UUID() uuid.UUID {
+	return uuid.UUID(id)
+}
+
+// String returns the canonical textual form, or an empty string for the zero value
+func (id UserID) String() string {
+	if id.IsZero() {
+		return ""
+	}
+	return uuid.UUID(id).String()
+}
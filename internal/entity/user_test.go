@@ -0,0 +1,90 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUserID(t *testing.T) {
+	valid := uuid.New()
+	tests := []struct {
+		name    string
+		input   string
+		want    UserID
+		wantErr bool
+	}{
+		{name: "valid", input: valid.String(), want: UserID(valid)},
+		{name: "empty", input: "", wantErr: true},
+		{name: "malformed", input: "not-a-uuid", wantErr: true},
+		{name: "nil uuid", input: uuid.Nil.String(), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseUserID(tt.input)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidUserID)
+				assert.True(t, got.IsZero())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.input, got.String())
+		})
+	}
+}
+
+func TestUserID_Zero(t *testing.T) {
+	var id UserID
+	assert.True(t, id.IsZero())
+	assert.Equal(t, "", id.String())
+}
+
+func TestSettings_Validate(t *testing.T) {
+	uid := UserID(uuid.New())
+	tests := []struct {
+		name   string
+		modify func(*Settings)
+		want   error
+	}{
+		{name: "defaults", modify: func(*Settings) {}},
+		{name: "english locale", modify: func(s *Settings) { s.Locale = "en-US"; s.FirstDayOfWeek = time.Sunday; s.DateFormat = "Jan 2006" }},
+		{name: "no user", modify: func(s *Settings) { s.UserID = UserID{} }, want: ErrInvalidUserID},
+		{name: "lower currency", modify: func(s *Settings) { s.Currency = "usd" }, want: ErrInvalidSettings},
+		{name: "bad locale", modify: func(s *Settings) { s.Locale = "russian" }, want: ErrInvalidSettings},
+		{name: "bad weekday", modify: func(s *Settings) { s.FirstDayOfWeek = 7 }, want: ErrInvalidSettings},
+		{name: "layout without year", modify: func(s *Settings) { s.DateFormat = "01" }, want: ErrInvalidSettings},
+		{name: "iana timezone", modify: func(s *Settings) { s.Timezone = "Asia/Yekaterinburg" }},
+		{name: "unknown timezone", modify: func(s *Settings) { s.Timezone = "Mars/Olympus" }, want: ErrInvalidSettings},
+		{name: "host timezone", modify: func(s *Settings) { s.Timezone = "Local" }, want: ErrInvalidSettings},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := DefaultSettings(uid)
+			tt.modify(&s)
+			err := s.Validate()
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestSettings_MonthOf(t *testing.T) {
+	s := DefaultSettings(UserID(uuid.New()))
+	instant := time.Date(2025, time.July, 31, 22, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC), s.MonthOf(instant))
+
+	s.Timezone = "Europe/Moscow"
+	assert.Equal(t, time.Date(2025, time.August, 1, 0, 0, 0, 0, time.UTC), s.MonthOf(instant))
+
+	s.Timezone = "America/Los_Angeles"
+	assert.Equal(t, time.Date(2025, time.August, 1, 0, 0, 0, 0, time.UTC), s.MonthOf(time.Date(2025, time.August, 1, 7, 0, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC), s.MonthOf(time.Date(2025, time.August, 1, 6, 59, 0, 0, time.UTC)))
+}
@@ -0,0 +1,154 @@
+// Package errcode is the catalog of machine-readable codes sent with every API error next to the message,
+// and with the warnings about successful writes. Messages are translated and may be reworded, codes never
+// change, so clients branch on them. Domain errors carry their code (see New and Wrap), and every transport
+// maps a code to its own status
+package errcode
+
+import (
+	"errors"
+
+	"subs_tracker/pkg/dates"
+	"subs_tracker/pkg/filterexpr"
+	"subs_tracker/pkg/pagination"
+)
+
+// Code — machine-readable error code, UPPER_SNAKE_CASE
+type Code string
+
+// Domain errors
+const (
+	SubNotFound        Code = "SUB_NOT_FOUND"
+	SubInvalid         Code = "SUB_INVALID"
+	SubModified        Code = "SUB_MODIFIED"
+	CostNegative       Code = "COST_NEGATIVE"
+	CostTooHigh        Code = "COST_TOO_HIGH"
+	ServiceNameInvalid Code = "SERVICE_NAME_INVALID"
+	EndDateRequired    Code = "END_DATE_REQUIRED"
+	PeriodInvalid      Code = "PERIOD_INVALID"
+	PeriodTooLong      Code = "PERIOD_TOO_LONG"
+	DateInvalid        Code = "DATE_INVALID"
+	DateOutOfRange     Code = "DATE_OUT_OF_RANGE"
+	IDInvalid          Code = "ID_INVALID"
+	UserIDInvalid      Code = "USER_ID_INVALID"
+	UserNotEmpty       Code = "USER_NOT_EMPTY"
+	SettingsInvalid    Code = "SETTINGS_INVALID"
+	SettingsNotFound   Code = "SETTINGS_NOT_FOUND"
+	GoalNotSet         Code = "GOAL_NOT_SET"
+	PaginationInvalid  Code = "PAGINATION_INVALID"
+	CursorInvalid      Code = "CURSOR_INVALID"
+	FilterInvalid      Code = "FILTER_INVALID"
+	GroupByInvalid     Code = "GROUP_BY_INVALID"
+	FieldsInvalid      Code = "FIELDS_INVALID"
+	FormatUnknown      Code = "FORMAT_UNKNOWN"
+	ShareNotFound      Code = "SHARE_NOT_FOUND"
+	ShareGone          Code = "SHARE_GONE"
+	ShareTTLInvalid    Code = "SHARE_TTL_INVALID"
+	ThemeNotFound      Code = "THEME_NOT_FOUND"
+	ThemeInvalid       Code = "THEME_INVALID"
+	WidgetNotFound     Code = "WIDGET_NOT_FOUND"
+	WidgetInvalid      Code = "WIDGET_INVALID"
+	UserHookNotFound   Code = "USER_HOOK_NOT_FOUND"
+	UserHookInvalid    Code = "USER_HOOK_INVALID"
+	UserHookLimited    Code = "USER_HOOK_LIMITED"
+	SnapshotMalformed  Code = "SNAPSHOT_MALFORMED"
+	StatementInvalid   Code = "STATEMENT_INVALID"
+	ImportTooLarge     Code = "IMPORT_TOO_LARGE"
+	QuarantineNotFound Code = "QUARANTINE_NOT_FOUND"
+	ReceiptUnknown     Code = "RECEIPT_UNKNOWN"
+	SignatureInvalid   Code = "SIGNATURE_INVALID"
+	AdjustmentInvalid  Code = "ADJUSTMENT_INVALID"
+	SeatsInvalid       Code = "SEATS_INVALID"
+	UserHasActiveSubs  Code = "USER_HAS_ACTIVE_SUBS"
+	Duplicate          Code = "DUPLICATE"
+	ReferenceMissing   Code = "REFERENCE_MISSING"
+	ConstraintViolated Code = "CONSTRAINT_VIOLATED"
+)
+
+// Request and server errors, also the codes of errors without a more specific one
+const (
+	BadRequest           Code = "BAD_REQUEST"
+	ValidationFailed     Code = "VALIDATION_FAILED"
+	Unauthorized         Code = "UNAUTHORIZED"
+	Forbidden            Code = "FORBIDDEN"
+	FeatureDisabled      Code = "FEATURE_DISABLED"
+	NotFound             Code = "NOT_FOUND"
+	MethodNotAllowed     Code = "METHOD_NOT_ALLOWED"
+	NotAcceptable        Code = "NOT_ACCEPTABLE"
+	Conflict             Code = "CONFLICT"
+	Gone                 Code = "GONE"
+	PreconditionFailed   Code = "PRECONDITION_FAILED"
+	PayloadTooLarge      Code = "PAYLOAD_TOO_LARGE"
+	UnsupportedMedia     Code = "UNSUPPORTED_MEDIA_TYPE"
+	PreconditionRequired Code = "PRECONDITION_REQUIRED"
+	RateLimited          Code = "RATE_LIMITED"
+	Internal             Code = "INTERNAL"
+	ServerBusy           Code = "SERVER_BUSY"
+)
+
+// Warnings about a subscription that was written anyway
+const (
+	PossibleDuplicate Code = "POSSIBLE_DUPLICATE"
+	EndDateFar        Code = "END_DATE_FAR"
+)
+
+// Error — an error with a code; it matches itself in errors.Is, so it serves as a sentinel
+type Error struct {
+	Code Code
+	msg  string
+	err  error
+}
+
+// New creates a sentinel error with the code
+func New(code Code, msg string) *Error {
+	return &Error{Code: code, msg: msg}
+}
+
+// Wrap gives err a more specific code, keeping its message and its sentinels for errors.Is
+func Wrap(code Code, err error) error {
+	return &Error{Code: code, err: err}
+}
+
+func (e *Error) Error() string {
+	if e.err != nil {
+		return e.err.Error()
+	}
+	return e.msg
+}
+
+func (e *Error) Unwrap() error { return e.err }
+
+// shared — codes of the errors of pkg, which knows nothing of the catalog
+var shared = []struct {
+	err  error
+	code Code
+}{
+	{dates.ErrOutOfRange, DateOutOfRange},
+	{dates.ErrEmpty, DateInvalid},
+	{dates.ErrInvalid, DateInvalid},
+	{dates.ErrNotMonthStart, DateInvalid},
+	{dates.ErrUnexpectedFormat, DateInvalid},
+	{pagination.ErrInvalidCursor, CursorInvalid},
+	{pagination.ErrNegativeOffset, PaginationInvalid},
+	{pagination.ErrOffsetTooLarge, PaginationInvalid},
+	{pagination.ErrInvalidLimit, PaginationInvalid},
+	{pagination.ErrInvalidOffset, PaginationInvalid},
+	{pagination.ErrCursorWithOffset, PaginationInvalid},
+	{pagination.ErrOutOfRange, PaginationInvalid},
+	{filterexpr.ErrSyntax, FilterInvalid},
+	{filterexpr.ErrTooLong, FilterInvalid},
+}
+
+// Of returns the code of err: the outermost Error in its chain, or the code of a known pkg error. It is
+// empty for errors outside the catalog, which the transport reports with its generic code
+func Of(err error) Code {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	for _, s := range shared {
+		if errors.Is(err, s.err) {
+			return s.code
+		}
+	}
+	return ""
+}
@@ -0,0 +1,28 @@
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"subs_tracker/pkg/dates"
+)
+
+func TestOf(t *testing.T) {
+	errInvalid := New(SubInvalid, "invalid subscription")
+
+	assert.Equal(t, SubInvalid, Of(errInvalid))
+	assert.Equal(t, SubInvalid, Of(fmt.Errorf("%w: empty user_id", errInvalid)), "wrapped sentinel")
+
+	cost := Wrap(CostNegative, fmt.Errorf("%w: cost must be > 0", errInvalid))
+	assert.Equal(t, CostNegative, Of(cost), "the outermost code wins")
+	assert.Equal(t, "invalid subscription: cost must be > 0", cost.Error())
+	assert.True(t, errors.Is(cost, errInvalid), "the sentinel still matches")
+
+	assert.Equal(t, DateInvalid, Of(fmt.Errorf("parse: %w", dates.ErrInvalid)), "pkg errors are known")
+	assert.Equal(t, SettingsInvalid, Of(errors.Join(New(SettingsInvalid, "invalid settings"), errors.New("bad locale"))))
+	assert.Empty(t, Of(errors.New("boom")))
+	assert.Empty(t, Of(nil))
+}
@@ -0,0 +1,374 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"subs_tracker/internal/activity"
+	"subs_tracker/internal/changes"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/internal/userhooks"
+	"subs_tracker/internal/webhooks"
+)
+
+const defaultTimeout = 5 * time.Second
+
+// ErrPublishFailed - the broker did not accept the event
+var ErrPublishFailed = errors.New("event publish failed")
+
+// Webhook subscribes the webhook client: one POST per attempt, drops show up in its Status
+func Webhook(c *webhooks.Client) Subscriber {
+	return Subscriber{
+		Name: "webhooks",
+		Handle: func(ctx context.Context, e usecase.SubscriptionEvent) error {
+			_, err := c.Deliver(ctx, e)
+			return err
+		},
+		Dropped: c.Dropped,
+	}
+}
+
+// UserWebhooks subscribes the webhooks users registered for themselves: each event goes to the hook of its
+// user only, events of users without a hook or over their limit are skipped rather than retried
+func UserWebhooks(h *userhooks.Hooks) Subscriber {
+	return Subscriber{
+		Name: "user_webhooks",
+		Handle: func(ctx context.Context, e usecase.SubscriptionEvent) error {
+			err := h.Deliver(ctx, e)
+			if errors.Is(err, userhooks.ErrNotFound) || errors.Is(err, userhooks.ErrLimited) {
+				return ErrSkipped
+			}
+			return err
+		},
+		Dropped: h.Dropped,
+	}
+}
+
+// UserActivity subscribes the activity feeds of users; events that make no entry are skipped
+func UserActivity(f *activity.Feed) Subscriber {
+	return Subscriber{
+		Name: "user_activity",
+		Handle: func(ctx context.Context, e usecase.SubscriptionEvent) error {
+			err := f.Record(ctx, e)
+			if errors.Is(err, activity.ErrIgnored) {
+				return ErrSkipped
+			}
+			return err
+		},
+	}
+}
+
+// Changes subscribes the log long-polled by clients for the changes of subscriptions
+func Changes(l *changes.Log) Subscriber {
+	return Subscriber{
+		Name: "changes",
+		Handle: func(_ context.Context, e usecase.SubscriptionEvent) error {
+			return l.Record(e)
+		},
+	}
+}
+
+// message is the broker payload: the envelope of the webhooks, so every sink sees the same JSON
+func message(e usecase.SubscriptionEvent) ([]byte, error) {
+	body, err := json.Marshal(webhooks.Payload(webhooks.FormatEnvelope, e))
+	if err != nil {
+		return nil, fmt.Errorf("event payload: %w", err)
+	}
+	return body, nil
+}
+
+// NATS publishes events to core NATS over the text protocol, to the subject "<prefix>.<event type>",
+// e.g. subs_tracker.subscription.created. Every publish is confirmed with PING/PONG; a failed one drops
+// the connection and the next attempt dials again
+type NATS struct {
+	addr    string
+	user    string
+	pass    string
+	prefix  string
+	timeout time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// NewNATS creates a publisher for a nats://[user:pass@]host:port URL; it connects on the first event
+func NewNATS(rawURL, prefix string, options ...func(*NATS)) (*NATS, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "nats" || u.Host == "" {
+		return nil, fmt.Errorf("nats url: must be nats://host:port, got %q", rawURL)
+	}
+	n := &NATS{addr: u.Host, prefix: strings.TrimSuffix(prefix, "."), timeout: defaultTimeout}
+	if u.Port() == "" {
+		n.addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	if u.User != nil {
+		n.user = u.User.Username()
+		n.pass, _ = u.User.Password()
+	}
+	for _, o := range options {
+		o(n)
+	}
+	return n, nil
+}
+
+// WithNATSTimeout bounds connecting and a single publish
+func WithNATSTimeout(timeout time.Duration) func(*NATS) {
+	return func(n *NATS) {
+		if timeout > 0 {
+			n.timeout = timeout
+		}
+	}
+}
+
+// Subscriber returns the bus subscriber publishing through n
+func (n *NATS) Subscriber() Subscriber {
+	return Subscriber{Name: "nats", Handle: n.Publish, Ping: n.Ping}
+}
+
+// Subject returns the subject events of type typ are published to
+func (n *NATS) Subject(typ usecase.SubscriptionEventType) string {
+	return n.prefix + "." + string(typ)
+}
+
+// Publish sends the event and waits for the server to process it
+func (n *NATS) Publish(ctx context.Context, e usecase.SubscriptionEvent) error {
+	body, err := message(e)
+	if err != nil {
+		return err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if err := n.connect(ctx); err != nil {
+		return err
+	}
+	n.deadline(ctx)
+
+	var buf bytes.Buffer
+	buf.WriteString("PUB " + n.Subject(e.Type) + " " + strconv.Itoa(len(body)) + "\r\n")
+	buf.Write(body)
+	buf.WriteString("\r\nPING\r\n")
+	if _, err := n.conn.Write(buf.Bytes()); err != nil {
+		n.close()
+		return fmt.Errorf("%w: nats: %w", ErrPublishFailed, err)
+	}
+	if err := n.awaitPong(); err != nil {
+		n.close()
+		return err
+	}
+	return nil
+}
+
+// Ping checks the server answers on the publishing connection, dialing it when needed
+func (n *NATS) Ping(ctx context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if err := n.connect(ctx); err != nil {
+		return err
+	}
+	n.deadline(ctx)
+	if _, err := n.conn.Write([]byte("PING\r\n")); err != nil {
+		n.close()
+		return fmt.Errorf("nats: %w", err)
+	}
+	if err := n.awaitPong(); err != nil {
+		n.close()
+		return err
+	}
+	return nil
+}
+
+// Close closes the connection, if any
+func (n *NATS) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.close()
+	return nil
+}
+
+// connect dials the server and completes the handshake: INFO from the server, CONNECT and PING from us
+func (n *NATS) connect(ctx context.Context) error {
+	if n.conn != nil {
+		return nil
+	}
+	d := net.Dialer{Timeout: n.timeout}
+	conn, err := d.DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return fmt.Errorf("%w: nats: %w", ErrPublishFailed, err)
+	}
+	n.conn, n.r = conn, bufio.NewReader(conn)
+	n.deadline(ctx)
+
+	line, err := n.r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "INFO ") {
+		n.close()
+		return fmt.Errorf("%w: nats: no INFO from server", ErrPublishFailed)
+	}
+	opts, _ := json.Marshal(map[string]any{
+		"verbose": false, "pedantic": false, "lang": "go", "name": "subs_tracker",
+		"user": n.user, "pass": n.pass,
+	})
+	if _, err := n.conn.Write([]byte("CONNECT " + string(opts) + "\r\nPING\r\n")); err != nil {
+		n.close()
+		return fmt.Errorf("%w: nats: %w", ErrPublishFailed, err)
+	}
+	if err := n.awaitPong(); err != nil {
+		n.close()
+		return err
+	}
+	return nil
+}
+
+// awaitPong reads until PONG, answering server PINGs; -ERR fails
+func (n *NATS) awaitPong() error {
+	for {
+		line, err := n.r.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("%w: nats: %w", ErrPublishFailed, err)
+		}
+		switch line = strings.TrimSpace(line); {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := n.conn.Write([]byte("PONG\r\n")); err != nil {
+				return fmt.Errorf("%w: nats: %w", ErrPublishFailed, err)
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("%w: nats: %s", ErrPublishFailed, strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+// deadline bounds the next exchange by the timeout or ctx, whichever ends first
+func (n *NATS) deadline(ctx context.Context) {
+	t := time.Now().Add(n.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(t) {
+		t = d
+	}
+	_ = n.conn.SetDeadline(t)
+}
+
+func (n *NATS) close() {
+	if n.conn != nil {
+		_ = n.conn.Close()
+		n.conn, n.r = nil, nil
+	}
+}
+
+// KafkaREST produces events to a Kafka topic through a Confluent REST Proxy (API v2), keyed by
+// subscription ID so the events of one subscription keep their order within a partition
+type KafkaREST struct {
+	url    string
+	client *http.Client
+}
+
+// NewKafkaREST creates a producer for the topic behind the proxy at baseURL
+func NewKafkaREST(baseURL, topic string, options ...func(*KafkaREST)) *KafkaREST {
+	k := &KafkaREST{
+		url:    strings.TrimSuffix(baseURL, "/") + "/topics/" + url.PathEscape(topic),
+		client: &http.Client{Timeout: defaultTimeout},
+	}
+	for _, o := range options {
+		o(k)
+	}
+	return k
+}
+
+// WithKafkaTimeout bounds a single produce request
+func WithKafkaTimeout(timeout time.Duration) func(*KafkaREST) {
+	return func(k *KafkaREST) {
+		if timeout > 0 {
+			k.client = &http.Client{Timeout: timeout}
+		}
+	}
+}
+
+// Subscriber returns the bus subscriber producing through k
+func (k *KafkaREST) Subscriber() Subscriber {
+	return Subscriber{Name: "kafka", Handle: k.Produce, Ping: k.Ping}
+}
+
+// Ping checks the proxy knows the topic
+func (k *KafkaREST) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.url, nil)
+	if err != nil {
+		return fmt.Errorf("kafka request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("kafka: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("kafka: topic status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+type kafkaRecord struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+type kafkaResponse struct {
+	Offsets []struct {
+		ErrorCode *int   `json:"error_code"`
+		Error     string `json:"error"`
+	} `json:"offsets"`
+}
+
+// Produce sends the event as one record and checks the proxy accepted it
+func (k *KafkaREST) Produce(ctx context.Context, e usecase.SubscriptionEvent) error {
+	value, err := message(e)
+	if err != nil {
+		return err
+	}
+	// records of one subscription share a partition; the public ID keeps the serial one out of the key
+	key := e.PublicID
+	if key == "" && e.Subscription != nil {
+		key = strconv.FormatInt(e.Subscription.ID, 10)
+	}
+	body, err := json.Marshal(map[string][]kafkaRecord{"records": {{Key: key, Value: value}}})
+	if err != nil {
+		return fmt.Errorf("kafka records: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("kafka request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: kafka: %w", ErrPublishFailed, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%w: kafka: status %d", ErrPublishFailed, resp.StatusCode)
+	}
+	var out kafkaResponse
+	if err := json.Unmarshal(raw, &out); err == nil {
+		for _, o := range out.Offsets {
+			if o.ErrorCode != nil {
+				return fmt.Errorf("%w: kafka: %s (code %d)", ErrPublishFailed, o.Error, *o.ErrorCode)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNATS accepts one connection, checks the handshake and reports published messages as "subject payload"
+func fakeNATS(t *testing.T, reject string) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+	msgs := make(chan string, 4)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		_, _ = conn.Write([]byte(`INFO {"server_id":"test"}` + "\r\n"))
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			switch {
+			case len(fields) == 0:
+			case fields[0] == "CONNECT":
+				var opts map[string]any
+				_ = json.Unmarshal([]byte(strings.TrimPrefix(strings.TrimSpace(line), "CONNECT ")), &opts)
+				msgs <- "CONNECT " + opts["user"].(string)
+			case fields[0] == "PING":
+				_, _ = conn.Write([]byte("PONG\r\n"))
+			case fields[0] == "PUB" && len(fields) == 3:
+				n, _ := strconv.Atoi(fields[2])
+				payload := make([]byte, n+2)
+				_, _ = io.ReadFull(r, payload)
+				if reject != "" {
+					_, _ = conn.Write([]byte("-ERR '" + reject + "'\r\n"))
+					continue
+				}
+				msgs <- fields[1] + " " + string(payload[:n])
+			}
+		}
+	}()
+	return ln.Addr().String(), msgs
+}
+
+func TestNATS_Publish(t *testing.T) {
+	addr, msgs := fakeNATS(t, "")
+	n, err := NewNATS("nats://svc:pw@"+addr, "subs_tracker.")
+	require.NoError(t, err)
+	defer n.Close()
+
+	require.NoError(t, n.Publish(context.Background(), sampleEvent()))
+	assert.Equal(t, "CONNECT svc", <-msgs)
+	msg := <-msgs
+	subject, payload, _ := strings.Cut(msg, " ")
+	assert.Equal(t, "subs_tracker.subscription.created", subject)
+	assert.JSONEq(t, `{"event":"subscription.created","occurred_at":"2025-07-03T10:00:00Z","data":{
+		"id":7,"user_id":"60601fee-2bf1-4721-ae6f-7636e79a0cba","service_name":"Netflix","cost":999,"start_date":"07-2025"}}`, payload)
+
+	require.NoError(t, n.Ping(context.Background()), "on the publishing connection")
+
+	_, err = NewNATS("http://"+addr, "x")
+	assert.Error(t, err)
+}
+
+func TestNATS_ServerError(t *testing.T) {
+	addr, _ := fakeNATS(t, "Permissions Violation")
+	n, err := NewNATS("nats://"+addr, "subs_tracker")
+	require.NoError(t, err)
+	defer n.Close()
+
+	err = n.Publish(context.Background(), sampleEvent())
+	assert.ErrorIs(t, err, ErrPublishFailed)
+	assert.Contains(t, err.Error(), "Permissions Violation")
+}
+
+func TestKafkaREST_Produce(t *testing.T) {
+	var body map[string][]map[string]any
+	status, reply := http.StatusOK, `{"offsets":[{"partition":0,"offset":1}]}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/topics/subscriptions", r.URL.Path)
+		if r.Method == http.MethodGet {
+			w.WriteHeader(status)
+			return
+		}
+		assert.Equal(t, "application/vnd.kafka.json.v2+json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(reply))
+	}))
+	defer srv.Close()
+	k := NewKafkaREST(srv.URL+"/", "subscriptions")
+
+	require.NoError(t, k.Produce(context.Background(), sampleEvent()))
+	require.Len(t, body["records"], 1)
+	assert.Equal(t, "7", body["records"][0]["key"])
+	assert.Equal(t, "subscription.created", body["records"][0]["value"].(map[string]any)["event"])
+
+	reply = `{"offsets":[{"partition":null,"offset":null,"error_code":40403,"error":"topic not found"}]}`
+	assert.ErrorIs(t, k.Produce(context.Background(), sampleEvent()), ErrPublishFailed)
+
+	require.NoError(t, k.Ping(context.Background()))
+
+	status, reply = http.StatusInternalServerError, `{}`
+	assert.ErrorIs(t, k.Produce(context.Background(), sampleEvent()), ErrPublishFailed)
+	assert.EqualError(t, k.Ping(context.Background()), "kafka: topic status 500")
+}
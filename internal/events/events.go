@@ -0,0 +1,239 @@
+// Package events carries subscription events from the use case to everything that reacts to writes:
+// an in-process bus fans each event out to subscribers, each with its own queue, so a slow or broken sink
+// never delays the write or the other sinks. Adapters deliver to webhooks, NATS and Kafka
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"subs_tracker/internal/integrations"
+	"subs_tracker/internal/tenancy"
+	"subs_tracker/internal/usecase"
+)
+
+const (
+	defaultQueueSize = 256
+	maxAttempts      = 3
+	retryBackoff     = time.Second
+)
+
+// Publisher — accepts events without blocking; the use case publishes through it
+type Publisher interface {
+	Publish(ctx context.Context, e usecase.SubscriptionEvent)
+}
+
+// ErrSkipped - returned by a Handler for an event its sink has nothing to do with: the event is neither
+// retried nor counted as a call of the integration
+var ErrSkipped = errors.New("event skipped")
+
+// Handler - delivers one event; an error other than ErrSkipped makes the bus retry it
+type Handler func(ctx context.Context, e usecase.SubscriptionEvent) error
+
+// Subscriber — named consumer of the bus
+type Subscriber struct {
+	// Name - identifies the subscriber in logs
+	Name string
+	// Handle - delivers an event
+	Handle Handler
+	// Dropped - optional, told how many events were given up on
+	Dropped func(n int)
+	// Ping - optional, checks the sink is reachable for readiness reports
+	Ping func(ctx context.Context) error
+}
+
+// Bus queues every published event for each subscriber and delivers them in the background. Events that
+// do not fit into a queue, fail every attempt or are still queued at shutdown are logged and dropped
+type Bus struct {
+	log          *slog.Logger
+	queueSize    int
+	backoff      time.Duration
+	integrations *integrations.Registry
+	subs         []*subscription
+}
+
+type subscription struct {
+	Subscriber
+	queue   chan usecase.SubscriptionEvent
+	tracker *integrations.Tracker
+}
+
+var _ Publisher = (*Bus)(nil)
+
+// NewBus creates a bus without subscribers and applies options
+func NewBus(log *slog.Logger, options ...func(*Bus)) *Bus {
+	b := &Bus{
+		log:       log,
+		queueSize: defaultQueueSize,
+		backoff:   retryBackoff,
+	}
+	for _, o := range options {
+		o(b)
+	}
+	return b
+}
+
+// WithQueueSize sets how many events may wait for delivery to each subscriber
+func WithQueueSize(n int) func(*Bus) {
+	return func(b *Bus) {
+		if n > 0 {
+			b.queueSize = n
+		}
+	}
+}
+
+// WithIntegrations records every delivery attempt as a call of the integration named after the subscriber
+func WithIntegrations(r *integrations.Registry) func(*Bus) {
+	return func(b *Bus) {
+		b.integrations = r
+	}
+}
+
+// Subscribe adds a subscriber; subscribers must be added before the first Publish and Run
+func (b *Bus) Subscribe(s Subscriber) {
+	if s.Handle == nil {
+		return
+	}
+	b.subs = append(b.subs, &subscription{
+		Subscriber: s,
+		queue:      make(chan usecase.SubscriptionEvent, b.queueSize),
+		tracker:    b.integrations.Track(s.Name),
+	})
+}
+
+// Subscribers reports the names of the subscribers in subscription order
+func (b *Bus) Subscribers() []string {
+	names := make([]string, 0, len(b.subs))
+	for _, s := range b.subs {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// SinkStatus — state of a subscriber reported by Check
+type SinkStatus struct {
+	// Queued - events waiting for delivery
+	Queued int `json:"queued"`
+	// Capacity - events the queue holds before new ones are dropped
+	Capacity int `json:"capacity"`
+	// Error - why the sink did not answer its ping
+	Error string `json:"error,omitempty"`
+}
+
+// Check pings every subscriber that can be pinged and reports the queues by subscriber name; it fails when
+// a ping fails or a queue is full, i.e. events are being dropped
+func (b *Bus) Check(ctx context.Context) (any, error) {
+	status := make(map[string]SinkStatus, len(b.subs))
+	var errs []error
+	for _, s := range b.subs {
+		st := SinkStatus{Queued: len(s.queue), Capacity: cap(s.queue)}
+		if s.Ping != nil {
+			if err := s.Ping(ctx); err != nil {
+				st.Error = err.Error()
+				errs = append(errs, fmt.Errorf("%s: %w", s.Name, err))
+			}
+		}
+		if st.Queued == st.Capacity {
+			errs = append(errs, fmt.Errorf("%s: queue is full", s.Name))
+		}
+		status[s.Name] = st
+	}
+	return status, errors.Join(errs...)
+}
+
+// Publish enqueues the event for every subscriber without blocking, along with the tenant of ctx; implements
+// usecase.SubscriptionEvents
+func (b *Bus) Publish(ctx context.Context, e usecase.SubscriptionEvent) {
+	if e.Tenant == "" {
+		e.Tenant = tenancy.FromContext(ctx)
+	}
+	for _, s := range b.subs {
+		select {
+		case s.queue <- e:
+		default:
+			s.dropped(1)
+			b.log.Warn("event queue full, event dropped", append(eventAttrs(e), slog.String("subscriber", s.Name))...)
+		}
+	}
+}
+
+// Run delivers queued events to every subscriber until ctx is cancelled
+func (b *Bus) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, s := range b.subs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.consume(ctx, s)
+		}()
+	}
+	<-ctx.Done()
+	wg.Wait()
+	return nil
+}
+
+func (b *Bus) consume(ctx context.Context, s *subscription) {
+	for {
+		select {
+		case <-ctx.Done():
+			if n := len(s.queue); n > 0 {
+				s.dropped(n)
+				b.log.Warn("events dropped at shutdown", slog.String("subscriber", s.Name), slog.Int("count", n))
+			}
+			return
+		case e := <-s.queue:
+			b.deliver(ctx, s, e)
+		}
+	}
+}
+
+// deliver tries the event up to maxAttempts times with a doubling pause between attempts
+func (b *Bus) deliver(ctx context.Context, s *subscription, e usecase.SubscriptionEvent) {
+	wait := b.backoff
+	if e.Tenant != "" {
+		ctx = tenancy.WithTenant(ctx, e.Tenant)
+	}
+	for attempt := 1; ; attempt++ {
+		err := s.Handle(ctx, e)
+		if errors.Is(err, ErrSkipped) {
+			return
+		}
+		s.tracker.Record(err)
+		if err == nil {
+			return
+		}
+		if attempt == maxAttempts || ctx.Err() != nil {
+			s.dropped(1)
+			b.log.Warn("event delivery failed", append(eventAttrs(e),
+				slog.String("subscriber", s.Name), slog.Int("attempts", attempt), slog.Any("error", err))...)
+			return
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+func (s *subscription) dropped(n int) {
+	if s.Dropped != nil {
+		s.Dropped(n)
+	}
+}
+
+// eventAttrs identifies an event in log records
+func eventAttrs(e usecase.SubscriptionEvent) []any {
+	attrs := []any{slog.String("event", string(e.Type))}
+	if e.Subscription != nil {
+		attrs = append(attrs, slog.Int64("subscription_id", e.Subscription.ID))
+	}
+	if e.PublicID != "" {
+		attrs = append(attrs, slog.String("subscription_public_id", e.PublicID))
+	}
+	return attrs
+}
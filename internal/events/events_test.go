@@ -0,0 +1,192 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/integrations"
+	"subs_tracker/internal/tenancy"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/internal/webhooks"
+)
+
+func sampleEvent() usecase.SubscriptionEvent {
+	return usecase.SubscriptionEvent{
+		Type:       usecase.EventSubscriptionCreated,
+		OccurredAt: time.Date(2025, time.July, 3, 10, 0, 0, 0, time.UTC),
+		Subscription: &entity.Subscription{
+			ID:          7,
+			UserID:      entity.UserID(uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba")),
+			ServiceName: "Netflix",
+			Cost:        999,
+			DateFrom:    time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+}
+
+func discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestBus_Webhook(t *testing.T) {
+	var calls atomic.Int32
+	delivered := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		close(delivered)
+	}))
+	defer srv.Close()
+
+	client := webhooks.NewClient(srv.URL)
+	b := NewBus(discard(), WithQueueSize(1))
+	b.backoff = time.Millisecond
+	b.Subscribe(Webhook(client))
+
+	b.Publish(context.Background(), sampleEvent())
+	b.Publish(context.Background(), sampleEvent()) // queue full: dropped, Publish does not block
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- b.Run(ctx) }()
+
+	select {
+	case <-delivered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("event was not retried")
+	}
+	require.Eventually(t, func() bool { return client.Status().Delivered == 1 }, 5*time.Second, time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+	assert.Equal(t, int32(2), calls.Load())
+
+	st := client.Status()
+	assert.Equal(t, webhooks.Status{Delivered: 1, Failed: 1, Dropped: 1},
+		webhooks.Status{Delivered: st.Delivered, Failed: st.Failed, Dropped: st.Dropped})
+}
+
+func TestBus_SubscribersAreIndependent(t *testing.T) {
+	b := NewBus(discard())
+	b.backoff = time.Millisecond
+	var dropped atomic.Int32
+	got := make(chan usecase.SubscriptionEvent, 1)
+	b.Subscribe(Subscriber{
+		Name:    "broken",
+		Handle:  func(context.Context, usecase.SubscriptionEvent) error { return errors.New("down") },
+		Dropped: func(n int) { dropped.Add(int32(n)) },
+	})
+	b.Subscribe(Subscriber{Name: "read-model", Handle: func(_ context.Context, e usecase.SubscriptionEvent) error {
+		got <- e
+		return nil
+	}})
+	assert.Equal(t, []string{"broken", "read-model"}, b.Subscribers())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- b.Run(ctx) }()
+	b.Publish(ctx, sampleEvent())
+
+	select {
+	case e := <-got:
+		assert.Equal(t, int64(7), e.Subscription.ID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("healthy subscriber did not get the event")
+	}
+	require.Eventually(t, func() bool { return dropped.Load() == 1 }, 5*time.Second, time.Millisecond, "retries exhausted")
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestBus_CarriesTenant(t *testing.T) {
+	b := NewBus(discard())
+	got := make(chan string, 1)
+	b.Subscribe(Subscriber{Name: "activity", Handle: func(ctx context.Context, _ usecase.SubscriptionEvent) error {
+		got <- tenancy.FromContext(ctx)
+		return nil
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- b.Run(ctx) }()
+	b.Publish(tenancy.WithTenant(context.Background(), "acme"), sampleEvent())
+
+	select {
+	case tenant := <-got:
+		assert.Equal(t, "acme", tenant, "the subscriber stores into the schema of the tenant")
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscriber did not get the event")
+	}
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestBus_Check(t *testing.T) {
+	b := NewBus(discard(), WithQueueSize(1))
+	noop := func(context.Context, usecase.SubscriptionEvent) error { return nil }
+	b.Subscribe(Subscriber{Name: "nats", Handle: noop, Ping: func(context.Context) error { return errors.New("connection refused") }})
+	b.Subscribe(Subscriber{Name: "read-model", Handle: noop})
+
+	details, err := b.Check(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, "nats: connection refused", err.Error())
+	assert.Equal(t, map[string]SinkStatus{
+		"nats":       {Queued: 0, Capacity: 1, Error: "connection refused"},
+		"read-model": {Queued: 0, Capacity: 1},
+	}, details)
+
+	healthy := NewBus(discard(), WithQueueSize(1))
+	healthy.Subscribe(Subscriber{Name: "read-model", Handle: noop})
+	_, err = healthy.Check(context.Background())
+	require.NoError(t, err)
+	healthy.Publish(context.Background(), sampleEvent())
+	_, err = healthy.Check(context.Background())
+	assert.EqualError(t, err, "read-model: queue is full", "events are being dropped")
+}
+
+func TestBus_Integrations(t *testing.T) {
+	tracked := integrations.NewRegistry()
+	b := NewBus(discard(), WithIntegrations(tracked))
+	b.backoff = time.Millisecond
+	var handled atomic.Int32
+	count := func(err error) Handler {
+		return func(context.Context, usecase.SubscriptionEvent) error {
+			handled.Add(1)
+			return err
+		}
+	}
+	b.Subscribe(Subscriber{Name: "kafka", Handle: count(errors.New("connection refused"))})
+	b.Subscribe(Subscriber{Name: "user_webhooks", Handle: count(ErrSkipped)})
+	b.Subscribe(Subscriber{Name: "webhooks", Handle: count(nil)})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- b.Run(ctx) }()
+	b.Publish(ctx, sampleEvent())
+	require.Eventually(t, func() bool { return handled.Load() == 5 }, 5*time.Second, time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	st := tracked.Status()
+	require.Len(t, st, 3)
+	assert.Equal(t, integrations.StateDown, st[0].State, "every attempt failed")
+	assert.Equal(t, int64(3), st[0].Failures)
+	assert.Equal(t, "connection refused", st[0].LastError)
+	assert.Equal(t, integrations.StateIdle, st[1].State, "skipped events are not calls")
+	assert.Equal(t, integrations.StateOK, st[2].State)
+	assert.Equal(t, int64(1), st[2].Calls)
+}
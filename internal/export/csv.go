@@ -0,0 +1,50 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+func init() {
+	Register(Format{
+		Name:        "csv",
+		ContentType: "text/csv; charset=utf-8",
+		Extension:   "csv",
+		New:         func(w io.Writer) Exporter { return &csvExporter{w: csv.NewWriter(w)} },
+	})
+}
+
+// csvExporter writes RFC 4180 CSV with a header line
+type csvExporter struct {
+	w    *csv.Writer
+	cols []Column
+	rec  []string
+}
+
+func (e *csvExporter) WriteHeader(cols []Column) error {
+	e.cols = cols
+	e.rec = make([]string, len(cols))
+	for i, c := range cols {
+		e.rec[i] = c.Name
+	}
+	return e.w.Write(e.rec)
+}
+
+func (e *csvExporter) WriteRow(values []any) error {
+	if err := checkRow(e.cols, values); err != nil {
+		return err
+	}
+	for i, v := range values {
+		s, err := text(e.cols[i].Kind, v)
+		if err != nil {
+			return err
+		}
+		e.rec[i] = s
+	}
+	return e.w.Write(e.rec)
+}
+
+func (e *csvExporter) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
@@ -0,0 +1,155 @@
+// Package export writes subscription lists as downloadable files. Every file format is an Exporter registered
+// under the name clients pass in ?format=, so adding a format never touches the handlers
+package export
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/pkg/dates"
+)
+
+// Kind — type of the values of a column
+type Kind int
+
+const (
+	// String - values are strings
+	String Kind = iota
+	// Int - values are int64
+	Int
+	// Amount - values are int64 sums of money in whole currency units, grouped by thousands where the format
+	// has display formatting
+	Amount
+	// Month - values are time.Time at the first day of a month, written as MM-YYYY by text formats
+	Month
+	// Time - values are time.Time instants, written as RFC 3339 in UTC by text formats
+	Time
+)
+
+// Column — a named column of an export
+type Column struct {
+	Name string
+	Kind Kind
+}
+
+// Exporter — writes a table to a file: the header once, then the rows, then Flush. A row holds a value of the
+// column kind or nil, for an empty cell, per column. Nothing is guaranteed to reach the writer before Flush
+type Exporter interface {
+	// WriteHeader - start the file with the columns of every following row
+	WriteHeader(cols []Column) error
+	// WriteRow - append a row
+	WriteRow(values []any) error
+	// Flush - write out everything buffered and finish the file; the exporter is not used afterwards
+	Flush() error
+}
+
+// Format — a registered file format
+type Format struct {
+	// Name - value of ?format= selecting the format
+	Name string
+	// ContentType - media type of the file
+	ContentType string
+	// Extension - file name extension without the dot
+	Extension string
+	// New - create an exporter writing the file to w
+	New func(w io.Writer) Exporter
+}
+
+var ErrUnknownFormat = errcode.New(errcode.FormatUnknown, "unknown export format")
+
+var (
+	mu      sync.RWMutex
+	formats = map[string]Format{}
+)
+
+// Register makes f selectable by its name; it panics when the name is empty or already taken, like other
+// registration mistakes found at startup
+func Register(f Format) {
+	mu.Lock()
+	defer mu.Unlock()
+	if f.Name == "" || f.New == nil {
+		panic("export: format without a name or constructor")
+	}
+	if _, dup := formats[f.Name]; dup {
+		panic("export: format " + f.Name + " registered twice")
+	}
+	formats[f.Name] = f
+}
+
+// Lookup returns the format registered under name, case-insensitive
+func Lookup(name string) (Format, error) {
+	mu.RLock()
+	f, ok := formats[strings.ToLower(strings.TrimSpace(name))]
+	mu.RUnlock()
+	if !ok {
+		return Format{}, fmt.Errorf("%w %q: want %s", ErrUnknownFormat, name, strings.Join(Names(), ", "))
+	}
+	return f, nil
+}
+
+// Names lists the registered formats in alphabetical order
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// SubscriptionColumns — columns of an exported subscription, see SubscriptionRow
+var SubscriptionColumns = []Column{
+	{Name: "id", Kind: Int},
+	{Name: "user_id", Kind: String},
+	{Name: "service_name", Kind: String},
+	{Name: "cost", Kind: Amount},
+	{Name: "start_date", Kind: Month},
+	{Name: "end_date", Kind: Month},
+	{Name: "created_at", Kind: Time},
+	{Name: "updated_at", Kind: Time},
+}
+
+// SubscriptionRow returns the values of s in SubscriptionColumns order
+func SubscriptionRow(s *entity.Subscription) []any {
+	var end any
+	if s.DateTo != nil {
+		end = *s.DateTo
+	}
+	return []any{s.ID, s.UserID.String(), s.ServiceName, s.Cost, s.DateFrom, end, s.CreatedAt, s.UpdatedAt}
+}
+
+// text renders v of the kind for formats that store every value as text; nil is empty
+func text(kind Kind, v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case time.Time:
+		if kind == Month {
+			return dates.Format(v), nil
+		}
+		return v.UTC().Format(time.RFC3339), nil
+	default:
+		return "", fmt.Errorf("export: unsupported value %T", v)
+	}
+}
+
+// checkRow reports a row whose length does not match the header
+func checkRow(cols []Column, values []any) error {
+	if len(values) != len(cols) {
+		return fmt.Errorf("export: row has %d values for %d columns", len(values), len(cols))
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/internal/entity"
+)
+
+func sampleSub(t *testing.T) *entity.Subscription {
+	t.Helper()
+	uid, err := entity.ParseUserID("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+	require.NoError(t, err)
+	at := time.Date(2025, time.March, 4, 10, 30, 0, 0, time.FixedZone("MSK", 3*60*60))
+	return &entity.Subscription{
+		ID: 7, UserID: uid, ServiceName: `Yandex "Plus", family`, Cost: 399,
+		DateFrom: time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC), CreatedAt: at, UpdatedAt: at,
+	}
+}
+
+func exportSubs(t *testing.T, name string, subs ...*entity.Subscription) string {
+	t.Helper()
+	f, err := Lookup(name)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	e := f.New(&buf)
+	require.NoError(t, e.WriteHeader(SubscriptionColumns))
+	for _, s := range subs {
+		require.NoError(t, e.WriteRow(SubscriptionRow(s)))
+	}
+	require.NoError(t, e.Flush())
+	return buf.String()
+}
+
+func TestCSV(t *testing.T) {
+	got := exportSubs(t, "csv", sampleSub(t))
+	assert.Equal(t, "id,user_id,service_name,cost,start_date,end_date,created_at,updated_at\n"+
+		`7,60601fee-2bf1-4721-ae6f-7636e79a0cba,"Yandex ""Plus"", family",399,03-2025,,2025-03-04T07:30:00Z,2025-03-04T07:30:00Z`+"\n", got)
+
+	assert.Equal(t, "id,user_id,service_name,cost,start_date,end_date,created_at,updated_at\n", exportSubs(t, "csv"),
+		"an empty export still has the header")
+}
+
+func TestNDJSON(t *testing.T) {
+	s := sampleSub(t)
+	end := time.Date(2025, time.December, 1, 0, 0, 0, 0, time.UTC)
+	ended := *s
+	ended.DateTo = &end
+	got := exportSubs(t, "ndjson", s, &ended)
+	assert.Equal(t,
+		`{"id":7,"user_id":"60601fee-2bf1-4721-ae6f-7636e79a0cba","service_name":"Yandex \"Plus\", family","cost":399,"start_date":"03-2025","end_date":null,"created_at":"2025-03-04T07:30:00Z","updated_at":"2025-03-04T07:30:00Z"}`+"\n"+
+			`{"id":7,"user_id":"60601fee-2bf1-4721-ae6f-7636e79a0cba","service_name":"Yandex \"Plus\", family","cost":399,"start_date":"03-2025","end_date":"12-2025","created_at":"2025-03-04T07:30:00Z","updated_at":"2025-03-04T07:30:00Z"}`+"\n",
+		got)
+	assert.Empty(t, exportSubs(t, "ndjson"))
+}
+
+func TestRowMustMatchHeader(t *testing.T) {
+	for _, name := range Names() {
+		f, err := Lookup(name)
+		require.NoError(t, err)
+		e := f.New(io.Discard)
+		require.NoError(t, e.WriteHeader([]Column{{Name: "id", Kind: Int}}))
+		assert.Error(t, e.WriteRow([]any{int64(1), "extra"}), name)
+		assert.Error(t, e.WriteRow([]any{3.5}), name)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	f, err := Lookup(" CSV ")
+	require.NoError(t, err)
+	assert.Equal(t, "csv", f.Name)
+
+	_, err = Lookup("xml")
+	assert.True(t, errors.Is(err, ErrUnknownFormat))
+	assert.ErrorContains(t, err, "want csv, ndjson, parquet, xlsx")
+
+	assert.Panics(t, func() { Register(Format{Name: "csv", New: func(io.Writer) Exporter { return nil }}) })
+}
+
+// xlsxCells reads the cells of a worksheet of the workbook by reference: inline text, value or "=" formula
+func xlsxCells(t *testing.T, book []byte, sheet string) map[string]string {
+	t.Helper()
+	z, err := zip.NewReader(bytes.NewReader(book), int64(len(book)))
+	require.NoError(t, err)
+	f, err := z.Open(sheet)
+	require.NoError(t, err)
+	defer f.Close()
+	var ws struct {
+		Rows []struct {
+			Cells []struct {
+				Ref     string `xml:"r,attr"`
+				Value   string `xml:"v"`
+				Text    string `xml:"is>t"`
+				Formula string `xml:"f"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	require.NoError(t, xml.NewDecoder(f).Decode(&ws))
+	cells := map[string]string{}
+	for _, r := range ws.Rows {
+		for _, c := range r.Cells {
+			switch {
+			case c.Formula != "":
+				cells[c.Ref] = "=" + c.Formula
+			case c.Text != "":
+				cells[c.Ref] = c.Text
+			default:
+				cells[c.Ref] = c.Value
+			}
+		}
+	}
+	return cells
+}
+
+func TestXLSX(t *testing.T) {
+	defer func(orig func() time.Time) { now = orig }(now)
+	now = func() time.Time { return time.Date(2025, time.May, 20, 0, 0, 0, 0, time.UTC) }
+
+	netflix := sampleSub(t)
+	netflix.ServiceName = "Netflix"
+	end := time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC)
+	ended := *netflix
+	ended.ID, ended.Cost, ended.DateTo = 8, 1000, &end
+	spotify := *netflix
+	spotify.ID, spotify.ServiceName, spotify.Cost = 9, "Spotify & Co", 299
+	spotify.DateFrom = time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	book := []byte(exportSubs(t, "xlsx", netflix, &ended, &spotify))
+
+	z, err := zip.NewReader(bytes.NewReader(book), int64(len(book)))
+	require.NoError(t, err)
+	var names []string
+	for _, f := range z.File {
+		names = append(names, f.Name)
+	}
+	assert.Subset(t, names, []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/styles.xml",
+		"xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml", "xl/worksheets/sheet3.xml", "xl/charts/chart1.xml"})
+
+	data := xlsxCells(t, book, "xl/worksheets/sheet1.xml")
+	assert.Equal(t, "service_name", data["C1"])
+	assert.Equal(t, "Spotify & Co", data["C4"])
+	assert.Equal(t, "45717", data["E2"], "03-2025 as an Excel date")
+	assert.Equal(t, "45748", data["F3"])
+	assert.NotContains(t, data, "F2", "no cell for an open subscription's end")
+
+	services := xlsxCells(t, book, "xl/worksheets/sheet2.xml")
+	assert.Equal(t, map[string]string{
+		"A1": "Service", "B1": "Subscriptions", "C1": "Active", "D1": "Monthly cost", "E1": "Total spent",
+		// 399 for March..May plus 1000 for March..April
+		"A2": "Netflix", "B2": "2", "C2": "1", "D2": "399", "E2": "3197",
+		"A3": "Spotify & Co", "B3": "1", "C3": "0", "D3": "0", "E3": "0",
+		"A4": "Total", "B4": "=SUM(B2:B3)", "C4": "=SUM(C2:C3)", "D4": "=SUM(D2:D3)", "E4": "=SUM(E2:E3)",
+	}, services)
+
+	monthly := xlsxCells(t, book, "xl/worksheets/sheet3.xml")
+	assert.Equal(t, "45717", monthly["A2"], "from the earliest start")
+	assert.Equal(t, "1399", monthly["B2"])
+	assert.Equal(t, "399", monthly["B4"], "May")
+	assert.Equal(t, "698", monthly["B6"], "up to the latest start, past the current month")
+	assert.Equal(t, "2", monthly["C6"])
+	assert.NotContains(t, monthly, "A7")
+}
+
+func TestXLSXWithoutSummaries(t *testing.T) {
+	var buf bytes.Buffer
+	f, err := Lookup("xlsx")
+	require.NoError(t, err)
+	e := f.New(&buf)
+	require.NoError(t, e.WriteHeader([]Column{{Name: "id", Kind: Int}}))
+	require.NoError(t, e.WriteRow([]any{int64(1)}))
+	require.NoError(t, e.Flush())
+
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	for _, f := range z.File {
+		assert.NotEqual(t, "xl/worksheets/sheet2.xml", f.Name, "summaries need the subscription columns")
+	}
+	assert.Equal(t, map[string]string{"A1": "id", "A2": "1"}, xlsxCells(t, buf.Bytes(), "xl/worksheets/sheet1.xml"))
+}
+
+func TestParquet(t *testing.T) {
+	s := sampleSub(t)
+	end := time.Date(2025, time.December, 1, 0, 0, 0, 0, time.UTC)
+	ended := *s
+	ended.ID, ended.Cost, ended.DateTo = 8, 0, &end
+	data := []byte(exportSubs(t, "parquet", s, &ended))
+
+	f, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	var names []string
+	for _, field := range f.Schema().Fields() {
+		names = append(names, field.Name())
+		assert.True(t, field.Optional(), field.Name())
+	}
+	assert.Equal(t, []string{"id", "user_id", "service_name", "cost", "start_date", "end_date", "created_at", "updated_at"}, names,
+		"columns keep the export order")
+
+	r := parquet.NewReader(f)
+	rows := make([]parquet.Row, 3)
+	n, err := r.ReadRows(rows)
+	require.ErrorIs(t, err, io.EOF)
+	require.Equal(t, 2, n)
+	assert.Equal(t, int64(7), rows[0][0].Int64())
+	assert.Equal(t, s.ServiceName, rows[0][2].String())
+	assert.Equal(t, int64(399), rows[0][3].Int64())
+	assert.Equal(t, int32(20148), rows[0][4].Int32(), "2025-03-01 in days since the epoch")
+	assert.True(t, rows[0][5].IsNull())
+	assert.Equal(t, int32(20423), rows[1][5].Int32())
+	assert.False(t, rows[1][3].IsNull(), "zero is a value")
+	assert.Equal(t, s.CreatedAt.UnixMilli(), rows[0][6].Int64())
+}
+
+func BenchmarkNDJSON(b *testing.B) {
+	uid, err := entity.ParseUserID("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+	require.NoError(b, err)
+	at := time.Date(2025, time.March, 4, 10, 30, 0, 0, time.UTC)
+	rows := make([][]any, 0, 1000)
+	for i := range 1000 {
+		rows = append(rows, SubscriptionRow(&entity.Subscription{
+			ID: int64(i + 1), UserID: uid, ServiceName: "Service", Cost: 399,
+			DateFrom: time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC), CreatedAt: at, UpdatedAt: at,
+		}))
+	}
+	f, err := Lookup("ndjson")
+	require.NoError(b, err)
+	b.ReportAllocs()
+	for b.Loop() {
+		e := f.New(io.Discard)
+		if err := e.WriteHeader(SubscriptionColumns); err != nil {
+			b.Fatal(err)
+		}
+		for _, r := range rows {
+			if err := e.WriteRow(r); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := e.Flush(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
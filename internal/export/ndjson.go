@@ -0,0 +1,72 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"subs_tracker/pkg/jsonenc"
+)
+
+func init() {
+	Register(Format{
+		Name:        "ndjson",
+		ContentType: "application/x-ndjson",
+		Extension:   "ndjson",
+		New:         func(w io.Writer) Exporter { return &ndjsonExporter{w: bufio.NewWriter(w)} },
+	})
+}
+
+// ndjsonExporter writes a JSON object per row, one per line, with keys in column order. Integers stay
+// numbers, empty cells are null; the header itself is not written
+type ndjsonExporter struct {
+	w    *bufio.Writer
+	keys [][]byte
+	cols []Column
+	buf  []byte
+}
+
+func (e *ndjsonExporter) WriteHeader(cols []Column) error {
+	e.cols = cols
+	e.keys = make([][]byte, len(cols))
+	for i, c := range cols {
+		key, err := json.Marshal(c.Name)
+		if err != nil {
+			return err
+		}
+		e.keys[i] = append(key, ':')
+	}
+	return nil
+}
+
+func (e *ndjsonExporter) WriteRow(values []any) error {
+	if err := checkRow(e.cols, values); err != nil {
+		return err
+	}
+	e.buf = append(e.buf[:0], '{')
+	for i, v := range values {
+		if i > 0 {
+			e.buf = append(e.buf, ',')
+		}
+		e.buf = append(e.buf, e.keys[i]...)
+		switch v := v.(type) {
+		case nil:
+			e.buf = append(e.buf, "null"...)
+		case int64:
+			e.buf = jsonenc.AppendInt(e.buf, v)
+		default:
+			s, err := text(e.cols[i].Kind, v)
+			if err != nil {
+				return err
+			}
+			e.buf = jsonenc.AppendString(e.buf, s)
+		}
+	}
+	e.buf = append(e.buf, '}', '\n')
+	_, err := e.w.Write(e.buf)
+	return err
+}
+
+func (e *ndjsonExporter) Flush() error {
+	return e.w.Flush()
+}
@@ -0,0 +1,104 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func init() {
+	Register(Format{
+		Name:        "parquet",
+		ContentType: "application/vnd.apache.parquet",
+		Extension:   "parquet",
+		New:         func(w io.Writer) Exporter { return &parquetExporter{out: w} },
+	})
+}
+
+// parquetExporter writes a zstd-compressed Parquet file for DuckDB, Spark and the like. Columns keep their
+// order and are optional; months are dates and instants millisecond timestamps in UTC, as in the archive.
+// Row groups are held in memory until they are full or Flush closes the file
+type parquetExporter struct {
+	out  io.Writer
+	w    *parquet.Writer
+	cols []Column
+	row  parquet.Row
+}
+
+// parquetFields — Go type and tag of a column of each kind. The schema is taken from a struct built of them,
+// since a parquet.Group would sort the columns by name; rows are written as values, so that zero is not null
+var parquetFields = map[Kind]struct {
+	typ reflect.Type
+	tag string
+	// value - type of the row values
+	value reflect.Type
+}{
+	String: {reflect.TypeFor[string](), "", reflect.TypeFor[string]()},
+	Int:    {reflect.TypeFor[int64](), "", reflect.TypeFor[int64]()},
+	Amount: {reflect.TypeFor[int64](), "", reflect.TypeFor[int64]()},
+	Month:  {reflect.TypeFor[int32](), ",date", reflect.TypeFor[time.Time]()},
+	Time:   {reflect.TypeFor[time.Time](), ",timestamp(millisecond)", reflect.TypeFor[time.Time]()},
+}
+
+func (e *parquetExporter) WriteHeader(cols []Column) error {
+	fields := make([]reflect.StructField, len(cols))
+	for i, c := range cols {
+		f := parquetFields[c.Kind]
+		fields[i] = reflect.StructField{
+			Name: "F" + strconv.Itoa(i),
+			Type: f.typ,
+			Tag:  reflect.StructTag(`parquet:"` + c.Name + f.tag + `,optional"`),
+		}
+	}
+	e.cols = cols
+	e.row = make(parquet.Row, len(cols))
+	schema := parquet.SchemaOf(reflect.New(reflect.StructOf(fields)).Interface())
+	e.w = parquet.NewWriter(e.out, schema, parquet.Compression(&parquet.Zstd))
+	return nil
+}
+
+func (e *parquetExporter) WriteRow(values []any) error {
+	if err := checkRow(e.cols, values); err != nil {
+		return err
+	}
+	for i, v := range values {
+		if v == nil {
+			e.row[i] = parquet.NullValue().Level(0, 0, i)
+			continue
+		}
+		var val parquet.Value
+		switch kind := e.cols[i].Kind; v := v.(type) {
+		case string:
+			val = parquet.ByteArrayValue([]byte(v))
+		case int64:
+			val = parquet.Int64Value(v)
+		case time.Time:
+			if kind == Month {
+				val = parquet.Int32Value(int32(v.Unix() / (24 * 60 * 60)))
+			} else {
+				val = parquet.Int64Value(v.UnixMilli())
+			}
+		default:
+			return fmt.Errorf("export: unsupported value %T", v)
+		}
+		if reflect.TypeOf(v) != parquetFields[e.cols[i].Kind].value {
+			return fmt.Errorf("export: %T in column %s", v, e.cols[i].Name)
+		}
+		e.row[i] = val.Level(0, 1, i)
+	}
+	_, err := e.w.WriteRows([]parquet.Row{e.row})
+	return err
+}
+
+func (e *parquetExporter) Flush() error {
+	if e.w == nil {
+		if err := e.WriteHeader(nil); err != nil {
+			return err
+		}
+	}
+	return e.w.Close()
+}
@@ -0,0 +1,549 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"subs_tracker/pkg/dates"
+)
+
+func init() {
+	Register(Format{
+		Name:        "xlsx",
+		ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		Extension:   "xlsx",
+		New:         func(w io.Writer) Exporter { return &xlsxExporter{z: zip.NewWriter(w)} },
+	})
+}
+
+// now - current time deciding up to which month open subscriptions are counted in the summary sheets
+var now = time.Now
+
+// Sheet names of the workbook
+const (
+	dataSheet    = "Subscriptions"
+	serviceSheet = "Services"
+	monthlySheet = "Monthly cost"
+)
+
+// Cell styles, indexes into cellXfs of xlsxStyles
+const (
+	styleDefault = iota
+	styleHeader
+	styleAmount
+	styleMonth
+	styleTime
+)
+
+// xlsxExporter writes an Excel workbook. Rows go to the data sheet as they come; when the columns include
+// service_name, cost, start_date and end_date, Flush adds a per-service summary sheet and a sheet of monthly
+// totals with a column chart. Open subscriptions count up to the current month, or to the latest month of the
+// export if that is later
+type xlsxExporter struct {
+	z    *zip.Writer
+	w    *bufio.Writer
+	cols []Column
+	rows int
+	// subs - what the summary sheets need of every row; nil when the columns do not allow the summaries
+	subs []xlsxSub
+	// idx - positions of service_name, cost, start_date and end_date among the columns
+	idx [4]int
+	buf []byte
+}
+
+// xlsxSub — a row as seen by the summary sheets
+type xlsxSub struct {
+	service    string
+	cost       int64
+	start, end time.Time // end is zero for open subscriptions
+}
+
+func (e *xlsxExporter) WriteHeader(cols []Column) error {
+	e.cols = cols
+	e.idx = [4]int{-1, -1, -1, -1}
+	want := []Column{{"service_name", String}, {"cost", Amount}, {"start_date", Month}, {"end_date", Month}}
+	summaries := true
+	for i, c := range want {
+		e.idx[i] = slices.Index(cols, c)
+		summaries = summaries && e.idx[i] >= 0
+	}
+	if summaries {
+		e.subs = []xlsxSub{}
+	}
+
+	f, err := e.z.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	e.w = bufio.NewWriter(f)
+	widths := make([]float64, len(cols))
+	names := make([]any, len(cols))
+	for i, c := range cols {
+		widths[i] = kindWidth[c.Kind]
+		names[i] = c.Name
+	}
+	e.buf = sheetStart(e.buf[:0], widths)
+	e.buf = appendRow(e.buf, 1, names, headerStyle)
+	e.rows = 1
+	_, err = e.w.Write(e.buf)
+	return err
+}
+
+func (e *xlsxExporter) WriteRow(values []any) error {
+	if err := checkRow(e.cols, values); err != nil {
+		return err
+	}
+	for _, v := range values {
+		switch v.(type) {
+		case nil, string, int64, time.Time:
+		default:
+			return fmt.Errorf("export: unsupported value %T", v)
+		}
+	}
+	if e.subs != nil {
+		s := xlsxSub{}
+		s.service, _ = values[e.idx[0]].(string)
+		s.cost, _ = values[e.idx[1]].(int64)
+		s.start, _ = values[e.idx[2]].(time.Time)
+		s.end, _ = values[e.idx[3]].(time.Time)
+		e.subs = append(e.subs, s)
+	}
+	e.rows++
+	e.buf = appendRow(e.buf[:0], e.rows, values, func(i int) int { return kindStyle[e.cols[i].Kind] })
+	_, err := e.w.Write(e.buf)
+	return err
+}
+
+func (e *xlsxExporter) Flush() error {
+	if e.w == nil {
+		if err := e.WriteHeader(nil); err != nil {
+			return err
+		}
+	}
+	ref := fmt.Sprintf("A1:%s%d", colName(max(len(e.cols), 1)-1), e.rows)
+	e.buf = append(e.buf[:0], `</sheetData><autoFilter ref="`...)
+	e.buf = append(e.buf, ref...)
+	e.buf = append(e.buf, `"/></worksheet>`...)
+	if _, err := e.w.Write(e.buf); err != nil {
+		return err
+	}
+	if err := e.w.Flush(); err != nil {
+		return err
+	}
+
+	sheets := []string{dataSheet}
+	chart := false
+	if e.subs != nil {
+		sheets = append(sheets, serviceSheet, monthlySheet)
+		services, months := summarize(e.subs, dates.MonthStart(now().UTC()))
+		if err := e.part("xl/worksheets/sheet2.xml", serviceSheetXML(services)); err != nil {
+			return err
+		}
+		chart = len(months) > 0
+		if err := e.part("xl/worksheets/sheet3.xml", monthlySheetXML(months, chart)); err != nil {
+			return err
+		}
+		if chart {
+			for _, p := range []xlsxPart{
+				{"xl/worksheets/_rels/sheet3.xml.rels", rels(rel{"rId1", relDrawing, "../drawings/drawing1.xml"})},
+				{"xl/drawings/drawing1.xml", xlsxDrawing},
+				{"xl/drawings/_rels/drawing1.xml.rels", rels(rel{"rId1", relChart, "../charts/chart1.xml"})},
+				{"xl/charts/chart1.xml", chartXML(len(months))},
+			} {
+				if err := e.part(p.name, p.body); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	wbRels := make([]rel, 0, len(sheets)+1)
+	for i := range sheets {
+		wbRels = append(wbRels, rel{fmt.Sprintf("rId%d", i+1), relWorksheet, fmt.Sprintf("worksheets/sheet%d.xml", i+1)})
+	}
+	wbRels = append(wbRels, rel{fmt.Sprintf("rId%d", len(sheets)+1), relStyles, "styles.xml"})
+	for _, p := range []xlsxPart{
+		{"[Content_Types].xml", contentTypes(len(sheets), chart)},
+		{"_rels/.rels", rels(rel{"rId1", relOfficeDocument, "xl/workbook.xml"})},
+		{"xl/workbook.xml", workbookXML(sheets, ref)},
+		{"xl/_rels/workbook.xml.rels", rels(wbRels...)},
+		{"xl/styles.xml", xlsxStyles},
+	} {
+		if err := e.part(p.name, p.body); err != nil {
+			return err
+		}
+	}
+	return e.z.Close()
+}
+
+// xlsxPart — a file of the workbook archive
+type xlsxPart struct{ name, body string }
+
+// part adds a file to the workbook archive
+func (e *xlsxExporter) part(name, body string) error {
+	f, err := e.z.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, body)
+	return err
+}
+
+// appendRow appends row n of values, the cell in column i styled by style(i)
+func appendRow(b []byte, n int, values []any, style func(i int) int) []byte {
+	b = append(b, `<row r="`...)
+	b = strconv.AppendInt(b, int64(n), 10)
+	b = append(b, `">`...)
+	for i, v := range values {
+		b = appendCell(b, colName(i)+strconv.Itoa(n), v, style(i))
+	}
+	return append(b, `</row>`...)
+}
+
+// appendCell appends a cell; strings are stored inline, times as Excel serial dates, nil as no cell
+func appendCell(b []byte, ref string, v any, style int) []byte {
+	if v == nil {
+		return b
+	}
+	b = append(b, `<c r="`...)
+	b = append(b, ref...)
+	b = append(b, '"')
+	if style != styleDefault {
+		b = append(b, ` s="`...)
+		b = strconv.AppendInt(b, int64(style), 10)
+		b = append(b, '"')
+	}
+	switch v := v.(type) {
+	case string:
+		b = append(b, ` t="inlineStr"><is><t xml:space="preserve">`...)
+		b = appendEscaped(b, v)
+		b = append(b, `</t></is></c>`...)
+	case int64:
+		b = append(b, `><v>`...)
+		b = strconv.AppendInt(b, v, 10)
+		b = append(b, `</v></c>`...)
+	case time.Time:
+		b = append(b, `><v>`...)
+		b = strconv.AppendFloat(b, serial(v), 'f', -1, 64)
+		b = append(b, `</v></c>`...)
+	case formula:
+		b = append(b, `><f>`...)
+		b = append(b, v...)
+		b = append(b, `</f></c>`...)
+	}
+	return b
+}
+
+// formula — a cell computed by Excel when the workbook is opened
+type formula string
+
+var excelEpoch = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+// serial converts t to the days since the Excel epoch, the way the workbook stores dates, in UTC
+func serial(t time.Time) float64 {
+	return t.UTC().Sub(excelEpoch).Seconds() / (24 * 60 * 60)
+}
+
+func appendEscaped(b []byte, s string) []byte {
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(s))
+	return append(b, sb.String()...)
+}
+
+// colName returns the letters of the zero-based column i: A, B, ..., Z, AA, ...
+func colName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+var kindStyle = map[Kind]int{String: styleDefault, Int: styleDefault, Amount: styleAmount, Month: styleMonth, Time: styleTime}
+
+var kindWidth = map[Kind]float64{String: 24, Int: 10, Amount: 14, Month: 12, Time: 20}
+
+// serviceTotals — a row of the per-service summary
+type serviceTotals struct {
+	service       string
+	subscriptions int64
+	// active, monthly - subscriptions active in the current month and their summed cost
+	active, monthly int64
+	// spent - paid so far: cost times the months each subscription was active up to the current month
+	spent int64
+}
+
+// monthTotals — a row of the monthly cost sheet
+type monthTotals struct {
+	month  time.Time
+	cost   int64
+	active int64
+}
+
+// summarize totals subs per service, by name, and per month from the earliest start up to current or the
+// latest start or end month, whichever is later
+func summarize(subs []xlsxSub, current time.Time) ([]serviceTotals, []monthTotals) {
+	byService := map[string]*serviceTotals{}
+	last := current
+	var first time.Time
+	for _, s := range subs {
+		t := byService[s.service]
+		if t == nil {
+			t = &serviceTotals{service: s.service}
+			byService[s.service] = t
+		}
+		t.subscriptions++
+		if activeIn(s, current) {
+			t.active++
+			t.monthly += s.cost
+		}
+		if !s.start.After(current) {
+			to := current
+			if !s.end.IsZero() && s.end.Before(to) {
+				to = s.end
+			}
+			t.spent += s.cost * int64(monthsBetween(s.start, to)+1)
+		}
+		if first.IsZero() || s.start.Before(first) {
+			first = s.start
+		}
+		last = latest(last, s.start, s.end)
+	}
+
+	services := make([]serviceTotals, 0, len(byService))
+	for _, t := range byService {
+		services = append(services, *t)
+	}
+	slices.SortFunc(services, func(a, b serviceTotals) int { return strings.Compare(a.service, b.service) })
+
+	var months []monthTotals
+	if len(subs) > 0 {
+		months = make([]monthTotals, monthsBetween(first, last)+1)
+		for i := range months {
+			months[i].month = first.AddDate(0, i, 0)
+		}
+		for _, s := range subs {
+			to := len(months) - 1
+			if !s.end.IsZero() {
+				to = monthsBetween(first, s.end)
+			}
+			for i := monthsBetween(first, s.start); i <= to; i++ {
+				months[i].cost += s.cost
+				months[i].active++
+			}
+		}
+	}
+	return services, months
+}
+
+func activeIn(s xlsxSub, month time.Time) bool {
+	return !s.start.After(month) && (s.end.IsZero() || !s.end.Before(month))
+}
+
+// monthsBetween counts whole months from a to b, negative when b is earlier
+func monthsBetween(a, b time.Time) int {
+	return (b.Year()-a.Year())*12 + int(b.Month()) - int(a.Month())
+}
+
+func latest(t time.Time, others ...time.Time) time.Time {
+	for _, o := range others {
+		if o.After(t) {
+			t = o
+		}
+	}
+	return t
+}
+
+func serviceSheetXML(services []serviceTotals) string {
+	b := sheetStart(nil, []float64{24, 14, 10, 14, 14})
+	header := []any{"Service", "Subscriptions", "Active", "Monthly cost", "Total spent"}
+	b = appendRow(b, 1, header, headerStyle)
+	for i, t := range services {
+		n := strconv.Itoa(i + 2)
+		b = append(b, `<row r="`+n+`">`...)
+		b = appendCell(b, "A"+n, t.service, styleDefault)
+		b = appendCell(b, "B"+n, t.subscriptions, styleDefault)
+		b = appendCell(b, "C"+n, t.active, styleDefault)
+		b = appendCell(b, "D"+n, t.monthly, styleAmount)
+		b = appendCell(b, "E"+n, t.spent, styleAmount)
+		b = append(b, `</row>`...)
+	}
+	last, n := strconv.Itoa(len(services)+1), strconv.Itoa(len(services)+2)
+	b = append(b, `<row r="`+n+`">`...)
+	b = appendCell(b, "A"+n, "Total", styleHeader)
+	for _, col := range []string{"B", "C", "D", "E"} {
+		style := styleDefault
+		if col == "D" || col == "E" {
+			style = styleAmount
+		}
+		b = appendCell(b, col+n, formula("SUM("+col+"2:"+col+last+")"), style)
+	}
+	b = append(b, `</row></sheetData></worksheet>`...)
+	return string(b)
+}
+
+func monthlySheetXML(months []monthTotals, chart bool) string {
+	b := sheetStart(nil, []float64{12, 14, 10})
+	b = appendRow(b, 1, []any{"Month", "Cost", "Active"}, headerStyle)
+	for i, m := range months {
+		n := strconv.Itoa(i + 2)
+		b = append(b, `<row r="`+n+`">`...)
+		b = appendCell(b, "A"+n, m.month, styleMonth)
+		b = appendCell(b, "B"+n, m.cost, styleAmount)
+		b = appendCell(b, "C"+n, m.active, styleDefault)
+		b = append(b, `</row>`...)
+	}
+	b = append(b, `</sheetData>`...)
+	if chart {
+		b = append(b, `<drawing r:id="rId1"/>`...)
+	}
+	b = append(b, `</worksheet>`...)
+	return string(b)
+}
+
+func headerStyle(int) int { return styleHeader }
+
+// sheetStart opens a worksheet with a frozen header row and the column widths, up to the first row
+func sheetStart(b []byte, widths []float64) []byte {
+	b = append(b, xml.Header...)
+	b = append(b, `<worksheet xmlns="`+nsMain+`" xmlns:r="`+nsRelationships+`">`...)
+	b = append(b, `<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`...)
+	if len(widths) > 0 {
+		b = append(b, `<cols>`...)
+		for i, w := range widths {
+			n := strconv.Itoa(i + 1)
+			b = append(b, `<col min="`+n+`" max="`+n+`" width="`...)
+			b = strconv.AppendFloat(b, w, 'f', -1, 64)
+			b = append(b, `" customWidth="1"/>`...)
+		}
+		b = append(b, `</cols>`...)
+	}
+	return append(b, `<sheetData>`...)
+}
+
+const (
+	nsMain            = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+	nsRelationships   = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	relOfficeDocument = nsRelationships + "/officeDocument"
+	relWorksheet      = nsRelationships + "/worksheet"
+	relStyles         = nsRelationships + "/styles"
+	relDrawing        = nsRelationships + "/drawing"
+	relChart          = nsRelationships + "/chart"
+)
+
+type rel struct{ id, typ, target string }
+
+func rels(rs ...rel) string {
+	var sb strings.Builder
+	sb.WriteString(xml.Header)
+	sb.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for _, r := range rs {
+		fmt.Fprintf(&sb, `<Relationship Id="%s" Type="%s" Target="%s"/>`, r.id, r.typ, r.target)
+	}
+	sb.WriteString(`</Relationships>`)
+	return sb.String()
+}
+
+func contentTypes(sheets int, chart bool) string {
+	var sb strings.Builder
+	sb.WriteString(xml.Header)
+	sb.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	sb.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	sb.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	sb.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	sb.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := 1; i <= sheets; i++ {
+		fmt.Fprintf(&sb, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+	}
+	if chart {
+		sb.WriteString(`<Override PartName="/xl/drawings/drawing1.xml" ContentType="application/vnd.openxmlformats-officedocument.drawing+xml"/>`)
+		sb.WriteString(`<Override PartName="/xl/charts/chart1.xml" ContentType="application/vnd.openxmlformats-officedocument.drawingml.chart+xml"/>`)
+	}
+	sb.WriteString(`</Types>`)
+	return sb.String()
+}
+
+// workbookXML lists the sheets; filter is the range of the data sheet autofilter
+func workbookXML(sheets []string, filter string) string {
+	var sb strings.Builder
+	sb.WriteString(xml.Header)
+	sb.WriteString(`<workbook xmlns="` + nsMain + `" xmlns:r="` + nsRelationships + `"><sheets>`)
+	for i, name := range sheets {
+		fmt.Fprintf(&sb, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, name, i+1, i+1)
+	}
+	sb.WriteString(`</sheets><definedNames><definedName name="_xlnm._FilterDatabase" localSheetId="0" hidden="1">`)
+	sb.WriteString(sheetRef(dataSheet, filter))
+	sb.WriteString(`</definedName></definedNames><calcPr fullCalcOnLoad="1"/></workbook>`)
+	return sb.String()
+}
+
+// sheetRef returns an absolute reference to the A1:B2 style range of the sheet
+func sheetRef(sheet, cells string) string {
+	from, to, _ := strings.Cut(cells, ":")
+	abs := func(cell string) string {
+		i := strings.IndexAny(cell, "0123456789")
+		return "$" + cell[:i] + "$" + cell[i:]
+	}
+	ref := "'" + sheet + "'!" + abs(from)
+	if to != "" {
+		ref += ":" + abs(to)
+	}
+	return ref
+}
+
+// chartXML draws the monthly costs of rows 2 to n+1 of the monthly sheet as columns
+func chartXML(n int) string {
+	last := strconv.Itoa(n + 1)
+	return xml.Header + `<c:chartSpace xmlns:c="http://schemas.openxmlformats.org/drawingml/2006/chart" ` +
+		`xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:r="` + nsRelationships + `">` +
+		`<c:chart><c:title><c:tx><c:rich><a:bodyPr/><a:p><a:r><a:t>` + monthlySheet + `</a:t></a:r></a:p></c:rich></c:tx>` +
+		`<c:overlay val="0"/></c:title><c:autoTitleDeleted val="0"/><c:plotArea><c:layout/>` +
+		`<c:barChart><c:barDir val="col"/><c:grouping val="clustered"/><c:varyColors val="0"/>` +
+		`<c:ser><c:idx val="0"/><c:order val="0"/>` +
+		`<c:tx><c:strRef><c:f>` + sheetRef(monthlySheet, "B1") + `</c:f></c:strRef></c:tx>` +
+		`<c:cat><c:numRef><c:f>` + sheetRef(monthlySheet, "A2:A"+last) + `</c:f></c:numRef></c:cat>` +
+		`<c:val><c:numRef><c:f>` + sheetRef(monthlySheet, "B2:B"+last) + `</c:f></c:numRef></c:val></c:ser>` +
+		`<c:gapWidth val="50"/><c:axId val="1"/><c:axId val="2"/></c:barChart>` +
+		`<c:catAx><c:axId val="1"/><c:scaling><c:orientation val="minMax"/></c:scaling><c:delete val="0"/>` +
+		`<c:axPos val="b"/><c:numFmt formatCode="mm.yyyy" sourceLinked="0"/><c:tickLblPos val="nextTo"/>` +
+		`<c:crossAx val="2"/><c:crosses val="autoZero"/><c:auto val="1"/><c:lblAlgn val="ctr"/><c:lblOffset val="100"/>` +
+		`<c:noMultiLvlLbl val="0"/></c:catAx>` +
+		`<c:valAx><c:axId val="2"/><c:scaling><c:orientation val="minMax"/></c:scaling><c:delete val="0"/>` +
+		`<c:axPos val="l"/><c:majorGridlines/><c:numFmt formatCode="#,##0" sourceLinked="0"/><c:tickLblPos val="nextTo"/>` +
+		`<c:crossAx val="1"/><c:crosses val="autoZero"/><c:crossBetween val="between"/></c:valAx>` +
+		`</c:plotArea><c:plotVisOnly val="1"/></c:chart></c:chartSpace>`
+}
+
+// xlsxDrawing places the chart right of the monthly table
+const xlsxDrawing = xml.Header + `<xdr:wsDr xmlns:xdr="http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing" ` +
+	`xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><xdr:twoCellAnchor>` +
+	`<xdr:from><xdr:col>4</xdr:col><xdr:colOff>0</xdr:colOff><xdr:row>1</xdr:row><xdr:rowOff>0</xdr:rowOff></xdr:from>` +
+	`<xdr:to><xdr:col>16</xdr:col><xdr:colOff>0</xdr:colOff><xdr:row>22</xdr:row><xdr:rowOff>0</xdr:rowOff></xdr:to>` +
+	`<xdr:graphicFrame macro=""><xdr:nvGraphicFramePr><xdr:cNvPr id="2" name="` + monthlySheet + `"/><xdr:cNvGraphicFramePr/>` +
+	`</xdr:nvGraphicFramePr><xdr:xfrm><a:off x="0" y="0"/><a:ext cx="0" cy="0"/></xdr:xfrm>` +
+	`<a:graphic><a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/chart">` +
+	`<c:chart xmlns:c="http://schemas.openxmlformats.org/drawingml/2006/chart" xmlns:r="` + nsRelationships + `" r:id="rId1"/>` +
+	`</a:graphicData></a:graphic></xdr:graphicFrame><xdr:clientData/></xdr:twoCellAnchor></xdr:wsDr>`
+
+// xlsxStyles — bold headers, amounts grouped by thousands, months as MM.YYYY and instants with seconds;
+// the order of cellXfs matches the style constants
+const xlsxStyles = xml.Header + `<styleSheet xmlns="` + nsMain + `">` +
+	`<numFmts count="3"><numFmt numFmtId="164" formatCode="#,##0"/><numFmt numFmtId="165" formatCode="mm.yyyy"/>` +
+	`<numFmt numFmtId="166" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="5"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="166" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles></styleSheet>`
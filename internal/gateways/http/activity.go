@@ -0,0 +1,108 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/activity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/pkg/dates"
+	"subs_tracker/pkg/pagination"
+)
+
+// activityEntry is an item of GET /api/v1/users/{user_id}/activity.
+type activityEntry struct {
+	// Kind is created, price_changed, cancelled or deleted
+	Kind       string    `json:"kind"`
+	OccurredAt time.Time `json:"occurred_at"`
+	// SubscriptionID is left out under a public ID strategy, which sends SubscriptionPublicID instead
+	SubscriptionID       int64  `json:"subscription_id,omitempty"`
+	SubscriptionPublicID string `json:"subscription_public_id,omitempty"`
+	ServiceName          string `json:"service_name"`
+	Cost                 int64  `json:"cost"`
+	// PreviousCost is set for price_changed
+	PreviousCost *int64 `json:"previous_cost,omitempty"`
+	// EndDate is set for cancelled
+	EndDate string `json:"end_date,omitempty"`
+}
+
+// activityPage is the response of GET /api/v1/users/{user_id}/activity.
+type activityPage struct {
+	Items []activityEntry `json:"items"`
+	// NextCursor continues with older entries; absent on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// activityCursor is the position after the last entry of a page.
+type activityCursor struct {
+	Before int64 `json:"b"`
+}
+
+// setupActivity registers the activity feed of a user, newest first.
+func setupActivity(r *gin.RouterGroup, u UseCases, cursors *pagination.Codec, paging pagingPolicy) {
+	r.GET("/users/:user_id/activity", mw.Budget(budgetRead), func(c *gin.Context) {
+		uid, ok := deactivationUser(c)
+		if !ok || !paging.check(c, activity.Limits) {
+			return
+		}
+		if u.Activity == nil {
+			jsonErr(c, http.StatusForbidden, "user activity is disabled")
+			return
+		}
+		limit, err := pagination.ParseLimit(c.Query("limit"))
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.PaginationInvalid, "invalid limit")
+			return
+		}
+		var pos activityCursor
+		if v := strings.TrimSpace(c.Query("cursor")); v != "" {
+			if err := cursors.Decode(v, &pos); err != nil {
+				jsonErrCode(c, http.StatusUnprocessableEntity, errcode.CursorInvalid, pagination.ErrInvalidCursor.Error())
+				return
+			}
+		}
+
+		page, err := u.Activity.List(c, uid, pos.Before, limit)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		out := activityPage{Items: make([]activityEntry, 0, len(page.Entries))}
+		for _, e := range page.Entries {
+			out.Items = append(out.Items, buildActivityEntry(e))
+		}
+		if page.HasMore {
+			next, err := cursors.Encode(activityCursor{Before: page.Entries[len(page.Entries)-1].ID})
+			if err != nil {
+				jsonErr(c, http.StatusInternalServerError, "internal error")
+				return
+			}
+			out.NextCursor = next
+		}
+		c.JSON(http.StatusOK, out)
+	})
+}
+
+// buildActivityEntry maps an entry of the feed to the response, naming the subscription the way its event did.
+func buildActivityEntry(e activity.Entry) activityEntry {
+	out := activityEntry{
+		Kind:                 string(e.Kind),
+		OccurredAt:           e.At.UTC(),
+		SubscriptionID:       e.SubscriptionID,
+		SubscriptionPublicID: e.PublicID,
+		ServiceName:          e.ServiceName,
+		Cost:                 e.Cost,
+		EndDate:              dates.FormatPtr(e.EndDate),
+	}
+	if e.PublicID != "" {
+		out.SubscriptionID = 0
+	}
+	if e.Kind == activity.KindPriceChanged {
+		prev := e.PreviousCost
+		out.PreviousCost = &prev
+	}
+	return out
+}
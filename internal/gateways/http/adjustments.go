@@ -0,0 +1,105 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/pkg/dates"
+)
+
+// adjustmentRequest is the payload of POST /api/v1/subscriptions/{id}/adjustments.
+type adjustmentRequest struct {
+	Month string `json:"month" binding:"required"`
+	// Amount is added to the spend of the month: negative for a refund or credit
+	Amount int64  `json:"amount"`
+	Note   string `json:"note"`
+}
+
+// adjustment is a single adjustment in the responses of the /api/v1/subscriptions/{id}/adjustments routes.
+type adjustment struct {
+	ID int64 `json:"id"`
+	// SubscriptionID is left out for SubscriptionPublicID under a public ID strategy
+	SubscriptionID       int64     `json:"subscription_id,omitempty"`
+	SubscriptionPublicID string    `json:"subscription_public_id,omitempty"`
+	Month                string    `json:"month"`
+	Amount               int64     `json:"amount"`
+	Note                 string    `json:"note,omitempty"`
+	CreatedAt            time.Time `json:"created_at"`
+}
+
+// setupAdjustments registers recording and listing refunds and other adjustments of a subscription; cost
+// totals are net of them.
+func setupAdjustments(r *gin.RouterGroup, u UseCases, dp *dates.Parser) {
+	r.POST("/subscriptions/:id/adjustments", mw.Budget(budgetWrite), func(c *gin.Context) {
+		if !requireAcceptJSON(c) || !requireJSONContent(c) {
+			return
+		}
+		id, publicID, ok := subResourceID(c, u.Sub)
+		if !ok {
+			return
+		}
+		var req adjustmentRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			jsonErrOf(c, http.StatusBadRequest, err)
+			return
+		}
+		month, err := dp.Parse(req.Month)
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.DateInvalid, dateErrMsg("invalid month", err))
+			return
+		}
+
+		saved, err := u.Sub.RecordAdjustment(c, &entity.Adjustment{
+			SubscriptionID: id,
+			Month:          month,
+			Amount:         req.Amount,
+			Note:           req.Note,
+		})
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		c.JSON(http.StatusCreated, buildAdjustmentDTO(*saved, publicID))
+	})
+
+	r.GET("/subscriptions/:id/adjustments", mw.Budget(budgetRead), func(c *gin.Context) {
+		if !requireAcceptJSON(c) {
+			return
+		}
+		id, publicID, ok := subResourceID(c, u.Sub)
+		if !ok {
+			return
+		}
+		list, err := u.Sub.ListAdjustments(c, id)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		out := make([]adjustment, 0, len(list))
+		for _, a := range list {
+			out = append(out, buildAdjustmentDTO(a, publicID))
+		}
+		c.JSON(http.StatusOK, out)
+	})
+}
+
+// buildAdjustmentDTO maps an adjustment to the response, its month in the MM-YYYY form of subscription dates.
+// A non-empty publicID names the subscription instead of the serial ID.
+func buildAdjustmentDTO(a entity.Adjustment, publicID string) adjustment {
+	out := adjustment{
+		ID:                   a.ID,
+		SubscriptionID:       a.SubscriptionID,
+		SubscriptionPublicID: publicID,
+		Month:                dates.Format(a.Month),
+		Amount:               a.Amount,
+		Note:                 a.Note,
+		CreatedAt:            a.CreatedAt.UTC(),
+	}
+	if publicID != "" {
+		out.SubscriptionID = 0
+	}
+	return out
+}
@@ -0,0 +1,398 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/buildinfo"
+	cfg "subs_tracker/internal/config"
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/internal/integrations"
+	"subs_tracker/internal/leader"
+	"subs_tracker/internal/webhooks"
+)
+
+// adminInfo is the payload of GET /api/v1/admin/info.
+type adminInfo struct {
+	Build         buildinfo.Info    `json:"build"`
+	GoVersion     string            `json:"go_version"`
+	Goroutines    int               `json:"goroutines"`
+	Uptime        string            `json:"uptime"`
+	UptimeSeconds int64             `json:"uptime_seconds"`
+	Config        map[string]string `json:"config"`
+	Instance      *instanceInfo     `json:"instance,omitempty"`
+}
+
+// instanceInfo describes this replica among the others sharing the database.
+type instanceInfo struct {
+	Name           string         `json:"name"`
+	LeaderElection bool           `json:"leader_election"`
+	Leading        []leader.Lease `json:"leading"`
+}
+
+// reassignRequest is the payload of POST /api/v1/admin/users/reassign.
+type reassignRequest struct {
+	FromUserID string `json:"from_user_id" binding:"required"`
+	ToUserID   string `json:"to_user_id" binding:"required"`
+}
+
+// reassignResult is the response of POST /api/v1/admin/users/reassign.
+type reassignResult struct {
+	Moved int64 `json:"moved"`
+}
+
+// revokeTokensRequest is the payload of POST /api/v1/admin/tokens/revoke; both fields are optional.
+type revokeTokensRequest struct {
+	// IssuedBefore is the RFC 3339 cutoff, now when omitted.
+	IssuedBefore *time.Time `json:"issued_before"`
+	UserID       string     `json:"user_id"`
+}
+
+// revokeTokensResult is the response of POST /api/v1/admin/tokens/revoke.
+type revokeTokensResult struct {
+	Revoked      int64     `json:"revoked"`
+	IssuedBefore time.Time `json:"issued_before"`
+}
+
+// legacyCostsRequest is the payload of POST /api/v1/admin/migrations/legacy-costs; both fields are optional.
+type legacyCostsRequest struct {
+	DryRun    bool `json:"dry_run"`
+	BatchSize int  `json:"batch_size" binding:"omitempty,min=1"`
+}
+
+// legacyCostsResult is the response of POST /api/v1/admin/migrations/legacy-costs.
+type legacyCostsResult struct {
+	Pending   int64 `json:"pending"`
+	Migrated  int64 `json:"migrated"`
+	Remaining int64 `json:"remaining"`
+	Done      bool  `json:"done"`
+	DryRun    bool  `json:"dry_run"`
+}
+
+// webhookTestResult is the response of POST /api/v1/admin/webhooks/test.
+type webhookTestResult struct {
+	Delivered  bool            `json:"delivered"`
+	Event      string          `json:"event"`
+	Format     string          `json:"format"`
+	StatusCode int             `json:"status_code,omitempty"`
+	DurationMs int64           `json:"duration_ms"`
+	Error      string          `json:"error,omitempty"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+}
+
+// clientsReport is the response of GET /api/v1/admin/clients.
+type clientsReport struct {
+	WindowSeconds int64          `json:"window_seconds"`
+	Clients       []clientReport `json:"clients"`
+}
+
+// clientReport is the traffic of a client in the window.
+type clientReport struct {
+	Client      string     `json:"client"`
+	Requests    int        `json:"requests"`
+	Errors      int        `json:"errors"`
+	ErrorRate   float64    `json:"error_rate"`
+	Abusive     bool       `json:"abusive"`
+	BannedUntil *time.Time `json:"banned_until,omitempty"`
+}
+
+// integrationsReport is the response of GET /api/v1/admin/integrations/status.
+type integrationsReport struct {
+	// Degraded names the integrations that are degraded or down, empty when all are fine
+	Degraded     []string            `json:"degraded"`
+	Integrations []integrationStatus `json:"integrations"`
+}
+
+// integrationStatus is the soft service level of an outbound integration.
+type integrationStatus struct {
+	Name        string     `json:"name"`
+	State       string     `json:"state"`
+	Calls       int64      `json:"calls"`
+	Failures    int64      `json:"failures"`
+	SuccessRate float64    `json:"success_rate"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastFailure *time.Time `json:"last_failure,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+// cacheFlushResult is the response of POST /api/v1/admin/cache/flush.
+type cacheFlushResult struct {
+	// Flushed counts the current-month totals forgotten
+	Flushed int `json:"flushed"`
+	// Priming tells whether the largest spenders are computed again in the background, see HTTP_COST_NOW_PRIME
+	Priming bool `json:"priming"`
+}
+
+// maxClientsReported bounds ?limit= of GET /api/v1/admin/clients.
+const maxClientsReported = 1000
+
+// setupAdmin registers support endpoints for self-hosted installs.
+// All endpoints additionally require a bearer token with the admin scope, e.g. HTTP_ADMIN_TOKEN.
+func setupAdmin(r *gin.RouterGroup, conf cfg.Config, tokens *mw.Tokens, abuse *mw.Abuse, u UseCases) {
+	build := buildinfo.Get()
+	summary := redactSummaryURLs(conf.Summary())
+
+	r.GET("/info", mw.AdminToken(tokens), func(c *gin.Context) {
+		if !requireAcceptJSON(c) {
+			return
+		}
+		uptime := buildinfo.Uptime()
+		info := adminInfo{
+			Build:         build,
+			GoVersion:     runtime.Version(),
+			Goroutines:    runtime.NumGoroutine(),
+			Uptime:        uptime.Truncate(time.Second).String(),
+			UptimeSeconds: int64(uptime.Seconds()),
+			Config:        summary,
+		}
+		if u.Jobs != nil {
+			info.Instance = &instanceInfo{
+				Name:           u.Jobs.Instance(),
+				LeaderElection: conf.Jobs.LeaderElection,
+				Leading:        u.Jobs.Leading(),
+			}
+		}
+		c.JSON(http.StatusOK, info)
+	})
+
+	r.POST("/users/reassign", mw.AdminToken(tokens), func(c *gin.Context) {
+		if !requireAcceptJSON(c) || !requireJSONContent(c) {
+			return
+		}
+		var req reassignRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			jsonErrOf(c, http.StatusBadRequest, err)
+			return
+		}
+		from, err := entity.ParseUserID(req.FromUserID)
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.UserIDInvalid, "invalid from_user_id")
+			return
+		}
+		to, err := entity.ParseUserID(req.ToUserID)
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.UserIDInvalid, "invalid to_user_id")
+			return
+		}
+
+		moved, err := u.Sub.ReassignUser(c, from, to, c.ClientIP())
+		if errors.Is(err, entity.ErrInvalidUserID) {
+			jsonErrOf(c, http.StatusUnprocessableEntity, err)
+			return
+		}
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		c.JSON(http.StatusOK, reassignResult{Moved: moved})
+	})
+
+	// invalidates share link tokens issued before a cutoff, e.g. after a leak; recorded in the audit log
+	r.POST("/tokens/revoke", mw.AdminToken(tokens), func(c *gin.Context) {
+		if !requireAcceptJSON(c) || !requireJSONContent(c) {
+			return
+		}
+		if u.Shares == nil {
+			jsonErr(c, http.StatusForbidden, "sharing is disabled")
+			return
+		}
+		var req revokeTokensRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			jsonErrOf(c, http.StatusBadRequest, err)
+			return
+		}
+		var uid entity.UserID
+		if req.UserID != "" {
+			var err error
+			if uid, err = entity.ParseUserID(req.UserID); err != nil {
+				jsonErrCode(c, http.StatusUnprocessableEntity, errcode.UserIDInvalid, "invalid user_id")
+				return
+			}
+		}
+		before := u.Sub.Now()
+		if req.IssuedBefore != nil && req.IssuedBefore.Before(before) {
+			before = *req.IssuedBefore
+		}
+
+		n, err := u.Shares.RevokeIssuedBefore(c, uid, before, c.ClientIP())
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		c.JSON(http.StatusOK, revokeTokensResult{Revoked: n, IssuedBefore: before.UTC()})
+	})
+
+	// backfills the currency and minor-unit cost of subscriptions stored with the legacy ruble cost, a batch per
+	// call; repeated until done before multi-currency is enabled on an existing install
+	r.POST("/migrations/legacy-costs", mw.AdminToken(tokens), func(c *gin.Context) {
+		if !requireAcceptJSON(c) || !requireJSONContent(c) {
+			return
+		}
+		var req legacyCostsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			jsonErrOf(c, http.StatusBadRequest, err)
+			return
+		}
+		rep, err := u.Sub.MigrateLegacyCosts(c, req.BatchSize, req.DryRun)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		c.JSON(http.StatusOK, legacyCostsResult{
+			Pending:   rep.Pending,
+			Migrated:  rep.Migrated,
+			Remaining: rep.Remaining,
+			Done:      rep.Done(),
+			DryRun:    rep.DryRun,
+		})
+	})
+
+	// sends a sample event, webhook.test or the ?event= type, so no-code tools (Zapier, IFTTT) can pick up the
+	// payload fields; 502 reports an endpoint that did not accept it
+	r.POST("/webhooks/test", mw.AdminToken(tokens), func(c *gin.Context) {
+		if !requireAcceptJSON(c) {
+			return
+		}
+		if u.Webhooks == nil {
+			jsonErr(c, http.StatusForbidden, "webhooks are disabled")
+			return
+		}
+		event, err := webhooks.ParseSampleEvent(c.Query("event"))
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.ValidationFailed, err.Error())
+			return
+		}
+		d, err := u.Webhooks.Test(c, event)
+		res := webhookTestResult{
+			Delivered:  err == nil,
+			Event:      string(event),
+			Format:     string(u.Webhooks.Format()),
+			StatusCode: d.StatusCode,
+			DurationMs: d.Duration.Milliseconds(),
+			Payload:    d.Body,
+		}
+		if err != nil {
+			res.Error = err.Error()
+			c.JSON(http.StatusBadGateway, res)
+			return
+		}
+		c.JSON(http.StatusOK, res)
+	})
+
+	// requests and error rates per client in the rolling window of HTTP_ABUSE_WINDOW, busiest first
+	r.GET("/clients", mw.AdminToken(tokens), func(c *gin.Context) {
+		if !requireAcceptJSON(c) {
+			return
+		}
+		if abuse == nil {
+			jsonErr(c, http.StatusForbidden, "client tracking is disabled")
+			return
+		}
+		limit := 50
+		if v := c.Query("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxClientsReported {
+				jsonErr(c, http.StatusUnprocessableEntity, "invalid limit")
+				return
+			}
+			limit = n
+		}
+		out := clientsReport{WindowSeconds: int64(abuse.Window().Seconds()), Clients: []clientReport{}}
+		for _, st := range abuse.Clients(limit) {
+			cr := clientReport{
+				Client:    st.Client,
+				Requests:  st.Requests,
+				Errors:    st.Errors,
+				ErrorRate: st.ErrorRate,
+				Abusive:   st.Abusive,
+			}
+			if !st.BannedUntil.IsZero() {
+				until := st.BannedUntil.UTC()
+				cr.BannedUntil = &until
+			}
+			out.Clients = append(out.Clients, cr)
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	// lifts the ban of a client before its cooldown ends, e.g. after a false positive
+	r.DELETE("/clients/:client/ban", mw.AdminToken(tokens), func(c *gin.Context) {
+		if !abuse.Unban(c.Param("client")) {
+			jsonErr(c, http.StatusNotFound, "client is not banned")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	// forgets the current-month totals of /subscriptions/cost/now kept by this instance, e.g. after the database
+	// was restored or edited by hand; the primer then fills the cache again
+	r.POST("/cache/flush", mw.AdminToken(tokens), func(c *gin.Context) {
+		c.JSON(http.StatusOK, cacheFlushResult{Flushed: u.Sub.FlushCostNow(), Priming: u.Sub.CostNowPriming()})
+	})
+
+	// success rates of the latest calls to webhooks, brokers, Stripe and the usage sink, to spot a degraded
+	// integration at a glance; only calls made by this instance are counted
+	r.GET("/integrations/status", mw.AdminToken(tokens), func(c *gin.Context) {
+		if !requireAcceptJSON(c) {
+			return
+		}
+		out := integrationsReport{Degraded: []string{}, Integrations: []integrationStatus{}}
+		if u.Integrations == nil {
+			c.JSON(http.StatusOK, out)
+			return
+		}
+		for _, st := range u.Integrations.Status() {
+			is := integrationStatus{
+				Name:        st.Name,
+				State:       string(st.State),
+				Calls:       st.Calls,
+				Failures:    st.Failures,
+				SuccessRate: st.SuccessRate,
+				LastError:   st.LastError,
+			}
+			if !st.LastSuccess.IsZero() {
+				is.LastSuccess = &st.LastSuccess
+			}
+			if !st.LastFailure.IsZero() {
+				is.LastFailure = &st.LastFailure
+			}
+			if st.State == integrations.StateDegraded || st.State == integrations.StateDown {
+				out.Degraded = append(out.Degraded, st.Name)
+			}
+			out.Integrations = append(out.Integrations, is)
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	setupThemes(r, tokens, u)
+	setupPriceReviews(r, tokens, u)
+	setupReconciliation(r, tokens, u)
+}
+
+// redactSummaryURLs hides credentials, paths and queries of URL-valued settings in the configuration summary:
+// broker and collector URLs may embed a user and password, webhook URLs carry tokens in the path.
+// Values that are not URLs, e.g. HTTP_HOST, are kept as is.
+func redactSummaryURLs(summary map[string]string) map[string]string {
+	for key, value := range summary {
+		urlKey := strings.HasSuffix(key, "_URL") || strings.HasSuffix(key, "_ENDPOINT")
+		parts := strings.Split(value, ",")
+		for i, part := range parts {
+			u, err := url.Parse(strings.TrimSpace(part))
+			switch {
+			case err == nil && u.Scheme != "" && u.Host != "":
+				parts[i] = redactURL(u.String())
+			case urlKey && strings.TrimSpace(part) != "":
+				parts[i] = "***"
+			}
+		}
+		summary[key] = strings.Join(parts, ",")
+	}
+	return summary
+}
@@ -0,0 +1,84 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/dates"
+)
+
+// priceBenchmark is the anonymous cost statistics of one service.
+type priceBenchmark struct {
+	Service       string `json:"service"`
+	Users         int64  `json:"users"`
+	Subscriptions int64  `json:"subscriptions"`
+	AverageCost   int64  `json:"average_cost"`
+	MedianCost    int64  `json:"median_cost"`
+	YourCost      *int64 `json:"your_cost,omitempty"`
+}
+
+// priceBenchmarks is the response of GET /api/v1/subscriptions/benchmarks.
+type priceBenchmarks struct {
+	Month    string           `json:"month"`
+	Services []priceBenchmark `json:"services"`
+}
+
+// setupBenchmarks registers cross-user price statistics per service.
+func setupBenchmarks(r *gin.RouterGroup, u UseCases, dp *dates.Parser) {
+	r.GET("/subscriptions/benchmarks", mw.Budget(budgetReport), func(c *gin.Context) {
+		if !requireAcceptJSON(c) {
+			return
+		}
+		month := dates.MonthStart(u.Sub.Now())
+		if raw := strings.TrimSpace(c.Query("month")); raw != "" {
+			t, err := dp.Parse(raw)
+			if err != nil {
+				jsonErrCode(c, http.StatusUnprocessableEntity, errcode.DateInvalid, dateErrMsg("invalid month", err))
+				return
+			}
+			month = t
+		}
+		var uid entity.UserID
+		if raw := strings.TrimSpace(c.Query("user_id")); raw != "" {
+			id, err := entity.ParseUserID(raw)
+			if err != nil {
+				jsonErrCode(c, http.StatusUnprocessableEntity, errcode.UserIDInvalid, "uuid invalid")
+				return
+			}
+			uid = id
+		}
+		service := strings.ToLower(strings.TrimSpace(c.Query("service_name")))
+
+		list, err := u.Sub.PriceBenchmarks(c, month, uid)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		c.JSON(http.StatusOK, buildBenchmarksDTO(dates.MonthStart(month), list, service))
+	})
+}
+
+// buildBenchmarksDTO maps benchmarks to the response, keeping only service when it is set.
+func buildBenchmarksDTO(month time.Time, list []usecase.PriceBenchmark, service string) priceBenchmarks {
+	out := priceBenchmarks{Month: dates.Format(month), Services: []priceBenchmark{}}
+	for _, b := range list {
+		if service != "" && b.Service != service {
+			continue
+		}
+		out.Services = append(out.Services, priceBenchmark{
+			Service:       b.Service,
+			Users:         b.Users,
+			Subscriptions: b.Subscriptions,
+			AverageCost:   b.AverageCost,
+			MedianCost:    b.MedianCost,
+			YourCost:      b.YourCost,
+		})
+	}
+	return out
+}
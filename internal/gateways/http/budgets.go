@@ -0,0 +1,103 @@
+package http
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/dates"
+)
+
+// uncategorized groups services the catalog does not know, or every service when there is no catalog.
+const uncategorized = "other"
+
+// budgetCategory is the suggested monthly budget of one service category.
+type budgetCategory struct {
+	Category string   `json:"category"`
+	Budget   int64    `json:"budget"`
+	Services []string `json:"services"`
+}
+
+// budgetRecommendations is the response of GET /api/v1/users/{user_id}/budgets/recommendations.
+type budgetRecommendations struct {
+	From       string           `json:"from"`
+	To         string           `json:"to"`
+	Months     int              `json:"months"`
+	Currency   string           `json:"currency"`
+	Total      int64            `json:"total"`
+	Categories []budgetCategory `json:"categories"`
+}
+
+// setupBudgets registers budget suggestions drawn from the spend history.
+func setupBudgets(r *gin.RouterGroup, u UseCases) {
+	r.GET("/users/:user_id/budgets/recommendations", mw.Budget(budgetReport), func(c *gin.Context) {
+		if !requireAcceptJSON(c) {
+			return
+		}
+		uid, err := entity.ParseUserID(c.Param("user_id"))
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.UserIDInvalid, "uuid invalid")
+			return
+		}
+		settings, err := u.Sub.GetSettings(c, uid)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		basis, err := u.Sub.BudgetBasis(c, uid)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+
+		categories := make(map[string]string, len(basis.Services))
+		if u.Catalog != nil {
+			names := make([]string, 0, len(basis.Services))
+			for _, s := range basis.Services {
+				names = append(names, s.ServiceName)
+			}
+			for name, info := range u.Catalog.Enrich(c, names) {
+				categories[name] = info.Category
+			}
+		}
+		c.JSON(http.StatusOK, buildBudgetRecommendations(basis, categories, settings.Currency))
+	})
+}
+
+// buildBudgetRecommendations sums the average spend of the services per category; categories are ordered by
+// budget, largest first.
+func buildBudgetRecommendations(basis usecase.BudgetBasis, categories map[string]string, currency string) budgetRecommendations {
+	out := budgetRecommendations{
+		From:       dates.Format(basis.From),
+		To:         dates.Format(basis.To),
+		Months:     usecase.BudgetMonths,
+		Currency:   currency,
+		Categories: []budgetCategory{},
+	}
+	index := make(map[string]int)
+	for _, s := range basis.Services {
+		category := categories[s.ServiceName]
+		if category == "" {
+			category = uncategorized
+		}
+		i, ok := index[category]
+		if !ok {
+			i = len(out.Categories)
+			index[category] = i
+			out.Categories = append(out.Categories, budgetCategory{Category: category})
+		}
+		out.Categories[i].Budget += s.Average
+		out.Categories[i].Services = append(out.Categories[i].Services, s.ServiceName)
+		out.Total += s.Average
+	}
+	sort.SliceStable(out.Categories, func(i, j int) bool {
+		if out.Categories[i].Budget != out.Categories[j].Budget {
+			return out.Categories[i].Budget > out.Categories[j].Budget
+		}
+		return out.Categories[i].Category < out.Categories[j].Category
+	})
+	return out
+}
@@ -0,0 +1,106 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/dates"
+)
+
+// calendarEvent is a single charge shown on a calendar day.
+type calendarEvent struct {
+	SubscriptionID       int64  `json:"subscription_id,omitempty"`
+	SubscriptionPublicID string `json:"subscription_public_id,omitempty"`
+	ServiceName          string `json:"service_name"`
+	Cost                 int64  `json:"cost"`
+	Kind                 string `json:"kind"`
+}
+
+// calendarDay is one cell of the month grid; days without charges have no events.
+type calendarDay struct {
+	Date    string          `json:"date"`
+	Weekday int             `json:"weekday"`
+	Total   int64           `json:"total"`
+	Events  []calendarEvent `json:"events"`
+}
+
+// calendarMonth is the response of GET /api/v1/subscriptions/calendar.
+type calendarMonth struct {
+	Month string `json:"month"`
+	// MonthLabel is the month spelled out in the response language, with ?labels=true only
+	MonthLabel     string        `json:"month_label,omitempty"`
+	FirstDayOfWeek int           `json:"first_day_of_week"`
+	Currency       string        `json:"currency"`
+	Total          int64         `json:"total"`
+	Days           []calendarDay `json:"days"`
+}
+
+// setupCalendar registers the month calendar of charges.
+func setupCalendar(r *gin.RouterGroup, u UseCases, dp *dates.Parser) {
+	r.GET("/subscriptions/calendar", mw.Budget(budgetReport), func(c *gin.Context) {
+		if !requireAcceptJSON(c) {
+			return
+		}
+		uid, err := entity.ParseUserID(strings.TrimSpace(c.Query("user_id")))
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.UserIDInvalid, "uuid invalid")
+			return
+		}
+		month, err := dp.Parse(c.Query("month"))
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.DateInvalid, dateErrMsg("invalid month", err))
+			return
+		}
+		label, ok := monthLabeler(c)
+		if !ok {
+			return
+		}
+
+		cal, err := u.Sub.Calendar(c, uid, month)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		out := buildCalendarDTO(cal, u.Sub.IDs())
+		if label != nil {
+			out.MonthLabel = label(cal.Month)
+		}
+		c.JSON(http.StatusOK, out)
+	})
+}
+
+// buildCalendarDTO lays the events out over every day of the month.
+func buildCalendarDTO(cal usecase.Calendar, ids usecase.IDs) calendarMonth {
+	out := calendarMonth{
+		Month:          dates.Format(cal.Month),
+		FirstDayOfWeek: int(cal.Settings.FirstDayOfWeek),
+		Currency:       cal.Settings.Currency,
+	}
+	next := cal.Month.AddDate(0, 1, 0)
+	for d := cal.Month; d.Before(next); d = d.AddDate(0, 0, 1) {
+		out.Days = append(out.Days, calendarDay{
+			Date:    d.Format(time.DateOnly),
+			Weekday: int(d.Weekday()),
+			Events:  []calendarEvent{},
+		})
+	}
+	for _, ev := range cal.Events {
+		day := &out.Days[ev.Date.Day()-1]
+		event := calendarEvent{
+			ServiceName: ev.Subscription.ServiceName,
+			Cost:        ev.Subscription.Cost,
+			Kind:        string(ev.Kind),
+		}
+		event.SubscriptionID, event.SubscriptionPublicID = subRef(ids, ev.Subscription.ID, ev.Subscription.PublicID)
+		day.Events = append(day.Events, event)
+		day.Total += ev.Subscription.Cost
+		out.Total += ev.Subscription.Cost
+	}
+	return out
+}
@@ -0,0 +1,118 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/changes"
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+)
+
+// longPollRoutes hold the request until something happens instead of working on it, relative to the API
+// version prefix; they are left out of the in-flight limits, which idle waiters would otherwise fill up.
+var longPollRoutes = map[string]bool{
+	"/subscriptions/changes": true,
+}
+
+// subscriptionChange is an item of GET /api/v1/subscriptions/changes.
+type subscriptionChange struct {
+	// Type is the event type of the webhooks, e.g. subscription.created
+	Type string `json:"type"`
+	// SubscriptionID is left out under a public ID strategy, which sends SubscriptionPublicID instead
+	SubscriptionID       int64     `json:"subscription_id,omitempty"`
+	SubscriptionPublicID string    `json:"subscription_public_id,omitempty"`
+	UserID               string    `json:"user_id"`
+	OccurredAt           time.Time `json:"occurred_at"`
+}
+
+// subscriptionChanges is the response of GET /api/v1/subscriptions/changes.
+type subscriptionChanges struct {
+	Changes []subscriptionChange `json:"changes"`
+	// Cursor is the since of the next poll
+	Cursor string `json:"cursor"`
+	// Reset tells the client changes may have been missed, so it reloads the list before polling on
+	Reset bool `json:"reset"`
+}
+
+// setupChanges registers the long-poll of subscription changes for clients that cannot keep SSE or
+// WebSockets open. Without since it answers at once with the cursor to start from, which the client takes
+// before loading the list; with since it answers as soon as there are changes after it, or with none
+// once wait runs out.
+func setupChanges(r *gin.RouterGroup, u UseCases) {
+	r.GET("/subscriptions/changes", func(c *gin.Context) {
+		if u.Changes == nil {
+			jsonErr(c, http.StatusForbidden, "change notifications are disabled")
+			return
+		}
+		wait := changes.DefaultWait
+		if v := strings.TrimSpace(c.Query("wait")); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d < 0 || d > changes.MaxWait {
+				jsonErr(c, http.StatusUnprocessableEntity, "invalid wait")
+				return
+			}
+			wait = d
+		}
+		var user *entity.UserID
+		if v := strings.TrimSpace(c.Query("user_id")); v != "" {
+			uid, err := entity.ParseUserID(v)
+			if err != nil {
+				jsonErrCode(c, http.StatusUnprocessableEntity, errcode.UserIDInvalid, "uuid invalid")
+				return
+			}
+			user = &uid
+		}
+
+		c.Header("Cache-Control", "no-store")
+		v := strings.TrimSpace(c.Query("since"))
+		if v == "" {
+			c.JSON(http.StatusOK, subscriptionChanges{Changes: []subscriptionChange{}, Cursor: u.Changes.Head().String()})
+			return
+		}
+		since, err := changes.ParseCursor(v)
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.CursorInvalid, "invalid cursor")
+			return
+		}
+		batch := u.Changes.Wait(c, since, wait, user)
+		out := subscriptionChanges{
+			Changes: make([]subscriptionChange, 0, len(batch.Changes)),
+			Cursor:  batch.Cursor.String(),
+			Reset:   batch.Reset,
+		}
+		for _, ch := range batch.Changes {
+			out.Changes = append(out.Changes, buildSubscriptionChange(ch))
+		}
+		c.JSON(http.StatusOK, out)
+	})
+}
+
+// buildSubscriptionChange maps a change to the response, naming the subscription the way its event did.
+func buildSubscriptionChange(ch changes.Change) subscriptionChange {
+	out := subscriptionChange{
+		Type:                 string(ch.Type),
+		SubscriptionID:       ch.SubscriptionID,
+		SubscriptionPublicID: ch.PublicID,
+		UserID:               ch.UserID.String(),
+		OccurredAt:           ch.OccurredAt.UTC(),
+	}
+	if ch.PublicID != "" {
+		out.SubscriptionID = 0
+	}
+	return out
+}
+
+// skipLongPolls runs h on every API route but the long-polls.
+func skipLongPolls(h gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if route, ok := apiRoute(c.FullPath()); ok && longPollRoutes[route] {
+			c.Next()
+			return
+		}
+		h(c)
+	}
+}
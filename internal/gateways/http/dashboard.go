@@ -0,0 +1,227 @@
+package http
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/buildinfo"
+	cfg "subs_tracker/internal/config"
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/internal/webhooks"
+	"subs_tracker/pkg/dates"
+	"subs_tracker/pkg/pagination"
+)
+
+// dashboardPageSize is the number of subscriptions per dashboard page.
+const dashboardPageSize = 50
+
+//go:embed dashboard
+var dashboardFS embed.FS
+
+var dashboardTemplates = template.Must(template.New("").Funcs(template.FuncMap{
+	"month": dates.Format,
+	"endMonth": func(t *time.Time) string {
+		if t == nil {
+			return "—"
+		}
+		return dates.Format(*t)
+	},
+	"ts": func(t time.Time) string {
+		if t.IsZero() {
+			return "—"
+		}
+		return t.UTC().Format("2006-01-02 15:04:05 UTC")
+	},
+}).ParseFS(dashboardFS, "dashboard/*.html"))
+
+// dashboardPage is the data shared by every dashboard template; Data holds the page specific part.
+type dashboardPage struct {
+	Base    string
+	Page    string
+	Title   string
+	Error   string
+	Version string
+	Data    any
+}
+
+// dashboardSubs is the data of the subscriptions page.
+type dashboardSubs struct {
+	UserID      string
+	ServiceName string
+	Subs        []*entity.Subscription
+	Prev        template.URL
+	Next        template.URL
+}
+
+// dashboardUsers is the data of the per-user totals page.
+type dashboardUsers struct {
+	Month  string
+	Users  []usecase.UserMonthSpend
+	Total  int64
+	Active int
+}
+
+// dashboardWebhooks is the data of the webhook delivery page.
+type dashboardWebhooks struct {
+	Enabled bool
+	URL     string
+	Format  string
+	Status  webhooks.Status
+}
+
+// setupDashboard registers the server-rendered admin UI for operators without the separate frontend.
+// Pages use HTTP Basic auth with an admin token as the password and are disabled without one.
+func setupDashboard(r *gin.RouterGroup, conf cfg.Config, tokens *mw.Tokens, u UseCases, dp *dates.Parser) {
+	base := strings.TrimSuffix(r.BasePath(), "/")
+	version := buildinfo.Get().Version
+	static, _ := fs.Sub(dashboardFS, "dashboard/static")
+
+	r.Use(mw.AdminPage(tokens))
+	r.StaticFS("/static", http.FS(static))
+
+	page := func(name, title string) dashboardPage {
+		return dashboardPage{Base: base, Page: name, Title: title, Version: version}
+	}
+
+	r.GET("", func(c *gin.Context) {
+		c.Redirect(http.StatusFound, base+"/subscriptions")
+	})
+
+	r.GET("/subscriptions", func(c *gin.Context) {
+		p := page("subscriptions", "Subscriptions")
+		data := dashboardSubs{
+			UserID:      strings.TrimSpace(c.Query("user_id")),
+			ServiceName: strings.TrimSpace(c.Query("service_name")),
+		}
+		p.Data = &data
+
+		filter := usecase.SubFilter{Limit: dashboardPageSize}
+		if data.UserID != "" {
+			uid, err := entity.ParseUserID(data.UserID)
+			if err != nil {
+				p.Error = "user_id must be a UUID"
+				renderDashboard(c, http.StatusUnprocessableEntity, p)
+				return
+			}
+			filter.UserID = uid
+		}
+		if data.ServiceName != "" {
+			filter.ServiceName = &data.ServiceName
+		}
+		// a malformed offset shows the first page
+		offset, _ := pagination.ParseOffset(c.Query("offset"))
+		if offset > 0 {
+			filter.Offset = offset
+		}
+
+		subs, err := u.Sub.ListSubsByFilter(c, filter)
+		if err != nil {
+			_ = c.Error(err)
+			p.Error = "failed to load subscriptions"
+			renderDashboard(c, http.StatusInternalServerError, p)
+			return
+		}
+		data.Subs = subs
+		if filter.Offset > 0 {
+			data.Prev = dashboardPageURL(base, data.UserID, data.ServiceName, max(filter.Offset-dashboardPageSize, 0))
+		}
+		if len(subs) == dashboardPageSize {
+			data.Next = dashboardPageURL(base, data.UserID, data.ServiceName, filter.Offset+dashboardPageSize)
+		}
+		renderDashboard(c, http.StatusOK, p)
+	})
+
+	r.GET("/users", func(c *gin.Context) {
+		p := page("users", "Users")
+		month := dates.MonthStart(u.Sub.Now())
+		if raw := strings.TrimSpace(c.Query("month")); raw != "" {
+			t, err := dp.Parse(raw)
+			if err != nil {
+				p.Data = &dashboardUsers{Month: raw}
+				p.Error = dateErrMsg("invalid month", err)
+				renderDashboard(c, http.StatusUnprocessableEntity, p)
+				return
+			}
+			month = dates.MonthStart(t)
+		}
+		data := dashboardUsers{Month: dates.Format(month)}
+		p.Data = &data
+
+		totals, err := u.Sub.UserTotals(c, month)
+		if err != nil {
+			_ = c.Error(err)
+			p.Error = "failed to load totals"
+			renderDashboard(c, http.StatusInternalServerError, p)
+			return
+		}
+		data.Users = totals
+		data.Active = len(totals)
+		for _, t := range totals {
+			data.Total += t.Total
+		}
+		renderDashboard(c, http.StatusOK, p)
+	})
+
+	r.GET("/webhooks", func(c *gin.Context) {
+		p := page("webhooks", "Webhooks")
+		data := dashboardWebhooks{Enabled: u.Webhooks != nil}
+		if u.Webhooks != nil {
+			data.URL = redactURL(u.Webhooks.URL())
+			data.Format = string(u.Webhooks.Format())
+			data.Status = u.Webhooks.Status()
+		}
+		p.Data = &data
+		renderDashboard(c, http.StatusOK, p)
+	})
+}
+
+// renderDashboard executes the template named after the page.
+func renderDashboard(c *gin.Context, status int, p dashboardPage) {
+	var buf bytes.Buffer
+	if err := dashboardTemplates.ExecuteTemplate(&buf, p.Page, p); err != nil {
+		_ = c.Error(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Data(status, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// dashboardPageURL builds the link to a subscriptions page; url.Values escapes every part.
+func dashboardPageURL(base, userID, service string, offset int) template.URL {
+	q := url.Values{}
+	if userID != "" {
+		q.Set("user_id", userID)
+	}
+	if service != "" {
+		q.Set("service_name", service)
+	}
+	if offset > 0 {
+		q.Set("offset", strconv.Itoa(offset))
+	}
+	return template.URL(base + "/subscriptions?" + q.Encode())
+}
+
+// redactURL shows only the scheme and host of a webhook URL: catch-hook paths and queries carry tokens.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	out := u.Scheme + "://" + u.Host
+	if strings.Trim(u.Path, "/") != "" || u.RawQuery != "" {
+		out += "/…"
+	}
+	return out
+}
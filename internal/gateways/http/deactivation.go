@@ -0,0 +1,82 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/gateways/http/mw"
+)
+
+// userStatus is the response of the /api/v1/users/{user_id}/deactivation routes.
+type userStatus struct {
+	UserID        string     `json:"user_id"`
+	Active        bool       `json:"active"`
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+}
+
+// setupDeactivation registers reading, setting and clearing the deactivation of a user. A deactivated user's
+// subscriptions are kept but left out of lists, costs, analytics and exports.
+func setupDeactivation(r *gin.RouterGroup, u UseCases) {
+	r.GET("/users/:user_id/deactivation", mw.Budget(budgetRead), func(c *gin.Context) {
+		uid, ok := deactivationUser(c)
+		if !ok {
+			return
+		}
+		at, err := u.Sub.UserDeactivatedAt(c, uid)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		c.JSON(http.StatusOK, buildUserStatus(uid, at))
+	})
+
+	r.PUT("/users/:user_id/deactivation", mw.Budget(budgetWrite), func(c *gin.Context) {
+		uid, ok := deactivationUser(c)
+		if !ok {
+			return
+		}
+		at, err := u.Sub.DeactivateUser(c, uid)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		c.JSON(http.StatusOK, buildUserStatus(uid, at))
+	})
+
+	r.DELETE("/users/:user_id/deactivation", mw.Budget(budgetWrite), func(c *gin.Context) {
+		uid, ok := deactivationUser(c)
+		if !ok {
+			return
+		}
+		err := u.Sub.ReactivateUser(c, uid)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		c.JSON(http.StatusOK, buildUserStatus(uid, time.Time{}))
+	})
+}
+
+// deactivationUser parses the user of a deactivation or deletion route, answering 422 when it is not a UUID.
+func deactivationUser(c *gin.Context) (entity.UserID, bool) {
+	if !requireAcceptJSON(c) {
+		return entity.UserID{}, false
+	}
+	uid, err := entity.ParseUserID(c.Param("user_id"))
+	if err != nil {
+		jsonErrCode(c, http.StatusUnprocessableEntity, errcode.UserIDInvalid, "uuid invalid")
+		return entity.UserID{}, false
+	}
+	return uid, true
+}
+
+// buildUserStatus maps the deactivation time, zero for an active user, to the response.
+func buildUserStatus(uid entity.UserID, deactivatedAt time.Time) userStatus {
+	out := userStatus{UserID: uid.String(), Active: deactivatedAt.IsZero()}
+	if !out.Active {
+		at := deactivatedAt.UTC()
+		out.DeactivatedAt = &at
+	}
+	return out
+}
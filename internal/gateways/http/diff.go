@@ -0,0 +1,92 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/dates"
+)
+
+// serviceDiff is the monthly cost of one service in both compared months.
+type serviceDiff struct {
+	ServiceName string `json:"service_name"`
+	FromCost    int64  `json:"from_cost"`
+	ToCost      int64  `json:"to_cost"`
+	Delta       int64  `json:"delta"`
+}
+
+// monthDiff is the response of GET /api/v1/subscriptions/diff.
+type monthDiff struct {
+	From      string        `json:"from"`
+	To        string        `json:"to"`
+	Currency  string        `json:"currency"`
+	FromTotal int64         `json:"from_total"`
+	ToTotal   int64         `json:"to_total"`
+	Delta     int64         `json:"delta"`
+	Added     []serviceDiff `json:"added"`
+	Removed   []serviceDiff `json:"removed"`
+	Changed   []serviceDiff `json:"changed"`
+}
+
+// setupDiff registers the comparison of a user's subscriptions between two months.
+func setupDiff(r *gin.RouterGroup, u UseCases, dp *dates.Parser) {
+	r.GET("/subscriptions/diff", mw.Budget(budgetReport), func(c *gin.Context) {
+		if !requireAcceptJSON(c) {
+			return
+		}
+		uid, err := entity.ParseUserID(strings.TrimSpace(c.Query("user_id")))
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.UserIDInvalid, "uuid invalid")
+			return
+		}
+		from, err := dp.Parse(c.Query("from"))
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.DateInvalid, dateErrMsg("invalid from", err))
+			return
+		}
+		to, err := dp.Parse(c.Query("to"))
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.DateInvalid, dateErrMsg("invalid to", err))
+			return
+		}
+
+		settings, err := u.Sub.GetSettings(c, uid)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		diff, err := u.Sub.DiffMonths(c, uid, from, to)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		c.JSON(http.StatusOK, buildMonthDiffDTO(diff, settings.Currency))
+	})
+}
+
+// buildMonthDiffDTO maps a diff to its response; empty lists are sent as [].
+func buildMonthDiffDTO(d usecase.MonthDiff, currency string) monthDiff {
+	return monthDiff{
+		From:      dates.Format(d.From),
+		To:        dates.Format(d.To),
+		Currency:  currency,
+		FromTotal: d.FromTotal,
+		ToTotal:   d.ToTotal,
+		Delta:     d.ToTotal - d.FromTotal,
+		Added:     serviceDiffs(d.Added),
+		Removed:   serviceDiffs(d.Removed),
+		Changed:   serviceDiffs(d.Changed),
+	}
+}
+
+func serviceDiffs(in []usecase.ServiceDiff) []serviceDiff {
+	out := make([]serviceDiff, 0, len(in))
+	for _, s := range in {
+		out = append(out, serviceDiff{ServiceName: s.ServiceName, FromCost: s.FromCost, ToCost: s.ToCost, Delta: s.ToCost - s.FromCost})
+	}
+	return out
+}
@@ -0,0 +1,190 @@
+package http
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"subs_tracker/internal/entity/generated"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ugorji/go/codec"
+)
+
+// Response media types negotiated for read endpoints.
+const (
+	mimeJSON       = "application/json"
+	mimeMsgPack    = "application/x-msgpack"
+	mimeMsgPackAlt = "application/msgpack"
+	mimeXML        = "application/xml"
+	mimeTextXML    = "text/xml"
+)
+
+// msgpackHandle is shared by all msgpack encoders; it reads json struct tags.
+var msgpackHandle = &codec.MsgpackHandle{}
+
+// negotiateFormat picks the response media type from the Accept header.
+// JSON wins for an empty header and wildcards; "" means nothing is acceptable.
+func negotiateFormat(h string) string {
+	if strings.TrimSpace(h) == "" {
+		return mimeJSON
+	}
+	for _, p := range strings.Split(h, ",") {
+		mt := strings.ToLower(strings.TrimSpace(strings.SplitN(p, ";", 2)[0]))
+		switch mt {
+		case mimeJSON, "*/*", "application/*":
+			return mimeJSON
+		case mimeJSONAPI:
+			return mimeJSONAPI
+		case mimeMsgPack, mimeMsgPackAlt:
+			return mimeMsgPack
+		case mimeXML, mimeTextXML:
+			return mimeXML
+		}
+	}
+	return ""
+}
+
+// requireAcceptEncoded enforces an Accept header one of the read encoders can serve.
+func requireAcceptEncoded(c *gin.Context) (string, bool) {
+	if f := negotiateFormat(c.GetHeader("Accept")); f != "" {
+		return f, true
+	}
+	jsonErr(c, http.StatusNotAcceptable, "Accept application/json, application/vnd.api+json, application/x-msgpack or application/xml only")
+	return "", false
+}
+
+// respond writes data in the negotiated format, keeping only the requested
+// fields (nil keeps all). Non-JSON formats and sparse JSON use the wire DTOs
+// below so they stay flat and independent of go-swagger types.
+func respond(c *gin.Context, code int, format string, data any, fields fieldSet) {
+	switch format {
+	case mimeMsgPack:
+		c.Render(code, msgpackRender{Data: wireOf(data, false, fields)})
+	case mimeXML:
+		c.XML(code, wireOf(data, true, fields))
+	case mimeJSONAPI:
+		c.Header("Content-Type", mimeJSONAPI)
+		c.JSON(code, jsonAPIDocumentOf(c, data, fields))
+	default:
+		if fields != nil {
+			c.JSON(code, wireOf(data, false, fields))
+			return
+		}
+		if subs, ok := data.([]*generated.Subscription); ok {
+			c.Render(code, subscriptionsJSON(subs))
+			return
+		}
+		c.JSON(code, data)
+	}
+}
+
+// msgpackRender renders msgpack with the application/x-msgpack content type.
+type msgpackRender struct {
+	Data any
+}
+
+// Render encodes Data into the response body.
+func (r msgpackRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return codec.NewEncoder(w, msgpackHandle).Encode(r.Data)
+}
+
+// WriteContentType sets the msgpack content type.
+func (r msgpackRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", mimeMsgPack)
+}
+
+// subscriptionWire is the msgpack/XML shape of a subscription.
+type subscriptionWire struct {
+	XMLName     xml.Name     `json:"-" xml:"subscription"`
+	ID          int64        `json:"id,omitempty" xml:"id,omitempty"`
+	PublicID    string       `json:"public_id,omitempty" xml:"public_id,omitempty"`
+	ServiceName string       `json:"service_name,omitempty" xml:"service_name,omitempty"`
+	Cost        int64        `json:"cost,omitempty" xml:"cost,omitempty"`
+	UserID      string       `json:"user_id,omitempty" xml:"user_id,omitempty"`
+	StartDate   string       `json:"start_date,omitempty" xml:"start_date,omitempty"`
+	EndDate     string       `json:"end_date,omitempty" xml:"end_date,omitempty"`
+	Free        bool         `json:"free,omitempty" xml:"free,omitempty"`
+	CreatedAt   string       `json:"created_at,omitempty" xml:"created_at,omitempty"`
+	UpdatedAt   string       `json:"updated_at,omitempty" xml:"updated_at,omitempty"`
+	Service     *serviceWire `json:"service,omitempty" xml:"service,omitempty"`
+}
+
+// serviceWire is the msgpack/XML shape of catalog enrichment.
+type serviceWire struct {
+	Domain   string `json:"domain,omitempty" xml:"domain,omitempty"`
+	Logo     string `json:"logo,omitempty" xml:"logo,omitempty"`
+	Category string `json:"category,omitempty" xml:"category,omitempty"`
+}
+
+// subscriptionsWire wraps a list for XML, which needs a single root element.
+type subscriptionsWire struct {
+	XMLName xml.Name           `xml:"subscriptions"`
+	Items   []subscriptionWire `xml:"subscription"`
+}
+
+// costWire is the msgpack/XML shape of the aggregate cost.
+type costWire struct {
+	XMLName  xml.Name `json:"-" xml:"cost"`
+	Total    int64    `json:"total" xml:"total"`
+	Currency string   `json:"currency,omitempty" xml:"currency,omitempty"`
+}
+
+// wireOf maps response DTOs to their wire shape; unknown types pass through.
+func wireOf(data any, forXML bool, fields fieldSet) any {
+	switch v := data.(type) {
+	case generated.Subscription:
+		return fields.apply(buildSubWire(&v))
+	case *generated.Subscription:
+		return fields.apply(buildSubWire(v))
+	case []*generated.Subscription:
+		items := make([]subscriptionWire, 0, len(v))
+		for _, s := range v {
+			items = append(items, fields.apply(buildSubWire(s)))
+		}
+		if forXML {
+			return subscriptionsWire{Items: items}
+		}
+		return items
+	case generated.SubscriptionsCost:
+		return costWire{Total: v.Total, Currency: v.Currency}
+	}
+	return data
+}
+
+// buildSubWire flattens a generated subscription.
+func buildSubWire(s *generated.Subscription) subscriptionWire {
+	out := subscriptionWire{
+		ID:        s.ID,
+		PublicID:  s.PublicID,
+		EndDate:   s.EndDate,
+		Free:      s.Free,
+		CreatedAt: wireTime(time.Time(s.CreatedAt)),
+		UpdatedAt: wireTime(time.Time(s.UpdatedAt)),
+	}
+	if s.ServiceName != nil {
+		out.ServiceName = *s.ServiceName
+	}
+	if s.Cost != nil {
+		out.Cost = *s.Cost
+	}
+	if s.UserID != nil {
+		out.UserID = s.UserID.String()
+	}
+	if s.StartDate != nil {
+		out.StartDate = *s.StartDate
+	}
+	if s.Service != nil {
+		out.Service = &serviceWire{Domain: s.Service.Domain, Logo: s.Service.Logo, Category: s.Service.Category}
+	}
+	return out
+}
+
+// wireTime formats t as RFC 3339, leaving zero times empty.
+func wireTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,177 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"subs_tracker/internal/enrichment"
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/entity/generated"
+	"subs_tracker/internal/usecase"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugorji/go/codec"
+)
+
+// benchSubs builds a list page the size internal consumers typically fetch.
+func benchSubs(n int) []*generated.Subscription {
+	uid := entity.UserID(uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba"))
+	out := make([]*generated.Subscription, 0, n)
+	for i := range n {
+		end := time.Date(2025, time.December, 1, 0, 0, 0, 0, time.UTC)
+		dto := buildSubDTO(&entity.Subscription{
+			ID:          int64(i + 1),
+			ServiceName: fmt.Sprintf("Service %d", i),
+			Cost:        int64(100 + i),
+			UserID:      uid,
+			DateFrom:    time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC),
+			DateTo:      &end,
+			CreatedAt:   stubVersion,
+			UpdatedAt:   stubVersion,
+		}, usecase.IDs{})
+		out = append(out, &dto)
+	}
+	return out
+}
+
+func TestSubscriptionsJSON(t *testing.T) {
+	cost, name, start := int64(299), `Кино <HD> & "Premium"`+"\n\xff", "07-2025"
+	uid := strfmt.UUID("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+	msk := time.FixedZone("MSK", 3*3600)
+	full := &generated.Subscription{}
+	full.Cost, full.ServiceName, full.StartDate, full.UserID = &cost, &name, &start, &uid
+	full.EndDate, full.ID = "12-2025", 42
+	full.CreatedAt = strfmt.DateTime(time.Date(2025, time.July, 3, 10, 4, 5, 123456789, msk))
+	full.UpdatedAt = strfmt.DateTime(time.Date(2025, time.August, 1, 0, 0, 0, 0, time.UTC))
+	full.Service = &generated.ServiceMeta{Category: "video", Logo: "https://logo.example/kino.png?a=1&b=2"}
+	noCategory := *full
+	noCategory.Service = &generated.ServiceMeta{Domain: "kino.example"}
+	zero, free := int64(0), *full
+	free.Cost, free.Free = &zero, true
+
+	for name, subs := range map[string][]*generated.Subscription{
+		"nil":        nil,
+		"empty":      {},
+		"zero":       {{}},
+		"nil item":   {nil, full},
+		"full":       {full, &noCategory, {SubscriptionEnrichment: generated.SubscriptionEnrichment{Service: &generated.ServiceMeta{}}}},
+		"list page":  benchSubs(3),
+		"enrichment": {&noCategory},
+		"free":       {&free},
+	} {
+		t.Run(name, func(t *testing.T) {
+			want, err := json.Marshal(subs)
+			require.NoError(t, err)
+			assert.Equal(t, string(want), string(appendSubscriptions(nil, subs)))
+		})
+	}
+}
+
+// benchEntities builds a list page of n subscriptions of a few users, every other one ended.
+func benchEntities(n int) []*entity.Subscription {
+	users := []entity.UserID{
+		entity.UserID(uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba")),
+		entity.UserID(uuid.MustParse("2f0c6a57-8c4e-4f43-9a49-3b8f1d3b9e21")),
+	}
+	out := make([]*entity.Subscription, 0, n)
+	for i := range n {
+		s := &entity.Subscription{
+			ID:          int64(i + 1),
+			ServiceName: fmt.Sprintf("Service %d", i%10),
+			Cost:        int64(100 + i),
+			UserID:      users[i%len(users)],
+			DateFrom:    time.Date(2025, time.Month(1+i%12), 1, 0, 0, 0, 0, time.UTC),
+			CreatedAt:   stubVersion,
+			UpdatedAt:   stubVersion,
+		}
+		if i%2 == 0 {
+			end := time.Date(2026, time.December, 1, 0, 0, 0, 0, time.UTC)
+			s.DateTo = &end
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestSubDTOBatch(t *testing.T) {
+	catalog := map[string]enrichment.ServiceInfo{"Service 3": {Domain: "three.example", Category: "video"}}
+	for _, page := range [][]*entity.Subscription{benchEntities(25), benchEntities(3), nil} {
+		want := make([]*generated.Subscription, 0, len(page))
+		for _, s := range page {
+			dto := buildSubDTO(s, usecase.IDs{})
+			if info, ok := catalog[s.ServiceName]; ok {
+				dto.Service = &generated.ServiceMeta{Domain: info.Domain, Category: info.Category}
+			}
+			want = append(want, &dto)
+		}
+		// the second page reuses the batch of the first
+		batch := newSubDTOBatch(page, usecase.IDs{}, catalog)
+		assert.Equal(t, want, batch.ptrs)
+		wantJSON, err := json.Marshal(want)
+		require.NoError(t, err)
+		assert.Equal(t, string(wantJSON), string(appendSubscriptions(nil, batch.ptrs)))
+		batch.release()
+	}
+}
+
+// BenchmarkListDTOs compares building the DTOs of a 1k list page one by one, as the list handler did, with
+// the pooled batch it uses now
+func BenchmarkListDTOs(b *testing.B) {
+	subs := benchEntities(1000)
+	b.Run("per_item", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			resp := make([]*generated.Subscription, 0, len(subs))
+			for _, s := range subs {
+				item := buildSubDTO(s, usecase.IDs{})
+				resp = append(resp, &item)
+			}
+		}
+	})
+	b.Run("batch", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			newSubDTOBatch(subs, usecase.IDs{}, nil).release()
+		}
+	})
+}
+
+func BenchmarkListEncoding(b *testing.B) {
+	subs := benchSubs(1000)
+	encoders := []struct {
+		Name   string
+		Encode func(*bytes.Buffer) error
+	}{
+		{"json", func(buf *bytes.Buffer) error { return json.NewEncoder(buf).Encode(subs) }},
+		{"json_append", func(buf *bytes.Buffer) error {
+			b := jsonBufs.Get().(*[]byte)
+			*b = appendSubscriptions((*b)[:0], subs)
+			_, err := buf.Write(*b)
+			jsonBufs.Put(b)
+			return err
+		}},
+		{"msgpack", func(buf *bytes.Buffer) error {
+			return codec.NewEncoder(buf, msgpackHandle).Encode(wireOf(subs, false, nil))
+		}},
+		{"xml", func(buf *bytes.Buffer) error { return xml.NewEncoder(buf).Encode(wireOf(subs, true, nil)) }},
+	}
+	for _, enc := range encoders {
+		b.Run(enc.Name, func(b *testing.B) {
+			var buf bytes.Buffer
+			b.ReportAllocs()
+			for b.Loop() {
+				buf.Reset()
+				if err := enc.Encode(&buf); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(buf.Len()), "bytes/op")
+		})
+	}
+}
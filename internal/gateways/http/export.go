@@ -0,0 +1,92 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/export"
+	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/dates"
+	"subs_tracker/pkg/pagination"
+)
+
+// setupExport registers the download of filtered subscriptions as a file of a registered export format.
+func setupExport(r *gin.RouterGroup, u UseCases, dp *dates.Parser) {
+	r.GET("/subscriptions/export", mw.Budget(budgetImport), func(c *gin.Context) {
+		format, err := export.Lookup(c.DefaultQuery("format", "csv"))
+		if err != nil {
+			jsonErrOf(c, http.StatusUnprocessableEntity, err)
+			return
+		}
+		f, err := exportFilter(c, dp)
+		if err != nil {
+			jsonErrOf(c, http.StatusUnprocessableEntity, err)
+			return
+		}
+		// the first page is read before the status is sent, so a failing filter still gets a JSON error
+		page, err := u.Sub.ListSubsByFilter(c, f)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="subscriptions.%s"`, format.Extension))
+		c.Header("Content-Type", format.ContentType)
+		c.Status(http.StatusOK)
+		if err := writeExport(c, u.Sub, f, page, format.New(c.Writer)); err != nil {
+			// the status is already sent: the file ends early and the error is left to the logs
+			_ = c.Error(err)
+		}
+	})
+}
+
+// exportFilter reads the list filters of GET /subscriptions; paging parameters are ignored, the export
+// always holds every matching subscription.
+func exportFilter(c *gin.Context, dp *dates.Parser) (usecase.SubFilter, error) {
+	filterDTO, err := buildSubscriptionsFilterFromQuery(c)
+	if err != nil {
+		return usecase.SubFilter{}, err
+	}
+	f, err := mapFilterDTOToUsecase(filterDTO, dp)
+	if err != nil {
+		return usecase.SubFilter{}, err
+	}
+	if v := strings.TrimSpace(c.Query("filter")); v != "" {
+		if err := applyFilterExpr(&f, v, dp); err != nil {
+			return usecase.SubFilter{}, err
+		}
+	}
+	f.Limit, f.Offset = pagination.MaxLimit, 0
+	return f, nil
+}
+
+// writeExport writes page and every following page of f to e in keyset order.
+func writeExport(c *gin.Context, sub *usecase.Subscription, f usecase.SubFilter, page []*entity.Subscription, e export.Exporter) error {
+	if err := e.WriteHeader(export.SubscriptionColumns); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	for {
+		for _, s := range page {
+			if err := e.WriteRow(export.SubscriptionRow(s)); err != nil {
+				return fmt.Errorf("export: %w", err)
+			}
+		}
+		if len(page) < f.Limit {
+			break
+		}
+		last := page[len(page)-1]
+		f.After = usecase.CursorAt(last)
+		var err error
+		if page, err = sub.ListSubsByFilter(c, f); err != nil {
+			return fmt.Errorf("export: %w", err)
+		}
+	}
+	if err := e.Flush(); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/errcode"
+)
+
+// errInvalidFields reports an unknown name in ?fields=.
+var errInvalidFields = errcode.New(errcode.FieldsInvalid, "invalid fields")
+
+// subscriptionFields are the names accepted by ?fields= on subscription reads.
+var subscriptionFields = []string{
+	"id", "service_name", "cost", "user_id", "start_date", "end_date", "free", "created_at", "updated_at", "service",
+}
+
+// fieldSet is a sparse fieldset; nil selects every field.
+type fieldSet map[string]bool
+
+// parseFields reads ?fields=a,b (or the JSON:API spelling fields[subscriptions]=a,b).
+func parseFields(c *gin.Context) (fieldSet, error) {
+	raw, ok := c.GetQuery("fields")
+	if !ok {
+		raw, ok = c.GetQuery("fields[subscriptions]")
+	}
+	if !ok {
+		return nil, nil
+	}
+	fs := fieldSet{}
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, f := range subscriptionFields {
+			if f == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, errInvalidFields
+		}
+		fs[name] = true
+	}
+	if len(fs) == 0 {
+		return nil, errInvalidFields
+	}
+	return fs, nil
+}
+
+// apply zeroes every field not in the set; omitempty tags drop them on the wire.
+func (fs fieldSet) apply(w subscriptionWire) subscriptionWire {
+	if fs == nil {
+		return w
+	}
+	out := subscriptionWire{}
+	// id selects the identifier of the configured strategy, whichever of the two it is
+	if fs["id"] {
+		out.ID = w.ID
+		out.PublicID = w.PublicID
+	}
+	if fs["service_name"] {
+		out.ServiceName = w.ServiceName
+	}
+	if fs["cost"] {
+		out.Cost = w.Cost
+	}
+	if fs["user_id"] {
+		out.UserID = w.UserID
+	}
+	if fs["start_date"] {
+		out.StartDate = w.StartDate
+	}
+	if fs["end_date"] {
+		out.EndDate = w.EndDate
+	}
+	if fs["free"] {
+		out.Free = w.Free
+	}
+	if fs["created_at"] {
+		out.CreatedAt = w.CreatedAt
+	}
+	if fs["updated_at"] {
+		out.UpdatedAt = w.UpdatedAt
+	}
+	if fs["service"] {
+		out.Service = w.Service
+	}
+	return out
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/dates"
+	"subs_tracker/pkg/filterexpr"
+)
+
+// errInvalidFilter reports a ?filter= condition that parses but means nothing for subscriptions.
+var errInvalidFilter = errcode.New(errcode.FilterInvalid, "invalid filter")
+
+// applyFilterExpr narrows f by the ?filter= expression, e.g. cost>500 AND service_name~"net" AND
+// start_date>=01-2025. cost takes whole amounts, start_date dates of dp compared by month; both accept
+// = > >= < <=. service_name takes = for the exact name and ~ for a case-insensitive part of it.
+// Repeated bounds keep the tightest one.
+func applyFilterExpr(f *usecase.SubFilter, expr string, dp *dates.Parser) error {
+	conds, err := filterexpr.Parse(expr)
+	if err != nil {
+		return err
+	}
+	w := &f.Where
+	for _, c := range conds {
+		switch c.Field {
+		case "cost":
+			v, err := strconv.ParseInt(c.Value, 10, 64)
+			if err != nil || v < 0 {
+				return fmt.Errorf("%w at %d: cost must be a non-negative integer", errInvalidFilter, c.Pos)
+			}
+			switch c.Op {
+			case filterexpr.Eq:
+				narrow(&w.CostMin, v, above)
+				narrow(&w.CostMax, v, below)
+			case filterexpr.Greater:
+				narrow(&w.CostMin, min(v, math.MaxInt64-1)+1, above)
+			case filterexpr.GreaterE:
+				narrow(&w.CostMin, v, above)
+			case filterexpr.Less:
+				narrow(&w.CostMax, v-1, below)
+			case filterexpr.LessE:
+				narrow(&w.CostMax, v, below)
+			default:
+				return unsupportedOp(c)
+			}
+		case "service_name":
+			v := c.Value
+			switch {
+			case c.Op == filterexpr.Eq && (f.ServiceName == nil || *f.ServiceName == v):
+				f.ServiceName = &v
+			case c.Op == filterexpr.Eq:
+				return fmt.Errorf("%w at %d: service_name is already %q", errInvalidFilter, c.Pos, *f.ServiceName)
+			case c.Op == filterexpr.Contains && w.ServiceContains == nil:
+				w.ServiceContains = &v
+			case c.Op == filterexpr.Contains:
+				return fmt.Errorf("%w at %d: only one service_name~ condition is supported", errInvalidFilter, c.Pos)
+			default:
+				return unsupportedOp(c)
+			}
+		case "start_date":
+			v, err := dp.Parse(c.Value)
+			if err != nil {
+				return fmt.Errorf("%w at %d: %s", errInvalidFilter, c.Pos, dateErrMsg("invalid start_date", err))
+			}
+			switch c.Op {
+			case filterexpr.Eq:
+				narrow(&w.StartFrom, v, time.Time.After)
+				narrow(&w.StartTo, v, time.Time.Before)
+			case filterexpr.Greater:
+				narrow(&w.StartFrom, v.AddDate(0, 1, 0), time.Time.After)
+			case filterexpr.GreaterE:
+				narrow(&w.StartFrom, v, time.Time.After)
+			case filterexpr.Less:
+				narrow(&w.StartTo, v.AddDate(0, -1, 0), time.Time.Before)
+			case filterexpr.LessE:
+				narrow(&w.StartTo, v, time.Time.Before)
+			default:
+				return unsupportedOp(c)
+			}
+		default:
+			return fmt.Errorf("%w at %d: unknown field %q, use cost, service_name or start_date", errInvalidFilter, c.Pos, c.Field)
+		}
+	}
+	return nil
+}
+
+func unsupportedOp(c filterexpr.Cond) error {
+	return fmt.Errorf("%w at %d: %s does not support %s", errInvalidFilter, c.Pos, c.Field, c.Op)
+}
+
+// narrow sets *bound to v unless the bound already set is tighter; tighter(a, b) reports whether a is
+func narrow[T any](bound **T, v T, tighter func(a, b T) bool) {
+	if *bound == nil || tighter(v, **bound) {
+		*bound = &v
+	}
+}
+
+func above(a, b int64) bool { return a > b }
+
+func below(a, b int64) bool { return a < b }
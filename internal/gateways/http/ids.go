@@ -0,0 +1,54 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/entity/generated"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/usecase"
+)
+
+// subIDParam resolves the :id of a subscription route to the serial ID, answering invalidStatus when it is not
+// an ID of the configured strategy and 404 when no subscription has the public ID.
+func subIDParam(c *gin.Context, sub *usecase.Subscription, invalidStatus int) (int64, bool) {
+	id, err := sub.ResolveID(c, c.Param("id"))
+	if errors.Is(err, usecase.ErrInvalidID) {
+		jsonErrCode(c, invalidStatus, errcode.IDInvalid, "invalid id")
+		return 0, false
+	}
+	if handled := handleUsecaseErr(c, err); handled {
+		return 0, false
+	}
+	return id, true
+}
+
+// subResourceID resolves the subscription of a route nested under /subscriptions/:id, answering 422 when it is
+// not an ID. The public ID is returned as the strategy writes it, "" with the serial strategy.
+func subResourceID(c *gin.Context, sub *usecase.Subscription) (int64, string, bool) {
+	id, ok := subIDParam(c, sub, http.StatusUnprocessableEntity)
+	if !ok {
+		return 0, "", false
+	}
+	// ResolveID has accepted the parameter, so under a strategy storing public IDs it parses
+	pid, _ := entity.ParsePublicID(c.Param("id"))
+	return id, sub.IDs().Ref(id, pid), true
+}
+
+// subRef returns what a response naming a subscription carries: the serial ID, or only the public ID under a
+// public strategy, so serial IDs never reach clients that should not enumerate them.
+func subRef(ids usecase.IDs, id int64, pid entity.PublicID) (int64, string) {
+	if ids.Public() {
+		return 0, ids.Ref(id, pid)
+	}
+	return id, ""
+}
+
+// subscriptionID is the identifier of s in the subscription DTO.
+func subscriptionID(s *entity.Subscription, ids usecase.IDs) generated.SubscriptionID {
+	id, pid := subRef(ids, s.ID, s.PublicID)
+	return generated.SubscriptionID{ID: id, PublicID: pid}
+}
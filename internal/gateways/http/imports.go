@@ -0,0 +1,393 @@
+package http
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/entity/generated"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/internal/importer"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/dates"
+	"subs_tracker/pkg/pagination"
+)
+
+const (
+	// downloadRejected is the ?download= value returning the skipped rows of an upload instead of the proposals.
+	downloadRejected = "rejected"
+	// maxStatementSize bounds uploaded statements and exports.
+	maxStatementSize = 2 << 20
+	// importTokenTTL is how long a proposal can be confirmed after the upload.
+	importTokenTTL = 24 * time.Hour
+	// importKeyBytes is the length of the random key of a proposal.
+	importKeyBytes = 16
+)
+
+// importToken is the signed proposal handed back on upload and accepted on confirm,
+// so only subscriptions actually proposed by an importer can be created. Its random key makes confirming
+// it again return the subscription created the first time.
+type importToken struct {
+	Kind        string     `json:"k"`
+	Key         string     `json:"n,omitempty"`
+	UserID      string     `json:"u"`
+	ServiceName string     `json:"s"`
+	Cost        int64      `json:"c"`
+	StartDate   time.Time  `json:"d"`
+	EndDate     *time.Time `json:"e,omitempty"`
+	IssuedAt    time.Time  `json:"t"`
+}
+
+// importProposal is a single proposed subscription of an import response.
+type importProposal struct {
+	Token       string  `json:"token"`
+	ServiceName string  `json:"service_name"`
+	Cost        int64   `json:"cost"`
+	StartDate   string  `json:"start_date"`
+	EndDate     string  `json:"end_date,omitempty"`
+	LastCharge  string  `json:"last_charge"`
+	TrialStart  string  `json:"trial_start,omitempty"`
+	RenewsAt    string  `json:"renews_at,omitempty"`
+	Charges     int     `json:"charges"`
+	Recurring   bool    `json:"recurring"`
+	Merchant    string  `json:"merchant"`
+	Score       float64 `json:"score"`
+}
+
+// importProposals is the response of the POST /api/v1/imports/* upload endpoints.
+type importProposals struct {
+	Transactions int              `json:"transactions,omitempty"`
+	Proposals    []importProposal `json:"proposals"`
+	Report       importReport     `json:"report"`
+}
+
+// importReport groups the rows of the uploaded file by what became of them.
+type importReport struct {
+	Rows int `json:"rows"`
+	// New are the rows of the proposals, one group per service
+	New []importGroup `json:"new"`
+	// Matched are the rows of services the user already tracks, with the subscription covering them
+	Matched []importGroup `json:"matched"`
+	// Skipped are the rows left out of every proposal; ?download=rejected returns them as CSV
+	Skipped []importSkipped `json:"skipped"`
+}
+
+// importGroup is the rows of the uploaded file read as one service.
+type importGroup struct {
+	ServiceName string `json:"service_name"`
+	ID          int64  `json:"id,omitempty"`
+	PublicID    string `json:"public_id,omitempty"`
+	Lines       []int  `json:"lines"`
+}
+
+// importSkipped is a row of the uploaded file left out of every proposal.
+type importSkipped struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+// importConfirm is the payload of POST /api/v1/imports/confirm.
+type importConfirm struct {
+	Tokens []string `json:"tokens" binding:"required"`
+}
+
+// importFailed is the response of a confirmation that failed part way: the subscriptions saved before the
+// failure and, when a chunk failed to save, the positions of its tokens. Confirming the same tokens again
+// creates the rest.
+type importFailed struct {
+	Error   string                    `json:"error"`
+	Code    errcode.Code              `json:"code"`
+	Created []*generated.Subscription `json:"created"`
+	Failed  *importRange              `json:"failed,omitempty"`
+}
+
+// importRange is the 1-based positions of the first and the last token of a chunk in the confirmation.
+type importRange struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// setupImports registers the imports: uploads return proposals, confirm creates the chosen ones.
+func setupImports(r *gin.RouterGroup, u UseCases, tokens *pagination.Codec) {
+	r.POST("/imports/bank", mw.Budget(budgetImport), func(c *gin.Context) {
+		uid, body, ok := importUpload(c)
+		if !ok {
+			return
+		}
+		defer body.Close()
+
+		txs, rows, err := importer.ParseStatement(body)
+		if !handleImportErr(c, err) {
+			return
+		}
+		proposals, err := u.Sub.ProposeImport(c, uid, txs)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		if u.Reconcile != nil {
+			// the charges are kept whether or not the proposals are confirmed: they are what the
+			// subscriptions get reconciled against
+			if _, err := u.Reconcile.RecordStatement(c, uid, txs, proposals.All()); handleUsecaseErr(c, err) {
+				return
+			}
+		}
+		writeProposals(c, tokens, u.Sub.IDs(), uid, len(txs), proposals, rows)
+	})
+
+	storeImport := func(parse func(io.Reader) ([]importer.Proposal, importer.Rows, error)) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			uid, body, ok := importUpload(c)
+			if !ok {
+				return
+			}
+			defer body.Close()
+
+			parsed, rows, err := parse(body)
+			if !handleImportErr(c, err) {
+				return
+			}
+			proposals, err := u.Sub.ProposeStoreImport(c, uid, parsed)
+			if handled := handleUsecaseErr(c, err); handled {
+				return
+			}
+			writeProposals(c, tokens, u.Sub.IDs(), uid, 0, proposals, rows)
+		}
+	}
+	r.POST("/imports/appstore", mw.Budget(budgetImport), storeImport(importer.ParseAppStore))
+	r.POST("/imports/googleplay", mw.Budget(budgetImport), storeImport(importer.ParseGooglePlay))
+
+	confirm := func(c *gin.Context) {
+		if !requireAcceptJSON(c) || !requireJSONContent(c) {
+			return
+		}
+		var req importConfirm
+		if err := c.ShouldBindJSON(&req); err != nil {
+			jsonErrOf(c, http.StatusBadRequest, err)
+			return
+		}
+
+		now := time.Now()
+		rows := make([]usecase.ImportRow, 0, len(req.Tokens))
+		for _, t := range req.Tokens {
+			var p importToken
+			if err := tokens.Decode(t, &p); err != nil || p.Kind != "import" || now.Sub(p.IssuedAt) > importTokenTTL {
+				jsonErr(c, http.StatusUnprocessableEntity, "invalid import token")
+				return
+			}
+			uid, err := entity.ParseUserID(p.UserID)
+			if err != nil {
+				jsonErr(c, http.StatusUnprocessableEntity, "invalid import token")
+				return
+			}
+			rows = append(rows, usecase.ImportRow{Key: p.Key, Sub: &entity.Subscription{
+				UserID:      uid,
+				ServiceName: p.ServiceName,
+				Cost:        p.Cost,
+				DateFrom:    p.StartDate,
+				DateTo:      p.EndDate,
+			}})
+		}
+
+		res, err := u.Sub.ImportSubs(c, rows)
+		var chunk *usecase.ImportChunkError
+		if !errors.As(err, &chunk) && len(res.Created) == 0 && handleUsecaseErr(c, err) {
+			return
+		}
+		if res.Quarantined > 0 {
+			// the rows failing validation wait under /users/{user_id}/imports/quarantine
+			c.Header("X-Import-Quarantined", strconv.Itoa(res.Quarantined))
+		}
+		created := make([]*generated.Subscription, 0, len(res.Created))
+		for _, s := range res.Created {
+			item := buildSubDTO(s, u.Sub.IDs())
+			created = append(created, &item)
+		}
+		if err != nil {
+			status, msg := importFailedStatus(err)
+			body := importFailed{Error: translate(c, msg), Code: errcode.Of(err), Created: created}
+			if body.Code == "" {
+				body.Code = statusCodes[status]
+			}
+			if chunk != nil {
+				body.Failed = &importRange{From: chunk.From, To: chunk.To}
+			}
+			c.JSON(status, body)
+			return
+		}
+		c.JSON(http.StatusCreated, created)
+	}
+	r.POST("/imports/confirm", mw.Budget(budgetImport), confirm)
+	// kept for clients of the first, bank-only import
+	r.POST("/imports/bank/confirm", mw.Budget(budgetImport), confirm)
+}
+
+// importFailedStatus maps the error of a confirmation that failed part way to the status and message of the
+// response: its rows passed validation, so either a storage constraint refused them or the storage failed.
+func importFailedStatus(err error) (int, string) {
+	var constraint *usecase.ConstraintError
+	switch {
+	case errors.As(err, &constraint) && errors.Is(constraint.Kind, usecase.ErrDuplicate):
+		return http.StatusConflict, constraint.Kind.Error()
+	case errors.As(err, &constraint):
+		return http.StatusUnprocessableEntity, constraint.Kind.Error()
+	default:
+		return http.StatusInternalServerError, "internal error"
+	}
+}
+
+// importUpload checks the user and opens the uploaded file; it writes the error response itself.
+func importUpload(c *gin.Context) (entity.UserID, io.ReadCloser, bool) {
+	switch v := strings.TrimSpace(c.Query("download")); v {
+	case "":
+		if !requireAcceptJSON(c) {
+			return entity.UserID{}, nil, false
+		}
+	case downloadRejected:
+	default:
+		jsonErr(c, http.StatusUnprocessableEntity, "invalid download")
+		return entity.UserID{}, nil, false
+	}
+	uid, err := entity.ParseUserID(strings.TrimSpace(c.Query("user_id")))
+	if err != nil {
+		jsonErrCode(c, http.StatusUnprocessableEntity, errcode.UserIDInvalid, "uuid invalid")
+		return entity.UserID{}, nil, false
+	}
+	body, err := statementBody(c)
+	if err != nil {
+		jsonErrOf(c, http.StatusBadRequest, err)
+		return entity.UserID{}, nil, false
+	}
+	return uid, body, true
+}
+
+// handleImportErr maps parser errors to responses and reports whether processing may continue.
+func handleImportErr(c *gin.Context, err error) bool {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &tooLarge):
+		jsonErr(c, http.StatusRequestEntityTooLarge, "statement too large")
+	case errors.Is(err, importer.ErrInvalidStatement):
+		jsonErrOf(c, http.StatusUnprocessableEntity, err)
+	default:
+		jsonErrOf(c, http.StatusBadRequest, err)
+	}
+	return false
+}
+
+// writeProposals signs every new proposal for the confirm step and writes the response, or only the skipped rows
+// as CSV when they were asked for with ?download=rejected.
+func writeProposals(c *gin.Context, tokens *pagination.Codec, ids usecase.IDs, uid entity.UserID, txs int, proposals usecase.ImportProposals, rows importer.Rows) {
+	all := append([]importer.Proposal{}, proposals.New...)
+	for _, t := range proposals.Tracked {
+		all = append(all, t.Proposal)
+	}
+	skipped := rows.Skipped(all)
+	if c.Query("download") == downloadRejected {
+		writeRejected(c, rows.Header, skipped)
+		return
+	}
+
+	now := time.Now().UTC()
+	key := make([]byte, importKeyBytes)
+	resp := importProposals{
+		Transactions: txs,
+		Proposals:    make([]importProposal, 0, len(proposals.New)),
+		Report: importReport{
+			Rows:    len(rows.Rows),
+			New:     make([]importGroup, 0, len(proposals.New)),
+			Matched: make([]importGroup, 0, len(proposals.Tracked)),
+			Skipped: make([]importSkipped, 0, len(skipped)),
+		},
+	}
+	for _, t := range proposals.Tracked {
+		id, pid := subRef(ids, t.Subscription.ID, t.Subscription.PublicID)
+		resp.Report.Matched = append(resp.Report.Matched, importGroup{ServiceName: t.ServiceName, ID: id, PublicID: pid, Lines: t.Lines})
+	}
+	for _, r := range skipped {
+		resp.Report.Skipped = append(resp.Report.Skipped, importSkipped{Line: r.Line, Reason: r.Skipped})
+	}
+	for _, p := range proposals.New {
+		resp.Report.New = append(resp.Report.New, importGroup{ServiceName: p.ServiceName, Lines: p.Lines})
+		if _, err := rand.Read(key); err != nil {
+			jsonErr(c, http.StatusInternalServerError, "internal error")
+			return
+		}
+		token, err := tokens.Encode(importToken{
+			Kind:        "import",
+			Key:         base64.RawURLEncoding.EncodeToString(key),
+			UserID:      uid.String(),
+			ServiceName: p.ServiceName,
+			Cost:        p.Cost,
+			StartDate:   p.StartDate,
+			EndDate:     p.EndDate,
+			IssuedAt:    now,
+		})
+		if err != nil {
+			jsonErr(c, http.StatusInternalServerError, "internal error")
+			return
+		}
+		item := importProposal{
+			Token:       token,
+			ServiceName: p.ServiceName,
+			Cost:        p.Cost,
+			StartDate:   dates.Format(p.StartDate),
+			EndDate:     dates.FormatPtr(p.EndDate),
+			LastCharge:  p.LastCharge.Format(time.DateOnly),
+			Charges:     p.Charges,
+			Recurring:   p.Recurring,
+			Merchant:    p.Merchant,
+			Score:       p.Score,
+		}
+		if p.TrialStart != nil {
+			item.TrialStart = p.TrialStart.Format(time.DateOnly)
+		}
+		if p.Renews != nil {
+			item.RenewsAt = p.Renews.Format(time.DateOnly)
+		}
+		resp.Proposals = append(resp.Proposals, item)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// writeRejected writes the skipped rows as CSV under the header of the uploaded file and a reason column, so
+// they can be fixed and uploaded again.
+func writeRejected(c *gin.Context, header []string, skipped []importer.Row) {
+	c.Header("Content-Disposition", `attachment; filename="rejected.csv"`)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write(append(append([]string{}, header...), "reason"))
+	for _, r := range skipped {
+		_ = w.Write(append(append([]string{}, r.Fields...), r.Skipped))
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		// the status is already sent: the file ends early and the error is left to the logs
+		_ = c.Error(err)
+	}
+}
+
+// statementBody returns the uploaded file, either the raw request body or the "file" multipart field.
+func statementBody(c *gin.Context) (io.ReadCloser, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxStatementSize)
+	if !strings.HasPrefix(c.ContentType(), "multipart/") {
+		return c.Request.Body, nil
+	}
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return nil, errors.New("multipart field \"file\" is required")
+	}
+	return fh.Open()
+}
@@ -0,0 +1,231 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	cfg "subs_tracker/internal/config"
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/entity/generated"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/internal/importer"
+	"subs_tracker/internal/stripe"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/dates"
+)
+
+// mailgunMaxSkew bounds the age of a signed Mailgun request to limit replays.
+const mailgunMaxSkew = 15 * time.Minute
+
+// receiptResult is the response of POST /api/v1/integrations/mailgun.
+type receiptResult struct {
+	Action       usecase.ReceiptAction   `json:"action"`
+	Subscription *generated.Subscription `json:"subscription"`
+}
+
+// Billing event types accepted by POST /api/v1/integrations/ingest.
+const (
+	ingestCharge = "charge"
+	ingestCancel = "cancel"
+)
+
+// maxStripeEventSize bounds a Stripe webhook body; subscription events are a few kilobytes.
+const maxStripeEventSize = 1 << 20
+
+// ingestEvent is the payload of POST /api/v1/integrations/ingest sent by external billing systems.
+type ingestEvent struct {
+	// Type is "charge" (default) to record a payment or "cancel" to end the subscription after period.
+	Type    string `json:"type"`
+	UserRef string `json:"user_ref" binding:"required"`
+	Service string `json:"service" binding:"required"`
+	Amount  int64  `json:"amount"`
+	Period  string `json:"period" binding:"required"`
+}
+
+// setupInbound registers webhooks of inbound email providers and billing systems.
+// Receipts are forwarded to receipts+<user_id>@<domain>; the plus tag selects the user.
+func setupInbound(r *gin.RouterGroup, conf cfg.InboundConfig, u UseCases, dp *dates.Parser) {
+	r.POST("/ingest", func(c *gin.Context) {
+		if !requireAcceptJSON(c) || !requireJSONContent(c) {
+			return
+		}
+		if len(conf.IngestKeys) == 0 {
+			jsonErr(c, http.StatusForbidden, "ingest is disabled")
+			return
+		}
+		integration, ok := ingestIntegration(conf.IngestKeys, c)
+		if !ok {
+			c.Header("WWW-Authenticate", `Bearer realm="ingest"`)
+			jsonErr(c, http.StatusUnauthorized, "invalid api key")
+			return
+		}
+
+		var ev ingestEvent
+		if err := c.ShouldBindJSON(&ev); err != nil {
+			jsonErrOf(c, http.StatusBadRequest, err)
+			return
+		}
+		uid, err := entity.ParseUserID(strings.TrimSpace(ev.UserRef))
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.UserIDInvalid, "invalid user_ref")
+			return
+		}
+		mw.TraceCustomer(c, uid.String(), integration)
+		month, err := dp.Parse(ev.Period)
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.DateInvalid, dateErrMsg("invalid period", err))
+			return
+		}
+		service := strings.TrimSpace(ev.Service)
+
+		var (
+			sub    *entity.Subscription
+			action usecase.ReceiptAction
+		)
+		switch strings.ToLower(strings.TrimSpace(ev.Type)) {
+		case "", ingestCharge:
+			if ev.Amount <= 0 {
+				jsonErr(c, http.StatusUnprocessableEntity, "amount must be > 0")
+				return
+			}
+			sub, action, err = u.Sub.ApplyCharge(c, uid, service, ev.Amount, month)
+		case ingestCancel:
+			sub, action, err = u.Sub.EndSubscription(c, uid, service, month)
+		default:
+			jsonErr(c, http.StatusUnprocessableEntity, "type must be charge or cancel")
+			return
+		}
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		out := buildSubDTO(sub, u.Sub.IDs())
+		c.JSON(http.StatusOK, receiptResult{Action: action, Subscription: &out})
+	})
+
+	r.POST("/stripe", func(c *gin.Context) {
+		if u.Stripe == nil {
+			jsonErr(c, http.StatusForbidden, "stripe is disabled")
+			return
+		}
+		payload, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxStripeEventSize))
+		if err != nil {
+			jsonErr(c, http.StatusRequestEntityTooLarge, "event too large")
+			return
+		}
+		err = u.Stripe.HandleWebhook(c, payload, c.GetHeader("Stripe-Signature"))
+		switch {
+		case errors.Is(err, stripe.ErrWebhooksDisabled):
+			jsonErr(c, http.StatusForbidden, "stripe is disabled")
+			return
+		case errors.Is(err, stripe.ErrInvalidSignature):
+			jsonErrCode(c, http.StatusUnauthorized, errcode.SignatureInvalid, "invalid signature")
+			return
+		}
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"received": true})
+	})
+
+	r.POST("/mailgun", func(c *gin.Context) {
+		if conf.MailgunSigningKey == "" {
+			jsonErr(c, http.StatusForbidden, "inbound email is disabled")
+			return
+		}
+		if !validMailgunSignature(conf.MailgunSigningKey, c.PostForm("timestamp"), c.PostForm("token"), c.PostForm("signature"), time.Now()) {
+			jsonErrCode(c, http.StatusUnauthorized, errcode.SignatureInvalid, "invalid signature")
+			return
+		}
+
+		// 406 tells Mailgun not to retry: the message will never be accepted
+		uid, err := recipientUserID(c.PostForm("recipient"))
+		if err != nil {
+			jsonErrOf(c, http.StatusNotAcceptable, err)
+			return
+		}
+		sent := time.Now()
+		if ts, err := strconv.ParseInt(c.PostForm("timestamp"), 10, 64); err == nil {
+			sent = time.Unix(ts, 0)
+		}
+		from := c.PostForm("from")
+		if from == "" {
+			from = c.PostForm("sender")
+		}
+		receipt, err := importer.ParseReceipt(from, c.PostForm("subject"), c.PostForm("body-plain"), sent.UTC())
+		if errors.Is(err, importer.ErrUnknownReceipt) {
+			jsonErrOf(c, http.StatusNotAcceptable, err)
+			return
+		}
+
+		sub, action, err := u.Sub.ApplyReceipt(c, uid, receipt)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		out := buildSubDTO(sub, u.Sub.IDs())
+		c.JSON(http.StatusOK, receiptResult{Action: action, Subscription: &out})
+	})
+}
+
+// ingestIntegration returns the integration whose key is presented as a bearer token or X-Api-Key.
+// Every configured key is compared so the time taken does not hint which one nearly matched.
+func ingestIntegration(keys map[string]string, c *gin.Context) (string, bool) {
+	got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		got = c.GetHeader("X-Api-Key")
+	}
+	got = strings.TrimSpace(got)
+	if got == "" {
+		return "", false
+	}
+	var found string
+	for name, key := range keys {
+		if subtle.ConstantTimeCompare([]byte(got), []byte(key)) == 1 {
+			found = name
+		}
+	}
+	return found, found != ""
+}
+
+// validMailgunSignature checks signature = hex(HMAC-SHA256(key, timestamp+token)) and the timestamp freshness.
+func validMailgunSignature(key, timestamp, token, signature string, now time.Time) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || token == "" {
+		return false
+	}
+	if d := now.Sub(time.Unix(ts, 0)); d > mailgunMaxSkew || d < -mailgunMaxSkew {
+		return false
+	}
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	m := hmac.New(sha256.New, []byte(key))
+	m.Write([]byte(timestamp + token))
+	return hmac.Equal(sig, m.Sum(nil))
+}
+
+// recipientUserID extracts the user from the plus tag of receipts+<user_id>@domain.
+func recipientUserID(recipient string) (entity.UserID, error) {
+	addr, err := mail.ParseAddress(recipient)
+	if err != nil {
+		return entity.UserID{}, entity.ErrInvalidUserID
+	}
+	local, _, _ := strings.Cut(addr.Address, "@")
+	_, tag, ok := strings.Cut(local, "+")
+	if !ok {
+		return entity.UserID{}, entity.ErrInvalidUserID
+	}
+	return entity.ParseUserID(tag)
+}
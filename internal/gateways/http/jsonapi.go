@@ -0,0 +1,130 @@
+package http
+
+import (
+	"strconv"
+	"strings"
+	"subs_tracker/internal/entity/generated"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mimeJSONAPI is the opt-in JSON:API media type (https://jsonapi.org/format/).
+const mimeJSONAPI = "application/vnd.api+json"
+
+// jsonAPIDocument is a top-level JSON:API document.
+type jsonAPIDocument struct {
+	Data  any            `json:"data,omitempty"`
+	Links *jsonAPILinks  `json:"links,omitempty"`
+	Meta  map[string]any `json:"meta,omitempty"`
+}
+
+// jsonAPILinks holds self and pagination links.
+type jsonAPILinks struct {
+	Self string `json:"self,omitempty"`
+	Next string `json:"next,omitempty"`
+}
+
+// jsonAPIResource is a single resource object.
+type jsonAPIResource struct {
+	Type          string                         `json:"type"`
+	ID            string                         `json:"id"`
+	Attributes    any                            `json:"attributes,omitempty"`
+	Relationships map[string]jsonAPIRelationship `json:"relationships,omitempty"`
+	Links         *jsonAPILinks                  `json:"links,omitempty"`
+}
+
+// jsonAPIRelationship is a to-one relationship with resource linkage.
+type jsonAPIRelationship struct {
+	Data jsonAPIIdentifier `json:"data"`
+}
+
+// jsonAPIIdentifier identifies a related resource.
+type jsonAPIIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// subscriptionAttributes are the JSON:API attributes of a subscription.
+type subscriptionAttributes struct {
+	ServiceName string       `json:"service_name,omitempty"`
+	Cost        int64        `json:"cost,omitempty"`
+	StartDate   string       `json:"start_date,omitempty"`
+	EndDate     string       `json:"end_date,omitempty"`
+	CreatedAt   string       `json:"created_at,omitempty"`
+	UpdatedAt   string       `json:"updated_at,omitempty"`
+	Service     *serviceWire `json:"service,omitempty"`
+}
+
+// jsonAPIDocumentOf wraps response DTOs into a JSON:API document. Lists get
+// self/next links built from the request and the X-Next-Cursor header.
+func jsonAPIDocumentOf(c *gin.Context, data any, fields fieldSet) jsonAPIDocument {
+	switch v := data.(type) {
+	case generated.Subscription:
+		return jsonAPIDocument{Data: subscriptionResource(&v, collectionPath(c), fields)}
+	case *generated.Subscription:
+		return jsonAPIDocument{Data: subscriptionResource(v, collectionPath(c), fields)}
+	case []*generated.Subscription:
+		items := make([]jsonAPIResource, 0, len(v))
+		base := collectionPath(c)
+		for _, s := range v {
+			items = append(items, subscriptionResource(s, base, fields))
+		}
+		return jsonAPIDocument{Data: items, Links: listLinks(c)}
+	case generated.SubscriptionsCost:
+		// aggregates have no identity, so they travel as a meta-only document
+		meta := map[string]any{"total": v.Total}
+		if v.Currency != "" {
+			meta["currency"] = v.Currency
+		}
+		return jsonAPIDocument{Meta: meta}
+	}
+	return jsonAPIDocument{Data: data}
+}
+
+// subscriptionResource maps a subscription to a JSON:API resource object.
+// Sparse fieldsets apply to attributes and relationships, never to type/id.
+func subscriptionResource(s *generated.Subscription, base string, fields fieldSet) jsonAPIResource {
+	full := buildSubWire(s)
+	id := full.PublicID
+	if id == "" {
+		id = strconv.FormatInt(full.ID, 10)
+	}
+	w := fields.apply(full)
+	res := jsonAPIResource{
+		Type: "subscriptions",
+		ID:   id,
+		Attributes: subscriptionAttributes{
+			ServiceName: w.ServiceName,
+			Cost:        w.Cost,
+			StartDate:   w.StartDate,
+			EndDate:     w.EndDate,
+			CreatedAt:   w.CreatedAt,
+			UpdatedAt:   w.UpdatedAt,
+			Service:     w.Service,
+		},
+		Links: &jsonAPILinks{Self: base + "/" + id},
+	}
+	if w.UserID != "" {
+		res.Relationships = map[string]jsonAPIRelationship{
+			"user": {Data: jsonAPIIdentifier{Type: "users", ID: w.UserID}},
+		}
+	}
+	return res
+}
+
+// collectionPath is the subscriptions collection of the API version serving c.
+func collectionPath(c *gin.Context) string {
+	return strings.TrimSuffix(c.FullPath(), "/:id")
+}
+
+// listLinks builds the self link and, when another page exists, the next link.
+func listLinks(c *gin.Context) *jsonAPILinks {
+	links := &jsonAPILinks{Self: c.Request.URL.RequestURI()}
+	if next := c.Writer.Header().Get("X-Next-Cursor"); next != "" {
+		q := c.Request.URL.Query()
+		q.Del("offset")
+		q.Set("cursor", next)
+		links.Next = c.Request.URL.Path + "?" + q.Encode()
+	}
+	return links
+}
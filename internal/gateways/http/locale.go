@@ -0,0 +1,78 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/i18n"
+)
+
+// localize puts the response locale into the request context: Accept-Language first, then the saved settings
+// of the user named by the user_id path or query parameter. The locale is resolved on first use.
+func localize(tr *i18n.Translator, u UseCases) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		loc := tr.Negotiate(func() []string { return preferences(c, u) })
+		c.Request = c.Request.WithContext(i18n.NewContext(c.Request.Context(), loc))
+		c.Next()
+	}
+}
+
+// responseLocale returns the locale of the request and marks the response as language dependent; nil, which
+// is English, without the localize middleware.
+func responseLocale(c *gin.Context) *i18n.Locale {
+	loc := i18n.FromContext(c.Request.Context())
+	if loc != nil {
+		c.Header("Content-Language", loc.Tag().String())
+		c.Writer.Header().Add("Vary", "Accept-Language")
+	}
+	return loc
+}
+
+// translate returns msg in the response language.
+func translate(c *gin.Context, msg string) string {
+	return responseLocale(c).Translate(msg)
+}
+
+// monthLabeler reads ?labels= of a time series: with labels=true it returns the month label in the response
+// language, e.g. "Сентябрь 2025", otherwise nil and the series keeps its ISO keys only. Answers 422 and returns
+// false when the value is not a boolean.
+func monthLabeler(c *gin.Context) (label func(time.Time) string, ok bool) {
+	v := strings.TrimSpace(c.Query("labels"))
+	if v == "" {
+		return nil, true
+	}
+	labels, err := strconv.ParseBool(v)
+	if err != nil {
+		jsonErr(c, http.StatusUnprocessableEntity, "invalid labels")
+		return nil, false
+	}
+	if !labels {
+		return nil, true
+	}
+	return responseLocale(c).MonthLabel, true
+}
+
+func preferences(c *gin.Context, u UseCases) []string {
+	if v := strings.TrimSpace(c.GetHeader("Accept-Language")); v != "" {
+		return []string{v}
+	}
+	raw := c.Param("user_id")
+	if raw == "" {
+		raw = c.Query("user_id")
+	}
+	uid, err := entity.ParseUserID(raw)
+	if err != nil || u.Sub == nil {
+		return nil
+	}
+	settings, err := u.Sub.GetSettings(c, uid)
+	if err != nil || settings.UpdatedAt.IsZero() {
+		// defaults are not a choice the user made, keep the English default
+		return nil
+	}
+	return []string{settings.Locale}
+}
@@ -0,0 +1,132 @@
+package http
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+
+	cfg "subs_tracker/internal/config"
+	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/pkg/pagination"
+)
+
+// apiMeta is the response of GET /api/v1/meta: how the API behaves on retries, pages and limits, for client
+// generators to configure their retry and paging policies from.
+type apiMeta struct {
+	Idempotency metaIdempotency `json:"idempotency"`
+	Pagination  metaPagination  `json:"pagination"`
+	RateLimits  metaRateLimits  `json:"rate_limits"`
+}
+
+// metaIdempotency describes which requests may be repeated without side effects.
+type metaIdempotency struct {
+	// RetrySafeMethods may be repeated after a timeout or a dropped connection
+	RetrySafeMethods []string `json:"retry_safe_methods"`
+	// UnsafeMethods create a new resource on every call and must not be repeated blindly
+	UnsafeMethods []string `json:"unsafe_methods"`
+	// PreconditionHeader makes PUT/DELETE of a subscription conditional on its ETag, answered 412 when it moved on
+	PreconditionHeader string `json:"precondition_header"`
+	// PreconditionRequired is HTTP_REQUIRE_IF_MATCH: PUT/DELETE without the header are refused with 428
+	PreconditionRequired bool `json:"precondition_required"`
+	// DryRunParam validates a write and answers it without saving anything
+	DryRunParam string `json:"dry_run_param"`
+}
+
+// metaPagination describes the list query parameters and their ranges.
+type metaPagination struct {
+	LimitParam       string `json:"limit_param"`
+	OffsetParam      string `json:"offset_param"`
+	CursorParam      string `json:"cursor_param"`
+	NextCursorHeader string `json:"next_cursor_header"`
+	DefaultLimit     int    `json:"default_limit"`
+	MaxLimit         int    `json:"max_limit"`
+	MaxOffset        int    `json:"max_offset"`
+	// Strict is HTTP_STRICT_PAGINATION: out of range values are answered 400 instead of being clamped
+	Strict bool `json:"strict"`
+	// MaxPageBytes is HTTP_LIST_MAX_BYTES: larger pages end early with a next cursor; 0 means no budget
+	MaxPageBytes int `json:"max_page_bytes"`
+}
+
+// metaRateLimits describes when requests are refused for load and how clients learn when to come back.
+type metaRateLimits struct {
+	// RetryableStatuses are answered with a Retry-After header, the request may be repeated after it
+	RetryableStatuses []int  `json:"retryable_statuses"`
+	RetryAfterHeader  string `json:"retry_after_header"`
+	LimitHeader       string `json:"limit_header"`
+	RemainingHeader   string `json:"remaining_header"`
+	ResetHeader       string `json:"reset_header"`
+	// The configured limits; 0 means there is no such limit
+	MaxInFlight          int     `json:"max_in_flight"`
+	ReadMaxInFlight      int     `json:"read_max_in_flight"`
+	WriteMaxInFlight     int     `json:"write_max_in_flight"`
+	ExpensiveMaxInFlight int     `json:"expensive_max_in_flight"`
+	ExpensiveRate        float64 `json:"expensive_rate"`
+	ExpensiveBurst       int     `json:"expensive_burst"`
+	// ExpensiveRoutes are relative to the API version prefix
+	ExpensiveRoutes     []string `json:"expensive_routes"`
+	QueueLength         int      `json:"queue_length"`
+	QueueTimeoutSeconds float64  `json:"queue_timeout_seconds"`
+	AbuseWindowSeconds  float64  `json:"abuse_window_seconds"`
+	AbuseMaxRequests    int      `json:"abuse_max_requests"`
+	AbuseBanSeconds     float64  `json:"abuse_ban_seconds"`
+}
+
+// newAPIMeta describes the API as configured by c.
+func newAPIMeta(c cfg.ServerConfig) apiMeta {
+	expensive := make([]string, 0, len(expensiveRoutes))
+	for route := range expensiveRoutes {
+		expensive = append(expensive, route)
+	}
+	sort.Strings(expensive)
+
+	return apiMeta{
+		Idempotency: metaIdempotency{
+			RetrySafeMethods:     []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete},
+			UnsafeMethods:        []string{http.MethodPost},
+			PreconditionHeader:   "If-Match",
+			PreconditionRequired: c.RequireIfMatch,
+			DryRunParam:          "dry_run",
+		},
+		Pagination: metaPagination{
+			LimitParam:       "limit",
+			OffsetParam:      "offset",
+			CursorParam:      "cursor",
+			NextCursorHeader: "X-Next-Cursor",
+			DefaultLimit:     pagination.DefaultLimit,
+			MaxLimit:         pagination.MaxLimit,
+			MaxOffset:        pagination.MaxOffset,
+			Strict:           c.StrictPagination,
+			MaxPageBytes:     c.ListMaxBytes,
+		},
+		RateLimits: metaRateLimits{
+			RetryableStatuses:    []int{http.StatusTooManyRequests, http.StatusServiceUnavailable},
+			RetryAfterHeader:     "Retry-After",
+			LimitHeader:          mw.HeaderLimit,
+			RemainingHeader:      mw.HeaderRemaining,
+			ResetHeader:          mw.HeaderReset,
+			MaxInFlight:          c.MaxInFlight,
+			ReadMaxInFlight:      c.ReadMaxInFlight,
+			WriteMaxInFlight:     c.WriteMaxInFlight,
+			ExpensiveMaxInFlight: c.ExpensiveMaxInFlight,
+			ExpensiveRate:        c.ExpensiveRate,
+			ExpensiveBurst:       c.ExpensiveBurst,
+			ExpensiveRoutes:      expensive,
+			QueueLength:          c.QueueLength,
+			QueueTimeoutSeconds:  c.QueueTimeout.Seconds(),
+			AbuseWindowSeconds:   c.AbuseWindow.Seconds(),
+			AbuseMaxRequests:     c.AbuseMaxRequests,
+			AbuseBanSeconds:      c.AbuseBan.Seconds(),
+		},
+	}
+}
+
+// setupMeta registers GET /meta describing retries, paging and limits of the API.
+func setupMeta(r *gin.RouterGroup, meta apiMeta) {
+	r.GET("/meta", func(c *gin.Context) {
+		if !requireAcceptJSON(c) {
+			return
+		}
+		c.JSON(http.StatusOK, meta)
+	})
+}
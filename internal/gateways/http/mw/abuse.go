@@ -0,0 +1,256 @@
+package mw
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/errcode"
+	"subs_tracker/pkg/clock"
+)
+
+const (
+	// abuseSlots - the window is kept as this many slots, so it rolls in steps of a sixth
+	abuseSlots = 6
+	// abuseMinSample - requests in the window before the error rate of a client is judged
+	abuseMinSample = 20
+	// maxAbuseClients - clients tracked at once; requests of further ones are served but not counted
+	maxAbuseClients = 10000
+)
+
+// AbuseRules — when a client counts as abusive and what happens to it
+type AbuseRules struct {
+	// Window - span the counts are kept for, 0 disables tracking
+	Window time.Duration
+	// MaxRequests - requests a client may make in the window, 0 - no limit
+	MaxRequests int
+	// MaxErrorRate - share of responses >= 400 a client may get in the window, 0 - no limit
+	MaxErrorRate float64
+	// Ban - how long an abusive client is answered 429, 0 only reports it
+	Ban time.Duration
+}
+
+// ClientStats — requests of a client in the window
+type ClientStats struct {
+	// Client - "token:<fingerprint>" for a known bearer token, else "ip:<address>"
+	Client    string
+	Requests  int
+	Errors    int
+	ErrorRate float64
+	// Abusive - the client exceeds the rules now
+	Abusive bool
+	// BannedUntil - end of the ban, zero when the client is not banned
+	BannedUntil time.Time
+}
+
+// Abuse — counts requests and error responses per client in a rolling window and bans clients exceeding the
+// rules for a cooldown. A nil *Abuse tracks nothing
+type Abuse struct {
+	rules  AbuseRules
+	tokens *Tokens
+	log    *slog.Logger
+	clock  clock.Clock
+
+	mu      sync.Mutex
+	clients map[string]*clientWindow
+	pruned  time.Time
+}
+
+type clientWindow struct {
+	slots       [abuseSlots]slotCount
+	bannedUntil time.Time
+	last        time.Time
+}
+
+type slotCount struct {
+	slot             int64
+	requests, errors int
+}
+
+// NewAbuse creates the tracker of rules, telling clients apart by the tokens they present; nil for a zero
+// window, and applies options
+func NewAbuse(rules AbuseRules, tokens *Tokens, log *slog.Logger, options ...func(*Abuse)) *Abuse {
+	if rules.Window <= 0 {
+		return nil
+	}
+	a := &Abuse{rules: rules, tokens: tokens, log: log, clock: clock.System, clients: make(map[string]*clientWindow)}
+	for _, o := range options {
+		o(a)
+	}
+	return a
+}
+
+// WithAbuseClock sets the source of the current time
+func WithAbuseClock(c clock.Clock) func(*Abuse) {
+	return func(a *Abuse) {
+		if c != nil {
+			a.clock = c
+		}
+	}
+}
+
+// Window returns the span of the counts, 0 when nothing is tracked
+func (a *Abuse) Window() time.Duration {
+	if a == nil {
+		return 0
+	}
+	return a.rules.Window
+}
+
+// Track — answer a banned client 429 with Retry-After, count everything else once it is answered
+func (a *Abuse) Track() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if a == nil {
+			c.Next()
+			return
+		}
+		client := a.tokens.clientOf(c)
+		if until, banned := a.bannedUntil(client); banned {
+			c.Header("Retry-After", strconv.Itoa(int(max(math.Ceil(until.Sub(a.clock.Now()).Seconds()), 1))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "client is temporarily banned, retry later", "code": errcode.RateLimited})
+			return
+		}
+		c.Next()
+		a.record(client, c.Writer.Status() >= http.StatusBadRequest)
+	}
+}
+
+func (a *Abuse) bannedUntil(client string) (time.Time, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	w, ok := a.clients[client]
+	if !ok || !w.bannedUntil.After(a.clock.Now()) {
+		return time.Time{}, false
+	}
+	return w.bannedUntil, true
+}
+
+// record counts a request of client and bans it when it now exceeds the rules
+func (a *Abuse) record(client string, failed bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.clock.Now()
+	a.prune(now)
+	w, ok := a.clients[client]
+	if !ok {
+		if len(a.clients) >= maxAbuseClients {
+			return
+		}
+		w = &clientWindow{}
+		a.clients[client] = w
+	}
+	w.last = now
+
+	slot := a.slotOf(now)
+	s := &w.slots[slot%abuseSlots]
+	if s.slot != slot {
+		*s = slotCount{slot: slot}
+	}
+	s.requests++
+	if failed {
+		s.errors++
+	}
+
+	if a.rules.Ban <= 0 || w.bannedUntil.After(now) {
+		return
+	}
+	if st := a.statsOf(client, w, now); st.Abusive {
+		w.bannedUntil = now.Add(a.rules.Ban)
+		a.log.Warn("client banned for abuse",
+			slog.String("client", client),
+			slog.Int("requests", st.Requests),
+			slog.Int("errors", st.Errors),
+			slog.Duration("ban", a.rules.Ban),
+		)
+	}
+}
+
+// slotOf returns the number of the slot t falls in
+func (a *Abuse) slotOf(t time.Time) int64 {
+	return t.UnixNano() / int64(max(a.rules.Window/abuseSlots, 1))
+}
+
+// statsOf sums the slots of w still in the window
+func (a *Abuse) statsOf(client string, w *clientWindow, now time.Time) ClientStats {
+	st := ClientStats{Client: client}
+	cur := a.slotOf(now)
+	for _, s := range w.slots {
+		if s.slot > cur-abuseSlots && s.slot <= cur {
+			st.Requests += s.requests
+			st.Errors += s.errors
+		}
+	}
+	if st.Requests > 0 {
+		st.ErrorRate = float64(st.Errors) / float64(st.Requests)
+	}
+	st.Abusive = (a.rules.MaxRequests > 0 && st.Requests > a.rules.MaxRequests) ||
+		(a.rules.MaxErrorRate > 0 && st.Requests >= abuseMinSample && st.ErrorRate > a.rules.MaxErrorRate)
+	if w.bannedUntil.After(now) {
+		st.BannedUntil = w.bannedUntil
+	}
+	return st
+}
+
+// prune forgets clients idle for a window and not banned, at most once a window
+func (a *Abuse) prune(now time.Time) {
+	if now.Sub(a.pruned) < a.rules.Window {
+		return
+	}
+	a.pruned = now
+	for client, w := range a.clients {
+		if now.Sub(w.last) >= a.rules.Window && !w.bannedUntil.After(now) {
+			delete(a.clients, client)
+		}
+	}
+}
+
+// Clients returns the stats of up to limit clients seen in the window or banned, most requests first
+func (a *Abuse) Clients(limit int) []ClientStats {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	now := a.clock.Now()
+	out := make([]ClientStats, 0, len(a.clients))
+	for client, w := range a.clients {
+		if st := a.statsOf(client, w, now); st.Requests > 0 || !st.BannedUntil.IsZero() {
+			out = append(out, st)
+		}
+	}
+	a.mu.Unlock()
+
+	slices.SortFunc(out, func(x, y ClientStats) int {
+		if x.Requests != y.Requests {
+			return y.Requests - x.Requests
+		}
+		return strings.Compare(x.Client, y.Client)
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// Unban lifts the ban of client; false when it is not banned
+func (a *Abuse) Unban(client string) bool {
+	if a == nil {
+		return false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	w, ok := a.clients[client]
+	if !ok || !w.bannedUntil.After(a.clock.Now()) {
+		return false
+	}
+	w.bannedUntil = time.Time{}
+	// the counts that led to the ban would ban it again on its next request
+	w.slots = [abuseSlots]slotCount{}
+	return true
+}
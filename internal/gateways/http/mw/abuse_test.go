@@ -0,0 +1,131 @@
+package mw
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/pkg/clock"
+)
+
+func TestAbuse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setup := func(rules AbuseRules) (*Abuse, *clock.Fake, func(ip, token string, status int) *httptest.ResponseRecorder) {
+		fake := clock.NewFake(time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC))
+		a := NewAbuse(rules, NewTokens("adm1n", "read:dash"), slog.New(slog.NewTextHandler(io.Discard, nil)), WithAbuseClock(fake))
+		r := gin.New()
+		require.NoError(t, r.SetTrustedProxies(nil))
+		r.GET("/subs", a.Track(), func(c *gin.Context) {
+			code, _ := strconv.Atoi(c.Query("status"))
+			c.Status(code)
+		})
+		serve := func(ip, token string, status int) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/subs?status="+strconv.Itoa(status), nil)
+			req.RemoteAddr = ip + ":1234"
+			// made up by the client, no proxy is trusted
+			req.Header.Set("X-Forwarded-For", "203.0.113."+strconv.Itoa(status%200))
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			r.ServeHTTP(w, req)
+			return w
+		}
+		return a, fake, serve
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		a, _, serve := setup(AbuseRules{})
+		assert.Nil(t, a)
+		assert.Equal(t, http.StatusOK, serve("10.0.0.1", "", http.StatusOK).Code)
+		assert.Nil(t, a.Clients(10))
+		assert.False(t, a.Unban("ip:10.0.0.1"))
+	})
+
+	t.Run("counts per address and token", func(t *testing.T) {
+		a, _, serve := setup(AbuseRules{Window: time.Minute})
+		serve("10.0.0.1", "", http.StatusOK)
+		serve("10.0.0.1", "", http.StatusNotFound)
+		serve("10.0.0.1", "made-up", http.StatusOK)
+		serve("10.0.0.2", "dash", http.StatusOK)
+
+		got := a.Clients(10)
+		require.Len(t, got, 2)
+		assert.Equal(t, ClientStats{Client: "ip:10.0.0.1", Requests: 3, Errors: 1, ErrorRate: 1.0 / 3}, got[0])
+		assert.Regexp(t, `^token:[0-9a-f]{12}$`, got[1].Client)
+		assert.Equal(t, 1, got[1].Requests)
+		assert.Len(t, a.Clients(1), 1)
+	})
+
+	t.Run("window rolls", func(t *testing.T) {
+		a, fake, serve := setup(AbuseRules{Window: time.Minute})
+		serve("10.0.0.1", "", http.StatusOK)
+		fake.Advance(30 * time.Second)
+		serve("10.0.0.1", "", http.StatusOK)
+		fake.Advance(40 * time.Second)
+		require.Len(t, a.Clients(10), 1)
+		assert.Equal(t, 1, a.Clients(10)[0].Requests)
+		fake.Advance(time.Minute)
+		assert.Empty(t, a.Clients(10))
+	})
+
+	t.Run("report only", func(t *testing.T) {
+		a, _, serve := setup(AbuseRules{Window: time.Minute, MaxRequests: 2})
+		for range 3 {
+			assert.Equal(t, http.StatusOK, serve("10.0.0.1", "", http.StatusOK).Code)
+		}
+		got := a.Clients(10)
+		require.Len(t, got, 1)
+		assert.True(t, got[0].Abusive)
+		assert.True(t, got[0].BannedUntil.IsZero())
+	})
+
+	t.Run("ban on requests", func(t *testing.T) {
+		a, fake, serve := setup(AbuseRules{Window: time.Minute, MaxRequests: 2, Ban: 5 * time.Minute})
+		for range 3 {
+			assert.Equal(t, http.StatusOK, serve("10.0.0.1", "", http.StatusOK).Code)
+		}
+		w := serve("10.0.0.1", "", http.StatusOK)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "300", w.Header().Get("Retry-After"))
+		assert.JSONEq(t, `{"error":"client is temporarily banned, retry later","code":"RATE_LIMITED"}`, w.Body.String())
+		assert.Equal(t, http.StatusOK, serve("10.0.0.2", "", http.StatusOK).Code, "other clients are served")
+
+		fake.Advance(4 * time.Minute)
+		assert.Equal(t, "60", serve("10.0.0.1", "", http.StatusOK).Header().Get("Retry-After"))
+		got := a.Clients(10)
+		require.Len(t, got, 1, "the banned client is reported past its window")
+		assert.Equal(t, fake.Now().Add(time.Minute), got[0].BannedUntil)
+
+		fake.Advance(time.Minute)
+		assert.Equal(t, http.StatusOK, serve("10.0.0.1", "", http.StatusOK).Code)
+	})
+
+	t.Run("ban on error rate", func(t *testing.T) {
+		_, _, serve := setup(AbuseRules{Window: time.Minute, MaxErrorRate: 0.5, Ban: time.Minute})
+		for range abuseMinSample - 1 {
+			serve("10.0.0.1", "", http.StatusUnauthorized)
+		}
+		assert.Equal(t, http.StatusUnauthorized, serve("10.0.0.1", "", http.StatusUnauthorized).Code, "judged once the sample is complete")
+		assert.Equal(t, http.StatusTooManyRequests, serve("10.0.0.1", "", http.StatusOK).Code)
+	})
+
+	t.Run("unban", func(t *testing.T) {
+		a, _, serve := setup(AbuseRules{Window: time.Minute, MaxRequests: 1, Ban: time.Hour})
+		serve("10.0.0.1", "", http.StatusOK)
+		serve("10.0.0.1", "", http.StatusOK)
+		require.Equal(t, http.StatusTooManyRequests, serve("10.0.0.1", "", http.StatusOK).Code)
+
+		assert.True(t, a.Unban("ip:10.0.0.1"))
+		assert.False(t, a.Unban("ip:10.0.0.1"))
+		assert.Equal(t, http.StatusOK, serve("10.0.0.1", "", http.StatusOK).Code)
+	})
+}
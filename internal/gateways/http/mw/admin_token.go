@@ -0,0 +1,44 @@
+package mw
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/errcode"
+)
+
+// AdminToken — allow the request only with "Authorization: Bearer <token>" of a token with the admin scope:
+// HTTP_ADMIN_TOKEN or an admin entry of HTTP_API_TOKENS; without such tokens the guarded routes are disabled
+func AdminToken(tokens *Tokens) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !tokens.grants(ScopeAdmin) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin writes are disabled", "code": errcode.FeatureDisabled})
+			return
+		}
+		requireScope(c, tokens, ScopeAdmin, "admin")
+	}
+}
+
+// AdminPage — guard browser pages with HTTP Basic auth using a token with the admin scope as the password (any
+// user name): HTTP_ADMIN_TOKEN or an admin entry of HTTP_API_TOKENS; "Authorization: Bearer <token>" is accepted
+// too. Without such tokens the pages are disabled
+func AdminPage(tokens *Tokens) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !tokens.grants(ScopeAdmin) {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok {
+			_, got, ok = c.Request.BasicAuth()
+		}
+		if !ok || tokens.scopeOfToken(got) < ScopeAdmin {
+			c.Header("WWW-Authenticate", `Basic realm="admin", charset="UTF-8"`)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,98 @@
+package mw
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/audit"
+	"subs_tracker/internal/theme"
+	"subs_tracker/internal/tracing"
+)
+
+// Bounds of the headers kept, they are whatever the client or a proxy sends
+const (
+	maxAuditUserAgent = 512
+	maxAuditRequestID = 100
+)
+
+// Audit — record every write request that matched a route in the request audit: the SHA-256 of its raw body,
+// the client as the abuse stats name it and where the request came from: the client address X-Forwarded-For
+// gives when the peer is a trusted proxy, and the peer itself. The whole body is hashed, up to
+// audit.MaxHashedBody: what the handler left unread is read after it
+func Audit(l *audit.Log, tokens *Tokens) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		at := time.Now()
+		body := &hashedBody{hash: sha256.New()}
+		if c.Request.Body != nil {
+			body.ReadCloser = c.Request.Body
+			c.Request.Body = body
+		}
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			return
+		}
+		if body.ReadCloser != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(body, audit.MaxHashedBody-body.n))
+		}
+		tenant := tracing.TenantID(c.Request.Context())
+		if !theme.ValidTenantID(tenant) {
+			tenant = ""
+		}
+		l.Record(audit.Entry{
+			At:         at,
+			Method:     c.Request.Method,
+			Route:      route,
+			Path:       c.Request.URL.Path,
+			Status:     c.Writer.Status(),
+			BodySHA256: hex.EncodeToString(body.hash.Sum(nil)),
+			BodySize:   body.n,
+			Client:     tokens.clientOf(c),
+			RemoteIP:   c.ClientIP(),
+			PeerIP:     c.RemoteIP(),
+			UserAgent:  cut(c.Request.UserAgent(), maxAuditUserAgent),
+			RequestID:  cut(c.Writer.Header().Get("X-Request-ID"), maxAuditRequestID),
+			TenantID:   tenant,
+		})
+	}
+}
+
+// cut returns the first n bytes of s, backing off to a whole UTF-8 sequence
+func cut(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// hashedBody hashes the first audit.MaxHashedBody bytes read from the request body
+type hashedBody struct {
+	io.ReadCloser
+	hash hash.Hash
+	n    int64
+}
+
+func (b *hashedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if keep := min(int64(n), audit.MaxHashedBody-b.n); keep > 0 {
+		b.hash.Write(p[:keep])
+		b.n += keep
+	}
+	return n, err
+}
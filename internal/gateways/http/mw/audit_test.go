@@ -0,0 +1,80 @@
+package mw
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/internal/audit"
+	"subs_tracker/internal/storetest"
+)
+
+func TestAudit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := storetest.NewAuditStore()
+	l := audit.NewLog(store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	r := gin.New()
+	require.NoError(t, r.SetTrustedProxies([]string{"10.0.0.2"}))
+	r.Use(Audit(l, NewTokens("", "write:w1")))
+	r.POST("/subs", func(c *gin.Context) {
+		// reads only the first value of the body, the rest is hashed after the handler
+		var v map[string]any
+		_ = json.NewDecoder(c.Request.Body).Decode(&v)
+		c.Header("X-Request-ID", "req-1")
+		c.Status(http.StatusCreated)
+	})
+	r.GET("/subs", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	body := `{"service_name":"Netflix"}` + "\n" + `{"trailing":true}`
+	serveFrom := func(peer, method, path, body, token string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.RemoteAddr = peer + ":1234"
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		req.Header.Set("User-Agent", "client/1.0")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	serve := func(method, path, body, token string) { serveFrom("10.0.0.1", method, path, body, token) }
+	serve(http.MethodPost, "/subs", body, "w1")
+	serveFrom("10.0.0.2", http.MethodPost, "/subs", "{}", "")
+	serve(http.MethodGet, "/subs", "", "")
+	serve(http.MethodPost, "/unknown", "{}", "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, l.Run(ctx))
+
+	entries := store.Entries()
+	require.Len(t, entries, 2, "writes to matched routes only")
+	e := entries[0]
+	sum := sha256.Sum256([]byte(body))
+	assert.Equal(t, hex.EncodeToString(sum[:]), e.BodySHA256)
+	assert.Equal(t, int64(len(body)), e.BodySize)
+	assert.Equal(t, "POST", e.Method)
+	assert.Equal(t, "/subs", e.Route)
+	assert.Equal(t, http.StatusCreated, e.Status)
+	assert.Regexp(t, `^token:[0-9a-f]{12}$`, e.Client)
+	assert.Equal(t, "10.0.0.1", e.RemoteIP, "X-Forwarded-For of an untrusted peer is ignored")
+	assert.Equal(t, "10.0.0.1", e.PeerIP)
+	assert.Equal(t, "client/1.0", e.UserAgent)
+	assert.Equal(t, "req-1", e.RequestID)
+	assert.False(t, e.At.IsZero())
+
+	proxied := entries[1]
+	assert.Equal(t, "203.0.113.7", proxied.RemoteIP, "a trusted proxy names the client")
+	assert.Equal(t, "10.0.0.2", proxied.PeerIP)
+}
@@ -0,0 +1,191 @@
+package mw
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"mime"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const redactedValue = "[redacted]"
+
+// BodyLogConfig — which exchanges BodyLog records and how much of them
+type BodyLogConfig struct {
+	// SampleRate - share of requests, 0..1, logged regardless of route and status
+	SampleRate float64
+	// Routes - gin route patterns, e.g. /api/v1/subscriptions/:id, that are always logged
+	Routes []string
+	// Statuses - response status codes that are always logged
+	Statuses []int
+	// MaxBytes - kept bytes of each body, the rest is cut
+	MaxBytes int
+	// Sensitive - JSON keys and form fields whose values are replaced with [redacted]
+	Sensitive []string
+}
+
+// BodyLog — log request and response bodies of sampled requests, chosen routes and chosen statuses, to
+// troubleshoot client integrations without capturing all traffic. Values of sensitive keys are removed
+// here; user IDs and emails are left to the redacting log handler. Only the part of the request body the
+// handler reads is seen, and binary bodies are logged by size alone
+func BodyLog(l *slog.Logger, conf BodyLogConfig) gin.HandlerFunc {
+	return bodyLog(l, conf, rand.Float64)
+}
+
+func bodyLog(l *slog.Logger, conf BodyLogConfig, sample func() float64) gin.HandlerFunc {
+	limit := conf.MaxBytes
+	if limit <= 0 {
+		limit = 4096
+	}
+	redact := newBodyRedactor(conf.Sensitive)
+
+	return func(c *gin.Context) {
+		reason := ""
+		switch {
+		case slices.Contains(conf.Routes, c.FullPath()):
+			reason = "route"
+		case conf.SampleRate > 0 && sample() < conf.SampleRate:
+			reason = "sampled"
+		case len(conf.Statuses) == 0:
+			c.Next()
+			return
+		}
+
+		req := &cappedBuffer{max: limit}
+		if c.Request.Body != nil {
+			c.Request.Body = teeBody{Reader: io.TeeReader(c.Request.Body, req), Closer: c.Request.Body}
+		}
+		resp := &bodyRecorder{ResponseWriter: c.Writer, buf: cappedBuffer{max: limit}}
+		c.Writer = resp
+		c.Next()
+		c.Writer = resp.ResponseWriter
+
+		status := resp.Status()
+		if reason == "" {
+			if !slices.Contains(conf.Statuses, status) {
+				return
+			}
+			reason = "status"
+		}
+		attrs := []any{
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", status,
+			"reason", reason,
+			"request_body", redact.body(c.Request.Header.Get("Content-Type"), req),
+			"response_body", redact.body(resp.Header().Get("Content-Type"), &resp.buf),
+		}
+		if rid := resp.Header().Get("X-Request-ID"); rid != "" {
+			attrs = append(attrs, "request_id", rid)
+		}
+		l.Info("http body", attrs...)
+	}
+}
+
+// cappedBuffer keeps the first max bytes written to it and counts the rest
+type cappedBuffer struct {
+	max   int
+	buf   bytes.Buffer
+	total int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	b.total += len(p)
+	if room := b.max - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(room, len(p))])
+	}
+	return len(p), nil
+}
+
+func (b *cappedBuffer) truncated() bool {
+	return b.total > b.buf.Len()
+}
+
+// teeBody copies what the handler reads from the request body
+type teeBody struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyRecorder passes the response through and keeps the start of the body
+type bodyRecorder struct {
+	gin.ResponseWriter
+	buf cappedBuffer
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	_, _ = w.buf.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	_, _ = w.buf.Write([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// bodyRedactor removes values of sensitive keys from logged bodies
+type bodyRedactor struct {
+	keys map[string]bool
+	json *regexp.Regexp
+}
+
+func newBodyRedactor(keys []string) bodyRedactor {
+	r := bodyRedactor{keys: make(map[string]bool, len(keys))}
+	quoted := make([]string, 0, len(keys))
+	for _, k := range keys {
+		r.keys[strings.ToLower(k)] = true
+		quoted = append(quoted, regexp.QuoteMeta(k))
+	}
+	if len(quoted) > 0 {
+		// a string value, or anything up to the next separator; also works on a cut body
+		r.json = regexp.MustCompile(`(?i)("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`)
+	}
+	return r
+}
+
+func (r bodyRedactor) body(contentType string, b *cappedBuffer) string {
+	if b.total == 0 {
+		return ""
+	}
+	mt, _, _ := mime.ParseMediaType(contentType)
+	text := b.buf.String()
+	switch {
+	case mt == "application/json" || strings.HasSuffix(mt, "+json"):
+		if r.json != nil {
+			text = r.json.ReplaceAllString(text, `${1}"`+redactedValue+`"`)
+		}
+	case mt == "application/x-www-form-urlencoded":
+		text = r.form(text)
+	case mt == "" || strings.HasPrefix(mt, "text/") || strings.HasSuffix(mt, "+xml") || mt == "application/xml":
+		// logged as is
+	default:
+		return "[" + mt + ", " + byteCount(b.total) + "]"
+	}
+	if b.truncated() {
+		text += "… [" + byteCount(b.total) + "]"
+	}
+	return text
+}
+
+func (r bodyRedactor) form(text string) string {
+	pairs := strings.Split(text, "&")
+	for i, p := range pairs {
+		k, _, ok := strings.Cut(p, "=")
+		if name, err := url.QueryUnescape(k); ok && err == nil && r.keys[strings.ToLower(name)] {
+			pairs[i] = k + "=" + url.QueryEscape(redactedValue)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func byteCount(n int) string {
+	return strconv.Itoa(n) + " bytes"
+}
@@ -0,0 +1,83 @@
+package mw
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodyLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var logs bytes.Buffer
+	l := slog.New(slog.NewJSONHandler(&logs, nil))
+	roll := 0.9
+	r := gin.New()
+	r.Use(bodyLog(l, BodyLogConfig{
+		SampleRate: 0.5,
+		Routes:     []string{"/echo"},
+		Statuses:   []int{http.StatusUnprocessableEntity},
+		MaxBytes:   64,
+		Sensitive:  []string{"password", "token"},
+	}, func() float64 { return roll }))
+	r.POST("/echo", func(c *gin.Context) {
+		raw, _ := io.ReadAll(c.Request.Body)
+		c.Data(http.StatusOK, "application/json", raw)
+	})
+	r.POST("/reject", func(c *gin.Context) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid", "token": "abc"})
+	})
+	r.GET("/ok", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+	r.GET("/blob", func(c *gin.Context) { c.Data(http.StatusOK, "application/pdf", []byte("%PDF-1.7")) })
+
+	entries := func(method, path, body string) []map[string]any {
+		logs.Reset()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		var out []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var e map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &e))
+			out = append(out, e)
+		}
+		return out
+	}
+
+	got := entries(http.MethodPost, "/echo", `{"user":"ann","password":"hunter2","token": 42}`)
+	require.Len(t, got, 1)
+	assert.Equal(t, "route", got[0]["reason"])
+	assert.Equal(t, `{"user":"ann","password":"[redacted]","token": "[redacted]"}`, got[0]["request_body"])
+	assert.Equal(t, got[0]["request_body"], got[0]["response_body"], "the handler saw the whole body")
+
+	got = entries(http.MethodPost, "/echo", `{"note":"`+strings.Repeat("x", 100)+`"}`)
+	require.Len(t, got, 1)
+	assert.Equal(t, `{"note":"`+strings.Repeat("x", 55)+`… [111 bytes]`, got[0]["request_body"])
+
+	got = entries(http.MethodPost, "/reject", "")
+	require.Len(t, got, 1)
+	assert.Equal(t, "status", got[0]["reason"])
+	assert.Equal(t, `{"error":"invalid","token":"[redacted]"}`, got[0]["response_body"])
+
+	assert.Empty(t, entries(http.MethodGet, "/ok", ""), "neither sampled nor matched")
+	roll = 0.1
+	got = entries(http.MethodGet, "/ok", "")
+	require.Len(t, got, 1)
+	assert.Equal(t, "sampled", got[0]["reason"])
+	assert.Equal(t, "pong", got[0]["response_body"])
+	got = entries(http.MethodGet, "/blob", "")
+	require.Len(t, got, 1)
+	assert.Equal(t, "[application/pdf, 8 bytes]", got[0]["response_body"])
+}
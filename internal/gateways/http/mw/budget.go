@@ -0,0 +1,28 @@
+package mw
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const budgetKey = "mw.latency_budget"
+
+// Budget — annotate a route with its latency budget (SLO) at registration, e.g.
+// r.GET("/subscriptions", mw.Budget(300*time.Millisecond), list). GinSlog marks slower requests and
+// GinMetrics counts them in slo_violations_total; routes without a budget are never reported
+func Budget(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(budgetKey, d)
+	}
+}
+
+// overBudget returns the budget of the matched route and whether the request took longer
+func overBudget(c *gin.Context, took time.Duration) (time.Duration, bool) {
+	v, ok := c.Get(budgetKey)
+	if !ok {
+		return 0, false
+	}
+	budget, _ := v.(time.Duration)
+	return budget, budget > 0 && took > budget
+}
@@ -0,0 +1,59 @@
+package mw
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/internal/metrics"
+)
+
+func TestBudget(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var logs bytes.Buffer
+	reg := prometheus.NewRegistry()
+	r := gin.New()
+	r.Use(GinSlog(slog.New(slog.NewJSONHandler(&logs, nil))), GinMetrics(metrics.NewHTTP(reg, metrics.Options{})))
+	r.GET("/slow", Budget(time.Millisecond), func(c *gin.Context) {
+		time.Sleep(5 * time.Millisecond)
+		c.Status(http.StatusOK)
+	})
+	r.GET("/fast", Budget(time.Minute), func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/free", func(c *gin.Context) {
+		time.Sleep(5 * time.Millisecond)
+		c.Status(http.StatusOK)
+	})
+
+	entry := func(path string) map[string]any {
+		logs.Reset()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		var e map[string]any
+		require.NoError(t, json.Unmarshal(logs.Bytes(), &e))
+		return e
+	}
+
+	slow := entry("/slow")
+	assert.Equal(t, "WARN", slow["level"])
+	assert.Equal(t, true, slow["slo_violation"])
+	assert.EqualValues(t, 1, slow["budget_ms"])
+
+	fast := entry("/fast")
+	assert.Equal(t, "INFO", fast["level"])
+	assert.NotContains(t, fast, "slo_violation")
+	assert.EqualValues(t, 60000, fast["budget_ms"])
+
+	free := entry("/free")
+	assert.NotContains(t, free, "budget_ms", "routes without a budget are never reported")
+
+	assert.Equal(t, 1, testutil.CollectAndCount(reg, "slo_violations_total"))
+}
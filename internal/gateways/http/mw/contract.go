@@ -0,0 +1,128 @@
+package mw
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gin-gonic/gin"
+	"github.com/oasdiff/yaml"
+
+	"subs_tracker/internal/errcode"
+)
+
+// ContractValidation — check requests and responses of the routes described by a Swagger 2.0 spec.
+// A request breaking the contract is rejected with 400 before it reaches the handler; a response breaking
+// it is still sent but logged as an error, since that is drift between the handlers and the spec.
+// Routes missing from the spec pass unchecked. Meant for dev and test environments: every response
+// is copied and parsed once more.
+func ContractValidation(spec []byte, log *slog.Logger) (gin.HandlerFunc, error) {
+	var doc2 openapi2.T
+	if err := yaml.Unmarshal(spec, &doc2); err != nil {
+		return nil, fmt.Errorf("parse spec: %w", err)
+	}
+	doc, err := openapi2conv.ToV3(&doc2)
+	if err != nil {
+		return nil, fmt.Errorf("convert spec: %w", err)
+	}
+	// match by path only, whatever host the server is reached at
+	doc.Servers = openapi3.Servers{{URL: doc2.BasePath}}
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("build spec router: %w", err)
+	}
+	opts := &openapi3filter.Options{
+		AuthenticationFunc:  openapi3filter.NoopAuthenticationFunc,
+		SkipSettingDefaults: true,
+		MultiError:          true,
+	}
+
+	return func(c *gin.Context) {
+		route, params, err := router.FindRoute(c.Request)
+		if err != nil {
+			if !errors.Is(err, routers.ErrPathNotFound) && !errors.Is(err, routers.ErrMethodNotAllowed) {
+				log.Warn("contract route lookup failed", slog.String("path", c.Request.URL.Path), slog.Any("error", err))
+			}
+			c.Next()
+			return
+		}
+		in := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: params,
+			Route:      route,
+			Options:    opts,
+		}
+		if err := openapi3filter.ValidateRequest(c.Request.Context(), in); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "request does not match the API contract: " + firstLine(err), "code": errcode.BadRequest})
+			return
+		}
+
+		rec := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = rec
+		c.Next()
+		c.Writer = rec.ResponseWriter
+
+		if err := validateResponse(c.Request.Context(), in, rec); err != nil {
+			log.Error("response does not match the API contract",
+				slog.String("method", c.Request.Method),
+				slog.String("route", route.Path),
+				slog.Int("status", rec.Status()),
+				slog.Any("error", err),
+			)
+		}
+	}, nil
+}
+
+func validateResponse(ctx context.Context, in *openapi3filter.RequestValidationInput, rec *recordingWriter) error {
+	opts := *in.Options
+	// only JSON bodies are described by the spec; MessagePack and XML renderings of them are not checked
+	if !isJSON(rec.Header().Get("Content-Type")) {
+		opts.ExcludeResponseBody = true
+	}
+	return openapi3filter.ValidateResponse(ctx, &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: in,
+		Status:                 rec.Status(),
+		Header:                 rec.Header(),
+		Body:                   io.NopCloser(bytes.NewReader(rec.body.Bytes())),
+		Options:                &opts,
+	})
+}
+
+func isJSON(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mt == "application/json" || strings.HasSuffix(mt, "+json"))
+}
+
+// firstLine keeps a multi-error short enough for a response body
+func firstLine(err error) string {
+	msg, _, _ := strings.Cut(err.Error(), "\n")
+	return msg
+}
+
+// recordingWriter passes the response through and keeps a copy of the body
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,74 @@
+package mw
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSpec = `
+swagger: "2.0"
+info: {title: test, version: "1"}
+basePath: /api/v1
+paths:
+  /items/{id}:
+    get:
+      produces: [application/json]
+      parameters:
+        - {name: id, in: path, required: true, type: integer}
+      responses:
+        "200":
+          description: ok
+          schema:
+            type: object
+            required: [name]
+            properties:
+              name: {type: string}
+`
+
+func TestContractValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var logs bytes.Buffer
+	validate, err := ContractValidation([]byte(testSpec), slog.New(slog.NewTextHandler(&logs, nil)))
+	require.NoError(t, err)
+
+	r := gin.New()
+	r.Use(validate)
+	r.GET("/api/v1/items/:id", func(c *gin.Context) {
+		if c.Param("id") == "2" {
+			c.JSON(http.StatusOK, gin.H{"title": "drifted"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"name": "ok"})
+	})
+	r.GET("/api/v1/undocumented", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, serve("/api/v1/items/1").Code)
+	assert.Empty(t, logs.String())
+
+	w := serve("/api/v1/items/abc")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "request does not match the API contract")
+
+	assert.Equal(t, http.StatusOK, serve("/api/v1/items/2").Code, "responses are still sent")
+	assert.Contains(t, logs.String(), "response does not match the API contract")
+
+	assert.Equal(t, http.StatusNoContent, serve("/api/v1/undocumented").Code)
+}
+
+func TestContractValidation_InvalidSpec(t *testing.T) {
+	_, err := ContractValidation([]byte("swagger: [\n"), slog.New(slog.DiscardHandler))
+	assert.Error(t, err)
+}
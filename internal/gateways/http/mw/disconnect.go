@@ -0,0 +1,36 @@
+package mw
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatusClientClosedRequest — non-standard status (as in nginx) of a request the client gave up on before the
+// response; it is never sent, only logged and counted
+const StatusClientClosedRequest = 499
+
+// CancelOnDisconnect — end the context of a request when its handler returns, so queries and goroutines it
+// left behind stop with it, and record a request whose client went away before any response as
+// StatusClientClosedRequest. net/http cancels the request context when the client disconnects, the queries
+// started with it are then cancelled on the server, see postgres.CancelStatements. Register it after the
+// logging and metrics middleware so they see the status
+func CancelOnDisconnect() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if ClientGone(c) && !c.Writer.Written() {
+			c.AbortWithStatus(StatusClientClosedRequest)
+		}
+	}
+}
+
+// ClientGone reports whether the client of the request disconnected or the request was cancelled otherwise
+func ClientGone(c *gin.Context) bool {
+	return errors.Is(c.Request.Context().Err(), context.Canceled)
+}
@@ -0,0 +1,62 @@
+package mw
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCancelOnDisconnect(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var logs bytes.Buffer
+	r := gin.New()
+	r.Use(GinSlog(slog.New(slog.NewJSONHandler(&logs, nil))), CancelOnDisconnect())
+
+	var handlerCtx context.Context
+	r.GET("/slow", func(c *gin.Context) {
+		handlerCtx = c.Request.Context()
+		select {
+		case <-handlerCtx.Done():
+		case <-time.After(5 * time.Second):
+			c.Status(http.StatusOK)
+		}
+	})
+	r.GET("/fast", func(c *gin.Context) {
+		handlerCtx = c.Request.Context()
+		c.Status(http.StatusOK)
+	})
+
+	entry := func(req *http.Request) (*httptest.ResponseRecorder, map[string]any) {
+		logs.Reset()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var e map[string]any
+		require.NoError(t, json.Unmarshal(logs.Bytes(), &e))
+		return w, e
+	}
+
+	t.Run("client gone", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+		start := time.Now()
+		_, e := entry(httptest.NewRequest(http.MethodGet, "/slow", nil).WithContext(ctx))
+		assert.Less(t, time.Since(start), time.Second, "the handler stops with the client")
+		assert.EqualValues(t, StatusClientClosedRequest, e["status"])
+	})
+
+	t.Run("handler returned", func(t *testing.T) {
+		w, e := entry(httptest.NewRequest(http.MethodGet, "/fast", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.EqualValues(t, http.StatusOK, e["status"])
+		assert.ErrorIs(t, handlerCtx.Err(), context.Canceled, "work left behind by the handler is cancelled")
+	})
+}
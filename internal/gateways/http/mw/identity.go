@@ -0,0 +1,17 @@
+package mw
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/buildinfo"
+)
+
+// Identity — set Server and X-App-Version headers on every response
+func Identity(info buildinfo.Info) gin.HandlerFunc {
+	server := info.UserAgent()
+	return func(c *gin.Context) {
+		c.Header("Server", server)
+		c.Header("X-App-Version", info.Version)
+		c.Next()
+	}
+}
@@ -0,0 +1,149 @@
+package mw
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/errcode"
+)
+
+// Rate limit headers sent with limited requests so clients can back off
+const (
+	HeaderLimit     = "X-RateLimit-Limit"
+	HeaderRemaining = "X-RateLimit-Remaining"
+	HeaderReset     = "X-RateLimit-Reset"
+)
+
+// defaultHold - assumed slot hold time until the first request completes
+const defaultHold = time.Second
+
+// Limiter — bounds the number of in-flight requests and queues a limited number of waiters
+type Limiter struct {
+	slots   chan struct{}
+	queue   chan struct{}
+	maxWait time.Duration
+	hold    atomic.Int64 // moving average of the time a request holds a slot, ns
+}
+
+// NewLimiter creates a limiter allowing maxInFlight concurrent requests and queueLen waiting ones;
+// a queued request gives up after maxWait (0 waits until the client goes away)
+func NewLimiter(maxInFlight, queueLen int, maxWait time.Duration) *Limiter {
+	return &Limiter{
+		slots:   make(chan struct{}, maxInFlight),
+		queue:   make(chan struct{}, max(queueLen, 0)),
+		maxWait: maxWait,
+	}
+}
+
+// acquire takes a slot, waiting in the queue if there is room; it reports false on overflow or timeout
+func (l *Limiter) acquire(c *gin.Context) bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	select {
+	case l.queue <- struct{}{}:
+	default:
+		return false
+	}
+	defer func() { <-l.queue }()
+
+	var timeout <-chan time.Time
+	if l.maxWait > 0 {
+		t := time.NewTimer(l.maxWait)
+		defer t.Stop()
+		timeout = t.C
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timeout:
+		return false
+	case <-c.Request.Context().Done():
+		return false
+	}
+}
+
+func (l *Limiter) release(held time.Duration) {
+	<-l.slots
+	for {
+		old := l.hold.Load()
+		next := int64(held)
+		if old > 0 {
+			next = old + (int64(held)-old)/8
+		}
+		if l.hold.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+// RetryAfter estimates when a slot frees up for a new request: the requests in flight and in the queue
+// drain at the pace of the average hold time; it is never less than a second
+func (l *Limiter) RetryAfter() time.Duration {
+	hold := time.Duration(l.hold.Load())
+	if hold <= 0 {
+		hold = defaultHold
+	}
+	ahead := len(l.queue) + 1
+	wait := hold * time.Duration(ahead) / time.Duration(cap(l.slots))
+	return max(wait, time.Second)
+}
+
+// setHeaders reports the limiter state; with several limiters on a route the tightest one wins
+func (l *Limiter) setHeaders(c *gin.Context) {
+	remaining := cap(l.slots) - len(l.slots)
+	h := c.Writer.Header()
+	if prev, err := strconv.Atoi(h.Get(HeaderRemaining)); err == nil && prev <= remaining {
+		return
+	}
+	h.Set(HeaderLimit, strconv.Itoa(cap(l.slots)))
+	h.Set(HeaderRemaining, strconv.Itoa(remaining))
+}
+
+// serve runs the rest of the chain inside a limiter slot or aborts with 503 and a Retry-After hint
+func (l *Limiter) serve(c *gin.Context) {
+	if !l.acquire(c) {
+		secs := strconv.Itoa(int(math.Ceil(l.RetryAfter().Seconds())))
+		h := c.Writer.Header()
+		h.Set(HeaderLimit, strconv.Itoa(cap(l.slots)))
+		h.Set(HeaderRemaining, "0")
+		h.Set(HeaderReset, secs)
+		h.Set("Retry-After", secs)
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "server is busy, retry later", "code": errcode.ServerBusy})
+		return
+	}
+	start := time.Now()
+	defer func() { l.release(time.Since(start)) }()
+	l.setHeaders(c)
+	c.Next()
+}
+
+// ConcurrencyLimit — reject requests with 503 when the limiter and its queue are full
+func ConcurrencyLimit(l *Limiter) gin.HandlerFunc {
+	return l.serve
+}
+
+// MethodClassLimit — apply separate limiters to reads (GET, HEAD, OPTIONS) and writes,
+// so heavy list/export traffic cannot starve create/update; a nil limiter leaves its class unlimited
+func MethodClassLimit(read, write *Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		l := write
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			l = read
+		}
+		if l == nil {
+			c.Next()
+			return
+		}
+		l.serve(c)
+	}
+}
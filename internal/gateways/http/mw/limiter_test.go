@@ -0,0 +1,224 @@
+package mw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	release := make(chan struct{})
+	entered := make(chan struct{}, 2)
+
+	l := NewLimiter(1, 1, time.Second)
+	r := gin.New()
+	r.Use(ConcurrencyLimit(l))
+	r.GET("/slow", func(c *gin.Context) {
+		entered <- struct{}{}
+		<-release
+		c.Status(http.StatusOK)
+	})
+
+	serve := func() <-chan int {
+		code := make(chan int, 1)
+		go func() {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+			code <- w.Code
+		}()
+		return code
+	}
+
+	first := serve()
+	<-entered
+	queued := serve()
+	require.Eventually(t, func() bool { return len(l.queue) == 1 }, time.Second, time.Millisecond)
+
+	assert.Equal(t, http.StatusServiceUnavailable, <-serve(), "slot and queue are full")
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-first)
+	assert.Equal(t, http.StatusOK, <-queued)
+}
+
+func TestConcurrencyLimit_QueueTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	release := make(chan struct{})
+	defer close(release)
+	entered := make(chan struct{}, 1)
+
+	r := gin.New()
+	r.Use(ConcurrencyLimit(NewLimiter(1, 1, 10*time.Millisecond)))
+	r.GET("/slow", func(c *gin.Context) {
+		entered <- struct{}{}
+		<-release
+	})
+
+	go r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	<-entered
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestMethodClassLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	release := make(chan struct{})
+	entered := make(chan struct{}, 1)
+
+	r := gin.New()
+	r.Use(MethodClassLimit(NewLimiter(1, 0, 0), nil))
+	r.GET("/list", func(c *gin.Context) {
+		entered <- struct{}{}
+		<-release
+		c.Status(http.StatusOK)
+	})
+	r.POST("/create", func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	first := make(chan int, 1)
+	go func() {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/list", nil))
+		first <- w.Code
+	}()
+	<-entered
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/list", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code, "read class is saturated")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/create", nil))
+	assert.Equal(t, http.StatusCreated, w.Code, "writes are not starved by reads")
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-first)
+}
+
+func TestConcurrencyLimit_Headers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	release := make(chan struct{})
+	entered := make(chan struct{}, 1)
+
+	l := NewLimiter(2, 0, 0)
+	r := gin.New()
+	r.Use(ConcurrencyLimit(l))
+	r.GET("/slow", func(c *gin.Context) {
+		if c.Query("block") != "" {
+			entered <- struct{}{}
+			<-release
+		}
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	assert.Equal(t, "2", w.Header().Get(HeaderLimit))
+	assert.Equal(t, "1", w.Header().Get(HeaderRemaining), "the request itself holds a slot")
+	assert.Empty(t, w.Header().Get("Retry-After"))
+
+	done := make(chan struct{}, 2)
+	for range 2 {
+		go func() {
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow?block=1", nil))
+			done <- struct{}{}
+		}()
+		<-entered
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "0", w.Header().Get(HeaderRemaining))
+	assert.Equal(t, "1", w.Header().Get("Retry-After"), "fast requests round up to a second")
+	assert.Equal(t, w.Header().Get("Retry-After"), w.Header().Get(HeaderReset))
+
+	close(release)
+	<-done
+	<-done
+}
+
+func TestLimiter_RetryAfter(t *testing.T) {
+	l := NewLimiter(2, 4, 0)
+	assert.Equal(t, time.Second, l.RetryAfter(), "no history yet")
+
+	l.slots <- struct{}{}
+	l.release(10 * time.Second)
+	l.queue <- struct{}{}
+	l.queue <- struct{}{}
+	l.queue <- struct{}{}
+	assert.Equal(t, 20*time.Second, l.RetryAfter(), "four requests ahead, two at a time")
+}
+
+func TestRateLimiter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	rl := NewRateLimiter(0.5, 2)
+	rl.now = func() time.Time { return now }
+
+	r := gin.New()
+	r.Use(RouteClassLimit(func(string) bool { return true }, rl, nil, nil))
+	r.GET("/cost", func(c *gin.Context) { c.Status(http.StatusOK) })
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/cost", nil))
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, get().Code)
+	assert.Equal(t, http.StatusOK, get().Code, "burst")
+	w := get()
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"), "a token every two seconds")
+	assert.Equal(t, "2", w.Header().Get(HeaderLimit))
+
+	now = now.Add(2 * time.Second)
+	assert.Equal(t, http.StatusOK, get().Code, "refilled")
+	assert.Equal(t, http.StatusTooManyRequests, get().Code)
+
+	assert.Nil(t, NewRateLimiter(0, 5), "no rate, no limit")
+	assert.Equal(t, 3.0, NewRateLimiter(2.5, 0).burst, "a second worth by default")
+}
+
+func TestRouteClassLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	release := make(chan struct{})
+	entered := make(chan struct{}, 1)
+
+	read, expensive := NewLimiter(1, 0, 0), NewLimiter(1, 0, 0)
+	r := gin.New()
+	r.Use(RouteClassLimit(func(route string) bool { return route == "/cost" }, nil, expensive, MethodClassLimit(read, nil)))
+	r.GET("/cost", func(c *gin.Context) {
+		entered <- struct{}{}
+		<-release
+		c.Status(http.StatusOK)
+	})
+	r.GET("/list", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	first := make(chan int, 1)
+	go func() {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/cost", nil))
+		first <- w.Code
+	}()
+	<-entered
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/cost", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code, "expensive class is saturated")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/list", nil))
+	assert.Equal(t, http.StatusOK, w.Code, "reports do not take read slots")
+	assert.Zero(t, len(read.slots))
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-first)
+}
@@ -0,0 +1,26 @@
+package mw
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/metrics"
+)
+
+// GinMetrics — record HTTP request latency in the metrics histogram and count requests over their route's Budget
+func GinMetrics(m *metrics.HTTP) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		// the matched template, e.g. /api/v1/subscriptions/:id, keeps IDs out of the labels
+		route := c.FullPath()
+		took := time.Since(start)
+		m.Observe(c.Request.Method, route, c.Writer.Status(), took)
+		if _, over := overBudget(c, took); over {
+			m.ObserveSLOViolation(c.Request.Method, route)
+		}
+	}
+}
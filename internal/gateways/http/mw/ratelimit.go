@@ -0,0 +1,89 @@
+package mw
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/errcode"
+)
+
+// RateLimiter — token bucket shared by every client of a route class: it refills at a steady rate and lets
+// bursts of up to its capacity through
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// NewRateLimiter creates a limiter admitting perSecond requests with bursts of burst; a burst of 0 allows
+// one second worth of requests. It returns nil, no limit, for a non-positive rate
+func NewRateLimiter(perSecond float64, burst int) *RateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	b := float64(burst)
+	if burst <= 0 {
+		b = math.Ceil(perSecond)
+	}
+	return &RateLimiter{rate: perSecond, burst: b, tokens: b, now: time.Now}
+}
+
+// take spends a token; without one it reports how long until the next is refilled
+func (r *RateLimiter) take() (time.Duration, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if !r.last.IsZero() {
+		r.tokens = min(r.burst, r.tokens+now.Sub(r.last).Seconds()*r.rate)
+	}
+	r.last = now
+	if r.tokens >= 1 {
+		r.tokens--
+		return 0, true
+	}
+	return time.Duration((1 - r.tokens) / r.rate * float64(time.Second)), false
+}
+
+// admit spends a token for the request or aborts it with 429 and a Retry-After hint when the bucket is empty
+func (r *RateLimiter) admit(c *gin.Context) bool {
+	wait, ok := r.take()
+	if !ok {
+		secs := strconv.Itoa(int(max(math.Ceil(wait.Seconds()), 1)))
+		h := c.Writer.Header()
+		h.Set(HeaderLimit, strconv.Itoa(int(r.burst)))
+		h.Set(HeaderRemaining, "0")
+		h.Set(HeaderReset, secs)
+		h.Set("Retry-After", secs)
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many requests, retry later", "code": errcode.RateLimited})
+		return false
+	}
+	return true
+}
+
+// RouteClassLimit — give the routes picked by match, by their registered path, a rate and a concurrency
+// limit of their own, so expensive reports cannot take the slots of ordinary reads. Other requests, and
+// picked ones when l is nil, go through next; a nil rate or next leaves its part unlimited
+func RouteClassLimit(match func(route string) bool, rate *RateLimiter, l *Limiter, next gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		picked := match(c.FullPath())
+		if picked && rate != nil && !rate.admit(c) {
+			return
+		}
+		switch {
+		case picked && l != nil:
+			l.serve(c)
+		case next != nil:
+			next(c)
+		default:
+			c.Next()
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package mw
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/errcode"
+)
+
+// Scope — what an API token may do; every scope includes the ones below it
+type Scope int
+
+const (
+	// ScopeRead - GET, HEAD and OPTIONS requests, e.g. for dashboards
+	ScopeRead Scope = iota + 1
+	// ScopeWrite - every API request
+	ScopeWrite
+	// ScopeAdmin - also the admin write endpoints
+	ScopeAdmin
+)
+
+// scopeNames - scopes by their name in HTTP_API_TOKENS
+var scopeNames = map[string]Scope{"read": ScopeRead, "write": ScopeWrite, "admin": ScopeAdmin}
+
+// ParseScope returns the scope named s: read, write or admin
+func ParseScope(s string) (Scope, bool) {
+	scope, ok := scopeNames[strings.ToLower(strings.TrimSpace(s))]
+	return scope, ok
+}
+
+// String returns the name of the scope
+func (s Scope) String() string {
+	for name, scope := range scopeNames {
+		if scope == s {
+			return name
+		}
+	}
+	return "none"
+}
+
+// Tokens — bearer tokens with their scopes
+type Tokens struct {
+	tokens []scopedToken
+	// api - API tokens are configured, so API requests need one
+	api bool
+}
+
+type scopedToken struct {
+	token []byte
+	scope Scope
+}
+
+// NewTokens builds the tokens of "scope:token" entries, e.g. "read:s3cr3t", plus adminToken with the admin
+// scope when it is set. Only entries turn on tokens for the API; a malformed entry grants nothing
+func NewTokens(adminToken string, entries ...string) *Tokens {
+	t := &Tokens{api: len(entries) > 0}
+	if adminToken != "" {
+		t.tokens = append(t.tokens, scopedToken{token: []byte(adminToken), scope: ScopeAdmin})
+	}
+	for _, e := range entries {
+		name, token, _ := strings.Cut(e, ":")
+		scope, ok := ParseScope(name)
+		if token = strings.TrimSpace(token); ok && token != "" {
+			t.tokens = append(t.tokens, scopedToken{token: []byte(token), scope: scope})
+		}
+	}
+	return t
+}
+
+// scopeOf returns the scope of the bearer token of the request, 0 without a known one; every token is
+// compared so the time does not tell which one matched
+func (t *Tokens) scopeOf(c *gin.Context) Scope {
+	got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		return 0
+	}
+	return t.scopeOfToken(got)
+}
+
+// scopeOfToken returns the scope of token, 0 when it is unknown; see scopeOf
+func (t *Tokens) scopeOfToken(token string) Scope {
+	raw := []byte(strings.TrimSpace(token))
+	var scope Scope
+	for _, st := range t.tokens {
+		if subtle.ConstantTimeCompare(raw, st.token) == 1 && st.scope > scope {
+			scope = st.scope
+		}
+	}
+	return scope
+}
+
+// clientOf names the client of a request: a known bearer token by its fingerprint, so the token itself never
+// shows up in the stats, anything else by its address, so made-up tokens do not split a client
+func (t *Tokens) clientOf(c *gin.Context) string {
+	if t != nil && t.scopeOf(c) != 0 {
+		got, _ := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		sum := sha256.Sum256([]byte(strings.TrimSpace(got)))
+		return "token:" + hex.EncodeToString(sum[:6])
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// grants reports whether a token has at least scope
+func (t *Tokens) grants(scope Scope) bool {
+	for _, st := range t.tokens {
+		if st.scope >= scope {
+			return true
+		}
+	}
+	return false
+}
+
+// MethodScope — with API tokens configured, allow reads with a token of at least the read scope and other
+// methods with at least the write scope; without them every request passes, as before tokens existed
+func MethodScope(tokens *Tokens) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !tokens.api {
+			c.Next()
+			return
+		}
+		scope := ScopeWrite
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			scope = ScopeRead
+		}
+		requireScope(c, tokens, scope, "api")
+	}
+}
+
+// requireScope continues with the request when its token has at least scope and aborts it otherwise: 401
+// without a known token, 403 with a token of a lower scope
+func requireScope(c *gin.Context, tokens *Tokens, scope Scope, realm string) {
+	got := tokens.scopeOf(c)
+	switch {
+	case got == 0:
+		c.Header("WWW-Authenticate", `Bearer realm="`+realm+`"`)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized", "code": errcode.Unauthorized})
+	case got < scope:
+		c.Header("WWW-Authenticate", `Bearer realm="`+realm+`", error="insufficient_scope", scope="`+scope.String()+`"`)
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient token scope", "code": errcode.Forbidden})
+	default:
+		c.Next()
+	}
+}
@@ -0,0 +1,69 @@
+package mw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMethodScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	serve := func(tokens *Tokens, method, token string) *httptest.ResponseRecorder {
+		r := gin.New()
+		api := r.Group("/api", MethodScope(tokens))
+		api.GET("/subs", func(c *gin.Context) { c.Status(http.StatusOK) })
+		api.POST("/subs", func(c *gin.Context) { c.Status(http.StatusCreated) })
+		admin := r.Group("/admin", MethodScope(tokens))
+		admin.POST("/reassign", AdminToken(tokens), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+		path := "/api/subs"
+		if method == "ADMIN" {
+			method, path = http.MethodPost, "/admin/reassign"
+		}
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	open := NewTokens("adm1n")
+	scoped := NewTokens("adm1n", "read:dash", "write:app", "owner:bad", "admin:")
+	tests := []struct {
+		name   string
+		tokens *Tokens
+		method string
+		token  string
+		want   int
+	}{
+		{name: "no api tokens, open", tokens: open, method: http.MethodPost, want: http.StatusCreated},
+		{name: "no api tokens, admin token", tokens: open, method: "ADMIN", token: "adm1n", want: http.StatusOK},
+		{name: "admin disabled", tokens: NewTokens(""), method: "ADMIN", token: "adm1n", want: http.StatusForbidden},
+		{name: "missing token", tokens: scoped, method: http.MethodGet, want: http.StatusUnauthorized},
+		{name: "unknown token", tokens: scoped, method: http.MethodGet, token: "bad", want: http.StatusUnauthorized},
+		{name: "read reads", tokens: scoped, method: http.MethodGet, token: "dash", want: http.StatusOK},
+		{name: "read cannot write", tokens: scoped, method: http.MethodPost, token: "dash", want: http.StatusForbidden},
+		{name: "write writes", tokens: scoped, method: http.MethodPost, token: "app", want: http.StatusCreated},
+		{name: "write is not admin", tokens: scoped, method: "ADMIN", token: "app", want: http.StatusForbidden},
+		{name: "admin token writes", tokens: scoped, method: http.MethodPost, token: "adm1n", want: http.StatusCreated},
+		{name: "admin token admins", tokens: scoped, method: "ADMIN", token: "adm1n", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(tt.tokens, tt.method, tt.token)
+			assert.Equal(t, tt.want, w.Code, w.Body.String())
+			if tt.want == http.StatusUnauthorized {
+				assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Bearer")
+			}
+		})
+	}
+
+	w := serve(scoped, http.MethodPost, "dash")
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), `scope="write"`)
+	assert.JSONEq(t, `{"error":"insufficient token scope","code":"FORBIDDEN"}`, w.Body.String())
+}
@@ -0,0 +1,31 @@
+package mw
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/errcode"
+)
+
+// Shedder — admits or turns away low-priority requests by the current load, e.g. *loadshed.Controller
+type Shedder interface {
+	Admit() bool
+	RetryAfter() time.Duration
+}
+
+// Shed — reject the requests to low-priority routes the shedder turns away with 503 and a Retry-After hint;
+// the other routes always pass
+func Shed(s Shedder, lowPriority func(route string) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !lowPriority(c.FullPath()) || s.Admit() {
+			c.Next()
+			return
+		}
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(s.RetryAfter().Seconds()))))
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "server is overloaded, retry later", "code": errcode.ServerBusy})
+	}
+}
@@ -0,0 +1,35 @@
+package mw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// shedAll turns away every low-priority request
+type shedAll struct{}
+
+func (shedAll) Admit() bool               { return false }
+func (shedAll) RetryAfter() time.Duration { return 1500 * time.Millisecond }
+
+func TestShed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Shed(shedAll{}, func(route string) bool { return route == "/export" }))
+	r.GET("/export", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/subscriptions", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/export", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error":"server is overloaded, retry later","code":"SERVER_BUSY"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/subscriptions", nil))
+	assert.Equal(t, http.StatusOK, w.Code, "CRUD is never shed")
+}
@@ -7,7 +7,8 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// GinSlog — log HTTP-request with slog.Logger
+// GinSlog — log HTTP-request with slog.Logger; a request slower than its route's Budget is logged as a warning
+// with slo_violation set
 func GinSlog(l *slog.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
@@ -37,11 +38,18 @@ func GinSlog(l *slog.Logger) gin.HandlerFunc {
 		if len(c.Errors) > 0 {
 			attrs = append(attrs, "errors", c.Errors.ByType(gin.ErrorTypeAny).String())
 		}
+		budget, over := overBudget(c, lat)
+		if budget > 0 {
+			attrs = append(attrs, "budget_ms", budget.Milliseconds())
+		}
+		if over {
+			attrs = append(attrs, "slo_violation", true)
+		}
 
 		switch {
 		case status >= 500:
 			l.Error("http request", attrs...)
-		case status >= 400:
+		case status >= 400 || over:
 			l.Warn("http request", attrs...)
 		default:
 			l.Info("http request", attrs...)
@@ -0,0 +1,20 @@
+package mw
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/tenancy"
+	"subs_tracker/internal/tracing"
+)
+
+// TenantSchema — do the database work of a request in the schema of its tenant, the tenant_id baggage member
+// the gateway in front sends, when the tenant has a schema of its own; the requests of other tenants and
+// those without one use the shared schema. Register it after Tracing, which reads the baggage
+func TenantSchema(schemas *tenancy.Schemas) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tenant := tracing.TenantID(c.Request.Context()); schemas.Known(tenant) {
+			c.Request = c.Request.WithContext(tenancy.WithTenant(c.Request.Context(), tenant))
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,59 @@
+package mw
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/tracing"
+)
+
+// Tracing — continue the trace of the caller's traceparent/baggage headers in a server span per request and put
+// the customer into the baggage: tenant_id as sent by the gateway in front, user_id from the :user_id route
+// parameter or ?user_id=. Handlers learning the customer from the body add it with TraceCustomer
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := tracing.Propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx, span := tracing.Tracer().Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+			),
+		)
+		defer span.End()
+		span.SetAttributes(tracing.Attributes(ctx)...)
+		c.Request = c.Request.WithContext(ctx)
+
+		raw := c.Param("user_id")
+		if raw == "" {
+			raw = c.Query("user_id")
+		}
+		if uid, err := entity.ParseUserID(strings.TrimSpace(raw)); err == nil {
+			TraceCustomer(c, uid.String(), "")
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, "")
+		}
+	}
+}
+
+// TraceCustomer adds the customer to the baggage of the request, see tracing.WithCustomer; empty values keep
+// what is there
+func TraceCustomer(c *gin.Context, userID, tenantID string) {
+	c.Request = c.Request.WithContext(tracing.WithCustomer(c.Request.Context(), userID, tenantID))
+}
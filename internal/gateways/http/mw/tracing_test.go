@@ -0,0 +1,70 @@
+package mw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"subs_tracker/internal/tracing"
+)
+
+func TestTracing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	var seen []attribute.KeyValue
+	r := gin.New()
+	r.ContextWithFallback = true
+	r.Use(Tracing())
+	r.GET("/users/:user_id/settings", func(c *gin.Context) {
+		// the handler passes the gin context on, as the usecases get it
+		seen = tracing.Attributes(c)
+		c.Status(http.StatusOK)
+	})
+	r.POST("/ingest", func(c *gin.Context) {
+		TraceCustomer(c, "60601fee-2bf1-4721-ae6f-7636e79a0cba", "billing")
+		seen = tracing.Attributes(c)
+		c.Status(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/60601fee-2bf1-4721-ae6f-7636e79a0cba/settings", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("baggage", "tenant_id=acme")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Len(t, rec.Ended(), 1)
+	span := rec.Ended()[0]
+	assert.Equal(t, "GET /users/:user_id/settings", span.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String(), "continues the caller's trace")
+	want := []attribute.KeyValue{
+		attribute.String(tracing.TenantIDKey, "acme"),
+		attribute.String(tracing.UserIDKey, "60601fee-2bf1-4721-ae6f-7636e79a0cba"),
+	}
+	assert.Subset(t, span.Attributes(), want)
+	assert.ElementsMatch(t, want, seen)
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/not-a-uuid/settings", nil))
+	assert.Empty(t, seen, "a malformed user_id is left out")
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/ingest", nil))
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String(tracing.UserIDKey, "60601fee-2bf1-4721-ae6f-7636e79a0cba"),
+		attribute.String(tracing.TenantIDKey, "billing"),
+	}, seen)
+	ingest := rec.Ended()[2]
+	assert.Subset(t, ingest.Attributes(), []attribute.KeyValue{
+		attribute.String(tracing.TenantIDKey, "billing"),
+		attribute.Int("http.response.status_code", http.StatusInternalServerError),
+	})
+}
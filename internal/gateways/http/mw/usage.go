@@ -0,0 +1,32 @@
+package mw
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/theme"
+	"subs_tracker/internal/tracing"
+	"subs_tracker/internal/usage"
+)
+
+// Usage — count the request for product analytics by its route template, the tenant_id of its baggage and the
+// class of its status. Unmatched routes are not counted, and neither is a tenant that could not name one, so
+// clients cannot blow up the counts with made-up paths or tenants
+func Usage(counter *usage.Counter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			return
+		}
+		tenant := tracing.TenantID(c.Request.Context())
+		if !theme.ValidTenantID(tenant) {
+			tenant = ""
+		}
+		counter.Add(usage.Key{
+			Endpoint: c.Request.Method + " " + route,
+			Tenant:   tenant,
+			Result:   usage.ResultOf(c.Writer.Status()),
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package mw
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/widget"
+)
+
+// widgetKey - the context key Widget leaves the resolved token under
+const widgetKey = "mw.widget"
+
+// widgetPreflightMaxAge - how long browsers may reuse an answered preflight, in seconds
+const widgetPreflightMaxAge = "600"
+
+// Widget — allow the public widget routes with "Authorization: Bearer wdg_…" of a working widget token, and only
+// from a browser on one of the origins of the token. It answers CORS itself in place of HTTP_CORS_ORIGINS: the
+// origin of the request is allowed back, never with credentials. Preflights carry no token, so any origin gets
+// one for GET; the request that follows is what is checked. Without an Origin the request is not from a widget
+// and is refused
+func Widget(tokens *widget.Tokens) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		h := c.Writer.Header()
+		h.Add("Vary", "Origin")
+		if c.Request.Method == http.MethodOptions {
+			if origin != "" {
+				h.Set("Access-Control-Allow-Origin", origin)
+				h.Set("Access-Control-Allow-Methods", "GET")
+				h.Set("Access-Control-Allow-Headers", "Authorization")
+				h.Set("Access-Control-Max-Age", widgetPreflightMaxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		if tokens == nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "widgets are disabled", "code": errcode.FeatureDisabled})
+			return
+		}
+
+		got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok {
+			got = ""
+		}
+		tok, err := tokens.Resolve(c, strings.TrimSpace(got))
+		switch {
+		case errors.Is(err, widget.ErrNotFound), errors.Is(err, widget.ErrRevoked):
+			c.Header("WWW-Authenticate", `Bearer realm="widget"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized", "code": errcode.Unauthorized})
+			return
+		case err != nil:
+			_ = c.Error(err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal error", "code": errcode.Internal})
+			return
+		case !tok.Allows(origin):
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "origin not allowed", "code": errcode.Forbidden})
+			return
+		}
+		h.Set("Access-Control-Allow-Origin", origin)
+		TraceCustomer(c, tok.UserID.String(), "")
+		c.Set(widgetKey, tok)
+		c.Next()
+	}
+}
+
+// WidgetToken returns the token Widget accepted the request with
+func WidgetToken(c *gin.Context) widget.Token {
+	tok, _ := c.Get(widgetKey)
+	t, _ := tok.(widget.Token)
+	return t
+}
@@ -0,0 +1,107 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/internal/pricecheck"
+	"subs_tracker/pkg/pagination"
+)
+
+// priceReview is a subscription whose cost differs from the catalog price of its service, in admin responses.
+type priceReview struct {
+	ID int64 `json:"id"`
+	// SubscriptionID is left out under a public ID strategy, which sends SubscriptionPublicID instead
+	SubscriptionID       int64      `json:"subscription_id,omitempty"`
+	SubscriptionPublicID string     `json:"subscription_public_id,omitempty"`
+	UserID               string     `json:"user_id"`
+	ServiceName          string     `json:"service_name"`
+	Cost                 int64      `json:"cost"`
+	CatalogPrice         int64      `json:"catalog_price"`
+	OpenedAt             time.Time  `json:"opened_at"`
+	ResolvedAt           *time.Time `json:"resolved_at,omitempty"`
+}
+
+// priceReviewList is the response of GET /api/v1/admin/price-reviews.
+type priceReviewList struct {
+	Items []priceReview `json:"items"`
+}
+
+// setupPriceReviews registers the admin endpoints working through the reviews opened by the price check.
+func setupPriceReviews(r *gin.RouterGroup, tokens *mw.Tokens, u UseCases) {
+	// open reviews, oldest first
+	r.GET("/price-reviews", mw.AdminToken(tokens), func(c *gin.Context) {
+		if !requireAcceptJSON(c) || !requirePriceReviews(c, u) {
+			return
+		}
+		limit, err := pagination.ParseLimit(c.Query("limit"))
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.PaginationInvalid, "invalid limit")
+			return
+		}
+		reviews, err := u.PriceReviews.Open(c, limit)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		out := priceReviewList{Items: make([]priceReview, 0, len(reviews))}
+		for _, rv := range reviews {
+			out.Items = append(out.Items, buildPriceReview(rv, u))
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	// closes a review once the subscription was corrected, or left as it is on purpose; the subscription
+	// itself is changed through the subscriptions API
+	r.POST("/price-reviews/:id/resolve", mw.AdminToken(tokens), func(c *gin.Context) {
+		if !requireAcceptJSON(c) || !requirePriceReviews(c, u) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil || id <= 0 {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.IDInvalid, "invalid id")
+			return
+		}
+		rv, err := u.PriceReviews.Resolve(c, id)
+		if errors.Is(err, pricecheck.ErrNotFound) {
+			jsonErr(c, http.StatusNotFound, "not found")
+			return
+		}
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		c.JSON(http.StatusOK, buildPriceReview(rv, u))
+	})
+}
+
+// buildPriceReview maps a review to its admin representation, naming the subscription as clients know it.
+func buildPriceReview(rv pricecheck.Review, u UseCases) priceReview {
+	out := priceReview{
+		ID:           rv.ID,
+		UserID:       rv.UserID.String(),
+		ServiceName:  rv.ServiceName,
+		Cost:         rv.Cost,
+		CatalogPrice: rv.CatalogPrice,
+		OpenedAt:     rv.OpenedAt.UTC(),
+	}
+	out.SubscriptionID, out.SubscriptionPublicID = subRef(u.Sub.IDs(), rv.SubscriptionID, rv.PublicID)
+	if rv.ResolvedAt != nil {
+		at := rv.ResolvedAt.UTC()
+		out.ResolvedAt = &at
+	}
+	return out
+}
+
+// requirePriceReviews answers 403 when the price check is not configured.
+func requirePriceReviews(c *gin.Context, u UseCases) bool {
+	if u.PriceReviews == nil {
+		jsonErr(c, http.StatusForbidden, "price checks are disabled")
+		return false
+	}
+	return true
+}
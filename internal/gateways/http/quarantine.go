@@ -0,0 +1,178 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/internal/quarantine"
+	"subs_tracker/pkg/dates"
+	"subs_tracker/pkg/pagination"
+)
+
+// quarantinedRow is an imported subscription held for failing validation.
+type quarantinedRow struct {
+	ID          int64  `json:"id"`
+	UserID      string `json:"user_id"`
+	ServiceName string `json:"service_name"`
+	Cost        int64  `json:"cost"`
+	StartDate   string `json:"start_date"`
+	EndDate     string `json:"end_date,omitempty"`
+	// Reason, Code - the validation error the row was held for, as an API error would carry them
+	Reason string    `json:"reason"`
+	Code   string    `json:"code"`
+	HeldAt time.Time `json:"held_at"`
+}
+
+// quarantinedRowList is the response of GET /api/v1/users/{user_id}/imports/quarantine.
+type quarantinedRowList struct {
+	Items []quarantinedRow `json:"items"`
+}
+
+// quarantineFix is the payload of POST /api/v1/users/{user_id}/imports/quarantine/{id}/resubmit; fields left
+// out keep the imported value, an empty end_date drops it.
+type quarantineFix struct {
+	ServiceName *string `json:"service_name"`
+	Cost        *int64  `json:"cost"`
+	StartDate   *string `json:"start_date"`
+	EndDate     *string `json:"end_date"`
+}
+
+// setupQuarantine registers the review of the import rows held for failing validation: the user fixes a row
+// and submits it again, or discards it.
+func setupQuarantine(r *gin.RouterGroup, u UseCases, dp *dates.Parser) {
+	r.GET("/users/:user_id/imports/quarantine", mw.Budget(budgetRead), func(c *gin.Context) {
+		uid, ok := deactivationUser(c)
+		if !ok || !requireQuarantine(c, u) {
+			return
+		}
+		limit, err := pagination.ParseLimit(c.Query("limit"))
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.PaginationInvalid, "invalid limit")
+			return
+		}
+		rows, err := u.Quarantine.List(c, uid, limit)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		out := quarantinedRowList{Items: make([]quarantinedRow, 0, len(rows))}
+		for _, row := range rows {
+			out.Items = append(out.Items, buildQuarantinedRow(row))
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	// creates the subscription of the fixed row and drops the row; a row failing again stays held
+	r.POST("/users/:user_id/imports/quarantine/:id/resubmit", mw.Budget(budgetWrite), func(c *gin.Context) {
+		if !requireJSONContent(c) {
+			return
+		}
+		uid, ok := deactivationUser(c)
+		if !ok || !requireQuarantine(c, u) {
+			return
+		}
+		id, ok := quarantineRowID(c)
+		if !ok {
+			return
+		}
+		var req quarantineFix
+		if err := c.ShouldBindJSON(&req); err != nil {
+			jsonErrOf(c, http.StatusBadRequest, err)
+			return
+		}
+		fix := quarantine.Fix{ServiceName: req.ServiceName, Cost: req.Cost}
+		if req.StartDate != nil {
+			v, err := dp.Parse(*req.StartDate)
+			if err != nil {
+				jsonErrCode(c, http.StatusUnprocessableEntity, errcode.DateInvalid, dateErrMsg("invalid period: date from", err))
+				return
+			}
+			fix.StartDate = &v
+		}
+		if req.EndDate != nil {
+			if *req.EndDate == "" {
+				fix.ClearEndDate = true
+			} else {
+				v, err := dp.Parse(*req.EndDate)
+				if err != nil {
+					jsonErrCode(c, http.StatusUnprocessableEntity, errcode.DateInvalid, dateErrMsg("invalid period: date to", err))
+					return
+				}
+				fix.EndDate = &v
+			}
+		}
+
+		created, err := u.Quarantine.Resubmit(c, uid, id, fix, u.Sub)
+		if quarantineErr(c, err) {
+			return
+		}
+		c.Header("ETag", subETag(created))
+		c.JSON(http.StatusCreated, writtenSub(c, u.Sub, created))
+	})
+
+	r.DELETE("/users/:user_id/imports/quarantine/:id", mw.Budget(budgetWrite), func(c *gin.Context) {
+		uid, ok := deactivationUser(c)
+		if !ok || !requireQuarantine(c, u) {
+			return
+		}
+		id, ok := quarantineRowID(c)
+		if !ok {
+			return
+		}
+		if quarantineErr(c, u.Quarantine.Discard(c, uid, id)) {
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
+
+// buildQuarantinedRow maps a held row to the response.
+func buildQuarantinedRow(row quarantine.Row) quarantinedRow {
+	out := quarantinedRow{
+		ID:          row.ID,
+		UserID:      row.UserID.String(),
+		ServiceName: row.ServiceName,
+		Cost:        row.Cost,
+		StartDate:   dates.Format(row.StartDate),
+		Reason:      row.Reason,
+		Code:        string(row.Code),
+		HeldAt:      row.HeldAt.UTC(),
+	}
+	if row.EndDate != nil {
+		out.EndDate = dates.Format(*row.EndDate)
+	}
+	return out
+}
+
+// quarantineRowID parses the :id of a held row; it writes the error response itself.
+func quarantineRowID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		jsonErrCode(c, http.StatusUnprocessableEntity, errcode.IDInvalid, "invalid id")
+		return 0, false
+	}
+	return id, true
+}
+
+// requireQuarantine answers 403 when the import quarantine is not enabled.
+func requireQuarantine(c *gin.Context, u UseCases) bool {
+	if u.Quarantine == nil {
+		jsonErr(c, http.StatusForbidden, "import quarantine is disabled")
+		return false
+	}
+	return true
+}
+
+// quarantineErr maps quarantine errors to HTTP responses; returns true if handled.
+func quarantineErr(c *gin.Context, err error) bool {
+	if errors.Is(err, quarantine.ErrNotFound) {
+		jsonErrCode(c, http.StatusNotFound, errcode.QuarantineNotFound, "not found")
+		return true
+	}
+	return handleUsecaseErr(c, err)
+}
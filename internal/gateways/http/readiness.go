@@ -0,0 +1,78 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/concurrency"
+)
+
+const readyCheckTimeout = 2 * time.Second
+
+// HealthCheck is a dependency reported by /readyz.
+type HealthCheck struct {
+	Name string
+	// Soft checks are reported in the body but never make the instance unready.
+	Soft  bool
+	Check func(ctx context.Context) (details any, err error)
+}
+
+type checkResult struct {
+	Status string `json:"status"`
+	Soft   bool   `json:"soft,omitempty"`
+	// LatencyMS is how long the check took; a check cut off by the timeout takes readyCheckTimeout.
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+	Details   any     `json:"details,omitempty"`
+}
+
+type readyResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks"`
+}
+
+// readyHandler runs every check at once and answers 503 when a hard one fails, so a slow soft dependency
+// delays the answer by its own timeout at most.
+func readyHandler(checks []HealthCheck) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// checks report failures in their results, so none cancels the others
+		results, err := concurrency.Map(c.Request.Context(), len(checks), checks,
+			func(ctx context.Context, hc HealthCheck) (checkResult, error) { return runCheck(ctx, hc), nil })
+		if err != nil {
+			_ = c.Error(err)
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, readyResponse{Status: "not ready"})
+			return
+		}
+
+		resp := readyResponse{Status: "ready", Checks: make(map[string]checkResult, len(checks))}
+		code := http.StatusOK
+		for i, hc := range checks {
+			if results[i].Status != "ok" && !hc.Soft {
+				resp.Status, code = "not ready", http.StatusServiceUnavailable
+			}
+			resp.Checks[hc.Name] = results[i]
+		}
+		c.JSON(code, resp)
+	}
+}
+
+// runCheck runs hc within readyCheckTimeout.
+func runCheck(ctx context.Context, hc HealthCheck) checkResult {
+	ctx, cancel := context.WithTimeout(ctx, readyCheckTimeout)
+	defer cancel()
+	start := time.Now()
+	details, err := hc.Check(ctx)
+	res := checkResult{
+		Status:    "ok",
+		Soft:      hc.Soft,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000.0,
+		Details:   details,
+	}
+	if err != nil {
+		res.Status, res.Error = "failing", err.Error()
+	}
+	return res
+}
@@ -0,0 +1,128 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/internal/reconcile"
+	"subs_tracker/pkg/dates"
+	"subs_tracker/pkg/pagination"
+)
+
+// reconciliationItem is a month where the subscriptions and the recorded charges of a user disagree, in admin
+// responses.
+type reconciliationItem struct {
+	ID          int64  `json:"id"`
+	Kind        string `json:"kind"`
+	UserID      string `json:"user_id"`
+	ServiceName string `json:"service_name"`
+	Month       string `json:"month"`
+	Expected    int64  `json:"expected,omitempty"`
+	Charged     int64  `json:"charged,omitempty"`
+	// SubscriptionID is left out under a public ID strategy, which sends SubscriptionPublicID instead; both are
+	// left out for unexpected charges
+	SubscriptionID       int64      `json:"subscription_id,omitempty"`
+	SubscriptionPublicID string     `json:"subscription_public_id,omitempty"`
+	OpenedAt             time.Time  `json:"opened_at"`
+	ResolvedAt           *time.Time `json:"resolved_at,omitempty"`
+}
+
+// reconciliationItemList is the response of GET /api/v1/admin/reconciliation-items.
+type reconciliationItemList struct {
+	Items []reconciliationItem `json:"items"`
+}
+
+// setupReconciliation registers the admin endpoints working through the items opened by the charge
+// reconciliation.
+func setupReconciliation(r *gin.RouterGroup, tokens *mw.Tokens, u UseCases) {
+	// open items, oldest first, of a single user with ?user_id=
+	r.GET("/reconciliation-items", mw.AdminToken(tokens), func(c *gin.Context) {
+		if !requireAcceptJSON(c) || !requireReconcile(c, u) {
+			return
+		}
+		limit, err := pagination.ParseLimit(c.Query("limit"))
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.PaginationInvalid, "invalid limit")
+			return
+		}
+		var user *entity.UserID
+		if raw := strings.TrimSpace(c.Query("user_id")); raw != "" {
+			id, err := entity.ParseUserID(raw)
+			if err != nil {
+				jsonErrCode(c, http.StatusUnprocessableEntity, errcode.UserIDInvalid, "uuid invalid")
+				return
+			}
+			user = &id
+		}
+		items, err := u.Reconcile.Open(c, user, limit)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		out := reconciliationItemList{Items: make([]reconciliationItem, 0, len(items))}
+		for _, it := range items {
+			out.Items = append(out.Items, buildReconciliationItem(it, u))
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	// closes an item once the subscription or the bank side was sorted out; subscriptions are changed through
+	// the subscriptions API
+	r.POST("/reconciliation-items/:id/resolve", mw.AdminToken(tokens), func(c *gin.Context) {
+		if !requireAcceptJSON(c) || !requireReconcile(c, u) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil || id <= 0 {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.IDInvalid, "invalid id")
+			return
+		}
+		it, err := u.Reconcile.Resolve(c, id)
+		if errors.Is(err, reconcile.ErrNotFound) {
+			jsonErr(c, http.StatusNotFound, "not found")
+			return
+		}
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		c.JSON(http.StatusOK, buildReconciliationItem(it, u))
+	})
+}
+
+// buildReconciliationItem maps an item to its admin representation, naming the subscription as clients know it.
+func buildReconciliationItem(it reconcile.Item, u UseCases) reconciliationItem {
+	out := reconciliationItem{
+		ID:          it.ID,
+		Kind:        string(it.Kind),
+		UserID:      it.UserID.String(),
+		ServiceName: it.ServiceName,
+		Month:       dates.Format(it.Month),
+		Expected:    it.Expected,
+		Charged:     it.Charged,
+		OpenedAt:    it.OpenedAt.UTC(),
+	}
+	if it.Kind == reconcile.KindMissed {
+		out.SubscriptionID, out.SubscriptionPublicID = subRef(u.Sub.IDs(), it.SubscriptionID, it.PublicID)
+	}
+	if it.ResolvedAt != nil {
+		at := it.ResolvedAt.UTC()
+		out.ResolvedAt = &at
+	}
+	return out
+}
+
+// requireReconcile answers 403 when the charge reconciliation is not configured.
+func requireReconcile(c *gin.Context, u UseCases) bool {
+	if u.Reconcile == nil {
+		jsonErr(c, http.StatusForbidden, "charge reconciliation is disabled")
+		return false
+	}
+	return true
+}
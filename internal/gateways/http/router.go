@@ -1,6 +1,8 @@
 package http
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -11,111 +13,259 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/go-openapi/strfmt"
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"subs_tracker/internal/buildinfo"
+	"subs_tracker/internal/enrichment"
 	"subs_tracker/internal/entity"
 	"subs_tracker/internal/entity/generated"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/gateways/http/mw"
 	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/dates"
+	"subs_tracker/pkg/pagination"
 )
 
-// parseMonthYear parses several date layouts and normalizes to the first day of the month (UTC).
-func parseMonthYear(s string) (time.Time, error) {
-	s = strings.TrimSpace(s)
-	layouts := []string{"01-2006", "2006-01-02", "2006-01"}
-	var lastErr error
-	for _, layout := range layouts {
-		t, err := time.Parse(layout, s)
-		if err != nil {
-			lastErr = err
-			continue
+// Latency budgets (SLOs) of API routes, set at registration with mw.Budget; slower requests are logged with
+// slo_violation and counted in slo_violations_total.
+const (
+	budgetRead   = 200 * time.Millisecond
+	budgetWrite  = 500 * time.Millisecond
+	budgetReport = time.Second
+	// budgetPoll covers reads polled by widgets, served from memory most of the time.
+	budgetPoll = 10 * time.Millisecond
+	// budgetImport covers uploads and snapshots that parse or write many rows.
+	budgetImport = 5 * time.Second
+)
+
+// expensiveRoutes are the cost, timeseries and export routes, relative to the API version prefix; they get
+// the separate rate and concurrency limits of HTTP_EXPENSIVE_* instead of the read limit.
+var expensiveRoutes = map[string]bool{
+	"/subscriptions/cost":                     true,
+	"/subscriptions/cost/grouped":             true,
+	"/subscriptions/cost/summary":             true,
+	"/subscriptions/calendar":                 true,
+	"/subscriptions/diff":                     true,
+	"/subscriptions/year-in-review":           true,
+	"/subscriptions/benchmarks":               true,
+	"/subscriptions/export":                   true,
+	"/shared/:token":                          true,
+	"/users/:user_id/budgets/recommendations": true,
+	"/sandbox/reset":                          true,
+}
+
+// isExpensiveRoute reports whether the registered path of an API route is in expensiveRoutes.
+func isExpensiveRoute(route string) bool {
+	rest, ok := apiRoute(route)
+	return ok && expensiveRoutes[rest]
+}
+
+// apiRoute strips the API version prefix off the registered path of a route.
+func apiRoute(route string) (string, bool) {
+	for _, prefix := range []string{"/api/v1", "/api/v2"} {
+		if rest, ok := strings.CutPrefix(route, prefix); ok {
+			return rest, true
 		}
-		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC), nil
-	}
-	if lastErr == nil {
-		lastErr = errors.New("empty date value")
 	}
-	return time.Time{}, lastErr
+	return "", false
 }
 
-// setupRouter wires all routes and basic middleware.
-func setupRouter(r *gin.Engine, u UseCases) {
+// setupRouter wires all routes and basic middleware; apiMW applies to /api/v1 and /api/v2 routes only.
+func setupRouter(r *gin.Engine, u UseCases, cursors *pagination.Codec, dp *dates.Parser, costCache cachePolicy, paging pagingPolicy, periods periodPolicy, requireIfMatch bool, apiMW ...gin.HandlerFunc) {
 	r.HandleMethodNotAllowed = true
 	r.Use(gin.Recovery())
 	r.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+	r.GET("/readyz", readyHandler(u.Checks))
+	r.GET("/status", statusHandler(u.Checks))
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	info := buildinfo.Get()
+	r.GET("/version", func(c *gin.Context) { c.JSON(http.StatusOK, info) })
+
+	setupAPI(r.Group("api/v1/", apiMW...), u, cursors, dp, costCache, paging, periods, requireIfMatch)
+	setupAPI(r.Group("api/v2/", apiMW...), u, cursors, v2Dates(), costCache, paging, periods, requireIfMatch)
+}
 
-	v1 := r.Group("api/v1/")
-	setupSubscription(v1, u)
-	setupSubscriptionsId(v1, u)
-	setupSubscriptionsCost(v1, u)
+// v2Dates is the date parser of /api/v2: v2 differs only in dates, exactly one documented layout instead of the
+// configured set.
+func v2Dates() *dates.Parser {
+	return dates.NewParser(dates.WithExactLayout(dates.MonthYear))
+}
+
+// setupAPI registers the versioned API routes on g.
+func setupAPI(g *gin.RouterGroup, u UseCases, cursors *pagination.Codec, dp *dates.Parser, costCache cachePolicy, paging pagingPolicy, periods periodPolicy, requireIfMatch bool) {
+	setupSubscription(g, u, cursors, dp, paging, periods)
+	setupSubscriptionsId(g, u, dp, requireIfMatch)
+	setupAdjustments(g, u, dp)
+	setupSeats(g, u)
+	setupSubscriptionsCost(g, u, dp, costCache, periods)
+	setupCalendar(g, u, dp)
+	setupDiff(g, u, dp)
+	setupYearReview(g, u)
+	setupBenchmarks(g, u, dp)
+	setupSync(g, u, cursors, paging)
+	setupImports(g, u, cursors)
+	setupQuarantine(g, u, dp)
+	setupSettings(g, u)
+	setupBudgets(g, u)
+	setupDeactivation(g, u)
+	setupUsers(g, u)
+	setupUserHooks(g, u)
+	setupActivity(g, u, cursors, paging)
+	setupChanges(g, u)
+	setupSnapshots(g, u)
+	setupExport(g, u, dp)
+	setupShares(g, u, dp)
+	setupWidgets(g, u)
+	setupSandbox(g, u)
 }
 
 // setupSubscription registers list/create routes for subscriptions.
-func setupSubscription(r *gin.RouterGroup, u UseCases) {
-	r.GET("/subscriptions", func(c *gin.Context) {
-		if !requireAcceptJSON(c) {
+func setupSubscription(r *gin.RouterGroup, u UseCases, cursors *pagination.Codec, dp *dates.Parser, paging pagingPolicy, periods periodPolicy) {
+	r.GET("/subscriptions", mw.Budget(budgetRead), func(c *gin.Context) {
+		format, ok := requireAcceptEncoded(c)
+		if !ok || !paging.check(c, pagination.DefaultLimits()) {
+			return
+		}
+		fields, err := parseFields(c)
+		if err != nil {
+			jsonErrOf(c, http.StatusUnprocessableEntity, err)
 			return
 		}
 
 		filterDTO, err := buildSubscriptionsFilterFromQuery(c)
 		if err != nil {
-			jsonErr(c, http.StatusUnprocessableEntity, err.Error())
+			jsonErrOf(c, http.StatusUnprocessableEntity, err)
 			return
 		}
 
-		f, err := mapFilterDTOToUsecase(filterDTO)
+		f, err := mapFilterDTOToUsecase(filterDTO, dp)
 		if err != nil {
-			jsonErr(c, http.StatusUnprocessableEntity, err.Error())
+			jsonErrOf(c, http.StatusUnprocessableEntity, err)
 			return
 		}
+		if v := strings.TrimSpace(c.Query("cursor")); v != "" {
+			var after usecase.ListCursor
+			if err := cursors.Decode(v, &after); err != nil {
+				jsonErrCode(c, http.StatusUnprocessableEntity, errcode.CursorInvalid, pagination.ErrInvalidCursor.Error())
+				return
+			}
+			f.After = &after
+		}
+		if v := strings.TrimSpace(c.Query("filter")); v != "" {
+			if err := applyFilterExpr(&f, v, dp); err != nil {
+				jsonErrOf(c, http.StatusUnprocessableEntity, err)
+				return
+			}
+		}
+		if !periods.fill(c, u, &f) {
+			return
+		}
+		enrich := false
+		if v := strings.TrimSpace(c.Query("enrich")); v != "" {
+			if enrich, err = strconv.ParseBool(v); err != nil {
+				jsonErr(c, http.StatusUnprocessableEntity, "invalid enrich")
+				return
+			}
+		}
+		if v := strings.TrimSpace(c.Query("include_archived")); v != "" {
+			if f.IncludeArchived, err = strconv.ParseBool(v); err != nil {
+				jsonErr(c, http.StatusUnprocessableEntity, "invalid include_archived")
+				return
+			}
+			if f.IncludeArchived && !u.Sub.ArchiveEnabled() {
+				jsonErr(c, http.StatusForbidden, "archive is disabled")
+				return
+			}
+		}
 
 		subs, err := u.Sub.ListSubsByFilter(c, f)
 		if handled := handleUsecaseErr(c, err); handled {
 			return
 		}
+		var catalog map[string]enrichment.ServiceInfo
+		if enrich && u.Catalog != nil {
+			names := make([]string, 0, len(subs))
+			for _, s := range subs {
+				names = append(names, s.ServiceName)
+			}
+			catalog = u.Catalog.Enrich(c, names)
+		}
 
-		resp := make([]*generated.Subscription, 0, len(subs))
-		for _, s := range subs {
-			cp := s
-			item := buildSubDTO(cp)
-			resp = append(resp, &item)
+		batch := newSubDTOBatch(subs, u.Sub.IDs(), catalog)
+		defer batch.release()
+		resp := batch.ptrs
+		more := pagination.HasMore(len(subs), pagination.DefaultLimits().Clamp(f.Limit))
+		if n := paging.fit(resp); n < len(resp) {
+			// the rest of the page follows the cursor of the last subscription that fits
+			resp, subs, more = resp[:n], subs[:n], true
+		}
+		if next, ok := nextListCursor(subs, more, cursors); ok {
+			c.Header("X-Next-Cursor", next)
 		}
-		c.JSON(http.StatusOK, resp)
+		respond(c, http.StatusOK, format, resp, fields)
 	})
 
-	r.POST("/subscriptions", func(c *gin.Context) {
+	r.POST("/subscriptions", mw.Budget(budgetWrite), func(c *gin.Context) {
 		if !requireAcceptJSON(c) || !requireJSONContent(c) {
 			return
 		}
+		dryRun, ok := dryRunParam(c)
+		if !ok {
+			return
+		}
 
 		var input *generated.SubscriptionInput
 		if err := c.ShouldBindJSON(&input); err != nil {
-			jsonErr(c, http.StatusBadRequest, err.Error())
+			jsonErrOf(c, http.StatusBadRequest, err)
 			return
 		}
 		if err := input.Validate(strfmt.Default); err != nil {
-			jsonErr(c, http.StatusUnprocessableEntity, err.Error())
+			jsonErrOf(c, http.StatusUnprocessableEntity, err)
 			return
 		}
 
-		dateFrom, err := parseMonthYear(*input.StartDate)
+		dateFrom, err := dp.Parse(*input.StartDate)
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.DateInvalid, dateErrMsg("invalid period: date from", err))
+			return
+		}
+		uid, err := entity.ParseUserID(input.UserID.String())
 		if err != nil {
-			jsonErr(c, http.StatusUnprocessableEntity, "invalid period: date from")
+			jsonErrOf(c, http.StatusUnprocessableEntity, err)
+			return
+		}
+		mw.TraceCustomer(c, uid.String(), "")
+		if handled := handleUsecaseErr(c, checkFree(input)); handled {
 			return
 		}
 
 		sub := &entity.Subscription{
-			UserID:      *input.UserID,
+			UserID:      uid,
 			ServiceName: *input.ServiceName,
 			Cost:        *input.Cost,
 			DateFrom:    dateFrom,
 		}
 		if input.EndDate != "" {
-			v, err := parseMonthYear(input.EndDate)
+			v, err := dp.Parse(input.EndDate)
 			if err != nil {
-				jsonErr(c, http.StatusUnprocessableEntity, "invalid period: date to")
+				jsonErrCode(c, http.StatusUnprocessableEntity, errcode.DateInvalid, dateErrMsg("invalid period: date to", err))
 				return
 			}
 			sub.DateTo = &v
 		}
 
+		if dryRun {
+			preview, err := u.Sub.PreviewRegisterSub(c, sub)
+			if handled := handleUsecaseErr(c, err); handled {
+				return
+			}
+			out, err := previewedSub(c, u.Sub, preview)
+			if handled := handleUsecaseErr(c, err); handled {
+				return
+			}
+			c.JSON(http.StatusOK, out)
+			return
+		}
+
 		created, err := u.Sub.RegisterSub(c, sub)
 		if handled := handleUsecaseErr(c, err); handled {
 			return
@@ -124,8 +274,31 @@ func setupSubscription(r *gin.RouterGroup, u UseCases) {
 			jsonErr(c, http.StatusCreated, "nil result from RegisterSub")
 			return
 		}
-		out := buildSubDTO(created)
-		c.JSON(http.StatusCreated, out)
+		c.Header("ETag", subETag(created))
+		c.JSON(http.StatusCreated, writtenSub(c, u.Sub, created))
+	})
+
+	r.POST("/subscriptions/merge", mw.Budget(budgetWrite), func(c *gin.Context) {
+		if !requireAcceptJSON(c) || !requireJSONContent(c) {
+			return
+		}
+		var input *generated.SubscriptionsMerge
+		if err := c.ShouldBindJSON(&input); err != nil {
+			jsonErrOf(c, http.StatusBadRequest, err)
+			return
+		}
+		if err := input.Validate(strfmt.Default); err != nil {
+			jsonErrOf(c, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		merged, err := u.Sub.MergeSubs(c, *input.KeepID, *input.MergeID, c.ClientIP())
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		out := buildSubDTO(merged, u.Sub.IDs())
+		c.Header("ETag", subETag(merged))
+		c.JSON(http.StatusOK, out)
 	})
 
 	r.OPTIONS("/subscriptions", func(c *gin.Context) {
@@ -135,114 +308,165 @@ func setupSubscription(r *gin.RouterGroup, u UseCases) {
 }
 
 // setupSubscriptionsId registers get/update/delete by id routes.
-func setupSubscriptionsId(r *gin.RouterGroup, u UseCases) {
-	r.GET("/subscriptions/:id", func(c *gin.Context) {
-		if !requireAcceptJSON(c) {
+func setupSubscriptionsId(r *gin.RouterGroup, u UseCases, dp *dates.Parser, requireIfMatch bool) {
+	r.GET("/subscriptions/:id", mw.Budget(budgetRead), func(c *gin.Context) {
+		format, ok := requireAcceptEncoded(c)
+		if !ok {
 			return
 		}
-		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		fields, err := parseFields(c)
 		if err != nil {
-			jsonErr(c, http.StatusUnprocessableEntity, "invalid id")
+			jsonErrOf(c, http.StatusUnprocessableEntity, err)
+			return
+		}
+		id, ok := subIDParam(c, u.Sub, http.StatusUnprocessableEntity)
+		if !ok {
 			return
 		}
 		sub, err := u.Sub.GetSubByID(c, id)
 		if errors.Is(err, usecase.ErrInvalidID) {
-			jsonErr(c, http.StatusUnprocessableEntity, "invalid id")
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.IDInvalid, "invalid id")
 			return
 		}
-		if err != nil {
+		if err != nil && !errors.Is(err, usecase.ErrSubscriptionNotFound) {
 			jsonErr(c, http.StatusInternalServerError, "internal error")
 			return
 		}
 		if sub == nil {
-			jsonErr(c, http.StatusNotFound, "not found")
+			jsonErrCode(c, http.StatusNotFound, errcode.SubNotFound, "not found")
 			return
 		}
-		out := buildSubDTO(sub)
-		c.JSON(http.StatusOK, out)
+		out := buildSubDTO(sub, u.Sub.IDs())
+		c.Header("ETag", subETag(sub))
+		respond(c, http.StatusOK, format, out, fields)
 	})
 
-	r.PUT("/subscriptions/:id", func(c *gin.Context) {
+	r.PUT("/subscriptions/:id", mw.Budget(budgetWrite), func(c *gin.Context) {
 		if !requireAcceptJSON(c) || !requireJSONContent(c) {
 			return
 		}
-		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
-		if err != nil {
-			jsonErr(c, http.StatusUnprocessableEntity, "invalid id")
+		id, ok := subIDParam(c, u.Sub, http.StatusUnprocessableEntity)
+		if !ok {
+			return
+		}
+		version, ok := ifMatchVersion(c, requireIfMatch)
+		if !ok {
+			return
+		}
+		dryRun, ok := dryRunParam(c)
+		if !ok {
 			return
 		}
 
 		var input *generated.SubscriptionInput
 		if err := c.ShouldBindJSON(&input); err != nil {
-			jsonErr(c, http.StatusBadRequest, err.Error())
+			jsonErrOf(c, http.StatusBadRequest, err)
 			return
 		}
 		if err := input.Validate(strfmt.Default); err != nil {
-			jsonErr(c, http.StatusUnprocessableEntity, err.Error())
+			jsonErrOf(c, http.StatusUnprocessableEntity, err)
 			return
 		}
 
-		df, err := parseMonthYear(*input.StartDate)
+		df, err := dp.Parse(*input.StartDate)
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.DateInvalid, dateErrMsg("invalid period: date from", err))
+			return
+		}
+		uid, err := entity.ParseUserID(input.UserID.String())
 		if err != nil {
-			jsonErr(c, http.StatusUnprocessableEntity, "invalid period: date from")
+			jsonErrOf(c, http.StatusUnprocessableEntity, err)
+			return
+		}
+		mw.TraceCustomer(c, uid.String(), "")
+		if handled := handleUsecaseErr(c, checkFree(input)); handled {
 			return
 		}
 
 		newSub := entity.Subscription{
 			ID:          id,
-			UserID:      *input.UserID,
+			UserID:      uid,
 			ServiceName: *input.ServiceName,
 			Cost:        *input.Cost,
 			DateFrom:    df,
+			UpdatedAt:   version,
 		}
 		if input.EndDate != "" {
-			v, err := parseMonthYear(input.EndDate)
+			v, err := dp.Parse(input.EndDate)
 			if err != nil {
-				jsonErr(c, http.StatusUnprocessableEntity, "invalid period: date to")
+				jsonErrCode(c, http.StatusUnprocessableEntity, errcode.DateInvalid, dateErrMsg("invalid period: date to", err))
 				return
 			}
 			newSub.DateTo = &v
 		}
 
-		updated, err := u.Sub.UpdateSub(c, &newSub)
+		update := u.Sub.UpdateSub
+		if dryRun {
+			update = u.Sub.PreviewUpdateSub
+		}
+		updated, err := update(c, &newSub)
 		switch {
 		case errors.Is(err, usecase.ErrInvalidID):
-			jsonErr(c, http.StatusUnprocessableEntity, "invalid id")
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.IDInvalid, "invalid id")
 			return
 		case errors.Is(err, usecase.ErrInvalidSubscription):
-			jsonErr(c, http.StatusUnprocessableEntity, "invalid subscriptions data")
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.Of(err), "invalid subscriptions data")
 			return
 		case errors.Is(err, usecase.ErrInvalidPeriod):
-			jsonErr(c, http.StatusUnprocessableEntity, "invalid period")
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.Of(err), "invalid period")
+			return
+		case errors.Is(err, usecase.ErrDateOutOfRange):
+			jsonErrOf(c, http.StatusUnprocessableEntity, err)
+			return
+		case errors.Is(err, usecase.ErrPreconditionFailed):
+			jsonErrOf(c, http.StatusPreconditionFailed, err)
 			return
-		case err != nil || updated == nil:
-			jsonErr(c, http.StatusNotFound, "not found")
+		case errors.Is(err, usecase.ErrSubscriptionNotFound), err == nil && updated == nil:
+			jsonErrCode(c, http.StatusNotFound, errcode.SubNotFound, "not found")
+			return
+		case handleUsecaseErr(c, err):
 			return
 		}
 
-		out := buildSubDTO(updated)
-		c.JSON(http.StatusOK, out)
+		if dryRun {
+			out, err := previewedSub(c, u.Sub, updated)
+			if handled := handleUsecaseErr(c, err); handled {
+				return
+			}
+			c.JSON(http.StatusOK, out)
+			return
+		}
+		c.Header("ETag", subETag(updated))
+		c.JSON(http.StatusOK, writtenSub(c, u.Sub, updated))
 	})
 
-	r.DELETE("/subscriptions/:id", func(c *gin.Context) {
+	r.DELETE("/subscriptions/:id", mw.Budget(budgetWrite), func(c *gin.Context) {
 		if !requireAcceptJSON(c) {
 			return
 		}
-		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
-		if err != nil {
-			jsonErr(c, http.StatusBadRequest, "invalid id")
+		id, ok := subIDParam(c, u.Sub, http.StatusBadRequest)
+		if !ok {
 			return
 		}
-		deleted, err := u.Sub.DeleteSub(c, id)
+		version, ok := ifMatchVersion(c, requireIfMatch)
+		if !ok {
+			return
+		}
+		deleted, err := u.Sub.DeleteSub(c, id, version)
 		switch {
 		case errors.Is(err, usecase.ErrInvalidID):
-			jsonErr(c, http.StatusUnprocessableEntity, "invalid id")
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.IDInvalid, "invalid id")
+			return
+		case errors.Is(err, usecase.ErrPreconditionFailed):
+			jsonErrOf(c, http.StatusPreconditionFailed, err)
 			return
-		case err != nil, deleted == nil:
-			jsonErr(c, http.StatusNotFound, "not found")
+		case errors.Is(err, usecase.ErrSubscriptionNotFound), err == nil && deleted == nil:
+			jsonErrCode(c, http.StatusNotFound, errcode.SubNotFound, "not found")
+			return
+		case handleUsecaseErr(c, err):
 			return
 		}
-		out := buildSubDTO(deleted)
+		out := buildSubDTO(deleted, u.Sub.IDs())
 		c.JSON(http.StatusOK, out)
 	})
 
@@ -252,8 +476,163 @@ func setupSubscriptionsId(r *gin.RouterGroup, u UseCases) {
 	})
 }
 
-// setupSubscriptionsCost registers aggregate cost endpoint.
-func setupSubscriptionsCost(r *gin.RouterGroup, u UseCases) {
+// pagingPolicy controls what list endpoints do with a limit or offset outside the allowed range.
+type pagingPolicy struct {
+	// strict answers 400 naming the range instead of clamping the limit
+	strict bool
+	// maxBytes is the size budget of a list page, 0 means none
+	maxBytes int
+}
+
+// check answers 400 in strict mode when the limit or offset query value is outside limits; returns false if answered.
+func (p pagingPolicy) check(c *gin.Context, limits pagination.Limits) bool {
+	if !p.strict {
+		return true
+	}
+	if err := limits.Check(c.Query("limit"), c.Query("offset")); err != nil {
+		jsonErrOf(c, http.StatusBadRequest, err)
+		return false
+	}
+	return true
+}
+
+// fit returns how many leading items of a page fit into the size budget, always at least one so the list
+// moves on. Items are measured as full JSON, so pages of other formats or with fields come out smaller.
+func (p pagingPolicy) fit(items []*generated.Subscription) int {
+	if p.maxBytes <= 0 {
+		return len(items)
+	}
+	size := len("[]")
+	for i, item := range items {
+		b, err := json.Marshal(item)
+		if err != nil {
+			return len(items)
+		}
+		size += len(b)
+		if i > 0 {
+			size += len(",")
+		}
+		if size > p.maxBytes {
+			return max(i, 1)
+		}
+	}
+	return len(items)
+}
+
+// Values of HTTP_DEFAULT_PERIOD
+const (
+	periodAll          = "all"
+	periodCurrentMonth = "current_month"
+	periodCurrentYear  = "current_year"
+)
+
+// periodPolicy is the period of list and cost requests that name neither start_date nor end_date.
+type periodPolicy struct {
+	// def is one of the period* values; periodAll and empty leave such requests without a period
+	def string
+}
+
+// defaults reports whether requests without dates get a period.
+func (p periodPolicy) defaults() bool {
+	return p.def == periodCurrentMonth || p.def == periodCurrentYear
+}
+
+// fill sets the default period on f unless it has one; the current month is the one in the time zone of
+// f.UserID, UTC without a user. Returns false if an error was answered.
+func (p periodPolicy) fill(c *gin.Context, u UseCases, f *usecase.SubFilter) bool {
+	if !p.defaults() || f.Period != nil {
+		return true
+	}
+	var settings entity.Settings
+	if !f.UserID.IsZero() {
+		s, err := u.Sub.GetSettings(c, f.UserID)
+		if handled := handleUsecaseErr(c, err); handled {
+			return false
+		}
+		settings = s
+	}
+	month := settings.MonthOf(u.Sub.Now())
+	switch p.def {
+	case periodCurrentMonth:
+		f.Period = &usecase.Period{From: month, To: month}
+	case periodCurrentYear:
+		jan := time.Date(month.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		f.Period = &usecase.Period{From: jan, To: jan.AddDate(0, 11, 0)}
+	}
+	return true
+}
+
+// cachePolicy controls HTTP caching of aggregate responses; zero values disable caching.
+type cachePolicy struct {
+	maxAge time.Duration
+}
+
+// enabled reports whether responses may be cached at all.
+func (p cachePolicy) enabled() bool {
+	return p.maxAge > 0
+}
+
+// header renders the Cache-Control value: the responses are per user, so shared caches must not keep them.
+func (p cachePolicy) header() string {
+	return fmt.Sprintf("private, max-age=%d", int(p.maxAge.Seconds()))
+}
+
+// versionETag derives a strong entity tag from a data version.
+func versionETag(v usecase.DataVersion) string {
+	return `"` + strconv.FormatInt(v.Version, 36) + `"`
+}
+
+// notModified reports whether the conditional headers of the request still match the version; If-None-Match
+// takes precedence over If-Modified-Since, which only has a precision of seconds.
+func notModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	if h := c.GetHeader("If-None-Match"); h != "" {
+		for _, tag := range strings.Split(h, ",") {
+			if tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/"); tag == etag || tag == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	ifModifiedSince := c.GetHeader("If-Modified-Since")
+	if ifModifiedSince == "" || lastModified.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(t)
+}
+
+// subETag derives a strong entity tag from the subscription version, i.e. updated_at in microseconds.
+func subETag(s *entity.Subscription) string {
+	return `"` + strconv.FormatInt(s.UpdatedAt.UnixMicro(), 36) + `"`
+}
+
+// ifMatchVersion resolves the If-Match header into the subscription version a write is conditional on.
+// A zero version means unconditional; ok is false when an error response has already been written.
+// Only a single strong tag or "*" is understood, anything else can never match.
+func ifMatchVersion(c *gin.Context, required bool) (time.Time, bool) {
+	h := strings.TrimSpace(c.GetHeader("If-Match"))
+	switch {
+	case h == "" && required:
+		jsonErr(c, http.StatusPreconditionRequired, "If-Match header is required")
+		return time.Time{}, false
+	case h == "", h == "*":
+		return time.Time{}, true
+	}
+	tag, isQuoted := strings.CutPrefix(h, `"`)
+	tag, isClosed := strings.CutSuffix(tag, `"`)
+	micros, err := strconv.ParseInt(tag, 36, 64)
+	if !isQuoted || !isClosed || err != nil {
+		jsonErrCode(c, http.StatusPreconditionFailed, errcode.SubModified, usecase.ErrPreconditionFailed.Error())
+		return time.Time{}, false
+	}
+	return time.UnixMicro(micros).UTC(), true
+}
+
+// setupSubscriptionsCost registers aggregate cost endpoints.
+func setupSubscriptionsCost(r *gin.RouterGroup, u UseCases, dp *dates.Parser, cache cachePolicy, periods periodPolicy) {
 	methodNA := func(c *gin.Context) {
 		c.Header("Allow", "GET,OPTIONS")
 		jsonErr(c, http.StatusMethodNotAllowed, "method not allowed")
@@ -262,56 +641,277 @@ func setupSubscriptionsCost(r *gin.RouterGroup, u UseCases) {
 		r.Handle(m, "/subscriptions/cost", methodNA)
 	}
 
-	r.GET("/subscriptions/cost", func(c *gin.Context) {
-		if !requireAcceptJSON(c) {
+	r.GET("/subscriptions/cost", mw.Budget(budgetReport), func(c *gin.Context) {
+		format, ok := requireAcceptEncoded(c)
+		if !ok {
 			return
 		}
 
-		startRaw := strings.TrimSpace(c.Query("start_date"))
-		if startRaw == "" {
-			jsonErr(c, http.StatusUnprocessableEntity, "invalid start_date")
+		f, ok := costFilterFromQuery(c, u, dp, periods)
+		if !ok {
 			return
 		}
-		endRaw := strings.TrimSpace(c.Query("end_date"))
-		if endRaw == "" {
-			jsonErr(c, http.StatusUnprocessableEntity, "invalid end_date")
+
+		var settings *entity.Settings
+		if !f.UserID.IsZero() {
+			s, err := u.Sub.GetSettings(c, f.UserID)
+			if handled := handleUsecaseErr(c, err); handled {
+				return
+			}
+			settings = &s
+		}
+
+		if cache.enabled() {
+			// the version covers deletes, adjustments, seats, settings and deactivation too
+			v, err := u.Sub.DataVersion(c, f.UserID)
+			if handled := handleUsecaseErr(c, err); handled {
+				return
+			}
+			etag := versionETag(v)
+			c.Header("Cache-Control", cache.header())
+			// the body depends on the negotiated format and, through the locale, on the language
+			c.Header("Vary", "Accept, Accept-Language")
+			c.Header("ETag", etag)
+			if !v.ChangedAt.IsZero() {
+				c.Header("Last-Modified", v.ChangedAt.UTC().Format(http.TimeFormat))
+			}
+			if notModified(c, etag, v.ChangedAt) {
+				c.Status(http.StatusNotModified)
+				return
+			}
+		}
+
+		total, err := u.Sub.CostSubsByFilter(c, f)
+		if handled := handleUsecaseErr(c, err); handled {
 			return
 		}
+		out := generated.SubscriptionsCost{Total: total}
+		if settings != nil {
+			out.Currency = settings.Currency
+		}
+		respond(c, http.StatusOK, format, out, nil)
+	})
 
-		filterDTO, err := buildSubscriptionsFilterFromQuery(c)
-		if err != nil {
-			jsonErr(c, http.StatusUnprocessableEntity, err.Error())
+	r.OPTIONS("/subscriptions/cost", func(c *gin.Context) {
+		c.Header("Allow", "GET,OPTIONS")
+		c.Status(http.StatusNoContent)
+	})
+
+	r.GET("/subscriptions/cost/grouped", mw.Budget(budgetReport), func(c *gin.Context) {
+		if !requireAcceptJSON(c) {
+			return
+		}
+		by, granularity, ok := costGroupByFromQuery(c)
+		if !ok {
+			return
+		}
+		f, ok := costFilterFromQuery(c, u, dp, periods)
+		if !ok {
+			return
+		}
+		label, ok := monthLabeler(c)
+		if !ok {
 			return
 		}
 
-		f, err := mapFilterDTOToUsecase(filterDTO)
-		if err != nil {
-			jsonErr(c, http.StatusUnprocessableEntity, err.Error())
+		out := costGrouped{By: string(by), Groups: []costGroup{}}
+		if by.Temporal() {
+			out.By, out.Granularity = string(usecase.CostByMonth), string(granularity)
+			by = granularity
+		}
+		if !f.UserID.IsZero() {
+			s, err := u.Sub.GetSettings(c, f.UserID)
+			if handled := handleUsecaseErr(c, err); handled {
+				return
+			}
+			out.Currency = s.Currency
+		}
+		groups, err := u.Sub.CostGroupedByFilter(c, f, by)
+		if handled := handleUsecaseErr(c, err); handled {
 			return
 		}
+		for _, g := range groups {
+			key, keyLabel := g.Key(by), ""
+			switch by {
+			case usecase.CostByMonth:
+				key = dates.Format(g.Month)
+				if label != nil {
+					keyLabel = label(g.Month)
+				}
+			case usecase.CostByWeek:
+				year, week := g.Month.ISOWeek()
+				key = fmt.Sprintf("%d-W%02d", year, week)
+			case usecase.CostByYear:
+				key = strconv.Itoa(g.Month.Year())
+			}
+			out.Groups = append(out.Groups, costGroup{Key: key, Label: keyLabel, Total: g.Total, Count: g.Count})
+		}
+		c.JSON(http.StatusOK, out)
+	})
 
-		if f.Period == nil || f.Period.From.IsZero() || f.Period.To.IsZero() {
-			jsonErr(c, http.StatusUnprocessableEntity, "invalid period")
+	r.GET("/subscriptions/cost/summary", mw.Budget(budgetReport), func(c *gin.Context) {
+		if !requireAcceptJSON(c) {
 			return
 		}
-		if f.Period.From.After(f.Period.To) {
-			jsonErr(c, http.StatusUnprocessableEntity, "from must be <= to")
+		f, ok := costFilterFromQuery(c, u, dp, periods)
+		if !ok {
 			return
 		}
 
-		total, err := u.Sub.CostSubsByFilter(c, f)
+		var out costSummary
+		if !f.UserID.IsZero() {
+			s, err := u.Sub.GetSettings(c, f.UserID)
+			if handled := handleUsecaseErr(c, err); handled {
+				return
+			}
+			out.Currency = s.Currency
+		}
+		sum, err := u.Sub.CostSummaryByFilter(c, f)
 		if handled := handleUsecaseErr(c, err); handled {
 			return
 		}
-		c.JSON(http.StatusOK, generated.SubscriptionsCost{Total: total})
+		out.Total, out.Count = sum.Total, sum.Count
+		out.AverageCost, out.MinCost, out.MaxCost = sum.AverageCost(), sum.MinCost, sum.MaxCost
+		if !f.UserID.IsZero() {
+			out.Seats = make([]seatShare, 0, len(sum.Seats))
+			for _, s := range sum.Seats {
+				seat := seatShare{ServiceName: s.ServiceName, Seats: s.Seats, Share: s.Share()}
+				seat.SubscriptionID, seat.SubscriptionPublicID = subRef(u.Sub.IDs(), s.SubscriptionID, s.PublicID)
+				out.Seats = append(out.Seats, seat)
+			}
+			out.SeatShare = sum.SeatShareTotal()
+		}
+		c.JSON(http.StatusOK, out)
 	})
 
-	r.OPTIONS("/subscriptions/cost", func(c *gin.Context) {
-		c.Header("Allow", "GET,OPTIONS")
-		c.Status(http.StatusNoContent)
+	r.GET("/subscriptions/cost/now", mw.Budget(budgetPoll), func(c *gin.Context) {
+		if !requireAcceptJSON(c) {
+			return
+		}
+		uid, err := entity.ParseUserID(strings.TrimSpace(c.Query("user_id")))
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.UserIDInvalid, "uuid invalid")
+			return
+		}
+		now, err := u.Sub.CostNow(c, uid)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		c.JSON(http.StatusOK, costNow{
+			Month:    dates.Format(now.Month),
+			Total:    now.Total,
+			Currency: now.Currency,
+			AsOf:     now.AsOf,
+		})
 	})
 }
 
+// costNow is the response of GET /api/v1/subscriptions/cost/now.
+type costNow struct {
+	Month    string    `json:"month"`
+	Total    int64     `json:"total"`
+	Currency string    `json:"currency"`
+	AsOf     time.Time `json:"as_of"`
+}
+
+// costSummary is the response of GET /api/v1/subscriptions/cost/summary.
+type costSummary struct {
+	Total       int64  `json:"total"`
+	Count       int64  `json:"count"`
+	AverageCost int64  `json:"average_cost"`
+	MinCost     int64  `json:"min_cost"`
+	MaxCost     int64  `json:"max_cost"`
+	Currency    string `json:"currency,omitempty"`
+	// Seats are the seats the user of the filter holds in plans paid by others, not part of Total
+	Seats []seatShare `json:"seats,omitempty"`
+	// SeatShare is the summed monthly share of Seats
+	SeatShare int64 `json:"seat_share,omitempty"`
+}
+
+// costGroup is the summed cost of the subscriptions sharing one key.
+type costGroup struct {
+	Key string `json:"key"`
+	// Label is the month of a monthly series spelled out in the response language, with ?labels=true only
+	Label string `json:"label,omitempty"`
+	Total int64  `json:"total"`
+	Count int64  `json:"count"`
+}
+
+// costGrouped is the response of GET /api/v1/subscriptions/cost/grouped.
+type costGrouped struct {
+	By          string      `json:"by"`
+	Granularity string      `json:"granularity,omitempty"`
+	Currency    string      `json:"currency,omitempty"`
+	Groups      []costGroup `json:"groups"`
+}
+
+// costGroupByFromQuery reads the by and granularity parameters of GET /subscriptions/cost/grouped and answers
+// 422 when they are invalid. by=month is the time series, granularity picks its step and defaults to month.
+func costGroupByFromQuery(c *gin.Context) (by, granularity usecase.CostGroupBy, ok bool) {
+	by = usecase.CostGroupBy(strings.ToLower(strings.TrimSpace(c.Query("by"))))
+	granularity = usecase.CostGroupBy(strings.ToLower(strings.TrimSpace(c.Query("granularity"))))
+	switch by {
+	case usecase.CostByService, usecase.CostByUser, usecase.CostByMonth:
+	default:
+		jsonErrCode(c, http.StatusUnprocessableEntity, errcode.GroupByInvalid, "invalid by: want service, user or month")
+		return "", "", false
+	}
+	if granularity == "" {
+		granularity = usecase.CostByMonth
+	}
+	switch {
+	case granularity != usecase.CostByMonth && !by.Temporal():
+		jsonErrCode(c, http.StatusUnprocessableEntity, errcode.GroupByInvalid, "granularity applies to by=month only")
+		return "", "", false
+	case !granularity.Temporal():
+		jsonErrCode(c, http.StatusUnprocessableEntity, errcode.GroupByInvalid, "invalid granularity: want week, month or year")
+		return "", "", false
+	}
+	return by, granularity, true
+}
+
+// costFilterFromQuery builds the filter of a cost endpoint, which requires a whole start_date..end_date period,
+// and answers 422 when it is invalid.
+func costFilterFromQuery(c *gin.Context, u UseCases, dp *dates.Parser, periods periodPolicy) (usecase.SubFilter, bool) {
+	startRaw := strings.TrimSpace(c.Query("start_date"))
+	endRaw := strings.TrimSpace(c.Query("end_date"))
+	defaulted := startRaw == "" && endRaw == "" && periods.defaults()
+	if startRaw == "" && !defaulted {
+		jsonErrCode(c, http.StatusUnprocessableEntity, errcode.DateInvalid, "invalid start_date")
+		return usecase.SubFilter{}, false
+	}
+	if endRaw == "" && !defaulted {
+		jsonErrCode(c, http.StatusUnprocessableEntity, errcode.DateInvalid, "invalid end_date")
+		return usecase.SubFilter{}, false
+	}
+
+	filterDTO, err := buildSubscriptionsFilterFromQuery(c)
+	if err != nil {
+		jsonErrOf(c, http.StatusUnprocessableEntity, err)
+		return usecase.SubFilter{}, false
+	}
+
+	f, err := mapFilterDTOToUsecase(filterDTO, dp)
+	if err != nil {
+		jsonErrOf(c, http.StatusUnprocessableEntity, err)
+		return usecase.SubFilter{}, false
+	}
+	if defaulted && !periods.fill(c, u, &f) {
+		return usecase.SubFilter{}, false
+	}
+
+	if f.Period == nil || f.Period.From.IsZero() || f.Period.To.IsZero() {
+		jsonErrCode(c, http.StatusUnprocessableEntity, errcode.PeriodInvalid, "invalid period")
+		return usecase.SubFilter{}, false
+	}
+	if f.Period.From.After(f.Period.To) {
+		jsonErrCode(c, http.StatusUnprocessableEntity, errcode.PeriodInvalid, "from must be <= to")
+		return usecase.SubFilter{}, false
+	}
+
+	return f, true
+}
+
 // acceptsJSON checks if Accept header allows application/json.
 func acceptsJSON(h string) bool {
 	if h == "" || h == "*/*" {
@@ -335,6 +935,14 @@ func requireAcceptJSON(c *gin.Context) bool {
 	return false
 }
 
+// dateErrMsg returns msg, naming the expected layout when the parser runs in exact layout mode.
+func dateErrMsg(msg string, err error) string {
+	if errors.Is(err, dates.ErrUnexpectedFormat) {
+		return msg + ": " + err.Error()
+	}
+	return msg
+}
+
 // requireJSONContent enforces Content-Type: application/json (if provided).
 func requireJSONContent(c *gin.Context) bool {
 	ct := strings.TrimSpace(c.ContentType())
@@ -345,16 +953,99 @@ func requireJSONContent(c *gin.Context) bool {
 	return false
 }
 
-// buildSubDTO maps domain Subscription to generated transport model.
-func buildSubDTO(s *entity.Subscription) generated.Subscription {
+// syncToken is the opaque change log position handed to sync clients, bound to the user it was issued for.
+type syncToken struct {
+	Kind   string `json:"k"`
+	UserID string `json:"u"`
+	Tx     uint64 `json:"x"`
+	Seq    int64  `json:"q"`
+}
+
+// setupSync registers the incremental sync endpoint over the change log of a user's subscriptions.
+func setupSync(r *gin.RouterGroup, u UseCases, tokens *pagination.Codec, paging pagingPolicy) {
+	r.GET("/sync", mw.Budget(budgetRead), func(c *gin.Context) {
+		if !requireAcceptJSON(c) || !paging.check(c, usecase.SyncLimits) {
+			return
+		}
+		uid, err := entity.ParseUserID(strings.TrimSpace(c.Query("user_id")))
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.UserIDInvalid, "uuid invalid")
+			return
+		}
+
+		var since entity.ChangePos
+		if v := strings.TrimSpace(c.Query("since")); v != "" {
+			var t syncToken
+			// list cursors share the codec, the kind keeps them from passing for a position
+			if err := tokens.Decode(v, &t); err != nil || t.Kind != "sync" || t.UserID != uid.String() {
+				jsonErr(c, http.StatusUnprocessableEntity, "invalid sync token")
+				return
+			}
+			since = entity.ChangePos{Tx: t.Tx, Seq: t.Seq}
+		}
+		limit, err := pagination.ParseLimit(c.Query("limit"))
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.PaginationInvalid, "invalid limit")
+			return
+		}
+
+		changes, err := u.Sub.ChangesSince(c, uid, since, limit)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		next, err := tokens.Encode(syncToken{Kind: "sync", UserID: uid.String(), Tx: changes.Next.Tx, Seq: changes.Next.Seq})
+		if err != nil {
+			jsonErr(c, http.StatusInternalServerError, "internal error")
+			return
+		}
+
+		ids := u.Sub.IDs()
+		c.JSON(http.StatusOK, generated.SyncChanges{
+			Created: syncRefs(changes.Created, ids),
+			Updated: syncRefs(changes.Updated, ids),
+			Deleted: syncRefs(changes.Deleted, ids),
+			Next:    next,
+			HasMore: changes.HasMore,
+		})
+	})
+}
+
+// syncRefs names the changed subscriptions as the other responses do: by serial ID, or only by the public ID
+// under a public strategy.
+func syncRefs(subs []usecase.ChangedSub, ids usecase.IDs) []interface{} {
+	out := make([]interface{}, 0, len(subs))
+	for _, s := range subs {
+		switch {
+		case !ids.Public():
+			out = append(out, s.ID)
+		case ids.Strategy == usecase.IDHashid || !s.PublicID.IsZero():
+			// hashids encode the serial ID, nothing stored is needed for them
+			out = append(out, ids.Ref(s.ID, s.PublicID))
+		}
+		// an entry recorded without a public ID is left out rather than named by its serial ID
+	}
+	return out
+}
+
+// checkFree requires a subscription costing 0 to be marked free and a free one to cost 0, so that a cost left
+// at zero by mistake is not taken for a free tier.
+func checkFree(in *generated.SubscriptionInput) error {
+	switch {
+	case in.Free && *in.Cost != 0:
+		return errcode.Wrap(errcode.SubInvalid, fmt.Errorf("%w: a free subscription must cost 0", usecase.ErrInvalidSubscription))
+	case !in.Free && *in.Cost == 0:
+		return errcode.Wrap(errcode.CostNegative, fmt.Errorf("%w: cost must be > 0, or 0 with free", usecase.ErrInvalidSubscription))
+	}
+	return nil
+}
+
+// buildSubDTO maps domain Subscription to generated transport model, identified as the strategy says.
+func buildSubDTO(s *entity.Subscription, ids usecase.IDs) generated.Subscription {
 	name := s.ServiceName
 	cost := s.Cost
-	uid := s.UserID
-	start := s.DateFrom.Format("01-2006")
-	var end string
-	if s.DateTo != nil {
-		end = s.DateTo.Format("01-2006")
-	}
+	uid := strfmt.UUID(s.UserID.String())
+	start := dates.Format(s.DateFrom)
+	end := dates.FormatPtr(s.DateTo)
 	return generated.Subscription{
 		SubscriptionInput: generated.SubscriptionInput{
 			ServiceName: &name,
@@ -362,11 +1053,33 @@ func buildSubDTO(s *entity.Subscription) generated.Subscription {
 			UserID:      &uid,
 			StartDate:   &start,
 			EndDate:     end,
+			Free:        s.Free(),
+		},
+		SubscriptionID: subscriptionID(s, ids),
+		SubscriptionTimestamps: generated.SubscriptionTimestamps{
+			CreatedAt: strfmt.DateTime(s.CreatedAt.UTC()),
+			UpdatedAt: strfmt.DateTime(s.UpdatedAt.UTC()),
 		},
-		SubscriptionID: generated.SubscriptionID{ID: s.ID},
 	}
 }
 
+// nextListCursor encodes the keyset of the last subscription when more tells the page may be followed by another one.
+func nextListCursor(subs []*entity.Subscription, more bool, cursors *pagination.Codec) (string, bool) {
+	if len(subs) == 0 || !more {
+		return "", false
+	}
+	last := subs[len(subs)-1]
+	next, err := cursors.Encode(usecase.ListCursor{
+		StartDate:   last.DateFrom,
+		ServiceName: last.ServiceName,
+		ID:          last.ID,
+	})
+	if err != nil {
+		return "", false
+	}
+	return next, true
+}
+
 // buildSubscriptionsFilterFromQuery maps HTTP query parameters to transport filter model.
 func buildSubscriptionsFilterFromQuery(c *gin.Context) (*generated.SubscriptionsFilter, error) {
 	dto := &generated.SubscriptionsFilter{}
@@ -374,7 +1087,7 @@ func buildSubscriptionsFilterFromQuery(c *gin.Context) (*generated.Subscriptions
 	if uidStr := strings.TrimSpace(c.Query("user_id")); uidStr != "" {
 		uid, err := uuid.Parse(uidStr)
 		if err != nil {
-			return nil, fmt.Errorf("uuid invalid")
+			return nil, errcode.New(errcode.UserIDInvalid, "uuid invalid")
 		}
 		dto.UserID = strfmt.UUID(uid.String())
 	}
@@ -383,19 +1096,19 @@ func buildSubscriptionsFilterFromQuery(c *gin.Context) (*generated.Subscriptions
 		dto.ServiceName = svc
 	}
 
-	if v := strings.TrimSpace(c.Query("limit")); v != "" {
-		n, err := strconv.ParseInt(v, 10, 32)
-		if err != nil || n < 0 {
-			return nil, fmt.Errorf("invalid limit")
+	if v := c.Query("limit"); strings.TrimSpace(v) != "" {
+		n, err := pagination.ParseLimit(v)
+		if err != nil {
+			return nil, err
 		}
 		n32 := int32(n)
 		dto.Limit = &n32
 	}
 
-	if v := strings.TrimSpace(c.Query("offset")); v != "" {
-		n, err := strconv.ParseInt(v, 10, 32)
-		if err != nil || n < 0 {
-			return nil, fmt.Errorf("invalid offset")
+	if v := c.Query("offset"); strings.TrimSpace(v) != "" {
+		n, err := pagination.ParseOffset(v)
+		if err != nil {
+			return nil, err
 		}
 		n32 := int32(n)
 		dto.Offset = &n32
@@ -407,6 +1120,14 @@ func buildSubscriptionsFilterFromQuery(c *gin.Context) (*generated.Subscriptions
 		dto.Period = &generated.Period{StartDate: start, EndDate: end}
 	}
 
+	if v := strings.TrimSpace(c.Query("updated_since")); v != "" {
+		ts, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return nil, errcode.New(errcode.DateInvalid, "invalid updated_since")
+		}
+		dto.UpdatedSince = strfmt.DateTime(ts)
+	}
+
 	if err := dto.Validate(strfmt.Default); err != nil {
 		return nil, err
 	}
@@ -415,7 +1136,7 @@ func buildSubscriptionsFilterFromQuery(c *gin.Context) (*generated.Subscriptions
 }
 
 // mapFilterDTOToUsecase converts transport filter to usecase filter representation.
-func mapFilterDTOToUsecase(dto *generated.SubscriptionsFilter) (usecase.SubFilter, error) {
+func mapFilterDTOToUsecase(dto *generated.SubscriptionsFilter, dp *dates.Parser) (usecase.SubFilter, error) {
 	if dto == nil {
 		return usecase.SubFilter{}, nil
 	}
@@ -432,24 +1153,31 @@ func mapFilterDTOToUsecase(dto *generated.SubscriptionsFilter) (usecase.SubFilte
 		f.ServiceName = &svc
 	}
 	if dto.UserID.String() != "" {
-		f.UserID = dto.UserID
+		uid, err := entity.ParseUserID(dto.UserID.String())
+		if err != nil {
+			return f, err
+		}
+		f.UserID = uid
+	}
+	if since := time.Time(dto.UpdatedSince); !since.IsZero() {
+		f.UpdatedSince = &since
 	}
 
 	if dto.Period != nil {
 		var p usecase.Period
 		hasPeriod := false
 		if dto.Period.StartDate != "" {
-			from, err := parseMonthYear(dto.Period.StartDate)
+			from, err := dp.Parse(dto.Period.StartDate)
 			if err != nil {
-				return f, fmt.Errorf("invalid period: from")
+				return f, errcode.New(errcode.DateInvalid, dateErrMsg("invalid period: from", err))
 			}
 			p.From = from
 			hasPeriod = true
 		}
 		if dto.Period.EndDate != "" {
-			to, err := parseMonthYear(dto.Period.EndDate)
+			to, err := dp.Parse(dto.Period.EndDate)
 			if err != nil {
-				return f, fmt.Errorf("invalid period: to")
+				return f, errcode.New(errcode.DateInvalid, dateErrMsg("invalid period: to", err))
 			}
 			p.To = to
 			hasPeriod = true
@@ -462,21 +1190,102 @@ func mapFilterDTOToUsecase(dto *generated.SubscriptionsFilter) (usecase.SubFilte
 	return f, nil
 }
 
-// jsonErr sends a JSON error with status code.
-func jsonErr(c *gin.Context, code int, msg string) {
-	c.JSON(code, gin.H{"error": msg})
+// statusCodes are the error codes of responses without a more specific one.
+var statusCodes = map[int]errcode.Code{
+	http.StatusBadRequest:            errcode.BadRequest,
+	http.StatusUnauthorized:          errcode.Unauthorized,
+	http.StatusForbidden:             errcode.FeatureDisabled,
+	http.StatusNotFound:              errcode.NotFound,
+	http.StatusMethodNotAllowed:      errcode.MethodNotAllowed,
+	http.StatusNotAcceptable:         errcode.NotAcceptable,
+	http.StatusConflict:              errcode.Conflict,
+	http.StatusGone:                  errcode.Gone,
+	http.StatusPreconditionFailed:    errcode.PreconditionFailed,
+	http.StatusRequestEntityTooLarge: errcode.PayloadTooLarge,
+	http.StatusUnsupportedMediaType:  errcode.UnsupportedMedia,
+	http.StatusUnprocessableEntity:   errcode.ValidationFailed,
+	http.StatusPreconditionRequired:  errcode.PreconditionRequired,
+	http.StatusTooManyRequests:       errcode.RateLimited,
+	http.StatusInternalServerError:   errcode.Internal,
+	http.StatusServiceUnavailable:    errcode.ServerBusy,
+}
+
+// jsonErr sends a JSON error with status code and the generic error code of the status.
+func jsonErr(c *gin.Context, status int, msg string) {
+	jsonErrCode(c, status, "", msg)
+}
+
+// constraintErrBody is the error of a write refused by a storage constraint, naming the field when it is known.
+type constraintErrBody struct {
+	Error string       `json:"error"`
+	Code  errcode.Code `json:"code"`
+	Field string       `json:"field,omitempty"`
+}
+
+// jsonErrOf sends err as a JSON error with its catalog code.
+func jsonErrOf(c *gin.Context, status int, err error) {
+	jsonErrCode(c, status, errcode.Of(err), err.Error())
+}
+
+// jsonErrCode sends a JSON error with status code and error code; the message is translated, the code never is.
+func jsonErrCode(c *gin.Context, status int, code errcode.Code, msg string) {
+	msg = translate(c, msg)
+	if code == "" {
+		code = statusCodes[status]
+	}
+	if code == "" {
+		code = errcode.Internal
+	}
+	c.JSON(status, gin.H{"error": msg, "code": code})
 }
 
 // handleUsecaseErr maps domain errors to HTTP responses; returns true if handled.
 func handleUsecaseErr(c *gin.Context, err error) bool {
+	var constraint *usecase.ConstraintError
 	switch {
 	case err == nil:
 		return false
+	case errors.As(err, &constraint):
+		status := http.StatusUnprocessableEntity
+		if errors.Is(constraint.Kind, usecase.ErrDuplicate) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, constraintErrBody{
+			Error: translate(c, constraint.Kind.Error()),
+			Code:  errcode.Of(constraint),
+			Field: constraint.Field,
+		})
+		return true
 	case errors.Is(err, usecase.ErrInvalidID),
+		errors.Is(err, entity.ErrInvalidUserID),
+		errors.Is(err, entity.ErrInvalidSettings),
 		errors.Is(err, usecase.ErrInvalidSubscription),
 		errors.Is(err, usecase.ErrInvalidPagination),
-		errors.Is(err, usecase.ErrInvalidPeriod):
-		jsonErr(c, http.StatusUnprocessableEntity, strings.TrimPrefix(err.Error(), ": "))
+		errors.Is(err, usecase.ErrInvalidPeriod),
+		errors.Is(err, usecase.ErrInvalidGroupBy),
+		errors.Is(err, usecase.ErrInvalidAdjustment),
+		errors.Is(err, usecase.ErrInvalidSeats),
+		errors.Is(err, usecase.ErrDateOutOfRange):
+		jsonErrCode(c, http.StatusUnprocessableEntity, errcode.Of(err), strings.TrimPrefix(err.Error(), ": "))
+		return true
+	case errors.Is(err, usecase.ErrPreconditionFailed):
+		jsonErrOf(c, http.StatusPreconditionFailed, err)
+		return true
+	case errors.Is(err, usecase.ErrUserHasActiveSubs):
+		jsonErrOf(c, http.StatusConflict, err)
+		return true
+	case errors.Is(err, usecase.ErrSubscriptionNotFound):
+		jsonErrCode(c, http.StatusNotFound, errcode.SubNotFound, "not found")
+		return true
+	case errors.Is(err, usecase.ErrGoalNotSet):
+		jsonErrOf(c, http.StatusNotFound, err)
+		return true
+	case errors.Is(err, usecase.ErrImportTooLarge):
+		jsonErrOf(c, http.StatusRequestEntityTooLarge, err)
+		return true
+	case errors.Is(err, context.Canceled) && mw.ClientGone(c):
+		// nobody is left to read a body
+		c.AbortWithStatus(mw.StatusClientClosedRequest)
 		return true
 	default:
 		jsonErr(c, http.StatusInternalServerError, "internal error")
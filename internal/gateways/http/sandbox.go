@@ -0,0 +1,40 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/internal/sandbox"
+)
+
+// sandboxReset is the response of POST /api/v1/sandbox/reset.
+type sandboxReset struct {
+	Tenant string `json:"tenant"`
+	// Subscriptions is how many seeded subscriptions the sandbox holds now
+	Subscriptions int       `json:"subscriptions"`
+	ResetAt       time.Time `json:"reset_at"`
+}
+
+// setupSandbox registers the reset of the sandbox tenant; only requests the gateway routes to that tenant
+// may reset it.
+func setupSandbox(r *gin.RouterGroup, u UseCases) {
+	r.POST("/sandbox/reset", mw.Budget(budgetImport), func(c *gin.Context) {
+		if u.Sandbox == nil {
+			jsonErr(c, http.StatusForbidden, "sandbox is disabled")
+			return
+		}
+		res, err := u.Sandbox.Reset(c)
+		if errors.Is(err, sandbox.ErrNotSandbox) {
+			jsonErr(c, http.StatusForbidden, "only the sandbox tenant can be reset")
+			return
+		}
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		c.JSON(http.StatusOK, sandboxReset{Tenant: res.Tenant, Subscriptions: res.Subscriptions, ResetAt: res.ResetAt.UTC()})
+	})
+}
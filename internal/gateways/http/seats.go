@@ -0,0 +1,109 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/internal/usecase"
+)
+
+// seatsRequest is the payload of PUT /api/v1/subscriptions/{id}/seats.
+type seatsRequest struct {
+	// Total is the number of seats including the owner's, 0 to stop sharing
+	Total   int      `json:"total"`
+	UserIDs []string `json:"user_ids"`
+}
+
+// seats is the response of the /api/v1/subscriptions/{id}/seats routes.
+type seats struct {
+	// SubscriptionID is left out for SubscriptionPublicID under a public ID strategy
+	SubscriptionID       int64    `json:"subscription_id,omitempty"`
+	SubscriptionPublicID string   `json:"subscription_public_id,omitempty"`
+	Total                int      `json:"total"`
+	UserIDs              []string `json:"user_ids"`
+	// Share is the monthly cost of one seat
+	Share     int64      `json:"share"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// seatShare is a seat of the user in a plan paid by someone else, in the cost summary.
+type seatShare struct {
+	SubscriptionID       int64  `json:"subscription_id,omitempty"`
+	SubscriptionPublicID string `json:"subscription_public_id,omitempty"`
+	ServiceName          string `json:"service_name"`
+	Seats                int    `json:"seats"`
+	Share                int64  `json:"share"`
+}
+
+// setupSeats registers splitting a family or team plan into seats, whose members see their share in the
+// cost summary.
+func setupSeats(r *gin.RouterGroup, u UseCases) {
+	r.PUT("/subscriptions/:id/seats", mw.Budget(budgetWrite), func(c *gin.Context) {
+		if !requireAcceptJSON(c) || !requireJSONContent(c) {
+			return
+		}
+		id, _, ok := subResourceID(c, u.Sub)
+		if !ok {
+			return
+		}
+		var req seatsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			jsonErrOf(c, http.StatusBadRequest, err)
+			return
+		}
+		members := make([]entity.UserID, 0, len(req.UserIDs))
+		for _, raw := range req.UserIDs {
+			uid, err := entity.ParseUserID(raw)
+			if err != nil {
+				jsonErrCode(c, http.StatusUnprocessableEntity, errcode.UserIDInvalid, "uuid invalid")
+				return
+			}
+			members = append(members, uid)
+		}
+
+		plan, err := u.Sub.SetSeats(c, &entity.Seats{SubscriptionID: id, Total: req.Total, UserIDs: members})
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		c.JSON(http.StatusOK, buildSeatsDTO(plan, u.Sub.IDs()))
+	})
+
+	r.GET("/subscriptions/:id/seats", mw.Budget(budgetRead), func(c *gin.Context) {
+		if !requireAcceptJSON(c) {
+			return
+		}
+		id, _, ok := subResourceID(c, u.Sub)
+		if !ok {
+			return
+		}
+		plan, err := u.Sub.GetSeats(c, id)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		c.JSON(http.StatusOK, buildSeatsDTO(plan, u.Sub.IDs()))
+	})
+}
+
+// buildSeatsDTO maps a shared plan to the response; a plan that is not shared has no seats and costs its
+// owner the whole price.
+func buildSeatsDTO(p *usecase.SharedPlan, ids usecase.IDs) seats {
+	out := seats{
+		Total:   p.Seats.Total,
+		UserIDs: make([]string, 0, len(p.Seats.UserIDs)),
+		Share:   p.Share(),
+	}
+	out.SubscriptionID, out.SubscriptionPublicID = subRef(ids, p.Subscription.ID, p.Subscription.PublicID)
+	for _, id := range p.Seats.UserIDs {
+		out.UserIDs = append(out.UserIDs, id.String())
+	}
+	if !p.Seats.UpdatedAt.IsZero() {
+		t := p.Seats.UpdatedAt.UTC()
+		out.UpdatedAt = &t
+	}
+	return out
+}
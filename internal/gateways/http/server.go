@@ -5,15 +5,48 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"subs_tracker/api/swagger"
+	"subs_tracker/internal/activity"
+	"subs_tracker/internal/archive"
+	"subs_tracker/internal/audit"
+	"subs_tracker/internal/buildinfo"
+	"subs_tracker/internal/changes"
 	cfg "subs_tracker/internal/config"
+	"subs_tracker/internal/enrichment"
 	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/internal/i18n"
+	"subs_tracker/internal/integrations"
+	"subs_tracker/internal/leader"
+	"subs_tracker/internal/loadshed"
+	"subs_tracker/internal/metrics"
+	"subs_tracker/internal/pricecheck"
+	"subs_tracker/internal/quarantine"
+	"subs_tracker/internal/readmodel"
+	"subs_tracker/internal/reconcile"
+	"subs_tracker/internal/sandbox"
+	"subs_tracker/internal/share"
+	"subs_tracker/internal/snapshot"
+	"subs_tracker/internal/stripe"
+	"subs_tracker/internal/tenancy"
+	"subs_tracker/internal/theme"
+	"subs_tracker/internal/usage"
 	"subs_tracker/internal/usecase"
+	"subs_tracker/internal/userhooks"
+	"subs_tracker/internal/webhooks"
+	"subs_tracker/internal/widget"
+	"subs_tracker/pkg/dates"
+	"subs_tracker/pkg/pagination"
 )
 
 const (
@@ -24,29 +57,84 @@ const (
 
 // Server holds HTTP server address, router, logger, and graceful shutdown settings.
 type Server struct {
-	host            string
+	hosts           []string
 	port            uint16
 	shutdownTimeout time.Duration
 	router          *gin.Engine
 	log             *slog.Logger
+	httpMetrics     *metrics.HTTP
+	reusePort       bool
+	drainDelay      time.Duration
+	draining        atomic.Bool
 	srv             *http.Server
+	// changes wakes the long-polls at shutdown, which would otherwise hold it up until they time out
+	changes *changes.Log
 }
 
 // UseCases bundles application use cases injected into HTTP handlers.
 type UseCases struct {
 	Sub *usecase.Subscription
+	// Catalog enriches list responses with service metadata; nil disables ?enrich=true
+	Catalog *enrichment.Enricher
+	// Webhooks delivers test events to the configured webhook endpoint; nil when webhooks are off
+	Webhooks *webhooks.Client
+	// Stripe applies Stripe subscription webhooks; nil when the Stripe sync is off
+	Stripe *stripe.Syncer
+	// Snapshots signs exported user archives and verifies restored ones; nil when SNAPSHOT_SECRET is unset
+	Snapshots *snapshot.Sealer
+	// Shares creates and resolves read-only public links to a user's summary; nil disables sharing
+	Shares *share.Links
+	// Widgets creates and resolves the public tokens of the embeddable monthly spend widget; nil disables it
+	Widgets *widget.Tokens
+	// UserHooks registers the webhooks users keep for their own automations; nil disables them
+	UserHooks *userhooks.Hooks
+	// Activity reads the activity feeds of users; nil disables them
+	Activity *activity.Feed
+	// Changes serves the long-poll of subscription changes; nil disables it
+	Changes *changes.Log
+	// PriceReviews lists and resolves the reviews of the price check for /admin/price-reviews; nil disables them
+	PriceReviews *pricecheck.Reviews
+	// Reconcile keeps the charges of uploaded bank statements and serves the mismatches found in them for
+	// /admin/reconciliation-items; nil disables both
+	Reconcile *reconcile.Ledger
+	// Quarantine holds the confirmed import rows failing validation for /users/{user_id}/imports/quarantine; nil
+	// fails the import on the first of them and disables the endpoints
+	Quarantine *quarantine.Box
+	// Archive is rewritten without the subscriptions of a deleted user; nil when ARCHIVE_S3_BUCKET is not set
+	Archive *archive.Archiver
+	// ReadModel is refreshed for a deleted user, so their rows of the analytics read model go with them; nil when
+	// READ_MODEL_ENABLED is off
+	ReadModel *readmodel.Projector
+	// Integrations tracks the calls to outbound integrations for /admin/integrations/status; nil reports none
+	Integrations *integrations.Registry
+	// Themes brands the shared summaries of tenants; nil renders them in the default look and disables the admin
+	// theme endpoints
+	Themes *theme.Themes
+	// Checks are the dependencies reported by /readyz
+	Checks []HealthCheck
+	// Jobs runs the scheduled jobs this instance leads, reported by /admin/info; nil when there are none
+	Jobs *leader.Elector
+	// Usage counts API requests for product analytics; nil when ANALYTICS_SINK is none
+	Usage *usage.Counter
+	// Audit records the body hash and source of every write request; nil when REQUEST_AUDIT_ENABLED is off
+	Audit *audit.Log
+	// Tenants are the tenants whose requests work in a schema of their own; nil keeps every tenant in the
+	// shared schema
+	Tenants *tenancy.Schemas
+	// Sandbox resets the data of the sandbox tenant for /sandbox/reset; nil when SANDBOX_TENANT is unset
+	Sandbox *sandbox.Sandbox
+	// LoadShed turns the expensive routes away while the database is overloaded; nil when LOAD_SHED_ENABLED is off
+	LoadShed *loadshed.Controller
 }
 
 // New constructs a Server with defaults, applies options, and wires the Gin router.
 func New(useCases UseCases, cfg cfg.Config, log *slog.Logger, options ...func(server *Server)) *Server {
-	r := SetupGin(cfg, useCases, log)
-
 	s := &Server{
-		host:            "localhost",
+		hosts:           []string{"localhost"},
 		port:            8080,
-		router:          r,
 		log:             slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})),
 		shutdownTimeout: 5 * time.Second,
+		changes:         useCases.Changes,
 	}
 
 	for _, o := range options {
@@ -57,14 +145,27 @@ func New(useCases UseCases, cfg cfg.Config, log *slog.Logger, options ...func(se
 		s.shutdownTimeout = 5 * time.Second
 	}
 
+	s.router = SetupGin(cfg, useCases, log, s.httpMetrics)
+
 	return s
 }
 
 // WithHost returns an option that sets the server host.
 func WithHost(host string) func(*Server) {
+	return WithHosts(host)
+}
+
+// WithHosts returns an option that makes the server listen on every given host (IPv4, IPv6 or name).
+func WithHosts(hosts ...string) func(*Server) {
 	return func(s *Server) {
-		if host != "" {
-			s.host = host
+		var hs []string
+		for _, h := range hosts {
+			if h != "" {
+				hs = append(hs, h)
+			}
+		}
+		if len(hs) > 0 {
+			s.hosts = hs
 		}
 	}
 }
@@ -96,8 +197,35 @@ func WithTimeout(timeout time.Duration) func(server *Server) {
 	}
 }
 
+// WithMetrics returns an option that enables request latency metrics.
+func WithMetrics(m *metrics.HTTP) func(*Server) {
+	return func(s *Server) {
+		if m != nil {
+			s.httpMetrics = m
+		}
+	}
+}
+
+// WithReusePort returns an option that binds the listener with SO_REUSEPORT,
+// letting a new instance start listening before the old one has shut down.
+func WithReusePort(enabled bool) func(*Server) {
+	return func(s *Server) {
+		s.reusePort = enabled
+	}
+}
+
+// WithDrainDelay returns an option that keeps serving for delay after shutdown is requested
+// while /ping reports 503, so load balancers stop routing new requests first.
+func WithDrainDelay(delay time.Duration) func(*Server) {
+	return func(s *Server) {
+		if delay > 0 {
+			s.drainDelay = delay
+		}
+	}
+}
+
 // SetupGin configures Gin mode, middleware, CORS, and routes from the provided config.
-func SetupGin(cfg cfg.Config, useCases UseCases, log *slog.Logger) *gin.Engine {
+func SetupGin(cfg cfg.Config, useCases UseCases, log *slog.Logger, httpMetrics *metrics.HTTP) *gin.Engine {
 	switch cfg.Env {
 	case envLocal:
 		gin.SetMode(gin.DebugMode)
@@ -107,31 +235,163 @@ func SetupGin(cfg cfg.Config, useCases UseCases, log *slog.Logger) *gin.Engine {
 		gin.SetMode(gin.ReleaseMode)
 	}
 	r := gin.New()
+	// handlers pass the gin context down, it must see the trace and baggage of the request context
+	r.ContextWithFallback = true
+	// gin believes X-Forwarded-For from anyone by default; the abuse bans and the audit log key on the client,
+	// so only the configured proxies may name it
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Error("set trusted proxies, trusting none", slog.Any("error", err))
+		_ = r.SetTrustedProxies(nil)
+	}
 
+	r.Use(mw.Identity(buildinfo.Get()))
+	r.Use(mw.Tracing())
 	r.Use(mw.RecoveryWithSlog(log))
 	r.Use(mw.GinSlog(log))
+	if cfg.Log.BodyLogEnabled() {
+		r.Use(mw.BodyLog(log, mw.BodyLogConfig{
+			SampleRate: cfg.Log.BodySampleRate,
+			Routes:     cfg.Log.BodyRoutes,
+			Statuses:   cfg.Log.BodyStatuses,
+			MaxBytes:   cfg.Log.BodyMaxBytes,
+			Sensitive:  cfg.Log.SensitiveParams,
+		}))
+	}
+	if httpMetrics != nil {
+		r.Use(mw.GinMetrics(httpMetrics))
+	}
+	r.Use(mw.CancelOnDisconnect())
+	if useCases.Tenants != nil {
+		r.Use(mw.TenantSchema(useCases.Tenants))
+	}
+	if tr, err := i18n.NewTranslator(); err != nil {
+		log.Error("load translations, error messages stay in English", slog.Any("error", err))
+	} else {
+		r.Use(localize(tr, useCases))
+	}
+
+	tokens := mw.NewTokens(cfg.Server.AdminToken, cfg.Server.APITokens...)
+	if useCases.Audit != nil {
+		// ahead of CORS and the contract check, so writes they refuse are recorded too
+		r.Use(mw.Audit(useCases.Audit, tokens))
+	}
 
 	origins := cfg.Server.CORSOrigins
 	if len(origins) == 0 {
 		origins = buildAllowedOrigins(cfg)
 	}
 	origins = append(origins, []string{"http://localhost:8082", "http://127.0.0.1:8082"}...)
-	r.Use(cors.New(cors.Config{
+	apiCORS := cors.New(cors.Config{
 		AllowOrigins:     origins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Content-Type", "Authorization"},
 		AllowCredentials: true,
-	}))
+	})
+	r.Use(func(c *gin.Context) {
+		// the widget is embedded on sites of users, its routes allow the origins of each token instead
+		if strings.HasPrefix(c.Request.URL.Path, widgetPath) {
+			c.Next()
+			return
+		}
+		apiCORS(c)
+	})
 
-	setupRouter(r, useCases)
+	if cfg.Server.ContractValidation && cfg.Env != envProd {
+		if validate, err := mw.ContractValidation(swagger.Spec, log); err != nil {
+			log.Error("load API contract, requests are not validated", slog.Any("error", err))
+		} else {
+			r.Use(validate)
+		}
+	}
+
+	dp := dates.NewParser(
+		dates.WithLayouts(cfg.Dates.Layouts...),
+		dates.WithStrict(cfg.Dates.Strict),
+		dates.WithMonthNames(dates.MonthNames(cfg.Dates.Locale)),
+	)
+	costCache := cachePolicy{maxAge: cfg.Server.CostMaxAge}
+	paging := pagingPolicy{strict: cfg.Server.StrictPagination, maxBytes: cfg.Server.ListMaxBytes}
+	periods := periodPolicy{def: cfg.Server.DefaultPeriod}
+	abuse := mw.NewAbuse(mw.AbuseRules{
+		Window:       cfg.Server.AbuseWindow,
+		MaxRequests:  cfg.Server.AbuseMaxRequests,
+		MaxErrorRate: cfg.Server.AbuseMaxErrorRate,
+		Ban:          cfg.Server.AbuseBan,
+	}, tokens, log)
+	limits := apiLimits(cfg.Server)
+	// apiChain builds the middleware of API routes around the token scope; public routes pass no scope
+	apiChain := func(scope ...gin.HandlerFunc) []gin.HandlerFunc {
+		chain := append([]gin.HandlerFunc{abuse.Track()}, scope...)
+		if useCases.LoadShed != nil {
+			// ahead of the limits, so a shed request never waits for a slot
+			chain = append(chain, mw.Shed(useCases.LoadShed, isExpensiveRoute))
+		}
+		chain = append(chain, limits...)
+		if useCases.Usage != nil {
+			// first, so refusals of the scope and limits are counted too
+			chain = append([]gin.HandlerFunc{mw.Usage(useCases.Usage)}, chain...)
+		}
+		return chain
+	}
+	apiMW := apiChain(mw.MethodScope(tokens))
+	var sealing []func(*pagination.Codec)
+	if useCases.Sub != nil && useCases.Sub.IDStrategy().Public() {
+		// keyset cursors carry the serial ID of the last row, which public IDs are there to hide
+		sealing = append(sealing, pagination.WithSealing())
+	}
+	cursors := pagination.NewCodec([]byte(cfg.Server.CursorSecret), sealing...)
+	setupRouter(r, useCases, cursors, dp, costCache, paging, periods, cfg.Server.RequireIfMatch, apiMW...)
+	meta := newAPIMeta(cfg.Server)
+	setupMeta(r.Group("api/v1/", apiMW...), meta)
+	setupMeta(r.Group("api/v2/", apiMW...), meta)
+	setupAdmin(r.Group("api/v1/admin", mw.MethodScope(tokens)), cfg, tokens, abuse, useCases)
+	setupDashboard(r.Group("admin"), cfg, tokens, useCases, dp)
+	setupSPA(r, spaFS(cfg.Server.SPADir))
+	setupInbound(r.Group("api/v1/integrations"), cfg.Inbound, useCases, dp)
+	setupWidget(r.Group(widgetPath, abuse.Track(), mw.Widget(useCases.Widgets)), useCases)
+	// share links are opened by people without API tokens, the limits still apply
+	setupSharedPage(r.Group("api/v1/", apiChain()...), useCases, dp)
+	setupSharedPage(r.Group("api/v2/", apiChain()...), useCases, v2Dates())
 	return r
 }
 
+// apiLimits builds the limiting middleware for API routes: per expensive/read/write class first, then the total;
+// long-polls are left out of all of them.
+func apiLimits(c cfg.ServerConfig) []gin.HandlerFunc {
+	newLimiter := func(n int) *mw.Limiter {
+		if n <= 0 {
+			return nil
+		}
+		return mw.NewLimiter(n, c.QueueLength, c.QueueTimeout)
+	}
+
+	var out []gin.HandlerFunc
+	var class gin.HandlerFunc
+	read, write := newLimiter(c.ReadMaxInFlight), newLimiter(c.WriteMaxInFlight)
+	if read != nil || write != nil {
+		class = mw.MethodClassLimit(read, write)
+	}
+	rate, expensive := mw.NewRateLimiter(c.ExpensiveRate, c.ExpensiveBurst), newLimiter(c.ExpensiveMaxInFlight)
+	if rate != nil || expensive != nil {
+		class = mw.RouteClassLimit(isExpensiveRoute, rate, expensive, class)
+	}
+	if class != nil {
+		out = append(out, class)
+	}
+	if total := newLimiter(c.MaxInFlight); total != nil {
+		out = append(out, mw.ConcurrencyLimit(total))
+	}
+	for i, h := range out {
+		out[i] = skipLongPolls(h)
+	}
+	return out
+}
+
 // buildAllowedOrigins derives default allowed CORS origins from the server host and swagger port.
 func buildAllowedOrigins(c cfg.Config) []string {
-	host := c.Server.Host
-	if host == "" {
-		host = "127.0.0.1"
+	host := "127.0.0.1"
+	if len(c.Server.Hosts) > 0 && c.Server.Hosts[0] != "" {
+		host = c.Server.Hosts[0]
 	}
 	swPort := os.Getenv("SWAGGER_PORT_HOST")
 	if swPort == "" {
@@ -139,46 +399,120 @@ func buildAllowedOrigins(c cfg.Config) []string {
 	}
 
 	return []string{
-		fmt.Sprintf("http://%s:%s", host, swPort),
-		fmt.Sprintf("https://%s:%s", host, swPort),
+		"http://" + net.JoinHostPort(host, swPort),
+		"https://" + net.JoinHostPort(host, swPort),
 	}
 }
 
 // Run starts the HTTP server, listens for context cancellation, and shuts down gracefully.
 func (s *Server) Run(ctx context.Context) error {
-	addr := fmt.Sprintf("%s:%d", s.host, s.port)
 	srv := &http.Server{
-		Addr:    addr,
-		Handler: s.router,
+		Handler: s.handler(),
+	}
+	if s.changes != nil {
+		srv.RegisterOnShutdown(s.changes.Close)
 	}
 	s.srv = srv
 
-	errCh := make(chan error, 1)
-	go func() {
-		s.log.Info("http server started", slog.String("addr", addr))
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			errCh <- err
-			return
+	listeners := make([]net.Listener, 0, len(s.hosts))
+	for _, host := range s.hosts {
+		addr := net.JoinHostPort(host, strconv.Itoa(int(s.port)))
+		ln, err := s.listen(ctx, addr)
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return fmt.Errorf("listen %s: %w", addr, err)
 		}
-		close(errCh)
-	}()
+		listeners = append(listeners, ln)
+	}
+
+	errCh := make(chan error, len(listeners))
+	for _, ln := range listeners {
+		go func() {
+			s.log.Info("http server started", slog.String("addr", ln.Addr().String()), slog.Bool("reuse_port", s.reusePort))
+			err := srv.Serve(ln)
+			if errors.Is(err, http.ErrServerClosed) {
+				err = nil
+			}
+			errCh <- err
+		}()
+	}
 
+	var runErr error
 	select {
 	case <-ctx.Done():
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
-		defer cancel()
-		if err := srv.Shutdown(shutdownCtx); err != nil {
-			return fmt.Errorf("shutdown server: %w", err)
-		}
+		s.drain()
+	case runErr = <-errCh:
+		listeners = listeners[1:]
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return errors.Join(runErr, fmt.Errorf("shutdown server: %w", err))
+	}
+	for range listeners {
 		<-errCh
-		s.log.Info("server shutdown complete")
-		return nil
-	case err := <-errCh:
-		if err != nil {
-			return err
+	}
+	s.log.Info("server shutdown complete")
+	return runErr
+}
+
+// listen opens the TCP listener, with SO_REUSEPORT when enabled.
+func (s *Server) listen(ctx context.Context, addr string) (net.Listener, error) {
+	var lc net.ListenConfig
+	if s.reusePort {
+		lc.Control = reusePortControl
+	}
+	return lc.Listen(ctx, s.network(addr), addr)
+}
+
+// network picks the network of the listener on addr. On "tcp" Go opens the wildcards dual-stack, so 0.0.0.0 and
+// [::] would take each other's port and the second fails with "address already in use"; when hosts of both
+// families are listed, every IP host listens on its own family, tcp6 setting IPV6_V6ONLY.
+func (s *Server) network(addr string) string {
+	var v4, v6 bool
+	for _, h := range s.hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			v4, v6 = v4 || ip.To4() != nil, v6 || ip.To4() == nil
 		}
-		return nil
 	}
+	host, _, err := net.SplitHostPort(addr)
+	ip := net.ParseIP(host)
+	switch {
+	case err != nil || ip == nil || !v4 || !v6:
+		return "tcp"
+	case ip.To4() != nil:
+		return "tcp4"
+	default:
+		return "tcp6"
+	}
+}
+
+// drain marks the server as draining and waits drainDelay so load balancers can take it out of rotation.
+func (s *Server) drain() {
+	if s.drainDelay <= 0 {
+		return
+	}
+	s.draining.Store(true)
+	s.srv.SetKeepAlivesEnabled(false)
+	s.log.Info("draining before shutdown", slog.Duration("delay", s.drainDelay))
+	time.Sleep(s.drainDelay)
+}
+
+// handler wraps the router so the health checks fail while the server is draining;
+// Retry-After tells probes when another instance should have taken over.
+func (s *Server) handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (r.URL.Path == "/ping" || r.URL.Path == "/readyz") && s.draining.Load() {
+			w.Header().Set("Connection", "close")
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(s.drainDelay.Seconds()))))
+			http.Error(w, "draining", http.StatusServiceUnavailable)
+			return
+		}
+		s.router.ServeHTTP(w, r)
+	})
 }
 
 // Close gracefully shuts down the underlying HTTP server if it is running.
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cfg "subs_tracker/internal/config"
+	"subs_tracker/internal/usecase"
+)
+
+func newTestServer(options ...func(*Server)) *Server {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return New(UseCases{Sub: usecase.NewSubscription(stubSubRepo{})}, cfg.Config{Env: "local"}, log, options...)
+}
+
+func TestServer_DrainFailsHealthCheck(t *testing.T) {
+	s := newTestServer(WithDrainDelay(time.Second))
+	h := s.handler()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	s.draining.Store(true)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/version", nil)
+	h.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code, "regular routes are still served while draining")
+}
+
+func TestServer_ReusePort(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SO_REUSEPORT is not supported on windows")
+	}
+	s := newTestServer(WithReusePort(true))
+
+	first, err := s.listen(context.Background(), "127.0.0.1:0")
+	require.NoError(t, err)
+	defer first.Close()
+
+	second, err := s.listen(context.Background(), first.Addr().String())
+	require.NoError(t, err, "second listener must bind the same address")
+	defer second.Close()
+
+	plain := newTestServer()
+	_, err = plain.listen(context.Background(), first.Addr().String())
+	assert.Error(t, err, "without SO_REUSEPORT the address is taken")
+}
+
+func TestServer_RunMultipleHosts(t *testing.T) {
+	hosts := []string{"127.0.0.1"}
+	if ln, err := net.Listen("tcp", "[::1]:0"); err == nil {
+		_ = ln.Close()
+		hosts = append(hosts, "::1")
+	}
+	probe, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := probe.Addr().(*net.TCPAddr).Port
+	require.NoError(t, probe.Close())
+
+	s := newTestServer(WithHosts(hosts...), WithPort(uint16(port)))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+
+	for _, h := range hosts {
+		url := "http://" + net.JoinHostPort(h, strconv.Itoa(port)) + "/ping"
+		require.Eventually(t, func() bool {
+			resp, err := http.Get(url)
+			if err != nil {
+				return false
+			}
+			_ = resp.Body.Close()
+			return resp.StatusCode == http.StatusOK
+		}, 2*time.Second, 20*time.Millisecond, url)
+	}
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestServer_RunBothWildcards(t *testing.T) {
+	if ln, err := net.Listen("tcp6", "[::]:0"); err != nil {
+		t.Skip("IPv6 is not available")
+	} else {
+		_ = ln.Close()
+	}
+	probe, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := probe.Addr().(*net.TCPAddr).Port
+	require.NoError(t, probe.Close())
+
+	s := newTestServer(WithHosts("0.0.0.0", "::"), WithPort(uint16(port)))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+
+	for _, h := range []string{"127.0.0.1", "::1"} {
+		url := "http://" + net.JoinHostPort(h, strconv.Itoa(port)) + "/ping"
+		require.Eventually(t, func() bool {
+			resp, err := http.Get(url)
+			if err != nil {
+				return false
+			}
+			_ = resp.Body.Close()
+			return resp.StatusCode == http.StatusOK
+		}, 2*time.Second, 20*time.Millisecond, url)
+	}
+
+	cancel()
+	require.NoError(t, <-done, "both wildcards bind the same port")
+}
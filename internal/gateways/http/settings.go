@@ -0,0 +1,127 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-openapi/strfmt"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/entity/generated"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/pkg/dates"
+)
+
+// goalProgress is the response of GET /api/v1/users/{user_id}/goal/progress.
+type goalProgress struct {
+	Month     string    `json:"month"`
+	Goal      int64     `json:"goal"`
+	Spent     int64     `json:"spent"`
+	Projected int64     `json:"projected"`
+	Remaining int64     `json:"remaining"`
+	Percent   int64     `json:"percent"`
+	Over      bool      `json:"over"`
+	Currency  string    `json:"currency"`
+	AsOf      time.Time `json:"as_of"`
+}
+
+// setupSettings registers read/replace routes for per-user settings and the progress of the monthly goal.
+func setupSettings(r *gin.RouterGroup, u UseCases) {
+	r.GET("/users/:user_id/settings", mw.Budget(budgetRead), func(c *gin.Context) {
+		if !requireAcceptJSON(c) {
+			return
+		}
+		uid, err := entity.ParseUserID(c.Param("user_id"))
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.UserIDInvalid, "uuid invalid")
+			return
+		}
+		settings, err := u.Sub.GetSettings(c, uid)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		c.JSON(http.StatusOK, buildSettingsDTO(settings))
+	})
+
+	r.PUT("/users/:user_id/settings", mw.Budget(budgetWrite), func(c *gin.Context) {
+		if !requireAcceptJSON(c) || !requireJSONContent(c) {
+			return
+		}
+		uid, err := entity.ParseUserID(c.Param("user_id"))
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.UserIDInvalid, "uuid invalid")
+			return
+		}
+		var input *generated.UserSettings
+		if err := c.ShouldBindJSON(&input); err != nil {
+			jsonErrOf(c, http.StatusBadRequest, err)
+			return
+		}
+		if err := input.Validate(strfmt.Default); err != nil {
+			jsonErrOf(c, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		saved, err := u.Sub.UpdateSettings(c, entity.Settings{
+			UserID:          uid,
+			Currency:        *input.Currency,
+			Locale:          *input.Locale,
+			FirstDayOfWeek:  time.Weekday(*input.FirstDayOfWeek),
+			DateFormat:      *input.DateFormat,
+			Timezone:        input.Timezone,
+			SharePriceStats: input.SharePriceStats,
+			MonthlyGoal:     input.MonthlyGoal,
+		})
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		c.JSON(http.StatusOK, buildSettingsDTO(saved))
+	})
+
+	r.GET("/users/:user_id/goal/progress", mw.Budget(budgetPoll), func(c *gin.Context) {
+		if !requireAcceptJSON(c) {
+			return
+		}
+		uid, err := entity.ParseUserID(c.Param("user_id"))
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.UserIDInvalid, "uuid invalid")
+			return
+		}
+		p, err := u.Sub.GoalProgress(c, uid)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		c.JSON(http.StatusOK, goalProgress{
+			Month:     dates.Format(p.Month),
+			Goal:      p.Goal,
+			Spent:     p.Spent,
+			Projected: p.Projected,
+			Remaining: p.Remaining,
+			Percent:   p.Percent,
+			Over:      p.Over(),
+			Currency:  p.Currency,
+			AsOf:      p.AsOf,
+		})
+	})
+}
+
+// buildSettingsDTO maps domain Settings to generated transport model.
+func buildSettingsDTO(s entity.Settings) generated.UserSettings {
+	currency, locale, layout := s.Currency, s.Locale, s.DateFormat
+	day := int32(s.FirstDayOfWeek)
+	out := generated.UserSettings{
+		Currency:        &currency,
+		Locale:          &locale,
+		FirstDayOfWeek:  &day,
+		DateFormat:      &layout,
+		Timezone:        s.Timezone,
+		SharePriceStats: s.SharePriceStats,
+		MonthlyGoal:     s.MonthlyGoal,
+	}
+	if !s.UpdatedAt.IsZero() {
+		out.UpdatedAt = strfmt.DateTime(s.UpdatedAt.UTC())
+	}
+	return out
+}
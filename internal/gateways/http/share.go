@@ -0,0 +1,224 @@
+package http
+
+import (
+	"embed"
+	"errors"
+	"html/template"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/internal/i18n"
+	"subs_tracker/internal/share"
+	"subs_tracker/internal/theme"
+	"subs_tracker/internal/tracing"
+	"subs_tracker/pkg/dates"
+	"subs_tracker/pkg/money"
+)
+
+//go:embed shared
+var sharedFS embed.FS
+
+var sharedTemplate = template.Must(template.ParseFS(sharedFS, "shared/summary.html"))
+
+// shareRequest is the payload of POST /api/v1/subscriptions/share.
+type shareRequest struct {
+	UserID      string `json:"user_id" binding:"required"`
+	ServiceName string `json:"service_name"`
+	// ExpiresInDays is the lifetime of the link, 0 means a week
+	ExpiresInDays int `json:"expires_in_days"`
+	// TenantID themes the summary, the tenant_id baggage sent by the gateway when omitted
+	TenantID string `json:"tenant_id"`
+}
+
+// shareCreated is the response of POST /api/v1/subscriptions/share.
+type shareCreated struct {
+	Token     string    `json:"token"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// sharedSubscription is one subscription on a shared summary; IDs and the user are left out on purpose.
+type sharedSubscription struct {
+	ServiceName string `json:"service_name"`
+	Cost        int64  `json:"cost"`
+	StartDate   string `json:"start_date"`
+	EndDate     string `json:"end_date,omitempty"`
+}
+
+// sharedSummary is the response of GET /api/v1/shared/{token}.
+type sharedSummary struct {
+	Month string `json:"month"`
+	// MonthLabel is the month spelled out in the response language, e.g. "September 2025"
+	MonthLabel    string               `json:"month_label"`
+	ServiceName   string               `json:"service_name,omitempty"`
+	Currency      string               `json:"currency"`
+	Total         int64                `json:"total"`
+	ExpiresAt     time.Time            `json:"expires_at"`
+	Subscriptions []sharedSubscription `json:"subscriptions"`
+	// Money formats amounts on the page in the owner's currency and locale; JSON keeps plain numbers
+	Money money.Formatter `json:"-"`
+	// Locale translates the texts of the page
+	Locale *i18n.Locale `json:"-"`
+	// Theme is the branding of the tenant the link was created for, nil for the default look
+	Theme *sharedTheme `json:"theme,omitempty"`
+}
+
+// sharedTheme is the tenant branding of a shared summary, for clients rendering it themselves.
+type sharedTheme struct {
+	LogoURL string `json:"logo_url,omitempty"`
+	Color   string `json:"color,omitempty"`
+	Footer  string `json:"footer,omitempty"`
+}
+
+// setupShares registers the management of read-only public links to a user's monthly summary.
+func setupShares(r *gin.RouterGroup, u UseCases, dp *dates.Parser) {
+	r.POST("/subscriptions/share", mw.Budget(budgetWrite), func(c *gin.Context) {
+		if !requireAcceptJSON(c) || !requireJSONContent(c) || !requireShares(c, u) {
+			return
+		}
+		var req shareRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			jsonErrOf(c, http.StatusBadRequest, err)
+			return
+		}
+		uid, err := entity.ParseUserID(req.UserID)
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.UserIDInvalid, "uuid invalid")
+			return
+		}
+		if req.ExpiresInDays < 0 {
+			jsonErr(c, http.StatusUnprocessableEntity, "invalid expires_in_days")
+			return
+		}
+		tenant := strings.TrimSpace(req.TenantID)
+		if tenant == "" {
+			tenant = tracing.TenantID(c.Request.Context())
+		}
+		if tenant != "" && !theme.ValidTenantID(tenant) {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.ThemeInvalid, "invalid tenant_id")
+			return
+		}
+
+		token, link, err := u.Shares.Create(c, uid, tenant, req.ServiceName, time.Duration(req.ExpiresInDays)*24*time.Hour)
+		if shareErr(c, err) {
+			return
+		}
+		c.JSON(http.StatusCreated, shareCreated{
+			Token:     token,
+			URL:       path.Join(r.BasePath(), "shared", token),
+			ExpiresAt: link.ExpiresAt,
+		})
+	})
+
+	r.DELETE("/subscriptions/share/:token", mw.Budget(budgetWrite), func(c *gin.Context) {
+		if !requireAcceptJSON(c) || !requireShares(c, u) {
+			return
+		}
+		if shareErr(c, u.Shares.Revoke(c, c.Param("token"))) {
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
+
+// setupSharedPage registers the page behind a share link. It is opened by people without any access to the API,
+// so it is registered outside the token scope and answers browsers with HTML.
+func setupSharedPage(r *gin.RouterGroup, u UseCases, dp *dates.Parser) {
+	r.GET("/shared/:token", mw.Budget(budgetReport), func(c *gin.Context) {
+		html := strings.Contains(c.GetHeader("Accept"), "text/html")
+		if !html && !requireAcceptJSON(c) || !requireShares(c, u) {
+			return
+		}
+		c.Header("Cache-Control", "no-store")
+		c.Header("Referrer-Policy", "no-referrer")
+		c.Header("X-Robots-Tag", "noindex")
+
+		link, err := u.Shares.Resolve(c, c.Param("token"))
+		if shareErr(c, err) {
+			return
+		}
+		settings, err := u.Sub.GetSettings(c, link.UserID)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		month := settings.MonthOf(u.Sub.Now())
+		if v := strings.TrimSpace(c.Query("month")); v != "" {
+			if month, err = dp.Parse(v); err != nil {
+				jsonErrCode(c, http.StatusUnprocessableEntity, errcode.DateInvalid, dateErrMsg("invalid month", err))
+				return
+			}
+		}
+		cal, err := u.Sub.Calendar(c, link.UserID, month)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+
+		loc := responseLocale(c)
+		out := sharedSummary{
+			Month:         dates.Format(cal.Month),
+			MonthLabel:    loc.Month(cal.Month),
+			ServiceName:   link.ServiceName,
+			Currency:      cal.Settings.Currency,
+			ExpiresAt:     link.ExpiresAt,
+			Subscriptions: []sharedSubscription{},
+			Money:         money.New(cal.Settings.Currency, cal.Settings.Locale),
+			Locale:        loc,
+		}
+		if th := u.Themes.ForReport(c, link.TenantID); !th.Default() {
+			out.Theme = &sharedTheme{LogoURL: th.LogoURL, Color: th.Color, Footer: th.Footer}
+		}
+		for _, ev := range cal.Events {
+			s := ev.Subscription
+			if link.ServiceName != "" && !strings.EqualFold(s.ServiceName, link.ServiceName) {
+				continue
+			}
+			out.Subscriptions = append(out.Subscriptions, sharedSubscription{
+				ServiceName: s.ServiceName,
+				Cost:        s.Cost,
+				StartDate:   dates.Format(s.DateFrom),
+				EndDate:     dates.FormatPtr(s.DateTo),
+			})
+			out.Total += s.Cost
+		}
+		if html {
+			c.Header("Content-Type", "text/html; charset=utf-8")
+			c.Status(http.StatusOK)
+			_ = sharedTemplate.Execute(c.Writer, out)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+}
+
+// requireShares answers 403 when share links are not configured.
+func requireShares(c *gin.Context, u UseCases) bool {
+	if u.Shares == nil {
+		jsonErr(c, http.StatusForbidden, "sharing is disabled")
+		return false
+	}
+	return true
+}
+
+// shareErr maps share link errors to HTTP responses; returns true if handled.
+func shareErr(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, share.ErrNotFound):
+		jsonErrCode(c, http.StatusNotFound, errcode.ShareNotFound, "not found")
+	case errors.Is(err, share.ErrGone):
+		jsonErrOf(c, http.StatusGone, err)
+	case errors.Is(err, share.ErrInvalidTTL):
+		jsonErrOf(c, http.StatusUnprocessableEntity, err)
+	default:
+		return handleUsecaseErr(c, err)
+	}
+	return true
+}
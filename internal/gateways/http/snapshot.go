@@ -0,0 +1,109 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/internal/snapshot"
+	"subs_tracker/internal/usecase"
+)
+
+// snapshotRestored is the response of POST /api/v1/users/{user_id}/snapshot.
+type snapshotRestored struct {
+	UserID        string `json:"user_id"`
+	SourceUserID  string `json:"source_user_id"`
+	Subscriptions int    `json:"subscriptions"`
+	Settings      bool   `json:"settings"`
+}
+
+// setupSnapshots registers export and restore of a user's signed data archive.
+func setupSnapshots(r *gin.RouterGroup, u UseCases) {
+	r.GET("/users/:user_id/snapshot", mw.Budget(budgetImport), func(c *gin.Context) {
+		uid, ok := snapshotUser(c, u)
+		if !ok {
+			return
+		}
+		data, err := u.Sub.ExportUser(c, uid)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		raw, err := u.Snapshots.Seal(uid, data, u.Sub.Now())
+		if err != nil {
+			jsonErr(c, http.StatusInternalServerError, "internal error")
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="subs_tracker-%s.json"`, uid))
+		c.Data(http.StatusOK, "application/json; charset=utf-8", raw)
+	})
+
+	r.POST("/users/:user_id/snapshot", mw.Budget(budgetImport), func(c *gin.Context) {
+		uid, ok := snapshotUser(c, u)
+		if !ok {
+			return
+		}
+		body, err := statementBody(c)
+		if err != nil {
+			jsonErrOf(c, http.StatusBadRequest, err)
+			return
+		}
+		defer body.Close()
+		raw, err := io.ReadAll(body)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			jsonErr(c, http.StatusRequestEntityTooLarge, "snapshot too large")
+			return
+		}
+		if err != nil {
+			jsonErrOf(c, http.StatusBadRequest, err)
+			return
+		}
+
+		source, data, err := u.Snapshots.Open(raw)
+		switch {
+		case errors.Is(err, snapshot.ErrMalformed):
+			jsonErrOf(c, http.StatusBadRequest, err)
+			return
+		case err != nil:
+			jsonErrOf(c, http.StatusUnprocessableEntity, err)
+			return
+		}
+		restored, err := u.Sub.RestoreUser(c, uid, data)
+		if errors.Is(err, usecase.ErrUserNotEmpty) {
+			jsonErrOf(c, http.StatusConflict, err)
+			return
+		}
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		c.JSON(http.StatusCreated, snapshotRestored{
+			UserID:        uid.String(),
+			SourceUserID:  source.String(),
+			Subscriptions: len(restored.Subscriptions),
+			Settings:      restored.Settings != nil,
+		})
+	})
+}
+
+// snapshotUser checks that snapshots are on and parses the path user; it responds itself when not ok.
+func snapshotUser(c *gin.Context, u UseCases) (entity.UserID, bool) {
+	if !requireAcceptJSON(c) {
+		return entity.UserID{}, false
+	}
+	if u.Snapshots == nil {
+		jsonErr(c, http.StatusForbidden, "snapshots are disabled")
+		return entity.UserID{}, false
+	}
+	uid, err := entity.ParseUserID(c.Param("user_id"))
+	if err != nil {
+		jsonErrCode(c, http.StatusUnprocessableEntity, errcode.UserIDInvalid, "uuid invalid")
+		return entity.UserID{}, false
+	}
+	return uid, true
+}
@@ -0,0 +1,62 @@
+package http
+
+import (
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/web"
+)
+
+// spaReserved are path prefixes owned by the backend; unknown paths under them keep the plain 404.
+var spaReserved = []string{"/api", "/admin", "/metrics", "/ping", "/readyz", "/status"}
+
+// spaFS returns the frontend to serve: dir when set, otherwise the build embedded with -tags spa.
+func spaFS(dir string) fs.FS {
+	if dir != "" {
+		return os.DirFS(dir)
+	}
+	return web.Dist()
+}
+
+// setupSPA serves the frontend for every GET route the API does not handle: existing files as is,
+// other paths without an extension get index.html so history-mode client routes survive a reload.
+// Nothing is registered when fsys has no index.html.
+func setupSPA(r *gin.Engine, fsys fs.FS) {
+	if fsys == nil {
+		return
+	}
+	index, err := fs.ReadFile(fsys, "index.html")
+	if err != nil {
+		return
+	}
+	files := http.FileServerFS(fsys)
+
+	r.NoRoute(func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			return
+		}
+		p := path.Clean("/" + c.Request.URL.Path)
+		for _, prefix := range spaReserved {
+			if p == prefix || strings.HasPrefix(p, prefix+"/") {
+				return
+			}
+		}
+
+		if name := strings.TrimPrefix(p, "/"); name != "" && name != "index.html" {
+			if fi, err := fs.Stat(fsys, name); err == nil && !fi.IsDir() {
+				files.ServeHTTP(c.Writer, c.Request)
+				return
+			}
+			if path.Ext(name) != "" {
+				return
+			}
+		}
+		c.Header("Cache-Control", "no-cache")
+		c.Data(http.StatusOK, "text/html; charset=utf-8", index)
+	})
+}
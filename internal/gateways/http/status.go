@@ -0,0 +1,119 @@
+package http
+
+import (
+	"context"
+	"embed"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/buildinfo"
+	"subs_tracker/internal/concurrency"
+)
+
+// statusCacheTTL is how long the answer of /status is reused; the page is public, so the dependencies are
+// checked at most this often however hard it is polled.
+const statusCacheTTL = 10 * time.Second
+
+//go:embed status
+var statusFS embed.FS
+
+var statusTemplate = template.Must(template.ParseFS(statusFS, "status/status.html"))
+
+// Overall states of /status.
+const (
+	statusOperational = "operational"
+	statusDegraded    = "degraded"
+	statusDown        = "down"
+)
+
+// statusComponent is a dependency on the status page: its name and whether it works, nothing else.
+type statusComponent struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// statusPage is the response of GET /status.
+type statusPage struct {
+	// Status is down when a dependency /readyz requires fails, degraded when only a soft one does
+	Status        string            `json:"status"`
+	Version       string            `json:"version"`
+	Uptime        string            `json:"uptime"`
+	UptimeSeconds int64             `json:"uptime_seconds"`
+	Components    []statusComponent `json:"components"`
+	CheckedAt     time.Time         `json:"checked_at"`
+}
+
+// statusHandler answers uptime monitors and the public status page with the checks of /readyz reduced to a
+// state per dependency: no errors, latencies or details, and no authentication. Browsers get HTML. The checks
+// run at most once per statusCacheTTL; a down service answers 503.
+func statusHandler(checks []HealthCheck) gin.HandlerFunc {
+	version := buildinfo.Get().Version
+	var (
+		mu     sync.Mutex
+		last   statusPage
+		expiry time.Time
+	)
+	return func(c *gin.Context) {
+		mu.Lock()
+		if time.Now().After(expiry) {
+			// a client hanging up must not leave its cancelled checks cached as failures for everyone
+			last = checkStatus(context.WithoutCancel(c.Request.Context()), checks, version)
+			expiry = last.CheckedAt.Add(statusCacheTTL)
+		}
+		page := last
+		mu.Unlock()
+
+		uptime := buildinfo.Uptime()
+		page.Uptime = uptime.Truncate(time.Second).String()
+		page.UptimeSeconds = int64(uptime.Seconds())
+		code := http.StatusOK
+		if page.Status == statusDown {
+			code = http.StatusServiceUnavailable
+		}
+		c.Header("Cache-Control", "no-cache")
+		if strings.Contains(c.GetHeader("Accept"), "text/html") {
+			c.Header("Content-Type", "text/html; charset=utf-8")
+			c.Status(code)
+			_ = statusTemplate.Execute(c.Writer, page)
+			return
+		}
+		c.JSON(code, page)
+	}
+}
+
+// checkStatus runs every check at once, like /readyz, and keeps only whether each one passed.
+func checkStatus(ctx context.Context, checks []HealthCheck, version string) statusPage {
+	page := statusPage{
+		Status:     statusOperational,
+		Version:    version,
+		Components: make([]statusComponent, 0, len(checks)),
+		CheckedAt:  time.Now().UTC(),
+	}
+	results, err := concurrency.Map(ctx, len(checks), checks,
+		func(ctx context.Context, hc HealthCheck) (checkResult, error) { return runCheck(ctx, hc), nil })
+	if err != nil {
+		page.Status = statusDown
+		return page
+	}
+	for i, hc := range checks {
+		state := statusOperational
+		if results[i].Status != "ok" {
+			state = statusDown
+			if hc.Soft {
+				state = statusDegraded
+			}
+			if page.Status != statusDown {
+				page.Status = state
+			}
+		}
+		page.Components = append(page.Components, statusComponent{Name: hc.Name, Status: state})
+	}
+	sort.Slice(page.Components, func(i, j int) bool { return page.Components[i].Name < page.Components[j].Name })
+	return page
+}
@@ -0,0 +1,132 @@
+package http
+
+import (
+	"encoding/hex"
+	"slices"
+	"sync"
+
+	"github.com/go-openapi/strfmt"
+
+	"subs_tracker/internal/enrichment"
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/entity/generated"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/dates"
+)
+
+// maxPooledDTOs - batches grown past it by an unusually large page are left to the GC
+const maxPooledDTOs = 4096
+
+// subDTOBatches - batches of list pages reused between requests
+var subDTOBatches = sync.Pool{New: func() any { return new(subDTOBatch) }}
+
+// subDTOBatch holds the DTOs of a list page together with everything their pointer fields point to. Built
+// one by one with buildSubDTO, every subscription costs the DTO and a copy of each of its pointer fields
+// and strings; here they share a few slices reused through subDTOBatches, and the user IDs and dates are
+// cut from a single string, so a page costs a handful of allocations whatever its size. The DTOs point
+// into the batch and into the subscriptions it was built from: they are valid until release and must not
+// be changed
+type subDTOBatch struct {
+	items    []generated.Subscription
+	ptrs     []*generated.Subscription
+	texts    []subDTOText
+	services []generated.ServiceMeta
+	buf      []byte
+}
+
+// subDTOText - the strings a DTO points to, cut from the page text at the offsets found while writing it
+type subDTOText struct {
+	uid      strfmt.UUID
+	start    string
+	uidEnd   int
+	startEnd int
+	endEnd   int
+}
+
+// newSubDTOBatch builds the DTOs of subs like buildSubDTO, adding the catalog metadata of each service
+// found in catalog; release the batch once the response is written.
+func newSubDTOBatch(subs []*entity.Subscription, ids usecase.IDs, catalog map[string]enrichment.ServiceInfo) *subDTOBatch {
+	b := subDTOBatches.Get().(*subDTOBatch)
+	n := len(subs)
+	b.items = slices.Grow(b.items[:0], n)[:n]
+	b.ptrs = slices.Grow(b.ptrs[:0], n)[:n]
+	if b.ptrs == nil {
+		// an empty page is [], not null
+		b.ptrs = []*generated.Subscription{}
+	}
+	b.texts = slices.Grow(b.texts[:0], n)[:n]
+	b.services = slices.Grow(b.services[:0], n)
+
+	buf := b.buf[:0]
+	for i, s := range subs {
+		t := &b.texts[i]
+		buf = appendUUID(buf, s.UserID)
+		t.uidEnd = len(buf)
+		buf = s.DateFrom.AppendFormat(buf, dates.MonthYear)
+		t.startEnd = len(buf)
+		if s.DateTo != nil {
+			buf = s.DateTo.AppendFormat(buf, dates.MonthYear)
+		}
+		t.endEnd = len(buf)
+	}
+	// one string for the whole page, the fields below are substrings of it
+	text := string(buf)
+	b.buf = buf
+
+	from := 0
+	for i, s := range subs {
+		t := &b.texts[i]
+		t.uid = strfmt.UUID(text[from:t.uidEnd])
+		t.start = text[t.uidEnd:t.startEnd]
+		b.items[i] = generated.Subscription{
+			SubscriptionInput: generated.SubscriptionInput{
+				ServiceName: &s.ServiceName,
+				Cost:        &s.Cost,
+				UserID:      &t.uid,
+				StartDate:   &t.start,
+				EndDate:     text[t.startEnd:t.endEnd],
+				Free:        s.Free(),
+			},
+			SubscriptionID: subscriptionID(s, ids),
+			SubscriptionTimestamps: generated.SubscriptionTimestamps{
+				CreatedAt: strfmt.DateTime(s.CreatedAt.UTC()),
+				UpdatedAt: strfmt.DateTime(s.UpdatedAt.UTC()),
+			},
+		}
+		if info, ok := catalog[s.ServiceName]; ok {
+			b.services = append(b.services, generated.ServiceMeta{Domain: info.Domain, Logo: info.Logo, Category: info.Category})
+			b.items[i].Service = &b.services[len(b.services)-1]
+		}
+		b.ptrs[i] = &b.items[i]
+		from = t.endEnd
+	}
+	return b
+}
+
+// release returns the batch for reuse; its DTOs must no longer be used.
+func (b *subDTOBatch) release() {
+	if cap(b.items) > maxPooledDTOs {
+		return
+	}
+	// drop the references to the subscriptions and the page text
+	clear(b.items)
+	clear(b.ptrs)
+	clear(b.texts)
+	clear(b.services)
+	subDTOBatches.Put(b)
+}
+
+// appendUUID appends the canonical text of the user ID, as uuid.UUID.String writes it.
+func appendUUID(dst []byte, id entity.UserID) []byte {
+	var text [36]byte
+	hex.Encode(text[0:8], id[0:4])
+	text[8] = '-'
+	hex.Encode(text[9:13], id[4:6])
+	text[13] = '-'
+	hex.Encode(text[14:18], id[6:8])
+	text[18] = '-'
+	hex.Encode(text[19:23], id[8:10])
+	text[23] = '-'
+	hex.Encode(text[24:], id[10:])
+	return append(dst, text[:]...)
+}
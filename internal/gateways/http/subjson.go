@@ -0,0 +1,117 @@
+package http
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+
+	"subs_tracker/internal/entity/generated"
+	"subs_tracker/pkg/jsonenc"
+)
+
+// maxPooledJSON - buffers grown past it by an unusually large page are left to the GC
+const maxPooledJSON = 4 << 20
+
+// jsonBufs - buffers of encoded list pages reused between requests
+var jsonBufs = sync.Pool{New: func() any { return new([]byte) }}
+
+// subscriptionsJSON renders a list of subscriptions as JSON into a pooled buffer. The generated models
+// marshal every subscription as four objects concatenated, then encoding/json validates the result again;
+// this writes the same bytes in one pass, without reflection
+type subscriptionsJSON []*generated.Subscription
+
+// Render encodes the list into the response body.
+func (r subscriptionsJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	buf := jsonBufs.Get().(*[]byte)
+	b := appendSubscriptions((*buf)[:0], r)
+	_, err := w.Write(b)
+	if cap(b) <= maxPooledJSON {
+		*buf = b
+		jsonBufs.Put(buf)
+	}
+	return err
+}
+
+// WriteContentType sets the JSON content type gin uses.
+func (subscriptionsJSON) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+}
+
+// appendSubscriptions appends subs as a JSON array, null for a nil slice as encoding/json does.
+func appendSubscriptions(dst []byte, subs []*generated.Subscription) []byte {
+	if subs == nil {
+		return append(dst, "null"...)
+	}
+	dst = append(dst, '[')
+	for i, s := range subs {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = appendSubscription(dst, s)
+	}
+	return append(dst, ']')
+}
+
+// appendSubscription appends s with the keys and omissions of generated.Subscription.MarshalJSON.
+func appendSubscription(dst []byte, s *generated.Subscription) []byte {
+	if s == nil {
+		return append(dst, "null"...)
+	}
+	dst = append(dst, `{"cost":`...)
+	dst = jsonenc.AppendIntPtr(dst, s.Cost)
+	if s.EndDate != "" {
+		dst = append(dst, `,"end_date":`...)
+		dst = jsonenc.AppendString(dst, s.EndDate)
+	}
+	if s.Free {
+		dst = append(dst, `,"free":true`...)
+	}
+	dst = append(dst, `,"service_name":`...)
+	dst = jsonenc.AppendStringPtr(dst, s.ServiceName)
+	dst = append(dst, `,"start_date":`...)
+	dst = jsonenc.AppendStringPtr(dst, s.StartDate)
+	dst = append(dst, `,"user_id":`...)
+	if s.UserID == nil {
+		dst = append(dst, "null"...)
+	} else {
+		dst = jsonenc.AppendString(dst, string(*s.UserID))
+	}
+	if s.ID != 0 {
+		dst = append(dst, `,"id":`...)
+		dst = jsonenc.AppendInt(dst, s.ID)
+	}
+	if s.PublicID != "" {
+		dst = append(dst, `,"public_id":`...)
+		dst = jsonenc.AppendString(dst, s.PublicID)
+	}
+	dst = append(dst, `,"created_at":`...)
+	dst = appendDateTime(dst, s.CreatedAt)
+	dst = append(dst, `,"updated_at":`...)
+	dst = appendDateTime(dst, s.UpdatedAt)
+	if m := s.Service; m != nil {
+		dst = append(dst, `,"service":{`...)
+		sep := ""
+		for _, kv := range [...]struct{ key, value string }{{"category", m.Category}, {"domain", m.Domain}, {"logo", m.Logo}} {
+			if kv.value == "" {
+				continue
+			}
+			dst = append(dst, sep...)
+			dst = jsonenc.AppendString(dst, kv.key)
+			dst = append(dst, ':')
+			dst = jsonenc.AppendString(dst, kv.value)
+			sep = ","
+		}
+		dst = append(dst, '}')
+	}
+	return append(dst, '}')
+}
+
+// appendDateTime appends t the way strfmt.DateTime.MarshalJSON writes it.
+func appendDateTime(dst []byte, t strfmt.DateTime) []byte {
+	dst = append(dst, '"')
+	dst = strfmt.NormalizeTimeForMarshal(time.Time(t)).AppendFormat(dst, strfmt.MarshalFormat)
+	return append(dst, '"')
+}
@@ -0,0 +1,109 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/internal/theme"
+)
+
+// themeInput is the payload of PUT /api/v1/admin/tenants/{tenant_id}/theme; omitted fields keep the default look.
+type themeInput struct {
+	LogoURL string `json:"logo_url"`
+	Color   string `json:"color"`
+	Footer  string `json:"footer"`
+}
+
+// themeDTO is a tenant theme in admin responses.
+type themeDTO struct {
+	TenantID  string    `json:"tenant_id"`
+	LogoURL   string    `json:"logo_url"`
+	Color     string    `json:"color"`
+	Footer    string    `json:"footer"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// setupThemes registers the admin endpoints managing the branding of tenants' shared summaries.
+func setupThemes(r *gin.RouterGroup, tokens *mw.Tokens, u UseCases) {
+	r.GET("/tenants/:tenant_id/theme", mw.AdminToken(tokens), func(c *gin.Context) {
+		if !requireAcceptJSON(c) || !requireThemes(c, u) {
+			return
+		}
+		th, err := u.Themes.Get(c, c.Param("tenant_id"))
+		if themeErr(c, err) {
+			return
+		}
+		c.JSON(http.StatusOK, buildThemeDTO(th))
+	})
+
+	r.PUT("/tenants/:tenant_id/theme", mw.AdminToken(tokens), func(c *gin.Context) {
+		if !requireAcceptJSON(c) || !requireJSONContent(c) || !requireThemes(c, u) {
+			return
+		}
+		var in themeInput
+		if err := c.ShouldBindJSON(&in); err != nil {
+			jsonErrOf(c, http.StatusBadRequest, err)
+			return
+		}
+		th, err := u.Themes.Save(c, theme.Theme{
+			TenantID: c.Param("tenant_id"),
+			LogoURL:  in.LogoURL,
+			Color:    in.Color,
+			Footer:   in.Footer,
+		})
+		if themeErr(c, err) {
+			return
+		}
+		c.JSON(http.StatusOK, buildThemeDTO(th))
+	})
+
+	r.DELETE("/tenants/:tenant_id/theme", mw.AdminToken(tokens), func(c *gin.Context) {
+		if !requireAcceptJSON(c) || !requireThemes(c, u) {
+			return
+		}
+		if themeErr(c, u.Themes.Delete(c, c.Param("tenant_id"))) {
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
+
+// buildThemeDTO maps a tenant theme to its admin representation.
+func buildThemeDTO(th theme.Theme) themeDTO {
+	return themeDTO{
+		TenantID:  th.TenantID,
+		LogoURL:   th.LogoURL,
+		Color:     th.Color,
+		Footer:    th.Footer,
+		UpdatedAt: th.UpdatedAt,
+	}
+}
+
+// requireThemes answers 403 when tenant themes are not configured.
+func requireThemes(c *gin.Context, u UseCases) bool {
+	if u.Themes == nil {
+		jsonErr(c, http.StatusForbidden, "theming is disabled")
+		return false
+	}
+	return true
+}
+
+// themeErr maps theme errors to HTTP responses; returns true if handled.
+func themeErr(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, theme.ErrNotFound):
+		jsonErrCode(c, http.StatusNotFound, errcode.ThemeNotFound, "not found")
+	case errors.Is(err, theme.ErrInvalid):
+		jsonErrOf(c, http.StatusUnprocessableEntity, err)
+	default:
+		return handleUsecaseErr(c, err)
+	}
+	return true
+}
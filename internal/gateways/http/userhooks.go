@@ -0,0 +1,150 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/internal/userhooks"
+	"subs_tracker/internal/webhooks"
+)
+
+// userHookRequest is the payload of PUT /api/v1/users/{user_id}/webhook.
+type userHookRequest struct {
+	URL string `json:"url" binding:"required"`
+	// Format is envelope (the default) or simple, as for WEBHOOK_FORMAT
+	Format string `json:"format"`
+}
+
+// userHook is the response of the /api/v1/users/{user_id}/webhook routes.
+type userHook struct {
+	UserID string `json:"user_id"`
+	URL    string `json:"url"`
+	Format string `json:"format"`
+	// Secret signs X-Webhook-Signature; it is only returned when the hook is registered
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// setupUserHooks registers the webhook a user keeps for their own automations. It receives the events of
+// that user's subscriptions only, up to WEBHOOK_USER_LIMIT deliveries an hour.
+func setupUserHooks(r *gin.RouterGroup, u UseCases) {
+	r.PUT("/users/:user_id/webhook", mw.Budget(budgetWrite), func(c *gin.Context) {
+		if !requireJSONContent(c) {
+			return
+		}
+		uid, ok := deactivationUser(c)
+		if !ok || !requireUserHooks(c, u) {
+			return
+		}
+		var req userHookRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			jsonErrOf(c, http.StatusBadRequest, err)
+			return
+		}
+		format, err := webhooks.ParseFormat(req.Format)
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.UserHookInvalid, err.Error())
+			return
+		}
+
+		hook, err := u.UserHooks.Register(c, uid, req.URL, format)
+		if userHookErr(c, err) {
+			return
+		}
+		out := buildUserHook(hook)
+		out.Secret = hook.Secret
+		c.JSON(http.StatusOK, out)
+	})
+
+	r.GET("/users/:user_id/webhook", mw.Budget(budgetRead), func(c *gin.Context) {
+		uid, ok := deactivationUser(c)
+		if !ok || !requireUserHooks(c, u) {
+			return
+		}
+		hook, err := u.UserHooks.Get(c, uid)
+		if userHookErr(c, err) {
+			return
+		}
+		c.JSON(http.StatusOK, buildUserHook(hook))
+	})
+
+	r.DELETE("/users/:user_id/webhook", mw.Budget(budgetWrite), func(c *gin.Context) {
+		uid, ok := deactivationUser(c)
+		if !ok || !requireUserHooks(c, u) {
+			return
+		}
+		if userHookErr(c, u.UserHooks.Remove(c, uid)) {
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	// the sample event of /admin/webhooks/test, signed with the secret of the hook, so the user can wire up and
+	// debug their receiver without touching real subscriptions; 502 reports an endpoint that did not accept it
+	r.POST("/users/:user_id/webhook/test", mw.Budget(budgetWrite), func(c *gin.Context) {
+		uid, ok := deactivationUser(c)
+		if !ok || !requireUserHooks(c, u) {
+			return
+		}
+		event, err := webhooks.ParseSampleEvent(c.Query("event"))
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.UserHookInvalid, err.Error())
+			return
+		}
+		hook, d, err := u.UserHooks.Test(c, uid, event)
+		if errors.Is(err, userhooks.ErrNotFound) || errors.Is(err, userhooks.ErrLimited) {
+			userHookErr(c, err)
+			return
+		}
+		res := webhookTestResult{
+			Delivered:  err == nil,
+			Event:      string(event),
+			Format:     string(hook.Format),
+			StatusCode: d.StatusCode,
+			DurationMs: d.Duration.Milliseconds(),
+			Payload:    d.Body,
+		}
+		if err != nil {
+			res.Error = err.Error()
+			c.JSON(http.StatusBadGateway, res)
+			return
+		}
+		c.JSON(http.StatusOK, res)
+	})
+}
+
+// buildUserHook maps a hook to the response, without its secret.
+func buildUserHook(h userhooks.Hook) userHook {
+	return userHook{UserID: h.UserID.String(), URL: h.URL, Format: string(h.Format), CreatedAt: h.CreatedAt}
+}
+
+// requireUserHooks answers 403 when user webhooks are not enabled.
+func requireUserHooks(c *gin.Context, u UseCases) bool {
+	if u.UserHooks == nil {
+		jsonErr(c, http.StatusForbidden, "user webhooks are disabled")
+		return false
+	}
+	return true
+}
+
+// userHookErr maps user webhook errors to HTTP responses; returns true if handled.
+func userHookErr(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, userhooks.ErrNotFound):
+		jsonErrCode(c, http.StatusNotFound, errcode.UserHookNotFound, "not found")
+	case errors.Is(err, userhooks.ErrInvalidURL):
+		jsonErrOf(c, http.StatusUnprocessableEntity, err)
+	case errors.Is(err, userhooks.ErrLimited):
+		jsonErrOf(c, http.StatusTooManyRequests, err)
+	default:
+		return handleUsecaseErr(c, err)
+	}
+	return true
+}
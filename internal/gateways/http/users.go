@@ -0,0 +1,83 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/internal/userhooks"
+)
+
+// deletedUser is the response of DELETE /api/v1/users/{user_id}.
+type deletedUser struct {
+	UserID string `json:"user_id"`
+	// Policy is what happened to the subscriptions: block, cascade or anonymize
+	Policy        string `json:"policy"`
+	Subscriptions int64  `json:"subscriptions"`
+	ShareLinks    int64  `json:"share_links"`
+}
+
+// setupUsers registers deleting a user. What happens to their subscriptions is set by USER_DELETE_POLICY, not
+// by the caller.
+func setupUsers(r *gin.RouterGroup, u UseCases) {
+	r.DELETE("/users/:user_id", mw.Budget(budgetWrite), func(c *gin.Context) {
+		uid, ok := deactivationUser(c)
+		if !ok {
+			return
+		}
+		deleted, err := u.Sub.DeleteUser(c, uid, c.ClientIP())
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		out := deletedUser{UserID: uid.String(), Policy: string(deleted.Policy), Subscriptions: deleted.Subscriptions}
+		// links, the webhook and the data kept next to the subscriptions go after the user is gone, so a retry after a failure
+		// here finds nothing left to delete and removes them again
+		if u.Shares != nil {
+			if out.ShareLinks, err = u.Shares.RevokeIssuedBefore(c, uid, time.Time{}, c.ClientIP()); err != nil {
+				handleUsecaseErr(c, err)
+				return
+			}
+		}
+		if u.UserHooks != nil {
+			if err := u.UserHooks.Remove(c, uid); err != nil && !errors.Is(err, userhooks.ErrNotFound) {
+				handleUsecaseErr(c, err)
+				return
+			}
+		}
+		forget := []func(context.Context, entity.UserID) error{}
+		if u.Activity != nil {
+			forget = append(forget, u.Activity.Forget)
+		}
+		if u.Widgets != nil {
+			forget = append(forget, u.Widgets.Forget)
+		}
+		if u.Quarantine != nil {
+			forget = append(forget, u.Quarantine.Forget)
+		}
+		if u.PriceReviews != nil {
+			forget = append(forget, u.PriceReviews.Forget)
+		}
+		if u.Reconcile != nil {
+			forget = append(forget, u.Reconcile.Forget)
+		}
+		if u.Archive != nil {
+			forget = append(forget, u.Archive.Forget)
+		}
+		if u.ReadModel != nil {
+			// the user has no subscriptions left, so the refresh leaves no changes of theirs
+			forget = append(forget, u.ReadModel.RefreshUser)
+		}
+		for _, f := range forget {
+			if err := f(c, uid); err != nil {
+				handleUsecaseErr(c, err)
+				return
+			}
+		}
+		c.JSON(http.StatusOK, out)
+	})
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-openapi/swag"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/entity/generated"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/usecase"
+)
+
+// warningDTO is a warning of a create or update response.
+type warningDTO struct {
+	Code    errcode.Code `json:"code"`
+	Message string       `json:"message"`
+	// SubscriptionID is left out for SubscriptionPublicID under a public ID strategy
+	SubscriptionID       int64  `json:"subscription_id,omitempty"`
+	SubscriptionPublicID string `json:"subscription_public_id,omitempty"`
+}
+
+// writtenSubDTO is the response of create and update: the subscription and, when there are any, the warnings
+// about it.
+type writtenSubDTO struct {
+	generated.Subscription
+	Warnings []warningDTO
+}
+
+// MarshalJSON appends "warnings" to the subscription, whose own MarshalJSON would otherwise be promoted.
+func (d writtenSubDTO) MarshalJSON() ([]byte, error) {
+	sub, err := d.Subscription.MarshalJSON()
+	if err != nil || len(d.Warnings) == 0 {
+		return sub, err
+	}
+	warnings, err := json.Marshal(struct {
+		Warnings []warningDTO `json:"warnings"`
+	}{d.Warnings})
+	if err != nil {
+		return nil, err
+	}
+	return swag.ConcatJSON(sub, warnings), nil
+}
+
+// writtenSub builds the response of a saved subscription. The write is done, so warnings that cannot be
+// worked out are left out of the response and only logged.
+func writtenSub(c *gin.Context, u *usecase.Subscription, s *entity.Subscription) writtenSubDTO {
+	out := writtenSubDTO{Subscription: buildSubDTO(s, u.IDs())}
+	warnings, err := u.Warnings(c, s)
+	if err != nil {
+		_ = c.Error(err)
+		return out
+	}
+	out.Warnings = warningDTOs(c, warnings)
+	return out
+}
+
+// warningDTOs maps warnings to their representation, in the language of the request.
+func warningDTOs(c *gin.Context, warnings []usecase.Warning) []warningDTO {
+	var out []warningDTO
+	for _, w := range warnings {
+		dto := warningDTO{Code: w.Code, Message: translate(c, w.Message), SubscriptionPublicID: w.PublicID}
+		if w.PublicID == "" {
+			dto.SubscriptionID = w.SubscriptionID
+		}
+		out = append(out, dto)
+	}
+	return out
+}
+
+// dryRunParam reads ?dry_run=; answers 422 and returns false when it is not a boolean.
+func dryRunParam(c *gin.Context) (dryRun, ok bool) {
+	v := strings.TrimSpace(c.Query("dry_run"))
+	if v == "" {
+		return false, true
+	}
+	dryRun, err := strconv.ParseBool(v)
+	if err != nil {
+		jsonErr(c, http.StatusUnprocessableEntity, "invalid dry_run")
+		return false, false
+	}
+	return dryRun, true
+}
+
+// previewedSub builds the response of a dry run: the subscription as it would be stored and the warnings it
+// would get. Nothing is written, so unlike writtenSub it fails when the warnings cannot be worked out; a new
+// subscription has no ID yet.
+func previewedSub(c *gin.Context, u *usecase.Subscription, s *entity.Subscription) (writtenSubDTO, error) {
+	out := writtenSubDTO{Subscription: buildSubDTO(s, u.IDs())}
+	if s.ID == 0 {
+		out.SubscriptionID = generated.SubscriptionID{}
+	}
+	warnings, err := u.Warnings(c, s)
+	if err != nil {
+		return writtenSubDTO{}, err
+	}
+	out.Warnings = warningDTOs(c, warnings)
+	return out, nil
+}
@@ -0,0 +1,132 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/internal/widget"
+	"subs_tracker/pkg/dates"
+)
+
+// widgetPath is where the public widget routes live; they answer CORS themselves, see mw.Widget.
+const widgetPath = "/api/v1/widget/"
+
+// widgetRequest is the payload of POST /api/v1/subscriptions/widgets.
+type widgetRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+	// Origins are the sites the widget is embedded on, e.g. "https://example.com"
+	Origins []string `json:"origins" binding:"required"`
+}
+
+// widgetCreated is the response of POST /api/v1/subscriptions/widgets.
+type widgetCreated struct {
+	Token     string    `json:"token"`
+	Origins   []string  `json:"origins"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// widgetSpend is the response of GET /api/v1/widget/monthly-spend.
+type widgetSpend struct {
+	Month string `json:"month"`
+	// MonthLabel is the month spelled out in the response language, e.g. "September 2025"
+	MonthLabel string `json:"month_label"`
+	Currency   string `json:"currency"`
+	Total      int64  `json:"total"`
+}
+
+// setupWidgets registers the management of widget tokens on the API.
+func setupWidgets(r *gin.RouterGroup, u UseCases) {
+	r.POST("/subscriptions/widgets", mw.Budget(budgetWrite), func(c *gin.Context) {
+		if !requireAcceptJSON(c) || !requireJSONContent(c) || !requireWidgets(c, u) {
+			return
+		}
+		var req widgetRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			jsonErrOf(c, http.StatusBadRequest, err)
+			return
+		}
+		uid, err := entity.ParseUserID(req.UserID)
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.UserIDInvalid, "uuid invalid")
+			return
+		}
+
+		token, tok, err := u.Widgets.Create(c, uid, req.Origins)
+		if widgetErr(c, err) {
+			return
+		}
+		c.JSON(http.StatusCreated, widgetCreated{Token: token, Origins: tok.Origins, CreatedAt: tok.CreatedAt})
+	})
+
+	r.DELETE("/subscriptions/widgets/:token", mw.Budget(budgetWrite), func(c *gin.Context) {
+		if !requireAcceptJSON(c) || !requireWidgets(c, u) {
+			return
+		}
+		if widgetErr(c, u.Widgets.Revoke(c, c.Param("token"))) {
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
+
+// setupWidget registers the public routes called by the embedded widget; r is guarded by mw.Widget.
+func setupWidget(r *gin.RouterGroup, u UseCases) {
+	r.GET("/monthly-spend", mw.Budget(budgetReport), func(c *gin.Context) {
+		if !requireAcceptJSON(c) {
+			return
+		}
+		// the answer depends on the token, shared caches must not reuse it for another site
+		c.Header("Cache-Control", "private, max-age=300")
+
+		tok := mw.WidgetToken(c)
+		settings, err := u.Sub.GetSettings(c, tok.UserID)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		cal, err := u.Sub.Calendar(c, tok.UserID, settings.MonthOf(u.Sub.Now()))
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		out := widgetSpend{
+			Month:      dates.Format(cal.Month),
+			MonthLabel: responseLocale(c).Month(cal.Month),
+			Currency:   cal.Settings.Currency,
+		}
+		for _, ev := range cal.Events {
+			out.Total += ev.Subscription.Cost
+		}
+		c.JSON(http.StatusOK, out)
+	})
+	// preflights are answered by mw.Widget, the route only has to exist
+	r.OPTIONS("/monthly-spend", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+}
+
+// requireWidgets answers 403 when widget tokens are not configured.
+func requireWidgets(c *gin.Context, u UseCases) bool {
+	if u.Widgets == nil {
+		jsonErr(c, http.StatusForbidden, "widgets are disabled")
+		return false
+	}
+	return true
+}
+
+// widgetErr maps widget token errors to HTTP responses; returns true if handled.
+func widgetErr(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, widget.ErrNotFound):
+		jsonErrCode(c, http.StatusNotFound, errcode.WidgetNotFound, "not found")
+	case errors.Is(err, widget.ErrInvalidOrigins):
+		jsonErrOf(c, http.StatusUnprocessableEntity, err)
+	default:
+		return handleUsecaseErr(c, err)
+	}
+	return true
+}
@@ -0,0 +1,131 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/gateways/http/mw"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/dates"
+)
+
+// monthSpend is one point of the month-by-month chart of a year review.
+type monthSpend struct {
+	Month string `json:"month"`
+	// Label is the month spelled out in the response language, with ?labels=true only
+	Label string `json:"label,omitempty"`
+	Total int64  `json:"total"`
+}
+
+// serviceYear is what one service cost over the reviewed year.
+type serviceYear struct {
+	ServiceName string `json:"service_name"`
+	Total       int64  `json:"total"`
+	FirstMonth  string `json:"first_month"`
+	LastMonth   string `json:"last_month"`
+}
+
+// priceHike is a rise of a service's monthly cost from the month before.
+type priceHike struct {
+	ServiceName string `json:"service_name"`
+	Month       string `json:"month"`
+	FromCost    int64  `json:"from_cost"`
+	ToCost      int64  `json:"to_cost"`
+	Delta       int64  `json:"delta"`
+}
+
+// yearReview is the response of GET /api/v1/subscriptions/year-in-review.
+type yearReview struct {
+	Year       int           `json:"year"`
+	Currency   string        `json:"currency"`
+	Total      int64         `json:"total"`
+	Months     []monthSpend  `json:"months"`
+	Services   []serviceYear `json:"services"`
+	PriceHikes []priceHike   `json:"price_hikes"`
+	New        []serviceYear `json:"new"`
+	Cancelled  []serviceYear `json:"cancelled"`
+}
+
+// setupYearReview registers the summary of a user's subscriptions over a calendar year.
+func setupYearReview(r *gin.RouterGroup, u UseCases) {
+	r.GET("/subscriptions/year-in-review", mw.Budget(budgetReport), func(c *gin.Context) {
+		if !requireAcceptJSON(c) {
+			return
+		}
+		uid, err := entity.ParseUserID(strings.TrimSpace(c.Query("user_id")))
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.UserIDInvalid, "uuid invalid")
+			return
+		}
+		year, err := strconv.Atoi(strings.TrimSpace(c.Query("year")))
+		if err != nil {
+			jsonErrCode(c, http.StatusUnprocessableEntity, errcode.PeriodInvalid, "invalid year")
+			return
+		}
+		label, ok := monthLabeler(c)
+		if !ok {
+			return
+		}
+
+		settings, err := u.Sub.GetSettings(c, uid)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		review, err := u.Sub.YearReview(c, uid, year)
+		if handled := handleUsecaseErr(c, err); handled {
+			return
+		}
+		c.JSON(http.StatusOK, buildYearReviewDTO(review, settings.Currency, label))
+	})
+}
+
+// buildYearReviewDTO maps a year review to its response; empty lists are sent as []. A non-nil label spells
+// out the months of the chart.
+func buildYearReviewDTO(r usecase.YearReview, currency string, label func(time.Time) string) yearReview {
+	out := yearReview{
+		Year:       r.Year,
+		Currency:   currency,
+		Total:      r.Total,
+		Months:     make([]monthSpend, 0, len(r.Months)),
+		Services:   serviceYears(r.Services),
+		PriceHikes: make([]priceHike, 0, len(r.PriceHikes)),
+		New:        serviceYears(r.New),
+		Cancelled:  serviceYears(r.Cancelled),
+	}
+	for _, m := range r.Months {
+		point := monthSpend{Month: dates.Format(m.Month), Total: m.Total}
+		if label != nil {
+			point.Label = label(m.Month)
+		}
+		out.Months = append(out.Months, point)
+	}
+	for _, h := range r.PriceHikes {
+		out.PriceHikes = append(out.PriceHikes, priceHike{
+			ServiceName: h.ServiceName,
+			Month:       dates.Format(h.Month),
+			FromCost:    h.FromCost,
+			ToCost:      h.ToCost,
+			Delta:       h.ToCost - h.FromCost,
+		})
+	}
+	return out
+}
+
+func serviceYears(in []usecase.ServiceYear) []serviceYear {
+	out := make([]serviceYear, 0, len(in))
+	for _, s := range in {
+		out = append(out, serviceYear{
+			ServiceName: s.ServiceName,
+			Total:       s.Total,
+			FirstMonth:  dates.Format(s.First),
+			LastMonth:   dates.Format(s.Last),
+		})
+	}
+	return out
+}
@@ -0,0 +1,66 @@
+// Package i18n translates user-facing texts with go-i18n bundles embedded into the binary.
+// Message IDs are the English texts themselves, so untranslated messages are shown as is.
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"strings"
+
+	goi18n "github.com/nicksnyder/go-i18n/v2/i18n"
+	"golang.org/x/text/language"
+)
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// separators - composite error messages are translated part by part
+var separators = []string{": ", "\n"}
+
+// Translator - message bundles of all supported languages, English being the default
+type Translator struct {
+	bundle  *goi18n.Bundle
+	matcher language.Matcher
+}
+
+// NewTranslator loads the embedded English and Russian bundles
+func NewTranslator() (*Translator, error) {
+	bundle := goi18n.NewBundle(language.English)
+	for _, name := range []string{"active.en.json", "active.ru.json"} {
+		if _, err := bundle.LoadMessageFileFS(locales, "locales/"+name); err != nil {
+			return nil, fmt.Errorf("load %s: %w", name, err)
+		}
+	}
+	return &Translator{bundle: bundle, matcher: language.NewMatcher(bundle.LanguageTags())}, nil
+}
+
+// Match picks the best supported language for Accept-Language values or plain tags, English if none fits
+func (t *Translator) Match(langs ...string) language.Tag {
+	var prefs []language.Tag
+	for _, l := range langs {
+		if tags, _, err := language.ParseAcceptLanguage(l); err == nil {
+			prefs = append(prefs, tags...)
+		}
+	}
+	_, i, _ := t.matcher.Match(prefs...)
+	return t.bundle.LanguageTags()[i]
+}
+
+// Translate returns msg in the language. Parts without a translation stay as they are
+func (t *Translator) Translate(msg string, lang language.Tag) string {
+	return t.translate(goi18n.NewLocalizer(t.bundle, lang.String()), msg, 0)
+}
+
+func (t *Translator) translate(l *goi18n.Localizer, msg string, sep int) string {
+	if out, err := l.Localize(&goi18n.LocalizeConfig{MessageID: msg}); err == nil {
+		return out
+	}
+	if sep == len(separators) {
+		return msg
+	}
+	parts := strings.Split(msg, separators[sep])
+	for i, p := range parts {
+		parts[i] = t.translate(l, p, sep+1)
+	}
+	return strings.Join(parts, separators[sep])
+}
@@ -0,0 +1,82 @@
+package i18n
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+)
+
+func TestTranslator(t *testing.T) {
+	tr, err := NewTranslator()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		langs []string
+		msg   string
+		tag   language.Tag
+		want  string
+	}{
+		{name: "default english", msg: "not found", tag: language.English, want: "not found"},
+		{name: "russian", langs: []string{"ru-RU,ru;q=0.9,en;q=0.8"}, msg: "not found", tag: language.Russian, want: "не найдено"},
+		{name: "unsupported falls back", langs: []string{"de-DE"}, msg: "not found", tag: language.English, want: "not found"},
+		{name: "settings locale after empty header", langs: []string{"", "ru"}, msg: "invalid id", tag: language.Russian, want: "некорректный id"},
+		{name: "wrapped parts", langs: []string{"ru"}, msg: "invalid subscription: cost must be > 0", tag: language.Russian, want: "некорректная подписка: стоимость должна быть больше 0"},
+		{name: "unknown part kept", langs: []string{"ru"}, msg: "invalid statement: line 3: invalid date \"x\"", tag: language.Russian, want: "некорректная выписка: line 3: invalid date \"x\""},
+		{name: "joined errors", langs: []string{"ru"}, msg: "invalid settings\ncurrency must be an ISO 4217 code", tag: language.Russian, want: "некорректные настройки\nвалюта должна быть кодом ISO 4217"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag := tr.Match(tt.langs...)
+			assert.Equal(t, tt.tag, tag)
+			assert.Equal(t, tt.want, tr.Translate(tt.msg, tag))
+		})
+	}
+}
+
+// Every English message must have a Russian translation
+func TestBundlesComplete(t *testing.T) {
+	read := func(name string) map[string]string {
+		raw, err := locales.ReadFile("locales/" + name)
+		require.NoError(t, err)
+		var m map[string]string
+		require.NoError(t, json.Unmarshal(raw, &m))
+		return m
+	}
+	en, ru := read("active.en.json"), read("active.ru.json")
+	for id := range en {
+		assert.Contains(t, ru, id)
+	}
+	assert.Len(t, ru, len(en))
+}
+
+func TestLocale(t *testing.T) {
+	tr, err := NewTranslator()
+	require.NoError(t, err)
+	sep := time.Date(2025, time.September, 1, 0, 0, 0, 0, time.UTC)
+
+	calls := 0
+	loc := tr.Negotiate(func() []string {
+		calls++
+		return []string{"ru-RU"}
+	})
+	ctx := NewContext(context.Background(), loc)
+	assert.Same(t, loc, FromContext(ctx))
+	assert.Equal(t, 0, calls, "negotiated on first use")
+	assert.Equal(t, "не найдено", FromContext(ctx).Translate("not found"))
+	assert.Equal(t, "сентябрь 2025", FromContext(ctx).Month(sep))
+	assert.Equal(t, "Сентябрь 2025", FromContext(ctx).MonthLabel(sep))
+	assert.Equal(t, 1, calls)
+
+	none := FromContext(context.Background())
+	assert.Nil(t, none)
+	assert.Equal(t, language.English, none.Tag())
+	assert.Equal(t, "not found", none.Translate("not found"))
+	assert.Equal(t, "September 2025", none.Month(sep))
+	assert.Equal(t, "September 2025", none.MonthLabel(sep))
+}
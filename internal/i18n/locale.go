@@ -0,0 +1,68 @@
+package i18n
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/text/language"
+
+	"subs_tracker/pkg/dates"
+)
+
+type localeKey struct{}
+
+// Locale - the language of one request's responses. It is negotiated on first use, so requests that never
+// show text do not pay for reading preferences. A nil Locale is English and leaves messages as they are
+type Locale struct {
+	tr    *Translator
+	prefs func() []string
+	once  sync.Once
+	tag   language.Tag
+}
+
+// Negotiate returns a Locale matching the Accept-Language values or tags returned by prefs, see Match
+func (t *Translator) Negotiate(prefs func() []string) *Locale {
+	return &Locale{tr: t, prefs: prefs}
+}
+
+// Tag returns the negotiated language
+func (l *Locale) Tag() language.Tag {
+	if l == nil {
+		return language.English
+	}
+	l.once.Do(func() {
+		l.tag = l.tr.Match(l.prefs()...)
+	})
+	return l.tag
+}
+
+// Translate returns msg in the negotiated language
+func (l *Locale) Translate(msg string) string {
+	if l == nil {
+		return msg
+	}
+	return l.tr.Translate(msg, l.Tag())
+}
+
+// Month returns the month of t with its year spelled out in the negotiated language, e.g. "September 2025"
+func (l *Locale) Month(t time.Time) string {
+	return dates.MonthName(t.Month(), l.Tag().String()) + " " + t.Format("2006")
+}
+
+// MonthLabel returns the month of t as a standalone label in the negotiated language, e.g. "Сентябрь 2025",
+// see dates.MonthLabel
+func (l *Locale) MonthLabel(t time.Time) string {
+	return dates.MonthLabel(t, l.Tag().String())
+}
+
+// NewContext returns ctx carrying the locale
+func NewContext(ctx context.Context, l *Locale) context.Context {
+	return context.WithValue(ctx, localeKey{}, l)
+}
+
+// FromContext returns the locale of ctx, nil (English) when there is none
+func FromContext(ctx context.Context) *Locale {
+	l, _ := ctx.Value(localeKey{}).(*Locale)
+	return l
+}
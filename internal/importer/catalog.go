@@ -0,0 +1,22 @@
+package importer
+
+// DefaultCatalog - well-known subscription services recognised in statements out of the box
+var DefaultCatalog = []string{
+	"Apple Music",
+	"Boosty",
+	"Google One",
+	"iCloud",
+	"ivi",
+	"Kinopoisk",
+	"Netflix",
+	"Okko",
+	"Premier",
+	"Skillbox",
+	"Spotify",
+	"Start",
+	"Telegram Premium",
+	"VK Music",
+	"Wink",
+	"Yandex Plus",
+	"YouTube Premium",
+}
@@ -0,0 +1,242 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStatement(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		want    []Transaction
+		wantErr bool
+	}{
+		{
+			name: "comma separated",
+			csv:  "Date,Description,Amount\n2025-07-03,NETFLIX.COM,-999.00\n2025-07-05,Salary,\n",
+			want: []Transaction{{Date: time.Date(2025, 7, 3, 0, 0, 0, 0, time.UTC), Description: "NETFLIX.COM", Amount: 999, Line: 2}},
+		},
+		{
+			name: "semicolon separated russian export",
+			csv:  "\ufeffДата операции;Описание;Сумма операции\n03.07.2025 12:30;YANDEX*PLUS;-1 299,50\n",
+			want: []Transaction{{Date: time.Date(2025, 7, 3, 12, 30, 0, 0, time.UTC), Description: "YANDEX*PLUS", Amount: 1300, Line: 2}},
+		},
+		{name: "missing columns in header", csv: "when,what\n2025-07-03,x\n", wantErr: true},
+		{name: "bad date", csv: "date,merchant,amount\n07/2025,x,1\n", wantErr: true},
+		{name: "bad amount", csv: "date,merchant,amount\n2025-07-03,x,abc\n", wantErr: true},
+		{name: "empty", csv: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := ParseStatement(strings.NewReader(tt.csv))
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidStatement)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMatcher_Match(t *testing.T) {
+	m := NewMatcher(DefaultCatalog)
+	tests := []struct {
+		description string
+		want        string
+		ok          bool
+	}{
+		{"NETFLIX.COM LOS GATOS", "Netflix", true},
+		{"YANDEX*PLUS MOSCOW", "Yandex Plus", true},
+		{"yandexplus", "Yandex Plus", true},
+		{"SPOTIFI AB", "Spotify", true},
+		{"Google *YouTubePremium", "YouTube Premium", true},
+		{"PYATEROCHKA 1234", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got, _, ok := m.Match(tt.description)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPropose(t *testing.T) {
+	day := func(m time.Month, d int) time.Time { return time.Date(2025, m, d, 0, 0, 0, 0, time.UTC) }
+	txs := []Transaction{
+		{Date: day(6, 3), Description: "NETFLIX.COM", Amount: 799},
+		{Date: day(7, 3), Description: "NETFLIX.COM", Amount: 999},
+		{Date: day(7, 10), Description: "SPOTIFY P1234", Amount: 169},
+		{Date: day(7, 11), Description: "PYATEROCHKA", Amount: 540},
+	}
+
+	got := Propose(txs, NewMatcher(DefaultCatalog))
+	require.Len(t, got, 2)
+
+	assert.Equal(t, "Netflix", got[0].ServiceName)
+	assert.Equal(t, int64(999), got[0].Cost)
+	assert.Equal(t, day(6, 1), got[0].StartDate)
+	assert.Equal(t, day(7, 3), got[0].LastCharge)
+	assert.Equal(t, 2, got[0].Charges)
+	assert.True(t, got[0].Recurring)
+
+	assert.Equal(t, "Spotify", got[1].ServiceName)
+	assert.False(t, got[1].Recurring)
+}
+
+func TestRows_Skipped(t *testing.T) {
+	csv := "date,description,amount\n" +
+		"2025-07-03,NETFLIX.COM,-999\n" +
+		"2025-07-04,PYATEROCHKA,-540\n" +
+		"2025-07-05,Salary,\n" +
+		"2025-08-03,NETFLIX.COM,-999\n"
+	txs, rows, err := ParseStatement(strings.NewReader(csv))
+	require.NoError(t, err)
+	proposals := Propose(txs, NewMatcher(DefaultCatalog))
+	require.Len(t, proposals, 1)
+	assert.Equal(t, []int{2, 5}, proposals[0].Lines)
+
+	assert.Equal(t, []string{"date", "description", "amount"}, rows.Header)
+	assert.Equal(t, []Row{
+		{Line: 3, Fields: []string{"2025-07-04", "PYATEROCHKA", "-540"}, Skipped: SkipUnmatched},
+		{Line: 4, Fields: []string{"2025-07-05", "Salary", ""}, Skipped: SkipNoAmount},
+	}, rows.Skipped(proposals))
+}
+
+func TestParseReceipt(t *testing.T) {
+	sent := time.Date(2025, 7, 15, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		from    string
+		body    string
+		want    Receipt
+		wantErr bool
+	}{
+		{
+			name: "netflix",
+			from: "Netflix <info@account.netflix.com>",
+			body: "Your monthly payment\nStandard plan\nTotal: 999,00 ₽\nNext billing date 15 Aug 2025",
+			want: Receipt{ServiceName: "Netflix", Amount: 999, Date: sent},
+		},
+		{
+			name: "forwarded spotify",
+			from: "me@example.com",
+			body: "---------- Forwarded message ---------\nFrom: Spotify <no-reply@spotify.com>\nPremium Individual RUB 169.00",
+			want: Receipt{ServiceName: "Spotify", Amount: 169, Date: sent},
+		},
+		{
+			name: "app store",
+			from: "App Store <no_reply@email.apple.com>",
+			body: "Receipt 15 Jul 2025\nYouTube Premium\nYouTube Premium (Monthly)\nRenews 15 Aug 2025\n1 299,00 ₽",
+			want: Receipt{ServiceName: "YouTube Premium", Amount: 1299, Date: sent},
+		},
+		{name: "app store without monthly item", from: "no_reply@email.apple.com", body: "Your Apple ID was used", wantErr: true},
+		{name: "unknown sender", from: "shop@example.com", body: "Total 100 ₽", wantErr: true},
+		{name: "no amount", from: "info@netflix.com", body: "Your password was changed", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseReceipt(tt.from, "", tt.body, sent)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrUnknownReceipt)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseAppStore(t *testing.T) {
+	day := func(m time.Month, d int) time.Time { return time.Date(2025, m, d, 0, 0, 0, 0, time.UTC) }
+	csv := "Subscription Name,Event Date,Event,Price\n" +
+		"YouTube Premium,2025-04-15,Free Trial,0\n" +
+		"YouTube Premium,2025-05-15,Subscription Start,169.00\n" +
+		"YouTube Premium,2025-06-15,Renewal,199.00\n" +
+		"Bear,2025-03-02,Subscription Start,$1.49\n" +
+		"Bear,2025-04-02,Renewal,$1.49\n" +
+		"Bear,2025-04-20,Cancellation,\n" +
+		"Tried Only,2025-06-01,Free Trial,0\n" +
+		"Tried Only,2025-06-07,Cancellation,\n"
+
+	got, rows, err := ParseAppStore(strings.NewReader(csv))
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+
+	bear := got[0]
+	assert.Equal(t, "Bear", bear.ServiceName)
+	assert.Equal(t, int64(1), bear.Cost)
+	assert.Equal(t, day(3, 1), bear.StartDate)
+	require.NotNil(t, bear.EndDate)
+	assert.Equal(t, day(4, 1), *bear.EndDate)
+	assert.Nil(t, bear.Renews)
+
+	yt := got[1]
+	assert.Equal(t, "YouTube Premium", yt.ServiceName)
+	assert.Equal(t, int64(199), yt.Cost)
+	assert.Equal(t, day(5, 1), yt.StartDate)
+	assert.Equal(t, 2, yt.Charges)
+	assert.True(t, yt.Recurring)
+	require.NotNil(t, yt.TrialStart)
+	assert.Equal(t, day(4, 15), *yt.TrialStart)
+	require.NotNil(t, yt.Renews)
+	assert.Equal(t, day(7, 15), *yt.Renews)
+	assert.Nil(t, yt.EndDate)
+	assert.Equal(t, []int{2, 3, 4}, yt.Lines)
+	assert.Equal(t, []Row{{Line: 8, Fields: []string{"Tried Only", "2025-06-01", "Free Trial", "0"}, Skipped: SkipNeverPaid},
+		{Line: 9, Fields: []string{"Tried Only", "2025-06-07", "Cancellation", ""}, Skipped: SkipNeverPaid}}, rows.Skipped(got))
+
+	_, _, err = ParseAppStore(strings.NewReader("a,b\n1,2\n"))
+	assert.ErrorIs(t, err, ErrInvalidStatement)
+}
+
+func TestParseGooglePlay(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	export := `[
+	  {"subscription": {"doc": {"title": "YouTube Music"}, "state": "ACTIVE",
+	    "startTime": "2025-01-10T08:00:00Z", "freeTrialEndTime": "2025-02-10T08:00:00Z",
+	    "renewalDate": "2025-08-10T08:00:00Z", "pricing": [{"period": "P1M", "price": "169,00 ₽"}]}},
+	  {"subscription": {"doc": {"title": "Google One"}, "state": "EXPIRED",
+	    "startTime": "2024-03-01T00:00:00Z", "expirationDate": "2025-03-01T00:00:00Z",
+	    "pricing": [{"period": "P1Y", "price": "1 690 ₽"}]}},
+	  {"subscription": {"doc": {"title": "Trial Only"}, "state": "EXPIRED",
+	    "startTime": "2025-05-01T00:00:00Z", "freeTrialEndTime": "2025-05-08T00:00:00Z", "expirationDate": "2025-05-08T00:00:00Z",
+	    "pricing": [{"period": "P1M", "price": "99 ₽"}]}}
+	]`
+
+	got, rows, err := ParseGooglePlay(strings.NewReader(export))
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+
+	one := got[0]
+	assert.Equal(t, "Google One", one.ServiceName)
+	assert.Equal(t, int64(141), one.Cost)
+	assert.Equal(t, day(2024, 3, 1), one.StartDate)
+	require.NotNil(t, one.EndDate)
+	assert.Equal(t, day(2025, 2, 1), *one.EndDate)
+
+	music := got[1]
+	assert.Equal(t, "YouTube Music", music.ServiceName)
+	assert.Equal(t, int64(169), music.Cost)
+	assert.Equal(t, day(2025, 2, 1), music.StartDate)
+	require.NotNil(t, music.TrialStart)
+	require.NotNil(t, music.Renews)
+	assert.Equal(t, time.Date(2025, 8, 10, 8, 0, 0, 0, time.UTC), *music.Renews)
+	assert.Nil(t, music.EndDate)
+	assert.Equal(t, 6, music.Charges)
+	assert.Equal(t, []int{1}, music.Lines)
+	if skipped := rows.Skipped(got); assert.Len(t, skipped, 1) {
+		assert.Equal(t, 3, skipped[0].Line)
+		assert.Equal(t, "Trial Only", skipped[0].Fields[0])
+		assert.Equal(t, SkipNeverPaid, skipped[0].Skipped)
+	}
+
+	_, _, err = ParseGooglePlay(strings.NewReader(`{"not": "a list"}`))
+	assert.ErrorIs(t, err, ErrInvalidStatement)
+}
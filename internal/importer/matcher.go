@@ -0,0 +1,191 @@
+package importer
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"subs_tracker/pkg/dates"
+)
+
+// Matcher maps merchant descriptions to service names by exact word match, then by edit distance
+type Matcher struct {
+	services  []string
+	threshold float64
+}
+
+// NewMatcher creates a matcher over the service names and applies options
+func NewMatcher(services []string, options ...func(*Matcher)) *Matcher {
+	m := &Matcher{threshold: 0.8}
+	seen := make(map[string]struct{}, len(services))
+	for _, s := range services {
+		key := normalize(s)
+		if _, dup := seen[key]; dup || key == "" {
+			continue
+		}
+		seen[key] = struct{}{}
+		m.services = append(m.services, s)
+	}
+	for _, o := range options {
+		o(m)
+	}
+	return m
+}
+
+// WithThreshold sets the minimal similarity (0..1] of a fuzzy match
+func WithThreshold(t float64) func(*Matcher) {
+	return func(m *Matcher) {
+		if t > 0 && t <= 1 {
+			m.threshold = t
+		}
+	}
+}
+
+// Match returns the best matching service for a merchant description and its similarity score
+func (m *Matcher) Match(description string) (string, float64, bool) {
+	words := strings.Fields(normalize(description))
+	best, bestScore := "", 0.0
+	for _, svc := range m.services {
+		score := similarity(words, strings.Fields(normalize(svc)))
+		if score > bestScore || (score == bestScore && len(svc) > len(best)) {
+			best, bestScore = svc, score
+		}
+	}
+	if bestScore < m.threshold {
+		return "", bestScore, false
+	}
+	return best, bestScore, true
+}
+
+// similarity compares the service words with every same-sized window of merchant words,
+// also trying the window glued together ("yandexplus" vs "yandex plus")
+func similarity(words, svc []string) float64 {
+	if len(svc) == 0 {
+		return 0
+	}
+	target := strings.Join(svc, "")
+	best := 0.0
+	for size := 1; size <= len(svc)+1; size++ {
+		for i := 0; i+size <= len(words); i++ {
+			candidate := strings.Join(words[i:i+size], "")
+			if candidate == target {
+				return 1
+			}
+			n := max(len([]rune(candidate)), len([]rune(target)))
+			if s := 1 - float64(levenshtein(candidate, target))/float64(n); s > best {
+				best = s
+			}
+		}
+	}
+	return best
+}
+
+// normalize lowercases and turns everything but letters and digits into single spaces
+func normalize(s string) string {
+	var b strings.Builder
+	space := true
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			space = false
+			continue
+		}
+		if !space {
+			b.WriteByte(' ')
+			space = true
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// levenshtein computes the edit distance between a and b over runes
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// Proposal - subscription suggested from matched statement charges
+type Proposal struct {
+	// ServiceName - matched catalog service
+	ServiceName string
+	// Cost - amount of the latest charge
+	Cost int64
+	// StartDate - month of the earliest charge
+	StartDate time.Time
+	// LastCharge - day of the latest charge
+	LastCharge time.Time
+	// Charges - number of matched charges
+	Charges int
+	// Recurring - charges fall into at least two different months
+	Recurring bool
+	// Merchant - statement description of the latest charge
+	Merchant string
+	// Score - similarity of the weakest matched description
+	Score float64
+	// EndDate - last paid month of a cancelled or expired subscription
+	EndDate *time.Time
+	// TrialStart - start of the free trial preceding the first charge
+	TrialStart *time.Time
+	// Renews - next expected charge of an active subscription
+	Renews *time.Time
+	// Lines - rows of the uploaded file the proposal was made of
+	Lines []int
+}
+
+// Propose groups matched transactions per service, ordered by service name
+func Propose(txs []Transaction, m *Matcher) []Proposal {
+	groups := make(map[string]*Proposal)
+	months := make(map[string]map[time.Time]struct{})
+	for _, tx := range txs {
+		if tx.Amount <= 0 {
+			continue
+		}
+		svc, score, ok := m.Match(tx.Description)
+		if !ok {
+			continue
+		}
+		p, seen := groups[svc]
+		if !seen {
+			p = &Proposal{ServiceName: svc, StartDate: dates.MonthStart(tx.Date), Score: score}
+			groups[svc] = p
+			months[svc] = make(map[time.Time]struct{})
+		}
+		p.Charges++
+		p.Score = min(p.Score, score)
+		p.Lines = append(p.Lines, tx.Line)
+		months[svc][dates.MonthStart(tx.Date)] = struct{}{}
+		if start := dates.MonthStart(tx.Date); start.Before(p.StartDate) {
+			p.StartDate = start
+		}
+		if !tx.Date.Before(p.LastCharge) {
+			p.LastCharge = tx.Date
+			p.Cost = tx.Amount
+			p.Merchant = tx.Description
+		}
+	}
+
+	out := make([]Proposal, 0, len(groups))
+	for svc, p := range groups {
+		p.Recurring = len(months[svc]) >= 2
+		out = append(out, *p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ServiceName < out[j].ServiceName })
+	return out
+}
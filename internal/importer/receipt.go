@@ -0,0 +1,78 @@
+package importer
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"subs_tracker/internal/errcode"
+)
+
+// ErrUnknownReceipt - the email does not match any known receipt template
+var ErrUnknownReceipt = errcode.New(errcode.ReceiptUnknown, "unknown receipt")
+
+// Receipt - subscription charge recognised in a receipt email
+type Receipt struct {
+	// ServiceName - charged service
+	ServiceName string
+	// Amount - charged amount in whole rubles
+	Amount int64
+	// Date - day the receipt was sent
+	Date time.Time
+}
+
+// receiptTemplate - known sender and the way to find the service name in its emails
+type receiptTemplate struct {
+	sender  *regexp.Regexp
+	service func(subject, body string) string
+}
+
+// appStoreItem - "YouTube Premium (Monthly)" line of an App Store receipt
+var appStoreItem = regexp.MustCompile(`(?mi)^[ \t]*([^\n(]+?)[ \t]*\((?:monthly|ежемесячно|1 месяц)\)`)
+
+// receiptAmount - amount next to a currency sign, with optional thousand groups and kopecks
+var receiptAmount = regexp.MustCompile(`(?i)(?:₽|rub|руб\.?)[ \x{00a0}]*(\b\d{1,3}(?:[ \x{00a0}]\d{3})*(?:[.,]\d{1,2})?|\b\d+(?:[.,]\d{1,2})?)|(\b\d{1,3}(?:[ \x{00a0}]\d{3})*(?:[.,]\d{1,2})?|\b\d+(?:[.,]\d{1,2})?)[ \x{00a0}]*(?:₽|rub|руб)`)
+
+// receiptTemplates - checked in order against the sender and, for forwarded emails, the body
+var receiptTemplates = []receiptTemplate{
+	{
+		sender:  regexp.MustCompile(`(?i)@(?:[a-z0-9-]+\.)*netflix\.com\b`),
+		service: func(_, _ string) string { return "Netflix" },
+	},
+	{
+		sender:  regexp.MustCompile(`(?i)@(?:[a-z0-9-]+\.)*spotify\.com\b`),
+		service: func(_, _ string) string { return "Spotify" },
+	},
+	{
+		sender: regexp.MustCompile(`(?i)@(?:[a-z0-9-]+\.)*apple\.com\b`),
+		service: func(_, body string) string {
+			if m := appStoreItem.FindStringSubmatch(body); m != nil {
+				return strings.TrimSpace(m[1])
+			}
+			return ""
+		},
+	},
+}
+
+// ParseReceipt recognises Netflix, Spotify and App Store monthly receipts, also when forwarded
+func ParseReceipt(from, subject, body string, sent time.Time) (Receipt, error) {
+	for _, t := range receiptTemplates {
+		if !t.sender.MatchString(from) && !t.sender.MatchString(body) {
+			continue
+		}
+		service := t.service(subject, body)
+		if service == "" {
+			return Receipt{}, ErrUnknownReceipt
+		}
+		m := receiptAmount.FindStringSubmatch(body)
+		if m == nil {
+			return Receipt{}, ErrUnknownReceipt
+		}
+		amount, err := parseAmount(m[1] + m[2])
+		if err != nil || amount <= 0 {
+			return Receipt{}, ErrUnknownReceipt
+		}
+		return Receipt{ServiceName: service, Amount: amount, Date: sent}, nil
+	}
+	return Receipt{}, ErrUnknownReceipt
+}
@@ -0,0 +1,53 @@
+package importer
+
+// Reasons a row of an uploaded file is left out of every proposal
+const (
+	// SkipNoAmount - a statement row without an amount, e.g. a pending operation
+	SkipNoAmount = "no_amount"
+	// SkipUnmatched - no service of the catalog or of the user matched the description
+	SkipUnmatched = "unmatched"
+	// SkipNoName - a store row or item without a subscription name
+	SkipNoName = "no_name"
+	// SkipNoPrice - a Google Play item without pricing
+	SkipNoPrice = "no_price"
+	// SkipNeverPaid - a store subscription that ended without a single paid period
+	SkipNeverPaid = "never_paid"
+)
+
+// Row - a row of an uploaded file as it was read
+type Row struct {
+	// Line - line of a CSV file or position of the item of a JSON export, from 1
+	Line int
+	// Fields - cells of the row as uploaded
+	Fields []string
+	// Skipped - why the row was left out while reading, empty when it was read
+	Skipped string
+}
+
+// Rows - the rows of an uploaded file, kept to report what became of each of them
+type Rows struct {
+	// Header - names of the Fields of every row
+	Header []string
+	Rows   []Row
+}
+
+// Skipped returns the rows that are not part of any of the proposals, each with the reason it was left out
+func (rs Rows) Skipped(proposals []Proposal) []Row {
+	used := make(map[int]bool)
+	for _, p := range proposals {
+		for _, line := range p.Lines {
+			used[line] = true
+		}
+	}
+	var out []Row
+	for _, r := range rs.Rows {
+		if used[r.Line] {
+			continue
+		}
+		if r.Skipped == "" {
+			r.Skipped = SkipUnmatched
+		}
+		out = append(out, r)
+	}
+	return out
+}
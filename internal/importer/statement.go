@@ -0,0 +1,157 @@
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"subs_tracker/internal/errcode"
+)
+
+// ErrInvalidStatement - the uploaded statement cannot be read as a transactions CSV
+var ErrInvalidStatement = errcode.New(errcode.StatementInvalid, "invalid statement")
+
+// Transaction - single card or bank account operation from a statement
+type Transaction struct {
+	// Date - day of the operation
+	Date time.Time
+	// Description - merchant or payment details as printed by the bank
+	Description string
+	// Amount - absolute operation amount in whole rubles
+	Amount int64
+	// Line - line of the operation in the statement
+	Line int
+}
+
+// statementLayouts - date formats seen in bank exports
+var statementLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05Z07:00",
+	"02.01.2006",
+	"02.01.2006 15:04",
+	"02.01.2006 15:04:05",
+	"02/01/2006",
+}
+
+// column name variants, compared lowercased
+var (
+	dateColumns        = []string{"date", "transaction date", "дата", "дата операции"}
+	descriptionColumns = []string{"description", "merchant", "details", "payee", "описание", "назначение", "назначение платежа"}
+	amountColumns      = []string{"amount", "sum", "сумма", "сумма операции"}
+)
+
+// ParseStatement reads a CSV statement with a header row; the delimiter (comma or semicolon) and the
+// date/description/amount columns are detected from the header. Rows without an amount are skipped; rows holds
+// every row for the import report
+func ParseStatement(r io.Reader) ([]Transaction, Rows, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, Rows{}, fmt.Errorf("read statement: %w", err)
+	}
+	text := strings.TrimPrefix(string(raw), "\ufeff")
+	header, _, _ := strings.Cut(text, "\n")
+
+	cr := csv.NewReader(strings.NewReader(text))
+	if strings.Count(header, ";") > strings.Count(header, ",") {
+		cr.Comma = ';'
+	}
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.TrimLeadingSpace = true
+
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, Rows{}, fmt.Errorf("%w: %w", ErrInvalidStatement, err)
+	}
+	if len(rows) == 0 {
+		return nil, Rows{}, fmt.Errorf("%w: empty", ErrInvalidStatement)
+	}
+
+	dateIdx, descIdx, amountIdx := -1, -1, -1
+	for i, name := range rows[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case dateIdx < 0 && contains(dateColumns, name):
+			dateIdx = i
+		case descIdx < 0 && contains(descriptionColumns, name):
+			descIdx = i
+		case amountIdx < 0 && contains(amountColumns, name):
+			amountIdx = i
+		}
+	}
+	if dateIdx < 0 || descIdx < 0 || amountIdx < 0 {
+		return nil, Rows{}, fmt.Errorf("%w: header must name date, description and amount columns", ErrInvalidStatement)
+	}
+
+	out := make([]Transaction, 0, len(rows)-1)
+	read := Rows{Header: rows[0], Rows: make([]Row, 0, len(rows)-1)}
+	for n, row := range rows[1:] {
+		line := n + 2
+		if max(dateIdx, descIdx, amountIdx) >= len(row) {
+			return nil, Rows{}, fmt.Errorf("%w: line %d: missing columns", ErrInvalidStatement, line)
+		}
+		if strings.TrimSpace(row[amountIdx]) == "" {
+			read.Rows = append(read.Rows, Row{Line: line, Fields: row, Skipped: SkipNoAmount})
+			continue
+		}
+		date, err := parseDate(row[dateIdx])
+		if err != nil {
+			return nil, Rows{}, fmt.Errorf("%w: line %d: %w", ErrInvalidStatement, line, err)
+		}
+		amount, err := parseAmount(row[amountIdx])
+		if err != nil {
+			return nil, Rows{}, fmt.Errorf("%w: line %d: %w", ErrInvalidStatement, line, err)
+		}
+		out = append(out, Transaction{
+			Date:        date,
+			Description: strings.TrimSpace(row[descIdx]),
+			Amount:      amount,
+			Line:        line,
+		})
+		read.Rows = append(read.Rows, Row{Line: line, Fields: row})
+	}
+	return out, read, nil
+}
+
+// parseDate tries every known statement layout
+func parseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range statementLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// parseAmount accepts "1 299,00", "-499.90", "$9.99" and similar, returning the absolute value rounded to rubles
+func parseAmount(s string) (int64, error) {
+	clean := strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		case r == ',':
+			return '.'
+		}
+		return -1
+	}, s)
+	v, err := strconv.ParseFloat(clean, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return int64(math.Round(math.Abs(v))), nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,287 @@
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"subs_tracker/pkg/dates"
+)
+
+// column name variants of the App Store purchase history, compared lowercased
+var (
+	storeNameColumns   = []string{"subscription name", "item", "item description", "app name", "title"}
+	storeDateColumns   = []string{"event date", "purchase date", "order date", "date"}
+	storeEventColumns  = []string{"event", "event type", "type", "action"}
+	storeAmountColumns = []string{"price", "amount", "invoice item total", "total"}
+)
+
+// storeEvent - single row of a store subscription history
+type storeEvent struct {
+	date   time.Time
+	kind   string
+	amount int64
+	line   int
+}
+
+// ParseAppStore reads the App Store purchase history CSV: one row per subscription event
+// (start, renewal, free trial, cancellation, expiration). Subscriptions that were never paid for are skipped;
+// rows holds every row for the import report
+func ParseAppStore(r io.Reader) ([]Proposal, Rows, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.TrimLeadingSpace = true
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, Rows{}, fmt.Errorf("%w: %w", ErrInvalidStatement, err)
+	}
+	if len(rows) == 0 {
+		return nil, Rows{}, fmt.Errorf("%w: empty", ErrInvalidStatement)
+	}
+
+	nameIdx, dateIdx, eventIdx, amountIdx := -1, -1, -1, -1
+	for i, name := range rows[0] {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		switch {
+		case nameIdx < 0 && contains(storeNameColumns, name):
+			nameIdx = i
+		case dateIdx < 0 && contains(storeDateColumns, name):
+			dateIdx = i
+		case eventIdx < 0 && contains(storeEventColumns, name):
+			eventIdx = i
+		case amountIdx < 0 && contains(storeAmountColumns, name):
+			amountIdx = i
+		}
+	}
+	if nameIdx < 0 || dateIdx < 0 || eventIdx < 0 || amountIdx < 0 {
+		return nil, Rows{}, fmt.Errorf("%w: header must name subscription, date, event and price columns", ErrInvalidStatement)
+	}
+
+	events := make(map[string][]storeEvent)
+	read := Rows{Header: rows[0], Rows: make([]Row, 0, len(rows)-1)}
+	for n, row := range rows[1:] {
+		line := n + 2
+		if max(nameIdx, dateIdx, eventIdx, amountIdx) >= len(row) {
+			return nil, Rows{}, fmt.Errorf("%w: line %d: missing columns", ErrInvalidStatement, line)
+		}
+		name := strings.TrimSpace(row[nameIdx])
+		if name == "" {
+			read.Rows = append(read.Rows, Row{Line: line, Fields: row, Skipped: SkipNoName})
+			continue
+		}
+		date, err := parseDate(row[dateIdx])
+		if err != nil {
+			return nil, Rows{}, fmt.Errorf("%w: line %d: %w", ErrInvalidStatement, line, err)
+		}
+		var amount int64
+		if v := strings.TrimSpace(row[amountIdx]); v != "" {
+			if amount, err = parseAmount(v); err != nil {
+				return nil, Rows{}, fmt.Errorf("%w: line %d: %w", ErrInvalidStatement, line, err)
+			}
+		}
+		events[name] = append(events[name], storeEvent{date: date, kind: strings.ToLower(row[eventIdx]), amount: amount, line: line})
+		read.Rows = append(read.Rows, Row{Line: line, Fields: row})
+	}
+
+	out := make([]Proposal, 0, len(events))
+	unpaid := make(map[int]bool)
+	for name, evs := range events {
+		if p, ok := storeProposal(name, evs); ok {
+			out = append(out, p)
+			continue
+		}
+		for _, ev := range evs {
+			unpaid[ev.line] = true
+		}
+	}
+	for i, row := range read.Rows {
+		if unpaid[row.Line] {
+			read.Rows[i].Skipped = SkipNeverPaid
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ServiceName < out[j].ServiceName })
+	return out, read, nil
+}
+
+// storeProposal folds the event history of one subscription
+func storeProposal(name string, evs []storeEvent) (Proposal, bool) {
+	sort.SliceStable(evs, func(i, j int) bool { return evs[i].date.Before(evs[j].date) })
+
+	p := Proposal{ServiceName: name, Merchant: name, Score: 1}
+	months := make(map[time.Time]struct{})
+	var stopped *time.Time
+	for _, ev := range evs {
+		p.Lines = append(p.Lines, ev.line)
+		switch {
+		case strings.Contains(ev.kind, "trial"):
+			if p.Charges == 0 {
+				d := ev.date
+				p.TrialStart = &d
+			}
+		case strings.Contains(ev.kind, "cancel"), strings.Contains(ev.kind, "expir"), strings.Contains(ev.kind, "refund"):
+			d := ev.date
+			stopped = &d
+		case ev.amount > 0:
+			if p.Charges == 0 {
+				p.StartDate = dates.MonthStart(ev.date)
+			}
+			p.Charges++
+			p.Cost = ev.amount
+			p.LastCharge = ev.date
+			months[dates.MonthStart(ev.date)] = struct{}{}
+			stopped = nil
+		}
+	}
+	if p.Charges == 0 {
+		return Proposal{}, false
+	}
+	p.Recurring = len(months) >= 2
+	if stopped != nil {
+		end := dates.MonthStart(p.LastCharge)
+		p.EndDate = &end
+	} else {
+		renews := p.LastCharge.AddDate(0, 1, 0)
+		p.Renews = &renews
+	}
+	return p, true
+}
+
+// playColumns - the fields of a Google Play item in the import report
+var playColumns = []string{"title", "state", "startTime", "freeTrialEndTime", "renewalDate", "expirationDate"}
+
+// playExport - item of Subscriptions.json from the Google Play section of Google Takeout
+type playExport struct {
+	Subscription struct {
+		Doc struct {
+			Title string `json:"title"`
+		} `json:"doc"`
+		Title            string `json:"title"`
+		State            string `json:"state"`
+		StartTime        string `json:"startTime"`
+		FreeTrialEndTime string `json:"freeTrialEndTime"`
+		RenewalDate      string `json:"renewalDate"`
+		ExpirationDate   string `json:"expirationDate"`
+		Pricing          []struct {
+			Period string `json:"period"`
+			Price  string `json:"price"`
+		} `json:"pricing"`
+	} `json:"subscription"`
+}
+
+// ParseGooglePlay reads Subscriptions.json of a Google Takeout export. Yearly prices are spread over months,
+// free trials shift the start to the first paid month. Rows holds the items as playColumns for the import report
+func ParseGooglePlay(r io.Reader) ([]Proposal, Rows, error) {
+	var items []playExport
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return nil, Rows{}, fmt.Errorf("%w: %w", ErrInvalidStatement, err)
+	}
+
+	out := make([]Proposal, 0, len(items))
+	read := Rows{Header: playColumns, Rows: make([]Row, 0, len(items))}
+	for i, it := range items {
+		s := it.Subscription
+		name := strings.TrimSpace(s.Doc.Title)
+		if name == "" {
+			name = strings.TrimSpace(s.Title)
+		}
+		row := Row{Line: i + 1, Fields: []string{name, s.State, s.StartTime, s.FreeTrialEndTime, s.RenewalDate, s.ExpirationDate}}
+		switch {
+		case name == "":
+			row.Skipped = SkipNoName
+		case len(s.Pricing) == 0:
+			row.Skipped = SkipNoPrice
+		}
+		read.Rows = append(read.Rows, row)
+		if row.Skipped != "" {
+			continue
+		}
+		cost, err := playMonthlyCost(s.Pricing[len(s.Pricing)-1].Period, s.Pricing[len(s.Pricing)-1].Price)
+		if err != nil {
+			return nil, Rows{}, fmt.Errorf("%w: item %d: %w", ErrInvalidStatement, i, err)
+		}
+		start, err := parseOptionalTime(s.StartTime)
+		if err != nil || start == nil {
+			return nil, Rows{}, fmt.Errorf("%w: item %d: invalid startTime %q", ErrInvalidStatement, i, s.StartTime)
+		}
+		trialEnd, err := parseOptionalTime(s.FreeTrialEndTime)
+		if err != nil {
+			return nil, Rows{}, fmt.Errorf("%w: item %d: %w", ErrInvalidStatement, i, err)
+		}
+		renews, err := parseOptionalTime(s.RenewalDate)
+		if err != nil {
+			return nil, Rows{}, fmt.Errorf("%w: item %d: %w", ErrInvalidStatement, i, err)
+		}
+		expires, err := parseOptionalTime(s.ExpirationDate)
+		if err != nil {
+			return nil, Rows{}, fmt.Errorf("%w: item %d: %w", ErrInvalidStatement, i, err)
+		}
+
+		p := Proposal{ServiceName: name, Cost: cost, Merchant: name, Score: 1, StartDate: dates.MonthStart(*start), LastCharge: *start, Lines: []int{row.Line}}
+		if trialEnd != nil {
+			p.TrialStart = start
+			p.StartDate = dates.MonthStart(*trialEnd)
+			p.LastCharge = *trialEnd
+		}
+		active := strings.EqualFold(s.State, "active") || strings.EqualFold(s.State, "grace_period")
+		switch {
+		case active:
+			p.Renews = renews
+			if renews != nil {
+				p.LastCharge = renews.AddDate(0, -1, 0)
+			}
+		case expires != nil:
+			if trialEnd != nil && !expires.After(*trialEnd) {
+				// cancelled during the trial, never paid
+				read.Rows[len(read.Rows)-1].Skipped = SkipNeverPaid
+				continue
+			}
+			end := dates.MonthStart(expires.AddDate(0, 0, -1))
+			if end.Before(p.StartDate) {
+				end = p.StartDate
+			}
+			p.EndDate = &end
+			p.LastCharge = *expires
+		}
+		p.Charges = monthsBetween(p.StartDate, dates.MonthStart(p.LastCharge)) + 1
+		p.Recurring = p.Charges >= 2
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ServiceName < out[j].ServiceName })
+	return out, read, nil
+}
+
+// playMonthlyCost converts a Play price to a monthly amount for ISO periods P1M, P3M, P6M and P1Y
+func playMonthlyCost(period, price string) (int64, error) {
+	amount, err := parseAmount(price)
+	if err != nil {
+		return 0, err
+	}
+	months := map[string]float64{"P1M": 1, "P3M": 3, "P6M": 6, "P1Y": 12}[strings.ToUpper(strings.TrimSpace(period))]
+	if months == 0 {
+		return 0, fmt.Errorf("unsupported period %q", period)
+	}
+	return int64(math.Round(float64(amount) / months)), nil
+}
+
+func parseOptionalTime(s string) (*time.Time, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	t, err := parseDate(s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// monthsBetween returns the number of whole months from a to b, both month starts
+func monthsBetween(a, b time.Time) int {
+	n := (b.Year()-a.Year())*12 + int(b.Month()) - int(a.Month())
+	return max(n, 0)
+}
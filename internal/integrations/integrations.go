@@ -0,0 +1,177 @@
+// Package integrations keeps the soft service levels of the outbound integrations: every call to a webhook,
+// broker or third-party API is recorded as a success or a failure, and the success rate of the latest calls
+// tells operators which integration is degraded. Nothing is enforced, the numbers live in process memory and
+// start over with it
+package integrations
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"subs_tracker/pkg/clock"
+)
+
+const (
+	// recentCalls - the success rate is taken over this many latest calls
+	recentCalls = 100
+	// degradedBelow - an integration whose recent success rate is lower is degraded
+	degradedBelow = 0.9
+	// downAfter - an integration is down after this many failed calls in a row
+	downAfter = 3
+)
+
+// State — how an integration is doing
+type State string
+
+const (
+	// StateIdle - no calls yet
+	StateIdle State = "idle"
+	// StateOK - the recent success rate is at the target
+	StateOK State = "ok"
+	// StateDegraded - the recent success rate is below the target
+	StateDegraded State = "degraded"
+	// StateDown - the latest downAfter calls failed
+	StateDown State = "down"
+)
+
+// Status — calls of an integration since the process started
+type Status struct {
+	Name  string
+	State State
+	// Calls - calls recorded, Failures - the failed ones among them
+	Calls    int64
+	Failures int64
+	// SuccessRate - share of successful calls among the latest ones, 1 before the first call
+	SuccessRate float64
+	// LastSuccess and LastFailure - times of the latest calls of each kind, zero before the first one
+	LastSuccess time.Time
+	LastFailure time.Time
+	// LastError - error of the latest failed call
+	LastError string
+}
+
+// Tracker records the calls of one integration. A nil tracker ignores them, so a component can hold one
+// whether or not tracking is wired
+type Tracker struct {
+	name  string
+	clock clock.Clock
+
+	mu     sync.Mutex
+	recent [recentCalls]bool
+	next   int
+	filled int
+	// failing - failed calls since the latest success
+	failing int
+	status  Status
+}
+
+// Record counts a call, a failure when err is not nil
+func (t *Tracker) Record(err error) {
+	if t == nil {
+		return
+	}
+	now := t.clock.Now().UTC()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.recent[t.next] = err == nil
+	t.next = (t.next + 1) % recentCalls
+	t.filled = min(t.filled+1, recentCalls)
+	t.status.Calls++
+	if err != nil {
+		t.failing++
+		t.status.Failures++
+		t.status.LastFailure = now
+		t.status.LastError = err.Error()
+		return
+	}
+	t.failing = 0
+	t.status.LastSuccess = now
+}
+
+// Status reports the calls recorded so far
+func (t *Tracker) Status() Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st := t.status
+	st.Name = t.name
+	st.SuccessRate = 1
+	if t.filled == 0 {
+		st.State = StateIdle
+		return st
+	}
+	ok := 0
+	for _, success := range t.recent[:t.filled] {
+		if success {
+			ok++
+		}
+	}
+	st.SuccessRate = float64(ok) / float64(t.filled)
+	switch {
+	case t.failing >= downAfter:
+		st.State = StateDown
+	case st.SuccessRate < degradedBelow:
+		st.State = StateDegraded
+	default:
+		st.State = StateOK
+	}
+	return st
+}
+
+// Registry holds the trackers of all integrations by name
+type Registry struct {
+	clock clock.Clock
+
+	mu       sync.Mutex
+	trackers map[string]*Tracker
+}
+
+// NewRegistry creates an empty registry and applies options
+func NewRegistry(options ...func(*Registry)) *Registry {
+	r := &Registry{clock: clock.System, trackers: map[string]*Tracker{}}
+	for _, o := range options {
+		o(r)
+	}
+	return r
+}
+
+// WithClock returns an option that sets the source of call times
+func WithClock(c clock.Clock) func(*Registry) {
+	return func(r *Registry) {
+		if c != nil {
+			r.clock = c
+		}
+	}
+}
+
+// Track returns the tracker of the integration, the same one for the same name; nil on a nil registry
+func (r *Registry) Track(name string) *Tracker {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.trackers[name]
+	if !ok {
+		t = &Tracker{name: name, clock: r.clock}
+		r.trackers[name] = t
+	}
+	return t
+}
+
+// Status reports every tracked integration by name
+func (r *Registry) Status() []Status {
+	r.mu.Lock()
+	trackers := make([]*Tracker, 0, len(r.trackers))
+	for _, t := range r.trackers {
+		trackers = append(trackers, t)
+	}
+	r.mu.Unlock()
+
+	out := make([]Status, 0, len(trackers))
+	for _, t := range trackers {
+		out = append(out, t.Status())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
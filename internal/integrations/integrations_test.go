@@ -0,0 +1,71 @@
+package integrations
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/pkg/clock"
+)
+
+func TestTracker(t *testing.T) {
+	now := clock.NewFake(time.Date(2025, time.July, 10, 12, 0, 0, 0, time.UTC))
+	r := NewRegistry(WithClock(now))
+	hooks := r.Track("webhooks")
+	assert.Same(t, hooks, r.Track("webhooks"))
+	assert.Equal(t, Status{Name: "webhooks", State: StateIdle, SuccessRate: 1}, hooks.Status())
+
+	for range 9 {
+		hooks.Record(nil)
+	}
+	started := now.Now()
+	now.Advance(time.Minute)
+	hooks.Record(errors.New("status 502"))
+	assert.Equal(t, Status{
+		Name:        "webhooks",
+		State:       StateOK,
+		Calls:       10,
+		Failures:    1,
+		SuccessRate: 0.9,
+		LastSuccess: started,
+		LastFailure: now.Now(),
+		LastError:   "status 502",
+	}, hooks.Status())
+
+	hooks.Record(errors.New("status 502"))
+	assert.Equal(t, StateDegraded, hooks.Status().State)
+	hooks.Record(errors.New("timeout"))
+	st := hooks.Status()
+	assert.Equal(t, StateDown, st.State, "three failures in a row")
+	assert.Equal(t, "timeout", st.LastError)
+	hooks.Record(nil)
+	assert.Equal(t, StateDegraded, hooks.Status().State, "a success ends the outage, the rate stays low")
+
+	// the rate only looks at the latest calls, old failures age out
+	for range recentCalls {
+		hooks.Record(nil)
+	}
+	st = hooks.Status()
+	assert.Equal(t, StateOK, st.State)
+	assert.Equal(t, 1.0, st.SuccessRate)
+	assert.Equal(t, int64(3), st.Failures)
+}
+
+func TestRegistry_Status(t *testing.T) {
+	var none *Registry
+	var tracker *Tracker
+	require.Nil(t, none.Track("stripe"))
+	tracker.Record(errors.New("ignored")) // a nil tracker must not panic
+
+	r := NewRegistry()
+	r.Track("usage").Record(nil)
+	r.Track("kafka").Record(errors.New("connection refused"))
+	st := r.Status()
+	require.Len(t, st, 2)
+	assert.Equal(t, "kafka", st[0].Name)
+	assert.Equal(t, StateDegraded, st[0].State)
+	assert.Equal(t, "usage", st[1].Name)
+}
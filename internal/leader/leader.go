@@ -0,0 +1,204 @@
+// Package leader makes scheduled jobs run on one instance at a time when several replicas share a database.
+// Every job is guarded by a lock named after it: the instance holding the lock runs the job, the others keep
+// trying and take over once the holder stops or loses the lock, e.g. with its database session
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"subs_tracker/pkg/clock"
+)
+
+const defaultInterval = 5 * time.Second
+
+// ErrLost is returned by Lock.Check once the lock may be held by another instance
+var ErrLost = errors.New("lock lost")
+
+// Lock — a held named lock
+type Lock interface {
+	// Check - ErrLost when the lock may have been lost since it was taken
+	Check(ctx context.Context) error
+	// Unlock - give the lock back; a lost lock is not an error
+	Unlock(ctx context.Context) error
+}
+
+// Locker — named locks shared by every instance
+type Locker interface {
+	// TryLock - take the lock of name without waiting; nil when another holder has it
+	TryLock(ctx context.Context, name string) (Lock, error)
+}
+
+// Lease — a job run by this instance
+type Lease struct {
+	Job   string    `json:"job"`
+	Since time.Time `json:"since"`
+}
+
+// Elector runs guarded jobs only while this instance holds their locks
+type Elector struct {
+	locker   Locker
+	instance string
+	interval time.Duration
+	clock    clock.Clock
+	log      *slog.Logger
+
+	mu      sync.Mutex
+	leading map[string]time.Time
+}
+
+// NewElector creates an elector taking locks from locker and applies options
+func NewElector(locker Locker, options ...func(*Elector)) *Elector {
+	e := &Elector{
+		locker:   locker,
+		instance: DefaultInstance(),
+		interval: defaultInterval,
+		clock:    clock.System,
+		log:      slog.New(slog.DiscardHandler),
+		leading:  map[string]time.Time{},
+	}
+	for _, o := range options {
+		o(e)
+	}
+	return e
+}
+
+// WithInstance returns an option that sets the name of this instance shown to operators
+func WithInstance(name string) func(*Elector) {
+	return func(e *Elector) {
+		if name = strings.TrimSpace(name); name != "" {
+			e.instance = name
+		}
+	}
+}
+
+// WithInterval returns an option that sets how often a free lock is retried and a held one checked
+func WithInterval(d time.Duration) func(*Elector) {
+	return func(e *Elector) {
+		if d > 0 {
+			e.interval = d
+		}
+	}
+}
+
+// WithClock returns an option that sets the source of lease times
+func WithClock(c clock.Clock) func(*Elector) {
+	return func(e *Elector) {
+		if c != nil {
+			e.clock = c
+		}
+	}
+}
+
+// WithLogger returns an option that sets the elector logger
+func WithLogger(log *slog.Logger) func(*Elector) {
+	return func(e *Elector) {
+		if log != nil {
+			e.log = log
+		}
+	}
+}
+
+// DefaultInstance names the instance after its host and process, e.g. "api-7f9c-1"
+func DefaultInstance() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// Instance returns the name of this instance
+func (e *Elector) Instance() string {
+	return e.instance
+}
+
+// Leading returns the jobs this instance runs right now, by name
+func (e *Elector) Leading() []Lease {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	out := make([]Lease, 0, len(e.leading))
+	for job, since := range e.leading {
+		out = append(out, Lease{Job: job, Since: since})
+	}
+	slices.SortFunc(out, func(a, b Lease) int { return strings.Compare(a.Job, b.Job) })
+	return out
+}
+
+// Guard wraps the Run of a job so that it only runs while this instance holds the lock of the job. A run is
+// cancelled within an interval of the lock being lost and started again when it is regained; an error of the job
+// ends the guard
+func (e *Elector) Guard(job string, run func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+		for {
+			lock, err := e.locker.TryLock(ctx, job)
+			switch {
+			case err != nil && ctx.Err() == nil:
+				e.log.Warn("job lock failed", slog.String("job", job), slog.Any("error", err))
+			case lock != nil:
+				if err := e.lead(ctx, job, lock, run); err != nil {
+					return err
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	}
+}
+
+// lead runs the job under a held lock, checking the lock every interval, and gives the lock back afterwards
+func (e *Elector) lead(ctx context.Context, job string, lock Lock, run func(ctx context.Context) error) error {
+	e.mu.Lock()
+	e.leading[job] = e.clock.Now()
+	e.mu.Unlock()
+	e.log.Info("leading job", slog.String("job", job), slog.String("instance", e.instance))
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- run(runCtx) }()
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	var err error
+wait:
+	for {
+		select {
+		case err = <-done:
+			break wait
+		case <-ticker.C:
+			if cerr := lock.Check(ctx); cerr != nil && ctx.Err() == nil {
+				e.log.Warn("job lock lost, stopping the job", slog.String("job", job), slog.Any("error", cerr))
+				cancel()
+				err = <-done
+				break wait
+			}
+		}
+	}
+
+	e.mu.Lock()
+	delete(e.leading, job)
+	e.mu.Unlock()
+	// the lock is given back even on shutdown, so that another instance takes over at once
+	unlockCtx, cancelUnlock := context.WithTimeout(context.WithoutCancel(ctx), e.interval)
+	defer cancelUnlock()
+	if uerr := lock.Unlock(unlockCtx); uerr != nil {
+		e.log.Warn("job unlock failed", slog.String("job", job), slog.Any("error", uerr))
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("%s: %w", job, err)
+	}
+	return nil
+}
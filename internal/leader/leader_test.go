@@ -0,0 +1,91 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingJob counts how many of its runs are in progress and how many have started
+type countingJob struct {
+	running, started atomic.Int32
+}
+
+func (j *countingJob) Run(ctx context.Context) error {
+	j.started.Add(1)
+	j.running.Add(1)
+	defer j.running.Add(-1)
+	<-ctx.Done()
+	return nil
+}
+
+func TestElector_OneInstanceRunsTheJob(t *testing.T) {
+	locks := NewLocalLocker()
+	a := NewElector(locks, WithInstance("a"), WithInterval(5*time.Millisecond))
+	b := NewElector(locks, WithInstance("b"), WithInterval(5*time.Millisecond))
+	var job countingJob
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stopA := make(chan error, 1)
+	ctxA, cancelA := context.WithCancel(ctx)
+	defer cancelA()
+	go func() { stopA <- a.Guard("archiver", job.Run)(ctxA) }()
+	require.Eventually(t, func() bool { return job.running.Load() == 1 }, time.Second, time.Millisecond)
+	go func() { _ = b.Guard("archiver", job.Run)(ctx) }()
+
+	time.Sleep(30 * time.Millisecond)
+	assert.EqualValues(t, 1, job.running.Load(), "the other instance waits")
+	assert.Equal(t, []string{"archiver"}, jobs(a.Leading()))
+	assert.Empty(t, b.Leading())
+
+	locks.Drop("archiver")
+	require.Eventually(t, func() bool { return job.started.Load() == 2 }, time.Second, time.Millisecond,
+		"the job starts again wherever the lock is taken")
+	require.Eventually(t, func() bool { return job.running.Load() == 1 }, time.Second, time.Millisecond,
+		"a lost lock stops the run within an interval")
+
+	if len(b.Leading()) == 0 {
+		cancelA()
+		assert.NoError(t, <-stopA)
+		require.Eventually(t, func() bool { return len(b.Leading()) == 1 }, time.Second, time.Millisecond,
+			"the other instance takes over")
+	}
+}
+
+func TestElector_HandsOverOnShutdown(t *testing.T) {
+	locks := NewLocalLocker()
+	a := NewElector(locks, WithInterval(time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- a.Guard("backups", (&countingJob{}).Run)(ctx) }()
+	require.Eventually(t, func() bool { return len(a.Leading()) == 1 }, time.Second, time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-done)
+	lock, err := locks.TryLock(context.Background(), "backups")
+	require.NoError(t, err)
+	assert.NotNil(t, lock, "the lock is given back")
+}
+
+func TestElector_JobError(t *testing.T) {
+	boom := errors.New("boom")
+	a := NewElector(NewLocalLocker(), WithInterval(time.Millisecond))
+	err := a.Guard("stripe-sync", func(context.Context) error { return boom })(context.Background())
+	assert.ErrorIs(t, err, boom)
+	assert.ErrorContains(t, err, "stripe-sync")
+	assert.Empty(t, a.Leading())
+}
+
+func jobs(leases []Lease) []string {
+	var out []string
+	for _, l := range leases {
+		out = append(out, l.Job)
+	}
+	return out
+}
@@ -0,0 +1,61 @@
+package leader
+
+import (
+	"context"
+	"sync"
+)
+
+// LocalLocker — Locker within the process, for a single instance and tests: every job runs here
+type LocalLocker struct {
+	mu   sync.Mutex
+	held map[string]*localLock
+}
+
+var _ Locker = (*LocalLocker)(nil)
+
+// NewLocalLocker creates a locker with no locks held
+func NewLocalLocker() *LocalLocker {
+	return &LocalLocker{held: map[string]*localLock{}}
+}
+
+// TryLock takes the lock of name unless it is held
+func (l *LocalLocker) TryLock(_ context.Context, name string) (Lock, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.held[name]; ok {
+		return nil, nil
+	}
+	lock := &localLock{owner: l, name: name}
+	l.held[name] = lock
+	return lock, nil
+}
+
+// Drop takes the lock of name away from its holder, as a lost database session would
+func (l *LocalLocker) Drop(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.held, name)
+}
+
+type localLock struct {
+	owner *LocalLocker
+	name  string
+}
+
+func (k *localLock) Check(context.Context) error {
+	k.owner.mu.Lock()
+	defer k.owner.mu.Unlock()
+	if k.owner.held[k.name] != k {
+		return ErrLost
+	}
+	return nil
+}
+
+func (k *localLock) Unlock(context.Context) error {
+	k.owner.mu.Lock()
+	defer k.owner.mu.Unlock()
+	if k.owner.held[k.name] == k {
+		delete(k.owner.held, k.name)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+// Package loadshed turns low-priority requests, e.g. exports and cost reports, away while the database is
+// struggling, so that CRUD keeps its connections. A controller samples the connection pools: while the average
+// wait for a connection or the share of connections in use is over its threshold it sheds a growing share of
+// the low-priority requests, and lets them back in step by step once the pools are healthy again
+package loadshed
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	defaultInterval = time.Second
+	// raise - added to the shed share on every overloaded sample: a lasting overload sheds everything after 4
+	raise = 0.25
+	// lower - taken off the share on every healthy sample; letting requests back slower than they are shed
+	// keeps the share from flapping around the thresholds
+	lower = 0.1
+)
+
+// Sample — state of a connection pool
+type Sample struct {
+	// Acquired, Max - connections in use and the size of the pool
+	Acquired, Max int32
+	// Acquires, AcquireWait - successful acquires since the pool was opened and how long they waited in total
+	Acquires    int64
+	AcquireWait time.Duration
+}
+
+// Thresholds — when a pool counts as overloaded; a zero threshold is not checked
+type Thresholds struct {
+	// MaxAcquireWait - average wait for a connection between two samples
+	MaxAcquireWait time.Duration
+	// MaxPoolUsage - share of the connections in use, 0..1
+	MaxPoolUsage float64
+}
+
+// Controller adjusts the share of low-priority requests to shed from the samples of the pools
+type Controller struct {
+	stats    func() []Sample
+	limits   Thresholds
+	interval time.Duration
+	log      *slog.Logger
+	rand     func() float64
+
+	mu   sync.Mutex
+	prev []Sample
+	// shed - math.Float64bits of the share of low-priority requests turned away, 0..1
+	shed atomic.Uint64
+}
+
+// NewController creates a controller sampling the pools through stats, which admits every request until the
+// first overloaded sample, and applies options
+func NewController(stats func() []Sample, limits Thresholds, log *slog.Logger, options ...func(*Controller)) *Controller {
+	c := &Controller{
+		stats:    stats,
+		limits:   limits,
+		interval: defaultInterval,
+		log:      log,
+		rand:     rand.Float64,
+	}
+	for _, o := range options {
+		o(c)
+	}
+	return c
+}
+
+// WithInterval sets how often the pools are sampled
+func WithInterval(d time.Duration) func(*Controller) {
+	return func(c *Controller) {
+		if d > 0 {
+			c.interval = d
+		}
+	}
+}
+
+// WithRand sets the source of the uniform numbers in [0, 1) low-priority requests are admitted by
+func WithRand(f func() float64) func(*Controller) {
+	return func(c *Controller) {
+		if f != nil {
+			c.rand = f
+		}
+	}
+}
+
+// PoolStats samples the pools, e.g. the main pool and the shards
+func PoolStats(pools ...*pgxpool.Pool) func() []Sample {
+	return func() []Sample {
+		out := make([]Sample, 0, len(pools))
+		for _, p := range pools {
+			st := p.Stat()
+			out = append(out, Sample{
+				Acquired:    st.AcquiredConns(),
+				Max:         st.MaxConns(),
+				Acquires:    st.AcquireCount(),
+				AcquireWait: st.AcquireDuration(),
+			})
+		}
+		return out
+	}
+}
+
+// Run samples the pools on every tick until ctx is cancelled
+func (c *Controller) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			c.Update()
+		}
+	}
+}
+
+// Update samples the pools and raises the shed share when any of them is overloaded, lowers it otherwise; it
+// returns the new share
+func (c *Controller) Update() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	samples := c.stats()
+	var usage float64
+	var wait time.Duration
+	for i, s := range samples {
+		if s.Max > 0 {
+			usage = max(usage, float64(s.Acquired)/float64(s.Max))
+		}
+		if i < len(c.prev) {
+			if n := s.Acquires - c.prev[i].Acquires; n > 0 {
+				wait = max(wait, (s.AcquireWait-c.prev[i].AcquireWait)/time.Duration(n))
+			}
+		}
+	}
+	c.prev = samples
+
+	overloaded := (c.limits.MaxPoolUsage > 0 && usage > c.limits.MaxPoolUsage) ||
+		(c.limits.MaxAcquireWait > 0 && wait > c.limits.MaxAcquireWait)
+	was := c.Shed()
+	share := max(was-lower, 0)
+	if overloaded {
+		share = min(was+raise, 1)
+	}
+	c.shed.Store(math.Float64bits(share))
+
+	switch {
+	case was == 0 && share > 0:
+		c.log.Warn("database overloaded, shedding low-priority requests",
+			slog.Float64("pool_usage", usage), slog.Duration("acquire_wait", wait))
+	case was > 0 && share == 0:
+		c.log.Info("database recovered, low-priority requests admitted again")
+	}
+	return share
+}
+
+// Shed returns the share of low-priority requests currently turned away
+func (c *Controller) Shed() float64 {
+	return math.Float64frombits(c.shed.Load())
+}
+
+// Admit reports whether a low-priority request is served under the current share
+func (c *Controller) Admit() bool {
+	share := c.Shed()
+	return share == 0 || c.rand() >= share
+}
+
+// RetryAfter estimates when low-priority requests are admitted again should the pools recover now; it is
+// never less than a second
+func (c *Controller) RetryAfter() time.Duration {
+	steps := math.Ceil(c.Shed() / lower)
+	return max(time.Duration(steps)*c.interval, time.Second)
+}
@@ -0,0 +1,54 @@
+package loadshed
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestController_Update(t *testing.T) {
+	pools := []Sample{{Max: 10}, {Max: 4}}
+	roll := 0.4
+	c := NewController(func() []Sample { return append([]Sample(nil), pools...) },
+		Thresholds{MaxAcquireWait: 50 * time.Millisecond, MaxPoolUsage: 0.9},
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		WithRand(func() float64 { return roll }),
+	)
+	assert.Equal(t, defaultInterval, c.interval)
+
+	assert.Zero(t, c.Update())
+	assert.True(t, c.Admit())
+	assert.Equal(t, time.Second, c.RetryAfter())
+
+	pools[1].Acquired = 4
+	assert.InDelta(t, 0.25, c.Update(), 1e-9, "a full shard is enough")
+	assert.InDelta(t, 0.5, c.Update(), 1e-9)
+	assert.False(t, c.Admit(), "0.5 is shed")
+	roll = 0.6
+	assert.True(t, c.Admit())
+	assert.Equal(t, 5*time.Second, c.RetryAfter())
+
+	pools[1].Acquired = 1
+	assert.InDelta(t, 0.4, c.Update(), 1e-9, "requests are let back slower than they are shed")
+
+	// 10 acquires waited 1s in total since the last sample: 100ms each
+	pools[0].Acquires, pools[0].AcquireWait = 10, time.Second
+	assert.InDelta(t, 0.65, c.Update(), 1e-9, "slow acquires overload the pool")
+	pools[0].Acquires, pools[0].AcquireWait = 110, 2*time.Second
+	assert.InDelta(t, 0.55, c.Update(), 1e-9, "10ms each is fine")
+
+	for range 6 {
+		c.Update()
+	}
+	assert.Zero(t, c.Shed())
+	assert.True(t, c.Admit())
+}
+
+func TestController_ZeroThresholds(t *testing.T) {
+	c := NewController(func() []Sample { return []Sample{{Acquired: 10, Max: 10}} }, Thresholds{},
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.Zero(t, c.Update(), "thresholds that are not set are not checked")
+}
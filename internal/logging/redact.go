@@ -0,0 +1,148 @@
+// Package logging holds slog handlers shared by the service binaries
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Redaction markers left in place of removed values
+const (
+	RedactedUUID  = "[uuid]"
+	RedactedEmail = "[email]"
+	Redacted      = "[redacted]"
+)
+
+var (
+	uuidRe  = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	emailRe = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+)
+
+// keepKeys are attributes that hold no personal data even when they look like it
+var keepKeys = map[string]bool{"request_id": true}
+
+// RedactHandler removes personal data before records reach the next handler: user IDs (UUIDs) and
+// emails anywhere in the message and string values, and whole values of sensitive query params
+// in the "query" attribute of access logs
+type RedactHandler struct {
+	next   slog.Handler
+	params map[string]bool
+}
+
+// NewRedactHandler wraps next; params are query parameter names whose values are always removed
+func NewRedactHandler(next slog.Handler, params ...string) *RedactHandler {
+	h := &RedactHandler{next: next, params: make(map[string]bool, len(params))}
+	for _, p := range params {
+		h.params[strings.ToLower(p)] = true
+	}
+	return h
+}
+
+// Enabled reports whether the next handler handles records at level
+func (h *RedactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle redacts the record and passes it on
+func (h *RedactHandler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, redactText(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(h.redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+// WithAttrs redacts attrs once and returns a handler carrying them
+func (h *RedactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		redacted = append(redacted, h.redactAttr(a))
+	}
+	return &RedactHandler{next: h.next.WithAttrs(redacted), params: h.params}
+}
+
+// WithGroup returns a handler nesting later attributes under name
+func (h *RedactHandler) WithGroup(name string) slog.Handler {
+	return &RedactHandler{next: h.next.WithGroup(name), params: h.params}
+}
+
+func (h *RedactHandler) redactAttr(a slog.Attr) slog.Attr {
+	if keepKeys[a.Key] {
+		return a
+	}
+	if h.params[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, Redacted)
+	}
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindGroup:
+		group := v.Group()
+		attrs := make([]any, 0, len(group))
+		for _, ga := range group {
+			attrs = append(attrs, h.redactAttr(ga))
+		}
+		return slog.Group(a.Key, attrs...)
+	case slog.KindString:
+		if a.Key == "query" {
+			return slog.String(a.Key, h.redactQuery(v.String()))
+		}
+		return slog.String(a.Key, redactText(v.String()))
+	case slog.KindAny:
+		switch x := v.Any().(type) {
+		case error:
+			return slog.String(a.Key, redactText(x.Error()))
+		case fmt.Stringer:
+			return slog.String(a.Key, redactText(x.String()))
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+// redactQuery removes sensitive params and personal data from a raw query, keeping the order of params
+func (h *RedactHandler) redactQuery(raw string) string {
+	if raw == "" {
+		return raw
+	}
+	parts := strings.Split(raw, "&")
+	for i, part := range parts {
+		rawKey, rawValue, hasValue := strings.Cut(part, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			parts[i] = redactText(part)
+			continue
+		}
+		if !hasValue {
+			parts[i] = redactText(rawKey)
+			continue
+		}
+		if h.params[strings.ToLower(key)] {
+			parts[i] = rawKey + "=" + Redacted
+			continue
+		}
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			parts[i] = rawKey + "=" + redactText(rawValue)
+			continue
+		}
+		if redacted := redactText(value); redacted != value {
+			parts[i] = rawKey + "=" + redacted
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// redactText replaces user IDs and emails
+func redactText(s string) string {
+	if strings.Contains(s, "-") {
+		s = uuidRe.ReplaceAllString(s, RedactedUUID)
+	}
+	if strings.Contains(s, "@") {
+		s = emailRe.ReplaceAllString(s, RedactedEmail)
+	}
+	return s
+}
@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(NewRedactHandler(slog.NewJSONHandler(buf, nil), "token", "api_key"))
+}
+
+func decode(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	buf.Reset()
+	return got
+}
+
+func TestRedactHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log := newTestLogger(&buf)
+	const uid = "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+
+	log.Info("http request",
+		"path", "/api/v1/users/"+uid+"/settings",
+		"query", "user_id="+uid+"&token=abc%20def&email=ann%40example.com&limit=10&flag",
+		"request_id", "0d5b8c8e-8f43-4b8e-9d55-0c1d1c0f5a11",
+		"status", 200,
+	)
+	got := decode(t, &buf)
+	assert.Equal(t, "/api/v1/users/[uuid]/settings", got["path"])
+	assert.Equal(t, "user_id=[uuid]&token=[redacted]&email=[email]&limit=10&flag", got["query"])
+	assert.Equal(t, "0d5b8c8e-8f43-4b8e-9d55-0c1d1c0f5a11", got["request_id"], "request IDs stay for triage")
+	assert.EqualValues(t, 200, got["status"])
+
+	log.Error("notify "+uid+" failed",
+		slog.Any("error", errors.New("send to ann.lee+subs@example.co.uk: refused")),
+		slog.Any("user", uuid.MustParse(uid)),
+		slog.String("api_key", "secret"),
+	)
+	got = decode(t, &buf)
+	assert.Equal(t, "notify [uuid] failed", got["msg"])
+	assert.Equal(t, "send to [email]: refused", got["error"])
+	assert.Equal(t, "[uuid]", got["user"])
+	assert.Equal(t, "[redacted]", got["api_key"])
+}
+
+func TestRedactHandler_AttrsAndGroups(t *testing.T) {
+	var buf bytes.Buffer
+	log := newTestLogger(&buf).With("user_id", "60601fee-2bf1-4721-ae6f-7636e79a0cba").WithGroup("req")
+
+	log.Info("done", slog.Group("auth", slog.String("email", "ann@example.com"), slog.String("token", "t")), slog.Int("n", 1))
+	got := decode(t, &buf)
+	assert.Equal(t, "[uuid]", got["user_id"])
+	req := got["req"].(map[string]any)
+	assert.Equal(t, map[string]any{"email": "[email]", "token": "[redacted]"}, req["auth"])
+	assert.EqualValues(t, 1, req["n"])
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Backup holds metrics about scheduled database backups exported to Prometheus
+type Backup struct {
+	runs        *prometheus.CounterVec
+	duration    prometheus.Gauge
+	size        prometheus.Gauge
+	lastSuccess prometheus.Gauge
+}
+
+// NewBackup creates the backup collectors and registers them in reg
+func NewBackup(reg prometheus.Registerer, opts Options) *Backup {
+	b := &Backup{
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   opts.Namespace,
+			Name:        "backup_runs_total",
+			Help:        "Number of database backups attempted, by result.",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"result"}),
+		duration: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   opts.Namespace,
+			Name:        "backup_last_duration_seconds",
+			Help:        "How long the latest backup attempt took.",
+			ConstLabels: opts.ConstLabels,
+		}),
+		size: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   opts.Namespace,
+			Name:        "backup_last_size_bytes",
+			Help:        "Compressed size of the latest successful backup.",
+			ConstLabels: opts.ConstLabels,
+		}),
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   opts.Namespace,
+			Name:        "backup_last_success_timestamp_seconds",
+			Help:        "Unix time of the latest successful backup.",
+			ConstLabels: opts.ConstLabels,
+		}),
+	}
+	reg.MustRegister(b.runs, b.duration, b.size, b.lastSuccess)
+	return b
+}
+
+// BackupDone records the outcome of a backup attempt
+func (b *Backup) BackupDone(took time.Duration, size int64, err error) {
+	b.duration.Set(took.Seconds())
+	if err != nil {
+		b.runs.WithLabelValues("failure").Inc()
+		return
+	}
+	b.runs.WithLabelValues("success").Inc()
+	b.size.Set(float64(size))
+	b.lastSuccess.SetToCurrentTime()
+}
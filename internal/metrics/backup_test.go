@@ -0,0 +1,26 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackup(t *testing.T) {
+	b := NewBackup(prometheus.NewRegistry(), Options{})
+
+	b.BackupDone(3*time.Second, 2048, nil)
+	assert.Equal(t, float64(1), testutil.ToFloat64(b.runs.WithLabelValues("success")))
+	assert.Equal(t, float64(2048), testutil.ToFloat64(b.size))
+	assert.Equal(t, float64(3), testutil.ToFloat64(b.duration))
+	assert.Positive(t, testutil.ToFloat64(b.lastSuccess))
+
+	b.BackupDone(time.Second, 0, errors.New("db down"))
+	assert.Equal(t, float64(1), testutil.ToFloat64(b.runs.WithLabelValues("failure")))
+	assert.Equal(t, float64(2048), testutil.ToFloat64(b.size), "a failure keeps the last good size")
+	assert.Equal(t, float64(1), testutil.ToFloat64(b.duration))
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"subs_tracker/internal/usecase"
+)
+
+// serviceLabels - how many services, those with the most active subscriptions, get a series of their own in
+// subscriptions_active; names are typed by users, so the rest are summed into service="other"
+const serviceLabels = 20
+
+// Business holds domain metrics about subscriptions exported to Prometheus
+type Business struct {
+	created     prometheus.Counter
+	active      *prometheus.GaugeVec
+	monthlyCost prometheus.Gauge
+}
+
+// NewBusiness creates the domain collectors and registers them in reg
+func NewBusiness(reg prometheus.Registerer, opts Options) *Business {
+	b := &Business{
+		created: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   opts.Namespace,
+			Name:        "subscriptions_created_total",
+			Help:        "Number of subscriptions created since start.",
+			ConstLabels: opts.ConstLabels,
+		}),
+		active: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   opts.Namespace,
+			Name:        "subscriptions_active",
+			Help:        "Number of subscriptions active in the current month per service, the less used ones as other.",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"service"}),
+		monthlyCost: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   opts.Namespace,
+			Name:        "total_monthly_cost",
+			Help:        "Summed cost of subscriptions active in the current month.",
+			ConstLabels: opts.ConstLabels,
+		}),
+	}
+	reg.MustRegister(b.created, b.active, b.monthlyCost)
+	return b
+}
+
+// SubCreated increments the created subscriptions counter
+func (b *Business) SubCreated() {
+	b.created.Inc()
+}
+
+// SetStats replaces per-service gauges and the total monthly cost with fresh statistics; services past the
+// first serviceLabels by active subscriptions are published as "other"
+func (b *Business) SetStats(stats []usecase.ServiceStats) {
+	b.active.Reset()
+	stats = slices.Clone(stats)
+	slices.SortStableFunc(stats, func(x, y usecase.ServiceStats) int { return cmp.Compare(y.Active, x.Active) })
+	var total int64
+	for i, s := range stats {
+		service := s.ServiceName
+		if i >= serviceLabels {
+			service = "other"
+		}
+		b.active.WithLabelValues(service).Add(float64(s.Active))
+		total += s.MonthlyCost
+	}
+	b.monthlyCost.Set(float64(total))
+}
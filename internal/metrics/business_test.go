@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"subs_tracker/internal/usecase"
+)
+
+func TestBusiness(t *testing.T) {
+	b := NewBusiness(prometheus.NewRegistry(), Options{})
+
+	b.SubCreated()
+	b.SubCreated()
+	assert.Equal(t, float64(2), testutil.ToFloat64(b.created))
+
+	b.SetStats([]usecase.ServiceStats{
+		{ServiceName: "Netflix", Active: 2, MonthlyCost: 1098},
+		{ServiceName: "Spotify", Active: 1, MonthlyCost: 299},
+	})
+	assert.Equal(t, float64(2), testutil.ToFloat64(b.active.WithLabelValues("Netflix")))
+	assert.Equal(t, float64(1397), testutil.ToFloat64(b.monthlyCost))
+
+	b.SetStats([]usecase.ServiceStats{{ServiceName: "Spotify", Active: 1, MonthlyCost: 299}})
+	assert.Equal(t, 1, testutil.CollectAndCount(b.active))
+	assert.Equal(t, float64(299), testutil.ToFloat64(b.monthlyCost))
+
+	stats := []usecase.ServiceStats{{ServiceName: "Netflix", Active: 100, MonthlyCost: 999}}
+	for i := range 2 * serviceLabels {
+		stats = append(stats, usecase.ServiceStats{ServiceName: fmt.Sprintf("typed by user %d", i), Active: 1, MonthlyCost: 100})
+	}
+	b.SetStats(stats)
+	assert.Equal(t, serviceLabels+1, testutil.CollectAndCount(b.active), "series are bounded")
+	assert.Equal(t, float64(100), testutil.ToFloat64(b.active.WithLabelValues("Netflix")))
+	assert.Equal(t, float64(serviceLabels+1), testutil.ToFloat64(b.active.WithLabelValues("other")))
+	assert.Equal(t, float64(999+2*serviceLabels*100), testutil.ToFloat64(b.monthlyCost))
+}
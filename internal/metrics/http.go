@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Label values standing for many routes or methods, so that scanners and junk requests add no series
+const (
+	// RouteUnmatched - requests that matched no route
+	RouteUnmatched = "unmatched"
+	// RouteOther - matched routes left out of Options.Routes
+	RouteOther = "other"
+	// MethodOther - methods no route is registered for
+	MethodOther = "OTHER"
+)
+
+// knownMethods - methods recorded under their own name
+var knownMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodPatch: true, http.MethodDelete: true, http.MethodOptions: true,
+}
+
+// HTTP holds request latency metrics of the HTTP gateway
+type HTTP struct {
+	duration      *prometheus.HistogramVec
+	sloViolations *prometheus.CounterVec
+	routes        []string
+}
+
+// NewHTTP creates the request latency histogram and registers it in reg
+func NewHTTP(reg prometheus.Registerer, opts Options) *HTTP {
+	h := &HTTP{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   opts.Namespace,
+			Name:        "http_request_duration_seconds",
+			Help:        "Latency of HTTP requests by method, route and status.",
+			ConstLabels: opts.ConstLabels,
+			Buckets:     opts.buckets(),
+		}, []string{"method", "route", "status"}),
+		sloViolations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   opts.Namespace,
+			Name:        "slo_violations_total",
+			Help:        "HTTP requests slower than the latency budget of their route.",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"method", "route"}),
+		routes: opts.Routes,
+	}
+	reg.MustRegister(h.duration, h.sloViolations)
+	return h
+}
+
+// Observe records a finished request; route is the template the request matched, empty when none
+func (h *HTTP) Observe(method, route string, status int, d time.Duration) {
+	method, route = h.labels(method, route)
+	h.duration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(d.Seconds())
+}
+
+// ObserveSLOViolation counts a request that exceeded the latency budget of its route
+func (h *HTTP) ObserveSLOViolation(method, route string) {
+	h.sloViolations.WithLabelValues(h.labels(method, route)).Inc()
+}
+
+// labels returns the method and route labels of a request, collapsing the values outside the known methods,
+// matched routes and the route allowlist
+func (h *HTTP) labels(method, route string) (string, string) {
+	if !knownMethods[method] {
+		method = MethodOther
+	}
+	switch {
+	case route == "":
+		route = RouteUnmatched
+	case !h.allowed(route):
+		route = RouteOther
+	}
+	return method, route
+}
+
+// allowed reports whether route is recorded under its own label
+func (h *HTTP) allowed(route string) bool {
+	if len(h.routes) == 0 {
+		return true
+	}
+	for _, r := range h.routes {
+		if prefix, ok := strings.CutSuffix(r, "*"); (ok && strings.HasPrefix(route, prefix)) || r == route {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTP_Observe(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	h := NewHTTP(reg, Options{
+		Namespace:   "subs",
+		ConstLabels: prometheus.Labels{"env": "test", "app_instance": "app-1"},
+		Buckets:     []float64{0.1, 1},
+	})
+
+	h.Observe("GET", "/api/v1/subscriptions/:id", 200, 50*time.Millisecond)
+
+	expected := `
+# HELP subs_http_request_duration_seconds Latency of HTTP requests by method, route and status.
+# TYPE subs_http_request_duration_seconds histogram
+subs_http_request_duration_seconds_bucket{app_instance="app-1",env="test",method="GET",route="/api/v1/subscriptions/:id",status="200",le="0.1"} 1
+subs_http_request_duration_seconds_bucket{app_instance="app-1",env="test",method="GET",route="/api/v1/subscriptions/:id",status="200",le="1"} 1
+subs_http_request_duration_seconds_bucket{app_instance="app-1",env="test",method="GET",route="/api/v1/subscriptions/:id",status="200",le="+Inf"} 1
+subs_http_request_duration_seconds_sum{app_instance="app-1",env="test",method="GET",route="/api/v1/subscriptions/:id",status="200"} 0.05
+subs_http_request_duration_seconds_count{app_instance="app-1",env="test",method="GET",route="/api/v1/subscriptions/:id",status="200"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected)))
+}
+
+func TestHTTP_ObserveSLOViolation(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	h := NewHTTP(reg, Options{Namespace: "subs"})
+
+	h.ObserveSLOViolation("GET", "/api/v1/subscriptions")
+	h.ObserveSLOViolation("GET", "/api/v1/subscriptions")
+
+	expected := `
+# HELP subs_slo_violations_total HTTP requests slower than the latency budget of their route.
+# TYPE subs_slo_violations_total counter
+subs_slo_violations_total{method="GET",route="/api/v1/subscriptions"} 2
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "subs_slo_violations_total"))
+}
+
+func TestHTTP_RouteLabels(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	h := NewHTTP(reg, Options{Routes: []string{"/api/v1/subscriptions/:id", "/api/v1/admin/*"}})
+
+	h.ObserveSLOViolation("GET", "/api/v1/subscriptions/:id")
+	h.ObserveSLOViolation("POST", "/api/v1/admin/users/reassign")
+	h.ObserveSLOViolation("GET", "/api/v1/subscriptions")
+	h.ObserveSLOViolation("GET", "")
+	h.ObserveSLOViolation("PROPFIND", "")
+
+	expected := `
+# HELP slo_violations_total HTTP requests slower than the latency budget of their route.
+# TYPE slo_violations_total counter
+slo_violations_total{method="GET",route="/api/v1/subscriptions/:id"} 1
+slo_violations_total{method="POST",route="/api/v1/admin/users/reassign"} 1
+slo_violations_total{method="GET",route="other"} 1
+slo_violations_total{method="GET",route="unmatched"} 1
+slo_violations_total{method="OTHER",route="unmatched"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "slo_violations_total"))
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Migrations holds the wait of startup migrations for their lock exported to Prometheus
+type Migrations struct {
+	waiting prometheus.Gauge
+	waited  prometheus.Counter
+}
+
+// NewMigrations creates the migration collectors and registers them in reg
+func NewMigrations(reg prometheus.Registerer, opts Options) *Migrations {
+	m := &Migrations{
+		waiting: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   opts.Namespace,
+			Name:        "migration_lock_waiting",
+			Help:        "1 while the instance waits for another one to finish the migrations.",
+			ConstLabels: opts.ConstLabels,
+		}),
+		waited: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   opts.Namespace,
+			Name:        "migration_lock_wait_seconds_total",
+			Help:        "Time spent waiting for the migration lock.",
+			ConstLabels: opts.ConstLabels,
+		}),
+	}
+	reg.MustRegister(m.waiting, m.waited)
+	return m
+}
+
+// LockWaiting records whether the instance is waiting for the migration lock
+func (m *Migrations) LockWaiting(waiting bool) {
+	if waiting {
+		m.waiting.Set(1)
+		return
+	}
+	m.waiting.Set(0)
+}
+
+// LockWaited adds the time waited for the migration lock
+func (m *Migrations) LockWaited(d time.Duration) {
+	m.waited.Add(d.Seconds())
+}
@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrations(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := NewMigrations(reg, Options{Namespace: "subs"})
+
+	m.LockWaiting(true)
+	m.LockWaited(1500 * time.Millisecond)
+
+	expected := `
+# HELP subs_migration_lock_wait_seconds_total Time spent waiting for the migration lock.
+# TYPE subs_migration_lock_wait_seconds_total counter
+subs_migration_lock_wait_seconds_total 1.5
+# HELP subs_migration_lock_waiting 1 while the instance waits for another one to finish the migrations.
+# TYPE subs_migration_lock_waiting gauge
+subs_migration_lock_waiting 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected)))
+
+	m.LockWaiting(false)
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.waiting))
+}
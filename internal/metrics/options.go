@@ -0,0 +1,26 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Options - settings shared by every collector exported by the service
+type Options struct {
+	// Namespace - prefix prepended to every metric name
+	Namespace string
+	// ConstLabels - labels attached to every series (env, app_instance)
+	ConstLabels prometheus.Labels
+	// Buckets - upper bounds of latency histogram buckets in seconds
+	Buckets []float64
+	// Routes - route templates recorded by the HTTP metrics, a trailing * matches any rest; other routes are
+	// recorded as "other". Empty records every matched route
+	Routes []string
+}
+
+// buckets returns configured histogram buckets or the Prometheus defaults
+func (o Options) buckets() []float64 {
+	if len(o.Buckets) == 0 {
+		return prometheus.DefBuckets
+	}
+	return o.Buckets
+}
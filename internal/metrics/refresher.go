@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+const defaultRefreshInterval = time.Minute
+
+// Refresher periodically recomputes gauges that cannot be derived from writes alone
+type Refresher struct {
+	interval time.Duration
+	refresh  func(ctx context.Context) error
+	log      *slog.Logger
+}
+
+// NewRefresher creates a refresher calling refresh every interval
+func NewRefresher(interval time.Duration, refresh func(ctx context.Context) error, log *slog.Logger) *Refresher {
+	if interval <= 0 {
+		interval = defaultRefreshInterval
+	}
+	return &Refresher{
+		interval: interval,
+		refresh:  refresh,
+		log:      log,
+	}
+}
+
+// Run refreshes immediately and then on every tick until ctx is cancelled
+func (r *Refresher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.refresh(ctx); err != nil && ctx.Err() == nil {
+			r.log.Warn("metrics refresh failed", slog.Any("error", err))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Repository holds per method call metrics of the subscription repository exported to Prometheus, so that
+// storage regressions show apart from the HTTP latency
+type Repository struct {
+	calls    *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewRepository creates the repository collectors and registers them in reg
+func NewRepository(reg prometheus.Registerer, opts Options) *Repository {
+	r := &Repository{
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   opts.Namespace,
+			Name:        "repository_calls_total",
+			Help:        "Subscription repository calls by method and error type, none for calls that succeeded.",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"method", "error"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   opts.Namespace,
+			Name:        "repository_call_duration_seconds",
+			Help:        "Latency of subscription repository calls by method.",
+			ConstLabels: opts.ConstLabels,
+			Buckets:     opts.buckets(),
+		}, []string{"method"}),
+	}
+	reg.MustRegister(r.calls, r.duration)
+	return r
+}
+
+// RepositoryCall records a finished call of method; errType classifies its error, empty when it succeeded
+func (r *Repository) RepositoryCall(method string, took time.Duration, errType string) {
+	if errType == "" {
+		errType = "none"
+	}
+	r.calls.WithLabelValues(method, errType).Inc()
+	r.duration.WithLabelValues(method).Observe(took.Seconds())
+}
@@ -0,0 +1,23 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepository(t *testing.T) {
+	r := NewRepository(prometheus.NewRegistry(), Options{})
+
+	r.RepositoryCall("SaveSub", 20*time.Millisecond, "")
+	r.RepositoryCall("SaveSub", 30*time.Millisecond, "timeout")
+	r.RepositoryCall("ListSubsByFilter", time.Millisecond, "")
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(r.calls.WithLabelValues("SaveSub", "none")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(r.calls.WithLabelValues("SaveSub", "timeout")))
+	assert.Equal(t, 3, testutil.CollectAndCount(r.calls))
+	assert.Equal(t, 2, testutil.CollectAndCount(r.duration))
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Tables holds database table sizes and growth alerts exported to Prometheus
+type Tables struct {
+	rows   *prometheus.GaugeVec
+	bytes  *prometheus.GaugeVec
+	alerts *prometheus.CounterVec
+}
+
+// NewTables creates the table collectors and registers them in reg
+func NewTables(reg prometheus.Registerer, opts Options) *Tables {
+	t := &Tables{
+		rows: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   opts.Namespace,
+			Name:        "table_rows",
+			Help:        "Estimated live rows of a database table.",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"table"}),
+		bytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   opts.Namespace,
+			Name:        "table_size_bytes",
+			Help:        "Size of a database table on disk, including indexes.",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"table"}),
+		alerts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   opts.Namespace,
+			Name:        "table_growth_alerts_total",
+			Help:        "Table growth alerts raised, by table and reason (rate, size).",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"table", "reason"}),
+	}
+	reg.MustRegister(t.rows, t.bytes, t.alerts)
+	return t
+}
+
+// TableSize records the latest size of a table
+func (t *Tables) TableSize(table string, rows, bytes int64) {
+	t.rows.WithLabelValues(table).Set(float64(rows))
+	t.bytes.WithLabelValues(table).Set(float64(bytes))
+}
+
+// GrowthAlerted counts an alert raised for a table
+func (t *Tables) GrowthAlerted(table, reason string) {
+	t.alerts.WithLabelValues(table, reason).Inc()
+}
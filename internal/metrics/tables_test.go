@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTables(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	tables := NewTables(reg, Options{Namespace: "subs"})
+
+	tables.TableSize("subscriptions", 1200, 4096)
+	tables.TableSize("subscriptions", 1500, 8192)
+	tables.GrowthAlerted("subscriptions", "rate")
+
+	expected := `
+# HELP subs_table_growth_alerts_total Table growth alerts raised, by table and reason (rate, size).
+# TYPE subs_table_growth_alerts_total counter
+subs_table_growth_alerts_total{reason="rate",table="subscriptions"} 1
+# HELP subs_table_rows Estimated live rows of a database table.
+# TYPE subs_table_rows gauge
+subs_table_rows{table="subscriptions"} 1500
+# HELP subs_table_size_bytes Size of a database table on disk, including indexes.
+# TYPE subs_table_size_bytes gauge
+subs_table_size_bytes{table="subscriptions"} 8192
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected)))
+}
@@ -0,0 +1,216 @@
+// Package migrate applies the SQL migrations when the server starts. Replicas starting together take turns
+// on a Postgres advisory lock, so only one of them applies the migrations while the others wait, saying in
+// the logs who holds the lock and in metrics that they are waiting
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"net/url"
+	"path/filepath"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"subs_tracker/internal/repository/subscription/postgres/sqlc"
+	"subs_tracker/pkg/clock"
+)
+
+const (
+	// lockName - the advisory lock key is derived from it, apart from the job locks
+	lockName = "subs_tracker/migrations"
+
+	defaultPollInterval = time.Second
+	defaultReportEvery  = 10 * time.Second
+)
+
+// Recorder — exports the wait for the migration lock, e.g. to Prometheus
+type Recorder interface {
+	// LockWaiting - the instance starts or stops waiting for the lock
+	LockWaiting(waiting bool)
+	// LockWaited - how long the instance waited before it got the lock
+	LockWaited(d time.Duration)
+}
+
+// Migrator applies the migrations of a directory to one database
+type Migrator struct {
+	pool        *pgxpool.Pool
+	dsn         string
+	dir         string
+	log         *slog.Logger
+	schema      string
+	recorder    Recorder
+	clock       clock.Clock
+	poll        time.Duration
+	reportEvery time.Duration
+}
+
+// New creates a migrator of the database of dsn; the lock is taken on a session of pool, which must be
+// connected to the same database. Applies options
+func New(pool *pgxpool.Pool, dsn, dir string, log *slog.Logger, options ...func(*Migrator)) *Migrator {
+	m := &Migrator{
+		pool:        pool,
+		dsn:         dsn,
+		dir:         dir,
+		log:         log,
+		clock:       clock.System,
+		poll:        defaultPollInterval,
+		reportEvery: defaultReportEvery,
+	}
+	for _, o := range options {
+		o(m)
+	}
+	return m
+}
+
+// WithRecorder sets where the wait for the lock is recorded
+func WithRecorder(r Recorder) func(*Migrator) {
+	return func(m *Migrator) {
+		m.recorder = r
+	}
+}
+
+// WithSchema applies the migrations to the schema, creating it when missing, instead of the one of the DSN
+func WithSchema(schema string) func(*Migrator) {
+	return func(m *Migrator) {
+		m.schema = schema
+	}
+}
+
+// WithReportEvery sets how often a waiting instance logs who holds the lock
+func WithReportEvery(d time.Duration) func(*Migrator) {
+	return func(m *Migrator) {
+		if d > 0 {
+			m.reportEvery = d
+		}
+	}
+}
+
+// Key returns the advisory lock key of the migrations
+func Key() int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(lockName))
+	return int64(h.Sum64())
+}
+
+// Up waits for the migration lock, applies every pending migration and releases the lock. Waiting ends with
+// ctx, so a replica stuck behind a hung migration can be stopped
+func (m *Migrator) Up(ctx context.Context) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("migrate: acquire lock session: %w", err)
+	}
+	// the session is closed rather than returned to the pool, so that the lock never outlives it
+	defer func() {
+		_ = conn.Conn().Close(context.WithoutCancel(ctx))
+		conn.Release()
+	}()
+
+	q := sqlc.New(conn)
+	key := Key()
+	if err := m.lock(ctx, q, key); err != nil {
+		return err
+	}
+	defer func() { _, _ = q.AdvisoryUnlock(context.WithoutCancel(ctx), key) }()
+
+	dsn := m.dsn
+	if m.schema != "" {
+		if _, err := conn.Exec(ctx, "CREATE SCHEMA IF NOT EXISTS "+pgx.Identifier{m.schema}.Sanitize()); err != nil {
+			return fmt.Errorf("migrate: create schema %s: %w", m.schema, err)
+		}
+		if dsn, err = withSearchPath(m.dsn, m.schema); err != nil {
+			return err
+		}
+	}
+	return m.apply(dsn)
+}
+
+// withSearchPath returns the postgres:// URL dsn whose sessions work in the schema, where golang-migrate
+// keeps its version table too
+func withSearchPath(dsn, schema string) (string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", fmt.Errorf("migrate: parse dsn: %w", err)
+	}
+	q := u.Query()
+	q.Set("search_path", pgx.Identifier{schema}.Sanitize())
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// lock polls the advisory lock until it is taken, reporting the wait
+func (m *Migrator) lock(ctx context.Context, q *sqlc.Queries, key int64) error {
+	start := m.clock.Now()
+	var reported time.Time
+	for {
+		ok, err := q.TryAdvisoryLock(ctx, key)
+		if err != nil {
+			return fmt.Errorf("migrate: lock: %w", err)
+		}
+		if ok {
+			break
+		}
+		if reported.IsZero() && m.recorder != nil {
+			m.recorder.LockWaiting(true)
+			defer m.recorder.LockWaiting(false)
+		}
+		if now := m.clock.Now(); reported.IsZero() || now.Sub(reported) >= m.reportEvery {
+			reported = now
+			m.reportHolder(ctx, q, key, now.Sub(start))
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("migrate: wait for lock: %w", ctx.Err())
+		case <-time.After(m.poll):
+		}
+	}
+	if !reported.IsZero() {
+		waited := m.clock.Now().Sub(start)
+		if m.recorder != nil {
+			m.recorder.LockWaited(waited)
+		}
+		m.log.Info("migration lock taken", slog.Duration("waited", waited))
+	}
+	return nil
+}
+
+// reportHolder logs the session holding the lock; it may have released it in the meantime
+func (m *Migrator) reportHolder(ctx context.Context, q *sqlc.Queries, key int64, waited time.Duration) {
+	attrs := []any{slog.Duration("waited", waited)}
+	if h, err := q.AdvisoryLockHolder(ctx, key); err == nil {
+		attrs = append(attrs,
+			slog.Int("holder_pid", int(h.Pid)),
+			slog.String("holder_application", h.ApplicationName),
+			slog.String("holder_addr", h.ClientAddr),
+		)
+	}
+	m.log.Warn("waiting for the migration lock held by another instance", attrs...)
+}
+
+// apply runs the pending migrations with golang-migrate, which takes its own lock too
+func (m *Migrator) apply(dsn string) error {
+	abs, err := filepath.Abs(m.dir)
+	if err != nil {
+		return fmt.Errorf("migrate: path: %w", err)
+	}
+	mg, err := migrate.New("file://"+filepath.ToSlash(abs), dsn)
+	if err != nil {
+		return fmt.Errorf("migrate: open: %w", err)
+	}
+	defer func() { _, _ = mg.Close() }()
+
+	err = mg.Up()
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("migrate: up: %w", err)
+	}
+	version, dirty, _ := mg.Version()
+	m.log.Info("database migrated", slog.String("schema", m.schema), slog.Uint64("version", uint64(version)), slog.Bool("changed", err == nil), slog.Bool("dirty", dirty))
+	return nil
+}
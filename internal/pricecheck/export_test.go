@@ -0,0 +1,11 @@
+package pricecheck
+
+import "time"
+
+// DefaultInterval is the interval NewJob falls back to, for the external tests
+const DefaultInterval = defaultInterval
+
+// Interval reports how often the job runs, for the external tests
+func (j *Job) Interval() time.Duration {
+	return j.interval
+}
@@ -0,0 +1,201 @@
+// Package pricecheck compares the cost of current subscriptions with the list price of their service in the
+// catalog and opens a review for every subscription that differs, e.g. after the catalog picked up a new price
+// list. Subscriptions are never changed here: a list price misses discounts, family plans and regional prices,
+// so an operator looks at each review and edits the subscription through the API when the price did change
+package pricecheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"subs_tracker/internal/enrichment"
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/clock"
+	"subs_tracker/pkg/dates"
+	"subs_tracker/pkg/pagination"
+)
+
+const (
+	defaultInterval = 24 * time.Hour
+	pageSize        = 500
+)
+
+// Limits - page sizes of the open reviews
+var Limits = pagination.Limits{Default: 50, Max: 500}
+
+// ErrNotFound - no open review with the ID
+var ErrNotFound = errors.New("price review not found")
+
+// Review — a subscription whose cost differs from the catalog price of its service
+type Review struct {
+	ID             int64
+	SubscriptionID int64
+	PublicID       entity.PublicID
+	UserID         entity.UserID
+	ServiceName    string
+	// Cost - monthly cost of the subscription when the review was opened
+	Cost int64
+	// CatalogPrice - monthly list price of the service in the catalog
+	CatalogPrice int64
+	OpenedAt     time.Time
+	// ResolvedAt - when an operator closed the review, nil while open
+	ResolvedAt *time.Time
+}
+
+// Store — storage of the reviews
+type Store interface {
+	// OpenReview - store the review and set its ID unless the subscription already has one, open or
+	// resolved, for the same catalog price; report whether it was stored
+	OpenReview(ctx context.Context, r *Review) (bool, error)
+	// ListOpen - up to limit open reviews, oldest first
+	ListOpen(ctx context.Context, limit int) ([]Review, error)
+	// Resolve - close the open review with the ID at the time given, ErrNotFound when there is none
+	Resolve(ctx context.Context, id int64, at time.Time) (Review, error)
+	// DeleteUser - remove every review of the user, open or resolved
+	DeleteUser(ctx context.Context, user entity.UserID) error
+}
+
+// Catalog — source of the list prices, e.g. *enrichment.Enricher
+type Catalog interface {
+	Lookup(ctx context.Context, name string) (enrichment.ServiceInfo, bool)
+}
+
+// Source — the subscriptions to check
+type Source interface {
+	ListSubsByFilter(ctx context.Context, f usecase.SubFilter) ([]*entity.Subscription, error)
+}
+
+// Reviews opens, lists and resolves the reviews kept in a store
+type Reviews struct {
+	store Store
+	clock clock.Clock
+}
+
+// NewReviews creates reviews kept in store and applies options
+func NewReviews(store Store, options ...func(*Reviews)) *Reviews {
+	r := &Reviews{store: store, clock: clock.System}
+	for _, o := range options {
+		o(r)
+	}
+	return r
+}
+
+// WithReviewsClock sets the source of the time reviews are opened and resolved at
+func WithReviewsClock(c clock.Clock) func(*Reviews) {
+	return func(r *Reviews) {
+		if c != nil {
+			r.clock = c
+		}
+	}
+}
+
+// Open returns up to limit open reviews, oldest first; limit is clamped by Limits
+func (r *Reviews) Open(ctx context.Context, limit int) ([]Review, error) {
+	out, err := r.store.ListOpen(ctx, Limits.Clamp(limit))
+	if err != nil {
+		return nil, fmt.Errorf("list price reviews: %w", err)
+	}
+	return out, nil
+}
+
+// Resolve closes the open review with the ID, ErrNotFound when there is none
+func (r *Reviews) Resolve(ctx context.Context, id int64) (Review, error) {
+	out, err := r.store.Resolve(ctx, id, r.clock.Now())
+	if err != nil {
+		return Review{}, fmt.Errorf("resolve price review: %w", err)
+	}
+	return out, nil
+}
+
+// Forget removes every review of the user
+func (r *Reviews) Forget(ctx context.Context, user entity.UserID) error {
+	if err := r.store.DeleteUser(ctx, user); err != nil {
+		return fmt.Errorf("forget price reviews: %w", err)
+	}
+	return nil
+}
+
+// Job periodically checks the subscriptions active in the current month against the catalog
+type Job struct {
+	interval time.Duration
+	subs     Source
+	catalog  Catalog
+	reviews  *Reviews
+	log      *slog.Logger
+}
+
+// NewJob creates a job checking subs against catalog every interval and opening reviews for the mismatches
+func NewJob(interval time.Duration, subs Source, catalog Catalog, reviews *Reviews, log *slog.Logger) *Job {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return &Job{interval: interval, subs: subs, catalog: catalog, reviews: reviews, log: log}
+}
+
+// Run checks immediately and then on every tick until ctx is cancelled
+func (j *Job) Run(ctx context.Context) error {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		opened, err := j.Check(ctx)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			j.log.Warn("price check failed", slog.Any("error", err))
+		case opened > 0:
+			j.log.Info("price reviews opened", slog.Int("opened", opened))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check pages through the paid subscriptions active in the current month and opens a review for each whose
+// cost differs from the catalog price of its service; services without a price in the catalog are skipped.
+// It returns how many reviews were opened, those already opened for the same price are not counted
+func (j *Job) Check(ctx context.Context) (int, error) {
+	month := dates.MonthStart(j.reviews.clock.Now())
+	f := usecase.SubFilter{Period: &usecase.Period{From: month, To: month}, Limit: pageSize}
+	opened := 0
+	for {
+		page, err := j.subs.ListSubsByFilter(ctx, f)
+		if err != nil {
+			return opened, fmt.Errorf("price check: %w", err)
+		}
+		for _, sub := range page {
+			if sub.Free() {
+				continue
+			}
+			info, ok := j.catalog.Lookup(ctx, sub.ServiceName)
+			if !ok || info.Price <= 0 || info.Price == sub.Cost {
+				continue
+			}
+			stored, err := j.reviews.store.OpenReview(ctx, &Review{
+				SubscriptionID: sub.ID,
+				PublicID:       sub.PublicID,
+				UserID:         sub.UserID,
+				ServiceName:    sub.ServiceName,
+				Cost:           sub.Cost,
+				CatalogPrice:   info.Price,
+				OpenedAt:       j.reviews.clock.Now(),
+			})
+			if err != nil {
+				return opened, fmt.Errorf("price check: open review: %w", err)
+			}
+			if stored {
+				opened++
+			}
+		}
+		if len(page) < f.Limit {
+			return opened, nil
+		}
+		last := page[len(page)-1]
+		f.After = usecase.CursorAt(last)
+	}
+}
@@ -0,0 +1,96 @@
+package pricecheck_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/internal/enrichment"
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/pricecheck"
+	"subs_tracker/internal/repository/subscription/memory"
+	"subs_tracker/internal/storetest"
+	"subs_tracker/pkg/clock"
+)
+
+// priceList - catalog with a price per lower-cased service name
+type priceList map[string]int64
+
+func (p priceList) Lookup(_ context.Context, name string) (enrichment.ServiceInfo, bool) {
+	price, ok := p[strings.ToLower(name)]
+	return enrichment.ServiceInfo{Price: price}, ok
+}
+
+func TestJob_Check(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, time.July, 10, 12, 0, 0, 0, time.UTC)
+	ann := entity.UserID(uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba"))
+	month := func(m time.Month) time.Time { return time.Date(2025, m, 1, 0, 0, 0, 0, time.UTC) }
+	ended := month(5)
+
+	repo := memory.NewRepository()
+	var netflix *entity.Subscription
+	for _, s := range []entity.Subscription{
+		{UserID: ann, ServiceName: "Netflix", Cost: 799, DateFrom: month(1)},
+		{UserID: ann, ServiceName: "Spotify", Cost: 299, DateFrom: month(1)},
+		{UserID: ann, ServiceName: "Netflix", Cost: 0, DateFrom: month(1)},
+		{UserID: ann, ServiceName: "Netflix", Cost: 599, DateFrom: month(1), DateTo: &ended},
+		{UserID: ann, ServiceName: "Local Gym", Cost: 3000, DateFrom: month(1)},
+	} {
+		saved, err := repo.SaveSub(ctx, &s)
+		require.NoError(t, err)
+		if netflix == nil {
+			netflix = saved
+		}
+	}
+	prices := priceList{"netflix": 999, "spotify": 299, "local gym": 0}
+	reviews := pricecheck.NewReviews(storetest.NewReviewStore(), pricecheck.WithReviewsClock(clock.NewFake(now)))
+	job := pricecheck.NewJob(0, repo, prices, reviews, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.Equal(t, pricecheck.DefaultInterval, job.Interval())
+
+	opened, err := job.Check(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, opened, "only the current paid subscription with another price")
+	open, err := reviews.Open(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, open, 1)
+	assert.Equal(t, pricecheck.Review{
+		ID:             1,
+		SubscriptionID: netflix.ID,
+		PublicID:       netflix.PublicID,
+		UserID:         ann,
+		ServiceName:    "Netflix",
+		Cost:           799,
+		CatalogPrice:   999,
+		OpenedAt:       now,
+	}, open[0])
+
+	opened, err = job.Check(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, opened, "the review is not opened twice")
+
+	resolved, err := reviews.Resolve(ctx, open[0].ID)
+	require.NoError(t, err)
+	require.NotNil(t, resolved.ResolvedAt)
+	_, err = reviews.Resolve(ctx, open[0].ID)
+	assert.ErrorIs(t, err, pricecheck.ErrNotFound)
+	opened, err = job.Check(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, opened, "a resolved review stays resolved while the price is the same")
+
+	prices["netflix"] = 1199
+	opened, err = job.Check(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, opened, "a new price list opens a new review")
+	open, err = reviews.Open(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, open, 1)
+	assert.Equal(t, int64(1199), open[0].CatalogPrice)
+}
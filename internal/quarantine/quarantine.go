@@ -0,0 +1,182 @@
+// Package quarantine keeps the rows of an import that failed validation instead of failing the whole import.
+// Each held row keeps the subscription as it was imported and why it was rejected; the user fixes it and
+// submits it again, which creates the subscription and drops the row, or discards it
+package quarantine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/clock"
+	"subs_tracker/pkg/pagination"
+)
+
+// Limits - page sizes of the held rows
+var Limits = pagination.Limits{Default: 50, Max: 500}
+
+// ErrNotFound - the user has no held row with the ID
+var ErrNotFound = errcode.New(errcode.QuarantineNotFound, "quarantined row not found")
+
+// Row — an imported subscription that failed validation
+type Row struct {
+	ID          int64
+	UserID      entity.UserID
+	ServiceName string
+	Cost        int64
+	StartDate   time.Time
+	EndDate     *time.Time
+	// Reason, Code - the validation error the row was held for
+	Reason string
+	Code   errcode.Code
+	HeldAt time.Time
+	// Key - the import key of the row, see usecase.ImportRow; a row is held once per non-empty key
+	Key string
+}
+
+// Subscription — the subscription the row was imported as
+func (r Row) Subscription() *entity.Subscription {
+	return &entity.Subscription{
+		UserID:      r.UserID,
+		ServiceName: r.ServiceName,
+		Cost:        r.Cost,
+		DateFrom:    r.StartDate,
+		DateTo:      r.EndDate,
+	}
+}
+
+// Fix — corrections of a held row; nil fields keep the imported value
+type Fix struct {
+	ServiceName *string
+	Cost        *int64
+	StartDate   *time.Time
+	EndDate     *time.Time
+	// ClearEndDate - drop the end date, for a row rejected for it
+	ClearEndDate bool
+}
+
+// Store — storage of the held rows
+type Store interface {
+	// Hold - store the rows and set their IDs; a row with the key of a held one gets the ID of that one
+	Hold(ctx context.Context, rows []Row) error
+	// List - up to limit rows of the user, oldest first
+	List(ctx context.Context, user entity.UserID, limit int) ([]Row, error)
+	// Get - the row of the user with the ID, ErrNotFound when there is none
+	Get(ctx context.Context, user entity.UserID, id int64) (Row, error)
+	// Delete - remove the row of the user with the ID, ErrNotFound when there is none
+	Delete(ctx context.Context, user entity.UserID, id int64) error
+	// DeleteUser - remove every row of the user
+	DeleteUser(ctx context.Context, user entity.UserID) error
+}
+
+// Creator — where a fixed row is submitted again, e.g. *usecase.Subscription
+type Creator interface {
+	RegisterSub(ctx context.Context, sub *entity.Subscription) (*entity.Subscription, error)
+}
+
+// Box holds, lists, resubmits and discards the rows kept in a store
+type Box struct {
+	store Store
+	clock clock.Clock
+}
+
+var _ usecase.Quarantine = (*Box)(nil)
+
+// NewBox creates a box kept in store and applies options
+func NewBox(store Store, options ...func(*Box)) *Box {
+	b := &Box{store: store, clock: clock.System}
+	for _, o := range options {
+		o(b)
+	}
+	return b
+}
+
+// WithClock sets the source of the time rows are held at
+func WithClock(c clock.Clock) func(*Box) {
+	return func(b *Box) {
+		if c != nil {
+			b.clock = c
+		}
+	}
+}
+
+// Hold keeps the rejected subscriptions of an import with the errors they were rejected for
+func (b *Box) Hold(ctx context.Context, rejected []usecase.RejectedSub) error {
+	now := b.clock.Now()
+	rows := make([]Row, 0, len(rejected))
+	for _, r := range rejected {
+		rows = append(rows, Row{
+			UserID:      r.Sub.UserID,
+			ServiceName: r.Sub.ServiceName,
+			Cost:        r.Sub.Cost,
+			StartDate:   r.Sub.DateFrom,
+			EndDate:     r.Sub.DateTo,
+			Reason:      r.Err.Error(),
+			Code:        errcode.Of(r.Err),
+			HeldAt:      now,
+			Key:         r.Key,
+		})
+	}
+	if err := b.store.Hold(ctx, rows); err != nil {
+		return fmt.Errorf("hold rows: %w", err)
+	}
+	return nil
+}
+
+// List returns up to limit held rows of the user, oldest first; limit is clamped by Limits
+func (b *Box) List(ctx context.Context, user entity.UserID, limit int) ([]Row, error) {
+	out, err := b.store.List(ctx, user, Limits.Clamp(limit))
+	if err != nil {
+		return nil, fmt.Errorf("list quarantined rows: %w", err)
+	}
+	return out, nil
+}
+
+// Resubmit applies the fix to the held row and creates its subscription with create, then drops the row.
+// A row failing validation again stays held as it was, with the error returned
+func (b *Box) Resubmit(ctx context.Context, user entity.UserID, id int64, fix Fix, create Creator) (*entity.Subscription, error) {
+	row, err := b.store.Get(ctx, user, id)
+	if err != nil {
+		return nil, err
+	}
+	sub := row.Subscription()
+	if fix.ServiceName != nil {
+		sub.ServiceName = *fix.ServiceName
+	}
+	if fix.Cost != nil {
+		sub.Cost = *fix.Cost
+	}
+	if fix.StartDate != nil {
+		sub.DateFrom = *fix.StartDate
+	}
+	if fix.EndDate != nil {
+		sub.DateTo = fix.EndDate
+	}
+	if fix.ClearEndDate {
+		sub.DateTo = nil
+	}
+	created, err := create.RegisterSub(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.store.Delete(ctx, user, id); err != nil {
+		return created, fmt.Errorf("drop resubmitted row: %w", err)
+	}
+	return created, nil
+}
+
+// Discard drops the held row without creating anything, ErrNotFound when there is none
+func (b *Box) Discard(ctx context.Context, user entity.UserID, id int64) error {
+	return b.store.Delete(ctx, user, id)
+}
+
+// Forget drops every held row of the user
+func (b *Box) Forget(ctx context.Context, user entity.UserID) error {
+	if err := b.store.DeleteUser(ctx, user); err != nil {
+		return fmt.Errorf("forget quarantine: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package quarantine_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/quarantine"
+	"subs_tracker/internal/repository/subscription/memory"
+	"subs_tracker/internal/storetest"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/clock"
+)
+
+func TestBox_ImportAndResubmit(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, time.July, 10, 12, 0, 0, 0, time.UTC)
+	ann := entity.UserID(uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba"))
+	bob := entity.UserID(uuid.MustParse("7a0b3c1e-51f2-4f4e-9d0e-0c6c3c1b2a11"))
+	jun := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+	box := quarantine.NewBox(storetest.NewQuarantineStore(), quarantine.WithClock(clock.NewFake(now)))
+	repo := memory.NewRepository()
+	subs := usecase.NewSubscription(repo,
+		usecase.WithClock(clock.NewFake(now)),
+		usecase.WithQuarantine(box),
+		usecase.WithImportLimits(usecase.ImportLimits{MaxRows: 3, ChunkSize: 2}),
+	)
+
+	_, err := subs.ImportSubs(ctx, []usecase.ImportRow{{}, {}, {}, {}})
+	require.ErrorIs(t, err, usecase.ErrImportTooLarge)
+
+	rows := func() []usecase.ImportRow {
+		return []usecase.ImportRow{
+			{Key: "n1", Sub: &entity.Subscription{UserID: ann, ServiceName: "Netflix", Cost: 799, DateFrom: jun}},
+			{Key: "n2", Sub: &entity.Subscription{UserID: ann, ServiceName: "Spotify", Cost: -299, DateFrom: jun}},
+			{Key: "n3", Sub: &entity.Subscription{UserID: ann, ServiceName: "Kinopoisk", Cost: 399, DateFrom: jun}},
+		}
+	}
+	res, err := subs.ImportSubs(ctx, rows())
+	require.NoError(t, err)
+	assert.Len(t, res.Created, 2, "two chunks, the second of one row")
+	assert.Equal(t, 1, res.Quarantined)
+
+	again, err := subs.ImportSubs(ctx, rows())
+	require.NoError(t, err)
+	assert.Equal(t, res.Created, again.Created, "confirming the same rows again creates nothing")
+	saved, err := repo.ListSubsByFilter(ctx, usecase.SubFilter{UserID: ann, Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, saved, 2)
+
+	held, err := box.List(ctx, ann, 0)
+	require.NoError(t, err)
+	require.Len(t, held, 1, "and holds nothing twice")
+	assert.Equal(t, "Spotify", held[0].ServiceName)
+	assert.Equal(t, errcode.CostNegative, held[0].Code)
+	assert.Equal(t, now, held[0].HeldAt)
+	others, err := box.List(ctx, bob, 0)
+	require.NoError(t, err)
+	assert.Empty(t, others)
+
+	_, err = box.Resubmit(ctx, bob, held[0].ID, quarantine.Fix{}, subs)
+	assert.ErrorIs(t, err, quarantine.ErrNotFound, "rows of other users are not found")
+	_, err = box.Resubmit(ctx, ann, held[0].ID, quarantine.Fix{}, subs)
+	assert.ErrorIs(t, err, usecase.ErrInvalidSubscription)
+	still, err := box.List(ctx, ann, 0)
+	require.NoError(t, err)
+	assert.Equal(t, held, still, "a row failing again stays as it was")
+
+	cost := int64(299)
+	created, err := box.Resubmit(ctx, ann, held[0].ID, quarantine.Fix{Cost: &cost}, subs)
+	require.NoError(t, err)
+	assert.Equal(t, "Spotify", created.ServiceName)
+	assert.Equal(t, cost, created.Cost)
+	held, err = box.List(ctx, ann, 0)
+	require.NoError(t, err)
+	assert.Empty(t, held)
+	assert.ErrorIs(t, box.Discard(ctx, ann, created.ID), quarantine.ErrNotFound)
+}
+
+func TestSubscription_ImportSubsWithoutQuarantine(t *testing.T) {
+	ctx := context.Background()
+	ann := entity.UserID(uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba"))
+	jun := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+	repo := memory.NewRepository()
+
+	_, err := usecase.NewSubscription(repo).ImportSubs(ctx, []usecase.ImportRow{
+		{Sub: &entity.Subscription{UserID: ann, ServiceName: "Netflix", Cost: 799, DateFrom: jun}},
+		{Sub: &entity.Subscription{UserID: ann, ServiceName: "", Cost: 299, DateFrom: jun}},
+	})
+	require.ErrorIs(t, err, usecase.ErrInvalidSubscription)
+	saved, err := repo.ListSubsByFilter(ctx, usecase.SubFilter{UserID: ann, Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, saved, "nothing is saved when a row fails")
+}
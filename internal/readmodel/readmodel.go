@@ -0,0 +1,199 @@
+// Package readmodel keeps analytics reads off the subscriptions table: a projection fed by subscription events
+// stores how each user's monthly spend changes from month to month, and per-user monthly totals are summed
+// from those changes instead of scanning every subscription
+package readmodel
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/events"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/dates"
+)
+
+const (
+	defaultRebuildInterval = time.Hour
+	pageSize               = 500
+)
+
+// Change — how a user's monthly spend and number of active subscriptions change from Month on
+type Change struct {
+	UserID entity.UserID
+	Month  time.Time
+	Cost   int64
+	Subs   int32
+}
+
+// Store — storage of the projection
+type Store interface {
+	// ReplaceUser - replace all changes of the user
+	ReplaceUser(ctx context.Context, user entity.UserID, changes []Change) error
+	// ReplaceAll - replace the whole projection
+	ReplaceAll(ctx context.Context, changes []Change) error
+	// MonthlySpendByUser - get per-user spend of every month in [from, to] with an active subscription
+	MonthlySpendByUser(ctx context.Context, from, to time.Time) ([]usecase.UserMonthSpend, error)
+}
+
+// Source — the transactional side the projection is computed from
+type Source interface {
+	ListSubsByFilter(ctx context.Context, f usecase.SubFilter) ([]*entity.Subscription, error)
+}
+
+// Changes folds subscriptions into changes: a subscription adds its cost from its start month and takes it
+// away after its end month. Months where nothing changes are omitted; the result is ordered by user and month
+func Changes(subs []*entity.Subscription) []Change {
+	type key struct {
+		user  entity.UserID
+		month time.Time
+	}
+	sums := map[key]*Change{}
+	add := func(s *entity.Subscription, month time.Time, sign int64) {
+		k := key{s.UserID, dates.MonthStart(month)}
+		c, ok := sums[k]
+		if !ok {
+			c = &Change{UserID: k.user, Month: k.month}
+			sums[k] = c
+		}
+		c.Cost += sign * s.Cost
+		c.Subs += int32(sign)
+	}
+	for _, s := range subs {
+		add(s, s.DateFrom, 1)
+		if s.DateTo != nil {
+			add(s, s.DateTo.AddDate(0, 1, 0), -1)
+		}
+	}
+
+	out := make([]Change, 0, len(sums))
+	for _, c := range sums {
+		if c.Cost != 0 || c.Subs != 0 {
+			out = append(out, *c)
+		}
+	}
+	slices.SortFunc(out, func(a, b Change) int {
+		if c := cmp.Compare(a.UserID.String(), b.UserID.String()); c != 0 {
+			return c
+		}
+		return a.Month.Compare(b.Month)
+	})
+	return out
+}
+
+// Projector keeps the store in step with the subscriptions: it recomputes the user of every event it gets
+// and rebuilds everything on start and periodically, which also picks up writes that publish no events
+// such as moving subscriptions between users or deactivating a user
+type Projector struct {
+	src      Source
+	store    Store
+	log      *slog.Logger
+	interval time.Duration
+
+	mu sync.Mutex
+}
+
+// NewProjector creates a projector from src into store and applies options
+func NewProjector(src Source, store Store, log *slog.Logger, options ...func(*Projector)) *Projector {
+	p := &Projector{src: src, store: store, log: log, interval: defaultRebuildInterval}
+	for _, o := range options {
+		o(p)
+	}
+	return p
+}
+
+// WithRebuildInterval sets how often the whole projection is rebuilt
+func WithRebuildInterval(d time.Duration) func(*Projector) {
+	return func(p *Projector) {
+		if d > 0 {
+			p.interval = d
+		}
+	}
+}
+
+// Subscriber returns the event bus subscriber updating the projection
+func (p *Projector) Subscriber() events.Subscriber {
+	return events.Subscriber{Name: "read-model", Handle: p.Handle}
+}
+
+// Handle recomputes the changes of the user whose subscription the event is about
+func (p *Projector) Handle(ctx context.Context, e usecase.SubscriptionEvent) error {
+	if e.Subscription == nil || e.Subscription.UserID.IsZero() {
+		return nil
+	}
+	return p.RefreshUser(ctx, e.Subscription.UserID)
+}
+
+// RefreshUser recomputes the changes of one user
+func (p *Projector) RefreshUser(ctx context.Context, user entity.UserID) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	subs, err := p.list(ctx, user)
+	if err != nil {
+		return fmt.Errorf("refresh read model: %w", err)
+	}
+	if err := p.store.ReplaceUser(ctx, user, Changes(subs)); err != nil {
+		return fmt.Errorf("refresh read model: %w", err)
+	}
+	return nil
+}
+
+// Rebuild recomputes the whole projection
+func (p *Projector) Rebuild(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	subs, err := p.list(ctx, entity.UserID{})
+	if err != nil {
+		return fmt.Errorf("rebuild read model: %w", err)
+	}
+	if err := p.store.ReplaceAll(ctx, Changes(subs)); err != nil {
+		return fmt.Errorf("rebuild read model: %w", err)
+	}
+	return nil
+}
+
+// Run rebuilds the projection now and then every interval until ctx is cancelled; a failed rebuild is logged
+// and retried at the next tick
+func (p *Projector) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		start := time.Now()
+		if err := p.Rebuild(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			p.log.Error("read model rebuild failed", slog.Any("error", err))
+		} else {
+			p.log.Debug("read model rebuilt", slog.Duration("took", time.Since(start)))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// list pages through the subscriptions of user, of everyone when user is zero
+func (p *Projector) list(ctx context.Context, user entity.UserID) ([]*entity.Subscription, error) {
+	var out []*entity.Subscription
+	f := usecase.SubFilter{UserID: user, Limit: pageSize}
+	for {
+		page, err := p.src.ListSubsByFilter(ctx, f)
+		if err != nil {
+			return nil, err
+		}
+		if len(page) == 0 {
+			return out, nil
+		}
+		out = append(out, page...)
+		last := page[len(page)-1]
+		f.After = usecase.CursorAt(last)
+	}
+}
@@ -0,0 +1,93 @@
+package readmodel_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/readmodel"
+	"subs_tracker/internal/repository/subscription/memory"
+	"subs_tracker/internal/storetest"
+	"subs_tracker/internal/usecase"
+)
+
+func month(y int, m time.Month) time.Time {
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestChanges(t *testing.T) {
+	ann := entity.UserID(uuid.MustParse("00000000-0000-0000-0000-000000000001"))
+	got := readmodel.Changes([]*entity.Subscription{
+		{UserID: ann, Cost: 400, DateFrom: month(2025, 1), DateTo: ptr(month(2025, 3))},
+		{UserID: ann, Cost: 300, DateFrom: month(2025, 4)},
+		{UserID: ann, Cost: 0, DateFrom: month(2025, 6), DateTo: ptr(month(2025, 6))},
+	})
+	assert.Equal(t, []readmodel.Change{
+		{UserID: ann, Month: month(2025, 1), Cost: 400, Subs: 1},
+		{UserID: ann, Month: month(2025, 4), Cost: -100, Subs: 0},
+		{UserID: ann, Month: month(2025, 6), Cost: 0, Subs: 1},
+		{UserID: ann, Month: month(2025, 7), Cost: 0, Subs: -1},
+	}, got)
+}
+
+func TestProjector_MatchesRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewRepository()
+	ann, bob := entity.UserID(uuid.New()), entity.UserID(uuid.New())
+	for _, s := range []entity.Subscription{
+		{UserID: ann, ServiceName: "Netflix", Cost: 400, DateFrom: month(2025, 1), DateTo: ptr(month(2025, 3))},
+		{UserID: ann, ServiceName: "Spotify", Cost: 200, DateFrom: month(2025, 2)},
+		{UserID: ann, ServiceName: "Free", Cost: 0, DateFrom: month(2025, 5), DateTo: ptr(month(2025, 5))},
+		{UserID: bob, ServiceName: "Netflix", Cost: 999, DateFrom: month(2024, 11), DateTo: ptr(month(2025, 1))},
+	} {
+		_, err := repo.SaveSub(ctx, &s)
+		require.NoError(t, err)
+	}
+	store := storetest.NewSpendStore()
+	p := readmodel.NewProjector(repo, store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, p.Rebuild(ctx))
+
+	same := func() {
+		t.Helper()
+		want, err := repo.MonthlySpendByUser(ctx, month(2024, 10), month(2025, 7))
+		require.NoError(t, err)
+		got, err := store.MonthlySpendByUser(ctx, month(2024, 10), month(2025, 7))
+		require.NoError(t, err)
+		assert.Equal(t, sorted(want), got)
+	}
+	same()
+
+	added, err := repo.SaveSub(ctx, &entity.Subscription{UserID: bob, ServiceName: "Yandex", Cost: 300, DateFrom: month(2025, 3)})
+	require.NoError(t, err)
+	require.NoError(t, p.Handle(ctx, usecase.SubscriptionEvent{Type: usecase.EventSubscriptionCreated, Subscription: added}))
+	same()
+
+	require.NoError(t, repo.DeleteSub(ctx, added.ID, time.Time{}))
+	require.NoError(t, p.Handle(ctx, usecase.SubscriptionEvent{Type: usecase.EventSubscriptionDeleted, Subscription: added}))
+	same()
+}
+
+// sorted orders the memory repository output like the stores: by user, then month
+func sorted(rows []usecase.UserMonthSpend) []usecase.UserMonthSpend {
+	out := slices.Clone(rows)
+	slices.SortFunc(out, func(a, b usecase.UserMonthSpend) int {
+		if c := strings.Compare(a.UserID.String(), b.UserID.String()); c != 0 {
+			return c
+		}
+		return a.Month.Compare(b.Month)
+	})
+	return out
+}
@@ -0,0 +1,369 @@
+// Package reconcile compares the charges subscriptions are expected to make with the charges recorded from bank
+// statements and opens a review item for every month where they disagree: a paid subscription without a charge
+// of its service (missed) or a charge of a service the user tracks no subscription of (unexpected). Only the
+// months a user has recorded charges in are checked, since without a statement there is nothing to compare
+// with; subscriptions are never changed here, an operator looks at each item and fixes the subscription or the
+// bank side
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/importer"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/clock"
+	"subs_tracker/pkg/dates"
+	"subs_tracker/pkg/pagination"
+)
+
+const (
+	defaultInterval = 24 * time.Hour
+	defaultMonths   = 3
+)
+
+// Limits - page sizes of the open items
+var Limits = pagination.Limits{Default: 50, Max: 500}
+
+// ErrNotFound - no open item with the ID
+var ErrNotFound = errors.New("reconciliation item not found")
+
+// Kind — what disagrees in a month
+type Kind string
+
+const (
+	// KindMissed - a paid subscription active in the month has no charge of its service
+	KindMissed Kind = "missed"
+	// KindUnexpected - a charge of a service no subscription of the user covers in the month
+	KindUnexpected Kind = "unexpected"
+)
+
+// Charge — a statement charge matched to a service
+type Charge struct {
+	UserID      entity.UserID
+	ServiceName string
+	// Date - day of the charge
+	Date   time.Time
+	Amount int64
+}
+
+// Item — a service and month where the subscriptions and the charges of a user disagree
+type Item struct {
+	ID          int64
+	Kind        Kind
+	UserID      entity.UserID
+	ServiceName string
+	// Month - first day of the reconciled month
+	Month time.Time
+	// Expected - monthly cost of the subscription, for KindMissed
+	Expected int64
+	// Charged - sum of the charges of the service in Month, for KindUnexpected
+	Charged int64
+	// SubscriptionID, PublicID - the subscription without a charge, for KindMissed
+	SubscriptionID int64
+	PublicID       entity.PublicID
+	OpenedAt       time.Time
+	// ResolvedAt - when an operator closed the item, nil while open
+	ResolvedAt *time.Time
+}
+
+// Store — storage of the charges and the items
+type Store interface {
+	// RecordCharges - store the charges, skipping those already stored for the same user, service, day and
+	// amount; report how many were new
+	RecordCharges(ctx context.Context, charges []Charge) (int, error)
+	// ChargedUsers - users with charges in the month
+	ChargedUsers(ctx context.Context, month time.Time) ([]entity.UserID, error)
+	// Charges - the charges of the user in the month, oldest first
+	Charges(ctx context.Context, user entity.UserID, month time.Time) ([]Charge, error)
+	// OpenItem - store the item and set its ID unless one of the same kind was opened for the service and
+	// month, open or resolved; report whether it was stored
+	OpenItem(ctx context.Context, it *Item) (bool, error)
+	// ListOpen - up to limit open items, of the user only when user is set, oldest first
+	ListOpen(ctx context.Context, user *entity.UserID, limit int) ([]Item, error)
+	// Resolve - close the open item with the ID at the time given, ErrNotFound when there is none
+	Resolve(ctx context.Context, id int64, at time.Time) (Item, error)
+	// DeleteUser - remove every charge and item of the user at once
+	DeleteUser(ctx context.Context, user entity.UserID) error
+}
+
+// Source — the subscriptions the charges are expected from
+type Source interface {
+	ListSubsByFilter(ctx context.Context, f usecase.SubFilter) ([]*entity.Subscription, error)
+}
+
+// Notifier — delivery channel for new items
+type Notifier interface {
+	// NotifyMismatch - deliver a single item
+	NotifyMismatch(ctx context.Context, it Item) error
+}
+
+// LogNotifier delivers items as structured warnings in the service log
+type LogNotifier struct {
+	log *slog.Logger
+}
+
+// NewLogNotifier creates a notifier writing to log
+func NewLogNotifier(log *slog.Logger) *LogNotifier {
+	return &LogNotifier{log: log}
+}
+
+// NotifyMismatch logs the item with the amounts on both sides
+func (n *LogNotifier) NotifyMismatch(_ context.Context, it Item) error {
+	n.log.Warn("charge mismatch",
+		slog.String("kind", string(it.Kind)),
+		slog.String("user_id", it.UserID.String()),
+		slog.String("service_name", it.ServiceName),
+		slog.String("month", dates.Format(it.Month)),
+		slog.Int64("expected", it.Expected),
+		slog.Int64("charged", it.Charged),
+	)
+	return nil
+}
+
+// Ledger records charges and opens, lists and resolves the items kept in a store
+type Ledger struct {
+	store Store
+	clock clock.Clock
+}
+
+// NewLedger creates a ledger kept in store and applies options
+func NewLedger(store Store, options ...func(*Ledger)) *Ledger {
+	l := &Ledger{store: store, clock: clock.System}
+	for _, o := range options {
+		o(l)
+	}
+	return l
+}
+
+// WithClock sets the source of the time items are opened and resolved at
+func WithClock(c clock.Clock) func(*Ledger) {
+	return func(l *Ledger) {
+		if c != nil {
+			l.clock = c
+		}
+	}
+}
+
+// RecordStatement keeps the charges of a bank statement the proposals of an import were matched from, and
+// returns how many were new; transactions no proposal matched are not kept
+func (l *Ledger) RecordStatement(ctx context.Context, user entity.UserID, txs []importer.Transaction, proposals []importer.Proposal) (int, error) {
+	byLine := make(map[int]importer.Transaction, len(txs))
+	for _, tx := range txs {
+		byLine[tx.Line] = tx
+	}
+	var charges []Charge
+	for _, p := range proposals {
+		for _, line := range p.Lines {
+			if tx, ok := byLine[line]; ok && tx.Amount > 0 {
+				charges = append(charges, Charge{UserID: user, ServiceName: p.ServiceName, Date: tx.Date, Amount: tx.Amount})
+			}
+		}
+	}
+	if len(charges) == 0 {
+		return 0, nil
+	}
+	n, err := l.store.RecordCharges(ctx, charges)
+	if err != nil {
+		return 0, fmt.Errorf("record charges: %w", err)
+	}
+	return n, nil
+}
+
+// Open returns up to limit open items, of the user only when user is set, oldest first; limit is clamped by
+// Limits
+func (l *Ledger) Open(ctx context.Context, user *entity.UserID, limit int) ([]Item, error) {
+	out, err := l.store.ListOpen(ctx, user, Limits.Clamp(limit))
+	if err != nil {
+		return nil, fmt.Errorf("list reconciliation items: %w", err)
+	}
+	return out, nil
+}
+
+// Resolve closes the open item with the ID, ErrNotFound when there is none
+func (l *Ledger) Resolve(ctx context.Context, id int64) (Item, error) {
+	out, err := l.store.Resolve(ctx, id, l.clock.Now())
+	if err != nil {
+		return Item{}, fmt.Errorf("resolve reconciliation item: %w", err)
+	}
+	return out, nil
+}
+
+// Forget removes the charges and the items of the user
+func (l *Ledger) Forget(ctx context.Context, user entity.UserID) error {
+	if err := l.store.DeleteUser(ctx, user); err != nil {
+		return fmt.Errorf("forget reconciliation: %w", err)
+	}
+	return nil
+}
+
+// Job periodically reconciles the last complete months
+type Job struct {
+	interval time.Duration
+	months   int
+	subs     Source
+	ledger   *Ledger
+	notifier Notifier
+	log      *slog.Logger
+}
+
+// NewJob creates a job reconciling the last complete months every interval and passing new items to notifier,
+// and applies options
+func NewJob(interval time.Duration, subs Source, ledger *Ledger, notifier Notifier, log *slog.Logger,
+	options ...func(*Job)) *Job {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	j := &Job{interval: interval, months: defaultMonths, subs: subs, ledger: ledger, notifier: notifier, log: log}
+	for _, o := range options {
+		o(j)
+	}
+	return j
+}
+
+// WithMonths sets how many complete months before the current one are reconciled, for statements uploaded late
+func WithMonths(n int) func(*Job) {
+	return func(j *Job) {
+		if n > 0 {
+			j.months = n
+		}
+	}
+}
+
+// Run checks immediately and then on every tick until ctx is cancelled
+func (j *Job) Run(ctx context.Context) error {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		opened, err := j.Check(ctx)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			j.log.Warn("charge reconciliation failed", slog.Any("error", err))
+		case opened > 0:
+			j.log.Info("reconciliation items opened", slog.Int("opened", opened))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check reconciles every user with charges in the complete months before the current one and opens an item
+// for each mismatch; the month in progress is left out, its charges may still come. It returns how many items
+// were opened, those opened before are not counted. A failed notification is logged, the item stays open
+func (j *Job) Check(ctx context.Context) (int, error) {
+	current := dates.MonthStart(j.ledger.clock.Now())
+	opened := 0
+	for i := j.months; i >= 1; i-- {
+		month := current.AddDate(0, -i, 0)
+		users, err := j.ledger.store.ChargedUsers(ctx, month)
+		if err != nil {
+			return opened, fmt.Errorf("reconcile %s: %w", dates.Format(month), err)
+		}
+		for _, user := range users {
+			items, err := j.mismatches(ctx, user, month)
+			if err != nil {
+				return opened, fmt.Errorf("reconcile %s: %w", dates.Format(month), err)
+			}
+			for _, it := range items {
+				it.OpenedAt = j.ledger.clock.Now()
+				stored, err := j.ledger.store.OpenItem(ctx, &it)
+				if err != nil {
+					return opened, fmt.Errorf("reconcile %s: open item: %w", dates.Format(month), err)
+				}
+				if !stored {
+					continue
+				}
+				opened++
+				if err := j.notifier.NotifyMismatch(ctx, it); err != nil {
+					j.log.Warn("charge mismatch notification failed", slog.Int64("item_id", it.ID), slog.Any("error", err))
+				}
+			}
+		}
+	}
+	return opened, nil
+}
+
+// mismatches compares the subscriptions of the user active in the month with the charges of the month, by
+// service name regardless of case
+func (j *Job) mismatches(ctx context.Context, user entity.UserID, month time.Time) ([]Item, error) {
+	charges, err := j.ledger.store.Charges(ctx, user, month)
+	if err != nil {
+		return nil, err
+	}
+	var subs []*entity.Subscription
+	f := usecase.SubFilter{
+		UserID:             user,
+		Period:             &usecase.Period{From: month, To: month},
+		Limit:              pagination.DefaultLimits().Max,
+		IncludeDeactivated: true,
+	}
+	for {
+		page, err := j.subs.ListSubsByFilter(ctx, f)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, page...)
+		if len(page) < f.Limit {
+			break
+		}
+		last := page[len(page)-1]
+		f.After = usecase.CursorAt(last)
+	}
+
+	charged := make(map[string]int64)
+	names := make(map[string]string)
+	for _, ch := range charges {
+		key := serviceKey(ch.ServiceName)
+		charged[key] += ch.Amount
+		names[key] = ch.ServiceName
+	}
+	var out []Item
+	tracked := make(map[string]bool, len(subs))
+	for _, sub := range subs {
+		key := serviceKey(sub.ServiceName)
+		tracked[key] = true
+		if _, ok := charged[key]; ok || sub.Free() {
+			continue
+		}
+		out = append(out, Item{
+			Kind:           KindMissed,
+			UserID:         user,
+			ServiceName:    sub.ServiceName,
+			Month:          month,
+			Expected:       sub.Cost,
+			SubscriptionID: sub.ID,
+			PublicID:       sub.PublicID,
+		})
+	}
+	keys := make([]string, 0, len(charged))
+	for key := range charged {
+		if !tracked[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		out = append(out, Item{
+			Kind:        KindUnexpected,
+			UserID:      user,
+			ServiceName: names[key],
+			Month:       month,
+			Charged:     charged[key],
+		})
+	}
+	return out, nil
+}
+
+// serviceKey compares service names the way the stores keep them unique
+func serviceKey(name string) string {
+	return strings.ToLower(name)
+}
@@ -0,0 +1,171 @@
+package reconcile_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/importer"
+	"subs_tracker/internal/reconcile"
+	"subs_tracker/internal/repository/subscription/memory"
+	"subs_tracker/internal/storetest"
+	"subs_tracker/pkg/clock"
+	"subs_tracker/pkg/pagination"
+)
+
+type notified struct {
+	items []reconcile.Item
+	err   error
+}
+
+func (n *notified) NotifyMismatch(_ context.Context, it reconcile.Item) error {
+	n.items = append(n.items, it)
+	return n.err
+}
+
+func TestJob_Check(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, time.July, 10, 12, 0, 0, 0, time.UTC)
+	ann := entity.UserID(uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba"))
+	bob := entity.UserID(uuid.MustParse("7a0b3c1e-51f2-4f4e-9d0e-0c6c3c1b2a11"))
+	month := func(m time.Month) time.Time { return time.Date(2025, m, 1, 0, 0, 0, 0, time.UTC) }
+	day := func(m time.Month, d int) time.Time { return time.Date(2025, m, d, 0, 0, 0, 0, time.UTC) }
+
+	repo := memory.NewRepository()
+	var spotify *entity.Subscription
+	for _, s := range []entity.Subscription{
+		{UserID: ann, ServiceName: "Netflix", Cost: 799, DateFrom: month(1)},
+		{UserID: ann, ServiceName: "Spotify", Cost: 299, DateFrom: month(1)},
+		{UserID: ann, ServiceName: "Kinopoisk", Cost: 0, DateFrom: month(1)},
+		{UserID: bob, ServiceName: "Netflix", Cost: 799, DateFrom: month(1)},
+	} {
+		saved, err := repo.SaveSub(ctx, &s)
+		require.NoError(t, err)
+		if s.ServiceName == "Spotify" {
+			spotify = saved
+		}
+	}
+
+	ledger := reconcile.NewLedger(storetest.NewLedgerStore(), reconcile.WithClock(clock.NewFake(now)))
+	txs := []importer.Transaction{
+		{Date: day(6, 3), Description: "NETFLIX.COM", Amount: 799, Line: 2},
+		{Date: day(6, 5), Description: "YANDEX*PLUS", Amount: 399, Line: 3},
+		{Date: day(6, 9), Description: "Coffee", Amount: 250, Line: 4},
+		{Date: day(7, 3), Description: "NETFLIX.COM", Amount: 799, Line: 5},
+	}
+	proposals := []importer.Proposal{
+		{ServiceName: "netflix", Lines: []int{2, 5}},
+		{ServiceName: "Yandex Plus", Lines: []int{3}},
+	}
+	n, err := ledger.RecordStatement(ctx, ann, txs, proposals)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n, "unmatched transactions are not kept")
+	n, err = ledger.RecordStatement(ctx, ann, txs, proposals)
+	require.NoError(t, err)
+	assert.Zero(t, n, "uploading the statement again keeps nothing new")
+
+	notifier := &notified{}
+	job := reconcile.NewJob(0, repo, ledger, notifier, slog.New(slog.NewTextHandler(io.Discard, nil)), reconcile.WithMonths(2))
+	assert.Equal(t, reconcile.DefaultInterval, job.Interval())
+
+	opened, err := job.Check(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, opened, "only June: bob has no charges, July is in progress, May has no statement")
+	open, err := ledger.Open(ctx, nil, 0)
+	require.NoError(t, err)
+	require.Len(t, open, 2)
+	assert.Equal(t, reconcile.Item{
+		ID:             1,
+		Kind:           reconcile.KindMissed,
+		UserID:         ann,
+		ServiceName:    "Spotify",
+		Month:          month(6),
+		Expected:       299,
+		SubscriptionID: spotify.ID,
+		PublicID:       spotify.PublicID,
+		OpenedAt:       now,
+	}, open[0])
+	assert.Equal(t, reconcile.Item{
+		ID:          2,
+		Kind:        reconcile.KindUnexpected,
+		UserID:      ann,
+		ServiceName: "Yandex Plus",
+		Month:       month(6),
+		Charged:     399,
+		OpenedAt:    now,
+	}, open[1])
+	assert.Equal(t, open, notifier.items)
+
+	opened, err = job.Check(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, opened, "an item is not opened twice")
+
+	resolved, err := ledger.Resolve(ctx, open[0].ID)
+	require.NoError(t, err)
+	require.NotNil(t, resolved.ResolvedAt)
+	_, err = ledger.Resolve(ctx, open[0].ID)
+	assert.ErrorIs(t, err, reconcile.ErrNotFound)
+	opened, err = job.Check(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, opened, "a resolved item stays resolved")
+
+	mine, err := ledger.Open(ctx, &ann, 0)
+	require.NoError(t, err)
+	assert.Len(t, mine, 1)
+	others, err := ledger.Open(ctx, &bob, 0)
+	require.NoError(t, err)
+	assert.Empty(t, others)
+}
+
+func TestJob_CheckEveryPage(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, time.July, 10, 12, 0, 0, 0, time.UTC)
+	ann := entity.UserID(uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba"))
+	repo := memory.NewRepository()
+	for i := range pagination.MaxLimit {
+		_, err := repo.SaveSub(ctx, &entity.Subscription{
+			UserID: ann, ServiceName: fmt.Sprintf("Free %03d", i), DateFrom: time.Date(2024, time.December, 1, 0, 0, 0, 0, time.UTC),
+		})
+		require.NoError(t, err)
+	}
+	_, err := repo.SaveSub(ctx, &entity.Subscription{
+		UserID: ann, ServiceName: "Yandex Plus", Cost: 399, DateFrom: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+
+	ledger := reconcile.NewLedger(storetest.NewLedgerStore(), reconcile.WithClock(clock.NewFake(now)))
+	_, err = ledger.RecordStatement(ctx, ann,
+		[]importer.Transaction{{Date: time.Date(2025, time.June, 5, 0, 0, 0, 0, time.UTC), Description: "YANDEX*PLUS", Amount: 399, Line: 2}},
+		[]importer.Proposal{{ServiceName: "Yandex Plus", Lines: []int{2}}})
+	require.NoError(t, err)
+
+	opened, err := reconcile.NewJob(0, repo, ledger, &notified{}, slog.New(slog.NewTextHandler(io.Discard, nil)), reconcile.WithMonths(1)).Check(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, opened, "the subscription after the first page covers the charge")
+}
+
+func TestJob_CheckNotifyFailure(t *testing.T) {
+	ctx := context.Background()
+	ann := entity.UserID(uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba"))
+	ledger := reconcile.NewLedger(storetest.NewLedgerStore(), reconcile.WithClock(clock.NewFake(time.Date(2025, time.July, 10, 0, 0, 0, 0, time.UTC))))
+	_, err := ledger.RecordStatement(ctx, ann,
+		[]importer.Transaction{{Date: time.Date(2025, time.June, 3, 0, 0, 0, 0, time.UTC), Amount: 799, Line: 2}},
+		[]importer.Proposal{{ServiceName: "Netflix", Lines: []int{2}}})
+	require.NoError(t, err)
+
+	notifier := &notified{err: errors.New("down")}
+	job := reconcile.NewJob(time.Hour, memory.NewRepository(), ledger, notifier, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	opened, err := job.Check(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, opened, "the item is kept for the operator")
+	assert.Len(t, notifier.items, 1)
+}
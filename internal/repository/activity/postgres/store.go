@@ -0,0 +1,82 @@
+// Package postgres stores the activity feeds of users in the user_activity table
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"subs_tracker/internal/activity"
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/repository/subscription/postgres/sqlc"
+)
+
+// Store — activity.Store over pgx and the sqlc queries
+type Store struct {
+	queries *sqlc.Queries
+}
+
+var _ activity.Store = (*Store)(nil)
+
+// NewStore creates a store bound to the pool
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{queries: sqlc.New(pool)}
+}
+
+// AddEntry inserts the entry and sets its ID
+func (s *Store) AddEntry(ctx context.Context, e *activity.Entry) error {
+	id, err := s.queries.InsertUserActivity(ctx, sqlc.InsertUserActivityParams{
+		UserID:         e.UserID.String(),
+		Kind:           string(e.Kind),
+		SubscriptionID: e.SubscriptionID,
+		PublicID:       e.PublicID,
+		ServiceName:    e.ServiceName,
+		Cost:           e.Cost,
+		PreviousCost:   e.PreviousCost,
+		EndDate:        e.EndDate,
+		OccurredAt:     e.At,
+	})
+	if err != nil {
+		return fmt.Errorf("add activity: %w", err)
+	}
+	e.ID = id
+	return nil
+}
+
+// ListEntries reads a page of the feed of the user, newest first
+func (s *Store) ListEntries(ctx context.Context, userID entity.UserID, before int64, limit int) ([]activity.Entry, error) {
+	rows, err := s.queries.ListUserActivity(ctx, sqlc.ListUserActivityParams{
+		UserID:    userID.String(),
+		Before:    before,
+		PageLimit: int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list activity: %w", err)
+	}
+	out := make([]activity.Entry, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, activity.Entry{
+			ID:             row.ID,
+			UserID:         userID,
+			Kind:           activity.Kind(row.Kind),
+			SubscriptionID: row.SubscriptionID,
+			PublicID:       row.PublicID,
+			ServiceName:    row.ServiceName,
+			Cost:           row.Cost,
+			PreviousCost:   row.PreviousCost,
+			EndDate:        row.EndDate,
+			At:             row.OccurredAt,
+		})
+	}
+	return out, nil
+}
+
+// DeleteEntries removes the feed of the user
+func (s *Store) DeleteEntries(ctx context.Context, userID entity.UserID) (int64, error) {
+	n, err := s.queries.DeleteUserActivity(ctx, userID.String())
+	if err != nil {
+		return 0, fmt.Errorf("delete activity: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,71 @@
+// Package postgres stores the request audit in the request_audit table
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"subs_tracker/internal/audit"
+	"subs_tracker/internal/repository/subscription/postgres/sqlc"
+)
+
+// Store — audit.Store over pgx and the sqlc queries
+type Store struct {
+	pool    *pgxpool.Pool
+	queries *sqlc.Queries
+}
+
+var _ audit.Store = (*Store)(nil)
+
+// NewStore creates a store bound to the pool
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool, queries: sqlc.New(pool)}
+}
+
+// SaveEntries inserts the entries in one transaction
+func (s *Store) SaveEntries(ctx context.Context, entries []audit.Entry) error {
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		q := s.queries.WithTx(tx)
+		for _, e := range entries {
+			err := q.InsertRequestAudit(ctx, sqlc.InsertRequestAuditParams{
+				ReceivedAt: e.At,
+				Method:     e.Method,
+				Route:      e.Route,
+				Path:       e.Path,
+				Status:     int16(e.Status),
+				BodySha256: e.BodySHA256,
+				BodySize:   e.BodySize,
+				Client:     e.Client,
+				RemoteIp:   e.RemoteIP,
+				PeerIp:     e.PeerIP,
+				UserAgent:  e.UserAgent,
+				RequestID:  e.RequestID,
+				TenantID:   e.TenantID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("save request audit: %w", err)
+	}
+	return nil
+}
+
+// PurgeBefore deletes up to limit of the oldest entries received before the instant
+func (s *Store) PurgeBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	n, err := s.queries.DeleteRequestAuditBefore(ctx, sqlc.DeleteRequestAuditBeforeParams{
+		ReceivedBefore: before,
+		PageLimit:      int32(limit),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("purge request audit: %w", err)
+	}
+	return n, nil
+}
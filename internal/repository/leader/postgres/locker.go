@@ -0,0 +1,112 @@
+// Package postgres implements job locks as Postgres session advisory locks
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"subs_tracker/internal/leader"
+	"subs_tracker/internal/repository/subscription/postgres/sqlc"
+)
+
+// keyPrefix keeps the lock keys apart from advisory locks of other applications sharing the database
+const keyPrefix = "subs_tracker/jobs/"
+
+// Locker — leader.Locker over advisory locks of one session. The session holds a pool connection while
+// it exists; when it breaks, Postgres releases every lock taken on it and the next TryLock opens a new one
+type Locker struct {
+	pool *pgxpool.Pool
+
+	mu   sync.Mutex
+	conn *pgxpool.Conn
+}
+
+var _ leader.Locker = (*Locker)(nil)
+
+// NewLocker creates a locker taking its session from the pool
+func NewLocker(pool *pgxpool.Pool) *Locker {
+	return &Locker{pool: pool}
+}
+
+// Key returns the advisory lock key of a job name
+func Key(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(keyPrefix + name))
+	return int64(h.Sum64())
+}
+
+// TryLock takes the advisory lock of name on the session, nil when another session holds it
+func (l *Locker) TryLock(ctx context.Context, name string) (leader.Lock, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn == nil {
+		conn, err := l.pool.Acquire(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("lock %s: %w", name, err)
+		}
+		l.conn = conn
+	}
+	key := Key(name)
+	ok, err := sqlc.New(l.conn).TryAdvisoryLock(ctx, key)
+	if err != nil {
+		l.drop(ctx)
+		return nil, fmt.Errorf("lock %s: %w", name, err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	return &lock{owner: l, conn: l.conn, key: key}, nil
+}
+
+// Close ends the session, releasing every lock still held
+func (l *Locker) Close(ctx context.Context) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn != nil {
+		l.drop(ctx)
+	}
+}
+
+// drop closes the session instead of returning it to the pool, so that its locks go with it
+func (l *Locker) drop(ctx context.Context) {
+	_ = l.conn.Conn().Close(ctx)
+	l.conn.Release()
+	l.conn = nil
+}
+
+// lock — an advisory lock taken on a session; it is lost once the locker moves to another session
+type lock struct {
+	owner *Locker
+	conn  *pgxpool.Conn
+	key   int64
+}
+
+func (k *lock) Check(ctx context.Context) error {
+	k.owner.mu.Lock()
+	defer k.owner.mu.Unlock()
+	if k.owner.conn != k.conn {
+		return leader.ErrLost
+	}
+	if err := k.conn.Ping(ctx); err != nil {
+		k.owner.drop(ctx)
+		return fmt.Errorf("%w: %w", leader.ErrLost, err)
+	}
+	return nil
+}
+
+func (k *lock) Unlock(ctx context.Context) error {
+	k.owner.mu.Lock()
+	defer k.owner.mu.Unlock()
+	if k.owner.conn != k.conn {
+		return nil
+	}
+	if _, err := sqlc.New(k.conn).AdvisoryUnlock(ctx, k.key); err != nil {
+		k.owner.drop(ctx)
+		return fmt.Errorf("unlock: %w", err)
+	}
+	return nil
+}
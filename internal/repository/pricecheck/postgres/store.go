@@ -0,0 +1,107 @@
+// Package postgres stores the price reviews in the price_reviews table
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/pricecheck"
+	"subs_tracker/internal/repository/subscription/postgres/sqlc"
+)
+
+// Store — pricecheck.Store over pgx and the sqlc queries
+type Store struct {
+	queries *sqlc.Queries
+}
+
+var _ pricecheck.Store = (*Store)(nil)
+
+// NewStore creates a store bound to the pool
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{queries: sqlc.New(pool)}
+}
+
+// OpenReview inserts the review and sets its ID; the unique index on the subscription and the price skips
+// the ones already stored
+func (s *Store) OpenReview(ctx context.Context, r *pricecheck.Review) (bool, error) {
+	id, err := s.queries.InsertPriceReview(ctx, sqlc.InsertPriceReviewParams{
+		SubscriptionID: r.SubscriptionID,
+		PublicID:       r.PublicID.String(),
+		UserID:         r.UserID.String(),
+		ServiceName:    r.ServiceName,
+		Cost:           r.Cost,
+		CatalogPrice:   r.CatalogPrice,
+		OpenedAt:       r.OpenedAt,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("open price review: %w", err)
+	}
+	r.ID = id
+	return true, nil
+}
+
+// ListOpen reads the oldest open reviews
+func (s *Store) ListOpen(ctx context.Context, limit int) ([]pricecheck.Review, error) {
+	rows, err := s.queries.ListOpenPriceReviews(ctx, int32(limit))
+	if err != nil {
+		return nil, fmt.Errorf("list price reviews: %w", err)
+	}
+	out := make([]pricecheck.Review, 0, len(rows))
+	for _, row := range rows {
+		r, err := fromRow(row)
+		if err != nil {
+			return nil, fmt.Errorf("list price reviews: %w", err)
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// Resolve sets the resolution time of the open review
+func (s *Store) Resolve(ctx context.Context, id int64, at time.Time) (pricecheck.Review, error) {
+	row, err := s.queries.ResolvePriceReview(ctx, sqlc.ResolvePriceReviewParams{ResolvedAt: at, ID: id})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return pricecheck.Review{}, pricecheck.ErrNotFound
+	}
+	if err != nil {
+		return pricecheck.Review{}, fmt.Errorf("resolve price review: %w", err)
+	}
+	return fromRow(row)
+}
+
+// DeleteUser removes the reviews of the user
+func (s *Store) DeleteUser(ctx context.Context, user entity.UserID) error {
+	if err := s.queries.DeleteUserPriceReviews(ctx, user.String()); err != nil {
+		return fmt.Errorf("delete price reviews: %w", err)
+	}
+	return nil
+}
+
+func fromRow(row sqlc.PriceReview) (pricecheck.Review, error) {
+	uid, err := entity.ParseUserID(row.UserID)
+	if err != nil {
+		return pricecheck.Review{}, err
+	}
+	pid, _ := uuid.Parse(row.PublicID)
+	return pricecheck.Review{
+		ID:             row.ID,
+		SubscriptionID: row.SubscriptionID,
+		PublicID:       entity.PublicID(pid),
+		UserID:         uid,
+		ServiceName:    row.ServiceName,
+		Cost:           row.Cost,
+		CatalogPrice:   row.CatalogPrice,
+		OpenedAt:       row.OpenedAt,
+		ResolvedAt:     row.ResolvedAt,
+	}, nil
+}
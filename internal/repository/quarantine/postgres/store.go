@@ -0,0 +1,119 @@
+// Package postgres stores the quarantined import rows in the import_quarantine table
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/quarantine"
+	"subs_tracker/internal/repository/subscription/postgres/sqlc"
+)
+
+// Store — quarantine.Store over pgx and the sqlc queries
+type Store struct {
+	queries *sqlc.Queries
+}
+
+var _ quarantine.Store = (*Store)(nil)
+
+// NewStore creates a store bound to the pool
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{queries: sqlc.New(pool)}
+}
+
+// Hold inserts the rows one by one and sets their IDs; a row with the key of a held one gets its ID
+func (s *Store) Hold(ctx context.Context, rows []quarantine.Row) error {
+	for i := range rows {
+		r := &rows[i]
+		id, err := s.queries.InsertQuarantinedImport(ctx, sqlc.InsertQuarantinedImportParams{
+			UserID:      r.UserID.String(),
+			ServiceName: r.ServiceName,
+			Cost:        r.Cost,
+			StartDate:   r.StartDate,
+			EndDate:     r.EndDate,
+			Reason:      r.Reason,
+			Code:        string(r.Code),
+			HeldAt:      r.HeldAt,
+			ImportKey:   r.Key,
+		})
+		if err != nil {
+			return fmt.Errorf("hold row: %w", err)
+		}
+		r.ID = id
+	}
+	return nil
+}
+
+// List reads the oldest rows of the user
+func (s *Store) List(ctx context.Context, user entity.UserID, limit int) ([]quarantine.Row, error) {
+	rows, err := s.queries.ListQuarantinedImports(ctx, sqlc.ListQuarantinedImportsParams{UserID: user.String(), Lim: int32(limit)})
+	if err != nil {
+		return nil, fmt.Errorf("list quarantined rows: %w", err)
+	}
+	out := make([]quarantine.Row, 0, len(rows))
+	for _, row := range rows {
+		r, err := fromRow(row)
+		if err != nil {
+			return nil, fmt.Errorf("list quarantined rows: %w", err)
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// Get reads the row of the user
+func (s *Store) Get(ctx context.Context, user entity.UserID, id int64) (quarantine.Row, error) {
+	row, err := s.queries.GetQuarantinedImport(ctx, sqlc.GetQuarantinedImportParams{ID: id, UserID: user.String()})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return quarantine.Row{}, quarantine.ErrNotFound
+	}
+	if err != nil {
+		return quarantine.Row{}, fmt.Errorf("get quarantined row: %w", err)
+	}
+	return fromRow(row)
+}
+
+// Delete removes the row of the user
+func (s *Store) Delete(ctx context.Context, user entity.UserID, id int64) error {
+	n, err := s.queries.DeleteQuarantinedImport(ctx, sqlc.DeleteQuarantinedImportParams{ID: id, UserID: user.String()})
+	if err != nil {
+		return fmt.Errorf("delete quarantined row: %w", err)
+	}
+	if n == 0 {
+		return quarantine.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteUser removes the rows of the user
+func (s *Store) DeleteUser(ctx context.Context, user entity.UserID) error {
+	if err := s.queries.DeleteUserQuarantinedImports(ctx, user.String()); err != nil {
+		return fmt.Errorf("delete quarantined rows: %w", err)
+	}
+	return nil
+}
+
+func fromRow(row sqlc.ImportQuarantine) (quarantine.Row, error) {
+	uid, err := entity.ParseUserID(row.UserID)
+	if err != nil {
+		return quarantine.Row{}, err
+	}
+	return quarantine.Row{
+		ID:          row.ID,
+		UserID:      uid,
+		ServiceName: row.ServiceName,
+		Cost:        row.Cost,
+		StartDate:   row.StartDate,
+		EndDate:     row.EndDate,
+		Reason:      row.Reason,
+		Code:        errcode.Code(row.Code),
+		HeldAt:      row.HeldAt,
+		Key:         row.ImportKey,
+	}, nil
+}
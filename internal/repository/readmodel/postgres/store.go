@@ -0,0 +1,91 @@
+// Package postgres stores the analytics read model in the user_spend_changes table. The pool may point at
+// another database than the subscriptions, such as a replica-side instance with the same migrations
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/readmodel"
+	"subs_tracker/internal/repository/subscription/postgres/sqlc"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/dates"
+)
+
+// Store — readmodel.Store over pgx and the sqlc queries
+type Store struct {
+	pool    *pgxpool.Pool
+	queries *sqlc.Queries
+}
+
+var _ readmodel.Store = (*Store)(nil)
+
+// NewStore creates a store bound to the pool
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool, queries: sqlc.New(pool)}
+}
+
+// ReplaceUser replaces all changes of the user in one transaction
+func (s *Store) ReplaceUser(ctx context.Context, user entity.UserID, changes []readmodel.Change) error {
+	return s.replace(ctx, changes, func(q *sqlc.Queries) error {
+		return q.DeleteUserSpendChanges(ctx, user.String())
+	})
+}
+
+// ReplaceAll replaces the whole projection in one transaction, so readers never see it half built
+func (s *Store) ReplaceAll(ctx context.Context, changes []readmodel.Change) error {
+	return s.replace(ctx, changes, func(q *sqlc.Queries) error {
+		return q.DeleteAllUserSpendChanges(ctx)
+	})
+}
+
+func (s *Store) replace(ctx context.Context, changes []readmodel.Change, clear func(q *sqlc.Queries) error) error {
+	params := sqlc.InsertUserSpendChangesParams{
+		UserIds:    make([]string, 0, len(changes)),
+		Months:     make([]time.Time, 0, len(changes)),
+		CostDeltas: make([]int64, 0, len(changes)),
+		SubsDeltas: make([]int32, 0, len(changes)),
+	}
+	for _, c := range changes {
+		params.UserIds = append(params.UserIds, c.UserID.String())
+		params.Months = append(params.Months, c.Month)
+		params.CostDeltas = append(params.CostDeltas, c.Cost)
+		params.SubsDeltas = append(params.SubsDeltas, c.Subs)
+	}
+
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		q := s.queries.WithTx(tx)
+		if err := clear(q); err != nil {
+			return fmt.Errorf("replace spend changes: %w", err)
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+		if err := q.InsertUserSpendChanges(ctx, params); err != nil {
+			return fmt.Errorf("replace spend changes: %w", err)
+		}
+		return nil
+	})
+}
+
+// MonthlySpendByUser sums the changes up to every month from..to, ordered by user and month
+func (s *Store) MonthlySpendByUser(ctx context.Context, from, to time.Time) ([]usecase.UserMonthSpend, error) {
+	rows, err := s.queries.UserMonthlySpendFromChanges(ctx, sqlc.UserMonthlySpendFromChangesParams{FromMonth: from, ToMonth: to})
+	if err != nil {
+		return nil, fmt.Errorf("monthly spend by user: %w", err)
+	}
+	out := make([]usecase.UserMonthSpend, 0, len(rows))
+	for _, row := range rows {
+		uid, err := entity.ParseUserID(row.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("monthly spend by user: %w", err)
+		}
+		out = append(out, usecase.UserMonthSpend{UserID: uid, Month: dates.MonthStart(row.Month), Total: row.Total})
+	}
+	return out, nil
+}
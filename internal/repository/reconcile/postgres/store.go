@@ -0,0 +1,169 @@
+// Package postgres stores the recorded charges and the reconciliation items in the charges and
+// reconciliation_items tables
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/reconcile"
+	"subs_tracker/internal/repository/subscription/postgres/sqlc"
+)
+
+// Store — reconcile.Store over pgx and the sqlc queries
+type Store struct {
+	queries *sqlc.Queries
+}
+
+var _ reconcile.Store = (*Store)(nil)
+
+// NewStore creates a store bound to the pool
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{queries: sqlc.New(pool)}
+}
+
+// RecordCharges inserts the charges one by one; the unique index on the user, service, day and amount skips
+// the ones already stored
+func (s *Store) RecordCharges(ctx context.Context, charges []reconcile.Charge) (int, error) {
+	n := 0
+	for _, ch := range charges {
+		inserted, err := s.queries.InsertCharge(ctx, sqlc.InsertChargeParams{
+			UserID:      ch.UserID.String(),
+			ServiceName: ch.ServiceName,
+			ChargedOn:   ch.Date,
+			Amount:      ch.Amount,
+		})
+		if err != nil {
+			return n, fmt.Errorf("record charge: %w", err)
+		}
+		n += int(inserted)
+	}
+	return n, nil
+}
+
+// ChargedUsers reads the users with charges in the month
+func (s *Store) ChargedUsers(ctx context.Context, month time.Time) ([]entity.UserID, error) {
+	rows, err := s.queries.ListChargedUsers(ctx, month)
+	if err != nil {
+		return nil, fmt.Errorf("list charged users: %w", err)
+	}
+	out := make([]entity.UserID, 0, len(rows))
+	for _, row := range rows {
+		uid, err := entity.ParseUserID(row)
+		if err != nil {
+			return nil, fmt.Errorf("list charged users: %w", err)
+		}
+		out = append(out, uid)
+	}
+	return out, nil
+}
+
+// Charges reads the charges of the user in the month
+func (s *Store) Charges(ctx context.Context, user entity.UserID, month time.Time) ([]reconcile.Charge, error) {
+	rows, err := s.queries.ListUserCharges(ctx, sqlc.ListUserChargesParams{UserID: user.String(), Month: month})
+	if err != nil {
+		return nil, fmt.Errorf("list charges: %w", err)
+	}
+	out := make([]reconcile.Charge, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, reconcile.Charge{UserID: user, ServiceName: row.ServiceName, Date: row.ChargedOn, Amount: row.Amount})
+	}
+	return out, nil
+}
+
+// OpenItem inserts the item and sets its ID; the unique index on the user, month, kind and service skips the
+// ones already stored
+func (s *Store) OpenItem(ctx context.Context, it *reconcile.Item) (bool, error) {
+	id, err := s.queries.InsertReconciliationItem(ctx, sqlc.InsertReconciliationItemParams{
+		Kind:           string(it.Kind),
+		UserID:         it.UserID.String(),
+		ServiceName:    it.ServiceName,
+		Month:          it.Month,
+		Expected:       it.Expected,
+		Charged:        it.Charged,
+		SubscriptionID: it.SubscriptionID,
+		PublicID:       pgtype.UUID{Bytes: it.PublicID, Valid: !it.PublicID.IsZero()},
+		OpenedAt:       it.OpenedAt,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("open reconciliation item: %w", err)
+	}
+	it.ID = id
+	return true, nil
+}
+
+// ListOpen reads the oldest open items
+func (s *Store) ListOpen(ctx context.Context, user *entity.UserID, limit int) ([]reconcile.Item, error) {
+	params := sqlc.ListOpenReconciliationItemsParams{Lim: int32(limit)}
+	if user != nil {
+		params.UserID = pgtype.UUID{Bytes: user.UUID(), Valid: true}
+	}
+	rows, err := s.queries.ListOpenReconciliationItems(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("list reconciliation items: %w", err)
+	}
+	out := make([]reconcile.Item, 0, len(rows))
+	for _, row := range rows {
+		it, err := fromRow(row)
+		if err != nil {
+			return nil, fmt.Errorf("list reconciliation items: %w", err)
+		}
+		out = append(out, it)
+	}
+	return out, nil
+}
+
+// Resolve sets the resolution time of the open item
+func (s *Store) Resolve(ctx context.Context, id int64, at time.Time) (reconcile.Item, error) {
+	row, err := s.queries.ResolveReconciliationItem(ctx, sqlc.ResolveReconciliationItemParams{ResolvedAt: at, ID: id})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return reconcile.Item{}, reconcile.ErrNotFound
+	}
+	if err != nil {
+		return reconcile.Item{}, fmt.Errorf("resolve reconciliation item: %w", err)
+	}
+	return fromRow(row)
+}
+
+// DeleteUser removes the charges and the items of the user in one statement
+func (s *Store) DeleteUser(ctx context.Context, user entity.UserID) error {
+	if err := s.queries.DeleteUserReconciliation(ctx, user.String()); err != nil {
+		return fmt.Errorf("delete reconciliation: %w", err)
+	}
+	return nil
+}
+
+func fromRow(row sqlc.ReconciliationItem) (reconcile.Item, error) {
+	uid, err := entity.ParseUserID(row.UserID)
+	if err != nil {
+		return reconcile.Item{}, err
+	}
+	var pid uuid.UUID
+	if row.PublicID.Valid {
+		pid = row.PublicID.Bytes
+	}
+	return reconcile.Item{
+		ID:             row.ID,
+		Kind:           reconcile.Kind(row.Kind),
+		UserID:         uid,
+		ServiceName:    row.ServiceName,
+		Month:          row.Month,
+		Expected:       row.Expected,
+		Charged:        row.Charged,
+		SubscriptionID: row.SubscriptionID,
+		PublicID:       entity.PublicID(pid),
+		OpenedAt:       row.OpenedAt,
+		ResolvedAt:     row.ResolvedAt,
+	}, nil
+}
@@ -0,0 +1,60 @@
+// Package postgres wipes the schema of the sandbox tenant
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"subs_tracker/internal/repository/subscription/postgres/sqlc"
+	"subs_tracker/internal/sandbox"
+)
+
+// Store — sandbox.Store over the main database and the shards
+type Store struct {
+	schema string
+	pools  []*pgxpool.Pool
+}
+
+var _ sandbox.Store = (*Store)(nil)
+
+// NewStore creates a store wiping the schema in the databases of pools, whose connections switch to it for
+// the sandbox tenant, see tenancy.Schemas.Configure
+func NewStore(schema string, pools ...*pgxpool.Pool) *Store {
+	return &Store{schema: schema, pools: pools}
+}
+
+// Wipe truncates every table of the schema but the migrations table, restarting the IDs, in one
+// transaction per database. A connection found at any other schema is refused, so that a tenant missing
+// from the context never wipes the shared one
+func (s *Store) Wipe(ctx context.Context) error {
+	for _, pool := range s.pools {
+		err := pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+			q := sqlc.New(tx)
+			schema, err := q.CurrentSchema(ctx)
+			if err != nil {
+				return err
+			}
+			if schema != s.schema {
+				return fmt.Errorf("connection is at schema %q, not %q", schema, s.schema)
+			}
+			tables, err := q.ListSchemaTables(ctx)
+			if err != nil || len(tables) == 0 {
+				return err
+			}
+			names := make([]string, 0, len(tables))
+			for _, t := range tables {
+				names = append(names, pgx.Identifier{schema, t}.Sanitize())
+			}
+			_, err = tx.Exec(ctx, "TRUNCATE "+strings.Join(names, ", ")+" RESTART IDENTITY CASCADE")
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("wipe sandbox: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// Package postgres stores share links in the share_links table
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/repository/subscription/postgres/sqlc"
+	"subs_tracker/internal/share"
+)
+
+// Store — share.Store over pgx and the sqlc queries
+type Store struct {
+	pool    *pgxpool.Pool
+	queries *sqlc.Queries
+}
+
+var _ share.Store = (*Store)(nil)
+
+// NewStore creates a store bound to the pool
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool, queries: sqlc.New(pool)}
+}
+
+// SaveLink inserts a new link
+func (s *Store) SaveLink(ctx context.Context, l share.Link) error {
+	err := s.queries.InsertShareLink(ctx, sqlc.InsertShareLinkParams{
+		TokenHash:   l.TokenHash,
+		UserID:      l.UserID.String(),
+		ServiceName: l.ServiceName,
+		CreatedAt:   l.CreatedAt,
+		ExpiresAt:   l.ExpiresAt,
+		TenantID:    l.TenantID,
+	})
+	if err != nil {
+		return fmt.Errorf("save share link: %w", err)
+	}
+	return nil
+}
+
+// GetLink returns the link with the token hash, share.ErrNotFound if there is none
+func (s *Store) GetLink(ctx context.Context, hash string) (*share.Link, error) {
+	row, err := s.queries.GetShareLink(ctx, hash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, share.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get share link: %w", err)
+	}
+	uid, err := entity.ParseUserID(row.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("get share link: %w", err)
+	}
+	return &share.Link{
+		TokenHash:   row.TokenHash,
+		UserID:      uid,
+		ServiceName: row.ServiceName,
+		TenantID:    row.TenantID,
+		CreatedAt:   row.CreatedAt,
+		ExpiresAt:   row.ExpiresAt,
+		RevokedAt:   row.RevokedAt,
+	}, nil
+}
+
+// RevokeLink marks the link revoked unless it already is
+func (s *Store) RevokeLink(ctx context.Context, hash string, at time.Time) error {
+	n, err := s.queries.RevokeShareLink(ctx, sqlc.RevokeShareLinkParams{RevokedAt: at, TokenHash: hash})
+	if err != nil {
+		return fmt.Errorf("revoke share link: %w", err)
+	}
+	if n == 0 {
+		return share.ErrNotFound
+	}
+	return nil
+}
+
+// RevokeIssuedBefore revokes unrevoked links created before the cutoff and records the revocation in the
+// admin audit log, both in a single transaction
+func (s *Store) RevokeIssuedBefore(ctx context.Context, userID entity.UserID, before, at time.Time, actor string) (int64, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("revoke share links: begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	q := sqlc.New(tx)
+	n, err := q.RevokeShareLinksIssuedBefore(ctx, sqlc.RevokeShareLinksIssuedBeforeParams{
+		RevokedAt:    at,
+		IssuedBefore: before,
+		UserID:       pgtype.UUID{Bytes: userID.UUID(), Valid: !userID.IsZero()},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("revoke share links: %w", err)
+	}
+	audit := map[string]any{"issued_before": before, "revoked": n}
+	if !userID.IsZero() {
+		audit["user_id"] = userID.String()
+	}
+	details, err := json.Marshal(audit)
+	if err != nil {
+		return 0, fmt.Errorf("revoke share links: audit details: %w", err)
+	}
+	if err := q.InsertAdminAudit(ctx, sqlc.InsertAdminAuditParams{
+		Action:  "revoke_share_links",
+		Actor:   actor,
+		Details: details,
+	}); err != nil {
+		return 0, fmt.Errorf("revoke share links: audit: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("revoke share links: commit: %w", err)
+	}
+	return n, nil
+}
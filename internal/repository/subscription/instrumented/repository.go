@@ -0,0 +1,240 @@
+// Package instrumented records per method call metrics of a subscription repository
+package instrumented
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/clock"
+)
+
+var _ usecase.SubscriptionRepository = (*Repository)(nil)
+
+// Recorder — receives every finished repository call, e.g. for metrics
+type Recorder interface {
+	// RepositoryCall - method took took and failed with an error of errType, see ErrorType; empty when it succeeded
+	RepositoryCall(method string, took time.Duration, errType string)
+}
+
+// Repository — usecase.SubscriptionRepository timing every call of next and classifying its error. The
+// methods change nothing about the calls, so it can wrap any repository, the sharded router included.
+type Repository struct {
+	next  usecase.SubscriptionRepository
+	rec   Recorder
+	clock clock.Clock
+}
+
+// NewRepository wraps next, reporting its calls to rec, and applies options
+func NewRepository(next usecase.SubscriptionRepository, rec Recorder, options ...func(*Repository)) *Repository {
+	r := &Repository{next: next, rec: rec, clock: clock.System}
+	for _, o := range options {
+		o(r)
+	}
+	return r
+}
+
+// WithClock returns an option that sets the clock calls are timed with
+func WithClock(cl clock.Clock) func(*Repository) {
+	return func(r *Repository) {
+		if cl != nil {
+			r.clock = cl
+		}
+	}
+}
+
+// ErrorType classifies err for metrics with a bounded set of values: empty for nil, canceled and timeout for
+// requests given up on, the lower-cased code of domain errors (sub_not_found, sub_modified), no_rows, and
+// postgres_<class> for database errors by their SQLSTATE class (postgres_23 for constraint violations);
+// anything else is other
+func ErrorType(err error) string {
+	var pgErr *pgconn.PgError
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded), pgconn.Timeout(err):
+		return "timeout"
+	case errcode.Of(err) != "":
+		return strings.ToLower(string(errcode.Of(err)))
+	case errors.Is(err, pgx.ErrNoRows):
+		return "no_rows"
+	case errors.As(err, &pgErr) && len(pgErr.Code) == 5:
+		return "postgres_" + pgErr.Code[:2]
+	default:
+		return "other"
+	}
+}
+
+// observe reports the call of method started at start with the error *err holds when it returns
+func (r *Repository) observe(method string, start time.Time, err *error) {
+	r.rec.RepositoryCall(method, r.clock.Now().Sub(start), ErrorType(*err))
+}
+
+func (r *Repository) SaveSub(ctx context.Context, s *entity.Subscription) (_ *entity.Subscription, err error) {
+	defer r.observe("SaveSub", r.clock.Now(), &err)
+	return r.next.SaveSub(ctx, s)
+}
+
+func (r *Repository) SaveImportedSub(ctx context.Context, key string, s *entity.Subscription) (_ *entity.Subscription, _ bool, err error) {
+	defer r.observe("SaveImportedSub", r.clock.Now(), &err)
+	return r.next.SaveImportedSub(ctx, key, s)
+}
+
+func (r *Repository) UpdateSub(ctx context.Context, s *entity.Subscription) (err error) {
+	defer r.observe("UpdateSub", r.clock.Now(), &err)
+	return r.next.UpdateSub(ctx, s)
+}
+
+func (r *Repository) DeleteSub(ctx context.Context, id int64, version time.Time) (err error) {
+	defer r.observe("DeleteSub", r.clock.Now(), &err)
+	return r.next.DeleteSub(ctx, id, version)
+}
+
+func (r *Repository) GetSubByID(ctx context.Context, id int64) (_ *entity.Subscription, err error) {
+	defer r.observe("GetSubByID", r.clock.Now(), &err)
+	return r.next.GetSubByID(ctx, id)
+}
+
+func (r *Repository) GetSubByIDForUpdate(ctx context.Context, id int64) (_ *entity.Subscription, err error) {
+	defer r.observe("GetSubByIDForUpdate", r.clock.Now(), &err)
+	return r.next.GetSubByIDForUpdate(ctx, id)
+}
+
+// InTx is not observed itself, the calls made in it are
+func (r *Repository) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.next.InTx(ctx, fn)
+}
+
+func (r *Repository) GetSubByPublicID(ctx context.Context, id entity.PublicID) (_ *entity.Subscription, err error) {
+	defer r.observe("GetSubByPublicID", r.clock.Now(), &err)
+	return r.next.GetSubByPublicID(ctx, id)
+}
+
+func (r *Repository) ListSubsByFilter(ctx context.Context, f usecase.SubFilter) (_ []*entity.Subscription, err error) {
+	defer r.observe("ListSubsByFilter", r.clock.Now(), &err)
+	return r.next.ListSubsByFilter(ctx, f)
+}
+
+func (r *Repository) CostSubsByFilter(ctx context.Context, f usecase.SubFilter) (_ int64, err error) {
+	defer r.observe("CostSubsByFilter", r.clock.Now(), &err)
+	return r.next.CostSubsByFilter(ctx, f)
+}
+
+func (r *Repository) CostGroupedByFilter(ctx context.Context, f usecase.SubFilter, by usecase.CostGroupBy) (_ []usecase.CostGroup, err error) {
+	defer r.observe("CostGroupedByFilter", r.clock.Now(), &err)
+	return r.next.CostGroupedByFilter(ctx, f, by)
+}
+
+func (r *Repository) CostSummaryByFilter(ctx context.Context, f usecase.SubFilter) (_ usecase.CostSummary, err error) {
+	defer r.observe("CostSummaryByFilter", r.clock.Now(), &err)
+	return r.next.CostSummaryByFilter(ctx, f)
+}
+
+func (r *Repository) DataVersion(ctx context.Context, user entity.UserID) (_ usecase.DataVersion, err error) {
+	defer r.observe("DataVersion", r.clock.Now(), &err)
+	return r.next.DataVersion(ctx, user)
+}
+
+func (r *Repository) ActiveStatsByService(ctx context.Context, month time.Time) (_ []usecase.ServiceStats, err error) {
+	defer r.observe("ActiveStatsByService", r.clock.Now(), &err)
+	return r.next.ActiveStatsByService(ctx, month)
+}
+
+func (r *Repository) PriceBenchmarks(ctx context.Context, month time.Time, minUsers int) (_ []usecase.PriceBenchmark, err error) {
+	defer r.observe("PriceBenchmarks", r.clock.Now(), &err)
+	return r.next.PriceBenchmarks(ctx, month, minUsers)
+}
+
+func (r *Repository) MonthlySpendByUser(ctx context.Context, from, to time.Time) (_ []usecase.UserMonthSpend, err error) {
+	defer r.observe("MonthlySpendByUser", r.clock.Now(), &err)
+	return r.next.MonthlySpendByUser(ctx, from, to)
+}
+
+func (r *Repository) ChangesSince(ctx context.Context, user entity.UserID, since entity.ChangePos, limit int) (_ []entity.SubscriptionChange, err error) {
+	defer r.observe("ChangesSince", r.clock.Now(), &err)
+	return r.next.ChangesSince(ctx, user, since, limit)
+}
+
+func (r *Repository) ReassignUser(ctx context.Context, from, to entity.UserID, actor string) (_ int64, err error) {
+	defer r.observe("ReassignUser", r.clock.Now(), &err)
+	return r.next.ReassignUser(ctx, from, to, actor)
+}
+
+func (r *Repository) MergeSubs(ctx context.Context, merged, dropped *entity.Subscription, actor string) (err error) {
+	defer r.observe("MergeSubs", r.clock.Now(), &err)
+	return r.next.MergeSubs(ctx, merged, dropped, actor)
+}
+
+func (r *Repository) EndedBefore(ctx context.Context, before time.Time, limit int) (_ []*entity.Subscription, err error) {
+	defer r.observe("EndedBefore", r.clock.Now(), &err)
+	return r.next.EndedBefore(ctx, before, limit)
+}
+
+func (r *Repository) PurgeSubs(ctx context.Context, ids []int64) (_ int64, err error) {
+	defer r.observe("PurgeSubs", r.clock.Now(), &err)
+	return r.next.PurgeSubs(ctx, ids)
+}
+
+func (r *Repository) SaveAdjustment(ctx context.Context, a *entity.Adjustment) (_ *entity.Adjustment, err error) {
+	defer r.observe("SaveAdjustment", r.clock.Now(), &err)
+	return r.next.SaveAdjustment(ctx, a)
+}
+
+func (r *Repository) ListAdjustments(ctx context.Context, subID int64) (_ []entity.Adjustment, err error) {
+	defer r.observe("ListAdjustments", r.clock.Now(), &err)
+	return r.next.ListAdjustments(ctx, subID)
+}
+
+func (r *Repository) SaveSeats(ctx context.Context, s *entity.Seats) (_ *entity.Seats, err error) {
+	defer r.observe("SaveSeats", r.clock.Now(), &err)
+	return r.next.SaveSeats(ctx, s)
+}
+
+func (r *Repository) GetSeats(ctx context.Context, subID int64) (_ *entity.Seats, err error) {
+	defer r.observe("GetSeats", r.clock.Now(), &err)
+	return r.next.GetSeats(ctx, subID)
+}
+
+func (r *Repository) SeatSharesByFilter(ctx context.Context, f usecase.SubFilter) (_ []usecase.SeatShare, err error) {
+	defer r.observe("SeatSharesByFilter", r.clock.Now(), &err)
+	return r.next.SeatSharesByFilter(ctx, f)
+}
+
+func (r *Repository) GetSettings(ctx context.Context, userID entity.UserID) (_ *entity.Settings, err error) {
+	defer r.observe("GetSettings", r.clock.Now(), &err)
+	return r.next.GetSettings(ctx, userID)
+}
+
+func (r *Repository) SaveSettings(ctx context.Context, s entity.Settings) (_ *entity.Settings, err error) {
+	defer r.observe("SaveSettings", r.clock.Now(), &err)
+	return r.next.SaveSettings(ctx, s)
+}
+
+func (r *Repository) DeactivateUser(ctx context.Context, userID entity.UserID, at time.Time) (_ time.Time, err error) {
+	defer r.observe("DeactivateUser", r.clock.Now(), &err)
+	return r.next.DeactivateUser(ctx, userID, at)
+}
+
+func (r *Repository) ReactivateUser(ctx context.Context, userID entity.UserID) (err error) {
+	defer r.observe("ReactivateUser", r.clock.Now(), &err)
+	return r.next.ReactivateUser(ctx, userID)
+}
+
+func (r *Repository) UserDeactivatedAt(ctx context.Context, userID entity.UserID) (_ time.Time, err error) {
+	defer r.observe("UserDeactivatedAt", r.clock.Now(), &err)
+	return r.next.UserDeactivatedAt(ctx, userID)
+}
+
+func (r *Repository) DeleteUser(ctx context.Context, userID entity.UserID, d usecase.UserDeletion, actor string) (_ int64, err error) {
+	defer r.observe("DeleteUser", r.clock.Now(), &err)
+	return r.next.DeleteUser(ctx, userID, d, actor)
+}
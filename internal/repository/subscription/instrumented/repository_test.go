@@ -0,0 +1,89 @@
+package instrumented
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/clock"
+)
+
+type call struct {
+	method  string
+	took    time.Duration
+	errType string
+}
+
+type recorder struct{ calls []call }
+
+func (r *recorder) RepositoryCall(method string, took time.Duration, errType string) {
+	r.calls = append(r.calls, call{method, took, errType})
+}
+
+// slowRepo - repository whose calls take a second of the fake clock
+type slowRepo struct {
+	usecase.SubscriptionRepository
+	clock *clock.Fake
+	err   error
+}
+
+func (s *slowRepo) SaveSub(_ context.Context, sub *entity.Subscription) (*entity.Subscription, error) {
+	s.clock.Advance(time.Second)
+	if s.err != nil {
+		return nil, s.err
+	}
+	out := *sub
+	out.ID = 1
+	return &out, nil
+}
+
+func (s *slowRepo) DeleteSub(context.Context, int64, time.Time) error {
+	s.clock.Advance(2 * time.Second)
+	return s.err
+}
+
+func TestRepository(t *testing.T) {
+	ctx := context.Background()
+	cl := clock.NewFake(time.Date(2025, time.July, 1, 10, 0, 0, 0, time.UTC))
+	next := &slowRepo{clock: cl}
+	rec := &recorder{}
+	r := NewRepository(next, rec, WithClock(cl))
+
+	saved, err := r.SaveSub(ctx, &entity.Subscription{ServiceName: "Netflix"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), saved.ID, "results are passed through")
+
+	next.err = usecase.ErrPreconditionFailed
+	assert.ErrorIs(t, r.DeleteSub(ctx, 1, time.Time{}), usecase.ErrPreconditionFailed)
+
+	assert.Equal(t, []call{
+		{method: "SaveSub", took: time.Second},
+		{method: "DeleteSub", took: 2 * time.Second, errType: "sub_modified"},
+	}, rec.calls)
+}
+
+func TestErrorType(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		want string
+	}{
+		{err: nil, want: ""},
+		{err: fmt.Errorf("list: %w", context.Canceled), want: "canceled"},
+		{err: context.DeadlineExceeded, want: "timeout"},
+		{err: usecase.ErrSubscriptionNotFound, want: "sub_not_found"},
+		{err: fmt.Errorf("get: %w", pgx.ErrNoRows), want: "no_rows"},
+		{err: fmt.Errorf("save: %w", &pgconn.PgError{Code: "23505"}), want: "postgres_23"},
+		{err: errors.New("boom"), want: "other"},
+	} {
+		assert.Equal(t, tt.want, ErrorType(tt.err), "%v", tt.err)
+	}
+}
@@ -0,0 +1,926 @@
+// Package memory keeps subscriptions in process memory with the semantics of the postgres repository,
+// for contract tests and demos; nothing survives a restart
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/clock"
+	"subs_tracker/pkg/dates"
+	"subs_tracker/pkg/pagination"
+)
+
+// Repository — usecase.SubscriptionRepository over maps guarded by a mutex. The admin audit log
+// of ReassignUser, MergeSubs and DeleteUser is not kept, and InTx does not undo the writes of a failed fn
+type Repository struct {
+	mu sync.Mutex
+	// txMu - held by InTx, which makes transactions run one at a time as if every row was locked
+	txMu     sync.Mutex
+	clock    clock.Clock
+	lastTime time.Time
+	nextID   int64
+	subs     map[int64]entity.Subscription
+	changes  []entity.SubscriptionChange
+	// adjustments - in the order recorded; those of a removed subscription are removed with it
+	adjustments  []entity.Adjustment
+	nextAdjustID int64
+	// seats - per shared subscription; removed with it
+	seats    map[int64]entity.Seats
+	settings map[entity.UserID]entity.Settings
+	// deactivated - deactivation time per deactivated user
+	deactivated map[entity.UserID]time.Time
+	// importKeys - subscription ID per import key; a key of a removed subscription counts as absent
+	importKeys map[string]int64
+	// versions - data version per user, bumped with the next versionSeq by every write the cost reports see
+	versions   map[entity.UserID]usecase.DataVersion
+	versionSeq int64
+}
+
+// NewRepository creates an empty repository and applies options
+func NewRepository(options ...func(*Repository)) *Repository {
+	r := &Repository{
+		clock:       clock.System,
+		subs:        map[int64]entity.Subscription{},
+		seats:       map[int64]entity.Seats{},
+		settings:    map[entity.UserID]entity.Settings{},
+		deactivated: map[entity.UserID]time.Time{},
+		importKeys:  map[string]int64{},
+		versions:    map[entity.UserID]usecase.DataVersion{},
+	}
+	for _, o := range options {
+		o(r)
+	}
+	return r
+}
+
+// WithClock returns an option that sets the source of created_at and updated_at
+func WithClock(c clock.Clock) func(*Repository) {
+	return func(r *Repository) {
+		if c != nil {
+			r.clock = c
+		}
+	}
+}
+
+// stamp returns the write time at database precision, always after the previous one so versions never repeat
+func (r *Repository) stamp() time.Time {
+	t := r.clock.Now().UTC().Truncate(time.Microsecond)
+	if !t.After(r.lastTime) {
+		t = r.lastTime.Add(time.Microsecond)
+	}
+	r.lastTime = t
+	return t
+}
+
+// logChange appends to the change log; writes are serialized by the lock, so entries commit in Seq order and
+// Tx simply follows it
+func (r *Repository) logChange(s entity.Subscription, op entity.ChangeOp, at time.Time) {
+	seq := int64(len(r.changes) + 1)
+	r.changes = append(r.changes, entity.SubscriptionChange{
+		Tx:             uint64(seq),
+		Seq:            seq,
+		SubscriptionID: s.ID,
+		UserID:         s.UserID,
+		PublicID:       s.PublicID,
+		Op:             op,
+		ChangedAt:      at,
+	})
+	r.bump(at, s.UserID)
+}
+
+// bump gives the users the next data version, as the triggers of the postgres repository do
+func (r *Repository) bump(at time.Time, users ...entity.UserID) {
+	for _, u := range users {
+		r.versionSeq++
+		r.versions[u] = usecase.DataVersion{Version: r.versionSeq, ChangedAt: at}
+	}
+}
+
+// clone copies s so callers never share the stored end date
+func clone(s entity.Subscription) *entity.Subscription {
+	if s.DateTo != nil {
+		end := *s.DateTo
+		s.DateTo = &end
+	}
+	return &s
+}
+
+// SaveSub stores a new subscription under the next ID
+func (r *Repository) SaveSub(_ context.Context, s *entity.Subscription) (*entity.Subscription, error) {
+	if s == nil || s.UserID.IsZero() {
+		return nil, fmt.Errorf("save sub: %w", usecase.ErrInvalidSubscription)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	now := r.stamp()
+	stored := *clone(*s)
+	stored.ID, stored.CreatedAt, stored.UpdatedAt = r.nextID, now, now
+	if stored.PublicID.IsZero() {
+		// the default of the public_id column
+		stored.PublicID = entity.PublicID(uuid.New())
+	}
+	r.subs[stored.ID] = stored
+	r.logChange(stored, entity.ChangeInsert, now)
+	return clone(stored), nil
+}
+
+// SaveImportedSub stores a new subscription under key, or returns the one stored under it before
+func (r *Repository) SaveImportedSub(ctx context.Context, key string, s *entity.Subscription) (*entity.Subscription, bool, error) {
+	r.mu.Lock()
+	if id, ok := r.importKeys[key]; ok {
+		if stored, ok := r.subs[id]; ok {
+			r.mu.Unlock()
+			return clone(stored), false, nil
+		}
+	}
+	r.mu.Unlock()
+	out, err := r.SaveSub(ctx, s)
+	if err != nil {
+		return nil, false, err
+	}
+	r.mu.Lock()
+	r.importKeys[key] = out.ID
+	r.mu.Unlock()
+	return out, true, nil
+}
+
+// UpdateSub replaces a subscription, only if still at s.UpdatedAt when it is set
+func (r *Repository) UpdateSub(_ context.Context, s *entity.Subscription) error {
+	if s == nil || s.UserID.IsZero() {
+		return fmt.Errorf("update sub: %w", usecase.ErrInvalidSubscription)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old, err := r.writable(s.ID, s.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	r.update(old, s)
+	return nil
+}
+
+// writable returns the stored subscription if it exists and is at version when version is non-zero
+func (r *Repository) writable(id int64, version time.Time) (entity.Subscription, error) {
+	old, ok := r.subs[id]
+	if !ok {
+		return old, usecase.ErrSubscriptionNotFound
+	}
+	if !version.IsZero() && !old.UpdatedAt.Equal(version) {
+		return old, usecase.ErrPreconditionFailed
+	}
+	return old, nil
+}
+
+func (r *Repository) update(old entity.Subscription, s *entity.Subscription) {
+	now := r.stamp()
+	stored := *clone(*s)
+	stored.ID, stored.PublicID, stored.CreatedAt, stored.UpdatedAt = old.ID, old.PublicID, old.CreatedAt, now
+	r.subs[old.ID] = stored
+	if old.UserID != stored.UserID {
+		// a subscription moved to another user leaves the sync of the former owner
+		r.logChange(old, entity.ChangeDelete, now)
+		r.logChange(stored, entity.ChangeInsert, now)
+		return
+	}
+	r.logChange(stored, entity.ChangeUpdate, now)
+}
+
+// DeleteSub removes a subscription, only if still at version when it is non-zero
+func (r *Repository) DeleteSub(_ context.Context, id int64, version time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old, err := r.writable(id, version)
+	if err != nil {
+		return err
+	}
+	delete(r.subs, id)
+	r.dropAdjustments(id)
+	r.dropSeats(id)
+	r.logChange(old, entity.ChangeDelete, r.stamp())
+	return nil
+}
+
+// GetSubByID returns the subscription or ErrSubscriptionNotFound
+func (r *Repository) GetSubByID(_ context.Context, id int64) (*entity.Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.subs[id]
+	if !ok {
+		return nil, usecase.ErrSubscriptionNotFound
+	}
+	return clone(s), nil
+}
+
+// GetSubByIDForUpdate returns the subscription like GetSubByID; InTx already keeps other transactions out
+func (r *Repository) GetSubByIDForUpdate(ctx context.Context, id int64) (*entity.Subscription, error) {
+	return r.GetSubByID(ctx, id)
+}
+
+// txKey - context key marking the calls made inside InTx of a repository
+type txKey struct {
+	r *Repository
+}
+
+// InTx runs fn while no other transaction of the repository runs; inside a transaction fn joins it
+func (r *Repository) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txKey{r}) != nil {
+		return fn(ctx)
+	}
+	r.txMu.Lock()
+	defer r.txMu.Unlock()
+	return fn(context.WithValue(ctx, txKey{r}, true))
+}
+
+// GetSubByPublicID returns the subscription with the public ID or ErrSubscriptionNotFound
+func (r *Repository) GetSubByPublicID(_ context.Context, id entity.PublicID) (*entity.Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range r.subs {
+		if s.PublicID == id {
+			return clone(s), nil
+		}
+	}
+	return nil, usecase.ErrSubscriptionNotFound
+}
+
+// listOrder is usecase.CompareListOrder over values
+func listOrder(a, b entity.Subscription) int {
+	return usecase.CompareListOrder(&a, &b)
+}
+
+// active reports whether s overlaps the months from..to
+func active(s entity.Subscription, from, to time.Time) bool {
+	return !s.DateFrom.After(to) && (s.DateTo == nil || !s.DateTo.Before(from))
+}
+
+// matches applies the user and service filters
+func matches(s entity.Subscription, f usecase.SubFilter) bool {
+	return (f.UserID.IsZero() || s.UserID == f.UserID) && (f.ServiceName == nil || s.ServiceName == *f.ServiceName)
+}
+
+// hidden reports whether s belongs to a deactivated user
+func (r *Repository) hidden(s entity.Subscription) bool {
+	_, ok := r.deactivated[s.UserID]
+	return ok
+}
+
+// ListSubsByFilter lists matching subscriptions in (start date, service name, ID) order
+func (r *Repository) ListSubsByFilter(_ context.Context, f usecase.SubFilter) ([]*entity.Subscription, error) {
+	limit, offset, err := pagination.DefaultLimits().Normalize(f.Limit, max(f.Offset, 0))
+	if err != nil {
+		return nil, fmt.Errorf("list subs by filter: %w", err)
+	}
+	if f.After != nil {
+		offset = 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rows := make([]entity.Subscription, 0, len(r.subs))
+	for _, s := range r.subs {
+		if !matches(s, f) || !f.IncludeDeactivated && r.hidden(s) {
+			continue
+		}
+		if f.Period != nil && !f.Period.From.IsZero() {
+			if s.DateTo != nil && s.DateTo.Before(f.Period.From) {
+				continue
+			}
+			if !f.Period.To.IsZero() && s.DateFrom.After(f.Period.To) {
+				continue
+			}
+		}
+		if f.After != nil && listOrder(s, entity.Subscription{DateFrom: f.After.StartDate, ServiceName: f.After.ServiceName, ID: f.After.ID}) <= 0 {
+			continue
+		}
+		if f.UpdatedSince != nil && !s.UpdatedAt.After(*f.UpdatedSince) {
+			continue
+		}
+		if !f.Where.Match(&s) {
+			continue
+		}
+		rows = append(rows, s)
+	}
+	slices.SortFunc(rows, listOrder)
+	rows = rows[min(offset, len(rows)):]
+	rows = rows[:min(limit, len(rows))]
+
+	out := make([]*entity.Subscription, 0, len(rows))
+	for _, s := range rows {
+		out = append(out, clone(s))
+	}
+	return out, nil
+}
+
+// CostSubsByFilter sums the cost of every month of the period each matching subscription is active in, plus
+// the adjustments of the period
+func (r *Repository) CostSubsByFilter(_ context.Context, f usecase.SubFilter) (int64, error) {
+	if f.Period == nil || f.Period.From.IsZero() || f.Period.To.IsZero() {
+		return 0, fmt.Errorf("cost subs by filter: %w", usecase.ErrInvalidPeriod)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var total int64
+	for _, s := range r.subs {
+		if matches(s, f) && active(s, f.Period.From, f.Period.To) && !r.hidden(s) {
+			total += s.Cost * activeMonths(s, *f.Period)
+		}
+	}
+	for _, a := range r.adjustmentsIn(f) {
+		total += a.Amount
+	}
+	return total, nil
+}
+
+// CostGroupedByFilter sums the cost and adjustments like CostSubsByFilter, per value of by; free subscriptions
+// are left out, their adjustments are not
+func (r *Repository) CostGroupedByFilter(_ context.Context, f usecase.SubFilter, by usecase.CostGroupBy) ([]usecase.CostGroup, error) {
+	if f.Period == nil || f.Period.From.IsZero() || f.Period.To.IsZero() {
+		return nil, fmt.Errorf("cost grouped by filter: %w", usecase.ErrInvalidPeriod)
+	}
+	if !by.Valid() {
+		return nil, fmt.Errorf("cost grouped by filter: %w", usecase.ErrInvalidGroupBy)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	groups := map[string]*usecase.CostGroup{}
+	for _, s := range r.subs {
+		if !matches(s, f) || !active(s, f.Period.From, f.Period.To) || r.hidden(s) || s.Free() {
+			continue
+		}
+		last := f.Period.To
+		if s.DateTo != nil && s.DateTo.Before(last) {
+			last = *s.DateTo
+		}
+		first := latest(s.DateFrom, f.Period.From)
+		for m := first; !m.After(last); m = m.AddDate(0, 1, 0) {
+			g := usecase.CostGroup{}
+			switch by {
+			case usecase.CostByService:
+				g.ServiceName = s.ServiceName
+			case usecase.CostByUser:
+				g.UserID = s.UserID
+			case usecase.CostByMonth, usecase.CostByWeek, usecase.CostByYear:
+				g.Month = by.PeriodStart(m)
+			}
+			key := g.Key(by)
+			if groups[key] == nil {
+				groups[key] = &g
+			}
+			groups[key].Total += s.Cost
+			// a subscription counts once per group: in every month or week, but once per year, service or user
+			if by == usecase.CostByMonth || by == usecase.CostByWeek || m.Equal(first) ||
+				(by == usecase.CostByYear && m.Month() == time.January) {
+				groups[key].Count++
+			}
+		}
+	}
+
+	for _, a := range r.adjustmentsIn(f) {
+		s := r.subs[a.SubscriptionID]
+		g := usecase.CostGroup{}
+		switch by {
+		case usecase.CostByService:
+			g.ServiceName = s.ServiceName
+		case usecase.CostByUser:
+			g.UserID = s.UserID
+		case usecase.CostByMonth, usecase.CostByWeek, usecase.CostByYear:
+			g.Month = by.PeriodStart(a.Month)
+		}
+		key := g.Key(by)
+		if groups[key] == nil {
+			groups[key] = &g
+		}
+		groups[key].Total += a.Amount
+	}
+
+	out := make([]usecase.CostGroup, 0, len(groups))
+	for _, g := range groups {
+		out = append(out, *g)
+	}
+	slices.SortFunc(out, func(a, b usecase.CostGroup) int {
+		return strings.Compare(a.Key(by), b.Key(by))
+	})
+	return out, nil
+}
+
+// CostSummaryByFilter sums the cost like CostSubsByFilter and aggregates the monthly cost of matching paid
+// subscriptions
+func (r *Repository) CostSummaryByFilter(_ context.Context, f usecase.SubFilter) (usecase.CostSummary, error) {
+	if f.Period == nil || f.Period.From.IsZero() || f.Period.To.IsZero() {
+		return usecase.CostSummary{}, fmt.Errorf("cost summary by filter: %w", usecase.ErrInvalidPeriod)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var sum usecase.CostSummary
+	for _, s := range r.subs {
+		if !matches(s, f) || !active(s, f.Period.From, f.Period.To) || r.hidden(s) || s.Free() {
+			continue
+		}
+		if sum.Count == 0 || s.Cost < sum.MinCost {
+			sum.MinCost = s.Cost
+		}
+		sum.MaxCost = max(sum.MaxCost, s.Cost)
+		sum.Total += s.Cost * activeMonths(s, *f.Period)
+		sum.MonthlyCost += s.Cost
+		sum.Count++
+	}
+	for _, a := range r.adjustmentsIn(f) {
+		sum.Total += a.Amount
+	}
+	return sum, nil
+}
+
+// adjustmentsIn returns the adjustments of the period made to subscriptions matching the filter
+func (r *Repository) adjustmentsIn(f usecase.SubFilter) []entity.Adjustment {
+	var out []entity.Adjustment
+	for _, a := range r.adjustments {
+		s, ok := r.subs[a.SubscriptionID]
+		if ok && matches(s, f) && !r.hidden(s) && !a.Month.Before(f.Period.From) && !a.Month.After(f.Period.To) {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// activeMonths counts the months of the period the subscription is active in
+func activeMonths(s entity.Subscription, p usecase.Period) int64 {
+	last := p.To
+	if s.DateTo != nil && s.DateTo.Before(last) {
+		last = *s.DateTo
+	}
+	var n int64
+	for m := latest(s.DateFrom, p.From); !m.After(last); m = m.AddDate(0, 1, 0) {
+		n++
+	}
+	return n
+}
+
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// DataVersion returns the version of the user, the sum over all users when user is zero
+func (r *Repository) DataVersion(_ context.Context, user entity.UserID) (usecase.DataVersion, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !user.IsZero() {
+		return r.versions[user], nil
+	}
+	var out usecase.DataVersion
+	for _, v := range r.versions {
+		out.Version += v.Version
+		if v.ChangedAt.After(out.ChangedAt) {
+			out.ChangedAt = v.ChangedAt
+		}
+	}
+	return out, nil
+}
+
+// ActiveStatsByService counts subscriptions active in the month per service name
+func (r *Repository) ActiveStatsByService(_ context.Context, month time.Time) ([]usecase.ServiceStats, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	byService := map[string]*usecase.ServiceStats{}
+	for _, s := range r.subs {
+		if !active(s, month, month) || r.hidden(s) {
+			continue
+		}
+		st, ok := byService[s.ServiceName]
+		if !ok {
+			st = &usecase.ServiceStats{ServiceName: s.ServiceName}
+			byService[s.ServiceName] = st
+		}
+		st.Active++
+		st.MonthlyCost += s.Cost
+	}
+	out := make([]usecase.ServiceStats, 0, len(byService))
+	for _, st := range byService {
+		out = append(out, *st)
+	}
+	slices.SortFunc(out, func(a, b usecase.ServiceStats) int { return strings.Compare(a.ServiceName, b.ServiceName) })
+	return out, nil
+}
+
+// PriceBenchmarks aggregates costs of the month per canonical service name over users who opted in
+func (r *Repository) PriceBenchmarks(_ context.Context, month time.Time, minUsers int) ([]usecase.PriceBenchmark, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	costs := map[string][]int64{}
+	users := map[string]map[entity.UserID]bool{}
+	for _, s := range r.subs {
+		if !active(s, month, month) || !r.settings[s.UserID].SharePriceStats || r.hidden(s) || s.Free() {
+			continue
+		}
+		service := strings.ToLower(strings.TrimSpace(s.ServiceName))
+		costs[service] = append(costs[service], s.Cost)
+		if users[service] == nil {
+			users[service] = map[entity.UserID]bool{}
+		}
+		users[service][s.UserID] = true
+	}
+	out := make([]usecase.PriceBenchmark, 0, len(costs))
+	for service, cs := range costs {
+		if len(users[service]) < minUsers {
+			continue
+		}
+		slices.Sort(cs)
+		var sum int64
+		for _, c := range cs {
+			sum += c
+		}
+		n := len(cs)
+		median := float64(cs[n/2])
+		if n%2 == 0 {
+			median = float64(cs[n/2-1]+cs[n/2]) / 2
+		}
+		out = append(out, usecase.PriceBenchmark{
+			Service:       service,
+			Users:         int64(len(users[service])),
+			Subscriptions: int64(n),
+			AverageCost:   int64(math.Round(float64(sum) / float64(n))),
+			MedianCost:    int64(math.RoundToEven(median)),
+		})
+	}
+	slices.SortFunc(out, func(a, b usecase.PriceBenchmark) int { return strings.Compare(a.Service, b.Service) })
+	return out, nil
+}
+
+// MonthlySpendByUser sums each user's active subscriptions per month from..to, omitting months without any
+func (r *Repository) MonthlySpendByUser(_ context.Context, from, to time.Time) ([]usecase.UserMonthSpend, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	type key struct {
+		user  entity.UserID
+		month time.Time
+	}
+	totals := map[key]int64{}
+	for m := dates.MonthStart(from); !m.After(to); m = m.AddDate(0, 1, 0) {
+		for _, s := range r.subs {
+			if active(s, m, m) && !r.hidden(s) {
+				totals[key{s.UserID, m}] += s.Cost
+			}
+		}
+	}
+	out := make([]usecase.UserMonthSpend, 0, len(totals))
+	for k, total := range totals {
+		out = append(out, usecase.UserMonthSpend{UserID: k.user, Month: k.month, Total: total})
+	}
+	slices.SortFunc(out, func(a, b usecase.UserMonthSpend) int {
+		if c := strings.Compare(a.UserID.String(), b.UserID.String()); c != 0 {
+			return c
+		}
+		return a.Month.Compare(b.Month)
+	})
+	return out, nil
+}
+
+// ChangesSince returns up to limit change log entries of the user after since, oldest first
+func (r *Repository) ChangesSince(_ context.Context, user entity.UserID, since entity.ChangePos, limit int) ([]entity.SubscriptionChange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := []entity.SubscriptionChange{}
+	for _, ch := range r.changes {
+		if len(out) == limit {
+			break
+		}
+		if ch.UserID == user && since.Before(ch.Pos()) {
+			out = append(out, ch)
+		}
+	}
+	return out, nil
+}
+
+// ReassignUser moves every subscription of from to the user to
+func (r *Repository) ReassignUser(_ context.Context, from, to entity.UserID, _ string) (int64, error) {
+	if from.IsZero() || to.IsZero() {
+		return 0, fmt.Errorf("reassign user: %w", entity.ErrInvalidUserID)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := make([]int64, 0)
+	for id, s := range r.subs {
+		if s.UserID == from {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		s := r.subs[id]
+		s.UserID = to
+		r.update(r.subs[id], &s)
+	}
+	return int64(len(ids)), nil
+}
+
+// MergeSubs stores merged over its row and removes dropped; both must still be at the versions they were read at
+func (r *Repository) MergeSubs(_ context.Context, merged, dropped *entity.Subscription, _ string) error {
+	if merged == nil || dropped == nil || merged.UserID.IsZero() {
+		return fmt.Errorf("merge subs: %w", usecase.ErrInvalidSubscription)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keep, err := r.writable(merged.ID, merged.UpdatedAt)
+	if err != nil {
+		return usecase.ErrPreconditionFailed
+	}
+	gone, err := r.writable(dropped.ID, dropped.UpdatedAt)
+	if err != nil {
+		return usecase.ErrPreconditionFailed
+	}
+	r.update(keep, merged)
+	for i := range r.adjustments {
+		if r.adjustments[i].SubscriptionID == dropped.ID {
+			r.adjustments[i].SubscriptionID = merged.ID
+		}
+	}
+	if seats, ok := r.seats[dropped.ID]; ok {
+		if _, shared := r.seats[merged.ID]; !shared {
+			seats.SubscriptionID = merged.ID
+			r.seats[merged.ID] = seats
+		}
+		delete(r.seats, dropped.ID)
+		r.bump(r.clock.Now().UTC().Truncate(time.Microsecond), seats.UserIDs...)
+	}
+	delete(r.subs, dropped.ID)
+	r.logChange(gone, entity.ChangeDelete, r.stamp())
+	return nil
+}
+
+// EndedBefore returns up to limit subscriptions that ended before the month, oldest IDs first
+func (r *Repository) EndedBefore(_ context.Context, before time.Time, limit int) ([]*entity.Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []*entity.Subscription
+	for _, s := range r.subs {
+		if s.DateTo != nil && s.DateTo.Before(before) {
+			out = append(out, clone(s))
+		}
+	}
+	slices.SortFunc(out, func(a, b *entity.Subscription) int { return cmp.Compare(a.ID, b.ID) })
+	return out[:min(limit, len(out))], nil
+}
+
+// PurgeSubs deletes the subscriptions with the given IDs and reports how many existed
+func (r *Repository) PurgeSubs(_ context.Context, ids []int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int64
+	for _, id := range ids {
+		if s, ok := r.subs[id]; ok {
+			delete(r.subs, id)
+			r.dropAdjustments(id)
+			r.dropSeats(id)
+			r.logChange(s, entity.ChangeDelete, r.stamp())
+			n++
+		}
+	}
+	return n, nil
+}
+
+// SaveAdjustment stores an adjustment of an existing subscription under the next adjustment ID and moves the
+// version of the subscription
+func (r *Repository) SaveAdjustment(_ context.Context, a *entity.Adjustment) (*entity.Adjustment, error) {
+	if a == nil || a.SubscriptionID <= 0 {
+		return nil, fmt.Errorf("save adjustment: %w", usecase.ErrInvalidID)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sub, ok := r.subs[a.SubscriptionID]
+	if !ok {
+		return nil, usecase.ErrSubscriptionNotFound
+	}
+	r.nextAdjustID++
+	stored := *a
+	stored.ID = r.nextAdjustID
+	stored.CreatedAt = r.clock.Now().UTC().Truncate(time.Microsecond)
+	r.adjustments = append(r.adjustments, stored)
+	// the net cost of the subscription changes, so does its version
+	sub.UpdatedAt = r.stamp()
+	r.subs[sub.ID] = sub
+	r.logChange(sub, entity.ChangeUpdate, sub.UpdatedAt)
+	return &stored, nil
+}
+
+// ListAdjustments returns the adjustments of the subscription ordered by month, then ID
+func (r *Repository) ListAdjustments(_ context.Context, subID int64) ([]entity.Adjustment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := []entity.Adjustment{}
+	for _, a := range r.adjustments {
+		if a.SubscriptionID == subID {
+			out = append(out, a)
+		}
+	}
+	slices.SortStableFunc(out, func(a, b entity.Adjustment) int {
+		return a.Month.Compare(b.Month)
+	})
+	return out, nil
+}
+
+// dropAdjustments removes the adjustments of a removed subscription, as the foreign key cascade does
+func (r *Repository) dropAdjustments(subID int64) {
+	r.adjustments = slices.DeleteFunc(r.adjustments, func(a entity.Adjustment) bool {
+		return a.SubscriptionID == subID
+	})
+}
+
+// dropSeats removes the seats of a removed subscription, as the foreign key cascade does, bumping its members
+func (r *Repository) dropSeats(subID int64) {
+	if seats, ok := r.seats[subID]; ok {
+		delete(r.seats, subID)
+		r.bump(r.clock.Now().UTC().Truncate(time.Microsecond), seats.UserIDs...)
+	}
+}
+
+// SaveSeats creates or replaces the seats of an existing subscription, deleting them when Total is 0
+func (r *Repository) SaveSeats(_ context.Context, s *entity.Seats) (*entity.Seats, error) {
+	if s == nil || s.SubscriptionID <= 0 {
+		return nil, fmt.Errorf("save seats: %w", usecase.ErrInvalidID)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sub, ok := r.subs[s.SubscriptionID]
+	if !ok {
+		return nil, usecase.ErrSubscriptionNotFound
+	}
+	now := r.clock.Now().UTC().Truncate(time.Microsecond)
+	r.bump(now, append([]entity.UserID{sub.UserID}, r.seats[s.SubscriptionID].UserIDs...)...)
+	if s.Total == 0 {
+		delete(r.seats, s.SubscriptionID)
+		return &entity.Seats{SubscriptionID: s.SubscriptionID}, nil
+	}
+	stored := *s
+	stored.UserIDs = slices.Clone(s.UserIDs)
+	stored.UpdatedAt = now
+	r.seats[s.SubscriptionID] = stored
+	r.bump(now, stored.UserIDs...)
+	out := stored
+	out.UserIDs = slices.Clone(stored.UserIDs)
+	return &out, nil
+}
+
+// GetSeats returns the seats of the subscription, nil when it is not shared
+func (r *Repository) GetSeats(_ context.Context, subID int64) (*entity.Seats, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seats, ok := r.seats[subID]
+	if !ok {
+		return nil, nil
+	}
+	seats.UserIDs = slices.Clone(seats.UserIDs)
+	return &seats, nil
+}
+
+// SeatSharesByFilter returns the seats the user of the filter holds in shared plans overlapping its period,
+// ordered by service name, then ID
+func (r *Repository) SeatSharesByFilter(_ context.Context, f usecase.SubFilter) ([]usecase.SeatShare, error) {
+	if f.Period == nil || f.Period.From.IsZero() || f.Period.To.IsZero() {
+		return nil, fmt.Errorf("seat shares by filter: %w", usecase.ErrInvalidPeriod)
+	}
+	if f.UserID.IsZero() {
+		return nil, fmt.Errorf("seat shares by filter: %w", entity.ErrInvalidUserID)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.deactivated[f.UserID]; ok {
+		return []usecase.SeatShare{}, nil
+	}
+	member := f.UserID
+	f.UserID = entity.UserID{}
+	out := []usecase.SeatShare{}
+	for id, seats := range r.seats {
+		s := r.subs[id]
+		if !slices.Contains(seats.UserIDs, member) || !matches(s, f) || r.hidden(s) || activeMonths(s, *f.Period) == 0 {
+			continue
+		}
+		out = append(out, usecase.SeatShare{SubscriptionID: id, PublicID: s.PublicID, ServiceName: s.ServiceName, Cost: s.Cost, Seats: seats.Total})
+	}
+	slices.SortFunc(out, func(a, b usecase.SeatShare) int {
+		return cmp.Or(strings.Compare(a.ServiceName, b.ServiceName), cmp.Compare(a.SubscriptionID, b.SubscriptionID))
+	})
+	return out, nil
+}
+
+// GetSettings returns the saved settings of the user or ErrSettingsNotFound
+func (r *Repository) GetSettings(_ context.Context, userID entity.UserID) (*entity.Settings, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.settings[userID]
+	if !ok {
+		return nil, usecase.ErrSettingsNotFound
+	}
+	return &s, nil
+}
+
+// SaveSettings creates or replaces the settings of the user
+func (r *Repository) SaveSettings(_ context.Context, s entity.Settings) (*entity.Settings, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s.UpdatedAt = r.stamp()
+	r.settings[s.UserID] = s
+	r.bump(s.UpdatedAt, s.UserID)
+	return &s, nil
+}
+
+// DeactivateUser marks the user deactivated at at, keeping an earlier mark
+func (r *Repository) DeactivateUser(_ context.Context, userID entity.UserID, at time.Time) (time.Time, error) {
+	if userID.IsZero() {
+		return time.Time{}, fmt.Errorf("deactivate user: %w", entity.ErrInvalidUserID)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if prev, ok := r.deactivated[userID]; ok {
+		return prev, nil
+	}
+	at = at.UTC().Truncate(time.Microsecond)
+	r.deactivated[userID] = at
+	r.bump(r.clock.Now().UTC().Truncate(time.Microsecond), userID)
+	return at, nil
+}
+
+// ReactivateUser removes the deactivation mark of the user
+func (r *Repository) ReactivateUser(_ context.Context, userID entity.UserID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.deactivated[userID]; ok {
+		delete(r.deactivated, userID)
+		r.bump(r.clock.Now().UTC().Truncate(time.Microsecond), userID)
+	}
+	return nil
+}
+
+// UserDeactivatedAt returns when the user was deactivated, zero time for an active user
+func (r *Repository) UserDeactivatedAt(_ context.Context, userID entity.UserID) (time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.deactivated[userID], nil
+}
+
+// DeleteUser removes the user as d says; with DeleteUserBlock nothing changes while a subscription of the user
+// has not ended by d.Since
+func (r *Repository) DeleteUser(_ context.Context, userID entity.UserID, d usecase.UserDeletion, _ string) (int64, error) {
+	if userID.IsZero() {
+		return 0, fmt.Errorf("delete user: %w", entity.ErrInvalidUserID)
+	}
+	if d.Policy == usecase.DeleteUserAnonymize && d.AnonymousID.IsZero() {
+		return 0, fmt.Errorf("delete user: anonymous id: %w", entity.ErrInvalidUserID)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := make([]int64, 0)
+	for id, s := range r.subs {
+		if s.UserID != userID {
+			continue
+		}
+		if d.Policy == usecase.DeleteUserBlock && (s.DateTo == nil || !s.DateTo.Before(d.Since)) {
+			return 0, usecase.ErrUserHasActiveSubs
+		}
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	switch d.Policy {
+	case usecase.DeleteUserBlock, usecase.DeleteUserCascade:
+		for _, id := range ids {
+			s := r.subs[id]
+			delete(r.subs, id)
+			r.dropAdjustments(id)
+			r.dropSeats(id)
+			r.logChange(s, entity.ChangeDelete, r.stamp())
+		}
+	case usecase.DeleteUserAnonymize:
+		for _, id := range ids {
+			s := r.subs[id]
+			s.UserID = d.AnonymousID
+			r.update(r.subs[id], &s)
+		}
+	default:
+		return 0, fmt.Errorf("delete user: unknown policy %q", d.Policy)
+	}
+	for id, seats := range r.seats {
+		if slices.Contains(seats.UserIDs, userID) {
+			seats.UserIDs = slices.DeleteFunc(slices.Clone(seats.UserIDs), func(m entity.UserID) bool { return m == userID })
+			seats.UpdatedAt = r.clock.Now().UTC().Truncate(time.Microsecond)
+			r.seats[id] = seats
+			r.bump(seats.UpdatedAt, r.subs[id].UserID)
+		}
+	}
+	delete(r.settings, userID)
+	delete(r.deactivated, userID)
+	r.bump(r.clock.Now().UTC().Truncate(time.Microsecond), userID)
+	return int64(len(ids)), nil
+}
@@ -0,0 +1,482 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/clock"
+)
+
+var _ usecase.SubscriptionRepository = (*Repository)(nil)
+
+func month(m time.Month) time.Time {
+	return time.Date(2025, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func TestRepository_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	r := NewRepository(WithClock(clock.NewFake(now)))
+	user := entity.UserID(uuid.New())
+
+	saved, err := r.SaveSub(ctx, &entity.Subscription{UserID: user, ServiceName: "Netflix", Cost: 400, DateFrom: month(7)})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), saved.ID)
+	assert.Equal(t, now, saved.UpdatedAt)
+	assert.False(t, saved.PublicID.IsZero(), "a public ID is made when none is given")
+
+	end := month(9)
+	upd := *saved
+	upd.Cost, upd.DateTo = 500, &end
+	require.NoError(t, r.UpdateSub(ctx, &upd))
+	got, err := r.GetSubByID(ctx, saved.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), got.Cost)
+	assert.True(t, got.UpdatedAt.After(saved.UpdatedAt), "versions never repeat under a frozen clock")
+	assert.Equal(t, saved.PublicID, got.PublicID, "updates keep the public ID")
+	byPublic, err := r.GetSubByPublicID(ctx, saved.PublicID)
+	require.NoError(t, err)
+	assert.Equal(t, got, byPublic)
+
+	assert.ErrorIs(t, r.UpdateSub(ctx, &upd), usecase.ErrPreconditionFailed, "stale version")
+	assert.ErrorIs(t, r.DeleteSub(ctx, saved.ID, saved.UpdatedAt), usecase.ErrPreconditionFailed)
+	require.NoError(t, r.DeleteSub(ctx, saved.ID, got.UpdatedAt))
+	_, err = r.GetSubByID(ctx, saved.ID)
+	assert.ErrorIs(t, err, usecase.ErrSubscriptionNotFound)
+	_, err = r.GetSubByPublicID(ctx, saved.PublicID)
+	assert.ErrorIs(t, err, usecase.ErrSubscriptionNotFound)
+
+	changes, err := r.ChangesSince(ctx, user, entity.ChangePos{Tx: 1, Seq: 1}, 10)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	assert.Equal(t, entity.ChangeUpdate, changes[0].Op)
+	assert.Equal(t, entity.ChangeDelete, changes[1].Op)
+	others, err := r.ChangesSince(ctx, entity.UserID(uuid.New()), entity.ChangePos{}, 10)
+	require.NoError(t, err)
+	assert.Empty(t, others, "the log is read per user")
+}
+
+func TestRepository_ListAndCost(t *testing.T) {
+	ctx := context.Background()
+	r := NewRepository()
+	ann, bob := entity.UserID(uuid.New()), entity.UserID(uuid.New())
+	aug := month(8)
+	for _, s := range []entity.Subscription{
+		{UserID: ann, ServiceName: "Yandex", Cost: 300, DateFrom: month(7)},
+		{UserID: ann, ServiceName: "Netflix", Cost: 400, DateFrom: month(7), DateTo: &aug},
+		{UserID: ann, ServiceName: "Spotify", Cost: 200, DateFrom: month(10)},
+		{UserID: bob, ServiceName: "Netflix", Cost: 999, DateFrom: month(1)},
+	} {
+		_, err := r.SaveSub(ctx, &s)
+		require.NoError(t, err)
+	}
+
+	list, err := r.ListSubsByFilter(ctx, usecase.SubFilter{UserID: ann, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, []string{"Netflix", "Yandex"}, []string{list[0].ServiceName, list[1].ServiceName})
+
+	next, err := r.ListSubsByFilter(ctx, usecase.SubFilter{UserID: ann, After: &usecase.ListCursor{
+		StartDate: list[1].DateFrom, ServiceName: list[1].ServiceName, ID: list[1].ID,
+	}})
+	require.NoError(t, err)
+	require.Len(t, next, 1)
+	assert.Equal(t, "Spotify", next[0].ServiceName)
+
+	net, cheap, jul := "NET", int64(350), month(7)
+	list, err = r.ListSubsByFilter(ctx, usecase.SubFilter{Where: usecase.Conditions{ServiceContains: &net, StartFrom: &jul}})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, int64(400), list[0].Cost, "bob's Netflix started in January")
+	list, err = r.ListSubsByFilter(ctx, usecase.SubFilter{Where: usecase.Conditions{CostMax: &cheap}})
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	total, err := r.CostSubsByFilter(ctx, usecase.SubFilter{UserID: ann, Period: &usecase.Period{From: month(8), To: month(9)}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2*300+400), total)
+	_, err = r.CostSubsByFilter(ctx, usecase.SubFilter{UserID: ann})
+	assert.ErrorIs(t, err, usecase.ErrInvalidPeriod)
+
+	summer := &usecase.Period{From: month(8), To: month(9)}
+	groups, err := r.CostGroupedByFilter(ctx, usecase.SubFilter{UserID: ann, Period: summer}, usecase.CostByService)
+	require.NoError(t, err)
+	assert.Equal(t, []usecase.CostGroup{
+		{ServiceName: "Netflix", Total: 400, Count: 1},
+		{ServiceName: "Yandex", Total: 600, Count: 1},
+	}, groups)
+	groups, err = r.CostGroupedByFilter(ctx, usecase.SubFilter{Period: summer}, usecase.CostByMonth)
+	require.NoError(t, err)
+	assert.Equal(t, []usecase.CostGroup{
+		{Month: month(8), Total: 300 + 400 + 999, Count: 3},
+		{Month: month(9), Total: 300 + 999, Count: 2},
+	}, groups)
+	groups, err = r.CostGroupedByFilter(ctx, usecase.SubFilter{Period: summer}, usecase.CostByWeek)
+	require.NoError(t, err)
+	assert.Equal(t, []usecase.CostGroup{
+		{Month: month(7).AddDate(0, 0, 27), Total: 300 + 400 + 999, Count: 3},
+		{Month: month(9), Total: 300 + 999, Count: 2},
+	}, groups, "august starts on a Friday, its week on Monday the 28th of July")
+	groups, err = r.CostGroupedByFilter(ctx, usecase.SubFilter{Period: summer}, usecase.CostByYear)
+	require.NoError(t, err)
+	assert.Equal(t, []usecase.CostGroup{{Month: month(1), Total: 2*300 + 400 + 2*999, Count: 3}}, groups)
+	_, err = r.CostGroupedByFilter(ctx, usecase.SubFilter{Period: summer}, "category")
+	assert.ErrorIs(t, err, usecase.ErrInvalidGroupBy)
+
+	sum, err := r.CostSummaryByFilter(ctx, usecase.SubFilter{Period: summer})
+	require.NoError(t, err)
+	assert.Equal(t, usecase.CostSummary{Total: 2*300 + 400 + 2*999, Count: 3, MonthlyCost: 300 + 400 + 999, MinCost: 300, MaxCost: 999}, sum)
+	assert.Equal(t, int64(566), sum.AverageCost())
+	sum, err = r.CostSummaryByFilter(ctx, usecase.SubFilter{UserID: ann, Period: &usecase.Period{From: month(1), To: month(2)}})
+	require.NoError(t, err)
+	assert.Zero(t, sum)
+
+	spend, err := r.MonthlySpendByUser(ctx, month(7), month(7))
+	require.NoError(t, err)
+	assert.Len(t, spend, 2)
+
+	n, err := r.ReassignUser(ctx, bob, ann, "merge accounts")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	stats, err := r.ActiveStatsByService(ctx, month(7))
+	require.NoError(t, err)
+	assert.Equal(t, []usecase.ServiceStats{
+		{ServiceName: "Netflix", Active: 2, MonthlyCost: 1399},
+		{ServiceName: "Yandex", Active: 1, MonthlyCost: 300},
+	}, stats)
+}
+
+func TestRepository_ListTies(t *testing.T) {
+	ctx := context.Background()
+	r := NewRepository()
+	ann := entity.UserID(uuid.New())
+	var want []int64
+	// inserted out of order: the IDs alone decide between equal start dates and services
+	for _, name := range []string{"netflix", "Netflix", "Netflix", "Okko", "netflix", "Netflix", "Okko"} {
+		saved, err := r.SaveSub(ctx, &entity.Subscription{UserID: ann, ServiceName: name, Cost: 100, DateFrom: month(7)})
+		require.NoError(t, err)
+		want = append(want, saved.ID)
+	}
+	// bytewise, upper case sorts before lower case
+	want = []int64{want[1], want[2], want[5], want[3], want[6], want[0], want[4]}
+
+	for size := 1; size <= len(want); size++ {
+		var byCursor, byOffset []int64
+		for f := (usecase.SubFilter{UserID: ann, Limit: size}); ; {
+			page, err := r.ListSubsByFilter(ctx, f)
+			require.NoError(t, err)
+			for _, s := range page {
+				byCursor = append(byCursor, s.ID)
+			}
+			if len(page) < size {
+				break
+			}
+			f.After = usecase.CursorAt(page[len(page)-1])
+		}
+		for offset := 0; offset < len(want); offset += size {
+			page, err := r.ListSubsByFilter(ctx, usecase.SubFilter{UserID: ann, Limit: size, Offset: offset})
+			require.NoError(t, err)
+			for _, s := range page {
+				byOffset = append(byOffset, s.ID)
+			}
+		}
+		assert.Equal(t, want, byCursor, "page size %d", size)
+		assert.Equal(t, want, byOffset, "page size %d", size)
+	}
+}
+
+func TestRepository_FreeSubscriptions(t *testing.T) {
+	ctx := context.Background()
+	r := NewRepository()
+	user := entity.UserID(uuid.New())
+	for _, s := range []entity.Subscription{
+		{UserID: user, ServiceName: "Netflix", Cost: 400, DateFrom: month(7)},
+		{UserID: user, ServiceName: "Spotify Free", Cost: 0, DateFrom: month(7)},
+	} {
+		_, err := r.SaveSub(ctx, &s)
+		require.NoError(t, err)
+	}
+	summer := &usecase.Period{From: month(7), To: month(8)}
+
+	list, err := r.ListSubsByFilter(ctx, usecase.SubFilter{UserID: user})
+	require.NoError(t, err)
+	assert.Len(t, list, 2, "free subscriptions are listed")
+
+	sum, err := r.CostSummaryByFilter(ctx, usecase.SubFilter{UserID: user, Period: summer})
+	require.NoError(t, err)
+	assert.Equal(t, usecase.CostSummary{Total: 800, Count: 1, MonthlyCost: 400, MinCost: 400, MaxCost: 400}, sum)
+
+	groups, err := r.CostGroupedByFilter(ctx, usecase.SubFilter{UserID: user, Period: summer}, usecase.CostByService)
+	require.NoError(t, err)
+	assert.Equal(t, []usecase.CostGroup{{ServiceName: "Netflix", Total: 800, Count: 1}}, groups)
+}
+
+func TestRepository_Adjustments(t *testing.T) {
+	ctx := context.Background()
+	r := NewRepository()
+	user := entity.UserID(uuid.New())
+	var ids []int64
+	for _, s := range []entity.Subscription{
+		{UserID: user, ServiceName: "Netflix", Cost: 400, DateFrom: month(7)},
+		{UserID: user, ServiceName: "Spotify", Cost: 200, DateFrom: month(7)},
+	} {
+		saved, err := r.SaveSub(ctx, &s)
+		require.NoError(t, err)
+		ids = append(ids, saved.ID)
+	}
+	_, err := r.SaveAdjustment(ctx, &entity.Adjustment{SubscriptionID: 99, Month: month(8), Amount: -1})
+	assert.ErrorIs(t, err, usecase.ErrSubscriptionNotFound)
+	for _, a := range []entity.Adjustment{
+		{SubscriptionID: ids[0], Month: month(8), Amount: -400, Note: "refund"},
+		{SubscriptionID: ids[1], Month: month(7), Amount: 50},
+		{SubscriptionID: ids[1], Month: month(9), Amount: -100, Note: "outside the period"},
+	} {
+		before, _ := r.GetSubByID(ctx, a.SubscriptionID)
+		_, err := r.SaveAdjustment(ctx, &a)
+		require.NoError(t, err)
+		after, _ := r.GetSubByID(ctx, a.SubscriptionID)
+		assert.True(t, after.UpdatedAt.After(before.UpdatedAt), "an adjustment moves the version of its subscription")
+	}
+	summer := &usecase.Period{From: month(7), To: month(8)}
+
+	total, err := r.CostSubsByFilter(ctx, usecase.SubFilter{UserID: user, Period: summer})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1200-400+50, total)
+	sum, err := r.CostSummaryByFilter(ctx, usecase.SubFilter{UserID: user, Period: summer})
+	require.NoError(t, err)
+	assert.Equal(t, usecase.CostSummary{Total: 850, Count: 2, MonthlyCost: 600, MinCost: 200, MaxCost: 400}, sum)
+	groups, err := r.CostGroupedByFilter(ctx, usecase.SubFilter{UserID: user, Period: summer}, usecase.CostByMonth)
+	require.NoError(t, err)
+	assert.Equal(t, []usecase.CostGroup{
+		{Month: month(7), Total: 650, Count: 2},
+		{Month: month(8), Total: 200, Count: 2},
+	}, groups)
+
+	// merging keeps the adjustments of the dropped subscription, deleting removes them
+	keep, _ := r.GetSubByID(ctx, ids[0])
+	drop, _ := r.GetSubByID(ctx, ids[1])
+	require.NoError(t, r.MergeSubs(ctx, keep, drop, "test"))
+	list, err := r.ListAdjustments(ctx, ids[0])
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	assert.Equal(t, []time.Time{month(7), month(8), month(9)}, []time.Time{list[0].Month, list[1].Month, list[2].Month})
+	require.NoError(t, r.DeleteSub(ctx, ids[0], time.Time{}))
+	list, err = r.ListAdjustments(ctx, ids[0])
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestRepository_Seats(t *testing.T) {
+	ctx := context.Background()
+	r := NewRepository()
+	owner, member := entity.UserID(uuid.New()), entity.UserID(uuid.New())
+	var ids []int64
+	var pids []entity.PublicID
+	for _, s := range []entity.Subscription{
+		{UserID: owner, ServiceName: "Spotify", Cost: 300, DateFrom: month(7)},
+		{UserID: owner, ServiceName: "Netflix", Cost: 1000, DateFrom: month(9)},
+		{UserID: owner, ServiceName: "Apple One", Cost: 900, DateFrom: month(7)},
+	} {
+		saved, err := r.SaveSub(ctx, &s)
+		require.NoError(t, err)
+		ids, pids = append(ids, saved.ID), append(pids, saved.PublicID)
+	}
+	_, err := r.SaveSeats(ctx, &entity.Seats{SubscriptionID: 99, Total: 2})
+	assert.ErrorIs(t, err, usecase.ErrSubscriptionNotFound)
+	seats, err := r.GetSeats(ctx, ids[0])
+	require.NoError(t, err)
+	assert.Nil(t, seats, "not shared")
+	for _, id := range ids[:2] {
+		_, err := r.SaveSeats(ctx, &entity.Seats{SubscriptionID: id, Total: 3, UserIDs: []entity.UserID{member}})
+		require.NoError(t, err)
+	}
+	seats, err = r.GetSeats(ctx, ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, []entity.UserID{member}, seats.UserIDs)
+	assert.EqualValues(t, 100, seats.Share(300))
+
+	summer := &usecase.Period{From: month(7), To: month(8)}
+	shares, err := r.SeatSharesByFilter(ctx, usecase.SubFilter{UserID: member, Period: summer})
+	require.NoError(t, err)
+	assert.Equal(t, []usecase.SeatShare{{SubscriptionID: ids[0], PublicID: pids[0], ServiceName: "Spotify", Cost: 300, Seats: 3}}, shares,
+		"Netflix starts after the period")
+	shares, err = r.SeatSharesByFilter(ctx, usecase.SubFilter{UserID: member, Period: &usecase.Period{From: month(7), To: month(9)}})
+	require.NoError(t, err)
+	require.Len(t, shares, 2)
+	assert.Equal(t, "Netflix", shares[0].ServiceName, "ordered by service name")
+	shares, err = r.SeatSharesByFilter(ctx, usecase.SubFilter{UserID: owner, Period: summer})
+	require.NoError(t, err)
+	assert.Empty(t, shares, "the owner holds no seat of their own plans")
+
+	// merging moves the seats to a kept subscription that has none, deleting removes them
+	keep, _ := r.GetSubByID(ctx, ids[2])
+	drop, _ := r.GetSubByID(ctx, ids[0])
+	require.NoError(t, r.MergeSubs(ctx, keep, drop, "test"))
+	seats, err = r.GetSeats(ctx, ids[2])
+	require.NoError(t, err)
+	require.NotNil(t, seats)
+	assert.Equal(t, ids[2], seats.SubscriptionID)
+	_, err = r.SaveSeats(ctx, &entity.Seats{SubscriptionID: ids[2]})
+	require.NoError(t, err)
+	seats, err = r.GetSeats(ctx, ids[2])
+	require.NoError(t, err)
+	assert.Nil(t, seats, "a total of 0 stops sharing")
+	require.NoError(t, r.DeleteSub(ctx, ids[1], time.Time{}))
+	seats, err = r.GetSeats(ctx, ids[1])
+	require.NoError(t, err)
+	assert.Nil(t, seats)
+}
+
+func TestRepository_Settings(t *testing.T) {
+	ctx := context.Background()
+	r := NewRepository()
+	user := entity.UserID(uuid.New())
+
+	_, err := r.GetSettings(ctx, user)
+	assert.ErrorIs(t, err, usecase.ErrSettingsNotFound)
+	saved, err := r.SaveSettings(ctx, entity.DefaultSettings(user))
+	require.NoError(t, err)
+	assert.False(t, saved.UpdatedAt.IsZero())
+	got, err := r.GetSettings(ctx, user)
+	require.NoError(t, err)
+	assert.Equal(t, *saved, *got)
+}
+
+func TestRepository_DataVersion(t *testing.T) {
+	ctx := context.Background()
+	r := NewRepository()
+	owner, member := entity.UserID(uuid.New()), entity.UserID(uuid.New())
+	version := func(u entity.UserID) int64 {
+		v, err := r.DataVersion(ctx, u)
+		require.NoError(t, err)
+		return v.Version
+	}
+	assert.Zero(t, version(owner))
+
+	saved, err := r.SaveSub(ctx, &entity.Subscription{UserID: owner, ServiceName: "Spotify", Cost: 300, DateFrom: month(7)})
+	require.NoError(t, err)
+	last := version(owner)
+	for _, write := range []func() error{
+		func() error {
+			_, err := r.SaveAdjustment(ctx, &entity.Adjustment{SubscriptionID: saved.ID, Month: month(7), Amount: -100})
+			return err
+		},
+		func() error {
+			_, err := r.SaveSeats(ctx, &entity.Seats{SubscriptionID: saved.ID, Total: 2, UserIDs: []entity.UserID{member}})
+			return err
+		},
+		func() error { _, err := r.DeactivateUser(ctx, owner, time.Now()); return err },
+		func() error { return r.ReactivateUser(ctx, owner) },
+		func() error { return r.DeleteSub(ctx, saved.ID, time.Time{}) },
+	} {
+		require.NoError(t, write())
+		assert.Greater(t, version(owner), last)
+		last = version(owner)
+	}
+	assert.NotZero(t, version(member), "seats bump their members")
+	assert.Equal(t, version(owner)+version(member), version(entity.UserID{}), "the sum over all users")
+}
+
+func TestRepository_DeleteUser(t *testing.T) {
+	ctx := context.Background()
+	r := NewRepository()
+	user, other := entity.UserID(uuid.New()), entity.UserID(uuid.New())
+	ended := month(8)
+	var ids []int64
+	for _, s := range []entity.Subscription{
+		{UserID: user, ServiceName: "Netflix", Cost: 1000, DateFrom: month(7)},
+		{UserID: user, ServiceName: "Spotify", Cost: 300, DateFrom: month(7), DateTo: &ended},
+		{UserID: other, ServiceName: "Apple One", Cost: 900, DateFrom: month(7)},
+	} {
+		saved, err := r.SaveSub(ctx, &s)
+		require.NoError(t, err)
+		ids = append(ids, saved.ID)
+	}
+	_, err := r.SaveSeats(ctx, &entity.Seats{SubscriptionID: ids[2], Total: 3, UserIDs: []entity.UserID{user}})
+	require.NoError(t, err)
+	_, err = r.SaveSettings(ctx, entity.DefaultSettings(user))
+	require.NoError(t, err)
+	_, err = r.DeactivateUser(ctx, user, time.Now())
+	require.NoError(t, err)
+
+	_, err = r.DeleteUser(ctx, user, usecase.UserDeletion{Policy: usecase.DeleteUserBlock, Since: month(9)}, "test")
+	assert.ErrorIs(t, err, usecase.ErrUserHasActiveSubs, "Netflix has no end date")
+	got, _ := r.GetSubByID(ctx, ids[0])
+	assert.NotNil(t, got, "a refused deletion changes nothing")
+
+	anon := entity.UserID(uuid.New())
+	n, err := r.DeleteUser(ctx, user, usecase.UserDeletion{Policy: usecase.DeleteUserAnonymize, AnonymousID: anon}, "test")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+	got, _ = r.GetSubByID(ctx, ids[0])
+	assert.Equal(t, anon, got.UserID)
+	seats, err := r.GetSeats(ctx, ids[2])
+	require.NoError(t, err)
+	assert.Empty(t, seats.UserIDs, "the seat in a plan of another user is given up")
+	_, err = r.GetSettings(ctx, user)
+	assert.ErrorIs(t, err, usecase.ErrSettingsNotFound)
+	at, _ := r.UserDeactivatedAt(ctx, user)
+	assert.True(t, at.IsZero())
+
+	n, err = r.DeleteUser(ctx, anon, usecase.UserDeletion{Policy: usecase.DeleteUserCascade}, "test")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+	got, _ = r.GetSubByID(ctx, ids[1])
+	assert.Nil(t, got)
+	got, _ = r.GetSubByID(ctx, ids[2])
+	assert.NotNil(t, got, "other users keep their subscriptions")
+
+	got.DateTo = &ended
+	require.NoError(t, r.UpdateSub(ctx, got))
+	n, err = r.DeleteUser(ctx, other, usecase.UserDeletion{Policy: usecase.DeleteUserBlock, Since: month(8)}, "test")
+	assert.ErrorIs(t, err, usecase.ErrUserHasActiveSubs, "ending in the month still counts")
+	n, err = r.DeleteUser(ctx, other, usecase.UserDeletion{Policy: usecase.DeleteUserBlock, Since: month(9)}, "test")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n, "ended subscriptions do not block and are deleted")
+	seats, err = r.GetSeats(ctx, ids[2])
+	require.NoError(t, err)
+	assert.Nil(t, seats)
+}
+
+func TestRepository_InTx(t *testing.T) {
+	ctx := context.Background()
+	r := NewRepository()
+	saved, err := r.SaveSub(ctx, &entity.Subscription{UserID: entity.UserID(uuid.New()), ServiceName: "Netflix", Cost: 400, DateFrom: month(7)})
+	require.NoError(t, err)
+
+	entered := make(chan struct{})
+	done := make(chan struct{})
+	err = r.InTx(ctx, func(ctx context.Context) error {
+		locked, err := r.GetSubByIDForUpdate(ctx, saved.ID)
+		require.NoError(t, err)
+		go func() {
+			defer close(done)
+			_ = r.InTx(context.Background(), func(context.Context) error {
+				close(entered)
+				return nil
+			})
+		}()
+		// a nested call joins the transaction instead of waiting for it
+		require.NoError(t, r.InTx(ctx, func(context.Context) error { return nil }))
+		select {
+		case <-entered:
+			t.Error("another transaction ran while the row was locked")
+		case <-time.After(20 * time.Millisecond):
+		}
+		locked.Cost = 500
+		return r.UpdateSub(ctx, locked)
+	})
+	require.NoError(t, err)
+	<-done
+
+	got, err := r.GetSubByID(ctx, saved.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), got.Cost)
+}
@@ -0,0 +1,23 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgconn/ctxwatch"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// cancelDeadlineDelay - how long a cancelled statement may take to stop on the server before its connection is
+// dropped instead
+const cancelDeadlineDelay = 2 * time.Second
+
+// CancelStatements makes the connections of cfg send Postgres a cancel request as soon as the context of a
+// running statement ends, e.g. when the HTTP client goes away. By default pgx only stops waiting: the statement
+// keeps running on the server and the connection is closed, here the statement stops and the connection goes
+// back to the pool
+func CancelStatements(cfg *pgxpool.Config) {
+	cfg.ConnConfig.BuildContextWatcherHandler = func(conn *pgconn.PgConn) ctxwatch.Handler {
+		return &pgconn.CancelRequestContextWatcherHandler{Conn: conn, DeadlineDelay: cancelDeadlineDelay}
+	}
+}
@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"subs_tracker/internal/usecase"
+)
+
+// SQLSTATEs of constraint violations
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgCheckViolation      = "23514"
+)
+
+// keyDetail - the columns in the detail of a unique or foreign key violation: Key (public_id)=(…) already exists
+var keyDetail = regexp.MustCompile(`^Key \(([a-z_]+)\)=`)
+
+// checkColumns - the columns of CHECK constraints over several columns, which Postgres names after the table alone
+var checkColumns = map[string]string{
+	"subscriptions_check":      "end_date",
+	"subscription_seats_check": "member_ids",
+}
+
+// apiFields - API names of the columns named differently in responses
+var apiFields = map[string]string{
+	"public_id":       "id",
+	"subscription_id": "id",
+	"member_ids":      "user_ids",
+	"cost_minor":      "cost",
+}
+
+// constraintErr turns a constraint violation into a usecase.ConstraintError naming the API field it is on, and
+// returns any other err as is
+func constraintErr(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	var kind error
+	switch pgErr.Code {
+	case pgUniqueViolation:
+		kind = usecase.ErrDuplicate
+	case pgForeignKeyViolation:
+		kind = usecase.ErrMissingReference
+	case pgCheckViolation:
+		kind = usecase.ErrConstraint
+	default:
+		return err
+	}
+	return usecase.NewConstraintError(kind, constraintField(pgErr), pgErr.ConstraintName, err)
+}
+
+// constraintField names the column of a violated constraint as the API does, empty when it is not known
+func constraintField(pgErr *pgconn.PgError) string {
+	column := pgErr.ColumnName
+	if m := keyDetail.FindStringSubmatch(pgErr.Detail); column == "" && m != nil {
+		column = m[1]
+	}
+	if column == "" && pgErr.Code == pgCheckViolation {
+		// a CHECK over one column is named <table>_<column>_check
+		column = checkColumns[pgErr.ConstraintName]
+		name, named := strings.CutSuffix(pgErr.ConstraintName, "_check")
+		if col, ok := strings.CutPrefix(name, pgErr.TableName+"_"); column == "" && named && ok {
+			column = col
+		}
+	}
+	if f, ok := apiFields[column]; ok {
+		return f
+	}
+	return column
+}
@@ -0,0 +1,167 @@
+package postgres
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"subs_tracker/internal/repository/subscription/postgres/sqlc"
+)
+
+// explainedQueries lists sqlc query names whose plans are logged when they run slow
+var explainedQueries = map[string]struct{}{
+	"ListSubscriptions":          {},
+	"SumSubscriptionCost":        {},
+	"SumAllSubscriptionsCost":    {},
+	"SumSubscriptionCostGrouped": {},
+	"SubscriptionCostSummary":    {},
+}
+
+const (
+	// explainTimeout bounds a single EXPLAIN ANALYZE, which runs the slow query once more
+	explainTimeout = 30 * time.Second
+	// explainInterval - a query is explained at most this often, however often it runs slow
+	explainInterval = time.Minute
+)
+
+// explainDB wraps sqlc.DBTX and logs EXPLAIN (ANALYZE, BUFFERS) output for slow read queries
+type explainDB struct {
+	sqlc.DBTX
+	threshold time.Duration
+	log       *slog.Logger
+
+	mu sync.Mutex
+	// explained - when each query was last explained
+	explained map[string]time.Time
+}
+
+// Query runs the query and explains it once the rows are closed if it exceeded the threshold
+func (e *explainDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	start := time.Now()
+	rows, err := e.DBTX.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &explainRows{Rows: rows, done: func() { e.explainIfSlow(ctx, sql, args, time.Since(start)) }}, nil
+}
+
+// QueryRow runs the query and explains it after Scan if it exceeded the threshold
+func (e *explainDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	start := time.Now()
+	row := e.DBTX.QueryRow(ctx, sql, args...)
+	return &explainRow{Row: row, done: func() { e.explainIfSlow(ctx, sql, args, time.Since(start)) }}
+}
+
+// Exec is passed through untouched: write statements are never explained with ANALYZE
+func (e *explainDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return e.DBTX.Exec(ctx, sql, args...)
+}
+
+// explainIfSlow re-runs a whitelisted slow query under EXPLAIN ANALYZE in the background and logs the plan.
+// The request does not wait for it nor cancels it, and a query explained within explainInterval is skipped
+func (e *explainDB) explainIfSlow(ctx context.Context, sql string, args []interface{}, took time.Duration) {
+	if took < e.threshold {
+		return
+	}
+	name := queryName(sql)
+	if _, ok := explainedQueries[name]; !ok || !e.due(name) {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), explainTimeout)
+		defer cancel()
+		e.explain(ctx, name, sql, args, took)
+	}()
+}
+
+// due reports whether the query may be explained now and, if so, marks it explained
+func (e *explainDB) due(name string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := time.Now()
+	if last, ok := e.explained[name]; ok && now.Sub(last) < explainInterval {
+		return false
+	}
+	if e.explained == nil {
+		e.explained = map[string]time.Time{}
+	}
+	e.explained[name] = now
+	return true
+}
+
+// explain runs the query under EXPLAIN ANALYZE and logs the plan
+func (e *explainDB) explain(ctx context.Context, name, sql string, args []interface{}, took time.Duration) {
+	rows, err := e.DBTX.Query(ctx, "EXPLAIN (ANALYZE, BUFFERS) "+sql, args...)
+	if err != nil {
+		e.log.Warn("explain slow query failed", slog.String("query", name), slog.Any("error", err))
+		return
+	}
+	defer rows.Close()
+
+	var plan []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			e.log.Warn("explain slow query failed", slog.String("query", name), slog.Any("error", err))
+			return
+		}
+		plan = append(plan, line)
+	}
+	if err := rows.Err(); err != nil {
+		e.log.Warn("explain slow query failed", slog.String("query", name), slog.Any("error", err))
+		return
+	}
+
+	e.log.Warn("slow query plan",
+		slog.String("query", name),
+		slog.Float64("latency_ms", float64(took.Microseconds())/1000.0),
+		slog.String("plan", strings.Join(plan, "\n")),
+	)
+}
+
+// queryName extracts the sqlc query name from the "-- name: X :kind" header
+func queryName(sql string) string {
+	const prefix = "-- name: "
+	if !strings.HasPrefix(sql, prefix) {
+		return ""
+	}
+	rest := sql[len(prefix):]
+	if i := strings.IndexAny(rest, " \n"); i >= 0 {
+		rest = rest[:i]
+	}
+	return rest
+}
+
+// explainRows calls done exactly once when the rows are closed
+type explainRows struct {
+	pgx.Rows
+	done func()
+}
+
+func (r *explainRows) Close() {
+	r.Rows.Close()
+	if r.done != nil {
+		done := r.done
+		r.done = nil
+		done()
+	}
+}
+
+// explainRow calls done after the single row has been scanned
+type explainRow struct {
+	pgx.Row
+	done func()
+}
+
+func (r *explainRow) Scan(dest ...any) error {
+	err := r.Row.Scan(dest...)
+	if err == nil {
+		r.done()
+	}
+	return err
+}
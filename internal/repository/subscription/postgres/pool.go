@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Statement cache modes of PoolOptions
+const (
+	// StatementCachePrepare - statements are prepared once per connection and reused, the fastest mode
+	StatementCachePrepare = "prepare"
+	// StatementCacheDescribe - only the parameter and result types are cached, statements stay unnamed
+	StatementCacheDescribe = "describe"
+	// StatementCacheExec - nothing is cached, parameter types follow the Go types of the arguments
+	StatementCacheExec = "exec"
+	// StatementCacheSimple - the simple protocol, arguments are interpolated by pgx
+	StatementCacheSimple = "simple"
+)
+
+// statementCacheModes maps the modes to pgx query exec modes
+var statementCacheModes = map[string]pgx.QueryExecMode{
+	StatementCachePrepare:  pgx.QueryExecModeCacheStatement,
+	StatementCacheDescribe: pgx.QueryExecModeCacheDescribe,
+	StatementCacheExec:     pgx.QueryExecModeExec,
+	StatementCacheSimple:   pgx.QueryExecModeSimpleProtocol,
+}
+
+// PoolOptions — sizing and statement cache of a pool
+type PoolOptions struct {
+	// MinConns - connections kept open, see WarmUp; 0 keeps the pgx default
+	MinConns int32
+	// MaxConns - upper bound of connections; 0 keeps the pgx default
+	MaxConns int32
+	// StatementCache - one of the StatementCache modes, empty keeps prepare. Anything but prepare works behind
+	// pgbouncer in transaction pooling, where a prepared statement may be missing on the next server connection
+	StatementCache string
+}
+
+// Apply sets the options on cfg; settings given in the DSN itself are overridden only by non-zero options
+func (o PoolOptions) Apply(cfg *pgxpool.Config) {
+	if o.MinConns > 0 {
+		cfg.MinConns = o.MinConns
+	}
+	if o.MaxConns > 0 {
+		cfg.MaxConns = o.MaxConns
+	}
+	if cfg.MinConns > cfg.MaxConns {
+		cfg.MaxConns = cfg.MinConns
+	}
+	if mode, ok := statementCacheModes[o.StatementCache]; ok {
+		cfg.ConnConfig.DefaultQueryExecMode = mode
+	}
+}
+
+// WarmUp opens the MinConns connections of pool now instead of in the background after the first queries,
+// so the first requests after a start do not pay for the connection setup. Returns the number of connections
+// held at once and the first error
+func WarmUp(ctx context.Context, pool *pgxpool.Pool) (int, error) {
+	n := int(pool.Config().MinConns)
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		conns    = make([]*pgxpool.Conn, 0, n)
+		firstErr error
+	)
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := pool.Acquire(ctx)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			conns = append(conns, conn)
+		}()
+	}
+	wg.Wait()
+	for _, conn := range conns {
+		conn.Release()
+	}
+	return len(conns), firstErr
+}
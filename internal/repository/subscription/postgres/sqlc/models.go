@@ -6,13 +6,202 @@ package sqlc
 
 import (
 	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
-type Subscription struct {
+type AdminAuditLog struct {
+	ID        int64     `json:"id"`
+	Action    string    `json:"action"`
+	Actor     string    `json:"actor"`
+	Details   []byte    `json:"details"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Charge struct {
+	ID          int64     `json:"id"`
+	UserID      string    `json:"user_id"`
+	ServiceName string    `json:"service_name"`
+	ChargedOn   time.Time `json:"charged_on"`
+	Amount      int64     `json:"amount"`
+	RecordedAt  time.Time `json:"recorded_at"`
+}
+
+type ImportKey struct {
+	Key            string    `json:"key"`
+	SubscriptionID int64     `json:"subscription_id"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type ImportQuarantine struct {
 	ID          int64      `json:"id"`
 	UserID      string     `json:"user_id"`
 	ServiceName string     `json:"service_name"`
 	Cost        int64      `json:"cost"`
 	StartDate   time.Time  `json:"start_date"`
 	EndDate     *time.Time `json:"end_date"`
+	Reason      string     `json:"reason"`
+	Code        string     `json:"code"`
+	HeldAt      time.Time  `json:"held_at"`
+	ImportKey   string     `json:"import_key"`
+}
+
+type PriceReview struct {
+	ID             int64      `json:"id"`
+	SubscriptionID int64      `json:"subscription_id"`
+	PublicID       string     `json:"public_id"`
+	UserID         string     `json:"user_id"`
+	ServiceName    string     `json:"service_name"`
+	Cost           int64      `json:"cost"`
+	CatalogPrice   int64      `json:"catalog_price"`
+	OpenedAt       time.Time  `json:"opened_at"`
+	ResolvedAt     *time.Time `json:"resolved_at"`
+}
+
+type ReconciliationItem struct {
+	ID             int64       `json:"id"`
+	Kind           string      `json:"kind"`
+	UserID         string      `json:"user_id"`
+	ServiceName    string      `json:"service_name"`
+	Month          time.Time   `json:"month"`
+	Expected       int64       `json:"expected"`
+	Charged        int64       `json:"charged"`
+	SubscriptionID int64       `json:"subscription_id"`
+	PublicID       pgtype.UUID `json:"public_id"`
+	OpenedAt       time.Time   `json:"opened_at"`
+	ResolvedAt     *time.Time  `json:"resolved_at"`
+}
+
+type RequestAudit struct {
+	ID         int64     `json:"id"`
+	ReceivedAt time.Time `json:"received_at"`
+	Method     string    `json:"method"`
+	Route      string    `json:"route"`
+	Path       string    `json:"path"`
+	Status     int16     `json:"status"`
+	BodySha256 string    `json:"body_sha256"`
+	BodySize   int64     `json:"body_size"`
+	Client     string    `json:"client"`
+	RemoteIp   string    `json:"remote_ip"`
+	UserAgent  string    `json:"user_agent"`
+	RequestID  string    `json:"request_id"`
+	TenantID   string    `json:"tenant_id"`
+	PeerIp     string    `json:"peer_ip"`
+}
+
+type ShareLink struct {
+	TokenHash   string     `json:"token_hash"`
+	UserID      string     `json:"user_id"`
+	ServiceName string     `json:"service_name"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	TenantID    string     `json:"tenant_id"`
+}
+
+type Subscription struct {
+	ID          int64       `json:"id"`
+	UserID      string      `json:"user_id"`
+	ServiceName string      `json:"service_name"`
+	Cost        int64       `json:"cost"`
+	StartDate   time.Time   `json:"start_date"`
+	EndDate     *time.Time  `json:"end_date"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+	CreatedAt   time.Time   `json:"created_at"`
+	Currency    pgtype.Text `json:"currency"`
+	CostMinor   pgtype.Int8 `json:"cost_minor"`
+	PublicID    string      `json:"public_id"`
+}
+
+type SubscriptionAdjustment struct {
+	ID             int64     `json:"id"`
+	SubscriptionID int64     `json:"subscription_id"`
+	Month          time.Time `json:"month"`
+	Amount         int64     `json:"amount"`
+	Note           string    `json:"note"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type SubscriptionChange struct {
+	Seq            int64       `json:"seq"`
+	SubscriptionID int64       `json:"subscription_id"`
+	Op             string      `json:"op"`
+	ChangedAt      time.Time   `json:"changed_at"`
+	UserID         pgtype.UUID `json:"user_id"`
+	Tx             uint64      `json:"tx"`
+	PublicID       pgtype.UUID `json:"public_id"`
+}
+
+type SubscriptionSeat struct {
+	SubscriptionID int64     `json:"subscription_id"`
+	Total          int32     `json:"total"`
+	MemberIds      []string  `json:"member_ids"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type TenantTheme struct {
+	TenantID  string    `json:"tenant_id"`
+	LogoUrl   string    `json:"logo_url"`
+	Color     string    `json:"color"`
+	Footer    string    `json:"footer"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type UserActivity struct {
+	ID             int64      `json:"id"`
+	UserID         string     `json:"user_id"`
+	Kind           string     `json:"kind"`
+	SubscriptionID int64      `json:"subscription_id"`
+	PublicID       string     `json:"public_id"`
+	ServiceName    string     `json:"service_name"`
+	Cost           int64      `json:"cost"`
+	PreviousCost   int64      `json:"previous_cost"`
+	EndDate        *time.Time `json:"end_date"`
+	OccurredAt     time.Time  `json:"occurred_at"`
+}
+
+type UserDeactivation struct {
+	UserID        string    `json:"user_id"`
+	DeactivatedAt time.Time `json:"deactivated_at"`
+}
+
+type UserPseudonym struct {
+	Pseudonym string    `json:"pseudonym"`
+	UserIDEnc string    `json:"user_id_enc"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type UserSetting struct {
+	UserID          string    `json:"user_id"`
+	Currency        string    `json:"currency"`
+	Locale          string    `json:"locale"`
+	FirstDayOfWeek  int16     `json:"first_day_of_week"`
+	DateFormat      string    `json:"date_format"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	Timezone        string    `json:"timezone"`
+	SharePriceStats bool      `json:"share_price_stats"`
+	MonthlyGoal     int64     `json:"monthly_goal"`
+}
+
+type UserSpendChange struct {
+	UserID    string    `json:"user_id"`
+	Month     time.Time `json:"month"`
+	CostDelta int64     `json:"cost_delta"`
+	SubsDelta int32     `json:"subs_delta"`
+}
+
+type UserWebhook struct {
+	UserID    string    `json:"user_id"`
+	Url       string    `json:"url"`
+	Format    string    `json:"format"`
+	Secret    string    `json:"secret"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WidgetToken struct {
+	TokenHash string     `json:"token_hash"`
+	UserID    string     `json:"user_id"`
+	Origins   []string   `json:"origins"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
 }
@@ -1,13 +1,16 @@
 -- name: CreateSubscription :one
-INSERT INTO subscriptions (user_id, service_name, cost, start_date, end_date)
+INSERT INTO subscriptions (user_id, service_name, cost, start_date, end_date, currency, cost_minor, public_id)
 VALUES (
     sqlc.arg(user_id),
     sqlc.arg(service_name),
     sqlc.arg(cost),
     sqlc.arg(start_date),
-    sqlc.narg(end_date)
+    sqlc.narg(end_date),
+    'RUB',
+    sqlc.arg(cost)::bigint * 100,
+    COALESCE(sqlc.narg(public_id)::uuid, gen_random_uuid())
 )
-RETURNING id, user_id, service_name, cost, start_date, end_date;
+RETURNING id, user_id, service_name, cost, start_date, end_date, created_at, updated_at, currency, cost_minor, public_id;
 
 -- name: UpdateSubscription :execrows
 UPDATE subscriptions
@@ -16,20 +19,31 @@ SET
     service_name = sqlc.arg(service_name),
     cost = sqlc.arg(cost),
     start_date = sqlc.arg(start_date),
-    end_date = sqlc.narg(end_date)
-WHERE id = sqlc.arg(id);
+    end_date = sqlc.narg(end_date),
+    currency = 'RUB',
+    cost_minor = sqlc.arg(cost)::bigint * 100,
+    updated_at = now()
+WHERE id = sqlc.arg(id)
+  AND (sqlc.narg(if_updated_at)::timestamptz IS NULL OR updated_at = sqlc.narg(if_updated_at)::timestamptz);
 
 -- name: DeleteSubscription :execrows
 DELETE FROM subscriptions
-WHERE id = sqlc.arg(id);
+WHERE id = sqlc.arg(id)
+  AND (sqlc.narg(if_updated_at)::timestamptz IS NULL OR updated_at = sqlc.narg(if_updated_at)::timestamptz);
 
 -- name: GetSubscription :one
-SELECT id, user_id, service_name, cost, start_date, end_date
+SELECT id, user_id, service_name, cost, start_date, end_date, created_at, updated_at, currency, cost_minor, public_id
 FROM subscriptions
 WHERE id = sqlc.arg(id);
 
+-- name: GetSubscriptionForUpdate :one
+SELECT id, user_id, service_name, cost, start_date, end_date, created_at, updated_at, currency, cost_minor, public_id
+FROM subscriptions
+WHERE id = sqlc.arg(id)
+FOR UPDATE;
+
 -- name: ListSubscriptions :many
-SELECT id, user_id, service_name, cost, start_date, end_date
+SELECT id, user_id, service_name, cost, start_date, end_date, created_at, updated_at, currency, cost_minor, public_id
 FROM subscriptions
 WHERE
     (sqlc.narg(user_id)::uuid IS NULL OR user_id = sqlc.narg(user_id)::uuid)
@@ -41,36 +55,639 @@ WHERE
             AND (sqlc.narg(period_to)::date IS NULL OR start_date <= sqlc.narg(period_to)::date)
         )
     )
-ORDER BY start_date, service_name, id
+    AND (
+        sqlc.narg(after_id)::bigint IS NULL
+        OR (start_date, service_name COLLATE "C", id) > (
+            sqlc.narg(after_start_date)::date,
+            sqlc.narg(after_service_name)::text,
+            sqlc.narg(after_id)::bigint
+        )
+    )
+    AND (sqlc.narg(updated_since)::timestamptz IS NULL OR updated_at > sqlc.narg(updated_since)::timestamptz)
+    AND (sqlc.narg(cost_min)::bigint IS NULL OR cost >= sqlc.narg(cost_min)::bigint)
+    AND (sqlc.narg(cost_max)::bigint IS NULL OR cost <= sqlc.narg(cost_max)::bigint)
+    AND (sqlc.narg(service_pattern)::text IS NULL OR service_name ILIKE sqlc.narg(service_pattern)::text)
+    AND (sqlc.narg(start_from)::date IS NULL OR start_date >= sqlc.narg(start_from)::date)
+    AND (sqlc.narg(start_to)::date IS NULL OR start_date <= sqlc.narg(start_to)::date)
+    AND (
+        sqlc.arg(include_deactivated)::boolean
+        OR NOT EXISTS (SELECT 1 FROM user_deactivations d WHERE d.user_id = subscriptions.user_id)
+    )
+ORDER BY start_date, service_name COLLATE "C", id
 LIMIT sqlc.arg(page_limit)
 OFFSET sqlc.arg(page_offset);
 
+-- name: ListSubscriptionsEndedBefore :many
+SELECT id, user_id, service_name, cost, start_date, end_date, created_at, updated_at, currency, cost_minor, public_id
+FROM subscriptions
+WHERE end_date < sqlc.arg(before)::date
+ORDER BY id
+LIMIT sqlc.arg(page_limit);
+
+-- name: CountLegacyCostSubscriptions :one
+SELECT count(*)
+FROM subscriptions
+WHERE cost_minor IS NULL;
+
+-- name: BackfillLegacyCosts :execrows
+UPDATE subscriptions
+SET
+    currency = 'RUB',
+    cost_minor = cost::bigint * 100
+WHERE id IN (
+    SELECT id
+    FROM subscriptions
+    WHERE cost_minor IS NULL
+    ORDER BY id
+    LIMIT sqlc.arg(page_limit)
+    FOR UPDATE SKIP LOCKED
+);
+
+-- name: DeleteSubscriptionsByIDs :execrows
+DELETE FROM subscriptions
+WHERE id = ANY(sqlc.arg(ids)::bigint[]);
+
+-- name: ListSubscriptionChanges :many
+-- seq is taken at insert, so entries are read in transaction order and only up to the oldest transaction
+-- still running: nothing can commit before a position once it was returned
+SELECT seq, subscription_id, op, changed_at, user_id, tx, public_id
+FROM subscription_changes
+WHERE user_id = sqlc.arg(user_id)::uuid
+  AND (tx, seq) > (sqlc.arg(since_tx)::xid8, sqlc.arg(since_seq)::bigint)
+  AND tx < pg_snapshot_xmin(pg_current_snapshot())
+ORDER BY tx, seq
+LIMIT sqlc.arg(page_limit);
+
 -- name: SumSubscriptionCost :one
-WITH params AS (
-    SELECT
-        sqlc.arg(period_from)::date AS start_date,
-        sqlc.arg(period_to)::date AS end_date,
-        sqlc.narg(user_id)::uuid AS user_id,
-        sqlc.narg(service_name)::text AS service_name
-),
-filtered AS (
-    SELECT s.*
-    FROM subscriptions s
-    CROSS JOIN params p
-    WHERE s.start_date <= p.end_date
-      AND (s.end_date IS NULL OR s.end_date >= p.start_date)
-      AND (p.user_id IS NULL OR s.user_id = p.user_id)
-      AND (p.service_name IS NULL OR s.service_name = p.service_name)
-),
-expanded AS (
-    SELECT f.cost
-    FROM filtered f
-    CROSS JOIN params p
-    CROSS JOIN LATERAL generate_series(
-        GREATEST(f.start_date, p.start_date),
-        LEAST(COALESCE(f.end_date, p.end_date), p.end_date),
+SELECT COALESCE(SUM(s.cost), 0)::bigint AS total_cost
+FROM subscriptions s
+CROSS JOIN LATERAL generate_series(
+    GREATEST(s.start_date, sqlc.arg(period_from)::date),
+    LEAST(COALESCE(s.end_date, sqlc.arg(period_to)::date), sqlc.arg(period_to)::date),
+    interval '1 month'
+) AS month_start
+WHERE s.user_id = sqlc.arg(user_id)::uuid
+  AND s.start_date <= sqlc.arg(period_to)::date
+  AND (s.end_date IS NULL OR s.end_date >= sqlc.arg(period_from)::date)
+  AND (sqlc.narg(service_name)::text IS NULL OR s.service_name = sqlc.narg(service_name)::text)
+  AND NOT EXISTS (SELECT 1 FROM user_deactivations d WHERE d.user_id = s.user_id);
+
+-- name: SumAllSubscriptionsCost :one
+SELECT COALESCE(SUM(s.cost), 0)::bigint AS total_cost
+FROM subscriptions s
+CROSS JOIN LATERAL generate_series(
+    GREATEST(s.start_date, sqlc.arg(period_from)::date),
+    LEAST(COALESCE(s.end_date, sqlc.arg(period_to)::date), sqlc.arg(period_to)::date),
+    interval '1 month'
+) AS month_start
+WHERE s.start_date <= sqlc.arg(period_to)::date
+  AND (s.end_date IS NULL OR s.end_date >= sqlc.arg(period_from)::date)
+  AND (sqlc.narg(service_name)::text IS NULL OR s.service_name = sqlc.narg(service_name)::text)
+  AND NOT EXISTS (SELECT 1 FROM user_deactivations d WHERE d.user_id = s.user_id);
+
+-- name: SumSubscriptionCostGrouped :many
+SELECT
+    (CASE sqlc.arg(group_by)::text
+        WHEN 'service' THEN s.service_name
+        WHEN 'user' THEN s.user_id::text
+        WHEN 'week' THEN to_char(date_trunc('week', month_start), 'YYYY-MM-DD')
+        WHEN 'year' THEN to_char(date_trunc('year', month_start), 'YYYY-MM-DD')
+        ELSE to_char(month_start, 'YYYY-MM-DD')
+    END)::text AS group_key,
+    COALESCE(SUM(s.cost), 0)::bigint AS total,
+    COUNT(DISTINCT s.id)::bigint AS subscriptions
+FROM subscriptions s
+CROSS JOIN LATERAL generate_series(
+    GREATEST(s.start_date, sqlc.arg(period_from)::date),
+    LEAST(COALESCE(s.end_date, sqlc.arg(period_to)::date), sqlc.arg(period_to)::date),
+    interval '1 month'
+) AS month_start
+WHERE s.start_date <= sqlc.arg(period_to)::date
+  AND (s.end_date IS NULL OR s.end_date >= sqlc.arg(period_from)::date)
+  AND (sqlc.narg(user_id)::uuid IS NULL OR s.user_id = sqlc.narg(user_id)::uuid)
+  AND (sqlc.narg(service_name)::text IS NULL OR s.service_name = sqlc.narg(service_name)::text)
+  AND s.cost > 0
+  AND NOT EXISTS (SELECT 1 FROM user_deactivations d WHERE d.user_id = s.user_id)
+GROUP BY group_key
+ORDER BY group_key;
+
+-- name: SubscriptionCostSummary :one
+SELECT
+    COALESCE(SUM(s.cost * m.months), 0)::bigint AS total_cost,
+    COUNT(*)::bigint AS subscriptions,
+    COALESCE(SUM(s.cost), 0)::bigint AS monthly_cost,
+    COALESCE(MIN(s.cost), 0)::bigint AS min_cost,
+    COALESCE(MAX(s.cost), 0)::bigint AS max_cost
+FROM subscriptions s
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS months
+    FROM generate_series(
+        GREATEST(s.start_date, sqlc.arg(period_from)::date),
+        LEAST(COALESCE(s.end_date, sqlc.arg(period_to)::date), sqlc.arg(period_to)::date),
         interval '1 month'
-    ) AS month_start
+    )
+) AS m
+WHERE s.start_date <= sqlc.arg(period_to)::date
+  AND (s.end_date IS NULL OR s.end_date >= sqlc.arg(period_from)::date)
+  AND (sqlc.narg(user_id)::uuid IS NULL OR s.user_id = sqlc.narg(user_id)::uuid)
+  AND (sqlc.narg(service_name)::text IS NULL OR s.service_name = sqlc.narg(service_name)::text)
+  AND s.cost > 0
+  AND NOT EXISTS (SELECT 1 FROM user_deactivations d WHERE d.user_id = s.user_id);
+
+-- name: UserDataVersion :one
+SELECT COALESCE(SUM(version), 0)::bigint AS version,
+       COALESCE(MAX(changed_at), to_timestamp(0))::timestamptz AS changed_at
+FROM user_versions
+WHERE sqlc.narg(user_id)::uuid IS NULL OR user_id = sqlc.narg(user_id)::uuid;
+
+-- name: ActiveSubscriptionStats :many
+SELECT
+    service_name,
+    COUNT(*)::bigint AS active,
+    COALESCE(SUM(cost), 0)::bigint AS monthly_cost
+FROM subscriptions
+WHERE start_date <= sqlc.arg(month)::date
+  AND (end_date IS NULL OR end_date >= sqlc.arg(month)::date)
+  AND NOT EXISTS (SELECT 1 FROM user_deactivations d WHERE d.user_id = subscriptions.user_id)
+GROUP BY service_name
+ORDER BY service_name;
+
+-- name: UserMonthlySpend :many
+SELECT
+    s.user_id,
+    m.month::date AS month,
+    COALESCE(SUM(s.cost), 0)::bigint AS total
+FROM generate_series(sqlc.arg(from_month)::date, sqlc.arg(to_month)::date, interval '1 month') AS m(month)
+JOIN subscriptions s
+  ON s.start_date <= m.month
+ AND (s.end_date IS NULL OR s.end_date >= m.month)
+WHERE NOT EXISTS (SELECT 1 FROM user_deactivations d WHERE d.user_id = s.user_id)
+GROUP BY s.user_id, m.month
+ORDER BY s.user_id, m.month;
+
+-- name: PriceBenchmarks :many
+SELECT
+    lower(btrim(s.service_name))::text AS service,
+    COUNT(DISTINCT s.user_id)::bigint AS users,
+    COUNT(*)::bigint AS subscriptions,
+    round(AVG(s.cost))::bigint AS average_cost,
+    round(percentile_cont(0.5) WITHIN GROUP (ORDER BY s.cost))::bigint AS median_cost
+FROM subscriptions s
+JOIN user_settings us ON us.user_id = s.user_id AND us.share_price_stats
+WHERE s.start_date <= sqlc.arg(month)::date
+  AND (s.end_date IS NULL OR s.end_date >= sqlc.arg(month)::date)
+  AND s.cost > 0
+  AND NOT EXISTS (SELECT 1 FROM user_deactivations d WHERE d.user_id = s.user_id)
+GROUP BY lower(btrim(s.service_name))
+HAVING COUNT(DISTINCT s.user_id) >= sqlc.arg(min_users)::bigint
+ORDER BY service;
+
+-- name: ReassignSubscriptionsUser :execrows
+UPDATE subscriptions
+SET
+    user_id = sqlc.arg(to_user_id),
+    updated_at = now()
+WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: InsertAdminAudit :exec
+INSERT INTO admin_audit_log (action, actor, details)
+VALUES (sqlc.arg(action), sqlc.arg(actor), sqlc.arg(details));
+
+-- name: GetUserSettings :one
+SELECT user_id, currency, locale, first_day_of_week, date_format, updated_at, timezone, share_price_stats, monthly_goal
+FROM user_settings
+WHERE user_id = $1;
+
+-- name: UpsertUserSettings :one
+INSERT INTO user_settings (user_id, currency, locale, first_day_of_week, date_format, timezone, share_price_stats, monthly_goal)
+VALUES (sqlc.arg(user_id), sqlc.arg(currency), sqlc.arg(locale), sqlc.arg(first_day_of_week), sqlc.arg(date_format), sqlc.arg(timezone), sqlc.arg(share_price_stats), sqlc.arg(monthly_goal))
+ON CONFLICT (user_id) DO UPDATE
+SET
+    currency = EXCLUDED.currency,
+    locale = EXCLUDED.locale,
+    first_day_of_week = EXCLUDED.first_day_of_week,
+    date_format = EXCLUDED.date_format,
+    timezone = EXCLUDED.timezone,
+    share_price_stats = EXCLUDED.share_price_stats,
+    monthly_goal = EXCLUDED.monthly_goal,
+    updated_at = now()
+RETURNING user_id, currency, locale, first_day_of_week, date_format, updated_at, timezone, share_price_stats, monthly_goal;
+
+-- name: InsertUserPseudonym :exec
+INSERT INTO user_pseudonyms (pseudonym, user_id_enc)
+VALUES ($1, $2)
+ON CONFLICT (pseudonym) DO NOTHING;
+
+-- name: ListUserPseudonyms :many
+SELECT pseudonym, user_id_enc, created_at
+FROM user_pseudonyms
+WHERE pseudonym = ANY($1::uuid[]);
+
+-- name: DeleteUserSpendChanges :exec
+DELETE FROM user_spend_changes
+WHERE user_id = $1;
+
+-- name: DeleteAllUserSpendChanges :exec
+DELETE FROM user_spend_changes;
+
+-- name: InsertUserSpendChanges :exec
+INSERT INTO user_spend_changes (user_id, month, cost_delta, subs_delta)
+SELECT
+    unnest(sqlc.arg(user_ids)::uuid[]),
+    unnest(sqlc.arg(months)::date[]),
+    unnest(sqlc.arg(cost_deltas)::bigint[]),
+    unnest(sqlc.arg(subs_deltas)::int[]);
+
+-- name: UserMonthlySpendFromChanges :many
+SELECT
+    c.user_id,
+    m.month::date AS month,
+    SUM(c.cost_delta)::bigint AS total
+FROM generate_series(sqlc.arg(from_month)::date, sqlc.arg(to_month)::date, interval '1 month') AS m(month)
+JOIN user_spend_changes c ON c.month <= m.month
+GROUP BY c.user_id, m.month
+HAVING SUM(c.subs_delta) > 0
+ORDER BY c.user_id, m.month;
+
+-- name: InsertShareLink :exec
+INSERT INTO share_links (token_hash, user_id, service_name, created_at, expires_at, tenant_id)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: GetShareLink :one
+SELECT token_hash, user_id, service_name, created_at, expires_at, revoked_at, tenant_id
+FROM share_links
+WHERE token_hash = $1;
+
+-- name: RevokeShareLink :execrows
+UPDATE share_links
+SET revoked_at = COALESCE(revoked_at, sqlc.arg(revoked_at))
+WHERE token_hash = sqlc.arg(token_hash);
+
+-- name: RevokeShareLinksIssuedBefore :execrows
+UPDATE share_links
+SET revoked_at = sqlc.arg(revoked_at)
+WHERE revoked_at IS NULL
+  AND created_at < sqlc.arg(issued_before)
+  AND (sqlc.narg(user_id)::uuid IS NULL OR user_id = sqlc.narg(user_id)::uuid);
+
+-- name: InsertWidgetToken :exec
+INSERT INTO widget_tokens (token_hash, user_id, origins, created_at)
+VALUES ($1, $2, $3, $4);
+
+-- name: GetWidgetToken :one
+SELECT token_hash, user_id, origins, created_at, revoked_at
+FROM widget_tokens
+WHERE token_hash = $1;
+
+-- name: RevokeWidgetToken :execrows
+UPDATE widget_tokens
+SET revoked_at = COALESCE(revoked_at, sqlc.arg(revoked_at))
+WHERE token_hash = sqlc.arg(token_hash);
+
+-- name: DeleteUserWidgetTokens :exec
+DELETE FROM widget_tokens
+WHERE user_id = $1;
+
+-- name: UpsertUserWebhook :exec
+INSERT INTO user_webhooks (user_id, url, format, secret, created_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id) DO UPDATE
+SET url = EXCLUDED.url, format = EXCLUDED.format, secret = EXCLUDED.secret, created_at = EXCLUDED.created_at;
+
+-- name: GetUserWebhook :one
+SELECT user_id, url, format, secret, created_at
+FROM user_webhooks
+WHERE user_id = $1;
+
+-- name: DeleteUserWebhook :execrows
+DELETE FROM user_webhooks
+WHERE user_id = $1;
+
+-- name: InsertUserActivity :one
+INSERT INTO user_activity (user_id, kind, subscription_id, public_id, service_name, cost, previous_cost, end_date, occurred_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id;
+
+-- name: ListUserActivity :many
+SELECT id, user_id, kind, subscription_id, public_id, service_name, cost, previous_cost, end_date, occurred_at
+FROM user_activity
+WHERE user_id = sqlc.arg(user_id)
+  AND (sqlc.arg(before)::bigint <= 0 OR id < sqlc.arg(before)::bigint)
+ORDER BY id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: DeleteUserActivity :execrows
+DELETE FROM user_activity
+WHERE user_id = $1;
+
+-- name: InsertPriceReview :one
+INSERT INTO price_reviews (subscription_id, public_id, user_id, service_name, cost, catalog_price, opened_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (subscription_id, catalog_price) DO NOTHING
+RETURNING id;
+
+-- name: ListOpenPriceReviews :many
+SELECT id, subscription_id, public_id, user_id, service_name, cost, catalog_price, opened_at, resolved_at
+FROM price_reviews
+WHERE resolved_at IS NULL
+ORDER BY id
+LIMIT $1;
+
+-- name: ResolvePriceReview :one
+UPDATE price_reviews
+SET resolved_at = sqlc.arg(resolved_at)
+WHERE id = sqlc.arg(id)
+  AND resolved_at IS NULL
+RETURNING id, subscription_id, public_id, user_id, service_name, cost, catalog_price, opened_at, resolved_at;
+
+-- name: DeleteUserPriceReviews :exec
+DELETE FROM price_reviews
+WHERE user_id = $1;
+
+-- name: InsertCharge :execrows
+INSERT INTO charges (user_id, service_name, charged_on, amount)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, lower(service_name), charged_on, amount) DO NOTHING;
+
+-- name: ListChargedUsers :many
+SELECT DISTINCT user_id
+FROM charges
+WHERE charged_on >= sqlc.arg(month)::date
+  AND charged_on < sqlc.arg(month)::date + INTERVAL '1 month'
+ORDER BY user_id;
+
+-- name: ListUserCharges :many
+SELECT id, user_id, service_name, charged_on, amount, recorded_at
+FROM charges
+WHERE user_id = sqlc.arg(user_id)
+  AND charged_on >= sqlc.arg(month)::date
+  AND charged_on < sqlc.arg(month)::date + INTERVAL '1 month'
+ORDER BY charged_on, id;
+
+-- name: InsertReconciliationItem :one
+INSERT INTO reconciliation_items (kind, user_id, service_name, month, expected, charged, subscription_id, public_id,
+                                  opened_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (user_id, month, kind, lower(service_name)) DO NOTHING
+RETURNING id;
+
+-- name: ListOpenReconciliationItems :many
+SELECT id, kind, user_id, service_name, month, expected, charged, subscription_id, public_id, opened_at, resolved_at
+FROM reconciliation_items
+WHERE resolved_at IS NULL
+  AND (sqlc.narg(user_id)::uuid IS NULL OR user_id = sqlc.narg(user_id)::uuid)
+ORDER BY id
+LIMIT sqlc.arg(lim);
+
+-- name: ResolveReconciliationItem :one
+UPDATE reconciliation_items
+SET resolved_at = sqlc.arg(resolved_at)
+WHERE id = sqlc.arg(id)
+  AND resolved_at IS NULL
+RETURNING id, kind, user_id, service_name, month, expected, charged, subscription_id, public_id, opened_at, resolved_at;
+
+-- name: DeleteUserReconciliation :exec
+WITH items AS (
+    DELETE FROM reconciliation_items
+    WHERE user_id = sqlc.arg(user_id)
 )
-SELECT COALESCE(SUM(cost), 0)::bigint AS total_cost
-FROM expanded;
+DELETE FROM charges
+WHERE user_id = sqlc.arg(user_id);
+
+-- name: InsertQuarantinedImport :one
+INSERT INTO import_quarantine (user_id, service_name, cost, start_date, end_date, reason, code, held_at, import_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (import_key) WHERE import_key <> '' DO UPDATE
+SET import_key = EXCLUDED.import_key
+RETURNING id;
+
+-- name: GetImportKey :one
+SELECT subscription_id
+FROM import_keys
+WHERE key = $1;
+
+-- name: InsertImportKey :exec
+INSERT INTO import_keys (key, subscription_id)
+VALUES ($1, $2);
+
+-- name: ListQuarantinedImports :many
+SELECT id, user_id, service_name, cost, start_date, end_date, reason, code, held_at, import_key
+FROM import_quarantine
+WHERE user_id = sqlc.arg(user_id)
+ORDER BY id
+LIMIT sqlc.arg(lim);
+
+-- name: GetQuarantinedImport :one
+SELECT id, user_id, service_name, cost, start_date, end_date, reason, code, held_at, import_key
+FROM import_quarantine
+WHERE id = $1
+  AND user_id = $2;
+
+-- name: DeleteQuarantinedImport :execrows
+DELETE FROM import_quarantine
+WHERE id = $1
+  AND user_id = $2;
+
+-- name: DeleteUserQuarantinedImports :exec
+DELETE FROM import_quarantine
+WHERE user_id = $1;
+
+-- name: DeactivateUser :one
+INSERT INTO user_deactivations (user_id, deactivated_at)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET deactivated_at = user_deactivations.deactivated_at
+RETURNING deactivated_at;
+
+-- name: ReactivateUser :exec
+DELETE FROM user_deactivations
+WHERE user_id = $1;
+
+-- name: GetUserDeactivation :one
+SELECT deactivated_at
+FROM user_deactivations
+WHERE user_id = $1;
+
+-- name: TryAdvisoryLock :one
+SELECT pg_try_advisory_lock(sqlc.arg(key)::bigint);
+
+-- name: AdvisoryUnlock :one
+SELECT pg_advisory_unlock(sqlc.arg(key)::bigint);
+
+-- name: TableStats :many
+SELECT
+    relname::text AS table_name,
+    GREATEST(n_live_tup, 0)::bigint AS live_rows,
+    pg_total_relation_size(relid)::bigint AS total_bytes
+FROM pg_stat_user_tables
+WHERE schemaname = current_schema()
+ORDER BY relname;
+
+-- name: AdvisoryLockHolder :one
+SELECT
+    a.pid,
+    COALESCE(a.application_name, '')::text AS application_name,
+    COALESCE(host(a.client_addr), '')::text AS client_addr
+FROM pg_locks l
+JOIN pg_stat_activity a ON a.pid = l.pid
+WHERE l.locktype = 'advisory'
+  AND l.granted
+  AND l.objsubid = 1
+  AND l.classid::bigint = (sqlc.arg(key)::bigint >> 32) & 4294967295
+  AND l.objid::bigint = sqlc.arg(key)::bigint & 4294967295
+LIMIT 1;
+
+-- name: CurrentSchema :one
+SELECT current_schema()::text AS schema_name;
+
+-- name: ListSchemaTables :many
+SELECT tablename::text AS table_name
+FROM pg_tables
+WHERE schemaname = current_schema()
+  AND tablename <> 'schema_migrations'
+ORDER BY tablename;
+
+-- name: InsertSubscriptionAdjustment :one
+WITH touched AS (
+    -- the net cost of the subscription changes, so does its version
+    UPDATE subscriptions SET updated_at = now()
+    WHERE id = sqlc.arg(subscription_id)
+    RETURNING id
+)
+INSERT INTO subscription_adjustments (subscription_id, month, amount, note)
+SELECT touched.id, sqlc.arg(month), sqlc.arg(amount), sqlc.arg(note)
+FROM touched
+RETURNING id, subscription_id, month, amount, note, created_at;
+
+-- name: ListSubscriptionAdjustments :many
+SELECT id, subscription_id, month, amount, note, created_at
+FROM subscription_adjustments
+WHERE subscription_id = $1
+ORDER BY month, id;
+
+-- name: MoveSubscriptionAdjustments :execrows
+UPDATE subscription_adjustments
+SET subscription_id = sqlc.arg(to_subscription_id)
+WHERE subscription_id = sqlc.arg(from_subscription_id);
+
+-- name: SumAdjustments :one
+SELECT COALESCE(SUM(a.amount), 0)::bigint AS total
+FROM subscription_adjustments a
+JOIN subscriptions s ON s.id = a.subscription_id
+WHERE a.month BETWEEN sqlc.arg(period_from)::date AND sqlc.arg(period_to)::date
+  AND (sqlc.narg(user_id)::uuid IS NULL OR s.user_id = sqlc.narg(user_id)::uuid)
+  AND (sqlc.narg(service_name)::text IS NULL OR s.service_name = sqlc.narg(service_name)::text)
+  AND NOT EXISTS (SELECT 1 FROM user_deactivations d WHERE d.user_id = s.user_id);
+
+-- name: SumAdjustmentsGrouped :many
+SELECT
+    (CASE sqlc.arg(group_by)::text
+        WHEN 'service' THEN s.service_name
+        WHEN 'user' THEN s.user_id::text
+        WHEN 'week' THEN to_char(date_trunc('week', a.month), 'YYYY-MM-DD')
+        WHEN 'year' THEN to_char(date_trunc('year', a.month), 'YYYY-MM-DD')
+        ELSE to_char(a.month, 'YYYY-MM-DD')
+    END)::text AS group_key,
+    COALESCE(SUM(a.amount), 0)::bigint AS total
+FROM subscription_adjustments a
+JOIN subscriptions s ON s.id = a.subscription_id
+WHERE a.month BETWEEN sqlc.arg(period_from)::date AND sqlc.arg(period_to)::date
+  AND (sqlc.narg(user_id)::uuid IS NULL OR s.user_id = sqlc.narg(user_id)::uuid)
+  AND (sqlc.narg(service_name)::text IS NULL OR s.service_name = sqlc.narg(service_name)::text)
+  AND NOT EXISTS (SELECT 1 FROM user_deactivations d WHERE d.user_id = s.user_id)
+GROUP BY group_key
+ORDER BY group_key;
+
+-- name: UpsertSubscriptionSeats :one
+INSERT INTO subscription_seats (subscription_id, total, member_ids)
+VALUES (sqlc.arg(subscription_id), sqlc.arg(total), sqlc.arg(member_ids)::uuid[])
+ON CONFLICT (subscription_id) DO UPDATE
+SET total      = EXCLUDED.total,
+    member_ids = EXCLUDED.member_ids,
+    updated_at = now()
+RETURNING subscription_id, total, member_ids, updated_at;
+
+-- name: DeleteSubscriptionSeats :exec
+DELETE FROM subscription_seats
+WHERE subscription_id = $1;
+
+-- name: GetSubscriptionSeats :one
+SELECT subscription_id, total, member_ids, updated_at
+FROM subscription_seats
+WHERE subscription_id = $1;
+
+-- name: ListSeatShares :many
+SELECT s.id, s.service_name, s.cost, seats.total, s.public_id
+FROM subscription_seats seats
+JOIN subscriptions s ON s.id = seats.subscription_id
+WHERE sqlc.arg(user_id)::uuid = ANY (seats.member_ids)
+  AND s.start_date <= sqlc.arg(period_to)::date
+  AND (s.end_date IS NULL OR s.end_date >= sqlc.arg(period_from)::date)
+  AND (sqlc.narg(service_name)::text IS NULL OR s.service_name = sqlc.narg(service_name)::text)
+  AND NOT EXISTS (SELECT 1 FROM user_deactivations d WHERE d.user_id IN (s.user_id, sqlc.arg(user_id)::uuid))
+ORDER BY s.service_name, s.id;
+
+-- name: MoveSubscriptionSeats :execrows
+UPDATE subscription_seats
+SET subscription_id = sqlc.arg(to_subscription_id)
+WHERE subscription_id = sqlc.arg(from_subscription_id)
+  AND NOT EXISTS (SELECT 1 FROM subscription_seats k WHERE k.subscription_id = sqlc.arg(to_subscription_id));
+
+-- name: CountUnendedUserSubscriptions :one
+SELECT count(*)
+FROM subscriptions
+WHERE user_id = sqlc.arg(user_id)
+  AND (end_date IS NULL OR end_date >= sqlc.arg(since)::date);
+
+-- name: DeleteUserSubscriptions :execrows
+DELETE FROM subscriptions
+WHERE user_id = $1;
+
+-- name: DeleteUserSettings :exec
+DELETE FROM user_settings
+WHERE user_id = $1;
+
+-- name: RemoveSeatMember :execrows
+UPDATE subscription_seats
+SET member_ids = array_remove(member_ids, sqlc.arg(user_id)::uuid),
+    updated_at = now()
+WHERE sqlc.arg(user_id)::uuid = ANY (member_ids);
+
+-- name: GetSubscriptionByPublicID :one
+SELECT id, user_id, service_name, cost, start_date, end_date, created_at, updated_at, currency, cost_minor, public_id
+FROM subscriptions
+WHERE public_id = sqlc.arg(public_id);
+
+-- name: GetTenantTheme :one
+SELECT tenant_id, logo_url, color, footer, updated_at
+FROM tenant_themes
+WHERE tenant_id = $1;
+
+-- name: UpsertTenantTheme :exec
+INSERT INTO tenant_themes (tenant_id, logo_url, color, footer, updated_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (tenant_id) DO UPDATE
+SET
+    logo_url = EXCLUDED.logo_url,
+    color = EXCLUDED.color,
+    footer = EXCLUDED.footer,
+    updated_at = EXCLUDED.updated_at;
+
+-- name: DeleteTenantTheme :execrows
+DELETE FROM tenant_themes
+WHERE tenant_id = $1;
+
+-- name: InsertRequestAudit :exec
+INSERT INTO request_audit (received_at, method, route, path, status, body_sha256, body_size, client, remote_ip,
+                           peer_ip, user_agent, request_id, tenant_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13);
+
+-- name: DeleteRequestAuditBefore :execrows
+DELETE FROM request_audit
+WHERE id IN (
+    SELECT id
+    FROM request_audit
+    WHERE received_at < sqlc.arg(received_before)
+    ORDER BY id
+    LIMIT sqlc.arg(page_limit)
+);
@@ -12,24 +12,161 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const activeSubscriptionStats = `-- name: ActiveSubscriptionStats :many
+SELECT
+    service_name,
+    COUNT(*)::bigint AS active,
+    COALESCE(SUM(cost), 0)::bigint AS monthly_cost
+FROM subscriptions
+WHERE start_date <= $1::date
+  AND (end_date IS NULL OR end_date >= $1::date)
+  AND NOT EXISTS (SELECT 1 FROM user_deactivations d WHERE d.user_id = subscriptions.user_id)
+GROUP BY service_name
+ORDER BY service_name
+`
+
+type ActiveSubscriptionStatsRow struct {
+	ServiceName string `json:"service_name"`
+	Active      int64  `json:"active"`
+	MonthlyCost int64  `json:"monthly_cost"`
+}
+
+func (q *Queries) ActiveSubscriptionStats(ctx context.Context, month time.Time) ([]ActiveSubscriptionStatsRow, error) {
+	rows, err := q.db.Query(ctx, activeSubscriptionStats, month)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ActiveSubscriptionStatsRow
+	for rows.Next() {
+		var i ActiveSubscriptionStatsRow
+		if err := rows.Scan(&i.ServiceName, &i.Active, &i.MonthlyCost); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const advisoryLockHolder = `-- name: AdvisoryLockHolder :one
+SELECT
+    a.pid,
+    COALESCE(a.application_name, '')::text AS application_name,
+    COALESCE(host(a.client_addr), '')::text AS client_addr
+FROM pg_locks l
+JOIN pg_stat_activity a ON a.pid = l.pid
+WHERE l.locktype = 'advisory'
+  AND l.granted
+  AND l.objsubid = 1
+  AND l.classid::bigint = ($1::bigint >> 32) & 4294967295
+  AND l.objid::bigint = $1::bigint & 4294967295
+LIMIT 1
+`
+
+type AdvisoryLockHolderRow struct {
+	Pid             int32  `json:"pid"`
+	ApplicationName string `json:"application_name"`
+	ClientAddr      string `json:"client_addr"`
+}
+
+func (q *Queries) AdvisoryLockHolder(ctx context.Context, key int64) (AdvisoryLockHolderRow, error) {
+	row := q.db.QueryRow(ctx, advisoryLockHolder, key)
+	var i AdvisoryLockHolderRow
+	err := row.Scan(&i.Pid, &i.ApplicationName, &i.ClientAddr)
+	return i, err
+}
+
+const advisoryUnlock = `-- name: AdvisoryUnlock :one
+SELECT pg_advisory_unlock($1::bigint)
+`
+
+func (q *Queries) AdvisoryUnlock(ctx context.Context, key int64) (bool, error) {
+	row := q.db.QueryRow(ctx, advisoryUnlock, key)
+	var pg_advisory_unlock bool
+	err := row.Scan(&pg_advisory_unlock)
+	return pg_advisory_unlock, err
+}
+
+const backfillLegacyCosts = `-- name: BackfillLegacyCosts :execrows
+UPDATE subscriptions
+SET
+    currency = 'RUB',
+    cost_minor = cost::bigint * 100
+WHERE id IN (
+    SELECT id
+    FROM subscriptions
+    WHERE cost_minor IS NULL
+    ORDER BY id
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+`
+
+func (q *Queries) BackfillLegacyCosts(ctx context.Context, pageLimit int32) (int64, error) {
+	result, err := q.db.Exec(ctx, backfillLegacyCosts, pageLimit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const countLegacyCostSubscriptions = `-- name: CountLegacyCostSubscriptions :one
+SELECT count(*)
+FROM subscriptions
+WHERE cost_minor IS NULL
+`
+
+func (q *Queries) CountLegacyCostSubscriptions(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, countLegacyCostSubscriptions)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUnendedUserSubscriptions = `-- name: CountUnendedUserSubscriptions :one
+SELECT count(*)
+FROM subscriptions
+WHERE user_id = $1
+  AND (end_date IS NULL OR end_date >= $2::date)
+`
+
+type CountUnendedUserSubscriptionsParams struct {
+	UserID string    `json:"user_id"`
+	Since  time.Time `json:"since"`
+}
+
+func (q *Queries) CountUnendedUserSubscriptions(ctx context.Context, arg CountUnendedUserSubscriptionsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countUnendedUserSubscriptions, arg.UserID, arg.Since)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createSubscription = `-- name: CreateSubscription :one
-INSERT INTO subscriptions (user_id, service_name, cost, start_date, end_date)
+INSERT INTO subscriptions (user_id, service_name, cost, start_date, end_date, currency, cost_minor, public_id)
 VALUES (
     $1,
     $2,
     $3,
     $4,
-    $5
+    $5,
+    'RUB',
+    $3::bigint * 100,
+    COALESCE($6::uuid, gen_random_uuid())
 )
-RETURNING id, user_id, service_name, cost, start_date, end_date
+RETURNING id, user_id, service_name, cost, start_date, end_date, created_at, updated_at, currency, cost_minor, public_id
 `
 
 type CreateSubscriptionParams struct {
-	UserID      string     `json:"user_id"`
-	ServiceName string     `json:"service_name"`
-	Cost        int64      `json:"cost"`
-	StartDate   time.Time  `json:"start_date"`
-	EndDate     *time.Time `json:"end_date"`
+	UserID      string      `json:"user_id"`
+	ServiceName string      `json:"service_name"`
+	Cost        int64       `json:"cost"`
+	StartDate   time.Time   `json:"start_date"`
+	EndDate     *time.Time  `json:"end_date"`
+	PublicID    pgtype.UUID `json:"public_id"`
 }
 
 func (q *Queries) CreateSubscription(ctx context.Context, arg CreateSubscriptionParams) (Subscription, error) {
@@ -39,103 +176,1878 @@ func (q *Queries) CreateSubscription(ctx context.Context, arg CreateSubscription
 		arg.Cost,
 		arg.StartDate,
 		arg.EndDate,
+		arg.PublicID,
+	)
+	var i Subscription
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ServiceName,
+		&i.Cost,
+		&i.StartDate,
+		&i.EndDate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Currency,
+		&i.CostMinor,
+		&i.PublicID,
+	)
+	return i, err
+}
+
+const currentSchema = `-- name: CurrentSchema :one
+SELECT current_schema()::text AS schema_name
+`
+
+func (q *Queries) CurrentSchema(ctx context.Context) (string, error) {
+	row := q.db.QueryRow(ctx, currentSchema)
+	var schema_name string
+	err := row.Scan(&schema_name)
+	return schema_name, err
+}
+
+const deactivateUser = `-- name: DeactivateUser :one
+INSERT INTO user_deactivations (user_id, deactivated_at)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET deactivated_at = user_deactivations.deactivated_at
+RETURNING deactivated_at
+`
+
+type DeactivateUserParams struct {
+	UserID        string    `json:"user_id"`
+	DeactivatedAt time.Time `json:"deactivated_at"`
+}
+
+func (q *Queries) DeactivateUser(ctx context.Context, arg DeactivateUserParams) (time.Time, error) {
+	row := q.db.QueryRow(ctx, deactivateUser, arg.UserID, arg.DeactivatedAt)
+	var deactivated_at time.Time
+	err := row.Scan(&deactivated_at)
+	return deactivated_at, err
+}
+
+const deleteAllUserSpendChanges = `-- name: DeleteAllUserSpendChanges :exec
+DELETE FROM user_spend_changes
+`
+
+func (q *Queries) DeleteAllUserSpendChanges(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, deleteAllUserSpendChanges)
+	return err
+}
+
+const deleteQuarantinedImport = `-- name: DeleteQuarantinedImport :execrows
+DELETE FROM import_quarantine
+WHERE id = $1
+  AND user_id = $2
+`
+
+type DeleteQuarantinedImportParams struct {
+	ID     int64  `json:"id"`
+	UserID string `json:"user_id"`
+}
+
+func (q *Queries) DeleteQuarantinedImport(ctx context.Context, arg DeleteQuarantinedImportParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteQuarantinedImport, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteRequestAuditBefore = `-- name: DeleteRequestAuditBefore :execrows
+DELETE FROM request_audit
+WHERE id IN (
+    SELECT id
+    FROM request_audit
+    WHERE received_at < $1
+    ORDER BY id
+    LIMIT $2
+)
+`
+
+type DeleteRequestAuditBeforeParams struct {
+	ReceivedBefore time.Time `json:"received_before"`
+	PageLimit      int32     `json:"page_limit"`
+}
+
+func (q *Queries) DeleteRequestAuditBefore(ctx context.Context, arg DeleteRequestAuditBeforeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteRequestAuditBefore, arg.ReceivedBefore, arg.PageLimit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteSubscription = `-- name: DeleteSubscription :execrows
+DELETE FROM subscriptions
+WHERE id = $1
+  AND ($2::timestamptz IS NULL OR updated_at = $2::timestamptz)
+`
+
+type DeleteSubscriptionParams struct {
+	ID          int64      `json:"id"`
+	IfUpdatedAt *time.Time `json:"if_updated_at"`
+}
+
+func (q *Queries) DeleteSubscription(ctx context.Context, arg DeleteSubscriptionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteSubscription, arg.ID, arg.IfUpdatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteSubscriptionSeats = `-- name: DeleteSubscriptionSeats :exec
+DELETE FROM subscription_seats
+WHERE subscription_id = $1
+`
+
+func (q *Queries) DeleteSubscriptionSeats(ctx context.Context, subscriptionID int64) error {
+	_, err := q.db.Exec(ctx, deleteSubscriptionSeats, subscriptionID)
+	return err
+}
+
+const deleteSubscriptionsByIDs = `-- name: DeleteSubscriptionsByIDs :execrows
+DELETE FROM subscriptions
+WHERE id = ANY($1::bigint[])
+`
+
+func (q *Queries) DeleteSubscriptionsByIDs(ctx context.Context, ids []int64) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteSubscriptionsByIDs, ids)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteTenantTheme = `-- name: DeleteTenantTheme :execrows
+DELETE FROM tenant_themes
+WHERE tenant_id = $1
+`
+
+func (q *Queries) DeleteTenantTheme(ctx context.Context, tenantID string) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteTenantTheme, tenantID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteUserActivity = `-- name: DeleteUserActivity :execrows
+DELETE FROM user_activity
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteUserActivity(ctx context.Context, userID string) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteUserActivity, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteUserPriceReviews = `-- name: DeleteUserPriceReviews :exec
+DELETE FROM price_reviews
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteUserPriceReviews(ctx context.Context, userID string) error {
+	_, err := q.db.Exec(ctx, deleteUserPriceReviews, userID)
+	return err
+}
+
+const deleteUserQuarantinedImports = `-- name: DeleteUserQuarantinedImports :exec
+DELETE FROM import_quarantine
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteUserQuarantinedImports(ctx context.Context, userID string) error {
+	_, err := q.db.Exec(ctx, deleteUserQuarantinedImports, userID)
+	return err
+}
+
+const deleteUserReconciliation = `-- name: DeleteUserReconciliation :exec
+WITH items AS (
+    DELETE FROM reconciliation_items
+    WHERE user_id = $1
+)
+DELETE FROM charges
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteUserReconciliation(ctx context.Context, userID string) error {
+	_, err := q.db.Exec(ctx, deleteUserReconciliation, userID)
+	return err
+}
+
+const deleteUserSettings = `-- name: DeleteUserSettings :exec
+DELETE FROM user_settings
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteUserSettings(ctx context.Context, userID string) error {
+	_, err := q.db.Exec(ctx, deleteUserSettings, userID)
+	return err
+}
+
+const deleteUserSpendChanges = `-- name: DeleteUserSpendChanges :exec
+DELETE FROM user_spend_changes
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteUserSpendChanges(ctx context.Context, userID string) error {
+	_, err := q.db.Exec(ctx, deleteUserSpendChanges, userID)
+	return err
+}
+
+const deleteUserSubscriptions = `-- name: DeleteUserSubscriptions :execrows
+DELETE FROM subscriptions
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteUserSubscriptions(ctx context.Context, userID string) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteUserSubscriptions, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteUserWebhook = `-- name: DeleteUserWebhook :execrows
+DELETE FROM user_webhooks
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteUserWebhook(ctx context.Context, userID string) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteUserWebhook, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteUserWidgetTokens = `-- name: DeleteUserWidgetTokens :exec
+DELETE FROM widget_tokens
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteUserWidgetTokens(ctx context.Context, userID string) error {
+	_, err := q.db.Exec(ctx, deleteUserWidgetTokens, userID)
+	return err
+}
+
+const getImportKey = `-- name: GetImportKey :one
+SELECT subscription_id
+FROM import_keys
+WHERE key = $1
+`
+
+func (q *Queries) GetImportKey(ctx context.Context, key string) (int64, error) {
+	row := q.db.QueryRow(ctx, getImportKey, key)
+	var subscription_id int64
+	err := row.Scan(&subscription_id)
+	return subscription_id, err
+}
+
+const getQuarantinedImport = `-- name: GetQuarantinedImport :one
+SELECT id, user_id, service_name, cost, start_date, end_date, reason, code, held_at, import_key
+FROM import_quarantine
+WHERE id = $1
+  AND user_id = $2
+`
+
+type GetQuarantinedImportParams struct {
+	ID     int64  `json:"id"`
+	UserID string `json:"user_id"`
+}
+
+func (q *Queries) GetQuarantinedImport(ctx context.Context, arg GetQuarantinedImportParams) (ImportQuarantine, error) {
+	row := q.db.QueryRow(ctx, getQuarantinedImport, arg.ID, arg.UserID)
+	var i ImportQuarantine
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ServiceName,
+		&i.Cost,
+		&i.StartDate,
+		&i.EndDate,
+		&i.Reason,
+		&i.Code,
+		&i.HeldAt,
+		&i.ImportKey,
+	)
+	return i, err
+}
+
+const getShareLink = `-- name: GetShareLink :one
+SELECT token_hash, user_id, service_name, created_at, expires_at, revoked_at, tenant_id
+FROM share_links
+WHERE token_hash = $1
+`
+
+func (q *Queries) GetShareLink(ctx context.Context, tokenHash string) (ShareLink, error) {
+	row := q.db.QueryRow(ctx, getShareLink, tokenHash)
+	var i ShareLink
+	err := row.Scan(
+		&i.TokenHash,
+		&i.UserID,
+		&i.ServiceName,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.TenantID,
+	)
+	return i, err
+}
+
+const getSubscription = `-- name: GetSubscription :one
+SELECT id, user_id, service_name, cost, start_date, end_date, created_at, updated_at, currency, cost_minor, public_id
+FROM subscriptions
+WHERE id = $1
+`
+
+func (q *Queries) GetSubscription(ctx context.Context, id int64) (Subscription, error) {
+	row := q.db.QueryRow(ctx, getSubscription, id)
+	var i Subscription
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ServiceName,
+		&i.Cost,
+		&i.StartDate,
+		&i.EndDate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Currency,
+		&i.CostMinor,
+		&i.PublicID,
 	)
+	return i, err
+}
+
+const getSubscriptionByPublicID = `-- name: GetSubscriptionByPublicID :one
+SELECT id, user_id, service_name, cost, start_date, end_date, created_at, updated_at, currency, cost_minor, public_id
+FROM subscriptions
+WHERE public_id = $1
+`
+
+func (q *Queries) GetSubscriptionByPublicID(ctx context.Context, publicID string) (Subscription, error) {
+	row := q.db.QueryRow(ctx, getSubscriptionByPublicID, publicID)
 	var i Subscription
 	err := row.Scan(
 		&i.ID,
 		&i.UserID,
 		&i.ServiceName,
-		&i.Cost,
-		&i.StartDate,
-		&i.EndDate,
+		&i.Cost,
+		&i.StartDate,
+		&i.EndDate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Currency,
+		&i.CostMinor,
+		&i.PublicID,
+	)
+	return i, err
+}
+
+const getSubscriptionForUpdate = `-- name: GetSubscriptionForUpdate :one
+SELECT id, user_id, service_name, cost, start_date, end_date, created_at, updated_at, currency, cost_minor, public_id
+FROM subscriptions
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetSubscriptionForUpdate(ctx context.Context, id int64) (Subscription, error) {
+	row := q.db.QueryRow(ctx, getSubscriptionForUpdate, id)
+	var i Subscription
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ServiceName,
+		&i.Cost,
+		&i.StartDate,
+		&i.EndDate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Currency,
+		&i.CostMinor,
+		&i.PublicID,
+	)
+	return i, err
+}
+
+const getSubscriptionSeats = `-- name: GetSubscriptionSeats :one
+SELECT subscription_id, total, member_ids, updated_at
+FROM subscription_seats
+WHERE subscription_id = $1
+`
+
+func (q *Queries) GetSubscriptionSeats(ctx context.Context, subscriptionID int64) (SubscriptionSeat, error) {
+	row := q.db.QueryRow(ctx, getSubscriptionSeats, subscriptionID)
+	var i SubscriptionSeat
+	err := row.Scan(
+		&i.SubscriptionID,
+		&i.Total,
+		&i.MemberIds,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getTenantTheme = `-- name: GetTenantTheme :one
+SELECT tenant_id, logo_url, color, footer, updated_at
+FROM tenant_themes
+WHERE tenant_id = $1
+`
+
+func (q *Queries) GetTenantTheme(ctx context.Context, tenantID string) (TenantTheme, error) {
+	row := q.db.QueryRow(ctx, getTenantTheme, tenantID)
+	var i TenantTheme
+	err := row.Scan(
+		&i.TenantID,
+		&i.LogoUrl,
+		&i.Color,
+		&i.Footer,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getUserDeactivation = `-- name: GetUserDeactivation :one
+SELECT deactivated_at
+FROM user_deactivations
+WHERE user_id = $1
+`
+
+func (q *Queries) GetUserDeactivation(ctx context.Context, userID string) (time.Time, error) {
+	row := q.db.QueryRow(ctx, getUserDeactivation, userID)
+	var deactivated_at time.Time
+	err := row.Scan(&deactivated_at)
+	return deactivated_at, err
+}
+
+const getUserSettings = `-- name: GetUserSettings :one
+SELECT user_id, currency, locale, first_day_of_week, date_format, updated_at, timezone, share_price_stats, monthly_goal
+FROM user_settings
+WHERE user_id = $1
+`
+
+func (q *Queries) GetUserSettings(ctx context.Context, userID string) (UserSetting, error) {
+	row := q.db.QueryRow(ctx, getUserSettings, userID)
+	var i UserSetting
+	err := row.Scan(
+		&i.UserID,
+		&i.Currency,
+		&i.Locale,
+		&i.FirstDayOfWeek,
+		&i.DateFormat,
+		&i.UpdatedAt,
+		&i.Timezone,
+		&i.SharePriceStats,
+		&i.MonthlyGoal,
+	)
+	return i, err
+}
+
+const getUserWebhook = `-- name: GetUserWebhook :one
+SELECT user_id, url, format, secret, created_at
+FROM user_webhooks
+WHERE user_id = $1
+`
+
+func (q *Queries) GetUserWebhook(ctx context.Context, userID string) (UserWebhook, error) {
+	row := q.db.QueryRow(ctx, getUserWebhook, userID)
+	var i UserWebhook
+	err := row.Scan(
+		&i.UserID,
+		&i.Url,
+		&i.Format,
+		&i.Secret,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getWidgetToken = `-- name: GetWidgetToken :one
+SELECT token_hash, user_id, origins, created_at, revoked_at
+FROM widget_tokens
+WHERE token_hash = $1
+`
+
+func (q *Queries) GetWidgetToken(ctx context.Context, tokenHash string) (WidgetToken, error) {
+	row := q.db.QueryRow(ctx, getWidgetToken, tokenHash)
+	var i WidgetToken
+	err := row.Scan(
+		&i.TokenHash,
+		&i.UserID,
+		&i.Origins,
+		&i.CreatedAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const insertAdminAudit = `-- name: InsertAdminAudit :exec
+INSERT INTO admin_audit_log (action, actor, details)
+VALUES ($1, $2, $3)
+`
+
+type InsertAdminAuditParams struct {
+	Action  string `json:"action"`
+	Actor   string `json:"actor"`
+	Details []byte `json:"details"`
+}
+
+func (q *Queries) InsertAdminAudit(ctx context.Context, arg InsertAdminAuditParams) error {
+	_, err := q.db.Exec(ctx, insertAdminAudit, arg.Action, arg.Actor, arg.Details)
+	return err
+}
+
+const insertCharge = `-- name: InsertCharge :execrows
+INSERT INTO charges (user_id, service_name, charged_on, amount)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, lower(service_name), charged_on, amount) DO NOTHING
+`
+
+type InsertChargeParams struct {
+	UserID      string    `json:"user_id"`
+	ServiceName string    `json:"service_name"`
+	ChargedOn   time.Time `json:"charged_on"`
+	Amount      int64     `json:"amount"`
+}
+
+func (q *Queries) InsertCharge(ctx context.Context, arg InsertChargeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, insertCharge,
+		arg.UserID,
+		arg.ServiceName,
+		arg.ChargedOn,
+		arg.Amount,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const insertImportKey = `-- name: InsertImportKey :exec
+INSERT INTO import_keys (key, subscription_id)
+VALUES ($1, $2)
+`
+
+type InsertImportKeyParams struct {
+	Key            string `json:"key"`
+	SubscriptionID int64  `json:"subscription_id"`
+}
+
+func (q *Queries) InsertImportKey(ctx context.Context, arg InsertImportKeyParams) error {
+	_, err := q.db.Exec(ctx, insertImportKey, arg.Key, arg.SubscriptionID)
+	return err
+}
+
+const insertPriceReview = `-- name: InsertPriceReview :one
+INSERT INTO price_reviews (subscription_id, public_id, user_id, service_name, cost, catalog_price, opened_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (subscription_id, catalog_price) DO NOTHING
+RETURNING id
+`
+
+type InsertPriceReviewParams struct {
+	SubscriptionID int64     `json:"subscription_id"`
+	PublicID       string    `json:"public_id"`
+	UserID         string    `json:"user_id"`
+	ServiceName    string    `json:"service_name"`
+	Cost           int64     `json:"cost"`
+	CatalogPrice   int64     `json:"catalog_price"`
+	OpenedAt       time.Time `json:"opened_at"`
+}
+
+func (q *Queries) InsertPriceReview(ctx context.Context, arg InsertPriceReviewParams) (int64, error) {
+	row := q.db.QueryRow(ctx, insertPriceReview,
+		arg.SubscriptionID,
+		arg.PublicID,
+		arg.UserID,
+		arg.ServiceName,
+		arg.Cost,
+		arg.CatalogPrice,
+		arg.OpenedAt,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertQuarantinedImport = `-- name: InsertQuarantinedImport :one
+INSERT INTO import_quarantine (user_id, service_name, cost, start_date, end_date, reason, code, held_at, import_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (import_key) WHERE import_key <> '' DO UPDATE
+SET import_key = EXCLUDED.import_key
+RETURNING id
+`
+
+type InsertQuarantinedImportParams struct {
+	UserID      string     `json:"user_id"`
+	ServiceName string     `json:"service_name"`
+	Cost        int64      `json:"cost"`
+	StartDate   time.Time  `json:"start_date"`
+	EndDate     *time.Time `json:"end_date"`
+	Reason      string     `json:"reason"`
+	Code        string     `json:"code"`
+	HeldAt      time.Time  `json:"held_at"`
+	ImportKey   string     `json:"import_key"`
+}
+
+func (q *Queries) InsertQuarantinedImport(ctx context.Context, arg InsertQuarantinedImportParams) (int64, error) {
+	row := q.db.QueryRow(ctx, insertQuarantinedImport,
+		arg.UserID,
+		arg.ServiceName,
+		arg.Cost,
+		arg.StartDate,
+		arg.EndDate,
+		arg.Reason,
+		arg.Code,
+		arg.HeldAt,
+		arg.ImportKey,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertReconciliationItem = `-- name: InsertReconciliationItem :one
+INSERT INTO reconciliation_items (kind, user_id, service_name, month, expected, charged, subscription_id, public_id,
+                                  opened_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (user_id, month, kind, lower(service_name)) DO NOTHING
+RETURNING id
+`
+
+type InsertReconciliationItemParams struct {
+	Kind           string      `json:"kind"`
+	UserID         string      `json:"user_id"`
+	ServiceName    string      `json:"service_name"`
+	Month          time.Time   `json:"month"`
+	Expected       int64       `json:"expected"`
+	Charged        int64       `json:"charged"`
+	SubscriptionID int64       `json:"subscription_id"`
+	PublicID       pgtype.UUID `json:"public_id"`
+	OpenedAt       time.Time   `json:"opened_at"`
+}
+
+func (q *Queries) InsertReconciliationItem(ctx context.Context, arg InsertReconciliationItemParams) (int64, error) {
+	row := q.db.QueryRow(ctx, insertReconciliationItem,
+		arg.Kind,
+		arg.UserID,
+		arg.ServiceName,
+		arg.Month,
+		arg.Expected,
+		arg.Charged,
+		arg.SubscriptionID,
+		arg.PublicID,
+		arg.OpenedAt,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertRequestAudit = `-- name: InsertRequestAudit :exec
+INSERT INTO request_audit (received_at, method, route, path, status, body_sha256, body_size, client, remote_ip,
+                           peer_ip, user_agent, request_id, tenant_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+`
+
+type InsertRequestAuditParams struct {
+	ReceivedAt time.Time `json:"received_at"`
+	Method     string    `json:"method"`
+	Route      string    `json:"route"`
+	Path       string    `json:"path"`
+	Status     int16     `json:"status"`
+	BodySha256 string    `json:"body_sha256"`
+	BodySize   int64     `json:"body_size"`
+	Client     string    `json:"client"`
+	RemoteIp   string    `json:"remote_ip"`
+	PeerIp     string    `json:"peer_ip"`
+	UserAgent  string    `json:"user_agent"`
+	RequestID  string    `json:"request_id"`
+	TenantID   string    `json:"tenant_id"`
+}
+
+func (q *Queries) InsertRequestAudit(ctx context.Context, arg InsertRequestAuditParams) error {
+	_, err := q.db.Exec(ctx, insertRequestAudit,
+		arg.ReceivedAt,
+		arg.Method,
+		arg.Route,
+		arg.Path,
+		arg.Status,
+		arg.BodySha256,
+		arg.BodySize,
+		arg.Client,
+		arg.RemoteIp,
+		arg.PeerIp,
+		arg.UserAgent,
+		arg.RequestID,
+		arg.TenantID,
+	)
+	return err
+}
+
+const insertShareLink = `-- name: InsertShareLink :exec
+INSERT INTO share_links (token_hash, user_id, service_name, created_at, expires_at, tenant_id)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+type InsertShareLinkParams struct {
+	TokenHash   string    `json:"token_hash"`
+	UserID      string    `json:"user_id"`
+	ServiceName string    `json:"service_name"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	TenantID    string    `json:"tenant_id"`
+}
+
+func (q *Queries) InsertShareLink(ctx context.Context, arg InsertShareLinkParams) error {
+	_, err := q.db.Exec(ctx, insertShareLink,
+		arg.TokenHash,
+		arg.UserID,
+		arg.ServiceName,
+		arg.CreatedAt,
+		arg.ExpiresAt,
+		arg.TenantID,
+	)
+	return err
+}
+
+const insertSubscriptionAdjustment = `-- name: InsertSubscriptionAdjustment :one
+WITH touched AS (
+    -- the net cost of the subscription changes, so does its version
+    UPDATE subscriptions SET updated_at = now()
+    WHERE id = $1
+    RETURNING id
+)
+INSERT INTO subscription_adjustments (subscription_id, month, amount, note)
+SELECT touched.id, $2, $3, $4
+FROM touched
+RETURNING id, subscription_id, month, amount, note, created_at
+`
+
+type InsertSubscriptionAdjustmentParams struct {
+	SubscriptionID int64     `json:"subscription_id"`
+	Month          time.Time `json:"month"`
+	Amount         int64     `json:"amount"`
+	Note           string    `json:"note"`
+}
+
+func (q *Queries) InsertSubscriptionAdjustment(ctx context.Context, arg InsertSubscriptionAdjustmentParams) (SubscriptionAdjustment, error) {
+	row := q.db.QueryRow(ctx, insertSubscriptionAdjustment,
+		arg.SubscriptionID,
+		arg.Month,
+		arg.Amount,
+		arg.Note,
+	)
+	var i SubscriptionAdjustment
+	err := row.Scan(
+		&i.ID,
+		&i.SubscriptionID,
+		&i.Month,
+		&i.Amount,
+		&i.Note,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const insertUserActivity = `-- name: InsertUserActivity :one
+INSERT INTO user_activity (user_id, kind, subscription_id, public_id, service_name, cost, previous_cost, end_date, occurred_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id
+`
+
+type InsertUserActivityParams struct {
+	UserID         string     `json:"user_id"`
+	Kind           string     `json:"kind"`
+	SubscriptionID int64      `json:"subscription_id"`
+	PublicID       string     `json:"public_id"`
+	ServiceName    string     `json:"service_name"`
+	Cost           int64      `json:"cost"`
+	PreviousCost   int64      `json:"previous_cost"`
+	EndDate        *time.Time `json:"end_date"`
+	OccurredAt     time.Time  `json:"occurred_at"`
+}
+
+func (q *Queries) InsertUserActivity(ctx context.Context, arg InsertUserActivityParams) (int64, error) {
+	row := q.db.QueryRow(ctx, insertUserActivity,
+		arg.UserID,
+		arg.Kind,
+		arg.SubscriptionID,
+		arg.PublicID,
+		arg.ServiceName,
+		arg.Cost,
+		arg.PreviousCost,
+		arg.EndDate,
+		arg.OccurredAt,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertUserPseudonym = `-- name: InsertUserPseudonym :exec
+INSERT INTO user_pseudonyms (pseudonym, user_id_enc)
+VALUES ($1, $2)
+ON CONFLICT (pseudonym) DO NOTHING
+`
+
+type InsertUserPseudonymParams struct {
+	Pseudonym string `json:"pseudonym"`
+	UserIDEnc string `json:"user_id_enc"`
+}
+
+func (q *Queries) InsertUserPseudonym(ctx context.Context, arg InsertUserPseudonymParams) error {
+	_, err := q.db.Exec(ctx, insertUserPseudonym, arg.Pseudonym, arg.UserIDEnc)
+	return err
+}
+
+const insertUserSpendChanges = `-- name: InsertUserSpendChanges :exec
+INSERT INTO user_spend_changes (user_id, month, cost_delta, subs_delta)
+SELECT
+    unnest($1::uuid[]),
+    unnest($2::date[]),
+    unnest($3::bigint[]),
+    unnest($4::int[])
+`
+
+type InsertUserSpendChangesParams struct {
+	UserIds    []string    `json:"user_ids"`
+	Months     []time.Time `json:"months"`
+	CostDeltas []int64     `json:"cost_deltas"`
+	SubsDeltas []int32     `json:"subs_deltas"`
+}
+
+func (q *Queries) InsertUserSpendChanges(ctx context.Context, arg InsertUserSpendChangesParams) error {
+	_, err := q.db.Exec(ctx, insertUserSpendChanges,
+		arg.UserIds,
+		arg.Months,
+		arg.CostDeltas,
+		arg.SubsDeltas,
+	)
+	return err
+}
+
+const insertWidgetToken = `-- name: InsertWidgetToken :exec
+INSERT INTO widget_tokens (token_hash, user_id, origins, created_at)
+VALUES ($1, $2, $3, $4)
+`
+
+type InsertWidgetTokenParams struct {
+	TokenHash string    `json:"token_hash"`
+	UserID    string    `json:"user_id"`
+	Origins   []string  `json:"origins"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (q *Queries) InsertWidgetToken(ctx context.Context, arg InsertWidgetTokenParams) error {
+	_, err := q.db.Exec(ctx, insertWidgetToken,
+		arg.TokenHash,
+		arg.UserID,
+		arg.Origins,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const listChargedUsers = `-- name: ListChargedUsers :many
+SELECT DISTINCT user_id
+FROM charges
+WHERE charged_on >= $1::date
+  AND charged_on < $1::date + INTERVAL '1 month'
+ORDER BY user_id
+`
+
+func (q *Queries) ListChargedUsers(ctx context.Context, month time.Time) ([]string, error) {
+	rows, err := q.db.Query(ctx, listChargedUsers, month)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var user_id string
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOpenPriceReviews = `-- name: ListOpenPriceReviews :many
+SELECT id, subscription_id, public_id, user_id, service_name, cost, catalog_price, opened_at, resolved_at
+FROM price_reviews
+WHERE resolved_at IS NULL
+ORDER BY id
+LIMIT $1
+`
+
+func (q *Queries) ListOpenPriceReviews(ctx context.Context, limit int32) ([]PriceReview, error) {
+	rows, err := q.db.Query(ctx, listOpenPriceReviews, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PriceReview
+	for rows.Next() {
+		var i PriceReview
+		if err := rows.Scan(
+			&i.ID,
+			&i.SubscriptionID,
+			&i.PublicID,
+			&i.UserID,
+			&i.ServiceName,
+			&i.Cost,
+			&i.CatalogPrice,
+			&i.OpenedAt,
+			&i.ResolvedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOpenReconciliationItems = `-- name: ListOpenReconciliationItems :many
+SELECT id, kind, user_id, service_name, month, expected, charged, subscription_id, public_id, opened_at, resolved_at
+FROM reconciliation_items
+WHERE resolved_at IS NULL
+  AND ($1::uuid IS NULL OR user_id = $1::uuid)
+ORDER BY id
+LIMIT $2
+`
+
+type ListOpenReconciliationItemsParams struct {
+	UserID pgtype.UUID `json:"user_id"`
+	Lim    int32       `json:"lim"`
+}
+
+func (q *Queries) ListOpenReconciliationItems(ctx context.Context, arg ListOpenReconciliationItemsParams) ([]ReconciliationItem, error) {
+	rows, err := q.db.Query(ctx, listOpenReconciliationItems, arg.UserID, arg.Lim)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ReconciliationItem
+	for rows.Next() {
+		var i ReconciliationItem
+		if err := rows.Scan(
+			&i.ID,
+			&i.Kind,
+			&i.UserID,
+			&i.ServiceName,
+			&i.Month,
+			&i.Expected,
+			&i.Charged,
+			&i.SubscriptionID,
+			&i.PublicID,
+			&i.OpenedAt,
+			&i.ResolvedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listQuarantinedImports = `-- name: ListQuarantinedImports :many
+SELECT id, user_id, service_name, cost, start_date, end_date, reason, code, held_at, import_key
+FROM import_quarantine
+WHERE user_id = $1
+ORDER BY id
+LIMIT $2
+`
+
+type ListQuarantinedImportsParams struct {
+	UserID string `json:"user_id"`
+	Lim    int32  `json:"lim"`
+}
+
+func (q *Queries) ListQuarantinedImports(ctx context.Context, arg ListQuarantinedImportsParams) ([]ImportQuarantine, error) {
+	rows, err := q.db.Query(ctx, listQuarantinedImports, arg.UserID, arg.Lim)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ImportQuarantine
+	for rows.Next() {
+		var i ImportQuarantine
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ServiceName,
+			&i.Cost,
+			&i.StartDate,
+			&i.EndDate,
+			&i.Reason,
+			&i.Code,
+			&i.HeldAt,
+			&i.ImportKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSchemaTables = `-- name: ListSchemaTables :many
+SELECT tablename::text AS table_name
+FROM pg_tables
+WHERE schemaname = current_schema()
+  AND tablename <> 'schema_migrations'
+ORDER BY tablename
+`
+
+func (q *Queries) ListSchemaTables(ctx context.Context) ([]string, error) {
+	rows, err := q.db.Query(ctx, listSchemaTables)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var table_name string
+		if err := rows.Scan(&table_name); err != nil {
+			return nil, err
+		}
+		items = append(items, table_name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSeatShares = `-- name: ListSeatShares :many
+SELECT s.id, s.service_name, s.cost, seats.total, s.public_id
+FROM subscription_seats seats
+JOIN subscriptions s ON s.id = seats.subscription_id
+WHERE $1::uuid = ANY (seats.member_ids)
+  AND s.start_date <= $2::date
+  AND (s.end_date IS NULL OR s.end_date >= $3::date)
+  AND ($4::text IS NULL OR s.service_name = $4::text)
+  AND NOT EXISTS (SELECT 1 FROM user_deactivations d WHERE d.user_id IN (s.user_id, $1::uuid))
+ORDER BY s.service_name, s.id
+`
+
+type ListSeatSharesParams struct {
+	UserID      string      `json:"user_id"`
+	PeriodTo    time.Time   `json:"period_to"`
+	PeriodFrom  time.Time   `json:"period_from"`
+	ServiceName pgtype.Text `json:"service_name"`
+}
+
+type ListSeatSharesRow struct {
+	ID          int64  `json:"id"`
+	ServiceName string `json:"service_name"`
+	Cost        int64  `json:"cost"`
+	Total       int32  `json:"total"`
+	PublicID    string `json:"public_id"`
+}
+
+func (q *Queries) ListSeatShares(ctx context.Context, arg ListSeatSharesParams) ([]ListSeatSharesRow, error) {
+	rows, err := q.db.Query(ctx, listSeatShares,
+		arg.UserID,
+		arg.PeriodTo,
+		arg.PeriodFrom,
+		arg.ServiceName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSeatSharesRow
+	for rows.Next() {
+		var i ListSeatSharesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ServiceName,
+			&i.Cost,
+			&i.Total,
+			&i.PublicID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSubscriptionAdjustments = `-- name: ListSubscriptionAdjustments :many
+SELECT id, subscription_id, month, amount, note, created_at
+FROM subscription_adjustments
+WHERE subscription_id = $1
+ORDER BY month, id
+`
+
+func (q *Queries) ListSubscriptionAdjustments(ctx context.Context, subscriptionID int64) ([]SubscriptionAdjustment, error) {
+	rows, err := q.db.Query(ctx, listSubscriptionAdjustments, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SubscriptionAdjustment
+	for rows.Next() {
+		var i SubscriptionAdjustment
+		if err := rows.Scan(
+			&i.ID,
+			&i.SubscriptionID,
+			&i.Month,
+			&i.Amount,
+			&i.Note,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSubscriptionChanges = `-- name: ListSubscriptionChanges :many
+SELECT seq, subscription_id, op, changed_at, user_id, tx, public_id
+FROM subscription_changes
+WHERE user_id = $1::uuid
+  AND (tx, seq) > ($2::xid8, $3::bigint)
+  AND tx < pg_snapshot_xmin(pg_current_snapshot())
+ORDER BY tx, seq
+LIMIT $4
+`
+
+type ListSubscriptionChangesParams struct {
+	UserID    pgtype.UUID `json:"user_id"`
+	SinceTx   uint64      `json:"since_tx"`
+	SinceSeq  int64       `json:"since_seq"`
+	PageLimit int32       `json:"page_limit"`
+}
+
+// seq is taken at insert, so entries are read in transaction order and only up to the oldest transaction
+// still running: nothing can commit before a position once it was returned
+func (q *Queries) ListSubscriptionChanges(ctx context.Context, arg ListSubscriptionChangesParams) ([]SubscriptionChange, error) {
+	rows, err := q.db.Query(ctx, listSubscriptionChanges,
+		arg.UserID,
+		arg.SinceTx,
+		arg.SinceSeq,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SubscriptionChange
+	for rows.Next() {
+		var i SubscriptionChange
+		if err := rows.Scan(
+			&i.Seq,
+			&i.SubscriptionID,
+			&i.Op,
+			&i.ChangedAt,
+			&i.UserID,
+			&i.Tx,
+			&i.PublicID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSubscriptions = `-- name: ListSubscriptions :many
+SELECT id, user_id, service_name, cost, start_date, end_date, created_at, updated_at, currency, cost_minor, public_id
+FROM subscriptions
+WHERE
+    ($1::uuid IS NULL OR user_id = $1::uuid)
+    AND ($2::text IS NULL OR service_name = $2::text)
+    AND (
+        $3::date IS NULL
+        OR (
+            (end_date IS NULL OR end_date >= $3::date)
+            AND ($4::date IS NULL OR start_date <= $4::date)
+        )
+    )
+    AND (
+        $5::bigint IS NULL
+        OR (start_date, service_name COLLATE "C", id) > (
+            $6::date,
+            $7::text,
+            $5::bigint
+        )
+    )
+    AND ($8::timestamptz IS NULL OR updated_at > $8::timestamptz)
+    AND ($9::bigint IS NULL OR cost >= $9::bigint)
+    AND ($10::bigint IS NULL OR cost <= $10::bigint)
+    AND ($11::text IS NULL OR service_name ILIKE $11::text)
+    AND ($12::date IS NULL OR start_date >= $12::date)
+    AND ($13::date IS NULL OR start_date <= $13::date)
+    AND (
+        $14::boolean
+        OR NOT EXISTS (SELECT 1 FROM user_deactivations d WHERE d.user_id = subscriptions.user_id)
+    )
+ORDER BY start_date, service_name COLLATE "C", id
+LIMIT $16
+OFFSET $15
+`
+
+type ListSubscriptionsParams struct {
+	UserID             pgtype.UUID `json:"user_id"`
+	ServiceName        pgtype.Text `json:"service_name"`
+	PeriodFrom         pgtype.Date `json:"period_from"`
+	PeriodTo           pgtype.Date `json:"period_to"`
+	AfterID            pgtype.Int8 `json:"after_id"`
+	AfterStartDate     pgtype.Date `json:"after_start_date"`
+	AfterServiceName   pgtype.Text `json:"after_service_name"`
+	UpdatedSince       *time.Time  `json:"updated_since"`
+	CostMin            pgtype.Int8 `json:"cost_min"`
+	CostMax            pgtype.Int8 `json:"cost_max"`
+	ServicePattern     pgtype.Text `json:"service_pattern"`
+	StartFrom          pgtype.Date `json:"start_from"`
+	StartTo            pgtype.Date `json:"start_to"`
+	IncludeDeactivated bool        `json:"include_deactivated"`
+	PageOffset         int32       `json:"page_offset"`
+	PageLimit          int32       `json:"page_limit"`
+}
+
+func (q *Queries) ListSubscriptions(ctx context.Context, arg ListSubscriptionsParams) ([]Subscription, error) {
+	rows, err := q.db.Query(ctx, listSubscriptions,
+		arg.UserID,
+		arg.ServiceName,
+		arg.PeriodFrom,
+		arg.PeriodTo,
+		arg.AfterID,
+		arg.AfterStartDate,
+		arg.AfterServiceName,
+		arg.UpdatedSince,
+		arg.CostMin,
+		arg.CostMax,
+		arg.ServicePattern,
+		arg.StartFrom,
+		arg.StartTo,
+		arg.IncludeDeactivated,
+		arg.PageOffset,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Subscription
+	for rows.Next() {
+		var i Subscription
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ServiceName,
+			&i.Cost,
+			&i.StartDate,
+			&i.EndDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Currency,
+			&i.CostMinor,
+			&i.PublicID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSubscriptionsEndedBefore = `-- name: ListSubscriptionsEndedBefore :many
+SELECT id, user_id, service_name, cost, start_date, end_date, created_at, updated_at, currency, cost_minor, public_id
+FROM subscriptions
+WHERE end_date < $1::date
+ORDER BY id
+LIMIT $2
+`
+
+type ListSubscriptionsEndedBeforeParams struct {
+	Before    time.Time `json:"before"`
+	PageLimit int32     `json:"page_limit"`
+}
+
+func (q *Queries) ListSubscriptionsEndedBefore(ctx context.Context, arg ListSubscriptionsEndedBeforeParams) ([]Subscription, error) {
+	rows, err := q.db.Query(ctx, listSubscriptionsEndedBefore, arg.Before, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Subscription
+	for rows.Next() {
+		var i Subscription
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ServiceName,
+			&i.Cost,
+			&i.StartDate,
+			&i.EndDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Currency,
+			&i.CostMinor,
+			&i.PublicID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserActivity = `-- name: ListUserActivity :many
+SELECT id, user_id, kind, subscription_id, public_id, service_name, cost, previous_cost, end_date, occurred_at
+FROM user_activity
+WHERE user_id = $1
+  AND ($2::bigint <= 0 OR id < $2::bigint)
+ORDER BY id DESC
+LIMIT $3
+`
+
+type ListUserActivityParams struct {
+	UserID    string `json:"user_id"`
+	Before    int64  `json:"before"`
+	PageLimit int32  `json:"page_limit"`
+}
+
+func (q *Queries) ListUserActivity(ctx context.Context, arg ListUserActivityParams) ([]UserActivity, error) {
+	rows, err := q.db.Query(ctx, listUserActivity, arg.UserID, arg.Before, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UserActivity
+	for rows.Next() {
+		var i UserActivity
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Kind,
+			&i.SubscriptionID,
+			&i.PublicID,
+			&i.ServiceName,
+			&i.Cost,
+			&i.PreviousCost,
+			&i.EndDate,
+			&i.OccurredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserCharges = `-- name: ListUserCharges :many
+SELECT id, user_id, service_name, charged_on, amount, recorded_at
+FROM charges
+WHERE user_id = $1
+  AND charged_on >= $2::date
+  AND charged_on < $2::date + INTERVAL '1 month'
+ORDER BY charged_on, id
+`
+
+type ListUserChargesParams struct {
+	UserID string    `json:"user_id"`
+	Month  time.Time `json:"month"`
+}
+
+func (q *Queries) ListUserCharges(ctx context.Context, arg ListUserChargesParams) ([]Charge, error) {
+	rows, err := q.db.Query(ctx, listUserCharges, arg.UserID, arg.Month)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Charge
+	for rows.Next() {
+		var i Charge
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ServiceName,
+			&i.ChargedOn,
+			&i.Amount,
+			&i.RecordedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserPseudonyms = `-- name: ListUserPseudonyms :many
+SELECT pseudonym, user_id_enc, created_at
+FROM user_pseudonyms
+WHERE pseudonym = ANY($1::uuid[])
+`
+
+func (q *Queries) ListUserPseudonyms(ctx context.Context, pseudonyms []string) ([]UserPseudonym, error) {
+	rows, err := q.db.Query(ctx, listUserPseudonyms, pseudonyms)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UserPseudonym
+	for rows.Next() {
+		var i UserPseudonym
+		if err := rows.Scan(
+			&i.Pseudonym,
+			&i.UserIDEnc,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const moveSubscriptionAdjustments = `-- name: MoveSubscriptionAdjustments :execrows
+UPDATE subscription_adjustments
+SET subscription_id = $1
+WHERE subscription_id = $2
+`
+
+type MoveSubscriptionAdjustmentsParams struct {
+	ToSubscriptionID   int64 `json:"to_subscription_id"`
+	FromSubscriptionID int64 `json:"from_subscription_id"`
+}
+
+func (q *Queries) MoveSubscriptionAdjustments(ctx context.Context, arg MoveSubscriptionAdjustmentsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, moveSubscriptionAdjustments, arg.ToSubscriptionID, arg.FromSubscriptionID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const moveSubscriptionSeats = `-- name: MoveSubscriptionSeats :execrows
+UPDATE subscription_seats
+SET subscription_id = $1
+WHERE subscription_id = $2
+  AND NOT EXISTS (SELECT 1 FROM subscription_seats k WHERE k.subscription_id = $1)
+`
+
+type MoveSubscriptionSeatsParams struct {
+	ToSubscriptionID   int64 `json:"to_subscription_id"`
+	FromSubscriptionID int64 `json:"from_subscription_id"`
+}
+
+func (q *Queries) MoveSubscriptionSeats(ctx context.Context, arg MoveSubscriptionSeatsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, moveSubscriptionSeats, arg.ToSubscriptionID, arg.FromSubscriptionID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const priceBenchmarks = `-- name: PriceBenchmarks :many
+SELECT
+    lower(btrim(s.service_name))::text AS service,
+    COUNT(DISTINCT s.user_id)::bigint AS users,
+    COUNT(*)::bigint AS subscriptions,
+    round(AVG(s.cost))::bigint AS average_cost,
+    round(percentile_cont(0.5) WITHIN GROUP (ORDER BY s.cost))::bigint AS median_cost
+FROM subscriptions s
+JOIN user_settings us ON us.user_id = s.user_id AND us.share_price_stats
+WHERE s.start_date <= $1::date
+  AND (s.end_date IS NULL OR s.end_date >= $1::date)
+  AND s.cost > 0
+  AND NOT EXISTS (SELECT 1 FROM user_deactivations d WHERE d.user_id = s.user_id)
+GROUP BY lower(btrim(s.service_name))
+HAVING COUNT(DISTINCT s.user_id) >= $2::bigint
+ORDER BY service
+`
+
+type PriceBenchmarksParams struct {
+	Month    time.Time `json:"month"`
+	MinUsers int64     `json:"min_users"`
+}
+
+type PriceBenchmarksRow struct {
+	Service       string `json:"service"`
+	Users         int64  `json:"users"`
+	Subscriptions int64  `json:"subscriptions"`
+	AverageCost   int64  `json:"average_cost"`
+	MedianCost    int64  `json:"median_cost"`
+}
+
+func (q *Queries) PriceBenchmarks(ctx context.Context, arg PriceBenchmarksParams) ([]PriceBenchmarksRow, error) {
+	rows, err := q.db.Query(ctx, priceBenchmarks, arg.Month, arg.MinUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PriceBenchmarksRow
+	for rows.Next() {
+		var i PriceBenchmarksRow
+		if err := rows.Scan(
+			&i.Service,
+			&i.Users,
+			&i.Subscriptions,
+			&i.AverageCost,
+			&i.MedianCost,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const reactivateUser = `-- name: ReactivateUser :exec
+DELETE FROM user_deactivations
+WHERE user_id = $1
+`
+
+func (q *Queries) ReactivateUser(ctx context.Context, userID string) error {
+	_, err := q.db.Exec(ctx, reactivateUser, userID)
+	return err
+}
+
+const reassignSubscriptionsUser = `-- name: ReassignSubscriptionsUser :execrows
+UPDATE subscriptions
+SET
+    user_id = $1,
+    updated_at = now()
+WHERE user_id = $2
+`
+
+type ReassignSubscriptionsUserParams struct {
+	ToUserID   string `json:"to_user_id"`
+	FromUserID string `json:"from_user_id"`
+}
+
+func (q *Queries) ReassignSubscriptionsUser(ctx context.Context, arg ReassignSubscriptionsUserParams) (int64, error) {
+	result, err := q.db.Exec(ctx, reassignSubscriptionsUser, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const removeSeatMember = `-- name: RemoveSeatMember :execrows
+UPDATE subscription_seats
+SET member_ids = array_remove(member_ids, $1::uuid),
+    updated_at = now()
+WHERE $1::uuid = ANY (member_ids)
+`
+
+func (q *Queries) RemoveSeatMember(ctx context.Context, userID string) (int64, error) {
+	result, err := q.db.Exec(ctx, removeSeatMember, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const resolvePriceReview = `-- name: ResolvePriceReview :one
+UPDATE price_reviews
+SET resolved_at = $1
+WHERE id = $2
+  AND resolved_at IS NULL
+RETURNING id, subscription_id, public_id, user_id, service_name, cost, catalog_price, opened_at, resolved_at
+`
+
+type ResolvePriceReviewParams struct {
+	ResolvedAt time.Time `json:"resolved_at"`
+	ID         int64     `json:"id"`
+}
+
+func (q *Queries) ResolvePriceReview(ctx context.Context, arg ResolvePriceReviewParams) (PriceReview, error) {
+	row := q.db.QueryRow(ctx, resolvePriceReview, arg.ResolvedAt, arg.ID)
+	var i PriceReview
+	err := row.Scan(
+		&i.ID,
+		&i.SubscriptionID,
+		&i.PublicID,
+		&i.UserID,
+		&i.ServiceName,
+		&i.Cost,
+		&i.CatalogPrice,
+		&i.OpenedAt,
+		&i.ResolvedAt,
+	)
+	return i, err
+}
+
+const resolveReconciliationItem = `-- name: ResolveReconciliationItem :one
+UPDATE reconciliation_items
+SET resolved_at = $1
+WHERE id = $2
+  AND resolved_at IS NULL
+RETURNING id, kind, user_id, service_name, month, expected, charged, subscription_id, public_id, opened_at, resolved_at
+`
+
+type ResolveReconciliationItemParams struct {
+	ResolvedAt time.Time `json:"resolved_at"`
+	ID         int64     `json:"id"`
+}
+
+func (q *Queries) ResolveReconciliationItem(ctx context.Context, arg ResolveReconciliationItemParams) (ReconciliationItem, error) {
+	row := q.db.QueryRow(ctx, resolveReconciliationItem, arg.ResolvedAt, arg.ID)
+	var i ReconciliationItem
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.UserID,
+		&i.ServiceName,
+		&i.Month,
+		&i.Expected,
+		&i.Charged,
+		&i.SubscriptionID,
+		&i.PublicID,
+		&i.OpenedAt,
+		&i.ResolvedAt,
 	)
 	return i, err
 }
 
-const deleteSubscription = `-- name: DeleteSubscription :execrows
-DELETE FROM subscriptions
-WHERE id = $1
+const revokeShareLink = `-- name: RevokeShareLink :execrows
+UPDATE share_links
+SET revoked_at = COALESCE(revoked_at, $1)
+WHERE token_hash = $2
 `
 
-func (q *Queries) DeleteSubscription(ctx context.Context, id int64) (int64, error) {
-	result, err := q.db.Exec(ctx, deleteSubscription, id)
+type RevokeShareLinkParams struct {
+	RevokedAt time.Time `json:"revoked_at"`
+	TokenHash string    `json:"token_hash"`
+}
+
+func (q *Queries) RevokeShareLink(ctx context.Context, arg RevokeShareLinkParams) (int64, error) {
+	result, err := q.db.Exec(ctx, revokeShareLink, arg.RevokedAt, arg.TokenHash)
 	if err != nil {
 		return 0, err
 	}
 	return result.RowsAffected(), nil
 }
 
-const getSubscription = `-- name: GetSubscription :one
-SELECT id, user_id, service_name, cost, start_date, end_date
-FROM subscriptions
-WHERE id = $1
+const revokeShareLinksIssuedBefore = `-- name: RevokeShareLinksIssuedBefore :execrows
+UPDATE share_links
+SET revoked_at = $1
+WHERE revoked_at IS NULL
+  AND created_at < $2
+  AND ($3::uuid IS NULL OR user_id = $3::uuid)
 `
 
-func (q *Queries) GetSubscription(ctx context.Context, id int64) (Subscription, error) {
-	row := q.db.QueryRow(ctx, getSubscription, id)
-	var i Subscription
+type RevokeShareLinksIssuedBeforeParams struct {
+	RevokedAt    time.Time   `json:"revoked_at"`
+	IssuedBefore time.Time   `json:"issued_before"`
+	UserID       pgtype.UUID `json:"user_id"`
+}
+
+func (q *Queries) RevokeShareLinksIssuedBefore(ctx context.Context, arg RevokeShareLinksIssuedBeforeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, revokeShareLinksIssuedBefore, arg.RevokedAt, arg.IssuedBefore, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const revokeWidgetToken = `-- name: RevokeWidgetToken :execrows
+UPDATE widget_tokens
+SET revoked_at = COALESCE(revoked_at, $1)
+WHERE token_hash = $2
+`
+
+type RevokeWidgetTokenParams struct {
+	RevokedAt time.Time `json:"revoked_at"`
+	TokenHash string    `json:"token_hash"`
+}
+
+func (q *Queries) RevokeWidgetToken(ctx context.Context, arg RevokeWidgetTokenParams) (int64, error) {
+	result, err := q.db.Exec(ctx, revokeWidgetToken, arg.RevokedAt, arg.TokenHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const subscriptionCostSummary = `-- name: SubscriptionCostSummary :one
+SELECT
+    COALESCE(SUM(s.cost * m.months), 0)::bigint AS total_cost,
+    COUNT(*)::bigint AS subscriptions,
+    COALESCE(SUM(s.cost), 0)::bigint AS monthly_cost,
+    COALESCE(MIN(s.cost), 0)::bigint AS min_cost,
+    COALESCE(MAX(s.cost), 0)::bigint AS max_cost
+FROM subscriptions s
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS months
+    FROM generate_series(
+        GREATEST(s.start_date, $1::date),
+        LEAST(COALESCE(s.end_date, $2::date), $2::date),
+        interval '1 month'
+    )
+) AS m
+WHERE s.start_date <= $2::date
+  AND (s.end_date IS NULL OR s.end_date >= $1::date)
+  AND ($3::uuid IS NULL OR s.user_id = $3::uuid)
+  AND ($4::text IS NULL OR s.service_name = $4::text)
+  AND s.cost > 0
+  AND NOT EXISTS (SELECT 1 FROM user_deactivations d WHERE d.user_id = s.user_id)
+`
+
+type SubscriptionCostSummaryParams struct {
+	PeriodFrom  time.Time   `json:"period_from"`
+	PeriodTo    time.Time   `json:"period_to"`
+	UserID      pgtype.UUID `json:"user_id"`
+	ServiceName pgtype.Text `json:"service_name"`
+}
+
+type SubscriptionCostSummaryRow struct {
+	TotalCost     int64 `json:"total_cost"`
+	Subscriptions int64 `json:"subscriptions"`
+	MonthlyCost   int64 `json:"monthly_cost"`
+	MinCost       int64 `json:"min_cost"`
+	MaxCost       int64 `json:"max_cost"`
+}
+
+func (q *Queries) SubscriptionCostSummary(ctx context.Context, arg SubscriptionCostSummaryParams) (SubscriptionCostSummaryRow, error) {
+	row := q.db.QueryRow(ctx, subscriptionCostSummary,
+		arg.PeriodFrom,
+		arg.PeriodTo,
+		arg.UserID,
+		arg.ServiceName,
+	)
+	var i SubscriptionCostSummaryRow
 	err := row.Scan(
-		&i.ID,
-		&i.UserID,
-		&i.ServiceName,
-		&i.Cost,
-		&i.StartDate,
-		&i.EndDate,
+		&i.TotalCost,
+		&i.Subscriptions,
+		&i.MonthlyCost,
+		&i.MinCost,
+		&i.MaxCost,
 	)
 	return i, err
 }
 
-const listSubscriptions = `-- name: ListSubscriptions :many
-SELECT id, user_id, service_name, cost, start_date, end_date
-FROM subscriptions
-WHERE
-    ($1::uuid IS NULL OR user_id = $1::uuid)
-    AND ($2::text IS NULL OR service_name = $2::text)
-    AND (
-        $3::date IS NULL
-        OR (
-            (end_date IS NULL OR end_date >= $3::date)
-            AND ($4::date IS NULL OR start_date <= $4::date)
-        )
-    )
-ORDER BY start_date, service_name, id
-LIMIT $6
-OFFSET $5
+const sumAdjustments = `-- name: SumAdjustments :one
+SELECT COALESCE(SUM(a.amount), 0)::bigint AS total
+FROM subscription_adjustments a
+JOIN subscriptions s ON s.id = a.subscription_id
+WHERE a.month BETWEEN $1::date AND $2::date
+  AND ($3::uuid IS NULL OR s.user_id = $3::uuid)
+  AND ($4::text IS NULL OR s.service_name = $4::text)
+  AND NOT EXISTS (SELECT 1 FROM user_deactivations d WHERE d.user_id = s.user_id)
 `
 
-type ListSubscriptionsParams struct {
+type SumAdjustmentsParams struct {
+	PeriodFrom  time.Time   `json:"period_from"`
+	PeriodTo    time.Time   `json:"period_to"`
 	UserID      pgtype.UUID `json:"user_id"`
 	ServiceName pgtype.Text `json:"service_name"`
-	PeriodFrom  pgtype.Date `json:"period_from"`
-	PeriodTo    pgtype.Date `json:"period_to"`
-	PageOffset  int32       `json:"page_offset"`
-	PageLimit   int32       `json:"page_limit"`
 }
 
-func (q *Queries) ListSubscriptions(ctx context.Context, arg ListSubscriptionsParams) ([]Subscription, error) {
-	rows, err := q.db.Query(ctx, listSubscriptions,
+func (q *Queries) SumAdjustments(ctx context.Context, arg SumAdjustmentsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, sumAdjustments,
+		arg.PeriodFrom,
+		arg.PeriodTo,
 		arg.UserID,
 		arg.ServiceName,
+	)
+	var total int64
+	err := row.Scan(&total)
+	return total, err
+}
+
+const sumAdjustmentsGrouped = `-- name: SumAdjustmentsGrouped :many
+SELECT
+    (CASE $1::text
+        WHEN 'service' THEN s.service_name
+        WHEN 'user' THEN s.user_id::text
+        WHEN 'week' THEN to_char(date_trunc('week', a.month), 'YYYY-MM-DD')
+        WHEN 'year' THEN to_char(date_trunc('year', a.month), 'YYYY-MM-DD')
+        ELSE to_char(a.month, 'YYYY-MM-DD')
+    END)::text AS group_key,
+    COALESCE(SUM(a.amount), 0)::bigint AS total
+FROM subscription_adjustments a
+JOIN subscriptions s ON s.id = a.subscription_id
+WHERE a.month BETWEEN $2::date AND $3::date
+  AND ($4::uuid IS NULL OR s.user_id = $4::uuid)
+  AND ($5::text IS NULL OR s.service_name = $5::text)
+  AND NOT EXISTS (SELECT 1 FROM user_deactivations d WHERE d.user_id = s.user_id)
+GROUP BY group_key
+ORDER BY group_key
+`
+
+type SumAdjustmentsGroupedParams struct {
+	GroupBy     string      `json:"group_by"`
+	PeriodFrom  time.Time   `json:"period_from"`
+	PeriodTo    time.Time   `json:"period_to"`
+	UserID      pgtype.UUID `json:"user_id"`
+	ServiceName pgtype.Text `json:"service_name"`
+}
+
+type SumAdjustmentsGroupedRow struct {
+	GroupKey string `json:"group_key"`
+	Total    int64  `json:"total"`
+}
+
+func (q *Queries) SumAdjustmentsGrouped(ctx context.Context, arg SumAdjustmentsGroupedParams) ([]SumAdjustmentsGroupedRow, error) {
+	rows, err := q.db.Query(ctx, sumAdjustmentsGrouped,
+		arg.GroupBy,
 		arg.PeriodFrom,
 		arg.PeriodTo,
-		arg.PageOffset,
-		arg.PageLimit,
+		arg.UserID,
+		arg.ServiceName,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Subscription
+	var items []SumAdjustmentsGroupedRow
 	for rows.Next() {
-		var i Subscription
-		if err := rows.Scan(
-			&i.ID,
-			&i.UserID,
-			&i.ServiceName,
-			&i.Cost,
-			&i.StartDate,
-			&i.EndDate,
-		); err != nil {
+		var i SumAdjustmentsGroupedRow
+		if err := rows.Scan(&i.GroupKey, &i.Total); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -146,41 +2058,52 @@ func (q *Queries) ListSubscriptions(ctx context.Context, arg ListSubscriptionsPa
 	return items, nil
 }
 
+const sumAllSubscriptionsCost = `-- name: SumAllSubscriptionsCost :one
+SELECT COALESCE(SUM(s.cost), 0)::bigint AS total_cost
+FROM subscriptions s
+CROSS JOIN LATERAL generate_series(
+    GREATEST(s.start_date, $1::date),
+    LEAST(COALESCE(s.end_date, $2::date), $2::date),
+    interval '1 month'
+) AS month_start
+WHERE s.start_date <= $2::date
+  AND (s.end_date IS NULL OR s.end_date >= $1::date)
+  AND ($3::text IS NULL OR s.service_name = $3::text)
+  AND NOT EXISTS (SELECT 1 FROM user_deactivations d WHERE d.user_id = s.user_id)
+`
+
+type SumAllSubscriptionsCostParams struct {
+	PeriodFrom  time.Time   `json:"period_from"`
+	PeriodTo    time.Time   `json:"period_to"`
+	ServiceName pgtype.Text `json:"service_name"`
+}
+
+func (q *Queries) SumAllSubscriptionsCost(ctx context.Context, arg SumAllSubscriptionsCostParams) (int64, error) {
+	row := q.db.QueryRow(ctx, sumAllSubscriptionsCost, arg.PeriodFrom, arg.PeriodTo, arg.ServiceName)
+	var total_cost int64
+	err := row.Scan(&total_cost)
+	return total_cost, err
+}
+
 const sumSubscriptionCost = `-- name: SumSubscriptionCost :one
-WITH params AS (
-    SELECT
-        $1::date AS start_date,
-        $2::date AS end_date,
-        $3::uuid AS user_id,
-        $4::text AS service_name
-),
-filtered AS (
-    SELECT s.id, s.user_id, s.service_name, s.cost, s.start_date, s.end_date
-    FROM subscriptions s
-    CROSS JOIN params p
-    WHERE s.start_date <= p.end_date
-      AND (s.end_date IS NULL OR s.end_date >= p.start_date)
-      AND (p.user_id IS NULL OR s.user_id = p.user_id)
-      AND (p.service_name IS NULL OR s.service_name = p.service_name)
-),
-expanded AS (
-    SELECT f.cost
-    FROM filtered f
-    CROSS JOIN params p
-    CROSS JOIN LATERAL generate_series(
-        GREATEST(f.start_date, p.start_date),
-        LEAST(COALESCE(f.end_date, p.end_date), p.end_date),
-        interval '1 month'
-    ) AS month_start
-)
-SELECT COALESCE(SUM(cost), 0)::bigint AS total_cost
-FROM expanded
+SELECT COALESCE(SUM(s.cost), 0)::bigint AS total_cost
+FROM subscriptions s
+CROSS JOIN LATERAL generate_series(
+    GREATEST(s.start_date, $1::date),
+    LEAST(COALESCE(s.end_date, $2::date), $2::date),
+    interval '1 month'
+) AS month_start
+WHERE s.user_id = $3::uuid
+  AND s.start_date <= $2::date
+  AND (s.end_date IS NULL OR s.end_date >= $1::date)
+  AND ($4::text IS NULL OR s.service_name = $4::text)
+  AND NOT EXISTS (SELECT 1 FROM user_deactivations d WHERE d.user_id = s.user_id)
 `
 
 type SumSubscriptionCostParams struct {
 	PeriodFrom  time.Time   `json:"period_from"`
-	PeriodTo    *time.Time  `json:"period_to"`
-	UserID      pgtype.UUID `json:"user_id"`
+	PeriodTo    time.Time   `json:"period_to"`
+	UserID      string      `json:"user_id"`
 	ServiceName pgtype.Text `json:"service_name"`
 }
 
@@ -196,6 +2119,120 @@ func (q *Queries) SumSubscriptionCost(ctx context.Context, arg SumSubscriptionCo
 	return total_cost, err
 }
 
+const sumSubscriptionCostGrouped = `-- name: SumSubscriptionCostGrouped :many
+SELECT
+    (CASE $1::text
+        WHEN 'service' THEN s.service_name
+        WHEN 'user' THEN s.user_id::text
+        WHEN 'week' THEN to_char(date_trunc('week', month_start), 'YYYY-MM-DD')
+        WHEN 'year' THEN to_char(date_trunc('year', month_start), 'YYYY-MM-DD')
+        ELSE to_char(month_start, 'YYYY-MM-DD')
+    END)::text AS group_key,
+    COALESCE(SUM(s.cost), 0)::bigint AS total,
+    COUNT(DISTINCT s.id)::bigint AS subscriptions
+FROM subscriptions s
+CROSS JOIN LATERAL generate_series(
+    GREATEST(s.start_date, $2::date),
+    LEAST(COALESCE(s.end_date, $3::date), $3::date),
+    interval '1 month'
+) AS month_start
+WHERE s.start_date <= $3::date
+  AND (s.end_date IS NULL OR s.end_date >= $2::date)
+  AND ($4::uuid IS NULL OR s.user_id = $4::uuid)
+  AND ($5::text IS NULL OR s.service_name = $5::text)
+  AND s.cost > 0
+  AND NOT EXISTS (SELECT 1 FROM user_deactivations d WHERE d.user_id = s.user_id)
+GROUP BY group_key
+ORDER BY group_key
+`
+
+type SumSubscriptionCostGroupedParams struct {
+	GroupBy     string      `json:"group_by"`
+	PeriodFrom  time.Time   `json:"period_from"`
+	PeriodTo    time.Time   `json:"period_to"`
+	UserID      pgtype.UUID `json:"user_id"`
+	ServiceName pgtype.Text `json:"service_name"`
+}
+
+type SumSubscriptionCostGroupedRow struct {
+	GroupKey      string `json:"group_key"`
+	Total         int64  `json:"total"`
+	Subscriptions int64  `json:"subscriptions"`
+}
+
+func (q *Queries) SumSubscriptionCostGrouped(ctx context.Context, arg SumSubscriptionCostGroupedParams) ([]SumSubscriptionCostGroupedRow, error) {
+	rows, err := q.db.Query(ctx, sumSubscriptionCostGrouped,
+		arg.GroupBy,
+		arg.PeriodFrom,
+		arg.PeriodTo,
+		arg.UserID,
+		arg.ServiceName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SumSubscriptionCostGroupedRow
+	for rows.Next() {
+		var i SumSubscriptionCostGroupedRow
+		if err := rows.Scan(&i.GroupKey, &i.Total, &i.Subscriptions); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const tableStats = `-- name: TableStats :many
+SELECT
+    relname::text AS table_name,
+    GREATEST(n_live_tup, 0)::bigint AS live_rows,
+    pg_total_relation_size(relid)::bigint AS total_bytes
+FROM pg_stat_user_tables
+WHERE schemaname = current_schema()
+ORDER BY relname
+`
+
+type TableStatsRow struct {
+	TableName  string `json:"table_name"`
+	LiveRows   int64  `json:"live_rows"`
+	TotalBytes int64  `json:"total_bytes"`
+}
+
+func (q *Queries) TableStats(ctx context.Context) ([]TableStatsRow, error) {
+	rows, err := q.db.Query(ctx, tableStats)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TableStatsRow
+	for rows.Next() {
+		var i TableStatsRow
+		if err := rows.Scan(&i.TableName, &i.LiveRows, &i.TotalBytes); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const tryAdvisoryLock = `-- name: TryAdvisoryLock :one
+SELECT pg_try_advisory_lock($1::bigint)
+`
+
+func (q *Queries) TryAdvisoryLock(ctx context.Context, key int64) (bool, error) {
+	row := q.db.QueryRow(ctx, tryAdvisoryLock, key)
+	var pg_try_advisory_lock bool
+	err := row.Scan(&pg_try_advisory_lock)
+	return pg_try_advisory_lock, err
+}
+
 const updateSubscription = `-- name: UpdateSubscription :execrows
 UPDATE subscriptions
 SET
@@ -203,8 +2240,12 @@ SET
     service_name = $2,
     cost = $3,
     start_date = $4,
-    end_date = $5
+    end_date = $5,
+    currency = 'RUB',
+    cost_minor = $3::bigint * 100,
+    updated_at = now()
 WHERE id = $6
+  AND ($7::timestamptz IS NULL OR updated_at = $7::timestamptz)
 `
 
 type UpdateSubscriptionParams struct {
@@ -214,6 +2255,7 @@ type UpdateSubscriptionParams struct {
 	StartDate   time.Time  `json:"start_date"`
 	EndDate     *time.Time `json:"end_date"`
 	ID          int64      `json:"id"`
+	IfUpdatedAt *time.Time `json:"if_updated_at"`
 }
 
 func (q *Queries) UpdateSubscription(ctx context.Context, arg UpdateSubscriptionParams) (int64, error) {
@@ -224,9 +2266,254 @@ func (q *Queries) UpdateSubscription(ctx context.Context, arg UpdateSubscription
 		arg.StartDate,
 		arg.EndDate,
 		arg.ID,
+		arg.IfUpdatedAt,
 	)
 	if err != nil {
 		return 0, err
 	}
 	return result.RowsAffected(), nil
 }
+
+const upsertSubscriptionSeats = `-- name: UpsertSubscriptionSeats :one
+INSERT INTO subscription_seats (subscription_id, total, member_ids)
+VALUES ($1, $2, $3::uuid[])
+ON CONFLICT (subscription_id) DO UPDATE
+SET total      = EXCLUDED.total,
+    member_ids = EXCLUDED.member_ids,
+    updated_at = now()
+RETURNING subscription_id, total, member_ids, updated_at
+`
+
+type UpsertSubscriptionSeatsParams struct {
+	SubscriptionID int64    `json:"subscription_id"`
+	Total          int32    `json:"total"`
+	MemberIds      []string `json:"member_ids"`
+}
+
+func (q *Queries) UpsertSubscriptionSeats(ctx context.Context, arg UpsertSubscriptionSeatsParams) (SubscriptionSeat, error) {
+	row := q.db.QueryRow(ctx, upsertSubscriptionSeats, arg.SubscriptionID, arg.Total, arg.MemberIds)
+	var i SubscriptionSeat
+	err := row.Scan(
+		&i.SubscriptionID,
+		&i.Total,
+		&i.MemberIds,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertTenantTheme = `-- name: UpsertTenantTheme :exec
+INSERT INTO tenant_themes (tenant_id, logo_url, color, footer, updated_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (tenant_id) DO UPDATE
+SET
+    logo_url = EXCLUDED.logo_url,
+    color = EXCLUDED.color,
+    footer = EXCLUDED.footer,
+    updated_at = EXCLUDED.updated_at
+`
+
+type UpsertTenantThemeParams struct {
+	TenantID  string    `json:"tenant_id"`
+	LogoUrl   string    `json:"logo_url"`
+	Color     string    `json:"color"`
+	Footer    string    `json:"footer"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (q *Queries) UpsertTenantTheme(ctx context.Context, arg UpsertTenantThemeParams) error {
+	_, err := q.db.Exec(ctx, upsertTenantTheme,
+		arg.TenantID,
+		arg.LogoUrl,
+		arg.Color,
+		arg.Footer,
+		arg.UpdatedAt,
+	)
+	return err
+}
+
+const upsertUserSettings = `-- name: UpsertUserSettings :one
+INSERT INTO user_settings (user_id, currency, locale, first_day_of_week, date_format, timezone, share_price_stats, monthly_goal)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (user_id) DO UPDATE
+SET
+    currency = EXCLUDED.currency,
+    locale = EXCLUDED.locale,
+    first_day_of_week = EXCLUDED.first_day_of_week,
+    date_format = EXCLUDED.date_format,
+    timezone = EXCLUDED.timezone,
+    share_price_stats = EXCLUDED.share_price_stats,
+    monthly_goal = EXCLUDED.monthly_goal,
+    updated_at = now()
+RETURNING user_id, currency, locale, first_day_of_week, date_format, updated_at, timezone, share_price_stats, monthly_goal
+`
+
+type UpsertUserSettingsParams struct {
+	UserID          string `json:"user_id"`
+	Currency        string `json:"currency"`
+	Locale          string `json:"locale"`
+	FirstDayOfWeek  int16  `json:"first_day_of_week"`
+	DateFormat      string `json:"date_format"`
+	Timezone        string `json:"timezone"`
+	SharePriceStats bool   `json:"share_price_stats"`
+	MonthlyGoal     int64  `json:"monthly_goal"`
+}
+
+func (q *Queries) UpsertUserSettings(ctx context.Context, arg UpsertUserSettingsParams) (UserSetting, error) {
+	row := q.db.QueryRow(ctx, upsertUserSettings,
+		arg.UserID,
+		arg.Currency,
+		arg.Locale,
+		arg.FirstDayOfWeek,
+		arg.DateFormat,
+		arg.Timezone,
+		arg.SharePriceStats,
+		arg.MonthlyGoal,
+	)
+	var i UserSetting
+	err := row.Scan(
+		&i.UserID,
+		&i.Currency,
+		&i.Locale,
+		&i.FirstDayOfWeek,
+		&i.DateFormat,
+		&i.UpdatedAt,
+		&i.Timezone,
+		&i.SharePriceStats,
+		&i.MonthlyGoal,
+	)
+	return i, err
+}
+
+const upsertUserWebhook = `-- name: UpsertUserWebhook :exec
+INSERT INTO user_webhooks (user_id, url, format, secret, created_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id) DO UPDATE
+SET url = EXCLUDED.url, format = EXCLUDED.format, secret = EXCLUDED.secret, created_at = EXCLUDED.created_at
+`
+
+type UpsertUserWebhookParams struct {
+	UserID    string    `json:"user_id"`
+	Url       string    `json:"url"`
+	Format    string    `json:"format"`
+	Secret    string    `json:"secret"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (q *Queries) UpsertUserWebhook(ctx context.Context, arg UpsertUserWebhookParams) error {
+	_, err := q.db.Exec(ctx, upsertUserWebhook,
+		arg.UserID,
+		arg.Url,
+		arg.Format,
+		arg.Secret,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const userDataVersion = `-- name: UserDataVersion :one
+SELECT COALESCE(SUM(version), 0)::bigint AS version,
+       COALESCE(MAX(changed_at), to_timestamp(0))::timestamptz AS changed_at
+FROM user_versions
+WHERE $1::uuid IS NULL OR user_id = $1::uuid
+`
+
+type UserDataVersionRow struct {
+	Version   int64     `json:"version"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+func (q *Queries) UserDataVersion(ctx context.Context, userID pgtype.UUID) (UserDataVersionRow, error) {
+	row := q.db.QueryRow(ctx, userDataVersion, userID)
+	var i UserDataVersionRow
+	err := row.Scan(&i.Version, &i.ChangedAt)
+	return i, err
+}
+
+const userMonthlySpend = `-- name: UserMonthlySpend :many
+SELECT
+    s.user_id,
+    m.month::date AS month,
+    COALESCE(SUM(s.cost), 0)::bigint AS total
+FROM generate_series($1::date, $2::date, interval '1 month') AS m(month)
+JOIN subscriptions s
+  ON s.start_date <= m.month
+ AND (s.end_date IS NULL OR s.end_date >= m.month)
+WHERE NOT EXISTS (SELECT 1 FROM user_deactivations d WHERE d.user_id = s.user_id)
+GROUP BY s.user_id, m.month
+ORDER BY s.user_id, m.month
+`
+
+type UserMonthlySpendParams struct {
+	FromMonth time.Time `json:"from_month"`
+	ToMonth   time.Time `json:"to_month"`
+}
+
+type UserMonthlySpendRow struct {
+	UserID string    `json:"user_id"`
+	Month  time.Time `json:"month"`
+	Total  int64     `json:"total"`
+}
+
+func (q *Queries) UserMonthlySpend(ctx context.Context, arg UserMonthlySpendParams) ([]UserMonthlySpendRow, error) {
+	rows, err := q.db.Query(ctx, userMonthlySpend, arg.FromMonth, arg.ToMonth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UserMonthlySpendRow
+	for rows.Next() {
+		var i UserMonthlySpendRow
+		if err := rows.Scan(&i.UserID, &i.Month, &i.Total); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const userMonthlySpendFromChanges = `-- name: UserMonthlySpendFromChanges :many
+SELECT
+    c.user_id,
+    m.month::date AS month,
+    SUM(c.cost_delta)::bigint AS total
+FROM generate_series($1::date, $2::date, interval '1 month') AS m(month)
+JOIN user_spend_changes c ON c.month <= m.month
+GROUP BY c.user_id, m.month
+HAVING SUM(c.subs_delta) > 0
+ORDER BY c.user_id, m.month
+`
+
+type UserMonthlySpendFromChangesParams struct {
+	FromMonth time.Time `json:"from_month"`
+	ToMonth   time.Time `json:"to_month"`
+}
+
+type UserMonthlySpendFromChangesRow struct {
+	UserID string    `json:"user_id"`
+	Month  time.Time `json:"month"`
+	Total  int64     `json:"total"`
+}
+
+func (q *Queries) UserMonthlySpendFromChanges(ctx context.Context, arg UserMonthlySpendFromChangesParams) ([]UserMonthlySpendFromChangesRow, error) {
+	rows, err := q.db.Query(ctx, userMonthlySpendFromChanges, arg.FromMonth, arg.ToMonth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UserMonthlySpendFromChangesRow
+	for rows.Next() {
+		var i UserMonthlySpendFromChangesRow
+		if err := rows.Scan(&i.UserID, &i.Month, &i.Total); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
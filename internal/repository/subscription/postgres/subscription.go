@@ -2,39 +2,65 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
 	"time"
 
-	"github.com/go-openapi/strfmt"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"subs_tracker/internal/entity"
 	"subs_tracker/internal/repository/subscription/postgres/sqlc"
 	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/dates"
+	"subs_tracker/pkg/pagination"
 )
 
+// likeEscaper makes a value match itself in a LIKE pattern, backslash being the default escape character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 // SubRepository wraps a pgx pool and sqlc-generated Queries to persist subscriptions
 type SubRepository struct {
 	pool    *pgxpool.Pool
 	queries *sqlc.Queries
 }
 
-const defaultListLimit = 50
-
-// NewSubRepository creates a repository bound to the given pgx connection pool
-func NewSubRepository(pool *pgxpool.Pool) *SubRepository {
-	return &SubRepository{
+// NewSubRepository creates a repository bound to the given pgx connection pool and applies options
+func NewSubRepository(pool *pgxpool.Pool, options ...func(*SubRepository)) *SubRepository {
+	r := &SubRepository{
 		pool:    pool,
 		queries: sqlc.New(pool),
 	}
+	for _, o := range options {
+		o(r)
+	}
+	return r
+}
+
+// WithExplain returns an option that logs EXPLAIN (ANALYZE, BUFFERS) plans of list/cost queries slower than threshold.
+// The plans are taken in the background, each query at most once a minute.
+func WithExplain(threshold time.Duration, log *slog.Logger) func(*SubRepository) {
+	return func(r *SubRepository) {
+		if threshold <= 0 || log == nil {
+			return
+		}
+		r.queries = sqlc.New(&explainDB{
+			DBTX:      r.pool,
+			threshold: threshold,
+			log:       log,
+		})
+	}
 }
 
 // SaveSub inserts a new subscription via sqlc and returns the created entity
 func (r *SubRepository) SaveSub(ctx context.Context, sub *entity.Subscription) (*entity.Subscription, error) {
-	if sub == nil {
+	if sub == nil || sub.UserID.IsZero() {
 		return nil, fmt.Errorf("save sub: %w", usecase.ErrInvalidSubscription)
 	}
 
@@ -47,17 +73,41 @@ func (r *SubRepository) SaveSub(ctx context.Context, sub *entity.Subscription) (
 	if sub.DateTo != nil {
 		params.EndDate = sub.DateTo
 	}
+	if !sub.PublicID.IsZero() {
+		params.PublicID = pgtype.UUID{Bytes: sub.PublicID, Valid: true}
+	}
 
-	out, err := r.queries.CreateSubscription(ctx, params)
+	out, err := r.q(ctx).CreateSubscription(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("save sub: %w", err)
+		return nil, fmt.Errorf("save sub: %w", constraintErr(err))
 	}
 	return toEntity(out), nil
 }
 
+// SaveImportedSub inserts the subscription and its import key, or reads the subscription the key was stored
+// with; a key whose subscription was deleted went away with it, so the subscription is created again
+func (r *SubRepository) SaveImportedSub(ctx context.Context, key string, sub *entity.Subscription) (*entity.Subscription, bool, error) {
+	id, err := r.q(ctx).GetImportKey(ctx, key)
+	switch {
+	case err == nil:
+		out, err := r.GetSubByID(ctx, id)
+		return out, false, err
+	case !errors.Is(err, pgx.ErrNoRows):
+		return nil, false, fmt.Errorf("get import key: %w", err)
+	}
+	out, err := r.SaveSub(ctx, sub)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := r.q(ctx).InsertImportKey(ctx, sqlc.InsertImportKeyParams{Key: key, SubscriptionID: out.ID}); err != nil {
+		return nil, false, fmt.Errorf("save import key: %w", constraintErr(err))
+	}
+	return out, true, nil
+}
+
 // UpdateSub updates an existing subscription by ID and reports not-found if no rows were affected
 func (r *SubRepository) UpdateSub(ctx context.Context, sub *entity.Subscription) error {
-	if sub == nil {
+	if sub == nil || sub.UserID.IsZero() {
 		return fmt.Errorf("update sub: %w", usecase.ErrInvalidSubscription)
 	}
 
@@ -71,32 +121,249 @@ func (r *SubRepository) UpdateSub(ctx context.Context, sub *entity.Subscription)
 	if sub.DateTo != nil {
 		params.EndDate = sub.DateTo
 	}
+	if !sub.UpdatedAt.IsZero() {
+		params.IfUpdatedAt = &sub.UpdatedAt
+	}
 
-	rows, err := r.queries.UpdateSubscription(ctx, params)
+	rows, err := r.q(ctx).UpdateSubscription(ctx, params)
 	if err != nil {
-		return fmt.Errorf("update sub: %w", err)
+		return fmt.Errorf("update sub: %w", constraintErr(err))
 	}
 	if rows == 0 {
-		return usecase.ErrSubscriptionNotFound
+		return r.missedWrite(ctx, sub.ID, params.IfUpdatedAt != nil)
 	}
 	return nil
 }
 
-// DeleteSub removes a subscription by ID and reports not-found if no rows were affected
-func (r *SubRepository) DeleteSub(ctx context.Context, id int64) error {
-	rows, err := r.queries.DeleteSubscription(ctx, id)
+// DeleteSub removes a subscription by ID, only if it is still at version when version is non-zero
+func (r *SubRepository) DeleteSub(ctx context.Context, id int64, version time.Time) error {
+	params := sqlc.DeleteSubscriptionParams{ID: id}
+	if !version.IsZero() {
+		params.IfUpdatedAt = &version
+	}
+	rows, err := r.q(ctx).DeleteSubscription(ctx, params)
 	if err != nil {
 		return fmt.Errorf("delete sub: %w", err)
 	}
 	if rows == 0 {
-		return usecase.ErrSubscriptionNotFound
+		return r.missedWrite(ctx, id, params.IfUpdatedAt != nil)
+	}
+	return nil
+}
+
+// EndedBefore returns up to limit subscriptions whose end_date is before the given month, oldest IDs first
+func (r *SubRepository) EndedBefore(ctx context.Context, before time.Time, limit int) ([]*entity.Subscription, error) {
+	rows, err := r.q(ctx).ListSubscriptionsEndedBefore(ctx, sqlc.ListSubscriptionsEndedBeforeParams{
+		Before:    before,
+		PageLimit: int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ended before: %w", err)
+	}
+	out := make([]*entity.Subscription, 0, len(rows))
+	for _, item := range rows {
+		out = append(out, toEntity(item))
+	}
+	return out, nil
+}
+
+// PurgeSubs deletes the subscriptions with the given IDs unconditionally and reports how many existed
+func (r *SubRepository) PurgeSubs(ctx context.Context, ids []int64) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	n, err := r.q(ctx).DeleteSubscriptionsByIDs(ctx, ids)
+	if err != nil {
+		return 0, fmt.Errorf("purge subs: %w", err)
+	}
+	return n, nil
+}
+
+// SaveAdjustment stores an adjustment and moves the version of its subscription in the same statement;
+// ErrSubscriptionNotFound if the subscription does not exist
+func (r *SubRepository) SaveAdjustment(ctx context.Context, a *entity.Adjustment) (*entity.Adjustment, error) {
+	if a == nil || a.SubscriptionID <= 0 {
+		return nil, fmt.Errorf("save adjustment: %w", usecase.ErrInvalidID)
+	}
+	row, err := r.q(ctx).InsertSubscriptionAdjustment(ctx, sqlc.InsertSubscriptionAdjustmentParams{
+		SubscriptionID: a.SubscriptionID,
+		Month:          a.Month,
+		Amount:         a.Amount,
+		Note:           a.Note,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.Is(err, pgx.ErrNoRows) || errors.As(err, &pgErr) && pgErr.Code == pgForeignKeyViolation {
+			return nil, usecase.ErrSubscriptionNotFound
+		}
+		return nil, fmt.Errorf("save adjustment: %w", constraintErr(err))
+	}
+	return toAdjustment(row), nil
+}
+
+// ListAdjustments returns the adjustments of the subscription ordered by month, then ID
+func (r *SubRepository) ListAdjustments(ctx context.Context, subID int64) ([]entity.Adjustment, error) {
+	rows, err := r.q(ctx).ListSubscriptionAdjustments(ctx, subID)
+	if err != nil {
+		return nil, fmt.Errorf("list adjustments: %w", err)
+	}
+	out := make([]entity.Adjustment, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, *toAdjustment(row))
+	}
+	return out, nil
+}
+
+// SaveSeats creates or replaces the seats of a subscription, deleting them when Total is 0;
+// ErrSubscriptionNotFound if the subscription does not exist
+func (r *SubRepository) SaveSeats(ctx context.Context, s *entity.Seats) (*entity.Seats, error) {
+	if s == nil || s.SubscriptionID <= 0 {
+		return nil, fmt.Errorf("save seats: %w", usecase.ErrInvalidID)
+	}
+	if s.Total == 0 {
+		if err := r.q(ctx).DeleteSubscriptionSeats(ctx, s.SubscriptionID); err != nil {
+			return nil, fmt.Errorf("save seats: %w", err)
+		}
+		return &entity.Seats{SubscriptionID: s.SubscriptionID}, nil
+	}
+	members := make([]string, 0, len(s.UserIDs))
+	for _, id := range s.UserIDs {
+		members = append(members, id.String())
+	}
+	row, err := r.q(ctx).UpsertSubscriptionSeats(ctx, sqlc.UpsertSubscriptionSeatsParams{
+		SubscriptionID: s.SubscriptionID,
+		Total:          int32(s.Total),
+		MemberIds:      members,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgForeignKeyViolation {
+			return nil, usecase.ErrSubscriptionNotFound
+		}
+		return nil, fmt.Errorf("save seats: %w", constraintErr(err))
+	}
+	return toSeats(row), nil
+}
+
+// GetSeats returns the seats of the subscription, nil when it is not shared
+func (r *SubRepository) GetSeats(ctx context.Context, subID int64) (*entity.Seats, error) {
+	row, err := r.q(ctx).GetSubscriptionSeats(ctx, subID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get seats: %w", err)
+	}
+	return toSeats(row), nil
+}
+
+// SeatSharesByFilter returns the seats the user of the filter holds in shared plans overlapping its period
+func (r *SubRepository) SeatSharesByFilter(ctx context.Context, f usecase.SubFilter) ([]usecase.SeatShare, error) {
+	if f.Period == nil || f.Period.From.IsZero() || f.Period.To.IsZero() {
+		return nil, fmt.Errorf("seat shares by filter: %w", usecase.ErrInvalidPeriod)
+	}
+	if f.UserID.IsZero() {
+		return nil, fmt.Errorf("seat shares by filter: %w", entity.ErrInvalidUserID)
+	}
+	params := sqlc.ListSeatSharesParams{
+		UserID:     f.UserID.String(),
+		PeriodTo:   f.Period.To,
+		PeriodFrom: f.Period.From,
+	}
+	if f.ServiceName != nil {
+		params.ServiceName = pgtype.Text{String: *f.ServiceName, Valid: true}
+	}
+	rows, err := r.q(ctx).ListSeatShares(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("seat shares by filter: %w", err)
+	}
+	out := make([]usecase.SeatShare, 0, len(rows))
+	for _, row := range rows {
+		pid, _ := uuid.Parse(row.PublicID)
+		out = append(out, usecase.SeatShare{
+			SubscriptionID: row.ID,
+			PublicID:       entity.PublicID(pid),
+			ServiceName:    row.ServiceName,
+			Cost:           row.Cost,
+			Seats:          int(row.Total),
+		})
+	}
+	return out, nil
+}
+
+// PendingLegacyCosts counts subscriptions whose currency and cost_minor are not filled in yet
+func (r *SubRepository) PendingLegacyCosts(ctx context.Context) (int64, error) {
+	n, err := r.q(ctx).CountLegacyCostSubscriptions(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("pending legacy costs: %w", err)
+	}
+	return n, nil
+}
+
+// BackfillLegacyCosts sets currency and cost_minor of up to limit legacy subscriptions from their ruble cost,
+// lowest IDs first. updated_at is kept, so versions and ETags held by clients stay valid; rows locked by
+// a concurrent write are skipped and picked up by a later batch
+func (r *SubRepository) BackfillLegacyCosts(ctx context.Context, limit int) (int64, error) {
+	n, err := r.q(ctx).BackfillLegacyCosts(ctx, int32(limit))
+	if err != nil {
+		return 0, fmt.Errorf("backfill legacy costs: %w", err)
+	}
+	return n, nil
+}
+
+// SavePseudonym stores the sealed real user ID of a pseudonym; a pseudonym already stored is kept
+func (r *SubRepository) SavePseudonym(ctx context.Context, pseudonym entity.UserID, sealed string) error {
+	err := r.q(ctx).InsertUserPseudonym(ctx, sqlc.InsertUserPseudonymParams{
+		Pseudonym: pseudonym.String(),
+		UserIDEnc: sealed,
+	})
+	if err != nil {
+		return fmt.Errorf("save pseudonym: %w", err)
 	}
 	return nil
 }
 
+// SealedUserIDs returns the sealed real user IDs of the known pseudonyms
+func (r *SubRepository) SealedUserIDs(ctx context.Context, pseudonyms []entity.UserID) (map[entity.UserID]string, error) {
+	if len(pseudonyms) == 0 {
+		return map[entity.UserID]string{}, nil
+	}
+	ids := make([]string, 0, len(pseudonyms))
+	for _, p := range pseudonyms {
+		ids = append(ids, p.String())
+	}
+	rows, err := r.q(ctx).ListUserPseudonyms(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("list pseudonyms: %w", err)
+	}
+	out := make(map[entity.UserID]string, len(rows))
+	for _, row := range rows {
+		p, err := entity.ParseUserID(row.Pseudonym)
+		if err != nil {
+			return nil, fmt.Errorf("list pseudonyms: %w", err)
+		}
+		out[p] = row.UserIDEnc
+	}
+	return out, nil
+}
+
+// missedWrite explains a write that affected no rows: a conditional write on an existing row lost the version race
+func (r *SubRepository) missedWrite(ctx context.Context, id int64, conditional bool) error {
+	if !conditional {
+		return usecase.ErrSubscriptionNotFound
+	}
+	if _, err := r.q(ctx).GetSubscription(ctx, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return usecase.ErrSubscriptionNotFound
+		}
+		return fmt.Errorf("check sub id=%d: %w", id, err)
+	}
+	return usecase.ErrPreconditionFailed
+}
+
 // GetSubByID fetches a subscription by its ID, mapping pgx.ErrNoRows to a domain not-found error
 func (r *SubRepository) GetSubByID(ctx context.Context, id int64) (*entity.Subscription, error) {
-	sub, err := r.queries.GetSubscription(ctx, id)
+	sub, err := r.q(ctx).GetSubscription(ctx, id)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, usecase.ErrSubscriptionNotFound
@@ -106,32 +373,52 @@ func (r *SubRepository) GetSubByID(ctx context.Context, id int64) (*entity.Subsc
 	return toEntity(sub), nil
 }
 
-// ListSubsByFilter converts a SubFilter to sqlc params (handling nullable fields) and returns matching rows
-func (r *SubRepository) ListSubsByFilter(ctx context.Context, f usecase.SubFilter) ([]*entity.Subscription, error) {
-	limit := f.Limit
-	if limit <= 0 {
-		limit = defaultListLimit
+// GetSubByIDForUpdate fetches a subscription like GetSubByID and locks its row until the InTx transaction of ctx
+// ends, so concurrent writers of the subscription wait for each other instead of the last one winning
+func (r *SubRepository) GetSubByIDForUpdate(ctx context.Context, id int64) (*entity.Subscription, error) {
+	tx, ok := r.tx(ctx)
+	if !ok {
+		return nil, fmt.Errorf("get sub for update id=%d: %w", id, errNoTx)
 	}
-	offset := f.Offset
-	if offset < 0 {
-		offset = 0
+	sub, err := r.queries.WithTx(tx).GetSubscriptionForUpdate(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, usecase.ErrSubscriptionNotFound
+		}
+		return nil, fmt.Errorf("get sub for update id=%d: %w", id, err)
+	}
+	return toEntity(sub), nil
+}
+
+// GetSubByPublicID fetches a subscription by its public ID, mapping pgx.ErrNoRows to a domain not-found error
+func (r *SubRepository) GetSubByPublicID(ctx context.Context, id entity.PublicID) (*entity.Subscription, error) {
+	sub, err := r.q(ctx).GetSubscriptionByPublicID(ctx, id.String())
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, usecase.ErrSubscriptionNotFound
+		}
+		return nil, fmt.Errorf("get sub by public id=%s: %w", id, err)
+	}
+	return toEntity(sub), nil
+}
+
+// ListSubsByFilter converts a SubFilter to sqlc params (handling nullable fields) and returns matching rows in
+// usecase.CompareListOrder; service names are sorted with the C collation whatever the database default, so
+// keyset cursors and the merges of shards and archive agree with the order of the rows
+func (r *SubRepository) ListSubsByFilter(ctx context.Context, f usecase.SubFilter) ([]*entity.Subscription, error) {
+	limit, offset, err := pagination.DefaultLimits().Normalize(f.Limit, max(f.Offset, 0))
+	if err != nil {
+		return nil, fmt.Errorf("list subs by filter: %w", err)
 	}
 
 	params := sqlc.ListSubscriptionsParams{
 		PageLimit:   int32(limit),
 		PageOffset:  int32(offset),
-		UserID:      pgtype.UUID{Valid: false},
+		UserID:      toPgUUID(f.UserID),
 		ServiceName: pgtype.Text{Valid: false},
 		PeriodFrom:  pgtype.Date{Valid: false},
 		PeriodTo:    pgtype.Date{Valid: false},
 	}
-	if f.UserID.String() != "" {
-		uid, err := toPgUUID(f.UserID.String())
-		if err != nil {
-			return nil, fmt.Errorf("list subs by filter: %w", err)
-		}
-		params.UserID = uid
-	}
 	if f.ServiceName != nil {
 		params.ServiceName = pgtype.Text{
 			String: *f.ServiceName,
@@ -152,8 +439,34 @@ func (r *SubRepository) ListSubsByFilter(ctx context.Context, f usecase.SubFilte
 			}
 		}
 	}
+	if f.After != nil {
+		params.AfterID = pgtype.Int8{Int64: f.After.ID, Valid: true}
+		params.AfterStartDate = pgtype.Date{Time: f.After.StartDate, Valid: true}
+		params.AfterServiceName = pgtype.Text{String: f.After.ServiceName, Valid: true}
+		params.PageOffset = 0
+	}
+	if f.UpdatedSince != nil {
+		params.UpdatedSince = f.UpdatedSince
+	}
+	w := f.Where
+	if w.CostMin != nil {
+		params.CostMin = pgtype.Int8{Int64: *w.CostMin, Valid: true}
+	}
+	if w.CostMax != nil {
+		params.CostMax = pgtype.Int8{Int64: *w.CostMax, Valid: true}
+	}
+	if w.ServiceContains != nil {
+		params.ServicePattern = pgtype.Text{String: "%" + likeEscaper.Replace(*w.ServiceContains) + "%", Valid: true}
+	}
+	if w.StartFrom != nil {
+		params.StartFrom = pgtype.Date{Time: *w.StartFrom, Valid: true}
+	}
+	if w.StartTo != nil {
+		params.StartTo = pgtype.Date{Time: *w.StartTo, Valid: true}
+	}
+	params.IncludeDeactivated = f.IncludeDeactivated
 
-	rows, err := r.queries.ListSubscriptions(ctx, params)
+	rows, err := r.q(ctx).ListSubscriptions(ctx, params)
 	if err != nil {
 		return nil, fmt.Errorf("list subs by filter: %w", err)
 	}
@@ -164,31 +477,361 @@ func (r *SubRepository) ListSubsByFilter(ctx context.Context, f usecase.SubFilte
 	return out, nil
 }
 
-// CostSubsByFilter validates the period and computes the total monthly cost using the aggregate sqlc query
+// CostSubsByFilter validates the period and computes the total monthly cost net of adjustments. Without a user
+// the cost of all users is summed by its own query, so the per-user query keeps an index-friendly plan
 func (r *SubRepository) CostSubsByFilter(ctx context.Context, f usecase.SubFilter) (int64, error) {
 	if f.Period == nil || f.Period.From.IsZero() || f.Period.To.IsZero() {
 		return 0, fmt.Errorf("cost subs by filter: %w", usecase.ErrInvalidPeriod)
 	}
-	params := sqlc.SumSubscriptionCostParams{
-		PeriodFrom: f.Period.From,
-		PeriodTo:   &f.Period.To,
+	var service pgtype.Text
+	if f.ServiceName != nil {
+		service = pgtype.Text{String: *f.ServiceName, Valid: true}
+	}
+
+	var (
+		total int64
+		err   error
+	)
+	if f.UserID.IsZero() {
+		total, err = r.q(ctx).SumAllSubscriptionsCost(ctx, sqlc.SumAllSubscriptionsCostParams{
+			PeriodFrom:  f.Period.From,
+			PeriodTo:    f.Period.To,
+			ServiceName: service,
+		})
+	} else {
+		total, err = r.q(ctx).SumSubscriptionCost(ctx, sqlc.SumSubscriptionCostParams{
+			PeriodFrom:  f.Period.From,
+			PeriodTo:    f.Period.To,
+			UserID:      f.UserID.String(),
+			ServiceName: service,
+		})
 	}
-	uid, err := toPgUUID(f.UserID.String())
 	if err != nil {
 		return 0, fmt.Errorf("cost subs by filter: %w", err)
 	}
-	params.UserID = uid
+	adjusted, err := r.q(ctx).SumAdjustments(ctx, sqlc.SumAdjustmentsParams{
+		PeriodFrom:  f.Period.From,
+		PeriodTo:    f.Period.To,
+		UserID:      toPgUUID(f.UserID),
+		ServiceName: service,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("cost subs by filter: adjustments: %w", err)
+	}
+	return total + adjusted, nil
+}
+
+// CostGroupedByFilter sums the monthly cost of matching subscriptions and their adjustments per value of by, in
+// a GROUP BY query each; the dimension is passed as a parameter the queries switch on, never spliced into the SQL
+func (r *SubRepository) CostGroupedByFilter(ctx context.Context, f usecase.SubFilter, by usecase.CostGroupBy) ([]usecase.CostGroup, error) {
+	if f.Period == nil || f.Period.From.IsZero() || f.Period.To.IsZero() {
+		return nil, fmt.Errorf("cost grouped by filter: %w", usecase.ErrInvalidPeriod)
+	}
+	if !by.Valid() {
+		return nil, fmt.Errorf("cost grouped by filter: %w", usecase.ErrInvalidGroupBy)
+	}
+	params := sqlc.SumSubscriptionCostGroupedParams{
+		GroupBy:    string(by),
+		PeriodFrom: f.Period.From,
+		PeriodTo:   f.Period.To,
+		UserID:     toPgUUID(f.UserID),
+	}
 	if f.ServiceName != nil {
-		params.ServiceName = pgtype.Text{
-			String: *f.ServiceName,
-			Valid:  true,
+		params.ServiceName = pgtype.Text{String: *f.ServiceName, Valid: true}
+	}
+	rows, err := r.q(ctx).SumSubscriptionCostGrouped(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("cost grouped by filter: %w", err)
+	}
+	adjusted, err := r.q(ctx).SumAdjustmentsGrouped(ctx, sqlc.SumAdjustmentsGroupedParams(params))
+	if err != nil {
+		return nil, fmt.Errorf("cost grouped by filter: adjustments: %w", err)
+	}
+
+	// both row sets are ordered by group_key: merge them, adjustments alone making a group with no subscriptions
+	out := make([]usecase.CostGroup, 0, len(rows))
+	for i, j := 0, 0; i < len(rows) || j < len(adjusted); {
+		var key string
+		var g usecase.CostGroup
+		switch {
+		case j == len(adjusted) || i < len(rows) && rows[i].GroupKey < adjusted[j].GroupKey:
+			key, g = rows[i].GroupKey, usecase.CostGroup{Total: rows[i].Total, Count: rows[i].Subscriptions}
+			i++
+		case i == len(rows) || adjusted[j].GroupKey < rows[i].GroupKey:
+			key, g = adjusted[j].GroupKey, usecase.CostGroup{Total: adjusted[j].Total}
+			j++
+		default:
+			key, g = rows[i].GroupKey, usecase.CostGroup{Total: rows[i].Total + adjusted[j].Total, Count: rows[i].Subscriptions}
+			i, j = i+1, j+1
 		}
+		switch by {
+		case usecase.CostByService:
+			g.ServiceName = key
+		case usecase.CostByUser:
+			if g.UserID, err = entity.ParseUserID(key); err != nil {
+				return nil, fmt.Errorf("cost grouped by filter: %w", err)
+			}
+		case usecase.CostByMonth, usecase.CostByWeek, usecase.CostByYear:
+			if g.Month, err = time.Parse(time.DateOnly, key); err != nil {
+				return nil, fmt.Errorf("cost grouped by filter: %w", err)
+			}
+		}
+		out = append(out, g)
 	}
-	total, err := r.queries.SumSubscriptionCost(ctx, params)
+	return out, nil
+}
+
+// CostSummaryByFilter returns the total together with the count and monthly cost aggregates in one query; the
+// adjustments of the period are added to the total only
+func (r *SubRepository) CostSummaryByFilter(ctx context.Context, f usecase.SubFilter) (usecase.CostSummary, error) {
+	if f.Period == nil || f.Period.From.IsZero() || f.Period.To.IsZero() {
+		return usecase.CostSummary{}, fmt.Errorf("cost summary by filter: %w", usecase.ErrInvalidPeriod)
+	}
+	params := sqlc.SubscriptionCostSummaryParams{
+		PeriodFrom: f.Period.From,
+		PeriodTo:   f.Period.To,
+		UserID:     toPgUUID(f.UserID),
+	}
+	if f.ServiceName != nil {
+		params.ServiceName = pgtype.Text{String: *f.ServiceName, Valid: true}
+	}
+	row, err := r.q(ctx).SubscriptionCostSummary(ctx, params)
 	if err != nil {
-		return 0, fmt.Errorf("cost subs by filter: %w", err)
+		return usecase.CostSummary{}, fmt.Errorf("cost summary by filter: %w", err)
+	}
+	adjusted, err := r.q(ctx).SumAdjustments(ctx, sqlc.SumAdjustmentsParams(params))
+	if err != nil {
+		return usecase.CostSummary{}, fmt.Errorf("cost summary by filter: adjustments: %w", err)
+	}
+	return usecase.CostSummary{
+		Total:       row.TotalCost + adjusted,
+		Count:       row.Subscriptions,
+		MonthlyCost: row.MonthlyCost,
+		MinCost:     row.MinCost,
+		MaxCost:     row.MaxCost,
+	}, nil
+}
+
+// DataVersion returns the version the triggers keep for the user, the sum over all users when user is zero
+func (r *SubRepository) DataVersion(ctx context.Context, user entity.UserID) (usecase.DataVersion, error) {
+	row, err := r.q(ctx).UserDataVersion(ctx, toPgUUID(user))
+	if err != nil {
+		return usecase.DataVersion{}, fmt.Errorf("data version: %w", err)
+	}
+	v := usecase.DataVersion{Version: row.Version, ChangedAt: row.ChangedAt}
+	if row.ChangedAt.Unix() == 0 {
+		v.ChangedAt = time.Time{}
 	}
-	return total, nil
+	return v, nil
+}
+
+// ChangesSince returns up to limit change log entries of the user recorded after the since position by
+// transactions that already finished, oldest first
+func (r *SubRepository) ChangesSince(ctx context.Context, user entity.UserID, since entity.ChangePos, limit int) ([]entity.SubscriptionChange, error) {
+	rows, err := r.q(ctx).ListSubscriptionChanges(ctx, sqlc.ListSubscriptionChangesParams{
+		UserID:    toPgUUID(user),
+		SinceTx:   since.Tx,
+		SinceSeq:  since.Seq,
+		PageLimit: int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("changes since tx=%d seq=%d: %w", since.Tx, since.Seq, err)
+	}
+	out := make([]entity.SubscriptionChange, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, entity.SubscriptionChange{
+			Tx:             row.Tx,
+			Seq:            row.Seq,
+			SubscriptionID: row.SubscriptionID,
+			UserID:         entity.UserID(row.UserID.Bytes),
+			PublicID:       entity.PublicID(row.PublicID.Bytes),
+			Op:             entity.ChangeOp(row.Op),
+			ChangedAt:      row.ChangedAt,
+		})
+	}
+	return out, nil
+}
+
+// ActiveStatsByService aggregates subscriptions active in the given month per service name
+func (r *SubRepository) ActiveStatsByService(ctx context.Context, month time.Time) ([]usecase.ServiceStats, error) {
+	rows, err := r.q(ctx).ActiveSubscriptionStats(ctx, month)
+	if err != nil {
+		return nil, fmt.Errorf("active stats by service: %w", err)
+	}
+	out := make([]usecase.ServiceStats, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, usecase.ServiceStats{
+			ServiceName: row.ServiceName,
+			Active:      row.Active,
+			MonthlyCost: row.MonthlyCost,
+		})
+	}
+	return out, nil
+}
+
+// PriceBenchmarks aggregates costs of the month per canonical service name over users who opted in
+// to share them, leaving out services with fewer than minUsers distinct users
+func (r *SubRepository) PriceBenchmarks(ctx context.Context, month time.Time, minUsers int) ([]usecase.PriceBenchmark, error) {
+	rows, err := r.q(ctx).PriceBenchmarks(ctx, sqlc.PriceBenchmarksParams{Month: month, MinUsers: int64(minUsers)})
+	if err != nil {
+		return nil, fmt.Errorf("price benchmarks: %w", err)
+	}
+	out := make([]usecase.PriceBenchmark, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, usecase.PriceBenchmark{
+			Service:       row.Service,
+			Users:         row.Users,
+			Subscriptions: row.Subscriptions,
+			AverageCost:   row.AverageCost,
+			MedianCost:    row.MedianCost,
+		})
+	}
+	return out, nil
+}
+
+// MonthlySpendByUser sums the cost of each user's subscriptions active in every month from..to;
+// months without any active subscription of the user are omitted
+func (r *SubRepository) MonthlySpendByUser(ctx context.Context, from, to time.Time) ([]usecase.UserMonthSpend, error) {
+	rows, err := r.q(ctx).UserMonthlySpend(ctx, sqlc.UserMonthlySpendParams{FromMonth: from, ToMonth: to})
+	if err != nil {
+		return nil, fmt.Errorf("monthly spend by user: %w", err)
+	}
+	out := make([]usecase.UserMonthSpend, 0, len(rows))
+	for _, row := range rows {
+		uid, err := entity.ParseUserID(row.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("monthly spend by user: %w", err)
+		}
+		out = append(out, usecase.UserMonthSpend{
+			UserID: uid,
+			Month:  dates.MonthStart(row.Month),
+			Total:  row.Total,
+		})
+	}
+	return out, nil
+}
+
+// ReassignUser moves every subscription of from to the user to and records the move in the admin audit log,
+// both in a single transaction
+func (r *SubRepository) ReassignUser(ctx context.Context, from, to entity.UserID, actor string) (int64, error) {
+	if from.IsZero() || to.IsZero() {
+		return 0, fmt.Errorf("reassign user: %w", entity.ErrInvalidUserID)
+	}
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("reassign user: begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	q := sqlc.New(tx)
+	moved, err := q.ReassignSubscriptionsUser(ctx, sqlc.ReassignSubscriptionsUserParams{
+		FromUserID: from.String(),
+		ToUserID:   to.String(),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("reassign user: %w", constraintErr(err))
+	}
+	details, err := json.Marshal(map[string]any{
+		"from_user_id": from.String(),
+		"to_user_id":   to.String(),
+		"moved":        moved,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("reassign user: audit details: %w", err)
+	}
+	if err := q.InsertAdminAudit(ctx, sqlc.InsertAdminAuditParams{
+		Action:  "reassign_user",
+		Actor:   actor,
+		Details: details,
+	}); err != nil {
+		return 0, fmt.Errorf("reassign user: audit: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("reassign user: commit: %w", err)
+	}
+	return moved, nil
+}
+
+// MergeSubs stores merged over its row and removes dropped, recording a snapshot of dropped in the admin audit log.
+// Both rows must still be at the versions they were read at, otherwise nothing changes
+func (r *SubRepository) MergeSubs(ctx context.Context, merged, dropped *entity.Subscription, actor string) error {
+	if merged == nil || dropped == nil || merged.UserID.IsZero() {
+		return fmt.Errorf("merge subs: %w", usecase.ErrInvalidSubscription)
+	}
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("merge subs: begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	q := sqlc.New(tx)
+	rows, err := q.UpdateSubscription(ctx, sqlc.UpdateSubscriptionParams{
+		ID:          merged.ID,
+		UserID:      merged.UserID.String(),
+		ServiceName: merged.ServiceName,
+		Cost:        merged.Cost,
+		StartDate:   merged.DateFrom,
+		EndDate:     merged.DateTo,
+		IfUpdatedAt: &merged.UpdatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("merge subs: update id=%d: %w", merged.ID, constraintErr(err))
+	}
+	if rows == 0 {
+		return usecase.ErrPreconditionFailed
+	}
+	// the spend history of the dropped subscription stays with the kept one instead of cascading away
+	if _, err := q.MoveSubscriptionAdjustments(ctx, sqlc.MoveSubscriptionAdjustmentsParams{
+		ToSubscriptionID:   merged.ID,
+		FromSubscriptionID: dropped.ID,
+	}); err != nil {
+		return fmt.Errorf("merge subs: move adjustments id=%d: %w", dropped.ID, err)
+	}
+	// so do the seats, unless the kept subscription is shared already
+	if _, err := q.MoveSubscriptionSeats(ctx, sqlc.MoveSubscriptionSeatsParams{
+		ToSubscriptionID:   merged.ID,
+		FromSubscriptionID: dropped.ID,
+	}); err != nil {
+		return fmt.Errorf("merge subs: move seats id=%d: %w", dropped.ID, constraintErr(err))
+	}
+	rows, err = q.DeleteSubscription(ctx, sqlc.DeleteSubscriptionParams{
+		ID:          dropped.ID,
+		IfUpdatedAt: &dropped.UpdatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("merge subs: delete id=%d: %w", dropped.ID, err)
+	}
+	if rows == 0 {
+		return usecase.ErrPreconditionFailed
+	}
+
+	details, err := json.Marshal(map[string]any{
+		"kept_id": merged.ID,
+		"archived": map[string]any{
+			"id":           dropped.ID,
+			"user_id":      dropped.UserID.String(),
+			"service_name": dropped.ServiceName,
+			"cost":         dropped.Cost,
+			"start_date":   dropped.DateFrom,
+			"end_date":     dropped.DateTo,
+			"created_at":   dropped.CreatedAt,
+			"updated_at":   dropped.UpdatedAt,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("merge subs: audit details: %w", err)
+	}
+	if err := q.InsertAdminAudit(ctx, sqlc.InsertAdminAuditParams{
+		Action:  "merge_subscriptions",
+		Actor:   actor,
+		Details: details,
+	}); err != nil {
+		return fmt.Errorf("merge subs: audit: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("merge subs: commit: %w", err)
+	}
+	return nil
 }
 
 // toEntity maps a sqlc row to the domain Subscription, handling a nullable end_date safely
@@ -198,21 +841,211 @@ func toEntity(s sqlc.Subscription) *entity.Subscription {
 		t := *s.EndDate
 		end = &t
 	}
+	// user_id and public_id are uuid columns, so the stored values always parse
+	uid, _ := uuid.Parse(s.UserID)
+	pid, _ := uuid.Parse(s.PublicID)
 	return &entity.Subscription{
 		ID:          s.ID,
-		UserID:      strfmt.UUID(s.UserID),
+		PublicID:    entity.PublicID(pid),
+		UserID:      entity.UserID(uid),
 		ServiceName: s.ServiceName,
 		Cost:        s.Cost,
 		DateFrom:    s.StartDate,
 		DateTo:      end,
+		CreatedAt:   s.CreatedAt,
+		UpdatedAt:   s.UpdatedAt,
+	}
+}
+
+// toAdjustment maps a sqlc row to the domain Adjustment
+func toAdjustment(a sqlc.SubscriptionAdjustment) *entity.Adjustment {
+	return &entity.Adjustment{
+		ID:             a.ID,
+		SubscriptionID: a.SubscriptionID,
+		Month:          a.Month,
+		Amount:         a.Amount,
+		Note:           a.Note,
+		CreatedAt:      a.CreatedAt,
+	}
+}
+
+// toSeats maps a sqlc row to the domain Seats
+func toSeats(s sqlc.SubscriptionSeat) *entity.Seats {
+	members := make([]entity.UserID, 0, len(s.MemberIds))
+	for _, id := range s.MemberIds {
+		// member_ids is a uuid[] column, so the stored values always parse
+		uid, _ := uuid.Parse(id)
+		members = append(members, entity.UserID(uid))
+	}
+	return &entity.Seats{
+		SubscriptionID: s.SubscriptionID,
+		Total:          int(s.Total),
+		UserIDs:        members,
+		UpdatedAt:      s.UpdatedAt,
+	}
+}
+
+// toPgUUID converts a UserID into pgtype.UUID, returning an invalid (NULL) value for the zero ID
+func toPgUUID(id entity.UserID) pgtype.UUID {
+	return pgtype.UUID{Bytes: id.UUID(), Valid: !id.IsZero()}
+}
+
+// GetSettings returns saved settings of the user or usecase.ErrSettingsNotFound
+func (r *SubRepository) GetSettings(ctx context.Context, userID entity.UserID) (*entity.Settings, error) {
+	row, err := r.q(ctx).GetUserSettings(ctx, userID.String())
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, usecase.ErrSettingsNotFound
+		}
+		return nil, fmt.Errorf("get settings: %w", err)
+	}
+	return settingsToEntity(row)
+}
+
+// SaveSettings creates or replaces settings of the user
+func (r *SubRepository) SaveSettings(ctx context.Context, s entity.Settings) (*entity.Settings, error) {
+	if s.UserID.IsZero() {
+		return nil, fmt.Errorf("save settings: %w", entity.ErrInvalidUserID)
+	}
+	row, err := r.q(ctx).UpsertUserSettings(ctx, sqlc.UpsertUserSettingsParams{
+		UserID:          s.UserID.String(),
+		Currency:        s.Currency,
+		Locale:          s.Locale,
+		FirstDayOfWeek:  int16(s.FirstDayOfWeek),
+		DateFormat:      s.DateFormat,
+		Timezone:        s.Timezone,
+		SharePriceStats: s.SharePriceStats,
+		MonthlyGoal:     s.MonthlyGoal,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("save settings: %w", constraintErr(err))
+	}
+	return settingsToEntity(row)
+}
+
+func settingsToEntity(row sqlc.UserSetting) (*entity.Settings, error) {
+	uid, err := entity.ParseUserID(row.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("settings user id: %w", err)
+	}
+	return &entity.Settings{
+		UserID:          uid,
+		Currency:        row.Currency,
+		Locale:          row.Locale,
+		FirstDayOfWeek:  time.Weekday(row.FirstDayOfWeek),
+		DateFormat:      row.DateFormat,
+		Timezone:        row.Timezone,
+		SharePriceStats: row.SharePriceStats,
+		MonthlyGoal:     row.MonthlyGoal,
+		UpdatedAt:       row.UpdatedAt,
+	}, nil
+}
+
+// DeactivateUser records the deactivation of the user, keeping the time of an earlier one
+func (r *SubRepository) DeactivateUser(ctx context.Context, userID entity.UserID, at time.Time) (time.Time, error) {
+	if userID.IsZero() {
+		return time.Time{}, fmt.Errorf("deactivate user: %w", entity.ErrInvalidUserID)
+	}
+	out, err := r.q(ctx).DeactivateUser(ctx, sqlc.DeactivateUserParams{UserID: userID.String(), DeactivatedAt: at})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("deactivate user: %w", err)
+	}
+	return out, nil
+}
+
+// ReactivateUser deletes the deactivation of the user, if any
+func (r *SubRepository) ReactivateUser(ctx context.Context, userID entity.UserID) error {
+	if err := r.q(ctx).ReactivateUser(ctx, userID.String()); err != nil {
+		return fmt.Errorf("reactivate user: %w", err)
+	}
+	return nil
+}
+
+// UserDeactivatedAt returns when the user was deactivated, zero time when they are active
+func (r *SubRepository) UserDeactivatedAt(ctx context.Context, userID entity.UserID) (time.Time, error) {
+	at, err := r.q(ctx).GetUserDeactivation(ctx, userID.String())
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return time.Time{}, nil
+	case err != nil:
+		return time.Time{}, fmt.Errorf("user deactivated at: %w", err)
 	}
+	return at, nil
 }
 
-// toPgUUID parses a string UUID into pgtype.UUID, returning an invalid value when the input is empty
-func toPgUUID(s string) (pgtype.UUID, error) {
-	var u pgtype.UUID
-	if s == "" {
-		return pgtype.UUID{Valid: false}, nil
+// DeleteUser removes the user as d says and records it in the admin audit log, all in a single transaction.
+// With DeleteUserBlock nothing changes while a subscription of the user has not ended by d.Since
+func (r *SubRepository) DeleteUser(ctx context.Context, userID entity.UserID, d usecase.UserDeletion, actor string) (int64, error) {
+	if userID.IsZero() {
+		return 0, fmt.Errorf("delete user: %w", entity.ErrInvalidUserID)
+	}
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("delete user: begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	q := sqlc.New(tx)
+	var n int64
+	switch d.Policy {
+	case usecase.DeleteUserBlock, usecase.DeleteUserCascade:
+		if d.Policy == usecase.DeleteUserBlock {
+			active, err := q.CountUnendedUserSubscriptions(ctx, sqlc.CountUnendedUserSubscriptionsParams{
+				UserID: userID.String(),
+				Since:  d.Since,
+			})
+			if err != nil {
+				return 0, fmt.Errorf("delete user: count active: %w", err)
+			}
+			if active > 0 {
+				return 0, usecase.ErrUserHasActiveSubs
+			}
+		}
+		// adjustments and seats of the subscriptions cascade with them
+		if n, err = q.DeleteUserSubscriptions(ctx, userID.String()); err != nil {
+			return 0, fmt.Errorf("delete user: subscriptions: %w", err)
+		}
+	case usecase.DeleteUserAnonymize:
+		if d.AnonymousID.IsZero() {
+			return 0, fmt.Errorf("delete user: anonymous id: %w", entity.ErrInvalidUserID)
+		}
+		if n, err = q.ReassignSubscriptionsUser(ctx, sqlc.ReassignSubscriptionsUserParams{
+			FromUserID: userID.String(),
+			ToUserID:   d.AnonymousID.String(),
+		}); err != nil {
+			return 0, fmt.Errorf("delete user: anonymize subscriptions: %w", err)
+		}
+	default:
+		return 0, fmt.Errorf("delete user: unknown policy %q", d.Policy)
+	}
+	if _, err := q.RemoveSeatMember(ctx, userID.String()); err != nil {
+		return 0, fmt.Errorf("delete user: seats: %w", err)
+	}
+	if err := q.DeleteUserSettings(ctx, userID.String()); err != nil {
+		return 0, fmt.Errorf("delete user: settings: %w", err)
+	}
+	if err := q.ReactivateUser(ctx, userID.String()); err != nil {
+		return 0, fmt.Errorf("delete user: deactivation: %w", err)
+	}
+
+	// the anonymous ID is left out on purpose, it must not lead back to the user
+	details, err := json.Marshal(map[string]any{
+		"user_id":       userID.String(),
+		"policy":        d.Policy,
+		"subscriptions": n,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("delete user: audit details: %w", err)
+	}
+	if err := q.InsertAdminAudit(ctx, sqlc.InsertAdminAuditParams{
+		Action:  "delete_user",
+		Actor:   actor,
+		Details: details,
+	}); err != nil {
+		return 0, fmt.Errorf("delete user: audit: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("delete user: commit: %w", err)
 	}
-	return u, u.Scan(s)
+	return n, nil
 }
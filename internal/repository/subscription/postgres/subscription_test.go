@@ -1,18 +1,21 @@
 package postgres
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
+	"sync"
 	"syscall"
 	"testing"
 	"time"
 
-	"github.com/go-openapi/strfmt"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
@@ -107,7 +110,7 @@ func TestSubRepository_SaveSub(t *testing.T) {
 	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
 	require.NoError(t, err)
 	pool, err := pgxpool.New(ctx, connStr)
-	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions RESTART IDENTITY`)
+	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions RESTART IDENTITY CASCADE`)
 	require.NoError(t, err)
 	defer pool.Close()
 	sr := NewSubRepository(pool)
@@ -123,7 +126,7 @@ func TestSubRepository_SaveSub(t *testing.T) {
 			Name: "valid test SaveSub, without DateTo",
 			ForSave: entity.Subscription{
 				ID:          0,
-				UserID:      strfmt.UUID(uid.String()),
+				UserID:      entity.UserID(uid),
 				ServiceName: "Skillbox",
 				Cost:        10_000,
 				DateFrom:    start,
@@ -161,12 +164,52 @@ func TestSubRepository_SaveSub(t *testing.T) {
 	}
 }
 
+func TestSubRepository_ConstraintErrors(t *testing.T) {
+	ctx := context.Background()
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions RESTART IDENTITY CASCADE`)
+	sr := NewSubRepository(pool)
+
+	start := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	before := start.AddDate(0, -1, 0)
+	publicID := entity.PublicID(uuid.New())
+	valid := entity.Subscription{UserID: entity.UserID(uuid.New()), ServiceName: "Netflix", Cost: 999, DateFrom: start, PublicID: publicID}
+	_, err = sr.SaveSub(ctx, &valid)
+	require.NoError(t, err)
+
+	tcases := []struct {
+		Name  string
+		Sub   entity.Subscription
+		Kind  error
+		Field string
+	}{
+		{Name: "negative cost", Sub: entity.Subscription{UserID: valid.UserID, ServiceName: "Netflix", Cost: -1, DateFrom: start}, Kind: usecase.ErrConstraint, Field: "cost"},
+		{Name: "end before start", Sub: entity.Subscription{UserID: valid.UserID, ServiceName: "Netflix", Cost: 1, DateFrom: start, DateTo: &before}, Kind: usecase.ErrConstraint, Field: "end_date"},
+		{Name: "start mid-month", Sub: entity.Subscription{UserID: valid.UserID, ServiceName: "Netflix", Cost: 1, DateFrom: start.AddDate(0, 0, 3)}, Kind: usecase.ErrConstraint, Field: "start_date"},
+		{Name: "public id taken", Sub: valid, Kind: usecase.ErrDuplicate, Field: "id"},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			_, err := sr.SaveSub(ctx, &tc.Sub)
+			require.ErrorIs(t, err, tc.Kind)
+			var ce *usecase.ConstraintError
+			require.ErrorAs(t, err, &ce)
+			assert.Equal(t, tc.Field, ce.Field)
+			assert.NotEmpty(t, ce.Constraint)
+		})
+	}
+}
+
 func TestSubRepository_UpdateSub(t *testing.T) {
 	ctx := context.Background()
 	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
 	require.NoError(t, err)
 	pool, err := pgxpool.New(ctx, connStr)
-	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions RESTART IDENTITY`)
+	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions RESTART IDENTITY CASCADE`)
 
 	require.NoError(t, err)
 	defer pool.Close()
@@ -186,7 +229,7 @@ func TestSubRepository_UpdateSub(t *testing.T) {
 			Name: "valid test UpdateSub",
 			ForSave: entity.Subscription{
 				ID:          0,
-				UserID:      strfmt.UUID(uid.String()),
+				UserID:      entity.UserID(uid),
 				ServiceName: "Skillbox",
 				Cost:        10_000,
 				DateFrom:    start,
@@ -194,7 +237,7 @@ func TestSubRepository_UpdateSub(t *testing.T) {
 			},
 			ForUpdate: entity.Subscription{
 				ID:          0,
-				UserID:      strfmt.UUID(uid.String()),
+				UserID:      entity.UserID(uid),
 				ServiceName: "SKILLBOX",
 				Cost:        100_000,
 				DateFrom:    afterStart,
@@ -237,12 +280,50 @@ func TestSubRepository_UpdateSub(t *testing.T) {
 	}
 }
 
+func TestSubRepository_ConditionalWrites(t *testing.T) {
+	ctx := context.Background()
+
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	r := NewSubRepository(pool)
+
+	start := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.UTC)
+	created, err := r.SaveSub(ctx, &entity.Subscription{
+		UserID:      entity.UserID(uuid.New()),
+		ServiceName: "Netflix",
+		Cost:        499,
+		DateFrom:    start,
+	})
+	require.NoError(t, err)
+	stale := created.UpdatedAt
+
+	created.Cost = 599
+	require.NoError(t, r.UpdateSub(ctx, created), "update at the current version")
+
+	created.Cost = 699
+	created.UpdatedAt = stale
+	assert.ErrorIs(t, r.UpdateSub(ctx, created), usecase.ErrPreconditionFailed)
+	assert.ErrorIs(t, r.DeleteSub(ctx, created.ID, stale), usecase.ErrPreconditionFailed)
+
+	current, err := r.GetSubByID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(599), current.Cost)
+
+	require.NoError(t, r.DeleteSub(ctx, created.ID, current.UpdatedAt))
+	assert.ErrorIs(t, r.DeleteSub(ctx, created.ID, current.UpdatedAt), usecase.ErrSubscriptionNotFound)
+}
+
 func TestSubRepository_DeleteSub(t *testing.T) {
 	ctx := context.Background()
 	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
 	require.NoError(t, err)
 	pool, err := pgxpool.New(ctx, connStr)
-	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions RESTART IDENTITY`)
+	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions RESTART IDENTITY CASCADE`)
 
 	require.NoError(t, err)
 	defer pool.Close()
@@ -259,7 +340,7 @@ func TestSubRepository_DeleteSub(t *testing.T) {
 			Name: "valid test DeleteSub",
 			ForSave: entity.Subscription{
 				ID:          0,
-				UserID:      strfmt.UUID(uid.String()),
+				UserID:      entity.UserID(uid),
 				ServiceName: "Skillbox",
 				Cost:        10_000,
 				DateFrom:    start,
@@ -271,7 +352,7 @@ func TestSubRepository_DeleteSub(t *testing.T) {
 			Name: "error test DeleteSub, not found",
 			ForSave: entity.Subscription{
 				ID:          0,
-				UserID:      strfmt.UUID(uid.String()),
+				UserID:      entity.UserID(uid),
 				ServiceName: "Skillbox",
 				Cost:        10_000,
 				DateFrom:    start,
@@ -291,7 +372,7 @@ func TestSubRepository_DeleteSub(t *testing.T) {
 			if tc.Error != nil {
 				delID = created.ID + 1
 			}
-			err = sr.DeleteSub(ctx, delID)
+			err = sr.DeleteSub(ctx, delID, time.Time{})
 			if tc.Error != nil {
 				assert.ErrorIs(t, err, tc.Error)
 				return
@@ -308,12 +389,235 @@ func TestSubRepository_DeleteSub(t *testing.T) {
 	}
 }
 
+func TestSubRepository_EndedBeforeAndPurge(t *testing.T) {
+	ctx := context.Background()
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions RESTART IDENTITY CASCADE`)
+	sr := NewSubRepository(pool)
+
+	uid := entity.UserID(uuid.New())
+	month := func(y int, m time.Month) *time.Time {
+		t := time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	var ids []int64
+	for _, end := range []*time.Time{month(2019, 5), nil, month(2020, 12), month(2021, 1)} {
+		created, err := sr.SaveSub(ctx, &entity.Subscription{
+			UserID: uid, ServiceName: "Okko", Cost: 399, DateFrom: *month(2019, 1), DateTo: end,
+		})
+		require.NoError(t, err)
+		ids = append(ids, created.ID)
+	}
+
+	got, err := sr.EndedBefore(ctx, *month(2021, 1), 10)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, []int64{ids[0], ids[2]}, []int64{got[0].ID, got[1].ID})
+
+	got, err = sr.EndedBefore(ctx, *month(2021, 1), 1)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+
+	n, err := sr.PurgeSubs(ctx, []int64{ids[0], ids[2], 999})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+	_, err = sr.GetSubByID(ctx, ids[0])
+	assert.ErrorIs(t, err, usecase.ErrSubscriptionNotFound)
+	_, err = sr.GetSubByID(ctx, ids[1])
+	assert.NoError(t, err)
+}
+
+func TestSubRepository_Adjustments(t *testing.T) {
+	ctx := context.Background()
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions RESTART IDENTITY CASCADE`)
+	sr := NewSubRepository(pool)
+
+	uid := entity.UserID(uuid.New())
+	month := func(m time.Month) time.Time {
+		return time.Date(2025, m, 1, 0, 0, 0, 0, time.UTC)
+	}
+	var subs []*entity.Subscription
+	for _, s := range []entity.Subscription{
+		{UserID: uid, ServiceName: "Netflix", Cost: 400, DateFrom: month(7)},
+		{UserID: uid, ServiceName: "Spotify", Cost: 200, DateFrom: month(7)},
+	} {
+		created, err := sr.SaveSub(ctx, &s)
+		require.NoError(t, err)
+		subs = append(subs, created)
+	}
+	_, err = sr.SaveAdjustment(ctx, &entity.Adjustment{SubscriptionID: 999, Month: month(8), Amount: -1})
+	assert.ErrorIs(t, err, usecase.ErrSubscriptionNotFound)
+	for _, a := range []entity.Adjustment{
+		{SubscriptionID: subs[0].ID, Month: month(8), Amount: -400, Note: "refund"},
+		{SubscriptionID: subs[1].ID, Month: month(7), Amount: 50},
+		{SubscriptionID: subs[1].ID, Month: month(9), Amount: -100},
+	} {
+		before, err := sr.GetSubByID(ctx, a.SubscriptionID)
+		require.NoError(t, err)
+		saved, err := sr.SaveAdjustment(ctx, &a)
+		require.NoError(t, err)
+		assert.NotZero(t, saved.ID)
+		assert.False(t, saved.CreatedAt.IsZero())
+		after, err := sr.GetSubByID(ctx, a.SubscriptionID)
+		require.NoError(t, err)
+		assert.True(t, after.UpdatedAt.After(before.UpdatedAt), "an adjustment moves the version of its subscription")
+	}
+	summer := &usecase.Period{From: month(7), To: month(8)}
+
+	total, err := sr.CostSubsByFilter(ctx, usecase.SubFilter{UserID: uid, Period: summer})
+	require.NoError(t, err)
+	assert.EqualValues(t, 850, total)
+	total, err = sr.CostSubsByFilter(ctx, usecase.SubFilter{Period: summer})
+	require.NoError(t, err)
+	assert.EqualValues(t, 850, total, "all users")
+	sum, err := sr.CostSummaryByFilter(ctx, usecase.SubFilter{UserID: uid, Period: summer})
+	require.NoError(t, err)
+	assert.Equal(t, usecase.CostSummary{Total: 850, Count: 2, MonthlyCost: 600, MinCost: 200, MaxCost: 400}, sum)
+	groups, err := sr.CostGroupedByFilter(ctx, usecase.SubFilter{UserID: uid, Period: &usecase.Period{From: month(7), To: month(9)}}, usecase.CostByMonth)
+	require.NoError(t, err)
+	assert.Equal(t, []usecase.CostGroup{
+		{Month: month(7), Total: 650, Count: 2},
+		{Month: month(8), Total: 200, Count: 2},
+		{Month: month(9), Total: 500, Count: 2},
+	}, groups)
+
+	require.NoError(t, sr.MergeSubs(ctx, subs[0], subs[1], "test"))
+	list, err := sr.ListAdjustments(ctx, subs[0].ID)
+	require.NoError(t, err)
+	require.Len(t, list, 3, "merging keeps the adjustments of the dropped subscription")
+	assert.Equal(t, month(7), list[0].Month)
+	require.NoError(t, sr.DeleteSub(ctx, subs[0].ID, time.Time{}))
+	list, err = sr.ListAdjustments(ctx, subs[0].ID)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestSubRepository_Seats(t *testing.T) {
+	ctx := context.Background()
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions RESTART IDENTITY CASCADE`)
+	sr := NewSubRepository(pool)
+
+	owner, member, other := entity.UserID(uuid.New()), entity.UserID(uuid.New()), entity.UserID(uuid.New())
+	month := func(m time.Month) time.Time {
+		return time.Date(2025, m, 1, 0, 0, 0, 0, time.UTC)
+	}
+	var subs []*entity.Subscription
+	for _, s := range []entity.Subscription{
+		{UserID: owner, ServiceName: "Spotify", Cost: 300, DateFrom: month(7)},
+		{UserID: owner, ServiceName: "Netflix", Cost: 1000, DateFrom: month(9)},
+	} {
+		created, err := sr.SaveSub(ctx, &s)
+		require.NoError(t, err)
+		subs = append(subs, created)
+	}
+	_, err = sr.SaveSeats(ctx, &entity.Seats{SubscriptionID: 999, Total: 2})
+	assert.ErrorIs(t, err, usecase.ErrSubscriptionNotFound)
+	seats, err := sr.GetSeats(ctx, subs[0].ID)
+	require.NoError(t, err)
+	assert.Nil(t, seats)
+	for _, sub := range subs {
+		saved, err := sr.SaveSeats(ctx, &entity.Seats{SubscriptionID: sub.ID, Total: 3, UserIDs: []entity.UserID{member}})
+		require.NoError(t, err)
+		assert.Equal(t, []entity.UserID{member}, saved.UserIDs)
+		assert.False(t, saved.UpdatedAt.IsZero())
+	}
+	_, err = sr.SaveSeats(ctx, &entity.Seats{SubscriptionID: subs[0].ID, Total: 4, UserIDs: []entity.UserID{member, other}})
+	require.NoError(t, err)
+	seats, err = sr.GetSeats(ctx, subs[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, 4, seats.Total, "replaced")
+	assert.Equal(t, []entity.UserID{member, other}, seats.UserIDs)
+
+	shares, err := sr.SeatSharesByFilter(ctx, usecase.SubFilter{UserID: member, Period: &usecase.Period{From: month(7), To: month(8)}})
+	require.NoError(t, err)
+	assert.Equal(t, []usecase.SeatShare{{SubscriptionID: subs[0].ID, ServiceName: "Spotify", Cost: 300, Seats: 4}}, shares)
+	shares, err = sr.SeatSharesByFilter(ctx, usecase.SubFilter{UserID: member, Period: &usecase.Period{From: month(7), To: month(9)}})
+	require.NoError(t, err)
+	require.Len(t, shares, 2)
+	assert.Equal(t, "Netflix", shares[0].ServiceName)
+
+	_, err = sr.SaveSeats(ctx, &entity.Seats{SubscriptionID: subs[0].ID})
+	require.NoError(t, err)
+	seats, err = sr.GetSeats(ctx, subs[0].ID)
+	require.NoError(t, err)
+	assert.Nil(t, seats, "a total of 0 stops sharing")
+	require.NoError(t, sr.DeleteSub(ctx, subs[1].ID, time.Time{}))
+	seats, err = sr.GetSeats(ctx, subs[1].ID)
+	require.NoError(t, err)
+	assert.Nil(t, seats)
+}
+
+func TestSubRepository_BackfillLegacyCosts(t *testing.T) {
+	ctx := context.Background()
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions RESTART IDENTITY CASCADE`)
+	sr := NewSubRepository(pool)
+
+	created, err := sr.SaveSub(ctx, &entity.Subscription{
+		UserID: entity.UserID(uuid.New()), ServiceName: "Okko", Cost: 399,
+		DateFrom: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	// rows written before the columns existed
+	_, err = pool.Exec(ctx, `INSERT INTO subscriptions (user_id, service_name, cost, start_date)
+		SELECT gen_random_uuid(), 'Kion', 100 + g, DATE '2024-01-01' FROM generate_series(1, 3) AS g`)
+	require.NoError(t, err)
+
+	pending, err := sr.PendingLegacyCosts(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, pending, "new rows are written with both columns")
+
+	var changes int64
+	require.NoError(t, pool.QueryRow(ctx, `SELECT count(*) FROM subscription_changes`).Scan(&changes))
+	n, err := sr.BackfillLegacyCosts(ctx, 2)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+	n, err = sr.BackfillLegacyCosts(ctx, 2)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+	n, err = sr.BackfillLegacyCosts(ctx, 2)
+	require.NoError(t, err)
+	assert.Zero(t, n, "a finished backfill is a no-op")
+
+	var currency string
+	var minor int64
+	require.NoError(t, pool.QueryRow(ctx, `SELECT currency, cost_minor FROM subscriptions WHERE id = $1`, created.ID+3).
+		Scan(&currency, &minor))
+	assert.Equal(t, "RUB", currency)
+	assert.EqualValues(t, 10300, minor)
+
+	var after int64
+	require.NoError(t, pool.QueryRow(ctx, `SELECT count(*) FROM subscription_changes`).Scan(&after))
+	assert.Equal(t, changes, after, "the backfill is not a change of the subscriptions")
+	got, err := sr.GetSubByID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created.UpdatedAt, got.UpdatedAt)
+}
+
 func TestSubRepository_GetSubByID(t *testing.T) {
 	ctx := context.Background()
 	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
 	require.NoError(t, err)
 	pool, err := pgxpool.New(ctx, connStr)
-	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions RESTART IDENTITY`)
+	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions RESTART IDENTITY CASCADE`)
 
 	require.NoError(t, err)
 	defer pool.Close()
@@ -330,7 +634,7 @@ func TestSubRepository_GetSubByID(t *testing.T) {
 			Name: "valid test GetSubByID",
 			ForSave: entity.Subscription{
 				ID:          0,
-				UserID:      strfmt.UUID(uid.String()),
+				UserID:      entity.UserID(uid),
 				ServiceName: "Skillbox",
 				Cost:        10_000,
 				DateFrom:    start,
@@ -342,7 +646,7 @@ func TestSubRepository_GetSubByID(t *testing.T) {
 			Name: "error test GetSubByID, not found",
 			ForSave: entity.Subscription{
 				ID:          0,
-				UserID:      strfmt.UUID(uid.String()),
+				UserID:      entity.UserID(uid),
 				ServiceName: "Skillbox",
 				Cost:        10_000,
 				DateFrom:    start,
@@ -373,12 +677,38 @@ func TestSubRepository_GetSubByID(t *testing.T) {
 	}
 }
 
+func TestSubRepository_GetSubByPublicID(t *testing.T) {
+	ctx := context.Background()
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions RESTART IDENTITY CASCADE`)
+	sr := NewSubRepository(pool)
+
+	start := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.UTC)
+	pid := entity.PublicID(uuid.New())
+	given, err := sr.SaveSub(ctx, &entity.Subscription{UserID: entity.UserID(uuid.New()), PublicID: pid, ServiceName: "Skillbox", Cost: 10_000, DateFrom: start})
+	require.NoError(t, err)
+	assert.Equal(t, pid, given.PublicID, "a given public ID is stored")
+	made, err := sr.SaveSub(ctx, &entity.Subscription{UserID: entity.UserID(uuid.New()), ServiceName: "Skillbox", Cost: 10_000, DateFrom: start})
+	require.NoError(t, err)
+	assert.False(t, made.PublicID.IsZero(), "the database makes one when none is given")
+
+	got, err := sr.GetSubByPublicID(ctx, pid)
+	require.NoError(t, err)
+	assert.Equal(t, *given, *got)
+	_, err = sr.GetSubByPublicID(ctx, entity.PublicID(uuid.New()))
+	assert.ErrorIs(t, err, usecase.ErrSubscriptionNotFound)
+}
+
 func TestSubRepository_ListSubsByFilter(t *testing.T) {
 	ctx := context.Background()
 	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
 	require.NoError(t, err)
 	pool, err := pgxpool.New(ctx, connStr)
-	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions RESTART IDENTITY`)
+	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions RESTART IDENTITY CASCADE`)
 	require.NoError(t, err)
 	defer pool.Close()
 	r := NewSubRepository(pool)
@@ -389,7 +719,7 @@ func TestSubRepository_ListSubsByFilter(t *testing.T) {
 	userA := uuid.New()
 	userB := uuid.New()
 	s1, err := r.SaveSub(ctx, &entity.Subscription{
-		UserID:      strfmt.UUID(userA.String()),
+		UserID:      entity.UserID(userA),
 		ServiceName: "Skillbox",
 		Cost:        10000,
 		DateFrom:    start,
@@ -397,7 +727,7 @@ func TestSubRepository_ListSubsByFilter(t *testing.T) {
 	})
 	require.NoError(t, err)
 	s2, err := r.SaveSub(ctx, &entity.Subscription{
-		UserID:      strfmt.UUID(userA.String()),
+		UserID:      entity.UserID(userA),
 		ServiceName: "Netflix",
 		Cost:        499,
 		DateFrom:    prev2,
@@ -406,7 +736,7 @@ func TestSubRepository_ListSubsByFilter(t *testing.T) {
 
 	require.NoError(t, err)
 	s3, err := r.SaveSub(ctx, &entity.Subscription{
-		UserID:      strfmt.UUID(userB.String()),
+		UserID:      entity.UserID(userB),
 		ServiceName: "Spotify",
 		Cost:        299,
 		DateFrom:    prev2,
@@ -416,6 +746,8 @@ func TestSubRepository_ListSubsByFilter(t *testing.T) {
 	period := &usecase.Period{From: start, To: next1}
 	serviceNetflix := "Netflix"
 	nonexistentUser := uuid.New()
+	beforeWrites := s1.CreatedAt.Add(-time.Second)
+	cheap, flix, likeAll := int64(500), "FLIX", "%"
 	tcases := []struct {
 		Name     string
 		Filter   usecase.SubFilter
@@ -432,11 +764,11 @@ func TestSubRepository_ListSubsByFilter(t *testing.T) {
 		},
 		{
 			Name:    "filter by user",
-			Filter:  usecase.SubFilter{Period: period, UserID: strfmt.UUID(userA.String())},
+			Filter:  usecase.SubFilter{Period: period, UserID: entity.UserID(userA)},
 			WantLen: 2,
 			AssertFn: func(t *testing.T, got []*entity.Subscription) {
-				assert.Equal(t, strfmt.UUID(userA.String()), got[0].UserID)
-				assert.Equal(t, strfmt.UUID(userA.String()), got[1].UserID)
+				assert.Equal(t, entity.UserID(userA), got[0].UserID)
+				assert.Equal(t, entity.UserID(userA), got[1].UserID)
 			},
 		},
 		{
@@ -450,7 +782,7 @@ func TestSubRepository_ListSubsByFilter(t *testing.T) {
 		},
 		{
 			Name:     "empty by user",
-			Filter:   usecase.SubFilter{Period: period, UserID: strfmt.UUID(nonexistentUser.String())},
+			Filter:   usecase.SubFilter{Period: period, UserID: entity.UserID(nonexistentUser)},
 			WantLen:  0,
 			AssertFn: func(t *testing.T, got []*entity.Subscription) {},
 		},
@@ -465,6 +797,50 @@ func TestSubRepository_ListSubsByFilter(t *testing.T) {
 				assert.Contains(t, ids, s3.ID)
 			},
 		},
+		{
+			Name: "after keyset cursor",
+			Filter: usecase.SubFilter{Period: period, After: &usecase.ListCursor{
+				StartDate:   s2.DateFrom,
+				ServiceName: s2.ServiceName,
+				ID:          s2.ID,
+			}},
+			WantLen: 2,
+			AssertFn: func(t *testing.T, got []*entity.Subscription) {
+				assert.Equal(t, s3.ID, got[0].ID)
+				assert.Equal(t, s1.ID, got[1].ID)
+			},
+		},
+		{
+			Name:    "updated since before all writes",
+			Filter:  usecase.SubFilter{Period: period, UpdatedSince: &beforeWrites},
+			WantLen: 3,
+			AssertFn: func(t *testing.T, got []*entity.Subscription) {
+				for _, sub := range got {
+					assert.False(t, sub.CreatedAt.IsZero())
+					assert.False(t, sub.UpdatedAt.Before(sub.CreatedAt))
+				}
+			},
+		},
+		{
+			Name:    "conditions on cost, service and start",
+			Filter:  usecase.SubFilter{Period: period, Where: usecase.Conditions{CostMax: &cheap, ServiceContains: &flix, StartTo: &prev2}},
+			WantLen: 1,
+			AssertFn: func(t *testing.T, got []*entity.Subscription) {
+				assert.Equal(t, s2.ID, got[0].ID)
+			},
+		},
+		{
+			Name:     "like wildcards match themselves",
+			Filter:   usecase.SubFilter{Period: period, Where: usecase.Conditions{ServiceContains: &likeAll, StartFrom: &start}},
+			WantLen:  0,
+			AssertFn: func(t *testing.T, got []*entity.Subscription) {},
+		},
+		{
+			Name:     "updated since last write",
+			Filter:   usecase.SubFilter{Period: period, UpdatedSince: &s3.UpdatedAt},
+			WantLen:  0,
+			AssertFn: func(t *testing.T, got []*entity.Subscription) {},
+		},
 	}
 	t.Cleanup(func() {
 		require.NoError(t, err)
@@ -479,6 +855,52 @@ func TestSubRepository_ListSubsByFilter(t *testing.T) {
 	}
 }
 
+func TestSubRepository_ListSubsByFilterTies(t *testing.T) {
+	ctx := context.Background()
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions RESTART IDENTITY CASCADE`)
+	r := NewSubRepository(pool)
+
+	user := entity.UserID(uuid.New())
+	start := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	var saved []*entity.Subscription
+	for _, name := range []string{"netflix", "Netflix", "Netflix", "Okko", "netflix", "Netflix", "Okko"} {
+		s, err := r.SaveSub(ctx, &entity.Subscription{UserID: user, ServiceName: name, Cost: 100, DateFrom: start})
+		require.NoError(t, err)
+		saved = append(saved, s)
+	}
+	// bytewise whatever the collation of the database: upper case first, then the IDs
+	want := []int64{saved[1].ID, saved[2].ID, saved[5].ID, saved[3].ID, saved[6].ID, saved[0].ID, saved[4].ID}
+
+	for size := 1; size <= len(want); size++ {
+		var byCursor, byOffset []int64
+		for f := (usecase.SubFilter{UserID: user, Limit: size}); ; {
+			page, err := r.ListSubsByFilter(ctx, f)
+			require.NoError(t, err)
+			for _, s := range page {
+				byCursor = append(byCursor, s.ID)
+			}
+			if len(page) < size {
+				break
+			}
+			f.After = usecase.CursorAt(page[len(page)-1])
+		}
+		for offset := 0; offset < len(want); offset += size {
+			page, err := r.ListSubsByFilter(ctx, usecase.SubFilter{UserID: user, Limit: size, Offset: offset})
+			require.NoError(t, err)
+			for _, s := range page {
+				byOffset = append(byOffset, s.ID)
+			}
+		}
+		assert.Equal(t, want, byCursor, "page size %d", size)
+		assert.Equal(t, want, byOffset, "page size %d", size)
+	}
+}
+
 func TestSubRepository_CostSubsByFilter(t *testing.T) {
 	ctx := context.Background()
 
@@ -489,7 +911,7 @@ func TestSubRepository_CostSubsByFilter(t *testing.T) {
 	require.NoError(t, err)
 	defer pool.Close()
 
-	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions RESTART IDENTITY`)
+	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions RESTART IDENTITY CASCADE`)
 
 	r := NewSubRepository(pool)
 
@@ -500,7 +922,7 @@ func TestSubRepository_CostSubsByFilter(t *testing.T) {
 	userA := uuid.New()
 
 	_, err = r.SaveSub(ctx, &entity.Subscription{
-		UserID:      strfmt.UUID(userA.String()),
+		UserID:      entity.UserID(userA),
 		ServiceName: "Skillbox",
 		Cost:        10000,
 		DateFrom:    start,
@@ -509,7 +931,7 @@ func TestSubRepository_CostSubsByFilter(t *testing.T) {
 	require.NoError(t, err)
 
 	_, err = r.SaveSub(ctx, &entity.Subscription{
-		UserID:      strfmt.UUID(userA.String()),
+		UserID:      entity.UserID(userA),
 		ServiceName: "Netflix",
 		Cost:        499,
 		DateFrom:    prev2,
@@ -518,7 +940,7 @@ func TestSubRepository_CostSubsByFilter(t *testing.T) {
 	require.NoError(t, err)
 
 	_, err = r.SaveSub(ctx, &entity.Subscription{
-		UserID:      strfmt.UUID(uuid.New().String()),
+		UserID:      entity.UserID(uuid.New()),
 		ServiceName: "Spotify",
 		Cost:        299,
 		DateFrom:    prev2,
@@ -528,6 +950,7 @@ func TestSubRepository_CostSubsByFilter(t *testing.T) {
 
 	period := &usecase.Period{From: start, To: next1}
 	serviceNetflix := "Netflix"
+	serviceSpotify := "Spotify"
 	nonexistentUser := uuid.New()
 
 	tcases := []struct {
@@ -542,7 +965,7 @@ func TestSubRepository_CostSubsByFilter(t *testing.T) {
 		},
 		{
 			Name:   "filter by userA",
-			Filter: usecase.SubFilter{Period: period, UserID: strfmt.UUID(userA.String())},
+			Filter: usecase.SubFilter{Period: period, UserID: entity.UserID(userA)},
 			Want:   20000 + 499,
 		},
 		{
@@ -552,7 +975,7 @@ func TestSubRepository_CostSubsByFilter(t *testing.T) {
 		},
 		{
 			Name:   "empty by nonexistent user",
-			Filter: usecase.SubFilter{Period: period, UserID: strfmt.UUID(nonexistentUser.String())},
+			Filter: usecase.SubFilter{Period: period, UserID: entity.UserID(nonexistentUser)},
 			Want:   0,
 		},
 		{
@@ -560,6 +983,21 @@ func TestSubRepository_CostSubsByFilter(t *testing.T) {
 			Filter: usecase.SubFilter{Period: period},
 			Want:   21097,
 		},
+		{
+			Name:   "all users of service Spotify",
+			Filter: usecase.SubFilter{Period: period, ServiceName: &serviceSpotify},
+			Want:   299 + 299,
+		},
+		{
+			Name:   "userA and service Netflix",
+			Filter: usecase.SubFilter{Period: period, UserID: entity.UserID(userA), ServiceName: &serviceNetflix},
+			Want:   499,
+		},
+		{
+			Name:   "userA has no Spotify",
+			Filter: usecase.SubFilter{Period: period, UserID: entity.UserID(userA), ServiceName: &serviceSpotify},
+			Want:   0,
+		},
 	}
 
 	for _, tc := range tcases {
@@ -570,3 +1008,779 @@ func TestSubRepository_CostSubsByFilter(t *testing.T) {
 		})
 	}
 }
+
+func TestSubRepository_CostGroupedByFilter(t *testing.T) {
+	ctx := context.Background()
+
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions RESTART IDENTITY CASCADE`)
+
+	r := NewSubRepository(pool)
+
+	start := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.UTC)
+	prev1 := start.AddDate(0, -1, 0)
+	user := entity.UserID(uuid.New())
+
+	for _, s := range []entity.Subscription{
+		{UserID: user, ServiceName: "Netflix", Cost: 499, DateFrom: prev1},
+		{UserID: user, ServiceName: "Spotify", Cost: 299, DateFrom: prev1, DateTo: &prev1},
+		{UserID: entity.UserID(uuid.New()), ServiceName: "Netflix", Cost: 599, DateFrom: start},
+	} {
+		_, err = r.SaveSub(ctx, &s)
+		require.NoError(t, err)
+	}
+	period := &usecase.Period{From: prev1, To: start}
+
+	got, err := r.CostGroupedByFilter(ctx, usecase.SubFilter{Period: period}, usecase.CostByService)
+	require.NoError(t, err)
+	assert.Equal(t, []usecase.CostGroup{
+		{ServiceName: "Netflix", Total: 2*499 + 599, Count: 2},
+		{ServiceName: "Spotify", Total: 299, Count: 1},
+	}, got)
+
+	got, err = r.CostGroupedByFilter(ctx, usecase.SubFilter{UserID: user, Period: period}, usecase.CostByMonth)
+	require.NoError(t, err)
+	assert.Equal(t, []usecase.CostGroup{
+		{Month: prev1, Total: 499 + 299, Count: 2},
+		{Month: start, Total: 499, Count: 1},
+	}, got)
+
+	got, err = r.CostGroupedByFilter(ctx, usecase.SubFilter{UserID: user, Period: period}, usecase.CostByWeek)
+	require.NoError(t, err)
+	assert.Equal(t, []usecase.CostGroup{
+		{Month: usecase.CostByWeek.PeriodStart(prev1), Total: 499 + 299, Count: 2},
+		{Month: usecase.CostByWeek.PeriodStart(start), Total: 499, Count: 1},
+	}, got)
+
+	got, err = r.CostGroupedByFilter(ctx, usecase.SubFilter{UserID: user, Period: period}, usecase.CostByYear)
+	require.NoError(t, err)
+	if prev1.Year() == start.Year() {
+		assert.Equal(t, []usecase.CostGroup{{Month: usecase.CostByYear.PeriodStart(start), Total: 2*499 + 299, Count: 2}}, got)
+	} else {
+		assert.Equal(t, []usecase.CostGroup{
+			{Month: usecase.CostByYear.PeriodStart(prev1), Total: 499 + 299, Count: 2},
+			{Month: usecase.CostByYear.PeriodStart(start), Total: 499, Count: 1},
+		}, got)
+	}
+
+	got, err = r.CostGroupedByFilter(ctx, usecase.SubFilter{UserID: user, Period: period}, usecase.CostByUser)
+	require.NoError(t, err)
+	assert.Equal(t, []usecase.CostGroup{{UserID: user, Total: 2*499 + 299, Count: 2}}, got)
+
+	_, err = r.CostGroupedByFilter(ctx, usecase.SubFilter{Period: period}, "service_name; DROP TABLE subscriptions")
+	assert.ErrorIs(t, err, usecase.ErrInvalidGroupBy)
+}
+
+func TestSubRepository_CostSummaryByFilter(t *testing.T) {
+	ctx := context.Background()
+
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions RESTART IDENTITY CASCADE`)
+
+	r := NewSubRepository(pool)
+
+	start := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.UTC)
+	prev1 := start.AddDate(0, -1, 0)
+	user := entity.UserID(uuid.New())
+
+	for _, s := range []entity.Subscription{
+		{UserID: user, ServiceName: "Netflix", Cost: 499, DateFrom: prev1},
+		{UserID: user, ServiceName: "Spotify", Cost: 299, DateFrom: prev1, DateTo: &prev1},
+		{UserID: entity.UserID(uuid.New()), ServiceName: "Netflix", Cost: 599, DateFrom: start},
+		{UserID: user, ServiceName: "Spotify Free", Cost: 0, DateFrom: prev1},
+	} {
+		_, err = r.SaveSub(ctx, &s)
+		require.NoError(t, err)
+	}
+	period := &usecase.Period{From: prev1, To: start}
+
+	got, err := r.CostSummaryByFilter(ctx, usecase.SubFilter{Period: period})
+	require.NoError(t, err)
+	assert.Equal(t, usecase.CostSummary{Total: 2*499 + 299 + 599, Count: 3, MonthlyCost: 499 + 299 + 599, MinCost: 299, MaxCost: 599}, got,
+		"the free subscription is left out")
+	total, err := r.CostSubsByFilter(ctx, usecase.SubFilter{Period: period})
+	require.NoError(t, err)
+	assert.Equal(t, total, got.Total, "same total as the cost query")
+
+	got, err = r.CostSummaryByFilter(ctx, usecase.SubFilter{UserID: entity.UserID(uuid.New()), Period: period})
+	require.NoError(t, err)
+	assert.Zero(t, got)
+}
+
+func TestSubRepository_ActiveStatsByService(t *testing.T) {
+	ctx := context.Background()
+
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions RESTART IDENTITY CASCADE`)
+
+	r := NewSubRepository(pool)
+
+	start := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.UTC)
+	prev2 := start.AddDate(0, -2, 0)
+	prev1 := start.AddDate(0, -1, 0)
+
+	for _, s := range []entity.Subscription{
+		{UserID: entity.UserID(uuid.New()), ServiceName: "Netflix", Cost: 499, DateFrom: prev2},
+		{UserID: entity.UserID(uuid.New()), ServiceName: "Netflix", Cost: 599, DateFrom: start},
+		{UserID: entity.UserID(uuid.New()), ServiceName: "Spotify", Cost: 299, DateFrom: prev2, DateTo: &prev1},
+	} {
+		_, err = r.SaveSub(ctx, &s)
+		require.NoError(t, err)
+	}
+
+	got, err := r.ActiveStatsByService(ctx, start)
+	require.NoError(t, err)
+	assert.Equal(t, []usecase.ServiceStats{
+		{ServiceName: "Netflix", Active: 2, MonthlyCost: 499 + 599},
+	}, got)
+}
+
+func TestSubRepository_MonthlySpendByUser(t *testing.T) {
+	ctx := context.Background()
+
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions RESTART IDENTITY CASCADE`)
+
+	r := NewSubRepository(pool)
+
+	start := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.UTC)
+	prev2 := start.AddDate(0, -2, 0)
+	prev1 := start.AddDate(0, -1, 0)
+	user := entity.UserID(uuid.New())
+
+	for _, s := range []entity.Subscription{
+		{UserID: user, ServiceName: "Netflix", Cost: 499, DateFrom: prev2},
+		{UserID: user, ServiceName: "Spotify", Cost: 299, DateFrom: prev2, DateTo: &prev2},
+		{UserID: user, ServiceName: "Yandex", Cost: 399, DateFrom: start},
+	} {
+		_, err = r.SaveSub(ctx, &s)
+		require.NoError(t, err)
+	}
+
+	got, err := r.MonthlySpendByUser(ctx, prev2, start)
+	require.NoError(t, err)
+	assert.Equal(t, []usecase.UserMonthSpend{
+		{UserID: user, Month: prev2, Total: 499 + 299},
+		{UserID: user, Month: prev1, Total: 499},
+		{UserID: user, Month: start, Total: 499 + 399},
+	}, got)
+}
+
+func TestSubRepository_PriceBenchmarks(t *testing.T) {
+	ctx := context.Background()
+
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions, user_settings RESTART IDENTITY CASCADE`)
+
+	r := NewSubRepository(pool)
+
+	start := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.UTC)
+	costs := []int64{100, 200, 600}
+	for i, cost := range costs {
+		user := entity.UserID(uuid.New())
+		settings := entity.DefaultSettings(user)
+		settings.SharePriceStats = true
+		_, err = r.SaveSettings(ctx, settings)
+		require.NoError(t, err)
+		name := "Netflix"
+		if i == 1 {
+			name = " netflix "
+		}
+		_, err = r.SaveSub(ctx, &entity.Subscription{UserID: user, ServiceName: name, Cost: cost, DateFrom: start})
+		require.NoError(t, err)
+		_, err = r.SaveSub(ctx, &entity.Subscription{UserID: user, ServiceName: "Spotify", Cost: 299, DateFrom: start})
+		require.NoError(t, err)
+	}
+	// not opted in: never counted
+	_, err = r.SaveSub(ctx, &entity.Subscription{UserID: entity.UserID(uuid.New()), ServiceName: "Netflix", Cost: 10000, DateFrom: start})
+	require.NoError(t, err)
+
+	got, err := r.PriceBenchmarks(ctx, start, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []usecase.PriceBenchmark{
+		{Service: "netflix", Users: 3, Subscriptions: 3, AverageCost: 300, MedianCost: 200},
+		{Service: "spotify", Users: 3, Subscriptions: 3, AverageCost: 299, MedianCost: 299},
+	}, got)
+
+	got, err = r.PriceBenchmarks(ctx, start, 4)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestSubRepository_DataVersion(t *testing.T) {
+	ctx := context.Background()
+
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions, user_versions, user_deactivations RESTART IDENTITY CASCADE`)
+
+	r := NewSubRepository(pool)
+	ann := entity.UserID(uuid.New())
+	start := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	got, err := r.DataVersion(ctx, ann)
+	require.NoError(t, err)
+	assert.Zero(t, got, "no data, no version")
+
+	created, err := r.SaveSub(ctx, &entity.Subscription{UserID: ann, ServiceName: "Netflix", Cost: 499, DateFrom: start})
+	require.NoError(t, err)
+	v1, err := r.DataVersion(ctx, ann)
+	require.NoError(t, err)
+	assert.NotZero(t, v1.Version)
+	assert.False(t, v1.ChangedAt.IsZero())
+
+	_, err = r.SaveAdjustment(ctx, &entity.Adjustment{SubscriptionID: created.ID, Month: start, Amount: -100})
+	require.NoError(t, err)
+	v2, err := r.DataVersion(ctx, ann)
+	require.NoError(t, err)
+	assert.Greater(t, v2.Version, v1.Version, "an adjustment bumps the owner")
+
+	_, err = r.DeactivateUser(ctx, ann, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, r.ReactivateUser(ctx, ann))
+	v3, err := r.DataVersion(ctx, ann)
+	require.NoError(t, err)
+	assert.Greater(t, v3.Version, v2.Version, "deactivation and reactivation bump the user")
+
+	require.NoError(t, r.DeleteSub(ctx, created.ID, time.Time{}))
+	v4, err := r.DataVersion(ctx, ann)
+	require.NoError(t, err)
+	assert.Greater(t, v4.Version, v3.Version, "a delete bumps the user")
+
+	all, err := r.DataVersion(ctx, entity.UserID{})
+	require.NoError(t, err)
+	assert.Equal(t, v4.Version, all.Version, "the sum over the only user")
+}
+
+func TestSubRepository_ChangesSince(t *testing.T) {
+	ctx := context.Background()
+
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	r := NewSubRepository(pool)
+	user := entity.UserID(uuid.New())
+
+	start := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.UTC)
+	created, err := r.SaveSub(ctx, &entity.Subscription{
+		UserID:      user,
+		ServiceName: "Netflix",
+		Cost:        499,
+		DateFrom:    start,
+	})
+	require.NoError(t, err)
+	created.Cost = 599
+	require.NoError(t, r.UpdateSub(ctx, created))
+	require.NoError(t, r.DeleteSub(ctx, created.ID, time.Time{}))
+	_, err = r.SaveSub(ctx, &entity.Subscription{UserID: entity.UserID(uuid.New()), ServiceName: "Netflix", Cost: 499, DateFrom: start})
+	require.NoError(t, err)
+
+	got, err := r.ChangesSince(ctx, user, entity.ChangePos{}, 10)
+	require.NoError(t, err)
+	require.Len(t, got, 3, "entries of other users are left out")
+	assert.Equal(t, entity.ChangeInsert, got[0].Op)
+	assert.Equal(t, entity.ChangeUpdate, got[1].Op)
+	assert.Equal(t, entity.ChangeDelete, got[2].Op)
+	for i, ch := range got {
+		assert.Equal(t, created.ID, ch.SubscriptionID)
+		assert.Equal(t, created.PublicID, ch.PublicID, "the delete too")
+		assert.Equal(t, user, ch.UserID)
+		assert.False(t, ch.ChangedAt.IsZero())
+		if i > 0 {
+			assert.True(t, got[i-1].Pos().Before(ch.Pos()))
+		}
+	}
+
+	page, err := r.ChangesSince(ctx, user, entity.ChangePos{}, 1)
+	require.NoError(t, err)
+	assert.Len(t, page, 1)
+
+	rest, err := r.ChangesSince(ctx, user, got[2].Pos(), 10)
+	require.NoError(t, err)
+	assert.Empty(t, rest)
+
+	// a transaction still running holds back the entries of the ones that committed after it started writing
+	slow, err := pool.Begin(ctx)
+	require.NoError(t, err)
+	defer func() { _ = slow.Rollback(ctx) }()
+	_, err = slow.Exec(ctx, `INSERT INTO subscriptions (user_id, service_name, cost, start_date) VALUES ($1, 'Spotify', 299, $2)`,
+		user.UUID(), start)
+	require.NoError(t, err)
+	_, err = r.SaveSub(ctx, &entity.Subscription{UserID: user, ServiceName: "Kinopoisk", Cost: 399, DateFrom: start})
+	require.NoError(t, err)
+	held, err := r.ChangesSince(ctx, user, got[2].Pos(), 10)
+	require.NoError(t, err)
+	assert.Empty(t, held)
+
+	require.NoError(t, slow.Commit(ctx))
+	both, err := r.ChangesSince(ctx, user, got[2].Pos(), 10)
+	require.NoError(t, err)
+	require.Len(t, both, 2, "the earlier transaction first, whatever seq it took")
+	assert.Less(t, both[0].Tx, both[1].Tx)
+}
+
+func TestSubRepository_ReassignUser(t *testing.T) {
+	ctx := context.Background()
+
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions RESTART IDENTITY CASCADE`)
+
+	r := NewSubRepository(pool)
+
+	from := entity.UserID(uuid.New())
+	to := entity.UserID(uuid.New())
+	start := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.UTC)
+	for _, name := range []string{"Netflix", "Spotify"} {
+		_, err := r.SaveSub(ctx, &entity.Subscription{UserID: from, ServiceName: name, Cost: 100, DateFrom: start})
+		require.NoError(t, err)
+	}
+
+	moved, err := r.ReassignUser(ctx, from, to, "127.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), moved)
+
+	got, err := r.ListSubsByFilter(ctx, usecase.SubFilter{UserID: to})
+	require.NoError(t, err)
+	assert.Len(t, got, 2)
+
+	var action, actor string
+	var details []byte
+	require.NoError(t, pool.QueryRow(ctx,
+		`SELECT action, actor, details FROM admin_audit_log ORDER BY id DESC LIMIT 1`,
+	).Scan(&action, &actor, &details))
+	assert.Equal(t, "reassign_user", action)
+	assert.Equal(t, "127.0.0.1", actor)
+	assert.JSONEq(t, `{"from_user_id":"`+from.String()+`","to_user_id":"`+to.String()+`","moved":2}`, string(details))
+
+	_, err = r.ReassignUser(ctx, entity.UserID{}, to, "")
+	assert.ErrorIs(t, err, entity.ErrInvalidUserID)
+}
+
+func TestSubRepository_MergeSubs(t *testing.T) {
+	ctx := context.Background()
+
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	r := NewSubRepository(pool)
+
+	user := entity.UserID(uuid.New())
+	start := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.UTC)
+	keep, err := r.SaveSub(ctx, &entity.Subscription{UserID: user, ServiceName: "Netflix", Cost: 499, DateFrom: start})
+	require.NoError(t, err)
+	drop, err := r.SaveSub(ctx, &entity.Subscription{UserID: user, ServiceName: "Netflix", Cost: 599, DateFrom: start.AddDate(0, 1, 0)})
+	require.NoError(t, err)
+
+	stale := *keep
+	stale.UpdatedAt = keep.UpdatedAt.Add(-time.Second)
+	assert.ErrorIs(t, r.MergeSubs(ctx, &stale, drop, ""), usecase.ErrPreconditionFailed)
+
+	merged := *keep
+	merged.Cost = drop.Cost
+	require.NoError(t, r.MergeSubs(ctx, &merged, drop, "127.0.0.1"))
+
+	got, err := r.GetSubByID(ctx, keep.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(599), got.Cost)
+	_, err = r.GetSubByID(ctx, drop.ID)
+	assert.ErrorIs(t, err, usecase.ErrSubscriptionNotFound)
+
+	var archivedID int64
+	require.NoError(t, pool.QueryRow(ctx,
+		`SELECT (details->'archived'->>'id')::bigint FROM admin_audit_log WHERE action = 'merge_subscriptions' ORDER BY id DESC LIMIT 1`,
+	).Scan(&archivedID))
+	assert.Equal(t, drop.ID, archivedID)
+}
+
+func TestSubRepository_WithExplain(t *testing.T) {
+	ctx := context.Background()
+
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions RESTART IDENTITY CASCADE`)
+
+	var buf lockedBuffer
+	log := slog.New(slog.NewTextHandler(&buf, nil))
+	r := NewSubRepository(pool, WithExplain(time.Nanosecond, log))
+
+	start := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.UTC)
+	_, err = r.SaveSub(ctx, &entity.Subscription{
+		UserID:      entity.UserID(uuid.New()),
+		ServiceName: "Netflix",
+		Cost:        499,
+		DateFrom:    start,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, buf.String(), "writes must not be explained")
+
+	// the request does not wait for the plan, and cancelling it does not stop the plan
+	listCtx, cancel := context.WithCancel(ctx)
+	_, err = r.ListSubsByFilter(listCtx, usecase.SubFilter{})
+	cancel()
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return strings.Contains(buf.String(), "query=ListSubscriptions") && strings.Contains(buf.String(), "Execution Time")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, err = r.CostSubsByFilter(ctx, usecase.SubFilter{Period: &usecase.Period{From: start, To: start}})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return strings.Contains(buf.String(), "query=SumSubscriptionCost") },
+		5*time.Second, 10*time.Millisecond)
+
+	buf.Reset()
+	_, err = r.ListSubsByFilter(ctx, usecase.SubFilter{})
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, buf.String(), "a query is explained at most once a minute")
+}
+
+// lockedBuffer is a bytes.Buffer the background plans and the test may use at once
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *lockedBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+}
+
+func TestSubRepository_Settings(t *testing.T) {
+	ctx := context.Background()
+
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	r := NewSubRepository(pool)
+	uid := entity.UserID(uuid.New())
+
+	_, err = r.GetSettings(ctx, uid)
+	assert.ErrorIs(t, err, usecase.ErrSettingsNotFound)
+
+	s := entity.DefaultSettings(uid)
+	s.Currency = "EUR"
+	s.FirstDayOfWeek = time.Sunday
+	saved, err := r.SaveSettings(ctx, s)
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", saved.Currency)
+	assert.False(t, saved.UpdatedAt.IsZero())
+
+	s.Locale = "en-US"
+	_, err = r.SaveSettings(ctx, s)
+	require.NoError(t, err)
+
+	got, err := r.GetSettings(ctx, uid)
+	require.NoError(t, err)
+	assert.Equal(t, "en-US", got.Locale)
+	assert.Equal(t, time.Sunday, got.FirstDayOfWeek)
+	assert.Equal(t, "01-2006", got.DateFormat)
+}
+
+func TestSubRepository_Deactivation(t *testing.T) {
+	ctx := context.Background()
+
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions, user_deactivations RESTART IDENTITY CASCADE`)
+
+	r := NewSubRepository(pool)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	user, other := entity.UserID(uuid.New()), entity.UserID(uuid.New())
+	for _, u := range []entity.UserID{user, other} {
+		_, err = r.SaveSub(ctx, &entity.Subscription{UserID: u, ServiceName: "Netflix", Cost: 100, DateFrom: start})
+		require.NoError(t, err)
+	}
+	period := &usecase.Period{From: start, To: start}
+
+	at, err := r.UserDeactivatedAt(ctx, user)
+	require.NoError(t, err)
+	assert.True(t, at.IsZero())
+
+	first := time.Date(2025, 2, 1, 10, 0, 0, 0, time.UTC)
+	at, err = r.DeactivateUser(ctx, user, first)
+	require.NoError(t, err)
+	assert.True(t, first.Equal(at))
+	at, err = r.DeactivateUser(ctx, user, first.Add(time.Hour))
+	require.NoError(t, err)
+	assert.True(t, first.Equal(at), "an earlier deactivation is kept")
+
+	list, err := r.ListSubsByFilter(ctx, usecase.SubFilter{})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, other, list[0].UserID)
+	list, err = r.ListSubsByFilter(ctx, usecase.SubFilter{UserID: user, IncludeDeactivated: true})
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	total, err := r.CostSubsByFilter(ctx, usecase.SubFilter{UserID: user, Period: period})
+	require.NoError(t, err)
+	assert.Zero(t, total)
+	total, err = r.CostSubsByFilter(ctx, usecase.SubFilter{Period: period})
+	require.NoError(t, err)
+	assert.EqualValues(t, 100, total)
+	spend, err := r.MonthlySpendByUser(ctx, start, start)
+	require.NoError(t, err)
+	assert.Len(t, spend, 1)
+
+	require.NoError(t, r.ReactivateUser(ctx, user))
+	require.NoError(t, r.ReactivateUser(ctx, user))
+	total, err = r.CostSubsByFilter(ctx, usecase.SubFilter{UserID: user, Period: period})
+	require.NoError(t, err)
+	assert.EqualValues(t, 100, total)
+}
+
+func TestSubRepository_DeleteUser(t *testing.T) {
+	ctx := context.Background()
+
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, _ = pool.Exec(ctx, `TRUNCATE TABLE subscriptions, user_settings, user_deactivations RESTART IDENTITY CASCADE`)
+
+	r := NewSubRepository(pool)
+	month := func(m time.Month) time.Time {
+		return time.Date(2025, m, 1, 0, 0, 0, 0, time.UTC)
+	}
+	user, other := entity.UserID(uuid.New()), entity.UserID(uuid.New())
+	ended := month(8)
+	var subs []*entity.Subscription
+	for _, s := range []entity.Subscription{
+		{UserID: user, ServiceName: "Netflix", Cost: 1000, DateFrom: month(7)},
+		{UserID: user, ServiceName: "Spotify", Cost: 300, DateFrom: month(7), DateTo: &ended},
+		{UserID: other, ServiceName: "Apple One", Cost: 900, DateFrom: month(7)},
+	} {
+		created, err := r.SaveSub(ctx, &s)
+		require.NoError(t, err)
+		subs = append(subs, created)
+	}
+	_, err = r.SaveSeats(ctx, &entity.Seats{SubscriptionID: subs[2].ID, Total: 3, UserIDs: []entity.UserID{user}})
+	require.NoError(t, err)
+	_, err = r.SaveSettings(ctx, entity.DefaultSettings(user))
+	require.NoError(t, err)
+	_, err = r.DeactivateUser(ctx, user, month(9))
+	require.NoError(t, err)
+
+	_, err = r.DeleteUser(ctx, user, usecase.UserDeletion{Policy: usecase.DeleteUserBlock, Since: month(9)}, "127.0.0.1")
+	assert.ErrorIs(t, err, usecase.ErrUserHasActiveSubs)
+	_, err = r.GetSettings(ctx, user)
+	require.NoError(t, err, "a refused deletion changes nothing")
+
+	anon := entity.UserID(uuid.New())
+	n, err := r.DeleteUser(ctx, user, usecase.UserDeletion{Policy: usecase.DeleteUserAnonymize, AnonymousID: anon}, "127.0.0.1")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+	got, err := r.ListSubsByFilter(ctx, usecase.SubFilter{UserID: anon})
+	require.NoError(t, err)
+	assert.Len(t, got, 2)
+	seats, err := r.GetSeats(ctx, subs[2].ID)
+	require.NoError(t, err)
+	assert.Empty(t, seats.UserIDs, "the seat in a plan of another user is given up")
+	_, err = r.GetSettings(ctx, user)
+	assert.ErrorIs(t, err, usecase.ErrSettingsNotFound)
+	at, err := r.UserDeactivatedAt(ctx, user)
+	require.NoError(t, err)
+	assert.True(t, at.IsZero())
+
+	var action, actor string
+	var details []byte
+	require.NoError(t, pool.QueryRow(ctx,
+		`SELECT action, actor, details FROM admin_audit_log ORDER BY id DESC LIMIT 1`,
+	).Scan(&action, &actor, &details))
+	assert.Equal(t, "delete_user", action)
+	assert.Equal(t, "127.0.0.1", actor)
+	assert.JSONEq(t, `{"user_id":"`+user.String()+`","policy":"anonymize","subscriptions":2}`, string(details))
+
+	n, err = r.DeleteUser(ctx, anon, usecase.UserDeletion{Policy: usecase.DeleteUserCascade}, "127.0.0.1")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+	_, err = r.GetSubByID(ctx, subs[0].ID)
+	assert.ErrorIs(t, err, usecase.ErrSubscriptionNotFound)
+	list, err := r.ListSubsByFilter(ctx, usecase.SubFilter{})
+	require.NoError(t, err)
+	require.Len(t, list, 1, "other users keep their subscriptions")
+
+	_, err = r.DeleteUser(ctx, entity.UserID{}, usecase.UserDeletion{Policy: usecase.DeleteUserCascade}, "")
+	assert.ErrorIs(t, err, entity.ErrInvalidUserID)
+}
+
+func TestSubRepository_Pseudonyms(t *testing.T) {
+	ctx := context.Background()
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+	_, _ = pool.Exec(ctx, `TRUNCATE TABLE user_pseudonyms`)
+	sr := NewSubRepository(pool)
+
+	p1, p2 := entity.UserID(uuid.New()), entity.UserID(uuid.New())
+	require.NoError(t, sr.SavePseudonym(ctx, p1, "v1:k1:first"))
+	require.NoError(t, sr.SavePseudonym(ctx, p1, "v1:k1:second"), "a known pseudonym is kept")
+
+	got, err := sr.SealedUserIDs(ctx, []entity.UserID{p1, p2})
+	require.NoError(t, err)
+	assert.Equal(t, map[entity.UserID]string{p1: "v1:k1:first"}, got)
+
+	got, err = sr.SealedUserIDs(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestSubRepository_ContextCancel(t *testing.T) {
+	ctx := context.Background()
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable", "application_name=cancel_test")
+	require.NoError(t, err)
+	cfg, err := pgxpool.ParseConfig(connStr)
+	require.NoError(t, err)
+	cfg.MaxConns = 1
+	CancelStatements(cfg)
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	require.NoError(t, err)
+	defer pool.Close()
+	sr := NewSubRepository(pool)
+
+	t.Run("cancelled before the call", func(t *testing.T) {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := sr.GetSubByID(cancelled, 1)
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = sr.ListSubsByFilter(cancelled, usecase.SubFilter{Limit: 10})
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = sr.CostSubsByFilter(cancelled, usecase.SubFilter{})
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = sr.SaveSub(cancelled, &entity.Subscription{UserID: entity.UserID(uuid.New()), ServiceName: "Cancelled", Cost: 1, DateFrom: time.Now()})
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("statement cancelled on the server", func(t *testing.T) {
+		short, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := pool.Exec(short, `SELECT pg_sleep(30)`)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 5*time.Second)
+
+		// the only connection of the pool survives and the sleep is gone from the server
+		var sleeping int
+		require.NoError(t, pool.QueryRow(ctx, `
+			SELECT count(*) FROM pg_stat_activity
+			WHERE application_name = 'cancel_test' AND query LIKE 'SELECT pg_sleep%' AND state = 'active'`).Scan(&sleeping))
+		assert.Zero(t, sleeping)
+		assert.EqualValues(t, 1, pool.Stat().TotalConns())
+	})
+}
+
+func TestPoolOptions(t *testing.T) {
+	ctx := context.Background()
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	for _, mode := range []string{StatementCachePrepare, StatementCacheDescribe, StatementCacheExec, StatementCacheSimple} {
+		t.Run(mode, func(t *testing.T) {
+			cfg, err := pgxpool.ParseConfig(connStr)
+			require.NoError(t, err)
+			PoolOptions{MinConns: 3, MaxConns: 2, StatementCache: mode}.Apply(cfg)
+			assert.EqualValues(t, 3, cfg.MaxConns, "the minimum raises a lower maximum")
+			pool, err := pgxpool.NewWithConfig(ctx, cfg)
+			require.NoError(t, err)
+			defer pool.Close()
+
+			n, err := WarmUp(ctx, pool)
+			require.NoError(t, err)
+			assert.Equal(t, 3, n)
+			assert.GreaterOrEqual(t, pool.Stat().TotalConns(), int32(3))
+
+			sr := NewSubRepository(pool)
+			uid := entity.UserID(uuid.New())
+			start := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+			_, err = sr.SaveSub(ctx, &entity.Subscription{UserID: uid, ServiceName: "Pool " + mode, Cost: 100, DateFrom: start})
+			require.NoError(t, err)
+			// the second run goes through the cache of the mode
+			for range 2 {
+				subs, err := sr.ListSubsByFilter(ctx, usecase.SubFilter{UserID: uid, Limit: 10})
+				require.NoError(t, err)
+				require.Len(t, subs, 1)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"subs_tracker/internal/repository/subscription/postgres/sqlc"
+)
+
+// errNoTx - a row lock was asked for outside InTx, where it would end with its own statement
+var errNoTx = errors.New("row lock outside a transaction")
+
+// txKey - context key of the transaction InTx opened for a repository; it names the repository, so the
+// transactions of several repositories sharing a context, e.g. shards, do not mix
+type txKey struct {
+	r *SubRepository
+}
+
+// InTx runs fn in a single transaction: repository calls made with the ctx passed to fn join it, and an error
+// returned by fn rolls it back and is returned as is. Inside a transaction fn joins the one already open
+func (r *SubRepository) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := r.tx(ctx); ok {
+		return fn(ctx)
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("in tx: begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := fn(context.WithValue(ctx, txKey{r}, tx)); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("in tx: commit: %w", err)
+	}
+	return nil
+}
+
+// tx returns the transaction InTx opened in ctx
+func (r *SubRepository) tx(ctx context.Context) (pgx.Tx, bool) {
+	tx, ok := ctx.Value(txKey{r}).(pgx.Tx)
+	return tx, ok
+}
+
+// q returns the queries of the transaction of ctx, those of the pool outside one
+func (r *SubRepository) q(ctx context.Context) *sqlc.Queries {
+	if tx, ok := r.tx(ctx); ok {
+		return r.queries.WithTx(tx)
+	}
+	return r.queries
+}
+
+// begin starts a transaction for a write spanning several statements; inside the transaction of ctx it is a
+// savepoint, so rolling it back leaves the outer transaction usable
+func (r *SubRepository) begin(ctx context.Context) (pgx.Tx, error) {
+	if tx, ok := r.tx(ctx); ok {
+		return tx.Begin(ctx)
+	}
+	return r.pool.Begin(ctx)
+}
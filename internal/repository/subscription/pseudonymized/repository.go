@@ -0,0 +1,519 @@
+// Package pseudonymized stores user IDs as keyed pseudonyms, keeping the real IDs only in an encrypted lookup table
+package pseudonymized
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/fieldcrypt"
+)
+
+// MinKeySize - shortest accepted HMAC key
+const MinKeySize = 32
+
+// Lookup — storage of the sealed real user ID behind every pseudonym
+type Lookup interface {
+	// SavePseudonym - store the sealed real user ID of a pseudonym, keeping one already stored
+	SavePseudonym(ctx context.Context, pseudonym entity.UserID, sealed string) error
+	// SealedUserIDs - get the sealed real user IDs of the known pseudonyms
+	SealedUserIDs(ctx context.Context, pseudonyms []entity.UserID) (map[entity.UserID]string, error)
+}
+
+// Repository — usecase.SubscriptionRepository replacing user IDs with HMAC pseudonyms before they reach next.
+// The pseudonym of a user is stable, so filtering by user keeps working, but without the key the stored IDs
+// cannot be matched to real users; the lookup table sealed with the keyring is what maps them back.
+//
+// Rows written before pseudonymization was enabled have no lookup entry and are returned unchanged,
+// so it is meant to be enabled on an empty database.
+type Repository struct {
+	next    usecase.SubscriptionRepository
+	lookup  Lookup
+	key     []byte
+	keyring *fieldcrypt.Keyring
+
+	mu    sync.RWMutex
+	known map[entity.UserID]entity.UserID // pseudonym -> real user ID, stored in the lookup table
+}
+
+// NewRepository wraps next; key is the HMAC key of this deployment and keyring seals the lookup table
+func NewRepository(next usecase.SubscriptionRepository, lookup Lookup, key []byte, keyring *fieldcrypt.Keyring) (*Repository, error) {
+	if len(key) < MinKeySize {
+		return nil, fmt.Errorf("pseudonym key: want at least %d bytes, got %d", MinKeySize, len(key))
+	}
+	if keyring == nil {
+		return nil, errors.New("pseudonym keyring is missing")
+	}
+	return &Repository{
+		next:    next,
+		lookup:  lookup,
+		key:     key,
+		keyring: keyring,
+		known:   map[entity.UserID]entity.UserID{},
+	}, nil
+}
+
+// Pseudonym returns the pseudonym of userID: HMAC-SHA256 truncated to a version 8 UUID, the zero ID stays zero
+func (r *Repository) Pseudonym(userID entity.UserID) entity.UserID {
+	if userID.IsZero() {
+		return userID
+	}
+	mac := hmac.New(sha256.New, r.key)
+	_, _ = mac.Write(userID[:])
+	var p entity.UserID
+	copy(p[:], mac.Sum(nil))
+	p[6] = p[6]&0x0f | 0x80
+	p[8] = p[8]&0x3f | 0x80
+	return p
+}
+
+// remember returns the pseudonym of userID, storing its lookup entry the first time it is seen
+func (r *Repository) remember(ctx context.Context, userID entity.UserID) (entity.UserID, error) {
+	p := r.Pseudonym(userID)
+	if p.IsZero() {
+		return p, nil
+	}
+	r.mu.RLock()
+	_, ok := r.known[p]
+	r.mu.RUnlock()
+	if ok {
+		return p, nil
+	}
+	sealed, err := r.keyring.Encrypt(userID[:], p[:])
+	if err != nil {
+		return p, fmt.Errorf("seal user id: %w", err)
+	}
+	if err := r.lookup.SavePseudonym(ctx, p, sealed); err != nil {
+		return p, err
+	}
+	r.mu.Lock()
+	r.known[p] = userID
+	r.mu.Unlock()
+	return p, nil
+}
+
+// reveal maps pseudonyms back to real user IDs; unknown ones are kept as they are
+func (r *Repository) reveal(ctx context.Context, pseudonyms []entity.UserID) (map[entity.UserID]entity.UserID, error) {
+	out := make(map[entity.UserID]entity.UserID, len(pseudonyms))
+	var missing []entity.UserID
+	r.mu.RLock()
+	for _, p := range pseudonyms {
+		if p.IsZero() {
+			continue
+		}
+		if real, ok := r.known[p]; ok {
+			out[p] = real
+		} else if !slices.Contains(missing, p) {
+			missing = append(missing, p)
+		}
+	}
+	r.mu.RUnlock()
+	if len(missing) == 0 {
+		return out, nil
+	}
+	sealed, err := r.lookup.SealedUserIDs(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for p, s := range sealed {
+		plain, err := r.keyring.Decrypt(s, p[:])
+		if err != nil {
+			return nil, fmt.Errorf("open user id of %s: %w", p, err)
+		}
+		var real entity.UserID
+		if len(plain) != len(real) {
+			return nil, fmt.Errorf("open user id of %s: %w", p, fieldcrypt.ErrDecrypt)
+		}
+		copy(real[:], plain)
+		r.known[p] = real
+		out[p] = real
+	}
+	return out, nil
+}
+
+// RevealSubs replaces the pseudonyms of subs kept outside the database, e.g. in the archive, with the real user IDs
+func (r *Repository) RevealSubs(ctx context.Context, subs ...*entity.Subscription) error {
+	return r.revealSubs(ctx, subs...)
+}
+
+// revealSubs replaces pseudonyms in subs in place
+func (r *Repository) revealSubs(ctx context.Context, subs ...*entity.Subscription) error {
+	ids := make([]entity.UserID, 0, len(subs))
+	for _, s := range subs {
+		if s != nil {
+			ids = append(ids, s.UserID)
+		}
+	}
+	real, err := r.reveal(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, s := range subs {
+		if s == nil {
+			continue
+		}
+		if u, ok := real[s.UserID]; ok {
+			s.UserID = u
+		}
+	}
+	return nil
+}
+
+func (r *Repository) filter(f usecase.SubFilter) usecase.SubFilter {
+	f.UserID = r.Pseudonym(f.UserID)
+	return f
+}
+
+// SaveSub stores the subscription under the pseudonym of its user
+func (r *Repository) SaveSub(ctx context.Context, s *entity.Subscription) (*entity.Subscription, error) {
+	if s == nil {
+		return nil, fmt.Errorf("save sub: %w", usecase.ErrInvalidSubscription)
+	}
+	in := *s
+	p, err := r.remember(ctx, in.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("save sub: %w", err)
+	}
+	in.UserID = p
+	out, err := r.next.SaveSub(ctx, &in)
+	if err != nil || out == nil {
+		return out, err
+	}
+	if out.UserID == p {
+		out.UserID = s.UserID
+	}
+	return out, nil
+}
+
+// SaveImportedSub stores the subscription under the pseudonym of its user, or reads the one stored under key
+func (r *Repository) SaveImportedSub(ctx context.Context, key string, s *entity.Subscription) (*entity.Subscription, bool, error) {
+	if s == nil {
+		return nil, false, fmt.Errorf("save imported sub: %w", usecase.ErrInvalidSubscription)
+	}
+	in := *s
+	p, err := r.remember(ctx, in.UserID)
+	if err != nil {
+		return nil, false, fmt.Errorf("save imported sub: %w", err)
+	}
+	in.UserID = p
+	out, created, err := r.next.SaveImportedSub(ctx, key, &in)
+	if err != nil || out == nil {
+		return out, created, err
+	}
+	if err := r.revealSubs(ctx, out); err != nil {
+		return nil, false, fmt.Errorf("save imported sub: %w", err)
+	}
+	return out, created, nil
+}
+
+// UpdateSub updates the subscription, pseudonymizing its new user
+func (r *Repository) UpdateSub(ctx context.Context, s *entity.Subscription) error {
+	if s == nil {
+		return fmt.Errorf("update sub: %w", usecase.ErrInvalidSubscription)
+	}
+	p, err := r.remember(ctx, s.UserID)
+	if err != nil {
+		return fmt.Errorf("update sub: %w", err)
+	}
+	in := *s
+	in.UserID = p
+	return r.next.UpdateSub(ctx, &in)
+}
+
+// DeleteSub deletes the subscription
+func (r *Repository) DeleteSub(ctx context.Context, id int64, version time.Time) error {
+	return r.next.DeleteSub(ctx, id, version)
+}
+
+// GetSubByID reads the subscription and reveals its user
+func (r *Repository) GetSubByID(ctx context.Context, id int64) (*entity.Subscription, error) {
+	out, err := r.next.GetSubByID(ctx, id)
+	if err != nil {
+		return out, err
+	}
+	if err := r.revealSubs(ctx, out); err != nil {
+		return nil, fmt.Errorf("get sub by id: %w", err)
+	}
+	return out, nil
+}
+
+// GetSubByIDForUpdate reads and locks the subscription and reveals its user
+func (r *Repository) GetSubByIDForUpdate(ctx context.Context, id int64) (*entity.Subscription, error) {
+	out, err := r.next.GetSubByIDForUpdate(ctx, id)
+	if err != nil {
+		return out, err
+	}
+	if err := r.revealSubs(ctx, out); err != nil {
+		return nil, fmt.Errorf("get sub for update: %w", err)
+	}
+	return out, nil
+}
+
+// InTx runs fn in a transaction of the wrapped repository; pseudonyms are kept apart and not part of it
+func (r *Repository) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.next.InTx(ctx, fn)
+}
+
+// GetSubByPublicID reads the subscription and reveals its user
+func (r *Repository) GetSubByPublicID(ctx context.Context, id entity.PublicID) (*entity.Subscription, error) {
+	out, err := r.next.GetSubByPublicID(ctx, id)
+	if err != nil {
+		return out, err
+	}
+	if err := r.revealSubs(ctx, out); err != nil {
+		return nil, fmt.Errorf("get sub by public id: %w", err)
+	}
+	return out, nil
+}
+
+// ListSubsByFilter lists the subscriptions and reveals their users
+func (r *Repository) ListSubsByFilter(ctx context.Context, f usecase.SubFilter) ([]*entity.Subscription, error) {
+	out, err := r.next.ListSubsByFilter(ctx, r.filter(f))
+	if err != nil {
+		return out, err
+	}
+	if err := r.revealSubs(ctx, out...); err != nil {
+		return nil, fmt.Errorf("list subs by filter: %w", err)
+	}
+	return out, nil
+}
+
+// CostSubsByFilter sums the cost of the subscriptions matching the filter
+func (r *Repository) CostSubsByFilter(ctx context.Context, f usecase.SubFilter) (int64, error) {
+	return r.next.CostSubsByFilter(ctx, r.filter(f))
+}
+
+// CostGroupedByFilter sums the cost per value of by; groups by user get real user IDs and are re-ordered by them
+func (r *Repository) CostGroupedByFilter(ctx context.Context, f usecase.SubFilter, by usecase.CostGroupBy) ([]usecase.CostGroup, error) {
+	groups, err := r.next.CostGroupedByFilter(ctx, r.filter(f), by)
+	if err != nil || by != usecase.CostByUser {
+		return groups, err
+	}
+	ids := make([]entity.UserID, 0, len(groups))
+	for _, g := range groups {
+		ids = append(ids, g.UserID)
+	}
+	real, err := r.reveal(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("cost grouped by filter: %w", err)
+	}
+	for i := range groups {
+		if u, ok := real[groups[i].UserID]; ok {
+			groups[i].UserID = u
+		}
+	}
+	slices.SortFunc(groups, func(a, b usecase.CostGroup) int {
+		return strings.Compare(a.Key(by), b.Key(by))
+	})
+	return groups, nil
+}
+
+// CostSummaryByFilter returns the cost aggregates of the subscriptions matching the filter, which hold no user IDs
+func (r *Repository) CostSummaryByFilter(ctx context.Context, f usecase.SubFilter) (usecase.CostSummary, error) {
+	return r.next.CostSummaryByFilter(ctx, r.filter(f))
+}
+
+// DataVersion returns the version of the data of the pseudonym of the user, of all users when user is zero
+func (r *Repository) DataVersion(ctx context.Context, user entity.UserID) (usecase.DataVersion, error) {
+	return r.next.DataVersion(ctx, r.Pseudonym(user))
+}
+
+// ActiveStatsByService returns per-service statistics, which hold no user IDs
+func (r *Repository) ActiveStatsByService(ctx context.Context, month time.Time) ([]usecase.ServiceStats, error) {
+	return r.next.ActiveStatsByService(ctx, month)
+}
+
+// PriceBenchmarks returns per-service benchmarks, which hold no user IDs
+func (r *Repository) PriceBenchmarks(ctx context.Context, month time.Time, minUsers int) ([]usecase.PriceBenchmark, error) {
+	return r.next.PriceBenchmarks(ctx, month, minUsers)
+}
+
+// MonthlySpendByUser returns the per-user spend with real user IDs, ordered by user and month
+func (r *Repository) MonthlySpendByUser(ctx context.Context, from, to time.Time) ([]usecase.UserMonthSpend, error) {
+	rows, err := r.next.MonthlySpendByUser(ctx, from, to)
+	if err != nil {
+		return rows, err
+	}
+	if err := revealAll(ctx, r, rows, func(m *usecase.UserMonthSpend) *entity.UserID { return &m.UserID }); err != nil {
+		return nil, fmt.Errorf("monthly spend by user: %w", err)
+	}
+	sortSpend(rows)
+	return rows, nil
+}
+
+// sortSpend orders revealed rows by the real user ID and month again
+func sortSpend(rows []usecase.UserMonthSpend) {
+	slices.SortStableFunc(rows, func(a, b usecase.UserMonthSpend) int {
+		if c := strings.Compare(a.UserID.String(), b.UserID.String()); c != 0 {
+			return c
+		}
+		return a.Month.Compare(b.Month)
+	})
+}
+
+// ChangesSince reads the change log of the pseudonym of the user
+func (r *Repository) ChangesSince(ctx context.Context, user entity.UserID, since entity.ChangePos, limit int) ([]entity.SubscriptionChange, error) {
+	out, err := r.next.ChangesSince(ctx, r.Pseudonym(user), since, limit)
+	for i := range out {
+		out[i].UserID = user
+	}
+	return out, err
+}
+
+// ReassignUser moves subscriptions between the pseudonyms of the users
+func (r *Repository) ReassignUser(ctx context.Context, from, to entity.UserID, actor string) (int64, error) {
+	p, err := r.remember(ctx, to)
+	if err != nil {
+		return 0, fmt.Errorf("reassign user: %w", err)
+	}
+	return r.next.ReassignUser(ctx, r.Pseudonym(from), p, actor)
+}
+
+// MergeSubs merges two subscriptions, pseudonymizing the user of the merged one
+func (r *Repository) MergeSubs(ctx context.Context, merged, dropped *entity.Subscription, actor string) error {
+	if merged == nil || dropped == nil {
+		return fmt.Errorf("merge subs: %w", usecase.ErrInvalidSubscription)
+	}
+	p, err := r.remember(ctx, merged.UserID)
+	if err != nil {
+		return fmt.Errorf("merge subs: %w", err)
+	}
+	keep, drop := *merged, *dropped
+	keep.UserID, drop.UserID = p, r.Pseudonym(dropped.UserID)
+	return r.next.MergeSubs(ctx, &keep, &drop, actor)
+}
+
+// EndedBefore returns the ended subscriptions with their users revealed
+func (r *Repository) EndedBefore(ctx context.Context, before time.Time, limit int) ([]*entity.Subscription, error) {
+	out, err := r.next.EndedBefore(ctx, before, limit)
+	if err != nil {
+		return out, err
+	}
+	if err := r.revealSubs(ctx, out...); err != nil {
+		return nil, fmt.Errorf("ended before: %w", err)
+	}
+	return out, nil
+}
+
+// PurgeSubs deletes subscriptions by ID
+func (r *Repository) PurgeSubs(ctx context.Context, ids []int64) (int64, error) {
+	return r.next.PurgeSubs(ctx, ids)
+}
+
+// SaveAdjustment stores the adjustment, which holds no user IDs
+func (r *Repository) SaveAdjustment(ctx context.Context, a *entity.Adjustment) (*entity.Adjustment, error) {
+	return r.next.SaveAdjustment(ctx, a)
+}
+
+// ListAdjustments lists the adjustments of the subscription
+func (r *Repository) ListAdjustments(ctx context.Context, subID int64) ([]entity.Adjustment, error) {
+	return r.next.ListAdjustments(ctx, subID)
+}
+
+// SaveSeats stores the seats under the pseudonyms of their members
+func (r *Repository) SaveSeats(ctx context.Context, s *entity.Seats) (*entity.Seats, error) {
+	if s == nil {
+		return nil, fmt.Errorf("save seats: %w", usecase.ErrInvalidID)
+	}
+	in := *s
+	in.UserIDs = make([]entity.UserID, 0, len(s.UserIDs))
+	for _, id := range s.UserIDs {
+		p, err := r.remember(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("save seats: %w", err)
+		}
+		in.UserIDs = append(in.UserIDs, p)
+	}
+	out, err := r.next.SaveSeats(ctx, &in)
+	if err != nil || out == nil {
+		return out, err
+	}
+	return out, r.revealSeats(ctx, out)
+}
+
+// GetSeats reads the seats of the subscription, revealing their members
+func (r *Repository) GetSeats(ctx context.Context, subID int64) (*entity.Seats, error) {
+	out, err := r.next.GetSeats(ctx, subID)
+	if err != nil || out == nil {
+		return out, err
+	}
+	return out, r.revealSeats(ctx, out)
+}
+
+// revealSeats replaces the pseudonyms of the members in place
+func (r *Repository) revealSeats(ctx context.Context, s *entity.Seats) error {
+	real, err := r.reveal(ctx, s.UserIDs)
+	if err != nil {
+		return err
+	}
+	for i, p := range s.UserIDs {
+		if u, ok := real[p]; ok {
+			s.UserIDs[i] = u
+		}
+	}
+	return nil
+}
+
+// SeatSharesByFilter returns the seats held under the pseudonym of the user, which hold no user IDs
+func (r *Repository) SeatSharesByFilter(ctx context.Context, f usecase.SubFilter) ([]usecase.SeatShare, error) {
+	return r.next.SeatSharesByFilter(ctx, r.filter(f))
+}
+
+// GetSettings reads the settings stored under the pseudonym of the user
+func (r *Repository) GetSettings(ctx context.Context, userID entity.UserID) (*entity.Settings, error) {
+	out, err := r.next.GetSettings(ctx, r.Pseudonym(userID))
+	if out != nil {
+		out.UserID = userID
+	}
+	return out, err
+}
+
+// SaveSettings stores the settings under the pseudonym of the user
+func (r *Repository) SaveSettings(ctx context.Context, s entity.Settings) (*entity.Settings, error) {
+	userID := s.UserID
+	p, err := r.remember(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("save settings: %w", err)
+	}
+	s.UserID = p
+	out, err := r.next.SaveSettings(ctx, s)
+	if out != nil {
+		out.UserID = userID
+	}
+	return out, err
+}
+
+// DeactivateUser deactivates the pseudonym of the user, which the subscriptions are stored under
+func (r *Repository) DeactivateUser(ctx context.Context, userID entity.UserID, at time.Time) (time.Time, error) {
+	p, err := r.remember(ctx, userID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("deactivate user: %w", err)
+	}
+	return r.next.DeactivateUser(ctx, p, at)
+}
+
+// ReactivateUser reactivates the pseudonym of the user
+func (r *Repository) ReactivateUser(ctx context.Context, userID entity.UserID) error {
+	return r.next.ReactivateUser(ctx, r.Pseudonym(userID))
+}
+
+// UserDeactivatedAt reads the deactivation of the pseudonym of the user
+func (r *Repository) UserDeactivatedAt(ctx context.Context, userID entity.UserID) (time.Time, error) {
+	return r.next.UserDeactivatedAt(ctx, r.Pseudonym(userID))
+}
+
+// DeleteUser deletes the user under their pseudonym; the anonymous ID is random, it is stored as it is and has
+// no lookup entry to reveal
+func (r *Repository) DeleteUser(ctx context.Context, userID entity.UserID, d usecase.UserDeletion, actor string) (int64, error) {
+	return r.next.DeleteUser(ctx, r.Pseudonym(userID), d, actor)
+}
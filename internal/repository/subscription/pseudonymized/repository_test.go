@@ -0,0 +1,242 @@
+package pseudonymized
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/fieldcrypt"
+)
+
+// memRepo - in-memory repository implementing the methods the tests use
+type memRepo struct {
+	usecase.SubscriptionRepository
+	subs     []entity.Subscription
+	settings map[entity.UserID]entity.Settings
+	seats    *entity.Seats
+	filter   usecase.SubFilter
+}
+
+func (m *memRepo) DeleteUser(_ context.Context, userID entity.UserID, d usecase.UserDeletion, _ string) (int64, error) {
+	var n int64
+	for i := range m.subs {
+		if m.subs[i].UserID == userID {
+			m.subs[i].UserID = d.AnonymousID
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *memRepo) SaveSub(_ context.Context, s *entity.Subscription) (*entity.Subscription, error) {
+	out := *s
+	out.ID = int64(len(m.subs) + 1)
+	m.subs = append(m.subs, out)
+	return &out, nil
+}
+
+func (m *memRepo) GetSubByID(_ context.Context, id int64) (*entity.Subscription, error) {
+	for _, s := range m.subs {
+		if s.ID == id {
+			return &s, nil
+		}
+	}
+	return nil, usecase.ErrSubscriptionNotFound
+}
+
+func (m *memRepo) ListSubsByFilter(_ context.Context, f usecase.SubFilter) ([]*entity.Subscription, error) {
+	var out []*entity.Subscription
+	for _, s := range m.subs {
+		if f.UserID.IsZero() || s.UserID == f.UserID {
+			out = append(out, &s)
+		}
+	}
+	return out, nil
+}
+
+func (m *memRepo) MonthlySpendByUser(context.Context, time.Time, time.Time) ([]usecase.UserMonthSpend, error) {
+	var out []usecase.UserMonthSpend
+	for _, s := range m.subs {
+		out = append(out, usecase.UserMonthSpend{UserID: s.UserID, Month: s.DateFrom, Total: s.Cost})
+	}
+	return out, nil
+}
+
+func (m *memRepo) GetSettings(_ context.Context, userID entity.UserID) (*entity.Settings, error) {
+	s, ok := m.settings[userID]
+	if !ok {
+		return nil, usecase.ErrSettingsNotFound
+	}
+	return &s, nil
+}
+
+func (m *memRepo) SaveSettings(_ context.Context, s entity.Settings) (*entity.Settings, error) {
+	m.settings[s.UserID] = s
+	return &s, nil
+}
+
+func (m *memRepo) SaveSeats(_ context.Context, s *entity.Seats) (*entity.Seats, error) {
+	stored := *s
+	m.seats = &stored
+	out := stored
+	out.UserIDs = append([]entity.UserID(nil), stored.UserIDs...)
+	return &out, nil
+}
+
+func (m *memRepo) GetSeats(context.Context, int64) (*entity.Seats, error) {
+	out := *m.seats
+	out.UserIDs = append([]entity.UserID(nil), m.seats.UserIDs...)
+	return &out, nil
+}
+
+func (m *memRepo) SeatSharesByFilter(_ context.Context, f usecase.SubFilter) ([]usecase.SeatShare, error) {
+	m.filter = f
+	return nil, nil
+}
+
+// memLookup - in-memory lookup table
+type memLookup map[entity.UserID]string
+
+func (m memLookup) SavePseudonym(_ context.Context, p entity.UserID, sealed string) error {
+	if _, ok := m[p]; !ok {
+		m[p] = sealed
+	}
+	return nil
+}
+
+func (m memLookup) SealedUserIDs(_ context.Context, ps []entity.UserID) (map[entity.UserID]string, error) {
+	out := map[entity.UserID]string{}
+	for _, p := range ps {
+		if s, ok := m[p]; ok {
+			out[p] = s
+		}
+	}
+	return out, nil
+}
+
+func newRepo(t *testing.T, next *memRepo, lookup memLookup) *Repository {
+	t.Helper()
+	keyring, err := fieldcrypt.ParseKeyring("k1:" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)))
+	require.NoError(t, err)
+	r, err := NewRepository(next, lookup, bytes.Repeat([]byte{7}, MinKeySize), keyring)
+	require.NoError(t, err)
+	return r
+}
+
+func TestRepository_StoresPseudonyms(t *testing.T) {
+	ctx := context.Background()
+	next, lookup := &memRepo{settings: map[entity.UserID]entity.Settings{}}, memLookup{}
+	r := newRepo(t, next, lookup)
+	ann, bob := entity.UserID(uuid.New()), entity.UserID(uuid.New())
+	month := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	saved, err := r.SaveSub(ctx, &entity.Subscription{UserID: ann, ServiceName: "Netflix", Cost: 400, DateFrom: month})
+	require.NoError(t, err)
+	assert.Equal(t, ann, saved.UserID)
+	_, err = r.SaveSub(ctx, &entity.Subscription{UserID: bob, ServiceName: "Yandex", Cost: 300, DateFrom: month})
+	require.NoError(t, err)
+
+	stored := next.subs[0].UserID
+	assert.NotEqual(t, ann, stored, "the real ID never reaches the table")
+	assert.Equal(t, r.Pseudonym(ann), stored)
+	assert.Equal(t, byte(8), stored[6]>>4, "pseudonyms are version 8 UUIDs")
+	assert.Len(t, lookup, 2)
+	assert.NotContains(t, lookup[stored], ann.String())
+
+	list, err := r.ListSubsByFilter(ctx, usecase.SubFilter{UserID: ann})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, ann, list[0].UserID)
+
+	// a fresh instance has no cache and reads the lookup table
+	r = newRepo(t, next, lookup)
+	got, err := r.GetSubByID(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, bob, got.UserID)
+
+	spend, err := r.MonthlySpendByUser(ctx, month, month)
+	require.NoError(t, err)
+	require.Len(t, spend, 2)
+	assert.ElementsMatch(t, []entity.UserID{ann, bob}, []entity.UserID{spend[0].UserID, spend[1].UserID})
+	assert.Less(t, spend[0].UserID.String(), spend[1].UserID.String(), "rows stay ordered by the real user ID")
+}
+
+func TestRepository_Settings(t *testing.T) {
+	ctx := context.Background()
+	next := &memRepo{settings: map[entity.UserID]entity.Settings{}}
+	r := newRepo(t, next, memLookup{})
+	ann := entity.UserID(uuid.New())
+
+	_, err := r.GetSettings(ctx, ann)
+	assert.ErrorIs(t, err, usecase.ErrSettingsNotFound)
+	saved, err := r.SaveSettings(ctx, entity.DefaultSettings(ann))
+	require.NoError(t, err)
+	assert.Equal(t, ann, saved.UserID)
+	assert.Contains(t, next.settings, r.Pseudonym(ann))
+
+	got, err := r.GetSettings(ctx, ann)
+	require.NoError(t, err)
+	assert.Equal(t, ann, got.UserID)
+}
+
+func TestRepository_Seats(t *testing.T) {
+	ctx := context.Background()
+	next, lookup := &memRepo{}, memLookup{}
+	r := newRepo(t, next, lookup)
+	ann, bob := entity.UserID(uuid.New()), entity.UserID(uuid.New())
+
+	saved, err := r.SaveSeats(ctx, &entity.Seats{SubscriptionID: 1, Total: 3, UserIDs: []entity.UserID{ann, bob}})
+	require.NoError(t, err)
+	assert.Equal(t, []entity.UserID{ann, bob}, saved.UserIDs)
+	assert.Equal(t, []entity.UserID{r.Pseudonym(ann), r.Pseudonym(bob)}, next.seats.UserIDs, "members are stored as pseudonyms")
+	assert.Len(t, lookup, 2)
+
+	got, err := newRepo(t, next, lookup).GetSeats(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []entity.UserID{ann, bob}, got.UserIDs)
+
+	_, err = r.SeatSharesByFilter(ctx, usecase.SubFilter{UserID: bob})
+	require.NoError(t, err)
+	assert.Equal(t, r.Pseudonym(bob), next.filter.UserID)
+}
+
+func TestRepository_DeleteUser(t *testing.T) {
+	ctx := context.Background()
+	next, lookup := &memRepo{}, memLookup{}
+	r := newRepo(t, next, lookup)
+	user, anon := entity.UserID(uuid.New()), entity.UserID(uuid.New())
+	_, err := r.SaveSub(ctx, &entity.Subscription{UserID: user, ServiceName: "Netflix"})
+	require.NoError(t, err)
+
+	n, err := r.DeleteUser(ctx, user, usecase.UserDeletion{Policy: usecase.DeleteUserAnonymize, AnonymousID: anon}, "admin")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n, "found under the pseudonym")
+	got, err := r.GetSubByID(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, anon, got.UserID)
+	assert.Len(t, lookup, 1, "the anonymous ID gets no lookup entry")
+}
+
+func TestRepository_UnknownPseudonym(t *testing.T) {
+	ctx := context.Background()
+	legacy := entity.UserID(uuid.New())
+	next := &memRepo{subs: []entity.Subscription{{ID: 1, UserID: legacy}}}
+	r := newRepo(t, next, memLookup{})
+
+	got, err := r.GetSubByID(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, legacy, got.UserID, "rows written before pseudonymization are returned as stored")
+}
+
+func TestNewRepository_Invalid(t *testing.T) {
+	_, err := NewRepository(&memRepo{}, memLookup{}, []byte("short"), nil)
+	assert.Error(t, err)
+}
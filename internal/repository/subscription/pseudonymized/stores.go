@@ -0,0 +1,337 @@
+package pseudonymized
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"subs_tracker/internal/activity"
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/pricecheck"
+	"subs_tracker/internal/quarantine"
+	"subs_tracker/internal/readmodel"
+	"subs_tracker/internal/reconcile"
+	"subs_tracker/internal/usecase"
+)
+
+// The stores below keep the user IDs of the tables next to the subscriptions under the same pseudonyms as
+// the Repository they are built from, so one key and one lookup table cover the whole database.
+
+// rememberAll replaces the user IDs of items with their pseudonyms in place, storing new lookup entries
+func rememberAll[T any](ctx context.Context, r *Repository, items []T, user func(*T) *entity.UserID) error {
+	for i := range items {
+		p, err := r.remember(ctx, *user(&items[i]))
+		if err != nil {
+			return err
+		}
+		*user(&items[i]) = p
+	}
+	return nil
+}
+
+// revealAll replaces the pseudonyms of items with the real user IDs in place
+func revealAll[T any](ctx context.Context, r *Repository, items []T, user func(*T) *entity.UserID) error {
+	ids := make([]entity.UserID, 0, len(items))
+	for i := range items {
+		ids = append(ids, *user(&items[i]))
+	}
+	real, err := r.reveal(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		if u, ok := real[*user(&items[i])]; ok {
+			*user(&items[i]) = u
+		}
+	}
+	return nil
+}
+
+// ActivityStore — activity.Store keeping the feeds under the pseudonyms of their users
+type ActivityStore struct {
+	next activity.Store
+	r    *Repository
+}
+
+var _ activity.Store = (*ActivityStore)(nil)
+
+// NewActivityStore wraps next with the pseudonyms of r
+func NewActivityStore(next activity.Store, r *Repository) *ActivityStore {
+	return &ActivityStore{next: next, r: r}
+}
+
+// AddEntry appends the entry to the feed of the pseudonym of its user
+func (s *ActivityStore) AddEntry(ctx context.Context, e *activity.Entry) error {
+	in := *e
+	p, err := s.r.remember(ctx, e.UserID)
+	if err != nil {
+		return fmt.Errorf("add activity: %w", err)
+	}
+	in.UserID = p
+	if err := s.next.AddEntry(ctx, &in); err != nil {
+		return err
+	}
+	e.ID = in.ID
+	return nil
+}
+
+// ListEntries reads the feed of the pseudonym of the user
+func (s *ActivityStore) ListEntries(ctx context.Context, userID entity.UserID, before int64, limit int) ([]activity.Entry, error) {
+	out, err := s.next.ListEntries(ctx, s.r.Pseudonym(userID), before, limit)
+	for i := range out {
+		out[i].UserID = userID
+	}
+	return out, err
+}
+
+// DeleteEntries removes the feed of the pseudonym of the user
+func (s *ActivityStore) DeleteEntries(ctx context.Context, userID entity.UserID) (int64, error) {
+	return s.next.DeleteEntries(ctx, s.r.Pseudonym(userID))
+}
+
+// SpendStore — readmodel.Store keeping the spend changes under the pseudonyms of their users
+type SpendStore struct {
+	next readmodel.Store
+	r    *Repository
+}
+
+var _ readmodel.Store = (*SpendStore)(nil)
+
+// NewSpendStore wraps next with the pseudonyms of r
+func NewSpendStore(next readmodel.Store, r *Repository) *SpendStore {
+	return &SpendStore{next: next, r: r}
+}
+
+// ReplaceUser replaces the changes of the pseudonym of the user
+func (s *SpendStore) ReplaceUser(ctx context.Context, user entity.UserID, changes []readmodel.Change) error {
+	in := slices.Clone(changes)
+	if err := rememberAll(ctx, s.r, in, func(c *readmodel.Change) *entity.UserID { return &c.UserID }); err != nil {
+		return fmt.Errorf("replace spend changes: %w", err)
+	}
+	return s.next.ReplaceUser(ctx, s.r.Pseudonym(user), in)
+}
+
+// ReplaceAll replaces the whole projection, storing every user under their pseudonym
+func (s *SpendStore) ReplaceAll(ctx context.Context, changes []readmodel.Change) error {
+	in := slices.Clone(changes)
+	if err := rememberAll(ctx, s.r, in, func(c *readmodel.Change) *entity.UserID { return &c.UserID }); err != nil {
+		return fmt.Errorf("replace spend changes: %w", err)
+	}
+	return s.next.ReplaceAll(ctx, in)
+}
+
+// MonthlySpendByUser returns the per-user spend with real user IDs, ordered by user and month
+func (s *SpendStore) MonthlySpendByUser(ctx context.Context, from, to time.Time) ([]usecase.UserMonthSpend, error) {
+	rows, err := s.next.MonthlySpendByUser(ctx, from, to)
+	if err != nil {
+		return rows, err
+	}
+	if err := revealAll(ctx, s.r, rows, func(m *usecase.UserMonthSpend) *entity.UserID { return &m.UserID }); err != nil {
+		return nil, fmt.Errorf("monthly spend by user: %w", err)
+	}
+	sortSpend(rows)
+	return rows, nil
+}
+
+// ReviewStore — pricecheck.Store keeping the reviews under the pseudonyms of their users
+type ReviewStore struct {
+	next pricecheck.Store
+	r    *Repository
+}
+
+var _ pricecheck.Store = (*ReviewStore)(nil)
+
+// NewReviewStore wraps next with the pseudonyms of r
+func NewReviewStore(next pricecheck.Store, r *Repository) *ReviewStore {
+	return &ReviewStore{next: next, r: r}
+}
+
+// OpenReview stores the review under the pseudonym of its user
+func (s *ReviewStore) OpenReview(ctx context.Context, rv *pricecheck.Review) (bool, error) {
+	in := *rv
+	p, err := s.r.remember(ctx, rv.UserID)
+	if err != nil {
+		return false, fmt.Errorf("open price review: %w", err)
+	}
+	in.UserID = p
+	ok, err := s.next.OpenReview(ctx, &in)
+	rv.ID = in.ID
+	return ok, err
+}
+
+// ListOpen lists the open reviews and reveals their users
+func (s *ReviewStore) ListOpen(ctx context.Context, limit int) ([]pricecheck.Review, error) {
+	out, err := s.next.ListOpen(ctx, limit)
+	if err != nil {
+		return out, err
+	}
+	if err := revealAll(ctx, s.r, out, func(rv *pricecheck.Review) *entity.UserID { return &rv.UserID }); err != nil {
+		return nil, fmt.Errorf("list price reviews: %w", err)
+	}
+	return out, nil
+}
+
+// Resolve closes the review and reveals its user
+func (s *ReviewStore) Resolve(ctx context.Context, id int64, at time.Time) (pricecheck.Review, error) {
+	out, err := s.next.Resolve(ctx, id, at)
+	if err != nil {
+		return out, err
+	}
+	one := []pricecheck.Review{out}
+	if err := revealAll(ctx, s.r, one, func(rv *pricecheck.Review) *entity.UserID { return &rv.UserID }); err != nil {
+		return pricecheck.Review{}, fmt.Errorf("resolve price review: %w", err)
+	}
+	return one[0], nil
+}
+
+// DeleteUser removes the reviews of the pseudonym of the user
+func (s *ReviewStore) DeleteUser(ctx context.Context, user entity.UserID) error {
+	return s.next.DeleteUser(ctx, s.r.Pseudonym(user))
+}
+
+// LedgerStore — reconcile.Store keeping the charges and the items under the pseudonyms of their users
+type LedgerStore struct {
+	next reconcile.Store
+	r    *Repository
+}
+
+var _ reconcile.Store = (*LedgerStore)(nil)
+
+// NewLedgerStore wraps next with the pseudonyms of r
+func NewLedgerStore(next reconcile.Store, r *Repository) *LedgerStore {
+	return &LedgerStore{next: next, r: r}
+}
+
+// RecordCharges stores the charges under the pseudonyms of their users
+func (s *LedgerStore) RecordCharges(ctx context.Context, charges []reconcile.Charge) (int, error) {
+	in := slices.Clone(charges)
+	if err := rememberAll(ctx, s.r, in, func(c *reconcile.Charge) *entity.UserID { return &c.UserID }); err != nil {
+		return 0, fmt.Errorf("record charges: %w", err)
+	}
+	return s.next.RecordCharges(ctx, in)
+}
+
+// ChargedUsers returns the real IDs of the users with charges in the month
+func (s *LedgerStore) ChargedUsers(ctx context.Context, month time.Time) ([]entity.UserID, error) {
+	out, err := s.next.ChargedUsers(ctx, month)
+	if err != nil {
+		return out, err
+	}
+	if err := revealAll(ctx, s.r, out, func(u *entity.UserID) *entity.UserID { return u }); err != nil {
+		return nil, fmt.Errorf("charged users: %w", err)
+	}
+	return out, nil
+}
+
+// Charges reads the charges of the pseudonym of the user
+func (s *LedgerStore) Charges(ctx context.Context, user entity.UserID, month time.Time) ([]reconcile.Charge, error) {
+	out, err := s.next.Charges(ctx, s.r.Pseudonym(user), month)
+	for i := range out {
+		out[i].UserID = user
+	}
+	return out, err
+}
+
+// OpenItem stores the item under the pseudonym of its user
+func (s *LedgerStore) OpenItem(ctx context.Context, it *reconcile.Item) (bool, error) {
+	in := *it
+	p, err := s.r.remember(ctx, it.UserID)
+	if err != nil {
+		return false, fmt.Errorf("open reconcile item: %w", err)
+	}
+	in.UserID = p
+	ok, err := s.next.OpenItem(ctx, &in)
+	it.ID = in.ID
+	return ok, err
+}
+
+// ListOpen lists the open items, of the pseudonym of the user when it is set, and reveals their users
+func (s *LedgerStore) ListOpen(ctx context.Context, user *entity.UserID, limit int) ([]reconcile.Item, error) {
+	if user != nil {
+		p := s.r.Pseudonym(*user)
+		user = &p
+	}
+	out, err := s.next.ListOpen(ctx, user, limit)
+	if err != nil {
+		return out, err
+	}
+	if err := revealAll(ctx, s.r, out, func(it *reconcile.Item) *entity.UserID { return &it.UserID }); err != nil {
+		return nil, fmt.Errorf("list reconcile items: %w", err)
+	}
+	return out, nil
+}
+
+// Resolve closes the item and reveals its user
+func (s *LedgerStore) Resolve(ctx context.Context, id int64, at time.Time) (reconcile.Item, error) {
+	out, err := s.next.Resolve(ctx, id, at)
+	if err != nil {
+		return out, err
+	}
+	one := []reconcile.Item{out}
+	if err := revealAll(ctx, s.r, one, func(it *reconcile.Item) *entity.UserID { return &it.UserID }); err != nil {
+		return reconcile.Item{}, fmt.Errorf("resolve reconcile item: %w", err)
+	}
+	return one[0], nil
+}
+
+// DeleteUser removes the charges and the items of the pseudonym of the user
+func (s *LedgerStore) DeleteUser(ctx context.Context, user entity.UserID) error {
+	return s.next.DeleteUser(ctx, s.r.Pseudonym(user))
+}
+
+// QuarantineStore — quarantine.Store keeping the held import rows under the pseudonyms of their users
+type QuarantineStore struct {
+	next quarantine.Store
+	r    *Repository
+}
+
+var _ quarantine.Store = (*QuarantineStore)(nil)
+
+// NewQuarantineStore wraps next with the pseudonyms of r
+func NewQuarantineStore(next quarantine.Store, r *Repository) *QuarantineStore {
+	return &QuarantineStore{next: next, r: r}
+}
+
+// Hold stores the rows under the pseudonyms of their users
+func (s *QuarantineStore) Hold(ctx context.Context, rows []quarantine.Row) error {
+	in := slices.Clone(rows)
+	if err := rememberAll(ctx, s.r, in, func(row *quarantine.Row) *entity.UserID { return &row.UserID }); err != nil {
+		return fmt.Errorf("hold rows: %w", err)
+	}
+	if err := s.next.Hold(ctx, in); err != nil {
+		return err
+	}
+	for i := range rows {
+		rows[i].ID = in[i].ID
+	}
+	return nil
+}
+
+// List reads the rows of the pseudonym of the user
+func (s *QuarantineStore) List(ctx context.Context, user entity.UserID, limit int) ([]quarantine.Row, error) {
+	out, err := s.next.List(ctx, s.r.Pseudonym(user), limit)
+	for i := range out {
+		out[i].UserID = user
+	}
+	return out, err
+}
+
+// Get reads the row of the pseudonym of the user
+func (s *QuarantineStore) Get(ctx context.Context, user entity.UserID, id int64) (quarantine.Row, error) {
+	out, err := s.next.Get(ctx, s.r.Pseudonym(user), id)
+	if err == nil {
+		out.UserID = user
+	}
+	return out, err
+}
+
+// Delete removes the row of the pseudonym of the user
+func (s *QuarantineStore) Delete(ctx context.Context, user entity.UserID, id int64) error {
+	return s.next.Delete(ctx, s.r.Pseudonym(user), id)
+}
+
+// DeleteUser removes the rows of the pseudonym of the user
+func (s *QuarantineStore) DeleteUser(ctx context.Context, user entity.UserID) error {
+	return s.next.DeleteUser(ctx, s.r.Pseudonym(user))
+}
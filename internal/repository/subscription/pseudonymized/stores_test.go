@@ -0,0 +1,177 @@
+package pseudonymized
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/internal/activity"
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/pricecheck"
+	"subs_tracker/internal/quarantine"
+	"subs_tracker/internal/readmodel"
+	"subs_tracker/internal/reconcile"
+	"subs_tracker/internal/storetest"
+)
+
+func TestActivityStore(t *testing.T) {
+	ctx := context.Background()
+	r := newRepo(t, &memRepo{}, memLookup{})
+	next := storetest.NewActivityStore()
+	s := NewActivityStore(next, r)
+	ann := entity.UserID(uuid.New())
+
+	e := activity.Entry{UserID: ann, Kind: activity.KindCreated, ServiceName: "Netflix"}
+	require.NoError(t, s.AddEntry(ctx, &e))
+	assert.NotZero(t, e.ID)
+	assert.Equal(t, ann, e.UserID)
+
+	raw, err := next.ListEntries(ctx, ann, 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, raw, "the real ID never reaches the table")
+	raw, err = next.ListEntries(ctx, r.Pseudonym(ann), 0, 10)
+	require.NoError(t, err)
+	assert.Len(t, raw, 1)
+
+	got, err := s.ListEntries(ctx, ann, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, ann, got[0].UserID)
+
+	n, err := s.DeleteEntries(ctx, ann)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+}
+
+func TestSpendStore(t *testing.T) {
+	ctx := context.Background()
+	lookup := memLookup{}
+	r := newRepo(t, &memRepo{}, lookup)
+	s := NewSpendStore(storetest.NewSpendStore(), r)
+	ann, bob := entity.UserID(uuid.New()), entity.UserID(uuid.New())
+	month := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, s.ReplaceAll(ctx, []readmodel.Change{
+		{UserID: ann, Month: month, Cost: 400, Subs: 1},
+		{UserID: bob, Month: month, Cost: 300, Subs: 1},
+	}))
+	require.NoError(t, s.ReplaceUser(ctx, ann, []readmodel.Change{{UserID: ann, Month: month, Cost: 500, Subs: 1}}))
+	assert.Len(t, lookup, 2)
+
+	spend, err := NewSpendStore(s.next, newRepo(t, &memRepo{}, lookup)).MonthlySpendByUser(ctx, month, month)
+	require.NoError(t, err)
+	require.Len(t, spend, 2)
+	byUser := map[entity.UserID]int64{spend[0].UserID: spend[0].Total, spend[1].UserID: spend[1].Total}
+	assert.Equal(t, map[entity.UserID]int64{ann: 500, bob: 300}, byUser)
+	assert.Less(t, spend[0].UserID.String(), spend[1].UserID.String(), "rows stay ordered by the real user ID")
+}
+
+func TestReviewStore(t *testing.T) {
+	ctx := context.Background()
+	r := newRepo(t, &memRepo{}, memLookup{})
+	next := storetest.NewReviewStore()
+	s := NewReviewStore(next, r)
+	ann := entity.UserID(uuid.New())
+
+	rv := pricecheck.Review{SubscriptionID: 1, UserID: ann, ServiceName: "Netflix", Cost: 400, CatalogPrice: 500}
+	ok, err := s.OpenReview(ctx, &rv)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	raw, err := next.ListOpen(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, raw, 1)
+	assert.Equal(t, r.Pseudonym(ann), raw[0].UserID)
+
+	got, err := s.ListOpen(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, ann, got[0].UserID)
+	resolved, err := s.Resolve(ctx, rv.ID, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, ann, resolved.UserID)
+
+	_, err = s.OpenReview(ctx, &pricecheck.Review{SubscriptionID: 1, UserID: ann, ServiceName: "Netflix", Cost: 400, CatalogPrice: 600})
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteUser(ctx, ann))
+	raw, err = next.ListOpen(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, raw, "the reviews of the pseudonym are gone")
+}
+
+func TestLedgerStore(t *testing.T) {
+	ctx := context.Background()
+	r := newRepo(t, &memRepo{}, memLookup{})
+	next := storetest.NewLedgerStore()
+	s := NewLedgerStore(next, r)
+	ann := entity.UserID(uuid.New())
+	month := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	n, err := s.RecordCharges(ctx, []reconcile.Charge{{UserID: ann, ServiceName: "Netflix", Date: month, Amount: 400}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	raw, err := next.ChargedUsers(ctx, month)
+	require.NoError(t, err)
+	assert.Equal(t, []entity.UserID{r.Pseudonym(ann)}, raw, "the real ID never reaches the table")
+
+	users, err := s.ChargedUsers(ctx, month)
+	require.NoError(t, err)
+	assert.Equal(t, []entity.UserID{ann}, users)
+	charges, err := s.Charges(ctx, ann, month)
+	require.NoError(t, err)
+	require.Len(t, charges, 1)
+	assert.Equal(t, ann, charges[0].UserID)
+
+	it := reconcile.Item{Kind: reconcile.KindUnexpected, UserID: ann, ServiceName: "Netflix", Month: month, Charged: 400}
+	ok, err := s.OpenItem(ctx, &it)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	items, err := s.ListOpen(ctx, &ann, 10)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, ann, items[0].UserID)
+	resolved, err := s.Resolve(ctx, it.ID, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, ann, resolved.UserID)
+
+	require.NoError(t, s.DeleteUser(ctx, ann))
+	raw, err = next.ChargedUsers(ctx, month)
+	require.NoError(t, err)
+	assert.Empty(t, raw, "the charges of the pseudonym are gone")
+}
+
+func TestQuarantineStore(t *testing.T) {
+	ctx := context.Background()
+	r := newRepo(t, &memRepo{}, memLookup{})
+	next := storetest.NewQuarantineStore()
+	s := NewQuarantineStore(next, r)
+	ann := entity.UserID(uuid.New())
+
+	rows := []quarantine.Row{{UserID: ann, Key: "n1", ServiceName: "Netflix", Cost: -400}}
+	require.NoError(t, s.Hold(ctx, rows))
+	assert.NotZero(t, rows[0].ID)
+	assert.Equal(t, ann, rows[0].UserID)
+
+	raw, err := next.List(ctx, ann, 10)
+	require.NoError(t, err)
+	assert.Empty(t, raw, "the real ID never reaches the table")
+
+	got, err := s.List(ctx, ann, 10)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, ann, got[0].UserID)
+	one, err := s.Get(ctx, ann, rows[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, ann, one.UserID)
+	require.NoError(t, s.Delete(ctx, ann, rows[0].ID))
+
+	require.NoError(t, s.Hold(ctx, []quarantine.Row{{UserID: ann, Key: "n2", ServiceName: "Netflix", Cost: -400}}))
+	require.NoError(t, s.DeleteUser(ctx, ann))
+	raw, err = next.List(ctx, r.Pseudonym(ann), 10)
+	require.NoError(t, err)
+	assert.Empty(t, raw, "the rows of the pseudonym are gone")
+}
@@ -0,0 +1,668 @@
+// Package sharded spreads subscriptions over several repositories by a hash of user_id
+package sharded
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"subs_tracker/internal/concurrency"
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/pagination"
+)
+
+// ErrCrossShard - the operation would move data between shards, which is not atomic and therefore refused
+var ErrCrossShard = errors.New("users are stored on different shards")
+
+// Router — usecase.SubscriptionRepository routing every user to one shard by a hash of the user ID.
+// Queries not bound to a user are scattered to all shards at once and their results merged.
+//
+// Subscription IDs are shard-local sequences, so the router exposes them as local*N + shard, N being
+// the number of shards. With a single shard IDs are unchanged; the number of shards must not change
+// once data is stored, since both the IDs and the user placement depend on it.
+type Router struct {
+	shards []usecase.SubscriptionRepository
+}
+
+// NewRouter creates a router over shards; their order is part of the data layout and must stay stable
+func NewRouter(shards ...usecase.SubscriptionRepository) *Router {
+	if len(shards) == 0 {
+		panic("sharded: no shards")
+	}
+	return &Router{shards: shards}
+}
+
+// shardOf returns the index of the shard storing the user
+func (r *Router) shardOf(userID entity.UserID) int {
+	h := fnv.New32a()
+	_, _ = h.Write(userID[:])
+	return int(h.Sum32() % uint32(len(r.shards)))
+}
+
+// globalID converts a shard-local ID into the ID seen by callers
+func (r *Router) globalID(local int64, shard int) int64 {
+	return local*int64(len(r.shards)) + int64(shard)
+}
+
+// localID splits a caller-visible ID into its shard and the shard-local ID
+func (r *Router) localID(id int64) (int, int64) {
+	if id <= 0 {
+		// invalid anyway: let the first shard reject it the usual way
+		return 0, id
+	}
+	n := int64(len(r.shards))
+	return int(id % n), id / n
+}
+
+func (r *Router) toGlobal(sub *entity.Subscription, shard int) *entity.Subscription {
+	if sub != nil {
+		sub.ID = r.globalID(sub.ID, shard)
+	}
+	return sub
+}
+
+// SaveSub stores the subscription on the shard of its user
+func (r *Router) SaveSub(ctx context.Context, s *entity.Subscription) (*entity.Subscription, error) {
+	if s == nil {
+		return nil, fmt.Errorf("save sub: %w", usecase.ErrInvalidSubscription)
+	}
+	shard := r.shardOf(s.UserID)
+	out, err := r.shards[shard].SaveSub(ctx, s)
+	return r.toGlobal(out, shard), err
+}
+
+// SaveImportedSub stores the subscription and its import key on the shard of its user
+func (r *Router) SaveImportedSub(ctx context.Context, key string, s *entity.Subscription) (*entity.Subscription, bool, error) {
+	if s == nil {
+		return nil, false, fmt.Errorf("save imported sub: %w", usecase.ErrInvalidSubscription)
+	}
+	shard := r.shardOf(s.UserID)
+	out, created, err := r.shards[shard].SaveImportedSub(ctx, key, s)
+	return r.toGlobal(out, shard), created, err
+}
+
+// UpdateSub updates the subscription in place; moving it to a user of another shard is refused
+func (r *Router) UpdateSub(ctx context.Context, s *entity.Subscription) error {
+	if s == nil {
+		return fmt.Errorf("update sub: %w", usecase.ErrInvalidSubscription)
+	}
+	shard, local := r.localID(s.ID)
+	if s.ID > 0 && !s.UserID.IsZero() && r.shardOf(s.UserID) != shard {
+		return fmt.Errorf("update sub: %w: %w", usecase.ErrInvalidSubscription, ErrCrossShard)
+	}
+	id := s.ID
+	s.ID = local
+	err := r.shards[shard].UpdateSub(ctx, s)
+	s.ID = id
+	return err
+}
+
+// DeleteSub deletes the subscription on the shard encoded in its ID
+func (r *Router) DeleteSub(ctx context.Context, id int64, version time.Time) error {
+	shard, local := r.localID(id)
+	return r.shards[shard].DeleteSub(ctx, local, version)
+}
+
+// GetSubByID reads the subscription from the shard encoded in its ID
+func (r *Router) GetSubByID(ctx context.Context, id int64) (*entity.Subscription, error) {
+	shard, local := r.localID(id)
+	out, err := r.shards[shard].GetSubByID(ctx, local)
+	return r.toGlobal(out, shard), err
+}
+
+// GetSubByIDForUpdate reads and locks the subscription on the shard encoded in its ID
+func (r *Router) GetSubByIDForUpdate(ctx context.Context, id int64) (*entity.Subscription, error) {
+	shard, local := r.localID(id)
+	out, err := r.shards[shard].GetSubByIDForUpdate(ctx, local)
+	return r.toGlobal(out, shard), err
+}
+
+// InTx runs fn in a transaction of every shard, since the subscriptions fn touches may be on any of them.
+// The shards commit one after another, so a failed commit can leave the earlier shards committed; the
+// writes of a single user stay on one shard and are not affected
+func (r *Router) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.inTx(ctx, 0, fn)
+}
+
+// inTx nests the transactions of the shards from shard on around fn
+func (r *Router) inTx(ctx context.Context, shard int, fn func(ctx context.Context) error) error {
+	if shard == len(r.shards) {
+		return fn(ctx)
+	}
+	return r.shards[shard].InTx(ctx, func(ctx context.Context) error {
+		return r.inTx(ctx, shard+1, fn)
+	})
+}
+
+// GetSubByPublicID asks every shard in turn: public IDs are made without knowing the shard, so they name
+// nothing about it
+func (r *Router) GetSubByPublicID(ctx context.Context, id entity.PublicID) (*entity.Subscription, error) {
+	for shard, repo := range r.shards {
+		out, err := repo.GetSubByPublicID(ctx, id)
+		if errors.Is(err, usecase.ErrSubscriptionNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return r.toGlobal(out, shard), nil
+	}
+	return nil, usecase.ErrSubscriptionNotFound
+}
+
+// ListSubsByFilter lists the subscriptions of one user from its shard, or merges all shards in the
+// repository order, usecase.CompareListOrder, when no user is given
+func (r *Router) ListSubsByFilter(ctx context.Context, f usecase.SubFilter) ([]*entity.Subscription, error) {
+	limit, offset, err := pagination.DefaultLimits().Normalize(f.Limit, max(f.Offset, 0))
+	if err != nil {
+		return nil, fmt.Errorf("list subs by filter: %w", err)
+	}
+	shards := r.targets(f.UserID)
+	if len(shards) == 1 {
+		shard := shards[0]
+		if f.After != nil {
+			f.After = r.localCursor(f.After, shard)
+		}
+		rows, err := r.shards[shard].ListSubsByFilter(ctx, f)
+		for _, s := range rows {
+			r.toGlobal(s, shard)
+		}
+		return rows, err
+	}
+	if f.After != nil {
+		offset = 0
+	}
+	heads := make([][]*entity.Subscription, len(r.shards))
+	cursors := make([]*usecase.ListCursor, len(r.shards))
+	done := make([]bool, len(r.shards))
+	// fetch loads the next page of a shard, continuing after the last row it returned
+	fetch := func(shard int) error {
+		page := f
+		page.Limit, page.Offset = limit, 0
+		page.After = cursors[shard]
+		if page.After == nil && f.After != nil {
+			page.After = r.localCursor(f.After, shard)
+		}
+		rows, err := r.shards[shard].ListSubsByFilter(ctx, page)
+		if err != nil {
+			return err
+		}
+		if len(rows) < limit {
+			done[shard] = true
+		}
+		if len(rows) > 0 {
+			last := rows[len(rows)-1]
+			cursors[shard] = usecase.CursorAt(last)
+		}
+		for _, s := range rows {
+			heads[shard] = append(heads[shard], r.toGlobal(s, shard))
+		}
+		return nil
+	}
+
+	out := make([]*entity.Subscription, 0, limit)
+	for skipped := 0; len(out) < limit; {
+		best := -1
+		for _, shard := range shards {
+			if len(heads[shard]) == 0 && !done[shard] {
+				if err := fetch(shard); err != nil {
+					return nil, fmt.Errorf("list subs by filter: %w", err)
+				}
+			}
+			if len(heads[shard]) > 0 && (best < 0 || usecase.CompareListOrder(heads[shard][0], heads[best][0]) < 0) {
+				best = shard
+			}
+		}
+		if best < 0 {
+			break
+		}
+		next := heads[best][0]
+		heads[best] = heads[best][1:]
+		if skipped < offset {
+			skipped++
+			continue
+		}
+		out = append(out, next)
+	}
+	return out, nil
+}
+
+// localCursor translates a keyset position into the one of a shard: with equal start date and service
+// name the shard must continue after the largest local ID whose global ID does not exceed the position
+func (r *Router) localCursor(c *usecase.ListCursor, shard int) *usecase.ListCursor {
+	n := int64(len(r.shards))
+	d := c.ID - int64(shard)
+	local := d / n
+	if d < 0 && d%n != 0 {
+		local--
+	}
+	return &usecase.ListCursor{StartDate: c.StartDate, ServiceName: c.ServiceName, ID: local}
+}
+
+// targets returns the shards a filter has to visit
+func (r *Router) targets(userID entity.UserID) []int {
+	if !userID.IsZero() {
+		return []int{r.shardOf(userID)}
+	}
+	return r.all()
+}
+
+// all returns every shard
+func (r *Router) all() []int {
+	all := make([]int, len(r.shards))
+	for i := range all {
+		all[i] = i
+	}
+	return all
+}
+
+// gather asks the shards at once and returns their answers in the order of shards; the first error cancels
+// the other queries
+func gather[R any](ctx context.Context, shards []int, ask func(ctx context.Context, shard int) (R, error)) ([]R, error) {
+	return concurrency.Map(ctx, len(shards), shards, ask)
+}
+
+// CostSubsByFilter sums the cost over the shards the filter touches
+func (r *Router) CostSubsByFilter(ctx context.Context, f usecase.SubFilter) (int64, error) {
+	costs, err := gather(ctx, r.targets(f.UserID), func(ctx context.Context, shard int) (int64, error) {
+		return r.shards[shard].CostSubsByFilter(ctx, f)
+	})
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, cost := range costs {
+		total += cost
+	}
+	return total, nil
+}
+
+// CostGroupedByFilter adds up the groups of the shards the filter touches; a service or month spans shards
+func (r *Router) CostGroupedByFilter(ctx context.Context, f usecase.SubFilter, by usecase.CostGroupBy) ([]usecase.CostGroup, error) {
+	targets := r.targets(f.UserID)
+	if len(targets) == 1 {
+		return r.shards[targets[0]].CostGroupedByFilter(ctx, f, by)
+	}
+	perShard, err := gather(ctx, targets, func(ctx context.Context, shard int) ([]usecase.CostGroup, error) {
+		return r.shards[shard].CostGroupedByFilter(ctx, f, by)
+	})
+	if err != nil {
+		return nil, err
+	}
+	byKey := map[string]*usecase.CostGroup{}
+	for _, groups := range perShard {
+		for _, g := range groups {
+			key := g.Key(by)
+			if sum, ok := byKey[key]; ok {
+				sum.Total += g.Total
+				sum.Count += g.Count
+				continue
+			}
+			byKey[key] = &g
+		}
+	}
+	out := make([]usecase.CostGroup, 0, len(byKey))
+	for _, g := range byKey {
+		out = append(out, *g)
+	}
+	slices.SortFunc(out, func(a, b usecase.CostGroup) int {
+		return strings.Compare(a.Key(by), b.Key(by))
+	})
+	return out, nil
+}
+
+// CostSummaryByFilter adds up the cost aggregates of the shards the filter touches
+func (r *Router) CostSummaryByFilter(ctx context.Context, f usecase.SubFilter) (usecase.CostSummary, error) {
+	summaries, err := gather(ctx, r.targets(f.UserID), func(ctx context.Context, shard int) (usecase.CostSummary, error) {
+		return r.shards[shard].CostSummaryByFilter(ctx, f)
+	})
+	if err != nil {
+		return usecase.CostSummary{}, err
+	}
+	var sum usecase.CostSummary
+	for _, s := range summaries {
+		// a shard with adjustments only still adds to the total
+		sum.Total += s.Total
+		if s.Count == 0 {
+			continue
+		}
+		if sum.Count == 0 || s.MinCost < sum.MinCost {
+			sum.MinCost = s.MinCost
+		}
+		sum.MaxCost = max(sum.MaxCost, s.MaxCost)
+		sum.Count += s.Count
+		sum.MonthlyCost += s.MonthlyCost
+	}
+	return sum, nil
+}
+
+// DataVersion adds up the versions of the shards the user touches, which only grow, and keeps the latest change
+func (r *Router) DataVersion(ctx context.Context, user entity.UserID) (usecase.DataVersion, error) {
+	versions, err := gather(ctx, r.targets(user), func(ctx context.Context, shard int) (usecase.DataVersion, error) {
+		return r.shards[shard].DataVersion(ctx, user)
+	})
+	if err != nil {
+		return usecase.DataVersion{}, err
+	}
+	var out usecase.DataVersion
+	for _, v := range versions {
+		out.Version += v.Version
+		if v.ChangedAt.After(out.ChangedAt) {
+			out.ChangedAt = v.ChangedAt
+		}
+	}
+	return out, nil
+}
+
+// ActiveStatsByService adds up the per-service statistics of all shards
+func (r *Router) ActiveStatsByService(ctx context.Context, month time.Time) ([]usecase.ServiceStats, error) {
+	if len(r.shards) == 1 {
+		return r.shards[0].ActiveStatsByService(ctx, month)
+	}
+	perShard, err := gather(ctx, r.all(), func(ctx context.Context, shard int) ([]usecase.ServiceStats, error) {
+		return r.shards[shard].ActiveStatsByService(ctx, month)
+	})
+	if err != nil {
+		return nil, err
+	}
+	byService := map[string]*usecase.ServiceStats{}
+	for _, stats := range perShard {
+		for _, st := range stats {
+			acc, ok := byService[st.ServiceName]
+			if !ok {
+				acc = &usecase.ServiceStats{ServiceName: st.ServiceName}
+				byService[st.ServiceName] = acc
+			}
+			acc.Active += st.Active
+			acc.MonthlyCost += st.MonthlyCost
+		}
+	}
+	out := make([]usecase.ServiceStats, 0, len(byService))
+	for _, st := range byService {
+		out = append(out, *st)
+	}
+	slices.SortFunc(out, func(a, b usecase.ServiceStats) int { return strings.Compare(a.ServiceName, b.ServiceName) })
+	return out, nil
+}
+
+// PriceBenchmarks merges the benchmarks of all shards. Users never span shards, so user and subscription
+// counts add up and the average is exact up to rounding; the median is approximated by the median of the
+// shard medians weighted by their subscriptions. minUsers is applied to the merged counts.
+func (r *Router) PriceBenchmarks(ctx context.Context, month time.Time, minUsers int) ([]usecase.PriceBenchmark, error) {
+	if len(r.shards) == 1 {
+		return r.shards[0].PriceBenchmarks(ctx, month, minUsers)
+	}
+	perShard, err := gather(ctx, r.all(), func(ctx context.Context, shard int) ([]usecase.PriceBenchmark, error) {
+		return r.shards[shard].PriceBenchmarks(ctx, month, 1)
+	})
+	if err != nil {
+		return nil, err
+	}
+	parts := map[string][]usecase.PriceBenchmark{}
+	for _, rows := range perShard {
+		for _, b := range rows {
+			parts[b.Service] = append(parts[b.Service], b)
+		}
+	}
+	out := make([]usecase.PriceBenchmark, 0, len(parts))
+	for service, bs := range parts {
+		merged := usecase.PriceBenchmark{Service: service}
+		var sum int64
+		for _, b := range bs {
+			merged.Users += b.Users
+			merged.Subscriptions += b.Subscriptions
+			sum += b.AverageCost * b.Subscriptions
+		}
+		if merged.Users < int64(minUsers) || merged.Subscriptions == 0 {
+			continue
+		}
+		merged.AverageCost = (sum + merged.Subscriptions/2) / merged.Subscriptions
+		merged.MedianCost = weightedMedian(bs, merged.Subscriptions)
+		out = append(out, merged)
+	}
+	slices.SortFunc(out, func(a, b usecase.PriceBenchmark) int { return strings.Compare(a.Service, b.Service) })
+	return out, nil
+}
+
+func weightedMedian(bs []usecase.PriceBenchmark, total int64) int64 {
+	slices.SortFunc(bs, func(a, b usecase.PriceBenchmark) int { return cmp.Compare(a.MedianCost, b.MedianCost) })
+	var seen int64
+	for _, b := range bs {
+		seen += b.Subscriptions
+		if 2*seen >= total {
+			return b.MedianCost
+		}
+	}
+	return bs[len(bs)-1].MedianCost
+}
+
+// MonthlySpendByUser concatenates the per-user spend of all shards, ordered by user and month
+func (r *Router) MonthlySpendByUser(ctx context.Context, from, to time.Time) ([]usecase.UserMonthSpend, error) {
+	if len(r.shards) == 1 {
+		return r.shards[0].MonthlySpendByUser(ctx, from, to)
+	}
+	perShard, err := gather(ctx, r.all(), func(ctx context.Context, shard int) ([]usecase.UserMonthSpend, error) {
+		return r.shards[shard].MonthlySpendByUser(ctx, from, to)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var out []usecase.UserMonthSpend
+	for _, rows := range perShard {
+		out = append(out, rows...)
+	}
+	slices.SortFunc(out, func(a, b usecase.UserMonthSpend) int {
+		if c := strings.Compare(a.UserID.String(), b.UserID.String()); c != 0 {
+			return c
+		}
+		return a.Month.Compare(b.Month)
+	})
+	return out, nil
+}
+
+// ChangesSince reads the change log of the shard of the user; positions are per database, which is enough as
+// all subscriptions of a user are on one shard
+func (r *Router) ChangesSince(ctx context.Context, user entity.UserID, since entity.ChangePos, limit int) ([]entity.SubscriptionChange, error) {
+	shard := r.shardOf(user)
+	out, err := r.shards[shard].ChangesSince(ctx, user, since, limit)
+	for i := range out {
+		out[i].SubscriptionID = r.globalID(out[i].SubscriptionID, shard)
+	}
+	return out, err
+}
+
+// ReassignUser moves subscriptions between users of the same shard; other moves are refused
+func (r *Router) ReassignUser(ctx context.Context, from, to entity.UserID, actor string) (int64, error) {
+	shard := r.shardOf(from)
+	if r.shardOf(to) != shard {
+		return 0, fmt.Errorf("reassign user: %w: %w", usecase.ErrInvalidSubscription, ErrCrossShard)
+	}
+	return r.shards[shard].ReassignUser(ctx, from, to, actor)
+}
+
+// MergeSubs merges two subscriptions of one user on its shard
+func (r *Router) MergeSubs(ctx context.Context, merged, dropped *entity.Subscription, actor string) error {
+	if merged == nil || dropped == nil {
+		return fmt.Errorf("merge subs: %w", usecase.ErrInvalidSubscription)
+	}
+	shard, keepLocal := r.localID(merged.ID)
+	dropShard, dropLocal := r.localID(dropped.ID)
+	if dropShard != shard {
+		return fmt.Errorf("merge subs: %w: %w", usecase.ErrInvalidSubscription, ErrCrossShard)
+	}
+	keepID, dropID := merged.ID, dropped.ID
+	merged.ID, dropped.ID = keepLocal, dropLocal
+	err := r.shards[shard].MergeSubs(ctx, merged, dropped, actor)
+	merged.ID, dropped.ID = keepID, dropID
+	return err
+}
+
+// EndedBefore collects the ended subscriptions of all shards and returns the limit ones with the lowest IDs
+func (r *Router) EndedBefore(ctx context.Context, before time.Time, limit int) ([]*entity.Subscription, error) {
+	perShard, err := gather(ctx, r.all(), func(ctx context.Context, shard int) ([]*entity.Subscription, error) {
+		return r.shards[shard].EndedBefore(ctx, before, limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var out []*entity.Subscription
+	for shard, rows := range perShard {
+		for _, sub := range rows {
+			out = append(out, r.toGlobal(sub, shard))
+		}
+	}
+	slices.SortFunc(out, func(a, b *entity.Subscription) int { return cmp.Compare(a.ID, b.ID) })
+	return out[:min(limit, len(out))], nil
+}
+
+// PurgeSubs deletes every subscription on the shard encoded in its ID
+func (r *Router) PurgeSubs(ctx context.Context, ids []int64) (int64, error) {
+	byShard := make([][]int64, len(r.shards))
+	for _, id := range ids {
+		shard, local := r.localID(id)
+		byShard[shard] = append(byShard[shard], local)
+	}
+	var total int64
+	for shard, local := range byShard {
+		if len(local) == 0 {
+			continue
+		}
+		n, err := r.shards[shard].PurgeSubs(ctx, local)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// SaveAdjustment stores the adjustment on the shard of its subscription; adjustment IDs are mapped like
+// subscription IDs
+func (r *Router) SaveAdjustment(ctx context.Context, a *entity.Adjustment) (*entity.Adjustment, error) {
+	if a == nil {
+		return nil, fmt.Errorf("save adjustment: %w", usecase.ErrInvalidID)
+	}
+	shard, local := r.localID(a.SubscriptionID)
+	in := *a
+	in.SubscriptionID = local
+	out, err := r.shards[shard].SaveAdjustment(ctx, &in)
+	if out != nil {
+		out.ID, out.SubscriptionID = r.globalID(out.ID, shard), a.SubscriptionID
+	}
+	return out, err
+}
+
+// ListAdjustments reads the adjustments of the subscription from the shard encoded in its ID
+func (r *Router) ListAdjustments(ctx context.Context, subID int64) ([]entity.Adjustment, error) {
+	shard, local := r.localID(subID)
+	out, err := r.shards[shard].ListAdjustments(ctx, local)
+	for i := range out {
+		out[i].ID, out[i].SubscriptionID = r.globalID(out[i].ID, shard), subID
+	}
+	return out, err
+}
+
+// SaveSeats stores the seats on the shard of their subscription; members may live on any shard
+func (r *Router) SaveSeats(ctx context.Context, s *entity.Seats) (*entity.Seats, error) {
+	if s == nil {
+		return nil, fmt.Errorf("save seats: %w", usecase.ErrInvalidID)
+	}
+	shard, local := r.localID(s.SubscriptionID)
+	in := *s
+	in.SubscriptionID = local
+	out, err := r.shards[shard].SaveSeats(ctx, &in)
+	if out != nil {
+		out.SubscriptionID = s.SubscriptionID
+	}
+	return out, err
+}
+
+// GetSeats reads the seats from the shard encoded in the subscription ID
+func (r *Router) GetSeats(ctx context.Context, subID int64) (*entity.Seats, error) {
+	shard, local := r.localID(subID)
+	out, err := r.shards[shard].GetSeats(ctx, local)
+	if out != nil {
+		out.SubscriptionID = subID
+	}
+	return out, err
+}
+
+// SeatSharesByFilter merges the seats of the user from all shards, since the shared plans are stored on the
+// shards of their owners
+func (r *Router) SeatSharesByFilter(ctx context.Context, f usecase.SubFilter) ([]usecase.SeatShare, error) {
+	perShard, err := gather(ctx, r.all(), func(ctx context.Context, shard int) ([]usecase.SeatShare, error) {
+		return r.shards[shard].SeatSharesByFilter(ctx, f)
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := []usecase.SeatShare{}
+	for shard, seats := range perShard {
+		for _, s := range seats {
+			s.SubscriptionID = r.globalID(s.SubscriptionID, shard)
+			out = append(out, s)
+		}
+	}
+	slices.SortFunc(out, func(a, b usecase.SeatShare) int {
+		return cmp.Or(strings.Compare(a.ServiceName, b.ServiceName), cmp.Compare(a.SubscriptionID, b.SubscriptionID))
+	})
+	return out, nil
+}
+
+// GetSettings reads the settings from the shard of the user
+func (r *Router) GetSettings(ctx context.Context, userID entity.UserID) (*entity.Settings, error) {
+	return r.shards[r.shardOf(userID)].GetSettings(ctx, userID)
+}
+
+// SaveSettings stores the settings on the shard of the user
+func (r *Router) SaveSettings(ctx context.Context, s entity.Settings) (*entity.Settings, error) {
+	return r.shards[r.shardOf(s.UserID)].SaveSettings(ctx, s)
+}
+
+// DeactivateUser records the deactivation on the shard of the user, next to their subscriptions
+func (r *Router) DeactivateUser(ctx context.Context, userID entity.UserID, at time.Time) (time.Time, error) {
+	return r.shards[r.shardOf(userID)].DeactivateUser(ctx, userID, at)
+}
+
+// ReactivateUser removes the deactivation from the shard of the user
+func (r *Router) ReactivateUser(ctx context.Context, userID entity.UserID) error {
+	return r.shards[r.shardOf(userID)].ReactivateUser(ctx, userID)
+}
+
+// UserDeactivatedAt reads the deactivation from the shard of the user
+func (r *Router) UserDeactivatedAt(ctx context.Context, userID entity.UserID) (time.Time, error) {
+	return r.shards[r.shardOf(userID)].UserDeactivatedAt(ctx, userID)
+}
+
+// DeleteUser removes the user on their shard first, which decides whether the policy allows it; the other
+// shards then only drop the seats the user holds in plans stored there, so that part is not atomic with the
+// rest. Anonymized subscriptions stay on the shard, under an anonymous ID that hashes to it
+func (r *Router) DeleteUser(ctx context.Context, userID entity.UserID, d usecase.UserDeletion, actor string) (int64, error) {
+	shard := r.shardOf(userID)
+	if d.Policy == usecase.DeleteUserAnonymize && !d.AnonymousID.IsZero() {
+		for r.shardOf(d.AnonymousID) != shard {
+			d.AnonymousID = entity.UserID(uuid.New())
+		}
+	}
+	n, err := r.shards[shard].DeleteUser(ctx, userID, d, actor)
+	if err != nil {
+		return 0, err
+	}
+	for other := range r.shards {
+		if other == shard {
+			continue
+		}
+		if _, err := r.shards[other].DeleteUser(ctx, userID, d, actor); err != nil {
+			return n, fmt.Errorf("delete user: shard %d: %w", other, err)
+		}
+	}
+	return n, nil
+}
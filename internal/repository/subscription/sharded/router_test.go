@@ -0,0 +1,435 @@
+package sharded
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/pagination"
+)
+
+// memShard - in-memory shard implementing the methods the tests use
+type memShard struct {
+	usecase.SubscriptionRepository
+	subs        []entity.Subscription
+	adjustments []entity.Adjustment
+	benchmarks  []usecase.PriceBenchmark
+	summary     usecase.CostSummary
+	seats       map[int64]entity.Seats
+	seatShares  []usecase.SeatShare
+	// deletions - users deleted on the shard in call order; blocked refuses them
+	deletions []usecase.UserDeletion
+	blocked   bool
+}
+
+func (m *memShard) SaveSub(_ context.Context, s *entity.Subscription) (*entity.Subscription, error) {
+	out := *s
+	out.ID = int64(len(m.subs) + 1)
+	m.subs = append(m.subs, out)
+	return &out, nil
+}
+
+func (m *memShard) GetSubByID(_ context.Context, id int64) (*entity.Subscription, error) {
+	for _, s := range m.subs {
+		if s.ID == id {
+			return &s, nil
+		}
+	}
+	return nil, usecase.ErrSubscriptionNotFound
+}
+
+func (m *memShard) GetSubByPublicID(_ context.Context, id entity.PublicID) (*entity.Subscription, error) {
+	for _, s := range m.subs {
+		if s.PublicID == id {
+			return &s, nil
+		}
+	}
+	return nil, usecase.ErrSubscriptionNotFound
+}
+
+func (m *memShard) ListSubsByFilter(_ context.Context, f usecase.SubFilter) ([]*entity.Subscription, error) {
+	limit, offset, err := pagination.DefaultLimits().Normalize(f.Limit, f.Offset)
+	if err != nil {
+		return nil, err
+	}
+	var out []*entity.Subscription
+	for _, s := range m.subs {
+		if !f.UserID.IsZero() && s.UserID != f.UserID {
+			continue
+		}
+		if f.After != nil && usecase.CompareListOrder(&s, &entity.Subscription{DateFrom: f.After.StartDate, ServiceName: f.After.ServiceName, ID: f.After.ID}) <= 0 {
+			continue
+		}
+		out = append(out, &s)
+	}
+	slices.SortFunc(out, usecase.CompareListOrder)
+	if f.After != nil {
+		offset = 0
+	}
+	out = out[min(offset, len(out)):]
+	return out[:min(limit, len(out))], nil
+}
+
+func (m *memShard) CostSubsByFilter(_ context.Context, f usecase.SubFilter) (int64, error) {
+	var total int64
+	for _, s := range m.subs {
+		if f.UserID.IsZero() || s.UserID == f.UserID {
+			total += s.Cost
+		}
+	}
+	return total, nil
+}
+
+func (m *memShard) CostSummaryByFilter(context.Context, usecase.SubFilter) (usecase.CostSummary, error) {
+	return m.summary, nil
+}
+
+func (m *memShard) PriceBenchmarks(context.Context, time.Time, int) ([]usecase.PriceBenchmark, error) {
+	return m.benchmarks, nil
+}
+
+func (m *memShard) SaveAdjustment(_ context.Context, a *entity.Adjustment) (*entity.Adjustment, error) {
+	if _, err := m.GetSubByID(context.Background(), a.SubscriptionID); err != nil {
+		return nil, err
+	}
+	out := *a
+	out.ID = int64(len(m.adjustments) + 1)
+	m.adjustments = append(m.adjustments, out)
+	return &out, nil
+}
+
+func (m *memShard) ListAdjustments(_ context.Context, subID int64) ([]entity.Adjustment, error) {
+	var out []entity.Adjustment
+	for _, a := range m.adjustments {
+		if a.SubscriptionID == subID {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+func (m *memShard) SaveSeats(_ context.Context, s *entity.Seats) (*entity.Seats, error) {
+	if _, err := m.GetSubByID(context.Background(), s.SubscriptionID); err != nil {
+		return nil, err
+	}
+	if m.seats == nil {
+		m.seats = map[int64]entity.Seats{}
+	}
+	m.seats[s.SubscriptionID] = *s
+	out := *s
+	return &out, nil
+}
+
+func (m *memShard) GetSeats(_ context.Context, subID int64) (*entity.Seats, error) {
+	s, ok := m.seats[subID]
+	if !ok {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+func (m *memShard) SeatSharesByFilter(context.Context, usecase.SubFilter) ([]usecase.SeatShare, error) {
+	return slices.Clone(m.seatShares), nil
+}
+
+func (m *memShard) DeleteUser(_ context.Context, userID entity.UserID, d usecase.UserDeletion, _ string) (int64, error) {
+	if m.blocked {
+		return 0, usecase.ErrUserHasActiveSubs
+	}
+	m.deletions = append(m.deletions, d)
+	var n int64
+	for _, s := range m.subs {
+		if s.UserID == userID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func newRouter(n int) (*Router, []*memShard) {
+	mems := make([]*memShard, n)
+	shards := make([]usecase.SubscriptionRepository, n)
+	for i := range mems {
+		mems[i] = &memShard{}
+		shards[i] = mems[i]
+	}
+	return NewRouter(shards...), mems
+}
+
+func userID(i int) entity.UserID {
+	return entity.UserID(uuid.NewSHA1(uuid.NameSpaceOID, []byte{byte(i)}))
+}
+
+func TestRouter_Placement(t *testing.T) {
+	ctx := context.Background()
+	r, mems := newRouter(3)
+
+	seen := map[int64]bool{}
+	for i := range 30 {
+		uid := userID(i % 10)
+		saved, err := r.SaveSub(ctx, &entity.Subscription{UserID: uid, ServiceName: "Netflix", Cost: 100})
+		require.NoError(t, err)
+		require.False(t, seen[saved.ID], "duplicate id %d", saved.ID)
+		seen[saved.ID] = true
+
+		got, err := r.GetSubByID(ctx, saved.ID)
+		require.NoError(t, err)
+		assert.Equal(t, uid, got.UserID)
+		assert.Equal(t, saved.ID, got.ID)
+	}
+
+	for _, m := range mems {
+		assert.NotEmpty(t, m.subs, "every shard gets users")
+		for _, s := range m.subs {
+			assert.Equal(t, mems[r.shardOf(s.UserID)], m)
+		}
+	}
+
+	cost, err := r.CostSubsByFilter(ctx, usecase.SubFilter{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 3000, cost)
+	cost, err = r.CostSubsByFilter(ctx, usecase.SubFilter{UserID: userID(1)})
+	require.NoError(t, err)
+	assert.EqualValues(t, 300, cost)
+
+	single, _ := newRouter(1)
+	saved, err := single.SaveSub(ctx, &entity.Subscription{UserID: userID(1)})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, saved.ID, "a single shard keeps its IDs")
+}
+
+func TestRouter_GetSubByPublicID(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newRouter(3)
+	pid := entity.PublicID(uuid.New())
+	for i := range 6 {
+		s := &entity.Subscription{UserID: userID(i), ServiceName: "Netflix", Cost: 100}
+		if i == 4 {
+			s.PublicID = pid
+		}
+		_, err := r.SaveSub(ctx, s)
+		require.NoError(t, err)
+	}
+
+	got, err := r.GetSubByPublicID(ctx, pid)
+	require.NoError(t, err)
+	want, err := r.GetSubByID(ctx, got.ID)
+	require.NoError(t, err)
+	assert.Equal(t, userID(4), want.UserID, "found on its shard and given the global ID")
+	_, err = r.GetSubByPublicID(ctx, entity.PublicID(uuid.New()))
+	assert.ErrorIs(t, err, usecase.ErrSubscriptionNotFound)
+}
+
+func TestRouter_Adjustments(t *testing.T) {
+	ctx := context.Background()
+	r, mems := newRouter(3)
+	var subs []*entity.Subscription
+	for i := range 3 {
+		saved, err := r.SaveSub(ctx, &entity.Subscription{UserID: userID(i), ServiceName: "Netflix", Cost: 100})
+		require.NoError(t, err)
+		subs = append(subs, saved)
+	}
+
+	seen := map[int64]bool{}
+	for _, sub := range subs {
+		saved, err := r.SaveAdjustment(ctx, &entity.Adjustment{SubscriptionID: sub.ID, Amount: -50})
+		require.NoError(t, err)
+		assert.Equal(t, sub.ID, saved.SubscriptionID)
+		require.False(t, seen[saved.ID], "duplicate id %d", saved.ID)
+		seen[saved.ID] = true
+
+		list, err := r.ListAdjustments(ctx, sub.ID)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, *saved, list[0])
+	}
+	var stored int
+	for _, m := range mems {
+		// memShard refuses adjustments of subscriptions it does not hold
+		stored += len(m.adjustments)
+	}
+	assert.Equal(t, 3, stored)
+}
+
+func TestRouter_CostSummaryByFilter(t *testing.T) {
+	r, mems := newRouter(3)
+	mems[0].summary = usecase.CostSummary{Total: -50}
+	mems[1].summary = usecase.CostSummary{Total: 600, Count: 2, MonthlyCost: 300, MinCost: 100, MaxCost: 200}
+	mems[2].summary = usecase.CostSummary{Total: 150, Count: 1, MonthlyCost: 50, MinCost: 50, MaxCost: 50}
+
+	sum, err := r.CostSummaryByFilter(context.Background(), usecase.SubFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, usecase.CostSummary{Total: 700, Count: 3, MonthlyCost: 350, MinCost: 50, MaxCost: 200}, sum,
+		"a shard holding adjustments only adds to the total")
+}
+
+func TestRouter_Seats(t *testing.T) {
+	ctx := context.Background()
+	r, mems := newRouter(3)
+	member := userID(9)
+	for i := range 3 {
+		sub, err := r.SaveSub(ctx, &entity.Subscription{UserID: userID(i), ServiceName: "Netflix", Cost: 900})
+		require.NoError(t, err)
+		saved, err := r.SaveSeats(ctx, &entity.Seats{SubscriptionID: sub.ID, Total: 3, UserIDs: []entity.UserID{member}})
+		require.NoError(t, err)
+		assert.Equal(t, sub.ID, saved.SubscriptionID)
+		got, err := r.GetSeats(ctx, sub.ID)
+		require.NoError(t, err)
+		assert.Equal(t, sub.ID, got.SubscriptionID)
+	}
+
+	mems[0].seatShares = []usecase.SeatShare{{SubscriptionID: 1, ServiceName: "Spotify", Cost: 300, Seats: 3}}
+	mems[2].seatShares = []usecase.SeatShare{{SubscriptionID: 1, ServiceName: "Netflix", Cost: 900, Seats: 3}}
+	shares, err := r.SeatSharesByFilter(ctx, usecase.SubFilter{UserID: member})
+	require.NoError(t, err)
+	assert.Equal(t, []usecase.SeatShare{
+		{SubscriptionID: r.globalID(1, 2), ServiceName: "Netflix", Cost: 900, Seats: 3},
+		{SubscriptionID: r.globalID(1, 0), ServiceName: "Spotify", Cost: 300, Seats: 3},
+	}, shares, "plans of owners on every shard")
+}
+
+func TestRouter_ListSubsByFilter(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newRouter(3)
+
+	services := []string{"Netflix", "Okko", "Spotify"}
+	var all []*entity.Subscription
+	for i := range 60 {
+		saved, err := r.SaveSub(ctx, &entity.Subscription{
+			UserID:      userID(i % 7),
+			ServiceName: services[i%len(services)],
+			DateFrom:    time.Date(2025, time.Month(1+i%4), 1, 0, 0, 0, 0, time.UTC),
+		})
+		require.NoError(t, err)
+		all = append(all, saved)
+	}
+	slices.SortFunc(all, usecase.CompareListOrder)
+	ids := func(subs []*entity.Subscription) []int64 {
+		out := make([]int64, 0, len(subs))
+		for _, s := range subs {
+			out = append(out, s.ID)
+		}
+		return out
+	}
+
+	t.Run("offset", func(t *testing.T) {
+		got, err := r.ListSubsByFilter(ctx, usecase.SubFilter{Limit: 7, Offset: 11})
+		require.NoError(t, err)
+		assert.Equal(t, ids(all[11:18]), ids(got))
+	})
+
+	t.Run("keyset", func(t *testing.T) {
+		var walked []*entity.Subscription
+		f := usecase.SubFilter{Limit: 8}
+		for {
+			page, err := r.ListSubsByFilter(ctx, f)
+			require.NoError(t, err)
+			walked = append(walked, page...)
+			if len(page) < f.Limit {
+				break
+			}
+			f.After = usecase.CursorAt(page[len(page)-1])
+		}
+		assert.Equal(t, ids(all), ids(walked))
+	})
+
+	t.Run("ties_at_page_boundaries", func(t *testing.T) {
+		// every page size splits runs of equal start date and service between pages and shards
+		for size := 1; size <= 5; size++ {
+			var walked []*entity.Subscription
+			f := usecase.SubFilter{Limit: size}
+			for {
+				page, err := r.ListSubsByFilter(ctx, f)
+				require.NoError(t, err)
+				walked = append(walked, page...)
+				if len(page) < f.Limit {
+					break
+				}
+				f.After = usecase.CursorAt(page[len(page)-1])
+			}
+			assert.Equal(t, ids(all), ids(walked), "page size %d", size)
+		}
+	})
+
+	t.Run("one_user", func(t *testing.T) {
+		got, err := r.ListSubsByFilter(ctx, usecase.SubFilter{UserID: userID(3)})
+		require.NoError(t, err)
+		var want []*entity.Subscription
+		for _, s := range all {
+			if s.UserID == userID(3) {
+				want = append(want, s)
+			}
+		}
+		assert.Equal(t, ids(want), ids(got))
+	})
+}
+
+func TestRouter_CrossShard(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newRouter(2)
+
+	from, to := userID(0), userID(1)
+	for i := 2; r.shardOf(from) == r.shardOf(to); i++ {
+		to = userID(i)
+	}
+	_, err := r.ReassignUser(ctx, from, to, "admin")
+	assert.ErrorIs(t, err, usecase.ErrInvalidSubscription)
+	assert.ErrorIs(t, err, ErrCrossShard)
+
+	err = r.UpdateSub(ctx, &entity.Subscription{ID: r.globalID(1, r.shardOf(from)), UserID: to})
+	assert.ErrorIs(t, err, ErrCrossShard)
+}
+
+func TestRouter_DeleteUser(t *testing.T) {
+	ctx := context.Background()
+	r, mems := newRouter(3)
+	user := userID(0)
+	home := r.shardOf(user)
+	_, err := r.SaveSub(ctx, &entity.Subscription{UserID: user, ServiceName: "Netflix"})
+	require.NoError(t, err)
+
+	anon := userID(1)
+	for i := 2; r.shardOf(anon) == home; i++ {
+		anon = userID(i)
+	}
+	n, err := r.DeleteUser(ctx, user, usecase.UserDeletion{Policy: usecase.DeleteUserAnonymize, AnonymousID: anon}, "admin")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n, "counted on the shard of the user")
+	require.Len(t, mems[home].deletions, 1)
+	got := mems[home].deletions[0].AnonymousID
+	assert.Equal(t, home, r.shardOf(got), "anonymized subscriptions stay on the shard")
+	for shard, m := range mems {
+		assert.Len(t, m.deletions, 1, "every shard gives up the seats of the user, shard %d", shard)
+	}
+
+	mems[home].blocked = true
+	_, err = r.DeleteUser(ctx, user, usecase.UserDeletion{Policy: usecase.DeleteUserBlock}, "admin")
+	assert.ErrorIs(t, err, usecase.ErrUserHasActiveSubs)
+	for shard, m := range mems {
+		assert.Len(t, m.deletions, 1, "a refusal on the shard of the user stops the others, shard %d", shard)
+	}
+}
+
+func TestRouter_PriceBenchmarks(t *testing.T) {
+	r, mems := newRouter(2)
+	mems[0].benchmarks = []usecase.PriceBenchmark{
+		{Service: "netflix", Users: 2, Subscriptions: 3, AverageCost: 100, MedianCost: 100},
+		{Service: "okko", Users: 1, Subscriptions: 1, AverageCost: 300, MedianCost: 300},
+	}
+	mems[1].benchmarks = []usecase.PriceBenchmark{
+		{Service: "netflix", Users: 1, Subscriptions: 1, AverageCost: 500, MedianCost: 500},
+	}
+
+	got, err := r.PriceBenchmarks(context.Background(), time.Now(), 3)
+	require.NoError(t, err)
+	assert.Equal(t, []usecase.PriceBenchmark{
+		{Service: "netflix", Users: 3, Subscriptions: 4, AverageCost: 200, MedianCost: 100},
+	}, got)
+}
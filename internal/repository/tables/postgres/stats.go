@@ -0,0 +1,36 @@
+// Package postgres reads the sizes of the application tables from Postgres statistics
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"subs_tracker/internal/alerts"
+	"subs_tracker/internal/repository/subscription/postgres/sqlc"
+)
+
+// Stats — sizes of the tables in the current schema of a database
+type Stats struct {
+	q *sqlc.Queries
+}
+
+// NewStats creates a reader of the tables of the pool's database
+func NewStats(pool *pgxpool.Pool) *Stats {
+	return &Stats{q: sqlc.New(pool)}
+}
+
+// Tables returns every table of the current schema by name. Row counts are the planner's estimates, kept
+// up to date by autovacuum, so reading them costs nothing even on large tables
+func (s *Stats) Tables(ctx context.Context) ([]alerts.TableSize, error) {
+	rows, err := s.q.TableStats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("table stats: %w", err)
+	}
+	out := make([]alerts.TableSize, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, alerts.TableSize{Table: r.TableName, Rows: r.LiveRows, Bytes: r.TotalBytes})
+	}
+	return out, nil
+}
@@ -0,0 +1,71 @@
+// Package postgres stores tenant themes in the tenant_themes table
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"subs_tracker/internal/repository/subscription/postgres/sqlc"
+	"subs_tracker/internal/theme"
+)
+
+// Store — theme.Store over pgx and the sqlc queries
+type Store struct {
+	queries *sqlc.Queries
+}
+
+var _ theme.Store = (*Store)(nil)
+
+// NewStore creates a store bound to the pool
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{queries: sqlc.New(pool)}
+}
+
+// GetTheme returns the theme of the tenant, theme.ErrNotFound if there is none
+func (s *Store) GetTheme(ctx context.Context, tenantID string) (*theme.Theme, error) {
+	row, err := s.queries.GetTenantTheme(ctx, tenantID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, theme.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get theme: %w", err)
+	}
+	return &theme.Theme{
+		TenantID:  row.TenantID,
+		LogoURL:   row.LogoUrl,
+		Color:     row.Color,
+		Footer:    row.Footer,
+		UpdatedAt: row.UpdatedAt,
+	}, nil
+}
+
+// SaveTheme creates or replaces the theme of t.TenantID
+func (s *Store) SaveTheme(ctx context.Context, t theme.Theme) error {
+	err := s.queries.UpsertTenantTheme(ctx, sqlc.UpsertTenantThemeParams{
+		TenantID:  t.TenantID,
+		LogoUrl:   t.LogoURL,
+		Color:     t.Color,
+		Footer:    t.Footer,
+		UpdatedAt: t.UpdatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("save theme: %w", err)
+	}
+	return nil
+}
+
+// DeleteTheme removes the theme of the tenant
+func (s *Store) DeleteTheme(ctx context.Context, tenantID string) error {
+	n, err := s.queries.DeleteTenantTheme(ctx, tenantID)
+	if err != nil {
+		return fmt.Errorf("delete theme: %w", err)
+	}
+	if n == 0 {
+		return theme.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+// Package postgres stores user webhooks in the user_webhooks table
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/repository/subscription/postgres/sqlc"
+	"subs_tracker/internal/userhooks"
+	"subs_tracker/internal/webhooks"
+)
+
+// Store — userhooks.Store over pgx and the sqlc queries
+type Store struct {
+	queries *sqlc.Queries
+}
+
+var _ userhooks.Store = (*Store)(nil)
+
+// NewStore creates a store bound to the pool
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{queries: sqlc.New(pool)}
+}
+
+// SaveHook inserts the hook or replaces the one of the user
+func (s *Store) SaveHook(ctx context.Context, h userhooks.Hook) error {
+	err := s.queries.UpsertUserWebhook(ctx, sqlc.UpsertUserWebhookParams{
+		UserID:    h.UserID.String(),
+		Url:       h.URL,
+		Format:    string(h.Format),
+		Secret:    h.Secret,
+		CreatedAt: h.CreatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("save user webhook: %w", err)
+	}
+	return nil
+}
+
+// GetHook returns the hook of the user, userhooks.ErrNotFound if there is none
+func (s *Store) GetHook(ctx context.Context, userID entity.UserID) (*userhooks.Hook, error) {
+	row, err := s.queries.GetUserWebhook(ctx, userID.String())
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, userhooks.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get user webhook: %w", err)
+	}
+	uid, err := entity.ParseUserID(row.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("get user webhook: %w", err)
+	}
+	return &userhooks.Hook{
+		UserID:    uid,
+		URL:       row.Url,
+		Format:    webhooks.Format(row.Format),
+		Secret:    row.Secret,
+		CreatedAt: row.CreatedAt,
+	}, nil
+}
+
+// DeleteHook removes the hook of the user
+func (s *Store) DeleteHook(ctx context.Context, userID entity.UserID) error {
+	n, err := s.queries.DeleteUserWebhook(ctx, userID.String())
+	if err != nil {
+		return fmt.Errorf("delete user webhook: %w", err)
+	}
+	if n == 0 {
+		return userhooks.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+// Package postgres stores widget tokens in the widget_tokens table
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/repository/subscription/postgres/sqlc"
+	"subs_tracker/internal/widget"
+)
+
+// Store — widget.Store over pgx and the sqlc queries
+type Store struct {
+	queries *sqlc.Queries
+}
+
+var _ widget.Store = (*Store)(nil)
+
+// NewStore creates a store bound to the pool
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{queries: sqlc.New(pool)}
+}
+
+// SaveToken inserts a new token
+func (s *Store) SaveToken(ctx context.Context, t widget.Token) error {
+	err := s.queries.InsertWidgetToken(ctx, sqlc.InsertWidgetTokenParams{
+		TokenHash: t.TokenHash,
+		UserID:    t.UserID.String(),
+		Origins:   t.Origins,
+		CreatedAt: t.CreatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("save widget token: %w", err)
+	}
+	return nil
+}
+
+// GetToken returns the token with the hash, widget.ErrNotFound if there is none
+func (s *Store) GetToken(ctx context.Context, hash string) (*widget.Token, error) {
+	row, err := s.queries.GetWidgetToken(ctx, hash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, widget.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get widget token: %w", err)
+	}
+	uid, err := entity.ParseUserID(row.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("get widget token: %w", err)
+	}
+	return &widget.Token{
+		TokenHash: row.TokenHash,
+		UserID:    uid,
+		Origins:   row.Origins,
+		CreatedAt: row.CreatedAt,
+		RevokedAt: row.RevokedAt,
+	}, nil
+}
+
+// RevokeToken marks the token revoked unless it already is
+func (s *Store) RevokeToken(ctx context.Context, hash string, at time.Time) error {
+	n, err := s.queries.RevokeWidgetToken(ctx, sqlc.RevokeWidgetTokenParams{RevokedAt: at, TokenHash: hash})
+	if err != nil {
+		return fmt.Errorf("revoke widget token: %w", err)
+	}
+	if n == 0 {
+		return widget.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteUser removes the tokens of the user
+func (s *Store) DeleteUser(ctx context.Context, user entity.UserID) error {
+	if err := s.queries.DeleteUserWidgetTokens(ctx, user.String()); err != nil {
+		return fmt.Errorf("delete widget tokens: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+// Package sandbox keeps a tenant whose data integrators may write freely while testing their create, update
+// and delete flows. The tenant lives in a schema of its own, see tenancy, so production data is never
+// touched, and can be reset at any time to the same seeded subscriptions
+package sandbox
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/tenancy"
+	"subs_tracker/pkg/clock"
+	"subs_tracker/pkg/dates"
+)
+
+// ErrNotSandbox - the request is not one of the sandbox tenant
+var ErrNotSandbox = errors.New("not a sandbox request")
+
+//go:embed seed.json
+var defaultSeed []byte
+
+// Store — storage of the sandbox data
+type Store interface {
+	// Wipe - remove everything stored in the schema of the tenant of ctx, which must be the sandbox one
+	Wipe(ctx context.Context) error
+}
+
+// Registrar — stores the seeded subscriptions, e.g. *usecase.Subscription
+type Registrar interface {
+	RegisterSubs(ctx context.Context, subs []*entity.Subscription) ([]*entity.Subscription, error)
+}
+
+// Result — what a reset left in the sandbox
+type Result struct {
+	Tenant        string
+	Subscriptions int
+	ResetAt       time.Time
+}
+
+// seedSub — a seeded subscription as written in the seed file
+type seedSub struct {
+	UserID      string `json:"user_id"`
+	ServiceName string `json:"service_name"`
+	Cost        int64  `json:"cost"`
+	StartDate   string `json:"start_date"`
+	EndDate     string `json:"end_date,omitempty"`
+}
+
+// Sandbox resets the data of the sandbox tenant
+type Sandbox struct {
+	tenant string
+	seed   []*entity.Subscription
+	store  Store
+	subs   Registrar
+	clock  clock.Clock
+}
+
+// New creates the sandbox of the tenant reset to seed, see ParseSeed, and applies options
+func New(tenant string, seed []*entity.Subscription, store Store, subs Registrar, options ...func(*Sandbox)) *Sandbox {
+	s := &Sandbox{tenant: tenant, seed: seed, store: store, subs: subs, clock: clock.System}
+	for _, o := range options {
+		o(s)
+	}
+	return s
+}
+
+// WithClock sets the source of the reset time
+func WithClock(c clock.Clock) func(*Sandbox) {
+	return func(s *Sandbox) {
+		if c != nil {
+			s.clock = c
+		}
+	}
+}
+
+// Tenant returns the sandbox tenant
+func (s *Sandbox) Tenant() string {
+	return s.tenant
+}
+
+// Reset wipes the sandbox and stores the seeded subscriptions again. ctx must be a request of the sandbox
+// tenant, ErrNotSandbox otherwise, so no other tenant can reset anything
+func (s *Sandbox) Reset(ctx context.Context) (Result, error) {
+	if tenancy.FromContext(ctx) != s.tenant {
+		return Result{}, ErrNotSandbox
+	}
+	if err := s.store.Wipe(ctx); err != nil {
+		return Result{}, fmt.Errorf("reset sandbox: %w", err)
+	}
+	subs := make([]*entity.Subscription, 0, len(s.seed))
+	for _, sub := range s.seed {
+		cp := *sub
+		subs = append(subs, &cp)
+	}
+	if len(subs) > 0 {
+		if _, err := s.subs.RegisterSubs(ctx, subs); err != nil {
+			return Result{}, fmt.Errorf("reset sandbox: seed: %w", err)
+		}
+	}
+	return Result{Tenant: s.tenant, Subscriptions: len(subs), ResetAt: s.clock.Now()}, nil
+}
+
+// LoadSeed reads the seed file at path, the built-in seed when path is empty
+func LoadSeed(path string) ([]*entity.Subscription, error) {
+	raw := defaultSeed
+	if path != "" {
+		var err error
+		if raw, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("load sandbox seed: %w", err)
+		}
+	}
+	return ParseSeed(raw)
+}
+
+// ParseSeed parses a JSON array of subscriptions with user_id, service_name, cost and the MM-YYYY start_date
+// and optional end_date, as the API takes them
+func ParseSeed(raw []byte) ([]*entity.Subscription, error) {
+	var in []seedSub
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return nil, fmt.Errorf("parse sandbox seed: %w", err)
+	}
+	out := make([]*entity.Subscription, 0, len(in))
+	for i, s := range in {
+		uid, err := entity.ParseUserID(s.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("parse sandbox seed: item %d: %w", i, err)
+		}
+		from, err := time.Parse(dates.MonthYear, s.StartDate)
+		if err != nil {
+			return nil, fmt.Errorf("parse sandbox seed: item %d: start_date: %w", i, err)
+		}
+		sub := &entity.Subscription{UserID: uid, ServiceName: s.ServiceName, Cost: s.Cost, DateFrom: from}
+		if s.EndDate != "" {
+			to, err := time.Parse(dates.MonthYear, s.EndDate)
+			if err != nil {
+				return nil, fmt.Errorf("parse sandbox seed: item %d: end_date: %w", i, err)
+			}
+			sub.DateTo = &to
+		}
+		out = append(out, sub)
+	}
+	return out, nil
+}
@@ -0,0 +1,64 @@
+package sandbox
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/internal/repository/subscription/memory"
+	"subs_tracker/internal/tenancy"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/clock"
+)
+
+// wipeCounter - Store counting the wipes
+type wipeCounter int
+
+func (w *wipeCounter) Wipe(context.Context) error {
+	*w++
+	return nil
+}
+
+func TestSandbox_Reset(t *testing.T) {
+	seed, err := LoadSeed("")
+	require.NoError(t, err)
+	require.NotEmpty(t, seed, "the built-in seed")
+
+	now := time.Date(2025, time.July, 10, 12, 0, 0, 0, time.UTC)
+	repo := memory.NewRepository()
+	var wipes wipeCounter
+	s := New("sandbox", seed, &wipes, usecase.NewSubscription(repo), WithClock(clock.NewFake(now)))
+
+	_, err = s.Reset(context.Background())
+	assert.ErrorIs(t, err, ErrNotSandbox, "a request of the shared schema")
+	_, err = s.Reset(tenancy.WithTenant(context.Background(), "acme"))
+	assert.ErrorIs(t, err, ErrNotSandbox, "a request of another tenant")
+	assert.Zero(t, wipes)
+
+	ctx := tenancy.WithTenant(context.Background(), "sandbox")
+	res, err := s.Reset(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Tenant: "sandbox", Subscriptions: len(seed), ResetAt: now}, res)
+	assert.Equal(t, wipeCounter(1), wipes)
+	stored, err := repo.ListSubsByFilter(ctx, usecase.SubFilter{Limit: 100})
+	require.NoError(t, err)
+	assert.Len(t, stored, len(seed))
+	assert.Zero(t, seed[0].ID, "the seed is copied, never stored itself")
+}
+
+func TestParseSeed(t *testing.T) {
+	seed, err := ParseSeed([]byte(`[{"user_id": "60601fee-2bf1-4721-ae6f-7636e79a0cba", "service_name": "Netflix", "cost": 999, "start_date": "03-2025", "end_date": "06-2025"}]`))
+	require.NoError(t, err)
+	require.Len(t, seed, 1)
+	assert.Equal(t, time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC), seed[0].DateFrom)
+	require.NotNil(t, seed[0].DateTo)
+	assert.Equal(t, time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC), *seed[0].DateTo)
+
+	for _, bad := range []string{`{}`, `[{"user_id": "nope", "start_date": "03-2025"}]`, `[{"user_id": "60601fee-2bf1-4721-ae6f-7636e79a0cba", "start_date": "2025-03-01"}]`} {
+		_, err := ParseSeed([]byte(bad))
+		assert.Error(t, err, bad)
+	}
+}
@@ -0,0 +1,189 @@
+// Package share hands out read-only links to a user's monthly subscription summary, e.g. for a family to
+// see what the household pays. A link is an unguessable token that expires and can be revoked; only the
+// SHA-256 of the token is stored, so a leaked table does not leak working links
+package share
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/pkg/clock"
+)
+
+const (
+	defaultTTL = 7 * 24 * time.Hour
+	defaultMax = 90 * 24 * time.Hour
+	tokenBytes = 24
+)
+
+var (
+	ErrNotFound   = errcode.New(errcode.ShareNotFound, "share link not found")
+	ErrGone       = errcode.New(errcode.ShareGone, "share link expired or revoked")
+	ErrInvalidTTL = errcode.New(errcode.ShareTTLInvalid, "invalid share link lifetime")
+)
+
+// Link — a stored share link
+type Link struct {
+	// TokenHash - hex SHA-256 of the token handed to the user
+	TokenHash string
+	// UserID - whose subscriptions the link shows
+	UserID entity.UserID
+	// ServiceName - only show this service (case-insensitive), empty shows all
+	ServiceName string
+	// TenantID - tenant whose theme the summary is rendered with, empty for the default look
+	TenantID  string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	// RevokedAt - when the link was revoked, nil while it works
+	RevokedAt *time.Time
+}
+
+// Active reports whether the link still works at now
+func (l Link) Active(now time.Time) bool {
+	return l.RevokedAt == nil && now.Before(l.ExpiresAt)
+}
+
+// Store — storage of share links
+type Store interface {
+	// SaveLink - store a new link
+	SaveLink(ctx context.Context, l Link) error
+	// GetLink - get a link by token hash, ErrNotFound if there is none
+	GetLink(ctx context.Context, hash string) (*Link, error)
+	// RevokeLink - mark a link revoked at, ErrNotFound if there is none; revoking twice keeps the first time
+	RevokeLink(ctx context.Context, hash string, at time.Time) error
+	// RevokeIssuedBefore - mark revoked at every unrevoked link created before the cutoff, only of userID unless
+	// it is zero, and return how many were revoked; the store records actor in the admin audit log if it keeps one
+	RevokeIssuedBefore(ctx context.Context, userID entity.UserID, before, at time.Time, actor string) (int64, error)
+}
+
+// Links creates, resolves and revokes share links
+type Links struct {
+	store      Store
+	clock      clock.Clock
+	defaultTTL time.Duration
+	maxTTL     time.Duration
+}
+
+// NewLinks creates links kept in store and applies options
+func NewLinks(store Store, options ...func(*Links)) *Links {
+	l := &Links{
+		store:      store,
+		clock:      clock.System,
+		defaultTTL: defaultTTL,
+		maxTTL:     defaultMax,
+	}
+	for _, o := range options {
+		o(l)
+	}
+	if l.defaultTTL > l.maxTTL {
+		l.defaultTTL = l.maxTTL
+	}
+	return l
+}
+
+// WithClock returns an option that sets the source of creation, expiry and revocation times
+func WithClock(c clock.Clock) func(*Links) {
+	return func(l *Links) {
+		if c != nil {
+			l.clock = c
+		}
+	}
+}
+
+// WithMaxTTL returns an option that bounds how long a link may live
+func WithMaxTTL(d time.Duration) func(*Links) {
+	return func(l *Links) {
+		if d > 0 {
+			l.maxTTL = d
+		}
+	}
+}
+
+// Create stores a link to the user's subscriptions, narrowed to serviceName when set and themed for tenantID
+// when set, and returns its token. A zero ttl means the default of a week
+func (l *Links) Create(ctx context.Context, userID entity.UserID, tenantID, serviceName string, ttl time.Duration) (string, Link, error) {
+	if userID.IsZero() {
+		return "", Link{}, entity.ErrInvalidUserID
+	}
+	if ttl == 0 {
+		ttl = l.defaultTTL
+	}
+	if ttl < 0 || ttl > l.maxTTL {
+		return "", Link{}, fmt.Errorf("%w: must be within %s", ErrInvalidTTL, l.maxTTL)
+	}
+
+	raw := make([]byte, tokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", Link{}, fmt.Errorf("create share link: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	now := l.clock.Now().UTC()
+	link := Link{
+		TokenHash:   hashToken(token),
+		UserID:      userID,
+		ServiceName: strings.TrimSpace(serviceName),
+		TenantID:    strings.TrimSpace(tenantID),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}
+	if err := l.store.SaveLink(ctx, link); err != nil {
+		return "", Link{}, fmt.Errorf("create share link: %w", err)
+	}
+	return token, link, nil
+}
+
+// Resolve returns the working link of token: ErrNotFound for unknown tokens, ErrGone once expired or revoked
+func (l *Links) Resolve(ctx context.Context, token string) (Link, error) {
+	link, err := l.get(ctx, token)
+	if err != nil {
+		return Link{}, err
+	}
+	if !link.Active(l.clock.Now()) {
+		return Link{}, ErrGone
+	}
+	return *link, nil
+}
+
+// Revoke stops the link of token from working
+func (l *Links) Revoke(ctx context.Context, token string) error {
+	link, err := l.get(ctx, token)
+	if err != nil {
+		return err
+	}
+	return l.store.RevokeLink(ctx, link.TokenHash, l.clock.Now().UTC())
+}
+
+// RevokeIssuedBefore revokes every link created before the cutoff, e.g. after a leak, narrowed to userID
+// unless it is zero. A zero cutoff revokes everything issued so far; actor is recorded in the audit log
+func (l *Links) RevokeIssuedBefore(ctx context.Context, userID entity.UserID, before time.Time, actor string) (int64, error) {
+	now := l.clock.Now().UTC()
+	if before.IsZero() || before.After(now) {
+		before = now
+	}
+	n, err := l.store.RevokeIssuedBefore(ctx, userID, before.UTC(), now, actor)
+	if err != nil {
+		return 0, fmt.Errorf("revoke share links: %w", err)
+	}
+	return n, nil
+}
+
+func (l *Links) get(ctx context.Context, token string) (*Link, error) {
+	if raw, err := base64.RawURLEncoding.DecodeString(token); err != nil || len(raw) != tokenBytes {
+		return nil, ErrNotFound
+	}
+	return l.store.GetLink(ctx, hashToken(token))
+}
+
+// hashToken is what the store keeps instead of the token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
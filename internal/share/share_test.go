@@ -0,0 +1,79 @@
+package share_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/share"
+	"subs_tracker/internal/storetest"
+	"subs_tracker/pkg/clock"
+)
+
+func TestLinks(t *testing.T) {
+	ctx := context.Background()
+	now := clock.NewFake(time.Date(2025, time.July, 10, 12, 0, 0, 0, time.UTC))
+	store := storetest.NewShareStore()
+	links := share.NewLinks(store, share.WithClock(now), share.WithMaxTTL(30*24*time.Hour))
+	ann := entity.UserID(uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba"))
+
+	_, _, err := links.Create(ctx, entity.UserID{}, "", "", 0)
+	assert.ErrorIs(t, err, entity.ErrInvalidUserID)
+	_, _, err = links.Create(ctx, ann, "", "", 31*24*time.Hour)
+	assert.ErrorIs(t, err, share.ErrInvalidTTL)
+
+	token, link, err := links.Create(ctx, ann, "acme", " Netflix ", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "Netflix", link.ServiceName)
+	assert.Equal(t, "acme", link.TenantID)
+	assert.Equal(t, now.Now().Add(7*24*time.Hour), link.ExpiresAt)
+	stored, err := store.GetLink(ctx, link.TokenHash)
+	require.NoError(t, err)
+	assert.NotContains(t, stored.TokenHash, token, "the token itself must not be stored")
+
+	got, err := links.Resolve(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, ann, got.UserID)
+
+	_, err = links.Resolve(ctx, "not-a-token")
+	assert.ErrorIs(t, err, share.ErrNotFound)
+	other, _, err := links.Create(ctx, ann, "", "", time.Hour)
+	require.NoError(t, err)
+	_, err = links.Resolve(ctx, strings.Repeat("A", 32))
+	assert.ErrorIs(t, err, share.ErrNotFound, "well-formed but unknown")
+
+	now.Advance(time.Hour)
+	_, err = links.Resolve(ctx, other)
+	assert.ErrorIs(t, err, share.ErrGone, "expired")
+
+	require.NoError(t, links.Revoke(ctx, token))
+	_, err = links.Resolve(ctx, token)
+	assert.ErrorIs(t, err, share.ErrGone, "revoked")
+	require.NoError(t, links.Revoke(ctx, token), "revoking twice is fine")
+
+	bob := entity.UserID(uuid.MustParse("0b6b7c2e-8f3c-4a43-9d5e-0d6f1f8a9a11"))
+	annNew, _, err := links.Create(ctx, ann, "", "", 0)
+	require.NoError(t, err)
+	bobNew, _, err := links.Create(ctx, bob, "", "", 0)
+	require.NoError(t, err)
+	now.Advance(time.Second)
+	n, err := links.RevokeIssuedBefore(ctx, bob, time.Time{}, "test")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n, "only bob's link")
+	_, err = links.Resolve(ctx, bobNew)
+	assert.ErrorIs(t, err, share.ErrGone)
+	n, err = links.RevokeIssuedBefore(ctx, entity.UserID{}, now.Now().Add(-2*time.Hour), "test")
+	require.NoError(t, err)
+	assert.Zero(t, n, "issued after the cutoff")
+	n, err = links.RevokeIssuedBefore(ctx, entity.UserID{}, time.Time{}, "test")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n, "ann's new link and the expired one; revoked links are not counted again")
+	_, err = links.Resolve(ctx, annNew)
+	assert.ErrorIs(t, err, share.ErrGone)
+}
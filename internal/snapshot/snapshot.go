@@ -0,0 +1,196 @@
+// Package snapshot turns one user's data into a signed archive and back, so users can move between
+// instances. An archive is JSON {"snapshot": {...}, "signature": "sha256=<hex>"} where the signature is
+// HMAC-SHA256 of the snapshot in compact JSON, keyed with the secret shared by both instances; reindenting
+// the file keeps it valid
+package snapshot
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/dates"
+)
+
+// Version - format of the snapshots written by Seal
+const Version = 1
+
+var (
+	ErrMalformed          = errcode.New(errcode.SnapshotMalformed, "malformed snapshot")
+	ErrBadSignature       = errors.New("snapshot signature mismatch")
+	ErrUnsupportedVersion = errors.New("unsupported snapshot version")
+)
+
+// archive is the file exchanged between instances
+type archive struct {
+	Snapshot  json.RawMessage `json:"snapshot"`
+	Signature string          `json:"signature"`
+}
+
+// snapshot is the signed part of the archive
+type snapshot struct {
+	Version       int            `json:"version"`
+	ExportedAt    string         `json:"exported_at"`
+	UserID        string         `json:"user_id"`
+	Settings      *settings      `json:"settings,omitempty"`
+	Subscriptions []subscription `json:"subscriptions"`
+	Archived      []subscription `json:"archived"`
+}
+
+type settings struct {
+	Currency        string `json:"currency"`
+	Locale          string `json:"locale"`
+	FirstDayOfWeek  int    `json:"first_day_of_week"`
+	DateFormat      string `json:"date_format"`
+	Timezone        string `json:"timezone"`
+	SharePriceStats bool   `json:"share_price_stats"`
+	MonthlyGoal     int64  `json:"monthly_goal,omitempty"`
+}
+
+type subscription struct {
+	ServiceName string  `json:"service_name"`
+	Cost        int64   `json:"cost"`
+	StartDate   string  `json:"start_date"`
+	EndDate     *string `json:"end_date,omitempty"`
+}
+
+// Sealer signs and verifies archives with one secret
+type Sealer struct {
+	secret []byte
+}
+
+// NewSealer creates a sealer; instances exchanging archives must share the secret
+func NewSealer(secret []byte) *Sealer {
+	return &Sealer{secret: secret}
+}
+
+// Seal renders the user's data as a signed archive
+func (s *Sealer) Seal(userID entity.UserID, data usecase.UserData, exportedAt time.Time) ([]byte, error) {
+	snap := snapshot{
+		Version:       Version,
+		ExportedAt:    exportedAt.UTC().Format(time.RFC3339),
+		UserID:        userID.String(),
+		Subscriptions: subscriptions(data.Subscriptions),
+		Archived:      subscriptions(data.Archived),
+	}
+	if st := data.Settings; st != nil {
+		snap.Settings = &settings{
+			Currency:        st.Currency,
+			Locale:          st.Locale,
+			FirstDayOfWeek:  int(st.FirstDayOfWeek),
+			DateFormat:      st.DateFormat,
+			Timezone:        st.Timezone,
+			SharePriceStats: st.SharePriceStats,
+			MonthlyGoal:     st.MonthlyGoal,
+		}
+	}
+	payload, err := json.Marshal(snap)
+	if err != nil {
+		return nil, fmt.Errorf("seal snapshot: %w", err)
+	}
+	out, err := json.MarshalIndent(archive{Snapshot: payload, Signature: "sha256=" + hex.EncodeToString(s.sign(payload))}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("seal snapshot: %w", err)
+	}
+	return out, nil
+}
+
+// Open verifies the archive signature and returns the data it holds, with the user it was exported from
+func (s *Sealer) Open(raw []byte) (entity.UserID, usecase.UserData, error) {
+	var a archive
+	if err := json.Unmarshal(raw, &a); err != nil || len(a.Snapshot) == 0 {
+		return entity.UserID{}, usecase.UserData{}, ErrMalformed
+	}
+	var payload bytes.Buffer
+	if err := json.Compact(&payload, a.Snapshot); err != nil {
+		return entity.UserID{}, usecase.UserData{}, ErrMalformed
+	}
+	hexSig, ok := strings.CutPrefix(a.Signature, "sha256=")
+	sig, err := hex.DecodeString(hexSig)
+	if !ok || err != nil || !hmac.Equal(sig, s.sign(payload.Bytes())) {
+		return entity.UserID{}, usecase.UserData{}, ErrBadSignature
+	}
+
+	var snap snapshot
+	dec := json.NewDecoder(&payload)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&snap); err != nil {
+		return entity.UserID{}, usecase.UserData{}, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	if snap.Version != Version {
+		return entity.UserID{}, usecase.UserData{}, fmt.Errorf("%w: %d", ErrUnsupportedVersion, snap.Version)
+	}
+	userID, err := entity.ParseUserID(snap.UserID)
+	if err != nil {
+		return entity.UserID{}, usecase.UserData{}, fmt.Errorf("%w: user_id: %v", ErrMalformed, err)
+	}
+
+	var data usecase.UserData
+	if st := snap.Settings; st != nil {
+		data.Settings = &entity.Settings{
+			UserID:          userID,
+			Currency:        st.Currency,
+			Locale:          st.Locale,
+			FirstDayOfWeek:  time.Weekday(st.FirstDayOfWeek),
+			DateFormat:      st.DateFormat,
+			Timezone:        st.Timezone,
+			SharePriceStats: st.SharePriceStats,
+			MonthlyGoal:     st.MonthlyGoal,
+		}
+	}
+	if data.Subscriptions, err = entities(userID, snap.Subscriptions); err != nil {
+		return entity.UserID{}, usecase.UserData{}, err
+	}
+	if data.Archived, err = entities(userID, snap.Archived); err != nil {
+		return entity.UserID{}, usecase.UserData{}, err
+	}
+	return userID, data, nil
+}
+
+func (s *Sealer) sign(payload []byte) []byte {
+	m := hmac.New(sha256.New, s.secret)
+	m.Write(payload)
+	return m.Sum(nil)
+}
+
+func subscriptions(subs []*entity.Subscription) []subscription {
+	out := make([]subscription, 0, len(subs))
+	for _, sub := range subs {
+		item := subscription{ServiceName: sub.ServiceName, Cost: sub.Cost, StartDate: dates.Format(sub.DateFrom)}
+		if sub.DateTo != nil {
+			end := dates.Format(*sub.DateTo)
+			item.EndDate = &end
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+func entities(userID entity.UserID, subs []subscription) ([]*entity.Subscription, error) {
+	out := make([]*entity.Subscription, 0, len(subs))
+	for i, sub := range subs {
+		from, err := time.Parse(dates.MonthYear, sub.StartDate)
+		if err != nil {
+			return nil, fmt.Errorf("%w: subscription %d: start_date: %v", ErrMalformed, i, err)
+		}
+		item := &entity.Subscription{UserID: userID, ServiceName: sub.ServiceName, Cost: sub.Cost, DateFrom: from}
+		if sub.EndDate != nil {
+			to, err := time.Parse(dates.MonthYear, *sub.EndDate)
+			if err != nil {
+				return nil, fmt.Errorf("%w: subscription %d: end_date: %v", ErrMalformed, i, err)
+			}
+			item.DateTo = &to
+		}
+		out = append(out, item)
+	}
+	return out, nil
+}
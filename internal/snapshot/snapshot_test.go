@@ -0,0 +1,55 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/usecase"
+)
+
+func TestSealer_RoundTrip(t *testing.T) {
+	user := entity.UserID(uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba"))
+	end := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	settings := entity.DefaultSettings(user)
+	settings.Currency = "EUR"
+	data := usecase.UserData{
+		Settings: &settings,
+		Subscriptions: []*entity.Subscription{
+			{ID: 3, UserID: user, ServiceName: "Netflix", Cost: 999, DateFrom: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		Archived: []*entity.Subscription{
+			{ID: 1, UserID: user, ServiceName: "Spotify", Cost: 199, DateFrom: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), DateTo: &end},
+		},
+	}
+	s := NewSealer([]byte("secret"))
+	raw, err := s.Seal(user, data, time.Date(2025, 7, 3, 10, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	gotUser, got, err := s.Open(raw)
+	require.NoError(t, err)
+	assert.Equal(t, user, gotUser)
+	assert.Equal(t, settings, *got.Settings)
+	require.Len(t, got.Subscriptions, 1)
+	assert.Equal(t, entity.Subscription{UserID: user, ServiceName: "Netflix", Cost: 999, DateFrom: data.Subscriptions[0].DateFrom}, *got.Subscriptions[0])
+	require.Len(t, got.Archived, 1)
+	assert.Equal(t, end, *got.Archived[0].DateTo)
+
+	var compact bytes.Buffer
+	require.NoError(t, json.Compact(&compact, raw))
+	_, _, err = s.Open(compact.Bytes())
+	assert.NoError(t, err, "reformatted archives stay valid")
+
+	_, _, err = NewSealer([]byte("other")).Open(raw)
+	assert.ErrorIs(t, err, ErrBadSignature)
+	_, _, err = s.Open(bytes.Replace(raw, []byte(`999`), []byte(`1`), 1))
+	assert.ErrorIs(t, err, ErrBadSignature)
+	_, _, err = s.Open([]byte(`{"signature":"sha256=00"}`))
+	assert.ErrorIs(t, err, ErrMalformed)
+}
@@ -0,0 +1,62 @@
+package storetest
+
+import (
+	"context"
+	"sync"
+
+	"subs_tracker/internal/activity"
+	"subs_tracker/internal/entity"
+)
+
+// ActivityStore — activity.Store in process memory
+type ActivityStore struct {
+	mu      sync.Mutex
+	nextID  int64
+	entries []activity.Entry
+}
+
+var _ activity.Store = (*ActivityStore)(nil)
+
+// NewActivityStore creates an empty store
+func NewActivityStore() *ActivityStore {
+	return &ActivityStore{}
+}
+
+// AddEntry appends a copy of the entry
+func (m *ActivityStore) AddEntry(_ context.Context, e *activity.Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	e.ID = m.nextID
+	m.entries = append(m.entries, *e)
+	return nil
+}
+
+// ListEntries walks the entries from the latest
+func (m *ActivityStore) ListEntries(_ context.Context, userID entity.UserID, before int64, limit int) ([]activity.Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := []activity.Entry{}
+	for i := len(m.entries) - 1; i >= 0 && len(out) < limit; i-- {
+		e := m.entries[i]
+		if e.UserID == userID && (before <= 0 || e.ID < before) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// DeleteEntries removes the entries of the user
+func (m *ActivityStore) DeleteEntries(_ context.Context, userID entity.UserID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := m.entries[:0]
+	for _, e := range m.entries {
+		if e.UserID != userID {
+			kept = append(kept, e)
+		}
+	}
+	n := int64(len(m.entries) - len(kept))
+	m.entries = kept
+	return n, nil
+}
@@ -0,0 +1,53 @@
+package storetest
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"subs_tracker/internal/audit"
+)
+
+// AuditStore — audit.Store in process memory
+type AuditStore struct {
+	mu      sync.Mutex
+	entries []audit.Entry
+}
+
+var _ audit.Store = (*AuditStore)(nil)
+
+// NewAuditStore creates an empty store
+func NewAuditStore() *AuditStore {
+	return &AuditStore{}
+}
+
+// SaveEntries appends copies of the entries
+func (m *AuditStore) SaveEntries(_ context.Context, entries []audit.Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = append(m.entries, entries...)
+	return nil
+}
+
+// PurgeBefore deletes up to limit of the oldest entries received before the instant
+func (m *AuditStore) PurgeBefore(_ context.Context, before time.Time, limit int) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	m.entries = slices.DeleteFunc(m.entries, func(e audit.Entry) bool {
+		if n < int64(limit) && e.At.Before(before) {
+			n++
+			return true
+		}
+		return false
+	})
+	return n, nil
+}
+
+// Entries returns the stored entries in the order they were saved
+func (m *AuditStore) Entries() []audit.Entry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.entries)
+}
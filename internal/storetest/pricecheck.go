@@ -0,0 +1,77 @@
+package storetest
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/pricecheck"
+)
+
+// ReviewStore — pricecheck.Store in process memory
+type ReviewStore struct {
+	mu      sync.Mutex
+	nextID  int64
+	reviews []pricecheck.Review
+}
+
+var _ pricecheck.Store = (*ReviewStore)(nil)
+
+// NewReviewStore creates an empty store
+func NewReviewStore() *ReviewStore {
+	return &ReviewStore{}
+}
+
+// OpenReview appends a copy of the review unless the subscription has one for the same price
+func (m *ReviewStore) OpenReview(_ context.Context, r *pricecheck.Review) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, have := range m.reviews {
+		if have.SubscriptionID == r.SubscriptionID && have.CatalogPrice == r.CatalogPrice {
+			return false, nil
+		}
+	}
+	m.nextID++
+	r.ID = m.nextID
+	m.reviews = append(m.reviews, *r)
+	return true, nil
+}
+
+// ListOpen walks the reviews from the first
+func (m *ReviewStore) ListOpen(_ context.Context, limit int) ([]pricecheck.Review, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := []pricecheck.Review{}
+	for _, r := range m.reviews {
+		if len(out) == limit {
+			break
+		}
+		if r.ResolvedAt == nil {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+// Resolve sets the resolution time of the open review
+func (m *ReviewStore) Resolve(_ context.Context, id int64, at time.Time) (pricecheck.Review, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.reviews {
+		if r := &m.reviews[i]; r.ID == id && r.ResolvedAt == nil {
+			r.ResolvedAt = &at
+			return *r, nil
+		}
+	}
+	return pricecheck.Review{}, pricecheck.ErrNotFound
+}
+
+// DeleteUser removes the reviews of the user
+func (m *ReviewStore) DeleteUser(_ context.Context, user entity.UserID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reviews = slices.DeleteFunc(m.reviews, func(r pricecheck.Review) bool { return r.UserID == user })
+	return nil
+}
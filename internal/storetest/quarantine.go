@@ -0,0 +1,89 @@
+package storetest
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/quarantine"
+)
+
+// QuarantineStore — quarantine.Store in process memory
+type QuarantineStore struct {
+	mu     sync.Mutex
+	nextID int64
+	rows   []quarantine.Row
+}
+
+var _ quarantine.Store = (*QuarantineStore)(nil)
+
+// NewQuarantineStore creates an empty store
+func NewQuarantineStore() *QuarantineStore {
+	return &QuarantineStore{}
+}
+
+// Hold appends copies of the rows not held under their key yet
+func (m *QuarantineStore) Hold(_ context.Context, rows []quarantine.Row) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range rows {
+		if held := slices.IndexFunc(m.rows, func(r quarantine.Row) bool { return r.Key != "" && r.Key == rows[i].Key }); held >= 0 {
+			rows[i].ID = m.rows[held].ID
+			continue
+		}
+		m.nextID++
+		rows[i].ID = m.nextID
+		m.rows = append(m.rows, rows[i])
+	}
+	return nil
+}
+
+// List walks the rows from the first
+func (m *QuarantineStore) List(_ context.Context, user entity.UserID, limit int) ([]quarantine.Row, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := []quarantine.Row{}
+	for _, r := range m.rows {
+		if len(out) == limit {
+			break
+		}
+		if r.UserID == user {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+// Get finds the row of the user
+func (m *QuarantineStore) Get(_ context.Context, user entity.UserID, id int64) (quarantine.Row, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range m.rows {
+		if r.ID == id && r.UserID == user {
+			return r, nil
+		}
+	}
+	return quarantine.Row{}, quarantine.ErrNotFound
+}
+
+// Delete removes the row of the user
+func (m *QuarantineStore) Delete(_ context.Context, user entity.UserID, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, r := range m.rows {
+		if r.ID == id && r.UserID == user {
+			m.rows = append(m.rows[:i], m.rows[i+1:]...)
+			return nil
+		}
+	}
+	return quarantine.ErrNotFound
+}
+
+// DeleteUser removes the rows of the user
+func (m *QuarantineStore) DeleteUser(_ context.Context, user entity.UserID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rows = slices.DeleteFunc(m.rows, func(r quarantine.Row) bool { return r.UserID == user })
+	return nil
+}
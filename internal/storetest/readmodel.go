@@ -0,0 +1,80 @@
+package storetest
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/readmodel"
+	"subs_tracker/internal/usecase"
+)
+
+// SpendStore — readmodel.Store in process memory
+type SpendStore struct {
+	mu      sync.RWMutex
+	changes map[entity.UserID][]readmodel.Change
+}
+
+var _ readmodel.Store = (*SpendStore)(nil)
+
+// NewSpendStore creates an empty store
+func NewSpendStore() *SpendStore {
+	return &SpendStore{changes: map[entity.UserID][]readmodel.Change{}}
+}
+
+// ReplaceUser replaces all changes of the user
+func (m *SpendStore) ReplaceUser(_ context.Context, user entity.UserID, changes []readmodel.Change) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.changes, user)
+	for _, c := range changes {
+		if c.UserID == user {
+			m.changes[user] = append(m.changes[user], c)
+		}
+	}
+	return nil
+}
+
+// ReplaceAll replaces the whole projection
+func (m *SpendStore) ReplaceAll(_ context.Context, changes []readmodel.Change) error {
+	byUser := map[entity.UserID][]readmodel.Change{}
+	for _, c := range changes {
+		byUser[c.UserID] = append(byUser[c.UserID], c)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.changes = byUser
+	return nil
+}
+
+// MonthlySpendByUser sums each user's changes up to every month from..to, ordered by user and month
+func (m *SpendStore) MonthlySpendByUser(_ context.Context, from, to time.Time) ([]usecase.UserMonthSpend, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []usecase.UserMonthSpend
+	for user, changes := range m.changes {
+		for month := from; !month.After(to); month = month.AddDate(0, 1, 0) {
+			var total int64
+			var subs int32
+			for _, c := range changes {
+				if !c.Month.After(month) {
+					total += c.Cost
+					subs += c.Subs
+				}
+			}
+			if subs > 0 {
+				out = append(out, usecase.UserMonthSpend{UserID: user, Month: month, Total: total})
+			}
+		}
+	}
+	slices.SortFunc(out, func(a, b usecase.UserMonthSpend) int {
+		if c := cmp.Compare(a.UserID.String(), b.UserID.String()); c != 0 {
+			return c
+		}
+		return a.Month.Compare(b.Month)
+	})
+	return out, nil
+}
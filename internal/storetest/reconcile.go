@@ -0,0 +1,137 @@
+package storetest
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/reconcile"
+	"subs_tracker/pkg/dates"
+)
+
+// LedgerStore — reconcile.Store in process memory
+type LedgerStore struct {
+	mu      sync.Mutex
+	nextID  int64
+	charges []reconcile.Charge
+	items   []reconcile.Item
+}
+
+var _ reconcile.Store = (*LedgerStore)(nil)
+
+// NewLedgerStore creates an empty store
+func NewLedgerStore() *LedgerStore {
+	return &LedgerStore{}
+}
+
+// RecordCharges appends the charges not stored yet
+func (m *LedgerStore) RecordCharges(_ context.Context, charges []reconcile.Charge) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, ch := range charges {
+		if !m.hasCharge(ch) {
+			m.charges = append(m.charges, ch)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *LedgerStore) hasCharge(ch reconcile.Charge) bool {
+	for _, have := range m.charges {
+		if have.UserID == ch.UserID && strings.EqualFold(have.ServiceName, ch.ServiceName) &&
+			have.Date.Equal(ch.Date) && have.Amount == ch.Amount {
+			return true
+		}
+	}
+	return false
+}
+
+// ChargedUsers walks the charges, users in ID order
+func (m *LedgerStore) ChargedUsers(_ context.Context, month time.Time) ([]entity.UserID, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seen := make(map[entity.UserID]bool)
+	var out []entity.UserID
+	for _, ch := range m.charges {
+		if dates.MonthStart(ch.Date).Equal(month) && !seen[ch.UserID] {
+			seen[ch.UserID] = true
+			out = append(out, ch.UserID)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].String() < out[j].String() })
+	return out, nil
+}
+
+// Charges walks the charges, oldest first
+func (m *LedgerStore) Charges(_ context.Context, user entity.UserID, month time.Time) ([]reconcile.Charge, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []reconcile.Charge
+	for _, ch := range m.charges {
+		if ch.UserID == user && dates.MonthStart(ch.Date).Equal(month) {
+			out = append(out, ch)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Date.Before(out[j].Date) })
+	return out, nil
+}
+
+// OpenItem appends a copy of the item unless one of the same kind exists for the service and month
+func (m *LedgerStore) OpenItem(_ context.Context, it *reconcile.Item) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, have := range m.items {
+		if have.UserID == it.UserID && have.Kind == it.Kind && have.Month.Equal(it.Month) &&
+			strings.EqualFold(have.ServiceName, it.ServiceName) {
+			return false, nil
+		}
+	}
+	m.nextID++
+	it.ID = m.nextID
+	m.items = append(m.items, *it)
+	return true, nil
+}
+
+// ListOpen walks the items from the first
+func (m *LedgerStore) ListOpen(_ context.Context, user *entity.UserID, limit int) ([]reconcile.Item, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := []reconcile.Item{}
+	for _, it := range m.items {
+		if len(out) == limit {
+			break
+		}
+		if it.ResolvedAt == nil && (user == nil || it.UserID == *user) {
+			out = append(out, it)
+		}
+	}
+	return out, nil
+}
+
+// Resolve sets the resolution time of the open item
+func (m *LedgerStore) Resolve(_ context.Context, id int64, at time.Time) (reconcile.Item, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.items {
+		if it := &m.items[i]; it.ID == id && it.ResolvedAt == nil {
+			it.ResolvedAt = &at
+			return *it, nil
+		}
+	}
+	return reconcile.Item{}, reconcile.ErrNotFound
+}
+
+// DeleteUser removes the charges and the items of the user
+func (m *LedgerStore) DeleteUser(_ context.Context, user entity.UserID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.charges = slices.DeleteFunc(m.charges, func(ch reconcile.Charge) bool { return ch.UserID == user })
+	m.items = slices.DeleteFunc(m.items, func(it reconcile.Item) bool { return it.UserID == user })
+	return nil
+}
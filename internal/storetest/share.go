@@ -0,0 +1,73 @@
+package storetest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/share"
+)
+
+// ShareStore — share.Store in process memory; the admin audit log is not kept
+type ShareStore struct {
+	mu    sync.Mutex
+	links map[string]share.Link
+}
+
+var _ share.Store = (*ShareStore)(nil)
+
+// NewShareStore creates an empty store
+func NewShareStore() *ShareStore {
+	return &ShareStore{links: map[string]share.Link{}}
+}
+
+// SaveLink stores a new link
+func (m *ShareStore) SaveLink(_ context.Context, l share.Link) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.links[l.TokenHash] = l
+	return nil
+}
+
+// GetLink returns a copy of the link with the token hash
+func (m *ShareStore) GetLink(_ context.Context, hash string) (*share.Link, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l, ok := m.links[hash]
+	if !ok {
+		return nil, share.ErrNotFound
+	}
+	return &l, nil
+}
+
+// RevokeLink marks the link revoked unless it already is
+func (m *ShareStore) RevokeLink(_ context.Context, hash string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l, ok := m.links[hash]
+	if !ok {
+		return share.ErrNotFound
+	}
+	if l.RevokedAt == nil {
+		l.RevokedAt = &at
+		m.links[hash] = l
+	}
+	return nil
+}
+
+// RevokeIssuedBefore revokes unrevoked links created before the cutoff
+func (m *ShareStore) RevokeIssuedBefore(_ context.Context, userID entity.UserID, before, at time.Time, _ string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for hash, l := range m.links {
+		if l.RevokedAt != nil || !l.CreatedAt.Before(before) || (!userID.IsZero() && l.UserID != userID) {
+			continue
+		}
+		l.RevokedAt = &at
+		m.links[hash] = l
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,3 @@
+// Package storetest keeps in process memory the stores of the packages next to the subscriptions, for the tests
+// of those packages and of the handlers built on them; the service itself always runs on their postgres stores
+package storetest
@@ -0,0 +1,51 @@
+package storetest
+
+import (
+	"context"
+	"sync"
+
+	"subs_tracker/internal/theme"
+)
+
+// ThemeStore — theme.Store in process memory
+type ThemeStore struct {
+	mu     sync.Mutex
+	themes map[string]theme.Theme
+}
+
+var _ theme.Store = (*ThemeStore)(nil)
+
+// NewThemeStore creates an empty store
+func NewThemeStore() *ThemeStore {
+	return &ThemeStore{themes: map[string]theme.Theme{}}
+}
+
+// GetTheme returns a copy of the theme of the tenant
+func (m *ThemeStore) GetTheme(_ context.Context, tenantID string) (*theme.Theme, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.themes[tenantID]
+	if !ok {
+		return nil, theme.ErrNotFound
+	}
+	return &t, nil
+}
+
+// SaveTheme creates or replaces the theme of t.TenantID
+func (m *ThemeStore) SaveTheme(_ context.Context, t theme.Theme) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.themes[t.TenantID] = t
+	return nil
+}
+
+// DeleteTheme removes the theme of the tenant
+func (m *ThemeStore) DeleteTheme(_ context.Context, tenantID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.themes[tenantID]; !ok {
+		return theme.ErrNotFound
+	}
+	delete(m.themes, tenantID)
+	return nil
+}
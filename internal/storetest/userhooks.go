@@ -0,0 +1,52 @@
+package storetest
+
+import (
+	"context"
+	"sync"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/userhooks"
+)
+
+// HookStore — userhooks.Store in process memory
+type HookStore struct {
+	mu    sync.Mutex
+	hooks map[entity.UserID]userhooks.Hook
+}
+
+var _ userhooks.Store = (*HookStore)(nil)
+
+// NewHookStore creates an empty store
+func NewHookStore() *HookStore {
+	return &HookStore{hooks: map[entity.UserID]userhooks.Hook{}}
+}
+
+// SaveHook stores the hook, replacing the one of the user
+func (m *HookStore) SaveHook(_ context.Context, h userhooks.Hook) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks[h.UserID] = h
+	return nil
+}
+
+// GetHook returns a copy of the hook of the user
+func (m *HookStore) GetHook(_ context.Context, userID entity.UserID) (*userhooks.Hook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.hooks[userID]
+	if !ok {
+		return nil, userhooks.ErrNotFound
+	}
+	return &h, nil
+}
+
+// DeleteHook removes the hook of the user
+func (m *HookStore) DeleteHook(_ context.Context, userID entity.UserID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.hooks[userID]; !ok {
+		return userhooks.ErrNotFound
+	}
+	delete(m.hooks, userID)
+	return nil
+}
@@ -0,0 +1,72 @@
+package storetest
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/widget"
+)
+
+// WidgetStore — widget.Store in process memory
+type WidgetStore struct {
+	mu     sync.Mutex
+	tokens map[string]widget.Token
+}
+
+var _ widget.Store = (*WidgetStore)(nil)
+
+// NewWidgetStore creates an empty store
+func NewWidgetStore() *WidgetStore {
+	return &WidgetStore{tokens: map[string]widget.Token{}}
+}
+
+// SaveToken stores a new token
+func (m *WidgetStore) SaveToken(_ context.Context, t widget.Token) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t.Origins = slices.Clone(t.Origins)
+	m.tokens[t.TokenHash] = t
+	return nil
+}
+
+// GetToken returns a copy of the token with the hash
+func (m *WidgetStore) GetToken(_ context.Context, hash string) (*widget.Token, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tokens[hash]
+	if !ok {
+		return nil, widget.ErrNotFound
+	}
+	t.Origins = slices.Clone(t.Origins)
+	return &t, nil
+}
+
+// RevokeToken marks the token revoked unless it already is
+func (m *WidgetStore) RevokeToken(_ context.Context, hash string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tokens[hash]
+	if !ok {
+		return widget.ErrNotFound
+	}
+	if t.RevokedAt == nil {
+		t.RevokedAt = &at
+		m.tokens[hash] = t
+	}
+	return nil
+}
+
+// DeleteUser removes the tokens of the user
+func (m *WidgetStore) DeleteUser(_ context.Context, user entity.UserID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for hash, t := range m.tokens {
+		if t.UserID == user {
+			delete(m.tokens, hash)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+// Package stripe mirrors active Stripe subscriptions of a customer into tracker entries
+package stripe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultBaseURL = "https://api.stripe.com"
+	defaultTimeout = 10 * time.Second
+	pageSize       = 100
+)
+
+// zeroDecimal - currencies whose Stripe amounts are already in whole units
+var zeroDecimal = map[string]bool{
+	"bif": true, "clp": true, "djf": true, "gnf": true, "jpy": true, "kmf": true, "krw": true, "mga": true,
+	"pyg": true, "rwf": true, "ugx": true, "vnd": true, "vuv": true, "xaf": true, "xof": true, "xpf": true,
+}
+
+var ErrUnexpectedStatus = errors.New("stripe: unexpected status")
+
+// Subscription — the fields of a Stripe subscription object used by the sync
+type Subscription struct {
+	ID                 string `json:"id"`
+	Status             string `json:"status"`
+	Customer           string `json:"customer"`
+	StartDate          int64  `json:"start_date"`
+	CurrentPeriodStart int64  `json:"current_period_start"`
+	// CancelAt - unix time the subscription is scheduled to end, 0 when it renews
+	CancelAt int64 `json:"cancel_at"`
+	EndedAt  int64 `json:"ended_at"`
+	Items    struct {
+		Data []Item `json:"data"`
+	} `json:"items"`
+}
+
+// Item — a single price of a subscription
+type Item struct {
+	Quantity           int64 `json:"quantity"`
+	Price              Price `json:"price"`
+	CurrentPeriodStart int64 `json:"current_period_start"`
+}
+
+// Price — amount and billing interval of an item
+type Price struct {
+	// Product - product ID; expanded product objects are reduced to their ID
+	Product    productRef `json:"product"`
+	Nickname   string     `json:"nickname"`
+	UnitAmount int64      `json:"unit_amount"`
+	Currency   string     `json:"currency"`
+	Recurring  struct {
+		Interval      string `json:"interval"`
+		IntervalCount int64  `json:"interval_count"`
+	} `json:"recurring"`
+}
+
+// productRef accepts both "prod_..." and an expanded {"id": ..., "name": ...} product
+type productRef struct {
+	ID   string
+	Name string
+}
+
+func (p *productRef) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		return json.Unmarshal(b, &p.ID)
+	}
+	var obj struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return err
+	}
+	p.ID, p.Name = obj.ID, obj.Name
+	return nil
+}
+
+// MonthlyCost converts the item price to whole currency units per month, rounded to the nearest unit
+func (it Item) MonthlyCost() int64 {
+	qty := it.Quantity
+	if qty <= 0 {
+		qty = 1
+	}
+	amount := float64(it.Price.UnitAmount * qty)
+	if !zeroDecimal[strings.ToLower(it.Price.Currency)] {
+		amount /= 100
+	}
+	count := float64(it.Price.Recurring.IntervalCount)
+	if count <= 0 {
+		count = 1
+	}
+	switch it.Price.Recurring.Interval {
+	case "day":
+		amount = amount * 365 / 12 / count
+	case "week":
+		amount = amount * 52 / 12 / count
+	case "year":
+		amount = amount / 12 / count
+	default:
+		amount /= count
+	}
+	return int64(math.Round(amount))
+}
+
+// periodStart returns the start of the current billing period; newer API versions keep it on items
+func (s Subscription) periodStart(it Item) time.Time {
+	for _, ts := range []int64{s.CurrentPeriodStart, it.CurrentPeriodStart, s.StartDate} {
+		if ts > 0 {
+			return time.Unix(ts, 0).UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// Client reads subscriptions and products from the Stripe REST API
+type Client struct {
+	baseURL  string
+	apiKey   string
+	customer string
+	client   *http.Client
+}
+
+// NewClient creates a client authenticating with the secret or restricted apiKey and applies options
+func NewClient(apiKey string, options ...func(*Client)) *Client {
+	c := &Client{
+		baseURL: defaultBaseURL,
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: defaultTimeout},
+	}
+	for _, o := range options {
+		o(c)
+	}
+	return c
+}
+
+// WithBaseURL overrides the API endpoint, e.g. for stripe-mock
+func WithBaseURL(u string) func(*Client) {
+	return func(c *Client) {
+		if u != "" {
+			c.baseURL = strings.TrimSuffix(u, "/")
+		}
+	}
+}
+
+// WithCustomer limits the sync to subscriptions of a single customer
+func WithCustomer(id string) func(*Client) {
+	return func(c *Client) {
+		c.customer = id
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for API calls
+func WithHTTPClient(hc *http.Client) func(*Client) {
+	return func(c *Client) {
+		if hc != nil {
+			c.client = hc
+		}
+	}
+}
+
+// ActiveSubscriptions lists every active subscription, following pagination
+func (c *Client) ActiveSubscriptions(ctx context.Context) ([]Subscription, error) {
+	var (
+		out   []Subscription
+		after string
+	)
+	for {
+		q := url.Values{}
+		q.Set("status", "active")
+		q.Set("limit", fmt.Sprint(pageSize))
+		if c.customer != "" {
+			q.Set("customer", c.customer)
+		}
+		if after != "" {
+			q.Set("starting_after", after)
+		}
+		var page struct {
+			Data    []Subscription `json:"data"`
+			HasMore bool           `json:"has_more"`
+		}
+		if err := c.get(ctx, "/v1/subscriptions?"+q.Encode(), &page); err != nil {
+			return nil, fmt.Errorf("list subscriptions: %w", err)
+		}
+		out = append(out, page.Data...)
+		if !page.HasMore || len(page.Data) == 0 {
+			return out, nil
+		}
+		after = page.Data[len(page.Data)-1].ID
+	}
+}
+
+// ProductName fetches the display name of a product
+func (c *Client) ProductName(ctx context.Context, id string) (string, error) {
+	var p struct {
+		Name string `json:"name"`
+	}
+	if err := c.get(ctx, "/v1/products/"+url.PathEscape(id), &p); err != nil {
+		return "", fmt.Errorf("get product %q: %w", id, err)
+	}
+	return p.Name, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w %d", ErrUnexpectedStatus, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
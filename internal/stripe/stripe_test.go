@@ -0,0 +1,143 @@
+package stripe
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/clock"
+)
+
+type call struct {
+	op      string
+	service string
+	amount  int64
+	month   time.Time
+}
+
+type recordingCharger struct {
+	calls []call
+}
+
+func (r *recordingCharger) ApplyCharge(_ context.Context, _ entity.UserID, service string, amount int64, month time.Time) (*entity.Subscription, usecase.ReceiptAction, error) {
+	r.calls = append(r.calls, call{op: "charge", service: service, amount: amount, month: month})
+	return &entity.Subscription{}, usecase.ReceiptCreated, nil
+}
+
+func (r *recordingCharger) EndSubscription(_ context.Context, _ entity.UserID, service string, month time.Time) (*entity.Subscription, usecase.ReceiptAction, error) {
+	r.calls = append(r.calls, call{op: "end", service: service, month: month})
+	return &entity.Subscription{}, usecase.ReceiptUpdated, nil
+}
+
+func month(y int, m time.Month) time.Time {
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func TestItem_MonthlyCost(t *testing.T) {
+	item := func(amount int64, currency, interval string, count int64) Item {
+		it := Item{Quantity: 1, Price: Price{UnitAmount: amount, Currency: currency}}
+		it.Price.Recurring.Interval = interval
+		it.Price.Recurring.IntervalCount = count
+		return it
+	}
+	assert.Equal(t, int64(10), item(999, "usd", "month", 1).MonthlyCost())
+	assert.Equal(t, int64(100), item(120000, "usd", "year", 1).MonthlyCost())
+	assert.Equal(t, int64(500), item(3000, "jpy", "month", 6).MonthlyCost())
+	assert.Equal(t, int64(43), item(1000, "eur", "week", 1).MonthlyCost())
+
+	seats := item(500, "usd", "month", 1)
+	seats.Quantity = 3
+	assert.Equal(t, int64(15), seats.MonthlyCost())
+}
+
+func TestSyncer_Sync(t *testing.T) {
+	jul := time.Date(2025, time.July, 12, 0, 0, 0, 0, time.UTC).Unix()
+	dec := time.Date(2025, time.December, 12, 0, 0, 0, 0, time.UTC).Unix()
+	productCalls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer rk_test", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/v1/subscriptions":
+			assert.Equal(t, "active", r.URL.Query().Get("status"))
+			assert.Equal(t, "cus_1", r.URL.Query().Get("customer"))
+			if r.URL.Query().Get("starting_after") == "" {
+				_, _ = fmt.Fprintf(w, `{"has_more":true,"data":[{"id":"sub_1","status":"active","current_period_start":%d,
+					"items":{"data":[{"quantity":1,"price":{"product":"prod_1","unit_amount":1500,"currency":"usd","recurring":{"interval":"month","interval_count":1}}}]}}]}`, jul)
+				return
+			}
+			_, _ = fmt.Fprintf(w, `{"has_more":false,"data":[{"id":"sub_2","status":"active","current_period_start":%d,"cancel_at":%d,
+				"items":{"data":[{"quantity":1,"price":{"product":"prod_1","unit_amount":12000,"currency":"usd","recurring":{"interval":"year","interval_count":1}}}]}}]}`, jul, dec)
+		case "/v1/products/prod_1":
+			productCalls++
+			_, _ = fmt.Fprint(w, `{"id":"prod_1","name":"Figma"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	charger := &recordingCharger{}
+	s := NewSyncer(NewClient("rk_test", WithBaseURL(srv.URL), WithCustomer("cus_1")), charger,
+		entity.UserID(uuid.New()), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	res, err := s.Sync(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, SyncResult{Created: 2}, res)
+	assert.Equal(t, []call{
+		{op: "charge", service: "Figma", amount: 15, month: month(2025, time.July)},
+		{op: "charge", service: "Figma", amount: 10, month: month(2025, time.July)},
+		{op: "end", service: "Figma", month: month(2025, time.December)},
+	}, charger.calls)
+	assert.Equal(t, 1, productCalls, "product names are cached")
+}
+
+func TestSyncer_HandleWebhook(t *testing.T) {
+	now := time.Date(2025, time.September, 3, 10, 0, 0, 0, time.UTC)
+	ended := time.Date(2025, time.September, 2, 0, 0, 0, 0, time.UTC).Unix()
+	payload := []byte(fmt.Sprintf(`{"type":"customer.subscription.deleted","data":{"object":{"id":"sub_1","status":"canceled","customer":"cus_1","ended_at":%d,
+		"items":{"data":[{"price":{"product":{"id":"prod_1","name":"Notion"},"unit_amount":800,"currency":"usd","recurring":{"interval":"month"}}}]}}}}`, ended))
+	signed := func(secret string, ts time.Time) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(fmt.Sprintf("%d.%s", ts.Unix(), payload)))
+		return fmt.Sprintf("t=%d,v1=%s", ts.Unix(), hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	newSyncer := func(secret string) (*Syncer, *recordingCharger) {
+		charger := &recordingCharger{}
+		s := NewSyncer(NewClient("rk_test"), charger, entity.UserID(uuid.New()),
+			slog.New(slog.NewTextHandler(io.Discard, nil)), WithWebhookSecret(secret), WithClock(clock.NewFake(now)))
+		return s, charger
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		s, _ := newSyncer("")
+		assert.ErrorIs(t, s.HandleWebhook(context.Background(), payload, signed("whsec", now)), ErrWebhooksDisabled)
+	})
+
+	t.Run("bad signature", func(t *testing.T) {
+		s, charger := newSyncer("whsec")
+		assert.ErrorIs(t, s.HandleWebhook(context.Background(), payload, signed("other", now)), ErrInvalidSignature)
+		assert.ErrorIs(t, s.HandleWebhook(context.Background(), payload, signed("whsec", now.Add(-time.Hour))), ErrInvalidSignature)
+		assert.Empty(t, charger.calls)
+	})
+
+	t.Run("deleted ends the subscription", func(t *testing.T) {
+		s, charger := newSyncer("whsec")
+		require.NoError(t, s.HandleWebhook(context.Background(), payload, signed("whsec", now)))
+		assert.Equal(t, []call{{op: "end", service: "Notion", month: month(2025, time.September)}}, charger.calls)
+	})
+}
@@ -0,0 +1,300 @@
+package stripe
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/integrations"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/clock"
+	"subs_tracker/pkg/dates"
+)
+
+const (
+	defaultSyncInterval = time.Hour
+	signatureTolerance  = 5 * time.Minute
+)
+
+var (
+	ErrWebhooksDisabled = errcode.New(errcode.FeatureDisabled, "stripe webhooks are disabled")
+	ErrInvalidSignature = errcode.New(errcode.SignatureInvalid, "invalid stripe signature")
+)
+
+// Charger — the tracker operations a Stripe subscription is mapped to, implemented by usecase.Subscription
+type Charger interface {
+	ApplyCharge(ctx context.Context, userID entity.UserID, service string, amount int64, month time.Time) (*entity.Subscription, usecase.ReceiptAction, error)
+	EndSubscription(ctx context.Context, userID entity.UserID, service string, month time.Time) (*entity.Subscription, usecase.ReceiptAction, error)
+}
+
+// SyncResult — counts of tracker changes made by a sync
+type SyncResult struct {
+	Created   int
+	Updated   int
+	Unchanged int
+}
+
+func (r *SyncResult) add(a usecase.ReceiptAction) {
+	switch a {
+	case usecase.ReceiptCreated:
+		r.Created++
+	case usecase.ReceiptUpdated:
+		r.Updated++
+	default:
+		r.Unchanged++
+	}
+}
+
+// Syncer maps Stripe subscriptions onto the tracker entries of a single user. Each item becomes an entry
+// named after its product that is charged the monthly cost in the current billing period; cancel_at and
+// ended subscriptions set the end month
+type Syncer struct {
+	client   *Client
+	charger  Charger
+	userID   entity.UserID
+	secret   string
+	interval time.Duration
+	log      *slog.Logger
+	clock    clock.Clock
+	tracker  *integrations.Tracker
+
+	mu       sync.Mutex
+	products map[string]string
+}
+
+// NewSyncer creates a syncer writing subscriptions read by client to userID through charger and applies options
+func NewSyncer(client *Client, charger Charger, userID entity.UserID, log *slog.Logger, options ...func(*Syncer)) *Syncer {
+	s := &Syncer{
+		client:   client,
+		charger:  charger,
+		userID:   userID,
+		interval: defaultSyncInterval,
+		log:      log,
+		clock:    clock.System,
+		products: make(map[string]string),
+	}
+	for _, o := range options {
+		o(s)
+	}
+	return s
+}
+
+// WithInterval sets the period of the full sync
+func WithInterval(d time.Duration) func(*Syncer) {
+	return func(s *Syncer) {
+		if d > 0 {
+			s.interval = d
+		}
+	}
+}
+
+// WithClock sets the source of the current time
+func WithClock(c clock.Clock) func(*Syncer) {
+	return func(s *Syncer) {
+		if c != nil {
+			s.clock = c
+		}
+	}
+}
+
+// WithWebhookSecret sets the endpoint signing secret (whsec_...); empty disables webhooks
+func WithWebhookSecret(secret string) func(*Syncer) {
+	return func(s *Syncer) {
+		s.secret = secret
+	}
+}
+
+// WithTracker records every scheduled sync as a call of the integration
+func WithTracker(t *integrations.Tracker) func(*Syncer) {
+	return func(s *Syncer) {
+		s.tracker = t
+	}
+}
+
+// Run syncs immediately and then on every tick until ctx is cancelled
+func (s *Syncer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		res, err := s.Sync(ctx)
+		if ctx.Err() == nil {
+			s.tracker.Record(err)
+		}
+		switch {
+		case err != nil && ctx.Err() == nil:
+			s.log.Warn("stripe sync failed", slog.Any("error", err))
+		case err == nil:
+			s.log.Info("stripe sync finished",
+				slog.Int("created", res.Created), slog.Int("updated", res.Updated), slog.Int("unchanged", res.Unchanged))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync applies every active Stripe subscription once; a failing subscription does not stop the others
+func (s *Syncer) Sync(ctx context.Context) (SyncResult, error) {
+	var res SyncResult
+	subs, err := s.client.ActiveSubscriptions(ctx)
+	if err != nil {
+		return res, err
+	}
+	var errs []error
+	for _, sub := range subs {
+		if err := s.apply(ctx, sub, &res); err != nil {
+			errs = append(errs, fmt.Errorf("subscription %s: %w", sub.ID, err))
+		}
+	}
+	return res, errors.Join(errs...)
+}
+
+// HandleWebhook verifies the Stripe-Signature header and applies customer.subscription.* events;
+// other event types are acknowledged and ignored
+func (s *Syncer) HandleWebhook(ctx context.Context, payload []byte, signature string) error {
+	if s.secret == "" {
+		return ErrWebhooksDisabled
+	}
+	if !validSignature(s.secret, payload, signature, s.clock.Now()) {
+		return ErrInvalidSignature
+	}
+	var ev struct {
+		Type string `json:"type"`
+		Data struct {
+			Object json.RawMessage `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &ev); err != nil {
+		return fmt.Errorf("stripe event: %w", err)
+	}
+	if !strings.HasPrefix(ev.Type, "customer.subscription.") {
+		return nil
+	}
+	var sub Subscription
+	if err := json.Unmarshal(ev.Data.Object, &sub); err != nil {
+		return fmt.Errorf("stripe event %s: %w", ev.Type, err)
+	}
+	if s.client.customer != "" && sub.Customer != s.client.customer {
+		return nil
+	}
+	var res SyncResult
+	return s.apply(ctx, sub, &res)
+}
+
+// apply maps every item of sub onto the tracker
+func (s *Syncer) apply(ctx context.Context, sub Subscription, res *SyncResult) error {
+	for _, it := range sub.Items.Data {
+		name, err := s.serviceName(ctx, it.Price)
+		if err != nil {
+			return err
+		}
+
+		if sub.Status == "canceled" || sub.Status == "incomplete_expired" {
+			end := sub.EndedAt
+			if end == 0 {
+				end = s.clock.Now().Unix()
+			}
+			_, action, err := s.charger.EndSubscription(ctx, s.userID, name, dates.MonthStart(time.Unix(end, 0).UTC()))
+			if errors.Is(err, usecase.ErrSubscriptionNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			res.add(action)
+			continue
+		}
+
+		month := dates.MonthStart(sub.periodStart(it))
+		_, action, err := s.charger.ApplyCharge(ctx, s.userID, name, it.MonthlyCost(), month)
+		if err != nil {
+			return err
+		}
+		if sub.CancelAt > 0 {
+			last := dates.MonthStart(time.Unix(sub.CancelAt, 0).UTC())
+			if _, endAction, err := s.charger.EndSubscription(ctx, s.userID, name, last); err != nil {
+				return err
+			} else if action == usecase.ReceiptUnchanged {
+				action = endAction
+			}
+		}
+		res.add(action)
+	}
+	return nil
+}
+
+// serviceName returns the product name of the price, fetching and caching it by product ID
+func (s *Syncer) serviceName(ctx context.Context, p Price) (string, error) {
+	if p.Product.Name != "" {
+		return p.Product.Name, nil
+	}
+	s.mu.Lock()
+	name, ok := s.products[p.Product.ID]
+	s.mu.Unlock()
+	if ok {
+		return name, nil
+	}
+	name, err := s.client.ProductName(ctx, p.Product.ID)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		name = p.Nickname
+	}
+	s.mu.Lock()
+	s.products[p.Product.ID] = name
+	s.mu.Unlock()
+	return name, nil
+}
+
+// validSignature checks a Stripe-Signature header "t=<unix>,v1=<hex>[,v1=...]" against
+// HMAC-SHA256("<t>.<payload>") and rejects timestamps further than signatureTolerance from now
+func validSignature(secret string, payload []byte, header string, now time.Time) bool {
+	var (
+		ts   int64
+		sigs []string
+	)
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			ts, _ = strconv.ParseInt(v, 10, 64)
+		case "v1":
+			sigs = append(sigs, v)
+		}
+	}
+	if ts == 0 || len(sigs) == 0 {
+		return false
+	}
+	if d := now.Sub(time.Unix(ts, 0)); d > signatureTolerance || d < -signatureTolerance {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts, 10) + "."))
+	mac.Write(payload)
+	want := mac.Sum(nil)
+	for _, sig := range sigs {
+		got, err := hex.DecodeString(sig)
+		if err == nil && hmac.Equal(got, want) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+// Package tenancy keeps the data of chosen tenants in a Postgres schema of their own. A request of such a
+// tenant carries it in its context, and every connection taken from the pool for that context has its
+// search_path switched to the tenant schema first; the other tenants share the schema of the DSN
+package tenancy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SchemaPrefix - the schema of a tenant is named by it and the tenant ID
+const SchemaPrefix = "tenant_"
+
+// connKey - the schema a connection is switched to, in its custom data
+const connKey = "tenancy.schema"
+
+type ctxKey struct{}
+
+// SchemaName returns the schema of the tenant
+func SchemaName(tenant string) string {
+	return SchemaPrefix + tenant
+}
+
+// WithTenant returns ctx whose database work is done in the schema of the tenant, "" for the shared one
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, tenant)
+}
+
+// FromContext returns the tenant of ctx, "" when it works in the shared schema
+func FromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(ctxKey{}).(string)
+	return tenant
+}
+
+// Schemas — the tenants having a schema of their own
+type Schemas struct {
+	tenants []string
+	known   map[string]bool
+}
+
+// NewSchemas creates the schemas of the tenants; the IDs are expected to be checked by the config
+func NewSchemas(tenants []string) *Schemas {
+	s := &Schemas{tenants: tenants, known: make(map[string]bool, len(tenants))}
+	for _, t := range tenants {
+		s.known[t] = true
+	}
+	return s
+}
+
+// Known tells whether the tenant has a schema of its own
+func (s *Schemas) Known(tenant string) bool {
+	return s.known[tenant]
+}
+
+// Tenants returns the tenants having a schema of their own, in config order
+func (s *Schemas) Tenants() []string {
+	return s.tenants
+}
+
+// Configure makes the pool switch the search_path of a connection to the schema of the tenant of the
+// acquiring context, and back to the one of the DSN for the shared schema. The schema a connection is at is
+// remembered on it, so only a connection moving between tenants costs a round trip
+func (s *Schemas) Configure(cfg *pgxpool.Config) {
+	cfg.PrepareConn = func(ctx context.Context, conn *pgx.Conn) (bool, error) {
+		schema := ""
+		if tenant := FromContext(ctx); tenant != "" && s.Known(tenant) {
+			schema = SchemaName(tenant)
+		}
+		data := conn.PgConn().CustomData()
+		if current, _ := data[connKey].(string); current == schema {
+			return true, nil
+		}
+		var err error
+		if schema == "" {
+			_, err = conn.Exec(ctx, "RESET search_path")
+		} else {
+			_, err = conn.Exec(ctx, "SELECT set_config('search_path', $1, false)", pgx.Identifier{schema}.Sanitize())
+		}
+		if err != nil {
+			// the connection is left at an unknown schema and is closed rather than reused
+			return false, fmt.Errorf("tenancy: switch to schema %q: %w", schema, err)
+		}
+		data[connKey] = schema
+		return true, nil
+	}
+}
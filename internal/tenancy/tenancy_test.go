@@ -0,0 +1,28 @@
+package tenancy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemas(t *testing.T) {
+	s := NewSchemas([]string{"acme", "globex.eu"})
+	assert.True(t, s.Known("acme"))
+	assert.False(t, s.Known("initech"))
+	assert.False(t, s.Known(""))
+	assert.Equal(t, []string{"acme", "globex.eu"}, s.Tenants())
+	assert.Equal(t, "tenant_globex.eu", SchemaName("globex.eu"))
+
+	ctx := context.Background()
+	assert.Empty(t, FromContext(ctx))
+	assert.Equal(t, "acme", FromContext(WithTenant(ctx, "acme")))
+
+	cfg, err := pgxpool.ParseConfig("postgres://u:p@db:5432/subs")
+	require.NoError(t, err)
+	s.Configure(cfg)
+	assert.NotNil(t, cfg.PrepareConn)
+}
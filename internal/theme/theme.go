@@ -0,0 +1,169 @@
+// Package theme keeps the branding tenants put on the reports rendered for their users: a logo, an accent color
+// and a footer line. Theming is soft: a report of a tenant without a theme, or whose theme cannot be read, is
+// rendered in the default look rather than failing
+package theme
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"subs_tracker/internal/errcode"
+	"subs_tracker/pkg/clock"
+)
+
+const (
+	maxTenantID = 64
+	maxLogoURL  = 2048
+	maxFooter   = 500
+)
+
+var (
+	ErrNotFound = errcode.New(errcode.ThemeNotFound, "theme not found")
+	ErrInvalid  = errcode.New(errcode.ThemeInvalid, "invalid theme")
+)
+
+var (
+	tenantPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+	colorPattern  = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+)
+
+// Theme — the branding of a tenant; empty fields keep the default look
+type Theme struct {
+	// TenantID - the tenant as the gateway in front names it, e.g. "acme"
+	TenantID string
+	// LogoURL - https URL of the logo shown above the report
+	LogoURL string
+	// Color - accent color of headings and rules, #rrggbb
+	Color string
+	// Footer - plain text line at the bottom of the report
+	Footer string
+	// UpdatedAt - last time the theme was saved
+	UpdatedAt time.Time
+}
+
+// Normalize trims the fields and lower-cases the color, e.g. "#1A2B3C" to "#1a2b3c"
+func (t Theme) Normalize() Theme {
+	t.TenantID = strings.TrimSpace(t.TenantID)
+	t.LogoURL = strings.TrimSpace(t.LogoURL)
+	t.Color = strings.ToLower(strings.TrimSpace(t.Color))
+	t.Footer = strings.TrimSpace(t.Footer)
+	return t
+}
+
+// Default reports whether the theme keeps the default look, i.e. sets none of the branding
+func (t Theme) Default() bool {
+	return t.LogoURL == "" && t.Color == "" && t.Footer == ""
+}
+
+// Validate checks a normalized theme
+func (t Theme) Validate() error {
+	if !ValidTenantID(t.TenantID) {
+		return fmt.Errorf("%w: tenant_id must be up to %d letters, digits, '.', '_' or '-'", ErrInvalid, maxTenantID)
+	}
+	if t.LogoURL != "" {
+		u, err := url.Parse(t.LogoURL)
+		if err != nil || u.Scheme != "https" || u.Host == "" || len(t.LogoURL) > maxLogoURL {
+			return fmt.Errorf("%w: logo_url must be an https URL", ErrInvalid)
+		}
+	}
+	if t.Color != "" && !colorPattern.MatchString(t.Color) {
+		return fmt.Errorf("%w: color must be #rrggbb", ErrInvalid)
+	}
+	if utf8.RuneCountInString(t.Footer) > maxFooter || strings.ContainsAny(t.Footer, "\r\n") {
+		return fmt.Errorf("%w: footer must be a single line of up to %d characters", ErrInvalid, maxFooter)
+	}
+	return nil
+}
+
+// ValidTenantID reports whether id can name a tenant
+func ValidTenantID(id string) bool {
+	return len(id) <= maxTenantID && tenantPattern.MatchString(id)
+}
+
+// Store — storage of themes
+type Store interface {
+	// GetTheme - get the theme of a tenant, ErrNotFound if there is none
+	GetTheme(ctx context.Context, tenantID string) (*Theme, error)
+	// SaveTheme - create or replace the theme of t.TenantID
+	SaveTheme(ctx context.Context, t Theme) error
+	// DeleteTheme - remove the theme of a tenant, ErrNotFound if there is none
+	DeleteTheme(ctx context.Context, tenantID string) error
+}
+
+// Themes reads and writes tenant themes
+type Themes struct {
+	store Store
+	clock clock.Clock
+}
+
+// NewThemes creates themes kept in store and applies options
+func NewThemes(store Store, options ...func(*Themes)) *Themes {
+	t := &Themes{store: store, clock: clock.System}
+	for _, o := range options {
+		o(t)
+	}
+	return t
+}
+
+// WithClock returns an option that sets the source of update times
+func WithClock(c clock.Clock) func(*Themes) {
+	return func(t *Themes) {
+		if c != nil {
+			t.clock = c
+		}
+	}
+}
+
+// Get returns the theme of a tenant, ErrNotFound if it has none
+func (t *Themes) Get(ctx context.Context, tenantID string) (Theme, error) {
+	tenantID = strings.TrimSpace(tenantID)
+	if !ValidTenantID(tenantID) {
+		return Theme{}, ErrNotFound
+	}
+	th, err := t.store.GetTheme(ctx, tenantID)
+	if err != nil {
+		return Theme{}, err
+	}
+	return *th, nil
+}
+
+// Save validates and stores the theme of th.TenantID, replacing the previous one
+func (t *Themes) Save(ctx context.Context, th Theme) (Theme, error) {
+	th = th.Normalize()
+	if err := th.Validate(); err != nil {
+		return Theme{}, err
+	}
+	th.UpdatedAt = t.clock.Now().UTC()
+	if err := t.store.SaveTheme(ctx, th); err != nil {
+		return Theme{}, fmt.Errorf("save theme: %w", err)
+	}
+	return th, nil
+}
+
+// Delete removes the theme of a tenant, whose reports go back to the default look
+func (t *Themes) Delete(ctx context.Context, tenantID string) error {
+	tenantID = strings.TrimSpace(tenantID)
+	if !ValidTenantID(tenantID) {
+		return ErrNotFound
+	}
+	return t.store.DeleteTheme(ctx, tenantID)
+}
+
+// ForReport returns the theme a report of tenantID is rendered with: the zero theme without a tenant, when it
+// has no theme or when the theme cannot be read, so a report never fails over its branding. A nil Themes
+// renders every report in the default look
+func (t *Themes) ForReport(ctx context.Context, tenantID string) Theme {
+	if t == nil || tenantID == "" {
+		return Theme{}
+	}
+	th, err := t.Get(ctx, tenantID)
+	if err != nil {
+		return Theme{}
+	}
+	return th
+}
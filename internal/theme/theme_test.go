@@ -0,0 +1,60 @@
+package theme_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/internal/storetest"
+	"subs_tracker/internal/theme"
+	"subs_tracker/pkg/clock"
+)
+
+func TestThemes(t *testing.T) {
+	ctx := context.Background()
+	now := clock.NewFake(time.Date(2025, time.July, 10, 12, 0, 0, 0, time.UTC))
+	themes := theme.NewThemes(storetest.NewThemeStore(), theme.WithClock(now))
+
+	for _, bad := range []theme.Theme{
+		{TenantID: ""},
+		{TenantID: "acme/eu"},
+		{TenantID: strings.Repeat("a", 65)},
+		{TenantID: "acme", LogoURL: "http://cdn.example.com/logo.png"},
+		{TenantID: "acme", LogoURL: "javascript:alert(1)"},
+		{TenantID: "acme", Color: "red"},
+		{TenantID: "acme", Color: "#12345"},
+		{TenantID: "acme", Footer: "two\nlines"},
+		{TenantID: "acme", Footer: strings.Repeat("я", 501)},
+	} {
+		_, err := themes.Save(ctx, bad)
+		assert.ErrorIs(t, err, theme.ErrInvalid, "%+v", bad)
+	}
+
+	saved, err := themes.Save(ctx, theme.Theme{TenantID: " acme ", LogoURL: "https://cdn.example.com/logo.png", Color: "#1A2B3C", Footer: " ACME Corp "})
+	require.NoError(t, err)
+	assert.Equal(t, theme.Theme{
+		TenantID:  "acme",
+		LogoURL:   "https://cdn.example.com/logo.png",
+		Color:     "#1a2b3c",
+		Footer:    "ACME Corp",
+		UpdatedAt: now.Now(),
+	}, saved)
+	got, err := themes.Get(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, saved, got)
+	assert.Equal(t, saved, themes.ForReport(ctx, "acme"))
+
+	_, err = themes.Get(ctx, "globex")
+	assert.ErrorIs(t, err, theme.ErrNotFound)
+	assert.Zero(t, themes.ForReport(ctx, "globex"), "a tenant without a theme gets the default look")
+	assert.Zero(t, themes.ForReport(ctx, ""))
+	assert.Zero(t, (*theme.Themes)(nil).ForReport(ctx, "acme"))
+
+	require.NoError(t, themes.Delete(ctx, "acme"))
+	assert.ErrorIs(t, themes.Delete(ctx, "acme"), theme.ErrNotFound)
+	assert.Zero(t, themes.ForReport(ctx, "acme"))
+}
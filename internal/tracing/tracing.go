@@ -0,0 +1,103 @@
+// Package tracing carries the customer a request acts for through its trace: user_id and tenant_id travel as
+// OpenTelemetry baggage from the HTTP middleware to every span below it, SQL spans included, so traces can be
+// filtered by customer during incident analysis. Spans go to the global tracer provider, a no-op until Setup
+// installs an exporter
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"subs_tracker/internal/buildinfo"
+)
+
+// Baggage members and span attributes naming the customer
+const (
+	UserIDKey   = "user_id"
+	TenantIDKey = "tenant_id"
+)
+
+// instrumentation - name of the tracers of this application
+const instrumentation = "subs_tracker"
+
+// Propagator reads and writes the W3C traceparent and baggage headers
+var Propagator propagation.TextMapPropagator = propagation.NewCompositeTextMapPropagator(
+	propagation.TraceContext{}, propagation.Baggage{},
+)
+
+// Tracer returns the tracer of the application from the global provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// WithCustomer returns ctx with userID and tenantID in its baggage, replacing values already there, and sets
+// them on the span of ctx; empty or malformed values are left out
+func WithCustomer(ctx context.Context, userID, tenantID string) context.Context {
+	bag := baggage.FromContext(ctx)
+	span := trace.SpanFromContext(ctx)
+	for _, kv := range [...]struct{ key, value string }{{UserIDKey, userID}, {TenantIDKey, tenantID}} {
+		if kv.value == "" {
+			continue
+		}
+		m, err := baggage.NewMemberRaw(kv.key, kv.value)
+		if err != nil {
+			continue
+		}
+		if b, err := bag.SetMember(m); err == nil {
+			bag = b
+			span.SetAttributes(attribute.String(kv.key, kv.value))
+		}
+	}
+	return baggage.ContextWithBaggage(ctx, bag)
+}
+
+// TenantID returns the tenant in the baggage of ctx, "" when there is none
+func TenantID(ctx context.Context) string {
+	return baggage.FromContext(ctx).Member(TenantIDKey).Value()
+}
+
+// Attributes returns the customer of ctx as span attributes, none when its baggage has no customer
+func Attributes(ctx context.Context) []attribute.KeyValue {
+	bag := baggage.FromContext(ctx)
+	var attrs []attribute.KeyValue
+	for _, key := range [...]string{UserIDKey, TenantIDKey} {
+		if v := bag.Member(key).Value(); v != "" {
+			attrs = append(attrs, attribute.String(key, v))
+		}
+	}
+	return attrs
+}
+
+// Setup installs a global tracer provider exporting spans over OTLP/HTTP to endpoint, a URL like
+// http://collector:4318, keeping the sampleRatio share of new traces; the returned func flushes and stops it
+func Setup(ctx context.Context, endpoint string, sampleRatio float64) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("tracing: create exporter: %w", err)
+	}
+	info := buildinfo.Get()
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", info.Name),
+		attribute.String("service.version", info.Version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("tracing: resource: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter, sdktrace.WithBatchTimeout(5*time.Second)),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
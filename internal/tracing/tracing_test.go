@@ -0,0 +1,68 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans makes the global provider keep every span until the test ends
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return rec
+}
+
+func TestWithCustomer(t *testing.T) {
+	ctx := WithCustomer(context.Background(), "", "acme")
+	assert.Equal(t, []attribute.KeyValue{attribute.String(TenantIDKey, "acme")}, Attributes(ctx))
+
+	ctx = WithCustomer(ctx, "60601fee-2bf1-4721-ae6f-7636e79a0cba", "")
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String(UserIDKey, "60601fee-2bf1-4721-ae6f-7636e79a0cba"),
+		attribute.String(TenantIDKey, "acme"),
+	}, Attributes(ctx), "an empty value keeps the tenant")
+
+	assert.Empty(t, Attributes(context.Background()))
+}
+
+func TestQueryTracer(t *testing.T) {
+	rec := recordSpans(t)
+	ctx, parent := Tracer().Start(context.Background(), "GET /api/v1/subscriptions")
+	ctx = WithCustomer(ctx, "60601fee-2bf1-4721-ae6f-7636e79a0cba", "acme")
+
+	var tracer QueryTracer
+	qctx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "-- name: ListSubscriptions :many\nSELECT 1"})
+	tracer.TraceQueryEnd(qctx, nil, pgx.TraceQueryEndData{})
+	qctx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "select pg_advisory_unlock($1)"})
+	tracer.TraceQueryEnd(qctx, nil, pgx.TraceQueryEndData{Err: errors.New("conn closed")})
+	parent.End()
+
+	spans := rec.Ended()
+	require.Len(t, spans, 3)
+	list, unlock := spans[0], spans[1]
+	assert.Equal(t, "postgres ListSubscriptions", list.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), list.Parent().SpanID())
+	assert.Subset(t, list.Attributes(), []attribute.KeyValue{
+		attribute.String("db.operation.name", "ListSubscriptions"),
+		attribute.String(UserIDKey, "60601fee-2bf1-4721-ae6f-7636e79a0cba"),
+		attribute.String(TenantIDKey, "acme"),
+	})
+	assert.Equal(t, codes.Unset, list.Status().Code)
+
+	assert.Equal(t, "postgres SELECT", unlock.Name())
+	assert.Equal(t, codes.Error, unlock.Status().Code)
+	assert.Equal(t, "conn closed", unlock.Status().Description)
+}
@@ -0,0 +1,110 @@
+package usage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPostHogHost - PostHog Cloud US ingestion
+	DefaultPostHogHost = "https://us.i.posthog.com"
+	// PostHogEvent - name of the events the counts are sent as
+	PostHogEvent = "api_request"
+	// anonymousID - distinct_id of counts without a tenant
+	anonymousID = "anonymous"
+
+	defaultTimeout = 5 * time.Second
+)
+
+// PostHog sends counts to the batch endpoint of PostHog as PostHogEvent events with the endpoint, tenant,
+// result and count as properties. Person profiles are turned off, so the distinct_id, the tenant, only groups
+// the events
+type PostHog struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+// NewPostHog creates a sink for the project of apiKey at host, e.g. DefaultPostHogHost
+func NewPostHog(host, apiKey string, options ...func(*PostHog)) *PostHog {
+	p := &PostHog{
+		url:    strings.TrimSuffix(host, "/") + "/batch/",
+		apiKey: apiKey,
+		client: &http.Client{Timeout: defaultTimeout},
+	}
+	for _, o := range options {
+		o(p)
+	}
+	return p
+}
+
+// WithPostHogTimeout bounds a single batch request
+func WithPostHogTimeout(timeout time.Duration) func(*PostHog) {
+	return func(p *PostHog) {
+		if timeout > 0 {
+			p.client = &http.Client{Timeout: timeout}
+		}
+	}
+}
+
+type postHogBatch struct {
+	APIKey string         `json:"api_key"`
+	Batch  []postHogEvent `json:"batch"`
+}
+
+type postHogEvent struct {
+	Event      string         `json:"event"`
+	DistinctID string         `json:"distinct_id"`
+	Timestamp  string         `json:"timestamp"`
+	Properties map[string]any `json:"properties"`
+}
+
+// Send posts the counts as one batch
+func (p *PostHog) Send(ctx context.Context, at time.Time, counts []Count) error {
+	batch := postHogBatch{APIKey: p.apiKey, Batch: make([]postHogEvent, 0, len(counts))}
+	for _, c := range counts {
+		id := c.Tenant
+		if id == "" {
+			id = anonymousID
+		}
+		batch.Batch = append(batch.Batch, postHogEvent{
+			Event:      PostHogEvent,
+			DistinctID: id,
+			Timestamp:  at.Format(time.RFC3339),
+			Properties: map[string]any{
+				"endpoint":                c.Endpoint,
+				"tenant":                  c.Tenant,
+				"result":                  string(c.Result),
+				"count":                   c.N,
+				"$process_person_profile": false,
+				"$lib":                    "subs_tracker",
+			},
+		})
+	}
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("posthog batch: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("posthog request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("posthog: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("posthog: status %d", resp.StatusCode)
+	}
+	return nil
+}
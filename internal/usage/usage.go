@@ -0,0 +1,192 @@
+// Package usage counts which API endpoints are used, for product analytics. The counts are anonymous: an
+// endpoint is its method and route template, so no IDs or query strings end up in them, and neither users nor
+// clients are recorded, only the tenant of the request if the gateway in front names one. Counts are kept in
+// memory and sent to a Sink in batches; analytics are best effort and never slow down or fail a request
+package usage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"subs_tracker/internal/integrations"
+	"subs_tracker/pkg/clock"
+)
+
+const (
+	defaultInterval = time.Minute
+	// maxKeys bounds the counts kept between flushes; past it new tenants are counted as OtherTenant
+	maxKeys = 10_000
+	// flushTimeout bounds the last flush on shutdown
+	flushTimeout = 5 * time.Second
+)
+
+// OtherTenant - tenant of the counts recorded once maxKeys distinct keys are waiting to be sent
+const OtherTenant = "other"
+
+// Result — outcome class of a request
+type Result string
+
+const (
+	ResultOK          Result = "ok"
+	ResultClientError Result = "client_error"
+	ResultServerError Result = "server_error"
+)
+
+// ResultOf classifies an HTTP status
+func ResultOf(status int) Result {
+	switch {
+	case status >= 500:
+		return ResultServerError
+	case status >= 400:
+		return ResultClientError
+	default:
+		return ResultOK
+	}
+}
+
+// Key — what a count is about
+type Key struct {
+	// Endpoint - method and route template, e.g. "GET /api/v1/subscriptions/:id"
+	Endpoint string
+	// Tenant - tenant of the requests, "" without one
+	Tenant string
+	Result Result
+}
+
+// Count — requests of one key in a batch
+type Count struct {
+	Key
+	N int64
+}
+
+// Sink — where counts are sent
+type Sink interface {
+	// Send delivers the counts of the window ending at at
+	Send(ctx context.Context, at time.Time, counts []Count) error
+}
+
+// Counter counts requests and sends the counts to its sink every interval
+type Counter struct {
+	sink     Sink
+	log      *slog.Logger
+	interval time.Duration
+	clock    clock.Clock
+	tracker  *integrations.Tracker
+
+	mu     sync.Mutex
+	counts map[Key]int64
+}
+
+// NewCounter creates a counter sending to sink and applies options
+func NewCounter(sink Sink, log *slog.Logger, options ...func(*Counter)) *Counter {
+	c := &Counter{
+		sink:     sink,
+		log:      log,
+		interval: defaultInterval,
+		clock:    clock.System,
+		counts:   make(map[Key]int64),
+	}
+	for _, o := range options {
+		o(c)
+	}
+	return c
+}
+
+// WithInterval returns an option that sets how often counts are sent
+func WithInterval(interval time.Duration) func(*Counter) {
+	return func(c *Counter) {
+		if interval > 0 {
+			c.interval = interval
+		}
+	}
+}
+
+// WithClock returns an option that sets the source of batch times
+func WithClock(cl clock.Clock) func(*Counter) {
+	return func(c *Counter) {
+		if cl != nil {
+			c.clock = cl
+		}
+	}
+}
+
+// WithTracker returns an option that records every send to the sink as a call of the integration
+func WithTracker(t *integrations.Tracker) func(*Counter) {
+	return func(c *Counter) {
+		c.tracker = t
+	}
+}
+
+// Add counts one request
+func (c *Counter) Add(k Key) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.counts[k]; !ok && len(c.counts) >= maxKeys {
+		k.Tenant = OtherTenant
+	}
+	c.counts[k]++
+}
+
+// Flush sends the counts recorded since the last flush, in key order. Counts the sink refused are kept for the
+// next flush
+func (c *Counter) Flush(ctx context.Context) error {
+	c.mu.Lock()
+	pending := c.counts
+	c.counts = make(map[Key]int64)
+	c.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	counts := make([]Count, 0, len(pending))
+	for k, n := range pending {
+		counts = append(counts, Count{Key: k, N: n})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		a, b := counts[i].Key, counts[j].Key
+		if a.Endpoint != b.Endpoint {
+			return a.Endpoint < b.Endpoint
+		}
+		if a.Tenant != b.Tenant {
+			return a.Tenant < b.Tenant
+		}
+		return a.Result < b.Result
+	})
+	err := c.sink.Send(ctx, c.clock.Now().UTC(), counts)
+	c.tracker.Record(err)
+	if err != nil {
+		c.mu.Lock()
+		for k, n := range pending {
+			c.counts[k] += n
+		}
+		c.mu.Unlock()
+		return fmt.Errorf("send usage: %w", err)
+	}
+	return nil
+}
+
+// Run flushes every interval until ctx is cancelled, then flushes what is left
+func (c *Counter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), flushTimeout)
+			defer cancel()
+			if err := c.Flush(flushCtx); err != nil {
+				c.log.Warn("last usage flush failed", slog.Any("error", err))
+			}
+			return nil
+		case <-ticker.C:
+			if err := c.Flush(ctx); err != nil && ctx.Err() == nil {
+				c.log.Warn("usage flush failed", slog.Any("error", err))
+			}
+		}
+	}
+}
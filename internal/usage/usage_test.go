@@ -0,0 +1,124 @@
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/pkg/clock"
+)
+
+type sinkRecorder struct {
+	fail  bool
+	at    []time.Time
+	sends [][]Count
+}
+
+func (s *sinkRecorder) Send(_ context.Context, at time.Time, counts []Count) error {
+	if s.fail {
+		return errors.New("sink down")
+	}
+	s.at = append(s.at, at)
+	s.sends = append(s.sends, counts)
+	return nil
+}
+
+func TestResultOf(t *testing.T) {
+	assert.Equal(t, ResultOK, ResultOf(http.StatusOK))
+	assert.Equal(t, ResultOK, ResultOf(http.StatusNotModified))
+	assert.Equal(t, ResultClientError, ResultOf(http.StatusNotFound))
+	assert.Equal(t, ResultServerError, ResultOf(http.StatusServiceUnavailable))
+}
+
+func TestCounter_Flush(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, time.July, 1, 10, 0, 0, 0, time.UTC)
+	sink := &sinkRecorder{fail: true}
+	c := NewCounter(sink, slog.New(slog.NewTextHandler(io.Discard, nil)), WithClock(clock.NewFake(now)))
+
+	list := Key{Endpoint: "GET /api/v1/subscriptions", Tenant: "acme", Result: ResultOK}
+	cost := Key{Endpoint: "GET /api/v1/subscriptions/cost", Result: ResultClientError}
+	c.Add(cost)
+	c.Add(list)
+	c.Add(list)
+
+	require.Error(t, c.Flush(ctx))
+	assert.Empty(t, sink.sends)
+
+	// refused counts are sent with the next batch
+	sink.fail = false
+	c.Add(list)
+	require.NoError(t, c.Flush(ctx))
+	require.Len(t, sink.sends, 1)
+	assert.Equal(t, []Count{{Key: list, N: 3}, {Key: cost, N: 1}}, sink.sends[0])
+	assert.Equal(t, []time.Time{now}, sink.at)
+
+	require.NoError(t, c.Flush(ctx))
+	assert.Len(t, sink.sends, 1, "nothing to send")
+}
+
+func TestCounter_AddBounded(t *testing.T) {
+	sink := &sinkRecorder{}
+	c := NewCounter(sink, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	for i := range maxKeys {
+		c.Add(Key{Endpoint: "GET /api/v1/subscriptions", Tenant: "t" + strconv.Itoa(i)})
+	}
+	c.Add(Key{Endpoint: "GET /api/v1/subscriptions", Tenant: "late"})
+	c.Add(Key{Endpoint: "GET /api/v1/subscriptions", Tenant: "later"})
+
+	require.NoError(t, c.Flush(context.Background()))
+	counts := sink.sends[0]
+	assert.Len(t, counts, maxKeys+1)
+	assert.Contains(t, counts, Count{Key: Key{Endpoint: "GET /api/v1/subscriptions", Tenant: OtherTenant}, N: 2})
+}
+
+func TestPostHog_Send(t *testing.T) {
+	var got postHogBatch
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/batch/", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	at := time.Date(2025, time.July, 1, 10, 0, 0, 0, time.UTC)
+	p := NewPostHog(srv.URL+"/", "phc_test")
+	err := p.Send(context.Background(), at, []Count{
+		{Key: Key{Endpoint: "GET /api/v1/subscriptions", Tenant: "acme", Result: ResultOK}, N: 3},
+		{Key: Key{Endpoint: "POST /api/v1/subscriptions", Result: ResultClientError}, N: 1},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "phc_test", got.APIKey)
+	require.Len(t, got.Batch, 2)
+	assert.Equal(t, postHogEvent{
+		Event:      PostHogEvent,
+		DistinctID: "acme",
+		Timestamp:  "2025-07-01T10:00:00Z",
+		Properties: map[string]any{
+			"endpoint":                "GET /api/v1/subscriptions",
+			"tenant":                  "acme",
+			"result":                  "ok",
+			"count":                   float64(3),
+			"$process_person_profile": false,
+			"$lib":                    "subs_tracker",
+		},
+	}, got.Batch[0])
+	assert.Equal(t, "anonymous", got.Batch[1].DistinctID)
+
+	status = http.StatusUnauthorized
+	assert.ErrorContains(t, p.Send(context.Background(), at, []Count{{N: 1}}), "status 401")
+}
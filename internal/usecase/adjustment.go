@@ -0,0 +1,58 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/pkg/dates"
+)
+
+// MaxAdjustmentNote - longest note of an adjustment, in characters
+const MaxAdjustmentNote = 500
+
+// RecordAdjustment validates and stores a refund, credit or extra charge of an existing subscription in a
+// month; from then on the cost totals of that month are net of it. The month is aligned to its first day
+func (s *Subscription) RecordAdjustment(ctx context.Context, a *entity.Adjustment) (*entity.Adjustment, error) {
+	if a == nil || a.SubscriptionID <= 0 {
+		return nil, ErrInvalidID
+	}
+	a.Note = strings.TrimSpace(a.Note)
+	switch {
+	case a.Amount == 0:
+		return nil, fmt.Errorf("%w: amount must not be 0", ErrInvalidAdjustment)
+	case a.Month.IsZero():
+		return nil, fmt.Errorf("%w: empty month", ErrInvalidAdjustment)
+	case utf8.RuneCountInString(a.Note) > MaxAdjustmentNote:
+		return nil, fmt.Errorf("%w: note is too long", ErrInvalidAdjustment)
+	}
+	a.Month = dates.MonthStart(a.Month)
+
+	sub, err := s.GetSubByID(ctx, a.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	saved, err := s.Sr.SaveAdjustment(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	s.costNow.drop(ctx, sub.UserID)
+	return saved, nil
+}
+
+// ListAdjustments returns the adjustments of an existing subscription, oldest month first
+func (s *Subscription) ListAdjustments(ctx context.Context, subID int64) ([]entity.Adjustment, error) {
+	sub, err := s.GetSubByID(ctx, subID)
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	return s.Sr.ListAdjustments(ctx, subID)
+}
@@ -0,0 +1,188 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/tenancy"
+)
+
+// DefaultCostNowTTL - how long a current-month total is served from memory unless WithCostNowTTL says otherwise
+const DefaultCostNowTTL = 30 * time.Second
+
+// CostNow — a user's spend in the current month, as polled by widgets
+type CostNow struct {
+	// Month - the current month in the user's timezone
+	Month time.Time
+	// Total - summed monthly cost of the subscriptions active in Month plus the adjustments of Month
+	Total    int64
+	Currency string
+	// AsOf - when Total was computed; it is at most the cache lifetime old
+	AsOf time.Time
+}
+
+// costNowCache keeps recent current-month totals per tenant and user
+type costNowCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[costNowKey]costNowEntry
+	// flushed - signalled by flush, buffered so a flush never waits for the primer
+	flushed chan struct{}
+}
+
+// costNowKey - a user of a tenant; the same user ID of two tenants are two accounts in two schemas
+type costNowKey struct {
+	tenant string
+	user   entity.UserID
+}
+
+func costNowKeyOf(ctx context.Context, user entity.UserID) costNowKey {
+	return costNowKey{tenant: tenancy.FromContext(ctx), user: user}
+}
+
+type costNowEntry struct {
+	cost CostNow
+	// expires - end of the cache lifetime, or the start of the next month if that comes first
+	expires time.Time
+}
+
+func newCostNowCache(ttl time.Duration) *costNowCache {
+	return &costNowCache{ttl: ttl, entries: map[costNowKey]costNowEntry{}, flushed: make(chan struct{}, 1)}
+}
+
+// get returns the entry of user in the tenant of ctx if it is still fresh at now
+func (c *costNowCache) get(ctx context.Context, user entity.UserID, now time.Time) (CostNow, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[costNowKeyOf(ctx, user)]
+	if !ok || !now.Before(e.expires) {
+		return CostNow{}, false
+	}
+	return e.cost, true
+}
+
+// put keeps cost of user in the tenant of ctx until the cache lifetime ends or the month changes in loc
+func (c *costNowCache) put(ctx context.Context, user entity.UserID, cost CostNow, loc *time.Location) {
+	if c.ttl <= 0 {
+		return
+	}
+	local := cost.AsOf.In(loc)
+	next := time.Date(local.Year(), local.Month()+1, 1, 0, 0, 0, 0, loc)
+	expires := cost.AsOf.Add(c.ttl)
+	if next.Before(expires) {
+		expires = next
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[costNowKeyOf(ctx, user)] = costNowEntry{cost: cost, expires: expires}
+}
+
+// drop forgets the totals of users in the tenant of ctx after a write changed them
+func (c *costNowCache) drop(ctx context.Context, users ...entity.UserID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, u := range users {
+		delete(c.entries, costNowKeyOf(ctx, u))
+	}
+}
+
+// flush forgets every total and returns how many there were
+func (c *costNowCache) flush() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := len(c.entries)
+	clear(c.entries)
+	select {
+	case c.flushed <- struct{}{}:
+	default:
+	}
+	return n
+}
+
+// WithCostNowTTL returns an option that sets how long CostNow serves a total from memory; 0 always reads the
+// repository, negative values keep the default
+func WithCostNowTTL(d time.Duration) func(*Subscription) {
+	return func(s *Subscription) {
+		if d >= 0 {
+			s.costNow = newCostNowCache(d)
+		}
+	}
+}
+
+// WithCostNowPrime returns an option that makes PrimeCostNow compute the totals of the n users with the largest
+// spend this month; 0 turns priming off
+func WithCostNowPrime(n int) func(*Subscription) {
+	return func(s *Subscription) {
+		if n >= 0 {
+			s.costNowPrime = n
+		}
+	}
+}
+
+// CostNow returns the user's spend in the current month of their timezone. Totals are kept in memory for the
+// cache lifetime and dropped on every write of the user's subscriptions or settings through this instance, so
+// frequent polling rarely reaches the repository; writes through other instances, and moving a subscription
+// to another user, show up once the entry expires
+func (s *Subscription) CostNow(ctx context.Context, user entity.UserID) (CostNow, error) {
+	if user.IsZero() {
+		return CostNow{}, entity.ErrInvalidUserID
+	}
+	now := s.clock.Now().UTC()
+	if cost, ok := s.costNow.get(ctx, user, now); ok {
+		return cost, nil
+	}
+
+	settings, err := s.GetSettings(ctx, user)
+	if err != nil {
+		return CostNow{}, fmt.Errorf("cost now: %w", err)
+	}
+	month := settings.MonthOf(now)
+	total, err := s.Sr.CostSubsByFilter(ctx, SubFilter{UserID: user, Period: &Period{From: month, To: month}})
+	if err != nil {
+		return CostNow{}, fmt.Errorf("cost now: %w", err)
+	}
+	cost := CostNow{Month: month, Total: total, Currency: settings.Currency, AsOf: now}
+	s.costNow.put(ctx, user, cost, settings.Location())
+	return cost, nil
+}
+
+// FlushCostNow forgets every total kept by CostNow, e.g. after the database was changed behind the service,
+// and returns how many there were
+func (s *Subscription) FlushCostNow() int {
+	return s.costNow.flush()
+}
+
+// CostNowFlushed is signalled after FlushCostNow, for the primer to fill the cache again; flushes in a row
+// may be signalled once
+func (s *Subscription) CostNowFlushed() <-chan struct{} {
+	return s.costNow.flushed
+}
+
+// CostNowPriming reports whether PrimeCostNow has anything to do
+func (s *Subscription) CostNowPriming() bool {
+	return s.costNowPrime > 0 && s.costNow.ttl > 0
+}
+
+// PrimeCostNow fills the CostNow cache with the totals of the users with the largest spend this month, the
+// accounts most likely to be polled right after a deploy, and returns how many it computed. It is meant to
+// run in the background at startup and after FlushCostNow; a failure leaves the rest to be computed on demand
+func (s *Subscription) PrimeCostNow(ctx context.Context) (int, error) {
+	if !s.CostNowPriming() {
+		return 0, nil
+	}
+	totals, err := s.UserTotals(ctx, s.clock.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("prime cost now: %w", err)
+	}
+	primed := 0
+	for _, t := range totals[:min(len(totals), s.costNowPrime)] {
+		if _, err := s.CostNow(ctx, t.UserID); err != nil {
+			return primed, fmt.Errorf("prime cost now: %w", err)
+		}
+		primed++
+	}
+	return primed, nil
+}
@@ -0,0 +1,70 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"subs_tracker/internal/entity"
+)
+
+// DeactivateUser hides the user's subscriptions from lists, cost rollups, analytics and exports without
+// deleting anything, until ReactivateUser. Deactivating an already deactivated user keeps the original time,
+// which is returned
+func (s *Subscription) DeactivateUser(ctx context.Context, userID entity.UserID) (time.Time, error) {
+	if userID.IsZero() {
+		return time.Time{}, entity.ErrInvalidUserID
+	}
+	defer s.costNow.drop(ctx, userID)
+	at, err := s.Sr.DeactivateUser(ctx, userID, s.clock.Now().UTC())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("deactivate user: %w", err)
+	}
+	return at, nil
+}
+
+// ReactivateUser brings the subscriptions of a deactivated user back; reactivating an active user does nothing
+func (s *Subscription) ReactivateUser(ctx context.Context, userID entity.UserID) error {
+	if userID.IsZero() {
+		return entity.ErrInvalidUserID
+	}
+	defer s.costNow.drop(ctx, userID)
+	if err := s.Sr.ReactivateUser(ctx, userID); err != nil {
+		return fmt.Errorf("reactivate user: %w", err)
+	}
+	return nil
+}
+
+// UserDeactivatedAt returns when the user was deactivated, zero time for an active user
+func (s *Subscription) UserDeactivatedAt(ctx context.Context, userID entity.UserID) (time.Time, error) {
+	if userID.IsZero() {
+		return time.Time{}, entity.ErrInvalidUserID
+	}
+	at, err := s.Sr.UserDeactivatedAt(ctx, userID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("user deactivated at: %w", err)
+	}
+	return at, nil
+}
+
+// withoutDeactivated drops subscriptions of deactivated users from rows the repository did not filter, such
+// as archived ones; every distinct user is looked up once
+func (s *Subscription) withoutDeactivated(ctx context.Context, subs []*entity.Subscription) ([]*entity.Subscription, error) {
+	deactivated := map[entity.UserID]bool{}
+	out := subs[:0]
+	for _, sub := range subs {
+		off, seen := deactivated[sub.UserID]
+		if !seen {
+			at, err := s.Sr.UserDeactivatedAt(ctx, sub.UserID)
+			if err != nil {
+				return nil, err
+			}
+			off = !at.IsZero()
+			deactivated[sub.UserID] = off
+		}
+		if !off {
+			out = append(out, sub)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,68 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+)
+
+// ErrGoalNotSet - the user has no monthly goal to track
+var ErrGoalNotSet = errcode.New(errcode.GoalNotSet, "monthly goal is not set")
+
+// GoalProgress — a user's spend in the current month against their monthly goal
+type GoalProgress struct {
+	// Month - the current month in the user's timezone
+	Month time.Time
+	// Goal - the monthly goal of the settings
+	Goal int64
+	// Spent - spend in Month so far, the total of CostNow
+	Spent int64
+	// Projected - expected spend by the end of Month. Subscriptions are billed for whole months and adjustments
+	// are booked to a month, so every charge of Month is known once it starts and Projected equals Spent until
+	// a write changes Month
+	Projected int64
+	// Remaining - Goal minus Projected, negative once the goal is exceeded
+	Remaining int64
+	// Percent - Projected as a share of Goal in percent, rounded down; over 100 once the goal is exceeded
+	Percent  int64
+	Currency string
+	// AsOf - when the spend was computed
+	AsOf time.Time
+}
+
+// Over reports whether the month is projected to exceed the goal
+func (p GoalProgress) Over() bool {
+	return p.Projected > p.Goal
+}
+
+// GoalProgress compares the user's spend in the current month with their monthly goal, ErrGoalNotSet when they
+// have none. The spend is the one of CostNow, cached alike
+func (s *Subscription) GoalProgress(ctx context.Context, user entity.UserID) (GoalProgress, error) {
+	if user.IsZero() {
+		return GoalProgress{}, entity.ErrInvalidUserID
+	}
+	settings, err := s.GetSettings(ctx, user)
+	if err != nil {
+		return GoalProgress{}, fmt.Errorf("goal progress: %w", err)
+	}
+	if settings.MonthlyGoal <= 0 {
+		return GoalProgress{}, ErrGoalNotSet
+	}
+	cost, err := s.CostNow(ctx, user)
+	if err != nil {
+		return GoalProgress{}, fmt.Errorf("goal progress: %w", err)
+	}
+	return GoalProgress{
+		Month:     cost.Month,
+		Goal:      settings.MonthlyGoal,
+		Spent:     cost.Total,
+		Projected: cost.Total,
+		Remaining: settings.MonthlyGoal - cost.Total,
+		Percent:   cost.Total * 100 / settings.MonthlyGoal,
+		Currency:  cost.Currency,
+		AsOf:      cost.AsOf,
+	}, nil
+}
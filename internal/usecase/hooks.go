@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"subs_tracker/internal/entity"
+)
+
+// BeforeSaveHook - runs on a validated subscription before it is created or updated; it may change the
+// subscription, which is validated again, and an error aborts the write. Wrap ErrInvalidSubscription
+// to reject the input as invalid rather than fail the request. Dry runs run it too but store nothing, so it
+// should not have side effects of its own
+type BeforeSaveHook func(ctx context.Context, sub *entity.Subscription) error
+
+// AfterSaveHook - runs on the stored copy after a subscription was created or updated
+type AfterSaveHook func(ctx context.Context, sub *entity.Subscription)
+
+// AfterDeleteHook - runs on the last stored copy after a subscription was removed
+type AfterDeleteHook func(ctx context.Context, sub *entity.Subscription)
+
+// Hooks — registry of functions run around subscription writes, in registration order, so embedders can
+// enrich, validate or react to writes without changing the use case. After hooks run synchronously
+// in the request and must not modify the subscription they get. Register before passing to WithHooks
+type Hooks struct {
+	beforeSave  []BeforeSaveHook
+	afterSave   []AfterSaveHook
+	afterDelete []AfterDeleteHook
+}
+
+// BeforeSave registers a hook run before every create and update
+func (h *Hooks) BeforeSave(fn BeforeSaveHook) *Hooks {
+	if fn != nil {
+		h.beforeSave = append(h.beforeSave, fn)
+	}
+	return h
+}
+
+// AfterSave registers a hook run after every create and update
+func (h *Hooks) AfterSave(fn AfterSaveHook) *Hooks {
+	if fn != nil {
+		h.afterSave = append(h.afterSave, fn)
+	}
+	return h
+}
+
+// AfterDelete registers a hook run after every delete, merged-away subscriptions included
+func (h *Hooks) AfterDelete(fn AfterDeleteHook) *Hooks {
+	if fn != nil {
+		h.afterDelete = append(h.afterDelete, fn)
+	}
+	return h
+}
+
+// WithHooks returns an option that runs the registered lifecycle hooks around writes
+func WithHooks(h *Hooks) func(*Subscription) {
+	return func(s *Subscription) {
+		if h != nil {
+			s.hooks = h
+		}
+	}
+}
+
+func (h *Hooks) runBeforeSave(ctx context.Context, sub *entity.Subscription) error {
+	if h == nil {
+		return nil
+	}
+	for _, fn := range h.beforeSave {
+		if err := fn(ctx, sub); err != nil {
+			return fmt.Errorf("before save hook: %w", err)
+		}
+	}
+	return nil
+}
+
+func (h *Hooks) runAfter(ctx context.Context, typ SubscriptionEventType, sub *entity.Subscription) {
+	if h == nil {
+		return
+	}
+	if typ == EventSubscriptionDeleted {
+		for _, fn := range h.afterDelete {
+			fn(ctx, sub)
+		}
+		return
+	}
+	for _, fn := range h.afterSave {
+		fn(ctx, sub)
+	}
+}
@@ -0,0 +1,180 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/pkg/hashid"
+)
+
+// IDStrategy — how subscriptions are identified to clients. Every subscription keeps its serial ID for
+// storage and gets a public ID on creation whatever the strategy, so switching needs no backfill of new rows
+type IDStrategy string
+
+const (
+	// IDSerial - clients see the serial IDs of the database; public IDs are stored but not shown
+	IDSerial IDStrategy = "serial"
+	// IDUUIDv7 - clients see public IDs written as UUIDs, version 7 for new subscriptions
+	IDUUIDv7 IDStrategy = "uuidv7"
+	// IDULID - clients see public IDs written as ULIDs
+	IDULID IDStrategy = "ulid"
+	// IDHashid - clients see the serial IDs obfuscated with Hashids; nothing is stored for them
+	IDHashid IDStrategy = "hashid"
+)
+
+// DefaultHashidMinLength - shortest hashid shown when WithHashids does not set it
+const DefaultHashidMinLength = 8
+
+// Public reports whether clients see public IDs instead of serial ones
+func (st IDStrategy) Public() bool {
+	return st == IDUUIDv7 || st == IDULID || st == IDHashid
+}
+
+// Format writes id as the strategy shows it, or "" with IDSerial and IDHashid, which do not show stored public
+// IDs
+func (st IDStrategy) Format(id entity.PublicID) string {
+	switch st {
+	case IDUUIDv7:
+		return id.String()
+	case IDULID:
+		return id.ULID()
+	default:
+		return ""
+	}
+}
+
+// IDs — the strategy identifying subscriptions to clients together with what it needs to write the IDs; the
+// zero value is IDSerial
+type IDs struct {
+	Strategy IDStrategy
+	hashids  *hashid.Codec
+}
+
+// Public reports whether clients see public IDs instead of serial ones
+func (x IDs) Public() bool {
+	return x.Strategy.Public()
+}
+
+// Ref returns the public ID of the subscription with serial ID id and stored public ID pid as clients see it,
+// "" with IDSerial
+func (x IDs) Ref(id int64, pid entity.PublicID) string {
+	if x.Strategy == IDHashid {
+		return x.hashids.Encode(id)
+	}
+	return x.Strategy.Format(pid)
+}
+
+// WithIDStrategy returns an option that sets how subscriptions are identified to clients; an empty strategy
+// keeps IDSerial
+func WithIDStrategy(st IDStrategy) func(*Subscription) {
+	return func(s *Subscription) {
+		if st != "" {
+			s.ids.Strategy = st
+		}
+	}
+}
+
+// WithHashids returns an option that sets the salt and the minimum length of the hashids of IDHashid; without
+// it the salt is empty, so anyone can decode them. minLength <= 0 keeps DefaultHashidMinLength
+func WithHashids(salt string, minLength int) func(*Subscription) {
+	return func(s *Subscription) {
+		if minLength <= 0 {
+			minLength = DefaultHashidMinLength
+		}
+		s.ids.hashids = hashid.New(salt, minLength)
+	}
+}
+
+// IDStrategy returns how subscriptions are identified to clients
+func (s *Subscription) IDStrategy() IDStrategy {
+	return s.ids.Strategy
+}
+
+// IDs returns how subscriptions are identified to clients, for writing the IDs of subscriptions in responses
+func (s *Subscription) IDs() IDs {
+	return s.ids
+}
+
+// PublicID returns the identifier clients know sub by under a public strategy, "" with IDSerial
+func (s *Subscription) PublicID(sub *entity.Subscription) string {
+	if sub == nil {
+		return ""
+	}
+	return s.ids.Ref(sub.ID, sub.PublicID)
+}
+
+// ResolveID turns the identifier a client sent into the serial ID of the subscription. IDSerial takes the
+// number and IDHashid its hashid, decoded without a lookup; the other public strategies take a UUID or a ULID.
+// Public strategies refuse serial numbers, which would make the subscriptions enumerable again. Unknown public
+// IDs are ErrSubscriptionNotFound
+func (s *Subscription) ResolveID(ctx context.Context, raw string) (int64, error) {
+	raw = strings.TrimSpace(raw)
+	switch s.ids.Strategy {
+	case IDUUIDv7, IDULID:
+	case IDHashid:
+		id, err := s.ids.hashids.Decode(raw)
+		if err != nil || id <= 0 {
+			return 0, ErrInvalidID
+		}
+		return id, nil
+	default:
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id <= 0 {
+			return 0, ErrInvalidID
+		}
+		return id, nil
+	}
+	pid, err := entity.ParsePublicID(raw)
+	if err != nil {
+		return 0, ErrInvalidID
+	}
+	sub, err := s.Sr.GetSubByPublicID(ctx, pid)
+	if errors.Is(err, ErrSubscriptionNotFound) {
+		return 0, err
+	}
+	if err != nil {
+		return 0, fmt.Errorf("resolve id: %w", err)
+	}
+	if sub == nil {
+		return 0, ErrSubscriptionNotFound
+	}
+	return sub.ID, nil
+}
+
+// assignPublicID gives sub a new public ID unless it has one, e.g. restored from a snapshot
+func (s *Subscription) assignPublicID(sub *entity.Subscription) error {
+	if !sub.PublicID.IsZero() {
+		return nil
+	}
+	id, err := newPublicID(s.ids.Strategy, s.clock.Now())
+	if err != nil {
+		return fmt.Errorf("public id: %w", err)
+	}
+	sub.PublicID = id
+	return nil
+}
+
+// newPublicID makes a time-ordered ID: 48 bits of Unix milliseconds followed by random bits, with the
+// version and variant bits of a UUIDv7 unless the strategy writes ULIDs. IDSerial makes UUIDv7s, so the
+// stored IDs are ready when the strategy is switched
+func newPublicID(st IDStrategy, now time.Time) (entity.PublicID, error) {
+	var id entity.PublicID
+	ms := uint64(now.UnixMilli())
+	binary.BigEndian.PutUint16(id[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(id[2:6], uint32(ms))
+	if _, err := rand.Read(id[6:]); err != nil {
+		return entity.PublicID{}, err
+	}
+	if st != IDULID {
+		id[6] = id[6]&0x0f | 0x70
+		id[8] = id[8]&0x3f | 0x80
+	}
+	return id, nil
+}
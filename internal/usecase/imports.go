@@ -0,0 +1,192 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+)
+
+const (
+	// DefaultImportMaxRows - rows a single import confirmation may create
+	DefaultImportMaxRows = 1000
+	// DefaultImportChunkSize - rows saved in one transaction
+	DefaultImportChunkSize = 100
+)
+
+// ErrImportTooLarge - an import confirmation with more rows than ImportLimits.MaxRows
+var ErrImportTooLarge = errcode.New(errcode.ImportTooLarge, "too many rows to import")
+
+// ImportLimits — how much a single import confirmation writes and how
+type ImportLimits struct {
+	// MaxRows - rows a confirmation may carry, larger ones are rejected as a whole
+	MaxRows int
+	// ChunkSize - rows saved in one transaction; a failed chunk is rolled back, the chunks before it stay
+	ChunkSize int
+}
+
+// ImportRow — a confirmed import proposal
+type ImportRow struct {
+	// Key - unique key of the proposal: a row confirmed again under the same key gets the subscription created
+	// the first time instead of another one, and is held in the quarantine once. Empty keys are not checked
+	Key string
+	Sub *entity.Subscription
+}
+
+// RejectedSub — an imported subscription failing validation and the reason
+type RejectedSub struct {
+	// Key - the key of the row, see ImportRow
+	Key string
+	Sub *entity.Subscription
+	Err error
+}
+
+// ImportChunkError — a chunk of an import that failed to save; the chunks before it stay saved
+type ImportChunkError struct {
+	// From, To - 1-based positions of the first and the last row of the chunk among the confirmed rows
+	From, To int
+	Err      error
+}
+
+func (e *ImportChunkError) Error() string {
+	return fmt.Sprintf("import rows %d-%d: %v", e.From, e.To, e.Err)
+}
+
+func (e *ImportChunkError) Unwrap() error { return e.Err }
+
+// Quarantine — keeps the imported subscriptions failing validation for the user to fix and submit again
+type Quarantine interface {
+	// Hold - keep the rows, once per non-empty key
+	Hold(ctx context.Context, rows []RejectedSub) error
+}
+
+// ImportResult — what became of the rows of an import
+type ImportResult struct {
+	Created []*entity.Subscription
+	// Quarantined - rows failing validation that were held instead of failing the import
+	Quarantined int
+}
+
+// WithImportLimits returns an option that sets the import limits; non-positive fields keep defaults
+func WithImportLimits(l ImportLimits) func(*Subscription) {
+	return func(s *Subscription) {
+		if l.MaxRows > 0 {
+			s.imports.MaxRows = l.MaxRows
+		}
+		if l.ChunkSize > 0 {
+			s.imports.ChunkSize = l.ChunkSize
+		}
+	}
+}
+
+// WithQuarantine returns an option that holds the imported rows failing validation instead of failing the import
+func WithQuarantine(q Quarantine) func(*Subscription) {
+	return func(s *Subscription) {
+		if q != nil {
+			s.quarantine = q
+		}
+	}
+}
+
+// ImportSubs saves imported subscriptions ImportLimits.ChunkSize at a time, each chunk in its own transaction,
+// and ErrImportTooLarge when there are more than ImportLimits.MaxRows. Rows failing validation go to the
+// quarantine once the valid ones are saved, when one is set; without it the first of them fails the import
+// before anything is saved. When a chunk fails, the chunks before it stay saved and are returned with an
+// *ImportChunkError; confirming the same rows again creates only the rest, see ImportRow.Key
+func (s *Subscription) ImportSubs(ctx context.Context, rows []ImportRow) (ImportResult, error) {
+	if len(rows) == 0 {
+		return ImportResult{}, fmt.Errorf("%w: nothing to register", ErrInvalidSubscription)
+	}
+	if len(rows) > s.imports.MaxRows {
+		return ImportResult{}, fmt.Errorf("%w: %d rows, at most %d", ErrImportTooLarge, len(rows), s.imports.MaxRows)
+	}
+
+	// positions - 1-based positions of the valid rows among rows, for the range of a failed chunk
+	valid := make([]ImportRow, 0, len(rows))
+	positions := make([]int, 0, len(rows))
+	var rejected []RejectedSub
+	for i, row := range rows {
+		err := s.prepare(ctx, row.Sub)
+		if err == nil {
+			err = s.assignPublicID(row.Sub)
+		}
+		switch {
+		case err == nil:
+			valid = append(valid, row)
+			positions = append(positions, i+1)
+		case s.quarantine != nil && invalidImportRow(err):
+			rejected = append(rejected, RejectedSub{Key: row.Key, Sub: row.Sub, Err: err})
+		default:
+			return ImportResult{}, err
+		}
+	}
+
+	out := ImportResult{Created: make([]*entity.Subscription, 0, len(valid))}
+	var saveErr error
+	for start := 0; start < len(valid); start += s.imports.ChunkSize {
+		end := min(start+s.imports.ChunkSize, len(valid))
+		created, fresh, err := s.importChunk(ctx, valid[start:end])
+		if err != nil {
+			saveErr = &ImportChunkError{From: positions[start], To: positions[end-1], Err: err}
+			break
+		}
+		for _, sub := range fresh {
+			if s.metrics != nil {
+				s.metrics.SubCreated()
+			}
+			s.publish(ctx, EventSubscriptionCreated, sub, nil)
+		}
+		out.Created = append(out.Created, created...)
+	}
+	// held only now, so a failed save leaves no row both held and saved; the keys keep a repeated
+	// confirmation from holding a row twice
+	if len(rejected) > 0 {
+		if err := s.quarantine.Hold(ctx, rejected); err != nil {
+			return out, errors.Join(saveErr, fmt.Errorf("quarantine import rows: %w", err))
+		}
+		out.Quarantined = len(rejected)
+	}
+	return out, saveErr
+}
+
+// importChunk saves the rows in one transaction; rows confirmed before under their key are not saved again.
+// It returns the subscriptions of all rows and those created now
+func (s *Subscription) importChunk(ctx context.Context, rows []ImportRow) ([]*entity.Subscription, []*entity.Subscription, error) {
+	all := make([]*entity.Subscription, 0, len(rows))
+	var fresh []*entity.Subscription
+	err := s.Sr.InTx(ctx, func(ctx context.Context) error {
+		for _, row := range rows {
+			if row.Key == "" {
+				saved, err := s.Sr.SaveSub(ctx, row.Sub)
+				if err != nil {
+					return err
+				}
+				all, fresh = append(all, saved), append(fresh, saved)
+				continue
+			}
+			saved, created, err := s.Sr.SaveImportedSub(ctx, row.Key, row.Sub)
+			if err != nil {
+				return err
+			}
+			all = append(all, saved)
+			if created {
+				fresh = append(fresh, saved)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return all, fresh, nil
+}
+
+// invalidImportRow reports whether err rejects the row itself, so the row can be fixed and submitted again
+func invalidImportRow(err error) bool {
+	return errors.Is(err, ErrInvalidSubscription) ||
+		errors.Is(err, ErrInvalidPeriod) ||
+		errors.Is(err, ErrDateOutOfRange) ||
+		errors.Is(err, entity.ErrInvalidUserID)
+}
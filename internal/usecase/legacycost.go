@@ -0,0 +1,64 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	// DefaultLegacyCostBatch - subscriptions backfilled per MigrateLegacyCosts call when the batch is not set
+	DefaultLegacyCostBatch = 1000
+	// MaxLegacyCostBatch - the most subscriptions backfilled per call, so a batch stays a short transaction
+	MaxLegacyCostBatch = 10000
+)
+
+// LegacyCostReport — outcome of a MigrateLegacyCosts call
+type LegacyCostReport struct {
+	// Pending - subscriptions holding only the legacy cost before the call
+	Pending int64
+	// Migrated - subscriptions backfilled by the call, always zero on a dry run
+	Migrated int64
+	// Remaining - subscriptions left for later calls
+	Remaining int64
+	DryRun    bool
+}
+
+// Done reports whether every subscription has its currency and minor-unit cost
+func (r LegacyCostReport) Done() bool { return r.Remaining == 0 }
+
+// WithLegacyCosts returns an option that sets the stores MigrateLegacyCosts backfills, e.g. every shard;
+// without any there is nothing to migrate
+func WithLegacyCosts(stores ...LegacyCostStore) func(*Subscription) {
+	return func(s *Subscription) {
+		s.legacyCosts = append(s.legacyCosts, stores...)
+	}
+}
+
+// MigrateLegacyCosts backfills the currency (RUB) and minor-unit cost of up to batch subscriptions that only have
+// the legacy whole-ruble cost. Rows are taken in ID order and a backfilled row is never picked again, so the
+// migration is resumed by calling it until the report is Done; a dry run only counts what is left.
+// New and updated subscriptions get both columns on write, so the count never grows
+func (s *Subscription) MigrateLegacyCosts(ctx context.Context, batch int, dryRun bool) (LegacyCostReport, error) {
+	if batch <= 0 {
+		batch = DefaultLegacyCostBatch
+	}
+	batch = min(batch, MaxLegacyCostBatch)
+	report := LegacyCostReport{DryRun: dryRun}
+	for _, store := range s.legacyCosts {
+		pending, err := store.PendingLegacyCosts(ctx)
+		if err != nil {
+			return LegacyCostReport{}, fmt.Errorf("legacy costs: %w", err)
+		}
+		report.Pending += pending
+		if dryRun || pending == 0 || report.Migrated >= int64(batch) {
+			continue
+		}
+		n, err := store.BackfillLegacyCosts(ctx, batch-int(report.Migrated))
+		if err != nil {
+			return LegacyCostReport{}, fmt.Errorf("legacy costs: %w", err)
+		}
+		report.Migrated += n
+	}
+	report.Remaining = max(report.Pending-report.Migrated, 0)
+	return report, nil
+}
@@ -0,0 +1,50 @@
+package usecase
+
+import (
+	"context"
+
+	"subs_tracker/internal/entity"
+)
+
+// PreviewRegisterSub runs the checks of RegisterSub, before save hooks included, and returns the subscription
+// as it would be stored, without storing it or publishing an event. The preview has no ID yet and is stamped
+// with the current time
+func (s *Subscription) PreviewRegisterSub(ctx context.Context, sub *entity.Subscription) (*entity.Subscription, error) {
+	if err := s.prepare(ctx, sub); err != nil {
+		return nil, err
+	}
+	preview := *sub
+	preview.ID, preview.PublicID = 0, entity.PublicID{}
+	preview.CreatedAt = s.clock.Now().UTC()
+	preview.UpdatedAt = preview.CreatedAt
+	return &preview, nil
+}
+
+// PreviewUpdateSub runs the checks of UpdateSub, before save hooks and the version of a conditional update
+// included, and returns the subscription as it would be stored, without writing it. An update that changes
+// nothing previews the stored subscription, as UpdateSub returns it
+func (s *Subscription) PreviewUpdateSub(ctx context.Context, sub *entity.Subscription) (*entity.Subscription, error) {
+	if sub == nil || sub.ID <= 0 {
+		return nil, ErrInvalidID
+	}
+	if err := s.prepare(ctx, sub); err != nil {
+		return nil, err
+	}
+	existing, err := s.Sr.GetSubByID(ctx, sub.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	if !sub.UpdatedAt.IsZero() && !existing.UpdatedAt.Equal(sub.UpdatedAt) {
+		return nil, ErrPreconditionFailed
+	}
+	if existing.SameContent(sub) {
+		return existing, nil
+	}
+	preview := *sub
+	preview.PublicID, preview.CreatedAt = existing.PublicID, existing.CreatedAt
+	preview.UpdatedAt = s.clock.Now().UTC()
+	return &preview, nil
+}
@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"subs_tracker/internal/entity"
+)
+
+// MaxSeats - most seats a shared plan may have
+const MaxSeats = 20
+
+// SetSeats validates and stores how an existing subscription is split into seats: the owner pays for the plan
+// and holds one seat, the members hold the others and see their share in their cost summary. A Total of 0
+// without members stops sharing the subscription
+func (s *Subscription) SetSeats(ctx context.Context, seats *entity.Seats) (*SharedPlan, error) {
+	if seats == nil || seats.SubscriptionID <= 0 {
+		return nil, ErrInvalidID
+	}
+	switch {
+	case seats.Total < 0 || seats.Total == 1:
+		return nil, fmt.Errorf("%w: a shared plan needs at least 2 seats", ErrInvalidSeats)
+	case seats.Total > MaxSeats:
+		return nil, fmt.Errorf("%w: too many seats", ErrInvalidSeats)
+	case len(seats.UserIDs) > max(seats.Total-1, 0):
+		return nil, fmt.Errorf("%w: more members than seats", ErrInvalidSeats)
+	}
+	for i, id := range seats.UserIDs {
+		if id.IsZero() {
+			return nil, fmt.Errorf("%w: empty member user_id", ErrInvalidSeats)
+		}
+		if slices.Contains(seats.UserIDs[:i], id) {
+			return nil, fmt.Errorf("%w: duplicate member", ErrInvalidSeats)
+		}
+	}
+
+	sub, err := s.GetSubByID(ctx, seats.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	if slices.Contains(seats.UserIDs, sub.UserID) {
+		return nil, fmt.Errorf("%w: the owner holds a seat already", ErrInvalidSeats)
+	}
+	prev, err := s.Sr.GetSeats(ctx, seats.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	saved, err := s.Sr.SaveSeats(ctx, seats)
+	if err != nil {
+		return nil, err
+	}
+	// the owner and the members, the ones leaving too, see another share in their totals from now on
+	users := append([]entity.UserID{sub.UserID}, seats.UserIDs...)
+	if prev != nil {
+		users = append(users, prev.UserIDs...)
+	}
+	s.costNow.drop(ctx, users...)
+	return &SharedPlan{Subscription: sub, Seats: *saved}, nil
+}
+
+// GetSeats returns an existing subscription with its seats, no seats when it is not shared
+func (s *Subscription) GetSeats(ctx context.Context, subID int64) (*SharedPlan, error) {
+	sub, err := s.GetSubByID(ctx, subID)
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	seats, err := s.Sr.GetSeats(ctx, subID)
+	if err != nil {
+		return nil, err
+	}
+	plan := &SharedPlan{Subscription: sub, Seats: entity.Seats{SubscriptionID: subID}}
+	if seats != nil {
+		plan.Seats = *seats
+	}
+	return plan, nil
+}
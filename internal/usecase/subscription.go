@@ -2,65 +2,592 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"strings"
 	"time"
 
 	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/importer"
+	"subs_tracker/pkg/clock"
+	"subs_tracker/pkg/dates"
+	"subs_tracker/pkg/hashid"
+	"subs_tracker/pkg/pagination"
 )
 
 // Subscription coordinates subscription use cases via the repository
 type Subscription struct {
-	Sr SubscriptionRepository
+	Sr                SubscriptionRepository
+	metrics           SubscriptionMetrics
+	events            SubscriptionEvents
+	archive           ArchiveReader
+	catalog           []string
+	rules             ValidationRules
+	anomaly           AnomalyRules
+	benchmarkMinUsers int
+	clock             clock.Clock
+	hooks             *Hooks
+	analytics         AnalyticsReader
+	costNow           *costNowCache
+	costNowPrime      int
+	legacyCosts       []LegacyCostStore
+	userDeletion      UserDeletionPolicy
+	ids               IDs
+	imports           ImportLimits
+	quarantine        Quarantine
 }
 
-// NewSubscription creates a use case service with the given repository
-func NewSubscription(sr SubscriptionRepository) *Subscription {
-	return &Subscription{
-		Sr: sr,
+// NewSubscription creates a use case service with the given repository and applies options
+func NewSubscription(sr SubscriptionRepository, options ...func(*Subscription)) *Subscription {
+	s := &Subscription{
+		Sr:                sr,
+		catalog:           importer.DefaultCatalog,
+		anomaly:           AnomalyRules{BaselineMonths: 3, ThresholdPercent: 50},
+		benchmarkMinUsers: 5,
+		clock:             clock.System,
+		analytics:         sr,
+		costNow:           newCostNowCache(DefaultCostNowTTL),
+		userDeletion:      DeleteUserBlock,
+		ids:               IDs{Strategy: IDSerial},
+		imports:           ImportLimits{MaxRows: DefaultImportMaxRows, ChunkSize: DefaultImportChunkSize},
+	}
+	for _, o := range options {
+		o(s)
+	}
+	if s.ids.hashids == nil {
+		s.ids.hashids = hashid.New("", DefaultHashidMinLength)
+	}
+	return s
+}
+
+// WithMetrics returns an option that sets the domain metrics sink.
+func WithMetrics(m SubscriptionMetrics) func(*Subscription) {
+	return func(s *Subscription) {
+		if m != nil {
+			s.metrics = m
+		}
+	}
+}
+
+// WithEvents returns an option that sets the sink notified about every subscription write
+func WithEvents(e SubscriptionEvents) func(*Subscription) {
+	return func(s *Subscription) {
+		if e != nil {
+			s.events = e
+		}
+	}
+}
+
+// WithClock returns an option that sets the source of "now": the current month, date bounds and event times
+func WithClock(c clock.Clock) func(*Subscription) {
+	return func(s *Subscription) {
+		if c != nil {
+			s.clock = c
+		}
+	}
+}
+
+// Now returns the current time of the use case clock, for callers that default to "this month"
+func (s *Subscription) Now() time.Time {
+	return s.clock.Now()
+}
+
+// WithAnalytics returns an option that serves per-user spend totals and anomaly detection from a read model
+// instead of the repository
+func WithAnalytics(a AnalyticsReader) func(*Subscription) {
+	return func(s *Subscription) {
+		if a != nil {
+			s.analytics = a
+		}
+	}
+}
+
+// WithArchive returns an option that lets lists include archived subscriptions on request
+func WithArchive(a ArchiveReader) func(*Subscription) {
+	return func(s *Subscription) {
+		if a != nil {
+			s.archive = a
+		}
+	}
+}
+
+// WithServiceCatalog returns an option that replaces the services recognised in imported statements
+func WithServiceCatalog(names []string) func(*Subscription) {
+	return func(s *Subscription) {
+		if len(names) > 0 {
+			s.catalog = names
+		}
+	}
+}
+
+// WithValidationRules returns an option that sets the configurable validation limits
+func WithValidationRules(r ValidationRules) func(*Subscription) {
+	return func(s *Subscription) {
+		s.rules = r
+	}
+}
+
+// WithAnomalyRules returns an option that sets how spending anomalies are detected; non-positive fields keep defaults
+func WithAnomalyRules(r AnomalyRules) func(*Subscription) {
+	return func(s *Subscription) {
+		if r.BaselineMonths > 0 {
+			s.anomaly.BaselineMonths = r.BaselineMonths
+		}
+		if r.ThresholdPercent > 0 {
+			s.anomaly.ThresholdPercent = r.ThresholdPercent
+		}
+	}
+}
+
+// WithBenchmarkMinUsers returns an option that sets how many users a service needs before its
+// price benchmark is published, keeping single users' costs from being identifiable
+func WithBenchmarkMinUsers(n int) func(*Subscription) {
+	return func(s *Subscription) {
+		if n > 0 {
+			s.benchmarkMinUsers = n
+		}
 	}
 }
 
 // RegisterSub validates/normalizes and saves a new subscription
 func (s *Subscription) RegisterSub(ctx context.Context, sub *entity.Subscription) (*entity.Subscription, error) {
-	if err := s.validateAndNormalize(sub); err != nil {
+	if err := s.prepare(ctx, sub); err != nil {
+		return nil, err
+	}
+	if err := s.assignPublicID(sub); err != nil {
 		return nil, err
 	}
 	created, err := s.Sr.SaveSub(ctx, sub)
 	if err != nil {
 		return nil, err
 	}
+	if s.metrics != nil {
+		s.metrics.SubCreated()
+	}
+	s.publish(ctx, EventSubscriptionCreated, created, nil)
 	return created, nil
 }
 
-// UpdateSub validates/normalizes and updates an existing subscription by ID, returning the fresh copy
+// RegisterSubs validates every subscription first and saves them only when all are valid
+func (s *Subscription) RegisterSubs(ctx context.Context, subs []*entity.Subscription) ([]*entity.Subscription, error) {
+	if len(subs) == 0 {
+		return nil, fmt.Errorf("%w: nothing to register", ErrInvalidSubscription)
+	}
+	for _, sub := range subs {
+		if err := s.prepare(ctx, sub); err != nil {
+			return nil, err
+		}
+		if err := s.assignPublicID(sub); err != nil {
+			return nil, err
+		}
+	}
+	out := make([]*entity.Subscription, 0, len(subs))
+	for _, sub := range subs {
+		created, err := s.Sr.SaveSub(ctx, sub)
+		if err != nil {
+			return out, err
+		}
+		if s.metrics != nil {
+			s.metrics.SubCreated()
+		}
+		out = append(out, created)
+	}
+	for _, created := range out {
+		s.publish(ctx, EventSubscriptionCreated, created, nil)
+	}
+	return out, nil
+}
+
+// ImportProposals - proposals of an import split by whether the user already tracks the service
+type ImportProposals struct {
+	// New - services the user does not track, to be confirmed
+	New []importer.Proposal
+	// Tracked - proposals covered by one of the user's subscriptions
+	Tracked []TrackedProposal
+}
+
+// All - the new proposals followed by the tracked ones
+func (p ImportProposals) All() []importer.Proposal {
+	out := make([]importer.Proposal, 0, len(p.New)+len(p.Tracked))
+	out = append(out, p.New...)
+	for _, t := range p.Tracked {
+		out = append(out, t.Proposal)
+	}
+	return out
+}
+
+// TrackedProposal - a proposal and the subscription already covering it
+type TrackedProposal struct {
+	importer.Proposal
+	Subscription *entity.Subscription
+}
+
+// ProposeImport matches statement charges against the service catalog and the user's own services.
+// Services the user still tracks at the time of the latest charge are set apart as Tracked
+func (s *Subscription) ProposeImport(ctx context.Context, userID entity.UserID, txs []importer.Transaction) (ImportProposals, error) {
+	if userID.IsZero() {
+		return ImportProposals{}, entity.ErrInvalidUserID
+	}
+	existing, err := s.allSubs(ctx, SubFilter{UserID: userID, IncludeDeactivated: true})
+	if err != nil {
+		return ImportProposals{}, err
+	}
+
+	names := append([]string{}, s.catalog...)
+	for _, sub := range existing {
+		names = append(names, sub.ServiceName)
+	}
+	return splitTracked(existing, importer.Propose(txs, importer.NewMatcher(names))), nil
+}
+
+// ProposeStoreImport sets apart app store subscriptions the user already tracks at the time of their latest charge
+func (s *Subscription) ProposeStoreImport(ctx context.Context, userID entity.UserID, proposals []importer.Proposal) (ImportProposals, error) {
+	if userID.IsZero() {
+		return ImportProposals{}, entity.ErrInvalidUserID
+	}
+	existing, err := s.allSubs(ctx, SubFilter{UserID: userID, IncludeDeactivated: true})
+	if err != nil {
+		return ImportProposals{}, err
+	}
+	return splitTracked(existing, proposals), nil
+}
+
+// splitTracked sets apart the proposals covered by one of the existing subscriptions
+func splitTracked(existing []*entity.Subscription, proposals []importer.Proposal) ImportProposals {
+	var out ImportProposals
+	for _, p := range proposals {
+		if sub := trackingAt(existing, p.ServiceName, dates.MonthStart(p.LastCharge)); sub != nil {
+			out.Tracked = append(out.Tracked, TrackedProposal{Proposal: p, Subscription: sub})
+			continue
+		}
+		out.New = append(out.New, p)
+	}
+	return out
+}
+
+// ApplyReceipt records a receipt charge: the subscription covering the charge month gets the charged price,
+// or a new one is started in that month when there is none
+func (s *Subscription) ApplyReceipt(ctx context.Context, userID entity.UserID, r importer.Receipt) (*entity.Subscription, ReceiptAction, error) {
+	if userID.IsZero() {
+		return nil, "", entity.ErrInvalidUserID
+	}
+	month, err := s.userMonth(ctx, userID, r.Date)
+	if err != nil {
+		return nil, "", err
+	}
+	return s.ApplyCharge(ctx, userID, r.ServiceName, r.Amount, month)
+}
+
+// ApplyCharge records a charge of amount for the service in month, like ApplyReceipt but for callers
+// that already know the billing month, e.g. external billing systems
+func (s *Subscription) ApplyCharge(ctx context.Context, userID entity.UserID, service string, amount int64, month time.Time) (*entity.Subscription, ReceiptAction, error) {
+	if userID.IsZero() {
+		return nil, "", entity.ErrInvalidUserID
+	}
+	month = dates.MonthStart(month)
+	current, err := s.coveringSub(ctx, userID, service, month)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if current == nil {
+		created, err := s.RegisterSub(ctx, &entity.Subscription{
+			UserID:      userID,
+			ServiceName: service,
+			Cost:        amount,
+			DateFrom:    month,
+		})
+		return created, ReceiptCreated, err
+	}
+	if current.Cost == amount {
+		return current, ReceiptUnchanged, nil
+	}
+	upd := *current
+	upd.Cost = amount
+	updated, err := s.UpdateSub(ctx, &upd)
+	return updated, ReceiptUpdated, err
+}
+
+// EndSubscription makes month the last paid month of the user's subscription to the service covering it,
+// ErrSubscriptionNotFound when none does
+func (s *Subscription) EndSubscription(ctx context.Context, userID entity.UserID, service string, month time.Time) (*entity.Subscription, ReceiptAction, error) {
+	if userID.IsZero() {
+		return nil, "", entity.ErrInvalidUserID
+	}
+	month = dates.MonthStart(month)
+	current, err := s.coveringSub(ctx, userID, service, month)
+	if err != nil {
+		return nil, "", err
+	}
+	if current == nil {
+		return nil, "", ErrSubscriptionNotFound
+	}
+	if current.DateTo != nil && current.DateTo.Equal(month) {
+		return current, ReceiptUnchanged, nil
+	}
+	upd := *current
+	upd.DateTo = &month
+	updated, err := s.UpdateSub(ctx, &upd)
+	return updated, ReceiptUpdated, err
+}
+
+// coveringSub finds the latest started subscription of the user to the service that is active in month
+func (s *Subscription) coveringSub(ctx context.Context, userID entity.UserID, service string, month time.Time) (*entity.Subscription, error) {
+	existing, err := s.allSubs(ctx, SubFilter{UserID: userID, IncludeDeactivated: true})
+	if err != nil {
+		return nil, err
+	}
+	var current *entity.Subscription
+	for _, sub := range existing {
+		if !strings.EqualFold(sub.ServiceName, service) || sub.DateFrom.After(month) {
+			continue
+		}
+		if sub.DateTo != nil && sub.DateTo.Before(month) {
+			continue
+		}
+		if current == nil || sub.DateFrom.After(current.DateFrom) {
+			current = sub
+		}
+	}
+	return current, nil
+}
+
+// Calendar returns the user's charges in month. Subscriptions are billed monthly and start_date is
+// month-granular, so every charge falls on the first day of the month
+func (s *Subscription) Calendar(ctx context.Context, userID entity.UserID, month time.Time) (Calendar, error) {
+	if userID.IsZero() {
+		return Calendar{}, entity.ErrInvalidUserID
+	}
+	month = dates.MonthStart(month)
+	if month.IsZero() {
+		return Calendar{}, fmt.Errorf("%w: empty month", ErrInvalidPeriod)
+	}
+	settings, err := s.GetSettings(ctx, userID)
+	if err != nil {
+		return Calendar{}, err
+	}
+	subs, err := s.allSubs(ctx, SubFilter{UserID: userID, Period: &Period{From: month, To: month}})
+	if err != nil {
+		return Calendar{}, err
+	}
+
+	cal := Calendar{Month: month, Settings: settings, Events: make([]CalendarEvent, 0, len(subs))}
+	for _, sub := range subs {
+		kind := CalendarCharge
+		switch {
+		case sub.DateFrom.Equal(month):
+			kind = CalendarFirstCharge
+		case sub.DateTo != nil && sub.DateTo.Equal(month):
+			kind = CalendarFinalCharge
+		}
+		cal.Events = append(cal.Events, CalendarEvent{Date: month, Kind: kind, Subscription: sub})
+	}
+	sort.SliceStable(cal.Events, func(i, j int) bool {
+		if !cal.Events[i].Date.Equal(cal.Events[j].Date) {
+			return cal.Events[i].Date.Before(cal.Events[j].Date)
+		}
+		return cal.Events[i].Subscription.ServiceName < cal.Events[j].Subscription.ServiceName
+	})
+	return cal, nil
+}
+
+// DiffMonths compares the user's subscriptions active in from with those active in to: which services were
+// added, removed or changed their cost. It is computed from the subscription periods, so a price change
+// recorded by ending a subscription and starting another shows up as changed
+func (s *Subscription) DiffMonths(ctx context.Context, userID entity.UserID, from, to time.Time) (MonthDiff, error) {
+	if userID.IsZero() {
+		return MonthDiff{}, entity.ErrInvalidUserID
+	}
+	from, to = dates.MonthStart(from), dates.MonthStart(to)
+	if from.IsZero() || to.IsZero() {
+		return MonthDiff{}, fmt.Errorf("%w: empty month", ErrInvalidPeriod)
+	}
+	if from.After(to) {
+		return MonthDiff{}, fmt.Errorf("%w: from must be <= to", ErrInvalidPeriod)
+	}
+
+	f := SubFilter{UserID: userID, Period: &Period{From: from, To: to}, Limit: pagination.DefaultLimits().Max}
+	type costs struct {
+		name     string
+		started  time.Time
+		from, to int64
+		inFrom   bool
+		inTo     bool
+	}
+	services := make(map[string]*costs)
+	for {
+		page, err := s.ListSubsByFilter(ctx, f)
+		if err != nil {
+			return MonthDiff{}, fmt.Errorf("diff months: %w", err)
+		}
+		for _, sub := range page {
+			key := strings.ToLower(strings.TrimSpace(sub.ServiceName))
+			c, ok := services[key]
+			if !ok {
+				c = &costs{}
+				services[key] = c
+			}
+			if c.name == "" || sub.DateFrom.After(c.started) {
+				c.name, c.started = sub.ServiceName, sub.DateFrom
+			}
+			if activeIn(sub, from) {
+				c.from += sub.Cost
+				c.inFrom = true
+			}
+			if activeIn(sub, to) {
+				c.to += sub.Cost
+				c.inTo = true
+			}
+		}
+		if len(page) < f.Limit {
+			break
+		}
+		last := page[len(page)-1]
+		f.After = CursorAt(last)
+	}
+
+	diff := MonthDiff{From: from, To: to}
+	for _, c := range services {
+		diff.FromTotal += c.from
+		diff.ToTotal += c.to
+		d := ServiceDiff{ServiceName: c.name, FromCost: c.from, ToCost: c.to}
+		switch {
+		case c.inTo && !c.inFrom:
+			diff.Added = append(diff.Added, d)
+		case c.inFrom && !c.inTo:
+			diff.Removed = append(diff.Removed, d)
+		case c.inFrom && c.inTo && c.from != c.to:
+			diff.Changed = append(diff.Changed, d)
+		}
+	}
+	for _, list := range [][]ServiceDiff{diff.Added, diff.Removed, diff.Changed} {
+		sort.Slice(list, func(i, j int) bool { return list[i].ServiceName < list[j].ServiceName })
+	}
+	return diff, nil
+}
+
+// BudgetBasis returns the user's average monthly spend per service over the BudgetMonths months before the
+// current month in the user's time zone
+func (s *Subscription) BudgetBasis(ctx context.Context, userID entity.UserID) (BudgetBasis, error) {
+	if userID.IsZero() {
+		return BudgetBasis{}, entity.ErrInvalidUserID
+	}
+	month, err := s.userMonth(ctx, userID, s.clock.Now())
+	if err != nil {
+		return BudgetBasis{}, err
+	}
+	basis := BudgetBasis{From: month.AddDate(0, -BudgetMonths, 0), To: month.AddDate(0, -1, 0)}
+	groups, err := s.CostGroupedByFilter(ctx, SubFilter{UserID: userID, Period: &Period{From: basis.From, To: basis.To}}, CostByService)
+	if err != nil {
+		return BudgetBasis{}, fmt.Errorf("budget basis: %w", err)
+	}
+	basis.Services = make([]ServiceSpend, 0, len(groups))
+	for _, g := range groups {
+		basis.Services = append(basis.Services, ServiceSpend{
+			ServiceName: g.ServiceName,
+			Average:     (g.Total + BudgetMonths - 1) / BudgetMonths,
+		})
+	}
+	sort.Slice(basis.Services, func(i, j int) bool { return basis.Services[i].ServiceName < basis.Services[j].ServiceName })
+	return basis, nil
+}
+
+// activeIn reports whether sub is paid for in month
+func activeIn(sub *entity.Subscription, month time.Time) bool {
+	return !sub.DateFrom.After(month) && (sub.DateTo == nil || !sub.DateTo.Before(month))
+}
+
+// userMonth returns the month t falls into in the user's time zone
+func (s *Subscription) userMonth(ctx context.Context, userID entity.UserID, t time.Time) (time.Time, error) {
+	settings, err := s.GetSettings(ctx, userID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("user month: %w", err)
+	}
+	return settings.MonthOf(t), nil
+}
+
+// trackingAt returns the one of subs covering the service in the month, nil if there is none
+func trackingAt(subs []*entity.Subscription, service string, month time.Time) *entity.Subscription {
+	for _, sub := range subs {
+		if !strings.EqualFold(sub.ServiceName, service) {
+			continue
+		}
+		if sub.DateTo == nil || !sub.DateTo.Before(month) {
+			return sub
+		}
+	}
+	return nil
+}
+
+// UpdateSub validates/normalizes and updates an existing subscription by ID, returning the fresh copy.
+// A non-zero sub.UpdatedAt makes the update conditional on the subscription not having changed since.
+// An update that changes nothing is not written and publishes no event: the stored subscription is returned
+// with its version unchanged
 func (s *Subscription) UpdateSub(ctx context.Context, sub *entity.Subscription) (*entity.Subscription, error) {
 	if sub == nil || sub.ID <= 0 {
 		return nil, ErrInvalidID
 	}
-	if err := s.validateAndNormalize(sub); err != nil {
+	if err := s.prepare(ctx, sub); err != nil {
 		return nil, err
 	}
-	if err := s.Sr.UpdateSub(ctx, sub); err != nil {
+	var existing *entity.Subscription
+	unchanged := false
+	err := s.Sr.InTx(ctx, func(ctx context.Context) error {
+		// the row stays locked until the update commits, so concurrent writers of it take turns
+		var err error
+		if existing, err = s.Sr.GetSubByIDForUpdate(ctx, sub.ID); err != nil {
+			return err
+		}
+		// clients that sync by sending every subscription back should not cause a write, an event or a new version
+		if existing != nil && existing.SameContent(sub) {
+			if !sub.UpdatedAt.IsZero() && !existing.UpdatedAt.Equal(sub.UpdatedAt) {
+				return ErrPreconditionFailed
+			}
+			unchanged = true
+			return nil
+		}
+		return s.Sr.UpdateSub(ctx, sub)
+	})
+	if err != nil {
 		return nil, err
 	}
-
-	return s.Sr.GetSubByID(ctx, sub.ID)
+	if unchanged {
+		return existing, nil
+	}
+	updated, err := s.Sr.GetSubByID(ctx, sub.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, EventSubscriptionUpdated, updated, existing)
+	return updated, nil
 }
 
-// DeleteSub removes a subscription by ID and returns the previously stored record
-func (s *Subscription) DeleteSub(ctx context.Context, ID int64) (*entity.Subscription, error) {
+// DeleteSub removes a subscription by ID and returns the previously stored record.
+// A non-zero version makes the delete conditional on the subscription not having changed since
+func (s *Subscription) DeleteSub(ctx context.Context, ID int64, version time.Time) (*entity.Subscription, error) {
 	if ID <= 0 {
 		return nil, ErrInvalidID
 	}
 
-	existing, err := s.Sr.GetSubByID(ctx, ID)
+	var existing *entity.Subscription
+	err := s.Sr.InTx(ctx, func(ctx context.Context) error {
+		// locked, so the record returned is the one deleted
+		var err error
+		if existing, err = s.Sr.GetSubByIDForUpdate(ctx, ID); err != nil {
+			return err
+		}
+		if existing != nil && !version.IsZero() && !existing.UpdatedAt.Equal(version) {
+			return ErrPreconditionFailed
+		}
+		return s.Sr.DeleteSub(ctx, ID, version)
+	})
 	if err != nil {
 		return nil, err
 	}
-	if err := s.Sr.DeleteSub(ctx, ID); err != nil {
-		return nil, err
-	}
+	s.publish(ctx, EventSubscriptionDeleted, existing, nil)
 	return existing, nil
 }
 
@@ -78,7 +605,78 @@ func (s *Subscription) ListSubsByFilter(ctx context.Context, filter SubFilter) (
 	if err != nil {
 		return nil, err
 	}
-	return s.Sr.ListSubsByFilter(ctx, nf)
+	if !nf.IncludeArchived || s.archive == nil {
+		return s.Sr.ListSubsByFilter(ctx, nf)
+	}
+	return s.listWithArchive(ctx, nf)
+}
+
+// allSubs reads every live subscription matching f from the repository, in keyset pages of the largest size
+func (s *Subscription) allSubs(ctx context.Context, f SubFilter) ([]*entity.Subscription, error) {
+	f.Limit, f.Offset = pagination.DefaultLimits().Max, 0
+	var out []*entity.Subscription
+	for {
+		page, err := s.Sr.ListSubsByFilter(ctx, f)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, page...)
+		if len(page) < f.Limit {
+			return out, nil
+		}
+		last := page[len(page)-1]
+		f.After = CursorAt(last)
+	}
+}
+
+// ArchiveEnabled reports whether lists can include archived subscriptions
+func (s *Subscription) ArchiveEnabled() bool {
+	return s.archive != nil
+}
+
+// listWithArchive merges archived subscriptions into the live list, both in (start_date, service_name, id)
+// order, and applies the page over the merged sequence
+func (s *Subscription) listWithArchive(ctx context.Context, f SubFilter) ([]*entity.Subscription, error) {
+	archived, err := s.archive.Archived(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("list archived: %w", err)
+	}
+	if !f.IncludeDeactivated {
+		if archived, err = s.withoutDeactivated(ctx, archived); err != nil {
+			return nil, fmt.Errorf("list archived: %w", err)
+		}
+	}
+	want := f.Offset + f.Limit
+
+	// live rows are read in keyset pages until the merged page is certainly complete
+	var live []*entity.Subscription
+	page := f
+	page.Offset = 0
+	for len(live) < want {
+		rows, err := s.Sr.ListSubsByFilter(ctx, page)
+		if err != nil {
+			return nil, err
+		}
+		live = append(live, rows...)
+		if len(rows) < page.Limit {
+			break
+		}
+		last := rows[len(rows)-1]
+		page.After = CursorAt(last)
+	}
+
+	merged := make([]*entity.Subscription, 0, len(live)+len(archived))
+	for len(live) > 0 || len(archived) > 0 {
+		if len(archived) == 0 || len(live) > 0 && CompareListOrder(live[0], archived[0]) < 0 {
+			merged, live = append(merged, live[0]), live[1:]
+		} else {
+			merged, archived = append(merged, archived[0]), archived[1:]
+		}
+	}
+	if f.Offset >= len(merged) {
+		return []*entity.Subscription{}, nil
+	}
+	return merged[f.Offset:min(want, len(merged))], nil
 }
 
 // CostSubsByFilter normalizes the filter and returns the total cost for matching subscriptions
@@ -90,49 +688,481 @@ func (s *Subscription) CostSubsByFilter(ctx context.Context, filter SubFilter) (
 	return s.Sr.CostSubsByFilter(ctx, nf)
 }
 
-// monthStart truncates a time to the first day of its month in UTC
-func monthStart(t time.Time) time.Time {
-	if t.IsZero() {
-		return t
+// CostGroupedByFilter normalizes the filter and returns the total cost of matching subscriptions per value of by
+func (s *Subscription) CostGroupedByFilter(ctx context.Context, filter SubFilter, by CostGroupBy) ([]CostGroup, error) {
+	if !by.Valid() {
+		return nil, fmt.Errorf("%w: %q, want service, user, month, week or year", ErrInvalidGroupBy, by)
+	}
+	nf, err := normalizeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	return s.Sr.CostGroupedByFilter(ctx, nf, by)
+}
+
+// CostSummaryByFilter normalizes the filter and returns the cost aggregates of matching subscriptions; with a
+// user it adds the seats the user holds in plans shared by others
+func (s *Subscription) CostSummaryByFilter(ctx context.Context, filter SubFilter) (CostSummary, error) {
+	nf, err := normalizeFilter(filter)
+	if err != nil {
+		return CostSummary{}, err
+	}
+	sum, err := s.Sr.CostSummaryByFilter(ctx, nf)
+	if err != nil || nf.UserID.IsZero() {
+		return sum, err
+	}
+	if sum.Seats, err = s.Sr.SeatSharesByFilter(ctx, nf); err != nil {
+		return CostSummary{}, err
+	}
+	return sum, nil
+}
+
+// DataVersion returns the version of the data behind the cost reports of the user, of all users when user is
+// zero; it changes with every write to them, so it validates cached reports
+func (s *Subscription) DataVersion(ctx context.Context, user entity.UserID) (DataVersion, error) {
+	return s.Sr.DataVersion(ctx, user)
+}
+
+// ReassignUser moves all subscriptions of from to the user to, e.g. to merge duplicate accounts, and reports how many moved
+func (s *Subscription) ReassignUser(ctx context.Context, from, to entity.UserID, actor string) (int64, error) {
+	if from.IsZero() || to.IsZero() {
+		return 0, entity.ErrInvalidUserID
+	}
+	if from == to {
+		return 0, fmt.Errorf("%w: source and target user are the same", entity.ErrInvalidUserID)
+	}
+	defer s.costNow.drop(ctx, from, to)
+	return s.Sr.ReassignUser(ctx, from, to, actor)
+}
+
+// MergeSubs folds the subscription mergeID into keepID. Both must belong to the same user and service.
+// The kept record spans both periods and takes the price of the most recently started one
+func (s *Subscription) MergeSubs(ctx context.Context, keepID, mergeID int64, actor string) (*entity.Subscription, error) {
+	if keepID <= 0 || mergeID <= 0 || keepID == mergeID {
+		return nil, ErrInvalidID
+	}
+	var keep, drop *entity.Subscription
+	err := s.Sr.InTx(ctx, func(ctx context.Context) error {
+		// both rows stay locked until the merge commits; locking the lower ID first keeps two merges of the
+		// same pair from waiting on each other forever
+		locked := map[int64]*entity.Subscription{}
+		for _, id := range []int64{min(keepID, mergeID), max(keepID, mergeID)} {
+			sub, err := s.Sr.GetSubByIDForUpdate(ctx, id)
+			if err != nil {
+				return err
+			}
+			locked[id] = sub
+		}
+		keep, drop = locked[keepID], locked[mergeID]
+		if keep == nil || drop == nil {
+			return ErrSubscriptionNotFound
+		}
+		if keep.UserID != drop.UserID || !strings.EqualFold(keep.ServiceName, drop.ServiceName) {
+			return fmt.Errorf("%w: merged subscriptions must share user and service", ErrInvalidSubscription)
+		}
+
+		merged := mergePeriods(*keep, drop)
+		return s.Sr.MergeSubs(ctx, &merged, drop, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	kept, err := s.Sr.GetSubByID(ctx, keepID)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, EventSubscriptionUpdated, kept, keep)
+	s.publish(ctx, EventSubscriptionDeleted, drop, nil)
+	return kept, nil
+}
+
+// mergePeriods widens keep to cover drop's period; an open end on either side leaves the result open
+func mergePeriods(keep entity.Subscription, drop *entity.Subscription) entity.Subscription {
+	if drop.DateFrom.After(keep.DateFrom) {
+		keep.Cost = drop.Cost
+	} else {
+		keep.DateFrom = drop.DateFrom
+	}
+	switch {
+	case keep.DateTo == nil || drop.DateTo == nil:
+		keep.DateTo = nil
+	case drop.DateTo.After(*keep.DateTo):
+		end := *drop.DateTo
+		keep.DateTo = &end
+	}
+	return keep
+}
+
+// SyncLimits bounds the number of change log entries read per sync call
+var SyncLimits = pagination.Limits{Default: 500, Max: 1000}
+
+// ChangesSince reads the change log of the user after the since position and collapses it into
+// created/updated/deleted IDs
+func (s *Subscription) ChangesSince(ctx context.Context, user entity.UserID, since entity.ChangePos, limit int) (ChangeSet, error) {
+	if user.IsZero() {
+		return ChangeSet{}, fmt.Errorf("changes since: %w", entity.ErrInvalidUserID)
+	}
+	if since.Seq < 0 {
+		return ChangeSet{}, fmt.Errorf("%w: negative sync position", ErrInvalidPagination)
+	}
+	limit = SyncLimits.Clamp(limit)
+	changes, err := s.Sr.ChangesSince(ctx, user, since, limit)
+	if err != nil {
+		return ChangeSet{}, err
+	}
+
+	out := collapseChanges(changes)
+	out.Next = since
+	if n := len(changes); n > 0 {
+		out.Next = changes[n-1].Pos()
+	}
+	out.HasMore = pagination.HasMore(len(changes), limit)
+	return out, nil
+}
+
+// collapseChanges reduces change log entries to one outcome per subscription, preserving first-seen order.
+// A subscription both created and deleted within the window is omitted entirely
+func collapseChanges(changes []entity.SubscriptionChange) ChangeSet {
+	type state struct {
+		created bool
+		deleted bool
+		pid     entity.PublicID
+	}
+	seen := make(map[int64]*state, len(changes))
+	order := make([]int64, 0, len(changes))
+	for _, ch := range changes {
+		st, ok := seen[ch.SubscriptionID]
+		if !ok {
+			st = &state{created: ch.Op == entity.ChangeInsert}
+			seen[ch.SubscriptionID] = st
+			order = append(order, ch.SubscriptionID)
+		}
+		if ch.Op == entity.ChangeDelete {
+			st.deleted = true
+		}
+		if !ch.PublicID.IsZero() {
+			st.pid = ch.PublicID
+		}
+	}
+
+	out := ChangeSet{Created: []ChangedSub{}, Updated: []ChangedSub{}, Deleted: []ChangedSub{}}
+	for _, id := range order {
+		st := seen[id]
+		sub := ChangedSub{ID: id, PublicID: st.pid}
+		switch {
+		case st.created && st.deleted:
+		case st.deleted:
+			out.Deleted = append(out.Deleted, sub)
+		case st.created:
+			out.Created = append(out.Created, sub)
+		default:
+			out.Updated = append(out.Updated, sub)
+		}
+	}
+	return out
+}
+
+// GetSettings returns the user's settings, falling back to defaults when none were saved
+func (s *Subscription) GetSettings(ctx context.Context, userID entity.UserID) (entity.Settings, error) {
+	if userID.IsZero() {
+		return entity.Settings{}, entity.ErrInvalidUserID
+	}
+	saved, err := s.Sr.GetSettings(ctx, userID)
+	if errors.Is(err, ErrSettingsNotFound) {
+		return entity.DefaultSettings(userID), nil
+	}
+	if err != nil {
+		return entity.Settings{}, err
+	}
+	return *saved, nil
+}
+
+// UpdateSettings validates and saves the user's settings; an empty timezone means UTC
+func (s *Subscription) UpdateSettings(ctx context.Context, settings entity.Settings) (entity.Settings, error) {
+	if settings.Timezone == "" {
+		settings.Timezone = entity.DefaultSettings(settings.UserID).Timezone
+	}
+	if err := settings.Validate(); err != nil {
+		return entity.Settings{}, err
+	}
+	saved, err := s.Sr.SaveSettings(ctx, settings)
+	if err != nil {
+		return entity.Settings{}, err
+	}
+	s.costNow.drop(ctx, settings.UserID)
+	return *saved, nil
+}
+
+// RefreshStats recomputes per-service statistics for the current month and publishes them to the metrics sink.
+// The query reads every active subscription, so writes leave it to the periodic metrics refresher
+func (s *Subscription) RefreshStats(ctx context.Context) error {
+	if s.metrics == nil {
+		return nil
+	}
+	stats, err := s.Sr.ActiveStatsByService(ctx, dates.MonthStart(s.clock.Now()))
+	if err != nil {
+		return err
+	}
+	s.metrics.SetStats(stats)
+	return nil
+}
+
+// PriceBenchmarks returns anonymous per-service cost statistics of the month; when userID is set,
+// each benchmark also carries that user's own cost of the service for comparison
+func (s *Subscription) PriceBenchmarks(ctx context.Context, month time.Time, userID entity.UserID) ([]PriceBenchmark, error) {
+	month = dates.MonthStart(month)
+	if month.IsZero() {
+		return nil, fmt.Errorf("%w: empty month", ErrInvalidPeriod)
+	}
+	out, err := s.Sr.PriceBenchmarks(ctx, month, s.benchmarkMinUsers)
+	if err != nil {
+		return nil, fmt.Errorf("price benchmarks: %w", err)
+	}
+	if userID.IsZero() || len(out) == 0 {
+		return out, nil
+	}
+
+	subs, err := s.allSubs(ctx, SubFilter{UserID: userID, Period: &Period{From: month, To: month}})
+	if err != nil {
+		return nil, fmt.Errorf("price benchmarks: %w", err)
+	}
+	yours := make(map[string]int64, len(subs))
+	for _, sub := range subs {
+		yours[strings.ToLower(strings.TrimSpace(sub.ServiceName))] += sub.Cost
+	}
+	for i := range out {
+		if cost, ok := yours[out[i].Service]; ok {
+			out[i].YourCost = &cost
+		}
+	}
+	return out, nil
+}
+
+// UserTotals returns the spend of every user with subscriptions active in month, largest first
+func (s *Subscription) UserTotals(ctx context.Context, month time.Time) ([]UserMonthSpend, error) {
+	month = dates.MonthStart(month)
+	if month.IsZero() {
+		return nil, fmt.Errorf("%w: empty month", ErrInvalidPeriod)
+	}
+	rows, err := s.analytics.MonthlySpendByUser(ctx, month, month)
+	if err != nil {
+		return nil, fmt.Errorf("user totals: %w", err)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Total != rows[j].Total {
+			return rows[i].Total > rows[j].Total
+		}
+		return rows[i].UserID.String() < rows[j].UserID.String()
+	})
+	return rows, nil
+}
+
+// DetectSpendAnomalies compares every user's spend in the month of now with the average of the
+// previous baseline months and reports those deviating by more than the threshold; users without
+// any baseline spend are skipped since there is nothing to compare with
+func (s *Subscription) DetectSpendAnomalies(ctx context.Context, now time.Time) ([]SpendAnomaly, error) {
+	month := dates.MonthStart(now)
+	from := month.AddDate(0, -s.anomaly.BaselineMonths, 0)
+	rows, err := s.analytics.MonthlySpendByUser(ctx, from, month)
+	if err != nil {
+		return nil, fmt.Errorf("detect spend anomalies: %w", err)
+	}
+
+	type spend struct {
+		baseline, months, projected int64
+	}
+	byUser := make(map[entity.UserID]*spend)
+	for _, row := range rows {
+		u, ok := byUser[row.UserID]
+		if !ok {
+			u = &spend{}
+			byUser[row.UserID] = u
+		}
+		if row.Month.Equal(month) {
+			u.projected += row.Total
+			continue
+		}
+		u.baseline += row.Total
+		u.months++
+	}
+
+	var out []SpendAnomaly
+	for userID, u := range byUser {
+		if u.months == 0 || u.baseline == 0 {
+			continue
+		}
+		baseline := u.baseline / u.months
+		deviation := float64(u.projected-baseline) / float64(baseline) * 100
+		if math.Abs(deviation) <= s.anomaly.ThresholdPercent {
+			continue
+		}
+		a := SpendAnomaly{
+			UserID:           userID,
+			Month:            month,
+			Baseline:         baseline,
+			Projected:        u.projected,
+			DeviationPercent: deviation,
+		}
+		if u.projected > baseline {
+			if a.NewServices, err = s.newServices(ctx, userID, month); err != nil {
+				return nil, fmt.Errorf("detect spend anomalies: %w", err)
+			}
+		}
+		out = append(out, a)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].UserID.String() < out[j].UserID.String()
+	})
+	return out, nil
+}
+
+// newServices lists services of the user first charged in the month
+func (s *Subscription) newServices(ctx context.Context, userID entity.UserID, month time.Time) ([]string, error) {
+	subs, err := s.allSubs(ctx, SubFilter{UserID: userID, Period: &Period{From: month, To: month}})
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, sub := range subs {
+		if sub.DateFrom.Equal(month) {
+			names = append(names, sub.ServiceName)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// publish forgets the user's cached current-month total, runs the after hooks for a completed write and hands
+// it to the event sink, if any; prev is the record an update replaced
+func (s *Subscription) publish(ctx context.Context, typ SubscriptionEventType, sub, prev *entity.Subscription) {
+	if sub == nil {
+		return
+	}
+	s.costNow.drop(ctx, sub.UserID)
+	s.hooks.runAfter(ctx, typ, sub)
+	if s.events == nil {
+		return
+	}
+	s.events.Publish(ctx, SubscriptionEvent{
+		Type:         typ,
+		OccurredAt:   s.clock.Now().UTC(),
+		Subscription: sub,
+		Previous:     prev,
+		PublicID:     s.PublicID(sub),
+	})
+}
+
+// prepare validates sub and runs the before save hooks; what the hooks change is validated again
+func (s *Subscription) prepare(ctx context.Context, sub *entity.Subscription) error {
+	if err := s.validateAndNormalize(sub); err != nil {
+		return err
+	}
+	if s.hooks == nil || len(s.hooks.beforeSave) == 0 {
+		return nil
+	}
+	if err := s.hooks.runBeforeSave(ctx, sub); err != nil {
+		return err
 	}
-	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return s.validateAndNormalize(sub)
 }
 
 // validateAndNormalize enforces business rules and aligns dates to month starts
 func (s *Subscription) validateAndNormalize(sub *entity.Subscription) error {
+	return s.rules.ValidateAt(sub, s.clock.Now())
+}
+
+// Validate enforces the business rules of a subscription and aligns its dates to month starts;
+// clients such as subsctl call it to reject input before it reaches the server
+func (r ValidationRules) Validate(sub *entity.Subscription) error {
+	return r.ValidateAt(sub, time.Now())
+}
+
+// ValidateAt is Validate with date bounds counted from now
+func (r ValidationRules) ValidateAt(sub *entity.Subscription, now time.Time) error {
 	if sub == nil {
 		return fmt.Errorf("%w: nil", ErrInvalidSubscription)
 	}
 	sub.ServiceName = strings.TrimSpace(sub.ServiceName)
 	if sub.ServiceName == "" {
-		return fmt.Errorf("%w: empty service_name", ErrInvalidSubscription)
+		return errcode.Wrap(errcode.ServiceNameInvalid, fmt.Errorf("%w: empty service_name", ErrInvalidSubscription))
 	}
-	if sub.Cost <= 0 {
-		return fmt.Errorf("%w: cost must be > 0", ErrInvalidSubscription)
+	if sub.Cost < 0 {
+		return errcode.Wrap(errcode.CostNegative, fmt.Errorf("%w: cost must be >= 0", ErrInvalidSubscription))
 	}
-	if sub.UserID.String() == "" {
-		return fmt.Errorf("%w: empty user_id", ErrInvalidSubscription)
+	if sub.UserID.IsZero() {
+		return errcode.Wrap(errcode.UserIDInvalid, fmt.Errorf("%w: empty user_id", ErrInvalidSubscription))
 	}
 	if sub.DateFrom.IsZero() {
 		return fmt.Errorf("%w: empty start_date", ErrInvalidSubscription)
 	}
 
-	sub.DateFrom = monthStart(sub.DateFrom)
+	sub.DateFrom = dates.MonthStart(sub.DateFrom)
 	if sub.DateTo != nil && !sub.DateTo.IsZero() {
-		d := monthStart(*sub.DateTo)
+		d := dates.MonthStart(*sub.DateTo)
 		sub.DateTo = &d
 		if d.Before(sub.DateFrom) {
 			return fmt.Errorf("%w: end_date before start_date", ErrInvalidPeriod)
 		}
 	}
+	return r.apply(sub, now)
+}
+
+// apply checks a normalized subscription against the configured limits
+func (r ValidationRules) apply(sub *entity.Subscription, now time.Time) error {
+	if err := r.checkDateBounds(sub, now); err != nil {
+		return err
+	}
+	if r.MaxCost > 0 && sub.Cost > r.MaxCost {
+		return errcode.Wrap(errcode.CostTooHigh, fmt.Errorf("%w: cost must be <= %d", ErrInvalidSubscription, r.MaxCost))
+	}
+	if r.ServiceName != nil && !r.ServiceName.MatchString(sub.ServiceName) {
+		return errcode.Wrap(errcode.ServiceNameInvalid, fmt.Errorf("%w: service_name contains disallowed characters", ErrInvalidSubscription))
+	}
+	hasEnd := sub.DateTo != nil && !sub.DateTo.IsZero()
+	if !hasEnd {
+		for _, name := range r.EndDateRequired {
+			if strings.EqualFold(name, sub.ServiceName) {
+				return errcode.Wrap(errcode.EndDateRequired, fmt.Errorf("%w: end_date is required for %s", ErrInvalidSubscription, sub.ServiceName))
+			}
+		}
+	}
+	if r.MaxPeriodMonths > 0 && hasEnd {
+		months := (sub.DateTo.Year()-sub.DateFrom.Year())*12 + int(sub.DateTo.Month()-sub.DateFrom.Month()) + 1
+		if months > r.MaxPeriodMonths {
+			return errcode.Wrap(errcode.PeriodTooLong, fmt.Errorf("%w: period longer than %d months", ErrInvalidPeriod, r.MaxPeriodMonths))
+		}
+	}
+	return nil
+}
+
+// checkDateBounds rejects typo years (e.g. 2205 or 1025) outside the sanity bounds
+func (r ValidationRules) checkDateBounds(sub *entity.Subscription, now time.Time) error {
+	check := func(field string, d time.Time) error {
+		if !r.EarliestDate.IsZero() && d.Before(dates.MonthStart(r.EarliestDate)) {
+			return fmt.Errorf("%w: %s before %s", ErrDateOutOfRange, field, r.EarliestDate.Format("01-2006"))
+		}
+		if r.MaxYearsAhead > 0 {
+			limit := dates.MonthStart(now).AddDate(r.MaxYearsAhead, 0, 0)
+			if d.After(limit) {
+				return fmt.Errorf("%w: %s more than %d years ahead", ErrDateOutOfRange, field, r.MaxYearsAhead)
+			}
+		}
+		return nil
+	}
+	if err := check("start_date", sub.DateFrom); err != nil {
+		return err
+	}
+	if sub.DateTo != nil && !sub.DateTo.IsZero() {
+		return check("end_date", *sub.DateTo)
+	}
 	return nil
 }
 
 // normalizeFilter validates period and pagination
 func normalizeFilter(f SubFilter) (SubFilter, error) {
 	if f.Period != nil {
-		from := monthStart(f.Period.From)
-		to := monthStart(f.Period.To)
+		from := dates.MonthStart(f.Period.From)
+		to := dates.MonthStart(f.Period.To)
 		if from.IsZero() {
 			return f, fmt.Errorf("%w: empty period bound", ErrInvalidPeriod)
 		}
@@ -147,19 +1177,16 @@ func normalizeFilter(f SubFilter) (SubFilter, error) {
 		f = ff
 	}
 
-	if f.Offset < 0 {
-		return f, fmt.Errorf("%w: offset must be >= 0", ErrInvalidPagination)
+	if f.After != nil && f.Offset > 0 {
+		return f, fmt.Errorf("%w: %w", ErrInvalidPagination, pagination.ErrCursorWithOffset)
 	}
-	limit := f.Limit
-	switch {
-	case limit <= 0:
-		limit = defaultListLimit
-	case limit > maxListLimit:
-		limit = maxListLimit
+	limit, offset, err := pagination.DefaultLimits().Normalize(f.Limit, f.Offset)
+	if err != nil {
+		return f, fmt.Errorf("%w: %w", ErrInvalidPagination, err)
 	}
 
 	ff := f
 	ff.Limit = limit
-	ff.Offset = f.Offset
+	ff.Offset = offset
 	return ff, nil
 }
@@ -3,7 +3,9 @@ package usecase
 import (
 	"context"
 	"errors"
-	"github.com/go-openapi/strfmt"
+	"fmt"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,6 +13,11 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/importer"
+	"subs_tracker/internal/tenancy"
+	"subs_tracker/pkg/clock"
+	"subs_tracker/pkg/pagination"
 )
 
 func Test_subscription_RegisterSub(t *testing.T) {
@@ -30,7 +37,7 @@ func Test_subscription_RegisterSub(t *testing.T) {
 		end := start.AddDate(0, -1, 0)
 		_, err := uc.RegisterSub(ctx, &entity.Subscription{
 			ID:          0,
-			UserID:      strfmt.UUID(uuid.New().String()),
+			UserID:      entity.UserID(uuid.New()),
 			ServiceName: "Skillbox",
 			Cost:        10000,
 			DateFrom:    start,
@@ -52,7 +59,7 @@ func Test_subscription_RegisterSub(t *testing.T) {
 		start := time.Date(2025, 8, 17, 10, 0, 0, 0, time.UTC)
 		_, err := uc.RegisterSub(ctx, &entity.Subscription{
 			ID:          0,
-			UserID:      strfmt.UUID(uuid.New().String()),
+			UserID:      entity.UserID(uuid.New()),
 			ServiceName: "Netflix",
 			Cost:        499,
 			DateFrom:    start,
@@ -80,7 +87,7 @@ func Test_subscription_RegisterSub(t *testing.T) {
 		start := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
 		got, err := uc.RegisterSub(ctx, &entity.Subscription{
 			ID:          0,
-			UserID:      strfmt.UUID(uuid.New().String()),
+			UserID:      entity.UserID(uuid.New()),
 			ServiceName: "YouTube",
 			Cost:        199,
 			DateFrom:    start,
@@ -91,6 +98,82 @@ func Test_subscription_RegisterSub(t *testing.T) {
 	})
 }
 
+func Test_subscription_ValidationRules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC)
+	uc := NewSubscription(NewMockSubscriptionRepository(ctrl), WithValidationRules(ValidationRules{
+		MaxCost:         10000,
+		MaxPeriodMonths: 12,
+		ServiceName:     regexp.MustCompile(`^[\p{L}\p{N} +]+$`),
+		EndDateRequired: []string{"Trial"},
+	}))
+	sub := func(name string, cost int64, to *time.Time) *entity.Subscription {
+		return &entity.Subscription{UserID: entity.UserID(uuid.New()), ServiceName: name, Cost: cost, DateFrom: start, DateTo: to}
+	}
+
+	tests := []struct {
+		Name string
+		Sub  *entity.Subscription
+		Want error
+	}{
+		{Name: "ok", Sub: sub("Яндекс Плюс", 399, nil)},
+		{Name: "cost_over_limit", Sub: sub("Netflix", 10001, nil), Want: ErrInvalidSubscription},
+		{Name: "disallowed_chars", Sub: sub("Netflix<script>", 999, nil), Want: ErrInvalidSubscription},
+		{Name: "end_date_required", Sub: sub("trial", 1, nil), Want: ErrInvalidSubscription},
+		{Name: "period_too_long", Sub: sub("Netflix", 999, &end), Want: ErrInvalidPeriod},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			err := uc.validateAndNormalize(tt.Sub)
+			if tt.Want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.Want)
+		})
+	}
+}
+
+func Test_subscription_DateBounds(t *testing.T) {
+	r := ValidationRules{EarliestDate: time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC), MaxYearsAhead: 20}
+	now := time.Date(2025, 8, 17, 0, 0, 0, 0, time.UTC)
+	month := func(y int, m time.Month) *time.Time {
+		d := time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+		return &d
+	}
+
+	tests := []struct {
+		Name     string
+		From     time.Time
+		To       *time.Time
+		WantErr  bool
+		Contains string
+	}{
+		{Name: "ok", From: *month(2025, 1), To: month(2045, 8)},
+		{Name: "typo_start_year", From: *month(2205, 1), WantErr: true, Contains: "start_date"},
+		{Name: "start_before_earliest", From: *month(1989, 12), WantErr: true, Contains: "start_date"},
+		{Name: "end_too_far", From: *month(2025, 1), To: month(2045, 9), WantErr: true, Contains: "end_date"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			err := r.checkDateBounds(&entity.Subscription{DateFrom: tt.From, DateTo: tt.To}, now)
+			if !tt.WantErr {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrDateOutOfRange)
+			assert.ErrorContains(t, err, tt.Contains)
+		})
+	}
+
+	t.Run("disabled_by_default", func(t *testing.T) {
+		assert.NoError(t, ValidationRules{}.checkDateBounds(&entity.Subscription{DateFrom: *month(2205, 1)}, now))
+	})
+}
+
 func Test_subscription_UpdateSub(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -109,7 +192,7 @@ func Test_subscription_UpdateSub(t *testing.T) {
 
 		_, err := uc.UpdateSub(ctx, &entity.Subscription{
 			ID:          10,
-			UserID:      strfmt.UUID(uuid.New().String()),
+			UserID:      entity.UserID(uuid.New()),
 			ServiceName: "A",
 			Cost:        1,
 			DateFrom:    start,
@@ -123,15 +206,23 @@ func Test_subscription_UpdateSub(t *testing.T) {
 		defer cancel()
 
 		repo := NewMockSubscriptionRepository(ctrl)
+		runTx(repo)
 
 		start := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
 		id := int64(77)
 		user := uuid.New()
 
+		repo.EXPECT().GetSubByIDForUpdate(ctx, id).Times(1).Return(&entity.Subscription{
+			ID:          id,
+			UserID:      entity.UserID(user),
+			ServiceName: "Pro",
+			Cost:        400,
+			DateFrom:    start,
+		}, nil)
 		repo.EXPECT().UpdateSub(ctx, gomock.Any()).Times(1).Return(nil)
 		repo.EXPECT().GetSubByID(ctx, id).Times(1).Return(&entity.Subscription{
 			ID:          id,
-			UserID:      strfmt.UUID(user.String()),
+			UserID:      entity.UserID(user),
 			ServiceName: "Pro",
 			Cost:        500,
 			DateFrom:    start,
@@ -141,7 +232,7 @@ func Test_subscription_UpdateSub(t *testing.T) {
 
 		got, err := uc.UpdateSub(ctx, &entity.Subscription{
 			ID:          id,
-			UserID:      strfmt.UUID(user.String()),
+			UserID:      entity.UserID(user),
 			ServiceName: "Pro",
 			Cost:        500,
 			DateFrom:    start.AddDate(0, 0, 15),
@@ -151,6 +242,34 @@ func Test_subscription_UpdateSub(t *testing.T) {
 		assert.Equal(t, 500, int(got.Cost))
 		assert.Equal(t, 1, got.DateFrom.Day())
 	})
+
+	t.Run("ok, nothing changed is not written", func(t *testing.T) {
+		ctx := context.Background()
+		start := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+		version := time.Date(2025, 8, 3, 10, 0, 0, 0, time.UTC)
+		stored := &entity.Subscription{ID: 5, UserID: entity.UserID(uuid.New()), ServiceName: "Pro", Cost: 500, DateFrom: start, UpdatedAt: version}
+
+		repo := NewMockSubscriptionRepository(ctrl)
+		runTx(repo)
+		repo.EXPECT().GetSubByIDForUpdate(ctx, int64(5)).Return(stored, nil).Times(3)
+		repo.EXPECT().UpdateSub(gomock.Any(), gomock.Any()).Times(0)
+		events := &stubEvents{}
+		uc := NewSubscription(repo, WithEvents(events))
+
+		same := *stored
+		same.ServiceName, same.DateFrom, same.UpdatedAt = " Pro ", start.AddDate(0, 0, 20), time.Time{}
+		got, err := uc.UpdateSub(ctx, &same)
+		assert.NoError(t, err)
+		assert.Same(t, stored, got)
+
+		same.UpdatedAt = version
+		_, err = uc.UpdateSub(ctx, &same)
+		assert.NoError(t, err, "the current version matches")
+		same.UpdatedAt = version.Add(-time.Hour)
+		_, err = uc.UpdateSub(ctx, &same)
+		assert.ErrorIs(t, err, ErrPreconditionFailed, "a stale version is still refused")
+		assert.Empty(t, events.got)
+	})
 }
 
 func Test_subscription_DeleteSub(t *testing.T) {
@@ -162,11 +281,12 @@ func Test_subscription_DeleteSub(t *testing.T) {
 		defer cancel()
 
 		repo := NewMockSubscriptionRepository(ctrl)
-		repo.EXPECT().GetSubByID(ctx, int64(123)).Times(1).Return(nil, ErrSubscriptionNotFound)
+		runTx(repo)
+		repo.EXPECT().GetSubByIDForUpdate(ctx, int64(123)).Times(1).Return(nil, ErrSubscriptionNotFound)
 
 		uc := NewSubscription(repo)
 
-		_, err := uc.DeleteSub(ctx, 123)
+		_, err := uc.DeleteSub(ctx, 123, time.Time{})
 		assert.ErrorIs(t, err, ErrSubscriptionNotFound)
 	})
 
@@ -179,21 +299,39 @@ func Test_subscription_DeleteSub(t *testing.T) {
 		user := uuid.New()
 		existing := &entity.Subscription{
 			ID:          id,
-			UserID:      strfmt.UUID(user.String()),
+			UserID:      entity.UserID(user),
 			ServiceName: "Skillbox",
 			Cost:        10000,
 			DateFrom:    time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC),
 		}
 
-		repo.EXPECT().GetSubByID(ctx, id).Times(1).Return(existing, nil)
-		repo.EXPECT().DeleteSub(ctx, id).Times(1).Return(nil)
+		runTx(repo)
+		repo.EXPECT().GetSubByIDForUpdate(ctx, id).Times(1).Return(existing, nil)
+		repo.EXPECT().DeleteSub(ctx, id, time.Time{}).Times(1).Return(nil)
 
 		uc := NewSubscription(repo)
 
-		got, err := uc.DeleteSub(ctx, id)
+		got, err := uc.DeleteSub(ctx, id, time.Time{})
 		assert.NoError(t, err)
 		assert.Equal(t, existing, got)
 	})
+
+	t.Run("err, stale version", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		repo := NewMockSubscriptionRepository(ctrl)
+		version := time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+		runTx(repo)
+		repo.EXPECT().GetSubByIDForUpdate(ctx, int64(7)).Times(1).Return(&entity.Subscription{
+			ID:        7,
+			UpdatedAt: version.Add(time.Second),
+		}, nil)
+		repo.EXPECT().DeleteSub(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		_, err := NewSubscription(repo).DeleteSub(ctx, 7, version)
+		assert.ErrorIs(t, err, ErrPreconditionFailed)
+	})
 }
 
 func Test_subscription_GetSubByID(t *testing.T) {
@@ -221,7 +359,7 @@ func Test_subscription_GetSubByID(t *testing.T) {
 		user := uuid.New()
 		repo.EXPECT().GetSubByID(ctx, int64(2)).Times(1).Return(&entity.Subscription{
 			ID:          2,
-			UserID:      strfmt.UUID(user.String()),
+			UserID:      entity.UserID(user),
 			ServiceName: "Netflix",
 			Cost:        499,
 			DateFrom:    time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC),
@@ -258,8 +396,8 @@ func Test_subscription_ListSubsByFilter(t *testing.T) {
 
 		repo := NewMockSubscriptionRepository(ctrl)
 		list := []*entity.Subscription{
-			{ID: 1, UserID: strfmt.UUID(uuid.New().String()), ServiceName: "A", Cost: 10, DateFrom: time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)},
-			{ID: 2, UserID: strfmt.UUID(uuid.New().String()), ServiceName: "B", Cost: 20, DateFrom: time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)},
+			{ID: 1, UserID: entity.UserID(uuid.New()), ServiceName: "A", Cost: 10, DateFrom: time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)},
+			{ID: 2, UserID: entity.UserID(uuid.New()), ServiceName: "B", Cost: 20, DateFrom: time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)},
 		}
 		repo.EXPECT().ListSubsByFilter(ctx, gomock.Any()).Times(1).Return(list, nil)
 
@@ -269,6 +407,50 @@ func Test_subscription_ListSubsByFilter(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Len(t, got, 2)
 	})
+
+	t.Run("with archive", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		sub := func(id int64, month time.Month) *entity.Subscription {
+			return &entity.Subscription{ID: id, ServiceName: "A", DateFrom: time.Date(2025, month, 1, 0, 0, 0, 0, time.UTC)}
+		}
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().ListSubsByFilter(ctx, gomock.Any()).Times(2).DoAndReturn(
+			func(_ context.Context, f SubFilter) ([]*entity.Subscription, error) {
+				assert.Zero(t, f.Offset, "live rows are read from the start")
+				if f.After == nil {
+					return []*entity.Subscription{sub(10, 1), sub(11, 3)}, nil
+				}
+				assert.EqualValues(t, 11, f.After.ID)
+				return []*entity.Subscription{sub(12, 5)}, nil
+			})
+		repo.EXPECT().UserDeactivatedAt(ctx, entity.UserID{}).Times(1).Return(time.Time{}, nil)
+		archive := &stubArchive{subs: []*entity.Subscription{
+			{ID: 1, ServiceName: "A", DateFrom: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)},
+			sub(2, 2),
+		}}
+
+		uc := NewSubscription(repo, WithArchive(archive))
+
+		got, err := uc.ListSubsByFilter(ctx, SubFilter{Limit: 2, Offset: 1, IncludeArchived: true})
+		assert.NoError(t, err)
+		if assert.Len(t, got, 2) {
+			assert.EqualValues(t, 10, got[0].ID)
+			assert.EqualValues(t, 2, got[1].ID)
+		}
+		assert.True(t, archive.got.IncludeArchived)
+	})
+}
+
+type stubArchive struct {
+	subs []*entity.Subscription
+	got  SubFilter
+}
+
+func (a *stubArchive) Archived(_ context.Context, f SubFilter) ([]*entity.Subscription, error) {
+	a.got = f
+	return a.subs, nil
 }
 
 func Test_subscription_CostSubsByFilter(t *testing.T) {
@@ -306,3 +488,1760 @@ func Test_subscription_CostSubsByFilter(t *testing.T) {
 		assert.Equal(t, int64(12345), sum)
 	})
 }
+
+func Test_subscription_CostNow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	user := entity.UserID(uuid.New())
+	settings := entity.DefaultSettings(user)
+	settings.Timezone, settings.Currency = "Europe/Moscow", "RUB"
+	sep := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	inSep := SubFilter{UserID: user, Period: &Period{From: sep, To: sep}}
+
+	t.Run("cached until a write or expiry", func(t *testing.T) {
+		// already September in Moscow
+		clk := clock.NewFake(time.Date(2025, 8, 31, 22, 30, 0, 0, time.UTC))
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().GetSettings(ctx, user).Times(3).Return(&settings, nil)
+		repo.EXPECT().CostSubsByFilter(ctx, inSep).Times(3).Return(int64(1299), nil)
+		repo.EXPECT().SaveSettings(ctx, settings).Return(&settings, nil)
+		uc := NewSubscription(repo, WithClock(clk))
+
+		got, err := uc.CostNow(ctx, user)
+		assert.NoError(t, err)
+		assert.Equal(t, CostNow{Month: sep, Total: 1299, Currency: "RUB", AsOf: clk.Now()}, got)
+		clk.Advance(10 * time.Second)
+		cached, err := uc.CostNow(ctx, user)
+		assert.NoError(t, err)
+		assert.Equal(t, got, cached)
+
+		_, err = uc.UpdateSettings(ctx, settings)
+		assert.NoError(t, err)
+		_, err = uc.CostNow(ctx, user)
+		assert.NoError(t, err)
+		clk.Advance(DefaultCostNowTTL)
+		got, err = uc.CostNow(ctx, user)
+		assert.NoError(t, err)
+		assert.Equal(t, clk.Now(), got.AsOf)
+	})
+
+	t.Run("month change expires the total early", func(t *testing.T) {
+		clk := clock.NewFake(time.Date(2025, 9, 30, 20, 59, 50, 0, time.UTC))
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().GetSettings(ctx, user).Times(2).Return(&settings, nil)
+		repo.EXPECT().CostSubsByFilter(ctx, inSep).Return(int64(1299), nil)
+		oct := sep.AddDate(0, 1, 0)
+		repo.EXPECT().CostSubsByFilter(ctx, SubFilter{UserID: user, Period: &Period{From: oct, To: oct}}).Return(int64(0), nil)
+		uc := NewSubscription(repo, WithClock(clk))
+
+		_, err := uc.CostNow(ctx, user)
+		assert.NoError(t, err)
+		clk.Advance(15 * time.Second)
+		got, err := uc.CostNow(ctx, user)
+		assert.NoError(t, err)
+		assert.Equal(t, oct, got.Month)
+	})
+
+	t.Run("tenants sharing a user ID are cached apart", func(t *testing.T) {
+		clk := clock.NewFake(time.Date(2025, 9, 10, 12, 0, 0, 0, time.UTC))
+		acme, globex := tenancy.WithTenant(ctx, "acme"), tenancy.WithTenant(ctx, "globex")
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().GetSettings(gomock.Any(), user).Times(2).Return(&settings, nil)
+		repo.EXPECT().CostSubsByFilter(acme, inSep).Return(int64(1299), nil)
+		repo.EXPECT().CostSubsByFilter(globex, inSep).Return(int64(500), nil)
+		repo.EXPECT().SaveSettings(acme, settings).Return(&settings, nil)
+		uc := NewSubscription(repo, WithClock(clk))
+
+		got, err := uc.CostNow(acme, user)
+		assert.NoError(t, err)
+		assert.EqualValues(t, 1299, got.Total)
+		got, err = uc.CostNow(globex, user)
+		assert.NoError(t, err)
+		assert.EqualValues(t, 500, got.Total, "not the total of the other tenant")
+
+		_, err = uc.UpdateSettings(acme, settings)
+		assert.NoError(t, err)
+		got, err = uc.CostNow(globex, user)
+		assert.NoError(t, err)
+		assert.EqualValues(t, 500, got.Total, "a write of the other tenant keeps the entry")
+		assert.Equal(t, 1, uc.FlushCostNow())
+	})
+
+	t.Run("priming computes the largest spenders", func(t *testing.T) {
+		clk := clock.NewFake(time.Date(2025, 9, 10, 12, 0, 0, 0, time.UTC))
+		small, large := entity.UserID(uuid.New()), entity.UserID(uuid.New())
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().MonthlySpendByUser(ctx, sep, sep).Return([]UserMonthSpend{
+			{UserID: small, Month: sep, Total: 100},
+			{UserID: user, Month: sep, Total: 1299},
+			{UserID: large, Month: sep, Total: 5000},
+		}, nil)
+		repo.EXPECT().GetSettings(ctx, gomock.Any()).Times(2).Return(&settings, nil)
+		repo.EXPECT().CostSubsByFilter(ctx, gomock.Any()).Times(2).Return(int64(1299), nil)
+		uc := NewSubscription(repo, WithClock(clk), WithCostNowPrime(2))
+
+		primed, err := uc.PrimeCostNow(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, primed)
+		_, err = uc.CostNow(ctx, user)
+		assert.NoError(t, err, "served from the primed cache")
+		assert.Equal(t, 2, uc.FlushCostNow())
+		assert.Equal(t, 0, uc.FlushCostNow())
+
+		primed, err = NewSubscription(repo).PrimeCostNow(ctx)
+		assert.NoError(t, err)
+		assert.Zero(t, primed, "priming is off by default")
+	})
+
+	t.Run("no user", func(t *testing.T) {
+		_, err := NewSubscription(NewMockSubscriptionRepository(ctrl)).CostNow(ctx, entity.UserID{})
+		assert.ErrorIs(t, err, entity.ErrInvalidUserID)
+	})
+}
+
+func Test_subscription_GoalProgress(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	user := entity.UserID(uuid.New())
+	settings := entity.DefaultSettings(user)
+	sep := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(time.Date(2025, 9, 10, 12, 0, 0, 0, time.UTC))
+
+	t.Run("spend against the goal", func(t *testing.T) {
+		withGoal := settings
+		withGoal.MonthlyGoal = 1000
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().GetSettings(ctx, user).Times(3).Return(&withGoal, nil)
+		repo.EXPECT().CostSubsByFilter(ctx, SubFilter{UserID: user, Period: &Period{From: sep, To: sep}}).Return(int64(1299), nil)
+		uc := NewSubscription(repo, WithClock(clk))
+
+		got, err := uc.GoalProgress(ctx, user)
+		assert.NoError(t, err)
+		assert.Equal(t, GoalProgress{
+			Month: sep, Goal: 1000, Spent: 1299, Projected: 1299, Remaining: -299, Percent: 129,
+			Currency: "RUB", AsOf: clk.Now(),
+		}, got)
+		assert.True(t, got.Over())
+
+		got, err = uc.GoalProgress(ctx, user)
+		assert.NoError(t, err, "the spend is served from the CostNow cache")
+		assert.Equal(t, int64(1299), got.Spent)
+	})
+
+	t.Run("no goal", func(t *testing.T) {
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().GetSettings(ctx, user).Return(nil, ErrSettingsNotFound)
+		_, err := NewSubscription(repo, WithClock(clk)).GoalProgress(ctx, user)
+		assert.ErrorIs(t, err, ErrGoalNotSet)
+
+		_, err = NewSubscription(repo).GoalProgress(ctx, entity.UserID{})
+		assert.ErrorIs(t, err, entity.ErrInvalidUserID)
+	})
+}
+
+// runTx makes repo run the functions passed to InTx in place, as a repository joining the caller would
+func runTx(repo *MockSubscriptionRepository) {
+	repo.EXPECT().InTx(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
+		func(ctx context.Context, fn func(context.Context) error) error { return fn(ctx) })
+}
+
+type stubMetrics struct {
+	created int
+	stats   []ServiceStats
+}
+
+func (m *stubMetrics) SubCreated() { m.created++ }
+
+func (m *stubMetrics) SetStats(stats []ServiceStats) { m.stats = stats }
+
+type stubEvents struct {
+	got []SubscriptionEvent
+}
+
+func (e *stubEvents) Publish(_ context.Context, ev SubscriptionEvent) { e.got = append(e.got, ev) }
+
+func Test_subscription_Events(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	jul := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	stored := &entity.Subscription{ID: 1, UserID: entity.UserID(uuid.New()), ServiceName: "Netflix", Cost: 999, DateFrom: jul}
+
+	repo := NewMockSubscriptionRepository(ctrl)
+	repo.EXPECT().SaveSub(ctx, gomock.Any()).Return(stored, nil)
+	repo.EXPECT().UpdateSub(ctx, gomock.Any()).Return(nil)
+	runTx(repo)
+	repo.EXPECT().GetSubByIDForUpdate(ctx, int64(1)).Return(stored, nil).Times(3)
+	repo.EXPECT().GetSubByID(ctx, int64(1)).Return(stored, nil)
+	repo.EXPECT().DeleteSub(ctx, int64(1), time.Time{}).Return(nil)
+	repo.EXPECT().DeleteSub(ctx, int64(1), time.Time{}).Return(ErrSubscriptionNotFound)
+
+	events := &stubEvents{}
+	uc := NewSubscription(repo, WithEvents(events))
+
+	_, err := uc.RegisterSub(ctx, &entity.Subscription{UserID: stored.UserID, ServiceName: "Netflix", Cost: 999, DateFrom: jul})
+	assert.NoError(t, err)
+	upd := *stored
+	upd.Cost = 1099
+	_, err = uc.UpdateSub(ctx, &upd)
+	assert.NoError(t, err)
+	_, err = uc.DeleteSub(ctx, 1, time.Time{})
+	assert.NoError(t, err)
+	_, err = uc.DeleteSub(ctx, 1, time.Time{})
+	assert.ErrorIs(t, err, ErrSubscriptionNotFound)
+
+	var types []SubscriptionEventType
+	for _, ev := range events.got {
+		assert.Same(t, stored, ev.Subscription)
+		assert.False(t, ev.OccurredAt.IsZero())
+		types = append(types, ev.Type)
+	}
+	assert.Equal(t, []SubscriptionEventType{EventSubscriptionCreated, EventSubscriptionUpdated, EventSubscriptionDeleted}, types,
+		"failed writes publish nothing")
+}
+
+func Test_subscription_ChangesSince(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	user := entity.UserID(uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba"))
+
+	t.Run("err, negative position", func(t *testing.T) {
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().ChangesSince(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		_, err := NewSubscription(repo).ChangesSince(context.Background(), user, entity.ChangePos{Seq: -1}, 0)
+		assert.ErrorIs(t, err, ErrInvalidPagination)
+		_, err = NewSubscription(repo).ChangesSince(context.Background(), entity.UserID{}, entity.ChangePos{}, 0)
+		assert.ErrorIs(t, err, entity.ErrInvalidUserID)
+	})
+
+	t.Run("ok, collapses per subscription", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		since := entity.ChangePos{Tx: 100, Seq: 10}
+		gone := entity.PublicID(uuid.MustParse("01920f3c-4a5b-7c6d-8e9f-a0b1c2d3e4f5"))
+		repo.EXPECT().ChangesSince(ctx, user, since, 500).Return([]entity.SubscriptionChange{
+			{Tx: 101, Seq: 11, SubscriptionID: 1, Op: entity.ChangeInsert},
+			{Tx: 101, Seq: 12, SubscriptionID: 2, Op: entity.ChangeUpdate},
+			{Tx: 102, Seq: 13, SubscriptionID: 1, Op: entity.ChangeUpdate},
+			{Tx: 103, Seq: 16, SubscriptionID: 4, Op: entity.ChangeInsert},
+			{Tx: 103, Seq: 17, SubscriptionID: 4, Op: entity.ChangeDelete},
+			{Tx: 104, Seq: 14, SubscriptionID: 3, PublicID: gone, Op: entity.ChangeUpdate},
+			{Tx: 104, Seq: 15, SubscriptionID: 3, PublicID: gone, Op: entity.ChangeDelete},
+		}, nil).Times(1)
+
+		got, err := NewSubscription(repo).ChangesSince(ctx, user, since, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, []ChangedSub{{ID: 1}}, got.Created)
+		assert.Equal(t, []ChangedSub{{ID: 2}}, got.Updated)
+		assert.Equal(t, []ChangedSub{{ID: 3, PublicID: gone}}, got.Deleted, "deleted ones keep their public ID")
+		assert.Equal(t, entity.ChangePos{Tx: 104, Seq: 15}, got.Next, "the position of the last entry read, not the highest seq")
+		assert.False(t, got.HasMore)
+	})
+
+	t.Run("ok, empty keeps position and reports more on full page", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		since := entity.ChangePos{Tx: 50, Seq: 5}
+		repo.EXPECT().ChangesSince(ctx, user, since, 1).Return(nil, nil).Times(1)
+		repo.EXPECT().ChangesSince(ctx, user, since, 1).Return([]entity.SubscriptionChange{
+			{Tx: 51, Seq: 6, SubscriptionID: 9, Op: entity.ChangeUpdate},
+		}, nil).Times(1)
+
+		uc := NewSubscription(repo)
+		got, err := uc.ChangesSince(ctx, user, since, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, since, got.Next)
+		assert.False(t, got.HasMore)
+
+		got, err = uc.ChangesSince(ctx, user, since, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, entity.ChangePos{Tx: 51, Seq: 6}, got.Next)
+		assert.True(t, got.HasMore)
+	})
+}
+
+func Test_subscription_ReassignUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	from := entity.UserID(uuid.New())
+	to := entity.UserID(uuid.New())
+
+	t.Run("err, invalid users", func(t *testing.T) {
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().ReassignUser(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+		uc := NewSubscription(repo)
+
+		_, err := uc.ReassignUser(context.Background(), entity.UserID{}, to, "")
+		assert.ErrorIs(t, err, entity.ErrInvalidUserID)
+		_, err = uc.ReassignUser(context.Background(), from, from, "")
+		assert.ErrorIs(t, err, entity.ErrInvalidUserID)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().ReassignUser(ctx, from, to, "10.0.0.1").Times(1).Return(int64(3), nil)
+
+		moved, err := NewSubscription(repo).ReassignUser(ctx, from, to, "10.0.0.1")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), moved)
+	})
+}
+
+func Test_subscription_DeactivateUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := entity.UserID(uuid.New())
+	other := entity.UserID(uuid.New())
+	clk := clock.NewFake(time.Date(2025, 9, 10, 12, 0, 0, 0, time.UTC))
+
+	t.Run("err, invalid user", func(t *testing.T) {
+		repo := NewMockSubscriptionRepository(ctrl)
+		uc := NewSubscription(repo)
+
+		_, err := uc.DeactivateUser(context.Background(), entity.UserID{})
+		assert.ErrorIs(t, err, entity.ErrInvalidUserID)
+		assert.ErrorIs(t, uc.ReactivateUser(context.Background(), entity.UserID{}), entity.ErrInvalidUserID)
+	})
+
+	t.Run("ok, keeps the earlier time", func(t *testing.T) {
+		ctx := context.Background()
+		earlier := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().DeactivateUser(ctx, user, clk.Now().UTC()).Times(1).Return(earlier, nil)
+
+		at, err := NewSubscription(repo, WithClock(clk)).DeactivateUser(ctx, user)
+		assert.NoError(t, err)
+		assert.Equal(t, earlier, at)
+	})
+
+	t.Run("export leaves out archived subscriptions of deactivated users", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().GetSettings(ctx, user).Times(1).Return(nil, ErrSettingsNotFound)
+		repo.EXPECT().ListSubsByFilter(ctx, gomock.Any()).Times(1).Return(nil, nil)
+		repo.EXPECT().UserDeactivatedAt(ctx, user).Times(1).Return(clk.Now(), nil)
+		repo.EXPECT().UserDeactivatedAt(ctx, other).Times(1).Return(time.Time{}, nil)
+		archive := &stubArchive{subs: []*entity.Subscription{
+			{ID: 1, UserID: user, ServiceName: "A"},
+			{ID: 2, UserID: other, ServiceName: "B"},
+			{ID: 3, UserID: user, ServiceName: "C"},
+		}}
+
+		data, err := NewSubscription(repo, WithArchive(archive)).ExportUser(ctx, user)
+		assert.NoError(t, err)
+		if assert.Len(t, data.Archived, 1) {
+			assert.EqualValues(t, 2, data.Archived[0].ID)
+		}
+	})
+}
+
+func Test_subscription_RecordAdjustment(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := entity.UserID(uuid.New())
+	sub := &entity.Subscription{ID: 7, UserID: user, ServiceName: "Netflix", Cost: 999}
+
+	t.Run("err, invalid input", func(t *testing.T) {
+		uc := NewSubscription(NewMockSubscriptionRepository(ctrl))
+		month := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+		_, err := uc.RecordAdjustment(context.Background(), &entity.Adjustment{Amount: -100, Month: month})
+		assert.ErrorIs(t, err, ErrInvalidID)
+		_, err = uc.RecordAdjustment(context.Background(), &entity.Adjustment{SubscriptionID: 7, Month: month})
+		assert.ErrorIs(t, err, ErrInvalidAdjustment)
+		assert.Equal(t, errcode.AdjustmentInvalid, errcode.Of(err))
+		_, err = uc.RecordAdjustment(context.Background(), &entity.Adjustment{SubscriptionID: 7, Amount: -100})
+		assert.ErrorIs(t, err, ErrInvalidAdjustment)
+	})
+
+	t.Run("err, subscription not found", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().GetSubByID(ctx, int64(7)).Times(1).Return(nil, ErrSubscriptionNotFound)
+
+		_, err := NewSubscription(repo).RecordAdjustment(ctx, &entity.Adjustment{
+			SubscriptionID: 7, Amount: -100, Month: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		})
+		assert.ErrorIs(t, err, ErrSubscriptionNotFound)
+	})
+
+	t.Run("ok, aligns the month and forgets the cached total", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		uc := NewSubscription(repo, WithCostNowTTL(time.Hour))
+		uc.costNow.put(ctx, user, CostNow{Total: 999, AsOf: time.Now()}, time.UTC)
+		repo.EXPECT().GetSubByID(ctx, int64(7)).Times(1).Return(sub, nil)
+		repo.EXPECT().SaveAdjustment(ctx, &entity.Adjustment{
+			SubscriptionID: 7, Amount: -500, Month: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), Note: "outage",
+		}).Times(1).DoAndReturn(func(_ context.Context, a *entity.Adjustment) (*entity.Adjustment, error) {
+			out := *a
+			out.ID = 1
+			return &out, nil
+		})
+
+		got, err := uc.RecordAdjustment(ctx, &entity.Adjustment{
+			SubscriptionID: 7, Amount: -500, Month: time.Date(2025, 3, 17, 10, 0, 0, 0, time.UTC), Note: "  outage ",
+		})
+		assert.NoError(t, err)
+		assert.EqualValues(t, 1, got.ID)
+		_, cached := uc.costNow.get(ctx, user, time.Now())
+		assert.False(t, cached)
+	})
+}
+
+func Test_subscription_SetSeats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	owner, member := entity.UserID(uuid.New()), entity.UserID(uuid.New())
+	sub := &entity.Subscription{ID: 7, UserID: owner, ServiceName: "Netflix", Cost: 1000}
+
+	t.Run("err, invalid input", func(t *testing.T) {
+		uc := NewSubscription(NewMockSubscriptionRepository(ctrl))
+		for _, seats := range []*entity.Seats{
+			{SubscriptionID: 7, Total: 1},
+			{SubscriptionID: 7, Total: MaxSeats + 1},
+			{SubscriptionID: 7, UserIDs: []entity.UserID{member}},
+			{SubscriptionID: 7, Total: 2, UserIDs: []entity.UserID{member, entity.UserID(uuid.New())}},
+			{SubscriptionID: 7, Total: 3, UserIDs: []entity.UserID{member, member}},
+			{SubscriptionID: 7, Total: 3, UserIDs: []entity.UserID{{}}},
+		} {
+			_, err := uc.SetSeats(context.Background(), seats)
+			assert.ErrorIs(t, err, ErrInvalidSeats, "%+v", seats)
+			assert.Equal(t, errcode.SeatsInvalid, errcode.Of(err))
+		}
+		_, err := uc.SetSeats(context.Background(), &entity.Seats{Total: 2})
+		assert.ErrorIs(t, err, ErrInvalidID)
+	})
+
+	t.Run("err, owner as member", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().GetSubByID(ctx, int64(7)).Times(1).Return(sub, nil)
+
+		_, err := NewSubscription(repo).SetSeats(ctx, &entity.Seats{SubscriptionID: 7, Total: 3, UserIDs: []entity.UserID{owner}})
+		assert.ErrorIs(t, err, ErrInvalidSeats)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		seats := &entity.Seats{SubscriptionID: 7, Total: 3, UserIDs: []entity.UserID{member}}
+		repo.EXPECT().GetSubByID(ctx, int64(7)).Times(1).Return(sub, nil)
+		repo.EXPECT().GetSeats(ctx, int64(7)).Times(1).Return(nil, nil)
+		repo.EXPECT().SaveSeats(ctx, seats).Times(1).Return(seats, nil)
+
+		plan, err := NewSubscription(repo).SetSeats(ctx, seats)
+		assert.NoError(t, err)
+		assert.Equal(t, sub, plan.Subscription)
+		assert.EqualValues(t, 333, plan.Share())
+	})
+
+	t.Run("ok, forgets the cached totals of the owner and the members", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		uc := NewSubscription(repo, WithCostNowTTL(time.Hour))
+		former := entity.UserID(uuid.New())
+		for _, u := range []entity.UserID{owner, member, former} {
+			uc.costNow.put(ctx, u, CostNow{Total: 999, AsOf: time.Now()}, time.UTC)
+		}
+		seats := &entity.Seats{SubscriptionID: 7, Total: 2, UserIDs: []entity.UserID{member}}
+		repo.EXPECT().GetSubByID(ctx, int64(7)).Times(1).Return(sub, nil)
+		repo.EXPECT().GetSeats(ctx, int64(7)).Times(1).Return(&entity.Seats{SubscriptionID: 7, Total: 2, UserIDs: []entity.UserID{former}}, nil)
+		repo.EXPECT().SaveSeats(ctx, seats).Times(1).Return(seats, nil)
+
+		_, err := uc.SetSeats(ctx, seats)
+		assert.NoError(t, err)
+		for _, u := range []entity.UserID{owner, member, former} {
+			_, cached := uc.costNow.get(ctx, u, time.Now())
+			assert.False(t, cached)
+		}
+	})
+}
+
+func Test_subscription_CostSummaryByFilter_Seats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	period := &Period{From: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)}
+	repo := NewMockSubscriptionRepository(ctrl)
+	repo.EXPECT().CostSummaryByFilter(ctx, gomock.Any()).Times(2).Return(CostSummary{Total: 300, Count: 1}, nil)
+	repo.EXPECT().SeatSharesByFilter(ctx, gomock.Any()).Times(1).Return([]SeatShare{
+		{SubscriptionID: 1, ServiceName: "Netflix", Cost: 1000, Seats: 4},
+		{SubscriptionID: 2, ServiceName: "Spotify", Cost: 300, Seats: 2},
+	}, nil)
+	uc := NewSubscription(repo)
+
+	sum, err := uc.CostSummaryByFilter(ctx, SubFilter{UserID: entity.UserID(uuid.New()), Period: period})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 300, sum.Total, "seats are not part of the total")
+	assert.EqualValues(t, 250+150, sum.SeatShareTotal())
+	sum, err = uc.CostSummaryByFilter(ctx, SubFilter{Period: period})
+	assert.NoError(t, err)
+	assert.Empty(t, sum.Seats, "only for a user")
+}
+
+func Test_subscription_DeleteUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := entity.UserID(uuid.New())
+	now := clock.NewFake(time.Date(2025, 9, 10, 12, 0, 0, 0, time.UTC))
+
+	t.Run("err, invalid user", func(t *testing.T) {
+		_, err := NewSubscription(NewMockSubscriptionRepository(ctrl)).DeleteUser(context.Background(), entity.UserID{}, "admin")
+		assert.ErrorIs(t, err, entity.ErrInvalidUserID)
+	})
+
+	t.Run("block, refused while a subscription has not ended", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().DeleteUser(ctx, user, UserDeletion{
+			Policy: DeleteUserBlock,
+			Since:  time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC),
+		}, "admin").Times(1).Return(int64(0), ErrUserHasActiveSubs)
+
+		_, err := NewSubscription(repo, WithClock(now)).DeleteUser(ctx, user, "admin")
+		assert.ErrorIs(t, err, ErrUserHasActiveSubs)
+		assert.Equal(t, errcode.UserHasActiveSubs, errcode.Of(err))
+	})
+
+	t.Run("cascade, forgets the cached total", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		uc := NewSubscription(repo, WithUserDeletion(DeleteUserCascade), WithCostNowTTL(time.Hour))
+		uc.costNow.put(ctx, user, CostNow{Total: 999, AsOf: time.Now()}, time.UTC)
+		repo.EXPECT().DeleteUser(ctx, user, UserDeletion{Policy: DeleteUserCascade}, "admin").Times(1).Return(int64(3), nil)
+
+		got, err := uc.DeleteUser(ctx, user, "admin")
+		assert.NoError(t, err)
+		assert.Equal(t, DeletedUser{Policy: DeleteUserCascade, Subscriptions: 3}, got)
+		_, cached := uc.costNow.get(ctx, user, time.Now())
+		assert.False(t, cached)
+	})
+
+	t.Run("anonymize, to a fresh user", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		var seen []entity.UserID
+		repo.EXPECT().DeleteUser(ctx, user, gomock.Any(), "admin").Times(2).
+			DoAndReturn(func(_ context.Context, _ entity.UserID, d UserDeletion, _ string) (int64, error) {
+				assert.Equal(t, DeleteUserAnonymize, d.Policy)
+				seen = append(seen, d.AnonymousID)
+				return 2, nil
+			})
+		uc := NewSubscription(repo, WithUserDeletion(DeleteUserAnonymize))
+
+		for range 2 {
+			got, err := uc.DeleteUser(ctx, user, "admin")
+			assert.NoError(t, err)
+			assert.Equal(t, DeletedUser{Policy: DeleteUserAnonymize, Subscriptions: 2}, got)
+		}
+		assert.Len(t, seen, 2)
+		assert.False(t, seen[0].IsZero())
+		assert.NotEqual(t, user, seen[0])
+		assert.NotEqual(t, seen[0], seen[1], "every deletion gets its own anonymous ID")
+	})
+}
+
+func Test_subscription_MergeSubs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := entity.UserID(uuid.New())
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	jun := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	dec := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("err, same id", func(t *testing.T) {
+		repo := NewMockSubscriptionRepository(ctrl)
+		_, err := NewSubscription(repo).MergeSubs(context.Background(), 1, 1, "")
+		assert.ErrorIs(t, err, ErrInvalidID)
+	})
+
+	t.Run("err, different users", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		runTx(repo)
+		repo.EXPECT().GetSubByIDForUpdate(ctx, int64(1)).Return(&entity.Subscription{ID: 1, UserID: user, ServiceName: "Netflix"}, nil)
+		repo.EXPECT().GetSubByIDForUpdate(ctx, int64(2)).Return(&entity.Subscription{ID: 2, UserID: entity.UserID(uuid.New()), ServiceName: "Netflix"}, nil)
+		repo.EXPECT().MergeSubs(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		_, err := NewSubscription(repo).MergeSubs(ctx, 1, 2, "")
+		assert.ErrorIs(t, err, ErrInvalidSubscription)
+	})
+
+	t.Run("ok, spans both periods with the latest price", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		keep := &entity.Subscription{ID: 1, UserID: user, ServiceName: "Netflix", Cost: 499, DateFrom: jan, DateTo: &jun}
+		drop := &entity.Subscription{ID: 2, UserID: user, ServiceName: "netflix", Cost: 599, DateFrom: jun, DateTo: &dec}
+		runTx(repo)
+		repo.EXPECT().GetSubByIDForUpdate(ctx, int64(1)).Return(keep, nil)
+		repo.EXPECT().GetSubByIDForUpdate(ctx, int64(2)).Return(drop, nil)
+		repo.EXPECT().MergeSubs(ctx, gomock.Any(), drop, "actor").
+			DoAndReturn(func(_ context.Context, merged, _ *entity.Subscription, _ string) error {
+				assert.Equal(t, int64(1), merged.ID)
+				assert.Equal(t, int64(599), merged.Cost)
+				assert.Equal(t, jan, merged.DateFrom)
+				assert.Equal(t, dec, *merged.DateTo)
+				return nil
+			})
+		repo.EXPECT().GetSubByID(ctx, int64(1)).Return(keep, nil)
+
+		got, err := NewSubscription(repo).MergeSubs(ctx, 1, 2, "actor")
+		assert.NoError(t, err)
+		assert.Equal(t, keep, got)
+		assert.Equal(t, jun, *keep.DateTo, "stored copy is not mutated")
+	})
+
+	t.Run("ok, open end wins", func(t *testing.T) {
+		merged := mergePeriods(
+			entity.Subscription{Cost: 100, DateFrom: jun, DateTo: &dec},
+			&entity.Subscription{Cost: 200, DateFrom: jan},
+		)
+		assert.Equal(t, int64(100), merged.Cost)
+		assert.Equal(t, jan, merged.DateFrom)
+		assert.Nil(t, merged.DateTo)
+	})
+}
+
+func Test_subscription_PreviewRegisterSub(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := entity.UserID(uuid.New())
+	now := time.Date(2025, 8, 17, 10, 0, 0, 0, time.UTC)
+
+	t.Run("err, invalid", func(t *testing.T) {
+		_, err := NewSubscription(NewMockSubscriptionRepository(ctrl)).PreviewRegisterSub(context.Background(), &entity.Subscription{
+			UserID: user, ServiceName: " ", Cost: 999, DateFrom: now,
+		})
+		assert.ErrorIs(t, err, ErrInvalidSubscription)
+	})
+
+	t.Run("ok, nothing saved", func(t *testing.T) {
+		hooks := (&Hooks{}).BeforeSave(func(_ context.Context, sub *entity.Subscription) error {
+			sub.ServiceName = strings.ToUpper(sub.ServiceName)
+			return nil
+		})
+		// the mock fails on SaveSub or any other call
+		uc := NewSubscription(NewMockSubscriptionRepository(ctrl), WithHooks(hooks), WithClock(clock.NewFake(now)))
+
+		got, err := uc.PreviewRegisterSub(context.Background(), &entity.Subscription{
+			UserID: user, ServiceName: " Netflix ", Cost: 999, DateFrom: now,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, &entity.Subscription{
+			UserID:      user,
+			ServiceName: "NETFLIX",
+			Cost:        999,
+			DateFrom:    time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC),
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}, got)
+	})
+}
+
+func Test_subscription_PreviewUpdateSub(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := entity.UserID(uuid.New())
+	aug := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2025, 8, 17, 10, 0, 0, 0, time.UTC)
+	created := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)
+	version := time.Date(2025, 7, 2, 9, 0, 0, 0, time.UTC)
+	stored := &entity.Subscription{ID: 7, UserID: user, ServiceName: "Netflix", Cost: 799, DateFrom: aug, CreatedAt: created, UpdatedAt: version}
+
+	t.Run("err, invalid id", func(t *testing.T) {
+		_, err := NewSubscription(NewMockSubscriptionRepository(ctrl)).PreviewUpdateSub(context.Background(), &entity.Subscription{})
+		assert.ErrorIs(t, err, ErrInvalidID)
+	})
+
+	t.Run("err, stale version", func(t *testing.T) {
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().GetSubByID(gomock.Any(), int64(7)).Return(stored, nil)
+
+		_, err := NewSubscription(repo).PreviewUpdateSub(context.Background(), &entity.Subscription{
+			ID: 7, UserID: user, ServiceName: "Netflix", Cost: 999, DateFrom: aug, UpdatedAt: version.Add(-time.Hour),
+		})
+		assert.ErrorIs(t, err, ErrPreconditionFailed)
+	})
+
+	t.Run("ok, nothing written", func(t *testing.T) {
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().GetSubByID(gomock.Any(), int64(7)).Return(stored, nil)
+
+		got, err := NewSubscription(repo, WithClock(clock.NewFake(now))).PreviewUpdateSub(context.Background(), &entity.Subscription{
+			ID: 7, UserID: user, ServiceName: "Netflix", Cost: 999, DateFrom: now, UpdatedAt: version,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, &entity.Subscription{
+			ID: 7, UserID: user, ServiceName: "Netflix", Cost: 999, DateFrom: aug, CreatedAt: created, UpdatedAt: now,
+		}, got)
+	})
+
+	t.Run("ok, no change", func(t *testing.T) {
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().GetSubByID(gomock.Any(), int64(7)).Return(stored, nil)
+
+		got, err := NewSubscription(repo).PreviewUpdateSub(context.Background(), &entity.Subscription{
+			ID: 7, UserID: user, ServiceName: "Netflix", Cost: 799, DateFrom: aug,
+		})
+		assert.NoError(t, err)
+		assert.Same(t, stored, got)
+	})
+}
+
+func Test_subscription_RegisterSubs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := entity.UserID(uuid.New())
+	jul := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("err, one invalid saves nothing", func(t *testing.T) {
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().SaveSub(gomock.Any(), gomock.Any()).Times(0)
+
+		_, err := NewSubscription(repo).RegisterSubs(context.Background(), []*entity.Subscription{
+			{UserID: user, ServiceName: "Netflix", Cost: 999, DateFrom: jul},
+			{UserID: user, ServiceName: "", Cost: 169, DateFrom: jul},
+		})
+		assert.ErrorIs(t, err, ErrInvalidSubscription)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().SaveSub(ctx, gomock.Any()).Times(2).DoAndReturn(func(_ context.Context, s *entity.Subscription) (*entity.Subscription, error) {
+			return s, nil
+		})
+
+		got, err := NewSubscription(repo).RegisterSubs(ctx, []*entity.Subscription{
+			{UserID: user, ServiceName: "Netflix", Cost: 999, DateFrom: jul},
+			{UserID: user, ServiceName: "Spotify", Cost: 169, DateFrom: jul},
+		})
+		assert.NoError(t, err)
+		assert.Len(t, got, 2)
+	})
+}
+
+func Test_subscription_ImportSubs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := entity.UserID(uuid.New())
+	jul := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("err, a failed chunk keeps the ones before it", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().InTx(ctx, gomock.Any()).Times(2).DoAndReturn(func(ctx context.Context, fn func(context.Context) error) error {
+			return fn(ctx)
+		})
+		saved := 0
+		repo.EXPECT().SaveSub(ctx, gomock.Any()).Times(3).DoAndReturn(func(_ context.Context, s *entity.Subscription) (*entity.Subscription, error) {
+			if saved++; saved == 3 {
+				return nil, errors.New("boom")
+			}
+			return s, nil
+		})
+
+		got, err := NewSubscription(repo, WithImportLimits(ImportLimits{ChunkSize: 2})).ImportSubs(ctx, []ImportRow{
+			{Sub: &entity.Subscription{UserID: user, ServiceName: "Netflix", Cost: 999, DateFrom: jul}},
+			{Sub: &entity.Subscription{UserID: user, ServiceName: "Spotify", Cost: 169, DateFrom: jul}},
+			{Sub: &entity.Subscription{UserID: user, ServiceName: "Kinopoisk", Cost: 399, DateFrom: jul}},
+		})
+		var chunk *ImportChunkError
+		if assert.ErrorAs(t, err, &chunk) {
+			assert.Equal(t, [2]int{3, 3}, [2]int{chunk.From, chunk.To})
+		}
+		assert.Len(t, got.Created, 2)
+	})
+
+	t.Run("ok, a row confirmed again is not created twice", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().InTx(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, fn func(context.Context) error) error {
+			return fn(ctx)
+		})
+		stored := &entity.Subscription{ID: 7, UserID: user, ServiceName: "Netflix", Cost: 999, DateFrom: jul}
+		repo.EXPECT().SaveImportedSub(ctx, "k1", gomock.Any()).Return(stored, false, nil)
+		events := &stubEvents{}
+
+		got, err := NewSubscription(repo, WithEvents(events)).ImportSubs(ctx, []ImportRow{
+			{Key: "k1", Sub: &entity.Subscription{UserID: user, ServiceName: "Netflix", Cost: 999, DateFrom: jul}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []*entity.Subscription{stored}, got.Created, "the earlier subscription")
+		assert.Empty(t, events.got, "and no second event")
+	})
+
+	t.Run("err, too large", func(t *testing.T) {
+		repo := NewMockSubscriptionRepository(ctrl)
+		_, err := NewSubscription(repo, WithImportLimits(ImportLimits{MaxRows: 1})).ImportSubs(context.Background(),
+			[]ImportRow{{}, {}})
+		assert.ErrorIs(t, err, ErrImportTooLarge)
+	})
+}
+
+func Test_subscription_ProposeImport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := entity.UserID(uuid.New())
+	day := func(m time.Month, d int) time.Time { return time.Date(2025, m, d, 0, 0, 0, 0, time.UTC) }
+	txs := []importer.Transaction{
+		{Date: day(6, 3), Description: "NETFLIX.COM", Amount: 999},
+		{Date: day(7, 3), Description: "NETFLIX.COM", Amount: 999},
+		{Date: day(7, 10), Description: "SPOTIFY P1234", Amount: 169},
+		{Date: day(7, 12), Description: "MY GYM CLUB", Amount: 2500},
+	}
+
+	t.Run("err, empty user", func(t *testing.T) {
+		repo := NewMockSubscriptionRepository(ctrl)
+		_, err := NewSubscription(repo).ProposeImport(context.Background(), entity.UserID{}, txs)
+		assert.ErrorIs(t, err, entity.ErrInvalidUserID)
+	})
+
+	t.Run("ok, skips tracked and matches own services", func(t *testing.T) {
+		ctx := context.Background()
+		ended := day(5, 1)
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().ListSubsByFilter(ctx, gomock.Any()).Return([]*entity.Subscription{
+			{UserID: user, ServiceName: "netflix", DateFrom: day(1, 1)},
+			{UserID: user, ServiceName: "Spotify", DateFrom: day(1, 1), DateTo: &ended},
+			{UserID: user, ServiceName: "My Gym", DateFrom: day(1, 1), DateTo: &ended},
+		}, nil)
+
+		got, err := NewSubscription(repo).ProposeImport(ctx, user, txs)
+		assert.NoError(t, err)
+		if assert.Len(t, got.New, 2) {
+			assert.Equal(t, "My Gym", got.New[0].ServiceName)
+			assert.Equal(t, "Spotify", got.New[1].ServiceName)
+		}
+		if assert.Len(t, got.Tracked, 1) {
+			assert.Equal(t, "Netflix", got.Tracked[0].ServiceName)
+			assert.Equal(t, "netflix", got.Tracked[0].Subscription.ServiceName)
+		}
+	})
+
+	t.Run("ok, reads every page of the user's services", func(t *testing.T) {
+		ctx := context.Background()
+		first := fullPage(user, day(1, 1))
+		f := SubFilter{UserID: user, Limit: pagination.MaxLimit, IncludeDeactivated: true}
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().ListSubsByFilter(ctx, f).Return(first, nil)
+		f.After = CursorAt(first[len(first)-1])
+		repo.EXPECT().ListSubsByFilter(ctx, f).Return([]*entity.Subscription{
+			{ID: 1000, UserID: user, ServiceName: "netflix", DateFrom: day(2, 1)},
+		}, nil)
+
+		got, err := NewSubscription(repo).ProposeImport(ctx, user, txs)
+		assert.NoError(t, err)
+		if assert.Len(t, got.Tracked, 1) {
+			assert.Equal(t, "Netflix", got.Tracked[0].ServiceName)
+		}
+	})
+}
+
+// fullPage returns a page of the largest size of the user's subscriptions started at from
+func fullPage(user entity.UserID, from time.Time) []*entity.Subscription {
+	page := make([]*entity.Subscription, pagination.MaxLimit)
+	for i := range page {
+		page[i] = &entity.Subscription{ID: int64(i + 1), UserID: user, ServiceName: fmt.Sprintf("Filler %03d", i), DateFrom: from}
+	}
+	return page
+}
+
+func Test_subscription_ProposeStoreImport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	user := entity.UserID(uuid.New())
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	first := fullPage(user, jan)
+	f := SubFilter{UserID: user, Limit: pagination.MaxLimit, IncludeDeactivated: true}
+	repo := NewMockSubscriptionRepository(ctrl)
+	repo.EXPECT().ListSubsByFilter(ctx, f).Return(first, nil)
+	f.After = CursorAt(first[len(first)-1])
+	repo.EXPECT().ListSubsByFilter(ctx, f).Return([]*entity.Subscription{
+		{ID: 1000, UserID: user, ServiceName: "YouTube Premium", DateFrom: jan},
+	}, nil)
+
+	got, err := NewSubscription(repo).ProposeStoreImport(ctx, user, []importer.Proposal{
+		{ServiceName: "Bear", Cost: 149, StartDate: jan, LastCharge: jan},
+		{ServiceName: "YouTube Premium", Cost: 199, StartDate: jan, LastCharge: jan.AddDate(0, 5, 0)},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, got.New, 1) {
+		assert.Equal(t, "Bear", got.New[0].ServiceName)
+	}
+	if assert.Len(t, got.Tracked, 1) {
+		assert.Equal(t, "YouTube Premium", got.Tracked[0].Subscription.ServiceName)
+	}
+}
+
+func Test_subscription_ApplyReceipt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := entity.UserID(uuid.New())
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	receipt := importer.Receipt{ServiceName: "Netflix", Amount: 999, Date: time.Date(2025, 7, 15, 9, 0, 0, 0, time.UTC)}
+
+	t.Run("created", func(t *testing.T) {
+		ctx := context.Background()
+		ended := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().ListSubsByFilter(ctx, gomock.Any()).Return([]*entity.Subscription{
+			{ID: 1, UserID: user, ServiceName: "Netflix", Cost: 799, DateFrom: jan, DateTo: &ended},
+		}, nil)
+		repo.EXPECT().GetSettings(ctx, user).Return(nil, ErrSettingsNotFound)
+		repo.EXPECT().SaveSub(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, s *entity.Subscription) (*entity.Subscription, error) {
+			assert.Equal(t, time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), s.DateFrom)
+			assert.Equal(t, int64(999), s.Cost)
+			return s, nil
+		})
+
+		_, action, err := NewSubscription(repo).ApplyReceipt(ctx, user, receipt)
+		assert.NoError(t, err)
+		assert.Equal(t, ReceiptCreated, action)
+	})
+
+	t.Run("updated", func(t *testing.T) {
+		ctx := context.Background()
+		sub := &entity.Subscription{ID: 1, UserID: user, ServiceName: "netflix", Cost: 799, DateFrom: jan}
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().ListSubsByFilter(ctx, gomock.Any()).Return([]*entity.Subscription{sub}, nil)
+		repo.EXPECT().GetSettings(ctx, user).Return(nil, ErrSettingsNotFound)
+		repo.EXPECT().UpdateSub(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, s *entity.Subscription) error {
+			assert.Equal(t, int64(999), s.Cost)
+			return nil
+		})
+		runTx(repo)
+		repo.EXPECT().GetSubByIDForUpdate(ctx, int64(1)).Return(sub, nil)
+		repo.EXPECT().GetSubByID(ctx, int64(1)).Return(sub, nil)
+
+		_, action, err := NewSubscription(repo).ApplyReceipt(ctx, user, receipt)
+		assert.NoError(t, err)
+		assert.Equal(t, ReceiptUpdated, action)
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().ListSubsByFilter(ctx, gomock.Any()).Return([]*entity.Subscription{
+			{ID: 1, UserID: user, ServiceName: "Netflix", Cost: 999, DateFrom: jan},
+		}, nil)
+		repo.EXPECT().GetSettings(ctx, user).Return(nil, ErrSettingsNotFound)
+
+		_, action, err := NewSubscription(repo).ApplyReceipt(ctx, user, receipt)
+		assert.NoError(t, err)
+		assert.Equal(t, ReceiptUnchanged, action)
+	})
+
+	t.Run("unchanged, covered on a later page", func(t *testing.T) {
+		ctx := context.Background()
+		first := fullPage(user, jan)
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().ListSubsByFilter(ctx, SubFilter{UserID: user, Limit: pagination.MaxLimit, IncludeDeactivated: true}).Return(first, nil)
+		repo.EXPECT().ListSubsByFilter(ctx, SubFilter{
+			UserID: user, Limit: pagination.MaxLimit, IncludeDeactivated: true, After: CursorAt(first[len(first)-1]),
+		}).Return([]*entity.Subscription{
+			{ID: 1000, UserID: user, ServiceName: "Netflix", Cost: 999, DateFrom: jan},
+		}, nil)
+		repo.EXPECT().GetSettings(ctx, user).Return(nil, ErrSettingsNotFound)
+
+		_, action, err := NewSubscription(repo).ApplyReceipt(ctx, user, receipt)
+		assert.NoError(t, err)
+		assert.Equal(t, ReceiptUnchanged, action)
+	})
+
+	t.Run("month_in_user_timezone", func(t *testing.T) {
+		ctx := context.Background()
+		// 31 Jul 22:30 UTC is already 1 Aug in Moscow
+		late := importer.Receipt{ServiceName: "Spotify", Amount: 299, Date: time.Date(2025, 7, 31, 22, 30, 0, 0, time.UTC)}
+		settings := entity.DefaultSettings(user)
+		settings.Timezone = "Europe/Moscow"
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().ListSubsByFilter(ctx, gomock.Any()).Return(nil, nil)
+		repo.EXPECT().GetSettings(ctx, user).Return(&settings, nil)
+		repo.EXPECT().SaveSub(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, s *entity.Subscription) (*entity.Subscription, error) {
+			assert.Equal(t, time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC), s.DateFrom)
+			return s, nil
+		})
+
+		_, action, err := NewSubscription(repo).ApplyReceipt(ctx, user, late)
+		assert.NoError(t, err)
+		assert.Equal(t, ReceiptCreated, action)
+	})
+}
+
+func Test_subscription_EndSubscription(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := entity.UserID(uuid.New())
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	jul := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("ended", func(t *testing.T) {
+		ctx := context.Background()
+		sub := &entity.Subscription{ID: 1, UserID: user, ServiceName: "Netflix", Cost: 999, DateFrom: jan}
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().ListSubsByFilter(ctx, gomock.Any()).Return([]*entity.Subscription{sub}, nil)
+		repo.EXPECT().UpdateSub(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, s *entity.Subscription) error {
+			assert.Equal(t, &jul, s.DateTo)
+			return nil
+		})
+		runTx(repo)
+		repo.EXPECT().GetSubByIDForUpdate(ctx, int64(1)).Return(sub, nil)
+		repo.EXPECT().GetSubByID(ctx, int64(1)).Return(sub, nil)
+
+		_, action, err := NewSubscription(repo).EndSubscription(ctx, user, "netflix", jul.AddDate(0, 0, 14))
+		assert.NoError(t, err)
+		assert.Equal(t, ReceiptUpdated, action)
+	})
+
+	t.Run("already_ended", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().ListSubsByFilter(ctx, gomock.Any()).Return([]*entity.Subscription{
+			{ID: 1, UserID: user, ServiceName: "Netflix", Cost: 999, DateFrom: jan, DateTo: &jul},
+		}, nil)
+
+		_, action, err := NewSubscription(repo).EndSubscription(ctx, user, "Netflix", jul)
+		assert.NoError(t, err)
+		assert.Equal(t, ReceiptUnchanged, action)
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().ListSubsByFilter(ctx, gomock.Any()).Return([]*entity.Subscription{
+			{ID: 1, UserID: user, ServiceName: "Netflix", Cost: 999, DateFrom: jul},
+		}, nil)
+
+		_, _, err := NewSubscription(repo).EndSubscription(ctx, user, "Netflix", jan)
+		assert.ErrorIs(t, err, ErrSubscriptionNotFound)
+	})
+}
+
+func Test_subscription_Calendar(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := entity.UserID(uuid.New())
+	sep := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("err, no user", func(t *testing.T) {
+		_, err := NewSubscription(NewMockSubscriptionRepository(ctrl)).Calendar(context.Background(), entity.UserID{}, sep)
+		assert.ErrorIs(t, err, entity.ErrInvalidUserID)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().GetSettings(ctx, user).Return(nil, ErrSettingsNotFound)
+		repo.EXPECT().ListSubsByFilter(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, f SubFilter) ([]*entity.Subscription, error) {
+			assert.Equal(t, &Period{From: sep, To: sep}, f.Period)
+			return []*entity.Subscription{
+				{ID: 1, UserID: user, ServiceName: "Spotify", Cost: 299, DateFrom: jan},
+				{ID: 2, UserID: user, ServiceName: "Netflix", Cost: 999, DateFrom: jan, DateTo: &sep},
+				{ID: 3, UserID: user, ServiceName: "Кинопоиск", Cost: 399, DateFrom: sep},
+			}, nil
+		})
+
+		got, err := NewSubscription(repo).Calendar(ctx, user, time.Date(2025, 9, 17, 0, 0, 0, 0, time.UTC))
+		assert.NoError(t, err)
+		assert.Equal(t, sep, got.Month)
+		assert.Equal(t, time.Monday, got.Settings.FirstDayOfWeek)
+		kinds := make(map[string]CalendarEventKind)
+		for _, ev := range got.Events {
+			assert.Equal(t, sep, ev.Date)
+			kinds[ev.Subscription.ServiceName] = ev.Kind
+		}
+		assert.Equal(t, map[string]CalendarEventKind{
+			"Spotify":   CalendarCharge,
+			"Netflix":   CalendarFinalCharge,
+			"Кинопоиск": CalendarFirstCharge,
+		}, kinds)
+		assert.Equal(t, "Netflix", got.Events[0].Subscription.ServiceName)
+	})
+
+	t.Run("ok, charges of every page", func(t *testing.T) {
+		ctx := context.Background()
+		first := fullPage(user, jan)
+		f := SubFilter{UserID: user, Period: &Period{From: sep, To: sep}, Limit: pagination.MaxLimit}
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().GetSettings(ctx, user).Return(nil, ErrSettingsNotFound)
+		repo.EXPECT().ListSubsByFilter(ctx, f).Return(first, nil)
+		f.After = CursorAt(first[len(first)-1])
+		repo.EXPECT().ListSubsByFilter(ctx, f).Return([]*entity.Subscription{
+			{ID: 1000, UserID: user, ServiceName: "Кинопоиск", Cost: 399, DateFrom: sep},
+		}, nil)
+
+		got, err := NewSubscription(repo).Calendar(ctx, user, sep)
+		assert.NoError(t, err)
+		assert.Len(t, got.Events, len(first)+1)
+	})
+}
+
+func Test_subscription_DiffMonths(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := entity.UserID(uuid.New())
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	jun := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	jul := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	aug := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	sep := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("err, no user", func(t *testing.T) {
+		_, err := NewSubscription(NewMockSubscriptionRepository(ctrl)).DiffMonths(context.Background(), entity.UserID{}, jun, sep)
+		assert.ErrorIs(t, err, entity.ErrInvalidUserID)
+	})
+
+	t.Run("err, from after to", func(t *testing.T) {
+		_, err := NewSubscription(NewMockSubscriptionRepository(ctrl)).DiffMonths(context.Background(), user, sep, jun)
+		assert.ErrorIs(t, err, ErrInvalidPeriod)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().ListSubsByFilter(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, f SubFilter) ([]*entity.Subscription, error) {
+			assert.Equal(t, user, f.UserID)
+			assert.Equal(t, &Period{From: jun, To: sep}, f.Period)
+			return []*entity.Subscription{
+				{ID: 1, UserID: user, ServiceName: "Netflix", Cost: 799, DateFrom: jan, DateTo: &jul},
+				{ID: 2, UserID: user, ServiceName: "netflix ", Cost: 999, DateFrom: aug},
+				{ID: 3, UserID: user, ServiceName: "Spotify", Cost: 299, DateFrom: jan},
+				{ID: 4, UserID: user, ServiceName: "Okko", Cost: 399, DateFrom: jan, DateTo: &aug},
+				{ID: 5, UserID: user, ServiceName: "Кинопоиск", Cost: 499, DateFrom: sep},
+				{ID: 6, UserID: user, ServiceName: "iCloud", Cost: 99, DateFrom: jul, DateTo: &aug},
+			}, nil
+		})
+
+		got, err := NewSubscription(repo).DiffMonths(ctx, user, time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC), sep)
+		assert.NoError(t, err)
+		assert.Equal(t, MonthDiff{
+			From:      jun,
+			To:        sep,
+			FromTotal: 799 + 299 + 399,
+			ToTotal:   999 + 299 + 499,
+			Added:     []ServiceDiff{{ServiceName: "Кинопоиск", ToCost: 499}},
+			Removed:   []ServiceDiff{{ServiceName: "Okko", FromCost: 399}},
+			Changed:   []ServiceDiff{{ServiceName: "netflix ", FromCost: 799, ToCost: 999}},
+		}, got)
+	})
+}
+
+func Test_subscription_YearReview(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := entity.UserID(uuid.New())
+	month := func(y int, m time.Month) time.Time { return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC) }
+	ptr := func(t time.Time) *time.Time { return &t }
+
+	t.Run("err, no user", func(t *testing.T) {
+		_, err := NewSubscription(NewMockSubscriptionRepository(ctrl)).YearReview(context.Background(), entity.UserID{}, 2025)
+		assert.ErrorIs(t, err, entity.ErrInvalidUserID)
+	})
+
+	t.Run("err, year", func(t *testing.T) {
+		_, err := NewSubscription(NewMockSubscriptionRepository(ctrl)).YearReview(context.Background(), user, 0)
+		assert.ErrorIs(t, err, ErrInvalidPeriod)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().ListSubsByFilter(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, f SubFilter) ([]*entity.Subscription, error) {
+			assert.Equal(t, user, f.UserID)
+			assert.Equal(t, &Period{From: month(2024, time.December), To: month(2025, time.December)}, f.Period)
+			assert.True(t, f.IncludeArchived)
+			return []*entity.Subscription{
+				{ID: 1, UserID: user, ServiceName: "Netflix", Cost: 799, DateFrom: month(2024, time.January), DateTo: ptr(month(2025, time.March))},
+				{ID: 2, UserID: user, ServiceName: "netflix ", Cost: 999, DateFrom: month(2025, time.April)},
+				{ID: 3, UserID: user, ServiceName: "Spotify", Cost: 299, DateFrom: month(2024, time.June), DateTo: ptr(month(2025, time.February))},
+				{ID: 4, UserID: user, ServiceName: "Кинопоиск", Cost: 499, DateFrom: month(2025, time.November)},
+				{ID: 5, UserID: user, ServiceName: "Okko", Cost: 399, DateFrom: month(2024, time.October), DateTo: ptr(month(2024, time.December))},
+			}, nil
+		})
+
+		got, err := NewSubscription(repo).YearReview(ctx, user, 2025)
+		assert.NoError(t, err)
+
+		assert.Equal(t, 2025, got.Year)
+		if !assert.Len(t, got.Months, 12) {
+			return
+		}
+		assert.Equal(t, MonthSpend{Month: month(2025, time.January), Total: 799 + 299}, got.Months[0])
+		assert.Equal(t, MonthSpend{Month: month(2025, time.March), Total: 799}, got.Months[2])
+		assert.Equal(t, MonthSpend{Month: month(2025, time.December), Total: 999 + 499}, got.Months[11])
+		assert.Equal(t, int64(3*799+9*999+2*299+2*499), got.Total)
+
+		netflix := ServiceYear{ServiceName: "netflix ", Total: 3*799 + 9*999, First: month(2025, time.January), Last: month(2025, time.December)}
+		spotify := ServiceYear{ServiceName: "Spotify", Total: 2 * 299, First: month(2025, time.January), Last: month(2025, time.February)}
+		kinopoisk := ServiceYear{ServiceName: "Кинопоиск", Total: 2 * 499, First: month(2025, time.November), Last: month(2025, time.December)}
+		assert.Equal(t, []ServiceYear{netflix, kinopoisk, spotify}, got.Services)
+		assert.Equal(t, []PriceHike{{ServiceName: "netflix ", Month: month(2025, time.April), FromCost: 799, ToCost: 999}}, got.PriceHikes)
+		assert.Equal(t, []ServiceYear{kinopoisk}, got.New)
+		assert.Equal(t, []ServiceYear{spotify}, got.Cancelled)
+	})
+}
+
+func Test_subscription_BudgetBasis(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := entity.UserID(uuid.New())
+	now := clock.NewFake(time.Date(2025, 9, 10, 12, 0, 0, 0, time.UTC))
+
+	t.Run("err, no user", func(t *testing.T) {
+		_, err := NewSubscription(NewMockSubscriptionRepository(ctrl)).BudgetBasis(context.Background(), entity.UserID{})
+		assert.ErrorIs(t, err, entity.ErrInvalidUserID)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().GetSettings(ctx, user).Return(nil, ErrSettingsNotFound)
+		repo.EXPECT().CostGroupedByFilter(ctx, gomock.Any(), CostByService).DoAndReturn(func(_ context.Context, f SubFilter, _ CostGroupBy) ([]CostGroup, error) {
+			assert.Equal(t, &Period{From: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)}, f.Period)
+			return []CostGroup{
+				{ServiceName: "Spotify", Total: 6 * 299, Count: 1},
+				{ServiceName: "Netflix", Total: 2 * 999, Count: 1},
+			}, nil
+		})
+
+		got, err := NewSubscription(repo, WithClock(now)).BudgetBasis(ctx, user)
+		assert.NoError(t, err)
+		assert.Equal(t, BudgetBasis{
+			From: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC),
+			Services: []ServiceSpend{
+				{ServiceName: "Netflix", Average: 333},
+				{ServiceName: "Spotify", Average: 299},
+			},
+		}, got)
+	})
+}
+
+func Test_subscription_PriceBenchmarks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	user := entity.UserID(uuid.New())
+	sep := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	stats := func() []PriceBenchmark {
+		return []PriceBenchmark{
+			{Service: "netflix", Users: 6, Subscriptions: 6, AverageCost: 899, MedianCost: 799},
+			{Service: "spotify", Users: 3, Subscriptions: 4, AverageCost: 299, MedianCost: 299},
+		}
+	}
+
+	t.Run("anonymous", func(t *testing.T) {
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().PriceBenchmarks(ctx, sep, 3).Return(stats(), nil)
+
+		got, err := NewSubscription(repo, WithBenchmarkMinUsers(3)).PriceBenchmarks(ctx, time.Date(2025, 9, 20, 0, 0, 0, 0, time.UTC), entity.UserID{})
+		assert.NoError(t, err)
+		assert.Equal(t, stats(), got)
+	})
+
+	t.Run("with own cost", func(t *testing.T) {
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().PriceBenchmarks(ctx, sep, 5).Return(stats(), nil)
+		repo.EXPECT().ListSubsByFilter(ctx, gomock.Any()).Return([]*entity.Subscription{
+			{UserID: user, ServiceName: " Netflix", Cost: 999, DateFrom: sep},
+			{UserID: user, ServiceName: "NETFLIX", Cost: 199, DateFrom: sep},
+			{UserID: user, ServiceName: "Кинопоиск", Cost: 399, DateFrom: sep},
+		}, nil)
+
+		got, err := NewSubscription(repo).PriceBenchmarks(ctx, sep, user)
+		assert.NoError(t, err)
+		if assert.NotNil(t, got[0].YourCost) {
+			assert.Equal(t, int64(1198), *got[0].YourCost)
+		}
+		assert.Nil(t, got[1].YourCost)
+	})
+
+	t.Run("with own cost on a later page", func(t *testing.T) {
+		first := fullPage(user, sep)
+		f := SubFilter{UserID: user, Period: &Period{From: sep, To: sep}, Limit: pagination.MaxLimit}
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().PriceBenchmarks(ctx, sep, 5).Return(stats(), nil)
+		repo.EXPECT().ListSubsByFilter(ctx, f).Return(first, nil)
+		f.After = CursorAt(first[len(first)-1])
+		repo.EXPECT().ListSubsByFilter(ctx, f).Return([]*entity.Subscription{
+			{ID: 1000, UserID: user, ServiceName: "Spotify", Cost: 169, DateFrom: sep},
+		}, nil)
+
+		got, err := NewSubscription(repo).PriceBenchmarks(ctx, sep, user)
+		assert.NoError(t, err)
+		if assert.NotNil(t, got[1].YourCost) {
+			assert.Equal(t, int64(169), *got[1].YourCost)
+		}
+	})
+
+	t.Run("err, no month", func(t *testing.T) {
+		_, err := NewSubscription(NewMockSubscriptionRepository(ctrl)).PriceBenchmarks(ctx, time.Time{}, user)
+		assert.ErrorIs(t, err, ErrInvalidPeriod)
+	})
+}
+
+func Test_subscription_UserTotals(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	jul := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	small, big := entity.UserID(uuid.New()), entity.UserID(uuid.New())
+	repo := NewMockSubscriptionRepository(ctrl)
+	repo.EXPECT().MonthlySpendByUser(ctx, jul, jul).Return([]UserMonthSpend{
+		{UserID: small, Month: jul, Total: 300},
+		{UserID: big, Month: jul, Total: 1500},
+	}, nil)
+
+	got, err := NewSubscription(repo).UserTotals(ctx, jul.AddDate(0, 0, 20))
+	assert.NoError(t, err)
+	assert.Equal(t, []UserMonthSpend{
+		{UserID: big, Month: jul, Total: 1500},
+		{UserID: small, Month: jul, Total: 300},
+	}, got)
+
+	readModel := NewMockSubscriptionRepository(ctrl)
+	readModel.EXPECT().MonthlySpendByUser(ctx, jul, jul).Return([]UserMonthSpend{{UserID: small, Month: jul, Total: 300}}, nil)
+	got, err = NewSubscription(NewMockSubscriptionRepository(ctrl), WithAnalytics(readModel)).UserTotals(ctx, jul)
+	assert.NoError(t, err)
+	assert.Equal(t, []UserMonthSpend{{UserID: small, Month: jul, Total: 300}}, got, "served by the read model, not the repository")
+}
+
+func Test_subscription_DetectSpendAnomalies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	sep := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	jun, jul, aug := sep.AddDate(0, -3, 0), sep.AddDate(0, -2, 0), sep.AddDate(0, -1, 0)
+	spiked := entity.UserID(uuid.MustParse("11111111-1111-1111-1111-111111111111"))
+	dropped := entity.UserID(uuid.MustParse("22222222-2222-2222-2222-222222222222"))
+	steady := entity.UserID(uuid.MustParse("33333333-3333-3333-3333-333333333333"))
+	newcomer := entity.UserID(uuid.MustParse("44444444-4444-4444-4444-444444444444"))
+
+	repo := NewMockSubscriptionRepository(ctrl)
+	repo.EXPECT().MonthlySpendByUser(ctx, jun, sep).Return([]UserMonthSpend{
+		{UserID: spiked, Month: jul, Total: 400},
+		{UserID: spiked, Month: aug, Total: 600},
+		{UserID: spiked, Month: sep, Total: 1500},
+		{UserID: dropped, Month: jun, Total: 1000},
+		{UserID: dropped, Month: sep, Total: 100},
+		{UserID: steady, Month: aug, Total: 500},
+		{UserID: steady, Month: sep, Total: 700},
+		{UserID: newcomer, Month: sep, Total: 5000},
+	}, nil)
+	repo.EXPECT().ListSubsByFilter(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, f SubFilter) ([]*entity.Subscription, error) {
+		assert.Equal(t, spiked, f.UserID)
+		assert.Equal(t, &Period{From: sep, To: sep}, f.Period)
+		return []*entity.Subscription{
+			{UserID: spiked, ServiceName: "Spotify", Cost: 500, DateFrom: jul},
+			{UserID: spiked, ServiceName: "YouTube", Cost: 500, DateFrom: sep},
+			{UserID: spiked, ServiceName: "Netflix", Cost: 500, DateFrom: sep},
+		}, nil
+	})
+
+	got, err := NewSubscription(repo).DetectSpendAnomalies(ctx, time.Date(2025, 9, 12, 8, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, []SpendAnomaly{
+		{UserID: spiked, Month: sep, Baseline: 500, Projected: 1500, DeviationPercent: 200, NewServices: []string{"Netflix", "YouTube"}},
+		{UserID: dropped, Month: sep, Baseline: 1000, Projected: 100, DeviationPercent: -90},
+	}, got)
+
+	t.Run("new services on a later page", func(t *testing.T) {
+		first := fullPage(spiked, jul)
+		f := SubFilter{UserID: spiked, Period: &Period{From: sep, To: sep}, Limit: pagination.MaxLimit}
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().MonthlySpendByUser(ctx, jun, sep).Return([]UserMonthSpend{
+			{UserID: spiked, Month: jul, Total: 400},
+			{UserID: spiked, Month: aug, Total: 600},
+			{UserID: spiked, Month: sep, Total: 1500},
+		}, nil)
+		repo.EXPECT().ListSubsByFilter(ctx, f).Return(first, nil)
+		f.After = CursorAt(first[len(first)-1])
+		repo.EXPECT().ListSubsByFilter(ctx, f).Return([]*entity.Subscription{
+			{ID: 1000, UserID: spiked, ServiceName: "YouTube", Cost: 500, DateFrom: sep},
+		}, nil)
+
+		got, err := NewSubscription(repo).DetectSpendAnomalies(ctx, sep)
+		assert.NoError(t, err)
+		if assert.Len(t, got, 1) {
+			assert.Equal(t, []string{"YouTube"}, got[0].NewServices)
+		}
+	})
+
+	t.Run("threshold", func(t *testing.T) {
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().MonthlySpendByUser(ctx, aug, sep).Return([]UserMonthSpend{
+			{UserID: steady, Month: aug, Total: 500},
+			{UserID: steady, Month: sep, Total: 700},
+		}, nil)
+		repo.EXPECT().ListSubsByFilter(ctx, gomock.Any()).Return(nil, nil)
+		uc := NewSubscription(repo, WithAnomalyRules(AnomalyRules{BaselineMonths: 1, ThresholdPercent: 30}))
+		got, err := uc.DetectSpendAnomalies(ctx, sep)
+		assert.NoError(t, err)
+		assert.Equal(t, []SpendAnomaly{{UserID: steady, Month: sep, Baseline: 500, Projected: 700, DeviationPercent: 40}}, got)
+	})
+}
+
+func Test_subscription_Settings(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := entity.UserID(uuid.New())
+
+	t.Run("defaults when none saved", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().GetSettings(ctx, user).Return(nil, ErrSettingsNotFound)
+
+		got, err := NewSubscription(repo).GetSettings(ctx, user)
+		assert.NoError(t, err)
+		assert.Equal(t, entity.DefaultSettings(user), got)
+	})
+
+	t.Run("ok, empty timezone saved as utc", func(t *testing.T) {
+		ctx := context.Background()
+		s := entity.DefaultSettings(user)
+		s.Timezone = ""
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().SaveSettings(ctx, entity.DefaultSettings(user)).DoAndReturn(func(_ context.Context, s entity.Settings) (*entity.Settings, error) {
+			return &s, nil
+		})
+
+		got, err := NewSubscription(repo).UpdateSettings(ctx, s)
+		assert.NoError(t, err)
+		assert.Equal(t, "UTC", got.Timezone)
+	})
+
+	t.Run("err, invalid settings not saved", func(t *testing.T) {
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().SaveSettings(gomock.Any(), gomock.Any()).Times(0)
+
+		s := entity.DefaultSettings(user)
+		s.DateFormat = "2006"
+		_, err := NewSubscription(repo).UpdateSettings(context.Background(), s)
+		assert.ErrorIs(t, err, entity.ErrInvalidSettings)
+	})
+
+	t.Run("ok, saved", func(t *testing.T) {
+		ctx := context.Background()
+		s := entity.DefaultSettings(user)
+		s.Currency = "USD"
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().SaveSettings(ctx, s).Return(&s, nil)
+
+		got, err := NewSubscription(repo).UpdateSettings(ctx, s)
+		assert.NoError(t, err)
+		assert.Equal(t, "USD", got.Currency)
+	})
+}
+
+func Test_subscription_RefreshStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("register counts and leaves the stats to the refresher", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().SaveSub(ctx, gomock.Any()).Times(1).Return(&entity.Subscription{ID: 1}, nil)
+		repo.EXPECT().ActiveStatsByService(gomock.Any(), gomock.Any()).Times(0)
+
+		m := &stubMetrics{}
+		uc := NewSubscription(repo, WithMetrics(m))
+
+		_, err := uc.RegisterSub(ctx, &entity.Subscription{
+			UserID:      entity.UserID(uuid.New()),
+			ServiceName: "Netflix",
+			Cost:        499,
+			DateFrom:    time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, m.created)
+		assert.Nil(t, m.stats)
+	})
+
+	t.Run("refresh publishes the stats", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMockSubscriptionRepository(ctrl)
+		stats := []ServiceStats{{ServiceName: "Netflix", Active: 1, MonthlyCost: 499}}
+		repo.EXPECT().ActiveStatsByService(ctx, gomock.Any()).Times(1).Return(stats, nil)
+
+		m := &stubMetrics{}
+		assert.NoError(t, NewSubscription(repo, WithMetrics(m)).RefreshStats(ctx))
+		assert.Equal(t, stats, m.stats)
+	})
+
+	t.Run("refresh error", func(t *testing.T) {
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().ActiveStatsByService(gomock.Any(), gomock.Any()).Times(1).Return(nil, errors.New("stats err"))
+
+		assert.Error(t, NewSubscription(repo, WithMetrics(&stubMetrics{})).RefreshStats(context.Background()))
+	})
+
+	t.Run("no metrics, no query", func(t *testing.T) {
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().ActiveStatsByService(gomock.Any(), gomock.Any()).Times(0)
+
+		uc := NewSubscription(repo)
+		assert.NoError(t, uc.RefreshStats(context.Background()))
+	})
+
+	t.Run("month of the injected clock", func(t *testing.T) {
+		repo := NewMockSubscriptionRepository(ctrl)
+		repo.EXPECT().ActiveStatsByService(gomock.Any(), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)).Times(1).Return(nil, nil)
+
+		uc := NewSubscription(repo, WithMetrics(&stubMetrics{}), WithClock(clock.NewFake(time.Date(2024, 2, 29, 23, 59, 0, 0, time.UTC))))
+		assert.NoError(t, uc.RefreshStats(context.Background()))
+	})
+}
+
+func Test_subscription_ClockDateBounds(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := NewMockSubscriptionRepository(ctrl)
+	repo.EXPECT().SaveSub(gomock.Any(), gomock.Any()).Times(1).DoAndReturn(
+		func(_ context.Context, s *entity.Subscription) (*entity.Subscription, error) { return s, nil })
+	clk := clock.NewFake(time.Date(2030, 1, 15, 0, 0, 0, 0, time.UTC))
+	uc := NewSubscription(repo, WithClock(clk), WithValidationRules(ValidationRules{MaxYearsAhead: 1}))
+	sub := func() *entity.Subscription {
+		return &entity.Subscription{
+			UserID:      entity.UserID(uuid.New()),
+			ServiceName: "Netflix",
+			Cost:        499,
+			DateFrom:    time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC),
+		}
+	}
+
+	_, err := uc.RegisterSub(context.Background(), sub())
+	assert.NoError(t, err, "a year ahead of the clock, not of the wall time")
+
+	clk.Set(time.Date(2029, 12, 1, 0, 0, 0, 0, time.UTC))
+	_, err = uc.RegisterSub(context.Background(), sub())
+	assert.ErrorIs(t, err, ErrDateOutOfRange)
+	assert.Equal(t, time.Date(2029, 12, 1, 0, 0, 0, 0, time.UTC), uc.Now())
+}
+
+func Test_subscription_Hooks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx := context.Background()
+	user := entity.UserID(uuid.New())
+	stored := &entity.Subscription{ID: 1, UserID: user, ServiceName: "Netflix", Cost: 499, DateFrom: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)}
+
+	repo := NewMockSubscriptionRepository(ctrl)
+	repo.EXPECT().SaveSub(ctx, gomock.Any()).Times(1).DoAndReturn(
+		func(_ context.Context, s *entity.Subscription) (*entity.Subscription, error) {
+			assert.Equal(t, "Netflix", s.ServiceName, "before save hooks change what is stored")
+			return stored, nil
+		})
+	runTx(repo)
+	repo.EXPECT().GetSubByIDForUpdate(ctx, int64(1)).Times(1).Return(stored, nil)
+	repo.EXPECT().DeleteSub(ctx, int64(1), time.Time{}).Times(1).Return(nil)
+
+	var log []string
+	hooks := (&Hooks{}).
+		BeforeSave(func(_ context.Context, s *entity.Subscription) error {
+			if s.ServiceName == "blocked" {
+				return ErrInvalidSubscription
+			}
+			if s.ServiceName == "netflix" {
+				s.ServiceName = "Netflix"
+			}
+			return nil
+		}).
+		AfterSave(func(_ context.Context, s *entity.Subscription) { log = append(log, "saved "+s.ServiceName) }).
+		AfterDelete(func(_ context.Context, s *entity.Subscription) { log = append(log, "deleted "+s.ServiceName) })
+	uc := NewSubscription(repo, WithHooks(hooks))
+
+	_, err := uc.RegisterSub(ctx, &entity.Subscription{UserID: user, ServiceName: "blocked", Cost: 1, DateFrom: stored.DateFrom})
+	assert.ErrorIs(t, err, ErrInvalidSubscription)
+
+	_, err = uc.RegisterSub(ctx, &entity.Subscription{UserID: user, ServiceName: "netflix", Cost: 499, DateFrom: stored.DateFrom})
+	assert.NoError(t, err)
+	_, err = uc.DeleteSub(ctx, 1, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"saved Netflix", "deleted Netflix"}, log)
+}
+
+func Test_subscription_Warnings(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx := context.Background()
+	user := entity.UserID(uuid.New())
+	month := func(y int, m time.Month) time.Time { return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC) }
+	ended := month(2025, time.March)
+	saved := &entity.Subscription{ID: 3, UserID: user, ServiceName: "Netflix", Cost: 499, DateFrom: month(2025, time.June)}
+
+	repo := NewMockSubscriptionRepository(ctrl)
+	repo.EXPECT().ListSubsByFilter(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, f SubFilter) ([]*entity.Subscription, error) {
+		assert.Equal(t, user, f.UserID)
+		assert.Equal(t, "Netflix", *f.Where.ServiceContains)
+		return []*entity.Subscription{
+			{ID: 1, UserID: user, ServiceName: "netflix", Cost: 499, DateFrom: month(2025, time.January)},
+			{ID: 2, UserID: user, ServiceName: "Netflix", Cost: 499, DateFrom: month(2024, time.January), DateTo: &ended},
+			{ID: 4, UserID: user, ServiceName: "Netflix Premium", Cost: 999, DateFrom: month(2025, time.January)},
+			saved,
+		}, nil
+	}).Times(2)
+	uc := NewSubscription(repo, WithClock(clock.NewFake(time.Date(2025, time.June, 10, 0, 0, 0, 0, time.UTC))))
+
+	got, err := uc.Warnings(ctx, saved)
+	assert.NoError(t, err)
+	assert.Equal(t, []Warning{{Code: errcode.PossibleDuplicate, Message: "possible duplicate of another subscription", SubscriptionID: 1}}, got,
+		"names match case-insensitively, ended and other services do not count")
+
+	far := *saved
+	end := month(2030, time.July)
+	far.ID, far.DateTo = 5, &end
+	got, err = uc.Warnings(ctx, &far)
+	assert.NoError(t, err)
+	if assert.Len(t, got, 3, "both open Netflix subscriptions overlap it") {
+		assert.Equal(t, Warning{Code: errcode.EndDateFar, Message: "end_date is more than 5 years away"}, got[2])
+	}
+
+	name := saved.ServiceName
+	first := fullPage(user, month(2025, time.January))
+	f := SubFilter{UserID: user, Where: Conditions{ServiceContains: &name}, Limit: pagination.MaxLimit}
+	repo = NewMockSubscriptionRepository(ctrl)
+	repo.EXPECT().ListSubsByFilter(ctx, f).Return(first, nil)
+	f.After = CursorAt(first[len(first)-1])
+	repo.EXPECT().ListSubsByFilter(ctx, f).Return([]*entity.Subscription{
+		{ID: 1000, UserID: user, ServiceName: "Netflix", Cost: 499, DateFrom: month(2025, time.February)},
+		saved,
+	}, nil)
+	got, err = NewSubscription(repo, WithClock(clock.NewFake(time.Date(2025, time.June, 10, 0, 0, 0, 0, time.UTC)))).Warnings(ctx, saved)
+	assert.NoError(t, err)
+	assert.Equal(t, []Warning{{Code: errcode.PossibleDuplicate, Message: "possible duplicate of another subscription", SubscriptionID: 1000}}, got,
+		"a duplicate past the first page is found too")
+}
+
+// legacyShard is a LegacyCostStore of n legacy subscriptions
+type legacyShard struct{ n int64 }
+
+func (l *legacyShard) PendingLegacyCosts(context.Context) (int64, error) { return l.n, nil }
+
+func (l *legacyShard) BackfillLegacyCosts(_ context.Context, limit int) (int64, error) {
+	done := min(l.n, int64(limit))
+	l.n -= done
+	return done, nil
+}
+
+func Test_subscription_MigrateLegacyCosts(t *testing.T) {
+	ctx := context.Background()
+	a, b := &legacyShard{n: 2}, &legacyShard{n: 3}
+	uc := NewSubscription(nil, WithLegacyCosts(a, b))
+
+	got, err := uc.MigrateLegacyCosts(ctx, 4, true)
+	assert.NoError(t, err)
+	assert.Equal(t, LegacyCostReport{Pending: 5, Remaining: 5, DryRun: true}, got)
+	assert.EqualValues(t, 5, a.n+b.n, "a dry run changes nothing")
+
+	got, err = uc.MigrateLegacyCosts(ctx, 4, false)
+	assert.NoError(t, err)
+	assert.Equal(t, LegacyCostReport{Pending: 5, Migrated: 4, Remaining: 1}, got, "a batch spans the stores")
+	assert.False(t, got.Done())
+
+	got, err = uc.MigrateLegacyCosts(ctx, 0, false)
+	assert.NoError(t, err)
+	assert.Equal(t, LegacyCostReport{Pending: 1, Migrated: 1}, got)
+	assert.True(t, got.Done())
+
+	got, err = NewSubscription(nil).MigrateLegacyCosts(ctx, 0, false)
+	assert.NoError(t, err)
+	assert.True(t, got.Done(), "nothing to migrate without stores")
+}
+
+func Test_subscription_ResolveID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx := context.Background()
+	pid := entity.PublicID(uuid.MustParse("01920f3c-4a5b-7c6d-8e9f-a0b1c2d3e4f5"))
+
+	repo := NewMockSubscriptionRepository(ctrl)
+	repo.EXPECT().GetSubByPublicID(ctx, pid).Times(2).Return(&entity.Subscription{ID: 7, PublicID: pid}, nil)
+	repo.EXPECT().GetSubByPublicID(ctx, gomock.Not(pid)).Times(1).Return(nil, ErrSubscriptionNotFound)
+
+	serial := NewSubscription(repo)
+	id, err := serial.ResolveID(ctx, "7")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 7, id)
+	_, err = serial.ResolveID(ctx, pid.ULID())
+	assert.ErrorIs(t, err, ErrInvalidID, "serial takes numbers only")
+	assert.Empty(t, serial.PublicID(&entity.Subscription{ID: 7, PublicID: pid}))
+
+	ulid := NewSubscription(repo, WithIDStrategy(IDULID))
+	for _, raw := range []string{pid.ULID(), pid.String()} {
+		id, err := ulid.ResolveID(ctx, raw)
+		assert.NoError(t, err)
+		assert.EqualValues(t, 7, id, raw)
+	}
+	_, err = ulid.ResolveID(ctx, "7")
+	assert.ErrorIs(t, err, ErrInvalidID, "serial IDs are not accepted")
+	_, err = ulid.ResolveID(ctx, "01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	assert.ErrorIs(t, err, ErrSubscriptionNotFound)
+	assert.Equal(t, "01J87KRJJVFHPRX7X0P71D7S7N", ulid.PublicID(&entity.Subscription{ID: 7, PublicID: pid}))
+
+	hashids := NewSubscription(repo, WithIDStrategy(IDHashid), WithHashids("this is my salt", 0))
+	ref := hashids.PublicID(&entity.Subscription{ID: 12345, PublicID: pid})
+	assert.Len(t, ref, DefaultHashidMinLength)
+	id, err = hashids.ResolveID(ctx, ref)
+	assert.NoError(t, err, "decoded without the repository")
+	assert.EqualValues(t, 12345, id)
+	for _, raw := range []string{"12345", pid.ULID(), NewSubscription(repo, WithIDStrategy(IDHashid)).PublicID(&entity.Subscription{ID: 12345})} {
+		_, err = hashids.ResolveID(ctx, raw)
+		assert.ErrorIs(t, err, ErrInvalidID, raw)
+	}
+}
+
+func Test_newPublicID(t *testing.T) {
+	now := time.Date(2025, time.July, 1, 12, 0, 0, 0, time.UTC)
+
+	v7, err := newPublicID(IDUUIDv7, now)
+	assert.NoError(t, err)
+	u := uuid.UUID(v7)
+	assert.EqualValues(t, 7, u.Version())
+	assert.Equal(t, uuid.RFC4122, u.Variant())
+	sec, nsec := u.Time().UnixTime()
+	assert.Equal(t, now, time.Unix(sec, nsec).UTC(), "the timestamp leads")
+
+	a, err := newPublicID(IDULID, now)
+	assert.NoError(t, err)
+	b, err := newPublicID(IDULID, now.Add(time.Millisecond))
+	assert.NoError(t, err)
+	assert.Less(t, a.ULID(), b.ULID(), "later IDs sort after earlier ones")
+	assert.Equal(t, a.ULID()[:10], v7.ULID()[:10], "same millisecond, same time part")
+}
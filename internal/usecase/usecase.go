@@ -1,29 +1,84 @@
 package usecase
 
 import (
+	"cmp"
 	"context"
-	"errors"
+	"math"
+	"regexp"
+	"strings"
 	"time"
 
-	"github.com/go-openapi/strfmt"
-
 	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
 )
 
 //go:generate go run github.com/golang/mock/mockgen@v1.6.0 -destination=usecase_mock.go -package=usecase subs_tracker/internal/usecase SubscriptionRepository
 
 var (
-	ErrInvalidPeriod        = errors.New("invalid period")
-	ErrSubscriptionNotFound = errors.New("subscription not found")
-	ErrInvalidSubscription  = errors.New("invalid subscription")
-	ErrInvalidID            = errors.New("invalid id")
-	ErrInvalidPagination    = errors.New("invalid pagination")
+	ErrInvalidPeriod        = errcode.New(errcode.PeriodInvalid, "invalid period")
+	ErrSubscriptionNotFound = errcode.New(errcode.SubNotFound, "subscription not found")
+	ErrInvalidSubscription  = errcode.New(errcode.SubInvalid, "invalid subscription")
+	ErrInvalidID            = errcode.New(errcode.IDInvalid, "invalid id")
+	ErrInvalidPagination    = errcode.New(errcode.PaginationInvalid, "invalid pagination")
+	ErrPreconditionFailed   = errcode.New(errcode.SubModified, "subscription was modified concurrently")
+	ErrSettingsNotFound     = errcode.New(errcode.SettingsNotFound, "settings not found")
+	ErrDateOutOfRange       = errcode.New(errcode.DateOutOfRange, "date out of range")
+	ErrUserNotEmpty         = errcode.New(errcode.UserNotEmpty, "user already has subscriptions")
+	ErrInvalidGroupBy       = errcode.New(errcode.GroupByInvalid, "invalid group by")
+	ErrInvalidAdjustment    = errcode.New(errcode.AdjustmentInvalid, "invalid adjustment")
+	ErrInvalidSeats         = errcode.New(errcode.SeatsInvalid, "invalid seats")
+	ErrUserHasActiveSubs    = errcode.New(errcode.UserHasActiveSubs, "user has active subscriptions")
+	ErrDuplicate            = errcode.New(errcode.Duplicate, "already exists")
+	ErrMissingReference     = errcode.New(errcode.ReferenceMissing, "refers to a missing record")
+	ErrConstraint           = errcode.New(errcode.ConstraintViolated, "violates a storage constraint")
 )
 
-const (
-	defaultListLimit = 50
-	maxListLimit     = 200
-)
+// ConstraintError — a write the storage refused for breaking one of its constraints. It matches Kind and the
+// error of the storage in errors.Is and errors.As
+type ConstraintError struct {
+	// Kind - ErrDuplicate, ErrMissingReference or ErrConstraint
+	Kind error
+	// Field - the API field the constraint is on, empty when the storage does not tell
+	Field string
+	// Constraint - name of the constraint in the storage
+	Constraint string
+	err        error
+}
+
+// NewConstraintError creates a ConstraintError of kind caused by err
+func NewConstraintError(kind error, field, constraint string, err error) *ConstraintError {
+	return &ConstraintError{Kind: kind, Field: field, Constraint: constraint, err: err}
+}
+
+func (e *ConstraintError) Error() string {
+	if e.Field == "" {
+		return e.Kind.Error()
+	}
+	return e.Field + ": " + e.Kind.Error()
+}
+
+func (e *ConstraintError) Unwrap() []error {
+	if e.err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.err}
+}
+
+// ValidationRules — configurable business limits applied on top of the built-in checks
+type ValidationRules struct {
+	// MaxCost - upper bound of the monthly cost, 0 disables the check
+	MaxCost int64
+	// MaxPeriodMonths - longest allowed start_date..end_date span in months, 0 disables the check
+	MaxPeriodMonths int
+	// ServiceName - pattern service names must match, nil allows any name
+	ServiceName *regexp.Regexp
+	// EndDateRequired - services (case-insensitive) that must be stored with an end_date
+	EndDateRequired []string
+	// EarliestDate - dates before this month are rejected as typos, zero disables the check
+	EarliestDate time.Time
+	// MaxYearsAhead - dates further than this many years from now are rejected, 0 disables the check
+	MaxYearsAhead int
+}
 
 // Period — period od subscription
 type Period struct {
@@ -36,7 +91,7 @@ type Period struct {
 // SubFilter — common filter for queries/aggregations
 type SubFilter struct {
 	// UserID - ID of the user to filter by
-	UserID strfmt.UUID
+	UserID entity.UserID
 	// ServiceName - service name to filter by
 	ServiceName *string
 	// Period - period to filter by
@@ -45,20 +100,513 @@ type SubFilter struct {
 	Limit int
 	// Offset - result set offset
 	Offset int
+	// After - keyset position to continue listing from, mutually exclusive with Offset
+	After *ListCursor
+	// UpdatedSince - only subscriptions written strictly after this instant
+	UpdatedSince *time.Time
+	// IncludeArchived - also list subscriptions moved to the archive, see WithArchive
+	IncludeArchived bool
+	// Where - further conditions of the list on subscription fields
+	Where Conditions
+	// IncludeDeactivated - also list subscriptions of deactivated users; costs and other aggregates always
+	// leave them out
+	IncludeDeactivated bool
+}
+
+// Conditions — bounds on subscription fields narrowing a list, e.g. from the ?filter= expression; nil bounds
+// do not apply, and bounds that exclude each other leave the list empty
+type Conditions struct {
+	// CostMin, CostMax - inclusive bounds of the monthly cost
+	CostMin, CostMax *int64
+	// ServiceContains - part of the service name, matched case-insensitively
+	ServiceContains *string
+	// StartFrom, StartTo - inclusive bounds of the start month
+	StartFrom, StartTo *time.Time
+}
+
+// Match reports whether s meets every set condition
+func (c Conditions) Match(s *entity.Subscription) bool {
+	switch {
+	case c.CostMin != nil && s.Cost < *c.CostMin, c.CostMax != nil && s.Cost > *c.CostMax:
+		return false
+	case c.ServiceContains != nil && !strings.Contains(strings.ToLower(s.ServiceName), strings.ToLower(*c.ServiceContains)):
+		return false
+	case c.StartFrom != nil && s.DateFrom.Before(*c.StartFrom), c.StartTo != nil && s.DateFrom.After(*c.StartTo):
+		return false
+	}
+	return true
+}
+
+// ListCursor — keyset position of the last listed subscription in (start_date, service_name, id) order
+type ListCursor struct {
+	// StartDate - start date of the last listed subscription
+	StartDate time.Time `json:"d"`
+	// ServiceName - service name of the last listed subscription
+	ServiceName string `json:"s"`
+	// ID - ID of the last listed subscription
+	ID int64 `json:"i"`
+}
+
+// CursorAt returns the keyset position of s, to continue a list right after it
+func CursorAt(s *entity.Subscription) *ListCursor {
+	return &ListCursor{StartDate: s.DateFrom, ServiceName: s.ServiceName, ID: s.ID}
+}
+
+// CompareListOrder compares subscriptions the way every list and cursor orders them: by start date, then by
+// service name compared bytewise (the C collation in Postgres), then by ID. IDs are unique, so no two
+// subscriptions tie and keyset pages never skip or repeat a row, however many share a start date and service
+func CompareListOrder(a, b *entity.Subscription) int {
+	if c := a.DateFrom.Compare(b.DateFrom); c != 0 {
+		return c
+	}
+	if c := strings.Compare(a.ServiceName, b.ServiceName); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.ID, b.ID)
+}
+
+// ServiceStats — aggregated active subscriptions of a single service
+type ServiceStats struct {
+	// ServiceName - name of the service
+	ServiceName string
+	// Active - number of subscriptions active in the month
+	Active int64
+	// MonthlyCost - summed monthly cost of the active subscriptions
+	MonthlyCost int64
+}
+
+// PriceBenchmark — anonymous cost statistics of one service across users who opted in
+type PriceBenchmark struct {
+	// Service - canonical service name: trimmed and lower-cased
+	Service string
+	// Users - distinct users contributing to the statistics
+	Users int64
+	// Subscriptions - subscriptions contributing to the statistics
+	Subscriptions int64
+	// AverageCost - mean monthly cost, rounded
+	AverageCost int64
+	// MedianCost - median monthly cost, rounded
+	MedianCost int64
+	// YourCost - summed cost of the asking user's subscriptions of the service, nil when none or not asked
+	YourCost *int64
+}
+
+// UserMonthSpend — summed cost of one user's subscriptions active in a month
+type UserMonthSpend struct {
+	// UserID - owner of the subscriptions
+	UserID entity.UserID
+	// Month - first day of the month
+	Month time.Time
+	// Total - summed monthly cost
+	Total int64
+}
+
+// CostGroupBy — dimension the cost of matching subscriptions is broken down by
+type CostGroupBy string
+
+const (
+	// CostByService - one group per service name
+	CostByService CostGroupBy = "service"
+	// CostByUser - one group per user
+	CostByUser CostGroupBy = "user"
+	// CostByMonth - one group per month of the period
+	CostByMonth CostGroupBy = "month"
+	// CostByWeek - one group per ISO week holding a monthly charge, which falls on the 1st of the month
+	CostByWeek CostGroupBy = "week"
+	// CostByYear - one group per calendar year of the period
+	CostByYear CostGroupBy = "year"
+)
+
+// Valid reports whether the dimension is one of the supported ones
+func (by CostGroupBy) Valid() bool {
+	switch by {
+	case CostByService, CostByUser, CostByMonth, CostByWeek, CostByYear:
+		return true
+	}
+	return false
+}
+
+// Temporal reports whether the groups of the dimension are periods of time, their start in CostGroup.Month
+func (by CostGroupBy) Temporal() bool {
+	return by == CostByMonth || by == CostByWeek || by == CostByYear
+}
+
+// PeriodStart returns the first day of the week (Monday) or year t falls in, for CostByWeek and CostByYear,
+// and the first day of its month otherwise
+func (by CostGroupBy) PeriodStart(t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch by {
+	case CostByWeek:
+		return time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location())
+	case CostByYear:
+		return time.Date(y, time.January, 1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+}
+
+// CostGroup — summed cost of the matching subscriptions sharing one value of the grouped dimension; only the
+// field of that dimension is set
+type CostGroup struct {
+	// ServiceName - the service, when grouped by service
+	ServiceName string
+	// UserID - owner of the subscriptions, when grouped by user
+	UserID entity.UserID
+	// Month - first day of the month, week or year, when grouped by time, see CostGroupBy.PeriodStart
+	Month time.Time
+	// Total - summed monthly cost within the period plus the adjustments of the group
+	Total int64
+	// Count - subscriptions contributing to Total, 0 for a group holding adjustments only
+	Count int64
+}
+
+// Key returns the value of the grouped dimension as a string that sorts in group order
+func (g CostGroup) Key(by CostGroupBy) string {
+	switch by {
+	case CostByUser:
+		return g.UserID.String()
+	case CostByMonth, CostByWeek, CostByYear:
+		return g.Month.Format(time.DateOnly)
+	}
+	return g.ServiceName
+}
+
+// DataVersion — version of the data behind the cost reports of a user, or of all users
+type DataVersion struct {
+	// Version - changes with every write to the subscriptions, adjustments, seats, settings or deactivation of
+	// the users, deletes included; 0 when they have none
+	Version int64
+	// ChangedAt - when the last of those writes was made, zero when there were none
+	ChangedAt time.Time
+}
+
+// CostSummary — aggregates of the subscriptions matching a filter over its period
+type CostSummary struct {
+	// Total - summed cost of every month of the period net of adjustments, as CostSubsByFilter
+	Total int64
+	// Count - matching subscriptions
+	Count int64
+	// MonthlyCost - summed monthly cost of the matching subscriptions, each counted once
+	MonthlyCost int64
+	// MinCost - lowest monthly cost, 0 when none match
+	MinCost int64
+	// MaxCost - highest monthly cost, 0 when none match
+	MaxCost int64
+	// Seats - seats the user of the filter holds in plans paid by other users, set by
+	// Subscription.CostSummaryByFilter only when the filter has a user; they are not part of the other fields
+	Seats []SeatShare
+}
+
+// AverageCost returns the mean monthly cost of the matching subscriptions, rounded, 0 when none match
+func (s CostSummary) AverageCost() int64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return int64(math.Round(float64(s.MonthlyCost) / float64(s.Count)))
+}
+
+// SeatShareTotal returns the summed monthly share of the seats
+func (s CostSummary) SeatShareTotal() int64 {
+	var total int64
+	for _, seat := range s.Seats {
+		total += seat.Share()
+	}
+	return total
+}
+
+// SeatShare — a seat a user holds in a shared plan paid by another user
+type SeatShare struct {
+	// SubscriptionID - ID of the shared subscription
+	SubscriptionID int64
+	// PublicID - public ID of the shared subscription
+	PublicID    entity.PublicID
+	ServiceName string
+	// Cost - monthly cost of the whole plan
+	Cost int64
+	// Seats - seats of the plan including the owner's
+	Seats int
+}
+
+// Share returns the monthly cost of the seat, see entity.Seats.Share
+func (s SeatShare) Share() int64 {
+	seats := entity.Seats{Total: s.Seats}
+	return seats.Share(s.Cost)
+}
+
+// SharedPlan — a subscription together with the assignment of its seats
+type SharedPlan struct {
+	Subscription *entity.Subscription
+	Seats        entity.Seats
+}
+
+// Share returns the monthly cost of one seat of the plan
+func (p SharedPlan) Share() int64 {
+	return p.Seats.Share(p.Subscription.Cost)
+}
+
+// AnomalyRules — when a user's monthly spend counts as unexpected
+type AnomalyRules struct {
+	// BaselineMonths - how many previous months the baseline averages
+	BaselineMonths int
+	// ThresholdPercent - deviation from the baseline, in percent, that raises an anomaly
+	ThresholdPercent float64
+}
+
+// SpendAnomaly — a user whose projected month deviates from their baseline spend
+type SpendAnomaly struct {
+	// UserID - owner of the subscriptions
+	UserID entity.UserID
+	// Month - first day of the checked month
+	Month time.Time
+	// Baseline - average monthly spend over the baseline months with any spend
+	Baseline int64
+	// Projected - spend of the checked month
+	Projected int64
+	// DeviationPercent - (Projected - Baseline) / Baseline in percent, negative for a drop
+	DeviationPercent float64
+	// NewServices - services first charged in the checked month
+	NewServices []string
+}
+
+// ChangeSet — subscriptions touched since a change log position, collapsed per subscription
+type ChangeSet struct {
+	// Created - subscriptions created after the position and still present
+	Created []ChangedSub
+	// Updated - subscriptions created before the position and modified after it
+	Updated []ChangedSub
+	// Deleted - subscriptions created before the position and removed after it
+	Deleted []ChangedSub
+	// Next - change log position to resume from
+	Next entity.ChangePos
+	// HasMore - more changes are available after Next
+	HasMore bool
+}
+
+// ChangedSub — a subscription of a ChangeSet by both of its IDs, as deleted ones can no longer be read
+type ChangedSub struct {
+	ID       int64
+	PublicID entity.PublicID
+}
+
+// ReceiptAction — what applying a receipt did to the user's subscriptions
+type ReceiptAction string
+
+const (
+	// ReceiptCreated - no subscription covered the charge, a new one was created
+	ReceiptCreated ReceiptAction = "created"
+	// ReceiptUpdated - the covering subscription got the charged price
+	ReceiptUpdated ReceiptAction = "updated"
+	// ReceiptUnchanged - the covering subscription already has the charged price
+	ReceiptUnchanged ReceiptAction = "unchanged"
+)
+
+// CalendarEventKind — which charge of a subscription a calendar event is
+type CalendarEventKind string
+
+const (
+	// CalendarFirstCharge - the subscription starts this month
+	CalendarFirstCharge CalendarEventKind = "first_charge"
+	// CalendarCharge - a regular monthly renewal
+	CalendarCharge CalendarEventKind = "charge"
+	// CalendarFinalCharge - the subscription ends this month
+	CalendarFinalCharge CalendarEventKind = "final_charge"
+)
+
+// CalendarEvent — a charge of a subscription on a given day
+type CalendarEvent struct {
+	// Date - day of the charge
+	Date time.Time
+	// Kind - first, regular or final charge
+	Kind CalendarEventKind
+	// Subscription - the charged subscription
+	Subscription *entity.Subscription
+}
+
+// Calendar — charge events of one user's month, ordered by day and service name
+type Calendar struct {
+	// Month - first day of the month
+	Month time.Time
+	// Settings - user settings the calendar is rendered with (first day of week, currency)
+	Settings entity.Settings
+	// Events - charges within the month
+	Events []CalendarEvent
+}
+
+// ServiceDiff — monthly cost of one service in both months of a MonthDiff, 0 in a month it was not paid
+type ServiceDiff struct {
+	// ServiceName - name as stored on the latest started subscription to the service
+	ServiceName string
+	FromCost    int64
+	ToCost      int64
+}
+
+// MonthDiff — how one user's subscriptions changed between two months; services are compared by name without
+// case and surrounding spaces and ordered by name
+type MonthDiff struct {
+	// From, To - first days of the compared months
+	From time.Time
+	To   time.Time
+	// FromTotal, ToTotal - monthly cost of all subscriptions in each month
+	FromTotal int64
+	ToTotal   int64
+	// Added - services paid in To but not in From
+	Added []ServiceDiff
+	// Removed - services paid in From but not in To
+	Removed []ServiceDiff
+	// Changed - services paid in both months at a different cost
+	Changed []ServiceDiff
+}
+
+// BudgetMonths - months of history budget recommendations average the spend over
+const BudgetMonths = 6
+
+// ServiceSpend — average monthly spend on one service
+type ServiceSpend struct {
+	ServiceName string
+	// Average - spend over the window divided by its months, rounded up; months without the service count
+	Average int64
+}
+
+// BudgetBasis — a user's average monthly spend per service over the BudgetMonths full months before the
+// current one, the basis of recommended budgets
+type BudgetBasis struct {
+	// From, To - first and last month of the window
+	From time.Time
+	To   time.Time
+	// Services - ordered by name
+	Services []ServiceSpend
+}
+
+// SubscriptionEventType — kind of a subscription write published to event sinks
+type SubscriptionEventType string
+
+const (
+	// EventSubscriptionCreated - a subscription was stored
+	EventSubscriptionCreated SubscriptionEventType = "subscription.created"
+	// EventSubscriptionUpdated - a stored subscription was changed
+	EventSubscriptionUpdated SubscriptionEventType = "subscription.updated"
+	// EventSubscriptionDeleted - a subscription was removed
+	EventSubscriptionDeleted SubscriptionEventType = "subscription.deleted"
+)
+
+// SubscriptionEvent — a successful write of a single subscription
+type SubscriptionEvent struct {
+	// Type - what happened
+	Type SubscriptionEventType
+	// OccurredAt - when the write completed
+	OccurredAt time.Time
+	// Subscription - the record after the write, or the removed record for deletes
+	Subscription *entity.Subscription
+	// Previous - the record before the write, for updates
+	Previous *entity.Subscription
+	// PublicID - the identifier clients know the subscription by, empty with IDSerial; sinks show it instead
+	// of the serial ID when set
+	PublicID string
+	// Tenant - the tenant whose schema the write went to, empty for the shared one; subscribers storing the
+	// event work in the same schema
+	Tenant string
 }
 
 // SubscriptionRepository — CRUD for subscriptions plus queries/aggregations
 type SubscriptionRepository interface {
 	// SaveSub - save a subscription
 	SaveSub(ctx context.Context, s *entity.Subscription) (*entity.Subscription, error)
-	// UpdateSub -  update subscription data
+	// SaveImportedSub - save a subscription confirmed from an import under key, or get the one saved under key
+	// before, reporting whether it was created; inside InTx the key is stored in the same transaction
+	SaveImportedSub(ctx context.Context, key string, s *entity.Subscription) (*entity.Subscription, bool, error)
+	// UpdateSub -  update subscription data, only if still at s.UpdatedAt when it is set
 	UpdateSub(ctx context.Context, s *entity.Subscription) error
-	// DeleteSub - delete a subscription
-	DeleteSub(ctx context.Context, id int64) error
+	// DeleteSub - delete a subscription, only if still at version when it is non-zero
+	DeleteSub(ctx context.Context, id int64, version time.Time) error
 	// GetSubByID -  get a subscription by ID
 	GetSubByID(ctx context.Context, id int64) (*entity.Subscription, error)
+	// GetSubByIDForUpdate - get a subscription by ID, keeping other writers of it waiting until the InTx transaction of ctx ends
+	GetSubByIDForUpdate(ctx context.Context, id int64) (*entity.Subscription, error)
+	// InTx - run fn in a single transaction joined by the calls made with its ctx, rolled back when fn returns an error
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+	// GetSubByPublicID - get a subscription by its public ID, ErrSubscriptionNotFound when there is none
+	GetSubByPublicID(ctx context.Context, id entity.PublicID) (*entity.Subscription, error)
 	// ListSubsByFilter - list subscriptions using SubFilter
 	ListSubsByFilter(ctx context.Context, f SubFilter) ([]*entity.Subscription, error)
-	// CostSubsByFilter -  get total subscription cost using SubFilter
+	// CostSubsByFilter -  get total subscription cost using SubFilter, net of adjustments in the period
 	CostSubsByFilter(ctx context.Context, f SubFilter) (int64, error)
+	// CostGroupedByFilter - get the total cost of paid subscriptions matching SubFilter per value of by, net of adjustments, ordered by CostGroup.Key
+	CostGroupedByFilter(ctx context.Context, f SubFilter, by CostGroupBy) ([]CostGroup, error)
+	// CostSummaryByFilter - get the total (net of adjustments), count and monthly cost aggregates of paid subscriptions matching SubFilter
+	CostSummaryByFilter(ctx context.Context, f SubFilter) (CostSummary, error)
+	// DataVersion - get the version of the data of the user, of all users when user is zero
+	DataVersion(ctx context.Context, user entity.UserID) (DataVersion, error)
+	// ActiveStatsByService - get active subscriptions per service for the month
+	ActiveStatsByService(ctx context.Context, month time.Time) ([]ServiceStats, error)
+	// PriceBenchmarks - get per-service cost statistics of paid subscriptions of the month over opted-in users, omitting services with fewer than minUsers
+	PriceBenchmarks(ctx context.Context, month time.Time, minUsers int) ([]PriceBenchmark, error)
+	// MonthlySpendByUser - get per-user spend of every month in [from, to] that has any
+	MonthlySpendByUser(ctx context.Context, from, to time.Time) ([]UserMonthSpend, error)
+	// ChangesSince - get change log entries of the user's subscriptions after the since position, oldest first,
+	// leaving out the transactions that may still commit
+	ChangesSince(ctx context.Context, user entity.UserID, since entity.ChangePos, limit int) ([]entity.SubscriptionChange, error)
+	// ReassignUser - move all subscriptions between users atomically, recording who did it
+	ReassignUser(ctx context.Context, from, to entity.UserID, actor string) (int64, error)
+	// MergeSubs - store merged and remove dropped atomically, archiving dropped in the audit log
+	MergeSubs(ctx context.Context, merged, dropped *entity.Subscription, actor string) error
+	// EndedBefore - get up to limit subscriptions that ended before the month, oldest IDs first
+	EndedBefore(ctx context.Context, before time.Time, limit int) ([]*entity.Subscription, error)
+	// PurgeSubs - delete subscriptions by ID unconditionally, e.g. once they are archived
+	PurgeSubs(ctx context.Context, ids []int64) (int64, error)
+	// SaveAdjustment - store a refund or other adjustment of an existing subscription
+	SaveAdjustment(ctx context.Context, a *entity.Adjustment) (*entity.Adjustment, error)
+	// ListAdjustments - get the adjustments of the subscription, oldest month first
+	ListAdjustments(ctx context.Context, subID int64) ([]entity.Adjustment, error)
+	// SaveSeats - create or replace the seats of an existing subscription, removing them when Total is 0
+	SaveSeats(ctx context.Context, s *entity.Seats) (*entity.Seats, error)
+	// GetSeats - get the seats of the subscription, nil when it is not shared
+	GetSeats(ctx context.Context, subID int64) (*entity.Seats, error)
+	// SeatSharesByFilter - get the seats SubFilter.UserID holds in shared plans matching the service and overlapping the period, ordered by service name and ID
+	SeatSharesByFilter(ctx context.Context, f SubFilter) ([]SeatShare, error)
+	// GetSettings - get saved settings of the user, ErrSettingsNotFound if there are none
+	GetSettings(ctx context.Context, userID entity.UserID) (*entity.Settings, error)
+	// SaveSettings - create or replace settings of the user
+	SaveSettings(ctx context.Context, s entity.Settings) (*entity.Settings, error)
+	// DeactivateUser - mark the user deactivated as of at, keeping an earlier mark; returns the mark in effect
+	DeactivateUser(ctx context.Context, userID entity.UserID, at time.Time) (time.Time, error)
+	// ReactivateUser - remove the deactivation mark of the user, if any
+	ReactivateUser(ctx context.Context, userID entity.UserID) error
+	// UserDeactivatedAt - get when the user was deactivated, zero time for an active user
+	UserDeactivatedAt(ctx context.Context, userID entity.UserID) (time.Time, error)
+	// DeleteUser - remove the user as d says in a single transaction, recording who did it; returns how many subscriptions were deleted or anonymized
+	DeleteUser(ctx context.Context, userID entity.UserID, d UserDeletion, actor string) (int64, error)
+}
+
+// SubscriptionEvents — sink for subscription writes; Publish must not block the caller
+type SubscriptionEvents interface {
+	// Publish - hand over an event for asynchronous delivery
+	Publish(ctx context.Context, e SubscriptionEvent)
+}
+
+// AnalyticsReader — source of aggregated reads, such as a read model kept apart from the subscriptions table
+type AnalyticsReader interface {
+	// MonthlySpendByUser - get per-user spend of every month in [from, to] that has any
+	MonthlySpendByUser(ctx context.Context, from, to time.Time) ([]UserMonthSpend, error)
+}
+
+// ArchiveReader — source of subscriptions moved out of the database into cold storage
+type ArchiveReader interface {
+	// Archived - get archived subscriptions matching the filter (ignoring Limit and Offset) in list order
+	Archived(ctx context.Context, f SubFilter) ([]*entity.Subscription, error)
+}
+
+// LegacyCostStore — storage holding subscriptions whose cost predates currencies: whole rubles without
+// the currency and minor-unit cost columns
+type LegacyCostStore interface {
+	// PendingLegacyCosts - count subscriptions still holding only the legacy cost
+	PendingLegacyCosts(ctx context.Context) (int64, error)
+	// BackfillLegacyCosts - fill the currency and minor-unit cost of up to limit legacy subscriptions, lowest IDs first
+	BackfillLegacyCosts(ctx context.Context, limit int) (int64, error)
+}
+
+// SubscriptionMetrics — sink for domain metrics about subscriptions
+type SubscriptionMetrics interface {
+	// SubCreated - count a newly created subscription
+	SubCreated()
+	// SetStats - publish the current per-service statistics
+	SetStats(stats []ServiceStats)
 }
@@ -8,6 +8,7 @@ import (
 	context "context"
 	reflect "reflect"
 	entity "subs_tracker/internal/entity"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -35,6 +36,51 @@ func (m *MockSubscriptionRepository) EXPECT() *MockSubscriptionRepositoryMockRec
 	return m.recorder
 }
 
+// ActiveStatsByService mocks base method.
+func (m *MockSubscriptionRepository) ActiveStatsByService(arg0 context.Context, arg1 time.Time) ([]ServiceStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ActiveStatsByService", arg0, arg1)
+	ret0, _ := ret[0].([]ServiceStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ActiveStatsByService indicates an expected call of ActiveStatsByService.
+func (mr *MockSubscriptionRepositoryMockRecorder) ActiveStatsByService(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ActiveStatsByService", reflect.TypeOf((*MockSubscriptionRepository)(nil).ActiveStatsByService), arg0, arg1)
+}
+
+// ChangesSince mocks base method.
+func (m *MockSubscriptionRepository) ChangesSince(arg0 context.Context, arg1 entity.UserID, arg2 entity.ChangePos, arg3 int) ([]entity.SubscriptionChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangesSince", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]entity.SubscriptionChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangesSince indicates an expected call of ChangesSince.
+func (mr *MockSubscriptionRepositoryMockRecorder) ChangesSince(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangesSince", reflect.TypeOf((*MockSubscriptionRepository)(nil).ChangesSince), arg0, arg1, arg2, arg3)
+}
+
+// CostGroupedByFilter mocks base method.
+func (m *MockSubscriptionRepository) CostGroupedByFilter(arg0 context.Context, arg1 SubFilter, arg2 CostGroupBy) ([]CostGroup, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CostGroupedByFilter", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]CostGroup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CostGroupedByFilter indicates an expected call of CostGroupedByFilter.
+func (mr *MockSubscriptionRepositoryMockRecorder) CostGroupedByFilter(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CostGroupedByFilter", reflect.TypeOf((*MockSubscriptionRepository)(nil).CostGroupedByFilter), arg0, arg1, arg2)
+}
+
 // CostSubsByFilter mocks base method.
 func (m *MockSubscriptionRepository) CostSubsByFilter(arg0 context.Context, arg1 SubFilter) (int64, error) {
 	m.ctrl.T.Helper()
@@ -50,18 +96,123 @@ func (mr *MockSubscriptionRepositoryMockRecorder) CostSubsByFilter(arg0, arg1 in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CostSubsByFilter", reflect.TypeOf((*MockSubscriptionRepository)(nil).CostSubsByFilter), arg0, arg1)
 }
 
+// CostSummaryByFilter mocks base method.
+func (m *MockSubscriptionRepository) CostSummaryByFilter(arg0 context.Context, arg1 SubFilter) (CostSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CostSummaryByFilter", arg0, arg1)
+	ret0, _ := ret[0].(CostSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CostSummaryByFilter indicates an expected call of CostSummaryByFilter.
+func (mr *MockSubscriptionRepositoryMockRecorder) CostSummaryByFilter(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CostSummaryByFilter", reflect.TypeOf((*MockSubscriptionRepository)(nil).CostSummaryByFilter), arg0, arg1)
+}
+
+// DataVersion mocks base method.
+func (m *MockSubscriptionRepository) DataVersion(arg0 context.Context, arg1 entity.UserID) (DataVersion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DataVersion", arg0, arg1)
+	ret0, _ := ret[0].(DataVersion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DataVersion indicates an expected call of DataVersion.
+func (mr *MockSubscriptionRepositoryMockRecorder) DataVersion(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DataVersion", reflect.TypeOf((*MockSubscriptionRepository)(nil).DataVersion), arg0, arg1)
+}
+
+// DeactivateUser mocks base method.
+func (m *MockSubscriptionRepository) DeactivateUser(arg0 context.Context, arg1 entity.UserID, arg2 time.Time) (time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeactivateUser", arg0, arg1, arg2)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeactivateUser indicates an expected call of DeactivateUser.
+func (mr *MockSubscriptionRepositoryMockRecorder) DeactivateUser(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeactivateUser", reflect.TypeOf((*MockSubscriptionRepository)(nil).DeactivateUser), arg0, arg1, arg2)
+}
+
 // DeleteSub mocks base method.
-func (m *MockSubscriptionRepository) DeleteSub(arg0 context.Context, arg1 int64) error {
+func (m *MockSubscriptionRepository) DeleteSub(arg0 context.Context, arg1 int64, arg2 time.Time) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteSub", arg0, arg1)
+	ret := m.ctrl.Call(m, "DeleteSub", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteSub indicates an expected call of DeleteSub.
-func (mr *MockSubscriptionRepositoryMockRecorder) DeleteSub(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockSubscriptionRepositoryMockRecorder) DeleteSub(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSub", reflect.TypeOf((*MockSubscriptionRepository)(nil).DeleteSub), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSub", reflect.TypeOf((*MockSubscriptionRepository)(nil).DeleteSub), arg0, arg1, arg2)
+}
+
+// DeleteUser mocks base method.
+func (m *MockSubscriptionRepository) DeleteUser(arg0 context.Context, arg1 entity.UserID, arg2 UserDeletion, arg3 string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUser", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteUser indicates an expected call of DeleteUser.
+func (mr *MockSubscriptionRepositoryMockRecorder) DeleteUser(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockSubscriptionRepository)(nil).DeleteUser), arg0, arg1, arg2, arg3)
+}
+
+// EndedBefore mocks base method.
+func (m *MockSubscriptionRepository) EndedBefore(arg0 context.Context, arg1 time.Time, arg2 int) ([]*entity.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EndedBefore", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]*entity.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EndedBefore indicates an expected call of EndedBefore.
+func (mr *MockSubscriptionRepositoryMockRecorder) EndedBefore(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EndedBefore", reflect.TypeOf((*MockSubscriptionRepository)(nil).EndedBefore), arg0, arg1, arg2)
+}
+
+// GetSeats mocks base method.
+func (m *MockSubscriptionRepository) GetSeats(arg0 context.Context, arg1 int64) (*entity.Seats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSeats", arg0, arg1)
+	ret0, _ := ret[0].(*entity.Seats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSeats indicates an expected call of GetSeats.
+func (mr *MockSubscriptionRepositoryMockRecorder) GetSeats(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSeats", reflect.TypeOf((*MockSubscriptionRepository)(nil).GetSeats), arg0, arg1)
+}
+
+// GetSettings mocks base method.
+func (m *MockSubscriptionRepository) GetSettings(arg0 context.Context, arg1 entity.UserID) (*entity.Settings, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSettings", arg0, arg1)
+	ret0, _ := ret[0].(*entity.Settings)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSettings indicates an expected call of GetSettings.
+func (mr *MockSubscriptionRepositoryMockRecorder) GetSettings(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSettings", reflect.TypeOf((*MockSubscriptionRepository)(nil).GetSettings), arg0, arg1)
 }
 
 // GetSubByID mocks base method.
@@ -79,6 +230,65 @@ func (mr *MockSubscriptionRepositoryMockRecorder) GetSubByID(arg0, arg1 interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubByID", reflect.TypeOf((*MockSubscriptionRepository)(nil).GetSubByID), arg0, arg1)
 }
 
+// GetSubByIDForUpdate mocks base method.
+func (m *MockSubscriptionRepository) GetSubByIDForUpdate(arg0 context.Context, arg1 int64) (*entity.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSubByIDForUpdate", arg0, arg1)
+	ret0, _ := ret[0].(*entity.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSubByIDForUpdate indicates an expected call of GetSubByIDForUpdate.
+func (mr *MockSubscriptionRepositoryMockRecorder) GetSubByIDForUpdate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubByIDForUpdate", reflect.TypeOf((*MockSubscriptionRepository)(nil).GetSubByIDForUpdate), arg0, arg1)
+}
+
+// GetSubByPublicID mocks base method.
+func (m *MockSubscriptionRepository) GetSubByPublicID(arg0 context.Context, arg1 entity.PublicID) (*entity.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSubByPublicID", arg0, arg1)
+	ret0, _ := ret[0].(*entity.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSubByPublicID indicates an expected call of GetSubByPublicID.
+func (mr *MockSubscriptionRepositoryMockRecorder) GetSubByPublicID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubByPublicID", reflect.TypeOf((*MockSubscriptionRepository)(nil).GetSubByPublicID), arg0, arg1)
+}
+
+// InTx mocks base method.
+func (m *MockSubscriptionRepository) InTx(arg0 context.Context, arg1 func(context.Context) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InTx", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InTx indicates an expected call of InTx.
+func (mr *MockSubscriptionRepositoryMockRecorder) InTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InTx", reflect.TypeOf((*MockSubscriptionRepository)(nil).InTx), arg0, arg1)
+}
+
+// ListAdjustments mocks base method.
+func (m *MockSubscriptionRepository) ListAdjustments(arg0 context.Context, arg1 int64) ([]entity.Adjustment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAdjustments", arg0, arg1)
+	ret0, _ := ret[0].([]entity.Adjustment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAdjustments indicates an expected call of ListAdjustments.
+func (mr *MockSubscriptionRepositoryMockRecorder) ListAdjustments(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAdjustments", reflect.TypeOf((*MockSubscriptionRepository)(nil).ListAdjustments), arg0, arg1)
+}
+
 // ListSubsByFilter mocks base method.
 func (m *MockSubscriptionRepository) ListSubsByFilter(arg0 context.Context, arg1 SubFilter) ([]*entity.Subscription, error) {
 	m.ctrl.T.Helper()
@@ -94,6 +304,155 @@ func (mr *MockSubscriptionRepositoryMockRecorder) ListSubsByFilter(arg0, arg1 in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSubsByFilter", reflect.TypeOf((*MockSubscriptionRepository)(nil).ListSubsByFilter), arg0, arg1)
 }
 
+// MergeSubs mocks base method.
+func (m *MockSubscriptionRepository) MergeSubs(arg0 context.Context, arg1, arg2 *entity.Subscription, arg3 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MergeSubs", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MergeSubs indicates an expected call of MergeSubs.
+func (mr *MockSubscriptionRepositoryMockRecorder) MergeSubs(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergeSubs", reflect.TypeOf((*MockSubscriptionRepository)(nil).MergeSubs), arg0, arg1, arg2, arg3)
+}
+
+// MonthlySpendByUser mocks base method.
+func (m *MockSubscriptionRepository) MonthlySpendByUser(arg0 context.Context, arg1, arg2 time.Time) ([]UserMonthSpend, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MonthlySpendByUser", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]UserMonthSpend)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MonthlySpendByUser indicates an expected call of MonthlySpendByUser.
+func (mr *MockSubscriptionRepositoryMockRecorder) MonthlySpendByUser(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MonthlySpendByUser", reflect.TypeOf((*MockSubscriptionRepository)(nil).MonthlySpendByUser), arg0, arg1, arg2)
+}
+
+// PriceBenchmarks mocks base method.
+func (m *MockSubscriptionRepository) PriceBenchmarks(arg0 context.Context, arg1 time.Time, arg2 int) ([]PriceBenchmark, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PriceBenchmarks", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]PriceBenchmark)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PriceBenchmarks indicates an expected call of PriceBenchmarks.
+func (mr *MockSubscriptionRepositoryMockRecorder) PriceBenchmarks(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PriceBenchmarks", reflect.TypeOf((*MockSubscriptionRepository)(nil).PriceBenchmarks), arg0, arg1, arg2)
+}
+
+// PurgeSubs mocks base method.
+func (m *MockSubscriptionRepository) PurgeSubs(arg0 context.Context, arg1 []int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeSubs", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeSubs indicates an expected call of PurgeSubs.
+func (mr *MockSubscriptionRepositoryMockRecorder) PurgeSubs(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeSubs", reflect.TypeOf((*MockSubscriptionRepository)(nil).PurgeSubs), arg0, arg1)
+}
+
+// ReactivateUser mocks base method.
+func (m *MockSubscriptionRepository) ReactivateUser(arg0 context.Context, arg1 entity.UserID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReactivateUser", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReactivateUser indicates an expected call of ReactivateUser.
+func (mr *MockSubscriptionRepositoryMockRecorder) ReactivateUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReactivateUser", reflect.TypeOf((*MockSubscriptionRepository)(nil).ReactivateUser), arg0, arg1)
+}
+
+// ReassignUser mocks base method.
+func (m *MockSubscriptionRepository) ReassignUser(arg0 context.Context, arg1, arg2 entity.UserID, arg3 string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReassignUser", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReassignUser indicates an expected call of ReassignUser.
+func (mr *MockSubscriptionRepositoryMockRecorder) ReassignUser(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReassignUser", reflect.TypeOf((*MockSubscriptionRepository)(nil).ReassignUser), arg0, arg1, arg2, arg3)
+}
+
+// SaveAdjustment mocks base method.
+func (m *MockSubscriptionRepository) SaveAdjustment(arg0 context.Context, arg1 *entity.Adjustment) (*entity.Adjustment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveAdjustment", arg0, arg1)
+	ret0, _ := ret[0].(*entity.Adjustment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveAdjustment indicates an expected call of SaveAdjustment.
+func (mr *MockSubscriptionRepositoryMockRecorder) SaveAdjustment(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAdjustment", reflect.TypeOf((*MockSubscriptionRepository)(nil).SaveAdjustment), arg0, arg1)
+}
+
+// SaveImportedSub mocks base method.
+func (m *MockSubscriptionRepository) SaveImportedSub(arg0 context.Context, arg1 string, arg2 *entity.Subscription) (*entity.Subscription, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveImportedSub", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*entity.Subscription)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// SaveImportedSub indicates an expected call of SaveImportedSub.
+func (mr *MockSubscriptionRepositoryMockRecorder) SaveImportedSub(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveImportedSub", reflect.TypeOf((*MockSubscriptionRepository)(nil).SaveImportedSub), arg0, arg1, arg2)
+}
+
+// SaveSeats mocks base method.
+func (m *MockSubscriptionRepository) SaveSeats(arg0 context.Context, arg1 *entity.Seats) (*entity.Seats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveSeats", arg0, arg1)
+	ret0, _ := ret[0].(*entity.Seats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveSeats indicates an expected call of SaveSeats.
+func (mr *MockSubscriptionRepositoryMockRecorder) SaveSeats(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveSeats", reflect.TypeOf((*MockSubscriptionRepository)(nil).SaveSeats), arg0, arg1)
+}
+
+// SaveSettings mocks base method.
+func (m *MockSubscriptionRepository) SaveSettings(arg0 context.Context, arg1 entity.Settings) (*entity.Settings, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveSettings", arg0, arg1)
+	ret0, _ := ret[0].(*entity.Settings)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveSettings indicates an expected call of SaveSettings.
+func (mr *MockSubscriptionRepositoryMockRecorder) SaveSettings(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveSettings", reflect.TypeOf((*MockSubscriptionRepository)(nil).SaveSettings), arg0, arg1)
+}
+
 // SaveSub mocks base method.
 func (m *MockSubscriptionRepository) SaveSub(arg0 context.Context, arg1 *entity.Subscription) (*entity.Subscription, error) {
 	m.ctrl.T.Helper()
@@ -109,6 +468,21 @@ func (mr *MockSubscriptionRepositoryMockRecorder) SaveSub(arg0, arg1 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveSub", reflect.TypeOf((*MockSubscriptionRepository)(nil).SaveSub), arg0, arg1)
 }
 
+// SeatSharesByFilter mocks base method.
+func (m *MockSubscriptionRepository) SeatSharesByFilter(arg0 context.Context, arg1 SubFilter) ([]SeatShare, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SeatSharesByFilter", arg0, arg1)
+	ret0, _ := ret[0].([]SeatShare)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SeatSharesByFilter indicates an expected call of SeatSharesByFilter.
+func (mr *MockSubscriptionRepositoryMockRecorder) SeatSharesByFilter(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SeatSharesByFilter", reflect.TypeOf((*MockSubscriptionRepository)(nil).SeatSharesByFilter), arg0, arg1)
+}
+
 // UpdateSub mocks base method.
 func (m *MockSubscriptionRepository) UpdateSub(arg0 context.Context, arg1 *entity.Subscription) error {
 	m.ctrl.T.Helper()
@@ -122,3 +496,18 @@ func (mr *MockSubscriptionRepositoryMockRecorder) UpdateSub(arg0, arg1 interface
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSub", reflect.TypeOf((*MockSubscriptionRepository)(nil).UpdateSub), arg0, arg1)
 }
+
+// UserDeactivatedAt mocks base method.
+func (m *MockSubscriptionRepository) UserDeactivatedAt(arg0 context.Context, arg1 entity.UserID) (time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserDeactivatedAt", arg0, arg1)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UserDeactivatedAt indicates an expected call of UserDeactivatedAt.
+func (mr *MockSubscriptionRepositoryMockRecorder) UserDeactivatedAt(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserDeactivatedAt", reflect.TypeOf((*MockSubscriptionRepository)(nil).UserDeactivatedAt), arg0, arg1)
+}
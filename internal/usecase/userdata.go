@@ -0,0 +1,118 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/pkg/pagination"
+)
+
+// UserData — everything stored about one user, as moved between instances
+type UserData struct {
+	// Settings - saved settings, nil when the user never saved any
+	Settings *entity.Settings
+	// Subscriptions - subscriptions in the database in list order
+	Subscriptions []*entity.Subscription
+	// Archived - subscriptions moved to the archive, empty when the archive is off
+	Archived []*entity.Subscription
+}
+
+// ExportUser collects the user's settings, subscriptions and archived subscriptions; a deactivated user's
+// subscriptions are left out like everywhere else
+func (s *Subscription) ExportUser(ctx context.Context, userID entity.UserID) (UserData, error) {
+	if userID.IsZero() {
+		return UserData{}, entity.ErrInvalidUserID
+	}
+	var out UserData
+	settings, err := s.Sr.GetSettings(ctx, userID)
+	switch {
+	case errors.Is(err, ErrSettingsNotFound):
+	case err != nil:
+		return UserData{}, fmt.Errorf("export user: %w", err)
+	default:
+		out.Settings = settings
+	}
+
+	f := SubFilter{UserID: userID, Limit: pagination.MaxLimit}
+	for {
+		page, err := s.Sr.ListSubsByFilter(ctx, f)
+		if err != nil {
+			return UserData{}, fmt.Errorf("export user: %w", err)
+		}
+		out.Subscriptions = append(out.Subscriptions, page...)
+		if len(page) < f.Limit {
+			break
+		}
+		last := page[len(page)-1]
+		f.After = CursorAt(last)
+	}
+
+	if s.archive != nil {
+		archived, err := s.archive.Archived(ctx, SubFilter{UserID: userID})
+		if err != nil {
+			return UserData{}, fmt.Errorf("export user: list archived: %w", err)
+		}
+		if archived, err = s.withoutDeactivated(ctx, archived); err != nil {
+			return UserData{}, fmt.Errorf("export user: list archived: %w", err)
+		}
+		out.Archived = archived
+	}
+	return out, nil
+}
+
+// RestoreUser stores exported data under userID, who must have no subscriptions yet (ErrUserNotEmpty).
+// Everything is validated before the first write; archived subscriptions come back as ended subscriptions
+// in the database. Returns what was stored
+func (s *Subscription) RestoreUser(ctx context.Context, userID entity.UserID, data UserData) (UserData, error) {
+	if userID.IsZero() {
+		return UserData{}, entity.ErrInvalidUserID
+	}
+	existing, err := s.Sr.ListSubsByFilter(ctx, SubFilter{UserID: userID, Limit: 1, IncludeDeactivated: true})
+	if err != nil {
+		return UserData{}, fmt.Errorf("restore user: %w", err)
+	}
+	if len(existing) > 0 {
+		return UserData{}, ErrUserNotEmpty
+	}
+
+	var settings *entity.Settings
+	if data.Settings != nil {
+		st := *data.Settings
+		st.UserID = userID
+		if st.Timezone == "" {
+			st.Timezone = entity.DefaultSettings(userID).Timezone
+		}
+		if err := st.Validate(); err != nil {
+			return UserData{}, err
+		}
+		settings = &st
+	}
+	subs := make([]*entity.Subscription, 0, len(data.Subscriptions)+len(data.Archived))
+	for _, sub := range slices.Concat(data.Subscriptions, data.Archived) {
+		subs = append(subs, &entity.Subscription{
+			UserID:      userID,
+			ServiceName: sub.ServiceName,
+			Cost:        sub.Cost,
+			DateFrom:    sub.DateFrom,
+			DateTo:      sub.DateTo,
+		})
+	}
+
+	var out UserData
+	if len(subs) > 0 {
+		if out.Subscriptions, err = s.RegisterSubs(ctx, subs); err != nil {
+			return out, err
+		}
+	}
+	if settings != nil {
+		saved, err := s.UpdateSettings(ctx, *settings)
+		if err != nil {
+			return out, err
+		}
+		out.Settings = &saved
+	}
+	return out, nil
+}
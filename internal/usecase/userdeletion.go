@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/pkg/dates"
+)
+
+// UserDeletionPolicy — what deleting a user does to their subscriptions
+type UserDeletionPolicy string
+
+const (
+	// DeleteUserBlock - refuse with ErrUserHasActiveSubs while a subscription has not ended, otherwise delete
+	// the ended ones
+	DeleteUserBlock UserDeletionPolicy = "block"
+	// DeleteUserCascade - delete every subscription together with its adjustments and seats
+	DeleteUserCascade UserDeletionPolicy = "cascade"
+	// DeleteUserAnonymize - keep the subscriptions for aggregate statistics under a new random user ID that
+	// leads back to nobody
+	DeleteUserAnonymize UserDeletionPolicy = "anonymize"
+)
+
+// UserDeletion — how the repository removes a user; whatever the policy, the settings, the deactivation mark
+// and the seats the user holds in plans of others are removed too
+type UserDeletion struct {
+	Policy UserDeletionPolicy
+	// Since - with DeleteUserBlock, subscriptions without an end date or ending in this month or later block
+	// the deletion
+	Since time.Time
+	// AnonymousID - with DeleteUserAnonymize, the user the subscriptions are moved to
+	AnonymousID entity.UserID
+}
+
+// DeletedUser — outcome of deleting a user
+type DeletedUser struct {
+	Policy UserDeletionPolicy
+	// Subscriptions - subscriptions deleted, or anonymized with DeleteUserAnonymize
+	Subscriptions int64
+}
+
+// WithUserDeletion returns an option that sets what deleting a user does to their subscriptions; an empty
+// policy keeps DeleteUserBlock
+func WithUserDeletion(p UserDeletionPolicy) func(*Subscription) {
+	return func(s *Subscription) {
+		if p != "" {
+			s.userDeletion = p
+		}
+	}
+}
+
+// DeleteUser removes everything stored about the user as the configured policy says, in a single
+// transaction of the repository: nothing is removed when the policy blocks the deletion. Deleting a user
+// the repository knows nothing about succeeds and removes nothing
+func (s *Subscription) DeleteUser(ctx context.Context, userID entity.UserID, actor string) (DeletedUser, error) {
+	if userID.IsZero() {
+		return DeletedUser{}, entity.ErrInvalidUserID
+	}
+	d := UserDeletion{Policy: s.userDeletion}
+	switch d.Policy {
+	case DeleteUserBlock:
+		d.Since = dates.MonthStart(s.clock.Now().UTC())
+	case DeleteUserCascade:
+	case DeleteUserAnonymize:
+		id, err := entity.NewUserID(uuid.New())
+		if err != nil {
+			return DeletedUser{}, fmt.Errorf("delete user: %w", err)
+		}
+		d.AnonymousID = id
+	default:
+		return DeletedUser{}, fmt.Errorf("delete user: unknown policy %q", d.Policy)
+	}
+
+	defer s.costNow.drop(ctx, userID)
+	n, err := s.Sr.DeleteUser(ctx, userID, d, actor)
+	if errors.Is(err, ErrUserHasActiveSubs) {
+		return DeletedUser{}, err
+	}
+	if err != nil {
+		return DeletedUser{}, fmt.Errorf("delete user: %w", err)
+	}
+	return DeletedUser{Policy: d.Policy, Subscriptions: n}, nil
+}
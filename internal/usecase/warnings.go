@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/pkg/dates"
+)
+
+// FarEndYears - an end_date further than this many years from now is likely a mistyped year
+const FarEndYears = 5
+
+// Warning — a remark about a subscription that was written anyway, for the UI to show the user
+type Warning struct {
+	// Code - machine-readable kind of the warning, from the errcode catalog
+	Code errcode.Code
+	// Message - English description without variable parts, so it can be translated
+	Message string
+	// SubscriptionID - the other subscription the warning is about, 0 if none
+	SubscriptionID int64
+	// PublicID - public ID of the other subscription when the strategy shows public IDs, otherwise ""
+	PublicID string
+}
+
+// Warnings reports what looks wrong with a saved subscription without making it invalid: other subscriptions
+// of the user to the same service over an overlapping period, and an end_date more than FarEndYears away
+func (s *Subscription) Warnings(ctx context.Context, sub *entity.Subscription) ([]Warning, error) {
+	var out []Warning
+	name := sub.ServiceName
+	same, err := s.allSubs(ctx, SubFilter{UserID: sub.UserID, Where: Conditions{ServiceContains: &name}})
+	if err != nil {
+		return nil, fmt.Errorf("find duplicates: %w", err)
+	}
+	for _, o := range same {
+		if o.ID == sub.ID || !strings.EqualFold(o.ServiceName, sub.ServiceName) || !overlaps(o, sub) {
+			continue
+		}
+		out = append(out, Warning{
+			Code:           errcode.PossibleDuplicate,
+			Message:        "possible duplicate of another subscription",
+			SubscriptionID: o.ID,
+			PublicID:       s.PublicID(o),
+		})
+	}
+	if sub.DateTo != nil && sub.DateTo.After(dates.MonthStart(s.clock.Now()).AddDate(FarEndYears, 0, 0)) {
+		out = append(out, Warning{
+			Code:    errcode.EndDateFar,
+			Message: fmt.Sprintf("end_date is more than %d years away", FarEndYears),
+		})
+	}
+	return out, nil
+}
+
+// overlaps reports whether two subscriptions share at least one month; an open end lasts forever
+func overlaps(a, b *entity.Subscription) bool {
+	endsBefore := func(x, y *entity.Subscription) bool { return x.DateTo != nil && x.DateTo.Before(y.DateFrom) }
+	return !endsBefore(a, b) && !endsBefore(b, a)
+}
@@ -0,0 +1,177 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/pkg/pagination"
+)
+
+// YearReviewTopHikes - price hikes a year review lists at most
+const YearReviewTopHikes = 5
+
+// YearReview — one user's year of subscriptions, e.g. for a yearly recap. Like DiffMonths it is computed from
+// the subscription periods, archived ones included, and compares services by name without case and surrounding
+// spaces
+type YearReview struct {
+	Year int
+	// Total - sum of Months
+	Total int64
+	// Months - monthly cost of all subscriptions, January to December
+	Months []MonthSpend
+	// Services - what each service cost over the year, most expensive first
+	Services []ServiceYear
+	// PriceHikes - the largest increases of a service's monthly cost from one month to the next, including from
+	// the December before; largest first, at most YearReviewTopHikes
+	PriceHikes []PriceHike
+	// New - services paid in the year but not in the December before, by name
+	New []ServiceYear
+	// Cancelled - services paid in the year but not in its December, by name
+	Cancelled []ServiceYear
+}
+
+// MonthSpend — monthly cost of all of a user's subscriptions
+type MonthSpend struct {
+	// Month - first day of the month
+	Month time.Time
+	Total int64
+}
+
+// ServiceYear — one service over a year
+type ServiceYear struct {
+	// ServiceName - name as stored on the latest started subscription to the service
+	ServiceName string
+	// Total - what the service cost over the year
+	Total int64
+	// First, Last - first days of the first and last months the service was paid for in the year
+	First time.Time
+	Last  time.Time
+}
+
+// PriceHike — a service costing more in Month than in the month before
+type PriceHike struct {
+	ServiceName string
+	Month       time.Time
+	FromCost    int64
+	ToCost      int64
+}
+
+// YearReview returns the user's review of year
+func (s *Subscription) YearReview(ctx context.Context, userID entity.UserID, year int) (YearReview, error) {
+	if userID.IsZero() {
+		return YearReview{}, entity.ErrInvalidUserID
+	}
+	if year < 1 || year > 9999 {
+		return YearReview{}, fmt.Errorf("%w: year must be between 1 and 9999", ErrInvalidPeriod)
+	}
+	jan := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	dec := time.Date(year, time.December, 1, 0, 0, 0, 0, time.UTC)
+	before := jan.AddDate(0, -1, 0)
+
+	// costs[0] is the December before the year, costs[1..12] its months
+	type service struct {
+		name    string
+		started time.Time
+		costs   [13]int64
+		paid    [13]bool
+	}
+	services := make(map[string]*service)
+	f := SubFilter{
+		UserID:          userID,
+		Period:          &Period{From: before, To: dec},
+		IncludeArchived: true,
+		Limit:           pagination.DefaultLimits().Max,
+	}
+	for {
+		page, err := s.ListSubsByFilter(ctx, f)
+		if err != nil {
+			return YearReview{}, fmt.Errorf("year review: %w", err)
+		}
+		for _, sub := range page {
+			key := strings.ToLower(strings.TrimSpace(sub.ServiceName))
+			sv, ok := services[key]
+			if !ok {
+				sv = &service{}
+				services[key] = sv
+			}
+			if sv.name == "" || sub.DateFrom.After(sv.started) {
+				sv.name, sv.started = sub.ServiceName, sub.DateFrom
+			}
+			for i := range sv.costs {
+				if activeIn(sub, before.AddDate(0, i, 0)) {
+					sv.costs[i] += sub.Cost
+					sv.paid[i] = true
+				}
+			}
+		}
+		if len(page) < f.Limit {
+			break
+		}
+		last := page[len(page)-1]
+		f.After = CursorAt(last)
+	}
+
+	review := YearReview{Year: year, Months: make([]MonthSpend, 12)}
+	for i := range review.Months {
+		review.Months[i].Month = jan.AddDate(0, i, 0)
+	}
+	for _, sv := range services {
+		sy := ServiceYear{ServiceName: sv.name}
+		for i := 1; i < len(sv.costs); i++ {
+			if !sv.paid[i] {
+				continue
+			}
+			month := before.AddDate(0, i, 0)
+			if sy.First.IsZero() {
+				sy.First = month
+			}
+			sy.Last = month
+			sy.Total += sv.costs[i]
+			review.Months[i-1].Total += sv.costs[i]
+			if sv.paid[i-1] && sv.costs[i] > sv.costs[i-1] {
+				review.PriceHikes = append(review.PriceHikes, PriceHike{
+					ServiceName: sv.name, Month: month, FromCost: sv.costs[i-1], ToCost: sv.costs[i],
+				})
+			}
+		}
+		if sy.First.IsZero() {
+			// paid only in the December before
+			continue
+		}
+		review.Total += sy.Total
+		review.Services = append(review.Services, sy)
+		if !sv.paid[0] {
+			review.New = append(review.New, sy)
+		}
+		if !sv.paid[12] {
+			review.Cancelled = append(review.Cancelled, sy)
+		}
+	}
+
+	sort.Slice(review.Services, func(i, j int) bool {
+		a, b := review.Services[i], review.Services[j]
+		if a.Total != b.Total {
+			return a.Total > b.Total
+		}
+		return a.ServiceName < b.ServiceName
+	})
+	sort.Slice(review.PriceHikes, func(i, j int) bool {
+		a, b := review.PriceHikes[i], review.PriceHikes[j]
+		if da, db := a.ToCost-a.FromCost, b.ToCost-b.FromCost; da != db {
+			return da > db
+		}
+		if !a.Month.Equal(b.Month) {
+			return a.Month.Before(b.Month)
+		}
+		return a.ServiceName < b.ServiceName
+	})
+	review.PriceHikes = review.PriceHikes[:min(len(review.PriceHikes), YearReviewTopHikes)]
+	for _, list := range [][]ServiceYear{review.New, review.Cancelled} {
+		sort.Slice(list, func(i, j int) bool { return list[i].ServiceName < list[j].ServiceName })
+	}
+	return review, nil
+}
@@ -0,0 +1,343 @@
+// Package userhooks delivers the events of a single user to the webhook that user registered for personal
+// automations, e.g. their Home Assistant, next to the deployment-wide WEBHOOK_URL. A user has at most one hook
+// and it receives only the events of their own subscriptions. Deliveries are capped per user and hour so one
+// busy account can not keep the event bus to itself, and since the server calls a URL the user chose,
+// addresses in private networks are refused unless the deployment allows them
+package userhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/internal/webhooks"
+	"subs_tracker/pkg/clock"
+)
+
+const (
+	// SecretPrefix - every generated signing secret starts with it
+	SecretPrefix = "uwh_"
+	secretBytes  = 24
+	maxURLLength = 2048
+	// limitWindow - deliveries of a user are counted per window, see WithLimit
+	limitWindow  = time.Hour
+	defaultLimit = 60
+)
+
+var (
+	ErrNotFound   = errcode.New(errcode.UserHookNotFound, "user webhook not found")
+	ErrInvalidURL = errcode.New(errcode.UserHookInvalid, "invalid webhook url")
+	ErrLimited    = errcode.New(errcode.UserHookLimited, "user webhook delivery limit reached")
+)
+
+// Hook — the webhook of a user
+type Hook struct {
+	UserID entity.UserID
+	URL    string
+	Format webhooks.Format
+	// Secret - key of the X-Webhook-Signature HMAC, generated on registration and shown to the user once
+	Secret    string
+	CreatedAt time.Time
+}
+
+// Store — storage of user webhooks
+type Store interface {
+	// SaveHook - store the hook of a user, replacing the one they had
+	SaveHook(ctx context.Context, h Hook) error
+	// GetHook - get the hook of a user, ErrNotFound if there is none
+	GetHook(ctx context.Context, userID entity.UserID) (*Hook, error)
+	// DeleteHook - remove the hook of a user, ErrNotFound if there is none
+	DeleteHook(ctx context.Context, userID entity.UserID) error
+}
+
+// Status — delivery statistics of all user webhooks since the process started
+type Status struct {
+	// Delivered - attempts answered with 2xx
+	Delivered int64
+	// Failed - attempts that got no response or a non-2xx one
+	Failed int64
+	// Limited - events not sent because their user had reached the limit
+	Limited int64
+	// Dropped - events the event bus gave up on
+	Dropped int64
+}
+
+// window — deliveries of a user since start
+type window struct {
+	start time.Time
+	n     int
+}
+
+// Hooks registers user webhooks and delivers events to them
+type Hooks struct {
+	store   Store
+	clock   clock.Clock
+	limit   int
+	timeout time.Duration
+	private bool
+	client  *http.Client
+
+	mu      sync.Mutex
+	windows map[entity.UserID]window
+	status  Status
+}
+
+// NewHooks creates hooks kept in store and applies options
+func NewHooks(store Store, options ...func(*Hooks)) *Hooks {
+	h := &Hooks{store: store, clock: clock.System, limit: defaultLimit, windows: map[entity.UserID]window{}}
+	for _, o := range options {
+		o(h)
+	}
+	if h.client == nil {
+		h.client = newHTTPClient(h.timeout, h.private)
+	}
+	return h
+}
+
+// WithClock returns an option that sets the source of registration times and limit windows
+func WithClock(c clock.Clock) func(*Hooks) {
+	return func(h *Hooks) {
+		if c != nil {
+			h.clock = c
+		}
+	}
+}
+
+// WithLimit returns an option that caps the delivery attempts to the hook of a user per hour, tests included;
+// events over the cap are counted in Status and not sent
+func WithLimit(n int) func(*Hooks) {
+	return func(h *Hooks) {
+		if n > 0 {
+			h.limit = n
+		}
+	}
+}
+
+// WithTimeout returns an option that bounds a single delivery attempt
+func WithTimeout(timeout time.Duration) func(*Hooks) {
+	return func(h *Hooks) {
+		if timeout > 0 {
+			h.timeout = timeout
+		}
+	}
+}
+
+// WithPrivateNetworks returns an option that allows hooks on loopback and private addresses, for
+// self-hosted installs next to the automations they feed
+func WithPrivateNetworks(allow bool) func(*Hooks) {
+	return func(h *Hooks) {
+		h.private = allow
+	}
+}
+
+// WithHTTPClient returns an option that sets the HTTP client of deliveries; it replaces the guard against
+// private addresses, so it is meant for tests
+func WithHTTPClient(hc *http.Client) func(*Hooks) {
+	return func(h *Hooks) {
+		if hc != nil {
+			h.client = hc
+		}
+	}
+}
+
+// Register sets the hook of a user to rawURL with format f and a new signing secret, replacing the one
+// they had; the returned hook carries the secret
+func (h *Hooks) Register(ctx context.Context, userID entity.UserID, rawURL string, f webhooks.Format) (Hook, error) {
+	if userID.IsZero() {
+		return Hook{}, entity.ErrInvalidUserID
+	}
+	clean, err := ParseURL(rawURL, h.private)
+	if err != nil {
+		return Hook{}, err
+	}
+	if f == "" {
+		f = webhooks.FormatEnvelope
+	}
+
+	raw := make([]byte, secretBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return Hook{}, fmt.Errorf("register user webhook: %w", err)
+	}
+	hook := Hook{
+		UserID:    userID,
+		URL:       clean,
+		Format:    f,
+		Secret:    SecretPrefix + base64.RawURLEncoding.EncodeToString(raw),
+		CreatedAt: h.clock.Now().UTC(),
+	}
+	if err := h.store.SaveHook(ctx, hook); err != nil {
+		return Hook{}, fmt.Errorf("register user webhook: %w", err)
+	}
+	return hook, nil
+}
+
+// Get returns the hook of a user, ErrNotFound if they have none
+func (h *Hooks) Get(ctx context.Context, userID entity.UserID) (Hook, error) {
+	hook, err := h.store.GetHook(ctx, userID)
+	if err != nil {
+		return Hook{}, err
+	}
+	return *hook, nil
+}
+
+// Remove deletes the hook of a user, ErrNotFound if they have none
+func (h *Hooks) Remove(ctx context.Context, userID entity.UserID) error {
+	return h.store.DeleteHook(ctx, userID)
+}
+
+// Deliver posts the event to the hook of its subscription's user, once. It is ErrNotFound when the user has
+// no hook and ErrLimited when they are over the limit; neither is worth a retry
+func (h *Hooks) Deliver(ctx context.Context, e usecase.SubscriptionEvent) error {
+	if e.Subscription == nil || e.Subscription.UserID.IsZero() {
+		return ErrNotFound
+	}
+	hook, err := h.store.GetHook(ctx, e.Subscription.UserID)
+	if errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("user webhook: %w", err)
+	}
+	if !h.take(hook.UserID) {
+		return ErrLimited
+	}
+	_, err = h.send(ctx, *hook, func(c *webhooks.Client) (webhooks.Delivery, error) { return c.Deliver(ctx, e) })
+	return err
+}
+
+// Test delivers the sample event of type t of webhooks.Client.Test to the hook of a user; it counts against
+// the limit, ErrLimited once it is reached
+func (h *Hooks) Test(ctx context.Context, userID entity.UserID, t usecase.SubscriptionEventType) (Hook, webhooks.Delivery, error) {
+	hook, err := h.Get(ctx, userID)
+	if err != nil {
+		return Hook{}, webhooks.Delivery{}, err
+	}
+	if !h.take(userID) {
+		return hook, webhooks.Delivery{}, ErrLimited
+	}
+	d, err := h.send(ctx, hook, func(c *webhooks.Client) (webhooks.Delivery, error) { return c.Test(ctx, t) })
+	return hook, d, err
+}
+
+// Status reports delivery statistics
+func (h *Hooks) Status() Status {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.status
+}
+
+// Dropped counts n events the event bus gave up on
+func (h *Hooks) Dropped(n int) {
+	h.mu.Lock()
+	h.status.Dropped += int64(n)
+	h.mu.Unlock()
+}
+
+// send makes one attempt with a client for the hook and records the outcome
+func (h *Hooks) send(ctx context.Context, hook Hook, attempt func(*webhooks.Client) (webhooks.Delivery, error)) (webhooks.Delivery, error) {
+	c := webhooks.NewClient(hook.URL,
+		webhooks.WithFormat(hook.Format),
+		webhooks.WithSecret(hook.Secret),
+		webhooks.WithHTTPClient(h.client),
+	)
+	d, err := attempt(c)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err != nil {
+		h.status.Failed++
+	} else {
+		h.status.Delivered++
+	}
+	return d, err
+}
+
+// take counts a delivery to the hook of the user, false once the user is over the limit of the window
+func (h *Hooks) take(userID entity.UserID) bool {
+	now := h.clock.Now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// windows of users quiet for a whole window are dropped, so the map only holds recent senders
+	for id, w := range h.windows {
+		if now.Sub(w.start) >= limitWindow {
+			delete(h.windows, id)
+		}
+	}
+	w, ok := h.windows[userID]
+	if !ok {
+		w = window{start: now}
+	}
+	if w.n >= h.limit {
+		h.status.Limited++
+		return false
+	}
+	w.n++
+	h.windows[userID] = w
+	return true
+}
+
+// ParseURL checks the URL of a hook: absolute http or https without credentials and, unless private is set,
+// not a loopback or private address
+func ParseURL(raw string, private bool) (string, error) {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Hostname() == "" || u.User != nil ||
+		len(raw) > maxURLLength {
+		return "", fmt.Errorf("%w: %q is not an absolute http(s) URL", ErrInvalidURL, raw)
+	}
+	if !private {
+		host := strings.ToLower(u.Hostname())
+		if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+			return "", fmt.Errorf("%w: %q is a private address", ErrInvalidURL, raw)
+		}
+		if ip, err := netip.ParseAddr(host); err == nil && !public(ip) {
+			return "", fmt.Errorf("%w: %q is a private address", ErrInvalidURL, raw)
+		}
+	}
+	return u.String(), nil
+}
+
+// public reports whether ip may be called by deliveries in a deployment that does not allow private networks
+func public(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsUnspecified() && !ip.IsMulticast() && !ip.IsInterfaceLocalMulticast()
+}
+
+// newHTTPClient builds the client of deliveries. Without private, every connection is checked after DNS
+// resolution, so names pointing into private networks and redirects there are refused as well
+func newHTTPClient(timeout time.Duration, private bool) *http.Client {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !private {
+		// behind a proxy the dial goes to the proxy, which has to do its own filtering
+		transport.Proxy = nil
+		dialer := &net.Dialer{Timeout: timeout, Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip, err := netip.ParseAddr(host); err != nil || !public(ip) {
+				return fmt.Errorf("%w: %s is a private address", ErrInvalidURL, host)
+			}
+			return nil
+		}}
+		transport.DialContext = dialer.DialContext
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -0,0 +1,367 @@
+// Package webhooks delivers subscription events to an external HTTP endpoint.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/usecase"
+	"subs_tracker/pkg/dates"
+)
+
+// Format — shape of the delivered JSON body
+type Format string
+
+const (
+	// FormatEnvelope - {"event", "occurred_at", "data": {subscription}}
+	FormatEnvelope Format = "envelope"
+	// FormatSimple - a single flat object with stable keys, for Zapier/IFTTT style consumers
+	FormatSimple Format = "simple"
+)
+
+// EventTest - event type of deliveries triggered by Client.Test
+const EventTest usecase.SubscriptionEventType = "webhook.test"
+
+const defaultTimeout = 5 * time.Second
+
+// sampleUserID - user of the sample subscription sent by Client.Test
+var sampleUserID = entity.UserID(uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba"))
+
+var (
+	ErrUnknownFormat  = errors.New("unknown webhook format")
+	ErrUnknownEvent   = errors.New("unknown webhook event")
+	ErrDeliveryFailed = errors.New("webhook delivery failed")
+)
+
+// SampleEvents - event types Client.Test can send, EventTest first
+var SampleEvents = []usecase.SubscriptionEventType{
+	EventTest,
+	usecase.EventSubscriptionCreated,
+	usecase.EventSubscriptionUpdated,
+	usecase.EventSubscriptionDeleted,
+}
+
+// ParseFormat parses a format name, case-insensitive; empty means FormatEnvelope
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case "":
+		return FormatEnvelope, nil
+	case FormatEnvelope, FormatSimple:
+		return f, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownFormat, s)
+	}
+}
+
+// ParseSampleEvent parses the event type of a sample delivery, case-insensitive; empty means EventTest
+func ParseSampleEvent(s string) (usecase.SubscriptionEventType, error) {
+	t := usecase.SubscriptionEventType(strings.ToLower(strings.TrimSpace(s)))
+	if t == "" {
+		return EventTest, nil
+	}
+	if !slices.Contains(SampleEvents, t) {
+		return "", fmt.Errorf("%w: %q", ErrUnknownEvent, s)
+	}
+	return t, nil
+}
+
+// SimplePayload is the body of FormatSimple. Every key is always present, so no-code tools can map
+// fields from the first sample: end_date is "" for open-ended subscriptions, dates are MM-YYYY. The ID
+// strategy is fixed per deployment, so subscription_public_id is present in every payload or in none, and
+// subscription_id is 0 when it is
+type SimplePayload struct {
+	Event                string `json:"event"`
+	OccurredAt           string `json:"occurred_at"`
+	SubscriptionID       int64  `json:"subscription_id"`
+	SubscriptionPublicID string `json:"subscription_public_id,omitempty"`
+	UserID               string `json:"user_id"`
+	ServiceName          string `json:"service_name"`
+	Cost                 int64  `json:"cost"`
+	StartDate            string `json:"start_date"`
+	EndDate              string `json:"end_date"`
+}
+
+// envelopePayload is the body of FormatEnvelope
+type envelopePayload struct {
+	Event      string           `json:"event"`
+	OccurredAt string           `json:"occurred_at"`
+	Data       subscriptionData `json:"data"`
+}
+
+// subscriptionData is a subscription as nested in FormatEnvelope
+type subscriptionData struct {
+	// ID is left out for PublicID under a public ID strategy
+	ID          int64   `json:"id,omitempty"`
+	PublicID    string  `json:"public_id,omitempty"`
+	UserID      string  `json:"user_id"`
+	ServiceName string  `json:"service_name"`
+	Cost        int64   `json:"cost"`
+	StartDate   string  `json:"start_date"`
+	EndDate     *string `json:"end_date,omitempty"`
+	CreatedAt   string  `json:"created_at,omitempty"`
+	UpdatedAt   string  `json:"updated_at,omitempty"`
+}
+
+// Payload renders the event body in format f; an event with a public ID names the subscription by it alone
+func Payload(f Format, e usecase.SubscriptionEvent) any {
+	sub := e.Subscription
+	if sub == nil {
+		sub = &entity.Subscription{}
+	}
+	id := sub.ID
+	if e.PublicID != "" {
+		id = 0
+	}
+	occurred := e.OccurredAt.UTC().Format(time.RFC3339)
+	if f == FormatSimple {
+		p := SimplePayload{
+			Event:                string(e.Type),
+			OccurredAt:           occurred,
+			SubscriptionID:       id,
+			SubscriptionPublicID: e.PublicID,
+			UserID:               sub.UserID.String(),
+			ServiceName:          sub.ServiceName,
+			Cost:                 sub.Cost,
+			StartDate:            dates.Format(sub.DateFrom),
+		}
+		if sub.DateTo != nil {
+			p.EndDate = dates.Format(*sub.DateTo)
+		}
+		return p
+	}
+
+	data := subscriptionData{
+		ID:          id,
+		PublicID:    e.PublicID,
+		UserID:      sub.UserID.String(),
+		ServiceName: sub.ServiceName,
+		Cost:        sub.Cost,
+		StartDate:   dates.Format(sub.DateFrom),
+	}
+	if sub.DateTo != nil {
+		end := dates.Format(*sub.DateTo)
+		data.EndDate = &end
+	}
+	if !sub.CreatedAt.IsZero() {
+		data.CreatedAt = sub.CreatedAt.UTC().Format(time.RFC3339)
+	}
+	if !sub.UpdatedAt.IsZero() {
+		data.UpdatedAt = sub.UpdatedAt.UTC().Format(time.RFC3339)
+	}
+	return envelopePayload{Event: string(e.Type), OccurredAt: occurred, Data: data}
+}
+
+// Delivery — outcome of a single POST to the endpoint
+type Delivery struct {
+	// StatusCode - response status, 0 when no response was received
+	StatusCode int
+	// Duration - time until the response headers arrived
+	Duration time.Duration
+	// Body - the delivered payload
+	Body json.RawMessage
+}
+
+// Status — delivery statistics of a client since the process started
+type Status struct {
+	// Delivered - attempts answered with 2xx
+	Delivered int64
+	// Failed - attempts that got no response or a non-2xx one
+	Failed int64
+	// Dropped - events given up on: queue full, retries exhausted or still queued at shutdown
+	Dropped int64
+	// LastAttempt - time of the latest attempt, zero before the first one
+	LastAttempt time.Time
+	// LastSuccess - time of the latest 2xx response
+	LastSuccess time.Time
+	// LastEvent - event type of the latest attempt
+	LastEvent string
+	// LastStatusCode - response status of the latest attempt, 0 when no response was received
+	LastStatusCode int
+	// LastError - error of the latest attempt, empty when it succeeded
+	LastError string
+}
+
+// Client posts events to the configured endpoint
+type Client struct {
+	url    string
+	format Format
+	secret string
+	client *http.Client
+	now    func() time.Time
+
+	mu     sync.Mutex
+	status Status
+}
+
+// NewClient creates a client for the endpoint URL and applies options
+func NewClient(endpoint string, options ...func(*Client)) *Client {
+	c := &Client{
+		url:    endpoint,
+		format: FormatEnvelope,
+		client: &http.Client{Timeout: defaultTimeout},
+		now:    time.Now,
+	}
+	for _, o := range options {
+		o(c)
+	}
+	return c
+}
+
+// WithFormat sets the payload format
+func WithFormat(f Format) func(*Client) {
+	return func(c *Client) {
+		if f != "" {
+			c.format = f
+		}
+	}
+}
+
+// WithSecret sets the key of the X-Webhook-Signature HMAC; empty sends unsigned requests
+func WithSecret(secret string) func(*Client) {
+	return func(c *Client) {
+		c.secret = secret
+	}
+}
+
+// WithTimeout bounds a single delivery attempt
+func WithTimeout(timeout time.Duration) func(*Client) {
+	return func(c *Client) {
+		if timeout > 0 {
+			c.client = &http.Client{Timeout: timeout}
+		}
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for deliveries
+func WithHTTPClient(hc *http.Client) func(*Client) {
+	return func(c *Client) {
+		if hc != nil {
+			c.client = hc
+		}
+	}
+}
+
+// Format reports the payload format of the client
+func (c *Client) Format() Format {
+	return c.format
+}
+
+// URL reports the endpoint of the client
+func (c *Client) URL() string {
+	return c.url
+}
+
+// Status reports delivery statistics
+func (c *Client) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status
+}
+
+// Deliver posts the event once and records the outcome in Status; any status outside 2xx is reported as ErrDeliveryFailed
+func (c *Client) Deliver(ctx context.Context, e usecase.SubscriptionEvent) (Delivery, error) {
+	start := c.now()
+	d, err := c.deliver(ctx, e)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status.LastAttempt = start
+	c.status.LastEvent = string(e.Type)
+	c.status.LastStatusCode = d.StatusCode
+	if err != nil {
+		c.status.Failed++
+		c.status.LastError = err.Error()
+	} else {
+		c.status.Delivered++
+		c.status.LastSuccess = start
+		c.status.LastError = ""
+	}
+	return d, err
+}
+
+// Dropped counts n events the event bus gave up on
+func (c *Client) Dropped(n int) {
+	c.mu.Lock()
+	c.status.Dropped += int64(n)
+	c.mu.Unlock()
+}
+
+func (c *Client) deliver(ctx context.Context, e usecase.SubscriptionEvent) (Delivery, error) {
+	body, err := json.Marshal(Payload(c.format, e))
+	if err != nil {
+		return Delivery{}, fmt.Errorf("webhook payload: %w", err)
+	}
+	d := Delivery{Body: body}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return d, fmt.Errorf("webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "subs_tracker-webhooks")
+	req.Header.Set("X-Webhook-Event", string(e.Type))
+	if c.secret != "" {
+		req.Header.Set("X-Webhook-Signature", "sha256="+sign(c.secret, body))
+	}
+
+	start := c.now()
+	resp, err := c.client.Do(req)
+	d.Duration = c.now().Sub(start)
+	if err != nil {
+		return d, fmt.Errorf("%w: %w", ErrDeliveryFailed, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	d.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return d, fmt.Errorf("%w: status %d", ErrDeliveryFailed, resp.StatusCode)
+	}
+	return d, nil
+}
+
+// Test delivers a sample event of type t, one of SampleEvents, with the same keys and signature as real ones,
+// for wiring up no-code tools and debugging receivers; an empty t sends EventTest
+func (c *Client) Test(ctx context.Context, t usecase.SubscriptionEventType) (Delivery, error) {
+	if t == "" {
+		t = EventTest
+	}
+	if !slices.Contains(SampleEvents, t) {
+		return Delivery{}, fmt.Errorf("%w: %q", ErrUnknownEvent, t)
+	}
+	month := dates.MonthStart(c.now())
+	sub := &entity.Subscription{
+		ID:          1,
+		UserID:      sampleUserID,
+		ServiceName: "Netflix",
+		Cost:        999,
+		DateFrom:    month,
+		CreatedAt:   month,
+		UpdatedAt:   month,
+	}
+	if t == usecase.EventSubscriptionDeleted {
+		// a deleted sample is a cancelled one, so receivers see end_date filled in
+		end := month.AddDate(0, 1, 0)
+		sub.DateTo = &end
+	}
+	return c.Deliver(ctx, usecase.SubscriptionEvent{Type: t, OccurredAt: c.now(), Subscription: sub})
+}
+
+// sign returns the hex HMAC-SHA256 of body
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
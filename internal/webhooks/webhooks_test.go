@@ -0,0 +1,152 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/usecase"
+)
+
+func sampleEvent() usecase.SubscriptionEvent {
+	jul := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	return usecase.SubscriptionEvent{
+		Type:       usecase.EventSubscriptionCreated,
+		OccurredAt: time.Date(2025, time.July, 3, 10, 0, 0, 0, time.UTC),
+		Subscription: &entity.Subscription{
+			ID:          7,
+			UserID:      entity.UserID(uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba")),
+			ServiceName: "Netflix",
+			Cost:        999,
+			DateFrom:    jul,
+		},
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for in, want := range map[string]Format{"": FormatEnvelope, "Simple": FormatSimple, " envelope ": FormatEnvelope} {
+		got, err := ParseFormat(in)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	_, err := ParseFormat("zapier")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestParseSampleEvent(t *testing.T) {
+	for in, want := range map[string]usecase.SubscriptionEventType{
+		"":                       EventTest,
+		"Subscription.Created":   usecase.EventSubscriptionCreated,
+		" subscription.deleted ": usecase.EventSubscriptionDeleted,
+	} {
+		got, err := ParseSampleEvent(in)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	_, err := ParseSampleEvent("user.deleted")
+	assert.ErrorIs(t, err, ErrUnknownEvent)
+}
+
+func TestClient_Deliver(t *testing.T) {
+	var (
+		body    []byte
+		headers http.Header
+		status  = http.StatusOK
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		headers = r.Header
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	t.Run("simple is flat with every key", func(t *testing.T) {
+		c := NewClient(srv.URL, WithFormat(FormatSimple), WithSecret("s3cret"))
+		d, err := c.Deliver(context.Background(), sampleEvent())
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, d.StatusCode)
+
+		var got map[string]any
+		require.NoError(t, json.Unmarshal(body, &got))
+		assert.Equal(t, map[string]any{
+			"event":           "subscription.created",
+			"occurred_at":     "2025-07-03T10:00:00Z",
+			"subscription_id": float64(7),
+			"user_id":         "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+			"service_name":    "Netflix",
+			"cost":            float64(999),
+			"start_date":      "07-2025",
+			"end_date":        "",
+		}, got)
+		assert.Equal(t, "subscription.created", headers.Get("X-Webhook-Event"))
+		assert.Equal(t, "sha256="+sign("s3cret", body), headers.Get("X-Webhook-Signature"))
+	})
+
+	t.Run("envelope nests the subscription", func(t *testing.T) {
+		c := NewClient(srv.URL)
+		_, err := c.Deliver(context.Background(), sampleEvent())
+		require.NoError(t, err)
+
+		var got envelopePayload
+		require.NoError(t, json.Unmarshal(body, &got))
+		assert.Equal(t, "subscription.created", got.Event)
+		assert.Equal(t, int64(7), got.Data.ID)
+		assert.Nil(t, got.Data.EndDate)
+		assert.Empty(t, headers.Get("X-Webhook-Signature"))
+	})
+
+	t.Run("public id replaces the serial one", func(t *testing.T) {
+		e := sampleEvent()
+		e.PublicID = "01J9Z8M2Q4V6X8Z0A2C4E6G8J0"
+		_, err := NewClient(srv.URL).Deliver(context.Background(), e)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), `"public_id":"01J9Z8M2Q4V6X8Z0A2C4E6G8J0"`)
+		assert.NotContains(t, string(body), `"id":7`)
+
+		_, err = NewClient(srv.URL, WithFormat(FormatSimple)).Deliver(context.Background(), e)
+		require.NoError(t, err)
+		var got SimplePayload
+		require.NoError(t, json.Unmarshal(body, &got))
+		assert.Equal(t, "01J9Z8M2Q4V6X8Z0A2C4E6G8J0", got.SubscriptionPublicID)
+		assert.Zero(t, got.SubscriptionID)
+	})
+
+	t.Run("test sends the chosen event signed", func(t *testing.T) {
+		c := NewClient(srv.URL, WithSecret("s3cret"))
+		d, err := c.Test(context.Background(), usecase.EventSubscriptionUpdated)
+		require.NoError(t, err)
+		assert.Equal(t, "subscription.updated", headers.Get("X-Webhook-Event"))
+		assert.Equal(t, "sha256="+sign("s3cret", body), headers.Get("X-Webhook-Signature"))
+		assert.JSONEq(t, string(body), string(d.Body))
+
+		_, err = c.Test(context.Background(), "user.deleted")
+		assert.ErrorIs(t, err, ErrUnknownEvent)
+		assert.Equal(t, int64(1), c.Status().Delivered, "an unknown event is not sent")
+	})
+
+	t.Run("non-2xx fails", func(t *testing.T) {
+		status = http.StatusGone
+		defer func() { status = http.StatusOK }()
+
+		c := NewClient(srv.URL)
+		d, err := c.Test(context.Background(), "")
+		assert.ErrorIs(t, err, ErrDeliveryFailed)
+		assert.Equal(t, http.StatusGone, d.StatusCode)
+
+		st := c.Status()
+		assert.Equal(t, int64(1), st.Failed)
+		assert.Equal(t, http.StatusGone, st.LastStatusCode)
+		assert.Equal(t, string(EventTest), st.LastEvent)
+		assert.NotEmpty(t, st.LastError)
+		assert.Contains(t, string(d.Body), `"webhook.test"`)
+	})
+}
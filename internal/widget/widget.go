@@ -0,0 +1,181 @@
+// Package widget hands out public tokens for the monthly spend widget users embed on their own sites. A token
+// reads one number, the user's spend this month, and only from the origins it was created for: it sits in the
+// page source for anyone to copy, the origin check keeps other sites from using it in their visitors' browsers.
+// As with share links only the SHA-256 of the token is stored
+package widget
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/errcode"
+	"subs_tracker/pkg/clock"
+)
+
+const (
+	// Prefix - every widget token starts with it, so it is told apart from API tokens and found by secret scanners
+	Prefix     = "wdg_"
+	tokenBytes = 24
+	maxOrigins = 10
+)
+
+var (
+	ErrNotFound       = errcode.New(errcode.WidgetNotFound, "widget token not found")
+	ErrRevoked        = errcode.New(errcode.WidgetNotFound, "widget token revoked")
+	ErrInvalidOrigins = errcode.New(errcode.WidgetInvalid, "invalid widget origins")
+)
+
+// Token — a stored widget token
+type Token struct {
+	// TokenHash - hex SHA-256 of the token handed to the user
+	TokenHash string
+	// UserID - whose spend the widget shows
+	UserID entity.UserID
+	// Origins - the sites allowed to call the widget, as browsers send them in Origin: "https://example.com"
+	Origins   []string
+	CreatedAt time.Time
+	// RevokedAt - when the token was revoked, nil while it works
+	RevokedAt *time.Time
+}
+
+// Allows reports whether a browser at origin may use the token
+func (t Token) Allows(origin string) bool {
+	return slices.Contains(t.Origins, strings.ToLower(origin))
+}
+
+// Store — storage of widget tokens
+type Store interface {
+	// SaveToken - store a new token
+	SaveToken(ctx context.Context, t Token) error
+	// GetToken - get a token by its hash, ErrNotFound if there is none
+	GetToken(ctx context.Context, hash string) (*Token, error)
+	// RevokeToken - mark a token revoked at, ErrNotFound if there is none; revoking twice keeps the first time
+	RevokeToken(ctx context.Context, hash string, at time.Time) error
+	// DeleteUser - remove every token of the user
+	DeleteUser(ctx context.Context, user entity.UserID) error
+}
+
+// Tokens creates, resolves and revokes widget tokens
+type Tokens struct {
+	store Store
+	clock clock.Clock
+}
+
+// NewTokens creates tokens kept in store and applies options
+func NewTokens(store Store, options ...func(*Tokens)) *Tokens {
+	t := &Tokens{store: store, clock: clock.System}
+	for _, o := range options {
+		o(t)
+	}
+	return t
+}
+
+// WithClock returns an option that sets the source of creation and revocation times
+func WithClock(c clock.Clock) func(*Tokens) {
+	return func(t *Tokens) {
+		if c != nil {
+			t.clock = c
+		}
+	}
+}
+
+// Create stores a token showing the user's spend to the origins and returns it. Origins are the scheme, host
+// and port of the sites, e.g. "https://example.com"; paths, wildcards and other schemes are refused
+func (t *Tokens) Create(ctx context.Context, userID entity.UserID, origins []string) (string, Token, error) {
+	if userID.IsZero() {
+		return "", Token{}, entity.ErrInvalidUserID
+	}
+	clean, err := ParseOrigins(origins)
+	if err != nil {
+		return "", Token{}, err
+	}
+
+	raw := make([]byte, tokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", Token{}, fmt.Errorf("create widget token: %w", err)
+	}
+	token := Prefix + base64.RawURLEncoding.EncodeToString(raw)
+	tok := Token{
+		TokenHash: hashToken(token),
+		UserID:    userID,
+		Origins:   clean,
+		CreatedAt: t.clock.Now().UTC(),
+	}
+	if err := t.store.SaveToken(ctx, tok); err != nil {
+		return "", Token{}, fmt.Errorf("create widget token: %w", err)
+	}
+	return token, tok, nil
+}
+
+// Resolve returns the working token: ErrNotFound for unknown tokens, ErrRevoked once revoked
+func (t *Tokens) Resolve(ctx context.Context, token string) (Token, error) {
+	tok, err := t.get(ctx, token)
+	if err != nil {
+		return Token{}, err
+	}
+	if tok.RevokedAt != nil {
+		return Token{}, ErrRevoked
+	}
+	return *tok, nil
+}
+
+// Revoke stops the token from working
+func (t *Tokens) Revoke(ctx context.Context, token string) error {
+	tok, err := t.get(ctx, token)
+	if err != nil {
+		return err
+	}
+	return t.store.RevokeToken(ctx, tok.TokenHash, t.clock.Now().UTC())
+}
+
+// Forget removes every token of the user, revoked ones too
+func (t *Tokens) Forget(ctx context.Context, userID entity.UserID) error {
+	if err := t.store.DeleteUser(ctx, userID); err != nil {
+		return fmt.Errorf("forget widget tokens: %w", err)
+	}
+	return nil
+}
+
+func (t *Tokens) get(ctx context.Context, token string) (*Token, error) {
+	enc, ok := strings.CutPrefix(token, Prefix)
+	if raw, err := base64.RawURLEncoding.DecodeString(enc); !ok || err != nil || len(raw) != tokenBytes {
+		return nil, ErrNotFound
+	}
+	return t.store.GetToken(ctx, hashToken(token))
+}
+
+// ParseOrigins checks and normalizes the origins of a token: one to ten http or https origins without a path,
+// lower-cased and without duplicates
+func ParseOrigins(origins []string) ([]string, error) {
+	if len(origins) == 0 || len(origins) > maxOrigins {
+		return nil, fmt.Errorf("%w: between 1 and %d are needed", ErrInvalidOrigins, maxOrigins)
+	}
+	out := make([]string, 0, len(origins))
+	for _, o := range origins {
+		u, err := url.Parse(strings.TrimSpace(o))
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.User != nil ||
+			strings.Trim(u.Path, "/") != "" || u.RawQuery != "" || u.Fragment != "" || strings.Contains(u.Host, "*") {
+			return nil, fmt.Errorf("%w: %q is not a site like https://example.com", ErrInvalidOrigins, o)
+		}
+		origin := strings.ToLower(u.Scheme + "://" + u.Host)
+		if !slices.Contains(out, origin) {
+			out = append(out, origin)
+		}
+	}
+	return out, nil
+}
+
+// hashToken is what the store keeps instead of the token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,74 @@
+package widget_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"subs_tracker/internal/entity"
+	"subs_tracker/internal/storetest"
+	"subs_tracker/internal/widget"
+	"subs_tracker/pkg/clock"
+)
+
+func TestTokens(t *testing.T) {
+	ctx := context.Background()
+	now := clock.NewFake(time.Date(2025, time.July, 10, 12, 0, 0, 0, time.UTC))
+	store := storetest.NewWidgetStore()
+	tokens := widget.NewTokens(store, widget.WithClock(now))
+	ann := entity.UserID(uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba"))
+
+	_, _, err := tokens.Create(ctx, entity.UserID{}, []string{"https://ann.example"})
+	assert.ErrorIs(t, err, entity.ErrInvalidUserID)
+	_, _, err = tokens.Create(ctx, ann, nil)
+	assert.ErrorIs(t, err, widget.ErrInvalidOrigins)
+
+	token, tok, err := tokens.Create(ctx, ann, []string{" https://Ann.example ", "https://ann.example/", "http://localhost:8080"})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, widget.Prefix))
+	assert.Equal(t, []string{"https://ann.example", "http://localhost:8080"}, tok.Origins)
+	stored, err := store.GetToken(ctx, tok.TokenHash)
+	require.NoError(t, err)
+	assert.NotContains(t, stored.TokenHash, token, "the token itself must not be stored")
+
+	got, err := tokens.Resolve(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, ann, got.UserID)
+	assert.True(t, got.Allows("https://ann.example"))
+	assert.True(t, got.Allows("HTTPS://ANN.EXAMPLE"))
+	assert.False(t, got.Allows("https://evil.example"))
+	assert.False(t, got.Allows(""), "requests without an origin are not from the widget")
+
+	_, err = tokens.Resolve(ctx, "not-a-token")
+	assert.ErrorIs(t, err, widget.ErrNotFound)
+	_, err = tokens.Resolve(ctx, widget.Prefix+strings.Repeat("A", 32))
+	assert.ErrorIs(t, err, widget.ErrNotFound, "well-formed but unknown")
+
+	require.NoError(t, tokens.Revoke(ctx, token))
+	_, err = tokens.Resolve(ctx, token)
+	assert.ErrorIs(t, err, widget.ErrRevoked)
+	require.NoError(t, tokens.Revoke(ctx, token), "revoking twice is fine")
+}
+
+func TestParseOrigins(t *testing.T) {
+	for _, bad := range []string{
+		"ann.example",
+		"ftp://ann.example",
+		"https://*.ann.example",
+		"https://ann.example/widget",
+		"https://ann.example?x=1",
+		"https://user@ann.example",
+		"*",
+		"null",
+	} {
+		_, err := widget.ParseOrigins([]string{bad})
+		assert.ErrorIs(t, err, widget.ErrInvalidOrigins, bad)
+	}
+	_, err := widget.ParseOrigins(make([]string, 11))
+	assert.ErrorIs(t, err, widget.ErrInvalidOrigins, "too many")
+}
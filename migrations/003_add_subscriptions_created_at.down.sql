@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_subs_updated;
+
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trg_subs_change_log ON subscriptions;
+DROP FUNCTION IF EXISTS log_subscription_change();
+DROP TABLE IF EXISTS subscription_changes;
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS subscription_changes
+(
+    seq             BIGSERIAL PRIMARY KEY,
+    subscription_id BIGINT      NOT NULL,
+    op              VARCHAR(10) NOT NULL CHECK (op IN ('insert', 'update', 'delete')),
+    changed_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE OR REPLACE FUNCTION log_subscription_change() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO subscription_changes (subscription_id, op) VALUES (OLD.id, 'delete');
+        RETURN OLD;
+    END IF;
+    INSERT INTO subscription_changes (subscription_id, op) VALUES (NEW.id, lower(TG_OP));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_subs_change_log ON subscriptions;
+CREATE TRIGGER trg_subs_change_log
+    AFTER INSERT OR UPDATE OR DELETE ON subscriptions
+    FOR EACH ROW EXECUTE FUNCTION log_subscription_change();
+
+-- rows written before the change log existed are reported as inserts
+INSERT INTO subscription_changes (subscription_id, op)
+SELECT id, 'insert' FROM subscriptions ORDER BY id;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS admin_audit_log
+(
+    id         BIGSERIAL PRIMARY KEY,
+    action     VARCHAR(50) NOT NULL,
+    actor      TEXT        NOT NULL DEFAULT '',
+    details    JSONB       NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_created ON admin_audit_log (created_at);
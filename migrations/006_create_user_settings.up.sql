@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS user_settings
+(
+    user_id           UUID PRIMARY KEY,
+    currency          VARCHAR(3)  NOT NULL DEFAULT 'RUB',
+    locale            VARCHAR(16) NOT NULL DEFAULT 'ru',
+    first_day_of_week SMALLINT    NOT NULL DEFAULT 1 CHECK (first_day_of_week BETWEEN 0 AND 6),
+    date_format       VARCHAR(32) NOT NULL DEFAULT '01-2006',
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT now()
+);
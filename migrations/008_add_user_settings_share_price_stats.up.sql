@@ -0,0 +1,2 @@
+ALTER TABLE user_settings
+    ADD COLUMN IF NOT EXISTS share_price_stats BOOLEAN NOT NULL DEFAULT FALSE;
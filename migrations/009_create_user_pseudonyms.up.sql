@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS user_pseudonyms
+(
+    pseudonym   UUID PRIMARY KEY,
+    user_id_enc TEXT        NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
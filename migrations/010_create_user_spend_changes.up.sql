@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS user_spend_changes
+(
+    user_id    UUID   NOT NULL,
+    month      DATE   NOT NULL,
+    cost_delta BIGINT NOT NULL,
+    subs_delta INT    NOT NULL,
+    PRIMARY KEY (user_id, month)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_spend_changes_month ON user_spend_changes (month);
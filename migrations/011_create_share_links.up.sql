@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS share_links
+(
+    token_hash   VARCHAR(64) PRIMARY KEY,
+    user_id      UUID         NOT NULL,
+    service_name VARCHAR(100) NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ  NOT NULL,
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_share_links_user ON share_links (user_id);
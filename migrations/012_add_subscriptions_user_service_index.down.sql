@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_subs_user_service_start;
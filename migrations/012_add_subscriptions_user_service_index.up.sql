@@ -0,0 +1,2 @@
+-- per-user cost and list queries filter by user and optionally service, then by period
+CREATE INDEX IF NOT EXISTS idx_subs_user_service_start ON subscriptions (user_id, service_name, start_date);
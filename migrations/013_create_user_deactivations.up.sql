@@ -0,0 +1,6 @@
+-- a row per deactivated user; their subscriptions stay but are left out of lists, costs and exports
+CREATE TABLE IF NOT EXISTS user_deactivations
+(
+    user_id        UUID PRIMARY KEY,
+    deactivated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
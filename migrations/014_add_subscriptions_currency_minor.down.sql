@@ -0,0 +1,9 @@
+DROP TRIGGER IF EXISTS trg_subs_change_log ON subscriptions;
+CREATE TRIGGER trg_subs_change_log
+    AFTER INSERT OR UPDATE OR DELETE ON subscriptions
+    FOR EACH ROW EXECUTE FUNCTION log_subscription_change();
+
+DROP INDEX IF EXISTS idx_subs_cost_minor_pending;
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS cost_minor,
+    DROP COLUMN IF EXISTS currency;
@@ -0,0 +1,12 @@
+-- currency and cost in minor units (kopecks) ahead of multi-currency; NULL marks a row still holding only the
+-- legacy whole-ruble cost, filled in by the legacy cost backfill (subsctl admin migrate-costs)
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS currency   VARCHAR(3),
+    ADD COLUMN IF NOT EXISTS cost_minor BIGINT;
+CREATE INDEX IF NOT EXISTS idx_subs_cost_minor_pending ON subscriptions (id) WHERE cost_minor IS NULL;
+
+-- the backfill only derives the new columns, so it is kept out of the change log read by sync clients
+DROP TRIGGER IF EXISTS trg_subs_change_log ON subscriptions;
+CREATE TRIGGER trg_subs_change_log
+    AFTER INSERT OR UPDATE OF user_id, service_name, cost, start_date, end_date, updated_at OR DELETE ON subscriptions
+    FOR EACH ROW EXECUTE FUNCTION log_subscription_change();
@@ -0,0 +1,16 @@
+-- refunds, credits and one-off extra charges of a subscription in a month; a negative amount lowers the spend of
+-- the month, so cost totals are net of them
+CREATE TABLE IF NOT EXISTS subscription_adjustments
+(
+    id              BIGSERIAL PRIMARY KEY,
+    subscription_id BIGINT      NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    month           DATE        NOT NULL,
+    amount          BIGINT      NOT NULL,
+    note            TEXT        NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CHECK (amount <> 0),
+    CHECK (extract(DAY FROM month) = 1)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sub_adjustments_sub_month ON subscription_adjustments (subscription_id, month);
@@ -0,0 +1,14 @@
+-- seats of a family or team plan: the owner of the subscription pays for it and holds one seat, the members in
+-- member_ids hold the others and see their share of the cost in their summary
+CREATE TABLE IF NOT EXISTS subscription_seats
+(
+    subscription_id BIGINT PRIMARY KEY REFERENCES subscriptions (id) ON DELETE CASCADE,
+    total           INT         NOT NULL,
+    member_ids      UUID[]      NOT NULL DEFAULT '{}',
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CHECK (total >= 2),
+    CHECK (cardinality(member_ids) < total)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sub_seats_members ON subscription_seats USING GIN (member_ids);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_subs_public_id;
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS public_id;
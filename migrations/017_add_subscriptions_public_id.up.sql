@@ -0,0 +1,6 @@
+-- public identifiers shown to clients instead of the serial id with SUBSCRIPTION_ID_STRATEGY=uuidv7 or ulid.
+-- The application writes time-ordered ones; the random default fills the rows written before this migration
+-- and by anything inserting without one. Adding the column rewrites the table, without firing the change log
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS public_id UUID NOT NULL DEFAULT gen_random_uuid();
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subs_public_id ON subscriptions (public_id);
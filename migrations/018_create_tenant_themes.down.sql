@@ -0,0 +1,3 @@
+ALTER TABLE share_links
+    DROP COLUMN IF EXISTS tenant_id;
+DROP TABLE IF EXISTS tenant_themes;
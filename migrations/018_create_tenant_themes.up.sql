@@ -0,0 +1,12 @@
+-- branding of the reports rendered for a tenant; share links remember the tenant they were created for
+CREATE TABLE IF NOT EXISTS tenant_themes
+(
+    tenant_id  VARCHAR(64)   PRIMARY KEY,
+    logo_url   VARCHAR(2048) NOT NULL DEFAULT '',
+    color      VARCHAR(7)    NOT NULL DEFAULT '',
+    footer     VARCHAR(500)  NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ   NOT NULL DEFAULT now()
+);
+
+ALTER TABLE share_links
+    ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT '';
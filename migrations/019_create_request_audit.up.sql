@@ -0,0 +1,19 @@
+-- who sent which write request: the hash of its raw body and its source, purged after REQUEST_AUDIT_RETENTION
+CREATE TABLE IF NOT EXISTS request_audit
+(
+    id          BIGSERIAL    PRIMARY KEY,
+    received_at TIMESTAMPTZ  NOT NULL,
+    method      VARCHAR(10)  NOT NULL,
+    route       VARCHAR(200) NOT NULL,
+    path        TEXT         NOT NULL,
+    status      SMALLINT     NOT NULL,
+    body_sha256 CHAR(64)     NOT NULL,
+    body_size   BIGINT       NOT NULL,
+    client      VARCHAR(100) NOT NULL,
+    remote_ip   VARCHAR(45)  NOT NULL,
+    user_agent  VARCHAR(512) NOT NULL DEFAULT '',
+    request_id  VARCHAR(100) NOT NULL DEFAULT '',
+    tenant_id   VARCHAR(64)  NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_request_audit_received ON request_audit (received_at);
@@ -0,0 +1,11 @@
+-- public tokens of the monthly spend widget, usable only from their origins
+CREATE TABLE IF NOT EXISTS widget_tokens
+(
+    token_hash VARCHAR(64) PRIMARY KEY,
+    user_id    UUID        NOT NULL,
+    origins    TEXT[]      NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_widget_tokens_user ON widget_tokens (user_id);
@@ -0,0 +1,9 @@
+-- webhooks users registered for their own automations, at most one per user
+CREATE TABLE IF NOT EXISTS user_webhooks
+(
+    user_id    UUID PRIMARY KEY,
+    url        TEXT        NOT NULL,
+    format     VARCHAR(16) NOT NULL,
+    secret     TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
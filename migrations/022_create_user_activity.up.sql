@@ -0,0 +1,16 @@
+-- feed of what happened to the subscriptions of each user, read newest first
+CREATE TABLE IF NOT EXISTS user_activity
+(
+    id              BIGSERIAL PRIMARY KEY,
+    user_id         UUID         NOT NULL,
+    kind            VARCHAR(32)  NOT NULL,
+    subscription_id BIGINT       NOT NULL,
+    public_id       TEXT         NOT NULL DEFAULT '',
+    service_name    VARCHAR(100) NOT NULL,
+    cost            BIGINT       NOT NULL,
+    previous_cost   BIGINT       NOT NULL DEFAULT 0,
+    end_date        DATE,
+    occurred_at     TIMESTAMPTZ  NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_activity_user_id ON user_activity (user_id, id DESC);
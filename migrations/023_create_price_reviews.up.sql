@@ -0,0 +1,17 @@
+-- subscriptions whose cost differs from the catalog price of their service, waiting for an operator
+CREATE TABLE IF NOT EXISTS price_reviews
+(
+    id              BIGSERIAL PRIMARY KEY,
+    subscription_id BIGINT       NOT NULL,
+    public_id       UUID         NOT NULL,
+    user_id         UUID         NOT NULL,
+    service_name    VARCHAR(100) NOT NULL,
+    cost            BIGINT       NOT NULL,
+    catalog_price   BIGINT       NOT NULL,
+    opened_at       TIMESTAMPTZ  NOT NULL,
+    resolved_at     TIMESTAMPTZ
+);
+
+-- a subscription is reviewed once per catalog price, however the review was resolved
+CREATE UNIQUE INDEX IF NOT EXISTS idx_price_reviews_subscription ON price_reviews (subscription_id, catalog_price);
+CREATE INDEX IF NOT EXISTS idx_price_reviews_open ON price_reviews (id) WHERE resolved_at IS NULL;
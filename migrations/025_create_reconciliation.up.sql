@@ -0,0 +1,40 @@
+-- charges of bank statements matched to a service, the history subscriptions are reconciled against; overlapping
+-- statements are uploaded again and again, so a charge is kept once per day and amount
+CREATE TABLE IF NOT EXISTS charges
+(
+    id           BIGSERIAL PRIMARY KEY,
+    user_id      UUID         NOT NULL,
+    service_name VARCHAR(100) NOT NULL,
+    charged_on   DATE         NOT NULL,
+    amount       BIGINT       NOT NULL,
+    recorded_at  TIMESTAMPTZ  NOT NULL DEFAULT now(),
+
+    CHECK (amount > 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_charges_unique ON charges (user_id, lower(service_name), charged_on, amount);
+CREATE INDEX IF NOT EXISTS idx_charges_charged_on ON charges (charged_on);
+
+-- months where the charges and the subscriptions of a user disagree, waiting for an operator
+CREATE TABLE IF NOT EXISTS reconciliation_items
+(
+    id              BIGSERIAL PRIMARY KEY,
+    kind            VARCHAR(16)  NOT NULL,
+    user_id         UUID         NOT NULL,
+    service_name    VARCHAR(100) NOT NULL,
+    month           DATE         NOT NULL,
+    expected        BIGINT       NOT NULL DEFAULT 0,
+    charged         BIGINT       NOT NULL DEFAULT 0,
+    subscription_id BIGINT       NOT NULL DEFAULT 0,
+    public_id       UUID,
+    opened_at       TIMESTAMPTZ  NOT NULL,
+    resolved_at     TIMESTAMPTZ,
+
+    CHECK (kind IN ('missed', 'unexpected')),
+    CHECK (extract(DAY FROM month) = 1)
+);
+
+-- a mismatch is reported once per service and month, however it was resolved
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reconciliation_items_unique
+    ON reconciliation_items (user_id, month, kind, lower(service_name));
+CREATE INDEX IF NOT EXISTS idx_reconciliation_items_open ON reconciliation_items (id) WHERE resolved_at IS NULL;
@@ -0,0 +1,16 @@
+-- rows of an import that failed validation, kept for the user to fix and submit again; the values are kept as
+-- imported, so the columns do not check what subscriptions do
+CREATE TABLE IF NOT EXISTS import_quarantine
+(
+    id           BIGSERIAL PRIMARY KEY,
+    user_id      UUID         NOT NULL,
+    service_name TEXT         NOT NULL,
+    cost         BIGINT       NOT NULL,
+    start_date   DATE         NOT NULL,
+    end_date     DATE,
+    reason       TEXT         NOT NULL,
+    code         VARCHAR(64)  NOT NULL DEFAULT '',
+    held_at      TIMESTAMPTZ  NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_quarantine_user ON import_quarantine (user_id, id);
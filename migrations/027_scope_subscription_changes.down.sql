@@ -0,0 +1,15 @@
+CREATE OR REPLACE FUNCTION log_subscription_change() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO subscription_changes (subscription_id, op) VALUES (OLD.id, 'delete');
+        RETURN OLD;
+    END IF;
+    INSERT INTO subscription_changes (subscription_id, op) VALUES (NEW.id, lower(TG_OP));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS idx_subs_changes_user;
+ALTER TABLE subscription_changes
+    DROP COLUMN IF EXISTS tx,
+    DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,30 @@
+-- sync reads the change log per user, and in transaction order up to the oldest transaction still running:
+-- seq is taken at insert, so a transaction can commit a lower seq after a reader saw a higher one
+ALTER TABLE subscription_changes
+    ADD COLUMN IF NOT EXISTS user_id UUID,
+    ADD COLUMN IF NOT EXISTS tx      XID8 NOT NULL DEFAULT pg_current_xact_id();
+
+-- entries of subscriptions deleted before now keep no owner and are not synced to anyone
+UPDATE subscription_changes c
+SET user_id = s.user_id
+FROM subscriptions s
+WHERE s.id = c.subscription_id;
+
+CREATE INDEX IF NOT EXISTS idx_subs_changes_user ON subscription_changes (user_id, tx, seq);
+
+CREATE OR REPLACE FUNCTION log_subscription_change() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO subscription_changes (subscription_id, user_id, op) VALUES (OLD.id, OLD.user_id, 'delete');
+        RETURN OLD;
+    END IF;
+    IF TG_OP = 'UPDATE' AND OLD.user_id <> NEW.user_id THEN
+        -- a subscription moved to another user leaves the sync of the former owner
+        INSERT INTO subscription_changes (subscription_id, user_id, op) VALUES (OLD.id, OLD.user_id, 'delete');
+        INSERT INTO subscription_changes (subscription_id, user_id, op) VALUES (NEW.id, NEW.user_id, 'insert');
+        RETURN NEW;
+    END IF;
+    INSERT INTO subscription_changes (subscription_id, user_id, op) VALUES (NEW.id, NEW.user_id, lower(TG_OP));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,19 @@
+CREATE OR REPLACE FUNCTION log_subscription_change() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO subscription_changes (subscription_id, user_id, op) VALUES (OLD.id, OLD.user_id, 'delete');
+        RETURN OLD;
+    END IF;
+    IF TG_OP = 'UPDATE' AND OLD.user_id <> NEW.user_id THEN
+        -- a subscription moved to another user leaves the sync of the former owner
+        INSERT INTO subscription_changes (subscription_id, user_id, op) VALUES (OLD.id, OLD.user_id, 'delete');
+        INSERT INTO subscription_changes (subscription_id, user_id, op) VALUES (NEW.id, NEW.user_id, 'insert');
+        RETURN NEW;
+    END IF;
+    INSERT INTO subscription_changes (subscription_id, user_id, op) VALUES (NEW.id, NEW.user_id, lower(TG_OP));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE subscription_changes
+    DROP COLUMN IF EXISTS public_id;
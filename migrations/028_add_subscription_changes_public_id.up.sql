@@ -0,0 +1,30 @@
+-- sync names subscriptions by their public ID under a public ID strategy, deleted ones included, so the log
+-- keeps it next to the serial ID
+ALTER TABLE subscription_changes
+    ADD COLUMN IF NOT EXISTS public_id UUID;
+
+UPDATE subscription_changes c
+SET public_id = s.public_id
+FROM subscriptions s
+WHERE s.id = c.subscription_id;
+
+CREATE OR REPLACE FUNCTION log_subscription_change() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO subscription_changes (subscription_id, public_id, user_id, op)
+        VALUES (OLD.id, OLD.public_id, OLD.user_id, 'delete');
+        RETURN OLD;
+    END IF;
+    IF TG_OP = 'UPDATE' AND OLD.user_id <> NEW.user_id THEN
+        -- a subscription moved to another user leaves the sync of the former owner
+        INSERT INTO subscription_changes (subscription_id, public_id, user_id, op)
+        VALUES (OLD.id, OLD.public_id, OLD.user_id, 'delete');
+        INSERT INTO subscription_changes (subscription_id, public_id, user_id, op)
+        VALUES (NEW.id, NEW.public_id, NEW.user_id, 'insert');
+        RETURN NEW;
+    END IF;
+    INSERT INTO subscription_changes (subscription_id, public_id, user_id, op)
+    VALUES (NEW.id, NEW.public_id, NEW.user_id, lower(TG_OP));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_import_quarantine_key;
+
+ALTER TABLE import_quarantine
+    DROP COLUMN IF EXISTS import_key;
+
+DROP TABLE IF EXISTS import_keys;
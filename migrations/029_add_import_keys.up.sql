@@ -0,0 +1,16 @@
+-- keys of the confirmed import proposals: confirming a proposal again returns the subscription it created
+-- instead of creating another one; a key goes away with its subscription
+CREATE TABLE IF NOT EXISTS import_keys
+(
+    key             TEXT PRIMARY KEY,
+    subscription_id BIGINT      NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_keys_subscription ON import_keys (subscription_id);
+
+-- and a rejected row of a proposal confirmed again is held once
+ALTER TABLE import_quarantine
+    ADD COLUMN IF NOT EXISTS import_key TEXT NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_import_quarantine_key ON import_quarantine (import_key) WHERE import_key <> '';
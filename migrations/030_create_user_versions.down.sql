@@ -0,0 +1,14 @@
+DROP TRIGGER IF EXISTS trg_user_deactivations_user_version ON user_deactivations;
+DROP TRIGGER IF EXISTS trg_user_settings_user_version ON user_settings;
+DROP TRIGGER IF EXISTS trg_sub_seats_user_version ON subscription_seats;
+DROP TRIGGER IF EXISTS trg_sub_adjustments_user_version ON subscription_adjustments;
+DROP TRIGGER IF EXISTS trg_subs_user_version ON subscriptions;
+
+DROP FUNCTION IF EXISTS bump_row_user_version();
+DROP FUNCTION IF EXISTS bump_seats_user_version();
+DROP FUNCTION IF EXISTS bump_adjustment_user_version();
+DROP FUNCTION IF EXISTS bump_subscription_user_version();
+DROP FUNCTION IF EXISTS bump_user_version(UUID);
+
+DROP TABLE IF EXISTS user_versions;
+DROP SEQUENCE IF EXISTS user_versions_seq;
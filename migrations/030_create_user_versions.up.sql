@@ -0,0 +1,116 @@
+-- a version per user, bumped in the writing transaction by every change to what their cost reports show:
+-- subscriptions, deletes included, their adjustments and seats, settings and deactivation. The cost endpoint
+-- serves it as its validator; a bump takes the next value of one sequence after locking the row, so a user's
+-- version only grows and the sum over all users changes with every committed bump
+CREATE SEQUENCE IF NOT EXISTS user_versions_seq;
+
+CREATE TABLE IF NOT EXISTS user_versions
+(
+    user_id    UUID PRIMARY KEY,
+    version    BIGINT      NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE OR REPLACE FUNCTION bump_user_version(uid UUID) RETURNS void AS $$
+BEGIN
+    IF uid IS NULL THEN
+        RETURN;
+    END IF;
+    INSERT INTO user_versions (user_id, version)
+    VALUES (uid, nextval('user_versions_seq'))
+    ON CONFLICT (user_id) DO UPDATE SET version = nextval('user_versions_seq'), changed_at = clock_timestamp();
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION bump_subscription_user_version() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        PERFORM bump_user_version(OLD.user_id);
+    END IF;
+    IF TG_OP <> 'DELETE' AND (TG_OP = 'INSERT' OR OLD.user_id <> NEW.user_id) THEN
+        PERFORM bump_user_version(NEW.user_id);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_subs_user_version ON subscriptions;
+CREATE TRIGGER trg_subs_user_version
+    AFTER INSERT OR UPDATE OF user_id, service_name, cost, start_date, end_date, updated_at OR DELETE ON subscriptions
+    FOR EACH ROW EXECUTE FUNCTION bump_subscription_user_version();
+
+-- adjustments and seats bump the owner of their subscription, seats the members too; a row removed with its
+-- subscription finds no owner, whose version the subscription bumped already
+CREATE OR REPLACE FUNCTION bump_adjustment_user_version() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        PERFORM bump_user_version((SELECT user_id FROM subscriptions WHERE id = OLD.subscription_id));
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        PERFORM bump_user_version((SELECT user_id FROM subscriptions WHERE id = NEW.subscription_id));
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_sub_adjustments_user_version ON subscription_adjustments;
+CREATE TRIGGER trg_sub_adjustments_user_version
+    AFTER INSERT OR UPDATE OR DELETE ON subscription_adjustments
+    FOR EACH ROW EXECUTE FUNCTION bump_adjustment_user_version();
+
+CREATE OR REPLACE FUNCTION bump_seats_user_version() RETURNS trigger AS $$
+DECLARE
+    member UUID;
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        PERFORM bump_user_version((SELECT user_id FROM subscriptions WHERE id = OLD.subscription_id));
+        FOREACH member IN ARRAY OLD.member_ids LOOP
+            PERFORM bump_user_version(member);
+        END LOOP;
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        PERFORM bump_user_version((SELECT user_id FROM subscriptions WHERE id = NEW.subscription_id));
+        FOREACH member IN ARRAY NEW.member_ids LOOP
+            PERFORM bump_user_version(member);
+        END LOOP;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_sub_seats_user_version ON subscription_seats;
+CREATE TRIGGER trg_sub_seats_user_version
+    AFTER INSERT OR UPDATE OR DELETE ON subscription_seats
+    FOR EACH ROW EXECUTE FUNCTION bump_seats_user_version();
+
+-- settings and deactivation are keyed by the user
+CREATE OR REPLACE FUNCTION bump_row_user_version() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM bump_user_version(OLD.user_id);
+    ELSE
+        PERFORM bump_user_version(NEW.user_id);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_user_settings_user_version ON user_settings;
+CREATE TRIGGER trg_user_settings_user_version
+    AFTER INSERT OR UPDATE OR DELETE ON user_settings
+    FOR EACH ROW EXECUTE FUNCTION bump_row_user_version();
+
+DROP TRIGGER IF EXISTS trg_user_deactivations_user_version ON user_deactivations;
+CREATE TRIGGER trg_user_deactivations_user_version
+    AFTER INSERT OR DELETE ON user_deactivations
+    FOR EACH ROW EXECUTE FUNCTION bump_row_user_version();
+
+-- users with data from before the versions get one of their own
+INSERT INTO user_versions (user_id, version)
+SELECT user_id, nextval('user_versions_seq')
+FROM (SELECT user_id FROM subscriptions
+      UNION
+      SELECT user_id FROM user_settings
+      UNION
+      SELECT user_id FROM user_deactivations) u
+ON CONFLICT (user_id) DO NOTHING;
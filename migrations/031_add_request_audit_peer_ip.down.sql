@@ -0,0 +1,2 @@
+ALTER TABLE request_audit
+    DROP COLUMN IF EXISTS peer_ip;
@@ -0,0 +1,3 @@
+-- the address the connection came from, next to remote_ip which a trusted proxy may have set from X-Forwarded-For
+ALTER TABLE request_audit
+    ADD COLUMN IF NOT EXISTS peer_ip VARCHAR(45) NOT NULL DEFAULT '';
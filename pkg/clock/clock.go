@@ -0,0 +1,56 @@
+// Package clock is the source of the current time for code that must be testable on specific dates:
+// production uses System, tests a Fake set to the date under test.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock — tells the current time
+type Clock interface {
+	Now() time.Time
+}
+
+// System — Clock reading the wall clock
+var System Clock = Func(time.Now)
+
+// Func adapts a function to Clock
+type Func func() time.Time
+
+// Now calls f
+func (f Func) Now() time.Time {
+	return f()
+}
+
+// Fake — Clock that stands still until moved; safe for concurrent use
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFake returns a Fake stopped at t
+func NewFake(t time.Time) *Fake {
+	return &Fake{now: t}
+}
+
+// Now returns the time the clock is set to
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Set moves the clock to t
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = t
+}
+
+// Advance moves the clock forward by d
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
@@ -0,0 +1,27 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	c := NewFake(start)
+	assert.Equal(t, start, c.Now())
+
+	c.Advance(36 * time.Hour)
+	assert.Equal(t, time.Date(2025, 7, 3, 0, 0, 0, 0, time.UTC), c.Now())
+
+	c.Set(start)
+	assert.Equal(t, start, c.Now())
+}
+
+func TestFunc(t *testing.T) {
+	at := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	var c Clock = Func(func() time.Time { return at })
+	assert.Equal(t, at, c.Now())
+	assert.WithinDuration(t, time.Now(), System.Now(), time.Second)
+}
@@ -0,0 +1,271 @@
+// Package contracttest is the published HTTP contract of the service: a suite of request/response
+// assertions that consumers run in their CI against the version they depend on, either on a server
+// started in process over the in-memory repository or on a deployed instance.
+package contracttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	cfg "subs_tracker/internal/config"
+	httpgw "subs_tracker/internal/gateways/http"
+	"subs_tracker/internal/repository/subscription/memory"
+	"subs_tracker/internal/usecase"
+)
+
+// Case — one request and what its response must contain. {name} placeholders in Path, Header, Body and JSON
+// are replaced with variables: {user} is a fresh user ID per run, the rest are captured by earlier cases
+type Case struct {
+	Name   string
+	Method string
+	Path   string
+	Header http.Header
+	Body   string
+	// Status is the expected response status
+	Status int
+	// JSON, when set, must be a subset of the response body: objects may carry extra keys, arrays must match in length
+	JSON string
+	// Vars captures top-level fields of the response object into variables for later cases
+	Vars map[string]string
+}
+
+// Suite returns the contract cases in the order they must run
+func Suite() []Case {
+	return []Case{
+		{
+			Name:   "create subscription",
+			Method: http.MethodPost,
+			Path:   "/api/v1/subscriptions",
+			Body:   `{"service_name":"Yandex Plus","cost":400,"user_id":"{user}","start_date":"07-2025"}`,
+			Status: http.StatusCreated,
+			JSON:   `{"service_name":"Yandex Plus","cost":400,"user_id":"{user}","start_date":"07-2025"}`,
+			Vars:   map[string]string{"id": "id"},
+		},
+		{
+			Name:   "get subscription",
+			Method: http.MethodGet,
+			Path:   "/api/v1/subscriptions/{id}",
+			Status: http.StatusOK,
+			JSON:   `{"id":{id},"service_name":"Yandex Plus","cost":400,"user_id":"{user}","start_date":"07-2025"}`,
+		},
+		{
+			Name:   "list subscriptions of the user",
+			Method: http.MethodGet,
+			Path:   "/api/v1/subscriptions?user_id={user}",
+			Status: http.StatusOK,
+			JSON:   `[{"id":{id},"service_name":"Yandex Plus"}]`,
+		},
+		{
+			Name:   "update subscription",
+			Method: http.MethodPut,
+			Path:   "/api/v1/subscriptions/{id}",
+			Body:   `{"service_name":"Yandex Plus","cost":500,"user_id":"{user}","start_date":"07-2025","end_date":"09-2025"}`,
+			Status: http.StatusOK,
+			JSON:   `{"id":{id},"cost":500,"end_date":"09-2025"}`,
+		},
+		{
+			Name:   "total cost of a period",
+			Method: http.MethodGet,
+			Path:   "/api/v1/subscriptions/cost?user_id={user}&start_date=06-2025&end_date=12-2025",
+			Status: http.StatusOK,
+			JSON:   `{"total":1500,"currency":"RUB"}`,
+		},
+		{
+			Name:   "cost without a period",
+			Method: http.MethodGet,
+			Path:   "/api/v1/subscriptions/cost?user_id={user}",
+			Status: http.StatusUnprocessableEntity,
+		},
+		{
+			Name:   "create without a service name",
+			Method: http.MethodPost,
+			Path:   "/api/v1/subscriptions",
+			Body:   `{"service_name":"","cost":400,"user_id":"{user}","start_date":"07-2025"}`,
+			Status: http.StatusUnprocessableEntity,
+		},
+		{
+			Name:   "default settings",
+			Method: http.MethodGet,
+			Path:   "/api/v1/users/{user}/settings",
+			Status: http.StatusOK,
+			JSON:   `{"currency":"RUB","locale":"ru","first_day_of_week":1,"date_format":"01-2006"}`,
+		},
+		{
+			Name:   "replace settings",
+			Method: http.MethodPut,
+			Path:   "/api/v1/users/{user}/settings",
+			Body:   `{"currency":"EUR","locale":"en","first_day_of_week":0,"date_format":"2006-01"}`,
+			Status: http.StatusOK,
+			JSON:   `{"currency":"EUR","locale":"en","first_day_of_week":0,"date_format":"2006-01"}`,
+		},
+		{
+			Name:   "cost in the user currency",
+			Method: http.MethodGet,
+			Path:   "/api/v1/subscriptions/cost?user_id={user}&start_date=07-2025&end_date=07-2025",
+			Status: http.StatusOK,
+			JSON:   `{"total":500,"currency":"EUR"}`,
+		},
+		{
+			Name:   "delete subscription",
+			Method: http.MethodDelete,
+			Path:   "/api/v1/subscriptions/{id}",
+			Status: http.StatusOK,
+			JSON:   `{"id":{id}}`,
+		},
+		{
+			Name:   "get deleted subscription",
+			Method: http.MethodGet,
+			Path:   "/api/v1/subscriptions/{id}",
+			Status: http.StatusNotFound,
+			JSON:   `{"error":"not found"}`,
+		},
+	}
+}
+
+// NewServer starts the service over an empty in-memory repository, configured as deployments are
+// (no HTTP_CONTRACT_VALIDATION, which would answer 400 where handlers answer 422). The caller closes it
+func NewServer() *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	r := httpgw.SetupGin(cfg.Config{Env: "test"}, httpgw.UseCases{Sub: usecase.NewSubscription(memory.NewRepository())}, log, nil)
+	return httptest.NewServer(r)
+}
+
+// Run runs the suite against a server from NewServer
+func Run(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	RunAgainst(t, srv.URL)
+}
+
+// RunAgainst runs the suite against the service at baseURL, e.g. a staging deployment.
+// Cases share state, so the first failure stops the run
+func RunAgainst(t *testing.T, baseURL string) {
+	vars := NewVars()
+	for _, tc := range Suite() {
+		if !t.Run(tc.Name, func(t *testing.T) {
+			if err := Check(baseURL, tc, vars); err != nil {
+				t.Fatal(err)
+			}
+		}) {
+			return
+		}
+	}
+}
+
+// NewVars returns the variables a run starts with: a fresh {user}
+func NewVars() map[string]string {
+	return map[string]string{"user": uuid.NewString()}
+}
+
+// Check sends the request of tc to baseURL, verifies the response and stores the captured variables in vars
+func Check(baseURL string, tc Case, vars map[string]string) error {
+	var body io.Reader
+	if tc.Body != "" {
+		body = strings.NewReader(expand(tc.Body, vars))
+	}
+	req, err := http.NewRequest(tc.Method, strings.TrimSuffix(baseURL, "/")+expand(tc.Path, vars), body)
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	for k, vs := range tc.Header {
+		for _, v := range vs {
+			req.Header.Add(k, expand(v, vars))
+		}
+	}
+	if tc.Body != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", req.Method, req.URL.Path, err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode != tc.Status {
+		return fmt.Errorf("status = %d, want %d; body: %s", resp.StatusCode, tc.Status, raw)
+	}
+	if tc.JSON == "" && len(tc.Vars) == 0 {
+		return nil
+	}
+
+	var got any
+	if err := json.Unmarshal(raw, &got); err != nil {
+		return fmt.Errorf("decode response: %w; body: %s", err, raw)
+	}
+	if tc.JSON != "" {
+		var want any
+		if err := json.Unmarshal([]byte(expand(tc.JSON, vars)), &want); err != nil {
+			return fmt.Errorf("decode expected JSON: %w", err)
+		}
+		if path, ok := contains(got, want, "$"); !ok {
+			return fmt.Errorf("response does not match at %s\n got: %s\nwant: %s", path, raw, expand(tc.JSON, vars))
+		}
+	}
+	obj, _ := got.(map[string]any)
+	for name, field := range tc.Vars {
+		v, ok := obj[field]
+		if !ok {
+			return fmt.Errorf("response has no %q to capture; body: %s", field, raw)
+		}
+		enc, _ := json.Marshal(v)
+		vars[name] = string(bytes.Trim(enc, `"`))
+	}
+	return nil
+}
+
+// expand replaces {name} placeholders with variables; unknown ones are left as is
+func expand(s string, vars map[string]string) string {
+	for k, v := range vars {
+		s = strings.ReplaceAll(s, "{"+k+"}", v)
+	}
+	return s
+}
+
+// contains reports whether want is a subset of got and, if not, where they differ
+func contains(got, want any, path string) (string, bool) {
+	switch w := want.(type) {
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			return path, false
+		}
+		for k, wv := range w {
+			gv, ok := g[k]
+			if !ok {
+				return path + "." + k, false
+			}
+			if p, ok := contains(gv, wv, path+"."+k); !ok {
+				return p, false
+			}
+		}
+		return "", true
+	case []any:
+		g, ok := got.([]any)
+		if !ok || len(g) != len(w) {
+			return path, false
+		}
+		for i := range w {
+			if p, ok := contains(g[i], w[i], fmt.Sprintf("%s[%d]", path, i)); !ok {
+				return p, false
+			}
+		}
+		return "", true
+	default:
+		return path, reflect.DeepEqual(got, want)
+	}
+}
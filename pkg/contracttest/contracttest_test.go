@@ -0,0 +1,20 @@
+package contracttest
+
+import "testing"
+
+func TestSuite(t *testing.T) {
+	Run(t)
+}
+
+func TestContains(t *testing.T) {
+	got := map[string]any{"id": 1.0, "tags": []any{"a", "b"}, "extra": true}
+	if _, ok := contains(got, map[string]any{"id": 1.0, "tags": []any{"a", "b"}}, "$"); !ok {
+		t.Error("subset not matched")
+	}
+	if path, ok := contains(got, map[string]any{"tags": []any{"a"}}, "$"); ok || path != "$.tags" {
+		t.Errorf("array length mismatch: path %q, ok %v", path, ok)
+	}
+	if path, ok := contains(got, map[string]any{"missing": 1.0}, "$"); ok || path != "$.missing" {
+		t.Errorf("missing key: path %q, ok %v", path, ok)
+	}
+}
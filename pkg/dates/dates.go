@@ -0,0 +1,163 @@
+// Package dates parses and formats the month-granular dates used by the API.
+package dates
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrEmpty - date value is empty
+	ErrEmpty = errors.New("empty date value")
+	// ErrInvalid - date value matches none of the accepted layouts
+	ErrInvalid = errors.New("invalid date value")
+	// ErrNotMonthStart - strict mode got a date that is not the first day of a month
+	ErrNotMonthStart = errors.New("date must be the first day of a month")
+	// ErrUnexpectedFormat - exact layout mode got a value in another (or no) layout
+	ErrUnexpectedFormat = errors.New("unexpected date format")
+	// ErrOutOfRange - date parses but cannot be told apart from an unset one (January of year 1 or earlier)
+	ErrOutOfRange = errors.New("date out of range")
+)
+
+// maxInputLen bounds the values Parse looks at; the longest layout with a month name is under 30 bytes
+const maxInputLen = 64
+
+// MonthYear - canonical API layout used when serializing dates
+const MonthYear = "01-2006"
+
+// DefaultLayouts - layouts accepted when none are configured
+var DefaultLayouts = []string{MonthYear, "2006-01-02", "2006-01"}
+
+// Parser — parses month dates using a configurable set of layouts
+type Parser struct {
+	layouts []string
+	strict  bool
+	exact   bool
+	months  map[string]time.Month
+}
+
+// monthNameLayout - layout added for localized month names when none of the layouts spells out the month
+const monthNameLayout = "January 2006"
+
+// NewParser creates a parser accepting DefaultLayouts and applies options
+func NewParser(options ...func(*Parser)) *Parser {
+	p := &Parser{layouts: DefaultLayouts}
+	for _, o := range options {
+		o(p)
+	}
+	if len(p.months) > 0 && !slices.ContainsFunc(p.layouts, func(l string) bool { return strings.Contains(l, "Jan") }) {
+		p.layouts = append(slices.Clip(p.layouts), monthNameLayout)
+	}
+	return p
+}
+
+// WithLayouts returns an option that replaces the accepted layouts; an empty list keeps the defaults
+func WithLayouts(layouts ...string) func(*Parser) {
+	return func(p *Parser) {
+		if len(layouts) > 0 {
+			p.layouts = layouts
+		}
+	}
+}
+
+// WithStrict returns an option that rejects dates which are not the first day of a month
+// instead of silently truncating them
+func WithStrict(strict bool) func(*Parser) {
+	return func(p *Parser) {
+		p.strict = strict
+	}
+}
+
+// WithExactLayout returns an option that accepts exactly one layout and reports any other
+// input with ErrUnexpectedFormat naming the expected format, avoiding "2006-01" vs "01-2006" ambiguity
+func WithExactLayout(layout string) func(*Parser) {
+	return func(p *Parser) {
+		p.layouts = []string{layout}
+		p.exact = true
+	}
+}
+
+// WithMonthNames returns an option that accepts localized month names in layouts containing "January" or "Jan"
+func WithMonthNames(names map[string]time.Month) func(*Parser) {
+	return func(p *Parser) {
+		if p.months == nil {
+			p.months = make(map[string]time.Month, len(names))
+		}
+		for name, m := range names {
+			p.months[strings.ToLower(name)] = m
+		}
+	}
+}
+
+// Parse parses s with the first matching layout and normalizes it to the first day of the month (UTC)
+func (p *Parser) Parse(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, ErrEmpty
+	}
+	if len(s) > maxInputLen {
+		return time.Time{}, fmt.Errorf("%w: %d bytes", ErrInvalid, len(s))
+	}
+	s = p.localize(s)
+	for _, layout := range p.layouts {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+		if p.strict && t.Day() != 1 {
+			return time.Time{}, fmt.Errorf("%w: %q", ErrNotMonthStart, s)
+		}
+		// the zero time means "unset" everywhere, so a date that truncates to it must not pass as one
+		if m := MonthStart(t); t.Year() >= 1 && !m.IsZero() {
+			return m, nil
+		}
+		return time.Time{}, fmt.Errorf("%w: %q", ErrOutOfRange, s)
+	}
+	if p.exact {
+		return time.Time{}, fmt.Errorf("%w: expected %s, got %q", ErrUnexpectedFormat, HumanLayout(p.layouts[0]), s)
+	}
+	return time.Time{}, fmt.Errorf("%w: %q", ErrInvalid, s)
+}
+
+// HumanLayout spells a Go layout the way API docs do, e.g. "01-2006" as "MM-YYYY"
+func HumanLayout(layout string) string {
+	return strings.NewReplacer("2006", "YYYY", "01", "MM", "02", "DD").Replace(layout)
+}
+
+// localize replaces localized month names with their English counterparts understood by time.Parse
+func (p *Parser) localize(s string) string {
+	if len(p.months) == 0 {
+		return s
+	}
+	fields := strings.Fields(s)
+	for i, f := range fields {
+		if m, ok := p.months[strings.ToLower(f)]; ok {
+			fields[i] = m.String()
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// MonthStart truncates t to the first day of its month in UTC
+func MonthStart(t time.Time) time.Time {
+	if t.IsZero() {
+		return time.Time{}
+	}
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Format serializes t using the canonical MonthYear layout
+func Format(t time.Time) string {
+	return t.Format(MonthYear)
+}
+
+// FormatPtr serializes an optional date, returning an empty string for nil
+func FormatPtr(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return Format(*t)
+}
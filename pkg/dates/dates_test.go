@@ -0,0 +1,97 @@
+package dates
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParser_Parse(t *testing.T) {
+	june := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		parser  *Parser
+		input   string
+		want    time.Time
+		wantErr error
+	}{
+		{name: "month-year", parser: NewParser(), input: "06-2025", want: june},
+		{name: "iso month", parser: NewParser(), input: "2025-06", want: june},
+		{name: "iso date truncated", parser: NewParser(), input: "2025-06-15", want: june},
+		{name: "month out of range", parser: NewParser(), input: "2025-13", wantErr: ErrInvalid},
+		{name: "month out of range month-year", parser: NewParser(), input: "13-2025", wantErr: ErrInvalid},
+		{name: "empty", parser: NewParser(), input: "  ", wantErr: ErrEmpty},
+		{name: "strict rejects mid-month", parser: NewParser(WithStrict(true)), input: "2025-06-15", wantErr: ErrNotMonthStart},
+		{name: "strict accepts month start", parser: NewParser(WithStrict(true)), input: "2025-06-01", want: june},
+		{name: "custom layouts", parser: NewParser(WithLayouts("2006/01")), input: "2025/06", want: june},
+		{name: "custom layouts drop defaults", parser: NewParser(WithLayouts("2006/01")), input: "06-2025", wantErr: ErrInvalid},
+		{name: "localized month name", parser: NewParser(WithMonthNames(RussianMonths)), input: "Июнь 2025", want: june},
+		{name: "english month name", parser: NewParser(WithMonthNames(RussianMonths)), input: "June 2025", want: june},
+		{name: "exact layout", parser: NewParser(WithExactLayout(MonthYear)), input: "06-2025", want: june},
+		{name: "exact layout rejects iso month", parser: NewParser(WithExactLayout(MonthYear)), input: "2025-06", wantErr: ErrUnexpectedFormat},
+		{name: "exact layout rejects iso date", parser: NewParser(WithExactLayout(MonthYear)), input: "2025-06-01", wantErr: ErrUnexpectedFormat},
+		{name: "year zero", parser: NewParser(), input: "0000-06", wantErr: ErrOutOfRange},
+		{name: "zero time", parser: NewParser(), input: "01-0001", wantErr: ErrOutOfRange},
+		{name: "second month of year one", parser: NewParser(), input: "02-0001", want: time.Date(1, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "oversized", parser: NewParser(WithMonthNames(RussianMonths)), input: strings.Repeat("Июнь ", 100) + "2025", wantErr: ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parser.Parse(tt.input)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParser_ExactLayoutError(t *testing.T) {
+	_, err := NewParser(WithExactLayout(MonthYear)).Parse("2025-06")
+	assert.EqualError(t, err, `unexpected date format: expected MM-YYYY, got "2025-06"`)
+}
+
+func TestFormat(t *testing.T) {
+	d := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, "06-2025", Format(d))
+	assert.Equal(t, "06-2025", FormatPtr(&d))
+	assert.Equal(t, "", FormatPtr(nil))
+}
+
+func TestMonthLabel(t *testing.T) {
+	d := time.Date(2025, time.September, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, "Сентябрь 2025", MonthLabel(d, "ru"))
+	assert.Equal(t, "September 2025", MonthLabel(d, "en"))
+	assert.Equal(t, "September 2025", MonthLabel(d, "de"), "English without names of its own")
+}
+
+func FuzzParser_Parse(f *testing.F) {
+	for _, s := range []string{"06-2025", "2025-06", "2025-06-15", "Июнь 2025", "June 2025", "01-0001", "0000-01", "", "13-2025", "9999-12-31"} {
+		f.Add(s)
+	}
+	parsers := []*Parser{
+		NewParser(WithMonthNames(RussianMonths)),
+		NewParser(WithStrict(true)),
+		NewParser(WithExactLayout(MonthYear)),
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		for _, p := range parsers {
+			got, err := p.Parse(s)
+			if err != nil {
+				continue
+			}
+			if got.IsZero() || !got.Equal(MonthStart(got)) || got.Location() != time.UTC {
+				t.Fatalf("Parse(%q) = %v, want a non-zero month start in UTC", s, got)
+			}
+			again, err := NewParser(WithExactLayout(MonthYear)).Parse(Format(got))
+			if err != nil || !again.Equal(got) {
+				t.Fatalf("Format(%v) = %q does not parse back: %v, %v", got, Format(got), again, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package dates
+
+import (
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// RussianMonths - Russian month names in nominative and genitive case
+var RussianMonths = map[string]time.Month{
+	"январь": time.January, "января": time.January,
+	"февраль": time.February, "февраля": time.February,
+	"март": time.March, "марта": time.March,
+	"апрель": time.April, "апреля": time.April,
+	"май": time.May, "мая": time.May,
+	"июнь": time.June, "июня": time.June,
+	"июль": time.July, "июля": time.July,
+	"август": time.August, "августа": time.August,
+	"сентябрь": time.September, "сентября": time.September,
+	"октябрь": time.October, "октября": time.October,
+	"ноябрь": time.November, "ноября": time.November,
+	"декабрь": time.December, "декабря": time.December,
+}
+
+// MonthNames returns localized month names for the locale, or nil when only English is supported
+func MonthNames(locale string) map[string]time.Month {
+	switch locale {
+	case "ru":
+		return RussianMonths
+	default:
+		return nil
+	}
+}
+
+// russianNominative - Russian month names in nominative case, indexed by time.Month
+var russianNominative = [...]string{"", "январь", "февраль", "март", "апрель", "май", "июнь", "июль", "август",
+	"сентябрь", "октябрь", "ноябрь", "декабрь"}
+
+// MonthName returns the name of m for the locale, English when the locale has no names of its own
+func MonthName(m time.Month, locale string) string {
+	if locale == "ru" && m >= time.January && m <= time.December {
+		return russianNominative[m]
+	}
+	return m.String()
+}
+
+// MonthLabel returns the month of t with its year as a standalone label for the locale, capitalized as a
+// heading or chart axis shows it, e.g. "Сентябрь 2025" or "September 2025"
+func MonthLabel(t time.Time, locale string) string {
+	name := MonthName(t.Month(), locale)
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(r)) + name[size:] + " " + t.Format("2006")
+}
@@ -0,0 +1,166 @@
+// Package fieldcrypt encrypts individual column values with AES-256-GCM under named keys,
+// so old keys can keep decrypting while new values are written with the current one.
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	keySize = 32
+	version = "v1"
+)
+
+var (
+	// ErrNotEncrypted - the value was not produced by Keyring.Encrypt
+	ErrNotEncrypted = errors.New("value is not encrypted")
+	// ErrUnknownKey - the value was encrypted with a key missing from the keyring
+	ErrUnknownKey = errors.New("unknown encryption key")
+	// ErrDecrypt - the value was tampered with or belongs to another context
+	ErrDecrypt = errors.New("decrypt failed")
+)
+
+// Keyring — encryption keys by ID; new values are always encrypted with the primary one
+type Keyring struct {
+	primary string
+	aeads   map[string]cipher.AEAD
+}
+
+// NewKeyring creates a keyring of 32-byte keys; primary must be one of them
+func NewKeyring(primary string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[primary]; !ok {
+		return nil, fmt.Errorf("primary key %q is missing", primary)
+	}
+	k := &Keyring{primary: primary, aeads: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid key id %q", id)
+		}
+		if len(key) != keySize {
+			return nil, fmt.Errorf("key %q: want %d bytes, got %d", id, keySize, len(key))
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		k.aeads[id] = aead
+	}
+	return k, nil
+}
+
+// ParseKeyring parses comma-separated id:base64key pairs; the first key is the primary one,
+// so rotating means prepending a new key and keeping the old ones until values are re-encrypted
+func ParseKeyring(raw string) (*Keyring, error) {
+	var (
+		primary string
+		keys    = map[string][]byte{}
+	)
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(item, ":")
+		id = strings.TrimSpace(id)
+		if !ok || id == "" {
+			return nil, fmt.Errorf("want id:key, got %q", id)
+		}
+		if _, dup := keys[id]; dup {
+			return nil, fmt.Errorf("duplicate key id %q", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		if primary == "" {
+			primary = id
+		}
+		keys[id] = key
+	}
+	if primary == "" {
+		return nil, errors.New("no keys")
+	}
+	return NewKeyring(primary, keys)
+}
+
+// Encrypt seals plaintext with the primary key as "v1:<key id>:<base64 nonce+ciphertext>";
+// aad binds the value to its place, e.g. table, column and row ID, so it cannot be moved to another row
+func (k *Keyring) Encrypt(plaintext, aad []byte) (string, error) {
+	aead := k.aeads[k.primary]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, aad)
+	return version + ":" + k.primary + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt with the same aad, using whichever key sealed it
+func (k *Keyring) Decrypt(value string, aad []byte) ([]byte, error) {
+	id, sealed, err := split(value)
+	if err != nil {
+		return nil, err
+	}
+	aead, ok := k.aeads[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// NeedsRotation reports whether value was sealed with a key other than the primary one
+func (k *Keyring) NeedsRotation(value string) bool {
+	id, _, err := split(value)
+	return err == nil && id != k.primary
+}
+
+// Rotate re-encrypts value with the primary key; values already under it are returned unchanged
+func (k *Keyring) Rotate(value string, aad []byte) (string, error) {
+	if !k.NeedsRotation(value) {
+		if _, err := k.Decrypt(value, aad); err != nil {
+			return "", err
+		}
+		return value, nil
+	}
+	plaintext, err := k.Decrypt(value, aad)
+	if err != nil {
+		return "", err
+	}
+	return k.Encrypt(plaintext, aad)
+}
+
+// IsEncrypted reports whether value looks like the output of Encrypt, e.g. to migrate plaintext columns
+func IsEncrypted(value string) bool {
+	_, _, err := split(value)
+	return err == nil
+}
+
+func split(value string) (string, []byte, error) {
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) != 3 || parts[0] != version || parts[1] == "" {
+		return "", nil, ErrNotEncrypted
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, ErrNotEncrypted
+	}
+	return parts[1], sealed, nil
+}
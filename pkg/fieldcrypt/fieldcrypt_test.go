@@ -0,0 +1,85 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func key(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, keySize))
+}
+
+func TestKeyring_EncryptDecrypt(t *testing.T) {
+	k, err := ParseKeyring("k1:" + key(1))
+	require.NoError(t, err)
+	aad := []byte("subscriptions.notes:42")
+
+	sealed, err := k.Encrypt([]byte("card ending 4242"), aad)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(sealed, "v1:k1:"))
+	assert.NotContains(t, sealed, "4242")
+	assert.True(t, IsEncrypted(sealed))
+
+	again, err := k.Encrypt([]byte("card ending 4242"), aad)
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again, "nonces are random")
+
+	got, err := k.Decrypt(sealed, aad)
+	require.NoError(t, err)
+	assert.Equal(t, "card ending 4242", string(got))
+
+	_, err = k.Decrypt(sealed, []byte("subscriptions.notes:43"))
+	assert.ErrorIs(t, err, ErrDecrypt, "a value moved to another row does not open")
+	tampered := []byte(sealed)
+	tampered[len(tampered)-5] ^= 'A' ^ 'B'
+	_, err = k.Decrypt(string(tampered), aad)
+	assert.Error(t, err)
+	_, err = k.Decrypt("card ending 4242", aad)
+	assert.ErrorIs(t, err, ErrNotEncrypted)
+	assert.False(t, IsEncrypted("v1:k1"))
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	aad := []byte("row:1")
+	old, err := ParseKeyring("k1:" + key(1))
+	require.NoError(t, err)
+	sealed, err := old.Encrypt([]byte("secret"), aad)
+	require.NoError(t, err)
+
+	k, err := ParseKeyring("k2:" + key(2) + ", k1:" + key(1))
+	require.NoError(t, err)
+	assert.True(t, k.NeedsRotation(sealed))
+	got, err := k.Decrypt(sealed, aad)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(got), "old keys keep decrypting")
+
+	rotated, err := k.Rotate(sealed, aad)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(rotated, "v1:k2:"))
+	assert.False(t, k.NeedsRotation(rotated))
+	same, err := k.Rotate(rotated, aad)
+	require.NoError(t, err)
+	assert.Equal(t, rotated, same)
+
+	_, err = old.Decrypt(rotated, aad)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestParseKeyring_Invalid(t *testing.T) {
+	for _, raw := range []string{
+		"",
+		"k1",
+		"k1:not base64",
+		"k1:" + base64.StdEncoding.EncodeToString([]byte("short")),
+		"k1:" + key(1) + ",k1:" + key(2),
+		":" + key(1),
+	} {
+		_, err := ParseKeyring(raw)
+		assert.Error(t, err, raw)
+	}
+}
@@ -0,0 +1,198 @@
+// Package filterexpr parses the small filter language of list endpoints: comparisons of a field with a value
+// joined by AND, e.g. cost>500 AND service_name~"net" AND start_date>=01-2025. It only splits the expression
+// into conditions; which fields and operators mean something is up to the caller.
+package filterexpr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrSyntax - the expression is not a list of conditions joined by AND
+	ErrSyntax = errors.New("invalid filter")
+	// ErrTooLong - the expression is longer than MaxLength or has more than MaxConditions conditions
+	ErrTooLong = errors.New("filter is too long")
+)
+
+// Bounds of an accepted expression, so a query string cannot make the parser or the storage query large
+const (
+	MaxLength     = 512
+	MaxConditions = 16
+)
+
+// Op — comparison operator of a condition
+type Op string
+
+const (
+	Eq       Op = "="
+	NotEq    Op = "!="
+	Greater  Op = ">"
+	GreaterE Op = ">="
+	Less     Op = "<"
+	LessE    Op = "<="
+	// Contains - the field contains the value, e.g. service_name~"net"
+	Contains Op = "~"
+)
+
+// Cond — one comparison of an expression
+type Cond struct {
+	// Field - lower-cased field name
+	Field string
+	Op    Op
+	// Value - the value with quotes removed and escapes resolved
+	Value string
+	// Pos - byte offset of the condition in the expression, for error messages
+	Pos int
+}
+
+// Parse splits expr into its conditions. Field names are identifiers of letters, digits and underscores;
+// values are bare words up to the next space or double-quoted strings with \" and \\ escapes.
+// The keyword AND is case-insensitive; OR and parentheses are not supported
+func Parse(expr string) ([]Cond, error) {
+	if len(expr) > MaxLength {
+		return nil, fmt.Errorf("%w: longer than %d bytes", ErrTooLong, MaxLength)
+	}
+	p := parser{src: expr}
+	var out []Cond
+	for {
+		p.skipSpace()
+		if p.done() {
+			if len(out) == 0 {
+				return nil, fmt.Errorf("%w: empty expression", ErrSyntax)
+			}
+			return out, nil
+		}
+		if len(out) > 0 {
+			word, at := p.word()
+			if !strings.EqualFold(word, "AND") {
+				return nil, p.errorf(at, "expected AND, got %q", word)
+			}
+			p.skipSpace()
+		}
+		if len(out) == MaxConditions {
+			return nil, fmt.Errorf("%w: more than %d conditions", ErrTooLong, MaxConditions)
+		}
+		c, err := p.cond()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+}
+
+type parser struct {
+	src string
+	pos int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *parser) skipSpace() {
+	for !p.done() && isSpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *parser) errorf(at int, format string, args ...any) error {
+	return fmt.Errorf("%w at %d: %s", ErrSyntax, at, fmt.Sprintf(format, args...))
+}
+
+// word reads up to the next space
+func (p *parser) word() (string, int) {
+	start := p.pos
+	for !p.done() && !isSpace(p.src[p.pos]) {
+		p.pos++
+	}
+	return p.src[start:p.pos], start
+}
+
+func (p *parser) cond() (Cond, error) {
+	c := Cond{Pos: p.pos}
+	for !p.done() && isIdent(p.src[p.pos]) {
+		p.pos++
+	}
+	if p.pos == c.Pos {
+		if p.done() {
+			return c, p.errorf(p.pos, "expected a field")
+		}
+		return c, p.errorf(p.pos, "expected a field, got %q", p.src[p.pos])
+	}
+	c.Field = strings.ToLower(p.src[c.Pos:p.pos])
+
+	p.skipSpace()
+	op, err := p.op()
+	if err != nil {
+		return c, err
+	}
+	c.Op = op
+
+	p.skipSpace()
+	if c.Value, err = p.value(); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+func (p *parser) op() (Op, error) {
+	rest := p.src[p.pos:]
+	// two-byte operators first, so >= is not read as >
+	for _, op := range []Op{GreaterE, LessE, NotEq, Eq, Greater, Less, Contains} {
+		if strings.HasPrefix(rest, string(op)) {
+			p.pos += len(op)
+			return op, nil
+		}
+	}
+	if rest == "" {
+		return "", p.errorf(p.pos, "expected an operator")
+	}
+	return "", p.errorf(p.pos, "expected an operator, got %q", rest[0])
+}
+
+func (p *parser) value() (string, error) {
+	start := p.pos
+	if p.done() {
+		return "", p.errorf(start, "expected a value")
+	}
+	if p.src[p.pos] != '"' {
+		v, _ := p.word()
+		if strings.ContainsRune(v, '"') {
+			return "", p.errorf(start, "unexpected quote in %q", v)
+		}
+		return v, nil
+	}
+
+	var b strings.Builder
+	p.pos++
+	for !p.done() {
+		ch := p.src[p.pos]
+		p.pos++
+		switch ch {
+		case '"':
+			if !p.done() && !isSpace(p.src[p.pos]) {
+				return "", p.errorf(p.pos, "expected a space after the closing quote")
+			}
+			return b.String(), nil
+		case '\\':
+			if p.done() || (p.src[p.pos] != '"' && p.src[p.pos] != '\\') {
+				return "", p.errorf(p.pos-1, `only \" and \\ can be escaped`)
+			}
+			b.WriteByte(p.src[p.pos])
+			p.pos++
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return "", p.errorf(start, "unterminated string")
+}
+
+func isSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
+}
+
+func isIdent(ch byte) bool {
+	return ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9'
+}
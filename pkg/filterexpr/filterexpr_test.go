@@ -0,0 +1,60 @@
+package filterexpr
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	got, err := Parse(`cost>500 and Service_Name ~ "net \"x\"" AND start_date>=01-2025`)
+	require.NoError(t, err)
+	assert.Equal(t, []Cond{
+		{Field: "cost", Op: Greater, Value: "500", Pos: 0},
+		{Field: "service_name", Op: Contains, Value: `net "x"`, Pos: 13},
+		{Field: "start_date", Op: GreaterE, Value: "01-2025", Pos: 44},
+	}, got)
+
+	got, err = Parse(`service_name=""`)
+	require.NoError(t, err)
+	assert.Equal(t, []Cond{{Field: "service_name", Op: Eq, Value: ""}}, got)
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{expr: "  ", want: "invalid filter: empty expression"},
+		{expr: "cost", want: "invalid filter at 4: expected an operator"},
+		{expr: "cost 500", want: `invalid filter at 5: expected an operator, got '5'`},
+		{expr: "cost>", want: "invalid filter at 5: expected a value"},
+		{expr: "cost>1 OR cost<2", want: `invalid filter at 7: expected AND, got "OR"`},
+		{expr: "cost>1 AND", want: "invalid filter at 10: expected a field"},
+		{expr: "(cost>1)", want: `invalid filter at 0: expected a field, got '('`},
+		{expr: `service_name~"net`, want: "invalid filter at 13: unterminated string"},
+		{expr: `service_name~"net"x`, want: "invalid filter at 18: expected a space after the closing quote"},
+		{expr: `service_name~"\n"`, want: `invalid filter at 14: only \" and \\ can be escaped`},
+		{expr: `service_name~ne"t`, want: `invalid filter at 13: unexpected quote in "ne\"t"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Parse(tt.expr)
+			assert.ErrorIs(t, err, ErrSyntax)
+			assert.EqualError(t, err, tt.want)
+		})
+	}
+}
+
+func TestParse_Bounds(t *testing.T) {
+	_, err := Parse(strings.Repeat("a", MaxLength+1))
+	assert.ErrorIs(t, err, ErrTooLong)
+
+	conds := strings.TrimSuffix(strings.Repeat("cost>1 AND ", MaxConditions), " AND ")
+	_, err = Parse(conds)
+	require.NoError(t, err)
+	_, err = Parse(conds + " AND cost<9")
+	assert.ErrorIs(t, err, ErrTooLong)
+}
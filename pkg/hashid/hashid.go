@@ -0,0 +1,197 @@
+// Package hashid turns non-negative integers into short opaque strings and back with the Hashids algorithm, so
+// the output matches other Hashids implementations given the same salt and minimum length. It obfuscates: the
+// salt keeps neighbouring numbers from looking alike, but it is not encryption.
+package hashid
+
+import (
+	"errors"
+	"strings"
+)
+
+// defaultAlphabet, defaultSeps - the alphabet and separators of the reference implementation
+const (
+	defaultAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890"
+	defaultSeps     = "cfhistuCFHISTU"
+)
+
+// sepDiv, guardDiv - ratios of alphabet to separator and guard characters of the reference implementation
+const (
+	sepDiv   = 3.5
+	guardDiv = 12
+)
+
+// ErrInvalid - the text was not made by this codec
+var ErrInvalid = errors.New("invalid hashid")
+
+// Codec — encodes numbers with one salt and minimum length; safe for concurrent use
+type Codec struct {
+	salt      []byte
+	minLength int
+	alphabet  []byte
+	seps      []byte
+	guards    []byte
+}
+
+// New creates a codec; minLength pads short outputs, 0 leaves them as short as the number allows
+func New(salt string, minLength int) *Codec {
+	alphabet := []byte(defaultAlphabet)
+	var seps []byte
+	for i := 0; i < len(defaultSeps); i++ {
+		c := defaultSeps[i]
+		if j := strings.IndexByte(string(alphabet), c); j >= 0 {
+			seps = append(seps, c)
+			alphabet = append(alphabet[:j], alphabet[j+1:]...)
+		}
+	}
+	saltBytes := []byte(salt)
+	shuffle(seps, saltBytes)
+
+	if len(seps) == 0 || float64(len(alphabet))/float64(len(seps)) > sepDiv {
+		n := ceilDiv(float64(len(alphabet)), sepDiv)
+		if n == 1 {
+			n = 2
+		}
+		if n > len(seps) {
+			diff := n - len(seps)
+			seps = append(seps, alphabet[:diff]...)
+			alphabet = alphabet[diff:]
+		} else {
+			seps = seps[:n]
+		}
+	}
+	shuffle(alphabet, saltBytes)
+
+	guardCount := ceilDiv(float64(len(alphabet)), guardDiv)
+	var guards []byte
+	if len(alphabet) < 3 {
+		guards, seps = seps[:guardCount], seps[guardCount:]
+	} else {
+		guards, alphabet = alphabet[:guardCount], alphabet[guardCount:]
+	}
+	return &Codec{salt: saltBytes, minLength: max(minLength, 0), alphabet: alphabet, seps: seps, guards: guards}
+}
+
+// Encode writes n; negative numbers have no encoding and give ""
+func (c *Codec) Encode(n int64) string {
+	if n < 0 {
+		return ""
+	}
+	alphabet := append([]byte(nil), c.alphabet...)
+	numbersHash := n % 100
+	lottery := alphabet[numbersHash%int64(len(alphabet))]
+
+	out := []byte{lottery}
+	buf := make([]byte, 0, 1+len(c.salt)+len(alphabet))
+	buf = append(append(append(buf, lottery), c.salt...), alphabet...)
+	shuffle(alphabet, buf[:len(alphabet)])
+	out = append(out, hash(n, alphabet)...)
+
+	if len(out) < c.minLength {
+		guard := c.guards[(numbersHash+int64(out[0]))%int64(len(c.guards))]
+		out = append([]byte{guard}, out...)
+		if len(out) < c.minLength {
+			guard = c.guards[(numbersHash+int64(out[2]))%int64(len(c.guards))]
+			out = append(out, guard)
+		}
+	}
+	half := len(alphabet) / 2
+	for len(out) < c.minLength {
+		shuffle(alphabet, append([]byte(nil), alphabet...))
+		padded := make([]byte, 0, len(out)+len(alphabet))
+		padded = append(append(append(padded, alphabet[half:]...), out...), alphabet[:half]...)
+		out = padded
+		if excess := len(out) - c.minLength; excess > 0 {
+			out = out[excess/2 : excess/2+c.minLength]
+		}
+	}
+	return string(out)
+}
+
+// Decode reads a single number written by Encode with the same salt and minimum length
+func (c *Codec) Decode(s string) (int64, error) {
+	if s == "" {
+		return 0, ErrInvalid
+	}
+	parts := splitAny(s, c.guards)
+	body := parts[0]
+	if len(parts) == 2 || len(parts) == 3 {
+		body = parts[1]
+	}
+	if body == "" {
+		return 0, ErrInvalid
+	}
+	lottery, body := body[0], body[1:]
+	if len(splitAny(body, c.seps)) != 1 {
+		return 0, ErrInvalid
+	}
+
+	alphabet := append([]byte(nil), c.alphabet...)
+	buf := make([]byte, 0, 1+len(c.salt)+len(alphabet))
+	buf = append(append(append(buf, lottery), c.salt...), alphabet...)
+	shuffle(alphabet, buf[:len(alphabet)])
+	n, ok := unhash(body, alphabet)
+	// anything decoding to a number but not written by Encode, e.g. with another salt or padding, is rejected
+	if !ok || c.Encode(n) != s {
+		return 0, ErrInvalid
+	}
+	return n, nil
+}
+
+// shuffle permutes alphabet in place, the same way for the same salt
+func shuffle(alphabet, salt []byte) {
+	if len(salt) == 0 {
+		return
+	}
+	for i, v, p := len(alphabet)-1, 0, 0; i > 0; i, v = i-1, v+1 {
+		v %= len(salt)
+		n := int(salt[v])
+		p += n
+		j := (n + v + p) % i
+		alphabet[i], alphabet[j] = alphabet[j], alphabet[i]
+	}
+}
+
+// hash writes n in the base of the alphabet, most significant digit first
+func hash(n int64, alphabet []byte) []byte {
+	base := int64(len(alphabet))
+	var out []byte
+	for {
+		out = append([]byte{alphabet[n%base]}, out...)
+		n /= base
+		if n == 0 {
+			return out
+		}
+	}
+}
+
+// unhash reads a number written by hash; ok is false for characters outside the alphabet and on overflow
+func unhash(s string, alphabet []byte) (int64, bool) {
+	if s == "" {
+		return 0, false
+	}
+	base := int64(len(alphabet))
+	var n int64
+	for i := 0; i < len(s); i++ {
+		d := strings.IndexByte(string(alphabet), s[i])
+		if d < 0 || n > (1<<63-1-int64(d))/base {
+			return 0, false
+		}
+		n = n*base + int64(d)
+	}
+	return n, true
+}
+
+// splitAny splits s at every byte of seps
+func splitAny(s string, seps []byte) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r < 0x80 && strings.IndexByte(string(seps), byte(r)) >= 0
+	})
+}
+
+func ceilDiv(a, b float64) int {
+	n := int(a / b)
+	if float64(n)*b < a {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,45 @@
+package hashid
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	// examples of the reference implementation
+	assert.Equal(t, "NkK9", New("this is my salt", 0).Encode(12345))
+	assert.Equal(t, "gB0NV05e", New("this is my salt", 8).Encode(1))
+
+	for _, c := range []*Codec{New("", 0), New("pepper", 0), New("pepper", 8), New("pepper", 30)} {
+		for _, n := range []int64{0, 1, 2, 99, 100, 12345, 1 << 40, math.MaxInt64} {
+			s := c.Encode(n)
+			assert.GreaterOrEqual(t, len(s), c.minLength, n)
+			got, err := c.Decode(s)
+			require.NoError(t, err, s)
+			assert.Equal(t, n, got, s)
+		}
+	}
+	assert.Empty(t, New("pepper", 8).Encode(-1))
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	c := New("pepper", 8)
+	s := c.Encode(42)
+	for _, v := range []string{
+		"",
+		"42",
+		s[:len(s)-1],
+		s + "a",
+		"!" + s[1:],
+	} {
+		_, err := c.Decode(v)
+		assert.ErrorIs(t, err, ErrInvalid, v)
+	}
+	_, err := New("salt", 8).Decode(s)
+	assert.ErrorIs(t, err, ErrInvalid, "another salt")
+	_, err = New("pepper", 0).Decode(s)
+	assert.ErrorIs(t, err, ErrInvalid, "another minimum length")
+}
@@ -0,0 +1,47 @@
+package jsonenc
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendString(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"Netflix",
+		`quote " and backslash \`,
+		"<script>&amp;</script>",
+		"tab\tnew line\nreturn\r\b\f\x00\x1f\x7f",
+		"Кинопоиск — 299 ₽",
+		"emoji 🎬",
+		"line\u2028paragraph\u2029",
+		"invalid \xff\xfe utf-8 \xe2\x82",
+	} {
+		want, err := json.Marshal(s)
+		require.NoError(t, err)
+		assert.Equal(t, string(want), string(AppendString(nil, s)), "%q", s)
+	}
+}
+
+func FuzzAppendString(f *testing.F) {
+	f.Add("Netflix <HD> & \"more\"\n")
+	f.Add("\xff\u2028")
+	f.Fuzz(func(t *testing.T, s string) {
+		want, err := json.Marshal(s)
+		require.NoError(t, err)
+		assert.Equal(t, string(want), string(AppendString(nil, s)))
+	})
+}
+
+func TestAppendPtr(t *testing.T) {
+	s, n := "x", int64(math.MinInt64)
+	assert.Equal(t, `null`, string(AppendStringPtr(nil, nil)))
+	assert.Equal(t, `"x"`, string(AppendStringPtr(nil, &s)))
+	assert.Equal(t, `null`, string(AppendIntPtr(nil, nil)))
+	assert.Equal(t, `-9223372036854775808`, string(AppendIntPtr(nil, &n)))
+	assert.Equal(t, `[1,"a"]`, string(append(AppendString(append(AppendInt([]byte("["), 1), ','), "a"), ']')))
+}
@@ -0,0 +1,92 @@
+// Package money formats amounts for people: digit grouping, decimal separator and symbol placement follow the
+// locale, decimal places follow the currency. Amounts stay plain integers in JSON, CSV and the database.
+package money
+
+import (
+	"math"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+const (
+	// defaultScale - decimal places of currencies x/text does not know
+	defaultScale = 2
+	// nbsp keeps the symbol on the line of the amount
+	nbsp = "\u00a0"
+)
+
+// suffixed - languages that write the symbol after the amount, e.g. "1 299 ₽" rather than "₽1,299"
+var suffixed = map[string]bool{
+	"be": true, "bg": true, "cs": true, "da": true, "de": true, "es": true, "et": true, "fi": true, "fr": true,
+	"hu": true, "it": true, "kk": true, "lt": true, "lv": true, "nb": true, "pl": true, "pt": true, "ro": true,
+	"ru": true, "sk": true, "sl": true, "sr": true, "sv": true, "uk": true,
+}
+
+// Formatter — formats amounts of one currency for one locale
+type Formatter struct {
+	printer *message.Printer
+	symbol  string
+	scale   int
+	suffix  bool
+}
+
+// New returns a formatter of the ISO 4217 currency code for the BCP 47 locale. An unknown currency is shown by
+// its code with two decimal places; an unparsable locale falls back to English
+func New(currencyCode, locale string) Formatter {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		tag = language.English
+	}
+	f := Formatter{printer: message.NewPrinter(tag), symbol: strings.ToUpper(currencyCode), scale: defaultScale}
+	if unit, err := currency.ParseISO(currencyCode); err == nil {
+		f.symbol = f.printer.Sprint(currency.Symbol(unit))
+		f.scale, _ = currency.Standard.Rounding(unit)
+	}
+	base, _ := tag.Base()
+	f.suffix = suffixed[base.String()]
+	return f
+}
+
+// Scale returns the decimal places of the currency
+func (f Formatter) Scale() int {
+	return f.scale
+}
+
+// Round rounds a computed amount, e.g. an average or a share of a yearly price, to the currency's decimal places
+func (f Formatter) Round(amount float64) float64 {
+	pow := math.Pow10(f.scale)
+	return math.Round(amount*pow) / pow
+}
+
+// Format renders a whole amount, as stored for subscriptions, without decimals: "1 299 ₽", "$1,299"
+func (f Formatter) Format(amount int64) string {
+	return f.withSymbol(f.printer.Sprint(number.Decimal(amount)))
+}
+
+// FormatFraction renders a computed amount rounded to the currency's decimal places: "433,33 ₽", "¥433"
+func (f Formatter) FormatFraction(amount float64) string {
+	return f.withSymbol(f.printer.Sprint(number.Decimal(f.Round(amount), number.Scale(f.scale))))
+}
+
+func (f Formatter) withSymbol(num string) string {
+	if f.symbol == "" {
+		return num
+	}
+	if f.suffix {
+		return num + " " + f.symbol
+	}
+	sign := ""
+	if n, ok := strings.CutPrefix(num, "-"); ok {
+		sign, num = "-", n
+	}
+	// a code such as RUB needs a space, a sign such as $ sticks to the digits
+	if unicode.IsLetter([]rune(f.symbol)[len([]rune(f.symbol))-1]) {
+		return sign + f.symbol + " " + num
+	}
+	return sign + f.symbol + num
+}
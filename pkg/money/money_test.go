@@ -0,0 +1,40 @@
+package money
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatter(t *testing.T) {
+	tests := []struct {
+		currency, locale string
+		amount           int64
+		fraction         float64
+		want, wantFrac   string
+	}{
+		{"RUB", "ru", 1299, 433.333, "1 299 ₽", "433,33 ₽"},
+		{"RUB", "en-US", 1299, 433.333, "RUB 1,299", "RUB 433.33"},
+		{"USD", "en-US", -1299, 0.005, "-$1,299", "$0.01"},
+		{"EUR", "de", 1234567, 9.5, "1.234.567 €", "9,50 €"},
+		{"JPY", "ja", 1299, 433.5, "￥1,299", "￥434"},
+		{"XYZ", "", 1299, 1.5, "XYZ 1,299", "XYZ 1.50"},
+		{"", "ru", 1299, 1.5, "1 299", "1,50"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.currency+"_"+tt.locale, func(t *testing.T) {
+			f := New(tt.currency, tt.locale)
+			// x/text groups with no-break spaces; compare with plain ones for readability
+			plain := func(s string) string { return strings.ReplaceAll(s, "\u00a0", " ") }
+			assert.Equal(t, tt.want, plain(f.Format(tt.amount)))
+			assert.Equal(t, tt.wantFrac, plain(f.FormatFraction(tt.fraction)))
+		})
+	}
+}
+
+func TestFormatter_Round(t *testing.T) {
+	assert.Equal(t, 433.33, New("RUB", "ru").Round(433.333))
+	assert.Equal(t, 434.0, New("JPY", "ja").Round(433.5))
+	assert.Equal(t, 0, New("JPY", "ja").Scale())
+}
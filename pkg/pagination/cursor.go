@@ -0,0 +1,131 @@
+package pagination
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidCursor - cursor is malformed or its signature does not match
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+const keySize = 32
+
+// maxCursorLen bounds what Decode reads: real cursors are well under 200 bytes
+const maxCursorLen = 1024
+
+// Codec — encodes keyset values into opaque signed cursors and back
+type Codec struct {
+	key []byte
+	// aead encrypts cursors when set, see WithSealing
+	aead cipher.AEAD
+}
+
+// WithSealing returns an option that encrypts cursors with AES-GCM instead of only signing them, so clients
+// cannot read the keyset values, e.g. IDs that are not meant to be shown
+func WithSealing() func(*Codec) {
+	return func(c *Codec) {
+		// the sealing key is derived from the signing one, so one secret keeps serving both
+		m := hmac.New(sha256.New, c.key)
+		m.Write([]byte("pagination cursor sealing"))
+		block, err := aes.NewCipher(m.Sum(nil))
+		if err != nil {
+			panic(fmt.Sprintf("pagination: cursor cipher: %v", err))
+		}
+		c.aead, err = cipher.NewGCM(block)
+		if err != nil {
+			panic(fmt.Sprintf("pagination: cursor cipher: %v", err))
+		}
+	}
+}
+
+// NewCodec creates a codec signing cursors with key; an empty key is replaced with a random one,
+// which makes cursors valid only for the lifetime of the process
+func NewCodec(key []byte, options ...func(*Codec)) *Codec {
+	if len(key) == 0 {
+		key = make([]byte, keySize)
+		if _, err := rand.Read(key); err != nil {
+			panic(fmt.Sprintf("pagination: generate cursor key: %v", err))
+		}
+	}
+	c := &Codec{key: key}
+	for _, o := range options {
+		o(c)
+	}
+	return c
+}
+
+// Encode marshals keyset values to JSON and returns base64(payload) "." base64(HMAC-SHA256(payload)), or
+// base64(nonce + AES-GCM(payload)) with WithSealing
+func (c *Codec) Encode(v any) (string, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("encode cursor: %w", err)
+	}
+	enc := base64.RawURLEncoding
+	if c.aead != nil {
+		nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(payload)+c.aead.Overhead())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", fmt.Errorf("encode cursor: %w", err)
+		}
+		return enc.EncodeToString(c.aead.Seal(nonce, nonce, payload, nil)), nil
+	}
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(c.sign(payload)), nil
+}
+
+// Decode verifies the cursor signature and unmarshals its keyset values into v
+func (c *Codec) Decode(cursor string, v any) error {
+	enc := base64.RawURLEncoding
+	if len(cursor) > maxCursorLen {
+		return ErrInvalidCursor
+	}
+	if c.aead != nil {
+		return c.open(cursor, v)
+	}
+	p, s, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return ErrInvalidCursor
+	}
+	payload, err := enc.DecodeString(p)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	sig, err := enc.DecodeString(s)
+	if err != nil || !hmac.Equal(sig, c.sign(payload)) {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	return nil
+}
+
+// open decrypts a sealed cursor, which fails for cursors tampered with as for signed ones
+func (c *Codec) open(cursor string, v any) error {
+	sealed, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return ErrInvalidCursor
+	}
+	n := c.aead.NonceSize()
+	payload, err := c.aead.Open(nil, sealed[:n], sealed[n:], nil)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	return nil
+}
+
+func (c *Codec) sign(payload []byte) []byte {
+	m := hmac.New(sha256.New, c.key)
+	m.Write(payload)
+	return m.Sum(nil)
+}
@@ -0,0 +1,126 @@
+// Package pagination holds limit/offset normalization and opaque keyset cursors
+// shared by all transport gateways, so paging semantics stay the same everywhere.
+package pagination
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrNegativeOffset - offset is below zero
+	ErrNegativeOffset = errors.New("offset must be >= 0")
+	// ErrOffsetTooLarge - offset is above MaxOffset
+	ErrOffsetTooLarge = errors.New("offset is too large")
+	// ErrInvalidLimit - limit query value is not a non-negative integer
+	ErrInvalidLimit = errors.New("invalid limit")
+	// ErrInvalidOffset - offset query value is not an integer in [0, MaxOffset]
+	ErrInvalidOffset = errors.New("invalid offset")
+	// ErrCursorWithOffset - cursor and offset were both requested
+	ErrCursorWithOffset = errors.New("cursor and offset are mutually exclusive")
+	// ErrOutOfRange - strict mode got a limit or offset that would otherwise be clamped or rejected as invalid
+	ErrOutOfRange = errors.New("out of range")
+)
+
+// Default page size bounds used by list endpoints
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+	// MaxOffset keeps offsets within the 32-bit OFFSET parameter of the storage queries
+	MaxOffset = math.MaxInt32
+)
+
+// Limits — default and maximum page size
+type Limits struct {
+	// Default - page size used when the limit is not set
+	Default int
+	// Max - upper bound for the page size
+	Max int
+}
+
+// DefaultLimits returns the limits used by list endpoints
+func DefaultLimits() Limits {
+	return Limits{Default: DefaultLimit, Max: MaxLimit}
+}
+
+// Normalize validates offset and clamps limit into (0, Max]; a non-positive limit becomes Default
+func (l Limits) Normalize(limit, offset int) (int, int, error) {
+	if offset < 0 {
+		return 0, 0, ErrNegativeOffset
+	}
+	if offset > MaxOffset {
+		return 0, 0, ErrOffsetTooLarge
+	}
+	return l.Clamp(limit), offset, nil
+}
+
+// Clamp returns the effective page size for the requested limit
+func (l Limits) Clamp(limit int) int {
+	switch {
+	case limit <= 0:
+		return l.Default
+	case l.Max > 0 && limit > l.Max:
+		return l.Max
+	}
+	return limit
+}
+
+// Check is the strict counterpart of ParseLimit, ParseOffset and Normalize: it reports limit query values outside
+// [1, Max] and offset values outside [0, MaxOffset], naming the allowed range. Empty values and values that are
+// not integers at all pass, the parsers reject the latter
+func (l Limits) Check(limit, offset string) error {
+	if n, ok := parseAny(limit); ok && (n < 1 || l.Max > 0 && n > int64(l.Max)) {
+		if l.Max > 0 {
+			return fmt.Errorf("limit %s is %w, allowed 1..%d", strings.TrimSpace(limit), ErrOutOfRange, l.Max)
+		}
+		return fmt.Errorf("limit %s is %w, allowed 1 and more", strings.TrimSpace(limit), ErrOutOfRange)
+	}
+	if n, ok := parseAny(offset); ok && (n < 0 || n > MaxOffset) {
+		return fmt.Errorf("offset %s is %w, allowed 0..%d", strings.TrimSpace(offset), ErrOutOfRange, MaxOffset)
+	}
+	return nil
+}
+
+// parseAny parses any integer, saturating values beyond 64 bits; ok is false for empty or non-integer values
+func parseAny(s string) (int64, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return 0, false
+	}
+	return n, true
+}
+
+// HasMore reports whether a page of n items filled the limit, i.e. a next page may exist
+func HasMore(n, limit int) bool {
+	return limit > 0 && n >= limit
+}
+
+// ParseLimit parses a limit query value; empty means unset (0). Values above MaxLimit are accepted
+// and clamped later, anything beyond 32 bits is rejected
+func ParseLimit(s string) (int, error) {
+	return parseCount(s, ErrInvalidLimit)
+}
+
+// ParseOffset parses an offset query value; empty means 0
+func ParseOffset(s string) (int, error) {
+	return parseCount(s, ErrInvalidOffset)
+}
+
+func parseCount(s string, invalid error) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 32)
+	if err != nil || n < 0 {
+		return 0, invalid
+	}
+	return int(n), nil
+}
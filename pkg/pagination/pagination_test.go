@@ -0,0 +1,207 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimits_Normalize(t *testing.T) {
+	l := Limits{Default: 50, Max: 200}
+	tests := []struct {
+		name       string
+		limit      int
+		offset     int
+		wantLimit  int
+		wantOffset int
+		wantErr    error
+	}{
+		{name: "default limit", limit: 0, offset: 5, wantLimit: 50, wantOffset: 5},
+		{name: "clamped to max", limit: 1000, wantLimit: 200},
+		{name: "kept as is", limit: 10, offset: 20, wantLimit: 10, wantOffset: 20},
+		{name: "negative offset", limit: 10, offset: -1, wantErr: ErrNegativeOffset},
+		{name: "offset beyond 32 bits", limit: 10, offset: MaxOffset + 1, wantErr: ErrOffsetTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit, offset, err := l.Normalize(tt.limit, tt.offset)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantLimit, limit)
+			assert.Equal(t, tt.wantOffset, offset)
+		})
+	}
+}
+
+func TestLimits_Check(t *testing.T) {
+	l := Limits{Default: 50, Max: 200}
+	tests := []struct {
+		limit, offset string
+		want          string
+	}{
+		{limit: "", offset: ""},
+		{limit: "200", offset: "0"},
+		{limit: "abc", offset: "-x"},
+		{limit: "201", want: "limit 201 is out of range, allowed 1..200"},
+		{limit: " 0 ", want: "limit 0 is out of range, allowed 1..200"},
+		{limit: "99999999999999999999", want: "limit 99999999999999999999 is out of range, allowed 1..200"},
+		{offset: "-1", want: "offset -1 is out of range, allowed 0..2147483647"},
+		{offset: "2147483648", want: "offset 2147483648 is out of range, allowed 0..2147483647"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.limit+"_"+tt.offset, func(t *testing.T) {
+			err := l.Check(tt.limit, tt.offset)
+			if tt.want == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrOutOfRange)
+			assert.EqualError(t, err, tt.want)
+		})
+	}
+}
+
+func TestCodec_RoundTrip(t *testing.T) {
+	type keyset struct {
+		Name string `json:"n"`
+		ID   int64  `json:"i"`
+	}
+	c := NewCodec([]byte("secret"))
+
+	cursor, err := c.Encode(keyset{Name: "Netflix", ID: 42})
+	require.NoError(t, err)
+
+	var got keyset
+	require.NoError(t, c.Decode(cursor, &got))
+	assert.Equal(t, keyset{Name: "Netflix", ID: 42}, got)
+
+	t.Run("foreign key", func(t *testing.T) {
+		var v keyset
+		assert.ErrorIs(t, NewCodec([]byte("other")).Decode(cursor, &v), ErrInvalidCursor)
+	})
+	t.Run("tampered", func(t *testing.T) {
+		var v keyset
+		assert.ErrorIs(t, c.Decode("x"+cursor, &v), ErrInvalidCursor)
+	})
+	t.Run("garbage", func(t *testing.T) {
+		var v keyset
+		assert.ErrorIs(t, c.Decode("not-a-cursor", &v), ErrInvalidCursor)
+	})
+}
+
+func TestCodec_Sealing(t *testing.T) {
+	type keyset struct {
+		Name string `json:"n"`
+		ID   int64  `json:"i"`
+	}
+	c := NewCodec([]byte("secret"), WithSealing())
+
+	cursor, err := c.Encode(keyset{Name: "Netflix", ID: 42})
+	require.NoError(t, err)
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "Netflix", "the keyset is not readable")
+
+	var got keyset
+	require.NoError(t, c.Decode(cursor, &got))
+	assert.Equal(t, keyset{Name: "Netflix", ID: 42}, got)
+
+	var v keyset
+	assert.ErrorIs(t, NewCodec([]byte("other"), WithSealing()).Decode(cursor, &v), ErrInvalidCursor, "foreign key")
+	assert.ErrorIs(t, NewCodec([]byte("secret")).Decode(cursor, &v), ErrInvalidCursor, "signed only")
+	signed, err := NewCodec([]byte("secret")).Encode(keyset{ID: 1})
+	require.NoError(t, err)
+	assert.ErrorIs(t, c.Decode(signed, &v), ErrInvalidCursor, "not sealed")
+	assert.ErrorIs(t, c.Decode("A"+cursor, &v), ErrInvalidCursor, "tampered")
+}
+
+func TestParseLimitOffset(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{input: "", want: 0},
+		{input: " 25 ", want: 25},
+		{input: "1000", want: 1000},
+		{input: "2147483647", want: MaxOffset},
+		{input: "2147483648", wantErr: true},
+		{input: "99999999999999999999", wantErr: true},
+		{input: "-1", wantErr: true},
+		{input: "1e3", wantErr: true},
+		{input: "0x10", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			limit, err := ParseLimit(tt.input)
+			offset, offErr := ParseOffset(tt.input)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidLimit)
+				assert.ErrorIs(t, offErr, ErrInvalidOffset)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, offErr)
+			assert.Equal(t, tt.want, limit)
+			assert.Equal(t, tt.want, offset)
+		})
+	}
+}
+
+func FuzzParseOffset(f *testing.F) {
+	for _, s := range []string{"", "0", "50", "-1", "2147483647", "2147483648", "9223372036854775808", " 7 ", "+3"} {
+		f.Add(s)
+	}
+	l := DefaultLimits()
+	f.Fuzz(func(t *testing.T, s string) {
+		n, err := ParseOffset(s)
+		if err != nil {
+			return
+		}
+		limit, offset, err := l.Normalize(n, n)
+		if err != nil {
+			t.Fatalf("Normalize(%d, %d) rejects a parsed value: %v", n, n, err)
+		}
+		if limit <= 0 || limit > l.Max || offset != n || offset+limit < offset {
+			t.Fatalf("Normalize(%d, %d) = %d, %d", n, n, limit, offset)
+		}
+	})
+}
+
+func FuzzCodec_Decode(f *testing.F) {
+	type keyset struct {
+		Date string `json:"d"`
+		Name string `json:"s"`
+		ID   int64  `json:"i"`
+	}
+	c := NewCodec([]byte("secret"))
+	valid, err := c.Encode(keyset{Date: "2025-07-01T00:00:00Z", Name: "Netflix", ID: 42})
+	require.NoError(f, err)
+	for _, s := range []string{valid, "", ".", "a.b", "not-a-cursor", valid + "=", strings.Repeat("A", 2000) + ".x"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		var v keyset
+		if err := c.Decode(s, &v); err != nil {
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("Decode(%q) = %v, want ErrInvalidCursor", s, err)
+			}
+			return
+		}
+		again, err := c.Encode(v)
+		if err != nil {
+			t.Fatalf("re-encode %+v: %v", v, err)
+		}
+		var back keyset
+		if err := c.Decode(again, &back); err != nil || back != v {
+			t.Fatalf("round trip of %+v = %+v, %v", v, back, err)
+		}
+	})
+}
@@ -0,0 +1,76 @@
+// Package ulid writes and reads 128-bit identifiers in the ULID text form: 26 characters of Crockford's
+// base32, the first 10 of them a millisecond timestamp, so the text sorts in the order the IDs were made.
+package ulid
+
+import (
+	"errors"
+	"strings"
+)
+
+// Len - characters of an encoded ULID
+const Len = 26
+
+// alphabet - Crockford's base32 without I, L, O and U
+const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ErrInvalid - the text is not a ULID
+var ErrInvalid = errors.New("invalid ulid")
+
+// decoding maps an upper-case character to its 5-bit value, 0xff for characters outside the alphabet
+var decoding = func() [256]byte {
+	var d [256]byte
+	for i := range d {
+		d[i] = 0xff
+	}
+	for i := 0; i < len(alphabet); i++ {
+		d[alphabet[i]] = byte(i)
+	}
+	return d
+}()
+
+// Encode writes id as 26 characters, most significant bits first
+func Encode(id [16]byte) string {
+	var out [Len]byte
+	// 26 characters hold 130 bits: two zero bits lead, so the first character carries 3 bits of the value
+	var acc uint64
+	bits, pos := 2, 0
+	for _, b := range id {
+		acc = acc<<8 | uint64(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			out[pos] = alphabet[(acc>>bits)&0x1f]
+			pos++
+		}
+	}
+	return string(out[:])
+}
+
+// Parse reads a ULID case-insensitively; values over 128 bits, i.e. starting with a digit above 7, are
+// rejected
+func Parse(s string) ([16]byte, error) {
+	var id [16]byte
+	if len(s) != Len {
+		return id, ErrInvalid
+	}
+	s = strings.ToUpper(s)
+	acc := uint64(decoding[s[0]])
+	if acc > 7 {
+		return id, ErrInvalid
+	}
+	bits, pos := 3, 0
+	for i := 1; i < Len; i++ {
+		v := decoding[s[i]]
+		if v == 0xff {
+			return id, ErrInvalid
+		}
+		acc = acc<<5 | uint64(v)
+		bits += 5
+		if bits >= 8 {
+			bits -= 8
+			id[pos] = byte(acc >> bits)
+			pos++
+		}
+	}
+	return id, nil
+}
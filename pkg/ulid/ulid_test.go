@@ -0,0 +1,47 @@
+package ulid
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeParse(t *testing.T) {
+	// the example of the ULID spec, made at 1469922850259 ms
+	const text = "01ARZ3NDEKTSV4RRFFQ69G5FAV"
+	raw, _ := hex.DecodeString("01563e3ab5d3d6764c61efb99302bd5b")
+	var want [16]byte
+	copy(want[:], raw)
+
+	got, err := Parse(text)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+	assert.Equal(t, text, Encode(want))
+
+	lower, err := Parse("01arz3ndektsv4rrffq69g5fav")
+	require.NoError(t, err)
+	assert.Equal(t, want, lower)
+
+	var top [16]byte
+	for i := range top {
+		top[i] = 0xff
+	}
+	assert.Equal(t, "7ZZZZZZZZZZZZZZZZZZZZZZZZZ", Encode(top))
+	assert.Equal(t, "00000000000000000000000000", Encode([16]byte{}))
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"01ARZ3NDEKTSV4RRFFQ69G5FA",   // too short
+		"01ARZ3NDEKTSV4RRFFQ69G5FAVX", // too long
+		"01ARZ3NDEKTSV4RRFFQ69G5FAU",  // U is not in the alphabet
+		"81ARZ3NDEKTSV4RRFFQ69G5FAV",  // over 128 bits
+		"01ARZ3NDEK-SV4RRFFQ69G5FAV",
+	} {
+		_, err := Parse(s)
+		assert.ErrorIs(t, err, ErrInvalid, s)
+	}
+}
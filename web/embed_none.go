@@ -0,0 +1,10 @@
+//go:build !spa
+
+package web
+
+import "io/fs"
+
+// Dist returns nil: the binary was built without -tags spa
+func Dist() fs.FS {
+	return nil
+}
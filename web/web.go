@@ -0,0 +1,3 @@
+// Package web holds the built single-page frontend. Copy the build output into web/dist and compile
+// with -tags spa to serve it from the binary at /
+package web